@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// flushCaches makes Nexus forget the in-memory state kept for disconnected minions
+func (c *Console) flushCaches(ctx context.Context) {
+	resp, err := c.grpc.FlushCaches(ctx)
+	if err != nil {
+		c.logger.Error("Failed to flush caches", zap.Error(err))
+		c.printError(fmt.Sprintf("Error flushing caches: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(FlushCachesOutput{PendingCommands: resp.PendingCommands, Diagnostics: resp.Diagnostics})
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Caches flushed: %d command trackers and %d connection histories forgotten",
+		resp.PendingCommands, resp.Diagnostics))
+}
+
+// setLogLevel changes the log level and sampling of Nexus, or shows them without argument
+func (c *Console) setLogLevel(ctx context.Context, args []string) {
+	level, sampling := "", ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--sampling" && i+1 < len(args):
+			sampling = args[i+1]
+			i++
+		case level == "" && !strings.HasPrefix(args[i], "--"):
+			level = args[i]
+		default:
+			c.printError("usage: admin-log-level [debug|info|warn|error] [--sampling <initial>,<thereafter>|off]")
+			return
+		}
+	}
+
+	resp, err := c.grpc.SetLogLevel(ctx, level, sampling)
+	if err != nil {
+		c.logger.Error("Failed to set log level", zap.String("level", level), zap.String("sampling", sampling), zap.Error(err))
+		c.printError(fmt.Sprintf("Error setting log level: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(LogLevelOutput{Level: resp.Level, PreviousLevel: resp.PreviousLevel, Sampling: resp.Sampling, PreviousSampling: resp.PreviousSampling})
+		return
+	}
+	if level == "" && sampling == "" {
+		fmt.Printf("Nexus log level: %s\n", resp.Level)
+		if resp.Sampling != "" {
+			fmt.Printf("Nexus log sampling: %s\n", resp.Sampling)
+		}
+		return
+	}
+	if level != "" {
+		c.ui.PrintSuccess(fmt.Sprintf("Nexus log level changed from %s to %s", resp.PreviousLevel, resp.Level))
+	}
+	if sampling != "" {
+		c.ui.PrintSuccess(fmt.Sprintf("Nexus log sampling changed from %s to %s", resp.PreviousSampling, resp.Sampling))
+	}
+}
+
+// dumpRegistry shows the in-memory state of the minions registered on Nexus
+func (c *Console) dumpRegistry(ctx context.Context) {
+	dump, err := c.grpc.DumpRegistry(ctx)
+	if err != nil {
+		c.logger.Error("Failed to dump registry", zap.Error(err))
+		c.printError(fmt.Sprintf("Error dumping registry: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := RegistryDumpOutput{
+			Minions:         make([]RegistryEntryOutput, 0, len(dump.Minions)),
+			Connected:       dump.Connected,
+			PendingCommands: dump.PendingCommands,
+			HeldCommands:    dump.HeldCommands,
+		}
+		for _, entry := range dump.Minions {
+			output.Minions = append(output.Minions, RegistryEntryOutput{
+				MinionID:        entry.MinionId,
+				Hostname:        entry.Hostname,
+				LastSeen:        entry.LastSeen,
+				Connected:       entry.Connected,
+				QueuedCommands:  entry.QueuedCommands,
+				InFlight:        entry.InFlight,
+				PendingCommands: entry.PendingCommands,
+				IDCollisions:    entry.IdCollisions,
+				LastIDCollision: entry.LastIdCollision,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	fmt.Printf("%-36s %-20s %-9s %-19s %6s %8s %7s %10s\n", "Minion", "Hostname", "Stream", "Last seen", "Queued", "InFlight", "Pending", "Collisions")
+	var collided []*pb.RegistryEntry
+	for _, entry := range dump.Minions {
+		stream := "closed"
+		if entry.Connected {
+			stream = "open"
+		}
+		fmt.Printf("%-36s %-20s %-9s %-19s %6d %8d %7d %10d\n", entry.MinionId, entry.Hostname, stream,
+			c.formatUnixTime(entry.LastSeen), entry.QueuedCommands, entry.InFlight, entry.PendingCommands, entry.IdCollisions)
+		if entry.IdCollisions > 0 {
+			collided = append(collided, entry)
+		}
+	}
+	fmt.Printf("\n%d minions, %d connected, %d commands awaiting results, %d held by maintenance windows\n",
+		len(dump.Minions), dump.Connected, dump.PendingCommands, dump.HeldCommands)
+	for _, entry := range collided {
+		c.ui.PrintWarning(fmt.Sprintf("Minion ID %s was claimed by another host %d time(s), last at %s: check for duplicated MINION_ID",
+			entry.MinionId, entry.IdCollisions, c.formatUnixTime(entry.LastIdCollision)))
+	}
+}
+
+// disconnectMinion closes the command stream of a minion, which reconnects and receives its queued commands
+func (c *Console) disconnectMinion(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.printError("usage: admin-disconnect <minion-id>")
+		return
+	}
+
+	if _, err := c.grpc.DisconnectMinion(ctx, args[0]); err != nil {
+		c.logger.Error("Failed to disconnect minion", zap.String("minion_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error disconnecting minion: %v", err))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Command stream of minion %s closed", args[0]))
+}
+
+// unbindMinion makes Nexus forget the certificate a minion ID is bound to, so that the minion can
+// register again with a new certificate
+func (c *Console) unbindMinion(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.printError("usage: admin-unbind <minion-id>")
+		return
+	}
+
+	if _, err := c.grpc.UnbindMinion(ctx, args[0]); err != nil {
+		c.logger.Error("Failed to unbind minion", zap.String("minion_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error unbinding minion: %v", err))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Minion %s unbound, its next certificate will be bound to it", args[0]))
+}
+
+// pruneDatabase makes Nexus archive the results and delete the minion logs older than their retention period right away
+func (c *Console) pruneDatabase(ctx context.Context) {
+	resp, err := c.grpc.PruneDatabase(ctx)
+	if err != nil {
+		c.logger.Error("Failed to prune database", zap.Error(err))
+		c.printError(fmt.Sprintf("Error pruning database: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(PruneDatabaseOutput{ArchivedCommands: resp.ArchivedCommands, PrunedLogs: resp.PrunedLogs})
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Database pruned: results of %d commands archived, %d minion log entries deleted",
+		resp.ArchivedCommands, resp.PrunedLogs))
+}
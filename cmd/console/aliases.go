@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultAliasFileName is the alias file created in the user's home directory
+const defaultAliasFileName = ".minexus_aliases.json"
+
+// aliasNamePattern restricts alias names to simple words
+var aliasNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// reservedCommands are console commands that cannot be shadowed by an alias
+var reservedCommands = map[string]bool{
+	"help": true, "h": true, "version": true, "v": true,
+	"minion-list": true, "lm": true, "minion-inspect": true, "minion-history": true, "minion-uptime": true, "minion-logs": true, "minion-retire": true, "crash-list": true, "minion-bootstrap-url": true,
+	"tag-list": true, "lt": true, "tag-set": true, "tag-update": true, "tag-schema-show": true,
+	"command-send": true, "cmd": true, "edit": true, "command-status": true, "target-explain": true,
+	"use": true, "run": true, "targets": true, "pick": true,
+	"command-approvals": true, "command-approve": true, "command-reject": true,
+	"result-get": true, "results": true, "result-view": true, "trace-get": true, "stats": true, "fleet-health": true, "fleet-versions": true, "fleet-patches": true, "compliance-export": true, "compliance-verify": true,
+	"file-pull": true, "file-download": true, "file-transfers": true, "validate": true,
+	"maintenance-add": true, "maintenance-list": true, "maintenance-remove": true,
+	"env-set": true, "env-unset": true, "env-list": true,
+	"admin-flush-caches": true, "admin-log-level": true, "admin-registry": true, "admin-disconnect": true, "admin-unbind": true, "admin-prune": true,
+	"alias": true, "alias-list": true, "alias-remove": true, "connect": true,
+	"set": true, "clear": true, "history": true, "quit": true, "exit": true,
+}
+
+// AliasStore keeps user-defined command aliases persisted in a JSON file.
+// An empty path keeps aliases in memory only.
+type AliasStore struct {
+	mu      sync.Mutex
+	path    string
+	aliases map[string]string
+}
+
+// DefaultAliasFile returns the alias file path in the user's home directory
+func DefaultAliasFile() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, defaultAliasFileName)
+}
+
+// LoadAliasStore loads aliases from path, a missing file yields an empty store
+func LoadAliasStore(path string) (*AliasStore, error) {
+	store := &AliasStore{
+		path:    path,
+		aliases: make(map[string]string),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alias file: %w", err)
+	}
+	if err := json.Unmarshal(data, &store.aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse alias file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Set defines or replaces an alias and persists the store
+func (s *AliasStore) Set(name, expansion string) error {
+	if !aliasNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid alias name '%s': use letters, digits, '-' and '_'", name)
+	}
+	if reservedCommands[strings.ToLower(name)] {
+		return fmt.Errorf("'%s' is a console command and cannot be used as an alias", name)
+	}
+	if strings.TrimSpace(expansion) == "" {
+		return fmt.Errorf("alias '%s' cannot be empty", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.aliases[name]
+	s.aliases[name] = strings.TrimSpace(expansion)
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			s.aliases[name] = previous
+		} else {
+			delete(s.aliases, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove deletes an alias and persists the store
+func (s *AliasStore) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expansion, exists := s.aliases[name]
+	if !exists {
+		return fmt.Errorf("alias '%s' not found", name)
+	}
+	delete(s.aliases, name)
+	if err := s.saveLocked(); err != nil {
+		s.aliases[name] = expansion
+		return err
+	}
+	return nil
+}
+
+// Names returns the alias names in alphabetical order
+func (s *AliasStore) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.aliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the expansion of an alias
+func (s *AliasStore) Get(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expansion, exists := s.aliases[name]
+	return expansion, exists
+}
+
+// Expand replaces a leading alias in line by its expansion, keeping the remaining arguments.
+// Expansion is not recursive so aliases cannot loop.
+func (s *AliasStore) Expand(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	name, rest := trimmed, ""
+	if idx := strings.IndexFunc(trimmed, func(r rune) bool { return r == ' ' || r == '\t' }); idx >= 0 {
+		name, rest = trimmed[:idx], trimmed[idx:]
+	}
+
+	expansion, exists := s.Get(name)
+	if !exists {
+		return line, false
+	}
+	return expansion + rest, true
+}
+
+// saveLocked writes the aliases to disk atomically, the caller must hold s.mu
+func (s *AliasStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.aliases, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode aliases: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write alias file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write alias file: %w", err)
+	}
+	return nil
+}
+
+// SetAliases configures the alias store used to expand console input
+func (c *Console) SetAliases(store *AliasStore) {
+	c.aliases = store
+}
+
+// expandAlias expands a leading alias in an input line
+func (c *Console) expandAlias(line string) string {
+	if c.aliases == nil {
+		return line
+	}
+	expanded, ok := c.aliases.Expand(line)
+	if ok {
+		c.logger.Debug("Alias expanded")
+	}
+	return expanded
+}
+
+// defineAlias handles alias <name>=<command>, without arguments it lists the aliases
+func (c *Console) defineAlias(args []string) {
+	if len(args) == 0 {
+		c.listAliases()
+		return
+	}
+	if c.aliases == nil {
+		c.printError("Aliases are not available")
+		return
+	}
+
+	definition := strings.Join(args, " ")
+	name, expansion, found := strings.Cut(definition, "=")
+	if !found {
+		if expansion, exists := c.aliases.Get(definition); exists {
+			fmt.Printf("%s=%s\n", definition, expansion)
+			return
+		}
+		c.printError("Usage: alias <name>=\"<command>\"")
+		return
+	}
+
+	if err := c.aliases.Set(strings.TrimSpace(name), expansion); err != nil {
+		c.printError(err.Error())
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Alias '%s' defined", strings.TrimSpace(name)))
+}
+
+// listAliases prints the defined aliases
+func (c *Console) listAliases() {
+	if c.aliases == nil {
+		c.printError("Aliases are not available")
+		return
+	}
+
+	names := c.aliases.Names()
+	if c.isJSONOutput() {
+		output := AliasListOutput{Count: len(names), Aliases: make(map[string]string, len(names))}
+		for _, name := range names {
+			output.Aliases[name], _ = c.aliases.Get(name)
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(names) == 0 {
+		c.ui.PrintInfo("No aliases defined")
+		return
+	}
+
+	fmt.Printf("Aliases (%d):\n", len(names))
+	for _, name := range names {
+		expansion, _ := c.aliases.Get(name)
+		fmt.Printf("  %s=%s\n", name, expansion)
+	}
+}
+
+// removeAlias handles alias-remove <name>
+func (c *Console) removeAlias(args []string) {
+	if len(args) != 1 {
+		c.printError("Usage: alias-remove <name>")
+		return
+	}
+	if c.aliases == nil {
+		c.printError("Aliases are not available")
+		return
+	}
+
+	if err := c.aliases.Remove(args[0]); err != nil {
+		c.printError(err.Error())
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Alias '%s' removed", args[0]))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// newCommandApprovalOutput converts an approval to its JSON representation
+func newCommandApprovalOutput(approval *pb.CommandApproval) CommandApprovalOutput {
+	targets := approval.TargetMinionIds
+	if targets == nil {
+		targets = []string{}
+	}
+	return CommandApprovalOutput{
+		CommandID:   approval.CommandId,
+		Payload:     approval.Payload,
+		Targets:     targets,
+		Reason:      approval.Reason,
+		Status:      approval.Status,
+		RequestedBy: approval.RequestedBy,
+		RequestedAt: approval.RequestedAt,
+		DecidedBy:   approval.DecidedBy,
+		DecidedAt:   approval.DecidedAt,
+		Comment:     approval.Comment,
+		Impact:      approval.Impact,
+	}
+}
+
+// approvalsByImpact counts the approvals of each impact class, those without class as unknown
+func approvalsByImpact(approvals []*pb.CommandApproval) map[string]int {
+	byImpact := make(map[string]int)
+	for _, approval := range approvals {
+		byImpact[formatImpact(approval.Impact)]++
+	}
+	return byImpact
+}
+
+// listCommandApprovals lists the commands requiring approval, pending ones first
+func (c *Console) listCommandApprovals(ctx context.Context) {
+	response, err := c.grpc.ListCommandApprovals(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error listing command approvals: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := CommandApprovalListOutput{
+			Count:     len(response.Approvals),
+			ByImpact:  approvalsByImpact(response.Approvals),
+			Approvals: make([]CommandApprovalOutput, 0, len(response.Approvals)),
+		}
+		for _, approval := range response.Approvals {
+			output.Approvals = append(output.Approvals, newCommandApprovalOutput(approval))
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(response.Approvals) == 0 {
+		c.ui.PrintInfo("No command requires approval")
+		return
+	}
+
+	fmt.Printf("Command approvals (%d):\n", len(response.Approvals))
+	fmt.Println("Command ID                           | Status           | Requested by     | Requested at     | Impact     | Targets | Command")
+	fmt.Println("------------------------------------ | ---------------- | ---------------- | ---------------- | ---------- | ------- | -------")
+	for _, approval := range response.Approvals {
+		fmt.Printf("%-36s | %-16s | %-16s | %-16s | %-10s | %7d | %s (%s)\n",
+			approval.CommandId, approval.Status, approval.RequestedBy,
+			time.Unix(approval.RequestedAt, 0).Format("2006-01-02 15:04"), formatImpact(approval.Impact),
+			len(approval.TargetMinionIds), approval.Payload, approval.Reason)
+		if approval.DecidedBy != "" {
+			fmt.Printf("    %s by %s at %s %s\n", strings.ToLower(approval.Status), approval.DecidedBy,
+				time.Unix(approval.DecidedAt, 0).Format("2006-01-02 15:04"), approval.Comment)
+		}
+	}
+
+	byImpact := approvalsByImpact(response.Approvals)
+	var breakdown []string
+	for _, impact := range command.ImpactClasses {
+		if count := byImpact[impact]; count > 0 {
+			breakdown = append(breakdown, fmt.Sprintf("%d %s", count, impact))
+		}
+	}
+	if count := byImpact[formatImpact("")]; count > 0 {
+		breakdown = append(breakdown, fmt.Sprintf("%d %s", count, formatImpact("")))
+	}
+	fmt.Printf("By impact: %s\n", strings.Join(breakdown, ", "))
+}
+
+// decideCommandApproval approves, dispatching it, or rejects a command sent by another operator
+func (c *Console) decideCommandApproval(ctx context.Context, args []string, reject bool) {
+	action := "approve"
+	if reject {
+		action = "reject"
+	}
+	if len(args) == 0 {
+		c.printError(fmt.Sprintf("usage: command-%s <cmd-id> [comment]", action))
+		return
+	}
+
+	decision := &pb.ApprovalDecision{CommandId: args[0], Reject: reject, Comment: strings.Join(args[1:], " ")}
+	response, err := c.grpc.DecideCommandApproval(ctx, decision)
+	if err != nil {
+		c.logger.Error("Failed to decide command approval", zap.String("command_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error trying to %s command: %v", action, err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(CommandSendOutput{
+			Accepted:    response.Accepted,
+			CommandID:   response.CommandId,
+			TraceID:     response.TraceId,
+			Targets:     response.TargetMinionIds,
+			Held:        response.HeldMinionIds,
+			Results:     []ResultOutput{},
+			LockWaiting: response.LockWaitingMinionIds,
+			Staggered:   response.StaggeredMinionIds,
+		})
+		return
+	}
+
+	if reject {
+		c.ui.PrintSuccess(fmt.Sprintf("Command %s rejected", response.CommandId))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Command %s approved and dispatched to %d minion(s)", response.CommandId, len(response.TargetMinionIds)))
+	if len(response.HeldMinionIds) > 0 {
+		c.ui.PrintInfo(fmt.Sprintf("Held until their maintenance window opens (%d): %s",
+			len(response.HeldMinionIds), strings.Join(response.HeldMinionIds, ", ")))
+	}
+	c.ui.AddToHistory(fmt.Sprintf("result-get %s", response.CommandId))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// createBootstrapURL generates a one-time URL installing a minion on a new host
+func (c *Console) createBootstrapURL(ctx context.Context, args []string) {
+	usage := "Usage: minion-bootstrap-url <linux|darwin|windows> <amd64|arm64> [--ttl <duration>]"
+	var ttl time.Duration
+	switch {
+	case len(args) == 4 && args[2] == "--ttl":
+		d, err := time.ParseDuration(args[3])
+		if err != nil || d <= 0 {
+			c.printError(fmt.Sprintf("Invalid TTL '%s', must be a positive duration such as 30m or 2h", args[3]))
+			return
+		}
+		ttl = d
+	case len(args) != 2:
+		c.printError(usage)
+		return
+	}
+
+	token, err := c.grpc.CreateBootstrapToken(ctx, args[0], args[1], ttl)
+	if err != nil {
+		c.logger.Error("Failed to create bootstrap token", zap.Error(err))
+		c.printError(fmt.Sprintf("Error creating bootstrap URL: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(BootstrapURLOutput{
+			URL:       token.Url,
+			OS:        token.Os,
+			Arch:      token.Arch,
+			ExpiresAt: token.ExpiresAt,
+		})
+		return
+	}
+
+	expires := time.Unix(token.ExpiresAt, 0).Format("2006-01-02 15:04:05")
+	fmt.Printf("Bootstrap URL for %s/%s (single use, expires %s):\n", token.Os, token.Arch, expires)
+	fmt.Printf("  %s\n", token.Url)
+	fmt.Println("Run on the new host:")
+	if token.Os == "windows" {
+		fmt.Printf("  powershell -ExecutionPolicy Bypass -Command \"iwr -UseBasicParsing '%s' | iex\"\n", token.Url)
+	} else {
+		fmt.Printf("  curl -fsSL '%s' | sudo sh\n", token.Url)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/compression"
 	"github.com/arhuman/minexus/internal/config"
 	"github.com/arhuman/minexus/internal/logging"
 	pb "github.com/arhuman/minexus/protogen"
@@ -21,8 +24,11 @@ import (
 // GRPCClient handles all gRPC communication with the Nexus server
 type GRPCClient struct {
 	client pb.ConsoleServiceClient
+	admin  pb.AdminServiceClient
 	conn   *grpc.ClientConn
 	logger *zap.Logger
+
+	onRetry func(attempt int, delay time.Duration, err error) // told about the requests retried while Nexus is unreachable
 }
 
 // NewGRPCClient creates a new gRPC client instance
@@ -61,14 +67,18 @@ func NewGRPCClient(cfg *config.ConsoleConfig, logger *zap.Logger) (*GRPCClient,
 	logger.Info("Configuring mTLS for console client authentication")
 
 	// Load console client certificate and private key
-	clientCert, err := tls.X509KeyPair(certs.ConsoleClientCertPEM, certs.ConsoleClientKeyPEM)
+	clientCertPEM, clientKeyPEM, caPEM, err := loadCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load console client certificate: %w", err)
 	}
 
 	// Load CA certificate for server verification
 	caCertPool := x509.NewCertPool()
-	if !caCertPool.AppendCertsFromPEM(certs.CAPem) {
+	if !caCertPool.AppendCertsFromPEM(caPEM) {
 		return nil, fmt.Errorf("failed to load CA certificate")
 	}
 
@@ -83,27 +93,88 @@ func NewGRPCClient(cfg *config.ConsoleConfig, logger *zap.Logger) (*GRPCClient,
 	logger.Info("mTLS credentials configured for console client",
 		zap.String("server_name", tlsConfig.ServerName))
 
-	// Create connection using modern gRPC pattern with timeout
-	conn, err := grpc.NewClient(cfg.ServerAddr,
+	// Create connection using modern gRPC pattern with timeout, the channel reconnecting by itself
+	// and the requests failing while Nexus is unreachable being retried
+	gc := &GRPCClient{logger: logger}
+	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(gc.retryUnary),
 		grpc.WithConnectParams(grpc.ConnectParams{
 			MinConnectTimeout: time.Duration(cfg.ConnectTimeout) * time.Second,
 		}),
-	)
+		compression.DialOption(cfg.Compression),
+	}
+	if cfg.TokenFile != "" {
+		token := tokenCredentials(cfg.TokenFile)
+		if _, err := token.read(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(token))
+		logger.Info("Presenting the OpenID Connect token of a file to Nexus", zap.String("token_file", cfg.TokenFile))
+	}
+	conn, err := grpc.NewClient(cfg.ServerAddr, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
 	}
 
 	logger.Info("Connected to Nexus server")
 
-	// Create Console service client
-	client := pb.NewConsoleServiceClient(conn)
+	// Create Console and Admin service clients
+	gc.client = pb.NewConsoleServiceClient(conn)
+	gc.admin = pb.NewAdminServiceClient(conn)
+	gc.conn = conn
+	return gc, nil
+}
+
+// loadCredentials returns the PEM client certificate, key and CA of the configuration,
+// defaulting to the embedded console credentials
+func loadCredentials(cfg *config.ConsoleConfig) (certPEM, keyPEM, caPEM []byte, err error) {
+	certPEM, keyPEM, caPEM = certs.ConsoleClientCertPEM, certs.ConsoleClientKeyPEM, certs.CAPem
+	if cfg.ClientCert != "" {
+		if certPEM, err = os.ReadFile(cfg.ClientCert); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		if keyPEM, err = os.ReadFile(cfg.ClientKey); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+	}
+	if cfg.CACert != "" {
+		if caPEM, err = os.ReadFile(cfg.CACert); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+	}
+	return certPEM, keyPEM, caPEM, nil
+}
+
+// tokenCredentials presents the OpenID Connect token of a file as a bearer token. The file is read at
+// every request, so that the tool logging the operator in can refresh the token while the console runs.
+type tokenCredentials string
 
-	return &GRPCClient{
-		client: client,
-		conn:   conn,
-		logger: logger,
-	}, nil
+// read returns the token of the file
+func (t tokenCredentials) read() (string, error) {
+	data, err := os.ReadFile(string(t))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", string(t))
+	}
+	return token, nil
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := t.read()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials, tokens only being sent over TLS
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return true
 }
 
 // Close closes the gRPC connection
@@ -129,11 +200,31 @@ func (gc *GRPCClient) SendCommand(ctx context.Context, req *pb.CommandRequest) (
 	return gc.client.SendCommand(ctx, req)
 }
 
+// ExplainTargets explains which minions a target matches and why
+func (gc *GRPCClient) ExplainTargets(ctx context.Context, req *pb.ExplainTargetsRequest) (*pb.TargetExplanations, error) {
+	return gc.client.ExplainTargets(ctx, req)
+}
+
 // GetCommandResults gets command execution results
 func (gc *GRPCClient) GetCommandResults(ctx context.Context, req *pb.ResultRequest) (*pb.CommandResults, error) {
 	return gc.client.GetCommandResults(ctx, req)
 }
 
+// GetCommandStatus gets the status of a command on each of its target minions
+func (gc *GRPCClient) GetCommandStatus(ctx context.Context, commandID string) (*pb.CommandStatusResponse, error) {
+	return gc.client.GetCommandStatus(ctx, &pb.ResultRequest{CommandId: commandID})
+}
+
+// ListCommandApprovals lists the commands requiring approval
+func (gc *GRPCClient) ListCommandApprovals(ctx context.Context) (*pb.CommandApprovalList, error) {
+	return gc.client.ListCommandApprovals(ctx, &pb.Empty{})
+}
+
+// DecideCommandApproval approves or rejects a command awaiting approval
+func (gc *GRPCClient) DecideCommandApproval(ctx context.Context, decision *pb.ApprovalDecision) (*pb.CommandDispatchResponse, error) {
+	return gc.client.DecideCommandApproval(ctx, decision)
+}
+
 // SetTags sets tags for a minion (replaces all existing tags)
 func (gc *GRPCClient) SetTags(ctx context.Context, req *pb.SetTagsRequest) (*pb.Ack, error) {
 	return gc.client.SetTags(ctx, req)
@@ -143,3 +234,183 @@ func (gc *GRPCClient) SetTags(ctx context.Context, req *pb.SetTagsRequest) (*pb.
 func (gc *GRPCClient) UpdateTags(ctx context.Context, req *pb.UpdateTagsRequest) (*pb.Ack, error) {
 	return gc.client.UpdateTags(ctx, req)
 }
+
+// GetTagSchema gets the tag schema the tags set are validated against
+func (gc *GRPCClient) GetTagSchema(ctx context.Context) (*pb.TagSchema, error) {
+	return gc.client.GetTagSchema(ctx, &pb.Empty{})
+}
+
+// CreateBootstrapToken creates a one-time URL installing a minion for os and arch, valid during ttl (0: Nexus default)
+func (gc *GRPCClient) CreateBootstrapToken(ctx context.Context, os, arch string, ttl time.Duration) (*pb.BootstrapToken, error) {
+	return gc.client.CreateBootstrapToken(ctx, &pb.BootstrapRequest{Os: os, Arch: arch, TtlSeconds: int64(ttl / time.Second)})
+}
+
+// AddMaintenanceWindow declares a maintenance window
+func (gc *GRPCClient) AddMaintenanceWindow(ctx context.Context, window *pb.MaintenanceWindow) (*pb.MaintenanceWindow, error) {
+	return gc.client.AddMaintenanceWindow(ctx, window)
+}
+
+// ListMaintenanceWindows lists current and upcoming maintenance windows
+func (gc *GRPCClient) ListMaintenanceWindows(ctx context.Context) (*pb.MaintenanceWindowList, error) {
+	return gc.client.ListMaintenanceWindows(ctx, &pb.Empty{})
+}
+
+// RemoveMaintenanceWindow deletes a maintenance window
+func (gc *GRPCClient) RemoveMaintenanceWindow(ctx context.Context, id string) (*pb.Ack, error) {
+	return gc.client.RemoveMaintenanceWindow(ctx, &pb.MaintenanceWindowRequest{Id: id})
+}
+
+// GetMinionDiagnostics gets the connection diagnostics of a minion
+func (gc *GRPCClient) GetMinionDiagnostics(ctx context.Context, minionID string) (*pb.MinionDiagnostics, error) {
+	return gc.client.GetMinionDiagnostics(ctx, &pb.MinionDiagnosticsRequest{MinionId: minionID})
+}
+
+// GetMinionUptime gets the connection periods of a minion over a window ending now, window 0 using the server default
+func (gc *GRPCClient) GetMinionUptime(ctx context.Context, minionID string, window time.Duration) (*pb.MinionUptime, error) {
+	return gc.client.GetMinionUptime(ctx, &pb.MinionUptimeRequest{MinionId: minionID, WindowSeconds: int64(window.Seconds())})
+}
+
+// GetMinionHistory gets the last commands executed on a minion, limit 0 using the server default
+func (gc *GRPCClient) GetMinionHistory(ctx context.Context, minionID string, limit int) (*pb.MinionHistory, error) {
+	return gc.client.GetMinionHistory(ctx, &pb.MinionHistoryRequest{MinionId: minionID, Limit: int32(limit)})
+}
+
+// RetireMinion decommissions a minion
+func (gc *GRPCClient) RetireMinion(ctx context.Context, req *pb.RetireMinionRequest) (*pb.RetireMinionResponse, error) {
+	return gc.client.RetireMinion(ctx, req)
+}
+
+// GetMinionLogs gets the logs a minion shipped to Nexus
+func (gc *GRPCClient) GetMinionLogs(ctx context.Context, req *pb.MinionLogsRequest) (*pb.MinionLogs, error) {
+	return gc.client.GetMinionLogs(ctx, req)
+}
+
+// SetCommandEnv sets an environment variable of the shell commands of a minion or tag
+func (gc *GRPCClient) SetCommandEnv(ctx context.Context, envVar *pb.CommandEnvVar) (*pb.Ack, error) {
+	return gc.client.SetCommandEnv(ctx, envVar)
+}
+
+// UnsetCommandEnv removes an environment variable of the shell commands of a minion or tag
+func (gc *GRPCClient) UnsetCommandEnv(ctx context.Context, envVar *pb.CommandEnvVar) (*pb.Ack, error) {
+	return gc.client.UnsetCommandEnv(ctx, envVar)
+}
+
+// ListCommandEnv lists the environment variables of shell commands
+func (gc *GRPCClient) ListCommandEnv(ctx context.Context) (*pb.CommandEnvList, error) {
+	return gc.client.ListCommandEnv(ctx, &pb.Empty{})
+}
+
+// ListCrashes gets the panics minions recovered from, most recent first
+func (gc *GRPCClient) ListCrashes(ctx context.Context, req *pb.CrashListRequest) (*pb.CrashList, error) {
+	return gc.client.ListCrashes(ctx, req)
+}
+
+// GetTrace gets the timeline of the commands sharing a trace ID
+func (gc *GRPCClient) GetTrace(ctx context.Context, traceID string) (*pb.Trace, error) {
+	return gc.client.GetTrace(ctx, &pb.TraceRequest{TraceId: traceID})
+}
+
+// GetFleetVersions gets the builds the minions of the fleet run
+func (gc *GRPCClient) GetFleetVersions(ctx context.Context) (*pb.FleetVersions, error) {
+	return gc.client.GetFleetVersions(ctx, &pb.Empty{})
+}
+
+// GetPatchCompliance gets the last patch status of the fleet, grouped by the value of the tag groupBy
+func (gc *GRPCClient) GetPatchCompliance(ctx context.Context, groupBy string) (*pb.PatchCompliance, error) {
+	return gc.client.GetPatchCompliance(ctx, &pb.PatchComplianceRequest{GroupBy: groupBy})
+}
+
+// GetFleetHealth gets the health summary of the fleet
+func (gc *GRPCClient) GetFleetHealth(ctx context.Context, req *pb.FleetHealthRequest) (*pb.FleetHealth, error) {
+	return gc.client.GetFleetHealth(ctx, req)
+}
+
+// ExportReceipts streams the signed execution receipts of the commands sent in a time range
+func (gc *GRPCClient) ExportReceipts(ctx context.Context, req *pb.ReceiptExportRequest) (pb.ConsoleService_ExportReceiptsClient, error) {
+	return gc.client.ExportReceipts(ctx, req)
+}
+
+// GetCommandStats gets the success and failure counts and execution durations of the fleet
+func (gc *GRPCClient) GetCommandStats(ctx context.Context, req *pb.CommandStatsRequest) (*pb.CommandStats, error) {
+	return gc.client.GetCommandStats(ctx, req)
+}
+
+// FlushCaches forgets the in-memory state Nexus keeps for disconnected minions
+func (gc *GRPCClient) FlushCaches(ctx context.Context) (*pb.FlushCachesResponse, error) {
+	return gc.admin.FlushCaches(ctx, &pb.Empty{})
+}
+
+// SetLogLevel changes the log level and sampling of Nexus, empty values getting the current ones
+func (gc *GRPCClient) SetLogLevel(ctx context.Context, level, sampling string) (*pb.LogLevelResponse, error) {
+	return gc.admin.SetLogLevel(ctx, &pb.LogLevelRequest{Level: level, Sampling: sampling})
+}
+
+// DumpRegistry gets the in-memory state of the registered minions
+func (gc *GRPCClient) DumpRegistry(ctx context.Context) (*pb.RegistryDump, error) {
+	return gc.admin.DumpRegistry(ctx, &pb.Empty{})
+}
+
+// DisconnectMinion closes the command stream of a minion
+func (gc *GRPCClient) DisconnectMinion(ctx context.Context, minionID string) (*pb.Ack, error) {
+	return gc.admin.DisconnectMinion(ctx, &pb.DisconnectMinionRequest{MinionId: minionID})
+}
+
+// UnbindMinion forgets the certificate a minion ID is bound to
+func (gc *GRPCClient) UnbindMinion(ctx context.Context, minionID string) (*pb.Ack, error) {
+	return gc.admin.UnbindMinion(ctx, &pb.UnbindMinionRequest{MinionId: minionID})
+}
+
+// PruneDatabase archives the results older than the retention period right away
+func (gc *GRPCClient) PruneDatabase(ctx context.Context) (*pb.PruneDatabaseResponse, error) {
+	return gc.admin.PruneDatabase(ctx, &pb.Empty{})
+}
+
+// CreateReport saves a report query
+func (gc *GRPCClient) CreateReport(ctx context.Context, report *pb.Report) (*pb.Report, error) {
+	return gc.client.CreateReport(ctx, report)
+}
+
+// ListReports lists the saved reports
+func (gc *GRPCClient) ListReports(ctx context.Context) (*pb.ReportList, error) {
+	return gc.client.ListReports(ctx, &pb.Empty{})
+}
+
+// RunReport runs a saved report with its parameters
+func (gc *GRPCClient) RunReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResult, error) {
+	return gc.client.RunReport(ctx, req)
+}
+
+// CheckDatabase runs the database integrity checks, repairing the inconsistencies found when repair is set
+func (gc *GRPCClient) CheckDatabase(ctx context.Context, repair bool) (*pb.DatabaseCheckReport, error) {
+	return gc.client.CheckDatabase(ctx, &pb.DatabaseCheckRequest{Repair: repair})
+}
+
+// ListDatabaseQueries lists the read-only database queries approved on Nexus
+func (gc *GRPCClient) ListDatabaseQueries(ctx context.Context) (*pb.DatabaseQueryList, error) {
+	return gc.client.ListDatabaseQueries(ctx, &pb.Empty{})
+}
+
+// RunDatabaseQuery runs an approved database query with its parameters
+func (gc *GRPCClient) RunDatabaseQuery(ctx context.Context, req *pb.DatabaseQueryRequest) (*pb.DatabaseQueryResult, error) {
+	return gc.client.RunDatabaseQuery(ctx, req)
+}
+
+// OpenShell opens an interactive shell session stream
+func (gc *GRPCClient) OpenShell(ctx context.Context) (pb.ConsoleService_OpenShellClient, error) {
+	return gc.client.OpenShell(ctx)
+}
+
+// PullFile starts or resumes pulling a file from a minion to Nexus, streaming its progress
+func (gc *GRPCClient) PullFile(ctx context.Context, req *pb.FilePullRequest) (pb.ConsoleService_PullFileClient, error) {
+	return gc.client.PullFile(ctx, req)
+}
+
+// ListFileTransfers lists the files pulled to Nexus
+func (gc *GRPCClient) ListFileTransfers(ctx context.Context) (*pb.FileTransferList, error) {
+	return gc.client.ListFileTransfers(ctx, &pb.Empty{})
+}
+
+// DownloadFile streams a file pulled to Nexus from offset
+func (gc *GRPCClient) DownloadFile(ctx context.Context, req *pb.FileDownloadRequest) (pb.ConsoleService_DownloadFileClient, error) {
+	return gc.client.DownloadFile(ctx, req)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// complianceExportUsage is the usage of the compliance-export command
+	complianceExportUsage = "usage: compliance-export [--since <duration|time>] [--until <duration|time>] [--out <file>]"
+	// complianceVerifyUsage is the usage of the compliance-verify command
+	complianceVerifyUsage = "usage: compliance-verify <file> [--ca <pem-bundle>]"
+)
+
+// receiptLine is a line of a compliance export, a receipt signed by Nexus
+type receiptLine struct {
+	Receipt           json.RawMessage `json:"receipt"`
+	Signature         []byte          `json:"signature"`          // base64 in JSON
+	SignerCertificate string          `json:"signer_certificate"` // PEM
+}
+
+// receiptChain holds the fields of a receipt chaining it to the other receipts of its export
+type receiptChain struct {
+	Sequence       int    `json:"sequence"`
+	Total          int    `json:"total"`
+	PreviousDigest string `json:"previous_digest"`
+	CommandID      string `json:"command_id"`
+}
+
+// parseComplianceExport parses compliance-export arguments, Nexus defaulting to the last 24 hours
+func parseComplianceExport(args []string, now time.Time) (*pb.ReceiptExportRequest, string, error) {
+	req := &pb.ReceiptExportRequest{}
+	out := ""
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return nil, "", fmt.Errorf(complianceExportUsage)
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--since", "--until":
+			t, err := parseStatsTime(value, now)
+			if err != nil {
+				return nil, "", err
+			}
+			if args[i] == "--since" {
+				req.Since = t.Unix()
+			} else {
+				req.Until = t.Unix()
+			}
+		case "--out":
+			out = value
+		default:
+			return nil, "", fmt.Errorf(complianceExportUsage)
+		}
+		i++
+	}
+	if out == "-" {
+		out = ""
+	}
+	return req, out, nil
+}
+
+// exportReceipts writes the signed execution receipts of the commands sent in a range as JSON lines
+func (c *Console) exportReceipts(ctx context.Context, args []string) {
+	req, out, err := parseComplianceExport(args, time.Now())
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	stream, err := c.grpc.ExportReceipts(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to export receipts", zap.Error(err))
+		c.printError(fmt.Sprintf("Error exporting receipts: %v", err))
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			c.printError(fmt.Sprintf("Error creating export file: %v", err))
+			return
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := writeReceipts(w, stream)
+	if err != nil {
+		c.logger.Error("Failed to export receipts", zap.Error(err))
+		if out != "" {
+			// A partial export would fail verification, it is not kept
+			os.Remove(out)
+		}
+		c.printError(fmt.Sprintf("Error exporting receipts: %v", err))
+		return
+	}
+
+	if out == "" {
+		return
+	}
+	if c.isJSONOutput() {
+		printJSON(ComplianceExportOutput{File: out, Count: count})
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Exported %d execution receipts to %s, check them with 'compliance-verify %s'", count, out, out))
+}
+
+// writeReceipts writes the receipts received on stream, one JSON line each, and returns their number
+func writeReceipts(w io.Writer, stream pb.ConsoleService_ExportReceiptsClient) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+	for {
+		receipt, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := encoder.Encode(receiptLine{
+			Receipt:           receipt.Receipt,
+			Signature:         receipt.Signature,
+			SignerCertificate: string(receipt.SignerCertificate),
+		}); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// verifyReceipts checks that the receipts of a compliance export were signed by a certificate chaining
+// to roots and that none was changed, removed, added or reordered. It returns the number of receipts and
+// the common name of their signer.
+func verifyReceipts(r io.Reader, roots *x509.CertPool) (int, string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	count, total, previous, signer := 0, 0, "", ""
+	for line := 1; scanner.Scan(); line++ {
+		var receipt receiptLine
+		if err := json.Unmarshal(scanner.Bytes(), &receipt); err != nil {
+			return count, signer, fmt.Errorf("line %d: invalid receipt: %w", line, err)
+		}
+		cert, err := certs.CheckDataSignature([]byte(receipt.SignerCertificate), receipt.Receipt, receipt.Signature)
+		if err != nil {
+			return count, signer, fmt.Errorf("line %d: %w", line, err)
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			return count, signer, fmt.Errorf("line %d: untrusted signer %q: %w", line, cert.Subject.CommonName, err)
+		}
+		if signer != "" && cert.Subject.CommonName != signer {
+			return count, signer, fmt.Errorf("line %d: signed by %q, previous receipts by %q", line, cert.Subject.CommonName, signer)
+		}
+		signer = cert.Subject.CommonName
+
+		var chain receiptChain
+		if err := json.Unmarshal(receipt.Receipt, &chain); err != nil {
+			return count, signer, fmt.Errorf("line %d: invalid receipt: %w", line, err)
+		}
+		if chain.Sequence != line || chain.PreviousDigest != previous {
+			return count, signer, fmt.Errorf("line %d: receipt of command %s is out of sequence, receipts were removed, added or reordered", line, chain.CommandID)
+		}
+		if total != 0 && chain.Total != total {
+			return count, signer, fmt.Errorf("line %d: receipt of command %s belongs to another export", line, chain.CommandID)
+		}
+		total = chain.Total
+		digest := sha256.Sum256(receipt.Receipt)
+		previous = hex.EncodeToString(digest[:])
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return count, signer, err
+	}
+	if count != total {
+		return count, signer, fmt.Errorf("export holds %d of its %d receipts, it was truncated", count, total)
+	}
+	return count, signer, nil
+}
+
+// verifyComplianceExport checks a compliance export, trusting the signers chaining to the CA bundle given
+// with --ca, the embedded CA otherwise. It reports whether the export is intact.
+func (c *Console) verifyComplianceExport(args []string) bool {
+	if len(args) != 1 && (len(args) != 3 || args[1] != "--ca") {
+		c.printError(complianceVerifyUsage)
+		return false
+	}
+
+	bundle := certs.CAPem
+	if len(args) == 3 {
+		var err error
+		if bundle, err = os.ReadFile(args[2]); err != nil {
+			c.printError(fmt.Sprintf("Error reading CA bundle: %v", err))
+			return false
+		}
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		c.printError("CA bundle contains no PEM certificate")
+		return false
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		c.printError(fmt.Sprintf("Error opening export: %v", err))
+		return false
+	}
+	defer file.Close()
+
+	count, signer, err := verifyReceipts(file, roots)
+	if c.isJSONOutput() {
+		output := ComplianceVerifyOutput{File: args[0], Valid: err == nil, Count: count, Signer: signer}
+		if err != nil {
+			output.Error = err.Error()
+		}
+		printJSON(output)
+		return err == nil
+	}
+	if err != nil {
+		c.printError(fmt.Sprintf("Export %s failed verification after %d receipts: %v", args[0], count, err))
+		return false
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Export %s is intact: %d execution receipts signed by %s", args[0], count, signer))
+	return true
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// Connection states shown in the prompt
+const (
+	ConnectionConnected    = "connected"
+	ConnectionReconnecting = "reconnecting"
+)
+
+const (
+	// maxReconnectAttempts is the number of times a request is sent while Nexus is unreachable
+	maxReconnectAttempts = 5
+	// reconnectBackoffMin is the delay before the first retry, doubled at each attempt
+	reconnectBackoffMin = 500 * time.Millisecond
+	// reconnectBackoffMax bounds the delay between two retries
+	reconnectBackoffMax = 4 * time.Second
+)
+
+// retriedMethods are the requests retried while Nexus is unreachable: reads only, since a request
+// failing with Unavailable may still have been handled by Nexus, and resending a command, a tag
+// change or a decision would apply it twice
+var retriedMethods = map[string]bool{
+	pb.ConsoleService_ListMinions_FullMethodName:            true,
+	pb.ConsoleService_ListTags_FullMethodName:               true,
+	pb.ConsoleService_GetTagSchema_FullMethodName:           true,
+	pb.ConsoleService_ExplainTargets_FullMethodName:         true,
+	pb.ConsoleService_GetCommandResults_FullMethodName:      true,
+	pb.ConsoleService_GetCommandStatus_FullMethodName:       true,
+	pb.ConsoleService_ListCommandApprovals_FullMethodName:   true,
+	pb.ConsoleService_ListMaintenanceWindows_FullMethodName: true,
+	pb.ConsoleService_ListCommandEnv_FullMethodName:         true,
+	pb.ConsoleService_GetMinionDiagnostics_FullMethodName:   true,
+	pb.ConsoleService_GetFleetHealth_FullMethodName:         true,
+	pb.ConsoleService_GetFleetVersions_FullMethodName:       true,
+	pb.ConsoleService_GetPatchCompliance_FullMethodName:     true,
+	pb.ConsoleService_GetMinionHistory_FullMethodName:       true,
+	pb.ConsoleService_GetMinionUptime_FullMethodName:        true,
+	pb.ConsoleService_GetMinionLogs_FullMethodName:          true,
+	pb.ConsoleService_ListCrashes_FullMethodName:            true,
+	pb.ConsoleService_GetTrace_FullMethodName:               true,
+	pb.ConsoleService_GetCommandStats_FullMethodName:        true,
+	pb.ConsoleService_ListReports_FullMethodName:            true,
+	pb.ConsoleService_CheckDatabase_FullMethodName:          true,
+	pb.ConsoleService_ListDatabaseQueries_FullMethodName:    true,
+	pb.ConsoleService_ListFileTransfers_FullMethodName:      true,
+	pb.AdminService_DumpRegistry_FullMethodName:             true,
+}
+
+// connectionState returns the state shown in the prompt for a channel state: an idle channel
+// connects on the next request, a shut down one is not watched anymore
+func connectionState(state connectivity.State) string {
+	switch state {
+	case connectivity.Connecting, connectivity.TransientFailure:
+		return ConnectionReconnecting
+	default:
+		return ConnectionConnected
+	}
+}
+
+// reconnectBackoff returns the delay before the retry following attempt
+func reconnectBackoff(attempt int) time.Duration {
+	delay := reconnectBackoffMin << (attempt - 1)
+	if delay <= 0 || delay > reconnectBackoffMax {
+		return reconnectBackoffMax
+	}
+	return delay
+}
+
+// State returns the state of the channel to Nexus, ConnectionConnected or ConnectionReconnecting
+func (gc *GRPCClient) State() string {
+	return connectionState(gc.conn.GetState())
+}
+
+// WatchState calls notify with the state of the channel to Nexus, then with each change of state,
+// until ctx is done or the client is closed
+func (gc *GRPCClient) WatchState(ctx context.Context, notify func(state string)) {
+	go func() {
+		for {
+			state := gc.conn.GetState()
+			if state == connectivity.Shutdown {
+				return
+			}
+			notify(connectionState(state))
+			if !gc.conn.WaitForStateChange(ctx, state) {
+				return
+			}
+		}
+	}()
+}
+
+// OnRetry sets the function told about the requests retried while Nexus is unreachable
+func (gc *GRPCClient) OnRetry(notify func(attempt int, delay time.Duration, err error)) {
+	gc.onRetry = notify
+}
+
+// transientFailure reports whether err is the failure of a request that didn't reach Nexus: Nexus
+// also answers Unavailable for features it doesn't provide, these errors come over a ready channel
+func (gc *GRPCClient) transientFailure(err error) bool {
+	return status.Code(err) == codes.Unavailable && gc.conn.GetState() != connectivity.Ready
+}
+
+// retryUnary retries the read requests failing while Nexus is unreachable with an exponential backoff,
+// so that a restart of Nexus or a network glitch doesn't fail the command. Other requests fail at once.
+func (gc *GRPCClient) retryUnary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !retriedMethods[method] {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	for attempt := 1; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || attempt >= maxReconnectAttempts || !gc.transientFailure(err) {
+			return err
+		}
+
+		delay := reconnectBackoff(attempt)
+		gc.logger.Debug("Nexus unreachable, retrying request",
+			zap.String("method", method),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+		if gc.onRetry != nil {
+			gc.onRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		// Reconnect right away rather than after the channel's own backoff
+		cc.ResetConnectBackoff()
+	}
+}
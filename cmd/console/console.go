@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/arhuman/minexus/internal/certs"
 	"github.com/arhuman/minexus/internal/command"
 	"github.com/arhuman/minexus/internal/config"
 	"github.com/arhuman/minexus/internal/logging"
@@ -33,22 +36,84 @@ type Console struct {
 	parser        *CommandParser
 	logger        *zap.Logger
 	commandStatus map[string]*CommandStatus // command_id -> status
+	outputFormat  string                    // "text" or "json"
+	aliases       *AliasStore               // user-defined command aliases
+	display       *DisplayStore             // timestamp format and table columns set with set
+	local         *localExecutor            // set in local mode, commands run on this machine
+	signer        *certs.CommandSigner      // set when commands are signed for the minions to verify
+	config        *config.ConsoleConfig     // connection settings, switched by connect
+	target        []string                  // working target selected with use, the target of run
 }
 
 // NewConsole creates a new console instance
 func NewConsole(grpcClient *GRPCClient, logger *zap.Logger) *Console {
+	console := newConsole(logger)
+	console.client = grpcClient.client
+	console.grpc = grpcClient
+	return console
+}
+
+// newConsole creates a console without Nexus connection
+func newConsole(logger *zap.Logger) *Console {
 	registry := command.SetupCommands(15 * time.Second) // Default 15s timeout for console commands
 
-	console := &Console{
-		client:        grpcClient.client,
-		grpc:          grpcClient,
+	return &Console{
 		ui:            NewUIManager(logger, registry),
 		parser:        NewCommandParser(registry),
 		logger:        logger,
 		commandStatus: make(map[string]*CommandStatus),
+		outputFormat:  OutputFormatText,
 	}
+}
 
-	return console
+// SetOutputFormat sets the output format used by data commands
+func (c *Console) SetOutputFormat(format string) error {
+	format = strings.ToLower(format)
+	if !IsValidOutputFormat(format) {
+		return fmt.Errorf("invalid output format '%s'. Use 'text' or 'json'", format)
+	}
+	c.outputFormat = format
+	return nil
+}
+
+// SetSigner makes the console sign the commands it sends with signer
+func (c *Console) SetSigner(signer *certs.CommandSigner) {
+	c.signer = signer
+}
+
+// loadSigner creates the command signer from the configured certificate and key,
+// defaulting to the console client credentials
+func loadSigner(cfg *config.ConsoleConfig) (*certs.CommandSigner, error) {
+	if cfg.SigningCert == "" {
+		certPEM, keyPEM, _, err := loadCredentials(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return certs.NewCommandSigner(certPEM, keyPEM)
+	}
+	certPEM, err := os.ReadFile(cfg.SigningCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(cfg.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	return certs.NewCommandSigner(certPEM, keyPEM)
+}
+
+// isJSONOutput reports whether data commands should emit JSON
+func (c *Console) isJSONOutput() bool {
+	return c.outputFormat == OutputFormatJSON
+}
+
+// printError prints an error in the current output format
+func (c *Console) printError(msg string) {
+	if c.isJSONOutput() {
+		printJSON(ErrorOutput{Error: msg})
+		return
+	}
+	c.ui.PrintError(msg)
 }
 
 // Shutdown gracefully closes the console components
@@ -63,6 +128,12 @@ func (c *Console) Start() {
 	defer c.ui.Shutdown()
 
 	c.ui.ShowWelcome()
+	if c.local != nil {
+		c.ui.PrintInfo("Local mode: commands run on this machine, no Nexus connection")
+	}
+	if c.grpc != nil {
+		c.watchConnection(c.grpc)
+	}
 
 	for {
 		line, err := c.ui.ReadLine()
@@ -84,6 +155,7 @@ func (c *Console) Start() {
 		if line == "" {
 			continue
 		}
+		line = c.expandAlias(line)
 
 		// Parse command and arguments with proper shell-style quoting support
 		parts, err := util.ParseCommandLine(line)
@@ -109,10 +181,30 @@ func (c *Console) Start() {
 	}
 }
 
+// watchConnection shows the state of the connection to Nexus in the prompt and tells the user
+// about the requests retried while reconnecting
+func (c *Console) watchConnection(grpcClient *GRPCClient) {
+	grpcClient.OnRetry(func(attempt int, delay time.Duration, err error) {
+		if !c.isJSONOutput() {
+			c.ui.PrintWarning(fmt.Sprintf("Nexus unreachable, retrying in %s (%d/%d)", delay, attempt, maxReconnectAttempts-1))
+		}
+	})
+	grpcClient.WatchState(context.Background(), func(state string) {
+		if state == ConnectionReconnecting {
+			c.logger.Debug("Connection to Nexus lost, reconnecting")
+		}
+		c.ui.SetConnectionState(state)
+	})
+}
+
 // handleCommand processes a single command
 func (c *Console) handleCommand(command string, args []string) {
 	ctx := context.Background()
 
+	if c.local != nil && c.handleLocalCommand(command, args) {
+		return
+	}
+
 	switch command {
 	case "help", "h":
 		c.ui.ShowHelp(args)
@@ -129,18 +221,176 @@ func (c *Console) handleCommand(command string, args []string) {
 	case "tag-list", "lt":
 		c.listTags(ctx)
 
+	case "minion-inspect":
+		c.inspectMinion(ctx, args)
+
+	case "minion-history":
+		c.showMinionHistory(ctx, args)
+	case "minion-uptime":
+		c.showMinionUptime(ctx, args)
+
+	case "minion-logs":
+		c.showMinionLogs(ctx, args)
+
+	case "minion-retire":
+		c.retireMinion(ctx, args)
+
+	case "crash-list":
+		c.listCrashes(ctx, args)
+
+	case "trace-get":
+		c.showTrace(ctx, args)
+
+	case "stats":
+		c.showCommandStats(ctx, args)
+
+	case "fleet-health":
+		c.showFleetHealth(ctx, args)
+
+	case "fleet-versions":
+		c.showFleetVersions(ctx, args)
+
+	case "fleet-patches":
+		c.showFleetPatches(ctx, args)
+
+	case "compliance-export":
+		c.exportReceipts(ctx, args)
+
+	case "compliance-verify":
+		c.verifyComplianceExport(args)
+
+	case "minion-bootstrap-url":
+		c.createBootstrapURL(ctx, args)
+
+	case "use":
+		c.selectTarget(args)
+
+	case "run":
+		c.runOnTarget(args)
+
+	case "targets":
+		c.showTarget(ctx)
+
+	case "pick":
+		c.pickMinions(ctx, args)
+
 	case "command-send", "cmd":
 		c.sendCommand(ctx, args)
 
+	case "edit":
+		c.editAndSendCommand(args)
+
+	case "target-explain":
+		c.explainTargets(ctx, args)
+
+	case "validate":
+		c.validateCommand(args)
+
+	case "command-approvals":
+		c.listCommandApprovals(ctx)
+
+	case "command-approve":
+		c.decideCommandApproval(ctx, args, false)
+
+	case "command-reject":
+		c.decideCommandApproval(ctx, args, true)
+
+	case "file-pull":
+		c.pullFile(ctx, args)
+
+	case "file-download":
+		c.downloadFile(ctx, args)
+
+	case "file-transfers":
+		c.listFileTransfers(ctx)
+
+	case "shell":
+		c.openShell(ctx, args)
+
 	case "result-get", "results":
 		c.getResults(ctx, args)
 
+	case "result-view":
+		c.viewResults(ctx, args)
+
+	case "result-export":
+		c.exportResults(ctx, args)
+
+	case "tag-schema-show":
+		c.showTagSchema(ctx)
+
 	case "tag-set":
 		c.setTags(ctx, args)
 
 	case "tag-update":
 		c.updateTags(ctx, args)
 
+	case "maintenance-add":
+		c.addMaintenanceWindow(ctx, args)
+
+	case "maintenance-list":
+		c.listMaintenanceWindows(ctx)
+
+	case "maintenance-remove":
+		c.removeMaintenanceWindow(ctx, args)
+
+	case "env-set":
+		c.setCommandEnv(ctx, args)
+
+	case "env-unset":
+		c.unsetCommandEnv(ctx, args)
+
+	case "env-list":
+		c.listCommandEnv(ctx)
+
+	case "report-create":
+		c.createReport(ctx, args)
+
+	case "report-list":
+		c.listReports(ctx)
+
+	case "report-run":
+		c.runReport(ctx, args)
+
+	case "db-check":
+		c.checkDatabase(ctx, args)
+
+	case "db-query":
+		c.databaseQuery(ctx, args)
+
+	case "admin-flush-caches":
+		c.flushCaches(ctx)
+
+	case "admin-log-level":
+		c.setLogLevel(ctx, args)
+
+	case "admin-registry":
+		c.dumpRegistry(ctx)
+
+	case "admin-disconnect":
+		c.disconnectMinion(ctx, args)
+
+	case "admin-unbind":
+		c.unbindMinion(ctx, args)
+
+	case "admin-prune":
+		c.pruneDatabase(ctx)
+
+	case "connect":
+		c.connect(args)
+
+	case "alias":
+		c.defineAlias(args)
+
+	case "alias-list":
+		c.listAliases()
+
+	case "alias-remove":
+		c.removeAlias(args)
+
+	case "set":
+		c.setOption(args)
+
 	case "clear":
 		c.ui.ClearScreen()
 
@@ -158,11 +408,16 @@ func (c *Console) listMinions(ctx context.Context) {
 	response, err := c.grpc.ListMinions(ctx)
 	if err != nil {
 		c.logger.Error("Failed to list minions from nexus server", zap.Error(err))
-		c.ui.PrintError(fmt.Sprintf("Error listing minions: %v", err))
+		c.printError(fmt.Sprintf("Error listing minions: %v", err))
 		return
 	}
 	c.logger.Debug("Successfully received minion list", zap.Int("count", len(response.Minions)))
 
+	if c.isJSONOutput() {
+		printJSON(newMinionListOutput(response.Minions))
+		return
+	}
+
 	if len(response.Minions) == 0 {
 		c.logger.Info("No minions are currently connected to nexus server")
 		c.ui.PrintInfo("No minions connected - Commands will not execute until minions connect")
@@ -170,22 +425,36 @@ func (c *Console) listMinions(ctx context.Context) {
 	}
 
 	fmt.Printf("Connected minions (%d):\n", len(response.Minions))
-	fmt.Println("ID                                   | Hostname          | IP             | OS       | Last Seen        | Tags")
-	fmt.Println("------------------------------------ | ----------------- | -------------- | -------- | ---------------- | ----")
-
+	columns := c.display.Columns("minion-list")
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = minionColumnDefs[column].header
+	}
+	rows := make([][]string, 0, len(response.Minions))
 	for _, minion := range response.Minions {
-		tags := util.FormatTags(minion.Tags)
-		lastSeen := util.FormatLastSeen(minion.LastSeen)
-		fmt.Printf("%-36s | %-17s | %-14s | %-8s | %-16s | %s\n",
-			minion.Id, minion.Hostname, minion.Ip, minion.Os, lastSeen, tags)
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = minionColumnDefs[column].value(c, minion)
+		}
+		rows = append(rows, row)
 	}
+	printTable(headers, rows)
 }
 
 // listTags lists all available tags
 func (c *Console) listTags(ctx context.Context) {
 	response, err := c.grpc.ListTags(ctx)
 	if err != nil {
-		c.ui.PrintError(fmt.Sprintf("Error listing tags: %v", err))
+		c.printError(fmt.Sprintf("Error listing tags: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		tags := response.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		printJSON(TagListOutput{Count: len(tags), Tags: tags})
 		return
 	}
 
@@ -200,6 +469,75 @@ func (c *Console) listTags(ctx context.Context) {
 	}
 }
 
+// showTagSchema shows the tag schema the tags set are validated against
+func (c *Console) showTagSchema(ctx context.Context) {
+	schema, err := c.grpc.GetTagSchema(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error getting tag schema: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := TagSchemaOutput{Enabled: schema.Enabled, AllowOtherKeys: schema.AllowOtherKeys, Keys: []TagSchemaKeyOutput{}}
+		for _, key := range schema.Keys {
+			output.Keys = append(output.Keys, TagSchemaKeyOutput{
+				Key:           key.Key,
+				Description:   key.Description,
+				AllowedValues: key.AllowedValues,
+				Pattern:       key.Pattern,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	if !schema.Enabled {
+		c.ui.PrintInfo("No tag schema configured, any tag is accepted")
+		return
+	}
+
+	fmt.Printf("Tag schema (%d keys):\n", len(schema.Keys))
+	for _, key := range schema.Keys {
+		values := "any value"
+		switch {
+		case len(key.AllowedValues) > 0 && key.Pattern != "":
+			values = fmt.Sprintf("one of %s, matching %s", strings.Join(key.AllowedValues, ", "), key.Pattern)
+		case len(key.AllowedValues) > 0:
+			values = "one of " + strings.Join(key.AllowedValues, ", ")
+		case key.Pattern != "":
+			values = "matching " + key.Pattern
+		}
+		fmt.Printf("  %-20s %s\n", key.Key, values)
+		if key.Description != "" {
+			fmt.Printf("  %-20s %s\n", "", key.Description)
+		}
+	}
+	if schema.AllowOtherKeys {
+		fmt.Println("Other keys are accepted with any value")
+	} else {
+		fmt.Println("Other keys are rejected")
+	}
+}
+
+// signedTargets returns the minions a command is signed for: the minion IDs of the request, or the
+// minions Nexus currently resolves its tag selector and failure domains to
+func (c *Console) signedTargets(ctx context.Context, req *pb.CommandRequest) ([]string, error) {
+	if len(req.MinionIds) > 0 {
+		return req.MinionIds, nil
+	}
+	explanations, err := c.grpc.ExplainTargets(ctx, &pb.ExplainTargetsRequest{TagSelector: req.TagSelector, Topology: req.Topology})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the targets to sign the command for: %v", err)
+	}
+	var targets []string
+	for _, minion := range explanations.Minions {
+		if minion.Matched {
+			targets = append(targets, minion.MinionId)
+		}
+	}
+	return targets, nil
+}
+
 // sendCommand sends a command to minions using the CommandParser
 func (c *Console) sendCommand(ctx context.Context, args []string) {
 	if len(args) == 0 {
@@ -213,10 +551,26 @@ func (c *Console) sendCommand(ctx context.Context, args []string) {
 	parsed, err := c.parser.ParseCommand(args)
 	if err != nil {
 		c.logger.Error("Failed to parse command", zap.Strings("args", args), zap.Error(err))
-		c.ui.PrintError(err.Error())
+		c.printError(err.Error())
+		return
+	}
+	if parsed.Validate {
+		c.showValidation(parsed, c.parser.CheckArguments(parsed))
 		return
 	}
 
+	if c.signer != nil {
+		targets, err := c.signedTargets(ctx, parsed.Request)
+		if err != nil {
+			c.printError(err.Error())
+			return
+		}
+		if err := c.signer.Sign(parsed.Request.Command, targets); err != nil {
+			c.printError(err.Error())
+			return
+		}
+	}
+
 	c.logger.Debug("Command parsed successfully",
 		zap.String("command_payload", parsed.Request.Command.Payload),
 		zap.String("command_id", parsed.Request.Command.Id),
@@ -229,7 +583,7 @@ func (c *Console) sendCommand(ctx context.Context, args []string) {
 		c.logger.Error("Failed to send command to nexus server",
 			zap.String("command_payload", parsed.Request.Command.Payload),
 			zap.Error(err))
-		c.ui.PrintError(fmt.Sprintf("Error sending command: %v", err))
+		c.printError(fmt.Sprintf("Error sending command: %v", err))
 		return
 	}
 
@@ -237,6 +591,19 @@ func (c *Console) sendCommand(ctx context.Context, args []string) {
 		zap.String("command_id", response.CommandId),
 		zap.Bool("accepted", response.Accepted))
 
+	// Destructive commands need a second, confirmed, request
+	if response.ConfirmationRequired && !parsed.DryRun && !c.isJSONOutput() {
+		var confirmed bool
+		if response, confirmed = c.confirmDestructiveCommand(ctx, args, parsed, response); !confirmed {
+			return
+		}
+	}
+
+	if parsed.DryRun {
+		c.showDryRun(parsed, response)
+		return
+	}
+
 	if response.Accepted {
 		// Initialize command status tracking
 		status := &CommandStatus{
@@ -246,7 +613,11 @@ func (c *Console) sendCommand(ctx context.Context, args []string) {
 		}
 
 		// Set initial status for targeted minions
-		if len(parsed.Request.MinionIds) > 0 {
+		if len(response.TargetMinionIds) > 0 {
+			for _, minionID := range response.TargetMinionIds {
+				status.Statuses[minionID] = "PENDING"
+			}
+		} else if len(parsed.Request.MinionIds) > 0 {
 			for _, minionID := range parsed.Request.MinionIds {
 				status.Statuses[minionID] = "PENDING"
 			}
@@ -262,8 +633,6 @@ func (c *Console) sendCommand(ctx context.Context, args []string) {
 
 		c.commandStatus[response.CommandId] = status
 
-		fmt.Printf("Command dispatched successfully. Command ID: %s\n", response.CommandId)
-
 		// Check if command result are available immediately **in database**
 		// if yes returns them immediately
 		// (with a header saying that further results will be available later through result-get)
@@ -271,35 +640,219 @@ func (c *Console) sendCommand(ctx context.Context, args []string) {
 			CommandId: response.CommandId,
 		}
 		resultsResponse, err := c.grpc.GetCommandResults(ctx, resultsReq)
+
+		if c.isJSONOutput() {
+			var results []*pb.CommandResult
+			if err == nil {
+				results = resultsResponse.Results
+			}
+			c.printCommandSendJSON(parsed, response, status, results)
+			c.ui.AddToHistory(fmt.Sprintf("result-get %s", response.CommandId))
+			return
+		}
+
+		fmt.Printf("Command dispatched successfully. Command ID: %s\n", response.CommandId)
+		if response.Impact != "" {
+			fmt.Printf("Impact: %s\n", response.Impact)
+		}
+		if response.TraceId != "" {
+			fmt.Printf("Trace ID: %s (see trace-get)\n", response.TraceId)
+		}
+		if parsed.Retry != nil && parsed.Retry.MaxAttempts > 1 {
+			fmt.Printf("Retry: up to %d attempts per minion, on %s\n", parsed.Retry.MaxAttempts, describeRetryExitCodes(parsed.Retry))
+		}
+		if parsed.Sample > 0 {
+			fmt.Printf("Sample: full output stored for %d successful minions, all failures stored\n", parsed.Sample)
+		}
+		if len(response.HeldMinionIds) > 0 {
+			c.ui.PrintInfo(fmt.Sprintf("Held until their maintenance window opens (%d): %s",
+				len(response.HeldMinionIds), strings.Join(response.HeldMinionIds, ", ")))
+		}
+		if len(response.LockWaitingMinionIds) > 0 {
+			c.ui.PrintInfo(fmt.Sprintf("Waiting for lock %s (%d): %s",
+				parsed.Lock, len(response.LockWaitingMinionIds), strings.Join(response.LockWaitingMinionIds, ", ")))
+		}
+		if len(response.StaggeredMinionIds) > 0 {
+			c.ui.PrintInfo(fmt.Sprintf("Staggered rollout, %d minion(s) reached within %ds: %s",
+				len(response.StaggeredMinionIds), response.RolloutSeconds, strings.Join(response.StaggeredMinionIds, ", ")))
+		}
+		c.warnSkippedMinions(response)
+
+		// Commands sent to several minions are followed until they all finished
+		resultCmd := fmt.Sprintf("result-get %s", response.CommandId)
+		waiting := append(append([]string{}, response.HeldMinionIds...), response.LockWaitingMinionIds...)
+		if !parsed.NoWait && len(status.Statuses) > 1 && c.followProgress(ctx, status, waiting) {
+			c.ui.AddToHistory(resultCmd)
+			return
+		}
+
 		if err == nil && len(resultsResponse.Results) > 0 {
 			fmt.Printf("Immediate results (%d):\n", len(resultsResponse.Results))
-			fmt.Println("Minion ID                            | Exit Code | Output")
-			fmt.Println("------------------------------------ | --------- | ------")
-			for _, result := range resultsResponse.Results {
-				timestamp := time.Unix(result.Timestamp, 0).Format("15:04:05")
-				output := strings.ReplaceAll(result.Stdout, "\n", "\\n")
-				if len(output) > 50 {
-					output = output[:47] + "..."
-				}
-				fmt.Printf("%-36s | %-9d | %s [%s]\n",
-					result.MinionId, result.ExitCode, output, timestamp)
-				if result.Stderr != "" {
-					stderr := strings.ReplaceAll(result.Stderr, "\n", "\\n")
-					if len(stderr) > 50 {
-						stderr = stderr[:47] + "..."
-					}
-					fmt.Printf("%-36s | %-9s | STDERR: %s\n", "", "", stderr)
-				}
-			}
+			c.printResultTable(resultsResponse.Results)
 		} else {
 			c.ui.PrintInfo("No immediate results available, check later with 'result-get " + response.CommandId + "'")
 		}
 		// Add command to history
-		resultCmd := fmt.Sprintf("result-get %s", response.CommandId)
 		c.ui.AddToHistory(resultCmd)
+	} else if c.isJSONOutput() {
+		c.printCommandSendJSON(parsed, response, nil, nil)
+	} else if response.ApprovalRequired {
+		c.ui.PrintWarning(fmt.Sprintf("Command %s requires approval (%s) before reaching %d minion(s)",
+			response.CommandId, response.ApprovalReason, len(response.TargetMinionIds)))
+		c.ui.PrintInfo(fmt.Sprintf("Another operator must approve it with 'command-approve %s'", response.CommandId))
+		c.warnSkippedMinions(response)
 	} else {
 		c.ui.PrintInfo("Command was not accepted")
+		c.warnSkippedMinions(response)
+	}
+}
+
+// confirmDestructiveCommand asks the user to confirm a destructive command and, if confirmed,
+// resends it with its confirm token. It returns the response to the confirmed request.
+func (c *Console) confirmDestructiveCommand(ctx context.Context, args []string, parsed *ParsedCommand, response *pb.CommandDispatchResponse) (*pb.CommandDispatchResponse, bool) {
+	c.ui.PrintWarning(fmt.Sprintf("Destructive command (%s) targeting %d minion(s): %s",
+		response.DestructiveReason, len(response.TargetMinionIds), strings.Join(response.TargetMinionIds, ", ")))
+
+	if !c.ui.Confirm("Dispatch it?") {
+		c.ui.PrintInfo("Command not sent. To confirm it within 5 minutes, run:")
+		c.ui.PrintInfo(fmt.Sprintf("  command-send --confirm %s %s", response.ConfirmToken, strings.Join(quoteArgs(args), " ")))
+		return response, false
 	}
+
+	parsed.Request.ConfirmToken = response.ConfirmToken
+	confirmed, err := c.grpc.SendCommand(ctx, parsed.Request)
+	if err != nil {
+		c.logger.Error("Failed to send confirmed command to nexus server", zap.Error(err))
+		c.printError(fmt.Sprintf("Error sending command: %v", err))
+		return response, false
+	}
+	return confirmed, true
+}
+
+// quoteArgs quotes the arguments containing spaces so they can be typed again
+func quoteArgs(args []string) []string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return quoted
+}
+
+// warnSkippedMinions warns about the targeted minions unable to execute the command, and the
+// ones which may not support its options
+func (c *Console) warnSkippedMinions(response *pb.CommandDispatchResponse) {
+	if len(response.SkippedMinionIds) > 0 {
+		c.ui.PrintWarning(fmt.Sprintf("Skipped, lacking the capability or command version the command requires (%d): %s",
+			len(response.SkippedMinionIds), strings.Join(response.SkippedMinionIds, ", ")))
+	}
+	if len(response.UnversionedMinionIds) > 0 {
+		c.ui.PrintWarning(fmt.Sprintf("May not support options requiring %s, predating command versioning (%d): %s",
+			response.RequiredVersion, len(response.UnversionedMinionIds), strings.Join(response.UnversionedMinionIds, ", ")))
+	}
+	if response.EnvUnavailable {
+		c.ui.PrintWarning("The command environment couldn't be loaded, the command is sent without it")
+	}
+	if len(response.EnvUnsupportedMinionIds) > 0 {
+		c.ui.PrintWarning(fmt.Sprintf("Running the command without their environment, predating it (%d): %s",
+			len(response.EnvUnsupportedMinionIds), strings.Join(response.EnvUnsupportedMinionIds, ", ")))
+	}
+}
+
+// showDryRun displays the minions a command would have been sent to
+func (c *Console) showDryRun(parsed *ParsedCommand, response *pb.CommandDispatchResponse) {
+	if c.isJSONOutput() {
+		targets := response.TargetMinionIds
+		if targets == nil {
+			targets = []string{}
+		}
+		printJSON(CommandSendOutput{
+			Accepted:    response.Accepted,
+			DryRun:      true,
+			Payload:     parsed.CommandText,
+			Impact:      response.Impact,
+			Targets:     targets,
+			Held:        response.HeldMinionIds,
+			Skipped:     response.SkippedMinionIds,
+			Unversioned: response.UnversionedMinionIds,
+			Results:     []ResultOutput{},
+
+			ConfirmationRequired: response.ConfirmationRequired,
+			ConfirmToken:         response.ConfirmToken,
+			DestructiveReason:    response.DestructiveReason,
+			ApprovalRequired:     response.ApprovalRequired,
+			ApprovalReason:       response.ApprovalReason,
+		})
+		return
+	}
+
+	if !response.Accepted || len(response.TargetMinionIds) == 0 {
+		c.ui.PrintInfo("Dry run: no minion matches the target, command would not be sent")
+		c.warnSkippedMinions(response)
+		return
+	}
+
+	fmt.Printf("Dry run: '%s' (%s) would be sent to %d minion(s):\n", parsed.CommandText, formatImpact(response.Impact), len(response.TargetMinionIds))
+	held := make(map[string]bool, len(response.HeldMinionIds))
+	for _, minionID := range response.HeldMinionIds {
+		held[minionID] = true
+	}
+	for _, minionID := range response.TargetMinionIds {
+		if held[minionID] {
+			fmt.Printf("  %s (held until maintenance window opens)\n", minionID)
+		} else {
+			fmt.Printf("  %s\n", minionID)
+		}
+	}
+	c.warnSkippedMinions(response)
+	if response.ConfirmationRequired {
+		c.ui.PrintWarning(fmt.Sprintf("Destructive command (%s): confirm it within 5 minutes with --confirm %s",
+			response.DestructiveReason, response.ConfirmToken))
+	}
+	if response.ApprovalRequired {
+		c.ui.PrintWarning(fmt.Sprintf("Approval required (%s): another operator must approve the command before its dispatch",
+			response.ApprovalReason))
+	}
+}
+
+// printCommandSendJSON emits the outcome of command-send as JSON
+func (c *Console) printCommandSendJSON(parsed *ParsedCommand, response *pb.CommandDispatchResponse, status *CommandStatus, results []*pb.CommandResult) {
+	targets := []string{}
+	if len(response.TargetMinionIds) > 0 {
+		targets = append(targets, response.TargetMinionIds...)
+	} else if status != nil {
+		for minionID := range status.Statuses {
+			targets = append(targets, minionID)
+		}
+		sort.Strings(targets)
+	}
+
+	printJSON(CommandSendOutput{
+		Accepted:    response.Accepted,
+		CommandID:   response.CommandId,
+		TraceID:     response.TraceId,
+		Payload:     parsed.CommandText,
+		Impact:      response.Impact,
+		Targets:     targets,
+		Held:        response.HeldMinionIds,
+		Skipped:     response.SkippedMinionIds,
+		Unversioned: response.UnversionedMinionIds,
+		Results:     newResultOutputs(results),
+
+		EnvUnavailable: response.EnvUnavailable,
+		EnvUnsupported: response.EnvUnsupportedMinionIds,
+
+		LockWaiting: response.LockWaitingMinionIds,
+		Staggered:   response.StaggeredMinionIds,
+
+		ConfirmationRequired: response.ConfirmationRequired,
+		ConfirmToken:         response.ConfirmToken,
+		DestructiveReason:    response.DestructiveReason,
+		ApprovalRequired:     response.ApprovalRequired,
+		ApprovalReason:       response.ApprovalReason,
+	})
 }
 
 // getResults gets command execution results
@@ -321,7 +874,7 @@ func (c *Console) getResults(ctx context.Context, args []string) {
 		c.logger.Error("Failed to get command results from nexus server",
 			zap.String("command_id", commandID),
 			zap.Error(err))
-		c.ui.PrintError(fmt.Sprintf("Error getting results: %v", err))
+		c.printError(fmt.Sprintf("Error getting results: %v", err))
 		return
 	}
 
@@ -329,7 +882,19 @@ func (c *Console) getResults(ctx context.Context, args []string) {
 		zap.String("command_id", commandID),
 		zap.Int("result_count", len(response.Results)))
 
-	if len(response.Results) == 0 {
+	c.updateStatusFromResults(commandID, response.Results)
+
+	if c.isJSONOutput() {
+		printJSON(ResultListOutput{
+			CommandID: commandID,
+			Count:     len(response.Results),
+			Results:   newResultOutputs(response.Results),
+			Omitted:   response.OmittedResults,
+		})
+		return
+	}
+
+	if len(response.Results) == 0 && response.OmittedResults == 0 {
 		c.logger.Info("No results available yet for command", zap.String("command_id", commandID))
 
 		// Check if we have any minions connected to help diagnose the issue
@@ -348,39 +913,138 @@ func (c *Console) getResults(ctx context.Context, args []string) {
 		return
 	}
 
-	// Update command status for received results
-	if status, ok := c.commandStatus[commandID]; ok {
-		for _, result := range response.Results {
-			if result.ExitCode == 0 {
-				status.Statuses[result.MinionId] = "COMPLETED"
-			} else {
-				status.Statuses[result.MinionId] = "FAILED"
+	fmt.Printf("Command results (%d):\n", len(response.Results))
+	c.printResultTable(response.Results)
+	if response.OmittedResults > 0 {
+		c.ui.PrintInfo(fmt.Sprintf("%d more minions succeeded, their output was not stored (result sampling)", response.OmittedResults))
+	}
+	if response.HasMore || outputTruncated(response.Results) {
+		c.ui.PrintInfo(fmt.Sprintf("Use 'result-view %s' to browse the full output", commandID))
+	}
+}
+
+// outputTruncated reports whether printResultTable cut the output of a result
+func outputTruncated(results []*pb.CommandResult) bool {
+	for _, result := range results {
+		for _, output := range []string{result.Stdout, result.Stderr} {
+			if len(output) > 50 || strings.Contains(output, "\n") {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	fmt.Printf("Command results (%d):\n", len(response.Results))
-	fmt.Println("Minion ID                            | Exit Code | Output")
-	fmt.Println("------------------------------------ | --------- | ------")
+// printResultTable prints results as a table of the columns set for result-get, with truncated output,
+// followed by their structured payloads and how the failed commands ran. Standard errors are shown
+// below the output column.
+func (c *Console) printResultTable(results []*pb.CommandResult) {
+	columns := c.display.Columns("result-get")
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = resultColumnDefs[column].header
+	}
+
+	var rows [][]string
+	for _, result := range results {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = resultColumnDefs[column].value(c, result)
+		}
+		rows = append(rows, row)
 
-	for _, result := range response.Results {
-		timestamp := time.Unix(result.Timestamp, 0).Format("15:04:05")
-		output := strings.ReplaceAll(result.Stdout, "\n", "\\n")
-		if len(output) > 50 {
-			output = output[:47] + "..."
+		if result.Stderr != "" && containsColumn(columns, "output") {
+			stderrRow := make([]string, len(columns))
+			for i, column := range columns {
+				if column == "output" {
+					stderrRow[i] = "STDERR: " + truncateResultOutput(result.Stderr)
+				}
+			}
+			rows = append(rows, stderrRow)
 		}
+	}
+	printTable(headers, rows)
+	printStructuredResults(results)
+	printFailedExecutions(results)
+}
 
-		fmt.Printf("%-36s | %-9d | %s [%s]\n",
-			result.MinionId, result.ExitCode, output, timestamp)
+// updateStatusFromResults updates the tracked command status for received results
+func (c *Console) updateStatusFromResults(commandID string, results []*pb.CommandResult) {
+	status, ok := c.commandStatus[commandID]
+	if !ok {
+		return
+	}
+	for _, result := range results {
+		if result.ExitCode == 0 {
+			status.Statuses[result.MinionId] = "COMPLETED"
+		} else {
+			status.Statuses[result.MinionId] = "FAILED"
+		}
+	}
+}
 
-		if result.Stderr != "" {
-			stderr := strings.ReplaceAll(result.Stderr, "\n", "\\n")
-			if len(stderr) > 50 {
-				stderr = stderr[:47] + "..."
-			}
-			fmt.Printf("%-36s | %-9s | STDERR: %s\n", "", "", stderr)
+// setOption changes a console setting (set <option> <value>). The timestamp format and the
+// table columns are display preferences, persisted for the next sessions.
+func (c *Console) setOption(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("output = %s\n", c.currentOutputFormat())
+		fmt.Printf("time = %s\n", c.display.TimeFormat())
+		for _, table := range []string{"minion-list", "result-get"} {
+			fmt.Printf("columns %s = %s\n", table, strings.Join(c.display.Columns(table), ","))
+		}
+		return
+	}
+
+	if c.display == nil {
+		c.display, _ = LoadDisplayStore("")
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "output":
+		if len(args) != 2 {
+			c.ui.PrintError("Usage: set output <text|json>")
+			return
 		}
+		if err := c.SetOutputFormat(args[1]); err != nil {
+			c.ui.PrintError(err.Error())
+			return
+		}
+		c.ui.PrintSuccess(fmt.Sprintf("Output format set to %s", c.outputFormat))
+	case "time":
+		if len(args) != 2 {
+			c.ui.PrintError("Usage: set time <local|utc|relative>")
+			return
+		}
+		if err := c.display.SetTimeFormat(strings.ToLower(args[1])); err != nil {
+			c.ui.PrintError(err.Error())
+			return
+		}
+		c.ui.PrintSuccess(fmt.Sprintf("Time format set to %s", c.display.TimeFormat()))
+	case "columns":
+		if len(args) != 3 {
+			c.ui.PrintError("Usage: set columns <minion-list|result-get> <column,...|default>")
+			return
+		}
+		var columns []string
+		if args[2] != "default" {
+			columns = strings.Split(strings.ToLower(args[2]), ",")
+		}
+		if err := c.display.SetColumns(args[1], columns); err != nil {
+			c.ui.PrintError(err.Error())
+			return
+		}
+		c.ui.PrintSuccess(fmt.Sprintf("Columns of %s set to %s", args[1], strings.Join(c.display.Columns(args[1]), ",")))
+	default:
+		c.ui.PrintError(fmt.Sprintf("Unknown option '%s'. Available options: output, time, columns", args[0]))
+	}
+}
+
+// currentOutputFormat returns the active output format
+func (c *Console) currentOutputFormat() string {
+	if c.outputFormat == "" {
+		return OutputFormatText
 	}
+	return c.outputFormat
 }
 
 // setTags sets tags for a minion (replaces all existing tags)
@@ -848,6 +1512,20 @@ func main() {
 			handleOfflineCommand(command, os.Args[2:])
 			return
 		}
+		if command == "validate" {
+			// Validation only needs the local command registry, its status telling scripts the result
+			if !newConsole(zap.NewNop()).validateCommand(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		}
+		if command == "compliance-verify" {
+			// Auditors check exports without access to Nexus
+			if !newConsole(zap.NewNop()).verifyComplianceExport(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
 	// Load configuration using the new unified system
@@ -858,7 +1536,7 @@ func main() {
 	}
 
 	// Set up logging
-	logger, _, err := logging.SetupLogger(cfg.Debug)
+	logger, atom, err := logging.SetupLogger(cfg.Debug)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create logger: %v", err))
 	}
@@ -878,15 +1556,52 @@ func main() {
 	logger.Info("Starting Console",
 		zap.String("version", version.Component("Console")))
 
-	// Create gRPC client
-	grpcClient, err := NewGRPCClient(cfg, logger)
+	// Create and start console, connected to Nexus unless running locally
+	var console *Console
+	if cfg.Local {
+		console = NewLocalConsole(logger, &atom)
+	} else {
+		grpcClient, err := NewGRPCClient(cfg, logger)
+		if err != nil {
+			logger.Fatal("Failed to connect to server", zap.Error(err))
+		}
+		defer grpcClient.Close()
+		console = NewConsole(grpcClient, logger)
+		console.SetConfig(cfg)
+
+		if cfg.SignCommands {
+			signer, err := loadSigner(cfg)
+			if err != nil {
+				logger.Fatal("Failed to load command signing credentials", zap.Error(err))
+			}
+			console.SetSigner(signer)
+		}
+	}
+	if err := console.SetOutputFormat(cfg.OutputFormat); err != nil {
+		logger.Fatal("Invalid output format", zap.Error(err))
+	}
+
+	aliasFile := cfg.AliasFile
+	if aliasFile == "" {
+		aliasFile = DefaultAliasFile()
+	}
+	aliases, err := LoadAliasStore(aliasFile)
 	if err != nil {
-		logger.Fatal("Failed to connect to server", zap.Error(err))
+		logger.Warn("Failed to load aliases, starting without them", zap.Error(err))
+		aliases, _ = LoadAliasStore("")
 	}
-	defer grpcClient.Close()
+	console.SetAliases(aliases)
 
-	// Create and start console
-	console := NewConsole(grpcClient, logger)
+	displayFile := cfg.DisplayFile
+	if displayFile == "" {
+		displayFile = DefaultDisplayFile()
+	}
+	display, err := LoadDisplayStore(displayFile)
+	if err != nil {
+		logger.Warn("Failed to load display preferences, starting with the defaults", zap.Error(err))
+		display, _ = LoadDisplayStore("")
+	}
+	console.SetDisplay(display)
 	console.Start()
 }
 
@@ -905,23 +1620,96 @@ func handleOfflineCommand(command string, args []string) {
 			fmt.Println("  version, v                                 - Show version information")
 			fmt.Println("  minion-list, lm                            - List all connected minions with last seen time")
 			fmt.Println("  tag-list, lt                               - List all available tags")
+			fmt.Println("  minion-inspect <id>                        - Show connection diagnostics of a minion")
+			fmt.Println("  minion-history <id> [count]                - Show the last commands executed on a minion")
+			fmt.Println("  minion-uptime <id> [window]                - Show the connectivity timeline and uptime of a minion (default 24h)")
+			fmt.Println("  minion-logs <id> [--level <lvl>] [--since <t>] [--limit <n>] - Show the logs a minion shipped to Nexus")
+			fmt.Println("  minion-retire <id> [--uninstall] [--reason <text>] [--yes] - Decommission a host, archiving its rows")
+			fmt.Println("  crash-list [id] [--limit <n>] [--stack]    - Show the panics minions recovered from")
+			fmt.Println("  minion-bootstrap-url <os> <arch> [--ttl <duration>] - Generate a one-time URL installing a minion")
 			fmt.Println("  command-send all <cmd>                     - Send command to all minions")
+			fmt.Println("  edit [options] <target>                    - Compose a multi-line payload in $EDITOR, then send it")
 			fmt.Println("  command-send minion <id> <cmd>             - Send command to specific minion")
 			fmt.Println("  command-send tag <key>=<value> <cmd>       - Send command to minions with tag")
+			fmt.Println("  command-send dc=<name>[,rack=<name>] <cmd> - Send command to minions of a region, datacenter or rack")
+			fmt.Println("  command-send --dry-run <target> <cmd>      - Show target minions without sending")
+			fmt.Println("  command-send --emergency <target> <cmd>    - Send even if a maintenance window holds it")
+			fmt.Println("  command-send --priority <level> <target> <cmd> - Queue with low, normal, high or emergency priority")
+			fmt.Println("  command-send --confirm <token> <target> <cmd> - Confirm a destructive command")
+			fmt.Println("  command-send --no-wait <target> <cmd>      - Don't follow the progress of multi-minion commands")
+			fmt.Println("  command-send --lock <name> <target> <cmd> - Wait for a host lock and hold it while the command runs")
+			fmt.Println("  command-send --impact <class> <target> <cmd> - Declare a read-only, mutating or disruptive impact")
+			fmt.Println("  command-send --retry <n> <target> <cmd>    - Retry failed targets up to n attempts, see --retry-backoff, --retry-on")
+			fmt.Println("  command-send --sample <n> <target> <cmd>   - Store n successful results in full and all failures, count the others")
+			fmt.Println("  command-send --validate <target> <cmd>     - Check a command and its arguments without contacting Nexus")
+			fmt.Println("  validate <target> <cmd>                    - Same as command-send --validate, also offline: ./console validate ...")
+			fmt.Println("  target-explain <target>                    - Show the minions a target matches and why, e.g. tag env=prod,!maintenance")
+			fmt.Println("  use <target> | use none                    - Select the working target of the session, or clear it")
+			fmt.Println("  run [options] <cmd>                        - Send command to the working target, as command-send would")
+			fmt.Println("  targets                                    - Show the working target and the minions it matches")
+			fmt.Println("  pick [filter]                              - Select minions in a filterable list as the working target")
+			fmt.Println("  command-approvals                          - List the commands requiring approval")
+			fmt.Println("  command-approve <cmd-id> [comment]         - Approve and dispatch a command sent by another operator")
+			fmt.Println("  command-reject <cmd-id> [comment]          - Reject a command awaiting approval")
+			fmt.Println("  shell <minion-id>                          - Open an interactive shell on a minion (Ctrl-] to detach)")
+			fmt.Println("  file-pull <minion-id> <path> [local-path]   - Pull a file of any size from a minion, resumable")
+			fmt.Println("  file-pull --resume <transfer-id> [local-path] - Resume an interrupted file pull")
+			fmt.Println("  file-download <transfer-id> [local-path]   - Download a file pulled to Nexus, resuming a partial download")
+			fmt.Println("  file-transfers                             - List the files pulled to Nexus")
 			fmt.Println("Command Status:")
 			fmt.Println("  command-status all                         - Show status breakdown of all commands")
 			fmt.Println("  command-status minion <id>                 - Show detailed status of commands for a minion")
 			fmt.Println("  command-status stats                       - Show command execution statistics by minion")
 			fmt.Println("  result-get <cmd-id>                        - Get results for a command ID")
+			fmt.Println("  result-view <cmd-id> [minion-id]           - Browse the full output of the results in a pager")
+			fmt.Println("  result-export <cmd-id> [--format csv|json] [--out <file>] - Export all results of a command")
+			fmt.Println("  trace-get <trace-id>                       - Show the timeline of a command across Nexus and minions")
 			fmt.Println("Tag Management:")
 			fmt.Println("  tag-set <minion-id> <key>=<value> [...]    - Set tags for a minion (replaces all)")
 			fmt.Println("  tag-update <minion-id> +<key>=<value> -<key> [...] - Update tags for a minion")
+			fmt.Println("  tag-schema-show                            - Show the keys and values allowed in tags")
+			fmt.Println("Maintenance Windows:")
+			fmt.Println("  maintenance-add minion <id>|tag <key>=<value> <start> <end> - Declare a maintenance window")
+			fmt.Println("  maintenance-list                           - List current and upcoming maintenance windows")
+			fmt.Println("  maintenance-remove <window-id>             - Remove a maintenance window")
+			fmt.Println("Command Environment:")
+			fmt.Println("  env-set minion <id>|tag <key>=<value> <NAME>=<value> - Set a variable for shell commands")
+			fmt.Println("  env-unset minion <id>|tag <key>=<value> <NAME> - Remove a variable of shell commands")
+			fmt.Println("  env-list                                   - List the variables Nexus sets for shell commands")
+			fmt.Println("Reports:")
+			fmt.Println("  report-create <name> \"<query>\" [description] - Save a report query over command results")
+			fmt.Println("  report-list                                - List saved reports")
+			fmt.Println("  report-run <name> [key=value ...]          - Run a saved report with its parameters")
+			fmt.Println("  stats [--since <t>] [--until <t>] [--command <pattern>] [--top <n>] - Show fleet-wide success rates and durations")
+			fmt.Println("  fleet-health [--below <score>]             - Show the fleet health and the anomalous minions")
+			fmt.Println("  fleet-versions                             - Show the minion builds and the outdated minions")
+			fmt.Println("  fleet-patches [tag-key]                    - Summarize the pending updates of the fleet, by tag value")
+			fmt.Println("  compliance-export [--since <t>] [--until <t>] [--out <file>] - Export signed execution receipts as JSON lines")
+			fmt.Println("  compliance-verify <file> [--ca <bundle>]   - Check the signatures and sequence of a receipts export")
+			fmt.Println("  db-check [--repair]                        - Check database integrity, optionally repairing orphans")
+			fmt.Println("  db-query [name] [key=value ...]            - List or run the read-only database queries approved on Nexus")
+			fmt.Println("  admin-flush-caches                         - Forget the state Nexus keeps for disconnected minions (admin)")
+			fmt.Println("  admin-log-level [level] [--sampling <n,m>] - Show or change the Nexus log level and sampling (admin)")
+			fmt.Println("  admin-registry                             - Dump the registry state of Nexus (admin)")
+			fmt.Println("  admin-disconnect <minion-id>               - Close the command stream of a minion (admin)")
+			fmt.Println("  admin-unbind <minion-id>                   - Forget the certificate a minion ID is bound to (admin)")
+			fmt.Println("  admin-prune                                - Archive old results and delete old minion logs now (admin)")
+			fmt.Println("Profiles:")
+			fmt.Println("  connect [profile]                          - Connect with a profile, or list the profiles")
+			fmt.Println("Aliases:")
+			fmt.Println("  alias <name>=\"<command>\"                   - Define a persistent command alias")
+			fmt.Println("  alias-list                                 - List defined aliases")
+			fmt.Println("  alias-remove <name>                        - Remove an alias")
 			fmt.Println("Other Commands:")
+			fmt.Println("  set output <text|json>                     - Set output format for data commands")
+			fmt.Println("  set time <local|utc|relative>              - Set the timestamp format (persisted)")
+			fmt.Println("  set columns <table> <column,...|default>   - Set the columns of minion-list or result-get (persisted)")
 			fmt.Println("  clear                                      - Clear screen")
 			fmt.Println("  history                                    - Show command history")
 			fmt.Println("  quit, exit                                 - Exit the console")
 			fmt.Println()
 			fmt.Println("Note: For full interactive mode and command execution, server connection is required.")
+			fmt.Println("      Start with --local to run commands on this machine without Nexus.")
 		}
 	}
 }
@@ -3,21 +3,35 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/arhuman/minexus/internal/certs"
 	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/config"
 	"github.com/arhuman/minexus/internal/util"
 	pb "github.com/arhuman/minexus/protogen"
 
 	"github.com/chzyer/readline"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // mockConsoleServiceClient is a mock implementation for testing
@@ -29,7 +43,37 @@ type mockConsoleServiceClient struct {
 	commandAccepted bool
 	commandID       string
 	results         []*pb.CommandResult
+	omittedResults  int32
 	tagSuccess      bool
+	windows         []*pb.MaintenanceWindow
+	lastRequest     *pb.CommandRequest
+	reports         []*pb.Report
+	lastReport      *pb.ReportRequest
+	lastDBCheck     *pb.DatabaseCheckRequest
+	lastHistory     *pb.MinionHistoryRequest
+	lastUptime      *pb.MinionUptimeRequest
+	lastCrashList   *pb.CrashListRequest
+	envVars         []*pb.CommandEnvVar
+	confirmToken    string // when set, commands must carry this confirm token
+	shell           *mockShellClient
+	statusPolls     []*pb.CommandStatusResponse // successive GetCommandStatus responses, the last one repeating
+	tagSchema       *pb.TagSchema
+	lastBootstrap   *pb.BootstrapRequest
+	lastDBQuery     *pb.DatabaseQueryRequest
+	lastDecision    *pb.ApprovalDecision
+	lastStats       *pb.CommandStatsRequest
+	lastLogs        *pb.MinionLogsRequest
+	lastRetire      *pb.RetireMinionRequest
+	lastFleetHealth *pb.FleetHealthRequest
+	lastPatchGroup  string
+	lastExplain     *pb.ExplainTargetsRequest
+	lastPull        *pb.FilePullRequest
+	pullStatuses    []*pb.FileTransferStatus
+	transfers       []*pb.FileTransferStatus
+	transferFile    []byte
+	lastDownload    *pb.FileDownloadRequest
+	lastExport      *pb.ReceiptExportRequest
+	receipts        []*pb.ExecutionReceipt
 }
 
 func (m *mockConsoleServiceClient) ListMinions(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.MinionList, error) {
@@ -68,6 +112,27 @@ func (m *mockConsoleServiceClient) SendCommand(ctx context.Context, req *pb.Comm
 		return &pb.CommandDispatchResponse{Accepted: false}, fmt.Errorf("command payload is empty")
 	}
 
+	m.lastRequest = req
+
+	if m.confirmToken != "" && req.ConfirmToken != m.confirmToken {
+		return &pb.CommandDispatchResponse{
+			Accepted:             req.DryRun,
+			DryRun:               req.DryRun,
+			TargetMinionIds:      req.MinionIds,
+			ConfirmationRequired: true,
+			ConfirmToken:         m.confirmToken,
+			DestructiveReason:    "recursive delete",
+		}, nil
+	}
+
+	if req.DryRun {
+		var targets []string
+		for _, minion := range m.minions {
+			targets = append(targets, minion.Id)
+		}
+		return &pb.CommandDispatchResponse{Accepted: len(targets) > 0, DryRun: true, TargetMinionIds: targets}, nil
+	}
+
 	return &pb.CommandDispatchResponse{Accepted: m.commandAccepted, CommandId: m.commandID}, nil
 }
 
@@ -75,7 +140,23 @@ func (m *mockConsoleServiceClient) GetCommandResults(ctx context.Context, req *p
 	if m.returnError {
 		return nil, errors.New("mock error")
 	}
-	return &pb.CommandResults{Results: m.results}, nil
+	if req.Limit > 0 {
+		start := min(int(req.Offset), len(m.results))
+		end := min(start+int(req.Limit), len(m.results))
+		return &pb.CommandResults{Results: m.results[start:end], HasMore: end < len(m.results)}, nil
+	}
+	return &pb.CommandResults{Results: m.results, OmittedResults: m.omittedResults}, nil
+}
+
+func (m *mockConsoleServiceClient) GetCommandStatus(ctx context.Context, req *pb.ResultRequest, opts ...grpc.CallOption) (*pb.CommandStatusResponse, error) {
+	if len(m.statusPolls) == 0 {
+		return nil, errors.New("unimplemented")
+	}
+	response := m.statusPolls[0]
+	if len(m.statusPolls) > 1 {
+		m.statusPolls = m.statusPolls[1:]
+	}
+	return response, nil
 }
 
 func (m *mockConsoleServiceClient) SetTags(ctx context.Context, req *pb.SetTagsRequest, opts ...grpc.CallOption) (*pb.Ack, error) {
@@ -92,6 +173,379 @@ func (m *mockConsoleServiceClient) UpdateTags(ctx context.Context, req *pb.Updat
 	return &pb.Ack{Success: m.tagSuccess}, nil
 }
 
+func (m *mockConsoleServiceClient) AddMaintenanceWindow(ctx context.Context, req *pb.MaintenanceWindow, opts ...grpc.CallOption) (*pb.MaintenanceWindow, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	req.Id = fmt.Sprintf("window-%d", len(m.windows)+1)
+	m.windows = append(m.windows, req)
+	return req, nil
+}
+
+func (m *mockConsoleServiceClient) ListMaintenanceWindows(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.MaintenanceWindowList, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.MaintenanceWindowList{Windows: m.windows, HeldCommands: 3}, nil
+}
+
+func (m *mockConsoleServiceClient) RemoveMaintenanceWindow(ctx context.Context, req *pb.MaintenanceWindowRequest, opts ...grpc.CallOption) (*pb.Ack, error) {
+	for i, window := range m.windows {
+		if window.Id == req.Id {
+			m.windows = append(m.windows[:i], m.windows[i+1:]...)
+			return &pb.Ack{Success: true}, nil
+		}
+	}
+	return &pb.Ack{Success: false}, errors.New("maintenance window not found")
+}
+
+func (m *mockConsoleServiceClient) GetMinionDiagnostics(ctx context.Context, req *pb.MinionDiagnosticsRequest, opts ...grpc.CallOption) (*pb.MinionDiagnostics, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.MinionDiagnostics{
+		MinionId:        req.MinionId,
+		Registered:      true,
+		StreamState:     "CONNECTED",
+		ActiveStreams:   2,
+		ChannelDepth:    3,
+		ChannelCapacity: 100,
+		Events:          []*pb.ConnectionEvent{{Timestamp: 1640995200, Event: "STREAM_OPENED", Detail: "concurrent stream"}},
+		LastSpeedTest:   &pb.SpeedTestSummary{CommandId: "cmd-9", Timestamp: 1640995200, LatencyMs: 12.5, UploadMbps: 80, DownloadMbps: 320},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) CreateReport(ctx context.Context, req *pb.Report, opts ...grpc.CallOption) (*pb.Report, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.reports = append(m.reports, req)
+	return req, nil
+}
+
+func (m *mockConsoleServiceClient) ListReports(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.ReportList, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.ReportList{Reports: m.reports}, nil
+}
+
+func (m *mockConsoleServiceClient) RunReport(ctx context.Context, req *pb.ReportRequest, opts ...grpc.CallOption) (*pb.ReportResult, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastReport = req
+	return &pb.ReportResult{
+		Name:    req.Name,
+		Columns: []string{"tag:env", "count", "failure_rate"},
+		Rows: []*pb.ReportRow{
+			{Values: []string{"prod", "8", "25.0%"}},
+			{Values: []string{"staging", "120", "0.0%"}},
+		},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) ListDatabaseQueries(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.DatabaseQueryList, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.DatabaseQueryList{Queries: []*pb.DatabaseQuery{
+		{Name: "stale-minions", Description: "Minions not seen for a while", Params: []string{"age"}},
+	}}, nil
+}
+
+func (m *mockConsoleServiceClient) RunDatabaseQuery(ctx context.Context, req *pb.DatabaseQueryRequest, opts ...grpc.CallOption) (*pb.DatabaseQueryResult, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastDBQuery = req
+	return &pb.DatabaseQueryResult{
+		Name:      req.Name,
+		Columns:   []string{"id", "last_seen"},
+		Rows:      []*pb.ReportRow{{Values: []string{"minion-1", "2025-01-02T03:04:05Z"}}},
+		Truncated: true,
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) CheckDatabase(ctx context.Context, req *pb.DatabaseCheckRequest, opts ...grpc.CallOption) (*pb.DatabaseCheckReport, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastDBCheck = req
+	check := &pb.DatabaseCheck{Name: "orphaned_results", Description: "Command results whose command does not exist", Found: 3, Repairable: true}
+	if req.Repair {
+		check.Repaired = 3
+	}
+	return &pb.DatabaseCheckReport{Checks: []*pb.DatabaseCheck{check}, Healthy: req.Repair}, nil
+}
+
+func (m *mockConsoleServiceClient) CreateBootstrapToken(ctx context.Context, req *pb.BootstrapRequest, opts ...grpc.CallOption) (*pb.BootstrapToken, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastBootstrap = req
+	return &pb.BootstrapToken{
+		Token:     "0123abcd",
+		Url:       "https://nexus:8086/bootstrap/0123abcd/install.sh",
+		Os:        req.Os,
+		Arch:      req.Arch,
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) GetTagSchema(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.TagSchema, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	if m.tagSchema == nil {
+		return &pb.TagSchema{}, nil
+	}
+	return m.tagSchema, nil
+}
+
+func (m *mockConsoleServiceClient) DecideCommandApproval(ctx context.Context, req *pb.ApprovalDecision, opts ...grpc.CallOption) (*pb.CommandDispatchResponse, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastDecision = req
+	return &pb.CommandDispatchResponse{Accepted: !req.Reject, CommandId: req.CommandId, TargetMinionIds: []string{"minion-1"}}, nil
+}
+
+func (m *mockConsoleServiceClient) GetTrace(ctx context.Context, req *pb.TraceRequest, opts ...grpc.CallOption) (*pb.Trace, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.Trace{
+		TraceId: req.TraceId,
+		Events: []*pb.TraceEvent{
+			{TimestampMs: 1640995200000, Component: "nexus", Event: "DISPATCHED", CommandId: "cmd-1", MinionId: "minion-1", Detail: "uptime"},
+			{TimestampMs: 1640995200150, Component: "minion", Event: "RECEIVED", CommandId: "cmd-1", MinionId: "minion-1"},
+			{TimestampMs: 1640995202400, Component: "nexus", Event: "RESULT", CommandId: "cmd-1", MinionId: "minion-1", Detail: "exit code 0"},
+		},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) GetCommandStats(ctx context.Context, req *pb.CommandStatsRequest, opts ...grpc.CallOption) (*pb.CommandStats, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastStats = req
+	return &pb.CommandStats{
+		Since: 1640908800, Until: 1640995200,
+		Count: 40, Failures: 4, MedianMs: 850, P90Ms: 4200, P99Ms: 9800, MaxMs: 12000,
+		SlowestMinions: []*pb.MinionCommandStats{
+			{MinionId: "minion-2", Count: 10, Failures: 4, MedianMs: 5100, MaxMs: 12000},
+			{MinionId: "minion-1", Count: 30, MedianMs: 600, MaxMs: 1900},
+		},
+		ByImpact: []*pb.ImpactCommandStats{
+			{Impact: "read-only", Count: 34, Failures: 1},
+			{Impact: "disruptive", Count: 6, Failures: 3},
+		},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) GetFleetVersions(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.FleetVersions, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.FleetVersions{
+		Total: 5, NexusVersion: "v1.3.0", NexusProtocolVersion: 1, MinProtocolVersion: 1,
+		Versions: []*pb.VersionCount{
+			{Version: "v1.3.0", BuildCommit: "def456", ProtocolVersion: 1, Minions: 4},
+			{Minions: 1},
+		},
+		Outdated: []*pb.HostInfo{{Id: "minion-9", Hostname: "legacy-01", Outdated: true}},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) GetPatchCompliance(ctx context.Context, req *pb.PatchComplianceRequest, opts ...grpc.CallOption) (*pb.PatchCompliance, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastPatchGroup = req.GroupBy
+	compliance := &pb.PatchCompliance{
+		GroupBy: req.GroupBy,
+		Total:   &pb.PatchGroup{Minions: 4, Compliant: 2, Vulnerable: 1, Unknown: 1, RebootRequired: 1, SecurityUpdates: 3, Updates: 7, OldestStatus: 1700000000},
+	}
+	if req.GroupBy != "" {
+		compliance.Groups = []*pb.PatchGroup{
+			{Value: "prod", Minions: 3, Compliant: 1, Vulnerable: 1, Unknown: 1, RebootRequired: 1, SecurityUpdates: 3, Updates: 5, OldestStatus: 1700000000},
+			{Minions: 1, Compliant: 1, Updates: 2, OldestStatus: 1700000500},
+		}
+	}
+	return compliance, nil
+}
+
+func (m *mockConsoleServiceClient) GetFleetHealth(ctx context.Context, req *pb.FleetHealthRequest, opts ...grpc.CallOption) (*pb.FleetHealth, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastFleetHealth = req
+	return &pb.FleetHealth{
+		Total: 10, Healthy: 8, Degraded: 1, Unhealthy: 1, AverageScore: 91,
+		Minions: []*pb.HostInfo{
+			{Id: "minion-2", Hostname: "db-01", Health: &pb.MinionHealth{
+				Score: 40, Anomalies: []string{"frequent-reconnects", "high-failure-rate"}, Reconnects: 4, Results: 6, FailedResults: 4,
+			}},
+			{Id: "minion-5", Hostname: "web-05", Health: &pb.MinionHealth{
+				Score: 70, Anomalies: []string{"slow-results"}, Results: 3, MeanLatencyMs: 95000,
+			}},
+		},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) ExplainTargets(ctx context.Context, req *pb.ExplainTargetsRequest, opts ...grpc.CallOption) (*pb.TargetExplanations, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastExplain = req
+	return &pb.TargetExplanations{
+		Matched: 1,
+		Minions: []*pb.TargetExplanation{
+			{MinionId: "minion-1", Hostname: "web-01", Matched: true, Rules: []*pb.RuleExplanation{
+				{Rule: "env=prod", Matched: true, Reason: "env is prod"},
+				{Rule: "!maintenance", Matched: true, Reason: "tag maintenance is not set"},
+			}},
+			{MinionId: "minion-2", Hostname: "web-02", Rules: []*pb.RuleExplanation{
+				{Rule: "env=prod", Matched: true, Reason: "env is prod"},
+				{Rule: "!maintenance", Reason: "maintenance is yes"},
+			}},
+		},
+		UnknownMinionIds: []string{"missing"},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) SetCommandEnv(ctx context.Context, req *pb.CommandEnvVar, opts ...grpc.CallOption) (*pb.Ack, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	req.UpdatedAt = 1640995200
+	m.envVars = append(m.envVars, req)
+	return &pb.Ack{Success: true}, nil
+}
+
+func (m *mockConsoleServiceClient) UnsetCommandEnv(ctx context.Context, req *pb.CommandEnvVar, opts ...grpc.CallOption) (*pb.Ack, error) {
+	for i, envVar := range m.envVars {
+		if envVar.MinionId == req.MinionId && envVar.TagKey == req.TagKey && envVar.TagValue == req.TagValue && envVar.Name == req.Name {
+			m.envVars = append(m.envVars[:i], m.envVars[i+1:]...)
+			return &pb.Ack{Success: true}, nil
+		}
+	}
+	return &pb.Ack{Success: false}, errors.New("environment variable not found")
+}
+
+func (m *mockConsoleServiceClient) ListCommandEnv(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.CommandEnvList, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.CommandEnvList{Vars: m.envVars}, nil
+}
+
+func (m *mockConsoleServiceClient) ListCrashes(ctx context.Context, req *pb.CrashListRequest, opts ...grpc.CallOption) (*pb.CrashList, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastCrashList = req
+	return &pb.CrashList{Crashes: []*pb.CrashReport{{
+		MinionId:  "abc123",
+		Timestamp: 1640995200,
+		Component: "command cmd-1: docker:ps",
+		Message:   "runtime error: index out of range [3] with length 3",
+		Stack:     "goroutine 42 [running]:\nmain.crash()",
+		Version:   "v1.2.3",
+	}}}, nil
+}
+
+func (m *mockConsoleServiceClient) RetireMinion(ctx context.Context, req *pb.RetireMinionRequest, opts ...grpc.CallOption) (*pb.RetireMinionResponse, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastRetire = req
+	return &pb.RetireMinionResponse{MinionId: req.MinionId, ArchivedResults: 42, DroppedCommands: 1}, nil
+}
+
+func (m *mockConsoleServiceClient) GetMinionLogs(ctx context.Context, req *pb.MinionLogsRequest, opts ...grpc.CallOption) (*pb.MinionLogs, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastLogs = req
+	return &pb.MinionLogs{
+		MinionId: req.MinionId,
+		Entries: []*pb.MinionLogEntry{
+			{TimestampMs: 1640995300000, Level: "error", Message: "Command failed", Caller: "minion/processor.go:120", Fields: `{"command_id":"cmd-2"}`},
+			{TimestampMs: 1640995200000, Level: "warn", Message: "Stream timeout"},
+		},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) GetMinionHistory(ctx context.Context, req *pb.MinionHistoryRequest, opts ...grpc.CallOption) (*pb.MinionHistory, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastHistory = req
+	return &pb.MinionHistory{
+		MinionId: req.MinionId,
+		Entries: []*pb.MinionHistoryEntry{
+			{CommandId: "cmd-2", Command: "system:info", Status: "EXECUTING", Timestamp: 1640995300},
+			{CommandId: "cmd-1", Command: "apt-get update", Status: "FAILED", Timestamp: 1640995200, HasResult: true, ExitCode: 100, DurationMs: 4250},
+		},
+	}, nil
+}
+
+func (m *mockConsoleServiceClient) GetMinionUptime(ctx context.Context, req *pb.MinionUptimeRequest, opts ...grpc.CallOption) (*pb.MinionUptime, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastUptime = req
+	return &pb.MinionUptime{
+		MinionId:      req.MinionId,
+		Since:         1640995200,
+		Until:         1641081600,
+		UptimePercent: 75,
+		Periods: []*pb.ConnectionPeriod{
+			{ConnectedAt: 1640995200, DisconnectedAt: 1641016800, Reason: "EOF"},
+			{ConnectedAt: 1641038400},
+		},
+	}, nil
+}
+
+// mockAdminServiceClient is an AdminService client recording the requests it receives
+type mockAdminServiceClient struct {
+	pb.AdminServiceClient
+	lastLevel      *pb.LogLevelRequest
+	lastDisconnect *pb.DisconnectMinionRequest
+	lastUnbind     *pb.UnbindMinionRequest
+}
+
+func (m *mockAdminServiceClient) SetLogLevel(ctx context.Context, req *pb.LogLevelRequest, opts ...grpc.CallOption) (*pb.LogLevelResponse, error) {
+	m.lastLevel = req
+	return &pb.LogLevelResponse{Level: req.Level, PreviousLevel: "info", Sampling: req.Sampling, PreviousSampling: "100,100"}, nil
+}
+
+func (m *mockAdminServiceClient) DumpRegistry(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.RegistryDump, error) {
+	return &pb.RegistryDump{
+		Minions: []*pb.RegistryEntry{
+			{MinionId: "minion-1", Hostname: "web-1", LastSeen: 1640995200, Connected: true, QueuedCommands: 2, InFlight: 1},
+			{MinionId: "minion-2", Hostname: "web-2", LastSeen: 1640995100, PendingCommands: 3},
+		},
+		Connected:       1,
+		PendingCommands: 3,
+	}, nil
+}
+
+func (m *mockAdminServiceClient) DisconnectMinion(ctx context.Context, req *pb.DisconnectMinionRequest, opts ...grpc.CallOption) (*pb.Ack, error) {
+	m.lastDisconnect = req
+	if req.MinionId == "minion-2" {
+		return nil, errors.New("minion minion-2 has no open command stream")
+	}
+	return &pb.Ack{Success: true}, nil
+}
+
+func (m *mockAdminServiceClient) UnbindMinion(ctx context.Context, req *pb.UnbindMinionRequest, opts ...grpc.CallOption) (*pb.Ack, error) {
+	m.lastUnbind = req
+	return &pb.Ack{Success: true}, nil
+}
+
 // Helper function to capture stdout
 func captureOutput(f func()) string {
 	oldStdout := os.Stdout
@@ -489,6 +943,127 @@ func TestSendCommand(t *testing.T) {
 	})
 }
 
+// fakeEditor sets VISUAL to a script writing content to the file it edits
+func fakeEditor(t *testing.T, content string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("editor scripts require a POSIX shell")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "content"), []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write editor content: %v", err)
+	}
+	script := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat '"+filepath.Join(dir, "content")+"' > \"$1\"\n"), 0700); err != nil {
+		t.Fatalf("Failed to write editor script: %v", err)
+	}
+	t.Setenv("VISUAL", script)
+}
+
+func TestEditCommand(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	script := "#!/bin/sh\nfor f in \"a b\" 'c'; do\n  echo \"$f\"\ndone\n\n"
+	fakeEditor(t, script)
+	output := captureOutput(func() {
+		console.handleCommand("edit", []string{"--no-wait", "minion", "abc123"})
+	})
+	if mockClient.lastRequest == nil || mockClient.lastRequest.Command.Payload != strings.TrimRight(script, "\n") {
+		t.Fatalf("Expected the script sent as written, got %v", mockClient.lastRequest)
+	}
+	if len(mockClient.lastRequest.MinionIds) != 1 || mockClient.lastRequest.MinionIds[0] != "abc123" || !strings.Contains(output, "cmd-123") {
+		t.Errorf("Unexpected dispatch: %v: %s", mockClient.lastRequest, output)
+	}
+
+	// Empty buffers are not sent
+	mockClient.lastRequest = nil
+	fakeEditor(t, "\n  \n")
+	output = captureOutput(func() {
+		console.handleCommand("edit", []string{"all"})
+	})
+	if mockClient.lastRequest != nil || !strings.Contains(output, "Empty payload") {
+		t.Errorf("Expected an empty payload not to be sent, got %v: %s", mockClient.lastRequest, output)
+	}
+
+	// Targets are checked before opening the editor
+	for _, args := range [][]string{nil, {"tag", "env"}, {"nowhere"}} {
+		output = captureOutput(func() {
+			console.handleCommand("edit", args)
+		})
+		if mockClient.lastRequest != nil || !strings.Contains(output, "rror") && !strings.Contains(output, "usage") {
+			t.Errorf("Expected an error for %v, got: %s", args, output)
+		}
+	}
+}
+
+func TestSendCommandDryRun(t *testing.T) {
+	minions := []*pb.HostInfo{{Id: "abc123"}, {Id: "def456"}}
+
+	t.Run("text", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{minions: minions, commandAccepted: true, commandID: "cmd-123"})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"--dry-run", "all", "uptime"})
+		})
+
+		for _, expected := range []string{"Dry run", "2 minion(s)", "abc123", "def456"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", expected, output)
+			}
+		}
+		if strings.Contains(output, "cmd-123") || len(console.commandStatus) != 0 {
+			t.Error("Dry run must not dispatch nor track the command")
+		}
+	})
+
+	t.Run("no_targets", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"--dry-run", "tag", "env=prod", "uptime"})
+		})
+
+		if !strings.Contains(output, "no minion matches") {
+			t.Errorf("Expected no target message, got: %s", output)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{minions: minions})
+		defer console.Shutdown()
+		console.SetOutputFormat(OutputFormatJSON)
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"--dry-run", "all", "uptime"})
+		})
+
+		var decoded CommandSendOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if !decoded.DryRun || len(decoded.Targets) != 2 || decoded.CommandID != "" {
+			t.Errorf("Unexpected dry run output: %+v", decoded)
+		}
+	})
+
+	t.Run("unknown_option", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"--force", "all", "uptime"})
+		})
+
+		if !strings.Contains(output, "unknown option: --force") {
+			t.Errorf("Expected unknown option error, got: %s", output)
+		}
+	})
+}
+
 func TestIsHexString(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -640,3 +1215,2747 @@ func TestFormatLastSeen(t *testing.T) {
 		})
 	}
 }
+
+func TestSetOption(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		expectedFormat string
+		expectedOutput string
+	}{
+		{"show_current", []string{}, OutputFormatText, "output = text"},
+		{"set_json", []string{"output", "json"}, OutputFormatJSON, "Output format set to json"},
+		{"set_text_uppercase", []string{"output", "TEXT"}, OutputFormatText, "Output format set to text"},
+		{"invalid_format", []string{"output", "yaml"}, OutputFormatText, "invalid output format"},
+		{"unknown_option", []string{"color", "on"}, OutputFormatText, "Unknown option"},
+		{"missing_value", []string{"output"}, OutputFormatText, "Usage: set output"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			console := createMockConsole(&mockConsoleServiceClient{})
+			defer console.Shutdown()
+
+			output := captureOutput(func() {
+				console.handleCommand("set", tt.args)
+			})
+
+			if !strings.Contains(output, tt.expectedOutput) {
+				t.Errorf("Expected output to contain '%s', got: %s", tt.expectedOutput, output)
+			}
+			if console.currentOutputFormat() != tt.expectedFormat {
+				t.Errorf("Expected output format '%s', got '%s'", tt.expectedFormat, console.currentOutputFormat())
+			}
+		})
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	newJSONConsole := func(mockClient *mockConsoleServiceClient) *Console {
+		console := createMockConsole(mockClient)
+		if err := console.SetOutputFormat(OutputFormatJSON); err != nil {
+			t.Fatalf("SetOutputFormat failed: %v", err)
+		}
+		return console
+	}
+
+	t.Run("minion_list", func(t *testing.T) {
+		console := newJSONConsole(&mockConsoleServiceClient{
+			minions: []*pb.HostInfo{
+				{Id: "abc123", Hostname: "testhost", Ip: "192.168.1.1", Os: "linux", LastSeen: 42, Tags: map[string]string{"env": "prod"}},
+			},
+		})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.listMinions(context.Background())
+		})
+
+		var decoded MinionListOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if decoded.Count != 1 || len(decoded.Minions) != 1 {
+			t.Fatalf("Expected 1 minion, got %+v", decoded)
+		}
+		if decoded.Minions[0].ID != "abc123" || decoded.Minions[0].Tags["env"] != "prod" || decoded.Minions[0].LastSeen != 42 {
+			t.Errorf("Unexpected minion data: %+v", decoded.Minions[0])
+		}
+	})
+
+	t.Run("empty_minion_list", func(t *testing.T) {
+		console := newJSONConsole(&mockConsoleServiceClient{})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.listMinions(context.Background())
+		})
+
+		if !strings.Contains(output, `"minions": []`) {
+			t.Errorf("Expected empty minions array, got: %s", output)
+		}
+	})
+
+	t.Run("tag_list", func(t *testing.T) {
+		console := newJSONConsole(&mockConsoleServiceClient{tags: []string{"env:prod", "role:web"}})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.listTags(context.Background())
+		})
+
+		var decoded TagListOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if decoded.Count != 2 || decoded.Tags[1] != "role:web" {
+			t.Errorf("Unexpected tag list: %+v", decoded)
+		}
+	})
+
+	t.Run("result_get", func(t *testing.T) {
+		console := newJSONConsole(&mockConsoleServiceClient{
+			results: []*pb.CommandResult{
+				{CommandId: "cmd-1", MinionId: "abc123", ExitCode: 0, Stdout: "line1\nline2", Timestamp: 100},
+			},
+		})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.getResults(context.Background(), []string{"cmd-1"})
+		})
+
+		var decoded ResultListOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if decoded.CommandID != "cmd-1" || decoded.Count != 1 {
+			t.Fatalf("Unexpected result list: %+v", decoded)
+		}
+		if decoded.Results[0].Stdout != "line1\nline2" {
+			t.Errorf("Expected untruncated stdout, got %q", decoded.Results[0].Stdout)
+		}
+	})
+
+	t.Run("command_send", func(t *testing.T) {
+		console := newJSONConsole(&mockConsoleServiceClient{
+			commandAccepted: true,
+			commandID:       "cmd-123",
+		})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"minion", "abc123", "echo", "test"})
+		})
+
+		var decoded CommandSendOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if !decoded.Accepted || decoded.CommandID != "cmd-123" || decoded.Payload != "echo test" {
+			t.Errorf("Unexpected command-send output: %+v", decoded)
+		}
+		if len(decoded.Targets) != 1 || decoded.Targets[0] != "abc123" {
+			t.Errorf("Expected target abc123, got %v", decoded.Targets)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		console := newJSONConsole(&mockConsoleServiceClient{returnError: true})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.listTags(context.Background())
+		})
+
+		var decoded ErrorOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if !strings.Contains(decoded.Error, "Error listing tags") {
+			t.Errorf("Unexpected error output: %+v", decoded)
+		}
+	})
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"minion", []string{"minion", "abc123", "2030-01-01T22:00", "2030-01-02T02:00"}, false},
+		{"tag_rfc3339", []string{"tag", "env=prod", "2030-01-01T22:00:00Z", "2030-01-02T02:00:00Z"}, false},
+		{"missing_args", []string{"minion", "abc123", "2030-01-01T22:00"}, true},
+		{"invalid_target", []string{"all", "x", "2030-01-01T22:00", "2030-01-02T02:00"}, true},
+		{"invalid_tag", []string{"tag", "env", "2030-01-01T22:00", "2030-01-02T02:00"}, true},
+		{"invalid_time", []string{"minion", "abc123", "tomorrow", "2030-01-02T02:00"}, true},
+		{"end_before_start", []string{"minion", "abc123", "2030-01-02T02:00", "2030-01-01T22:00"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := parseMaintenanceWindow(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMaintenanceWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && window.End <= window.Start {
+				t.Errorf("Expected end after start, got %+v", window)
+			}
+		})
+	}
+}
+
+func TestMaintenanceCommands(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("maintenance-add", []string{"tag", "env=prod", "2030-01-01T22:00", "2030-01-02T02:00"})
+	})
+	if !strings.Contains(output, "window-1") || len(mockClient.windows) != 1 {
+		t.Fatalf("Expected window to be added, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("maintenance-list", nil)
+	})
+	for _, expected := range []string{"window-1", "tag env=prod", "held commands: 3", "upcoming"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected list output to contain '%s', got: %s", expected, output)
+		}
+	}
+
+	captureOutput(func() {
+		console.handleCommand("maintenance-remove", []string{"window-1"})
+	})
+	if len(mockClient.windows) != 0 {
+		t.Error("Expected window to be removed")
+	}
+}
+
+func TestSendCommandEmergency(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"--emergency", "minion", "abc123", "uptime"})
+	})
+	if mockClient.lastRequest == nil || !mockClient.lastRequest.Emergency {
+		t.Fatal("Expected emergency flag to be sent to nexus")
+	}
+
+	captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"minion", "abc123", "uptime"})
+	})
+	if mockClient.lastRequest.Emergency {
+		t.Error("Expected emergency flag to be off by default")
+	}
+}
+
+func TestRenderStructured(t *testing.T) {
+	table, err := renderStructured(`[{"pid": 1, "command": "systemd"}, {"pid": 812, "command": "nginx"}]`)
+	if err != nil {
+		t.Fatalf("renderStructured failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(table, "\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(strings.TrimSpace(lines[0]), "PID") || !strings.Contains(lines[3], "812") {
+		t.Errorf("Unexpected table:\n%s", table)
+	}
+
+	object, err := renderStructured(`{"os": "linux", "goroutines": 12}`)
+	if err != nil {
+		t.Fatalf("renderStructured failed: %v", err)
+	}
+	if !strings.Contains(object, "os") || !strings.Contains(object, "linux") || strings.Index(object, "os") > strings.Index(object, "goroutines") {
+		t.Errorf("Unexpected object rendering:\n%s", object)
+	}
+
+	if _, err := renderStructured(`not json`); err == nil {
+		t.Error("Expected error for invalid payload")
+	}
+}
+
+func TestGetResultsStructured(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{
+		results: []*pb.CommandResult{{
+			CommandId:   "cmd-123",
+			MinionId:    "minion-1",
+			Stdout:      "OS: linux",
+			ContentType: "application/vnd.minexus.system-os+json",
+			Structured:  `{"os": "linux", "arch": "amd64"}`,
+		}},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		console := createMockConsole(mockClient)
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.getResults(context.Background(), []string{"cmd-123"})
+		})
+		if !strings.Contains(output, "system-os+json") || !strings.Contains(output, "amd64") {
+			t.Errorf("Expected structured rendering, got: %s", output)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		console := createMockConsole(mockClient)
+		defer console.Shutdown()
+		console.SetOutputFormat(OutputFormatJSON)
+
+		output := captureOutput(func() {
+			console.getResults(context.Background(), []string{"cmd-123"})
+		})
+
+		var decoded struct {
+			Results []struct {
+				ContentType string            `json:"content_type"`
+				Data        map[string]string `json:"data"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if len(decoded.Results) != 1 || decoded.Results[0].Data["arch"] != "amd64" {
+			t.Errorf("Unexpected structured JSON output: %s", output)
+		}
+	})
+}
+
+func TestGetResultsExecution(t *testing.T) {
+	execution := &pb.ExecutionInfo{WorkingDir: "/opt/app", User: "deploy", Path: "/usr/bin:/bin", DurationMs: 1500, MaxRssKb: 20480}
+	mockClient := &mockConsoleServiceClient{
+		results: []*pb.CommandResult{
+			{CommandId: "cmd-123", MinionId: "minion-1", ExitCode: 127, Stderr: "make: not found", Execution: execution},
+			{CommandId: "cmd-123", MinionId: "minion-2", Stdout: "built", Execution: &pb.ExecutionInfo{WorkingDir: "/srv", DurationMs: 200}},
+		},
+	}
+
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+	output := captureOutput(func() {
+		console.getResults(context.Background(), []string{"cmd-123"})
+	})
+	if !strings.Contains(output, "minion-1 execution: in /opt/app, as deploy, took 1.5s, max RSS 20.0 MiB, PATH=/usr/bin:/bin") ||
+		strings.Contains(output, "minion-2 execution") {
+		t.Errorf("Expected the execution of the failed result only, got: %s", output)
+	}
+
+	console.setOption([]string{"columns", "result-get", "minion,duration,user,workdir,max-rss"})
+	output = captureOutput(func() {
+		console.getResults(context.Background(), []string{"cmd-123"})
+	})
+	if !strings.Contains(output, "Working Dir") || !strings.Contains(output, "200ms") || !strings.Contains(output, "20.0 MiB") {
+		t.Errorf("Expected the execution columns, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.getResults(context.Background(), []string{"cmd-123"})
+	})
+	var decoded ResultListOutput
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+	}
+	if got := decoded.Results[0].Execution; got == nil || got.User != "deploy" || got.MaxRSSKB != 20480 || got.DurationMs != 1500 {
+		t.Errorf("Unexpected execution JSON: %s", output)
+	}
+}
+
+func TestInspectMinion(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.handleCommand("minion-inspect", []string{"abc123"})
+		})
+		for _, expected := range []string{"abc123", "CONNECTED (2 active)", "3/100", "STREAM_OPENED", "concurrent stream", "12.50 ms, up 80.00 Mbit/s, down 320.00 Mbit/s"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", expected, output)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{})
+		defer console.Shutdown()
+		console.SetOutputFormat(OutputFormatJSON)
+
+		output := captureOutput(func() {
+			console.handleCommand("minion-inspect", []string{"abc123"})
+		})
+		var decoded MinionDiagnosticsOutput
+		if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+		}
+		if decoded.MinionID != "abc123" || decoded.ChannelDepth != 3 || len(decoded.Events) != 1 || decoded.LastSpeedTest.DownloadMbps != 320 {
+			t.Errorf("Unexpected diagnostics output: %+v", decoded)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{returnError: true})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.handleCommand("minion-inspect", []string{"abc123"})
+		})
+		if !strings.Contains(output, "Error inspecting minion") {
+			t.Errorf("Expected error message, got: %s", output)
+		}
+	})
+}
+
+func TestAliasStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	store, err := LoadAliasStore(path)
+	if err != nil {
+		t.Fatalf("LoadAliasStore failed: %v", err)
+	}
+
+	if err := store.Set("deploy", `command-send tag role=web '{"command":"up"}'`); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	for _, name := range []string{"help", "command-send", "bad name", "1st", ""} {
+		if err := store.Set(name, "version"); err == nil {
+			t.Errorf("Expected error for alias name '%s'", name)
+		}
+	}
+	if err := store.Set("empty", "  "); err == nil {
+		t.Error("Expected error for an empty expansion")
+	}
+
+	// Aliases survive a reload
+	reloaded, err := LoadAliasStore(path)
+	if err != nil {
+		t.Fatalf("LoadAliasStore failed: %v", err)
+	}
+	if names := reloaded.Names(); len(names) != 1 || names[0] != "deploy" {
+		t.Fatalf("Expected persisted alias, got %v", names)
+	}
+
+	expanded, ok := reloaded.Expand("deploy")
+	if !ok {
+		t.Fatal("Expected alias to be expanded")
+	}
+	parts, err := util.ParseCommandLine(expanded)
+	if err != nil {
+		t.Fatalf("Failed to parse expansion: %v", err)
+	}
+	expected := []string{"command-send", "tag", "role=web", `{"command":"up"}`}
+	if strings.Join(parts, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %v, got %v", expected, parts)
+	}
+
+	if expanded, _ := reloaded.Expand("deploy --now"); expanded != `command-send tag role=web '{"command":"up"}' --now` {
+		t.Errorf("Expected arguments to be appended, got %q", expanded)
+	}
+	if line, ok := reloaded.Expand("deployer x"); ok || line != "deployer x" {
+		t.Errorf("Expected unknown word to be left untouched, got %q", line)
+	}
+
+	if err := reloaded.Remove("deploy"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := reloaded.Remove("deploy"); err == nil {
+		t.Error("Expected error when removing an unknown alias")
+	}
+	if store, _ := LoadAliasStore(path); len(store.Names()) != 0 {
+		t.Error("Expected removal to be persisted")
+	}
+}
+
+func TestAliasStoreInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAliasStore(path); err == nil {
+		t.Error("Expected error for an invalid alias file")
+	}
+}
+
+func TestDisplayStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "display.json")
+	store, err := LoadDisplayStore(path)
+	if err != nil {
+		t.Fatalf("LoadDisplayStore failed: %v", err)
+	}
+	if store.TimeFormat() != TimeFormatLocal || len(store.Columns("minion-list")) != 10 {
+		t.Fatalf("Expected the defaults, got %s %v", store.TimeFormat(), store.Columns("minion-list"))
+	}
+
+	if err := store.SetTimeFormat("utc"); err != nil {
+		t.Fatalf("SetTimeFormat failed: %v", err)
+	}
+	if err := store.SetColumns("minion-list", []string{"id", "region"}); err != nil {
+		t.Fatalf("SetColumns failed: %v", err)
+	}
+	if err := store.SetTimeFormat("epoch"); err == nil {
+		t.Error("Expected error for an unknown time format")
+	}
+	if err := store.SetColumns("minion-list", []string{"id", "uptime"}); err == nil {
+		t.Error("Expected error for an unknown column")
+	}
+	if err := store.SetColumns("tag-list", []string{"tag"}); err == nil {
+		t.Error("Expected error for an unknown table")
+	}
+
+	// Preferences survive a reload
+	reloaded, err := LoadDisplayStore(path)
+	if err != nil {
+		t.Fatalf("LoadDisplayStore failed: %v", err)
+	}
+	if reloaded.TimeFormat() != TimeFormatUTC || strings.Join(reloaded.Columns("minion-list"), ",") != "id,region" {
+		t.Errorf("Expected persisted preferences, got %s %v", reloaded.TimeFormat(), reloaded.Columns("minion-list"))
+	}
+	if err := reloaded.SetColumns("minion-list", nil); err != nil || len(reloaded.Columns("minion-list")) != 10 {
+		t.Errorf("Expected the default columns restored, got %v %v", reloaded.Columns("minion-list"), err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"time":"epoch"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDisplayStore(path); err == nil {
+		t.Error("Expected error for an invalid display file")
+	}
+}
+
+func TestDisplayPreferences(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{
+		minions: []*pb.HostInfo{
+			{Id: "abc123", Hostname: "testhost", Ip: "192.168.1.1", Region: "eu-west-1", LastSeen: time.Now().Add(-5 * time.Minute).Unix()},
+		},
+	}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	captureOutput(func() {
+		console.handleCommand("set", []string{"time", "relative"})
+		console.handleCommand("set", []string{"columns", "minion-list", "hostname,region,last-seen"})
+	})
+	output := captureOutput(func() {
+		console.listMinions(context.Background())
+	})
+	for _, expected := range []string{"Hostname | Region    | Last Seen", "testhost | eu-west-1 | 5m ago"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "abc123") {
+		t.Errorf("Expected the ID column hidden: %s", output)
+	}
+
+	captureOutput(func() {
+		console.handleCommand("set", []string{"time", "utc"})
+		console.handleCommand("set", []string{"columns", "result-get", "minion,time,output"})
+	})
+	output = captureOutput(func() {
+		console.printResultTable([]*pb.CommandResult{
+			{MinionId: "abc123", ExitCode: 1, Stdout: "ok", Stderr: "warning", Timestamp: 1640995200},
+		})
+	})
+	for _, expected := range []string{"Minion ID | Time                    | Output", "abc123    | 2022-01-01 00:00:00 UTC | ok", "STDERR: warning"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Exit Code") {
+		t.Errorf("Expected the exit code column hidden: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("set", []string{"columns", "result-get", "minion,size"})
+	})
+	if !strings.Contains(output, "unknown column 'size'") {
+		t.Errorf("Expected unknown column error, got: %s", output)
+	}
+	output = captureOutput(func() {
+		console.handleCommand("set", []string{})
+	})
+	for _, expected := range []string{"time = utc", "columns result-get = minion,time,output"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in settings: %s", expected, output)
+		}
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{now.Add(-2 * time.Hour), "2h ago"},
+		{now, "Just now"},
+		{now.Add(90 * time.Minute), "in 1h"},
+		{now.Add(3*24*time.Hour + time.Minute), "in 3d"},
+	}
+	for _, tt := range tests {
+		if got := formatRelativeTime(tt.t, now); got != tt.expected {
+			t.Errorf("formatRelativeTime(%v) = %s, expected %s", tt.t.Sub(now), got, tt.expected)
+		}
+	}
+}
+
+func TestAliasCommands(t *testing.T) {
+	console := createMockConsole(&mockConsoleServiceClient{})
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("alias", nil)
+	})
+	if !strings.Contains(output, "Aliases are not available") {
+		t.Errorf("Expected error without alias store, got: %s", output)
+	}
+
+	store, _ := LoadAliasStore(filepath.Join(t.TempDir(), "aliases.json"))
+	console.SetAliases(store)
+
+	// The parser hands the quoted definition as a single argument
+	args, _ := util.ParseCommandLine(`alias info="command-send all system:info"`)
+	captureOutput(func() {
+		console.handleCommand("alias", args[1:])
+	})
+	if expansion, ok := store.Get("info"); !ok || expansion != "command-send all system:info" {
+		t.Fatalf("Expected alias to be defined, got %q", expansion)
+	}
+	if line := console.expandAlias("info"); line != "command-send all system:info" {
+		t.Errorf("Expected expanded line, got %q", line)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("alias-list", nil)
+	})
+	if !strings.Contains(output, "info=command-send all system:info") {
+		t.Errorf("Expected alias in list, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("alias-list", nil)
+	})
+	var decoded AliasListOutput
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+	}
+	if decoded.Count != 1 || decoded.Aliases["info"] != "command-send all system:info" {
+		t.Errorf("Unexpected alias list output: %+v", decoded)
+	}
+	console.SetOutputFormat(OutputFormatText)
+
+	captureOutput(func() {
+		console.handleCommand("alias-remove", []string{"info"})
+	})
+	if _, ok := store.Get("info"); ok {
+		t.Error("Expected alias to be removed")
+	}
+}
+
+func TestSendCommandPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected pb.CommandPriority
+		wantErr  bool
+	}{
+		{"default", []string{"minion", "abc123", "uptime"}, pb.CommandPriority_NORMAL, false},
+		{"separate_value", []string{"--priority", "high", "minion", "abc123", "uptime"}, pb.CommandPriority_HIGH, false},
+		{"inline_value", []string{"--priority=LOW", "all", "pkg:list"}, pb.CommandPriority_LOW, false},
+		{"invalid", []string{"--priority", "urgent", "all", "uptime"}, pb.CommandPriority_NORMAL, true},
+		{"missing_value", []string{"--priority"}, pb.CommandPriority_NORMAL, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+			console := createMockConsole(mockClient)
+			defer console.Shutdown()
+
+			output := captureOutput(func() {
+				console.sendCommand(context.Background(), tt.args)
+			})
+			if tt.wantErr {
+				if mockClient.lastRequest != nil {
+					t.Errorf("Expected no request to be sent, output: %s", output)
+				}
+				return
+			}
+			if mockClient.lastRequest == nil || mockClient.lastRequest.Priority != tt.expected {
+				t.Fatalf("Expected priority %s, got request %v", tt.expected, mockClient.lastRequest)
+			}
+		})
+	}
+}
+
+func TestSendCommandLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		wantErr  bool
+	}{
+		{"none", []string{"minion", "abc123", "uptime"}, "", false},
+		{"separate_value", []string{"--lock", "deploy", "tag", "role=web", "/opt/app/deploy.sh"}, "deploy", false},
+		{"inline_value", []string{"--lock=db.backup", "all", "uptime"}, "db.backup", false},
+		{"invalid", []string{"--lock", "bad/name", "all", "uptime"}, "", true},
+		{"missing_value", []string{"--lock"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+			console := createMockConsole(mockClient)
+			defer console.Shutdown()
+
+			output := captureOutput(func() {
+				console.sendCommand(context.Background(), tt.args)
+			})
+			if tt.wantErr {
+				if mockClient.lastRequest != nil {
+					t.Errorf("Expected no request to be sent, output: %s", output)
+				}
+				return
+			}
+			if mockClient.lastRequest == nil || mockClient.lastRequest.Lock != tt.expected {
+				t.Fatalf("Expected lock %q, got request %v", tt.expected, mockClient.lastRequest)
+			}
+		})
+	}
+}
+
+func TestSendCommandImpact(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		wantErr  bool
+	}{
+		{"none", []string{"minion", "abc123", "uptime"}, "", false},
+		{"separate_value", []string{"--impact", "disruptive", "all", "systemctl restart nginx"}, "disruptive", false},
+		{"inline_value", []string{"--impact=read-only", "all", "uptime"}, "read-only", false},
+		{"invalid", []string{"--impact", "harmless", "all", "uptime"}, "", true},
+		{"missing_value", []string{"--impact"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+			console := createMockConsole(mockClient)
+			defer console.Shutdown()
+
+			output := captureOutput(func() {
+				console.sendCommand(context.Background(), tt.args)
+			})
+			if tt.wantErr {
+				if mockClient.lastRequest != nil {
+					t.Errorf("Expected no request to be sent, output: %s", output)
+				}
+				return
+			}
+			if mockClient.lastRequest == nil || mockClient.lastRequest.Impact != tt.expected {
+				t.Fatalf("Expected impact %q, got request %v", tt.expected, mockClient.lastRequest)
+			}
+		})
+	}
+}
+
+func TestSendCommandRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected *pb.RetryPolicy
+		wantErr  bool
+	}{
+		{"none", []string{"minion", "abc123", "uptime"}, nil, false},
+		{"attempts", []string{"--retry", "3", "all", "apt-get update"}, &pb.RetryPolicy{MaxAttempts: 3}, false},
+		{"full", []string{"--retry=4", "--retry-backoff", "1m", "--retry-on", "75,100", "all", "apt-get update"},
+			&pb.RetryPolicy{MaxAttempts: 4, BackoffSeconds: 60, ExitCodes: []int32{75, 100}}, false},
+		{"invalid_attempts", []string{"--retry", "zero", "all", "uptime"}, nil, true},
+		{"invalid_backoff", []string{"--retry", "3", "--retry-backoff", "500ms", "all", "uptime"}, nil, true},
+		{"invalid_exit_code", []string{"--retry", "3", "--retry-on", "0", "all", "uptime"}, nil, true},
+		{"without_retry", []string{"--retry-on", "75", "all", "uptime"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+			console := createMockConsole(mockClient)
+			defer console.Shutdown()
+
+			output := captureOutput(func() {
+				console.sendCommand(context.Background(), tt.args)
+			})
+			if tt.wantErr {
+				if mockClient.lastRequest != nil {
+					t.Errorf("Expected no request to be sent, output: %s", output)
+				}
+				return
+			}
+			if mockClient.lastRequest == nil || !proto.Equal(mockClient.lastRequest.Retry, tt.expected) {
+				t.Fatalf("Expected retry policy %v, got request %v", tt.expected, mockClient.lastRequest)
+			}
+			if tt.expected != nil && !strings.Contains(output, fmt.Sprintf("Retry: up to %d attempts", tt.expected.MaxAttempts)) {
+				t.Errorf("Expected the retry policy shown, got: %s", output)
+			}
+		})
+	}
+}
+
+func TestSendCommandSample(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	for _, args := range [][]string{{"--sample", "0", "all", "uptime"}, {"--sample=many", "all", "uptime"}} {
+		captureOutput(func() { console.sendCommand(context.Background(), args) })
+		if mockClient.lastRequest != nil {
+			t.Fatalf("Expected %v rejected", args)
+		}
+	}
+
+	output := captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"--sample", "50", "all", "uptime"})
+	})
+	if mockClient.lastRequest == nil || mockClient.lastRequest.Sample != 50 {
+		t.Fatalf("Expected a sample of 50 sent, got %v", mockClient.lastRequest)
+	}
+	if !strings.Contains(output, "Sample: full output stored for 50 successful minions") {
+		t.Errorf("Expected the sample shown, got: %s", output)
+	}
+
+	mockClient.results = []*pb.CommandResult{{CommandId: "cmd-123", MinionId: "minion-1", Stdout: "up 3 days"}}
+	mockClient.omittedResults = 1200
+	output = captureOutput(func() { console.getResults(context.Background(), []string{"cmd-123"}) })
+	if !strings.Contains(output, "1200 more minions succeeded") {
+		t.Errorf("Expected the omitted results counted, got: %s", output)
+	}
+}
+
+func TestSendCommandTopology(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected *pb.TopologySelector
+		wantErr  bool
+	}{
+		{"datacenter", []string{"dc=eu-west-1a", "uptime"}, &pb.TopologySelector{Datacenter: "eu-west-1a"}, false},
+		{"combined", []string{"region=eu-west-1,datacenter=eu-west-1a,rack=r12", "uptime"},
+			&pb.TopologySelector{Region: "eu-west-1", Datacenter: "eu-west-1a", Rack: "r12"}, false},
+		{"unknown_domain", []string{"dc=eu-west-1a,row=3", "uptime"}, nil, true},
+		{"missing_value", []string{"rack=", "uptime"}, nil, true},
+		{"missing_command", []string{"region=eu-west-1"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+			console := createMockConsole(mockClient)
+			defer console.Shutdown()
+
+			output := captureOutput(func() {
+				console.sendCommand(context.Background(), tt.args)
+			})
+			if tt.wantErr {
+				if mockClient.lastRequest != nil {
+					t.Errorf("Expected no request to be sent, output: %s", output)
+				}
+				return
+			}
+			if mockClient.lastRequest == nil || !proto.Equal(mockClient.lastRequest.Topology, tt.expected) {
+				t.Fatalf("Expected topology %v, got request %v", tt.expected, mockClient.lastRequest)
+			}
+		})
+	}
+}
+
+func TestResultPager(t *testing.T) {
+	results := []*pb.CommandResult{
+		{MinionId: "m1", Stdout: "line 1\nline 2\nline 3\nerror here\nline 5\n", Stderr: "warning\n"},
+		{MinionId: "m2", ExitCode: 1, Stderr: "boom\n"},
+	}
+	pager := newResultPager(results, 3, 40)
+
+	pager.handleKey("G")
+	if pager.top != 3 {
+		t.Errorf("Expected the last page to start at line 3, got %d", pager.top)
+	}
+	pager.handleKey("down")
+	if pager.top != 3 {
+		t.Errorf("Expected scrolling to stop at the end, got %d", pager.top)
+	}
+	pager.handleKey("g")
+	for _, key := range []string{"/", "e", "r", "r", "\r"} {
+		pager.handleKey(key)
+	}
+	if pager.search != "err" || pager.top != 3 {
+		t.Errorf("Expected the search to move to line 3, got %q at %d", pager.search, pager.top)
+	}
+
+	pager.handleKey("s")
+	if lines := pager.lines(); len(lines) != 1 || lines[0] != "warning" {
+		t.Errorf("Expected the stderr lines, got %v", lines)
+	}
+	pager.handleKey("s")
+	if lines := pager.lines(); len(lines) != 5 || !strings.HasPrefix(lines[0], "line 1") || !strings.HasSuffix(lines[0], " | warning") {
+		t.Errorf("Expected stdout and stderr side by side, got %v", lines)
+	}
+
+	pager.handleKey("l")
+	if pager.current != 1 || pager.top != 0 {
+		t.Errorf("Expected the second minion from its first line, got %d at %d", pager.current, pager.top)
+	}
+	pager.handleKey("l")
+	if pager.current != 1 || pager.message == "" {
+		t.Errorf("Expected to stay on the last minion, got %d", pager.current)
+	}
+
+	var screen bytes.Buffer
+	pager.render(&screen)
+	if !strings.Contains(screen.String(), "m2 (2/2) exit 1") {
+		t.Errorf("Expected the status line of m2, got %q", screen.String())
+	}
+	if pager.handleKey("q") {
+		t.Error("Expected q to close the viewer")
+	}
+}
+
+func TestParsePagerKeys(t *testing.T) {
+	keys := parsePagerKeys([]byte("j\x1b[B\x1b[6~é\x1b"))
+	expected := []string{"j", "down", "pgdn", "é", "\x1b"}
+	if strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected keys %q, got %q", expected, keys)
+	}
+}
+
+func TestViewResultsWithoutTerminal(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{results: []*pb.CommandResult{
+		{MinionId: "m1", Stdout: strings.Repeat("x", 100) + "\nend\n"},
+		{MinionId: "m2", Stderr: "failed\n", ExitCode: 2},
+	}}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	// Without a terminal the full output is printed
+	output := captureOutput(func() {
+		console.viewResults(context.Background(), []string{"cmd-123", "m2"})
+	})
+	if !strings.Contains(output, "=== m2 (exit 2) ===") || !strings.Contains(output, "failed") || strings.Contains(output, "m1") {
+		t.Errorf("Expected the output of m2 only, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.viewResults(context.Background(), []string{"cmd-123", "m3"})
+	})
+	if !strings.Contains(output, "No result of minion m3") {
+		t.Errorf("Expected unknown minion error, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.getResults(context.Background(), []string{"cmd-123"})
+	})
+	if !strings.Contains(output, "result-view cmd-123") {
+		t.Errorf("Expected result-get to point to result-view, got: %s", output)
+	}
+}
+
+func TestLocalMode(t *testing.T) {
+	console := NewLocalConsole(zap.NewNop(), nil)
+	defer console.Shutdown()
+
+	// Target is optional in local mode
+	output := captureOutput(func() {
+		console.handleCommand("command-send", []string{"system:os"})
+	})
+	if !strings.Contains(output, "Command executed locally") || !strings.Contains(output, localMinionID) {
+		t.Fatalf("Expected local execution, got: %s", output)
+	}
+	if len(console.local.results) != 1 {
+		t.Fatalf("Expected 1 local result, got %d", len(console.local.results))
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("command-send", []string{"all", "system:os"})
+	})
+	if !strings.Contains(output, "Command executed locally") {
+		t.Errorf("Expected local execution with target, got: %s", output)
+	}
+
+	var commandID string
+	for id := range console.local.results {
+		commandID = id
+	}
+	output = captureOutput(func() {
+		console.handleCommand("result-get", []string{commandID})
+	})
+	if !strings.Contains(output, "Command results (1)") {
+		t.Errorf("Expected local result, got: %s", output)
+	}
+
+	// Commands relying on Nexus are rejected
+	output = captureOutput(func() {
+		console.handleCommand("minion-list", nil)
+	})
+	if !strings.Contains(output, "not available in local mode") {
+		t.Errorf("Expected minion-list to be rejected, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("command-send", []string{"--dry-run", "all", "system:os"})
+	})
+	if !strings.Contains(output, "would run locally") {
+		t.Errorf("Expected dry run output, got: %s", output)
+	}
+}
+
+func TestReportCommands(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("report-create", []string{"env-failures", "SELECT count, failure_rate BY tag:env SINCE $period", "Failures", "by", "env"})
+	})
+	if !strings.Contains(output, "Report env-failures saved") {
+		t.Errorf("Expected report to be saved, got: %s", output)
+	}
+	if len(mockClient.reports) != 1 || mockClient.reports[0].Description != "Failures by env" {
+		t.Fatalf("Unexpected saved reports: %v", mockClient.reports)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("report-list", nil)
+	})
+	if !strings.Contains(output, "env-failures: SELECT count, failure_rate BY tag:env SINCE $period") {
+		t.Errorf("Expected report in list, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("report-run", []string{"env-failures", "period=7d"})
+	})
+	if mockClient.lastReport.Params["period"] != "7d" {
+		t.Errorf("Expected period parameter, got %v", mockClient.lastReport.Params)
+	}
+	if !strings.Contains(output, "tag:env | count | failure_rate") || !strings.Contains(output, "staging | 120   | 0.0%") {
+		t.Errorf("Expected aligned report table, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("report-run", []string{"env-failures", "period"})
+	})
+	if !strings.Contains(output, "invalid parameter") {
+		t.Errorf("Expected invalid parameter error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("report-run", []string{"env-failures", "period=7d"})
+	})
+	var result ReportResultOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if len(result.Rows) != 2 || result.Rows[0][0] != "prod" {
+		t.Errorf("Unexpected JSON report: %+v", result)
+	}
+}
+
+func TestDatabaseCheck(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("db-check", nil)
+	})
+	if mockClient.lastDBCheck == nil || mockClient.lastDBCheck.Repair {
+		t.Fatalf("Expected a check without repair, got %v", mockClient.lastDBCheck)
+	}
+	if !strings.Contains(output, "orphaned_results") || !strings.Contains(output, "db-check --repair") {
+		t.Errorf("Expected inconsistencies to be reported, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("db-check", []string{"--repair"})
+	})
+	if !mockClient.lastDBCheck.Repair || !strings.Contains(output, "Database is consistent") {
+		t.Errorf("Expected repaired database, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("db-check", []string{"--force"})
+	})
+	if !strings.Contains(output, "usage: db-check") {
+		t.Errorf("Expected usage error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("db-check", []string{"--repair"})
+	})
+	var result DatabaseCheckOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if !result.Healthy || len(result.Checks) != 1 || result.Checks[0].Repaired != 3 {
+		t.Errorf("Unexpected JSON report: %+v", result)
+	}
+}
+
+func TestMinionHistory(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("minion-history", []string{"abc123", "5"})
+	})
+	if mockClient.lastHistory == nil || mockClient.lastHistory.MinionId != "abc123" || mockClient.lastHistory.Limit != 5 {
+		t.Fatalf("Unexpected history request: %v", mockClient.lastHistory)
+	}
+	for _, expected := range []string{"Last commands on minion abc123 (2)", "apt-get update", "FAILED", "100", "4.2s"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("minion-history", []string{"abc123", "zero"})
+	})
+	if !strings.Contains(output, "Invalid count") {
+		t.Errorf("Expected invalid count error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("minion-history", []string{"abc123"})
+	})
+	var result MinionHistoryOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Count != 2 || result.Commands[0].ExitCode != nil || result.Commands[1].ExitCode == nil || *result.Commands[1].ExitCode != 100 {
+		t.Errorf("Unexpected JSON history: %+v", result)
+	}
+}
+
+func TestMinionUptime(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("minion-uptime", []string{"abc123", "7d"})
+	})
+	if mockClient.lastUptime == nil || mockClient.lastUptime.MinionId != "abc123" || mockClient.lastUptime.WindowSeconds != 7*24*3600 {
+		t.Fatalf("Unexpected uptime request: %v", mockClient.lastUptime)
+	}
+	timeline := "[" + strings.Repeat("#", 15) + strings.Repeat(".", 15) + strings.Repeat("#", 30) + "]"
+	for _, expected := range []string{"Uptime of minion abc123", "75.00%", timeline, "EOF", "6h0m0s", "connected"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	for _, window := range []string{"0d", "soon", "10s"} {
+		output = captureOutput(func() {
+			console.handleCommand("minion-uptime", []string{"abc123", window})
+		})
+		if !strings.Contains(output, "invalid window") {
+			t.Errorf("Expected invalid window error for %q, got: %s", window, output)
+		}
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("minion-uptime", []string{"abc123"})
+	})
+	var result MinionUptimeOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if mockClient.lastUptime.WindowSeconds != 0 || result.UptimePercent != 75 || len(result.Periods) != 2 || result.Periods[1].DisconnectedAt != 0 {
+		t.Errorf("Unexpected JSON uptime: %+v", result)
+	}
+}
+
+func TestCrashList(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("crash-list", []string{"abc123", "--limit", "5"})
+	})
+	if mockClient.lastCrashList == nil || mockClient.lastCrashList.MinionId != "abc123" || mockClient.lastCrashList.Limit != 5 {
+		t.Fatalf("Unexpected crash list request: %v", mockClient.lastCrashList)
+	}
+	for _, expected := range []string{"abc123", "v1.2.3", "command cmd-1: docker:ps", "index out of range"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "goroutine 42") {
+		t.Errorf("Expected the stack trace only with --stack: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("crash-list", []string{"--stack"})
+	})
+	if mockClient.lastCrashList.MinionId != "" || !strings.Contains(output, "goroutine 42 [running]") {
+		t.Errorf("Expected every minion with stack traces, got %v: %s", mockClient.lastCrashList, output)
+	}
+
+	for _, args := range [][]string{{"--limit", "0"}, {"--limit"}, {"a", "b"}, {"--verbose"}} {
+		output = captureOutput(func() {
+			console.handleCommand("crash-list", args)
+		})
+		if !strings.Contains(output, "limit") && !strings.Contains(output, "usage") {
+			t.Errorf("Expected an error for %v, got: %s", args, output)
+		}
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("crash-list", nil)
+	})
+	var result CrashListOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Count != 1 || result.Crashes[0].Stack == "" || result.Crashes[0].MinionID != "abc123" {
+		t.Errorf("Unexpected JSON crash list: %+v", result)
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("env-set", []string{"tag", "env=prod", "HTTP_PROXY=http://proxy:3128?a=b"})
+		console.handleCommand("env-set", []string{"minion", "abc123", "MIRROR=mirror.local"})
+	})
+	if len(mockClient.envVars) != 2 || !strings.Contains(output, "HTTP_PROXY set for the shell commands of tag env=prod") {
+		t.Fatalf("Unexpected variables %v: %s", mockClient.envVars, output)
+	}
+	if envVar := mockClient.envVars[0]; envVar.TagKey != "env" || envVar.TagValue != "prod" || envVar.Value != "http://proxy:3128?a=b" {
+		t.Errorf("Unexpected tag variable: %v", envVar)
+	}
+
+	for _, args := range [][]string{{"tag", "env", "A=b"}, {"host", "abc123", "A=b"}, {"minion", "abc123", "A"}, {"minion", "abc123"}} {
+		output = captureOutput(func() {
+			console.handleCommand("env-set", args)
+		})
+		if !strings.Contains(output, "usage") && !strings.Contains(output, "format") && !strings.Contains(output, "invalid") {
+			t.Errorf("Expected an error for %v, got: %s", args, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("env-list", nil)
+	})
+	for _, expected := range []string{"tag env=prod", "HTTP_PROXY", "minion abc123", "mirror.local"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("env-unset", []string{"minion", "abc123", "MIRROR"})
+	})
+	if len(mockClient.envVars) != 1 || !strings.Contains(output, "MIRROR unset") {
+		t.Errorf("Expected the variable removed, got %v: %s", mockClient.envVars, output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("env-list", nil)
+	})
+	var result CommandEnvListOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Count != 1 || result.Vars[0].Tag != "env=prod" || result.Vars[0].MinionID != "" {
+		t.Errorf("Unexpected JSON variables: %+v", result)
+	}
+}
+
+func TestMinionLogs(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("minion-logs", []string{"abc123", "--level", "warn", "--since", "2h", "--limit", "50"})
+	})
+	req := mockClient.lastLogs
+	if req == nil || req.MinionId != "abc123" || req.MinLevel != "warn" || req.Limit != 50 || req.Since == 0 {
+		t.Fatalf("Unexpected logs request: %v", req)
+	}
+	// The oldest entry is shown first
+	if first, second := strings.Index(output, "Stream timeout"), strings.Index(output, "Command failed"); first < 0 || second < first {
+		t.Errorf("Expected the entries oldest first, got: %s", output)
+	}
+	if !strings.Contains(output, `{"command_id":"cmd-2"}`) || !strings.Contains(output, "minion/processor.go:120") {
+		t.Errorf("Expected the caller and fields in output: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("minion-logs", []string{"abc123", "--limit", "none"})
+	})
+	if !strings.Contains(output, "invalid limit") {
+		t.Errorf("Expected invalid limit error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("minion-logs", []string{"abc123"})
+	})
+	var result MinionLogsOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Count != 2 || result.Entries[0].Level != "error" || result.Entries[1].Fields != "" {
+		t.Errorf("Unexpected JSON output: %+v", result)
+	}
+}
+
+func TestMinionRetire(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	// Without a terminal the prompt can't be answered: the minion is not retired
+	output := captureOutput(func() {
+		console.handleCommand("minion-retire", []string{"abc123", "--reason", "hardware refresh"})
+	})
+	if mockClient.lastRetire != nil {
+		t.Fatalf("Unconfirmed retirement must not be sent, got %v", mockClient.lastRetire)
+	}
+	if !strings.Contains(output, `minion-retire abc123 --reason "hardware refresh" --yes`) {
+		t.Errorf("Expected the command to retire without prompt, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("minion-retire", []string{"abc123", "--uninstall", "--reason", "hardware refresh", "--yes"})
+	})
+	req := mockClient.lastRetire
+	if req == nil || req.MinionId != "abc123" || !req.Uninstall || req.Reason != "hardware refresh" {
+		t.Fatalf("Unexpected retire request: %v", req)
+	}
+	if !strings.Contains(output, "Minion abc123 retired: 42 results archived, 1 queued commands dropped") || !strings.Contains(output, "next registration") {
+		t.Errorf("Unexpected output: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("minion-retire", []string{"abc123", "--force"})
+	})
+	if !strings.Contains(output, "usage: minion-retire") {
+		t.Errorf("Expected usage error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("minion-retire", []string{"abc123", "--yes"})
+	})
+	var result RetireMinionOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.MinionID != "abc123" || result.Notified || result.ArchivedResults != 42 {
+		t.Errorf("Unexpected JSON output: %+v", result)
+	}
+}
+
+func TestTraceGet(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("trace-get", []string{"trace-1"})
+	})
+	for _, expected := range []string{"Trace trace-1 (3 events", "DISPATCHED", "+150ms", "+2.4s", "exit code 0"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("trace-get", nil)
+	})
+	if !strings.Contains(output, "Usage: trace-get") {
+		t.Errorf("Expected usage error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("trace-get", []string{"trace-1"})
+	})
+	var result TraceOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if len(result.Events) != 3 || result.Events[2].OffsetMs != 2400 || result.Events[1].Component != "minion" {
+		t.Errorf("Unexpected JSON trace: %+v", result)
+	}
+}
+
+func TestResultExport(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	for i := 0; i < exportPageSize+2; i++ {
+		mockClient.results = append(mockClient.results, &pb.CommandResult{
+			CommandId: "cmd-123",
+			MinionId:  fmt.Sprintf("minion-%d", i),
+			ExitCode:  int32(i % 2),
+			Stdout:    "line1\nline2, with comma",
+			Timestamp: 1640995200,
+		})
+	}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "results.csv")
+	output := captureOutput(func() {
+		console.handleCommand("result-export", []string{"cmd-123", "--format", "csv", "--out", csvFile})
+	})
+	if !strings.Contains(output, fmt.Sprintf("Exported %d results", exportPageSize+2)) {
+		t.Errorf("Expected export confirmation, got: %s", output)
+	}
+
+	file, err := os.Open(csvFile)
+	if err != nil {
+		t.Fatalf("Export file not written: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV export: %v", err)
+	}
+	// All pages are exported, with the header
+	if len(rows) != exportPageSize+3 {
+		t.Fatalf("Expected %d rows, got %d", exportPageSize+3, len(rows))
+	}
+	if strings.Join(rows[0], ",") != "command_id,minion_id,exit_code,timestamp,stdout,stderr" {
+		t.Errorf("Unexpected header: %v", rows[0])
+	}
+	last := rows[len(rows)-1]
+	if last[1] != fmt.Sprintf("minion-%d", exportPageSize+1) || last[2] != "1" || last[3] != "2022-01-01T00:00:00Z" || last[4] != "line1\nline2, with comma" {
+		t.Errorf("Unexpected last row: %q", last)
+	}
+
+	// The format is deduced from the file extension
+	jsonFile := filepath.Join(dir, "results.json")
+	captureOutput(func() {
+		console.handleCommand("result-export", []string{"cmd-123", "--out", jsonFile})
+	})
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Export file not written: %v", err)
+	}
+	var exported ResultListOutput
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Invalid JSON export: %v", err)
+	}
+	if exported.Count != exportPageSize+2 || exported.Results[1].ExitCode != 1 {
+		t.Errorf("Unexpected JSON export: count %d", exported.Count)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("result-export", []string{"cmd-123", "--format", "xml"})
+	})
+	if !strings.Contains(output, "invalid format 'xml'") {
+		t.Errorf("Expected invalid format error, got: %s", output)
+	}
+}
+
+func TestCommandSendConfirmation(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{
+		commandAccepted: true,
+		commandID:       "cmd-123",
+		confirmToken:    "tok123",
+	}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	// Without a terminal the prompt can't be answered: the command is not sent
+	output := captureOutput(func() {
+		console.handleCommand("command-send", []string{"minion", "web-01", "rm -rf /var/cache/app"})
+	})
+	if !strings.Contains(output, "Destructive command (recursive delete) targeting 1 minion(s): web-01") {
+		t.Errorf("Expected destructive command warning, got: %s", output)
+	}
+	if !strings.Contains(output, `command-send --confirm tok123 minion web-01 "rm -rf /var/cache/app"`) {
+		t.Errorf("Expected confirmation hint, got: %s", output)
+	}
+	if strings.Contains(output, "dispatched successfully") {
+		t.Errorf("Unconfirmed command must not be dispatched, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("command-send", []string{"--confirm", "tok123", "minion", "web-01", "rm -rf /var/cache/app"})
+	})
+	if mockClient.lastRequest.ConfirmToken != "tok123" || !strings.Contains(output, "Command dispatched successfully. Command ID: cmd-123") {
+		t.Errorf("Expected confirmed command to be dispatched, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("command-send", []string{"--dry-run", "minion", "web-01", "rm -rf /var/cache/app"})
+	})
+	if !strings.Contains(output, "confirm it within 5 minutes with --confirm tok123") {
+		t.Errorf("Expected dry run to show the confirm token, got: %s", output)
+	}
+}
+
+// mockShellClient is an OpenShell client stream replaying canned minion messages
+type mockShellClient struct {
+	grpc.ClientStream
+	mu       sync.Mutex
+	sent     []*pb.ShellMessage
+	messages []*pb.ShellMessage
+}
+
+func (m *mockShellClient) Send(msg *pb.ShellMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mockShellClient) Recv() (*pb.ShellMessage, error) {
+	if len(m.messages) == 0 {
+		return nil, io.EOF
+	}
+	msg := m.messages[0]
+	m.messages = m.messages[1:]
+	return msg, nil
+}
+
+func (m *mockShellClient) CloseSend() error { return nil }
+
+func (m *mockConsoleServiceClient) OpenShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[pb.ShellMessage, pb.ShellMessage], error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return m.shell, nil
+}
+
+func TestShellCommand(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("shell", nil)
+	})
+	if !strings.Contains(output, "Usage: shell <minion-id>") {
+		t.Errorf("Expected usage, got: %s", output)
+	}
+
+	mockClient.shell = &mockShellClient{messages: []*pb.ShellMessage{
+		{SessionId: "s1", Open: true},
+		{SessionId: "s1", Data: []byte("hello from minion\n")},
+		{SessionId: "s1", Close: true, ExitCode: 2},
+	}}
+	output = captureOutput(func() {
+		console.handleCommand("shell", []string{"web-01"})
+	})
+	if !strings.Contains(output, "Connected to web-01") || !strings.Contains(output, "hello from minion") {
+		t.Errorf("Expected shell output, got: %s", output)
+	}
+	if !strings.Contains(output, "Shell exited with code 2") {
+		t.Errorf("Expected exit code, got: %s", output)
+	}
+
+	mockClient.shell.mu.Lock()
+	if len(mockClient.shell.sent) == 0 || mockClient.shell.sent[0].MinionId != "web-01" {
+		t.Errorf("Expected the session to be opened on web-01, sent: %v", mockClient.shell.sent)
+	}
+	mockClient.shell.mu.Unlock()
+
+	mockClient.shell.messages = []*pb.ShellMessage{{SessionId: "s2", Open: true}, {Close: true, Error: "minion disconnected"}}
+	output = captureOutput(func() {
+		console.handleCommand("shell", []string{"web-01"})
+	})
+	if !strings.Contains(output, "Shell session ended: minion disconnected") {
+		t.Errorf("Expected session error, got: %s", output)
+	}
+}
+
+func TestSendCommandProgress(t *testing.T) {
+	progressPollInterval = time.Millisecond
+	defer func() { progressPollInterval = time.Second }()
+
+	minions := []*pb.HostInfo{{Id: "abc123"}, {Id: "def456"}}
+	statuses := func(first, second string) *pb.CommandStatusResponse {
+		return &pb.CommandStatusResponse{CommandId: "cmd-123", Statuses: []*pb.CommandStatusResponse_MinionStatus{
+			{MinionId: "abc123", Status: first},
+			{MinionId: "def456", Status: second},
+		}}
+	}
+
+	t.Run("follows_until_done", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{
+			minions:         minions,
+			commandAccepted: true,
+			commandID:       "cmd-123",
+			statusPolls:     []*pb.CommandStatusResponse{statuses("RECEIVED", "PENDING"), statuses("COMPLETED", "EXECUTING"), statuses("COMPLETED", "FAILED")},
+			results: []*pb.CommandResult{
+				{CommandId: "cmd-123", MinionId: "abc123", ExitCode: 0, Stdout: "ok"},
+				{CommandId: "cmd-123", MinionId: "def456", ExitCode: 2, Stderr: "boom"},
+			},
+		})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"all", "uptime"})
+		})
+
+		for _, expected := range []string{"Progress: 0/2 done", "received 1", "Progress: 2/2 done", "Summary for command cmd-123", "FAILED    | 2", "1 completed, 1 failed", "Results (2)"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain '%s', got: %s", expected, output)
+			}
+		}
+		if console.commandStatus["cmd-123"].Statuses["def456"] != "FAILED" {
+			t.Errorf("Expected tracked status to be updated, got %v", console.commandStatus["cmd-123"].Statuses)
+		}
+	})
+
+	t.Run("no_wait", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{
+			minions:         minions,
+			commandAccepted: true,
+			commandID:       "cmd-123",
+			statusPolls:     []*pb.CommandStatusResponse{statuses("COMPLETED", "COMPLETED")},
+		})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"--no-wait", "all", "uptime"})
+		})
+
+		if strings.Contains(output, "Progress:") || !strings.Contains(output, "No immediate results") {
+			t.Errorf("Expected no progress display with --no-wait, got: %s", output)
+		}
+	})
+
+	t.Run("status_unavailable", func(t *testing.T) {
+		console := createMockConsole(&mockConsoleServiceClient{minions: minions, commandAccepted: true, commandID: "cmd-123"})
+		defer console.Shutdown()
+
+		output := captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"all", "uptime"})
+		})
+
+		if strings.Contains(output, "Summary for command") || !strings.Contains(output, "No immediate results") {
+			t.Errorf("Expected fallback to immediate results, got: %s", output)
+		}
+	})
+}
+
+func TestSendCommandSigned(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	signer, err := loadSigner(&config.ConsoleConfig{})
+	if err != nil {
+		t.Fatalf("Failed to load signer: %v", err)
+	}
+	console.SetSigner(signer)
+
+	captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"minion", "abc123", "uptime"})
+	})
+
+	cmd := mockClient.lastRequest.Command
+	if len(cmd.Signature) == 0 || len(cmd.SignerCertificate) == 0 || cmd.SignedAt == 0 {
+		t.Fatalf("Expected a signed command, got %v", cmd)
+	}
+	verifier, err := certs.NewCommandVerifier(certs.CAPem)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	if err := verifier.Verify(cmd, "abc123"); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+
+	// Commands sent to a tag are signed for the minions it matches
+	captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"tag", "env=prod", "uptime"})
+	})
+	if targets := mockClient.lastRequest.Command.SignedTargets; len(targets) != 1 || targets[0] != "minion-1" {
+		t.Errorf("Expected the command signed for the matched minion, got %v", targets)
+	}
+
+	if _, err := loadSigner(&config.ConsoleConfig{SigningCert: "/nonexistent.crt", SigningKey: "/nonexistent.key"}); err == nil {
+		t.Error("Expected an error for missing signing files")
+	}
+}
+
+func TestConnectProfile(t *testing.T) {
+	console := createMockConsole(&mockConsoleServiceClient{})
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("connect", nil)
+	})
+	if !strings.Contains(output, "Profiles are not available") {
+		t.Errorf("Expected error without configuration, got: %s", output)
+	}
+
+	profileFile := filepath.Join(t.TempDir(), "profiles.json")
+	profiles := `{
+		"staging": {"server": "localhost:21973", "output": "json"},
+		"broken": {"server": "localhost:21973", "client_cert": "console.crt"}
+	}`
+	if err := os.WriteFile(profileFile, []byte(profiles), 0600); err != nil {
+		t.Fatalf("Failed to write profile file: %v", err)
+	}
+	console.SetConfig(&config.ConsoleConfig{ServerAddr: "localhost:11973", OutputFormat: OutputFormatText, Compression: "none", ProfileFile: profileFile})
+
+	output = captureOutput(func() {
+		console.handleCommand("connect", nil)
+	})
+	if !strings.Contains(output, "staging") || !strings.Contains(output, "localhost:21973") {
+		t.Errorf("Expected profiles in list, got: %s", output)
+	}
+
+	for _, name := range []string{"unknown", "broken"} {
+		output = captureOutput(func() {
+			console.handleCommand("connect", []string{name})
+		})
+		if console.config.Profile != "" || !strings.Contains(output, name) {
+			t.Errorf("Expected connecting with profile %s to fail, got: %s", name, output)
+		}
+	}
+
+	captureOutput(func() {
+		console.handleCommand("connect", []string{"staging"})
+	})
+	defer console.grpc.Close()
+	if console.config.Profile != "staging" || console.config.ServerAddr != "localhost:21973" {
+		t.Errorf("Expected the staging profile to be used, got %+v", console.config)
+	}
+	if !console.isJSONOutput() {
+		t.Error("Expected the output format of the profile")
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("connect", nil)
+	})
+	var decoded ProfileListOutput
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+	}
+	if decoded.Current != "staging" || decoded.Count != 2 || !decoded.Profiles[1].Current {
+		t.Errorf("Unexpected profile list output: %+v", decoded)
+	}
+}
+
+func TestTagSchemaShow(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("tag-schema-show", nil)
+	})
+	if !strings.Contains(output, "No tag schema configured") {
+		t.Errorf("Expected no schema, got: %s", output)
+	}
+
+	mockClient.tagSchema = &pb.TagSchema{
+		Enabled: true,
+		Keys: []*pb.TagSchema_Key{
+			{Key: "env", Description: "Deployment environment", AllowedValues: []string{"prod", "dev"}},
+			{Key: "role", Pattern: "[a-z]+"},
+		},
+	}
+	output = captureOutput(func() {
+		console.handleCommand("tag-schema-show", nil)
+	})
+	for _, expected := range []string{"Tag schema (2 keys)", "one of prod, dev", "Deployment environment", "matching [a-z]+", "Other keys are rejected"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output, got: %s", expected, output)
+		}
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("tag-schema-show", nil)
+	})
+	var decoded TagSchemaOutput
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+	}
+	if !decoded.Enabled || len(decoded.Keys) != 2 || decoded.Keys[1].Pattern != "[a-z]+" {
+		t.Errorf("Unexpected tag schema output: %+v", decoded)
+	}
+}
+
+func TestMinionBootstrapURL(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("minion-bootstrap-url", []string{"linux", "arm64", "--ttl", "30m"})
+	})
+	if mockClient.lastBootstrap.Os != "linux" || mockClient.lastBootstrap.Arch != "arm64" || mockClient.lastBootstrap.TtlSeconds != 1800 {
+		t.Errorf("Unexpected bootstrap request: %v", mockClient.lastBootstrap)
+	}
+	for _, expected := range []string{"linux/arm64", "https://nexus:8086/bootstrap/0123abcd/install.sh", "| sudo sh"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output, got: %s", expected, output)
+		}
+	}
+
+	mockClient.lastBootstrap = nil
+	output = captureOutput(func() {
+		console.handleCommand("minion-bootstrap-url", []string{"linux", "amd64", "--ttl", "soon"})
+	})
+	if mockClient.lastBootstrap != nil || !strings.Contains(output, "Invalid TTL") {
+		t.Errorf("Expected an invalid TTL error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("minion-bootstrap-url", []string{"windows", "amd64"})
+	})
+	var decoded BootstrapURLOutput
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+	}
+	if decoded.OS != "windows" || decoded.URL == "" || mockClient.lastBootstrap.TtlSeconds != 0 {
+		t.Errorf("Unexpected bootstrap output: %+v", decoded)
+	}
+}
+
+func TestDatabaseQuery(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("db-query", nil)
+	})
+	if !strings.Contains(output, "stale-minions age=<value>") || !strings.Contains(output, "Minions not seen for a while") {
+		t.Errorf("Expected the query list, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("db-query", []string{"stale-minions", "age=7 days"})
+	})
+	if mockClient.lastDBQuery.Name != "stale-minions" || mockClient.lastDBQuery.Params["age"] != "7 days" {
+		t.Errorf("Unexpected query request: %v", mockClient.lastDBQuery)
+	}
+	for _, expected := range []string{"id       | last_seen", "minion-1 | 2025-01-02T03:04:05Z", "Only the first 1 rows"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output, got: %s", expected, output)
+		}
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("db-query", []string{"stale-minions", "age=1h"})
+	})
+	var decoded DatabaseQueryResultOutput
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for output: %s", err, output)
+	}
+	if decoded.Name != "stale-minions" || len(decoded.Rows) != 1 || !decoded.Truncated {
+		t.Errorf("Unexpected query output: %+v", decoded)
+	}
+}
+
+func TestDecideCommandApproval(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("command-approve", []string{"cmd-1", "looks", "good"})
+	})
+	if mockClient.lastDecision == nil || mockClient.lastDecision.CommandId != "cmd-1" || mockClient.lastDecision.Reject || mockClient.lastDecision.Comment != "looks good" {
+		t.Fatalf("Unexpected approval decision: %v", mockClient.lastDecision)
+	}
+	if !strings.Contains(output, "approved and dispatched to 1 minion(s)") {
+		t.Errorf("Expected the approval reported, got: %s", output)
+	}
+
+	console.handleCommand("command-reject", []string{"cmd-2"})
+	if mockClient.lastDecision.CommandId != "cmd-2" || !mockClient.lastDecision.Reject {
+		t.Errorf("Expected cmd-2 rejected, got %v", mockClient.lastDecision)
+	}
+
+	mockClient.lastDecision = nil
+	console.handleCommand("command-approve", nil)
+	if mockClient.lastDecision != nil {
+		t.Error("Expected no decision sent without a command ID")
+	}
+}
+
+func TestCommandStats(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("stats", []string{"--since", "7d", "--command", "docker:*", "--top", "2"})
+	})
+	for _, expected := range []string{"commands matching docker:*", "Failed:    4 (10.0%)", "median 850ms, p90 4.2s", "minion-2", "disruptive          6        3    50.0%"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+	if req := mockClient.lastStats; req.CommandPattern != "docker:*" || req.Slowest != 2 || req.Until != 0 ||
+		time.Since(time.Unix(req.Since, 0)).Round(time.Hour) != 7*24*time.Hour {
+		t.Errorf("Unexpected stats request: %+v", req)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("stats", []string{"--top"})
+	})
+	if !strings.Contains(output, "usage: stats") {
+		t.Errorf("Expected usage error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("stats", nil)
+	})
+	var result CommandStatsOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Successes != 36 || len(result.SlowestMinions) != 2 || result.SlowestMinions[0].MedianMs != 5100 ||
+		len(result.ByImpact) != 2 || result.ByImpact[1].Failures != 3 {
+		t.Errorf("Unexpected JSON stats: %+v", result)
+	}
+}
+
+func TestFleetHealth(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("fleet-health", []string{"--below", "75"})
+	})
+	for _, expected := range []string{"10 minions, average score 91", "Unhealthy: 1", "Anomalous minions (2)", "frequent-reconnects, high-failure-rate", "4/6", "1m35s"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+	if mockClient.lastFleetHealth.Below != 75 {
+		t.Errorf("Unexpected fleet health request: %+v", mockClient.lastFleetHealth)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("fleet-health", []string{"--below", "high"})
+	})
+	if !strings.Contains(output, "invalid score") {
+		t.Errorf("Expected an invalid score error, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("fleet-health", nil)
+	})
+	var result FleetHealthOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Degraded != 1 || len(result.Minions) != 2 || result.Minions[0].Health.Score != 40 || len(result.Minions[1].Health.Anomalies) != 1 {
+		t.Errorf("Unexpected JSON fleet health: %+v", result)
+	}
+}
+
+func TestFleetVersions(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{minions: []*pb.HostInfo{
+		{Id: "minion-1", Hostname: "web-01", Version: "v1.3.0", BuildCommit: "def456", ProtocolVersion: 1},
+		{Id: "minion-9", Hostname: "legacy-01", Outdated: true},
+	}}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("fleet-versions", nil)
+	})
+	for _, expected := range []string{"Nexus v1.3.0, protocol 1, minions require protocol 1", "Minion versions (5 minions)", "def456", "Outdated minions (1)", "legacy-01"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("minion-list", nil)
+	})
+	if !strings.Contains(output, "v1.3.0 (protocol 1)") || !strings.Contains(output, "- (outdated)") {
+		t.Errorf("Expected the minion versions listed, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("fleet-versions", nil)
+	})
+	var result FleetVersionsOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Total != 5 || len(result.Versions) != 2 || result.Versions[0].Minions != 4 || len(result.Outdated) != 1 || result.Outdated[0] != "minion-9" {
+		t.Errorf("Unexpected JSON fleet versions: %+v", result)
+	}
+}
+
+func TestFleetPatches(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("fleet-patches", []string{"env"})
+	})
+	if mockClient.lastPatchGroup != "env" {
+		t.Errorf("Expected the compliance grouped by env, got %q", mockClient.lastPatchGroup)
+	}
+	for _, expected := range []string{"Patch compliance of 4 minions", "Compliant:       2 (50.0%)", "3 security updates pending", "command-send all patch:status", "prod", "(untagged)"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("fleet-patches", nil)
+	})
+	var result PatchComplianceOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Total.Minions != 4 || result.Total.Vulnerable != 1 || len(result.Groups) != 0 || mockClient.lastPatchGroup != "" {
+		t.Errorf("Unexpected JSON patch compliance: %+v", result)
+	}
+}
+
+func TestParseExplainTargets(t *testing.T) {
+	req, err := parseExplainTargets([]string{"tag", "env=prod,role,!maintenance", "region=eu-west-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rules := req.TagSelector.Rules
+	if len(rules) != 3 || rules[0].GetEquals() != "prod" || !rules[1].GetExists() || rules[2].Key != "maintenance" || !rules[2].GetNotExists() {
+		t.Errorf("Unexpected tag rules: %v", rules)
+	}
+	if req.Topology.GetRegion() != "eu-west-1" {
+		t.Errorf("Unexpected topology: %v", req.Topology)
+	}
+
+	if req, err := parseExplainTargets([]string{"minion", "a,b"}); err != nil || len(req.MinionIds) != 2 {
+		t.Errorf("Expected two minion IDs, got %v, %v", req, err)
+	}
+	if req, err := parseExplainTargets([]string{"dc=eu-west-1a"}); err != nil || req.Topology.GetDatacenter() != "eu-west-1a" {
+		t.Errorf("Expected a datacenter target, got %v, %v", req, err)
+	}
+	for _, args := range [][]string{nil, {"all", "extra"}, {"tag", "!"}, {"tag", "env=prod", "web"}, {"minion", "a,"}, {"web-01"}} {
+		if _, err := parseExplainTargets(args); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
+
+func TestTargetExplain(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("target-explain", []string{"tag", "env=prod,!maintenance"})
+	})
+	for _, expected := range []string{"Minion missing is not registered", "Target matches 1 of 2 minions", "✓ env=prod (env is prod)", "✗ !maintenance (maintenance is yes)"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+	if len(mockClient.lastExplain.TagSelector.Rules) != 2 {
+		t.Errorf("Unexpected explain request: %+v", mockClient.lastExplain)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("target-explain", []string{"all"})
+	})
+	var result TargetExplanationsOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Matched != 1 || len(result.Minions) != 2 || result.Minions[1].Matched || result.Minions[1].Rules[1].Reason != "maintenance is yes" || len(result.UnknownMinionIDs) != 1 {
+		t.Errorf("Unexpected JSON explanations: %+v", result)
+	}
+}
+
+func TestMinionPicker(t *testing.T) {
+	minions := []*pb.HostInfo{
+		{Id: "a1", Hostname: "web-02", Tags: map[string]string{"env": "prod"}},
+		{Id: "b2", Hostname: "db-01", Tags: map[string]string{"env": "prod"}},
+		{Id: "c3", Hostname: "web-01", Tags: map[string]string{"env": "staging"}},
+	}
+	picker := newMinionPicker(minions, "", 10, 80)
+	if len(picker.visible) != 3 || picker.candidates[picker.visible[0]].minion.Hostname != "db-01" {
+		t.Fatalf("Expected all minions sorted by hostname, got %v", picker.visible)
+	}
+
+	for _, key := range []string{"w", "b", " ", "p", "r", "d"} {
+		picker.handleKey(key)
+	}
+	if len(picker.visible) != 1 || picker.candidates[picker.visible[0]].minion.Id != "a1" {
+		t.Fatalf("Expected 'wb prd' to fuzzy match web-02 only, got %v", picker.visible)
+	}
+	picker.handleKey("\t")
+	picker.handleKey("\x15")
+	picker.setFilter("web")
+	if len(picker.visible) != 2 {
+		t.Fatalf("Expected both web minions listed, got %v", picker.visible)
+	}
+	picker.handleKey("\x01")
+	if picker.handleKey("\r") || picker.cancelled {
+		t.Fatal("Expected Enter to confirm the selection")
+	}
+	if ids := picker.selection(); strings.Join(ids, ",") != "c3,a1" {
+		t.Errorf("Expected the selection kept across filters, got %v", ids)
+	}
+
+	picker = newMinionPicker(minions, "db", 10, 80)
+	if ids := picker.selection(); len(ids) != 1 || ids[0] != "b2" {
+		t.Errorf("Expected the highlighted minion without selection, got %v", ids)
+	}
+	if picker.handleKey("\x1b") || !picker.cancelled {
+		t.Error("Expected Esc to cancel")
+	}
+	if score, ok := fuzzyScore("xyz", "web-01"); ok {
+		t.Errorf("Expected no match, got score %d", score)
+	}
+
+	// Labels supplied by minions can't write escape sequences to the terminal
+	label := pickLabel(&pb.HostInfo{Id: "d4", Hostname: "evil\x1b]0;pwned\x07", Tags: map[string]string{"role": "web\x1b[2J"}})
+	if strings.ContainsAny(label, "\x1b\x07") || label != "d4  evil]0;pwned  role=web[2J" {
+		t.Errorf("Expected the control characters stripped, got %q", label)
+	}
+
+	// Outside a terminal, pick selects the minions matching its filter
+	mockClient := &mockConsoleServiceClient{minions: minions, commandAccepted: true, commandID: "cmd-1"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+	output := captureOutput(func() {
+		console.handleCommand("pick", []string{"web"})
+	})
+	if strings.Join(console.target, " ") != "minion c3,a1" {
+		t.Fatalf("Expected the web minions as working target, got %v: %s", console.target, output)
+	}
+	console.handleCommand("run", []string{"uptime"})
+	if req := mockClient.lastRequest; req == nil || strings.Join(req.MinionIds, ",") != "c3,a1" {
+		t.Errorf("Expected the command sent to the picked minions, got %v", req)
+	}
+}
+
+func TestWorkingTarget(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("run", []string{"uptime"})
+	})
+	if mockClient.lastRequest != nil || !strings.Contains(output, "no working target") {
+		t.Fatalf("Expected run to require a working target, got: %s", output)
+	}
+
+	for _, args := range [][]string{{"tag"}, {"minion"}, {"--emergency", "all"}, {"all", "uptime"}} {
+		output = captureOutput(func() {
+			console.handleCommand("use", args)
+		})
+		if console.target != nil {
+			t.Errorf("Expected %v rejected as working target, got %v: %s", args, console.target, output)
+		}
+	}
+
+	console.handleCommand("use", []string{"tag", "env=prod"})
+	if strings.Join(console.target, " ") != "tag env=prod" || console.ui.prompt != "minexus [tag env=prod]> " {
+		t.Fatalf("Expected the working target selected, got %v with prompt %q", console.target, console.ui.prompt)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("run", []string{"--impact", "read-only", "df", "-h"})
+	})
+	req := mockClient.lastRequest
+	if req == nil || req.Command.Payload != "df -h" || req.Impact != "read-only" || len(req.TagSelector.GetRules()) != 1 || req.TagSelector.Rules[0].Key != "env" {
+		t.Fatalf("Expected the command sent to the working target, got %v: %s", req, output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("targets", nil)
+	})
+	if !strings.Contains(output, "Working target: tag env=prod") || !strings.Contains(output, "Matches 1 minion(s): minion-1") {
+		t.Errorf("Unexpected working target: %s", output)
+	}
+
+	console.handleCommand("use", []string{"minion", "abc123"})
+	mockClient.lastRequest = nil
+	console.handleCommand("run", []string{"uptime"})
+	if mockClient.lastRequest == nil || len(mockClient.lastRequest.MinionIds) != 1 || mockClient.lastRequest.MinionIds[0] != "abc123" {
+		t.Errorf("Expected the command sent to the new working target, got %v", mockClient.lastRequest)
+	}
+
+	console.handleCommand("use", []string{"none"})
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("targets", nil)
+	})
+	var result WorkingTargetOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Target != "" || result.Matched != nil || console.ui.prompt != defaultPrompt {
+		t.Errorf("Expected the working target cleared, got %+v with prompt %q", result, console.ui.prompt)
+	}
+}
+
+func TestAdminCommands(t *testing.T) {
+	console := createMockConsole(&mockConsoleServiceClient{})
+	defer console.Shutdown()
+	mockAdmin := &mockAdminServiceClient{}
+	console.grpc.admin = mockAdmin
+
+	output := captureOutput(func() {
+		console.handleCommand("admin-log-level", []string{"debug"})
+	})
+	if mockAdmin.lastLevel.Level != "debug" || !strings.Contains(output, "changed from info to debug") {
+		t.Errorf("Expected the log level changed, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("admin-log-level", []string{"--sampling", "off"})
+	})
+	if mockAdmin.lastLevel.Level != "" || mockAdmin.lastLevel.Sampling != "off" || !strings.Contains(output, "sampling changed from 100,100 to off") {
+		t.Errorf("Expected the log sampling changed, got: %s", output)
+	}
+	output = captureOutput(func() {
+		console.handleCommand("admin-log-level", []string{"debug", "info"})
+	})
+	if !strings.Contains(output, "usage: admin-log-level") {
+		t.Errorf("Expected usage error, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("admin-registry", nil)
+	})
+	for _, expected := range []string{"minion-1", "web-2", "closed", "2 minions, 1 connected, 3 commands awaiting results"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output: %s", expected, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("admin-disconnect", []string{"minion-2"})
+	})
+	if mockAdmin.lastDisconnect.MinionId != "minion-2" || !strings.Contains(output, "has no open command stream") {
+		t.Errorf("Expected the disconnection error, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("admin-unbind", []string{"minion-1"})
+	})
+	if mockAdmin.lastUnbind.MinionId != "minion-1" || !strings.Contains(output, "Minion minion-1 unbound") {
+		t.Errorf("Expected the minion unbound, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("admin-registry", nil)
+	})
+	var dump RegistryDumpOutput
+	if err := json.Unmarshal([]byte(output), &dump); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if len(dump.Minions) != 2 || !dump.Minions[0].Connected || dump.Minions[1].PendingCommands != 3 {
+		t.Errorf("Unexpected JSON registry dump: %+v", dump)
+	}
+}
+
+func TestSendFileVerifyManifest(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "nginx.sha256")
+	content := "# nginx configuration\n" +
+		"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08  /etc/nginx/nginx.conf\n" +
+		"60303AE22B998861BCE3B28F33EEC1BE758A213C86C93C076DBE9F558C11C752 */etc/nginx/mime.types\n"
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"all", "file:verify " + manifest + " /etc/nginx"})
+	})
+	if mockClient.lastRequest == nil {
+		t.Fatalf("Expected the command to be sent, output: %s", output)
+	}
+	payload, found := strings.CutPrefix(mockClient.lastRequest.Command.Payload, "file:verify ")
+	if !found {
+		t.Fatalf("Unexpected payload: %s", mockClient.lastRequest.Command.Payload)
+	}
+	var request command.FileVerifyRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		t.Fatalf("Invalid manifest payload: %v", err)
+	}
+	if request.Algorithm != "sha256" || len(request.Files) != 2 || request.Dirs[0] != "/etc/nginx" ||
+		request.Files["/etc/nginx/mime.types"] != "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752" {
+		t.Errorf("Unexpected manifest: %+v", request)
+	}
+
+	// Malformed manifests are rejected before sending
+	if err := os.WriteFile(manifest, []byte("not a checksum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mockClient.lastRequest = nil
+	output = captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"all", "file:verify", manifest})
+	})
+	if mockClient.lastRequest != nil || !strings.Contains(output, "expected '<checksum>  <path>'") {
+		t.Errorf("Expected the manifest to be rejected, output: %s", output)
+	}
+}
+
+func TestSendStateApplyDocument(t *testing.T) {
+	document := filepath.Join(t.TempDir(), "web.yaml")
+	content := "packages: [nginx]\n" +
+		"files:\n" +
+		"  - path: /etc/nginx/conf.d/site.conf\n" +
+		"    mode: 0640\n" +
+		"    content: |\n" +
+		"      server { listen 80; }\n" +
+		"  - path: /etc/nginx/conf.d/default.conf\n" +
+		"    absent: true\n" +
+		"services: [nginx]\n"
+	if err := os.WriteFile(document, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.sendCommand(context.Background(), []string{"all", "state:apply", "--check", document})
+	})
+	if mockClient.lastRequest == nil {
+		t.Fatalf("Expected the command to be sent, output: %s", output)
+	}
+	payload, found := strings.CutPrefix(mockClient.lastRequest.Command.Payload, "state:apply ")
+	if !found {
+		t.Fatalf("Unexpected payload: %s", mockClient.lastRequest.Command.Payload)
+	}
+	var request command.StateDocument
+	if err := json.Unmarshal([]byte(payload), &request); err != nil {
+		t.Fatalf("Invalid document payload: %v", err)
+	}
+	if !request.Check || len(request.Files) != 2 || request.Files[0].Mode != "0640" ||
+		request.Files[0].Content != "server { listen 80; }\n" || !request.Files[1].Absent || request.Services[0] != "nginx" {
+		t.Errorf("Unexpected document: %+v", request)
+	}
+
+	// Unknown fields and invalid resources are rejected before sending
+	for _, invalid := range []string{"users: [bob]\n", "files:\n  - path: etc/motd\n"} {
+		if err := os.WriteFile(document, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mockClient.lastRequest = nil
+		output = captureOutput(func() {
+			console.sendCommand(context.Background(), []string{"all", "state:apply " + document})
+		})
+		if mockClient.lastRequest != nil {
+			t.Errorf("Expected %q rejected, output: %s", invalid, output)
+		}
+	}
+}
+
+// mockStreamClient is a server streaming client replaying canned messages
+type mockStreamClient[T any] struct {
+	grpc.ClientStream
+	messages []*T
+}
+
+func (m *mockStreamClient[T]) Recv() (*T, error) {
+	if len(m.messages) == 0 {
+		return nil, io.EOF
+	}
+	msg := m.messages[0]
+	m.messages = m.messages[1:]
+	return msg, nil
+}
+
+func (m *mockConsoleServiceClient) PullFile(ctx context.Context, req *pb.FilePullRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.FileTransferStatus], error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastPull = req
+	return &mockStreamClient[pb.FileTransferStatus]{messages: m.pullStatuses}, nil
+}
+
+func (m *mockConsoleServiceClient) ListFileTransfers(ctx context.Context, req *pb.Empty, opts ...grpc.CallOption) (*pb.FileTransferList, error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	return &pb.FileTransferList{Transfers: m.transfers}, nil
+}
+
+func (m *mockConsoleServiceClient) DownloadFile(ctx context.Context, req *pb.FileDownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.FileChunk], error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastDownload = req
+	var chunks []*pb.FileChunk
+	for offset := req.Offset; ; offset += 4 {
+		end := min(offset+4, int64(len(m.transferFile)))
+		chunk := &pb.FileChunk{TransferId: req.TransferId, Offset: offset, Data: m.transferFile[offset:end]}
+		chunks = append(chunks, chunk)
+		if end == int64(len(m.transferFile)) {
+			chunk.Eof = true
+			break
+		}
+	}
+	return &mockStreamClient[pb.FileChunk]{messages: chunks}, nil
+}
+
+func (m *mockConsoleServiceClient) ExportReceipts(ctx context.Context, req *pb.ReceiptExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[pb.ExecutionReceipt], error) {
+	if m.returnError {
+		return nil, errors.New("mock error")
+	}
+	m.lastExport = req
+	return &mockStreamClient[pb.ExecutionReceipt]{messages: m.receipts}, nil
+}
+
+// signedReceipts returns count receipts chained and signed like Nexus does
+func signedReceipts(t *testing.T, count int) []*pb.ExecutionReceipt {
+	t.Helper()
+	signer, err := certs.NewCommandSigner(certs.CertPEM, certs.KeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	var receipts []*pb.ExecutionReceipt
+	previous := ""
+	for i := 1; i <= count; i++ {
+		data, _ := json.Marshal(receiptChain{Sequence: i, Total: count, PreviousDigest: previous, CommandID: fmt.Sprintf("cmd-%d", i)})
+		signature, err := signer.SignData(data)
+		if err != nil {
+			t.Fatalf("SignData failed: %v", err)
+		}
+		receipts = append(receipts, &pb.ExecutionReceipt{Receipt: data, Signature: signature, SignerCertificate: signer.Certificate()})
+		digest := sha256.Sum256(data)
+		previous = hex.EncodeToString(digest[:])
+	}
+	return receipts
+}
+
+func TestComplianceExport(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{receipts: signedReceipts(t, 3)}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	output := captureOutput(func() {
+		console.handleCommand("compliance-export", []string{"--since", "7d", "--out", path})
+	})
+	if !strings.Contains(output, "Exported 3 execution receipts") {
+		t.Errorf("Expected the export reported, got: %s", output)
+	}
+	if req := mockClient.lastExport; req.Until != 0 || time.Since(time.Unix(req.Since, 0)).Round(time.Hour) != 7*24*time.Hour {
+		t.Errorf("Unexpected export request: %+v", req)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("compliance-verify", []string{path})
+	})
+	if !strings.Contains(output, "is intact: 3 execution receipts") {
+		t.Errorf("Expected the export verified, got: %s", output)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+	tampered := strings.Replace(lines[1], "cmd-2", "cmd-9", 1)
+	for name, content := range map[string]string{
+		"tampered":  lines[0] + tampered + lines[2],
+		"removed":   lines[0] + lines[2],
+		"reordered": lines[1] + lines[0] + lines[2],
+		"truncated": lines[0] + lines[1],
+	} {
+		file := filepath.Join(t.TempDir(), name+".jsonl")
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		var valid bool
+		output = captureOutput(func() {
+			valid = console.verifyComplianceExport([]string{file})
+		})
+		if valid || !strings.Contains(output, "failed verification") {
+			t.Errorf("Expected the %s export rejected, got: %s", name, output)
+		}
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("compliance-verify", []string{path, "--ca", filepath.Join(t.TempDir(), "missing.pem")})
+	})
+	if !strings.Contains(output, "Error reading CA bundle") {
+		t.Errorf("Expected a CA bundle error, got: %s", output)
+	}
+	output = captureOutput(func() {
+		console.handleCommand("compliance-verify", []string{path})
+	})
+	var result ComplianceVerifyOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if !result.Valid || result.Count != 3 || result.Signer == "" {
+		t.Errorf("Unexpected JSON verification: %+v", result)
+	}
+}
+
+func TestFilePull(t *testing.T) {
+	content := []byte("a file pulled in chunks")
+	sum := sha256.Sum256(content)
+	completed := &pb.FileTransferStatus{
+		TransferId: "t1", MinionId: "web-01", Path: "/var/log/app.log", State: "COMPLETED",
+		Received: int64(len(content)), Size: int64(len(content)), Sha256: hex.EncodeToString(sum[:]),
+	}
+	mockClient := &mockConsoleServiceClient{
+		pullStatuses: []*pb.FileTransferStatus{
+			{TransferId: "t1", MinionId: "web-01", Path: "/var/log/app.log", State: "RUNNING", Received: 8, Size: int64(len(content))},
+			completed,
+		},
+		transfers:    []*pb.FileTransferStatus{completed},
+		transferFile: content,
+	}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("file-pull", []string{"web-01", "logs/app.log"})
+	})
+	if !strings.Contains(output, "must be absolute") {
+		t.Errorf("Expected a relative path to be refused, got: %s", output)
+	}
+
+	local := filepath.Join(t.TempDir(), "app.log")
+	output = captureOutput(func() {
+		console.handleCommand("file-pull", []string{"web-01", "/var/log/app.log", local})
+	})
+	if !strings.Contains(output, "Downloaded "+local) {
+		t.Errorf("Expected the file downloaded, got: %s", output)
+	}
+	if mockClient.lastPull.MinionId != "web-01" || mockClient.lastPull.Path != "/var/log/app.log" {
+		t.Errorf("Unexpected pull request: %+v", mockClient.lastPull)
+	}
+	if data, err := os.ReadFile(local); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("Unexpected downloaded file %q: %v", data, err)
+	}
+
+	// A partial download is resumed from its size
+	if err := os.WriteFile(local+".part", content[:10], 0600); err != nil {
+		t.Fatal(err)
+	}
+	output = captureOutput(func() {
+		console.handleCommand("file-download", []string{"t1", local})
+	})
+	if mockClient.lastDownload.Offset != 10 || !strings.Contains(output, "Downloaded") {
+		t.Errorf("Expected the download resumed at 10, got %+v: %s", mockClient.lastDownload, output)
+	}
+	if data, _ := os.ReadFile(local); !bytes.Equal(data, content) {
+		t.Errorf("Unexpected resumed file %q", data)
+	}
+
+	// A download not matching the checksum is discarded
+	completed.Sha256 = "bad"
+	output = captureOutput(func() {
+		console.handleCommand("file-download", []string{"t1", local})
+	})
+	if !strings.Contains(output, "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got: %s", output)
+	}
+	if _, err := os.Stat(local + ".part"); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial download removed, got %v", err)
+	}
+
+	// An interrupted transfer is reported with how to resume it
+	mockClient.pullStatuses = []*pb.FileTransferStatus{{TransferId: "t2", State: "INTERRUPTED", Error: "minion disconnected"}}
+	output = captureOutput(func() {
+		console.handleCommand("file-pull", []string{"--resume", "t2"})
+	})
+	if mockClient.lastPull.TransferId != "t2" || !strings.Contains(output, "file-pull --resume t2") {
+		t.Errorf("Expected resume instructions, got: %s", output)
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("file-transfers", nil)
+	})
+	var result FileTransferListOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Count != 1 || result.Transfers[0].TransferID != "t1" || result.Transfers[0].State != "COMPLETED" {
+		t.Errorf("Unexpected transfers: %+v", result)
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	mockClient := &mockConsoleServiceClient{commandAccepted: true, commandID: "cmd-123"}
+	console := createMockConsole(mockClient)
+	defer console.Shutdown()
+
+	output := captureOutput(func() {
+		console.handleCommand("validate", []string{"tag", "env=prod", "file:grep", "-C", "2", "root", "/etc/ssh"})
+	})
+	if !strings.Contains(output, "Valid command: 'file:grep -C 2 root /etc/ssh' (INTERNAL) for tag env=prod") {
+		t.Errorf("Expected the command to be valid, got: %s", output)
+	}
+
+	output = captureOutput(func() {
+		console.handleCommand("command-send", []string{"--validate", "minion", "web-01", "config:get"})
+	})
+	if !strings.Contains(output, "Invalid command: usage: config:get <key>") {
+		t.Errorf("Expected an argument error, got: %s", output)
+	}
+	if mockClient.lastRequest != nil {
+		t.Errorf("Expected nothing sent to Nexus, got %+v", mockClient.lastRequest)
+	}
+
+	if !console.validateCommand([]string{"command-send", "dc=eu-west-1", "process:top", "memory"}) {
+		t.Error("Expected a command-send line to be valid")
+	}
+	if console.validateCommand([]string{"web-01", "uptime"}) {
+		t.Error("Expected a line without target to be invalid")
+	}
+
+	console.SetOutputFormat(OutputFormatJSON)
+	output = captureOutput(func() {
+		console.handleCommand("validate", []string{"all", "lock:acquire", "bad/name"})
+	})
+	var result ValidationOutput
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Invalid JSON output %q: %v", output, err)
+	}
+	if result.Valid || !strings.Contains(result.Error, "invalid lock name") {
+		t.Errorf("Unexpected validation: %+v", result)
+	}
+}
+
+func TestRetryWhileReconnecting(t *testing.T) {
+	// Nothing listens on the address: the channel never gets ready
+	conn, err := grpc.NewClient("passthrough:///127.0.0.1:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	grpcClient := &GRPCClient{conn: conn, logger: zap.NewNop()}
+	var retries []int
+	grpcClient.OnRetry(func(attempt int, delay time.Duration, err error) {
+		retries = append(retries, attempt)
+	})
+
+	invokeMethod := func(ctx context.Context, method string, errs ...error) (int, error) {
+		calls := 0
+		err := grpcClient.retryUnary(ctx, method, &pb.Empty{}, &pb.TagList{}, conn,
+			func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+				calls++
+				if calls > len(errs) {
+					return nil
+				}
+				return errs[calls-1]
+			})
+		return calls, err
+	}
+	invoke := func(ctx context.Context, errs ...error) (int, error) {
+		return invokeMethod(ctx, pb.ConsoleService_ListTags_FullMethodName, errs...)
+	}
+
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	if calls, err := invoke(context.Background(), unavailable); err != nil || calls != 2 || len(retries) != 1 {
+		t.Errorf("Expected the request retried once, got %d calls, %v retries: %v", calls, retries, err)
+	}
+
+	// Requests changing something may have reached Nexus, they are never sent twice
+	retries = nil
+	if calls, err := invokeMethod(context.Background(), pb.ConsoleService_SendCommand_FullMethodName, unavailable); status.Code(err) != codes.Unavailable || calls != 1 || retries != nil {
+		t.Errorf("Expected SendCommand not retried, got %d calls: %v", calls, err)
+	}
+
+	// Other errors fail right away
+	retries = nil
+	if calls, err := invoke(context.Background(), status.Error(codes.InvalidArgument, "bad")); status.Code(err) != codes.InvalidArgument || calls != 1 || retries != nil {
+		t.Errorf("Expected no retry, got %d calls: %v", calls, err)
+	}
+
+	// The command gives up when canceled while waiting to retry
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if calls, err := invoke(ctx, unavailable, unavailable); status.Code(err) != codes.Unavailable || calls != 1 {
+		t.Errorf("Expected the canceled request failed, got %d calls: %v", calls, err)
+	}
+
+	for attempt, want := range map[int]time.Duration{1: reconnectBackoffMin, 2: 2 * reconnectBackoffMin, 4: reconnectBackoffMax, 70: reconnectBackoffMax} {
+		if delay := reconnectBackoff(attempt); delay != want {
+			t.Errorf("reconnectBackoff(%d) = %s, want %s", attempt, delay, want)
+		}
+	}
+	if connectionState(connectivity.TransientFailure) != ConnectionReconnecting || connectionState(connectivity.Ready) != ConnectionConnected {
+		t.Error("Unexpected connection states")
+	}
+}
+
+func TestConnectionStatePrompt(t *testing.T) {
+	ui := NewUIManager(zap.NewNop(), command.SetupCommands(time.Second))
+	ui.SetPrompt("tag env=prod")
+	ui.SetConnectionState(ConnectionReconnecting)
+	if ui.prompt != "minexus [tag env=prod] (reconnecting)> " {
+		t.Errorf("Unexpected prompt while reconnecting %q", ui.prompt)
+	}
+	ui.SetPrompt("")
+	ui.SetConnectionState(ConnectionConnected)
+	if ui.prompt != defaultPrompt {
+		t.Errorf("Expected the default prompt once connected, got %q", ui.prompt)
+	}
+}
+
+func TestTokenCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("eyJhbGciOiJSUzI1NiJ9.e30.c2ln\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	token := tokenCredentials(path)
+	md, err := token.GetRequestMetadata(context.Background())
+	if err != nil || md["authorization"] != "Bearer eyJhbGciOiJSUzI1NiJ9.e30.c2ln" || !token.RequireTransportSecurity() {
+		t.Errorf("Unexpected request metadata %v: %v", md, err)
+	}
+
+	// The file is read at every request, picking up refreshed tokens
+	os.WriteFile(path, nil, 0600)
+	if _, err := token.GetRequestMetadata(context.Background()); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("Expected an empty token file rejected, got %v", err)
+	}
+	if _, err := NewGRPCClient(&config.ConsoleConfig{ServerAddr: "localhost:11973", TokenFile: filepath.Join(t.TempDir(), "missing")}, zap.NewNop()); err == nil {
+		t.Error("Expected the console to fail without its token file")
+	}
+}
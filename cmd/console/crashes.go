@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const crashListUsage = "usage: crash-list [minion-id] [--limit <n>] [--stack]"
+
+// parseCrashListArgs parses crash-list arguments, Nexus defaulting to the last 50 crashes of every minion
+func parseCrashListArgs(args []string) (*pb.CrashListRequest, bool, error) {
+	req := &pb.CrashListRequest{}
+	stack := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--stack":
+			stack = true
+		case "--limit":
+			if i+1 >= len(args) {
+				return nil, false, fmt.Errorf("%s", crashListUsage)
+			}
+			i++
+			limit, err := strconv.Atoi(args[i])
+			if err != nil || limit <= 0 {
+				return nil, false, fmt.Errorf("invalid limit '%s', must be a positive number", args[i])
+			}
+			req.Limit = int32(limit)
+		default:
+			if strings.HasPrefix(args[i], "--") || req.MinionId != "" {
+				return nil, false, fmt.Errorf("%s", crashListUsage)
+			}
+			req.MinionId = args[i]
+		}
+	}
+	return req, stack, nil
+}
+
+// listCrashes shows the panics minions recovered from, most recent first
+func (c *Console) listCrashes(ctx context.Context, args []string) {
+	req, showStack, err := parseCrashListArgs(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	crashes, err := c.grpc.ListCrashes(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to list crashes", zap.String("minion_id", req.MinionId), zap.Error(err))
+		c.printError(fmt.Sprintf("Error listing crashes: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := CrashListOutput{
+			Count:   len(crashes.Crashes),
+			Crashes: make([]CrashOutput, 0, len(crashes.Crashes)),
+		}
+		for _, crash := range crashes.Crashes {
+			output.Crashes = append(output.Crashes, CrashOutput{
+				MinionID:  crash.MinionId,
+				Timestamp: crash.Timestamp,
+				Component: crash.Component,
+				Message:   crash.Message,
+				Stack:     crash.Stack,
+				Version:   crash.Version,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(crashes.Crashes) == 0 {
+		c.ui.PrintInfo("No crash reported")
+		return
+	}
+
+	fmt.Printf("%-19s  %-20s  %-10s  %-30s  %s\n", "Time", "Minion", "Version", "Component", "Panic")
+	for _, crash := range crashes.Crashes {
+		fmt.Printf("%-19s  %-20s  %-10s  %-30s  %s\n",
+			c.formatUnixTime(crash.Timestamp), crash.MinionId, crash.Version, crash.Component, crash.Message)
+		if showStack {
+			fmt.Printf("%s\n", strings.TrimRight(crash.Stack, "\n"))
+			fmt.Println()
+		}
+	}
+}
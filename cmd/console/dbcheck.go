@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// checkDatabase runs the nexus database integrity checks and prints their report
+func (c *Console) checkDatabase(ctx context.Context, args []string) {
+	repair := false
+	for _, arg := range args {
+		if arg != "--repair" {
+			c.printError("usage: db-check [--repair]")
+			return
+		}
+		repair = true
+	}
+
+	report, err := c.grpc.CheckDatabase(ctx, repair)
+	if err != nil {
+		c.logger.Error("Failed to check database", zap.Bool("repair", repair), zap.Error(err))
+		c.printError(fmt.Sprintf("Error checking database: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := DatabaseCheckOutput{
+			Healthy: report.Healthy,
+			Checks:  make([]DatabaseCheckEntryOutput, 0, len(report.Checks)),
+		}
+		for _, check := range report.Checks {
+			output.Checks = append(output.Checks, DatabaseCheckEntryOutput{
+				Name:        check.Name,
+				Description: check.Description,
+				Found:       check.Found,
+				Repaired:    check.Repaired,
+				Repairable:  check.Repairable,
+				Error:       check.Error,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	fmt.Printf("%-24s %-8s %-8s %s\n", "Check", "Found", "Repaired", "Description")
+	fmt.Printf("%-24s %-8s %-8s %s\n", "-----", "-----", "--------", "-----------")
+	for _, check := range report.Checks {
+		repaired := fmt.Sprintf("%d", check.Repaired)
+		if !check.Repairable {
+			repaired = "-"
+		}
+		fmt.Printf("%-24s %-8d %-8s %s\n", check.Name, check.Found, repaired, check.Description)
+		if check.Error != "" {
+			fmt.Printf("  %s\n", check.Error)
+		}
+	}
+
+	switch {
+	case report.Healthy:
+		c.ui.PrintSuccess("Database is consistent")
+	case repair:
+		c.ui.PrintWarning("Database inconsistencies remain")
+	default:
+		c.ui.PrintWarning("Database inconsistencies found, run db-check --repair to fix them")
+	}
+}
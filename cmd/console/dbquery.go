@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// databaseQuery runs an approved read-only database query, listing the queries without argument
+func (c *Console) databaseQuery(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		c.listDatabaseQueries(ctx)
+		return
+	}
+
+	params, err := parseReportParams(args[1:])
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	result, err := c.grpc.RunDatabaseQuery(ctx, &pb.DatabaseQueryRequest{Name: args[0], Params: params})
+	if err != nil {
+		c.logger.Error("Failed to run database query", zap.String("query", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error running database query: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := DatabaseQueryResultOutput{
+			Name:      result.Name,
+			Columns:   result.Columns,
+			Rows:      make([][]string, 0, len(result.Rows)),
+			Truncated: result.Truncated,
+		}
+		for _, row := range result.Rows {
+			output.Rows = append(output.Rows, row.Values)
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(result.Rows) == 0 {
+		c.ui.PrintInfo(fmt.Sprintf("Query %s: no rows", result.Name))
+		return
+	}
+
+	fmt.Printf("Query %s (%d rows, %s):\n", result.Name, len(result.Rows), time.Now().Format("2006-01-02 15:04"))
+	printReportTable(&pb.ReportResult{Name: result.Name, Columns: result.Columns, Rows: result.Rows})
+	if result.Truncated {
+		c.ui.PrintWarning(fmt.Sprintf("Only the first %d rows are shown", len(result.Rows)))
+	}
+}
+
+// listDatabaseQueries lists the database queries approved on Nexus
+func (c *Console) listDatabaseQueries(ctx context.Context) {
+	response, err := c.grpc.ListDatabaseQueries(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error listing database queries: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := DatabaseQueryListOutput{
+			Count:   len(response.Queries),
+			Queries: make([]DatabaseQueryOutput, 0, len(response.Queries)),
+		}
+		for _, q := range response.Queries {
+			output.Queries = append(output.Queries, DatabaseQueryOutput{Name: q.Name, Description: q.Description, Params: q.Params})
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(response.Queries) == 0 {
+		c.ui.PrintInfo("No database queries configured on Nexus")
+		return
+	}
+
+	fmt.Printf("Database queries (%d):\n", len(response.Queries))
+	for _, q := range response.Queries {
+		usage := q.Name
+		for _, param := range q.Params {
+			usage += " " + param + "=<value>"
+		}
+		fmt.Printf("  %s\n", usage)
+		if q.Description != "" {
+			fmt.Printf("    %s\n", strings.TrimSpace(q.Description))
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/util"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// defaultDisplayFileName is the display preference file created in the user's home directory
+const defaultDisplayFileName = ".minexus_display.json"
+
+// Timestamp formats of the console
+const (
+	TimeFormatLocal    = "local"    // local time zone, the default
+	TimeFormatUTC      = "utc"      // UTC
+	TimeFormatRelative = "relative" // elapsed time, such as 5m ago
+)
+
+// IsValidTimeFormat reports whether format is a timestamp format of the console
+func IsValidTimeFormat(format string) bool {
+	return format == TimeFormatLocal || format == TimeFormatUTC || format == TimeFormatRelative
+}
+
+// minionColumn is a column of minion-list
+type minionColumn struct {
+	header string
+	value  func(c *Console, minion *pb.HostInfo) string
+}
+
+// minionColumns are the columns minion-list can show, in the order listed by set
+var minionColumns = []string{"id", "hostname", "ip", "os", "os-version", "version", "namespace", "region", "datacenter", "rack", "last-seen", "health", "queued", "next-wake", "power", "tags"}
+
+// minionColumnDefs defines the columns of minion-list
+var minionColumnDefs = map[string]minionColumn{
+	"id":         {"ID", func(c *Console, m *pb.HostInfo) string { return m.Id }},
+	"hostname":   {"Hostname", func(c *Console, m *pb.HostInfo) string { return m.Hostname }},
+	"ip":         {"IP", func(c *Console, m *pb.HostInfo) string { return m.Ip }},
+	"os":         {"OS", func(c *Console, m *pb.HostInfo) string { return m.Os }},
+	"os-version": {"OS Version", func(c *Console, m *pb.HostInfo) string { return m.OsVersion }},
+	"version":    {"Version", func(c *Console, m *pb.HostInfo) string { return formatMinionVersion(m) }},
+	"namespace":  {"Namespace", func(c *Console, m *pb.HostInfo) string { return m.Namespace }},
+	"region":     {"Region", func(c *Console, m *pb.HostInfo) string { return m.Region }},
+	"datacenter": {"Datacenter", func(c *Console, m *pb.HostInfo) string { return m.Datacenter }},
+	"rack":       {"Rack", func(c *Console, m *pb.HostInfo) string { return m.Rack }},
+	"last-seen":  {"Last Seen", func(c *Console, m *pb.HostInfo) string { return c.formatUnixTime(m.LastSeen) }},
+	"health":     {"Health", func(c *Console, m *pb.HostInfo) string { return formatHealth(m.Health) }},
+	"queued":     {"Queued", func(c *Console, m *pb.HostInfo) string { return strconv.Itoa(int(m.QueuedCommands)) }},
+	"next-wake":  {"Next Wake", func(c *Console, m *pb.HostInfo) string { return c.formatNextWake(m) }},
+	"power":      {"Power", func(c *Console, m *pb.HostInfo) string { return c.formatPower(m) }},
+	"tags":       {"Tags", func(c *Console, m *pb.HostInfo) string { return util.FormatTags(m.Tags) }},
+}
+
+// resultColumn is a column of the result tables of result-get and command-send
+type resultColumn struct {
+	header string
+	value  func(c *Console, result *pb.CommandResult) string
+}
+
+// resultColumns are the columns result tables can show, in the order listed by set
+var resultColumns = []string{"minion", "exit-code", "time", "output", "command-id", "trace-id", "attempt", "duration", "user", "workdir", "max-rss"}
+
+// resultColumnDefs defines the columns of result tables
+var resultColumnDefs = map[string]resultColumn{
+	"minion":     {"Minion ID", func(c *Console, r *pb.CommandResult) string { return r.MinionId }},
+	"exit-code":  {"Exit Code", func(c *Console, r *pb.CommandResult) string { return strconv.Itoa(int(r.ExitCode)) }},
+	"time":       {"Time", func(c *Console, r *pb.CommandResult) string { return c.formatUnixTime(r.Timestamp) }},
+	"output":     {"Output", func(c *Console, r *pb.CommandResult) string { return truncateResultOutput(r.Stdout) }},
+	"command-id": {"Command ID", func(c *Console, r *pb.CommandResult) string { return r.CommandId }},
+	"trace-id":   {"Trace ID", func(c *Console, r *pb.CommandResult) string { return r.TraceId }},
+	"attempt":    {"Attempt", func(c *Console, r *pb.CommandResult) string { return strconv.Itoa(int(max(r.Attempt, 1))) }},
+	"duration":   {"Duration", func(c *Console, r *pb.CommandResult) string { return formatExecutionDuration(r.Execution) }},
+	"user":       {"User", func(c *Console, r *pb.CommandResult) string { return r.GetExecution().GetUser() }},
+	"workdir":    {"Working Dir", func(c *Console, r *pb.CommandResult) string { return r.GetExecution().GetWorkingDir() }},
+	"max-rss":    {"Max RSS", func(c *Console, r *pb.CommandResult) string { return formatMaxRSS(r.Execution) }},
+}
+
+// defaultColumns are the columns shown by each table until changed with set columns
+var defaultColumns = map[string][]string{
+	"minion-list": {"id", "hostname", "ip", "os", "version", "namespace", "last-seen", "queued", "next-wake", "tags"},
+	"result-get":  {"minion", "exit-code", "time", "output"},
+}
+
+// availableColumns returns the columns a table can show
+func availableColumns(table string) ([]string, bool) {
+	switch table {
+	case "minion-list":
+		return minionColumns, true
+	case "result-get":
+		return resultColumns, true
+	}
+	return nil, false
+}
+
+// DisplayPreferences are the display settings changed with set and persisted between sessions
+type DisplayPreferences struct {
+	Time    string              `json:"time,omitempty"`
+	Columns map[string][]string `json:"columns,omitempty"` // table -> columns, the default when absent
+}
+
+// DisplayStore keeps the display preferences persisted in a JSON file.
+// An empty path keeps them in memory only, and a nil store uses the defaults.
+type DisplayStore struct {
+	mu    sync.Mutex
+	path  string
+	prefs DisplayPreferences
+}
+
+// DefaultDisplayFile returns the display preference file path in the user's home directory
+func DefaultDisplayFile() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, defaultDisplayFileName)
+}
+
+// LoadDisplayStore loads the display preferences from path, a missing file yields the defaults
+func LoadDisplayStore(path string) (*DisplayStore, error) {
+	store := &DisplayStore{path: path}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read display file: %w", err)
+	}
+	var prefs DisplayPreferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return nil, fmt.Errorf("failed to parse display file %s: %w", path, err)
+	}
+	if prefs.Time != "" && !IsValidTimeFormat(prefs.Time) {
+		return nil, fmt.Errorf("invalid time format '%s' in display file %s", prefs.Time, path)
+	}
+	for table, columns := range prefs.Columns {
+		if err := validateColumns(table, columns); err != nil {
+			return nil, fmt.Errorf("invalid columns in display file %s: %w", path, err)
+		}
+	}
+	store.prefs = prefs
+	return store, nil
+}
+
+// TimeFormat returns the timestamp format
+func (s *DisplayStore) TimeFormat() string {
+	if s == nil {
+		return TimeFormatLocal
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prefs.Time == "" {
+		return TimeFormatLocal
+	}
+	return s.prefs.Time
+}
+
+// SetTimeFormat changes the timestamp format and persists the store
+func (s *DisplayStore) SetTimeFormat(format string) error {
+	if !IsValidTimeFormat(format) {
+		return fmt.Errorf("invalid time format '%s'. Use 'local', 'utc' or 'relative'", format)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.prefs.Time
+	s.prefs.Time = format
+	if err := s.saveLocked(); err != nil {
+		s.prefs.Time = previous
+		return err
+	}
+	return nil
+}
+
+// Columns returns the columns shown by table
+func (s *DisplayStore) Columns(table string) []string {
+	if s == nil {
+		return defaultColumns[table]
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if columns, ok := s.prefs.Columns[table]; ok {
+		return columns
+	}
+	return defaultColumns[table]
+}
+
+// SetColumns changes the columns shown by table, nil restoring the default ones, and persists the store
+func (s *DisplayStore) SetColumns(table string, columns []string) error {
+	if columns != nil {
+		if err := validateColumns(table, columns); err != nil {
+			return err
+		}
+	} else if _, ok := availableColumns(table); !ok {
+		return fmt.Errorf("unknown table '%s'. Use 'minion-list' or 'result-get'", table)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.prefs.Columns[table]
+	if columns == nil {
+		delete(s.prefs.Columns, table)
+	} else {
+		if s.prefs.Columns == nil {
+			s.prefs.Columns = make(map[string][]string)
+		}
+		s.prefs.Columns[table] = columns
+	}
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			s.prefs.Columns[table] = previous
+		} else {
+			delete(s.prefs.Columns, table)
+		}
+		return err
+	}
+	return nil
+}
+
+// saveLocked writes the preferences to disk atomically, the caller must hold s.mu
+func (s *DisplayStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.prefs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode display preferences: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write display file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write display file: %w", err)
+	}
+	return nil
+}
+
+// validateColumns checks that table can show columns
+func validateColumns(table string, columns []string) error {
+	available, ok := availableColumns(table)
+	if !ok {
+		return fmt.Errorf("unknown table '%s'. Use 'minion-list' or 'result-get'", table)
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column of %s is required", table)
+	}
+	for _, column := range columns {
+		if !containsColumn(available, column) {
+			return fmt.Errorf("unknown column '%s' of %s. Available columns: %s", column, table, strings.Join(available, ", "))
+		}
+	}
+	return nil
+}
+
+// containsColumn reports whether columns contains column
+func containsColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDisplay configures the store of the display preferences
+func (c *Console) SetDisplay(store *DisplayStore) {
+	c.display = store
+}
+
+// formatTime formats a time in the timestamp format of the console
+func (c *Console) formatTime(t time.Time) string {
+	switch c.display.TimeFormat() {
+	case TimeFormatUTC:
+		return t.UTC().Format("2006-01-02 15:04:05") + " UTC"
+	case TimeFormatRelative:
+		return formatRelativeTime(t, time.Now())
+	default:
+		return t.Local().Format("2006-01-02 15:04:05")
+	}
+}
+
+// formatUnixTime formats an optional Unix timestamp in the timestamp format of the console
+func (c *Console) formatUnixTime(timestamp int64) string {
+	if timestamp == 0 {
+		return "never"
+	}
+	return c.formatTime(time.Unix(timestamp, 0))
+}
+
+// formatNextWake formats when a power-aware minion wakes next, "-" for the minions that never sleep
+func (c *Console) formatNextWake(m *pb.HostInfo) string {
+	if m.NextWake == 0 {
+		return "-"
+	}
+	if m.Sleeping {
+		return c.formatUnixTime(m.NextWake) + " (sleeping)"
+	}
+	return c.formatUnixTime(m.NextWake)
+}
+
+// formatPower formats the reboot or shutdown a minion announced, "-" for the minions without one
+func (c *Console) formatPower(m *pb.HostInfo) string {
+	if m.PowerAction == "" {
+		return "-"
+	}
+	if m.PoweredDown {
+		return fmt.Sprintf("%s at %s (down)", m.PowerAction, c.formatUnixTime(m.PowerAt))
+	}
+	return fmt.Sprintf("%s at %s", m.PowerAction, c.formatUnixTime(m.PowerAt))
+}
+
+// formatMinionVersion formats the build and protocol version of a minion, "-" for the minions
+// predating version reporting
+func formatMinionVersion(m *pb.HostInfo) string {
+	if m.Version == "" && m.ProtocolVersion == 0 {
+		if m.Outdated {
+			return "- (outdated)"
+		}
+		return "-"
+	}
+	formatted := fmt.Sprintf("%s (protocol %d)", m.Version, m.ProtocolVersion)
+	if m.Outdated {
+		formatted += " (outdated)"
+	}
+	return formatted
+}
+
+// formatRelativeTime formats t relatively to now, such as 5m ago or in 2h
+func formatRelativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	if d < time.Minute {
+		return util.FormatLastSeen(t.Unix())
+	}
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("in %dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("in %dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("in %dd", int(d.Hours()/24))
+	}
+}
+
+// printTable prints rows as a table whose columns are as wide as their widest cell, the last one unpadded
+func printTable(headers []string, rows [][]string) {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	printRow := func(cells []string) {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				parts[i] = cell
+			} else {
+				parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			}
+		}
+		fmt.Println(strings.TrimRight(strings.Join(parts, " | "), " "))
+	}
+
+	printRow(headers)
+	separators := make([]string, len(headers))
+	for i, header := range headers {
+		width := widths[i]
+		if i == len(headers)-1 {
+			width = len(header)
+		}
+		separators[i] = strings.Repeat("-", width)
+	}
+	printRow(separators)
+	for _, row := range rows {
+		printRow(row)
+	}
+}
+
+// truncateResultOutput shortens an output to a line of at most 50 characters for result tables
+func truncateResultOutput(output string) string {
+	output = strings.ReplaceAll(output, "\n", "\\n")
+	if len(output) > 50 {
+		output = output[:47] + "..."
+	}
+	return output
+}
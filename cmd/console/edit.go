@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const editUsage = "usage: edit [command-send options] <all|minion <id>|tag <key=value>|region=/dc=/rack=<name>>"
+
+// defaultEditor is the editor opened when neither VISUAL nor EDITOR is set
+const defaultEditor = "vi"
+
+// editorCommand returns the editor set by the user, VISUAL being preferred to EDITOR as for other tools
+func editorCommand() string {
+	for _, variable := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(variable)); editor != "" {
+			return editor
+		}
+	}
+	return defaultEditor
+}
+
+// runEditor opens file in the editor of the user and waits for it to exit. The editor is run by the
+// shell, so that it may carry arguments such as "code --wait".
+func runEditor(file string) error {
+	editor := editorCommand()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		fields := strings.Fields(editor)
+		cmd = exec.Command(fields[0], append(fields[1:], file)...)
+	} else {
+		cmd = exec.Command("sh", "-c", editor+` "$1"`, "sh", file)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor '%s' failed: %v", editor, err)
+	}
+	return nil
+}
+
+// editAndSendCommand composes the payload of a command in the editor of the user, then dispatches it
+// as command-send would with the same options and target. Multi-line scripts and JSON payloads are
+// sent as written, without shell quoting. In local mode the target is optional, as for command-send.
+func (c *Console) editAndSendCommand(args []string) {
+	if c.local == nil {
+		if len(args) == 0 {
+			c.printError(editUsage)
+			return
+		}
+		// Reject invalid targets before opening the editor, the payload being checked once written
+		if _, err := c.parser.ParseCommand(append(append([]string{}, args...), "true")); err != nil {
+			c.printError(err.Error())
+			return
+		}
+	}
+
+	file, err := os.CreateTemp("", "minexus-payload-*.txt")
+	if err != nil {
+		c.printError(fmt.Sprintf("Failed to create payload file: %v", err))
+		return
+	}
+	defer os.Remove(file.Name())
+	file.Close()
+
+	if err := runEditor(file.Name()); err != nil {
+		c.printError(err.Error())
+		return
+	}
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		c.printError(fmt.Sprintf("Failed to read payload file: %v", err))
+		return
+	}
+
+	payload := strings.TrimRight(string(data), " \t\r\n")
+	if strings.TrimSpace(payload) == "" {
+		c.ui.PrintInfo("Empty payload, command not sent")
+		return
+	}
+	c.handleCommand("command-send", append(append([]string{}, args...), payload))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const (
+	envSetUsage   = "usage: env-set minion <id>|tag <key>=<value> <NAME>=<value>"
+	envUnsetUsage = "usage: env-unset minion <id>|tag <key>=<value> <NAME>"
+)
+
+// parseCommandEnvTarget parses the target of env-set and env-unset: minion <id> or tag <key>=<value>
+func parseCommandEnvTarget(kind, target string) (*pb.CommandEnvVar, error) {
+	switch kind {
+	case "minion":
+		return &pb.CommandEnvVar{MinionId: target}, nil
+	case "tag":
+		tagParts := strings.SplitN(target, "=", 2)
+		if len(tagParts) != 2 || tagParts[0] == "" {
+			return nil, fmt.Errorf("tag format should be key=value")
+		}
+		return &pb.CommandEnvVar{TagKey: tagParts[0], TagValue: tagParts[1]}, nil
+	default:
+		return nil, fmt.Errorf("invalid target type: %s. Use 'minion' or 'tag'", kind)
+	}
+}
+
+// commandEnvTarget describes the minions a variable is set for
+func commandEnvTarget(envVar *pb.CommandEnvVar) string {
+	if envVar.MinionId != "" {
+		return "minion " + envVar.MinionId
+	}
+	return fmt.Sprintf("tag %s=%s", envVar.TagKey, envVar.TagValue)
+}
+
+// setCommandEnv sets an environment variable Nexus adds to the shell commands of a minion or tag
+func (c *Console) setCommandEnv(ctx context.Context, args []string) {
+	if len(args) != 3 {
+		c.printError(envSetUsage)
+		return
+	}
+	envVar, err := parseCommandEnvTarget(args[0], args[1])
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+	name, value, found := strings.Cut(args[2], "=")
+	if !found || name == "" {
+		c.printError(envSetUsage)
+		return
+	}
+	envVar.Name = name
+	envVar.Value = value
+
+	if _, err := c.grpc.SetCommandEnv(ctx, envVar); err != nil {
+		c.logger.Error("Failed to set environment variable", zap.String("name", name), zap.Error(err))
+		c.printError(fmt.Sprintf("Error setting environment variable: %v", err))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("%s set for the shell commands of %s", name, commandEnvTarget(envVar)))
+}
+
+// unsetCommandEnv removes an environment variable of the shell commands of a minion or tag
+func (c *Console) unsetCommandEnv(ctx context.Context, args []string) {
+	if len(args) != 3 {
+		c.printError(envUnsetUsage)
+		return
+	}
+	envVar, err := parseCommandEnvTarget(args[0], args[1])
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+	envVar.Name = args[2]
+
+	if _, err := c.grpc.UnsetCommandEnv(ctx, envVar); err != nil {
+		c.logger.Error("Failed to unset environment variable", zap.String("name", envVar.Name), zap.Error(err))
+		c.printError(fmt.Sprintf("Error unsetting environment variable: %v", err))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("%s unset for the shell commands of %s", envVar.Name, commandEnvTarget(envVar)))
+}
+
+// listCommandEnv lists the environment variables Nexus adds to shell commands
+func (c *Console) listCommandEnv(ctx context.Context) {
+	response, err := c.grpc.ListCommandEnv(ctx)
+	if err != nil {
+		c.logger.Error("Failed to list environment variables", zap.Error(err))
+		c.printError(fmt.Sprintf("Error listing environment variables: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := CommandEnvListOutput{
+			Count: len(response.Vars),
+			Vars:  make([]CommandEnvOutput, 0, len(response.Vars)),
+		}
+		for _, envVar := range response.Vars {
+			entry := CommandEnvOutput{
+				MinionID:  envVar.MinionId,
+				Name:      envVar.Name,
+				Value:     envVar.Value,
+				UpdatedAt: envVar.UpdatedAt,
+			}
+			if envVar.MinionId == "" {
+				entry.Tag = envVar.TagKey + "=" + envVar.TagValue
+			}
+			output.Vars = append(output.Vars, entry)
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(response.Vars) == 0 {
+		c.ui.PrintInfo("No environment variable set")
+		return
+	}
+
+	fmt.Printf("%-30s  %-25s  %-16s  %s\n", "Target", "Name", "Updated", "Value")
+	for _, envVar := range response.Vars {
+		fmt.Printf("%-30s  %-25s  %-16s  %s\n",
+			commandEnvTarget(envVar), envVar.Name,
+			time.Unix(envVar.UpdatedAt, 0).Format("2006-01-02 15:04"), envVar.Value)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// targetExplainUsage is the usage of the target-explain command
+const targetExplainUsage = "usage: target-explain all | minion <id>[,<id>...] | tag <rule>[,<rule>...] [region=<r>,dc=<d>,rack=<k>] | region=<r>,dc=<d>,rack=<k>"
+
+// parseExplainTargets parses target-explain arguments. Tag rules are key=value, key (tag set) and
+// !key (tag not set), all of which must match.
+func parseExplainTargets(args []string) (*pb.ExplainTargetsRequest, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf(targetExplainUsage)
+	}
+
+	req := &pb.ExplainTargetsRequest{}
+	switch args[0] {
+	case "all":
+		if len(args) != 1 {
+			return nil, fmt.Errorf(targetExplainUsage)
+		}
+	case "minion":
+		if len(args) != 2 {
+			return nil, fmt.Errorf(targetExplainUsage)
+		}
+		for _, id := range strings.Split(args[1], ",") {
+			if id == "" {
+				return nil, fmt.Errorf("empty minion ID in %s", args[1])
+			}
+			req.MinionIds = append(req.MinionIds, id)
+		}
+	case "tag":
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf(targetExplainUsage)
+		}
+		selector, err := parseTagRules(args[1])
+		if err != nil {
+			return nil, err
+		}
+		req.TagSelector = selector
+		if len(args) == 3 {
+			topology, err := parseTopologySelector(args[2])
+			if err != nil {
+				return nil, err
+			}
+			if topology == nil {
+				return nil, fmt.Errorf("invalid failure domains %s: use region=, dc= or rack=", args[2])
+			}
+			req.Topology = topology
+		}
+	default:
+		topology, err := parseTopologySelector(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if topology == nil || len(args) != 1 {
+			return nil, fmt.Errorf(targetExplainUsage)
+		}
+		req.Topology = topology
+	}
+	return req, nil
+}
+
+// parseTagRules parses comma-separated tag rules: key=value, key (tag set) or !key (tag not set)
+func parseTagRules(value string) (*pb.TagSelector, error) {
+	selector := &pb.TagSelector{}
+	for _, rule := range strings.Split(value, ",") {
+		key, equals, hasValue := strings.Cut(rule, "=")
+		match := &pb.TagMatch{Key: key}
+		switch {
+		case hasValue:
+			match.Condition = &pb.TagMatch_Equals{Equals: equals}
+		case strings.HasPrefix(key, "!"):
+			match.Key = strings.TrimPrefix(key, "!")
+			match.Condition = &pb.TagMatch_NotExists{NotExists: true}
+		default:
+			match.Condition = &pb.TagMatch_Exists{Exists: true}
+		}
+		if match.Key == "" {
+			return nil, fmt.Errorf("invalid tag rule '%s': use key=value, key or !key", rule)
+		}
+		selector.Rules = append(selector.Rules, match)
+	}
+	return selector, nil
+}
+
+// formatRuleExplanations formats the rules of a minion, each with its outcome and the reason
+func formatRuleExplanations(rules []*pb.RuleExplanation) string {
+	if len(rules) == 0 {
+		return "no rule, every minion matches"
+	}
+	parts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		mark := "✗"
+		if rule.Matched {
+			mark = "✓"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s (%s)", mark, rule.Rule, rule.Reason))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// explainTargets shows the minions a target resolves to and why each minion matches it or not,
+// without sending a command
+func (c *Console) explainTargets(ctx context.Context, args []string) {
+	req, err := parseExplainTargets(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	explanations, err := c.grpc.ExplainTargets(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to explain targets", zap.Error(err))
+		c.printError(fmt.Sprintf("Error explaining targets: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(newTargetExplanationsOutput(explanations))
+		return
+	}
+
+	for _, id := range explanations.UnknownMinionIds {
+		c.ui.PrintWarning(fmt.Sprintf("Minion %s is not registered", id))
+	}
+	if len(explanations.Minions) == 0 {
+		c.ui.PrintInfo("No minions connected")
+		return
+	}
+	fmt.Printf("Target matches %d of %d minions:\n", explanations.Matched, len(explanations.Minions))
+	rows := make([][]string, 0, len(explanations.Minions))
+	for _, minion := range explanations.Minions {
+		matched := "no"
+		if minion.Matched {
+			matched = "yes"
+		}
+		rows = append(rows, []string{minion.MinionId, minion.Hostname, matched, formatRuleExplanations(minion.Rules)})
+	}
+	printTable([]string{"ID", "Hostname", "Match", "Rules"}, rows)
+}
+
+// newTargetExplanationsOutput converts target explanations to their JSON representation
+func newTargetExplanationsOutput(explanations *pb.TargetExplanations) TargetExplanationsOutput {
+	output := TargetExplanationsOutput{
+		Matched:          explanations.Matched,
+		Minions:          make([]TargetExplanationOutput, 0, len(explanations.Minions)),
+		UnknownMinionIDs: explanations.UnknownMinionIds,
+	}
+	for _, minion := range explanations.Minions {
+		explanation := TargetExplanationOutput{
+			ID:       minion.MinionId,
+			Hostname: minion.Hostname,
+			Matched:  minion.Matched,
+			Rules:    make([]RuleExplanationOutput, 0, len(minion.Rules)),
+		}
+		for _, rule := range minion.Rules {
+			explanation.Rules = append(explanation.Rules, RuleExplanationOutput{Rule: rule.Rule, Matched: rule.Matched, Reason: rule.Reason})
+		}
+		output.Minions = append(output.Minions, explanation)
+	}
+	return output
+}
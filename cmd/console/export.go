@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// exportPageSize is the number of results fetched per request by result-export
+const exportPageSize = 500
+
+// resultExportCSVHeader are the columns of a CSV result export
+var resultExportCSVHeader = []string{"command_id", "minion_id", "exit_code", "timestamp", "stdout", "stderr"}
+
+// resultExportOptions are the parsed result-export arguments
+type resultExportOptions struct {
+	commandID string
+	format    string // csv or json
+	out       string // empty: standard output
+}
+
+// parseResultExport parses result-export arguments:
+// <command-id> [--format csv|json] [--out <file>]. Without --format, the format
+// is deduced from the extension of the output file, defaulting to CSV.
+func parseResultExport(args []string) (*resultExportOptions, error) {
+	usage := fmt.Errorf("usage: result-export <command-id> [--format csv|json] [--out <file>]")
+
+	opts := &resultExportOptions{}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format", "--out":
+			if i+1 >= len(args) {
+				return nil, usage
+			}
+			if args[i] == "--format" {
+				opts.format = strings.ToLower(args[i+1])
+			} else {
+				opts.out = args[i+1]
+			}
+			i++
+		default:
+			if opts.commandID != "" || strings.HasPrefix(args[i], "--") {
+				return nil, usage
+			}
+			opts.commandID = args[i]
+		}
+	}
+	if opts.commandID == "" {
+		return nil, usage
+	}
+
+	if opts.format == "" {
+		opts.format = "csv"
+		if strings.EqualFold(filepath.Ext(opts.out), ".json") {
+			opts.format = "json"
+		}
+	}
+	if opts.format != "csv" && opts.format != "json" {
+		return nil, fmt.Errorf("invalid format '%s', use csv or json", opts.format)
+	}
+	if opts.out == "-" {
+		opts.out = ""
+	}
+	return opts, nil
+}
+
+// fetchAllResults retrieves all the results of a command, page by page
+func (c *Console) fetchAllResults(ctx context.Context, commandID string) ([]*pb.CommandResult, error) {
+	var results []*pb.CommandResult
+	for {
+		page, err := c.grpc.GetCommandResults(ctx, &pb.ResultRequest{
+			CommandId: commandID,
+			Limit:     exportPageSize,
+			Offset:    int32(len(results)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, page.Results...)
+		if !page.HasMore || len(page.Results) == 0 {
+			return results, nil
+		}
+	}
+}
+
+// writeResultsCSV writes one row per minion result
+func writeResultsCSV(w io.Writer, results []*pb.CommandResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(resultExportCSVHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		row := []string{
+			result.CommandId,
+			result.MinionId,
+			strconv.Itoa(int(result.ExitCode)),
+			time.Unix(result.Timestamp, 0).UTC().Format(time.RFC3339),
+			result.Stdout,
+			result.Stderr,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeResultsJSON writes the results as result-get does in JSON output mode
+func writeResultsJSON(w io.Writer, commandID string, results []*pb.CommandResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ResultListOutput{
+		CommandID: commandID,
+		Count:     len(results),
+		Results:   newResultOutputs(results),
+	})
+}
+
+// exportResults writes all the results of a command to a CSV or JSON file
+func (c *Console) exportResults(ctx context.Context, args []string) {
+	opts, err := parseResultExport(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	results, err := c.fetchAllResults(ctx, opts.commandID)
+	if err != nil {
+		c.logger.Error("Failed to fetch command results", zap.String("command_id", opts.commandID), zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting results: %v", err))
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if opts.out != "" {
+		file, err := os.Create(opts.out)
+		if err != nil {
+			c.printError(fmt.Sprintf("Error creating export file: %v", err))
+			return
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if opts.format == "json" {
+		err = writeResultsJSON(w, opts.commandID, results)
+	} else {
+		err = writeResultsCSV(w, results)
+	}
+	if err != nil {
+		c.printError(fmt.Sprintf("Error writing export: %v", err))
+		return
+	}
+
+	if opts.out == "" {
+		return
+	}
+	if c.isJSONOutput() {
+		printJSON(ResultExportOutput{
+			CommandID: opts.commandID,
+			Format:    opts.format,
+			File:      opts.out,
+			Count:     len(results),
+		})
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Exported %d results of command %s to %s", len(results), opts.commandID, opts.out))
+}
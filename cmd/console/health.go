@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// fleetHealthUsage is the usage of the fleet-health command
+const fleetHealthUsage = "usage: fleet-health [--below <score>]"
+
+// parseFleetHealthRequest parses fleet-health arguments, Nexus defaulting to the minions scoring below 80
+func parseFleetHealthRequest(args []string) (*pb.FleetHealthRequest, error) {
+	req := &pb.FleetHealthRequest{}
+	if len(args) == 0 {
+		return req, nil
+	}
+	if len(args) != 2 || args[0] != "--below" {
+		return nil, fmt.Errorf(fleetHealthUsage)
+	}
+	below, err := strconv.Atoi(args[1])
+	if err != nil || below < 1 || below > 101 {
+		return nil, fmt.Errorf("invalid score '%s', must be between 1 and 101", args[1])
+	}
+	req.Below = int32(below)
+	return req, nil
+}
+
+// formatHealth formats the health score of a minion, "-" when Nexus didn't compute it
+func formatHealth(health *pb.MinionHealth) string {
+	if health == nil {
+		return "-"
+	}
+	return strconv.Itoa(int(health.Score))
+}
+
+// newMinionHealthOutput converts the health of a minion to its JSON representation
+func newMinionHealthOutput(health *pb.MinionHealth) *MinionHealthOutput {
+	if health == nil {
+		return nil
+	}
+	anomalies := health.Anomalies
+	if anomalies == nil {
+		anomalies = []string{}
+	}
+	return &MinionHealthOutput{
+		Score:               health.Score,
+		Anomalies:           anomalies,
+		Reconnects:          health.Reconnects,
+		Results:             health.Results,
+		FailedResults:       health.FailedResults,
+		MeanLatencyMs:       health.MeanLatencyMs,
+		HeartbeatIntervalMs: health.HeartbeatIntervalMs,
+	}
+}
+
+// showFleetHealth shows the health summary of the fleet and the minions scoring below a threshold
+func (c *Console) showFleetHealth(ctx context.Context, args []string) {
+	req, err := parseFleetHealthRequest(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	fleet, err := c.grpc.GetFleetHealth(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to get fleet health", zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting fleet health: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := FleetHealthOutput{
+			Total:        fleet.Total,
+			Healthy:      fleet.Healthy,
+			Degraded:     fleet.Degraded,
+			Unhealthy:    fleet.Unhealthy,
+			AverageScore: fleet.AverageScore,
+			Minions:      make([]FleetHealthMinionOutput, 0, len(fleet.Minions)),
+		}
+		for _, minion := range fleet.Minions {
+			output.Minions = append(output.Minions, FleetHealthMinionOutput{
+				ID:       minion.Id,
+				Hostname: minion.Hostname,
+				Health:   newMinionHealthOutput(minion.Health),
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	if fleet.Total == 0 {
+		c.ui.PrintInfo("No minions connected")
+		return
+	}
+	fmt.Printf("Fleet health over the last hour (%d minions, average score %d):\n", fleet.Total, fleet.AverageScore)
+	fmt.Printf("  Healthy:   %d\n", fleet.Healthy)
+	fmt.Printf("  Degraded:  %d\n", fleet.Degraded)
+	fmt.Printf("  Unhealthy: %d\n", fleet.Unhealthy)
+
+	if len(fleet.Minions) == 0 {
+		c.ui.PrintSuccess("No anomalous minion")
+		return
+	}
+	fmt.Printf("\nAnomalous minions (%d):\n", len(fleet.Minions))
+	rows := make([][]string, 0, len(fleet.Minions))
+	for _, minion := range fleet.Minions {
+		health := minion.Health
+		if health == nil {
+			health = &pb.MinionHealth{}
+		}
+		latency := "-"
+		if health.MeanLatencyMs > 0 {
+			latency = formatHistoryDuration(health.MeanLatencyMs)
+		}
+		rows = append(rows, []string{
+			minion.Id,
+			minion.Hostname,
+			formatHealth(minion.Health),
+			strconv.Itoa(int(health.Reconnects)),
+			fmt.Sprintf("%d/%d", health.FailedResults, health.Results),
+			latency,
+			strings.Join(health.Anomalies, ", "),
+		})
+	}
+	printTable([]string{"ID", "Hostname", "Score", "Reconnects", "Failed", "Latency", "Anomalies"}, rows)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxHistoryCommandWidth is the width of the command column of minion-history
+const maxHistoryCommandWidth = 40
+
+// showMinionHistory shows the last commands executed on a minion
+func (c *Console) showMinionHistory(ctx context.Context, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		c.printError("Usage: minion-history <minion-id> [count]")
+		return
+	}
+	limit := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			c.printError(fmt.Sprintf("Invalid count '%s', must be a positive integer", args[1]))
+			return
+		}
+		limit = n
+	}
+
+	history, err := c.grpc.GetMinionHistory(ctx, args[0], limit)
+	if err != nil {
+		c.logger.Error("Failed to get minion history", zap.String("minion_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting minion history: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := MinionHistoryOutput{
+			MinionID: history.MinionId,
+			Count:    len(history.Entries),
+			Commands: make([]MinionHistoryEntryOutput, 0, len(history.Entries)),
+		}
+		for _, entry := range history.Entries {
+			commandOutput := MinionHistoryEntryOutput{
+				CommandID: entry.CommandId,
+				Command:   entry.Command,
+				Status:    entry.Status,
+				Timestamp: entry.Timestamp,
+			}
+			if entry.HasResult {
+				exitCode, duration := entry.ExitCode, entry.DurationMs
+				commandOutput.ExitCode = &exitCode
+				commandOutput.DurationMs = &duration
+			}
+			output.Commands = append(output.Commands, commandOutput)
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(history.Entries) == 0 {
+		c.ui.PrintInfo(fmt.Sprintf("No command executed on minion %s", history.MinionId))
+		return
+	}
+
+	fmt.Printf("Last commands on minion %s (%d):\n", history.MinionId, len(history.Entries))
+	fmt.Printf("%-19s  %-9s  %-4s  %-9s  %-36s  %s\n", "Sent", "Status", "Exit", "Duration", "Command ID", "Command")
+	for _, entry := range history.Entries {
+		exitCode, duration := "-", "-"
+		if entry.HasResult {
+			exitCode = strconv.Itoa(int(entry.ExitCode))
+			duration = formatHistoryDuration(entry.DurationMs)
+		}
+		fmt.Printf("%-19s  %-9s  %-4s  %-9s  %-36s  %s\n",
+			c.formatUnixTime(entry.Timestamp), entry.Status, exitCode, duration, entry.CommandId,
+			truncateHistoryCommand(entry.Command))
+	}
+}
+
+// formatHistoryDuration formats a command duration in milliseconds for display
+func formatHistoryDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d < time.Second {
+		return fmt.Sprintf("%dms", ms)
+	}
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+// truncateHistoryCommand shortens long commands to the width of the command column
+func truncateHistoryCommand(command string) string {
+	runes := []rune(command)
+	if len(runes) <= maxHistoryCommandWidth {
+		return command
+	}
+	return string(runes[:maxHistoryCommandWidth-3]) + "..."
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// inspectMinion shows the connection diagnostics of a minion
+func (c *Console) inspectMinion(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.printError("Usage: minion-inspect <minion-id>")
+		return
+	}
+
+	diag, err := c.grpc.GetMinionDiagnostics(ctx, args[0])
+	if err != nil {
+		c.logger.Error("Failed to get minion diagnostics", zap.String("minion_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error inspecting minion: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(newMinionDiagnosticsOutput(diag))
+		return
+	}
+
+	fmt.Printf("Minion %s\n", diag.MinionId)
+	fmt.Printf("  Registered        : %t\n", diag.Registered)
+	fmt.Printf("  Stream state      : %s (%d active)\n", diag.StreamState, diag.ActiveStreams)
+	fmt.Printf("  Stream opened     : %s\n", c.formatUnixTime(diag.StreamConnectedAt))
+	fmt.Printf("  Last seen         : %s\n", c.formatUnixTime(diag.LastSeen))
+	fmt.Printf("  Channel depth     : %d/%d\n", diag.ChannelDepth, diag.ChannelCapacity)
+	fmt.Printf("  Pending commands  : %d\n", diag.PendingCommands)
+	if diag.InFlightLimit > 0 {
+		fmt.Printf("  In flight         : %d/%d\n", diag.InFlight, diag.InFlightLimit)
+	}
+	fmt.Printf("  Held commands     : %d\n", diag.HeldCommands)
+	fmt.Printf("  Commands sent     : %d\n", diag.CommandsSent)
+	fmt.Printf("  Results received  : %d\n", diag.ResultsReceived)
+	if diag.LastError != "" {
+		fmt.Printf("  Last error        : %s (%s)\n", diag.LastError, c.formatUnixTime(diag.LastErrorAt))
+	}
+	if test := diag.LastSpeedTest; test != nil {
+		fmt.Printf("  Last speed test   : %.2f ms, up %.2f Mbit/s, down %.2f Mbit/s (%s, %s)\n",
+			test.LatencyMs, test.UploadMbps, test.DownloadMbps, c.formatUnixTime(test.Timestamp), test.CommandId)
+	}
+	if diag.ActiveStreams > 1 {
+		c.ui.PrintWarning(fmt.Sprintf("%d concurrent command streams are open for this minion", diag.ActiveStreams))
+	}
+
+	if len(diag.Events) == 0 {
+		return
+	}
+	fmt.Println("Connection history:")
+	for _, event := range diag.Events {
+		if event.Detail != "" {
+			fmt.Printf("  %s  %-14s %s\n", c.formatUnixTime(event.Timestamp), event.Event, event.Detail)
+		} else {
+			fmt.Printf("  %s  %s\n", c.formatUnixTime(event.Timestamp), event.Event)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// localMinionID identifies results of commands run in local mode
+const localMinionID = "local"
+
+// localExecutor runs commands with the minion command handlers on this machine
+type localExecutor struct {
+	registry *command.Registry
+	atom     *zap.AtomicLevel
+	results  map[string]*pb.CommandResult // command_id -> result, for result-get
+}
+
+// NewLocalConsole creates a console running commands locally instead of sending them to Nexus,
+// to test command implementations without Nexus, a database or a minion
+func NewLocalConsole(logger *zap.Logger, atom *zap.AtomicLevel) *Console {
+	console := newConsole(logger)
+	console.local = &localExecutor{
+		registry: console.parser.registry,
+		atom:     atom,
+		results:  make(map[string]*pb.CommandResult),
+	}
+	return console
+}
+
+// handleLocalCommand handles commands whose behavior differs in local mode,
+// it returns false for commands that behave the same with or without Nexus
+func (c *Console) handleLocalCommand(command string, args []string) bool {
+	switch command {
+	case "command-send", "cmd":
+		c.sendLocalCommand(args)
+	case "result-get", "results":
+		c.getLocalResults(args)
+	case "minion-list", "lm", "tag-list", "lt", "minion-inspect", "minion-history", "minion-uptime", "minion-logs", "minion-retire", "crash-list", "tag-set", "tag-update",
+		"tag-schema-show", "command-status", "result-export", "maintenance-add", "maintenance-list", "maintenance-remove", "env-set", "env-unset", "env-list",
+		"report-create", "report-list", "report-run", "stats", "fleet-health", "fleet-versions", "fleet-patches", "compliance-export", "target-explain", "pick", "db-query", "shell", "file-pull", "file-download", "file-transfers", "connect", "minion-bootstrap-url",
+		"admin-flush-caches", "admin-log-level", "admin-registry", "admin-disconnect", "admin-unbind", "admin-prune":
+		c.printError(fmt.Sprintf("'%s' requires a Nexus connection, not available in local mode", command))
+	default:
+		return false
+	}
+	return true
+}
+
+// sendLocalCommand runs a command locally. The target is optional and ignored:
+// both 'command-send all system:info' and 'command-send system:info' run on this machine
+func (c *Console) sendLocalCommand(args []string) {
+	if len(args) == 0 {
+		c.ui.PrintInfo(c.parser.ShowSendCommandHelp())
+		return
+	}
+
+	parsed, err := c.parser.ParseCommand(args)
+	if err != nil {
+		// Retry without target, keeping the original error if the command is invalid too
+		withTarget, retryErr := c.parser.ParseCommand(append([]string{"all"}, args...))
+		if retryErr != nil {
+			c.printError(err.Error())
+			return
+		}
+		parsed = withTarget
+	}
+	if parsed.Validate {
+		c.showValidation(parsed, c.parser.CheckArguments(parsed))
+		return
+	}
+
+	if parsed.DryRun {
+		if c.isJSONOutput() {
+			printJSON(CommandSendOutput{
+				Accepted: true,
+				DryRun:   true,
+				Payload:  parsed.CommandText,
+				Targets:  []string{localMinionID},
+				Results:  []ResultOutput{},
+			})
+			return
+		}
+		fmt.Printf("Dry run: '%s' would run locally\n", parsed.CommandText)
+		return
+	}
+
+	result := c.local.execute(c.logger, parsed.Request.Command)
+	c.local.results[result.CommandId] = result
+	c.ui.AddToHistory(fmt.Sprintf("result-get %s", result.CommandId))
+
+	if c.isJSONOutput() {
+		printJSON(CommandSendOutput{
+			Accepted:  true,
+			CommandID: result.CommandId,
+			Payload:   parsed.CommandText,
+			Targets:   []string{localMinionID},
+			Results:   newResultOutputs([]*pb.CommandResult{result}),
+		})
+		return
+	}
+
+	fmt.Printf("Command executed locally. Command ID: %s\n", result.CommandId)
+	c.printResultTable([]*pb.CommandResult{result})
+}
+
+// getLocalResults shows the result of a command previously run locally
+func (c *Console) getLocalResults(args []string) {
+	if len(args) != 1 {
+		c.ui.PrintError("Usage: result-get <command-id>")
+		return
+	}
+
+	commandID := args[0]
+	var results []*pb.CommandResult
+	if result, ok := c.local.results[commandID]; ok {
+		results = append(results, result)
+	}
+
+	if c.isJSONOutput() {
+		printJSON(ResultListOutput{
+			CommandID: commandID,
+			Count:     len(results),
+			Results:   newResultOutputs(results),
+		})
+		return
+	}
+
+	if len(results) == 0 {
+		c.ui.PrintInfo("No local result for command " + commandID)
+		return
+	}
+	fmt.Printf("Command results (%d):\n", len(results))
+	c.printResultTable(results)
+}
+
+// execute runs a command through the registry like a minion would
+func (l *localExecutor) execute(logger *zap.Logger, cmd *pb.Command) *pb.CommandResult {
+	execCtx := command.NewExecutionContext(context.Background(), logger, l.atom, localMinionID, cmd.Id)
+
+	result, err := l.registry.Execute(execCtx, cmd)
+	if result == nil {
+		result = &pb.CommandResult{
+			CommandId: cmd.Id,
+			ExitCode:  1,
+		}
+		if err != nil {
+			result.Stderr = err.Error()
+		}
+	}
+	result.CommandId = cmd.Id
+	result.MinionId = localMinionID
+	if result.Timestamp == 0 {
+		result.Timestamp = time.Now().Unix()
+	}
+	return result
+}
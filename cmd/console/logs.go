@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const minionLogsUsage = "usage: minion-logs <minion-id> [--level <debug|info|warn|error>] [--since <duration|time>] [--limit <n>]"
+
+// parseMinionLogsRequest parses minion-logs arguments, Nexus defaulting to the last 100 entries at every level
+func parseMinionLogsRequest(args []string, now time.Time) (*pb.MinionLogsRequest, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf(minionLogsUsage)
+	}
+	req := &pb.MinionLogsRequest{MinionId: args[0]}
+	for i := 1; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf(minionLogsUsage)
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--level":
+			req.MinLevel = value
+		case "--since":
+			t, err := parseStatsTime(value, now)
+			if err != nil {
+				return nil, err
+			}
+			req.Since = t.Unix()
+		case "--limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid limit '%s', must be a positive integer", value)
+			}
+			req.Limit = int32(n)
+		default:
+			return nil, fmt.Errorf(minionLogsUsage)
+		}
+		i++
+	}
+	return req, nil
+}
+
+// showMinionLogs shows the logs a minion shipped to Nexus, oldest first
+func (c *Console) showMinionLogs(ctx context.Context, args []string) {
+	req, err := parseMinionLogsRequest(args, time.Now())
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	logs, err := c.grpc.GetMinionLogs(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to get minion logs", zap.String("minion_id", req.MinionId), zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting minion logs: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := MinionLogsOutput{
+			MinionID: logs.MinionId,
+			Count:    len(logs.Entries),
+			Entries:  make([]MinionLogEntryOutput, 0, len(logs.Entries)),
+		}
+		for _, entry := range logs.Entries {
+			output.Entries = append(output.Entries, MinionLogEntryOutput{
+				TimestampMs: entry.TimestampMs,
+				Level:       entry.Level,
+				Logger:      entry.Logger,
+				Message:     entry.Message,
+				Caller:      entry.Caller,
+				Fields:      entry.Fields,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(logs.Entries) == 0 {
+		c.ui.PrintInfo(fmt.Sprintf("No log shipped by minion %s", logs.MinionId))
+		return
+	}
+
+	// Nexus returns the most recent entries first, shown in the order they were logged
+	for i := len(logs.Entries) - 1; i >= 0; i-- {
+		entry := logs.Entries[i]
+		timestamp := time.UnixMilli(entry.TimestampMs).Format("2006-01-02 15:04:05.000")
+		fmt.Printf("%s  %-5s  %s", timestamp, entry.Level, entry.Message)
+		if entry.Caller != "" {
+			fmt.Printf("  (%s)", entry.Caller)
+		}
+		if entry.Fields != "" {
+			fmt.Printf("  %s", entry.Fields)
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// maintenanceTimeLayouts are the accepted formats for maintenance window boundaries.
+// Layouts without a zone are interpreted in local time.
+var maintenanceTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// parseMaintenanceTime parses a maintenance window boundary
+func parseMaintenanceTime(value string) (time.Time, error) {
+	for _, layout := range maintenanceTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', use RFC3339 or YYYY-MM-DDTHH:MM", value)
+}
+
+// parseMaintenanceWindow parses maintenance-add arguments:
+// minion <id> <start> <end> or tag <key>=<value> <start> <end>
+func parseMaintenanceWindow(args []string) (*pb.MaintenanceWindow, error) {
+	if len(args) != 4 {
+		return nil, fmt.Errorf("usage: maintenance-add minion <id>|tag <key>=<value> <start> <end>")
+	}
+
+	window := &pb.MaintenanceWindow{}
+	switch args[0] {
+	case "minion":
+		window.MinionId = args[1]
+	case "tag":
+		tagParts := strings.SplitN(args[1], "=", 2)
+		if len(tagParts) != 2 || tagParts[0] == "" {
+			return nil, fmt.Errorf("tag format should be key=value")
+		}
+		window.TagSelector = &pb.TagSelector{
+			Rules: []*pb.TagMatch{
+				{
+					Key:       tagParts[0],
+					Condition: &pb.TagMatch_Equals{Equals: tagParts[1]},
+				},
+			},
+		}
+	default:
+		return nil, fmt.Errorf("invalid target type: %s. Use 'minion' or 'tag'", args[0])
+	}
+
+	start, err := parseMaintenanceTime(args[2])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseMaintenanceTime(args[3])
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("window end must be after its start")
+	}
+
+	window.Start = start.Unix()
+	window.End = end.Unix()
+	return window, nil
+}
+
+// maintenanceTarget describes the minions covered by a window
+func maintenanceTarget(window *pb.MaintenanceWindow) string {
+	if window.MinionId != "" {
+		return "minion " + window.MinionId
+	}
+	var rules []string
+	for _, rule := range window.GetTagSelector().GetRules() {
+		rules = append(rules, fmt.Sprintf("%s=%s", rule.Key, rule.GetEquals()))
+	}
+	return "tag " + strings.Join(rules, ",")
+}
+
+// newMaintenanceWindowOutput converts a window to its JSON representation
+func newMaintenanceWindowOutput(window *pb.MaintenanceWindow) MaintenanceWindowOutput {
+	output := MaintenanceWindowOutput{
+		ID:       window.Id,
+		MinionID: window.MinionId,
+		Start:    window.Start,
+		End:      window.End,
+	}
+	if window.MinionId == "" {
+		output.Tag = strings.TrimPrefix(maintenanceTarget(window), "tag ")
+	}
+	return output
+}
+
+// addMaintenanceWindow declares a maintenance window on the nexus
+func (c *Console) addMaintenanceWindow(ctx context.Context, args []string) {
+	window, err := parseMaintenanceWindow(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	created, err := c.grpc.AddMaintenanceWindow(ctx, window)
+	if err != nil {
+		c.logger.Error("Failed to add maintenance window", zap.Error(err))
+		c.printError(fmt.Sprintf("Error adding maintenance window: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(newMaintenanceWindowOutput(created))
+		return
+	}
+
+	c.ui.PrintSuccess(fmt.Sprintf("Maintenance window %s added for %s (%s - %s)",
+		created.Id, maintenanceTarget(created),
+		time.Unix(created.Start, 0).Format("2006-01-02 15:04"),
+		time.Unix(created.End, 0).Format("2006-01-02 15:04")))
+}
+
+// listMaintenanceWindows lists current and upcoming maintenance windows
+func (c *Console) listMaintenanceWindows(ctx context.Context) {
+	response, err := c.grpc.ListMaintenanceWindows(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error listing maintenance windows: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := MaintenanceListOutput{
+			Count:        len(response.Windows),
+			HeldCommands: response.HeldCommands,
+			Windows:      make([]MaintenanceWindowOutput, 0, len(response.Windows)),
+		}
+		for _, window := range response.Windows {
+			output.Windows = append(output.Windows, newMaintenanceWindowOutput(window))
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(response.Windows) == 0 {
+		c.ui.PrintInfo("No maintenance windows declared")
+		return
+	}
+
+	now := time.Now().Unix()
+	fmt.Printf("Maintenance windows (%d), held commands: %d\n", len(response.Windows), response.HeldCommands)
+	fmt.Println("ID               | Target                         | Start            | End              | State")
+	fmt.Println("---------------- | ------------------------------ | ---------------- | ---------------- | --------")
+	for _, window := range response.Windows {
+		state := "upcoming"
+		if window.Start <= now {
+			state = "open"
+		}
+		fmt.Printf("%-16s | %-30s | %-16s | %-16s | %s\n",
+			window.Id, maintenanceTarget(window),
+			time.Unix(window.Start, 0).Format("2006-01-02 15:04"),
+			time.Unix(window.End, 0).Format("2006-01-02 15:04"),
+			state)
+	}
+}
+
+// removeMaintenanceWindow deletes a maintenance window
+func (c *Console) removeMaintenanceWindow(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.printError("usage: maintenance-remove <window-id>")
+		return
+	}
+
+	if _, err := c.grpc.RemoveMaintenanceWindow(ctx, args[0]); err != nil {
+		c.printError(fmt.Sprintf("Error removing maintenance window: %v", err))
+		return
+	}
+
+	c.ui.PrintSuccess(fmt.Sprintf("Maintenance window %s removed", args[0]))
+}
@@ -0,0 +1,744 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// Supported console output formats
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// MinionOutput is the JSON representation of a connected minion
+type MinionOutput struct {
+	ID           string            `json:"id"`
+	Hostname     string            `json:"hostname"`
+	IP           string            `json:"ip"`
+	OS           string            `json:"os"`
+	Namespace    string            `json:"namespace,omitempty"`
+	Region       string            `json:"region,omitempty"`
+	Datacenter   string            `json:"datacenter,omitempty"`
+	Rack         string            `json:"rack,omitempty"`
+	LastSeen     int64             `json:"last_seen"`
+	Tags         map[string]string `json:"tags"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+	// Versions of the command families the minion supports
+	CommandVersions map[string]int32    `json:"command_versions,omitempty"`
+	Health          *MinionHealthOutput `json:"health,omitempty"`
+	QueuedCommands  int32               `json:"queued_commands"`
+	// Availability windows of power-aware minions, which sleep outside them
+	Availability string `json:"availability,omitempty"`
+	Sleeping     bool   `json:"sleeping,omitempty"`
+	NextWake     int64  `json:"next_wake,omitempty"`
+	// Reboot or shutdown announced by the minion
+	PowerAction string `json:"power_action,omitempty"`
+	PowerAt     int64  `json:"power_at,omitempty"`
+	PoweredDown bool   `json:"powered_down,omitempty"`
+	// Build of the minion, empty for minions predating version reporting
+	Version         string `json:"version,omitempty"`
+	BuildCommit     string `json:"build_commit,omitempty"`
+	ProtocolVersion int32  `json:"protocol_version"`
+	Outdated        bool   `json:"outdated,omitempty"`
+}
+
+// MinionHealthOutput is the JSON representation of the health of a minion
+type MinionHealthOutput struct {
+	Score               int32    `json:"score"`
+	Anomalies           []string `json:"anomalies"`
+	Reconnects          int32    `json:"reconnects"`
+	Results             int32    `json:"results"`
+	FailedResults       int32    `json:"failed_results"`
+	MeanLatencyMs       int64    `json:"mean_latency_ms"`
+	HeartbeatIntervalMs int64    `json:"heartbeat_interval_ms"`
+}
+
+// MinionListOutput is the JSON representation of the minion-list command
+type MinionListOutput struct {
+	Count   int            `json:"count"`
+	Minions []MinionOutput `json:"minions"`
+}
+
+// TagListOutput is the JSON representation of the tag-list command
+type TagListOutput struct {
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+// TagSchemaKeyOutput is the JSON representation of a key of the tag schema
+type TagSchemaKeyOutput struct {
+	Key           string   `json:"key"`
+	Description   string   `json:"description,omitempty"`
+	AllowedValues []string `json:"allowed_values,omitempty"`
+	Pattern       string   `json:"pattern,omitempty"`
+}
+
+// TagSchemaOutput is the JSON representation of the tag-schema-show command
+type TagSchemaOutput struct {
+	Enabled        bool                 `json:"enabled"`
+	AllowOtherKeys bool                 `json:"allow_other_keys"`
+	Keys           []TagSchemaKeyOutput `json:"keys"`
+}
+
+// ResultOutput is the JSON representation of a single command result
+type ResultOutput struct {
+	CommandID   string           `json:"command_id"`
+	MinionID    string           `json:"minion_id"`
+	ExitCode    int32            `json:"exit_code"`
+	Stdout      string           `json:"stdout"`
+	Stderr      string           `json:"stderr"`
+	Timestamp   int64            `json:"timestamp"`
+	Attempt     int32            `json:"attempt,omitempty"` // dispatch the result comes from, above 1 when retried
+	ContentType string           `json:"content_type,omitempty"`
+	Data        json.RawMessage  `json:"data,omitempty"` // structured payload, if any
+	Execution   *ExecutionOutput `json:"execution,omitempty"`
+}
+
+// ExecutionOutput is the JSON representation of how a minion ran a command
+type ExecutionOutput struct {
+	WorkingDir string `json:"working_dir,omitempty"`
+	User       string `json:"user,omitempty"`
+	Path       string `json:"path,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	MaxRSSKB   int64  `json:"max_rss_kb,omitempty"`
+}
+
+// ResultListOutput is the JSON representation of the result-get command
+type ResultListOutput struct {
+	CommandID string         `json:"command_id"`
+	Count     int            `json:"count"`
+	Results   []ResultOutput `json:"results"`
+	Omitted   int32          `json:"omitted_results,omitempty"` // successful results counted but not stored
+}
+
+// ResultExportOutput is the JSON representation of the result-export command
+type ResultExportOutput struct {
+	CommandID string `json:"command_id"`
+	Format    string `json:"format"`
+	File      string `json:"file"`
+	Count     int    `json:"count"`
+}
+
+// CommandSendOutput is the JSON representation of the command-send command
+type CommandSendOutput struct {
+	Accepted  bool           `json:"accepted"`
+	DryRun    bool           `json:"dry_run"`
+	CommandID string         `json:"command_id"`
+	TraceID   string         `json:"trace_id,omitempty"`
+	Payload   string         `json:"payload"`
+	Impact    string         `json:"impact,omitempty"` // impact class Nexus resolved for the command
+	Targets   []string       `json:"targets"`
+	Held      []string       `json:"held,omitempty"`
+	Skipped   []string       `json:"skipped,omitempty"` // minions lacking the capability or command version the command requires
+	Results   []ResultOutput `json:"results"`
+
+	// Minions predating command versioning, which may not support the options of the command
+	Unversioned []string `json:"unversioned,omitempty"`
+	// Minions for which the command waits until its host lock is free
+	LockWaiting []string `json:"lock_waiting,omitempty"`
+	// Minions the command reaches later, its rollout staggered by the dispatch rate limits
+	Staggered []string `json:"staggered,omitempty"`
+	// Set when the command environment couldn't be loaded, the command being sent without it
+	EnvUnavailable bool `json:"env_unavailable,omitempty"`
+	// Minions predating the command environment, running the command without it
+	EnvUnsupported []string `json:"env_unsupported,omitempty"`
+
+	// Set when the command is destructive and must be resent with --confirm <confirm_token>
+	ConfirmationRequired bool   `json:"confirmation_required,omitempty"`
+	ConfirmToken         string `json:"confirm_token,omitempty"`
+	DestructiveReason    string `json:"destructive_reason,omitempty"`
+
+	// Set when the command awaits the approval of another operator, command_id identifying it
+	ApprovalRequired bool   `json:"approval_required,omitempty"`
+	ApprovalReason   string `json:"approval_reason,omitempty"`
+}
+
+// CommandApprovalOutput is the JSON representation of a command requiring approval
+type CommandApprovalOutput struct {
+	CommandID   string   `json:"command_id"`
+	Payload     string   `json:"payload"`
+	Targets     []string `json:"targets"`
+	Reason      string   `json:"reason"`
+	Status      string   `json:"status"`
+	RequestedBy string   `json:"requested_by"`
+	RequestedAt int64    `json:"requested_at"`
+	DecidedBy   string   `json:"decided_by,omitempty"`
+	DecidedAt   int64    `json:"decided_at,omitempty"`
+	Comment     string   `json:"comment,omitempty"`
+	Impact      string   `json:"impact,omitempty"` // empty for approvals stored before impact classes
+}
+
+// CommandApprovalListOutput is the JSON representation of the command-approvals command
+type CommandApprovalListOutput struct {
+	Count     int                     `json:"count"`
+	ByImpact  map[string]int          `json:"by_impact"` // approvals of each impact class
+	Approvals []CommandApprovalOutput `json:"approvals"`
+}
+
+// MaintenanceWindowOutput is the JSON representation of a maintenance window
+type MaintenanceWindowOutput struct {
+	ID       string `json:"id"`
+	MinionID string `json:"minion_id,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Start    int64  `json:"start"`
+	End      int64  `json:"end"`
+}
+
+// MaintenanceListOutput is the JSON representation of the maintenance-list command
+type MaintenanceListOutput struct {
+	Count        int                       `json:"count"`
+	HeldCommands int32                     `json:"held_commands"`
+	Windows      []MaintenanceWindowOutput `json:"windows"`
+}
+
+// CommandEnvOutput is the JSON representation of an environment variable of shell commands
+type CommandEnvOutput struct {
+	MinionID  string `json:"minion_id,omitempty"`
+	Tag       string `json:"tag,omitempty"`
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// CommandEnvListOutput is the JSON representation of the env-list command
+type CommandEnvListOutput struct {
+	Count int                `json:"count"`
+	Vars  []CommandEnvOutput `json:"vars"`
+}
+
+// ReportOutput is the JSON representation of a saved report
+type ReportOutput struct {
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Description string `json:"description,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// ReportListOutput is the JSON representation of the report-list command
+type ReportListOutput struct {
+	Count   int            `json:"count"`
+	Reports []ReportOutput `json:"reports"`
+}
+
+// ReportResultOutput is the JSON representation of the report-run command
+type ReportResultOutput struct {
+	Name    string     `json:"name"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// DatabaseCheckEntryOutput is the JSON representation of a database integrity check
+type DatabaseCheckEntryOutput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Found       int64  `json:"found"`
+	Repaired    int64  `json:"repaired"`
+	Repairable  bool   `json:"repairable"`
+	Error       string `json:"error,omitempty"`
+}
+
+// DatabaseCheckOutput is the JSON representation of the db-check command
+type DatabaseCheckOutput struct {
+	Healthy bool                       `json:"healthy"`
+	Checks  []DatabaseCheckEntryOutput `json:"checks"`
+}
+
+// FlushCachesOutput is the JSON representation of the admin-flush-caches command
+type FlushCachesOutput struct {
+	PendingCommands int32 `json:"pending_commands"`
+	Diagnostics     int32 `json:"diagnostics"`
+}
+
+// LogLevelOutput is the JSON representation of the admin-log-level command
+type LogLevelOutput struct {
+	Level            string `json:"level"`
+	PreviousLevel    string `json:"previous_level"`
+	Sampling         string `json:"sampling,omitempty"`
+	PreviousSampling string `json:"previous_sampling,omitempty"`
+}
+
+// PruneDatabaseOutput is the JSON representation of the admin-prune command
+type PruneDatabaseOutput struct {
+	ArchivedCommands int32 `json:"archived_commands"`
+	PrunedLogs       int64 `json:"pruned_logs"`
+}
+
+// RegistryEntryOutput is the JSON representation of a minion in the admin-registry command
+type RegistryEntryOutput struct {
+	MinionID        string `json:"minion_id"`
+	Hostname        string `json:"hostname"`
+	LastSeen        int64  `json:"last_seen"`
+	Connected       bool   `json:"connected"`
+	QueuedCommands  int32  `json:"queued_commands"`
+	InFlight        int32  `json:"in_flight"`
+	PendingCommands int32  `json:"pending_commands"`
+	IDCollisions    int32  `json:"id_collisions,omitempty"`
+	LastIDCollision int64  `json:"last_id_collision,omitempty"`
+}
+
+// RegistryDumpOutput is the JSON representation of the admin-registry command
+type RegistryDumpOutput struct {
+	Minions         []RegistryEntryOutput `json:"minions"`
+	Connected       int32                 `json:"connected"`
+	PendingCommands int32                 `json:"pending_commands"`
+	HeldCommands    int32                 `json:"held_commands"`
+}
+
+// DatabaseQueryOutput is the JSON representation of an approved database query
+type DatabaseQueryOutput struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Params      []string `json:"params,omitempty"`
+}
+
+// DatabaseQueryListOutput is the JSON representation of the db-query command without query
+type DatabaseQueryListOutput struct {
+	Count   int                   `json:"count"`
+	Queries []DatabaseQueryOutput `json:"queries"`
+}
+
+// DatabaseQueryResultOutput is the JSON representation of the db-query command
+type DatabaseQueryResultOutput struct {
+	Name      string     `json:"name"`
+	Columns   []string   `json:"columns"`
+	Rows      [][]string `json:"rows"`
+	Truncated bool       `json:"truncated"`
+}
+
+// AliasListOutput is the JSON representation of the alias-list command
+type AliasListOutput struct {
+	Count   int               `json:"count"`
+	Aliases map[string]string `json:"aliases"`
+}
+
+// MinionHistoryEntryOutput is the JSON representation of a command of a minion history
+type MinionHistoryEntryOutput struct {
+	CommandID  string `json:"command_id"`
+	Command    string `json:"command"`
+	Status     string `json:"status"`
+	Timestamp  int64  `json:"timestamp"`
+	ExitCode   *int32 `json:"exit_code,omitempty"`   // unset until a result is received
+	DurationMs *int64 `json:"duration_ms,omitempty"` // unset until a result is received
+}
+
+// MinionHistoryOutput is the JSON representation of the minion-history command
+type MinionHistoryOutput struct {
+	MinionID string                     `json:"minion_id"`
+	Count    int                        `json:"count"`
+	Commands []MinionHistoryEntryOutput `json:"commands"`
+}
+
+// ConnectionPeriodOutput is the JSON representation of a period a minion was connected
+type ConnectionPeriodOutput struct {
+	ConnectedAt    int64  `json:"connected_at"`
+	DisconnectedAt int64  `json:"disconnected_at,omitempty"` // unset while still connected
+	Reason         string `json:"reason,omitempty"`
+}
+
+// MinionUptimeOutput is the JSON representation of the minion-uptime command
+type MinionUptimeOutput struct {
+	MinionID      string                   `json:"minion_id"`
+	Since         int64                    `json:"since"`
+	Until         int64                    `json:"until"`
+	UptimePercent float64                  `json:"uptime_percent"`
+	Periods       []ConnectionPeriodOutput `json:"periods"`
+}
+
+// MinionLogEntryOutput is the JSON representation of a log entry shipped by a minion
+type MinionLogEntryOutput struct {
+	TimestampMs int64  `json:"timestamp_ms"`
+	Level       string `json:"level"`
+	Logger      string `json:"logger,omitempty"`
+	Message     string `json:"message"`
+	Caller      string `json:"caller,omitempty"`
+	Fields      string `json:"fields,omitempty"` // JSON object
+}
+
+// MinionLogsOutput is the JSON representation of the minion-logs command
+type MinionLogsOutput struct {
+	MinionID string                 `json:"minion_id"`
+	Count    int                    `json:"count"`
+	Entries  []MinionLogEntryOutput `json:"entries"` // most recent first
+}
+
+// RetireMinionOutput is the JSON representation of the minion-retire command
+type RetireMinionOutput struct {
+	MinionID        string `json:"minion_id"`
+	Notified        bool   `json:"notified"` // false: the minion learns it at its next registration
+	ArchivedResults int32  `json:"archived_results"`
+	DroppedCommands int32  `json:"dropped_commands"`
+}
+
+// CrashOutput is the JSON representation of a panic a minion recovered from
+type CrashOutput struct {
+	MinionID  string `json:"minion_id"`
+	Timestamp int64  `json:"timestamp"`
+	Component string `json:"component"`
+	Message   string `json:"message"`
+	Stack     string `json:"stack"`
+	Version   string `json:"version,omitempty"`
+}
+
+// CrashListOutput is the JSON representation of the crash-list command
+type CrashListOutput struct {
+	Count   int           `json:"count"`
+	Crashes []CrashOutput `json:"crashes"` // most recent first
+}
+
+// MinionCommandStatsOutput is the JSON representation of the statistics of a minion
+type MinionCommandStatsOutput struct {
+	MinionID string `json:"minion_id"`
+	Count    int64  `json:"count"`
+	Failures int64  `json:"failures"`
+	MedianMs int64  `json:"median_ms"`
+	MaxMs    int64  `json:"max_ms"`
+}
+
+// CommandStatsOutput is the JSON representation of the stats command
+type CommandStatsOutput struct {
+	Since          int64                      `json:"since"`
+	Until          int64                      `json:"until"`
+	CommandPattern string                     `json:"command_pattern,omitempty"`
+	Count          int64                      `json:"count"`
+	Successes      int64                      `json:"successes"`
+	Failures       int64                      `json:"failures"`
+	MedianMs       int64                      `json:"median_ms"`
+	P90Ms          int64                      `json:"p90_ms"`
+	P99Ms          int64                      `json:"p99_ms"`
+	MaxMs          int64                      `json:"max_ms"`
+	SlowestMinions []MinionCommandStatsOutput `json:"slowest_minions"`
+	ByImpact       []ImpactCommandStatsOutput `json:"by_impact"`
+}
+
+// ImpactCommandStatsOutput is the JSON representation of the results of an impact class
+type ImpactCommandStatsOutput struct {
+	Impact   string `json:"impact"` // empty for commands stored before impact classes
+	Count    int64  `json:"count"`
+	Failures int64  `json:"failures"`
+}
+
+// FleetHealthMinionOutput is the JSON representation of an anomalous minion
+type FleetHealthMinionOutput struct {
+	ID       string              `json:"id"`
+	Hostname string              `json:"hostname"`
+	Health   *MinionHealthOutput `json:"health"`
+}
+
+// WorkingTargetOutput is the JSON representation of the targets command
+type WorkingTargetOutput struct {
+	Target  string   `json:"target"`            // empty without working target
+	Matched []string `json:"matched,omitempty"` // minions the target matches, unset when Nexus was not asked
+}
+
+// TargetExplanationsOutput is the JSON representation of the target-explain command
+type TargetExplanationsOutput struct {
+	Matched          int32                     `json:"matched"`
+	Minions          []TargetExplanationOutput `json:"minions"`
+	UnknownMinionIDs []string                  `json:"unknown_minion_ids,omitempty"`
+}
+
+// TargetExplanationOutput is the JSON representation of why a minion matches a target or not
+type TargetExplanationOutput struct {
+	ID       string                  `json:"id"`
+	Hostname string                  `json:"hostname"`
+	Matched  bool                    `json:"matched"`
+	Rules    []RuleExplanationOutput `json:"rules"`
+}
+
+// RuleExplanationOutput is the JSON representation of a rule of a target checked against a minion
+type RuleExplanationOutput struct {
+	Rule    string `json:"rule"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+// FleetHealthOutput is the JSON representation of the fleet-health command
+type FleetHealthOutput struct {
+	Total        int32                     `json:"total"`
+	Healthy      int32                     `json:"healthy"`
+	Degraded     int32                     `json:"degraded"`
+	Unhealthy    int32                     `json:"unhealthy"`
+	AverageScore int32                     `json:"average_score"`
+	Minions      []FleetHealthMinionOutput `json:"minions"`
+}
+
+// ComplianceExportOutput is the JSON representation of the compliance-export command writing a file
+type ComplianceExportOutput struct {
+	File  string `json:"file"`
+	Count int    `json:"count"`
+}
+
+// ComplianceVerifyOutput is the JSON representation of the compliance-verify command
+type ComplianceVerifyOutput struct {
+	File   string `json:"file"`
+	Valid  bool   `json:"valid"`
+	Count  int    `json:"count"` // receipts verified
+	Signer string `json:"signer,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// FleetVersionsOutput is the JSON representation of the fleet-versions command
+type FleetVersionsOutput struct {
+	Total                int32                `json:"total"`
+	NexusVersion         string               `json:"nexus_version"`
+	NexusProtocolVersion int32                `json:"nexus_protocol_version"`
+	MinProtocolVersion   int32                `json:"min_protocol_version"`
+	Versions             []VersionCountOutput `json:"versions"`
+	Outdated             []string             `json:"outdated"` // minion IDs
+}
+
+// PatchComplianceOutput is the JSON representation of the fleet-patches command
+type PatchComplianceOutput struct {
+	GroupBy string             `json:"group_by,omitempty"`
+	Total   PatchGroupOutput   `json:"total"`
+	Groups  []PatchGroupOutput `json:"groups"`
+}
+
+// PatchGroupOutput is the JSON representation of the patch compliance of the minions sharing a tag value
+type PatchGroupOutput struct {
+	Value           string `json:"value"`
+	Minions         int32  `json:"minions"`
+	Compliant       int32  `json:"compliant"`
+	Vulnerable      int32  `json:"vulnerable"`
+	Unknown         int32  `json:"unknown"`
+	RebootRequired  int32  `json:"reboot_required"`
+	SecurityUpdates int32  `json:"security_updates"`
+	Updates         int32  `json:"updates"`
+	OldestStatus    int64  `json:"oldest_status,omitempty"` // unix time
+}
+
+// VersionCountOutput is the JSON representation of the minions running a build
+type VersionCountOutput struct {
+	Version         string `json:"version"`
+	BuildCommit     string `json:"build_commit"`
+	ProtocolVersion int32  `json:"protocol_version"`
+	Minions         int32  `json:"minions"`
+}
+
+// FileTransferOutput is the JSON representation of a file pulled from a minion
+type FileTransferOutput struct {
+	TransferID string `json:"transfer_id"`
+	MinionID   string `json:"minion_id"`
+	Path       string `json:"path"`
+	State      string `json:"state"`
+	Received   int64  `json:"received"`
+	Size       int64  `json:"size"`
+	Sha256     string `json:"sha256,omitempty"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  int64  `json:"started_at"`
+	UpdatedAt  int64  `json:"updated_at"`
+	LocalPath  string `json:"local_path,omitempty"` // where file-pull and file-download wrote the file
+}
+
+// FileTransferListOutput is the JSON representation of the file-transfers command
+type FileTransferListOutput struct {
+	Count     int                  `json:"count"`
+	Transfers []FileTransferOutput `json:"transfers"`
+}
+
+// TraceEventOutput is the JSON representation of an event of a command timeline
+type TraceEventOutput struct {
+	TimestampMs int64  `json:"timestamp_ms"`
+	OffsetMs    int64  `json:"offset_ms"` // since the first event of the trace
+	Component   string `json:"component"`
+	Event       string `json:"event"`
+	CommandID   string `json:"command_id"`
+	MinionID    string `json:"minion_id"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// TraceOutput is the JSON representation of the trace-get command
+type TraceOutput struct {
+	TraceID string             `json:"trace_id"`
+	Events  []TraceEventOutput `json:"events"`
+}
+
+// BootstrapURLOutput is the JSON representation of the minion-bootstrap-url command
+type BootstrapURLOutput struct {
+	URL       string `json:"url"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// ConnectionEventOutput is the JSON representation of a minion connection event
+type ConnectionEventOutput struct {
+	Timestamp int64  `json:"timestamp"`
+	Event     string `json:"event"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// MinionDiagnosticsOutput is the JSON representation of the minion-inspect command
+type MinionDiagnosticsOutput struct {
+	MinionID          string                  `json:"minion_id"`
+	Registered        bool                    `json:"registered"`
+	StreamState       string                  `json:"stream_state"`
+	ActiveStreams     int32                   `json:"active_streams"`
+	StreamConnectedAt int64                   `json:"stream_connected_at"`
+	LastSeen          int64                   `json:"last_seen"`
+	ChannelDepth      int32                   `json:"channel_depth"`
+	ChannelCapacity   int32                   `json:"channel_capacity"`
+	PendingCommands   int32                   `json:"pending_commands"`
+	HeldCommands      int32                   `json:"held_commands"`
+	InFlight          int32                   `json:"in_flight"`
+	InFlightLimit     int32                   `json:"in_flight_limit"`
+	CommandsSent      int64                   `json:"commands_sent"`
+	ResultsReceived   int64                   `json:"results_received"`
+	LastError         string                  `json:"last_error,omitempty"`
+	LastErrorAt       int64                   `json:"last_error_at,omitempty"`
+	LastSpeedTest     *SpeedTestOutput        `json:"last_speed_test,omitempty"`
+	Events            []ConnectionEventOutput `json:"events"`
+}
+
+// SpeedTestOutput is the JSON representation of the last net:speedtest of a minion
+type SpeedTestOutput struct {
+	CommandID    string  `json:"command_id"`
+	Timestamp    int64   `json:"timestamp"`
+	LatencyMs    float64 `json:"latency_ms"`
+	UploadMbps   float64 `json:"upload_mbps"`
+	DownloadMbps float64 `json:"download_mbps"`
+}
+
+// ValidationOutput is the JSON representation of the validate command
+type ValidationOutput struct {
+	Valid   bool   `json:"valid"`
+	Payload string `json:"payload,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ErrorOutput is the JSON representation of a console error
+type ErrorOutput struct {
+	Error string `json:"error"`
+}
+
+// IsValidOutputFormat reports whether format is a supported output format
+func IsValidOutputFormat(format string) bool {
+	return format == OutputFormatText || format == OutputFormatJSON
+}
+
+// newMinionListOutput converts minions to their JSON representation
+func newMinionListOutput(minions []*pb.HostInfo) MinionListOutput {
+	output := MinionListOutput{
+		Count:   len(minions),
+		Minions: make([]MinionOutput, 0, len(minions)),
+	}
+	for _, minion := range minions {
+		tags := minion.Tags
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		output.Minions = append(output.Minions, MinionOutput{
+			ID:              minion.Id,
+			Hostname:        minion.Hostname,
+			IP:              minion.Ip,
+			OS:              minion.Os,
+			Namespace:       minion.Namespace,
+			Region:          minion.Region,
+			Datacenter:      minion.Datacenter,
+			Rack:            minion.Rack,
+			LastSeen:        minion.LastSeen,
+			Tags:            tags,
+			Capabilities:    minion.Capabilities,
+			CommandVersions: minion.CommandVersions,
+			Health:          newMinionHealthOutput(minion.Health),
+			QueuedCommands:  minion.QueuedCommands,
+			Availability:    minion.Availability,
+			Sleeping:        minion.Sleeping,
+			NextWake:        minion.NextWake,
+			PowerAction:     minion.PowerAction,
+			PowerAt:         minion.PowerAt,
+			PoweredDown:     minion.PoweredDown,
+			Version:         minion.Version,
+			BuildCommit:     minion.BuildCommit,
+			ProtocolVersion: minion.ProtocolVersion,
+			Outdated:        minion.Outdated,
+		})
+	}
+	return output
+}
+
+// newResultOutputs converts command results to their JSON representation
+func newResultOutputs(results []*pb.CommandResult) []ResultOutput {
+	outputs := make([]ResultOutput, 0, len(results))
+	for _, result := range results {
+		output := ResultOutput{
+			CommandID: result.CommandId,
+			MinionID:  result.MinionId,
+			ExitCode:  result.ExitCode,
+			Stdout:    result.Stdout,
+			Stderr:    result.Stderr,
+			Timestamp: result.Timestamp,
+			Attempt:   result.Attempt,
+		}
+		if info := result.Execution; info != nil {
+			output.Execution = &ExecutionOutput{
+				WorkingDir: info.WorkingDir,
+				User:       info.User,
+				Path:       info.Path,
+				DurationMs: info.DurationMs,
+				MaxRSSKB:   info.MaxRssKb,
+			}
+		}
+		if result.Structured != "" && json.Valid([]byte(result.Structured)) {
+			output.ContentType = result.ContentType
+			output.Data = json.RawMessage(result.Structured)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
+
+// newMinionDiagnosticsOutput converts minion diagnostics to their JSON representation
+func newMinionDiagnosticsOutput(diag *pb.MinionDiagnostics) MinionDiagnosticsOutput {
+	output := MinionDiagnosticsOutput{
+		MinionID:          diag.MinionId,
+		Registered:        diag.Registered,
+		StreamState:       diag.StreamState,
+		ActiveStreams:     diag.ActiveStreams,
+		StreamConnectedAt: diag.StreamConnectedAt,
+		LastSeen:          diag.LastSeen,
+		ChannelDepth:      diag.ChannelDepth,
+		ChannelCapacity:   diag.ChannelCapacity,
+		PendingCommands:   diag.PendingCommands,
+		HeldCommands:      diag.HeldCommands,
+		InFlight:          diag.InFlight,
+		InFlightLimit:     diag.InFlightLimit,
+		CommandsSent:      diag.CommandsSent,
+		ResultsReceived:   diag.ResultsReceived,
+		LastError:         diag.LastError,
+		LastErrorAt:       diag.LastErrorAt,
+		Events:            make([]ConnectionEventOutput, 0, len(diag.Events)),
+	}
+	if test := diag.LastSpeedTest; test != nil {
+		output.LastSpeedTest = &SpeedTestOutput{
+			CommandID:    test.CommandId,
+			Timestamp:    test.Timestamp,
+			LatencyMs:    test.LatencyMs,
+			UploadMbps:   test.UploadMbps,
+			DownloadMbps: test.DownloadMbps,
+		}
+	}
+	for _, event := range diag.Events {
+		output.Events = append(output.Events, ConnectionEventOutput{
+			Timestamp: event.Timestamp,
+			Event:     event.Event,
+			Detail:    event.Detail,
+		})
+	}
+	return output
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Printf("{\"error\": %q}\n", fmt.Sprintf("failed to encode output: %v", err))
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/chzyer/readline"
+	"go.uber.org/zap"
+)
+
+// pagerStream is the output of a result displayed by the viewer
+type pagerStream int
+
+const (
+	pagerStdout pagerStream = iota
+	pagerStderr
+	pagerSideBySide
+)
+
+// pagerStreamNames are the status line names of the pager streams
+var pagerStreamNames = []string{"stdout", "stderr", "stdout | stderr"}
+
+// pagerHelp is shown on the status line when there is no message
+const pagerHelp = "j/k scroll  space/b page  h/l minion  s stream  / search  q quit"
+
+// pagerEscapeKeys names the escape sequences of the navigation keys
+var pagerEscapeKeys = map[string]string{
+	"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+	"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdn",
+	"\x1b[H": "home", "\x1b[F": "end", "\x1bOH": "home", "\x1bOF": "end", "\x1b[1~": "home", "\x1b[4~": "end",
+}
+
+// resultPager is a less-like viewer of the full output of command results, one minion at a time
+type resultPager struct {
+	results []*pb.CommandResult
+	current int // index of the displayed result
+	stream  pagerStream
+	top     int // first displayed line
+	rows    int
+	cols    int
+	search  string  // last searched text
+	prompt  *string // search being typed, nil outside search input
+	message string  // shown on the status line until the next key
+}
+
+// newResultPager creates a viewer of results for a terminal of rows x cols
+func newResultPager(results []*pb.CommandResult, rows, cols int) *resultPager {
+	p := &resultPager{results: results}
+	p.resize(rows, cols)
+	return p
+}
+
+// resize adapts the viewer to a new terminal size
+func (p *resultPager) resize(rows, cols int) {
+	p.rows, p.cols = max(rows, 2), max(cols, 20)
+	p.scroll(0)
+}
+
+// bodyRows is the number of output lines displayed above the status line
+func (p *resultPager) bodyRows() int {
+	return p.rows - 1
+}
+
+// lines returns the lines of the displayed stream of the current result
+func (p *resultPager) lines() []string {
+	result := p.results[p.current]
+	switch p.stream {
+	case pagerStderr:
+		return splitOutput(result.Stderr)
+	case pagerSideBySide:
+		stdout, stderr := splitOutput(result.Stdout), splitOutput(result.Stderr)
+		width := (p.cols - 3) / 2
+		lines := make([]string, max(len(stdout), len(stderr)))
+		for i := range lines {
+			var left, right string
+			if i < len(stdout) {
+				left = stdout[i]
+			}
+			if i < len(stderr) {
+				right = stderr[i]
+			}
+			lines[i] = fmt.Sprintf("%-*s | %s", width, fitWidth(left, width), right)
+		}
+		return lines
+	default:
+		return splitOutput(result.Stdout)
+	}
+}
+
+// splitOutput splits an output in lines, expanding tabs so that lines can be cut to the terminal width
+func splitOutput(output string) []string {
+	output = strings.TrimSuffix(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	if output == "" {
+		return nil
+	}
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "\t") {
+			continue
+		}
+		var expanded strings.Builder
+		column := 0
+		for _, r := range line {
+			if r == '\t' {
+				spaces := 8 - column%8
+				expanded.WriteString(strings.Repeat(" ", spaces))
+				column += spaces
+				continue
+			}
+			expanded.WriteRune(r)
+			column++
+		}
+		lines[i] = expanded.String()
+	}
+	return lines
+}
+
+// fitWidth cuts a line to width characters
+func fitWidth(line string, width int) string {
+	if utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	return string([]rune(line)[:width])
+}
+
+// scroll moves the displayed lines by delta, staying within the output
+func (p *resultPager) scroll(delta int) {
+	p.top = max(0, min(p.top+delta, len(p.lines())-p.bodyRows()))
+}
+
+// selectResult displays the result of index i from its first line
+func (p *resultPager) selectResult(i int) {
+	if i < 0 || i >= len(p.results) {
+		p.message = "No more minions"
+		return
+	}
+	p.current, p.top = i, 0
+}
+
+// find moves to the next line containing the searched text, wrapping around the output
+func (p *resultPager) find(forward bool) {
+	if p.search == "" {
+		return
+	}
+	lines := p.lines()
+	step := 1
+	if !forward {
+		step = -1
+	}
+	for n := 1; n <= len(lines); n++ {
+		i := ((p.top+n*step)%len(lines) + len(lines)) % len(lines)
+		if strings.Contains(lines[i], p.search) {
+			p.top = i
+			p.scroll(0)
+			return
+		}
+	}
+	p.message = "Pattern not found: " + p.search
+}
+
+// handleKey applies a keystroke, returning false when the viewer is closed
+func (p *resultPager) handleKey(key string) bool {
+	p.message = ""
+	if p.prompt != nil {
+		switch key {
+		case "\r", "\n":
+			p.search, p.prompt = *p.prompt, nil
+			p.find(true)
+		case "\x1b", "\x03":
+			p.prompt = nil
+		case "\x7f", "\b":
+			if typed := []rune(*p.prompt); len(typed) > 0 {
+				*p.prompt = string(typed[:len(typed)-1])
+			}
+		default:
+			if r, _ := utf8.DecodeRuneInString(key); r >= ' ' && utf8.RuneCountInString(key) == 1 {
+				*p.prompt += key
+			}
+		}
+		return true
+	}
+
+	switch key {
+	case "q", "Q", "\x03":
+		return false
+	case "j", "down", "\r", "\n":
+		p.scroll(1)
+	case "k", "up":
+		p.scroll(-1)
+	case " ", "f", "pgdn":
+		p.scroll(p.bodyRows())
+	case "b", "pgup":
+		p.scroll(-p.bodyRows())
+	case "d":
+		p.scroll(p.bodyRows() / 2)
+	case "u":
+		p.scroll(-p.bodyRows() / 2)
+	case "g", "home":
+		p.top = 0
+	case "G", "end":
+		p.top = len(p.lines())
+		p.scroll(0)
+	case "l", "right", "]":
+		p.selectResult(p.current + 1)
+	case "h", "left", "[":
+		p.selectResult(p.current - 1)
+	case "s":
+		p.stream = (p.stream + 1) % pagerStream(len(pagerStreamNames))
+		p.scroll(0)
+	case "/":
+		typed := ""
+		p.prompt = &typed
+	case "n":
+		p.find(true)
+	case "N":
+		p.find(false)
+	}
+	return true
+}
+
+// statusLine describes the displayed result and position, or the search being typed
+func (p *resultPager) statusLine(total int) string {
+	if p.prompt != nil {
+		return "/" + *p.prompt
+	}
+	result := p.results[p.current]
+	hint := p.message
+	if hint == "" {
+		hint = pagerHelp
+	}
+	return fmt.Sprintf("%s (%d/%d) exit %d | %s | lines %d-%d/%d | %s",
+		result.MinionId, p.current+1, len(p.results), result.ExitCode, pagerStreamNames[p.stream],
+		min(p.top+1, total), min(p.top+p.bodyRows(), total), total, hint)
+}
+
+// render draws the displayed lines and the status line, highlighting the searched text
+func (p *resultPager) render(w io.Writer) {
+	lines := p.lines()
+	end := min(p.top+p.bodyRows(), len(lines))
+
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	for _, line := range lines[p.top:end] {
+		line = fitWidth(line, p.cols)
+		if p.search != "" {
+			line = strings.ReplaceAll(line, p.search, "\x1b[7m"+p.search+"\x1b[27m")
+		}
+		screen.WriteString(line + "\r\n")
+	}
+	for i := end - p.top; i < p.bodyRows(); i++ {
+		screen.WriteString("~\r\n")
+	}
+	screen.WriteString("\x1b[7m" + fitWidth(p.statusLine(len(lines)), p.cols) + "\x1b[0m")
+	io.WriteString(w, screen.String())
+}
+
+// parsePagerKeys splits the bytes read from the terminal in keystrokes, naming the navigation keys
+func parsePagerKeys(data []byte) []string {
+	var keys []string
+	for len(data) > 0 {
+		size := 1
+		if data[0] == 0x1b && len(data) > 2 && (data[1] == '[' || data[1] == 'O') {
+			size = 2
+			for size < len(data) && (data[size] < 0x40 || data[size] > 0x7e) {
+				size++
+			}
+			size = min(size+1, len(data))
+		} else if _, runeSize := utf8.DecodeRune(data); runeSize > 1 {
+			size = runeSize
+		}
+
+		key := string(data[:size])
+		if name, ok := pagerEscapeKeys[key]; ok {
+			key = name
+		}
+		keys = append(keys, key)
+		data = data[size:]
+	}
+	return keys
+}
+
+// viewResults opens the full output of the results of a command in a pager, one minion at a time
+func (c *Console) viewResults(ctx context.Context, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		c.printError("Usage: result-view <command-id> [minion-id]")
+		return
+	}
+	if c.isJSONOutput() {
+		c.printError("'result-view' is interactive, not available with JSON output")
+		return
+	}
+
+	commandID := args[0]
+	results, err := c.resultsToView(ctx, commandID)
+	if err != nil {
+		c.logger.Error("Failed to get command results", zap.String("command_id", commandID), zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting results: %v", err))
+		return
+	}
+	if len(results) == 0 {
+		c.ui.PrintInfo("No results available yet for command " + commandID)
+		return
+	}
+
+	first := 0
+	if len(args) == 2 {
+		first = -1
+		for i, result := range results {
+			if result.MinionId == args[1] {
+				first = i
+			}
+		}
+		if first < 0 {
+			c.printError(fmt.Sprintf("No result of minion %s for command %s", args[1], commandID))
+			return
+		}
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		if len(args) == 2 {
+			results = results[first : first+1]
+		}
+		printFullResults(results)
+		return
+	}
+
+	width, height, err := readline.GetSize(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+	pager := newResultPager(results, height, width)
+	pager.selectResult(first)
+
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error opening the result viewer: %v", err))
+		return
+	}
+	defer readline.Restore(fd, state)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	done := make(chan struct{})
+	defer close(done)
+	keys := make(chan []byte)
+	go readPagerInput(done, keys)
+	resizes := make(chan [2]int, 1)
+	go watchTerminalSize(fd, done, func(rows, cols uint32) {
+		select {
+		case resizes <- [2]int{int(rows), int(cols)}:
+		default:
+		}
+	})
+
+	for {
+		pager.render(os.Stdout)
+		select {
+		case <-ctx.Done():
+			return
+		case size := <-resizes:
+			pager.resize(size[0], size[1])
+		case data, ok := <-keys:
+			if !ok {
+				return
+			}
+			for _, key := range parsePagerKeys(data) {
+				if !pager.handleKey(key) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// resultsToView returns all the results of a command, from Nexus or from the commands run locally
+func (c *Console) resultsToView(ctx context.Context, commandID string) ([]*pb.CommandResult, error) {
+	if c.local != nil {
+		if result, ok := c.local.results[commandID]; ok {
+			return []*pb.CommandResult{result}, nil
+		}
+		return nil, nil
+	}
+	return c.fetchAllResults(ctx, commandID)
+}
+
+// readPagerInput forwards the keystrokes read from the terminal until the viewer is closed
+func readPagerInput(done <-chan struct{}, keys chan<- []byte) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := readStdin(done, buf)
+		if err != nil || n == 0 {
+			return
+		}
+		select {
+		case keys <- append([]byte(nil), buf[:n]...):
+		case <-done:
+			return
+		}
+	}
+}
+
+// printFullResults prints the complete output of each result, when the console doesn't run in a terminal
+func printFullResults(results []*pb.CommandResult) {
+	for _, result := range results {
+		fmt.Printf("=== %s (exit %d) ===\n", result.MinionId, result.ExitCode)
+		if result.Stdout != "" {
+			fmt.Println(strings.TrimSuffix(result.Stdout, "\n"))
+		}
+		if result.Stderr != "" {
+			fmt.Println("--- stderr ---")
+			fmt.Println(strings.TrimSuffix(result.Stderr, "\n"))
+		}
+		if result.ExitCode != 0 && result.Execution != nil {
+			fmt.Println("--- execution ---")
+			fmt.Println(describeExecution(result.Execution))
+		}
+	}
+}
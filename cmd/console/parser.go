@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	pb "github.com/arhuman/minexus/protogen"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
 )
 
 // CommandParser handles command parsing and validation
@@ -30,10 +34,140 @@ type ParsedCommand struct {
 	Request     *pb.CommandRequest
 	CommandText string
 	CommandType pb.CommandType
+	DryRun      bool
+	Emergency   bool
+	NoWait      bool // don't follow the progress of multi-minion commands
+	Validate    bool // only validate the command locally, without contacting Nexus
+	Priority    pb.CommandPriority
+	Lock        string          // host lock the command waits for and holds while it runs
+	Impact      string          // impact class declared for the command, Nexus never lowering it
+	Retry       *pb.RetryPolicy // dispatches of the command to the targets whose result failed, nil: none
+	Sample      int32           // successful results stored in full, the others only counted, 0: all stored
 }
 
 // ParseCommand parses console command arguments into a structured command request
 func (p *CommandParser) ParseCommand(args []string) (*ParsedCommand, error) {
+	// Leading options apply to command-send itself, not to the command
+	dryRun := false
+	emergency := false
+	noWait := false
+	validate := false
+	confirmToken := ""
+	lock := ""
+	impact := ""
+	priority := pb.CommandPriority_NORMAL
+	var retry *pb.RetryPolicy
+	var retryBackoff time.Duration
+	var retryOn []int32
+	var sample int32
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		option, value, hasValue := strings.Cut(args[0], "=")
+		switch option {
+		case "--dry-run":
+			dryRun = true
+		case "--emergency":
+			emergency = true
+		case "--no-wait":
+			noWait = true
+		case "--validate":
+			validate = true
+		case "--confirm":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("missing value for --confirm")
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			confirmToken = value
+		case "--lock":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("missing value for --lock")
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			if err := command.ValidateLockName(value); err != nil {
+				return nil, err
+			}
+			lock = value
+		case "--impact":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("missing value for --impact")
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			if err := command.ValidateImpact(value); err != nil {
+				return nil, err
+			}
+			impact = value
+		case "--priority":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("missing value for --priority")
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			var err error
+			if priority, err = parsePriority(value); err != nil {
+				return nil, err
+			}
+		case "--retry", "--retry-backoff", "--retry-on":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("missing value for %s", option)
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			var err error
+			switch option {
+			case "--retry":
+				attempts, convErr := strconv.Atoi(value)
+				if convErr != nil || attempts < 1 {
+					return nil, fmt.Errorf("invalid --retry '%s': use the maximum number of attempts, e.g. 3", value)
+				}
+				retry = &pb.RetryPolicy{MaxAttempts: int32(attempts)}
+			case "--retry-backoff":
+				if retryBackoff, err = time.ParseDuration(value); err != nil || retryBackoff < time.Second {
+					return nil, fmt.Errorf("invalid --retry-backoff '%s': use a duration of at least 1s, e.g. 30s", value)
+				}
+			case "--retry-on":
+				if retryOn, err = parseExitCodes(value); err != nil {
+					return nil, err
+				}
+			}
+		case "--sample":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("missing value for --sample")
+				}
+				value = args[1]
+				args = args[1:]
+			}
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 1 {
+				return nil, fmt.Errorf("invalid --sample '%s': use the number of successful results stored in full, e.g. 50", value)
+			}
+			sample = int32(size)
+		default:
+			return nil, fmt.Errorf("unknown option: %s", args[0])
+		}
+		args = args[1:]
+	}
+
+	if retry == nil && (retryBackoff > 0 || retryOn != nil) {
+		return nil, fmt.Errorf("--retry-backoff and --retry-on require --retry")
+	}
+	if retry != nil {
+		retry.BackoffSeconds = int32(retryBackoff / time.Second)
+		retry.ExitCodes = retryOn
+	}
+
 	if len(args) == 0 {
 		return nil, fmt.Errorf("missing command arguments")
 	}
@@ -54,8 +188,13 @@ func (p *CommandParser) ParseCommand(args []string) (*ParsedCommand, error) {
 		if len(args) < 3 {
 			return nil, fmt.Errorf("missing minion ID or command")
 		}
-		// Target specific minion
-		req.MinionIds = []string{args[1]}
+		// Target specific minions, e.g. the selection of pick
+		for _, id := range strings.Split(args[1], ",") {
+			if id == "" {
+				return nil, fmt.Errorf("empty minion ID in %s", args[1])
+			}
+			req.MinionIds = append(req.MinionIds, id)
+		}
 		commandStart = 2
 
 	case "tag":
@@ -86,11 +225,48 @@ func (p *CommandParser) ParseCommand(args []string) (*ParsedCommand, error) {
 			return nil, fmt.Errorf("minion ID detected without target specifier. Did you mean: command-send minion %s %s", args[0], strings.Join(args[1:], " "))
 		}
 
-		return nil, fmt.Errorf("invalid target type: %s. Use 'all', 'minion', or 'tag'", args[0])
+		// Target by failure domain, e.g. dc=eu-west-1a or region=eu-west-1,rack=r12
+		selector, err := parseTopologySelector(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if selector == nil {
+			return nil, fmt.Errorf("invalid target type: %s. Use 'all', 'minion', 'tag' or region=/dc=/rack=", args[0])
+		}
+		if len(args) < 2 {
+			return nil, fmt.Errorf("missing command for '%s' target", args[0])
+		}
+		req.Topology = selector
+		commandStart = 1
 	}
 
 	// Parse command and determine type
 	cmdText, cmdType := p.parseCommandAndType(args[commandStart:])
+
+	// Certificate rotation reads the PEM files locally and embeds them in the payload
+	if args[commandStart] == "certs:rotate" && len(args) > commandStart+1 && !strings.HasPrefix(args[commandStart+1], "{") {
+		payload, err := p.formatCertsRotateCommand(args[commandStart+1:])
+		if err != nil {
+			return nil, err
+		}
+		cmdText, cmdType = payload, pb.CommandType_SYSTEM
+	}
+	// File verification reads the manifest locally and embeds it in the payload
+	if fields := strings.Fields(cmdText); len(fields) > 1 && fields[0] == "file:verify" && !strings.HasPrefix(fields[1], "{") {
+		payload, err := p.formatFileVerifyCommand(fields[1], fields[2:])
+		if err != nil {
+			return nil, err
+		}
+		cmdText, cmdType = payload, pb.CommandType_SYSTEM
+	}
+	// Desired state runs read the document locally and embed it in the payload
+	if fields := strings.Fields(cmdText); len(fields) > 1 && fields[0] == "state:apply" && !strings.HasPrefix(fields[1], "{") {
+		payload, err := p.formatStateApplyCommand(fields[1:])
+		if err != nil {
+			return nil, err
+		}
+		cmdText, cmdType = payload, pb.CommandType_SYSTEM
+	}
 	if cmdText == "" {
 		return nil, fmt.Errorf("command cannot be empty")
 	}
@@ -104,15 +280,115 @@ func (p *CommandParser) ParseCommand(args []string) (*ParsedCommand, error) {
 		Id:      fmt.Sprintf("cmd-%d", time.Now().UnixNano()),
 		Type:    cmdType,
 		Payload: cmdText,
+		TraceId: newTraceID(),
 	}
+	req.DryRun = dryRun
+	req.Emergency = emergency
+	req.ConfirmToken = confirmToken
+	req.Priority = priority
+	req.Lock = lock
+	req.Impact = impact
+	req.Retry = retry
+	req.Sample = sample
 
 	return &ParsedCommand{
 		Request:     &req,
 		CommandText: cmdText,
 		CommandType: cmdType,
+		DryRun:      dryRun,
+		Emergency:   emergency,
+		NoWait:      noWait,
+		Validate:    validate,
+		Priority:    priority,
+		Lock:        lock,
+		Impact:      impact,
+		Retry:       retry,
+		Sample:      sample,
 	}, nil
 }
 
+// ValidateCommand parses command-send arguments and checks the arguments of the command against
+// the local registry, as minions would parse them, without contacting Nexus
+func (p *CommandParser) ValidateCommand(args []string) (*ParsedCommand, error) {
+	parsed, err := p.ParseCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.CheckArguments(parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// CheckArguments checks the arguments of a parsed command against the local registry
+func (p *CommandParser) CheckArguments(parsed *ParsedCommand) error {
+	return p.registry.Validate(parsed.CommandText)
+}
+
+// parseTopologySelector parses a failure domain target, a comma separated list of region=<name>,
+// dc=<name> (or datacenter=<name>) and rack=<name>. It returns nil when target is not one.
+func parseTopologySelector(target string) (*pb.TopologySelector, error) {
+	key, _, _ := strings.Cut(target, "=")
+	switch key {
+	case "region", "dc", "datacenter", "rack":
+	default:
+		return nil, nil
+	}
+
+	selector := &pb.TopologySelector{}
+	for _, part := range strings.Split(target, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		if value == "" {
+			return nil, fmt.Errorf("missing value for '%s' in target %s", key, target)
+		}
+		switch key {
+		case "region":
+			selector.Region = value
+		case "dc", "datacenter":
+			selector.Datacenter = value
+		case "rack":
+			selector.Rack = value
+		default:
+			return nil, fmt.Errorf("invalid failure domain '%s' in target %s: use region, dc or rack", key, target)
+		}
+	}
+	return selector, nil
+}
+
+// parseExitCodes parses the comma-separated non-zero exit codes of --retry-on
+func parseExitCodes(value string) ([]int32, error) {
+	var codes []int32
+	for _, field := range strings.Split(value, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || code == 0 {
+			return nil, fmt.Errorf("invalid --retry-on '%s': use non-zero exit codes separated by commas, e.g. 75,100", value)
+		}
+		codes = append(codes, int32(code))
+	}
+	return codes, nil
+}
+
+// describeRetryExitCodes names the exit codes a retry policy retries
+func describeRetryExitCodes(policy *pb.RetryPolicy) string {
+	if len(policy.ExitCodes) == 0 {
+		return "any failure"
+	}
+	codes := make([]string, 0, len(policy.ExitCodes))
+	for _, code := range policy.ExitCodes {
+		codes = append(codes, strconv.Itoa(int(code)))
+	}
+	return "exit codes " + strings.Join(codes, ",")
+}
+
+// parsePriority parses a dispatch priority name (low, normal, high, emergency)
+func parsePriority(value string) (pb.CommandPriority, error) {
+	priority, ok := pb.CommandPriority_value[strings.ToUpper(value)]
+	if !ok {
+		return pb.CommandPriority_NORMAL, fmt.Errorf("invalid priority '%s': use low, normal, high or emergency", value)
+	}
+	return pb.CommandPriority(priority), nil
+}
+
 // parseCommandAndType determines the command type and formats the payload
 func (p *CommandParser) parseCommandAndType(args []string) (string, pb.CommandType) {
 	if len(args) == 0 {
@@ -252,6 +528,117 @@ func (p *CommandParser) formatSystemCommand(jsonCmd map[string]interface{}, comm
 	return "system:" + command
 }
 
+// formatCertsRotateCommand builds a certs:rotate payload from local PEM files
+func (p *CommandParser) formatCertsRotateCommand(files []string) (string, error) {
+	if len(files) != 3 {
+		return "", fmt.Errorf("usage: certs:rotate <cert-file> <key-file> <ca-file>")
+	}
+
+	contents := make([]string, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		contents[i] = string(data)
+	}
+
+	bundle, err := json.Marshal(command.CertsRotateRequest{
+		Cert: contents[0],
+		Key:  contents[1],
+		CA:   contents[2],
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode certificate bundle: %w", err)
+	}
+
+	return "certs:rotate " + string(bundle), nil
+}
+
+// manifestAlgorithms are the checksum algorithms of text manifests, by checksum length in hexadecimal
+var manifestAlgorithms = map[int]string{32: "md5", 40: "sha1", 64: "sha256", 128: "sha512"}
+
+// formatFileVerifyCommand builds a file:verify payload from a local manifest, either the JSON payload
+// of a file:hash result or "<checksum>  <path>" lines as written by file:hash and sha256sum.
+// Files of dirs missing from the manifest are reported by the minion.
+func (p *CommandParser) formatFileVerifyCommand(manifestFile string, dirs []string) (string, error) {
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", manifestFile, err)
+	}
+
+	request := command.FileVerifyRequest{Files: make(map[string]string), Dirs: dirs}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var hashed command.FileHashResult
+		if err := json.Unmarshal([]byte(trimmed), &hashed); err != nil {
+			return "", fmt.Errorf("invalid JSON manifest %s: %w", manifestFile, err)
+		}
+		request.Algorithm = hashed.Algorithm
+		for _, file := range hashed.Files {
+			request.Files[file.Path] = file.Hash
+		}
+	} else {
+		for i, line := range strings.Split(trimmed, "\n") {
+			if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			sum, path, found := strings.Cut(line, " ")
+			path = strings.TrimPrefix(strings.TrimLeft(path, " "), "*") // sha256sum marks binary mode with *
+			algorithm := manifestAlgorithms[len(sum)]
+			if !found || path == "" || algorithm == "" || !util.IsHexString(sum) {
+				return "", fmt.Errorf("%s:%d: expected '<checksum>  <path>'", manifestFile, i+1)
+			}
+			if request.Algorithm != "" && request.Algorithm != algorithm {
+				return "", fmt.Errorf("%s:%d: checksums of different algorithms", manifestFile, i+1)
+			}
+			request.Algorithm = algorithm
+			request.Files[path] = strings.ToLower(sum)
+		}
+	}
+	if len(request.Files) == 0 {
+		return "", fmt.Errorf("manifest %s lists no file", manifestFile)
+	}
+
+	manifest, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return "file:verify " + string(manifest), nil
+}
+
+// formatStateApplyCommand builds a state:apply payload from a local YAML or JSON document, --check
+// previewing the changes. The document is checked before it is sent.
+func (p *CommandParser) formatStateApplyCommand(args []string) (string, error) {
+	check := len(args) > 0 && args[0] == "--check"
+	if check {
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: state:apply [--check] <document>")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+	var document command.StateDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&document); err != nil {
+		return "", fmt.Errorf("invalid document %s: %w", args[0], err)
+	}
+	document.Check = document.Check || check
+
+	payload, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode document: %w", err)
+	}
+	if err := p.registry.Validate("state:apply " + string(payload)); err != nil {
+		return "", fmt.Errorf("invalid document %s: %w", args[0], err)
+	}
+	return "state:apply " + string(payload), nil
+}
+
 // validateStructuredCommand validates that structured commands (with ':' prefix) are valid
 func (p *CommandParser) validateStructuredCommand(cmdText string) error {
 	// Allow non-structured commands (no colon) to pass through
@@ -360,8 +747,19 @@ func (p *CommandParser) isStructuredCommand(cmdText string) bool {
 func (p *CommandParser) ShowSendCommandHelp() string {
 	helpText := `Usage:
   command-send all <command>                    - Send to all minions
-  command-send minion <id> <command>            - Send to specific minion
+  command-send minion <id>[,<id>...] <command> - Send to specific minions
   command-send tag <key>=<value> <command>      - Send to minions with tag
+  command-send dc=<name>[,rack=<name>] <command> - Send to minions of a region, datacenter or rack
+  command-send --dry-run <target> <command>     - Show targets without sending
+  command-send --emergency <target> <command>   - Bypass maintenance windows
+  command-send --priority <level> <target> <command> - Queue with low, normal, high or emergency priority
+  command-send --confirm <token> <target> <command> - Confirm a destructive command
+  command-send --no-wait <target> <command>     - Don't follow the progress of multi-minion commands
+  command-send --validate <target> <command>    - Check the command and its arguments without contacting Nexus
+  command-send --lock <name> <target> <command> - Wait for a host lock and hold it while the command runs
+  command-send --impact <class> <target> <command> - Declare a read-only, mutating or disruptive impact
+  command-send --retry <attempts> [--retry-backoff <duration>] [--retry-on <codes>] <target> <command> - Retry failed targets
+  command-send --sample <n> <target> <command>  - Store n successful results in full and all failures, count the others
 
 Available Commands:
 `
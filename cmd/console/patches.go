@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// showFleetPatches summarizes the last patch:status of the fleet, grouped by the value of a tag
+func (c *Console) showFleetPatches(ctx context.Context, args []string) {
+	if len(args) > 1 {
+		c.printError("usage: fleet-patches [tag-key]")
+		return
+	}
+	groupBy := ""
+	if len(args) == 1 {
+		groupBy = args[0]
+	}
+
+	compliance, err := c.grpc.GetPatchCompliance(ctx, groupBy)
+	if err != nil {
+		c.logger.Error("Failed to get patch compliance", zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting patch compliance: %v", err))
+		return
+	}
+	total := compliance.Total
+	if total == nil {
+		total = &pb.PatchGroup{}
+	}
+
+	if c.isJSONOutput() {
+		output := PatchComplianceOutput{
+			GroupBy: compliance.GroupBy,
+			Total:   newPatchGroupOutput(total),
+			Groups:  make([]PatchGroupOutput, 0, len(compliance.Groups)),
+		}
+		for _, group := range compliance.Groups {
+			output.Groups = append(output.Groups, newPatchGroupOutput(group))
+		}
+		printJSON(output)
+		return
+	}
+
+	if total.Minions == 0 {
+		c.ui.PrintInfo("No minions connected")
+		return
+	}
+	fmt.Printf("Patch compliance of %d minions, from their last patch:status:\n", total.Minions)
+	fmt.Printf("  Compliant:       %d (%s)\n", total.Compliant, formatStatsRate(int64(total.Compliant), int64(total.Minions)))
+	fmt.Printf("  Vulnerable:      %d, %d security updates pending\n", total.Vulnerable, total.SecurityUpdates)
+	fmt.Printf("  Unknown:         %d\n", total.Unknown)
+	fmt.Printf("  Reboot required: %d\n", total.RebootRequired)
+	if total.Unknown > 0 {
+		fmt.Println("Refresh the unknown minions with 'command-send all patch:status'")
+	}
+
+	if len(compliance.Groups) == 0 {
+		return
+	}
+	fmt.Printf("\nBy %s:\n", compliance.GroupBy)
+	rows := make([][]string, 0, len(compliance.Groups))
+	for _, group := range compliance.Groups {
+		value := group.Value
+		if value == "" {
+			value = "(untagged)"
+		}
+		rows = append(rows, []string{
+			value,
+			strconv.Itoa(int(group.Minions)),
+			fmt.Sprintf("%d (%s)", group.Compliant, formatStatsRate(int64(group.Compliant), int64(group.Minions))),
+			strconv.Itoa(int(group.Vulnerable)),
+			strconv.Itoa(int(group.Unknown)),
+			strconv.Itoa(int(group.SecurityUpdates)),
+			strconv.Itoa(int(group.Updates)),
+			strconv.Itoa(int(group.RebootRequired)),
+			c.formatUnixTime(group.OldestStatus),
+		})
+	}
+	printTable([]string{compliance.GroupBy, "Minions", "Compliant", "Vulnerable", "Unknown", "Security", "Updates", "Reboot", "Oldest status"}, rows)
+}
+
+// newPatchGroupOutput converts the patch compliance of a group to its JSON representation
+func newPatchGroupOutput(group *pb.PatchGroup) PatchGroupOutput {
+	return PatchGroupOutput{
+		Value:           group.Value,
+		Minions:         group.Minions,
+		Compliant:       group.Compliant,
+		Vulnerable:      group.Vulnerable,
+		Unknown:         group.Unknown,
+		RebootRequired:  group.RebootRequired,
+		SecurityUpdates: group.SecurityUpdates,
+		Updates:         group.Updates,
+		OldestStatus:    group.OldestStatus,
+	}
+}
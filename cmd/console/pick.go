@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/chzyer/readline"
+	"go.uber.org/zap"
+)
+
+const (
+	// pickUsage is the usage of the pick command
+	pickUsage = "usage: pick [filter]"
+	// pickHelp is shown on the status line of the picker
+	pickHelp = "type to filter  up/down move  tab select  ctrl-a all  enter use  esc cancel"
+)
+
+// pickCandidate is a minion listed by the picker, with the text its filter is matched against
+type pickCandidate struct {
+	minion *pb.HostInfo
+	label  string // ID, hostname and tags, as displayed
+}
+
+// minionPicker is a filterable, multi-select list of minions
+type minionPicker struct {
+	candidates []pickCandidate
+	filter     string
+	visible    []int           // indexes of the candidates matching the filter, best match first
+	selected   map[string]bool // IDs of the selected minions, kept when the filter changes
+	cursor     int             // index in visible
+	top        int             // first displayed entry of visible
+	rows       int
+	cols       int
+	cancelled  bool
+}
+
+// newMinionPicker creates a picker of minions, sorted by hostname, for a terminal of rows x cols
+func newMinionPicker(minions []*pb.HostInfo, filter string, rows, cols int) *minionPicker {
+	p := &minionPicker{selected: make(map[string]bool)}
+	for _, minion := range minions {
+		p.candidates = append(p.candidates, pickCandidate{minion: minion, label: pickLabel(minion)})
+	}
+	sort.SliceStable(p.candidates, func(i, j int) bool {
+		return p.candidates[i].minion.Hostname < p.candidates[j].minion.Hostname
+	})
+	p.rows, p.cols = max(rows, 3), max(cols, 20)
+	p.setFilter(filter)
+	return p
+}
+
+// pickLabel describes a minion by its ID, hostname and tags, sorted by key
+func pickLabel(minion *pb.HostInfo) string {
+	keys := make([]string, 0, len(minion.Tags))
+	for key := range minion.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	label := minion.Id + "  " + minion.Hostname
+	for _, key := range keys {
+		label += "  " + key + "=" + minion.Tags[key]
+	}
+	return stripControl(label)
+}
+
+// stripControl removes the control characters of text supplied by minions, such as escape sequences,
+// before it is written to a terminal in raw mode
+func stripControl(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// fuzzyScore matches a filter term against text, its characters having to appear in order, ignoring
+// case. It returns false when the term doesn't match, and a lower score for closer matches.
+func fuzzyScore(term, text string) (int, bool) {
+	text = strings.ToLower(text)
+	if strings.Contains(text, term) {
+		return 0, true
+	}
+
+	score, next := 1, 0
+	for n, r := range []rune(term) {
+		i := strings.IndexRune(text[next:], r)
+		if i < 0 {
+			return 0, false
+		}
+		if n > 0 {
+			score += i // characters skipped between two matched ones
+		}
+		next += i + utf8.RuneLen(r)
+	}
+	return score, true
+}
+
+// setFilter lists the candidates matching every term of filter, closest matches first
+func (p *minionPicker) setFilter(filter string) {
+	p.filter = filter
+	terms := strings.Fields(strings.ToLower(filter))
+
+	type match struct{ index, score int }
+	var matches []match
+	for i, candidate := range p.candidates {
+		total, ok := 0, true
+		for _, term := range terms {
+			score, matched := fuzzyScore(term, candidate.label)
+			if !matched {
+				ok = false
+				break
+			}
+			total += score
+		}
+		if ok {
+			matches = append(matches, match{i, total})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+
+	p.visible = p.visible[:0]
+	for _, m := range matches {
+		p.visible = append(p.visible, m.index)
+	}
+	p.cursor, p.top = 0, 0
+}
+
+// listRows is the number of minions displayed between the filter line and the status line
+func (p *minionPicker) listRows() int {
+	return p.rows - 2
+}
+
+// move moves the cursor by delta, scrolling the list to keep it displayed
+func (p *minionPicker) move(delta int) {
+	p.cursor = max(0, min(p.cursor+delta, len(p.visible)-1))
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+p.listRows() {
+		p.top = p.cursor - p.listRows() + 1
+	}
+}
+
+// toggle selects the minion under the cursor, or unselects it
+func (p *minionPicker) toggle() {
+	if len(p.visible) == 0 {
+		return
+	}
+	id := p.candidates[p.visible[p.cursor]].minion.Id
+	if p.selected[id] {
+		delete(p.selected, id)
+	} else {
+		p.selected[id] = true
+	}
+}
+
+// toggleAll selects all the listed minions, or unselects them when they all are
+func (p *minionPicker) toggleAll() {
+	all := true
+	for _, i := range p.visible {
+		all = all && p.selected[p.candidates[i].minion.Id]
+	}
+	for _, i := range p.visible {
+		if all {
+			delete(p.selected, p.candidates[i].minion.Id)
+		} else {
+			p.selected[p.candidates[i].minion.Id] = true
+		}
+	}
+}
+
+// selection returns the IDs of the selected minions in list order, the minion under the cursor when
+// none is selected
+func (p *minionPicker) selection() []string {
+	var ids []string
+	for _, candidate := range p.candidates {
+		if p.selected[candidate.minion.Id] {
+			ids = append(ids, candidate.minion.Id)
+		}
+	}
+	if len(ids) == 0 && len(p.visible) > 0 {
+		ids = []string{p.candidates[p.visible[p.cursor]].minion.Id}
+	}
+	return ids
+}
+
+// handleKey applies a keystroke, returning false when the picker is closed
+func (p *minionPicker) handleKey(key string) bool {
+	switch key {
+	case "\r", "\n":
+		return false
+	case "\x1b", "\x03", "\x07":
+		p.cancelled = true
+		return false
+	case "up", "\x10":
+		p.move(-1)
+	case "down", "\x0e":
+		p.move(1)
+	case "pgup":
+		p.move(-p.listRows())
+	case "pgdn":
+		p.move(p.listRows())
+	case "\t":
+		p.toggle()
+		p.move(1)
+	case "\x01":
+		p.toggleAll()
+	case "\x15":
+		p.setFilter("")
+	case "\x7f", "\b":
+		if typed := []rune(p.filter); len(typed) > 0 {
+			p.setFilter(string(typed[:len(typed)-1]))
+		}
+	default:
+		if r, _ := utf8.DecodeRuneInString(key); unicode.IsPrint(r) && utf8.RuneCountInString(key) == 1 {
+			p.setFilter(p.filter + key)
+		}
+	}
+	return true
+}
+
+// render draws the filter, the listed minions and the status line
+func (p *minionPicker) render(w io.Writer) {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	screen.WriteString(fitWidth("pick> "+p.filter, p.cols) + "\r\n")
+
+	end := min(p.top+p.listRows(), len(p.visible))
+	for row, i := range p.visible[p.top:end] {
+		candidate := p.candidates[i]
+		mark := "[ ] "
+		if p.selected[candidate.minion.Id] {
+			mark = "[x] "
+		}
+		line := fitWidth(mark+candidate.label, p.cols)
+		if p.top+row == p.cursor {
+			line = "\x1b[7m" + line + "\x1b[27m"
+		}
+		screen.WriteString(line + "\r\n")
+	}
+	for i := end - p.top; i < p.listRows(); i++ {
+		screen.WriteString("\r\n")
+	}
+
+	status := fmt.Sprintf("%d/%d minions, %d selected | %s", len(p.visible), len(p.candidates), len(p.selected), pickHelp)
+	screen.WriteString("\x1b[7m" + fitWidth(status, p.cols) + "\x1b[0m")
+	io.WriteString(w, screen.String())
+}
+
+// pickMinions opens a filterable list of the minions and makes the selected ones the working target,
+// the target of the next commands sent with run. Outside a terminal, the minions matching the filter
+// are selected.
+func (c *Console) pickMinions(ctx context.Context, args []string) {
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		c.printError(pickUsage)
+		return
+	}
+	if c.isJSONOutput() {
+		c.printError("'pick' is interactive, not available with JSON output")
+		return
+	}
+
+	response, err := c.grpc.ListMinions(ctx)
+	if err != nil {
+		c.logger.Error("Failed to list minions", zap.Error(err))
+		c.printError(fmt.Sprintf("Error listing minions: %v", err))
+		return
+	}
+	if len(response.Minions) == 0 {
+		c.ui.PrintInfo("No minions connected")
+		return
+	}
+
+	filter := strings.Join(args, " ")
+	fd := int(os.Stdin.Fd())
+	var ids []string
+	if !readline.IsTerminal(fd) || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		if filter == "" {
+			c.printError("'pick' needs a terminal, or a filter selecting the matching minions")
+			return
+		}
+		picker := newMinionPicker(response.Minions, filter, 0, 0)
+		for _, i := range picker.visible {
+			ids = append(ids, picker.candidates[i].minion.Id)
+		}
+	} else {
+		if ids, err = c.runMinionPicker(ctx, fd, response.Minions, filter); err != nil {
+			c.printError(fmt.Sprintf("Error opening the minion picker: %v", err))
+			return
+		}
+	}
+
+	if len(ids) == 0 {
+		c.ui.PrintInfo("No minion selected, the working target is unchanged")
+		return
+	}
+	c.selectTarget([]string{"minion", strings.Join(ids, ",")})
+}
+
+// runMinionPicker shows the picker until the selection is confirmed, returning no minion when cancelled
+func (c *Console) runMinionPicker(ctx context.Context, fd int, minions []*pb.HostInfo, filter string) ([]string, error) {
+	width, height, err := readline.GetSize(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+	picker := newMinionPicker(minions, filter, height, width)
+
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer readline.Restore(fd, state)
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	done := make(chan struct{})
+	defer close(done)
+	keys := make(chan []byte)
+	go readPagerInput(done, keys)
+	resizes := make(chan [2]int, 1)
+	go watchTerminalSize(fd, done, func(rows, cols uint32) {
+		select {
+		case resizes <- [2]int{int(rows), int(cols)}:
+		default:
+		}
+	})
+
+	for {
+		picker.render(os.Stdout)
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case size := <-resizes:
+			picker.rows, picker.cols = max(size[0], 3), max(size[1], 20)
+			picker.move(0)
+		case data, ok := <-keys:
+			if !ok {
+				return nil, nil
+			}
+			for _, key := range parsePagerKeys(data) {
+				if !picker.handleKey(key) {
+					if picker.cancelled {
+						return nil, nil
+					}
+					return picker.selection(), nil
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// ProfileOutput is the JSON representation of a connection profile
+type ProfileOutput struct {
+	Name    string `json:"name"`
+	Server  string `json:"server"`
+	Current bool   `json:"current"`
+}
+
+// ProfileListOutput is the JSON representation of the connect command without profile
+type ProfileListOutput struct {
+	Current  string          `json:"current,omitempty"`
+	Count    int             `json:"count"`
+	Profiles []ProfileOutput `json:"profiles"`
+}
+
+// SetConfig sets the configuration the console connected with, profiles switching from it
+func (c *Console) SetConfig(cfg *config.ConsoleConfig) {
+	c.config = cfg
+}
+
+// connect handles connect [profile], listing the profiles without argument
+func (c *Console) connect(args []string) {
+	if c.config == nil {
+		c.printError("Profiles are not available")
+		return
+	}
+	switch len(args) {
+	case 0:
+		c.listProfiles()
+		return
+	case 1:
+	default:
+		c.printError("Usage: connect [profile]")
+		return
+	}
+
+	cfg := *c.config
+	if err := cfg.UseProfile(args[0]); err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	grpcClient, err := NewGRPCClient(&cfg, c.logger)
+	if err != nil {
+		c.logger.Error("Failed to connect with profile", zap.String("profile", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error connecting with profile '%s': %v", args[0], err))
+		return
+	}
+	var signer *certs.CommandSigner
+	if cfg.SignCommands {
+		if signer, err = loadSigner(&cfg); err != nil {
+			grpcClient.Close()
+			c.printError(fmt.Sprintf("Error loading command signing credentials: %v", err))
+			return
+		}
+	}
+
+	if c.grpc != nil {
+		c.grpc.Close()
+	}
+	if cfg.OutputFormat != c.config.OutputFormat {
+		c.SetOutputFormat(cfg.OutputFormat)
+	}
+	c.grpc = grpcClient
+	c.client = grpcClient.client
+	c.signer = signer
+	c.config = &cfg
+	// The minions of the working target belong to the previous Nexus
+	c.target = nil
+	c.ui.SetPrompt("")
+	c.watchConnection(grpcClient)
+
+	c.ui.PrintSuccess(fmt.Sprintf("Connected to %s with profile '%s'", cfg.ServerAddr, cfg.Profile))
+}
+
+// listProfiles lists the connection profiles of the profile file
+func (c *Console) listProfiles() {
+	profiles, err := config.LoadConsoleProfiles(c.config.ProfileFile)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+	names := config.ProfileNames(profiles)
+
+	if c.isJSONOutput() {
+		output := ProfileListOutput{Current: c.config.Profile, Count: len(names), Profiles: make([]ProfileOutput, 0, len(names))}
+		for _, name := range names {
+			output.Profiles = append(output.Profiles, ProfileOutput{
+				Name:    name,
+				Server:  profiles[name].Server,
+				Current: name == c.config.Profile,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(names) == 0 {
+		file := c.config.ProfileFile
+		if file == "" {
+			file = config.DefaultProfileFile()
+		}
+		c.ui.PrintInfo(fmt.Sprintf("No profiles defined in %s", file))
+		return
+	}
+
+	fmt.Printf("Profiles (%d), connected to %s:\n", len(names), c.config.ServerAddr)
+	for _, name := range names {
+		marker := " "
+		if name == c.config.Profile {
+			marker = "*"
+		}
+		fmt.Printf("  %s %-20s %s\n", marker, name, profiles[name].Server)
+	}
+}
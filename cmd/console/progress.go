@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// progressPollInterval is how often the status of a followed command is polled
+var progressPollInterval = time.Second
+
+// progressStatuses lists the command statuses in the order they are displayed
+var progressStatuses = []string{"PENDING", "RECEIVED", "EXECUTING", "COMPLETED", "FAILED"}
+
+// isFinalStatus reports whether a minion is done with a command
+func isFinalStatus(status string) bool {
+	return status == "COMPLETED" || status == "FAILED"
+}
+
+// followProgress displays the progress of a command dispatched to several minions until every minion
+// not held by a maintenance window finished it, then prints a summary table.
+// Ctrl-C stops following the command, which keeps running on the minions.
+// It returns false when Nexus can't report the command status.
+func (c *Console) followProgress(ctx context.Context, status *CommandStatus, held []string) bool {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	waiting := make(map[string]bool, len(held))
+	for _, minionID := range held {
+		waiting[minionID] = true
+	}
+
+	start := time.Now()
+	var response *pb.CommandStatusResponse
+	lastLine := ""
+	for {
+		current, err := c.grpc.GetCommandStatus(ctx, status.CommandID)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if response == nil {
+				c.logger.Debug("Command status unavailable, not following progress",
+					zap.String("command_id", status.CommandID),
+					zap.Error(err))
+				return false
+			}
+			c.logger.Warn("Failed to poll command status", zap.String("command_id", status.CommandID), zap.Error(err))
+		} else {
+			response = current
+			for _, minionStatus := range response.Statuses {
+				status.Statuses[minionStatus.MinionId] = minionStatus.Status
+			}
+		}
+
+		line := formatProgress(status, time.Since(start))
+		if line != lastLine {
+			fmt.Printf("\r%s", line)
+			lastLine = line
+		}
+		if progressDone(status, waiting) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(progressPollInterval):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	fmt.Println()
+
+	if ctx.Err() != nil {
+		c.ui.PrintInfo("Stopped following the command, it keeps running. Check later with 'result-get " + status.CommandID + "'")
+	}
+	c.printProgressSummary(context.Background(), status, waiting)
+	return true
+}
+
+// progressDone reports whether all the minions not held by a maintenance window finished the command
+func progressDone(status *CommandStatus, held map[string]bool) bool {
+	for minionID, st := range status.Statuses {
+		if !held[minionID] && !isFinalStatus(st) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatProgress formats the progress line of a command
+func formatProgress(status *CommandStatus, elapsed time.Duration) string {
+	counts := make(map[string]int)
+	done := 0
+	for _, st := range status.Statuses {
+		counts[st]++
+		if isFinalStatus(st) {
+			done++
+		}
+	}
+
+	parts := make([]string, 0, len(progressStatuses))
+	for _, st := range progressStatuses {
+		parts = append(parts, fmt.Sprintf("%s %d", strings.ToLower(st), counts[st]))
+	}
+	return fmt.Sprintf("Progress: %d/%d done | %s | %s", done, len(status.Statuses),
+		strings.Join(parts, ", "), elapsed.Truncate(time.Second))
+}
+
+// printProgressSummary prints the final status of the command on each minion
+func (c *Console) printProgressSummary(ctx context.Context, status *CommandStatus, held map[string]bool) {
+	exitCodes := make(map[string]int32)
+	response, err := c.grpc.GetCommandResults(ctx, &pb.ResultRequest{CommandId: status.CommandID})
+	if err == nil {
+		for _, result := range response.Results {
+			exitCodes[result.MinionId] = result.ExitCode
+		}
+	}
+
+	minionIDs := make([]string, 0, len(status.Statuses))
+	for minionID := range status.Statuses {
+		minionIDs = append(minionIDs, minionID)
+	}
+	sort.Strings(minionIDs)
+
+	fmt.Printf("Summary for command %s:\n", status.CommandID)
+	fmt.Println("Minion ID                            | Status    | Exit Code")
+	fmt.Println("------------------------------------ | --------- | ---------")
+	counts := make(map[string]int)
+	for _, minionID := range minionIDs {
+		st := status.Statuses[minionID]
+		counts[st]++
+
+		exitCode := "-"
+		if code, ok := exitCodes[minionID]; ok {
+			exitCode = fmt.Sprintf("%d", code)
+		}
+		if held[minionID] && !isFinalStatus(st) {
+			st += " (held)"
+		}
+		fmt.Printf("%-36s | %-9s | %s\n", minionID, st, exitCode)
+	}
+
+	parts := make([]string, 0, len(progressStatuses))
+	for _, st := range progressStatuses {
+		if counts[st] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[st], strings.ToLower(st)))
+		}
+	}
+	fmt.Printf("Total: %d minion(s), %s\n", len(minionIDs), strings.Join(parts, ", "))
+
+	if err == nil && len(response.Results) > 0 {
+		fmt.Printf("Results (%d):\n", len(response.Results))
+		c.printResultTable(response.Results)
+	}
+}
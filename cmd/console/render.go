@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// printStructuredResults renders the structured payload of results as tables
+func printStructuredResults(results []*pb.CommandResult) {
+	for _, result := range results {
+		if result.Structured == "" {
+			continue
+		}
+		table, err := renderStructured(result.Structured)
+		if err != nil {
+			continue
+		}
+		fmt.Printf("\n%s (%s):\n%s", result.MinionId, result.ContentType, table)
+	}
+}
+
+// printFailedExecutions prints how the minions ran the commands that failed on them, to debug the
+// failures without running the commands again
+func printFailedExecutions(results []*pb.CommandResult) {
+	for _, result := range results {
+		if result.ExitCode != 0 && result.Execution != nil {
+			fmt.Printf("\n%s execution: %s\n", result.MinionId, describeExecution(result.Execution))
+		}
+	}
+}
+
+// describeExecution describes the execution info of a result on one line
+func describeExecution(info *pb.ExecutionInfo) string {
+	var parts []string
+	if info.WorkingDir != "" {
+		parts = append(parts, "in "+info.WorkingDir)
+	}
+	if info.User != "" {
+		parts = append(parts, "as "+info.User)
+	}
+	parts = append(parts, "took "+formatExecutionDuration(info))
+	if info.MaxRssKb > 0 {
+		parts = append(parts, "max RSS "+formatMaxRSS(info))
+	}
+	return strings.Join(append(parts, "PATH="+info.Path), ", ")
+}
+
+// formatExecutionDuration formats the time a minion spent executing a command, empty when unknown
+func formatExecutionDuration(info *pb.ExecutionInfo) string {
+	if info == nil {
+		return ""
+	}
+	return formatHistoryDuration(info.DurationMs)
+}
+
+// formatMaxRSS formats the peak resident memory of a command, empty when unknown
+func formatMaxRSS(info *pb.ExecutionInfo) string {
+	if info.GetMaxRssKb() <= 0 {
+		return ""
+	}
+	return formatTransferSize(info.MaxRssKb * 1024)
+}
+
+// renderStructured renders a JSON payload: objects as key/value lines,
+// arrays of objects as a table whose columns follow the first object's fields
+func renderStructured(payload string) (string, error) {
+	trimmed := strings.TrimSpace(payload)
+	if strings.HasPrefix(trimmed, "[") {
+		return renderArray([]byte(trimmed))
+	}
+	return renderObject([]byte(trimmed))
+}
+
+// renderObject renders a JSON object as aligned key/value lines
+func renderObject(raw []byte) (string, error) {
+	keys, err := objectKeys(raw)
+	if err != nil {
+		return "", err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return "", err
+	}
+
+	width := 0
+	for _, key := range keys {
+		if len(key) > width {
+			width = len(key)
+		}
+	}
+
+	var out strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&out, "  %-*s : %s\n", width, key, formatValue(values[key]))
+	}
+	return out.String(), nil
+}
+
+// renderArray renders a JSON array of objects as a table
+func renderArray(raw []byte) (string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "  (no entries)\n", nil
+	}
+
+	columns, err := objectKeys(items[0])
+	if err != nil {
+		return "", err
+	}
+
+	rows := make([][]string, 0, len(items))
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = len(column)
+	}
+	for _, item := range items {
+		var values map[string]interface{}
+		if err := json.Unmarshal(item, &values); err != nil {
+			return "", err
+		}
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = formatValue(values[column])
+			if len(row[i]) > widths[i] {
+				widths[i] = len(row[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var out strings.Builder
+	writeRow := func(cells []string) {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		fmt.Fprintf(&out, "  %s\n", strings.TrimRight(strings.Join(parts, " | "), " "))
+	}
+
+	header := make([]string, len(columns))
+	separator := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+		separator[i] = strings.Repeat("-", widths[i])
+	}
+	writeRow(header)
+	writeRow(separator)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return out.String(), nil
+}
+
+// objectKeys returns the keys of a JSON object in document order
+func objectKeys(raw []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	var keys []string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid object key")
+		}
+		keys = append(keys, key)
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// formatValue formats a decoded JSON value for display
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64, bool:
+		return fmt.Sprint(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// parseReportParams parses report-run parameters given as key=value
+func parseReportParams(args []string) (map[string]string, error) {
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid parameter '%s', use key=value", arg)
+		}
+		params[strings.TrimPrefix(key, "$")] = value
+	}
+	return params, nil
+}
+
+// createReport saves a report query on the nexus
+func (c *Console) createReport(ctx context.Context, args []string) {
+	if len(args) < 2 {
+		c.printError(`usage: report-create <name> "<query>" [description]`)
+		return
+	}
+
+	report := &pb.Report{
+		Name:        args[0],
+		Query:       args[1],
+		Description: strings.Join(args[2:], " "),
+	}
+	created, err := c.grpc.CreateReport(ctx, report)
+	if err != nil {
+		c.logger.Error("Failed to create report", zap.String("report", report.Name), zap.Error(err))
+		c.printError(fmt.Sprintf("Error creating report: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(newReportOutput(created))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Report %s saved", created.Name))
+}
+
+// listReports lists the saved reports
+func (c *Console) listReports(ctx context.Context) {
+	response, err := c.grpc.ListReports(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error listing reports: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := ReportListOutput{
+			Count:   len(response.Reports),
+			Reports: make([]ReportOutput, 0, len(response.Reports)),
+		}
+		for _, report := range response.Reports {
+			output.Reports = append(output.Reports, newReportOutput(report))
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(response.Reports) == 0 {
+		c.ui.PrintInfo("No reports saved")
+		return
+	}
+
+	fmt.Printf("Reports (%d):\n", len(response.Reports))
+	for _, report := range response.Reports {
+		fmt.Printf("  %s: %s\n", report.Name, report.Query)
+		if report.Description != "" {
+			fmt.Printf("    %s\n", report.Description)
+		}
+	}
+}
+
+// runReport runs a saved report and prints its table
+func (c *Console) runReport(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		c.printError("usage: report-run <name> [key=value ...]")
+		return
+	}
+
+	params, err := parseReportParams(args[1:])
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	result, err := c.grpc.RunReport(ctx, &pb.ReportRequest{Name: args[0], Params: params})
+	if err != nil {
+		c.logger.Error("Failed to run report", zap.String("report", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error running report: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := ReportResultOutput{
+			Name:    result.Name,
+			Columns: result.Columns,
+			Rows:    make([][]string, 0, len(result.Rows)),
+		}
+		for _, row := range result.Rows {
+			output.Rows = append(output.Rows, row.Values)
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(result.Rows) == 0 {
+		c.ui.PrintInfo(fmt.Sprintf("Report %s: no matching results", result.Name))
+		return
+	}
+
+	fmt.Printf("Report %s (%d rows, %s):\n", result.Name, len(result.Rows), time.Now().Format("2006-01-02 15:04"))
+	printReportTable(result)
+}
+
+// printReportTable prints report rows as an aligned table
+func printReportTable(result *pb.ReportResult) {
+	widths := make([]int, len(result.Columns))
+	for i, column := range result.Columns {
+		widths[i] = len(column)
+	}
+	for _, row := range result.Rows {
+		for i, value := range row.Values {
+			if i < len(widths) && len(value) > widths[i] {
+				widths[i] = len(value)
+			}
+		}
+	}
+
+	printRow := func(values []string) {
+		cells := make([]string, len(widths))
+		for i := range widths {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			cells[i] = fmt.Sprintf("%-*s", widths[i], value)
+		}
+		fmt.Println(strings.TrimRight(strings.Join(cells, " | "), " "))
+	}
+
+	printRow(result.Columns)
+	separators := make([]string, len(widths))
+	for i, width := range widths {
+		separators[i] = strings.Repeat("-", width)
+	}
+	printRow(separators)
+	for _, row := range result.Rows {
+		printRow(row.Values)
+	}
+}
+
+// newReportOutput converts a report to its JSON representation
+func newReportOutput(report *pb.Report) ReportOutput {
+	return ReportOutput{
+		Name:        report.Name,
+		Query:       report.Query,
+		Description: report.Description,
+		CreatedAt:   report.CreatedAt,
+	}
+}
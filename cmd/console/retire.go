@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const minionRetireUsage = "usage: minion-retire <minion-id> [--uninstall] [--reason <text>] [--yes]"
+
+// parseRetireMinionRequest parses minion-retire arguments, reporting whether the confirmation is skipped
+func parseRetireMinionRequest(args []string) (*pb.RetireMinionRequest, bool, error) {
+	if len(args) < 1 {
+		return nil, false, fmt.Errorf(minionRetireUsage)
+	}
+	req := &pb.RetireMinionRequest{MinionId: args[0]}
+	yes := false
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--uninstall":
+			req.Uninstall = true
+		case "--yes":
+			yes = true
+		case "--reason":
+			if i+1 >= len(args) {
+				return nil, false, fmt.Errorf(minionRetireUsage)
+			}
+			req.Reason = args[i+1]
+			i++
+		default:
+			return nil, false, fmt.Errorf(minionRetireUsage)
+		}
+	}
+	return req, yes, nil
+}
+
+// retireMinion decommissions a host once confirmed: Nexus stops dispatching to it, tells the minion to
+// stop for good and archives its rows into the history tables
+func (c *Console) retireMinion(ctx context.Context, args []string) {
+	req, yes, err := parseRetireMinionRequest(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	if !yes {
+		action := "stop for good"
+		if req.Uninstall {
+			action = "stop for good and uninstall itself"
+		}
+		c.ui.PrintWarning(fmt.Sprintf("Minion %s will %s, its host and results moving to the history tables", req.MinionId, action))
+		if !c.ui.Confirm("Retire it?") {
+			c.ui.PrintInfo(fmt.Sprintf("Minion not retired. To retire it without prompt, run: minion-retire %s --yes", strings.Join(quoteArgs(args), " ")))
+			return
+		}
+	}
+
+	resp, err := c.grpc.RetireMinion(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to retire minion", zap.String("minion_id", req.MinionId), zap.Error(err))
+		c.printError(fmt.Sprintf("Error retiring minion: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(RetireMinionOutput{
+			MinionID:        resp.MinionId,
+			Notified:        resp.Notified,
+			ArchivedResults: resp.ArchivedResults,
+			DroppedCommands: resp.DroppedCommands,
+		})
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Minion %s retired: %d results archived, %d queued commands dropped",
+		resp.MinionId, resp.ArchivedResults, resp.DroppedCommands))
+	if !resp.Notified {
+		c.ui.PrintInfo("The minion is not connected, it will be told at its next registration")
+	}
+}
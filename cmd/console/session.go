@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	useUsage = "usage: use <all|minion <id>|tag <key=value>|region=/dc=/rack=<name>> | use none"
+	runUsage = "usage: run [command-send options] <command>"
+)
+
+// sendValueOptions are the command-send options taking a value as their next argument
+var sendValueOptions = map[string]bool{"--confirm": true, "--lock": true, "--priority": true, "--impact": true,
+	"--retry": true, "--retry-backoff": true, "--retry-on": true, "--sample": true}
+
+// splitSendOptions splits command-send arguments into their leading options and the rest
+func splitSendOptions(args []string) ([]string, []string) {
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "--") {
+		if sendValueOptions[args[i]] && i+1 < len(args) {
+			i++
+		}
+		i++
+	}
+	return args[:i], args[i:]
+}
+
+// selectTarget sets the working target of the session, the target of the commands sent with run.
+// "use none" clears it, "use" alone shows it.
+func (c *Console) selectTarget(args []string) {
+	if len(args) == 0 {
+		c.showTarget(context.Background())
+		return
+	}
+	if len(args) == 1 && args[0] == "none" {
+		c.target = nil
+		c.ui.SetPrompt("")
+		c.ui.PrintInfo("Working target cleared, commands need a target again")
+		return
+	}
+
+	// The target must be complete: "true" has to be parsed as the command
+	probe, err := c.parser.ParseCommand(append(append([]string{}, args...), "true"))
+	if err == nil && (strings.HasPrefix(args[0], "--") || probe.CommandText != "true") {
+		err = fmt.Errorf("%s", useUsage)
+	}
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	c.target = append([]string{}, args...)
+	c.ui.SetPrompt(strings.Join(c.target, " "))
+	c.logger.Debug("Working target selected", zap.Strings("target", c.target))
+	c.ui.PrintSuccess(fmt.Sprintf("Working target: %s, send commands to it with 'run <command>'", strings.Join(c.target, " ")))
+}
+
+// runOnTarget sends a command to the working target, as command-send would with the same options
+func (c *Console) runOnTarget(args []string) {
+	if len(c.target) == 0 {
+		c.printError("no working target, select one with 'use <target>' or send the command with command-send")
+		return
+	}
+	options, rest := splitSendOptions(args)
+	if len(rest) == 0 {
+		c.printError(runUsage)
+		return
+	}
+
+	sendArgs := make([]string, 0, len(args)+len(c.target))
+	sendArgs = append(append(append(sendArgs, options...), c.target...), rest...)
+	c.handleCommand("command-send", sendArgs)
+}
+
+// showTarget shows the working target of the session and the minions it matches
+func (c *Console) showTarget(ctx context.Context) {
+	target := strings.Join(c.target, " ")
+	var matched []string
+	if len(c.target) > 0 && c.local == nil {
+		req, err := parseExplainTargets(c.target)
+		if err == nil {
+			explanations, explainErr := c.grpc.ExplainTargets(ctx, req)
+			if explainErr != nil {
+				c.logger.Warn("Failed to resolve the working target", zap.Error(explainErr))
+			} else {
+				matched = []string{}
+				for _, minion := range explanations.Minions {
+					if minion.Matched {
+						matched = append(matched, minion.MinionId)
+					}
+				}
+			}
+		}
+	}
+
+	if c.isJSONOutput() {
+		printJSON(WorkingTargetOutput{Target: target, Matched: matched})
+		return
+	}
+	if target == "" {
+		c.ui.PrintInfo("No working target, select one with 'use <target>'")
+		return
+	}
+	fmt.Printf("Working target: %s\n", target)
+	if matched != nil {
+		fmt.Printf("Matches %d minion(s): %s\n", len(matched), strings.Join(matched, ", "))
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/chzyer/readline"
+	"go.uber.org/zap"
+)
+
+// shellEscape is the key detaching the console from a shell session, as in telnet
+const shellEscape = 0x1d // Ctrl-]
+
+// openShell attaches the console to an interactive shell on a minion: keystrokes are streamed
+// to the minion as typed and the shell output is displayed live, until the shell exits or the
+// session is detached with Ctrl-]
+func (c *Console) openShell(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.printError("Usage: shell <minion-id>")
+		return
+	}
+	if c.isJSONOutput() {
+		c.printError("'shell' is interactive, not available with JSON output")
+		return
+	}
+	minionID := args[0]
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.grpc.OpenShell(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error opening shell: %v", err))
+		return
+	}
+
+	fd := int(os.Stdin.Fd())
+	terminal := readline.IsTerminal(fd)
+	open := &pb.ShellMessage{MinionId: minionID}
+	if terminal {
+		if width, height, err := readline.GetSize(fd); err == nil {
+			open.Cols, open.Rows = uint32(width), uint32(height)
+		}
+	}
+	if err := stream.Send(open); err != nil {
+		c.printError(fmt.Sprintf("Error opening shell: %v", err))
+		return
+	}
+	ack, err := stream.Recv()
+	if err != nil {
+		c.printError(fmt.Sprintf("Error opening shell: %v", err))
+		return
+	}
+
+	c.logger.Info("Shell session opened", zap.String("minion_id", minionID), zap.String("session_id", ack.SessionId))
+	c.ui.PrintInfo(fmt.Sprintf("Connected to %s, press Ctrl-] to detach", minionID))
+
+	var state *readline.State
+	if terminal {
+		if state, err = readline.MakeRaw(fd); err != nil {
+			c.logger.Warn("Failed to put the terminal in raw mode", zap.Error(err))
+		}
+	}
+
+	// Keystrokes and resizes are sent concurrently
+	var sendMu sync.Mutex
+	send := func(msg *pb.ShellMessage) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(msg)
+	}
+
+	done := make(chan struct{})
+	go forwardShellInput(send, done)
+	if terminal {
+		go watchTerminalSize(fd, done, func(rows, cols uint32) {
+			send(&pb.ShellMessage{Rows: rows, Cols: cols})
+		})
+	}
+
+	last := c.printShellOutput(stream)
+	close(done)
+	sendMu.Lock()
+	stream.CloseSend()
+	sendMu.Unlock()
+
+	if state != nil {
+		readline.Restore(fd, state)
+	}
+	fmt.Println()
+	switch {
+	case last == nil:
+		c.ui.PrintWarning("Shell session lost")
+	case last.Error != "":
+		c.ui.PrintWarning(fmt.Sprintf("Shell session ended: %s", last.Error))
+	default:
+		c.ui.PrintInfo(fmt.Sprintf("Shell exited with code %d", last.ExitCode))
+	}
+}
+
+// printShellOutput displays the shell output until the session ends, returning its close message
+func (c *Console) printShellOutput(stream pb.ConsoleService_OpenShellClient) *pb.ShellMessage {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			c.logger.Debug("Shell stream ended", zap.Error(err))
+			return nil
+		}
+		if len(msg.Data) > 0 {
+			os.Stdout.Write(msg.Data)
+		}
+		if msg.Close {
+			return msg
+		}
+	}
+}
+
+// forwardShellInput streams keystrokes to the shell until the session ends or is detached
+func forwardShellInput(send func(*pb.ShellMessage) error, done <-chan struct{}) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := readStdin(done, buf)
+		if err != nil {
+			send(&pb.ShellMessage{Close: true})
+			return
+		}
+		if n == 0 {
+			return
+		}
+
+		data := buf[:n]
+		escape := bytes.IndexByte(data, shellEscape)
+		if escape >= 0 {
+			data = data[:escape]
+		}
+		if len(data) > 0 {
+			if err := send(&pb.ShellMessage{Data: append([]byte(nil), data...)}); err != nil {
+				return
+			}
+		}
+		if escape >= 0 {
+			send(&pb.ShellMessage{Close: true})
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chzyer/readline"
+	"golang.org/x/sys/unix"
+)
+
+// readStdin reads keystrokes, returning 0 bytes once done is closed. Standard input is
+// polled so that no keystroke meant for the console prompt is consumed after a session.
+func readStdin(done <-chan struct{}, buf []byte) (int, error) {
+	fds := []unix.PollFd{{Fd: int32(os.Stdin.Fd()), Events: unix.POLLIN}}
+	for {
+		select {
+		case <-done:
+			return 0, nil
+		default:
+		}
+
+		n, err := unix.Poll(fds, 100)
+		if err == unix.EINTR || n == 0 {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return os.Stdin.Read(buf)
+	}
+}
+
+// watchTerminalSize calls resize with the new size of the terminal each time it changes
+func watchTerminalSize(fd int, done <-chan struct{}, resize func(rows, cols uint32)) {
+	changes := make(chan os.Signal, 1)
+	signal.Notify(changes, syscall.SIGWINCH)
+	defer signal.Stop(changes)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-changes:
+			if width, height, err := readline.GetSize(fd); err == nil {
+				resize(uint32(height), uint32(width))
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// readStdin reads keystrokes. Standard input can't be polled on Windows,
+// so the keystroke typed after a session ends is consumed by the session.
+func readStdin(done <-chan struct{}, buf []byte) (int, error) {
+	n, err := os.Stdin.Read(buf)
+	select {
+	case <-done:
+		return 0, nil
+	default:
+		return n, err
+	}
+}
+
+// watchTerminalSize does nothing: Windows consoles don't signal size changes
+func watchTerminalSize(fd int, done <-chan struct{}, resize func(rows, cols uint32)) {}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// statsUsage is the usage of the stats command
+const statsUsage = "usage: stats [--since <duration|time>] [--until <duration|time>] [--command <pattern>] [--top <n>]"
+
+// parseStatsTime parses a stats boundary, either a duration before now such as 7d or 12h,
+// or a time accepted by maintenance-add
+func parseStatsTime(value string, now time.Time) (time.Time, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := parseMaintenanceTime(value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', use a duration such as 7d or 12h, RFC3339 or YYYY-MM-DDTHH:MM", value)
+}
+
+// parseStatsRequest parses stats arguments, Nexus defaulting to the last 24 hours of every command
+func parseStatsRequest(args []string, now time.Time) (*pb.CommandStatsRequest, error) {
+	req := &pb.CommandStatsRequest{}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf(statsUsage)
+		}
+		value := args[i+1]
+		switch args[i] {
+		case "--since", "--until":
+			t, err := parseStatsTime(value, now)
+			if err != nil {
+				return nil, err
+			}
+			if args[i] == "--since" {
+				req.Since = t.Unix()
+			} else {
+				req.Until = t.Unix()
+			}
+		case "--command":
+			req.CommandPattern = value
+		case "--top":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count '%s', must be a positive integer", value)
+			}
+			req.Slowest = int32(n)
+		default:
+			return nil, fmt.Errorf(statsUsage)
+		}
+		i++
+	}
+	return req, nil
+}
+
+// showCommandStats shows the success and failure counts and execution durations of the fleet
+func (c *Console) showCommandStats(ctx context.Context, args []string) {
+	req, err := parseStatsRequest(args, time.Now())
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	stats, err := c.grpc.GetCommandStats(ctx, req)
+	if err != nil {
+		c.logger.Error("Failed to get command statistics", zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting command statistics: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := CommandStatsOutput{
+			Since:          stats.Since,
+			Until:          stats.Until,
+			CommandPattern: req.CommandPattern,
+			Count:          stats.Count,
+			Successes:      stats.Count - stats.Failures,
+			Failures:       stats.Failures,
+			MedianMs:       stats.MedianMs,
+			P90Ms:          stats.P90Ms,
+			P99Ms:          stats.P99Ms,
+			MaxMs:          stats.MaxMs,
+			SlowestMinions: make([]MinionCommandStatsOutput, 0, len(stats.SlowestMinions)),
+			ByImpact:       make([]ImpactCommandStatsOutput, 0, len(stats.ByImpact)),
+		}
+		for _, minion := range stats.SlowestMinions {
+			output.SlowestMinions = append(output.SlowestMinions, MinionCommandStatsOutput{
+				MinionID: minion.MinionId,
+				Count:    minion.Count,
+				Failures: minion.Failures,
+				MedianMs: minion.MedianMs,
+				MaxMs:    minion.MaxMs,
+			})
+		}
+		for _, impact := range stats.ByImpact {
+			output.ByImpact = append(output.ByImpact, ImpactCommandStatsOutput{
+				Impact:   impact.Impact,
+				Count:    impact.Count,
+				Failures: impact.Failures,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	commands := "all commands"
+	if req.CommandPattern != "" {
+		commands = "commands matching " + req.CommandPattern
+	}
+	fmt.Printf("Statistics of %s from %s to %s:\n", commands, c.formatUnixTime(stats.Since), c.formatUnixTime(stats.Until))
+	if stats.Count == 0 {
+		c.ui.PrintInfo("No result received in this range")
+		return
+	}
+
+	fmt.Printf("  Results:   %d\n", stats.Count)
+	fmt.Printf("  Succeeded: %d (%s)\n", stats.Count-stats.Failures, formatStatsRate(stats.Count-stats.Failures, stats.Count))
+	fmt.Printf("  Failed:    %d (%s)\n", stats.Failures, formatStatsRate(stats.Failures, stats.Count))
+	fmt.Printf("  Duration:  median %s, p90 %s, p99 %s, max %s\n",
+		formatHistoryDuration(stats.MedianMs), formatHistoryDuration(stats.P90Ms),
+		formatHistoryDuration(stats.P99Ms), formatHistoryDuration(stats.MaxMs))
+
+	fmt.Printf("\nSlowest minions (%d):\n", len(stats.SlowestMinions))
+	fmt.Printf("%-36s  %7s  %7s  %9s  %9s\n", "Minion", "Results", "Failed", "Median", "Max")
+	for _, minion := range stats.SlowestMinions {
+		fmt.Printf("%-36s  %7d  %7d  %9s  %9s\n", minion.MinionId, minion.Count, minion.Failures,
+			formatHistoryDuration(minion.MedianMs), formatHistoryDuration(minion.MaxMs))
+	}
+
+	fmt.Printf("\nBy impact:\n")
+	fmt.Printf("%-12s  %7s  %7s  %7s\n", "Impact", "Results", "Failed", "Rate")
+	for _, impact := range stats.ByImpact {
+		fmt.Printf("%-12s  %7d  %7d  %7s\n", formatImpact(impact.Impact), impact.Count, impact.Failures,
+			formatStatsRate(impact.Failures, impact.Count))
+	}
+}
+
+// formatImpact formats an impact class, commands stored before impact classes having none
+func formatImpact(impact string) string {
+	if impact == "" {
+		return "unknown"
+	}
+	return impact
+}
+
+// formatStatsRate formats part/total as a percentage
+func formatStatsRate(part, total int64) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+}
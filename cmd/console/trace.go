@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTraceID generates the trace ID following a command from the console to its results
+func newTraceID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// showTrace shows the timeline of the commands sharing a trace ID
+func (c *Console) showTrace(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		c.printError("Usage: trace-get <trace-id>")
+		return
+	}
+
+	trace, err := c.grpc.GetTrace(ctx, args[0])
+	if err != nil {
+		c.logger.Error("Failed to get trace", zap.String("trace_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting trace: %v", err))
+		return
+	}
+
+	var first int64
+	if len(trace.Events) > 0 {
+		first = trace.Events[0].TimestampMs
+	}
+
+	if c.isJSONOutput() {
+		output := TraceOutput{
+			TraceID: trace.TraceId,
+			Events:  make([]TraceEventOutput, 0, len(trace.Events)),
+		}
+		for _, event := range trace.Events {
+			output.Events = append(output.Events, TraceEventOutput{
+				TimestampMs: event.TimestampMs,
+				OffsetMs:    event.TimestampMs - first,
+				Component:   event.Component,
+				Event:       event.Event,
+				CommandID:   event.CommandId,
+				MinionID:    event.MinionId,
+				Detail:      event.Detail,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	fmt.Printf("Trace %s (%d events, started %s):\n", trace.TraceId, len(trace.Events),
+		time.UnixMilli(first).Format("2006-01-02 15:04:05.000"))
+	fmt.Printf("%-9s  %-6s  %-20s  %-10s  %s\n", "Offset", "From", "Minion", "Event", "Detail")
+	for _, event := range trace.Events {
+		fmt.Printf("%-9s  %-6s  %-20s  %-10s  %s\n",
+			"+"+formatHistoryDuration(event.TimestampMs-first), event.Component, event.MinionId, event.Event,
+			truncateHistoryCommand(event.Detail))
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// filePullUsage is the usage of the file-pull command
+const filePullUsage = "usage: file-pull <minion-id> <remote-path> [local-path] | file-pull --resume <transfer-id> [local-path]"
+
+// transferCompleted is the state of the transfers whose file Nexus received entirely
+const transferCompleted = "COMPLETED"
+
+// parseFilePullArgs parses file-pull arguments into the request and the local path, empty for the
+// base name of the remote file in the current directory
+func parseFilePullArgs(args []string) (*pb.FilePullRequest, string, error) {
+	if len(args) >= 2 && args[0] == "--resume" {
+		if len(args) > 3 {
+			return nil, "", fmt.Errorf(filePullUsage)
+		}
+		local := ""
+		if len(args) == 3 {
+			local = args[2]
+		}
+		return &pb.FilePullRequest{TransferId: args[1]}, local, nil
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return nil, "", fmt.Errorf(filePullUsage)
+	}
+	if !filepath.IsAbs(args[1]) && !isWindowsAbs(args[1]) {
+		return nil, "", fmt.Errorf("remote path %s must be absolute", args[1])
+	}
+	local := ""
+	if len(args) == 3 {
+		local = args[2]
+	}
+	return &pb.FilePullRequest{MinionId: args[0], Path: args[1]}, local, nil
+}
+
+// isWindowsAbs reports whether path is an absolute Windows path such as C:\logs\app.log,
+// which filepath.IsAbs only recognizes on Windows consoles
+func isWindowsAbs(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/')
+}
+
+// formatTransferSize formats a number of bytes with a binary unit
+func formatTransferSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatTransferProgress formats the bytes received of a transfer
+func formatTransferProgress(transfer *pb.FileTransferStatus) string {
+	if transfer.Size == 0 {
+		return formatTransferSize(transfer.Received)
+	}
+	return fmt.Sprintf("%s / %s (%.0f%%)", formatTransferSize(transfer.Received), formatTransferSize(transfer.Size),
+		float64(transfer.Received)*100/float64(transfer.Size))
+}
+
+// pullFile pulls a file from a minion to Nexus, showing its progress, then downloads it.
+// Ctrl-C stops following the transfer, which keeps running on Nexus.
+func (c *Console) pullFile(ctx context.Context, args []string) {
+	req, local, err := parseFilePullArgs(args)
+	if err != nil {
+		c.printError(err.Error())
+		return
+	}
+
+	followCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	stream, err := c.grpc.PullFile(followCtx, req)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error pulling file: %v", err))
+		return
+	}
+
+	var transfer *pb.FileTransferStatus
+	for {
+		current, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if transfer != nil && followCtx.Err() != nil {
+				if !c.isJSONOutput() {
+					fmt.Println()
+				}
+				c.ui.PrintInfo(fmt.Sprintf("Transfer %s continues on Nexus, download it with: file-download %s", transfer.TransferId, transfer.TransferId))
+				return
+			}
+			c.printError(fmt.Sprintf("Error pulling file: %v", err))
+			return
+		}
+		transfer = current
+		if !c.isJSONOutput() {
+			fmt.Printf("\rPulling %s from %s: %s   ", transfer.Path, transfer.MinionId, formatTransferProgress(transfer))
+		}
+	}
+	if transfer == nil {
+		c.printError("Nexus ended the transfer without status")
+		return
+	}
+	if !c.isJSONOutput() {
+		fmt.Println()
+	}
+
+	if transfer.State != transferCompleted {
+		c.logger.Warn("File transfer not completed",
+			zap.String("transfer_id", transfer.TransferId),
+			zap.String("state", transfer.State),
+			zap.String("error", transfer.Error))
+		if c.isJSONOutput() {
+			printJSON(newFileTransferOutput(transfer, ""))
+			return
+		}
+		c.printError(fmt.Sprintf("Transfer %s %s: %s", transfer.TransferId, transfer.State, transfer.Error))
+		c.ui.PrintInfo(fmt.Sprintf("Resume it with: file-pull --resume %s", transfer.TransferId))
+		return
+	}
+
+	c.downloadTransfer(ctx, transfer, local)
+}
+
+// downloadFile downloads a file pulled to Nexus
+func (c *Console) downloadFile(ctx context.Context, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		c.printError("usage: file-download <transfer-id> [local-path]")
+		return
+	}
+
+	list, err := c.grpc.ListFileTransfers(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error listing file transfers: %v", err))
+		return
+	}
+	var transfer *pb.FileTransferStatus
+	for _, t := range list.Transfers {
+		if t.TransferId == args[0] {
+			transfer = t
+		}
+	}
+	if transfer == nil {
+		c.printError(fmt.Sprintf("Transfer %s not found", args[0]))
+		return
+	}
+	if transfer.State != transferCompleted {
+		c.printError(fmt.Sprintf("Transfer %s is %s, resume it with: file-pull --resume %s", transfer.TransferId, transfer.State, transfer.TransferId))
+		return
+	}
+
+	local := ""
+	if len(args) == 2 {
+		local = args[1]
+	}
+	c.downloadTransfer(ctx, transfer, local)
+}
+
+// downloadTransfer downloads the file of a completed transfer to local, resuming the partial
+// download left in local.part by an interrupted one, and checks its checksum
+func (c *Console) downloadTransfer(ctx context.Context, transfer *pb.FileTransferStatus, local string) {
+	if local == "" {
+		local = filepath.Base(filepath.FromSlash(transfer.Path))
+	}
+	if err := c.download(ctx, transfer, local); err != nil {
+		c.logger.Error("Failed to download file", zap.String("transfer_id", transfer.TransferId), zap.Error(err))
+		c.printError(fmt.Sprintf("Error downloading file: %v", err))
+		c.ui.PrintInfo(fmt.Sprintf("Resume the download with: file-download %s %s", transfer.TransferId, local))
+		return
+	}
+
+	if c.isJSONOutput() {
+		printJSON(newFileTransferOutput(transfer, local))
+		return
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Downloaded %s (%s, sha256 %s)", local, formatTransferSize(transfer.Size), transfer.Sha256))
+}
+
+// download writes the file of a transfer to local through local.part
+func (c *Console) download(ctx context.Context, transfer *pb.FileTransferStatus, local string) error {
+	part := local + ".part"
+	file, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+	if offset > transfer.Size {
+		offset = 0
+	}
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+
+	stream, err := c.grpc.DownloadFile(ctx, &pb.FileDownloadRequest{TransferId: transfer.TransferId, Offset: offset})
+	if err != nil {
+		return err
+	}
+	var eof bool
+	for !eof {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return errors.New("download ended before the end of the file")
+		}
+		if err != nil {
+			return err
+		}
+		if chunk.Offset != offset {
+			return fmt.Errorf("unexpected chunk at offset %d, expected %d", chunk.Offset, offset)
+		}
+		if _, err := file.WriteAt(chunk.Data, offset); err != nil {
+			return err
+		}
+		offset += int64(len(chunk.Data))
+		eof = chunk.Eof
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	checksum, err := localChecksum(part)
+	if err != nil {
+		return err
+	}
+	if checksum != transfer.Sha256 {
+		os.Remove(part)
+		return fmt.Errorf("checksum mismatch, got %s instead of %s", checksum, transfer.Sha256)
+	}
+	return os.Rename(part, local)
+}
+
+// localChecksum returns the hex SHA-256 checksum of a local file
+func localChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// listFileTransfers lists the files pulled to Nexus
+func (c *Console) listFileTransfers(ctx context.Context) {
+	list, err := c.grpc.ListFileTransfers(ctx)
+	if err != nil {
+		c.printError(fmt.Sprintf("Error listing file transfers: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := FileTransferListOutput{Count: len(list.Transfers), Transfers: make([]FileTransferOutput, 0, len(list.Transfers))}
+		for _, transfer := range list.Transfers {
+			output.Transfers = append(output.Transfers, newFileTransferOutput(transfer, ""))
+		}
+		printJSON(output)
+		return
+	}
+
+	if len(list.Transfers) == 0 {
+		c.ui.PrintInfo("No file transfers")
+		return
+	}
+	fmt.Printf("File transfers (%d):\n", len(list.Transfers))
+	rows := make([][]string, 0, len(list.Transfers))
+	for _, transfer := range list.Transfers {
+		rows = append(rows, []string{
+			transfer.TransferId,
+			transfer.MinionId,
+			transfer.Path,
+			transfer.State,
+			formatTransferProgress(transfer),
+			c.formatUnixTime(transfer.StartedAt),
+			transfer.Error,
+		})
+	}
+	printTable([]string{"Transfer ID", "Minion ID", "Path", "State", "Received", "Started", "Error"}, rows)
+}
+
+// newFileTransferOutput converts a transfer to its JSON representation
+func newFileTransferOutput(transfer *pb.FileTransferStatus, local string) FileTransferOutput {
+	return FileTransferOutput{
+		TransferID: transfer.TransferId,
+		MinionID:   transfer.MinionId,
+		Path:       transfer.Path,
+		State:      transfer.State,
+		Received:   transfer.Received,
+		Size:       transfer.Size,
+		Sha256:     transfer.Sha256,
+		Error:      transfer.Error,
+		StartedAt:  transfer.StartedAt,
+		UpdatedAt:  transfer.UpdatedAt,
+		LocalPath:  local,
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/arhuman/minexus/internal/command"
@@ -15,11 +17,20 @@ import (
 	"go.uber.org/zap"
 )
 
+// defaultPrompt is the prompt of the console without working target
+const defaultPrompt = "minexus> "
+
 // UIManager handles all user interface operations
 type UIManager struct {
 	rl       *readline.Instance
 	logger   *zap.Logger
 	registry *command.Registry
+
+	promptMu   sync.Mutex // the connection state changes while the user types
+	prompt     string
+	target     string // working target shown in the prompt
+	connection string // state of the connection to Nexus shown in the prompt
+	asking     bool   // a question replaces the prompt
 }
 
 // NewUIManager creates a new UI manager
@@ -30,6 +41,7 @@ func NewUIManager(logger *zap.Logger, registry *command.Registry) *UIManager {
 	ui := &UIManager{
 		logger:   logger,
 		registry: registry,
+		prompt:   defaultPrompt,
 	}
 
 	// Set up readline with completion and history
@@ -62,7 +74,7 @@ func (ui *UIManager) setupReadline() {
 
 	// Create readline config
 	config := &readline.Config{
-		Prompt:              "minexus> ",
+		Prompt:              ui.prompt,
 		HistoryFile:         historyFile,
 		AutoComplete:        completer,
 		InterruptPrompt:     "^C",
@@ -76,7 +88,7 @@ func (ui *UIManager) setupReadline() {
 	if err != nil {
 		ui.logger.Error("Failed to create readline instance", zap.Error(err))
 		// Fallback to a basic readline without advanced features
-		basicRL, fallbackErr := readline.New("minexus> ")
+		basicRL, fallbackErr := readline.New(ui.prompt)
 		if fallbackErr != nil {
 			ui.logger.Error("Failed to create basic readline instance", zap.Error(fallbackErr))
 			// For testing environments or when no TTY available, create a minimal mock
@@ -127,23 +139,155 @@ func (ui *UIManager) createCompleter() *readline.PrefixCompleter {
 		readline.PcItem("v"),
 		readline.PcItem("minion-list"),
 		readline.PcItem("lm"),
+		readline.PcItem("minion-inspect"),
+		readline.PcItem("minion-history"),
+		readline.PcItem("minion-uptime"),
+		readline.PcItem("minion-logs"),
+		readline.PcItem("minion-retire"),
+		readline.PcItem("crash-list"),
+		readline.PcItem("minion-bootstrap-url",
+			readline.PcItem("linux"),
+			readline.PcItem("darwin"),
+			readline.PcItem("windows"),
+		),
+		readline.PcItem("command-approvals"),
+		readline.PcItem("command-approve"),
+		readline.PcItem("command-reject"),
+		readline.PcItem("shell"),
+		readline.PcItem("file-pull",
+			readline.PcItem("--resume"),
+		),
+		readline.PcItem("file-download"),
+		readline.PcItem("file-transfers"),
+		readline.PcItem("validate",
+			readline.PcItem("all"),
+			readline.PcItem("minion"),
+			readline.PcItem("tag"),
+		),
 		readline.PcItem("tag-list"),
 		readline.PcItem("lt"),
 		readline.PcItem("result-get"),
 		readline.PcItem("results"),
+		readline.PcItem("result-view"),
+		readline.PcItem("trace-get"),
+		readline.PcItem("result-export",
+			readline.PcItem("--format",
+				readline.PcItem("csv"),
+				readline.PcItem("json"),
+			),
+			readline.PcItem("--out"),
+		),
 		readline.PcItem("tag-set"),
 		readline.PcItem("tag-update"),
+		readline.PcItem("tag-schema-show"),
+		readline.PcItem("maintenance-add",
+			readline.PcItem("minion"),
+			readline.PcItem("tag"),
+		),
+		readline.PcItem("maintenance-list"),
+		readline.PcItem("maintenance-remove"),
+		readline.PcItem("env-set",
+			readline.PcItem("minion"),
+			readline.PcItem("tag"),
+		),
+		readline.PcItem("env-unset",
+			readline.PcItem("minion"),
+			readline.PcItem("tag"),
+		),
+		readline.PcItem("env-list"),
+		readline.PcItem("report-create"),
+		readline.PcItem("report-list"),
+		readline.PcItem("report-run"),
+		readline.PcItem("stats",
+			readline.PcItem("--since"),
+			readline.PcItem("--until"),
+			readline.PcItem("--command"),
+			readline.PcItem("--top"),
+		),
+		readline.PcItem("compliance-export",
+			readline.PcItem("--since"),
+			readline.PcItem("--until"),
+			readline.PcItem("--out"),
+		),
+		readline.PcItem("compliance-verify"),
+		readline.PcItem("fleet-health",
+			readline.PcItem("--below"),
+		),
+		readline.PcItem("fleet-versions"),
+		readline.PcItem("fleet-patches"),
+		readline.PcItem("db-check",
+			readline.PcItem("--repair"),
+		),
+		readline.PcItem("db-query"),
+		readline.PcItem("admin-flush-caches"),
+		readline.PcItem("admin-log-level",
+			readline.PcItem("debug"),
+			readline.PcItem("info"),
+			readline.PcItem("warn"),
+			readline.PcItem("error"),
+			readline.PcItem("--sampling"),
+		),
+		readline.PcItem("admin-registry"),
+		readline.PcItem("admin-disconnect"),
+		readline.PcItem("admin-unbind"),
+		readline.PcItem("admin-prune"),
+		readline.PcItem("connect"),
+		readline.PcItem("alias"),
+		readline.PcItem("alias-list"),
+		readline.PcItem("alias-remove"),
+		readline.PcItem("set",
+			readline.PcItem("output",
+				readline.PcItem("text"),
+				readline.PcItem("json"),
+			),
+			readline.PcItem("time",
+				readline.PcItem("local"),
+				readline.PcItem("utc"),
+				readline.PcItem("relative"),
+			),
+			readline.PcItem("columns",
+				readline.PcItem("minion-list"),
+				readline.PcItem("result-get"),
+			),
+		),
 		readline.PcItem("clear"),
 		readline.PcItem("history"),
 		readline.PcItem("quit"),
 		readline.PcItem("exit"),
 	}
 
+	consoleCommands = append(consoleCommands, readline.PcItem("target-explain",
+		readline.PcItem("all"),
+		readline.PcItem("minion"),
+		readline.PcItem("tag"),
+	))
+
 	// Command-send with subcommands
 	commandSendItem := readline.PcItem("command-send",
 		readline.PcItem("all"),
 		readline.PcItem("minion"),
 		readline.PcItem("tag"),
+		readline.PcItem("--dry-run"),
+		readline.PcItem("--emergency"),
+		readline.PcItem("--confirm"),
+		readline.PcItem("--no-wait"),
+		readline.PcItem("--validate"),
+		readline.PcItem("--lock"),
+		readline.PcItem("--impact",
+			readline.PcItem("read-only"),
+			readline.PcItem("mutating"),
+			readline.PcItem("disruptive"),
+		),
+		readline.PcItem("--priority",
+			readline.PcItem("low"),
+			readline.PcItem("normal"),
+			readline.PcItem("high"),
+			readline.PcItem("emergency"),
+		),
+		readline.PcItem("--retry"),
+		readline.PcItem("--retry-backoff"),
+		readline.PcItem("--retry-on"),
+		readline.PcItem("--sample"),
 	)
 	consoleCommands = append(consoleCommands, commandSendItem)
 
@@ -152,9 +296,48 @@ func (ui *UIManager) createCompleter() *readline.PrefixCompleter {
 		readline.PcItem("all"),
 		readline.PcItem("minion"),
 		readline.PcItem("tag"),
+		readline.PcItem("--dry-run"),
+		readline.PcItem("--emergency"),
+		readline.PcItem("--confirm"),
+		readline.PcItem("--priority",
+			readline.PcItem("low"),
+			readline.PcItem("normal"),
+			readline.PcItem("high"),
+			readline.PcItem("emergency"),
+		),
 	)
 	consoleCommands = append(consoleCommands, cmdItem)
 
+	consoleCommands = append(consoleCommands, readline.PcItem("edit",
+		readline.PcItem("all"),
+		readline.PcItem("minion"),
+		readline.PcItem("tag"),
+		readline.PcItem("--dry-run"),
+		readline.PcItem("--no-wait"),
+		readline.PcItem("--lock"),
+	))
+
+	// Working target of the session
+	consoleCommands = append(consoleCommands,
+		readline.PcItem("use",
+			readline.PcItem("all"),
+			readline.PcItem("minion"),
+			readline.PcItem("tag"),
+			readline.PcItem("none"),
+		),
+		readline.PcItem("pick"),
+		readline.PcItem("run",
+			readline.PcItem("--dry-run"),
+			readline.PcItem("--emergency"),
+			readline.PcItem("--confirm"),
+			readline.PcItem("--no-wait"),
+			readline.PcItem("--lock"),
+			readline.PcItem("--retry"),
+			readline.PcItem("--sample"),
+		),
+		readline.PcItem("targets"),
+	)
+
 	return readline.NewPrefixCompleter(consoleCommands...)
 }
 
@@ -205,12 +388,80 @@ func (ui *UIManager) ShowHelp(args []string) {
 	fmt.Println("  version, v                                 - Show version information")
 	fmt.Println("  minion-list, lm                            - List all connected minions with last seen time")
 	fmt.Println("  tag-list, lt                               - List all available tags")
+	fmt.Println("  minion-inspect <id>                        - Show connection diagnostics of a minion")
+	fmt.Println("  minion-history <id> [count]                - Show the last commands executed on a minion")
+	fmt.Println("  minion-uptime <id> [window]                - Show the connectivity timeline and uptime of a minion (default 24h)")
+	fmt.Println("  minion-logs <id> [--level <lvl>] [--since <t>] [--limit <n>] - Show the logs a minion shipped to Nexus")
+	fmt.Println("  minion-retire <id> [--uninstall] [--reason <text>] [--yes] - Decommission a host, archiving its rows")
+	fmt.Println("  crash-list [id] [--limit <n>] [--stack]    - Show the panics minions recovered from")
+	fmt.Println("  minion-bootstrap-url <os> <arch> [--ttl <duration>] - Generate a one-time URL installing a minion")
 	fmt.Println("  command-send all <cmd>                     - Send command to all minions")
+	fmt.Println("  edit [options] <target>                    - Compose a multi-line payload in $EDITOR, then send it")
 	fmt.Println("  command-send minion <id> <cmd>             - Send command to specific minion")
 	fmt.Println("  command-send tag <key>=<value> <cmd>       - Send command to minions with tag")
+	fmt.Println("  command-send dc=<name>[,rack=<name>] <cmd> - Send command to minions of a region, datacenter or rack")
+	fmt.Println("  command-send --dry-run <target> <cmd>      - Show target minions without sending")
+	fmt.Println("  command-send --emergency <target> <cmd>    - Send even if a maintenance window holds it")
+	fmt.Println("  command-send --priority <level> <target> <cmd> - Queue with low, normal, high or emergency priority")
+	fmt.Println("  command-send --confirm <token> <target> <cmd> - Confirm a destructive command")
+	fmt.Println("  command-send --no-wait <target> <cmd>      - Don't follow the progress of multi-minion commands")
+	fmt.Println("  command-send --lock <name> <target> <cmd> - Wait for a host lock and hold it while the command runs")
+	fmt.Println("  command-send --impact <class> <target> <cmd> - Declare a read-only, mutating or disruptive impact")
+	fmt.Println("  command-send --retry <n> <target> <cmd>    - Retry failed targets up to n attempts, see --retry-backoff, --retry-on")
+	fmt.Println("  command-send --sample <n> <target> <cmd>   - Store n successful results in full and all failures, count the others")
+	fmt.Println("  command-send --validate <target> <cmd>     - Check a command and its arguments without contacting Nexus")
+	fmt.Println("  validate <target> <cmd>                    - Same as command-send --validate, also offline: ./console validate ...")
+	fmt.Println("  target-explain <target>                    - Show the minions a target matches and why, e.g. tag env=prod,!maintenance")
+	fmt.Println("  use <target> | use none                    - Select the working target of the session, or clear it")
+	fmt.Println("  run [options] <cmd>                        - Send command to the working target, as command-send would")
+	fmt.Println("  targets                                    - Show the working target and the minions it matches")
+	fmt.Println("  pick [filter]                              - Select minions in a filterable list as the working target")
+	fmt.Println("  command-approvals                          - List the commands requiring approval")
+	fmt.Println("  command-approve <cmd-id> [comment]         - Approve and dispatch a command sent by another operator")
+	fmt.Println("  command-reject <cmd-id> [comment]          - Reject a command awaiting approval")
+	fmt.Println("  shell <minion-id>                          - Open an interactive shell on a minion (Ctrl-] to detach)")
+	fmt.Println("  file-pull <minion-id> <path> [local-path]   - Pull a file of any size from a minion, resumable")
+	fmt.Println("  file-pull --resume <transfer-id> [local-path] - Resume an interrupted file pull")
+	fmt.Println("  file-download <transfer-id> [local-path]   - Download a file pulled to Nexus, resuming a partial download")
+	fmt.Println("  file-transfers                             - List the files pulled to Nexus")
 	fmt.Println("  result-get <cmd-id>                        - Get results for a command ID")
+	fmt.Println("  result-view <cmd-id> [minion-id]           - Browse the full output of the results in a pager")
+	fmt.Println("  result-export <cmd-id> [--format csv|json] [--out <file>] - Export all results of a command")
+	fmt.Println("  trace-get <trace-id>                       - Show the timeline of a command across Nexus and minions")
 	fmt.Println("  tag-set <minion-id> <key>=<value> [...]    - Set tags for a minion (replaces all)")
 	fmt.Println("  tag-update <minion-id> +<key>=<value> -<key> [...] - Update tags for a minion")
+	fmt.Println("  tag-schema-show                            - Show the keys and values allowed in tags")
+	fmt.Println("  maintenance-add minion <id>|tag <key>=<value> <start> <end> - Declare a maintenance window")
+	fmt.Println("  maintenance-list                           - List current and upcoming maintenance windows")
+	fmt.Println("  maintenance-remove <window-id>             - Remove a maintenance window")
+	fmt.Println("Command Environment:")
+	fmt.Println("  env-set minion <id>|tag <key>=<value> <NAME>=<value> - Set a variable for shell commands")
+	fmt.Println("  env-unset minion <id>|tag <key>=<value> <NAME> - Remove a variable of shell commands")
+	fmt.Println("  env-list                                   - List the variables Nexus sets for shell commands")
+	fmt.Println("  report-create <name> \"<query>\" [description] - Save a report query over command results")
+	fmt.Println("  report-list                                - List saved reports")
+	fmt.Println("  report-run <name> [key=value ...]          - Run a saved report with its parameters")
+	fmt.Println("  stats [--since <t>] [--until <t>] [--command <pattern>] [--top <n>] - Show fleet-wide success rates and durations")
+	fmt.Println("  fleet-health [--below <score>]             - Show the fleet health and the anomalous minions")
+	fmt.Println("  fleet-versions                             - Show the minion builds and the outdated minions")
+	fmt.Println("  fleet-patches [tag-key]                    - Summarize the pending updates of the fleet, by tag value")
+	fmt.Println("  compliance-export [--since <t>] [--until <t>] [--out <file>] - Export signed execution receipts as JSON lines")
+	fmt.Println("  compliance-verify <file> [--ca <bundle>]   - Check the signatures and sequence of a receipts export")
+	fmt.Println("  db-check [--repair]                        - Check database integrity, optionally repairing orphans")
+	fmt.Println("  db-query [name] [key=value ...]            - List or run the read-only database queries approved on Nexus")
+	fmt.Println("  admin-flush-caches                         - Forget the state Nexus keeps for disconnected minions (admin)")
+	fmt.Println("  admin-log-level [level] [--sampling <n,m>] - Show or change the Nexus log level and sampling (admin)")
+	fmt.Println("  admin-registry                             - Dump the registry state of Nexus (admin)")
+	fmt.Println("  admin-disconnect <minion-id>               - Close the command stream of a minion (admin)")
+	fmt.Println("  admin-unbind <minion-id>                   - Forget the certificate a minion ID is bound to (admin)")
+	fmt.Println("  admin-prune                                - Archive old results and delete old minion logs now (admin)")
+	fmt.Println("  connect [profile]                          - Connect with a profile, or list the profiles")
+	fmt.Println("  alias <name>=\"<command>\"                   - Define a persistent command alias")
+	fmt.Println("  alias-list                                 - List defined aliases")
+	fmt.Println("  alias-remove <name>                        - Remove an alias")
+	fmt.Println("  set output <text|json>                     - Set output format for data commands")
+	fmt.Println("  set time <local|utc|relative>              - Set the timestamp format (persisted)")
+	fmt.Println("  set columns <table> <column,...|default>   - Set the columns of minion-list or result-get (persisted)")
 	fmt.Println("  clear                                      - Clear screen")
 	fmt.Println("  history                                    - Show command history")
 	fmt.Println("  quit, exit                                 - Exit the console")
@@ -269,6 +520,75 @@ func (ui *UIManager) PrintSuccess(msg string) {
 	fmt.Printf("✓ %s\n", msg)
 }
 
+// SetPrompt shows the working target in the prompt, the default prompt being restored without target
+func (ui *UIManager) SetPrompt(target string) {
+	ui.promptMu.Lock()
+	defer ui.promptMu.Unlock()
+
+	ui.target = target
+	ui.updatePrompt()
+}
+
+// SetConnectionState shows in the prompt whether the console is reconnecting to Nexus, the prompt
+// being refreshed while the user types
+func (ui *UIManager) SetConnectionState(state string) {
+	ui.promptMu.Lock()
+	defer ui.promptMu.Unlock()
+
+	if state == ui.connection {
+		return
+	}
+	ui.connection = state
+	ui.updatePrompt()
+	if ui.rl != nil && !ui.asking {
+		ui.rl.Refresh()
+	}
+}
+
+// updatePrompt composes the prompt from the working target and the connection state, promptMu held
+func (ui *UIManager) updatePrompt() {
+	prompt := "minexus"
+	if ui.target != "" {
+		prompt += fmt.Sprintf(" [%s]", ui.target)
+	}
+	if ui.connection == ConnectionReconnecting {
+		prompt += " (reconnecting)"
+	}
+	ui.prompt = prompt + "> "
+	if ui.rl != nil && !ui.asking {
+		ui.rl.SetPrompt(ui.prompt)
+	}
+}
+
+// Confirm asks a question and reports whether the user answered yes.
+// Without a terminal, nothing is confirmed.
+func (ui *UIManager) Confirm(question string) bool {
+	if ui.rl == nil {
+		return false
+	}
+
+	// The answer is not a command, keep it out of the history
+	ui.rl.HistoryDisable()
+	defer ui.rl.HistoryEnable()
+	ui.promptMu.Lock()
+	ui.asking = true
+	ui.rl.SetPrompt(question + " [yes/no]: ")
+	ui.promptMu.Unlock()
+	defer func() {
+		ui.promptMu.Lock()
+		defer ui.promptMu.Unlock()
+		ui.asking = false
+		ui.rl.SetPrompt(ui.prompt)
+	}()
+
+	answer, err := ui.rl.Readline()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "yes" || answer == "y"
+}
+
 // PrintInfo prints an informational message to the console
 func (ui *UIManager) PrintInfo(msg string) {
 	fmt.Println(msg)
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// uptimeTimelineWidth is the number of slots of the minion-uptime timeline
+const uptimeTimelineWidth = 60
+
+// parseUptimeWindow parses the window of minion-uptime, a duration such as 7d or 12h
+func parseUptimeWindow(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= time.Minute {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid window '%s', use a duration such as 7d or 12h", value)
+}
+
+// showMinionUptime shows the periods a minion was connected over a window and its uptime percentage
+func (c *Console) showMinionUptime(ctx context.Context, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		c.printError("Usage: minion-uptime <minion-id> [window]")
+		return
+	}
+	var window time.Duration
+	if len(args) == 2 {
+		var err error
+		if window, err = parseUptimeWindow(args[1]); err != nil {
+			c.printError(err.Error())
+			return
+		}
+	}
+
+	uptime, err := c.grpc.GetMinionUptime(ctx, args[0], window)
+	if err != nil {
+		c.logger.Error("Failed to get minion uptime", zap.String("minion_id", args[0]), zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting minion uptime: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := MinionUptimeOutput{
+			MinionID:      uptime.MinionId,
+			Since:         uptime.Since,
+			Until:         uptime.Until,
+			UptimePercent: uptime.UptimePercent,
+			Periods:       make([]ConnectionPeriodOutput, 0, len(uptime.Periods)),
+		}
+		for _, period := range uptime.Periods {
+			output.Periods = append(output.Periods, ConnectionPeriodOutput{
+				ConnectedAt:    period.ConnectedAt,
+				DisconnectedAt: period.DisconnectedAt,
+				Reason:         period.Reason,
+			})
+		}
+		printJSON(output)
+		return
+	}
+
+	fmt.Printf("Uptime of minion %s from %s to %s: %.2f%%\n",
+		uptime.MinionId, c.formatUnixTime(uptime.Since), c.formatUnixTime(uptime.Until), uptime.UptimePercent)
+	fmt.Printf("[%s]\n", uptimeTimeline(uptime, uptimeTimelineWidth))
+	if len(uptime.Periods) == 0 {
+		c.ui.PrintInfo("No connection recorded over the window")
+		return
+	}
+
+	fmt.Printf("%-19s  %-19s  %-9s  %s\n", "Connected", "Disconnected", "Duration", "Reason")
+	for _, period := range uptime.Periods {
+		disconnected, end := "connected", uptime.Until
+		if period.DisconnectedAt != 0 {
+			disconnected, end = c.formatUnixTime(period.DisconnectedAt), period.DisconnectedAt
+		}
+		duration := time.Duration(end-period.ConnectedAt) * time.Second
+		fmt.Printf("%-19s  %-19s  %-9s  %s\n", c.formatUnixTime(period.ConnectedAt), disconnected, duration.String(), period.Reason)
+	}
+}
+
+// uptimeTimeline renders the window as width slots, '#' when the minion was connected in the
+// middle of the slot and '.' otherwise
+func uptimeTimeline(uptime *pb.MinionUptime, width int) string {
+	slot := float64(uptime.Until-uptime.Since) / float64(width)
+	timeline := []byte(strings.Repeat(".", width))
+	for i := range timeline {
+		middle := float64(uptime.Since) + (float64(i)+0.5)*slot
+		for _, period := range uptime.Periods {
+			end := period.DisconnectedAt
+			if end == 0 {
+				end = uptime.Until
+			}
+			if middle >= float64(period.ConnectedAt) && middle < float64(end) {
+				timeline[i] = '#'
+				break
+			}
+		}
+	}
+	return string(timeline)
+}
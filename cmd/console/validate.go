@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateCommand checks a command-send line, optionally starting with command-send or cmd,
+// against the local command registry without contacting Nexus. It reports whether the
+// command is valid.
+func (c *Console) validateCommand(args []string) bool {
+	if len(args) > 0 && (args[0] == "command-send" || args[0] == "cmd") {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		c.printError("usage: validate <target> <command>")
+		return false
+	}
+
+	parsed, err := c.parser.ValidateCommand(args)
+	return c.showValidation(parsed, err)
+}
+
+// showValidation shows the outcome of the validation of a command, as it would be sent to Nexus
+// when valid. It reports whether the command is valid.
+func (c *Console) showValidation(parsed *ParsedCommand, err error) bool {
+	if err != nil {
+		if c.isJSONOutput() {
+			printJSON(ValidationOutput{Valid: false, Error: err.Error()})
+			return false
+		}
+		c.printError(fmt.Sprintf("Invalid command: %v", err))
+		return false
+	}
+
+	if c.isJSONOutput() {
+		printJSON(ValidationOutput{
+			Valid:   true,
+			Payload: parsed.CommandText,
+			Type:    parsed.CommandType.String(),
+			Target:  describeTarget(parsed),
+		})
+		return true
+	}
+	c.ui.PrintSuccess(fmt.Sprintf("Valid command: '%s' (%s) for %s", parsed.CommandText, parsed.CommandType, describeTarget(parsed)))
+	return true
+}
+
+// describeTarget describes the minions targeted by a parsed command
+func describeTarget(parsed *ParsedCommand) string {
+	req := parsed.Request
+	switch {
+	case len(req.MinionIds) > 0:
+		return "minion " + strings.Join(req.MinionIds, ", ")
+	case req.TagSelector != nil:
+		rules := make([]string, 0, len(req.TagSelector.Rules))
+		for _, rule := range req.TagSelector.Rules {
+			rules = append(rules, rule.Key+"="+rule.GetEquals())
+		}
+		return "tag " + strings.Join(rules, ",")
+	case req.Topology != nil:
+		var domains []string
+		for _, domain := range []struct{ key, value string }{
+			{"region", req.Topology.Region},
+			{"dc", req.Topology.Datacenter},
+			{"rack", req.Topology.Rack},
+		} {
+			if domain.value != "" {
+				domains = append(domains, domain.key+"="+domain.value)
+			}
+		}
+		return strings.Join(domains, ",")
+	default:
+		return "all minions"
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// showFleetVersions shows the builds the minions run and those below the minimum protocol version
+func (c *Console) showFleetVersions(ctx context.Context, args []string) {
+	if len(args) != 0 {
+		c.printError("usage: fleet-versions")
+		return
+	}
+
+	fleet, err := c.grpc.GetFleetVersions(ctx)
+	if err != nil {
+		c.logger.Error("Failed to get fleet versions", zap.Error(err))
+		c.printError(fmt.Sprintf("Error getting fleet versions: %v", err))
+		return
+	}
+
+	if c.isJSONOutput() {
+		output := FleetVersionsOutput{
+			Total:                fleet.Total,
+			NexusVersion:         fleet.NexusVersion,
+			NexusProtocolVersion: fleet.NexusProtocolVersion,
+			MinProtocolVersion:   fleet.MinProtocolVersion,
+			Versions:             make([]VersionCountOutput, 0, len(fleet.Versions)),
+			Outdated:             make([]string, 0, len(fleet.Outdated)),
+		}
+		for _, count := range fleet.Versions {
+			output.Versions = append(output.Versions, VersionCountOutput{
+				Version:         count.Version,
+				BuildCommit:     count.BuildCommit,
+				ProtocolVersion: count.ProtocolVersion,
+				Minions:         count.Minions,
+			})
+		}
+		for _, minion := range fleet.Outdated {
+			output.Outdated = append(output.Outdated, minion.Id)
+		}
+		printJSON(output)
+		return
+	}
+
+	fmt.Printf("Nexus %s, protocol %d", fleet.NexusVersion, fleet.NexusProtocolVersion)
+	if fleet.MinProtocolVersion > 0 {
+		fmt.Printf(", minions require protocol %d", fleet.MinProtocolVersion)
+	}
+	fmt.Println()
+	if fleet.Total == 0 {
+		c.ui.PrintInfo("No minions connected")
+		return
+	}
+
+	fmt.Printf("\nMinion versions (%d minions):\n", fleet.Total)
+	rows := make([][]string, 0, len(fleet.Versions))
+	for _, count := range fleet.Versions {
+		rows = append(rows, []string{orDash(count.Version), orDash(count.BuildCommit), strconv.Itoa(int(count.ProtocolVersion)), strconv.Itoa(int(count.Minions))})
+	}
+	printTable([]string{"Version", "Commit", "Protocol", "Minions"}, rows)
+
+	if len(fleet.Outdated) == 0 {
+		return
+	}
+	fmt.Printf("\nOutdated minions (%d), upgrade them to protocol %d:\n", len(fleet.Outdated), fleet.MinProtocolVersion)
+	rows = make([][]string, 0, len(fleet.Outdated))
+	for _, minion := range fleet.Outdated {
+		rows = append(rows, []string{minion.Id, minion.Hostname, orDash(minion.Version), strconv.Itoa(int(minion.ProtocolVersion))})
+	}
+	printTable([]string{"ID", "Hostname", "Version", "Protocol"}, rows)
+}
+
+// orDash returns value, "-" when empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
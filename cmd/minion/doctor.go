@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/config"
+)
+
+// certificateExpiryWarning is how long before expiry the doctor warns about a certificate
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// doctorStatus is the outcome of a doctor check
+type doctorStatus string
+
+const (
+	doctorOK   doctorStatus = " OK "
+	doctorWarn doctorStatus = "WARN"
+	doctorFail doctorStatus = "FAIL"
+	doctorSkip doctorStatus = "SKIP"
+)
+
+// doctorCheck is the result of a doctor check, with the action fixing a failure
+type doctorCheck struct {
+	name   string
+	status doctorStatus
+	detail string
+	hint   string
+}
+
+// doctor diagnoses why a minion cannot connect to Nexus
+type doctor struct {
+	cfg        *config.MinionConfig
+	now        func() time.Time
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	checks     []doctorCheck
+}
+
+// newDoctor creates a doctor for the minion configuration
+func newDoctor(cfg *config.MinionConfig) *doctor {
+	return &doctor{
+		cfg:        cfg,
+		now:        time.Now,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// run performs the checks, printing their results to out, and reports whether none failed
+func (d *doctor) run(ctx context.Context, out io.Writer) bool {
+	fmt.Fprintf(out, "Minion doctor, Nexus %s\n", d.cfg.ServerAddr)
+
+	d.checkConfiguration()
+	bundle, source := d.loadBundle()
+	if d.checkClock(bundle) {
+		bundle = d.checkCertificates(bundle, source)
+	} else {
+		bundle = nil
+	}
+	if d.checkDNS(ctx) && bundle != nil {
+		if d.checkConnectivity(ctx) {
+			d.checkHandshake(ctx, bundle)
+		}
+	} else {
+		d.add("connectivity", doctorSkip, "skipped after the previous failures", "")
+	}
+
+	healthy := true
+	for _, check := range d.checks {
+		fmt.Fprintf(out, "  [%s] %-14s %s\n", check.status, check.name, check.detail)
+		if check.hint != "" {
+			fmt.Fprintf(out, "         %-14s -> %s\n", "", check.hint)
+		}
+		if check.status == doctorFail {
+			healthy = false
+		}
+	}
+	if healthy {
+		fmt.Fprintln(out, "No problem found")
+	} else {
+		fmt.Fprintln(out, "Problems found, the minion will not work until they are fixed")
+	}
+	return healthy
+}
+
+// add records the result of a check
+func (d *doctor) add(name string, status doctorStatus, detail, hint string) {
+	d.checks = append(d.checks, doctorCheck{name: name, status: status, detail: detail, hint: hint})
+}
+
+// checkConfiguration checks the files the configuration refers to
+func (d *doctor) checkConfiguration() {
+	problems := 0
+
+	if d.cfg.CertDir != "" {
+		if err := checkWritableDir(d.cfg.CertDir); err != nil {
+			d.add("configuration", doctorFail, fmt.Sprintf("certificate directory %s is unusable: %v", d.cfg.CertDir, err),
+				"create the directory or fix its permissions, or unset MINION_CERT_DIR")
+			problems++
+		}
+	}
+
+	if d.cfg.CrashDir != "" {
+		if err := checkWritableDir(d.cfg.CrashDir); err != nil {
+			d.add("configuration", doctorFail, fmt.Sprintf("crash directory %s is unusable: %v", d.cfg.CrashDir, err),
+				"create the directory or fix its permissions, or unset MINION_CRASH_DIR")
+			problems++
+		}
+	}
+
+	if d.cfg.ScheduleFile != "" {
+		if err := checkScheduleFile(d.cfg.ScheduleFile); err != nil {
+			d.add("configuration", doctorFail, fmt.Sprintf("schedule file %s is unusable: %v", d.cfg.ScheduleFile, err),
+				"fix or remove the file, or unset MINION_SCHEDULE_FILE")
+			problems++
+		}
+	}
+
+	if d.cfg.CommandTrustBundle != "" {
+		bundle, err := os.ReadFile(d.cfg.CommandTrustBundle)
+		if err == nil {
+			_, err = certs.NewCommandVerifier(bundle)
+		}
+		if err != nil {
+			d.add("configuration", doctorFail, fmt.Sprintf("command trust bundle %s is unusable: %v", d.cfg.CommandTrustBundle, err),
+				"point MINION_COMMAND_TRUST_BUNDLE to a PEM file of the console signing certificates")
+			problems++
+		}
+	}
+
+	if problems == 0 {
+		d.add("configuration", doctorOK, "settings and referenced files are valid", "")
+	}
+}
+
+// loadBundle loads the credentials the minion connects with, and whether they are embedded or rotated
+func (d *doctor) loadBundle() (*certs.Bundle, string) {
+	store, err := certs.NewStore(d.cfg.CertDir)
+	if err != nil {
+		d.add("certificates", doctorFail, fmt.Sprintf("cannot load certificates: %v", err),
+			fmt.Sprintf("check MINION_CERT_DIR, or remove a corrupted %s to fall back to the embedded certificates", certs.BundleFileName))
+		return nil, ""
+	}
+
+	source := "embedded"
+	if d.cfg.CertDir != "" {
+		if _, err := os.Stat(filepath.Join(d.cfg.CertDir, certs.BundleFileName)); err == nil {
+			source = "rotated"
+		}
+	}
+	return store.Current(), source
+}
+
+// checkCertificates validates the minion certificate chain and returns the bundle when it is usable
+func (d *doctor) checkCertificates(bundle *certs.Bundle, source string) *certs.Bundle {
+	if bundle == nil {
+		return nil
+	}
+
+	if err := bundle.Validate(); err != nil {
+		d.add("certificates", doctorFail, fmt.Sprintf("%s certificates are invalid: %v", source, err),
+			"rotate the minion certificates or rebuild the minion with the Nexus CA")
+		return nil
+	}
+
+	leaf, err := parseCertificate(bundle.CertPEM)
+	if err != nil {
+		d.add("certificates", doctorFail, err.Error(), "rotate the minion certificates")
+		return nil
+	}
+
+	remaining := leaf.NotAfter.Sub(d.now())
+	switch {
+	case remaining <= 0:
+		d.add("certificates", doctorFail, fmt.Sprintf("%s certificate expired on %s", source, leaf.NotAfter.UTC().Format(time.DateOnly)),
+			"rotate the minion certificates, or check the system clock")
+		return nil
+	case remaining < certificateExpiryWarning:
+		d.add("certificates", doctorWarn, fmt.Sprintf("%s certificate expires on %s", source, leaf.NotAfter.UTC().Format(time.DateOnly)),
+			"rotate the minion certificates soon")
+	default:
+		d.add("certificates", doctorOK, fmt.Sprintf("%s certificate %s is signed by the CA, valid until %s",
+			source, bundle.Fingerprint(), leaf.NotAfter.UTC().Format(time.DateOnly)), "")
+	}
+	return bundle
+}
+
+// checkClock checks that the system clock is not set before the certificates were issued,
+// and reports whether it is
+func (d *doctor) checkClock(bundle *certs.Bundle) bool {
+	now := d.now()
+
+	if bundle != nil {
+		for _, data := range [][]byte{bundle.CAPEM, bundle.CertPEM} {
+			cert, err := parseCertificate(data)
+			if err == nil && now.Before(cert.NotBefore) {
+				d.add("clock", doctorFail, fmt.Sprintf("system time %s is before the certificate of %s was issued (%s)",
+					now.UTC().Format(time.RFC3339), cert.Subject.CommonName, cert.NotBefore.UTC().Format(time.RFC3339)),
+					"synchronize the system clock (NTP), TLS handshakes fail with a wrong clock")
+				return false
+			}
+		}
+	}
+
+	d.add("clock", doctorOK, fmt.Sprintf("system time %s", now.UTC().Format(time.RFC3339)), "")
+	return true
+}
+
+// checkDNS resolves the Nexus host name and reports whether it succeeded
+func (d *doctor) checkDNS(ctx context.Context) bool {
+	host, _, err := net.SplitHostPort(d.cfg.ServerAddr)
+	if err != nil {
+		d.add("dns", doctorFail, fmt.Sprintf("invalid server address %s: %v", d.cfg.ServerAddr, err),
+			"set NEXUS_SERVER and NEXUS_MINION_PORT, or -server host:port")
+		return false
+	}
+	if net.ParseIP(host) != nil {
+		d.add("dns", doctorOK, fmt.Sprintf("%s is an IP address", host), "")
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(d.cfg.ConnectTimeout)*time.Second)
+	defer cancel()
+	addrs, err := d.lookupHost(ctx, host)
+	if err != nil {
+		d.add("dns", doctorFail, fmt.Sprintf("cannot resolve %s: %v", host, err),
+			"check NEXUS_SERVER and the DNS configuration (/etc/resolv.conf, /etc/hosts)")
+		return false
+	}
+	d.add("dns", doctorOK, fmt.Sprintf("%s resolves to %v", host, addrs), "")
+	return true
+}
+
+// checkConnectivity opens a TCP connection to the Nexus minion port and reports whether it succeeded
+func (d *doctor) checkConnectivity(ctx context.Context) bool {
+	dialer := &net.Dialer{Timeout: time.Duration(d.cfg.ConnectTimeout) * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", d.cfg.ServerAddr)
+	if err != nil {
+		d.add("connectivity", doctorFail, fmt.Sprintf("cannot reach %s: %v", d.cfg.ServerAddr, err),
+			"check that Nexus is running and that firewalls allow the minion port (NEXUS_MINION_PORT)")
+		return false
+	}
+	conn.Close()
+	d.add("connectivity", doctorOK, fmt.Sprintf("%s is reachable", d.cfg.ServerAddr), "")
+	return true
+}
+
+// checkHandshake completes a mutual TLS handshake with Nexus
+func (d *doctor) checkHandshake(ctx context.Context, bundle *certs.Bundle) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(d.cfg.ConnectTimeout)*time.Second)
+	defer cancel()
+
+	err := certs.VerifyHandshake(ctx, d.cfg.ServerAddr, bundle)
+	if err == nil {
+		d.add("tls", doctorOK, "mutual TLS handshake with Nexus succeeded", "")
+		return
+	}
+
+	hint := "check that the minion and Nexus certificates come from the same CA"
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var unknown x509.UnknownAuthorityError
+	switch {
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		hint = "the Nexus certificate is expired or not yet valid, check the system clock"
+	case errors.As(err, &hostname):
+		hint = "the Nexus certificate does not cover this host name, use one of its names in NEXUS_SERVER"
+	case errors.As(err, &unknown):
+		hint = "the Nexus certificate is not signed by the minion CA, rebuild or rotate with the Nexus CA"
+	}
+	d.add("tls", doctorFail, err.Error(), hint)
+}
+
+// checkWritableDir checks that dir exists, or can be created, and is writable
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// checkScheduleFile checks that an existing schedule file holds a JSON list of tasks
+func checkScheduleFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkWritableDir(filepath.Dir(path))
+	}
+	if err != nil {
+		return err
+	}
+	var tasks []json.RawMessage
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+// parseCertificate parses the first certificate of a PEM block
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// runDoctor runs the doctor checks and exits, with status 1 when a check failed
+func runDoctor(cfg *config.MinionConfig) {
+	if !newDoctor(cfg).run(context.Background(), os.Stdout) {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
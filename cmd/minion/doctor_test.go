@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/config"
+)
+
+// startTLSServer starts a server completing mutual TLS handshakes with the embedded certificates
+func startTLSServer(t *testing.T) string {
+	t.Helper()
+	pair, err := tls.X509KeyPair(certs.CertPEM, certs.KeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certs.CAPem)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		NextProtos:   []string{"h2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestDoctor(t *testing.T) {
+	addr := startTLSServer(t)
+	cfg := config.DefaultMinionConfig()
+	cfg.ServerAddr = addr
+	cfg.CertDir = t.TempDir()
+
+	var out bytes.Buffer
+	if !newDoctor(cfg).run(context.Background(), &out) {
+		t.Fatalf("Expected healthy minion, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "mutual TLS handshake with Nexus succeeded") {
+		t.Errorf("Expected TLS check, got:\n%s", out.String())
+	}
+
+	// Unresolvable host name
+	cfg.ServerAddr = "nexus.invalid:11972"
+	d := newDoctor(cfg)
+	d.lookupHost = func(context.Context, string) ([]string, error) { return nil, errors.New("no such host") }
+	out.Reset()
+	if d.run(context.Background(), &out) {
+		t.Fatalf("Expected DNS failure, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "[FAIL] dns") || !strings.Contains(out.String(), "[SKIP] connectivity") {
+		t.Errorf("Expected DNS failure skipping connectivity, got:\n%s", out.String())
+	}
+
+	// Nexus not listening
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ServerAddr = listener.Addr().String()
+	listener.Close()
+	out.Reset()
+	if newDoctor(cfg).run(context.Background(), &out) || !strings.Contains(out.String(), "[FAIL] connectivity") {
+		t.Errorf("Expected connectivity failure, got:\n%s", out.String())
+	}
+
+	// Clock set before the certificates were issued, invalid schedule file
+	cfg.ServerAddr = addr
+	cfg.ScheduleFile = filepath.Join(t.TempDir(), "schedule.json")
+	if err := os.WriteFile(cfg.ScheduleFile, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d = newDoctor(cfg)
+	d.now = func() time.Time { return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC) }
+	out.Reset()
+	if d.run(context.Background(), &out) {
+		t.Fatalf("Expected failures, got:\n%s", out.String())
+	}
+	for _, expected := range []string{"[FAIL] clock", "synchronize the system clock", "[FAIL] configuration", "schedule file"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
@@ -4,68 +4,60 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/arhuman/minexus/internal/availability"
 	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/compression"
 	"github.com/arhuman/minexus/internal/config"
 	"github.com/arhuman/minexus/internal/logging"
+	"github.com/arhuman/minexus/internal/longpoll"
 	"github.com/arhuman/minexus/internal/minion"
 	"github.com/arhuman/minexus/internal/version"
 	pb "github.com/arhuman/minexus/protogen"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
 )
 
 // setupGRPCConnection establishes connection to the server
-func setupGRPCConnection(cfg *config.MinionConfig, logger *zap.Logger) (*grpc.ClientConn, error) {
+func setupGRPCConnection(cfg *config.MinionConfig, store *certs.Store, logger *zap.Logger) (*grpc.ClientConn, error) {
 	logger, start := logging.FuncLogger(logger, "setupGRPCConnection")
 	defer logging.FuncExit(logger, start)
 
-	// Configure TLS credentials (mandatory, embedded)
-	logger.Info("Configuring embedded TLS for minion client")
+	// Configure TLS credentials (mandatory, embedded unless rotated)
+	logger.Info("Configuring TLS for minion client")
 
-	cert, err := tls.X509KeyPair(certs.CertPEM, certs.KeyPEM)
-	if err != nil {
-		logger.Error("Failed to load embedded TLS certificates", zap.Error(err))
-		return nil, fmt.Errorf("failed to load embedded TLS certificates: %w", err)
-	}
-
-	// Create certificate pool with the CA certificate
-	caCertPool := x509.NewCertPool()
-	if !caCertPool.AppendCertsFromPEM(certs.CAPem) {
-		logger.Error("Failed to load CA certificate")
-		return nil, fmt.Errorf("failed to load CA certificate")
-	}
-
-	tlsConfig := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		RootCAs:            caCertPool,
-		InsecureSkipVerify: false,
+	if _, err := store.Current().TLSConfig(); err != nil {
+		logger.Error("Failed to load TLS certificates", zap.Error(err))
+		return nil, fmt.Errorf("failed to load TLS certificates: %w", err)
 	}
 
-	creds := credentials.NewTLS(tlsConfig)
-	logger.Info("Embedded TLS credentials configured for minion client with CA validation")
+	// Credentials are read from the store on every handshake so rotated
+	// certificates are used as soon as the connection is re-established
+	creds := store.Credentials()
+	logger.Info("TLS credentials configured for minion client with CA validation",
+		zap.String("certificate", store.Current().Fingerprint()))
 
 	// Create connection using modern gRPC pattern with timeout, keepalive and connection parameters
 	conn, err := grpc.NewClient(cfg.ServerAddr,
 		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                60 * time.Second, // Send pings every 60 seconds
-			Timeout:             20 * time.Second, // Wait 20 seconds for ping ack
-			PermitWithoutStream: true,             // Allow pings even without active streams
+			Time:                time.Duration(cfg.KeepaliveTime) * time.Second,    // Interval between pings
+			Timeout:             time.Duration(cfg.KeepaliveTimeout) * time.Second, // Wait for ping ack before reconnecting
+			PermitWithoutStream: true,                                              // Allow pings even without active streams
 		}),
 		grpc.WithConnectParams(grpc.ConnectParams{
 			MinConnectTimeout: time.Duration(cfg.ConnectTimeout) * time.Second,
 		}),
+		compression.DialOption(cfg.Compression),
 	)
 
 	return conn, err
@@ -84,8 +76,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Diagnose the configuration and the connection to Nexus instead of starting
+	if cfg.Doctor {
+		runDoctor(cfg)
+	}
+
 	// Set up logging with atomic level for dynamic log level control
-	logger, atom, err := logging.SetupLogger(cfg.Debug)
+	logger, atom, _, err := logging.NewLogger(cfg.Debug, cfg.Log)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create logger: %v", err))
 	}
@@ -97,10 +94,34 @@ func main() {
 	// Log the configuration
 	cfg.LogConfig(logger)
 
+	// Ship the logs to Nexus once the command stream is open
+	var shipper *minion.LogShipper
+	if cfg.LogShipping {
+		level, err := zapcore.ParseLevel(cfg.LogShippingLevel)
+		if err != nil {
+			logger.Fatal("Invalid log shipping level", zap.Error(err), zap.String("level", cfg.LogShippingLevel))
+		}
+		shipper = minion.NewLogShipper(level)
+		logger = shipper.Wrap(logger)
+	}
+
 	logger.Info("Connecting to server", zap.String("address", cfg.ServerAddr))
 
+	// Load credentials, preferring a previously rotated bundle
+	store, err := certs.NewStore(cfg.CertDir)
+	if err != nil {
+		logger.Fatal("Failed to load certificates", zap.Error(err), zap.String("cert_dir", cfg.CertDir))
+	}
+
+	// Run virtual minions for load testing instead of the minion of the host
+	if cfg.Simulate > 0 {
+		logger.Info("Simulation mode, running virtual minions", zap.Int("minions", cfg.Simulate))
+		runSimulation(cfg, store, logger, atom)
+		return
+	}
+
 	// Set up gRPC connection to the server with configurable timeout
-	conn, err := setupGRPCConnection(cfg, logger)
+	conn, err := setupGRPCConnection(cfg, store, logger)
 	if err != nil {
 		logger.Fatal("Failed to connect to server", zap.Error(err), zap.String("address", cfg.ServerAddr))
 	}
@@ -117,11 +138,78 @@ func main() {
 	shellTimeout := time.Duration(cfg.DefaultShellTimeout) * time.Second
 	streamTimeout := time.Duration(cfg.StreamTimeout) * time.Second
 	m := minion.NewMinion(cfg.ID, minionClient, heartbeatInterval, initialReconnectDelay, maxReconnectDelay, shellTimeout, streamTimeout, logger, atom)
+	m.SetReconnectPolicy(cfg.ReconnectJitter, time.Duration(cfg.ReconnectResetAfter)*time.Second)
+	m.EnableCertRotation(store, cfg.ServerAddr)
+	m.SetNamespace(cfg.Namespace)
+	m.SetTopology(cfg.Region, cfg.Datacenter, cfg.Rack)
+	if cfg.Availability != "" {
+		schedule, err := availability.Parse(cfg.Availability)
+		if err != nil {
+			logger.Fatal("Invalid availability windows", zap.Error(err), zap.String("availability", cfg.Availability))
+		}
+		m.SetAvailability(schedule)
+		logger.Info("Power-aware mode enabled, sleeping outside the availability windows", zap.String("availability", cfg.Availability))
+	}
+	if cfg.HTTPFallbackURL != "" {
+		fallbackClient := longpoll.NewClient(cfg.HTTPFallbackURL, &http.Client{Transport: store.HTTPTransport()})
+		m.EnableHTTPFallback(fallbackClient, cfg.HTTPFallbackAfter)
+		logger.Info("HTTP long-polling fallback enabled",
+			zap.String("url", cfg.HTTPFallbackURL),
+			zap.Int("after_failures", cfg.HTTPFallbackAfter))
+	}
+	if err := m.EnableScheduler(cfg.ScheduleFile); err != nil {
+		logger.Fatal("Failed to load scheduled tasks", zap.Error(err), zap.String("schedule_file", cfg.ScheduleFile))
+	}
+	if err := m.EnableWatcher(cfg.WatchFile); err != nil {
+		logger.Fatal("Failed to load watch rules", zap.Error(err), zap.String("watch_file", cfg.WatchFile))
+	}
+	if cfg.CommandTrustBundle != "" {
+		bundle, err := os.ReadFile(cfg.CommandTrustBundle)
+		if err != nil {
+			logger.Fatal("Failed to read command trust bundle", zap.Error(err), zap.String("command_trust_bundle", cfg.CommandTrustBundle))
+		}
+		if err := m.EnableCommandVerification(bundle); err != nil {
+			logger.Fatal("Invalid command trust bundle", zap.Error(err), zap.String("command_trust_bundle", cfg.CommandTrustBundle))
+		}
+		logger.Info("Command signature verification enabled")
+	}
+	m.EnableWatchdog(minion.WatchdogLimits{
+		MaxMemoryMB:           cfg.MaxMemoryMB,
+		MaxCPUPercent:         cfg.MaxCPUPercent,
+		MaxConcurrentCommands: cfg.MaxConcurrentCommands,
+	})
+	m.EnableTransferThrottle(cfg.TransferBandwidth, cfg.MaxFileTransfers)
+	m.EnableDiskGuard(minion.DiskThresholds{
+		MinFreeMB:            cfg.MinFreeDiskMB,
+		MinFreeInodesPercent: cfg.MinFreeInodesPercent,
+	})
+	if err := m.EnableRuntimeConfig(cfg.RuntimeConfigFile); err != nil {
+		logger.Fatal("Failed to load runtime settings", zap.Error(err), zap.String("runtime_config_file", cfg.RuntimeConfigFile))
+	}
+	if cfg.CrashDir != "" {
+		if err := m.PersistCrashReports(cfg.CrashDir); err != nil && cfg.CrashDir == config.DefaultCrashDir {
+			logger.Warn("Default crash directory unusable, crash reports are kept in memory only", zap.Error(err), zap.String("crash_dir", cfg.CrashDir))
+		} else if err != nil {
+			logger.Fatal("Failed to load crash reports", zap.Error(err), zap.String("crash_dir", cfg.CrashDir))
+		}
+	}
+	if shipper != nil {
+		m.EnableLogShipping(shipper)
+		logger.Info("Log shipping to Nexus enabled", zap.String("level", cfg.LogShippingLevel))
+	}
 
 	// Create context that can be canceled
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if cfg.CloudMetadata {
+		m.EnableCloudMetadata(ctx)
+	}
+	if len(cfg.Tags) > 0 {
+		m.AddTags(cfg.Tags)
+		logger.Info("Static tags advertised at registration", zap.Any("tags", cfg.Tags))
+	}
+
 	// Start minion
 	if err := m.Start(ctx); err != nil {
 		logger.Fatal("Failed to start minion", zap.Error(err))
@@ -132,11 +220,49 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Wait for termination signal
-	<-sigChan
-	logger.Info("Received termination signal, shutting down...")
+	// Wait for termination signal, for the watchdog to request a restart, or for Nexus to retire the host
+	restart := false
+	select {
+	case <-sigChan:
+		logger.Info("Received termination signal, shutting down...")
+	case <-m.RestartRequested():
+		logger.Warn("Watchdog requested a restart, shutting down...")
+		restart = true
+	case <-m.Retired():
+		logger.Warn("Host retired by Nexus, shutting down for good...")
+	}
 
 	// Stop minion gracefully
 	m.Stop()
 	logger.Info("Minion stopped")
+
+	if restart {
+		restartMinion(logger)
+	}
+	if notice := m.RetireNotice(); notice != nil && notice.Uninstall {
+		uninstallMinion(logger)
+	}
+}
+
+// uninstallMinion removes the minion executable of a retired host, the minion exiting successfully
+// so that a supervisor restarting it on failure doesn't
+func uninstallMinion(logger *zap.Logger) {
+	executable, err := minion.Uninstall()
+	if err != nil {
+		logger.Error("Failed to uninstall retired minion", zap.Error(err))
+		return
+	}
+	logger.Info("Retired minion uninstalled", zap.String("executable", executable))
+}
+
+// restartMinion replaces the minion process with a new one started with the same arguments
+// and environment, exiting with an error for a supervisor to restart it when it can't
+func restartMinion(logger *zap.Logger) {
+	executable, err := os.Executable()
+	if err == nil {
+		logger.Info("Restarting minion", zap.String("executable", executable))
+		logger.Sync()
+		err = syscall.Exec(executable, os.Args, os.Environ())
+	}
+	logger.Fatal("Failed to restart minion", zap.Error(err))
 }
@@ -50,6 +50,10 @@ func (m *mockMinionServiceClient) Register(_ context.Context, _ *pb.HostInfo, _
 	}, nil
 }
 
+func (m *mockMinionServiceClient) RelayStream(_ context.Context, _ ...grpc.CallOption) (pb.MinionService_RelayStreamClient, error) {
+	return nil, errors.New("relay stream not supported by mock")
+}
+
 func (m *mockMinionServiceClient) StreamCommands(_ context.Context, _ ...grpc.CallOption) (pb.MinionService_StreamCommandsClient, error) {
 	if m.streamError {
 		return nil, errors.New("mock stream error")
@@ -1088,3 +1092,12 @@ func TestCommandExecutionTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestSimulatedMinionID(t *testing.T) {
+	if id := simulatedMinionID("", 0); id != "sim-00001" {
+		t.Errorf("Expected sim-00001, got %s", id)
+	}
+	if id := simulatedMinionID("loadtest", 1999); id != "loadtest-02000" {
+		t.Errorf("Expected loadtest-02000, got %s", id)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/config"
+	"github.com/arhuman/minexus/internal/minion"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// simulatedMinionsPerConnection is the number of virtual minions multiplexing their streams on one gRPC connection
+const simulatedMinionsPerConnection = 100
+
+// simulatedStartInterval spreads the registrations, and thus the heartbeats, of virtual minions
+const simulatedStartInterval = 10 * time.Millisecond
+
+// simulatedIDPrefix prefixes the IDs of virtual minions when no minion ID is configured
+const simulatedIDPrefix = "sim"
+
+// simulatedMinionID returns the ID of the virtual minion of index i
+func simulatedMinionID(prefix string, i int) string {
+	if prefix == "" {
+		prefix = simulatedIDPrefix
+	}
+	return fmt.Sprintf("%s-%05d", prefix, i+1)
+}
+
+// runSimulation runs cfg.Simulate virtual minions until a termination signal, to load test the registry,
+// dispatch and database of Nexus without a host per minion. They share a few gRPC connections and fake
+// the execution of their commands.
+func runSimulation(cfg *config.MinionConfig, store *certs.Store, logger *zap.Logger, atom zap.AtomicLevel) {
+	tags := map[string]string{"simulated": "true"}
+	for key, value := range cfg.Tags {
+		tags[key] = value
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var conns []*grpc.ClientConn
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	minions := make([]*minion.Minion, 0, cfg.Simulate)
+	stopped := false
+	for i := 0; i < cfg.Simulate && !stopped; i++ {
+		if i%simulatedMinionsPerConnection == 0 {
+			conn, err := setupGRPCConnection(cfg, store, logger)
+			if err != nil {
+				logger.Fatal("Failed to connect to server", zap.Error(err), zap.String("address", cfg.ServerAddr))
+			}
+			conns = append(conns, conn)
+		}
+
+		id := simulatedMinionID(cfg.ID, i)
+		m := minion.NewMinion(id, pb.NewMinionServiceClient(conns[len(conns)-1]),
+			time.Duration(cfg.HeartbeatInterval)*time.Second,
+			time.Duration(cfg.InitialReconnectDelay)*time.Second,
+			time.Duration(cfg.MaxReconnectDelay)*time.Second,
+			time.Duration(cfg.DefaultShellTimeout)*time.Second,
+			time.Duration(cfg.StreamTimeout)*time.Second,
+			logger.With(zap.String("minion_id", id)), atom)
+		m.SetReconnectPolicy(cfg.ReconnectJitter, time.Duration(cfg.ReconnectResetAfter)*time.Second)
+		m.SetNamespace(cfg.Namespace)
+		m.SetTopology(cfg.Region, cfg.Datacenter, cfg.Rack)
+		m.AddTags(tags)
+		m.EnableSimulation(id, cfg.SimulateMinLatency, cfg.SimulateMaxLatency)
+		if err := m.Start(ctx); err != nil {
+			logger.Fatal("Failed to start virtual minion", zap.Error(err), zap.String("minion_id", id))
+		}
+		minions = append(minions, m)
+
+		select {
+		case <-sigChan:
+			logger.Info("Received termination signal while starting virtual minions")
+			stopped = true
+		case <-time.After(simulatedStartInterval):
+		}
+	}
+
+	if !stopped {
+		logger.Info("Virtual minions started",
+			zap.Int("minions", len(minions)),
+			zap.Int("connections", len(conns)),
+			zap.Duration("min_latency", cfg.SimulateMinLatency),
+			zap.Duration("max_latency", cfg.SimulateMaxLatency))
+		<-sigChan
+		logger.Info("Received termination signal, shutting down...")
+	}
+
+	cancel()
+	for _, m := range minions {
+		m.Stop()
+	}
+	logger.Info("Virtual minions stopped", zap.Int("minions", len(minions)))
+}
@@ -7,16 +7,20 @@ import (
 	"crypto/x509"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/compression"
 	"github.com/arhuman/minexus/internal/config"
 	"github.com/arhuman/minexus/internal/logging"
 	"github.com/arhuman/minexus/internal/nexus"
+	"github.com/arhuman/minexus/internal/oidc"
 	"github.com/arhuman/minexus/internal/version"
 	"github.com/arhuman/minexus/internal/web"
 	pb "github.com/arhuman/minexus/protogen"
@@ -24,6 +28,8 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
@@ -42,7 +48,7 @@ func main() {
 	}
 
 	// Set up logging
-	logger, _, err := logging.SetupLogger(cfg.Debug)
+	logger, atom, sampler, err := logging.NewLogger(cfg.Debug, cfg.Log)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create logger: %v", err))
 	}
@@ -64,6 +70,212 @@ func main() {
 	}
 	defer nexusServer.Shutdown()
 
+	// Serve heavy read queries from the read-only replica when configured
+	if cfg.DBReplicaURL != "" {
+		if err := nexusServer.EnableReadReplica(cfg.DBReplicaURL); err != nil {
+			logger.Fatal("Failed to enable database read replica", zap.Error(err))
+		}
+		logger.Info("Database read replica enabled")
+	}
+
+	// Let administrators change the log level and sampling at runtime
+	nexusServer.SetAtomicLevel(atom)
+	nexusServer.SetLogSampler(sampler)
+	if len(cfg.Admins) > 0 {
+		nexusServer.SetAdmins(cfg.Admins)
+		logger.Info("Admin service enabled", zap.Strings("admins", cfg.Admins))
+	}
+
+	// Let consoles authenticate with the tokens of the SSO of the organization, the token identity
+	// taking precedence over the certificate of consoles presenting both
+	if cfg.OIDCIssuer != "" {
+		verifier := oidc.NewVerifier(cfg.OIDCIssuer, cfg.OIDCAudience, &http.Client{Timeout: 10 * time.Second})
+		nexusServer.SetConsoleAuthenticators(
+			nexus.NewOIDCAuthenticator(verifier, cfg.OIDCIdentityClaim, cfg.OIDCNamespacesClaim),
+			nexus.CertificateAuthenticator(),
+		)
+		logger.Info("OpenID Connect console authentication enabled",
+			zap.String("issuer", cfg.OIDCIssuer),
+			zap.String("identity_claim", cfg.OIDCIdentityClaim))
+	}
+
+	// Set up webhook notifications for command completions
+	if cfg.WebhookFile != "" {
+		targets, err := nexus.LoadWebhookTargets(cfg.WebhookFile)
+		if err != nil {
+			logger.Fatal("Failed to load webhook configuration", zap.Error(err))
+		}
+		notifier := nexus.NewWebhookNotifier(targets, logger)
+		notifier.Start()
+		defer notifier.Stop()
+		nexusServer.SetNotifier(notifier)
+		logger.Info("Webhook notifications enabled", zap.Int("targets", len(targets)))
+	}
+
+	// Replace the built-in patterns of destructive commands requiring confirmation
+	if cfg.DestructivePatternsFile != "" {
+		patterns, err := nexus.LoadDestructivePatterns(cfg.DestructivePatternsFile)
+		if err != nil {
+			logger.Fatal("Failed to load destructive command patterns", zap.Error(err))
+		}
+		nexusServer.SetDestructivePatterns(patterns)
+		logger.Info("Destructive command patterns loaded", zap.Int("patterns", len(patterns)))
+	}
+
+	// Replace the built-in patterns of secrets redacted before commands and results are stored
+	if cfg.RedactionPatternsFile != "" {
+		patterns, err := nexus.LoadRedactionPatterns(cfg.RedactionPatternsFile)
+		if err != nil {
+			logger.Fatal("Failed to load redaction patterns", zap.Error(err))
+		}
+		nexusServer.SetRedactionPatterns(patterns)
+		logger.Info("Redaction patterns loaded", zap.Int("patterns", len(patterns)))
+	}
+
+	// Encrypt the command payloads and result output stored in the database
+	if cfg.EncryptionKeyFile != "" {
+		keys, err := nexus.LoadEncryptionKeys(cfg.EncryptionKeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load encryption keys", zap.Error(err))
+		}
+		if err := nexusServer.SetEncryptionKeys(keys); err != nil {
+			logger.Fatal("Invalid encryption keys", zap.Error(err))
+		}
+		logger.Info("Encryption at rest enabled", zap.Int("keys", len(keys)))
+	}
+
+	// Validate the tags set from consoles against the tag schema
+	if cfg.TagSchemaFile != "" {
+		schema, err := nexus.LoadTagSchema(cfg.TagSchemaFile)
+		if err != nil {
+			logger.Fatal("Failed to load tag schema", zap.Error(err))
+		}
+		nexusServer.SetTagSchema(schema)
+		logger.Info("Tag schema loaded", zap.Int("keys", len(schema.Keys)), zap.Bool("allow_other_keys", schema.AllowOtherKeys))
+	}
+
+	// Merge the tags advertised by re-registering minions with those set from consoles
+	if err := nexusServer.SetTagConflictPolicy(cfg.TagConflict); err != nil {
+		logger.Fatal("Invalid tag conflict policy", zap.Error(err))
+	}
+
+	// Decide which minion keeps an ID claimed by two connected hosts
+	if err := nexusServer.SetIDConflictPolicy(cfg.IDConflict); err != nil {
+		logger.Fatal("Invalid minion ID conflict policy", zap.Error(err))
+	}
+
+	// Refuse or flag the minions speaking a protocol version Nexus no longer supports
+	if err := nexusServer.SetMinimumProtocol(int32(cfg.MinMinionProtocol), cfg.OutdatedMinions); err != nil {
+		logger.Fatal("Invalid minimum minion protocol", zap.Error(err))
+	}
+
+	// Let consoles run the approved read-only database queries
+	if cfg.DBQueriesFile != "" {
+		queries, err := nexus.LoadDatabaseQueries(cfg.DBQueriesFile)
+		if err != nil {
+			logger.Fatal("Failed to load database queries", zap.Error(err))
+		}
+		nexusServer.SetDatabaseQueries(queries)
+		logger.Info("Database queries loaded", zap.Int("queries", len(queries)))
+	}
+
+	// Stagger the commands reaching many minions
+	if cfg.DispatchRatesFile != "" {
+		rates, err := nexus.LoadDispatchRates(cfg.DispatchRatesFile)
+		if err != nil {
+			logger.Fatal("Failed to load dispatch rates", zap.Error(err))
+		}
+		nexusServer.SetDispatchRates(rates)
+		logger.Info("Dispatch rates loaded", zap.Int("rates", rates.Len()))
+	}
+
+	// Make the commands matching the approval rules wait for a second operator
+	if cfg.ApprovalFile != "" {
+		policy, err := nexus.LoadApprovalPolicy(cfg.ApprovalFile)
+		if err != nil {
+			logger.Fatal("Failed to load approval policy", zap.Error(err))
+		}
+		nexusServer.SetApprovalPolicy(policy)
+		logger.Info("Approval policy loaded", zap.Int("rules", len(policy.Rules)), zap.Strings("approvers", policy.Approvers))
+	}
+
+	// Serve one-time minion install URLs from the web server
+	if cfg.BootstrapURL != "" {
+		provisioner, err := nexus.NewBootstrapProvisioner(cfg.BootstrapURL, cfg.MinionPort)
+		if err != nil {
+			logger.Fatal("Failed to enable minion bootstrap", zap.Error(err))
+		}
+		nexusServer.SetBootstrapProvisioner(provisioner)
+		logger.Info("Minion bootstrap enabled", zap.String("url", cfg.BootstrapURL))
+		if !cfg.WebEnabled {
+			logger.Warn("Minion bootstrap URLs are served by the web server, which is disabled")
+		}
+	}
+
+	// Detect minion streams whose TCP session died without FIN
+	if cfg.StreamDeadTimeout > 0 {
+		nexusServer.EnableStreamMonitor(time.Duration(cfg.StreamDeadTimeout) * time.Second)
+	}
+
+	// Minions split the results larger than the messages Nexus accepts
+	nexusServer.SetMaxMsgSize(cfg.MaxMsgSize)
+
+	// Limit the commands running concurrently on each minion
+	if cfg.MaxInFlight > 0 {
+		nexusServer.SetInFlightLimit(cfg.MaxInFlight)
+		logger.Info("Per-minion in-flight command limit enabled", zap.Int("limit", cfg.MaxInFlight))
+	}
+
+	// Shed console dispatches and slow down minion results while Nexus is overloaded
+	if cfg.OverloadMaxGoroutines > 0 || cfg.OverloadMaxDBLatencyMs > 0 || cfg.OverloadMaxQueued > 0 {
+		nexusServer.EnableLoadShedding(nexus.OverloadLimits{
+			MaxGoroutines:     cfg.OverloadMaxGoroutines,
+			MaxDBLatency:      time.Duration(cfg.OverloadMaxDBLatencyMs) * time.Millisecond,
+			MaxQueuedCommands: cfg.OverloadMaxQueued,
+		})
+		logger.Info("Load shedding enabled",
+			zap.Int("max_goroutines", cfg.OverloadMaxGoroutines),
+			zap.Int("max_db_latency_ms", cfg.OverloadMaxDBLatencyMs),
+			zap.Int("max_queued", cfg.OverloadMaxQueued))
+	}
+
+	// Move old command results to S3-compatible object storage
+	if cfg.ArchiveDays > 0 {
+		store, err := nexus.NewS3Store(nexus.S3Config{
+			Endpoint:  cfg.ArchiveEndpoint,
+			Bucket:    cfg.ArchiveBucket,
+			Region:    cfg.ArchiveRegion,
+			AccessKey: cfg.ArchiveAccessKey,
+			SecretKey: cfg.ArchiveSecretKey,
+		})
+		if err != nil {
+			logger.Fatal("Failed to configure result archive", zap.Error(err))
+		}
+		if err := nexusServer.EnableArchive(store, time.Duration(cfg.ArchiveDays)*24*time.Hour); err != nil {
+			logger.Fatal("Failed to enable result archival", zap.Error(err))
+		}
+		logger.Info("Result archival enabled",
+			zap.Int("days", cfg.ArchiveDays),
+			zap.String("bucket", cfg.ArchiveBucket))
+	}
+
+	// Delete the logs shipped by minions once older than their retention
+	if cfg.MinionLogRetentionDays > 0 {
+		if err := nexusServer.EnableMinionLogRetention(time.Duration(cfg.MinionLogRetentionDays) * 24 * time.Hour); err != nil {
+			logger.Warn("Minion log retention disabled", zap.Error(err))
+		} else {
+			logger.Info("Minion log retention enabled", zap.Int("days", cfg.MinionLogRetentionDays))
+		}
+	}
+
+	// Reassemble the files pulled from minions under the file root
+	transferDir := filepath.Join(cfg.FileRoot, "transfers")
+	if err := nexusServer.EnableFileTransfers(transferDir); err != nil {
+		logger.Warn("File transfers disabled", zap.Error(err))
+	} else {
+		logger.Info("File transfers enabled", zap.String("dir", transferDir))
+	}
+
 	// Load server certificate for both servers
 	logger.Info("Loading embedded TLS certificates")
 	serverCert, err := tls.X509KeyPair(certs.CertPEM, certs.KeyPEM)
@@ -78,14 +290,14 @@ func main() {
 	}
 
 	// Create minion server (standard TLS)
-	minionServer := createMinionServer(cfg, serverCert, logger)
+	minionServer := createMinionServer(cfg, serverCert, caCertPool, logger)
 	minionListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.MinionPort))
 	if err != nil {
 		logger.Fatal("Failed to create minion listener", zap.Error(err))
 	}
 
 	// Create console server (mTLS)
-	consoleServer := createConsoleServer(cfg, serverCert, caCertPool, logger)
+	consoleServer := createConsoleServer(cfg, nexusServer, serverCert, caCertPool, logger)
 	consoleListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.ConsolePort))
 	if err != nil {
 		logger.Fatal("Failed to create console listener", zap.Error(err))
@@ -94,11 +306,36 @@ func main() {
 	// Register services on both servers
 	pb.RegisterMinionServiceServer(minionServer, nexusServer)
 	pb.RegisterConsoleServiceServer(consoleServer, nexusServer)
+	pb.RegisterAdminServiceServer(consoleServer, nexusServer)
+
+	// Report readiness through the standard gRPC health service: minions are served right away,
+	// consoles once the database is reached
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.MinionService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(pb.ConsoleService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	go func() {
+		<-nexusServer.DatabaseReached()
+		healthServer.SetServingStatus(pb.ConsoleService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	}()
+	healthpb.RegisterHealthServer(minionServer, healthServer)
+	healthpb.RegisterHealthServer(consoleServer, healthServer)
 
 	// Register reflection service for grpcurl and similar tools
 	reflection.Register(minionServer)
 	reflection.Register(consoleServer)
 
+	// Serve the HTTP long-polling transport of the minions whose network blocks gRPC streams
+	var longPollServer *http.Server
+	if cfg.HTTPFallbackPort > 0 {
+		longPollServer = createLongPollServer(cfg, nexusServer, serverCert, caCertPool)
+		go func() {
+			logger.Info("Minion HTTP fallback server starting (TLS)", zap.Int("port", cfg.HTTPFallbackPort))
+			if err := longPollServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				logger.Error("Minion HTTP fallback server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	// Start all three servers concurrently
 	var wg sync.WaitGroup
 	var serverReady sync.WaitGroup
@@ -143,12 +380,22 @@ func main() {
 		}
 	}()
 
+	// Serve the web server over HTTPS when it carries the REST+JSON console API
+	var webTLS *tls.Config
+	if cfg.RESTAPI {
+		webTLS = webTLSConfig(serverCert, caCertPool)
+		if !cfg.WebEnabled {
+			logger.Warn("The REST API is served by the web server, which is disabled")
+		}
+	}
+
 	// Start web server
 	go func() {
 		defer wg.Done()
-		logger.Info("Web server starting (HTTP)",
+		logger.Info("Web server starting",
 			zap.Int("port", cfg.WebPort),
-			zap.Bool("enabled", cfg.WebEnabled))
+			zap.Bool("enabled", cfg.WebEnabled),
+			zap.Bool("tls", webTLS != nil))
 
 		// Signal server is about to start
 		go func() {
@@ -161,7 +408,7 @@ func main() {
 			serverReady.Done()
 		}()
 
-		if err := web.StartWebServer(cfg, nexusServer, logger); err != nil {
+		if err := web.StartWebServer(cfg, nexusServer, webTLS, logger); err != nil {
 			if cfg.WebEnabled {
 				logger.Error("Web server failed", zap.Error(err))
 			}
@@ -184,6 +431,10 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down all servers...")
+	healthServer.Shutdown()
+
+	// Let minions finish the commands they are executing before closing their streams
+	nexusServer.Drain(time.Duration(cfg.ShutdownGrace) * time.Second)
 
 	// Gracefully stop all servers
 	go func() {
@@ -196,6 +447,13 @@ func main() {
 		consoleServer.GracefulStop()
 	}()
 
+	if longPollServer != nil {
+		go func() {
+			logger.Info("Stopping minion HTTP fallback server...")
+			longPollServer.Close()
+		}()
+	}
+
 	go func() {
 		logger.Info("Stopping web server...")
 		// Web server shutdown is handled by process termination
@@ -206,59 +464,98 @@ func main() {
 	logger.Info("All servers stopped")
 }
 
-// createMinionServer creates a gRPC server for minion connections with standard TLS
-func createMinionServer(cfg *config.NexusConfig, serverCert tls.Certificate, logger *zap.Logger) *grpc.Server {
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{serverCert},
-	}
-
-	creds := credentials.NewTLS(tlsConfig)
+// createMinionServer creates a gRPC server for minion connections with standard TLS. Client
+// certificates are verified when given, their organizational units setting the minion namespaces.
+func createMinionServer(cfg *config.NexusConfig, serverCert tls.Certificate, caCertPool *x509.CertPool, logger *zap.Logger) *grpc.Server {
+	creds := credentials.NewTLS(minionTLSConfig(serverCert, caCertPool))
 	opts := []grpc.ServerOption{
 		grpc.Creds(creds),
 		grpc.MaxRecvMsgSize(cfg.MaxMsgSize),
 		grpc.MaxSendMsgSize(cfg.MaxMsgSize),
-		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
-			MinTime:             30 * time.Second,
-			PermitWithoutStream: true,
-		}),
-		grpc.KeepaliveParams(keepalive.ServerParameters{
-			MaxConnectionIdle:     10 * time.Minute, // Reduced from 30 to 10 minutes
-			MaxConnectionAge:      15 * time.Minute, // Reduced from 60 to 15 minutes
-			MaxConnectionAgeGrace: 10 * time.Second,
-			Time:                  60 * time.Second,
-			Timeout:               20 * time.Second,
-		}),
+		grpc.KeepaliveEnforcementPolicy(keepaliveEnforcementPolicy(cfg)),
+		grpc.KeepaliveParams(keepaliveServerParameters(cfg)),
 	}
+	opts = append(opts, compression.ServerOptions(cfg.Compression)...)
 
 	logger.Info("Minion server TLS credentials configured successfully")
 	return grpc.NewServer(opts...)
 }
 
-// createConsoleServer creates a gRPC server for console connections with mTLS
-func createConsoleServer(cfg *config.NexusConfig, serverCert tls.Certificate, caCertPool *x509.CertPool, logger *zap.Logger) *grpc.Server {
+// minionTLSConfig returns the TLS configuration of the servers minions connect to
+func minionTLSConfig(serverCert tls.Certificate, caCertPool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    caCertPool,
+	}
+}
+
+// webTLSConfig returns the TLS configuration of the web server serving the REST API. Client certificates
+// are verified when given, so that browsers still reach the dashboard, the API requiring one.
+func webTLSConfig(serverCert tls.Certificate, caCertPool *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    caCertPool,
+	}
+}
+
+// createLongPollServer creates the HTTPS server of the long-polling transport minions fall back
+// to, authenticating them like the minion gRPC server
+func createLongPollServer(cfg *config.NexusConfig, nexusServer *nexus.Server, serverCert tls.Certificate, caCertPool *x509.CertPool) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.HTTPFallbackPort),
+		Handler:           nexusServer.LongPollHandler(cfg.MaxMsgSize),
+		TLSConfig:         minionTLSConfig(serverCert, caCertPool),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// keepaliveEnforcementPolicy returns the policy rejecting clients pinging more often than configured
+func keepaliveEnforcementPolicy(cfg *config.NexusConfig) keepalive.EnforcementPolicy {
+	return keepalive.EnforcementPolicy{
+		MinTime:             time.Duration(cfg.KeepaliveMinTime) * time.Second,
+		PermitWithoutStream: true,
+	}
+}
+
+// keepaliveServerParameters returns the server keepalive parameters, closing connections
+// whose peer does not acknowledge pings so half-open TCP sessions end
+func keepaliveServerParameters(cfg *config.NexusConfig) keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		MaxConnectionIdle:     10 * time.Minute, // Reduced from 30 to 10 minutes
+		MaxConnectionAge:      15 * time.Minute, // Reduced from 60 to 15 minutes
+		MaxConnectionAgeGrace: 10 * time.Second,
+		Time:                  time.Duration(cfg.KeepaliveTime) * time.Second,
+		Timeout:               time.Duration(cfg.KeepaliveTimeout) * time.Second,
+	}
+}
+
+// createConsoleServer creates a gRPC server for console connections with mTLS, client certificates
+// being optional once consoles may authenticate with OpenID Connect tokens instead
+func createConsoleServer(cfg *config.NexusConfig, nexusServer *nexus.Server, serverCert tls.Certificate, caCertPool *x509.CertPool, logger *zap.Logger) *grpc.Server {
 	tlsConfig := &tls.Config{
 		Certificates: []tls.Certificate{serverCert},
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 		ClientCAs:    caCertPool,
 	}
+	if cfg.OIDCIssuer != "" {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
 
 	creds := credentials.NewTLS(tlsConfig)
 	opts := []grpc.ServerOption{
 		grpc.Creds(creds),
 		grpc.MaxRecvMsgSize(cfg.MaxMsgSize),
 		grpc.MaxSendMsgSize(cfg.MaxMsgSize),
-		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
-			MinTime:             30 * time.Second,
-			PermitWithoutStream: true,
-		}),
-		grpc.KeepaliveParams(keepalive.ServerParameters{
-			MaxConnectionIdle:     10 * time.Minute, // Reduced from 30 to 10 minutes
-			MaxConnectionAge:      15 * time.Minute, // Reduced from 60 to 15 minutes
-			MaxConnectionAgeGrace: 10 * time.Second,
-			Time:                  60 * time.Second,
-			Timeout:               20 * time.Second,
-		}),
+		grpc.KeepaliveEnforcementPolicy(keepaliveEnforcementPolicy(cfg)),
+		grpc.KeepaliveParams(keepaliveServerParameters(cfg)),
 	}
+	opts = append(opts, compression.ServerOptions(cfg.Compression)...)
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(nexusServer.ConsoleAuthUnary),
+		grpc.ChainStreamInterceptor(nexusServer.ConsoleAuthStream),
+	)
 
 	logger.Info("Console server mTLS credentials configured successfully")
 	return grpc.NewServer(opts...)
@@ -0,0 +1,151 @@
+// Package main implements the Relay command-line application.
+// Relay is a sub-Nexus serving the minions of a NAT'd or air-gapped network segment,
+// forwarding their traffic to Nexus over a single upstream connection.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/compression"
+	"github.com/arhuman/minexus/internal/config"
+	"github.com/arhuman/minexus/internal/logging"
+	"github.com/arhuman/minexus/internal/relay"
+	"github.com/arhuman/minexus/internal/version"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// setupUpstreamConnection establishes the connection to Nexus
+func setupUpstreamConnection(cfg *config.RelayConfig, logger *zap.Logger) (*grpc.ClientConn, error) {
+	logger, start := logging.FuncLogger(logger, "setupUpstreamConnection")
+	defer logging.FuncExit(logger, start)
+
+	store, err := certs.NewStore("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificates: %w", err)
+	}
+
+	return grpc.NewClient(cfg.ServerAddr,
+		grpc.WithTransportCredentials(store.Credentials()),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(cfg.MaxMsgSize),
+			grpc.MaxCallSendMsgSize(cfg.MaxMsgSize),
+		),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                60 * time.Second, // Send pings every 60 seconds
+			Timeout:             20 * time.Second, // Wait 20 seconds for ping ack
+			PermitWithoutStream: true,             // Allow pings even without active streams
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			MinConnectTimeout: time.Duration(cfg.ConnectTimeout) * time.Second,
+		}),
+		compression.DialOption(cfg.Compression),
+	)
+}
+
+// createDownstreamServer creates the gRPC server downstream minions connect to, with standard TLS
+func createDownstreamServer(cfg *config.RelayConfig, logger *zap.Logger) (*grpc.Server, error) {
+	serverCert, err := tls.X509KeyPair(certs.CertPEM, certs.KeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedded TLS certificates: %w", err)
+	}
+
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+	})
+	opts := []grpc.ServerOption{
+		grpc.Creds(creds),
+		grpc.MaxRecvMsgSize(cfg.MaxMsgSize),
+		grpc.MaxSendMsgSize(cfg.MaxMsgSize),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             30 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle: 10 * time.Minute,
+			Time:              60 * time.Second,
+			Timeout:           20 * time.Second,
+		}),
+	}
+
+	opts = append(opts, compression.ServerOptions(cfg.Compression)...)
+
+	logger.Info("Downstream server TLS credentials configured successfully")
+	return grpc.NewServer(opts...), nil
+}
+
+func main() {
+	// Check for version flag
+	if version.CheckAndHandleVersionFlag("Relay") {
+		return
+	}
+
+	// Load configuration from environment, .env file, and command line flags
+	cfg, err := config.LoadRelayConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger, _, _, err := logging.NewLogger(cfg.Debug, cfg.Log)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create logger: %v", err))
+	}
+	defer logger.Sync()
+
+	logger.Info("Starting Relay", zap.String("version", version.Component("Relay")))
+	cfg.LogConfig(logger)
+
+	conn, err := setupUpstreamConnection(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to Nexus", zap.Error(err), zap.String("address", cfg.ServerAddr))
+	}
+	defer conn.Close()
+
+	r := relay.NewRelay(cfg.ID, pb.NewMinionServiceClient(conn), logger)
+	r.SetMaxMsgSize(cfg.MaxMsgSize)
+
+	server, err := createDownstreamServer(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to create downstream server", zap.Error(err))
+	}
+	pb.RegisterMinionServiceServer(server, r)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.ListenPort))
+	if err != nil {
+		logger.Fatal("Failed to create downstream listener", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	go func() {
+		logger.Info("Relay listening for minions", zap.Int("port", cfg.ListenPort))
+		if err := server.Serve(listener); err != nil {
+			logger.Error("Downstream server stopped", zap.Error(err))
+		}
+	}()
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+	logger.Info("Received termination signal, shutting down...")
+
+	cancel()
+	server.GracefulStop()
+	logger.Info("Relay stopped")
+}
@@ -5,7 +5,8 @@ CREATE TABLE hosts (
     os VARCHAR(50),
     first_seen TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     last_seen TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    tags JSONB DEFAULT '{}'
+    tags JSONB DEFAULT '{}',
+    advertised_tags JSONB
 );
 
 -- Indexes for faster lookups and improved query performance
@@ -13,17 +14,52 @@ CREATE INDEX idx_hosts_last_seen ON hosts(last_seen);
 CREATE INDEX idx_hosts_hostname ON hosts(hostname);
 CREATE INDEX idx_hosts_ip ON hosts(ip);
 
+-- Table recording changes of the environment fingerprint reported by minions
+CREATE TABLE host_changes (
+    id SERIAL PRIMARY KEY,
+    host_id VARCHAR(128) NOT NULL REFERENCES hosts(id),
+    old_fingerprint VARCHAR(64),
+    new_fingerprint VARCHAR(64) NOT NULL,
+    changes TEXT,
+    detected_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_host_changes_host_id ON host_changes(host_id);
+
+CREATE TABLE connection_events (
+    id SERIAL PRIMARY KEY,
+    host_id VARCHAR(128) NOT NULL REFERENCES hosts(id),
+    event VARCHAR(10) NOT NULL CHECK (event IN ('CONNECT', 'DISCONNECT')),
+    reason TEXT,
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_connection_events_host_id_timestamp ON connection_events(host_id, timestamp);
+
+-- Bound at registration, before the host is stored, hence no reference to hosts
+CREATE TABLE minion_identities (
+    host_id VARCHAR(128) PRIMARY KEY,
+    cert_fingerprint VARCHAR(64) NOT NULL,
+    bound_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE commands (
     id VARCHAR(128) PRIMARY KEY,
     host_id VARCHAR(128) REFERENCES hosts(id),
     command TEXT NOT NULL,
     timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     direction VARCHAR(4) CHECK (direction IN ('SENT', 'RECV')),
-    status VARCHAR(20) DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'RECEIVED', 'EXECUTING', 'COMPLETED', 'FAILED'))
+    status VARCHAR(20) DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'RECEIVED', 'EXECUTING', 'COMPLETED', 'FAILED')),
+    redacted BOOLEAN NOT NULL DEFAULT FALSE, -- secrets were removed from the command before storage
+    trace_id VARCHAR(64),
+    impact VARCHAR(16) CHECK (impact IN ('read-only', 'mutating', 'disruptive')),
+    operator VARCHAR(255), -- common name of the certificate of the console that sent the command
+    targets JSONB -- minions the command was resolved to
 );
 
 -- Index for faster status lookups
 CREATE INDEX idx_commands_status ON commands(status);
+CREATE INDEX idx_commands_trace_id ON commands(trace_id);
 
 -- Table for storing command execution results
 CREATE TABLE command_results (
@@ -33,12 +69,173 @@ CREATE TABLE command_results (
     exit_code INTEGER NOT NULL DEFAULT 0,
     stdout TEXT,
     stderr TEXT,
+    content_type VARCHAR(100),
+    structured TEXT,
     timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    redacted BOOLEAN NOT NULL DEFAULT FALSE, -- secrets were removed from the output before storage
+    trace_id VARCHAR(64),
+    attempt INTEGER NOT NULL DEFAULT 1, -- dispatch of the command the result comes from, retried commands going above 1
+    execution TEXT, -- JSON execution info captured by the minion: working directory, user, PATH, duration, peak memory
     CONSTRAINT fk_command_results_host FOREIGN KEY (minion_id) REFERENCES hosts(id),
-    CONSTRAINT fk_command_results_command FOREIGN KEY (command_id) REFERENCES commands(id)
+    CONSTRAINT fk_command_results_command FOREIGN KEY (command_id) REFERENCES commands(id),
+    -- a minion resending a result after a lost acknowledgement does not store it twice
+    CONSTRAINT uq_command_results_command_minion UNIQUE (command_id, minion_id)
 );
 
+-- Results of the attempts of retried commands superseded by the result of a next attempt
+CREATE TABLE command_result_attempts (
+    id SERIAL PRIMARY KEY,
+    command_id VARCHAR(128) NOT NULL,
+    minion_id VARCHAR(128) NOT NULL,
+    attempt INTEGER NOT NULL,
+    exit_code INTEGER NOT NULL DEFAULT 0,
+    stdout TEXT,
+    stderr TEXT,
+    content_type VARCHAR(100),
+    structured TEXT,
+    timestamp TIMESTAMP WITH TIME ZONE,
+    redacted BOOLEAN NOT NULL DEFAULT FALSE,
+    trace_id VARCHAR(64),
+    execution TEXT,
+    superseded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_command_result_attempts_command_id ON command_result_attempts(command_id);
+
 -- Index for faster command result lookups
 CREATE INDEX idx_command_results_command_id ON command_results(command_id);
 CREATE INDEX idx_command_results_minion_id ON command_results(minion_id);
 CREATE INDEX idx_command_results_timestamp ON command_results(timestamp);
+
+-- Successful results counted but not stored, for the commands sampling their results
+CREATE TABLE command_result_samples (
+    command_id VARCHAR(128) PRIMARY KEY REFERENCES commands(id) ON DELETE CASCADE,
+    sample_size INTEGER NOT NULL,
+    omitted_results INTEGER NOT NULL DEFAULT 0,
+    last_result_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Timeline of traced commands: status updates of the minions and results received by Nexus
+CREATE TABLE command_events (
+    id SERIAL PRIMARY KEY,
+    trace_id VARCHAR(64) NOT NULL,
+    command_id VARCHAR(128) NOT NULL,
+    minion_id VARCHAR(128) NOT NULL,
+    event VARCHAR(20) NOT NULL,
+    detail TEXT,
+    timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_command_events_trace_id ON command_events(trace_id);
+
+-- Table storing saved report queries over command results
+CREATE TABLE reports (
+    name VARCHAR(128) PRIMARY KEY,
+    query TEXT NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Audit trail of the commands requiring the approval of a second operator
+CREATE TABLE command_approvals (
+    command_id VARCHAR(128) PRIMARY KEY,
+    payload TEXT NOT NULL,
+    targets TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    decided_by VARCHAR(255),
+    decided_at TIMESTAMP WITH TIME ZONE,
+    comment TEXT,
+    impact VARCHAR(16) CHECK (impact IN ('read-only', 'mutating', 'disruptive')),
+    request TEXT -- request of the pending command, dispatched once approved and cleared once decided
+);
+
+-- Table pointing to command results archived to object storage
+CREATE TABLE archived_results (
+    command_id VARCHAR(128) PRIMARY KEY REFERENCES commands(id),
+    object_key TEXT NOT NULL,
+    result_count INTEGER NOT NULL DEFAULT 0,
+    archived_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Structured logs shipped by the minions with log shipping enabled
+CREATE TABLE minion_logs (
+    id BIGSERIAL PRIMARY KEY,
+    minion_id VARCHAR(128) NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    level VARCHAR(10) NOT NULL,
+    logger VARCHAR(255),
+    message TEXT NOT NULL,
+    caller VARCHAR(255),
+    fields JSONB
+);
+
+CREATE INDEX idx_minion_logs_minion_id_timestamp ON minion_logs(minion_id, timestamp);
+CREATE INDEX idx_minion_logs_timestamp ON minion_logs(timestamp);
+
+CREATE TABLE minion_crashes (
+    id SERIAL PRIMARY KEY,
+    host_id VARCHAR(128) NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    component VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    stack TEXT NOT NULL,
+    version VARCHAR(64),
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_minion_crashes_host_id_timestamp ON minion_crashes(host_id, timestamp);
+CREATE INDEX idx_minion_crashes_timestamp ON minion_crashes(timestamp);
+
+CREATE TABLE command_env (
+    id SERIAL PRIMARY KEY,
+    minion_id VARCHAR(128) NOT NULL DEFAULT '',
+    tag_key VARCHAR(255) NOT NULL DEFAULT '',
+    tag_value VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (minion_id, tag_key, tag_value, name)
+);
+
+-- Hosts decommissioned with minion-retire, moved out of hosts with their results
+CREATE TABLE retired_hosts (
+    id SERIAL PRIMARY KEY,
+    host_id VARCHAR(128) NOT NULL,
+    hostname VARCHAR(255),
+    ip INET,
+    os VARCHAR(50),
+    first_seen TIMESTAMP WITH TIME ZONE,
+    last_seen TIMESTAMP WITH TIME ZONE,
+    tags JSONB DEFAULT '{}',
+    retired_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    retired_by VARCHAR(255), -- identity of the console that retired the host
+    reason TEXT,
+    uninstall BOOLEAN NOT NULL DEFAULT FALSE, -- the minion was asked to remove its binary
+    notified_at TIMESTAMP WITH TIME ZONE -- the minion received the retirement, NULL until then
+);
+
+CREATE INDEX idx_retired_hosts_host_id ON retired_hosts(host_id);
+
+-- Results of the retired hosts, without reference to hosts
+CREATE TABLE retired_command_results (
+    id SERIAL PRIMARY KEY,
+    command_id VARCHAR(128) NOT NULL,
+    minion_id VARCHAR(128) NOT NULL,
+    exit_code INTEGER NOT NULL DEFAULT 0,
+    stdout TEXT,
+    stderr TEXT,
+    content_type VARCHAR(100),
+    structured TEXT,
+    timestamp TIMESTAMP WITH TIME ZONE,
+    redacted BOOLEAN NOT NULL DEFAULT FALSE,
+    trace_id VARCHAR(64),
+    attempt INTEGER NOT NULL DEFAULT 1,
+    execution TEXT,
+    retired_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_retired_command_results_minion_id ON retired_command_results(minion_id);
+CREATE INDEX idx_retired_command_results_command_id ON retired_command_results(command_id);
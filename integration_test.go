@@ -15,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/arhuman/minexus/pkg/client"
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -293,6 +294,13 @@ func TestIntegrationSuite(t *testing.T) {
 			testMixedTrafficScenarios(t)
 			recordTime("MixedTrafficScenarios", time.Since(start))
 		})
+
+		t.Run("ClientLibrary", func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+			testClientLibrary(t)
+			recordTime("ClientLibrary", time.Since(start))
+		})
 	})
 
 	phase2Duration := time.Since(phase2Start)
@@ -613,6 +621,34 @@ func extractCommandID(output string) string {
 	return ""
 }
 
+// testClientLibrary drives the minion through the client package instead of the console binary
+func testClientLibrary(t *testing.T) {
+	c, err := client.New(client.Config{
+		Address:    "localhost:11973",
+		CACert:     "internal/certs/files/test/ca.crt",
+		ClientCert: "internal/certs/files/test/console.crt",
+		ClientKey:  "internal/certs/files/test/console.key",
+	})
+	require.NoError(t, err, "Client should connect to Nexus")
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, results, err := c.Run(ctx, client.Minions("docker-minion"), "echo client-library")
+	require.NoError(t, err, "Command should complete")
+	require.Len(t, results, 1, "The minion should return its result")
+	assert.Equal(t, response.CommandId, results[0].CommandId)
+	assert.Equal(t, int32(0), results[0].ExitCode)
+	assert.Contains(t, results[0].Stdout, "client-library")
+
+	require.NoError(t, c.UpdateTags(ctx, "docker-minion", map[string]string{"client": "library"}, nil))
+	tags, err := c.ListTags(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, tags, "client:library")
+	require.NoError(t, c.UpdateTags(ctx, "docker-minion", nil, []string{"client"}))
+}
+
 // testConsoleCommands tests basic console commands
 func testConsoleCommands(t *testing.T) {
 	tests := []struct {
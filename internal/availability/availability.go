@@ -0,0 +1,231 @@
+// Package availability parses the availability windows of power-aware minions, such as laptops
+// and edge devices that sleep outside their windows, and computes when they sleep and wake.
+package availability
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// horizon is how far ahead windows are searched, past a week every window has repeated
+const horizon = 8
+
+// dayNames maps the accepted day names to their weekday
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// window is a daily availability window on some days of the week. A window ending at or before
+// its start runs overnight into the next day.
+type window struct {
+	days       [7]bool
+	start, end time.Duration // offsets from midnight
+}
+
+// Schedule is a set of availability windows in the local time of the minion.
+// A nil Schedule is always available.
+type Schedule struct {
+	spec    string
+	windows []window
+}
+
+// interval is a period of availability
+type interval struct {
+	start, end time.Time
+}
+
+// Parse parses availability windows separated by semicolons, each made of optional days
+// (Mon-Fri, Sat, Mon,Wed,Fri, every day when omitted) and a time range (08:00-18:00,
+// 22:00-06:00 running overnight). An empty spec returns a nil Schedule, always available.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	schedule := &Schedule{spec: spec}
+	for _, part := range strings.Split(spec, ";") {
+		w, err := parseWindow(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid availability window '%s': %w", strings.TrimSpace(part), err)
+		}
+		schedule.windows = append(schedule.windows, w)
+	}
+	return schedule, nil
+}
+
+// parseWindow parses one window: [days] HH:MM-HH:MM
+func parseWindow(part string) (window, error) {
+	var w window
+	fields := strings.Fields(part)
+	switch len(fields) {
+	case 1:
+		for day := range w.days {
+			w.days[day] = true
+		}
+	case 2:
+		days, err := parseDays(fields[0])
+		if err != nil {
+			return w, err
+		}
+		w.days = days
+	default:
+		return w, fmt.Errorf("expected [days] HH:MM-HH:MM")
+	}
+
+	times := fields[len(fields)-1]
+	from, to, ok := strings.Cut(times, "-")
+	if !ok {
+		return w, fmt.Errorf("invalid time range '%s', expected HH:MM-HH:MM", times)
+	}
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return w, err
+	}
+	if w.end, err = parseClock(to); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("time range '%s' is empty", times)
+	}
+	return w, nil
+}
+
+// parseDays parses comma-separated days and day ranges, ranges wrapping around the week (Fri-Mon)
+func parseDays(field string) ([7]bool, error) {
+	var days [7]bool
+	for _, item := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, ok := dayNames[strings.ToLower(from)]
+		if !ok {
+			return days, fmt.Errorf("invalid day '%s', expected Mon, Tue, Wed, Thu, Fri, Sat or Sun", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = dayNames[strings.ToLower(to)]; !ok {
+				return days, fmt.Errorf("invalid day '%s', expected Mon, Tue, Wed, Thu, Fri, Sat or Sun", to)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseClock parses a time of day, 24:00 standing for the end of the day
+func parseClock(value string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// String returns the spec the schedule was parsed from, empty for a nil Schedule
+func (s *Schedule) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.spec
+}
+
+// intervals returns the merged periods of availability around t, from the day before its day
+// up to horizon days after it, in the location of t
+func (s *Schedule) intervals(t time.Time) []interval {
+	year, month, day := t.Date()
+	var periods []interval
+	for offset := -1; offset <= horizon; offset++ {
+		midnight := time.Date(year, month, day+offset, 0, 0, 0, 0, t.Location())
+		for _, w := range s.windows {
+			if !w.days[midnight.Weekday()] {
+				continue
+			}
+			start := clockTime(midnight, w.start)
+			end := clockTime(midnight, w.end)
+			if w.end <= w.start {
+				end = clockTime(midnight.AddDate(0, 0, 1), w.end)
+			}
+			periods = append(periods, interval{start, end})
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].start.Before(periods[j].start) })
+
+	var merged []interval
+	for _, p := range periods {
+		if n := len(merged); n > 0 && !p.start.After(merged[n-1].end) {
+			if p.end.After(merged[n-1].end) {
+				merged[n-1].end = p.end
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// clockTime returns the time at offset from midnight, following the clock across DST changes
+func clockTime(midnight time.Time, offset time.Duration) time.Time {
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(),
+		int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, midnight.Location())
+}
+
+// Available reports whether t is within one of the windows
+func (s *Schedule) Available(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	for _, p := range s.intervals(t) {
+		if !t.Before(p.start) && t.Before(p.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// SleepAt returns when the minion available at t goes to sleep, t itself when it is not
+// available, and the zero time when it never sleeps
+func (s *Schedule) SleepAt(t time.Time) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	periods := s.intervals(t)
+	for _, p := range periods {
+		if !t.Before(p.start) && t.Before(p.end) {
+			if p.end.Sub(t) > 7*24*time.Hour {
+				return time.Time{}
+			}
+			return p.end
+		}
+	}
+	return t
+}
+
+// NextWake returns the start of the first window after the minion goes to sleep, or the zero
+// time when it never sleeps
+func (s *Schedule) NextWake(t time.Time) time.Time {
+	sleep := s.SleepAt(t)
+	if sleep.IsZero() {
+		return time.Time{}
+	}
+	for _, p := range s.intervals(t) {
+		if p.start.After(sleep) {
+			return p.start
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,119 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+// at returns a time of the week of Monday 2024-01-01 in UTC
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"Mon-Fri 08:00-18:00",
+		"Mon-Fri 08:00-12:00; Mon-Fri 13:00-18:00",
+		"Mon,Wed,Fri 09:00-10:30",
+		"Fri-Mon 22:00-06:00",
+		"00:00-24:00",
+	}
+	for _, spec := range valid {
+		schedule, err := Parse(spec)
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", spec, err)
+			continue
+		}
+		if schedule.String() != spec {
+			t.Errorf("Parse(%q).String() = %q", spec, schedule.String())
+		}
+	}
+
+	invalid := []string{
+		"Mon-Fri",
+		"Monday 08:00-18:00",
+		"Mon-Fri 08:00",
+		"Mon-Fri 8h-18h",
+		"Mon-Fri 08:00-25:00",
+		"Mon-Fri 08:00-08:00",
+		"Mon-Fri 08:00-18:00 extra",
+	}
+	for _, spec := range invalid {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) expected an error", spec)
+		}
+	}
+
+	schedule, err := Parse("  ")
+	if err != nil || schedule != nil {
+		t.Errorf("Expected an empty spec to parse to a nil schedule, got %v, %v", schedule, err)
+	}
+	if !schedule.Available(at(1, 3, 0)) || !schedule.SleepAt(at(1, 3, 0)).IsZero() || !schedule.NextWake(at(1, 3, 0)).IsZero() {
+		t.Error("Expected a nil schedule to be always available")
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	// 2024-01-01 is a Monday
+	schedule, err := Parse("Mon-Fri 08:00-18:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		t         time.Time
+		available bool
+		sleepAt   time.Time
+		nextWake  time.Time
+	}{
+		{"monday morning", at(1, 9, 0), true, at(1, 18, 0), at(2, 8, 0)},
+		{"monday night", at(1, 20, 0), false, at(1, 20, 0), at(2, 8, 0)},
+		{"friday evening", at(5, 18, 0), false, at(5, 18, 0), at(8, 8, 0)},
+		{"sunday", at(7, 12, 0), false, at(7, 12, 0), at(8, 8, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule.Available(tt.t); got != tt.available {
+				t.Errorf("Available() = %v, want %v", got, tt.available)
+			}
+			if got := schedule.SleepAt(tt.t); !got.Equal(tt.sleepAt) {
+				t.Errorf("SleepAt() = %v, want %v", got, tt.sleepAt)
+			}
+			if got := schedule.NextWake(tt.t); !got.Equal(tt.nextWake) {
+				t.Errorf("NextWake() = %v, want %v", got, tt.nextWake)
+			}
+		})
+	}
+}
+
+func TestScheduleOvernightAndContiguous(t *testing.T) {
+	// Overnight windows run into the next day, and adjacent windows merge
+	schedule, err := Parse("22:00-24:00; 00:00-06:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !schedule.Available(at(2, 23, 0)) || !schedule.SleepAt(at(2, 23, 0)).Equal(at(3, 6, 0)) {
+		t.Errorf("Expected the windows merged until 06:00, got %v", schedule.SleepAt(at(2, 23, 0)))
+	}
+
+	schedule, err = Parse("Fri 20:00-02:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !schedule.Available(at(6, 1, 0)) || schedule.Available(at(6, 3, 0)) {
+		t.Error("Expected the Friday window to run until Saturday 02:00")
+	}
+	if got := schedule.NextWake(at(6, 3, 0)); !got.Equal(at(12, 20, 0)) {
+		t.Errorf("NextWake() = %v, want the next Friday", got)
+	}
+
+	// A schedule covering the whole week never sleeps
+	schedule, err = Parse("00:00-24:00")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !schedule.Available(at(3, 12, 0)) || !schedule.SleepAt(at(3, 12, 0)).IsZero() || !schedule.NextWake(at(3, 12, 0)).IsZero() {
+		t.Error("Expected a full week schedule to never sleep")
+	}
+}
@@ -160,3 +160,88 @@ func verifyCertificate(t *testing.T, cert *x509.Certificate, roots *x509.CertPoo
 		t.Errorf("%s certificate verification failed: %v", certType, err)
 	}
 }
+
+func TestBundleValidate(t *testing.T) {
+	if err := DefaultBundle().Validate(); err != nil {
+		t.Fatalf("Expected embedded bundle to be valid, got: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		bundle *Bundle
+	}{
+		{"nil bundle", nil},
+		{"mismatched key", &Bundle{CertPEM: CertPEM, KeyPEM: ConsoleClientKeyPEM, CAPEM: CAPem}},
+		{"missing CA", &Bundle{CertPEM: CertPEM, KeyPEM: KeyPEM}},
+		{"wrong CA", &Bundle{CertPEM: CertPEM, KeyPEM: KeyPEM, CAPEM: ConsoleClientCertPEM}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.bundle.Validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestStoreInstallAndRollback(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	if string(store.Current().CertPEM) != string(CertPEM) {
+		t.Fatal("Expected embedded certificate to be active by default")
+	}
+
+	rotated := &Bundle{CertPEM: ConsoleClientCertPEM, KeyPEM: ConsoleClientKeyPEM, CAPEM: CAPem}
+	if err := store.Install(rotated); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if string(store.Current().CertPEM) != string(ConsoleClientCertPEM) {
+		t.Error("Expected rotated certificate to be active")
+	}
+
+	// A new store must pick up the persisted bundle
+	reloaded, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore (reload) failed: %v", err)
+	}
+	if string(reloaded.Current().CertPEM) != string(ConsoleClientCertPEM) {
+		t.Error("Expected persisted certificate to be loaded")
+	}
+
+	if err := store.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if string(store.Current().CertPEM) != string(CertPEM) {
+		t.Error("Expected embedded certificate to be restored")
+	}
+	if err := store.Rollback(); err == nil {
+		t.Error("Expected error when rolling back twice")
+	}
+
+	reloaded, err = NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore (after rollback) failed: %v", err)
+	}
+	if string(reloaded.Current().CertPEM) != string(CertPEM) {
+		t.Error("Expected restored certificate to be persisted")
+	}
+}
+
+func TestStoreInstallRejectsInvalidBundle(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+
+	if err := store.Install(&Bundle{CertPEM: CertPEM, KeyPEM: ConsoleClientKeyPEM, CAPEM: CAPem}); err == nil {
+		t.Fatal("Expected invalid bundle to be rejected")
+	}
+	if string(store.Current().CertPEM) != string(CertPEM) {
+		t.Error("Expected active bundle to be unchanged")
+	}
+}
@@ -0,0 +1,379 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// BundleFileName is the name of the file holding the persisted minion credentials
+const BundleFileName = "minion-bundle.json"
+
+// Bundle holds the PEM encoded credentials a minion uses to connect to Nexus
+type Bundle struct {
+	CertPEM []byte `json:"cert"`
+	KeyPEM  []byte `json:"key"`
+	CAPEM   []byte `json:"ca"`
+}
+
+// DefaultBundle returns the credentials embedded at build time
+func DefaultBundle() *Bundle {
+	return &Bundle{
+		CertPEM: CertPEM,
+		KeyPEM:  KeyPEM,
+		CAPEM:   CAPem,
+	}
+}
+
+// Validate checks that the key pair matches and that the certificate is signed by the CA
+func (b *Bundle) Validate() error {
+	if b == nil {
+		return errors.New("bundle is nil")
+	}
+
+	keyPair, err := tls.X509KeyPair(b.CertPEM, b.KeyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid certificate/key pair: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b.CAPEM) {
+		return errors.New("invalid CA bundle: no certificate found")
+	}
+
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, der := range keyPair.Certificate[1:] {
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("certificate is not signed by the provided CA: %w", err)
+	}
+
+	return nil
+}
+
+// TLSConfig builds a client TLS configuration from the bundle
+func (b *Bundle) TLSConfig() (*tls.Config, error) {
+	keyPair, err := tls.X509KeyPair(b.CertPEM, b.KeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate/key pair: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b.CAPEM) {
+		return nil, errors.New("failed to load CA certificate")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		RootCAs:      pool,
+	}, nil
+}
+
+// Fingerprint returns a short description of the bundle certificate for logging
+func (b *Bundle) Fingerprint() string {
+	block, _ := pem.Decode(b.CertPEM)
+	if block == nil {
+		return "invalid"
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "invalid"
+	}
+	return fmt.Sprintf("CN=%s serial=%s", cert.Subject.CommonName, cert.SerialNumber.String())
+}
+
+// Store keeps the active minion credentials and persists rotated bundles.
+// Installing a bundle replaces a single file with an atomic rename so a crash
+// never leaves a partially written credential set on disk.
+type Store struct {
+	dir      string
+	mu       sync.RWMutex
+	current  *Bundle
+	previous *Bundle
+	conns    map[net.Conn]struct{}
+	connsMu  sync.Mutex
+}
+
+// NewStore creates a credential store backed by dir.
+// When dir is empty rotated credentials are kept in memory only.
+// When no valid bundle is persisted in dir the embedded credentials are used.
+func NewStore(dir string) (*Store, error) {
+	store := &Store{
+		dir:     dir,
+		current: DefaultBundle(),
+		conns:   make(map[net.Conn]struct{}),
+	}
+
+	if dir == "" {
+		return store, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, BundleFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate bundle: %w", err)
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode certificate bundle: %w", err)
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, fmt.Errorf("persisted certificate bundle is invalid: %w", err)
+	}
+
+	store.current = &bundle
+	return store, nil
+}
+
+// Current returns the active bundle
+func (s *Store) Current() *Bundle {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Install validates, persists and activates a new bundle.
+// The previously active bundle is kept so it can be restored with Rollback.
+func (s *Store) Install(bundle *Bundle) error {
+	if err := bundle.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.persist(bundle); err != nil {
+		return err
+	}
+
+	s.previous = s.current
+	s.current = bundle
+	return nil
+}
+
+// Rollback restores the bundle that was active before the last Install
+func (s *Store) Rollback() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.previous == nil {
+		return errors.New("no previous certificate bundle to restore")
+	}
+
+	if err := s.persist(s.previous); err != nil {
+		return err
+	}
+
+	s.current = s.previous
+	s.previous = nil
+	return nil
+}
+
+// persist atomically writes bundle to the store directory
+func (s *Store) persist(bundle *Bundle) error {
+	if s.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to encode certificate bundle: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, BundleFileName+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary bundle file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write certificate bundle: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync certificate bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close certificate bundle: %w", err)
+	}
+	if err := os.Chmod(tmpName, 0600); err != nil {
+		return fmt.Errorf("failed to set certificate bundle permissions: %w", err)
+	}
+
+	if err := os.Rename(tmpName, filepath.Join(s.dir, BundleFileName)); err != nil {
+		return fmt.Errorf("failed to install certificate bundle: %w", err)
+	}
+	return nil
+}
+
+// Credentials returns gRPC transport credentials that always handshake with the active bundle
+func (s *Store) Credentials() credentials.TransportCredentials {
+	return &storeCredentials{store: s}
+}
+
+// HTTPTransport returns an HTTP transport whose connections handshake with the active bundle,
+// closed like gRPC connections by ResetConnections
+func (s *Store) HTTPTransport() *http.Transport {
+	return &http.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			tlsConfig, err := s.Current().TLSConfig()
+			if err != nil {
+				return nil, err
+			}
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid server address %s: %w", addr, err)
+			}
+			tlsConfig.ServerName = host
+
+			dialer := &tls.Dialer{Config: tlsConfig}
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return s.track(conn), nil
+		},
+	}
+}
+
+// ResetConnections closes every connection established with the store credentials,
+// forcing gRPC to reconnect and handshake with the active bundle
+func (s *Store) ResetConnections() {
+	s.connsMu.Lock()
+	conns := make([]net.Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.connsMu.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// track registers an established connection
+func (s *Store) track(conn net.Conn) net.Conn {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	tracked := &trackedConn{Conn: conn, store: s}
+	s.conns[tracked] = struct{}{}
+	return tracked
+}
+
+// untrack forgets a closed connection
+func (s *Store) untrack(conn net.Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	delete(s.conns, conn)
+}
+
+// trackedConn removes itself from the store when closed
+type trackedConn struct {
+	net.Conn
+	store *Store
+	once  sync.Once
+}
+
+// Close closes the underlying connection and stops tracking it
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.store.untrack(c) })
+	return c.Conn.Close()
+}
+
+// storeCredentials implements credentials.TransportCredentials on top of a Store
+type storeCredentials struct {
+	store      *Store
+	serverName string
+}
+
+// ClientHandshake performs a TLS handshake with the currently active bundle
+func (c *storeCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	tlsConfig, err := c.store.Current().TLSConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig.ServerName = c.serverName
+
+	conn, authInfo, err := credentials.NewTLS(tlsConfig).ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.store.track(conn), authInfo, nil
+}
+
+// ServerHandshake is not supported, the store only provides client credentials
+func (c *storeCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("certificate store credentials only support client handshakes")
+}
+
+// Info returns the protocol information of the underlying TLS credentials
+func (c *storeCredentials) Info() credentials.ProtocolInfo {
+	return credentials.NewTLS(&tls.Config{ServerName: c.serverName}).Info()
+}
+
+// Clone returns a copy of the credentials
+func (c *storeCredentials) Clone() credentials.TransportCredentials {
+	return &storeCredentials{store: c.store, serverName: c.serverName}
+}
+
+// OverrideServerName overrides the server name used to verify the server certificate
+func (c *storeCredentials) OverrideServerName(serverName string) error {
+	c.serverName = serverName
+	return nil
+}
+
+// VerifyHandshake dials addr and completes a TLS handshake using bundle
+func VerifyHandshake(ctx context.Context, addr string, bundle *Bundle) error {
+	tlsConfig, err := bundle.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid server address %s: %w", addr, err)
+	}
+	tlsConfig.ServerName = host
+	tlsConfig.NextProtos = []string{"h2"} // gRPC servers require ALPN
+
+	dialer := &tls.Dialer{Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return conn.Close()
+}
@@ -0,0 +1,245 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+const (
+	// MaxSignatureAge is how long a signed command stays valid, bounding command replays
+	MaxSignatureAge = 24 * time.Hour
+	// maxSignatureSkew tolerates signing clocks running ahead of the minion clock
+	maxSignatureSkew = 5 * time.Minute
+	// signaturePrefix versions the signed representation of commands: v1 had no nonce and v2 no
+	// targets, both refused
+	signaturePrefix = "minexus-command-v3"
+	// signatureNonceSize is the size of the random nonce signed with each command
+	signatureNonceSize = 16
+)
+
+// CommandSigner signs the commands sent by a console
+type CommandSigner struct {
+	certPEM []byte
+	key     crypto.Signer
+	now     func() time.Time
+}
+
+// NewCommandSigner creates a signer from a PEM certificate and its private key
+func NewCommandSigner(certPEM, keyPEM []byte) (*CommandSigner, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing certificate: %w", err)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", pair.PrivateKey)
+	}
+	return &CommandSigner{
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pair.Certificate[0]}),
+		key:     key,
+		now:     time.Now,
+	}, nil
+}
+
+// Sign signs the type and payload of cmd for the minions targets, setting its signature, signer certificate,
+// signing time and signed targets. The command ID and priority are not signed as Nexus assigns them. A random
+// nonce makes the signature unique, even with the deterministic Ed25519 and RSA signatures of the same command.
+func (s *CommandSigner) Sign(cmd *pb.Command, targets []string) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("no target minion to sign the command for")
+	}
+	for _, target := range targets {
+		if target == "" || strings.Contains(target, "\n") {
+			return fmt.Errorf("invalid target minion ID %q", target)
+		}
+	}
+	cmd.SignedTargets = slices.Compact(slices.Sorted(slices.Values(targets)))
+	cmd.SignedAt = s.now().Unix()
+	cmd.SignatureNonce = make([]byte, signatureNonceSize)
+	if _, err := rand.Read(cmd.SignatureNonce); err != nil {
+		return fmt.Errorf("failed to generate signature nonce: %w", err)
+	}
+	signature, err := s.SignData(signedData(cmd))
+	if err != nil {
+		return fmt.Errorf("failed to sign command: %w", err)
+	}
+
+	cmd.Signature = signature
+	cmd.SignerCertificate = s.certPEM
+	return nil
+}
+
+// SignData signs data, the signature being checked with CheckDataSignature
+func (s *CommandSigner) SignData(data []byte) ([]byte, error) {
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		return s.key.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(data)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// Certificate returns the PEM certificate of the signer
+func (s *CommandSigner) Certificate() []byte {
+	return s.certPEM
+}
+
+// CheckDataSignature checks that signature was made on data by the key of the PEM certificate certPEM,
+// returning the certificate. Whether the certificate is trusted is left to the caller.
+func CheckDataSignature(certPEM, data, signature []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("invalid signer certificate")
+	}
+	signer, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signer certificate: %w", err)
+	}
+	if err := signer.CheckSignature(signatureAlgorithm(signer.PublicKey), data, signature); err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	return signer, nil
+}
+
+// CommandVerifier verifies command signatures against a trust bundle
+type CommandVerifier struct {
+	roots   *x509.CertPool
+	now     func() time.Time
+	started time.Time // signatures made before are refused, the signatures seen being kept in memory only
+	seen    *seenSignatures
+}
+
+// seenSignatures remembers the command each valid signature was seen with until the signature expires
+type seenSignatures struct {
+	mu       sync.Mutex
+	commands map[string]seenSignature // by SHA-256 of the signature
+}
+
+// seenSignature is the command a signature was seen with
+type seenSignature struct {
+	commandID string
+	expires   time.Time
+}
+
+// NewCommandVerifier creates a verifier trusting the signers whose certificates chain
+// to one of the PEM certificates of bundle
+func NewCommandVerifier(bundle []byte) (*CommandVerifier, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("trust bundle contains no PEM certificate")
+	}
+	now := time.Now()
+	return &CommandVerifier{
+		roots:   roots,
+		now:     time.Now,
+		started: now.Truncate(time.Second),
+		seen:    &seenSignatures{commands: make(map[string]seenSignature)},
+	}, nil
+}
+
+// Verify checks that cmd was signed for minionID by a trusted signer less than MaxSignatureAge ago. The
+// command ID isn't signed as Nexus assigns it, so a signature is only accepted with the ID of the first
+// command it was seen with: retries and redeliveries of that command, refusing its replay as another
+// command. The signatures seen being forgotten on restart, those made before the verifier started are refused.
+func (v *CommandVerifier) Verify(cmd *pb.Command, minionID string) error {
+	if len(cmd.Signature) == 0 {
+		return fmt.Errorf("command is not signed")
+	}
+	if len(cmd.SignedTargets) == 0 {
+		return fmt.Errorf("signature doesn't name the target minions, sign with an up-to-date console")
+	}
+	if !slices.Contains(cmd.SignedTargets, minionID) {
+		return fmt.Errorf("command was not signed for minion %s", minionID)
+	}
+
+	block, _ := pem.Decode(cmd.SignerCertificate)
+	if block == nil {
+		return fmt.Errorf("invalid signer certificate")
+	}
+	signer, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid signer certificate: %w", err)
+	}
+
+	now := v.now()
+	if _, err := signer.Verify(x509.VerifyOptions{
+		Roots:       v.roots,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("untrusted signer %q: %w", signer.Subject.CommonName, err)
+	}
+
+	signedAt := time.Unix(cmd.SignedAt, 0)
+	if now.Sub(signedAt) > MaxSignatureAge || signedAt.Sub(now) > maxSignatureSkew {
+		return fmt.Errorf("signature made at %s is expired", signedAt.UTC().Format(time.RFC3339))
+	}
+	if signedAt.Before(v.started) {
+		return fmt.Errorf("signature made at %s predates the minion start, send the command again", signedAt.UTC().Format(time.RFC3339))
+	}
+
+	if err := signer.CheckSignature(signatureAlgorithm(signer.PublicKey), signedData(cmd), cmd.Signature); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	return v.seen.record(cmd, signedAt.Add(MaxSignatureAge), now)
+}
+
+// record records the command a valid signature is seen with, refusing it when seen with another command.
+// Signatures are forgotten once expired, Verify refusing them anyway.
+func (s *seenSignatures) record(cmd *pb.Command, expires, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, seen := range s.commands {
+		if now.After(seen.expires) {
+			delete(s.commands, key)
+		}
+	}
+
+	sum := sha256.Sum256(cmd.Signature)
+	key := string(sum[:])
+	if seen, exists := s.commands[key]; exists && seen.commandID != cmd.Id {
+		return fmt.Errorf("signature already used by command %s, replay refused", seen.commandID)
+	}
+	s.commands[key] = seenSignature{commandID: cmd.Id, expires: expires}
+	return nil
+}
+
+// signatureAlgorithm returns the algorithm CommandSigner uses for a public key
+func signatureAlgorithm(publicKey any) x509.SignatureAlgorithm {
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return x509.ECDSAWithSHA256
+	case ed25519.PublicKey:
+		return x509.PureEd25519
+	case *rsa.PublicKey:
+		return x509.SHA256WithRSA
+	}
+	return x509.UnknownSignatureAlgorithm
+}
+
+// signedData returns the representation of a command covered by its signature, its targets one per line
+// after their count
+func signedData(cmd *pb.Command) []byte {
+	header := signaturePrefix + "\n" + cmd.Type.String() + "\n" + strconv.FormatInt(cmd.SignedAt, 10) + "\n" +
+		hex.EncodeToString(cmd.SignatureNonce) + "\n" + strconv.Itoa(len(cmd.SignedTargets)) + "\n"
+	for _, target := range cmd.SignedTargets {
+		header += target + "\n"
+	}
+	return append([]byte(header), cmd.Payload...)
+}
@@ -0,0 +1,212 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// selfSignedPair returns a self-signed ECDSA certificate and its key
+func selfSignedPair(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestCommandSignature(t *testing.T) {
+	signer, err := NewCommandSigner(ConsoleClientCertPEM, ConsoleClientKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	verifier, err := NewCommandVerifier(CAPem)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	sign := func(t *testing.T) *pb.Command {
+		cmd := &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"}
+		if err := signer.Sign(cmd, []string{"minion-2", "minion-1"}); err != nil {
+			t.Fatalf("Failed to sign command: %v", err)
+		}
+		// Nexus assigns the ID and may change the priority
+		cmd.Id = "cmd-1"
+		cmd.Priority = pb.CommandPriority_HIGH
+		return cmd
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if err := verifier.Verify(sign(t), "minion-1"); err != nil {
+			t.Errorf("Expected a valid signature, got %v", err)
+		}
+	})
+
+	t.Run("tampered_payload", func(t *testing.T) {
+		cmd := sign(t)
+		cmd.Payload = "rm -rf /"
+		if err := verifier.Verify(cmd, "minion-1"); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("Expected an invalid signature, got %v", err)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		cmd := sign(t)
+		cmd.Id = "cmd-replayed"
+		if err := verifier.Verify(cmd, "minion-1"); err != nil {
+			t.Fatalf("Expected a valid signature, got %v", err)
+		}
+		// Retries and redeliveries keep the command ID
+		if err := verifier.Verify(cmd, "minion-1"); err != nil {
+			t.Errorf("Expected the command delivered again accepted, got %v", err)
+		}
+		replayed := proto.Clone(cmd).(*pb.Command)
+		replayed.Id = "cmd-other"
+		if err := verifier.Verify(replayed, "minion-1"); err == nil || !strings.Contains(err.Error(), "replay") {
+			t.Errorf("Expected the signature replayed as another command refused, got %v", err)
+		}
+
+		// Signing the same command twice gives distinct signatures
+		again := sign(t)
+		again.Id = "cmd-again"
+		if bytes.Equal(again.SignatureNonce, cmd.SignatureNonce) || verifier.Verify(again, "minion-1") != nil {
+			t.Errorf("Expected the command signed again accepted with a new nonce")
+		}
+	})
+
+	t.Run("targets", func(t *testing.T) {
+		cmd := sign(t)
+		if !slices.Equal(cmd.SignedTargets, []string{"minion-1", "minion-2"}) {
+			t.Errorf("Expected the targets signed sorted, got %v", cmd.SignedTargets)
+		}
+		if err := verifier.Verify(cmd, "minion-3"); err == nil || !strings.Contains(err.Error(), "not signed for minion minion-3") {
+			t.Errorf("Expected the command refused by a minion it was not signed for, got %v", err)
+		}
+		cmd.SignedTargets = append(cmd.SignedTargets, "minion-3")
+		if err := verifier.Verify(cmd, "minion-3"); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("Expected a target added after signing refused, got %v", err)
+		}
+		if err := signer.Sign(&pb.Command{Payload: "uptime"}, nil); err == nil {
+			t.Error("Expected a command without target refused by the signer")
+		}
+	})
+
+	t.Run("without_targets", func(t *testing.T) {
+		// Commands signed by consoles predating the targets are refused
+		cmd := &pb.Command{Id: "cmd-legacy", Type: pb.CommandType_SYSTEM, Payload: "uptime", SignedAt: time.Now().Unix()}
+		signature, err := signer.SignData([]byte("minexus-command-v1\nSYSTEM\n" + strconv.FormatInt(cmd.SignedAt, 10) + "\nuptime"))
+		if err != nil {
+			t.Fatalf("Failed to sign command: %v", err)
+		}
+		cmd.Signature, cmd.SignerCertificate = signature, signer.Certificate()
+		if err := verifier.Verify(cmd, "minion-1"); err == nil || !strings.Contains(err.Error(), "target minions") {
+			t.Errorf("Expected a signature without targets refused, got %v", err)
+		}
+	})
+
+	t.Run("before_start", func(t *testing.T) {
+		// The signatures seen before a restart are forgotten, the commands signed before are refused
+		cmd := sign(t)
+		restarted := *verifier
+		restarted.started = time.Unix(cmd.SignedAt, 0).Add(time.Second)
+		if err := restarted.Verify(cmd, "minion-1"); err == nil || !strings.Contains(err.Error(), "predates the minion start") {
+			t.Errorf("Expected a command signed before the start refused, got %v", err)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		if err := verifier.Verify(&pb.Command{Payload: "uptime"}, "minion-1"); err == nil {
+			t.Error("Expected unsigned commands to be rejected")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		cmd := sign(t)
+		expired := *verifier
+		expired.now = func() time.Time { return time.Now().Add(MaxSignatureAge + time.Minute) }
+		if err := expired.Verify(cmd, "minion-1"); err == nil || !strings.Contains(err.Error(), "expired") {
+			t.Errorf("Expected an expired signature, got %v", err)
+		}
+	})
+
+	t.Run("untrusted_signer", func(t *testing.T) {
+		certPEM, keyPEM := selfSignedPair(t, "rogue")
+		rogue, err := NewCommandSigner(certPEM, keyPEM)
+		if err != nil {
+			t.Fatalf("Failed to create signer: %v", err)
+		}
+		cmd := &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"}
+		if err := rogue.Sign(cmd, []string{"minion-1"}); err != nil {
+			t.Fatalf("Failed to sign command: %v", err)
+		}
+		if err := verifier.Verify(cmd, "minion-1"); err == nil || !strings.Contains(err.Error(), "untrusted signer") {
+			t.Errorf("Expected an untrusted signer, got %v", err)
+		}
+
+		// Trusting the signer certificate itself is enough
+		trusting, err := NewCommandVerifier(certPEM)
+		if err != nil {
+			t.Fatalf("Failed to create verifier: %v", err)
+		}
+		if err := trusting.Verify(cmd, "minion-1"); err != nil {
+			t.Errorf("Expected an ECDSA signature from a trusted signer to be valid, got %v", err)
+		}
+	})
+
+	if _, err := NewCommandVerifier([]byte("not a certificate")); err == nil {
+		t.Error("Expected an error for an empty trust bundle")
+	}
+}
+
+func TestDataSignature(t *testing.T) {
+	certPEM, keyPEM := selfSignedPair(t, "nexus")
+	signer, err := NewCommandSigner(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	signature, err := signer.SignData([]byte(`{"command":"uptime"}`))
+	if err != nil {
+		t.Fatalf("Failed to sign data: %v", err)
+	}
+
+	cert, err := CheckDataSignature(signer.Certificate(), []byte(`{"command":"uptime"}`), signature)
+	if err != nil || cert.Subject.CommonName != "nexus" {
+		t.Fatalf("Expected the signature of nexus valid, got %v, %v", cert, err)
+	}
+	if _, err := CheckDataSignature(signer.Certificate(), []byte(`{"command":"reboot"}`), signature); err == nil {
+		t.Error("Expected the signature of other data rejected")
+	}
+	if _, err := CheckDataSignature([]byte("not a certificate"), []byte(`{"command":"uptime"}`), signature); err == nil {
+		t.Error("Expected an invalid certificate rejected")
+	}
+}
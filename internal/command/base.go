@@ -1,15 +1,29 @@
 package command
 
 import (
+	"encoding/json"
+
 	pb "github.com/arhuman/minexus/protogen"
 )
 
+// Content types of structured command results
+const (
+	ContentTypeSystemInfo  = "application/vnd.minexus.system-info+json"
+	ContentTypeSystemOS    = "application/vnd.minexus.system-os+json"
+	ContentTypeProcessList = "application/vnd.minexus.process-list+json"
+	ContentTypeProcessTop  = "application/vnd.minexus.process-top+json"
+	ContentTypePackageList = "application/vnd.minexus.package-list+json"
+)
+
 // BaseCommand provides common functionality for all commands
 type BaseCommand struct {
 	name        string
 	category    string
 	description string
 	usage       string
+	version     int
+	impact      string
+	diskHeavy   bool
 	examples    []Example
 	parameters  []Param
 	notes       []string
@@ -22,6 +36,8 @@ func NewBaseCommand(name, category, description, usage string) *BaseCommand {
 		category:    category,
 		description: description,
 		usage:       usage,
+		version:     1,
+		impact:      ImpactMutating,
 		examples:    make([]Example, 0),
 		parameters:  make([]Param, 0),
 		notes:       make([]string, 0),
@@ -35,12 +51,34 @@ func (b *BaseCommand) Metadata() Definition {
 		Category:    b.category,
 		Description: b.description,
 		Usage:       b.usage,
+		Version:     b.version,
+		Impact:      b.impact,
+		DiskHeavy:   b.diskHeavy,
 		Examples:    b.examples,
 		Parameters:  b.parameters,
 		Notes:       b.notes,
 	}
 }
 
+// WithVersion sets the handler version of the command, 1 by default
+func (b *BaseCommand) WithVersion(version int) *BaseCommand {
+	b.version = version
+	return b
+}
+
+// WithImpact sets the impact class of the command, mutating by default
+func (b *BaseCommand) WithImpact(impact string) *BaseCommand {
+	b.impact = impact
+	return b
+}
+
+// WithDiskHeavy flags the command as writing a lot to disk, refused by the minion when its disk is
+// short of space or inodes
+func (b *BaseCommand) WithDiskHeavy() *BaseCommand {
+	b.diskHeavy = true
+	return b
+}
+
 // WithExamples adds examples to the command
 func (b *BaseCommand) WithExamples(examples ...Example) *BaseCommand {
 	b.examples = append(b.examples, examples...)
@@ -70,6 +108,22 @@ func (b *BaseCommand) CreateSuccessResult(ctx *ExecutionContext, output string)
 	}
 }
 
+// CreateStructuredResult creates a success result carrying both a human readable
+// output and a machine-readable JSON payload of the given content type
+func (b *BaseCommand) CreateStructuredResult(ctx *ExecutionContext, output, contentType string, data interface{}) *pb.CommandResult {
+	result := b.CreateSuccessResult(ctx, output)
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		// Fall back to the plain text output
+		return result
+	}
+
+	result.ContentType = contentType
+	result.Structured = string(encoded)
+	return result
+}
+
 // CreateErrorResult creates a standardized error result
 func (b *BaseCommand) CreateErrorResult(ctx *ExecutionContext, err error) *pb.CommandResult {
 	return &pb.CommandResult{
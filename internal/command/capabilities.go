@@ -0,0 +1,113 @@
+package command
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Command families advertised by minions at registration
+const (
+	CapabilityDockerCompose = "docker-compose"
+	CapabilitySystemd       = "systemd"
+	CapabilityPackages      = "pkg" // advertised as pkg:<manager>, e.g. pkg:dpkg
+	CapabilitySandbox       = "sandbox"
+)
+
+// DetectCapabilities returns the command families the local host can execute
+func DetectCapabilities() []string {
+	var capabilities []string
+
+	if hasDockerCompose() {
+		capabilities = append(capabilities, CapabilityDockerCompose)
+	}
+
+	// Same check as sd_booted(3)
+	if runtime.GOOS == "linux" {
+		if info, err := os.Stat("/run/systemd/system"); err == nil && info.IsDir() {
+			capabilities = append(capabilities, CapabilitySystemd)
+		}
+	}
+
+	if sandboxSupported() {
+		capabilities = append(capabilities, CapabilitySandbox)
+	}
+
+	for _, manager := range packageManagers {
+		if _, err := exec.LookPath(manager.cmd); err == nil {
+			capabilities = append(capabilities, CapabilityPackages+":"+manager.name)
+		}
+	}
+
+	return capabilities
+}
+
+// hasDockerCompose reports whether the docker compose plugin used by docker-compose commands is available
+func hasDockerCompose() bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, "docker", "compose", "version").Run() == nil
+}
+
+// UnsupportedPlatforms returns the operating systems whose minions can't execute a payload, whatever
+// their capabilities: Windows minions can't run shell commands as another user
+func UnsupportedPlatforms(payload string) []string {
+	payload = strings.TrimSpace(payload)
+	if !strings.HasPrefix(payload, "{") {
+		return nil
+	}
+	if request, err := ParseShellRequest(payload); err == nil && request.RunAs != "" {
+		return []string{"windows"}
+	}
+	return nil
+}
+
+// RequiredCapability returns the command family a payload requires, empty when it runs anywhere
+func RequiredCapability(payload string) string {
+	payload = strings.TrimSpace(payload)
+	if strings.HasPrefix(payload, "{") {
+		request, err := ParseShellRequest(payload)
+		if err != nil {
+			return ""
+		}
+		if request.Sandbox != nil {
+			return CapabilitySandbox
+		}
+		payload = strings.TrimSpace(request.Command)
+	}
+
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	switch name := fields[0]; {
+	case strings.HasPrefix(name, "docker-compose:"):
+		return CapabilityDockerCompose
+	case strings.HasPrefix(name, CapabilityPackages+":"):
+		return CapabilityPackages
+	case name == "systemctl" || name == "journalctl":
+		return CapabilitySystemd
+	}
+	return ""
+}
+
+// HasCapability reports whether capabilities include required,
+// a family such as pkg being provided by any of its pkg:<manager> variants
+func HasCapability(capabilities []string, required string) bool {
+	if required == "" {
+		return true
+	}
+	for _, capability := range capabilities {
+		if capability == required || strings.HasPrefix(capability, required+":") {
+			return true
+		}
+	}
+	return false
+}
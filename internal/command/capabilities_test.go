@@ -0,0 +1,43 @@
+package command
+
+import "testing"
+
+func TestRequiredCapability(t *testing.T) {
+	tests := map[string]string{
+		"docker-compose:up /opt/app --build":                CapabilityDockerCompose,
+		"pkg:list openssl":                                  CapabilityPackages,
+		"systemctl status nginx":                            CapabilitySystemd,
+		`{"command": "journalctl -u nginx"}`:                CapabilitySystemd,
+		`{"command": "make", "sandbox": {"memory_mb": 64}}`: CapabilitySandbox,
+		"system:info":                                       "",
+		"ls -la":                                            "",
+		`{"path": "/tmp"}`:                                  "",
+		"":                                                  "",
+	}
+	for payload, expected := range tests {
+		if got := RequiredCapability(payload); got != expected {
+			t.Errorf("RequiredCapability(%q) = %q, expected %q", payload, got, expected)
+		}
+	}
+}
+
+func TestUnsupportedPlatforms(t *testing.T) {
+	if platforms := UnsupportedPlatforms(`{"command": "id", "run_as": "nobody"}`); len(platforms) != 1 || platforms[0] != "windows" {
+		t.Errorf("Expected run_as unsupported on Windows, got %v", platforms)
+	}
+	for _, payload := range []string{`{"command": "id"}`, "id", `{"command": "id", "run_as": 1}`, ""} {
+		if platforms := UnsupportedPlatforms(payload); platforms != nil {
+			t.Errorf("UnsupportedPlatforms(%q) = %v, expected none", payload, platforms)
+		}
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	capabilities := []string{CapabilityDockerCompose, "pkg:rpm"}
+	if !HasCapability(capabilities, CapabilityDockerCompose) || !HasCapability(capabilities, CapabilityPackages) || !HasCapability(nil, "") {
+		t.Error("Expected capabilities to be found")
+	}
+	if HasCapability(capabilities, CapabilitySystemd) || HasCapability(nil, CapabilityPackages) {
+		t.Error("Expected missing capabilities not to be found")
+	}
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/certs"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// CertRotator installs a new credential bundle and reconnects using it
+type CertRotator interface {
+	Rotate(ctx context.Context, bundle *certs.Bundle) error
+}
+
+// CertsRotateRequest represents a certs:rotate payload
+type CertsRotateRequest struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+	CA   string `json:"ca"`
+}
+
+// CertsRotateCommand rotates the credentials a minion uses to reach Nexus
+type CertsRotateCommand struct {
+	*BaseCommand
+	rotator CertRotator
+}
+
+// NewCertsRotateCommand creates a new certificate rotation command.
+// A nil rotator registers the command for validation and help purposes only.
+func NewCertsRotateCommand(rotator CertRotator) *CertsRotateCommand {
+	base := NewBaseCommand(
+		"certs:rotate",
+		"certs",
+		"Install a new client certificate/CA bundle and reconnect with it",
+		`certs:rotate {"cert": "<PEM>", "key": "<PEM>", "ca": "<PEM>"}`,
+	).WithExamples(
+		Example{
+			Description: "Rotate certificates from local PEM files (read by the console)",
+			Command:     "command-send minion abc123 certs:rotate minion.crt minion.key ca.crt",
+			Expected:    "New credentials are installed and the minion reconnects with them",
+		},
+	).WithParameters(
+		Param{Name: "cert", Type: "string", Required: true, Description: "PEM encoded client certificate"},
+		Param{Name: "key", Type: "string", Required: true, Description: "PEM encoded private key"},
+		Param{Name: "ca", Type: "string", Required: true, Description: "PEM encoded CA bundle used to verify Nexus"},
+	).WithNotes(
+		"The bundle is validated before being installed",
+		"The bundle is persisted atomically when the minion has a certificate directory",
+		"A TLS handshake with Nexus is attempted using the new credentials",
+		"Previous credentials are restored if the handshake fails",
+	)
+
+	return &CertsRotateCommand{
+		BaseCommand: base,
+		rotator:     rotator,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *CertsRotateCommand) ValidateArgs(payload string) error {
+	if _, err := ParseCertsRotateRequest(payload); err != nil {
+		return fmt.Errorf("failed to parse request: %w", err)
+	}
+	return nil
+}
+
+// Execute implements ExecutableCommand interface
+func (c *CertsRotateCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.rotator == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("certificate rotation is not enabled on this minion")), nil
+	}
+
+	request, err := ParseCertsRotateRequest(payload)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to parse request: %w", err)), nil
+	}
+
+	bundle := &certs.Bundle{
+		CertPEM: []byte(request.Cert),
+		KeyPEM:  []byte(request.Key),
+		CAPEM:   []byte(request.CA),
+	}
+
+	if err := c.rotator.Rotate(ctx.Context, bundle); err != nil {
+		ctx.Logger.Error("Certificate rotation failed", zap.Error(err))
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	ctx.Logger.Info("Certificate rotation succeeded", zap.String("certificate", bundle.Fingerprint()))
+	return c.BaseCommand.CreateSuccessResult(ctx, fmt.Sprintf("certificates rotated (%s), reconnecting with new credentials", bundle.Fingerprint())), nil
+}
+
+// ParseCertsRotateRequest extracts the JSON bundle from a certs:rotate payload
+func ParseCertsRotateRequest(payload string) (*CertsRotateRequest, error) {
+	body := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(payload), "certs:rotate"))
+	if body == "" {
+		return nil, fmt.Errorf("missing certificate bundle")
+	}
+
+	var request CertsRotateRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return nil, fmt.Errorf("invalid JSON bundle: %w", err)
+	}
+
+	if request.Cert == "" || request.Key == "" || request.CA == "" {
+		return nil, fmt.Errorf("cert, key and ca are required")
+	}
+
+	return &request, nil
+}
@@ -0,0 +1,198 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeConfigShow is the content type of config:show structured results
+const ContentTypeConfigShow = "application/vnd.minexus.config-show+json"
+
+// Setting is the effective value of a minion runtime setting
+type Setting struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Overridden  bool   `json:"overridden"` // set with config:set rather than by the minion configuration
+	Description string `json:"description"`
+	State       string `json:"state,omitempty"` // current state of what the setting limits, e.g. running file transfers
+}
+
+// RuntimeSettings reads and changes the settings of a running minion
+type RuntimeSettings interface {
+	Get(key string) (string, error)
+	// Set applies and persists a setting, returning its effective value
+	Set(key, value string) (string, error)
+	Show() []Setting
+}
+
+// ConfigSetCommand changes a runtime setting of the minion
+type ConfigSetCommand struct {
+	*BaseCommand
+	settings RuntimeSettings
+}
+
+// NewConfigSetCommand creates a new config:set command.
+// Nil settings register the command for validation and help purposes only.
+func NewConfigSetCommand(settings RuntimeSettings) *ConfigSetCommand {
+	base := NewBaseCommand(
+		"config:set",
+		"config",
+		"Change a runtime setting of the minion, applied without restart and persisted",
+		"config:set <key> <value>",
+	).WithExamples(
+		Example{
+			Description: "Send heartbeats every minute",
+			Command:     "command-send all config:set heartbeat_interval 60s",
+			Expected:    "Returns the new effective value",
+		},
+		Example{
+			Description: "Limit the commands executing at the same time",
+			Command:     "command-send tag role=db config:set max_concurrent_commands 2",
+		},
+		Example{
+			Description: "Cap the file transfers of production hosts to 10 MB/s",
+			Command:     "command-send tag env=prod config:set transfer_bandwidth 10485760",
+		},
+	).WithParameters(
+		Param{Name: "key", Type: "string", Required: true, Description: "heartbeat_interval, log_level, max_concurrent_commands, max_memory_mb, max_cpu_percent, transfer_bandwidth or max_file_transfers"},
+		Param{Name: "value", Type: "string", Required: true, Description: "New value, 'default' restoring the minion configuration"},
+	).WithNotes(
+		"Settings are persisted when the minion has a runtime config file",
+		"Resource limits set to 0 are disabled",
+		"transfer_bandwidth is shared by the file-pull transfers and file:get reads running on the minion",
+	)
+
+	return &ConfigSetCommand{
+		BaseCommand: base,
+		settings:    settings,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ConfigSetCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.settings == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("runtime configuration is not enabled on this minion")), nil
+	}
+
+	if err := c.ValidateArgs(payload); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	fields := strings.Fields(payload)
+	value, err := c.settings.Set(fields[1], fields[2])
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	return c.BaseCommand.CreateSuccessResult(ctx, fmt.Sprintf("%s set to %s", fields[1], value)), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *ConfigSetCommand) ValidateArgs(payload string) error {
+	if len(strings.Fields(payload)) != 3 {
+		return fmt.Errorf("usage: config:set <key> <value>")
+	}
+	return nil
+}
+
+// ConfigGetCommand returns the effective value of a runtime setting of the minion
+type ConfigGetCommand struct {
+	*BaseCommand
+	settings RuntimeSettings
+}
+
+// NewConfigGetCommand creates a new config:get command
+func NewConfigGetCommand(settings RuntimeSettings) *ConfigGetCommand {
+	base := NewBaseCommand(
+		"config:get",
+		"config",
+		"Get the effective value of a runtime setting of the minion",
+		"config:get <key>",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Get the heartbeat interval",
+			Command:     "command-send all config:get heartbeat_interval",
+			Expected:    "Returns the current heartbeat interval",
+		},
+	).WithParameters(
+		Param{Name: "key", Type: "string", Required: true, Description: "Setting name, as listed by config:show"},
+	)
+
+	return &ConfigGetCommand{
+		BaseCommand: base,
+		settings:    settings,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ConfigGetCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.settings == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("runtime configuration is not enabled on this minion")), nil
+	}
+
+	if err := c.ValidateArgs(payload); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	value, err := c.settings.Get(strings.Fields(payload)[1])
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	return c.BaseCommand.CreateSuccessResult(ctx, value), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *ConfigGetCommand) ValidateArgs(payload string) error {
+	if len(strings.Fields(payload)) != 2 {
+		return fmt.Errorf("usage: config:get <key>")
+	}
+	return nil
+}
+
+// ConfigShowCommand lists the effective runtime settings of the minion
+type ConfigShowCommand struct {
+	*BaseCommand
+	settings RuntimeSettings
+}
+
+// NewConfigShowCommand creates a new config:show command
+func NewConfigShowCommand(settings RuntimeSettings) *ConfigShowCommand {
+	base := NewBaseCommand(
+		"config:show",
+		"config",
+		"Show the effective runtime settings of the minion",
+		"config:show",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Show the runtime settings",
+			Command:     "command-send all config:show",
+			Expected:    "Returns each setting with its value, marking the ones set with config:set, and the state of the file transfers",
+		},
+	)
+
+	return &ConfigShowCommand{
+		BaseCommand: base,
+		settings:    settings,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ConfigShowCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.settings == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("runtime configuration is not enabled on this minion")), nil
+	}
+
+	settings := c.settings.Show()
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%-24s %-10s %-10s %s\n", "KEY", "VALUE", "SOURCE", "STATE"))
+	for _, setting := range settings {
+		source := "config"
+		if setting.Overridden {
+			source = "config:set"
+		}
+		output.WriteString(strings.TrimRight(fmt.Sprintf("%-24s %-10s %-10s %s", setting.Key, setting.Value, source, setting.State), " ") + "\n")
+	}
+
+	return c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeConfigShow, settings), nil
+}
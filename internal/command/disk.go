@@ -0,0 +1,55 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentTypeDiskFull is the content type of the results of the commands the minion refused to run
+// for lack of disk space or inodes
+const ContentTypeDiskFull = "application/vnd.minexus.disk-full+json"
+
+// ErrorCodeDiskFull is the code of the commands refused for lack of disk space or inodes
+const ErrorCodeDiskFull = "DISK_FULL"
+
+// DiskFullError is the structured error of a disk-heavy command refused because the filesystem it
+// writes to is below the thresholds of the minion
+type DiskFullError struct {
+	Code                 string  `json:"code"` // ErrorCodeDiskFull
+	Path                 string  `json:"path"` // path whose filesystem is short of space or inodes
+	FreeMB               uint64  `json:"free_mb"`
+	MinFreeMB            int     `json:"min_free_mb"`
+	FreeInodesPercent    float64 `json:"free_inodes_percent,omitempty"` // unset on filesystems without inodes
+	MinFreeInodesPercent int     `json:"min_free_inodes_percent"`
+}
+
+// Error implements the error interface
+func (e *DiskFullError) Error() string {
+	if e.MinFreeMB > 0 && e.FreeMB < uint64(e.MinFreeMB) {
+		return fmt.Sprintf("%s: %d MB free on the filesystem of %s, %d MB required", e.Code, e.FreeMB, e.Path, e.MinFreeMB)
+	}
+	return fmt.Sprintf("%s: %.1f%% inodes free on the filesystem of %s, %d%% required", e.Code, e.FreeInodesPercent, e.Path, e.MinFreeInodesPercent)
+}
+
+// DiskTargeter is implemented by the disk-heavy commands writing to paths given in their payload,
+// checked instead of the system disk
+type DiskTargeter interface {
+	DiskTargets(payload string) []string
+}
+
+// DiskTargets returns whether the command a payload names is disk-heavy, and the paths it writes
+// to when the command knows them
+func (r *Registry) DiskTargets(payload string) (paths []string, heavy bool) {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	cmd, exists := r.GetCommand(fields[0])
+	if !exists || !cmd.Metadata().DiskHeavy {
+		return nil, false
+	}
+	if targeter, ok := cmd.(DiskTargeter); ok {
+		return targeter.DiskTargets(payload), true
+	}
+	return nil, true
+}
@@ -31,7 +31,7 @@ func NewDockerComposePSCommand() *DockerComposePSCommand {
 		"docker",
 		"List docker-compose services and their status",
 		"docker-compose:ps <path>",
-	).WithParameters(
+	).WithImpact(ImpactReadOnly).WithParameters(
 		Param{Name: "path", Type: "string", Required: true, Description: "Path to directory containing docker-compose.yml"},
 	).WithExamples(
 		Example{
@@ -55,6 +55,11 @@ func NewDockerComposePSCommand() *DockerComposePSCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *DockerComposePSCommand) ValidateArgs(payload string) error {
+	return validateDockerComposePayload(payload)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *DockerComposePSCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	request, err := parseDockerComposePayload(payload)
@@ -126,6 +131,11 @@ func NewDockerComposeUpCommand() *DockerComposeUpCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *DockerComposeUpCommand) ValidateArgs(payload string) error {
+	return validateDockerComposePayload(payload)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *DockerComposeUpCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	request, err := parseDockerComposePayload(payload)
@@ -176,7 +186,7 @@ func NewDockerComposeDownCommand() *DockerComposeDownCommand {
 		"docker",
 		"Stop and remove docker-compose services",
 		"docker-compose:down <path> [service]",
-	).WithParameters(
+	).WithImpact(ImpactDisruptive).WithParameters(
 		Param{Name: "path", Type: "string", Required: true, Description: "Path to directory containing docker-compose.yml"},
 		Param{Name: "service", Type: "string", Required: false, Description: "Specific service to stop (optional)"},
 	).WithExamples(
@@ -206,6 +216,11 @@ func NewDockerComposeDownCommand() *DockerComposeDownCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *DockerComposeDownCommand) ValidateArgs(payload string) error {
+	return validateDockerComposePayload(payload)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *DockerComposeDownCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	request, err := parseDockerComposePayload(payload)
@@ -273,7 +288,7 @@ func NewDockerComposeFindCommand() *DockerComposeFindCommand {
 		"docker",
 		"Find all directories containing docker-compose.yml files under a given path",
 		"docker-compose:find <path>",
-	).WithParameters(
+	).WithImpact(ImpactReadOnly).WithParameters(
 		Param{Name: "path", Type: "string", Required: true, Description: "Root path to search for docker-compose.yml files"},
 	).WithExamples(
 		Example{
@@ -303,6 +318,11 @@ func NewDockerComposeFindCommand() *DockerComposeFindCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *DockerComposeFindCommand) ValidateArgs(payload string) error {
+	return validateDockerComposePayload(payload)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *DockerComposeFindCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	request, err := parseDockerComposePayload(payload)
@@ -354,7 +374,7 @@ func NewDockerComposeViewCommand() *DockerComposeViewCommand {
 		"docker",
 		"Display the content of docker-compose.yml file in the specified path",
 		"docker-compose:view <path>",
-	).WithParameters(
+	).WithImpact(ImpactReadOnly).WithParameters(
 		Param{Name: "path", Type: "string", Required: true, Description: "Path to directory containing docker-compose.yml file"},
 	).WithExamples(
 		Example{
@@ -384,6 +404,11 @@ func NewDockerComposeViewCommand() *DockerComposeViewCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *DockerComposeViewCommand) ValidateArgs(payload string) error {
+	return validateDockerComposePayload(payload)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *DockerComposeViewCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	request, err := parseDockerComposePayload(payload)
@@ -499,6 +524,18 @@ func parseDockerComposePayload(payload string) (*DockerComposeRequest, error) {
 	return request, nil
 }
 
+// validateDockerComposePayload checks a docker-compose payload parses and names a path
+func validateDockerComposePayload(payload string) error {
+	request, err := parseDockerComposePayload(payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if request.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
 // validateDockerComposePath checks if the path exists and contains a docker-compose file
 func validateDockerComposePath(path string) error {
 	// Check if path exists
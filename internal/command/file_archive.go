@@ -0,0 +1,665 @@
+package command
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeFileArchive is the content type of file:compress and file:extract structured results
+const ContentTypeFileArchive = "application/vnd.minexus.file-archive+json"
+
+// Archive limits, bounding the disk space an archive operation may use on the minion
+const (
+	MaxArchiveSize    = 1024 * 1024 * 1024 // uncompressed bytes archived or extracted at most
+	MaxArchiveEntries = 10000              // files and directories archived or extracted at most
+)
+
+// Archive formats, chosen from the archive file extension
+const (
+	ArchiveTarGz = "tar.gz"
+	ArchiveTar   = "tar"
+	ArchiveZip   = "zip"
+)
+
+// ArchiveResult describes an archive created by file:compress or extracted by file:extract
+type ArchiveResult struct {
+	Archive     string   `json:"archive"`
+	Format      string   `json:"format"`
+	Destination string   `json:"destination,omitempty"` // directory the archive was extracted to
+	Files       int      `json:"files"`
+	Bytes       int64    `json:"bytes"`             // uncompressed size of the files
+	Skipped     []string `json:"skipped,omitempty"` // entries left out: links, devices, unreadable files
+}
+
+// archiveRequest holds the parsed arguments of file:compress and file:extract
+type archiveRequest struct {
+	archive   string
+	paths     []string // paths to archive, or the destination directory to extract to
+	overwrite bool
+}
+
+// archiveFormat returns the format of an archive from its file name
+func archiveFormat(path string) (string, error) {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format for %s, expected .tar.gz, .tgz, .tar or .zip", path)
+	}
+}
+
+// parseArchiveRequest parses the arguments of file:compress and file:extract
+func parseArchiveRequest(args, usage string, minPaths int) (*archiveRequest, error) {
+	request := &archiveRequest{}
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		switch fields[0] {
+		case "-o":
+			request.overwrite = true
+		default:
+			return nil, fmt.Errorf("unknown option %s", fields[0])
+		}
+		fields = fields[1:]
+	}
+	if len(fields) < 1+minPaths {
+		return nil, fmt.Errorf("usage: %s", usage)
+	}
+
+	request.archive = fields[0]
+	request.paths = fields[1:]
+	for _, path := range fields {
+		if err := validatePath(path); err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", path, err)
+		}
+	}
+	return request, nil
+}
+
+// FileCompressCommand creates an archive of paths on the minion
+type FileCompressCommand struct {
+	*BaseCommand
+}
+
+// NewFileCompressCommand creates a new file compress command
+func NewFileCompressCommand() *FileCompressCommand {
+	base := NewBaseCommand(
+		"file:compress",
+		"file",
+		"Create a tar.gz, tar or zip archive of files and directories",
+		"file:compress [-o] <archive> <path-glob>...",
+	).WithVersion(2).WithDiskHeavy().WithExamples(
+		Example{
+			Description: "Bundle the logs of an application",
+			Command:     `command-send tag env=prod "file:compress /tmp/app-logs.tar.gz /var/log/app /var/log/nginx/*.log"`,
+			Expected:    "Creates /tmp/app-logs.tar.gz, ready to be fetched with file:get",
+		},
+	).WithParameters(
+		Param{Name: "archive", Type: "string", Required: true, Description: "Archive to create, its extension (.tar.gz, .tgz, .tar, .zip) sets the format"},
+		Param{Name: "path-glob", Type: "string", Required: true, Description: "Files, directories or glob patterns to archive; directories are archived recursively"},
+		Param{Name: "-o", Type: "bool", Required: false, Description: "Overwrite an existing archive", Default: "false"},
+	).WithNotes(
+		"Entries are named after the archived path, relative to its parent directory",
+		"Symbolic links, devices and unreadable files are skipped and reported",
+		"At most 10000 entries and 1GB of uncompressed data are archived, larger archives are not created",
+		"The result carries a structured JSON payload in addition to the text output",
+	)
+
+	return &FileCompressCommand{
+		BaseCommand: base,
+	}
+}
+
+// DiskTargets implements DiskTargeter interface
+func (c *FileCompressCommand) DiskTargets(payload string) []string {
+	request, err := parseArchiveRequest(commandArgument(payload, "file:compress"), "", 1)
+	if err != nil {
+		return nil
+	}
+	return []string{request.archive}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileCompressCommand) ValidateArgs(payload string) error {
+	request, err := parseArchiveRequest(commandArgument(payload, "file:compress"), "file:compress [-o] <archive> <path-glob>...", 1)
+	if err != nil {
+		return err
+	}
+	_, err = archiveFormat(request.archive)
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *FileCompressCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	funcName := "FileCompressCommand.Execute"
+	logger, start := logging.FuncLogger(ctx.Logger, funcName)
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseArchiveRequest(commandArgument(payload, "file:compress"), "file:compress [-o] <archive> <path-glob>...", 1)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	format, err := archiveFormat(request.archive)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	var sources []string
+	for _, pattern := range request.paths {
+		matches, err := filepath.Glob(filepath.Clean(pattern))
+		if err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("invalid path glob: %w", err)), nil
+		}
+		if len(matches) == 0 {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("no file matches %s", pattern)), nil
+		}
+		sources = append(sources, matches...)
+	}
+
+	archivePath := filepath.Clean(request.archive)
+	result, err := createArchive(ctx, archivePath, format, sources, request.overwrite)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to create archive: %w", err)), nil
+	}
+
+	output := fmt.Sprintf("Created %s (%s): %d files, %d bytes\n", result.Archive, result.Format, result.Files, result.Bytes)
+	if len(result.Skipped) > 0 {
+		output += fmt.Sprintf("Skipped %d entries: %s\n", len(result.Skipped), strings.Join(result.Skipped, ", "))
+	}
+	return c.BaseCommand.CreateStructuredResult(ctx, output, ContentTypeFileArchive, result), nil
+}
+
+// archiveWriter adds files to an archive whatever its format
+type archiveWriter interface {
+	addDir(name string, info fs.FileInfo) error
+	addFile(name string, info fs.FileInfo, content io.Reader) error
+	Close() error
+}
+
+// createArchive writes the sources to a new archive, removing it when the operation fails
+func createArchive(ctx *ExecutionContext, archivePath, format string, sources []string, overwrite bool) (result *ArchiveResult, err error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(archivePath, flags, 0640)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("%s already exists, use -o to overwrite it", archivePath)
+		}
+		return nil, err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(archivePath)
+		}
+	}()
+
+	writer := newArchiveWriter(file, format)
+	result = &ArchiveResult{Archive: archivePath, Format: format}
+	absArchive, _ := filepath.Abs(archivePath)
+
+	for _, source := range sources {
+		parent := filepath.Dir(source)
+		err := filepath.WalkDir(source, func(path string, entry fs.DirEntry, walkErr error) error {
+			if err := ctx.Context.Err(); err != nil {
+				return err
+			}
+			if walkErr != nil {
+				result.Skipped = append(result.Skipped, path)
+				if entry != nil && entry.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			// The archive may be created inside an archived directory
+			if absPath, _ := filepath.Abs(path); absPath == absArchive {
+				return nil
+			}
+
+			info, err := entry.Info()
+			if err != nil || !(info.IsDir() || info.Mode().IsRegular()) {
+				result.Skipped = append(result.Skipped, path)
+				return nil
+			}
+			if result.Files >= MaxArchiveEntries {
+				return fmt.Errorf("more than %d entries", MaxArchiveEntries)
+			}
+			name, err := filepath.Rel(parent, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+
+			if info.IsDir() {
+				return writer.addDir(name, info)
+			}
+			if result.Bytes+info.Size() > MaxArchiveSize {
+				return fmt.Errorf("more than %d bytes to archive", MaxArchiveSize)
+			}
+			content, err := os.Open(path)
+			if err != nil {
+				result.Skipped = append(result.Skipped, path)
+				return nil
+			}
+			defer content.Close()
+			if err := writer.addFile(name, info, content); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", path, err)
+			}
+			result.Files++
+			result.Bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			_ = writer.Close()
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newArchiveWriter returns a writer of the given format
+func newArchiveWriter(w io.Writer, format string) archiveWriter {
+	switch format {
+	case ArchiveZip:
+		return &zipArchiveWriter{zip.NewWriter(w)}
+	case ArchiveTarGz:
+		compressed := gzip.NewWriter(w)
+		return &tarArchiveWriter{tar.NewWriter(compressed), compressed}
+	default:
+		return &tarArchiveWriter{tar.NewWriter(w), nil}
+	}
+}
+
+// tarArchiveWriter writes tar archives, compressed with gzip for tar.gz
+type tarArchiveWriter struct {
+	tar  *tar.Writer
+	gzip *gzip.Writer
+}
+
+func (w *tarArchiveWriter) addDir(name string, info fs.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+	return w.tar.WriteHeader(header)
+}
+
+func (w *tarArchiveWriter) addFile(name string, info fs.FileInfo, content io.Reader) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := w.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	// The file may have grown since it was listed, the header bounds what is written
+	_, err = io.Copy(w.tar, io.LimitReader(content, info.Size()))
+	return err
+}
+
+func (w *tarArchiveWriter) Close() error {
+	err := w.tar.Close()
+	if w.gzip != nil {
+		if gzipErr := w.gzip.Close(); err == nil {
+			err = gzipErr
+		}
+	}
+	return err
+}
+
+// zipArchiveWriter writes zip archives
+type zipArchiveWriter struct {
+	zip *zip.Writer
+}
+
+func (w *zipArchiveWriter) addDir(name string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+	_, err = w.zip.CreateHeader(header)
+	return err
+}
+
+func (w *zipArchiveWriter) addFile(name string, info fs.FileInfo, content io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	writer, err := w.zip.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, io.LimitReader(content, info.Size()))
+	return err
+}
+
+func (w *zipArchiveWriter) Close() error {
+	return w.zip.Close()
+}
+
+// FileExtractCommand extracts an archive on the minion
+type FileExtractCommand struct {
+	*BaseCommand
+}
+
+// NewFileExtractCommand creates a new file extract command
+func NewFileExtractCommand() *FileExtractCommand {
+	base := NewBaseCommand(
+		"file:extract",
+		"file",
+		"Extract a tar.gz, tar or zip archive to a directory",
+		"file:extract [-o] <archive> <destination>",
+	).WithVersion(2).WithDiskHeavy().WithExamples(
+		Example{
+			Description: "Deploy a release artifact",
+			Command:     `command-send tag role=web "file:extract /tmp/release-1.4.tar.gz /opt/app/releases/1.4"`,
+			Expected:    "Extracts the release, refusing to overwrite existing files",
+		},
+	).WithParameters(
+		Param{Name: "archive", Type: "string", Required: true, Description: "Archive to extract, its extension (.tar.gz, .tgz, .tar, .zip) sets the format"},
+		Param{Name: "destination", Type: "string", Required: true, Description: "Directory to extract to, created if missing"},
+		Param{Name: "-o", Type: "bool", Required: false, Description: "Overwrite existing files", Default: "false"},
+	).WithNotes(
+		"Entries with absolute paths or escaping the destination are rejected, failing the extraction",
+		"Symbolic links, hard links and devices are skipped and reported; setuid and setgid bits are dropped",
+		"At most 10000 entries and 1GB of uncompressed data are extracted, guarding against archive bombs",
+		"A failed extraction may leave the files extracted before the failure",
+	)
+
+	return &FileExtractCommand{
+		BaseCommand: base,
+	}
+}
+
+// DiskTargets implements DiskTargeter interface
+func (c *FileExtractCommand) DiskTargets(payload string) []string {
+	request, err := parseArchiveRequest(commandArgument(payload, "file:extract"), "", 1)
+	if err != nil {
+		return nil
+	}
+	return request.paths
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileExtractCommand) ValidateArgs(payload string) error {
+	request, err := parseArchiveRequest(commandArgument(payload, "file:extract"), "file:extract [-o] <archive> <destination>", 1)
+	if err != nil {
+		return err
+	}
+	if len(request.paths) != 1 {
+		return fmt.Errorf("usage: file:extract [-o] <archive> <destination>")
+	}
+	_, err = archiveFormat(request.archive)
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *FileExtractCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	funcName := "FileExtractCommand.Execute"
+	logger, start := logging.FuncLogger(ctx.Logger, funcName)
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseArchiveRequest(commandArgument(payload, "file:extract"), "file:extract [-o] <archive> <destination>", 1)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	if len(request.paths) != 1 {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("usage: file:extract [-o] <archive> <destination>")), nil
+	}
+	format, err := archiveFormat(request.archive)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	archivePath := filepath.Clean(request.archive)
+	extractor := &archiveExtractor{
+		ctx:       ctx,
+		dest:      filepath.Clean(request.paths[0]),
+		overwrite: request.overwrite,
+		result:    &ArchiveResult{Archive: archivePath, Format: format, Destination: filepath.Clean(request.paths[0])},
+	}
+	if err := os.MkdirAll(extractor.dest, 0755); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to create destination: %w", err)), nil
+	}
+
+	if format == ArchiveZip {
+		err = extractor.extractZip(archivePath)
+	} else {
+		err = extractor.extractTar(archivePath, format == ArchiveTarGz)
+	}
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to extract archive: %w", err)), nil
+	}
+
+	result := extractor.result
+	output := fmt.Sprintf("Extracted %s (%s) to %s: %d files, %d bytes\n", result.Archive, result.Format, result.Destination, result.Files, result.Bytes)
+	if len(result.Skipped) > 0 {
+		output += fmt.Sprintf("Skipped %d entries: %s\n", len(result.Skipped), strings.Join(result.Skipped, ", "))
+	}
+	return c.BaseCommand.CreateStructuredResult(ctx, output, ContentTypeFileArchive, result), nil
+}
+
+// archiveExtractor writes the entries of an archive below a destination directory
+type archiveExtractor struct {
+	ctx       *ExecutionContext
+	dest      string
+	overwrite bool
+	entries   int
+	result    *ArchiveResult
+}
+
+// extractTar extracts a tar archive, compressed with gzip for tar.gz
+func (e *archiveExtractor) extractTar(archivePath string, compressed bool) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	archive := tar.NewReader(reader)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = e.extractDir(header.Name, header.FileInfo().Mode())
+		case tar.TypeReg:
+			err = e.extractFile(header.Name, header.FileInfo().Mode(), archive)
+		default:
+			err = e.skip(header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts a zip archive
+func (e *archiveExtractor) extractZip(archivePath string) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, entry := range archive.File {
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			err = e.extractDir(entry.Name, mode)
+		case mode.IsRegular():
+			err = e.extractZipFile(entry)
+		default:
+			err = e.skip(entry.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractZipFile extracts a regular file of a zip archive
+func (e *archiveExtractor) extractZipFile(entry *zip.File) error {
+	content, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	return e.extractFile(entry.Name, entry.Mode(), content)
+}
+
+// target returns the path an entry is extracted to, rejecting entries escaping the destination
+func (e *archiveExtractor) target(name string) (string, error) {
+	if err := e.ctx.Context.Err(); err != nil {
+		return "", err
+	}
+	e.entries++
+	if e.entries > MaxArchiveEntries {
+		return "", fmt.Errorf("more than %d entries", MaxArchiveEntries)
+	}
+
+	cleaned := filepath.FromSlash(name)
+	if filepath.IsAbs(cleaned) || filepath.VolumeName(cleaned) != "" || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("entry %s has an absolute path", name)
+	}
+	target := filepath.Join(e.dest, cleaned)
+	rel, err := filepath.Rel(e.dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("entry %s escapes the destination", name)
+	}
+	if err := e.checkParents(rel); err != nil {
+		return "", fmt.Errorf("entry %s escapes the destination: %w", name, err)
+	}
+	return target, nil
+}
+
+// checkParents verifies that no existing directory between the destination and the entry at rel is a
+// link, which would make the entry written outside the destination
+func (e *archiveExtractor) checkParents(rel string) error {
+	parent := e.dest
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		parent = filepath.Join(parent, part)
+		info, err := os.Lstat(parent)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // created as a directory by the extraction
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", parent)
+		}
+	}
+	return nil
+}
+
+// extractDir creates a directory entry
+func (e *archiveExtractor) extractDir(name string, mode fs.FileMode) error {
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(target, mode.Perm()|0700)
+}
+
+// extractFile writes a regular file entry, within the size budget left
+func (e *archiveExtractor) extractFile(name string, mode fs.FileMode, content io.Reader) error {
+	target, err := e.target(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Overwriting must not follow a link out of the destination
+	if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("%s exists and is not a regular file", target)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if e.overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(target, flags, mode.Perm())
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists, use -o to overwrite it", target)
+		}
+		return err
+	}
+
+	// Sizes declared by the archive cannot be trusted, the content is bounded while copied
+	remaining := MaxArchiveSize - e.result.Bytes
+	written, err := io.Copy(file, io.LimitReader(content, remaining+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if written > remaining {
+		_ = os.Remove(target)
+		return fmt.Errorf("more than %d bytes to extract", MaxArchiveSize)
+	}
+
+	e.result.Files++
+	e.result.Bytes += written
+	return nil
+}
+
+// skip records an entry which is not extracted, such as a link or a device
+func (e *archiveExtractor) skip(name string) error {
+	if _, err := e.target(name); err != nil {
+		return err
+	}
+	e.result.Skipped = append(e.result.Skipped, name)
+	return nil
+}
@@ -0,0 +1,163 @@
+package command
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	dir := t.TempDir()
+	logs := filepath.Join(dir, "logs")
+	if err := os.MkdirAll(filepath.Join(logs, "nginx"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"logs/app.log":          "started\nstopped\n",
+		"logs/nginx/access.log": "GET /\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(logs, "passwd")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"bundle.tar.gz", "bundle.tar", "bundle.zip"} {
+		archive := filepath.Join(dir, name)
+		result, err := NewFileCompressCommand().Execute(ctx, "file:compress "+archive+" "+logs)
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected %s to be created, got %v %v", name, result, err)
+		}
+		var created ArchiveResult
+		if err := json.Unmarshal([]byte(result.Structured), &created); err != nil {
+			t.Fatalf("Invalid structured result: %v", err)
+		}
+		if created.Files != 2 || len(created.Skipped) != 1 {
+			t.Errorf("Expected 2 files and the link skipped in %s, got %+v", name, created)
+		}
+
+		// Archives are not overwritten without -o
+		result, _ = NewFileCompressCommand().Execute(ctx, "file:compress "+archive+" "+logs)
+		if result.ExitCode == 0 || !strings.Contains(result.Stderr, "already exists") {
+			t.Errorf("Expected %s not to be overwritten, got %+v", name, result)
+		}
+
+		dest := filepath.Join(dir, "extracted-"+name)
+		result, err = NewFileExtractCommand().Execute(ctx, "file:extract "+archive+" "+dest)
+		if err != nil || result.ExitCode != 0 {
+			t.Fatalf("Expected %s to be extracted, got %v %v", name, result, err)
+		}
+		for file, content := range files {
+			data, err := os.ReadFile(filepath.Join(dest, file))
+			if err != nil || string(data) != content {
+				t.Errorf("Expected %s extracted from %s, got %q (%v)", file, name, data, err)
+			}
+		}
+
+		// Existing files are only replaced with -o
+		result, _ = NewFileExtractCommand().Execute(ctx, "file:extract "+archive+" "+dest)
+		if result.ExitCode == 0 {
+			t.Errorf("Expected existing files to be kept, got %+v", result)
+		}
+		result, _ = NewFileExtractCommand().Execute(ctx, "file:extract -o "+archive+" "+dest)
+		if result.ExitCode != 0 {
+			t.Errorf("Expected existing files to be overwritten, got %+v", result)
+		}
+	}
+}
+
+func TestExtractRejectsPathTraversal(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	dir := t.TempDir()
+
+	for _, entry := range []string{"../evil.sh", "a/../../evil.sh", "/tmp/evil.sh"} {
+		archive := filepath.Join(dir, "evil.tar")
+		file, err := os.Create(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer := tar.NewWriter(file)
+		content := "#!/bin/sh\n"
+		if err := writer.WriteHeader(&tar.Header{Name: entry, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		writer.Close()
+		file.Close()
+
+		dest := filepath.Join(dir, "dest")
+		result, _ := NewFileExtractCommand().Execute(ctx, "file:extract "+archive+" "+dest)
+		if result.ExitCode == 0 {
+			t.Errorf("Expected entry %s to be rejected", entry)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "evil.sh")); err == nil {
+			t.Errorf("Entry %s was written outside the destination", entry)
+		}
+	}
+}
+
+func TestExtractRejectsLinkedParents(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside")
+	dest := filepath.Join(dir, "dest")
+	for _, path := range []string{outside, dest} {
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "etc")); err != nil {
+		t.Skipf("Symlinks unavailable: %v", err)
+	}
+
+	archive := filepath.Join(dir, "evil.tar")
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := tar.NewWriter(file)
+	content := "root::0:0::/root:/bin/sh\n"
+	if err := writer.WriteHeader(&tar.Header{Name: "etc/passwd", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	file.Close()
+
+	result, _ := NewFileExtractCommand().Execute(ctx, "file:extract "+archive+" "+dest)
+	if result.ExitCode == 0 {
+		t.Error("Expected an entry below a link to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "passwd")); err == nil {
+		t.Error("Entry was written through the link, outside the destination")
+	}
+}
+
+func TestParseArchiveRequest(t *testing.T) {
+	request, err := parseArchiveRequest("-o /tmp/a.zip /var/log/app /etc/app", "usage", 1)
+	if err != nil || !request.overwrite || request.archive != "/tmp/a.zip" || len(request.paths) != 2 {
+		t.Errorf("Unexpected request %+v (%v)", request, err)
+	}
+	for _, args := range []string{"", "/tmp/a.zip", "-x /tmp/a.zip /tmp", "/tmp/a.zip ../etc"} {
+		if _, err := parseArchiveRequest(args, "usage", 1); err == nil {
+			t.Errorf("Expected %q to be rejected", args)
+		}
+	}
+	if _, err := archiveFormat("/tmp/a.rar"); err == nil {
+		t.Error("Expected unsupported formats to be rejected")
+	}
+}
@@ -190,6 +190,26 @@ func validatePath(path string) error {
 	return nil
 }
 
+// validateFileRequest checks a file:get, file:copy, file:move or file:info payload as the command parses it
+func validateFileRequest(payload string, command FileCommandType) error {
+	request, err := parseFileRequest(payload)
+	if err != nil {
+		return fmt.Errorf("failed to parse request: %w", err)
+	}
+	if request.Command != command {
+		return fmt.Errorf("invalid command type: %s", request.Command)
+	}
+	if err := validatePath(request.Source); err != nil {
+		return fmt.Errorf("invalid source path: %w", err)
+	}
+	if request.Destination != "" {
+		if err := validatePath(request.Destination); err != nil {
+			return fmt.Errorf("invalid destination path: %w", err)
+		}
+	}
+	return nil
+}
+
 // getFileInfo retrieves detailed information about a file or directory
 func getFileInfo(path string) (*FileInfo, error) {
 	stat, err := os.Stat(path)
@@ -385,7 +405,7 @@ func NewFileGetCommand() *FileGetCommand {
 		"file",
 		"Retrieve file content or information from minion",
 		`{"command": "get", "source": "/path/to/file", "options": {"max_size": 1048576}}`,
-	).WithExamples(
+	).WithImpact(ImpactReadOnly).WithExamples(
 		Example{
 			Description: "Get a text file",
 			Command:     `command-send minion abc123 '{"command": "get", "source": "/etc/hosts"}'`,
@@ -403,7 +423,9 @@ func NewFileGetCommand() *FileGetCommand {
 	).WithNotes(
 		"Binary files are returned as base64-encoded content",
 		"Large files are automatically truncated with preview",
+		"Use the file-pull console command to transfer large files in full",
 		"Directory requests return metadata only",
+		"Reads count against the transfer_bandwidth and max_file_transfers settings of the minion",
 	)
 
 	return &FileGetCommand{
@@ -411,6 +433,11 @@ func NewFileGetCommand() *FileGetCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileGetCommand) ValidateArgs(payload string) error {
+	return validateFileRequest(payload, CmdGet)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *FileGetCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	funcName := "FileGetCommand.Execute"
@@ -466,7 +493,14 @@ func (c *FileGetCommand) Execute(ctx *ExecutionContext, payload string) (*pb.Com
 		maxSize = MaxPreviewSize
 	}
 
-	// Read file content
+	// Read file content, at the pace of the transfer throttle of the minion
+	if ctx.Transfers != nil {
+		end, err := ctx.Transfers.Begin()
+		if err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, err), nil
+		}
+		defer end()
+	}
 	file, err := os.Open(sourcePath)
 	if err != nil {
 		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to open file: %w", err)), nil
@@ -475,7 +509,7 @@ func (c *FileGetCommand) Execute(ctx *ExecutionContext, payload string) (*pb.Com
 
 	// Read content up to max size
 	content := make([]byte, maxSize)
-	n, err := io.ReadFull(file, content)
+	n, err := io.ReadFull(ThrottledReader(ctx.Context, file, ctx.Transfers), content)
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to read file: %w", err)), nil
 	}
@@ -509,7 +543,7 @@ func NewFileCopyCommand() *FileCopyCommand {
 		"file",
 		"Copy files or directories on the minion",
 		`{"command": "copy", "source": "/src/path", "destination": "/dst/path", "recursive": true, "options": {"overwrite": true}}`,
-	).WithExamples(
+	).WithDiskHeavy().WithExamples(
 		Example{
 			Description: "Copy a single file",
 			Command:     `command-send minion abc123 '{"command": "copy", "source": "/tmp/source.txt", "destination": "/tmp/backup.txt"}'`,
@@ -535,6 +569,20 @@ func NewFileCopyCommand() *FileCopyCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileCopyCommand) ValidateArgs(payload string) error {
+	return validateFileRequest(payload, CmdCopy)
+}
+
+// DiskTargets implements DiskTargeter interface
+func (c *FileCopyCommand) DiskTargets(payload string) []string {
+	request, err := parseFileRequest(payload)
+	if err != nil || request.Destination == "" {
+		return nil
+	}
+	return []string{request.Destination}
+}
+
 // Execute implements ExecutableCommand interface
 func (c *FileCopyCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	funcName := "FileCopyCommand.Execute"
@@ -637,6 +685,11 @@ func NewFileMoveCommand() *FileMoveCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileMoveCommand) ValidateArgs(payload string) error {
+	return validateFileRequest(payload, CmdMove)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *FileMoveCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	funcName := "FileMoveCommand.Execute"
@@ -747,7 +800,7 @@ func NewFileInfoCommand() *FileInfoCommand {
 		"file",
 		"Get detailed information about files or directories",
 		`{"command": "info", "source": "/path/to/file", "recursive": true}`,
-	).WithExamples(
+	).WithImpact(ImpactReadOnly).WithExamples(
 		Example{
 			Description: "Get file information",
 			Command:     `command-send minion abc123 '{"command": "info", "source": "/etc/passwd"}'`,
@@ -769,6 +822,11 @@ func NewFileInfoCommand() *FileInfoCommand {
 	}
 }
 
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileInfoCommand) ValidateArgs(payload string) error {
+	return validateFileRequest(payload, CmdInfo)
+}
+
 // Execute implements ExecutableCommand interface
 func (c *FileInfoCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	funcName := "FileInfoCommand.Execute"
@@ -16,3 +16,19 @@ func addUnixOwnerInfo(stat os.FileInfo, info *FileInfo) {
 		info.Group = strconv.Itoa(int(sys.Gid))
 	}
 }
+
+// copyOwner gives file the owner and group of existing, the file it replaces
+func copyOwner(file *os.File, existing os.FileInfo) error {
+	sys, ok := existing.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if current, ok := stat.Sys().(*syscall.Stat_t); ok && current.Uid == sys.Uid && current.Gid == sys.Gid {
+		return nil
+	}
+	return file.Chown(int(sys.Uid), int(sys.Gid))
+}
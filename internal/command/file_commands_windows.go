@@ -12,3 +12,8 @@ func addUnixOwnerInfo(stat os.FileInfo, info *FileInfo) {
 	// Windows doesn't have Unix-style ownership
 	// So we leave the Owner and Group fields empty
 }
+
+// copyOwner is a no-op on Windows, replaced files keep no Unix-style ownership
+func copyOwner(file *os.File, existing os.FileInfo) error {
+	return nil
+}
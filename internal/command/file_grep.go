@@ -0,0 +1,374 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeFileGrep is the content type of file:grep structured results
+const ContentTypeFileGrep = "application/vnd.minexus.file-grep+json"
+
+// Search limits of file:grep, bounding the work done and the size of the result
+const (
+	MaxGrepFileSize   = 10 * 1024 * 1024 // larger files are skipped
+	MaxGrepFiles      = 1000             // files searched at most
+	MaxGrepLineLength = 512              // longer lines are truncated in matches
+	DefaultGrepMatch  = 100
+	MaxGrepMatches    = 1000
+	MaxGrepContext    = 5
+)
+
+// GrepMatch is a line matching a file:grep pattern
+type GrepMatch struct {
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"` // context lines preceding the match
+	After  []string `json:"after,omitempty"`  // context lines following the match
+}
+
+// grepRequest holds the parsed arguments of file:grep
+type grepRequest struct {
+	pattern    *regexp.Regexp
+	path       string
+	context    int
+	maxMatches int
+}
+
+// grepSearch accumulates the matches of a search across files
+type grepSearch struct {
+	request   *grepRequest
+	matches   []GrepMatch
+	files     int
+	skipped   int
+	truncated bool
+}
+
+// FileGrepCommand searches the content of files with a regular expression
+type FileGrepCommand struct {
+	*BaseCommand
+}
+
+// NewFileGrepCommand creates a new file grep command
+func NewFileGrepCommand() *FileGrepCommand {
+	base := NewBaseCommand(
+		"file:grep",
+		"file",
+		"Search files for lines matching a regular expression",
+		"file:grep [-i] [-C <lines>] [-m <max>] <pattern> <path-glob>",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Find where a setting is defined",
+			Command:     `command-send all "file:grep ^PermitRootLogin /etc/ssh/sshd_config"`,
+			Expected:    "Returns the matching lines with their file and line number",
+		},
+		Example{
+			Description: "Look for leaked credentials in configuration files",
+			Command:     `command-send tag env=prod "file:grep -i -C 1 (password|secret)\s*= /etc/app/*.conf"`,
+			Expected:    "Returns the matches with one line of context",
+		},
+	).WithParameters(
+		Param{Name: "pattern", Type: "string", Required: true, Description: "Regular expression (Go syntax) matched against each line"},
+		Param{Name: "path-glob", Type: "string", Required: true, Description: "File, directory or glob pattern; directories are searched recursively"},
+		Param{Name: "-i", Type: "bool", Required: false, Description: "Case insensitive match", Default: "false"},
+		Param{Name: "-C", Type: "int", Required: false, Description: "Context lines around matches (max 5)", Default: "0"},
+		Param{Name: "-m", Type: "int", Required: false, Description: "Maximum number of matches (max 1000)", Default: "100"},
+	).WithNotes(
+		"The last argument is the path, the pattern may contain spaces; quote it to keep leading or trailing spaces",
+		"Binary files and files larger than 10MB are skipped, at most 1000 files are searched",
+		"Lines are matched in full, lines longer than 512 bytes being truncated in the reported matches",
+		"The result carries a structured JSON payload in addition to the text output",
+	)
+
+	return &FileGrepCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileGrepCommand) ValidateArgs(payload string) error {
+	request, err := parseGrepRequest(commandArgument(payload, "file:grep"))
+	if err != nil {
+		return err
+	}
+	if err := validatePath(request.path); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+	return nil
+}
+
+// Execute implements ExecutableCommand interface
+func (c *FileGrepCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	funcName := "FileGrepCommand.Execute"
+	logger, start := logging.FuncLogger(ctx.Logger, funcName)
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseGrepRequest(commandArgument(payload, "file:grep"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	if err := validatePath(request.path); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("invalid path: %w", err)), nil
+	}
+
+	paths, err := filepath.Glob(filepath.Clean(request.path))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("invalid path glob: %w", err)), nil
+	}
+	if len(paths) == 0 {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("no file matches %s", request.path)), nil
+	}
+
+	search := &grepSearch{request: request, matches: []GrepMatch{}}
+	for _, path := range paths {
+		if err := ctx.Context.Err(); err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, err), nil
+		}
+		if search.truncated {
+			break
+		}
+		search.searchPath(path)
+	}
+
+	var output strings.Builder
+	for _, match := range search.matches {
+		for i, line := range match.Before {
+			fmt.Fprintf(&output, "%s-%d-%s\n", match.File, match.Line-len(match.Before)+i, line)
+		}
+		fmt.Fprintf(&output, "%s:%d:%s\n", match.File, match.Line, match.Text)
+		for i, line := range match.After {
+			fmt.Fprintf(&output, "%s-%d-%s\n", match.File, match.Line+i+1, line)
+		}
+	}
+	fmt.Fprintf(&output, "%d matches in %d files searched", len(search.matches), search.files)
+	if search.skipped > 0 {
+		fmt.Fprintf(&output, ", %d skipped", search.skipped)
+	}
+	if search.truncated {
+		output.WriteString(" (limit reached, results truncated)")
+	}
+	output.WriteString("\n")
+
+	return c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeFileGrep, search.matches), nil
+}
+
+// parseGrepRequest parses the arguments of file:grep. A pattern in single or double quotes is taken
+// as is, so it may end with spaces or look like an option; otherwise the pattern is the text before
+// the last argument, the path, with its spacing kept.
+func parseGrepRequest(args string) (*grepRequest, error) {
+	request := &grepRequest{maxMatches: DefaultGrepMatch}
+	insensitive := false
+	rest := strings.TrimSpace(args)
+
+options:
+	for strings.HasPrefix(rest, "-") {
+		var flag string
+		flag, rest = nextGrepField(rest)
+		switch flag {
+		case "-i":
+			insensitive = true
+		case "-C", "-m":
+			var value string
+			value, rest = nextGrepField(rest)
+			if value == "" {
+				return nil, fmt.Errorf("%s requires a value", flag)
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s value '%s'", flag, value)
+			}
+			if flag == "-C" {
+				request.context = min(n, MaxGrepContext)
+			} else if n > 0 {
+				request.maxMatches = min(n, MaxGrepMatches)
+			}
+		case "--":
+			break options
+		default:
+			return nil, fmt.Errorf("unknown option %s", flag)
+		}
+	}
+
+	usage := fmt.Errorf("usage: file:grep [-i] [-C <lines>] [-m <max>] <pattern> <path-glob>")
+	var pattern string
+	if rest != "" && (rest[0] == '\'' || rest[0] == '"') {
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quoted pattern")
+		}
+		pattern = rest[1 : end+1]
+		request.path = strings.TrimSpace(rest[end+2:])
+		if request.path == "" || strings.ContainsAny(request.path, " \t") || end+2 < len(rest) && rest[end+2] != ' ' && rest[end+2] != '\t' {
+			return nil, usage
+		}
+	} else {
+		split := strings.LastIndexAny(rest, " \t")
+		if split < 0 {
+			return nil, usage
+		}
+		pattern = strings.TrimRight(rest[:split], " \t")
+		request.path = rest[split+1:]
+	}
+	if pattern == "" {
+		return nil, usage
+	}
+
+	if insensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	request.pattern = re
+	return request, nil
+}
+
+// nextGrepField splits the first whitespace separated field of args from the rest
+func nextGrepField(args string) (string, string) {
+	if i := strings.IndexAny(args, " \t"); i >= 0 {
+		return args[:i], strings.TrimLeft(args[i:], " \t")
+	}
+	return args, ""
+}
+
+// searchPath searches a file, or the files below a directory
+func (s *grepSearch) searchPath(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		s.skipped++
+		return
+	}
+	if !info.IsDir() {
+		s.searchFile(path, info)
+		return
+	}
+
+	_ = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			s.skipped++
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if s.truncated {
+			return fs.SkipAll
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			s.skipped++
+			return nil
+		}
+		s.searchFile(file, info)
+		return nil
+	})
+}
+
+// searchFile searches a regular file, skipping binary and oversized files
+func (s *grepSearch) searchFile(path string, info os.FileInfo) {
+	if !info.Mode().IsRegular() || info.Size() > MaxGrepFileSize {
+		s.skipped++
+		return
+	}
+	if s.files >= MaxGrepFiles {
+		s.truncated = true
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		s.skipped++
+		return
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(8000)
+	if bytes.IndexByte(head, 0) >= 0 {
+		s.skipped++
+		return
+	}
+	s.files++
+
+	// previous holds the context lines preceding the current line,
+	// pending the matches still collecting their following context
+	var previous []string
+	var pending []*GrepMatch
+	lineNumber := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			break
+		}
+		lineNumber++
+		// Matching runs on the whole line, only the reported text is truncated
+		full := strings.TrimRight(line, "\r\n")
+		line = truncateLine(full)
+
+		for i := 0; i < len(pending); i++ {
+			pending[i].After = append(pending[i].After, line)
+		}
+		pending = s.flush(pending, false)
+
+		if s.request.pattern.MatchString(full) {
+			if len(s.matches)+len(pending) >= s.request.maxMatches {
+				s.truncated = true
+				break
+			}
+			match := &GrepMatch{File: path, Line: lineNumber, Text: line}
+			if len(previous) > 0 {
+				match.Before = append([]string(nil), previous...)
+			}
+			pending = append(pending, match)
+			pending = s.flush(pending, false)
+		}
+
+		if s.request.context > 0 {
+			previous = append(previous, line)
+			if len(previous) > s.request.context {
+				previous = previous[1:]
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	s.flush(pending, true)
+}
+
+// flush moves the matches whose following context is complete to the results
+func (s *grepSearch) flush(pending []*GrepMatch, all bool) []*GrepMatch {
+	for len(pending) > 0 && (all || len(pending[0].After) >= s.request.context) {
+		s.matches = append(s.matches, *pending[0])
+		pending = pending[1:]
+	}
+	return pending
+}
+
+// truncateLine shortens lines longer than MaxGrepLineLength, without splitting a UTF-8 character
+func truncateLine(line string) string {
+	if len(line) <= MaxGrepLineLength {
+		return line
+	}
+	cut := MaxGrepLineLength
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
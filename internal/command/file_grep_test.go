@@ -0,0 +1,113 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParseGrepRequest(t *testing.T) {
+	request, err := parseGrepRequest(`-i -C 9 -m 5 api key\s*= /etc/app`)
+	if err != nil {
+		t.Fatalf("parseGrepRequest failed: %v", err)
+	}
+	if request.path != "/etc/app" || request.context != MaxGrepContext || request.maxMatches != 5 {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+	if !request.pattern.MatchString("API KEY = 123") {
+		t.Errorf("Expected case insensitive pattern with spaces, got %s", request.pattern)
+	}
+
+	// Quoted patterns are taken as is, spaces and leading dashes included
+	for args, expected := range map[string]string{
+		`"key = " /etc/app`:   "key = ",
+		`-- '-x  y' /etc/app`: "-x  y",
+		`a  b /etc/app`:       "a  b",
+		`-i "a\tb" /etc/app`:  `(?i)a\tb`,
+	} {
+		request, err := parseGrepRequest(args)
+		if err != nil || request.pattern.String() != expected || request.path != "/etc/app" {
+			t.Errorf("Expected pattern %q for %q, got %+v (%v)", expected, args, request, err)
+		}
+	}
+
+	for _, args := range []string{"", "pattern", "-C", "-m x foo /etc", "-x foo /etc", "( /etc", `"open /etc`, `"a"/etc`, `"a"`} {
+		if _, err := parseGrepRequest(args); err == nil {
+			t.Errorf("Expected error for %q", args)
+		}
+	}
+}
+
+func TestFileGrepCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.conf":        "host = db\npassword = secret\nport = 5432\n",
+		"sub/other.conf":  "# nothing\nPASSWORD=hunter2",
+		"binary.bin":      "password\x00",
+		"sub/ignored.txt": "password = x\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	cmd := NewFileGrepCommand()
+
+	result, err := cmd.Execute(ctx, "file:grep -i -C 1 password "+dir)
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute failed: %v %s", err, result.Stderr)
+	}
+	if result.ContentType != ContentTypeFileGrep {
+		t.Errorf("Expected content type %s, got %s", ContentTypeFileGrep, result.ContentType)
+	}
+	var matches []GrepMatch
+	if err := json.Unmarshal([]byte(result.Structured), &matches); err != nil {
+		t.Fatalf("Invalid structured payload: %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 matches (binary file skipped), got %+v", matches)
+	}
+	first := matches[0]
+	if first.Line != 2 || first.Text != "password = secret" ||
+		strings.Join(first.Before, "|") != "host = db" || strings.Join(first.After, "|") != "port = 5432" {
+		t.Errorf("Unexpected match with context: %+v", first)
+	}
+	if !strings.Contains(result.Stdout, ":2:password = secret") || !strings.Contains(result.Stdout, "3 matches in 3 files searched, 1 skipped") {
+		t.Errorf("Unexpected output: %s", result.Stdout)
+	}
+
+	result, _ = cmd.Execute(ctx, "file:grep -i -m 1 password "+dir)
+	if !strings.Contains(result.Stdout, "1 matches") || !strings.Contains(result.Stdout, "results truncated") {
+		t.Errorf("Expected truncated results, got: %s", result.Stdout)
+	}
+
+	// Matches past the truncation of long lines are found, the reported text being truncated
+	long := filepath.Join(dir, "long.log")
+	if err := os.WriteFile(long, []byte(strings.Repeat("é", MaxGrepLineLength)+" token=abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = cmd.Execute(ctx, "file:grep token= "+long)
+	matches = nil
+	if err := json.Unmarshal([]byte(result.Structured), &matches); err != nil || len(matches) != 1 {
+		t.Fatalf("Expected the long line matched, got %s (%v)", result.Structured, err)
+	}
+	if text := matches[0].Text; len(text) > MaxGrepLineLength+3 || !strings.HasSuffix(text, "é...") {
+		t.Errorf("Expected the long line truncated on a character boundary, got %q", text)
+	}
+
+	result, _ = cmd.Execute(ctx, "file:grep password "+filepath.Join(dir, "*.missing"))
+	if result.ExitCode == 0 {
+		t.Errorf("Expected error when no file matches the glob")
+	}
+}
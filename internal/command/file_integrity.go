@@ -0,0 +1,440 @@
+package command
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeFileIntegrity is the content type of file:hash and file:verify structured results
+const ContentTypeFileIntegrity = "application/vnd.minexus.file-integrity+json"
+
+// MaxHashFiles is the number of files hashed at most by file:hash and file:verify
+const MaxHashFiles = 10000
+
+// DefaultHashAlgorithm is the checksum algorithm of file:hash and file:verify without -a
+const DefaultHashAlgorithm = "sha256"
+
+// Drift statuses of the files checked by file:verify
+const (
+	DriftModified   = "modified"   // the checksum differs from the manifest
+	DriftMissing    = "missing"    // the file of the manifest does not exist
+	DriftUnreadable = "unreadable" // the file of the manifest cannot be read
+	DriftAdded      = "added"      // a file of a watched directory is not in the manifest
+)
+
+// hashAlgorithms creates the hash of each supported checksum algorithm
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// FileHash is the checksum of a file
+type FileHash struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// FileHashResult lists the checksums computed by file:hash, sorted by path
+type FileHashResult struct {
+	Algorithm string     `json:"algorithm"`
+	Files     []FileHash `json:"files"`
+	Skipped   []string   `json:"skipped,omitempty"` // links, devices, unreadable files
+}
+
+// FileVerifyRequest represents a file:verify payload, the manifest of the expected checksums
+type FileVerifyRequest struct {
+	Algorithm string            `json:"algorithm,omitempty"` // empty: sha256
+	Files     map[string]string `json:"files"`               // expected checksum by path
+	Dirs      []string          `json:"dirs,omitempty"`      // directories whose files must all be in the manifest
+}
+
+// FileDrift is a file differing from the manifest
+type FileDrift struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// FileVerifyResult is the drift report of file:verify, drifts sorted by path
+type FileVerifyResult struct {
+	Algorithm string      `json:"algorithm"`
+	Checked   int         `json:"checked"` // files of the manifest
+	Matched   int         `json:"matched"`
+	Drift     []FileDrift `json:"drift"`
+}
+
+// contextReader stops reading once its context is done, interrupting the hash of large files
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// hashFile computes the checksum of a file with algorithm, which must be supported
+func hashFile(ctx context.Context, path, algorithm string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	h := hashAlgorithms[algorithm]()
+	size, err := io.Copy(h, &contextReader{ctx: ctx, reader: file})
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// checkHashAlgorithm normalizes a checksum algorithm name, empty for the default
+func checkHashAlgorithm(algorithm string) (string, error) {
+	if algorithm == "" {
+		return DefaultHashAlgorithm, nil
+	}
+	algorithm = strings.ToLower(algorithm)
+	if _, ok := hashAlgorithms[algorithm]; !ok {
+		return "", fmt.Errorf("unsupported algorithm %s, expected sha256, sha512, sha1 or md5", algorithm)
+	}
+	return algorithm, nil
+}
+
+// walkRegularFiles calls fn for the regular files below root, reporting the other entries to skip
+func walkRegularFiles(ctx context.Context, root string, fn func(path string) error, skip func(path string)) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, walkErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if walkErr != nil {
+			skip(path)
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			skip(path)
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// FileHashCommand computes the checksums of files on the minion
+type FileHashCommand struct {
+	*BaseCommand
+}
+
+// NewFileHashCommand creates a new file hash command
+func NewFileHashCommand() *FileHashCommand {
+	base := NewBaseCommand(
+		"file:hash",
+		"file",
+		"Compute the checksums of files and directories",
+		"file:hash [-a sha256|sha512|sha1|md5] <path-glob>...",
+	).WithImpact(ImpactReadOnly).WithVersion(3).WithExamples(
+		Example{
+			Description: "Record the checksums of a configuration directory",
+			Command:     `command-send minion web-01 "file:hash /etc/nginx"`,
+			Expected:    "One '<checksum>  <path>' line per file, usable as a file:verify manifest",
+		},
+	).WithParameters(
+		Param{Name: "path-glob", Type: "string", Required: true, Description: "Files, directories or glob patterns to hash; directories are hashed recursively"},
+		Param{Name: "-a", Type: "string", Required: false, Description: "Checksum algorithm: sha256, sha512, sha1 or md5", Default: DefaultHashAlgorithm},
+	).WithNotes(
+		"The output has the format of sha256sum, paths being those of the minion",
+		"Symbolic links, devices and unreadable files are skipped and reported",
+		"At most 10000 files are hashed",
+		"The result carries a structured JSON payload in addition to the text output",
+	)
+
+	return &FileHashCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileHashCommand) ValidateArgs(payload string) error {
+	_, _, err := parseFileHashArgs(commandArgument(payload, "file:hash"))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *FileHashCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	funcName := "FileHashCommand.Execute"
+	logger, start := logging.FuncLogger(ctx.Logger, funcName)
+	defer logging.FuncExit(logger, start)
+
+	algorithm, patterns, err := parseFileHashArgs(commandArgument(payload, "file:hash"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	result := &FileHashResult{Algorithm: algorithm, Files: []FileHash{}}
+	seen := make(map[string]bool)
+	hashOne := func(path string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		if len(result.Files) >= MaxHashFiles {
+			return fmt.Errorf("more than %d files to hash", MaxHashFiles)
+		}
+		sum, size, err := hashFile(ctx.Context, path, algorithm)
+		if err != nil {
+			if ctxErr := ctx.Context.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			result.Skipped = append(result.Skipped, path)
+			return nil
+		}
+		result.Files = append(result.Files, FileHash{Path: path, Hash: sum, Size: size})
+		return nil
+	}
+	skip := func(path string) {
+		result.Skipped = append(result.Skipped, path)
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Clean(pattern))
+		if err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("invalid path glob: %w", err)), nil
+		}
+		if len(matches) == 0 {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("no file matches %s", pattern)), nil
+		}
+		for _, match := range matches {
+			if err := walkRegularFiles(ctx.Context, match, hashOne, skip); err != nil {
+				return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to hash files: %w", err)), nil
+			}
+		}
+	}
+
+	sort.Slice(result.Files, func(i, j int) bool { return result.Files[i].Path < result.Files[j].Path })
+	var output strings.Builder
+	for _, file := range result.Files {
+		fmt.Fprintf(&output, "%s  %s\n", file.Hash, file.Path)
+	}
+	if len(result.Skipped) > 0 {
+		fmt.Fprintf(&output, "Skipped %d entries: %s\n", len(result.Skipped), strings.Join(result.Skipped, ", "))
+	}
+	return c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeFileIntegrity, result), nil
+}
+
+// parseFileHashArgs parses the arguments of file:hash
+func parseFileHashArgs(args string) (string, []string, error) {
+	usage := fmt.Errorf("usage: file:hash [-a sha256|sha512|sha1|md5] <path-glob>...")
+	fields := strings.Fields(args)
+	algorithm := ""
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		switch fields[0] {
+		case "-a":
+			if len(fields) < 2 {
+				return "", nil, usage
+			}
+			algorithm = fields[1]
+			fields = fields[2:]
+		default:
+			return "", nil, fmt.Errorf("unknown option %s", fields[0])
+		}
+	}
+	if len(fields) == 0 {
+		return "", nil, usage
+	}
+
+	algorithm, err := checkHashAlgorithm(algorithm)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, path := range fields {
+		if err := validatePath(path); err != nil {
+			return "", nil, fmt.Errorf("invalid path %s: %w", path, err)
+		}
+	}
+	return algorithm, fields, nil
+}
+
+// FileVerifyCommand checks files on the minion against a manifest of checksums
+type FileVerifyCommand struct {
+	*BaseCommand
+}
+
+// NewFileVerifyCommand creates a new file verify command
+func NewFileVerifyCommand() *FileVerifyCommand {
+	base := NewBaseCommand(
+		"file:verify",
+		"file",
+		"Verify files against a manifest of checksums and report drift",
+		`file:verify {"algorithm": "sha256", "files": {"<path>": "<checksum>"}, "dirs": ["<dir>"]}`,
+	).WithImpact(ImpactReadOnly).WithVersion(3).WithExamples(
+		Example{
+			Description: "Detect changes to the nginx configuration, the manifest being read by the console",
+			Command:     `command-send tag role=web "file:verify nginx.sha256 /etc/nginx"`,
+			Expected:    "Lists modified, missing and added files, failing when any file drifted",
+		},
+	).WithParameters(
+		Param{Name: "files", Type: "object", Required: true, Description: "Expected checksum of each file"},
+		Param{Name: "algorithm", Type: "string", Required: false, Description: "Checksum algorithm: sha256, sha512, sha1 or md5", Default: DefaultHashAlgorithm},
+		Param{Name: "dirs", Type: "array", Required: false, Description: "Directories whose files missing from the manifest are reported as added"},
+	).WithNotes(
+		"The console builds the JSON payload from a local manifest, in the format of sha256sum or of the file:hash JSON payload",
+		"The command fails with exit code 1 when a file is modified, missing, unreadable or added",
+		"At most 10000 files are checked",
+		"The result carries the drift report as a structured JSON payload",
+	)
+
+	return &FileVerifyCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *FileVerifyCommand) ValidateArgs(payload string) error {
+	if _, err := parseFileVerifyRequest(payload); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return nil
+}
+
+// Execute implements ExecutableCommand interface
+func (c *FileVerifyCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	funcName := "FileVerifyCommand.Execute"
+	logger, start := logging.FuncLogger(ctx.Logger, funcName)
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseFileVerifyRequest(payload)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to parse manifest: %w", err)), nil
+	}
+
+	result, err := verifyFiles(ctx.Context, request)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to verify files: %w", err)), nil
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Verified %d files (%s): %d matched, %d drifted\n", result.Checked, result.Algorithm, result.Matched, len(result.Drift))
+	for _, drift := range result.Drift {
+		fmt.Fprintf(&output, "%-10s  %s\n", strings.ToUpper(drift.Status), drift.Path)
+	}
+
+	commandResult := c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeFileIntegrity, result)
+	if len(result.Drift) > 0 {
+		commandResult.ExitCode = 1
+		commandResult.Stderr = fmt.Sprintf("%d files drifted from the manifest", len(result.Drift))
+	}
+	return commandResult, nil
+}
+
+// parseFileVerifyRequest extracts the manifest from a file:verify payload
+func parseFileVerifyRequest(payload string) (*FileVerifyRequest, error) {
+	body := commandArgument(payload, "file:verify")
+	if body == "" {
+		return nil, fmt.Errorf("missing manifest")
+	}
+
+	var request FileVerifyRequest
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return nil, fmt.Errorf("invalid JSON manifest: %w", err)
+	}
+	if len(request.Files) == 0 {
+		return nil, fmt.Errorf("the manifest lists no file")
+	}
+	if len(request.Files) > MaxHashFiles {
+		return nil, fmt.Errorf("the manifest lists more than %d files", MaxHashFiles)
+	}
+
+	algorithm, err := checkHashAlgorithm(request.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	request.Algorithm = algorithm
+	for path := range request.Files {
+		if err := validatePath(path); err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", path, err)
+		}
+	}
+	for _, dir := range request.Dirs {
+		if err := validatePath(dir); err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", dir, err)
+		}
+	}
+	return &request, nil
+}
+
+// verifyFiles compares the files of the manifest with their expected checksums, then looks for
+// files of the watched directories the manifest does not list
+func verifyFiles(ctx context.Context, request *FileVerifyRequest) (*FileVerifyResult, error) {
+	result := &FileVerifyResult{Algorithm: request.Algorithm, Checked: len(request.Files), Drift: []FileDrift{}}
+	expected := make(map[string]bool, len(request.Files))
+
+	for path, want := range request.Files {
+		expected[filepath.Clean(path)] = true
+		sum, _, err := hashFile(ctx, path, request.Algorithm)
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case os.IsNotExist(err):
+			result.Drift = append(result.Drift, FileDrift{Path: path, Status: DriftMissing, Expected: want})
+		case err != nil:
+			result.Drift = append(result.Drift, FileDrift{Path: path, Status: DriftUnreadable, Expected: want})
+		case !strings.EqualFold(sum, want):
+			result.Drift = append(result.Drift, FileDrift{Path: path, Status: DriftModified, Expected: want, Actual: sum})
+		default:
+			result.Matched++
+		}
+	}
+
+	walked := 0
+	for _, dir := range request.Dirs {
+		err := walkRegularFiles(ctx, filepath.Clean(dir), func(path string) error {
+			if walked++; walked > MaxHashFiles {
+				return fmt.Errorf("more than %d files in the watched directories", MaxHashFiles)
+			}
+			if expected[path] {
+				return nil
+			}
+			expected[path] = true // directories may overlap
+			sum, _, err := hashFile(ctx, path, request.Algorithm)
+			if err != nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			result.Drift = append(result.Drift, FileDrift{Path: path, Status: DriftAdded, Actual: sum})
+			return nil
+		}, func(string) {})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(result.Drift, func(i, j int) bool { return result.Drift[i].Path < result.Drift[j].Path })
+	return result, nil
+}
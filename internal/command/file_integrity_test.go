@@ -0,0 +1,126 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFileHashAndVerify(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "conf")
+	if err := os.MkdirAll(filepath.Join(conf, "sites"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"conf/main.conf":          "worker_processes 4;\n",
+		"conf/sites/default.conf": "listen 80;\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(conf, "passwd")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewFileHashCommand().Execute(ctx, "file:hash "+conf)
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Expected the files to be hashed, got %v %v", result, err)
+	}
+	var hashed FileHashResult
+	if err := json.Unmarshal([]byte(result.Structured), &hashed); err != nil {
+		t.Fatalf("Invalid structured result: %v", err)
+	}
+	if hashed.Algorithm != "sha256" || len(hashed.Files) != 2 || len(hashed.Skipped) != 1 {
+		t.Fatalf("Expected 2 files hashed and the link skipped, got %+v", hashed)
+	}
+	mainConf := filepath.Join(conf, "main.conf")
+	if !strings.Contains(result.Stdout, hashed.Files[0].Hash+"  "+mainConf+"\n") {
+		t.Errorf("Expected sha256sum formatted output, got %s", result.Stdout)
+	}
+
+	// An unchanged tree matches its manifest
+	manifest := FileVerifyRequest{Files: map[string]string{}, Dirs: []string{conf}}
+	for _, file := range hashed.Files {
+		manifest.Files[file.Path] = file.Hash
+	}
+	verify := func() (*FileVerifyResult, int32) {
+		t.Helper()
+		payload, _ := json.Marshal(manifest)
+		result, err := NewFileVerifyCommand().Execute(ctx, "file:verify "+string(payload))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report FileVerifyResult
+		if err := json.Unmarshal([]byte(result.Structured), &report); err != nil {
+			t.Fatalf("Invalid structured result %q: %v", result.Stderr, err)
+		}
+		return &report, result.ExitCode
+	}
+	if report, exitCode := verify(); exitCode != 0 || report.Matched != 2 || len(report.Drift) != 0 {
+		t.Errorf("Expected no drift, got %+v (exit code %d)", report, exitCode)
+	}
+
+	// Modified, removed and added files drift
+	if err := os.WriteFile(mainConf, []byte("worker_processes 64;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(conf, "sites", "default.conf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(conf, "sites", "backdoor.conf"), []byte("listen 4444;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, exitCode := verify()
+	if exitCode != 1 || report.Matched != 0 || len(report.Drift) != 3 {
+		t.Fatalf("Expected 3 drifted files, got %+v (exit code %d)", report, exitCode)
+	}
+	expected := map[string]string{
+		mainConf: DriftModified,
+		filepath.Join(conf, "sites", "backdoor.conf"): DriftAdded,
+		filepath.Join(conf, "sites", "default.conf"):  DriftMissing,
+	}
+	for _, drift := range report.Drift {
+		if expected[drift.Path] != drift.Status {
+			t.Errorf("Expected %s to be %s, got %s", drift.Path, expected[drift.Path], drift.Status)
+		}
+	}
+}
+
+func TestFileIntegrityArguments(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+
+	tests := []struct {
+		payload  string
+		expected string
+	}{
+		{"file:hash", "usage: file:hash"},
+		{"file:hash -a crc32 /etc/hosts", "unsupported algorithm"},
+		{"file:hash ../etc/passwd", "path traversal"},
+		{"file:verify", "missing manifest"},
+		{"file:verify /etc/hosts", "invalid JSON manifest"},
+		{`file:verify {"files": {}}`, "lists no file"},
+		{`file:verify {"algorithm": "crc32", "files": {"/etc/hosts": "00"}}`, "unsupported algorithm"},
+	}
+	for _, tt := range tests {
+		var err error
+		var stderr string
+		if strings.HasPrefix(tt.payload, "file:hash") {
+			result, execErr := NewFileHashCommand().Execute(ctx, tt.payload)
+			err, stderr = execErr, result.Stderr
+		} else {
+			result, execErr := NewFileVerifyCommand().Execute(ctx, tt.payload)
+			err, stderr = execErr, result.Stderr
+		}
+		if err != nil || !strings.Contains(stderr, tt.expected) {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.payload, tt.expected, stderr, err)
+		}
+	}
+}
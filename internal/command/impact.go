@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Impact classes of commands, from the least to the most dangerous
+const (
+	ImpactReadOnly   = "read-only"  // inspects the host without changing it
+	ImpactMutating   = "mutating"   // changes files, settings or services of the host
+	ImpactDisruptive = "disruptive" // interrupts services or the host itself
+)
+
+// ImpactClasses lists the impact classes, from the least to the most dangerous
+var ImpactClasses = []string{ImpactReadOnly, ImpactMutating, ImpactDisruptive}
+
+// ImpactRank returns the rank of an impact class, higher for more dangerous classes, 0 when unknown
+func ImpactRank(impact string) int {
+	for i, class := range ImpactClasses {
+		if class == impact {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ValidateImpact checks that impact is an impact class, empty being accepted as unset
+func ValidateImpact(impact string) error {
+	if impact != "" && ImpactRank(impact) == 0 {
+		return fmt.Errorf("invalid impact %q, expected %s", impact, strings.Join(ImpactClasses, ", "))
+	}
+	return nil
+}
+
+// MaxImpact returns the most dangerous of two impact classes
+func MaxImpact(a, b string) string {
+	if ImpactRank(b) > ImpactRank(a) {
+		return b
+	}
+	return a
+}
+
+// Impact returns the impact class of the command a payload names, empty for shell commands and
+// JSON shell requests, whose impact depends on what they run
+func (r *Registry) Impact(payload string) string {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 || !strings.Contains(fields[0], ":") {
+		return ""
+	}
+	cmd, exists := r.GetCommand(fields[0])
+	if !exists {
+		return ""
+	}
+	return cmd.Metadata().Impact
+}
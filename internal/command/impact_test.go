@@ -0,0 +1,29 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImpact(t *testing.T) {
+	if MaxImpact(ImpactReadOnly, ImpactDisruptive) != ImpactDisruptive || MaxImpact(ImpactMutating, "") != ImpactMutating || MaxImpact("", ImpactReadOnly) != ImpactReadOnly {
+		t.Error("Expected MaxImpact to return the most dangerous class")
+	}
+	if ValidateImpact("") != nil || ValidateImpact(ImpactReadOnly) != nil || ValidateImpact("harmless") == nil {
+		t.Error("Unexpected impact validation")
+	}
+
+	registry := SetupCommands(time.Second)
+	for payload, expected := range map[string]string{
+		"system:info":                    ImpactReadOnly,
+		"file:copy /etc/hosts /tmp":      ImpactMutating,
+		"power:reboot 5m kernel upgrade": ImpactDisruptive,
+		"docker-compose:down /opt/app":   ImpactDisruptive,
+		"uptime":                         "",
+		"unknown:command":                "",
+	} {
+		if impact := registry.Impact(payload); impact != expected {
+			t.Errorf("%q: expected impact %q, got %q", payload, expected, impact)
+		}
+	}
+}
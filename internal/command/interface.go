@@ -15,6 +15,9 @@ type ExecutionContext struct {
 	MinionID    string
 	CommandID   string
 	Timestamp   int64
+	Env         map[string]string // environment Nexus sets for the shell commands of the minion
+	Transfers   TransferThrottle  // caps the file transfers of the minion, nil: unlimited
+	SpeedTest   SpeedTester       // measures the channel between the minion and Nexus, nil: unavailable
 }
 
 // NewExecutionContext creates a new execution context
@@ -35,6 +38,9 @@ type Definition struct {
 	Category    string    `json:"category"`
 	Description string    `json:"description"`
 	Usage       string    `json:"usage"`
+	Version     int       `json:"version"`              // handler version, raised when the command gains options older minions ignore
+	Impact      string    `json:"impact"`               // impact class: read-only, mutating or disruptive
+	DiskHeavy   bool      `json:"disk_heavy,omitempty"` // refused by the minion when its disk is short of space
 	Examples    []Example `json:"examples,omitempty"`
 	Parameters  []Param   `json:"parameters,omitempty"`
 	Notes       []string  `json:"notes,omitempty"`
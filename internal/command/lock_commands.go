@@ -0,0 +1,102 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// lockNamePattern restricts host lock names
+var lockNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateLockName checks that name can be used as a host lock name
+func ValidateLockName(name string) error {
+	if !lockNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid lock name '%s': use letters, digits, '.', '-' and '_'", name)
+	}
+	return nil
+}
+
+// ParseLockCommand parses a lock:acquire or lock:release payload, returning its action
+// ("acquire" or "release") and lock name. ok is false for other payloads.
+func ParseLockCommand(payload string) (action, name string, ok bool, err error) {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return "", "", false, nil
+	}
+	action, found := strings.CutPrefix(fields[0], "lock:")
+	if !found {
+		return "", "", false, nil
+	}
+	if action != "acquire" && action != "release" {
+		return "", "", true, fmt.Errorf("unknown lock command lock:%s: use lock:acquire or lock:release", action)
+	}
+	if len(fields) != 2 {
+		return "", "", true, fmt.Errorf("usage: lock:%s <name>", action)
+	}
+	if err := ValidateLockName(fields[1]); err != nil {
+		return "", "", true, err
+	}
+	return action, fields[1], true, nil
+}
+
+// LockCommand takes or releases a named lock on hosts. Locks are coordinated by Nexus, which
+// answers these commands itself: the command is registered on minions for validation and help only.
+type LockCommand struct {
+	*BaseCommand
+}
+
+// NewLockAcquireCommand creates a new lock:acquire command
+func NewLockAcquireCommand() *LockCommand {
+	base := NewBaseCommand(
+		"lock:acquire",
+		"lock",
+		"Take a named lock on the target minions, coordinated by Nexus",
+		"lock:acquire <name>",
+	).WithExamples(
+		Example{
+			Description: "Keep deployments off the web servers during a manual intervention",
+			Command:     "command-send tag role=web lock:acquire deploy",
+			Expected:    "Fails on the minions where the lock is already held",
+		},
+	).WithParameters(
+		Param{Name: "name", Type: "string", Required: true, Description: "Lock name, letters, digits, '.', '-' and '_'"},
+	).WithNotes(
+		"Commands sent with command-send --lock <name> wait on each minion until the lock is free",
+		"The lock is held until lock:release, locks are kept in Nexus memory and lost on restart",
+	)
+	return &LockCommand{BaseCommand: base}
+}
+
+// NewLockReleaseCommand creates a new lock:release command
+func NewLockReleaseCommand() *LockCommand {
+	base := NewBaseCommand(
+		"lock:release",
+		"lock",
+		"Release a named lock on the target minions, whoever holds it",
+		"lock:release <name>",
+	).WithExamples(
+		Example{
+			Description: "Let deployments run again on the web servers",
+			Command:     "command-send tag role=web lock:release deploy",
+		},
+	).WithParameters(
+		Param{Name: "name", Type: "string", Required: true, Description: "Lock name"},
+	).WithNotes(
+		"The next command waiting for the lock on each minion is dispatched",
+	)
+	return &LockCommand{BaseCommand: base}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *LockCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s is handled by Nexus and can't run on a minion", c.Metadata().Name)), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *LockCommand) ValidateArgs(payload string) error {
+	_, _, _, err := ParseLockCommand(payload)
+	return err
+}
@@ -0,0 +1,813 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// Content types of the net command structured results
+const (
+	ContentTypeNetPing       = "application/vnd.minexus.net-ping+json"
+	ContentTypeNetTraceroute = "application/vnd.minexus.net-traceroute+json"
+	ContentTypeNetPortCheck  = "application/vnd.minexus.net-port-check+json"
+	ContentTypeNetDNS        = "application/vnd.minexus.net-dns+json"
+)
+
+// Limits of the net commands, bounding the time they run
+const (
+	DefaultPingCount      = 4
+	MaxPingCount          = 20
+	DefaultTracerouteHops = 30
+	MaxTracerouteHops     = 64
+	DefaultPortTimeout    = 3 // seconds
+	MaxPortTimeout        = 30
+	MaxPortChecks         = 100
+	portCheckWorkers      = 10
+	dnsTimeout            = 10 * time.Second
+)
+
+// PingResult summarizes the replies to net:ping
+type PingResult struct {
+	Host        string  `json:"host"`
+	Transmitted int     `json:"transmitted"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	MinMs       float64 `json:"min_ms"`
+	AvgMs       float64 `json:"avg_ms"`
+	MaxMs       float64 `json:"max_ms"`
+}
+
+// TracerouteHop is a hop of net:traceroute, Address being empty when the hop did not answer
+type TracerouteHop struct {
+	Hop     int     `json:"hop"`
+	Address string  `json:"address"`
+	RTTMs   float64 `json:"rtt_ms"`
+}
+
+// PortCheck is the reachability of a port checked by net:port-check
+type PortCheck struct {
+	Host      string  `json:"host"`
+	Port      int     `json:"port"`
+	Protocol  string  `json:"protocol"`
+	State     string  `json:"state"` // open, closed, filtered or open|filtered (UDP without answer)
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// DNSRecord is a record resolved by net:dns
+type DNSRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// hostPattern matches host names and IP addresses, rejecting values that tools would take for options
+var hostPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.:%-]*$`)
+
+// validateHost checks a host given to the net commands
+func validateHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if len(host) > 253 || !hostPattern.MatchString(host) {
+		return fmt.Errorf("invalid host '%s'", host)
+	}
+	return nil
+}
+
+// parseNetOption parses the integer value of a net command option, bounded to [1, max]
+func parseNetOption(flag string, fields []string, max int) (int, []string, error) {
+	if len(fields) == 0 {
+		return 0, nil, fmt.Errorf("%s requires a value", flag)
+	}
+	value, err := strconv.Atoi(fields[0])
+	if err != nil || value < 1 {
+		return 0, nil, fmt.Errorf("invalid %s value '%s'", flag, fields[0])
+	}
+	return min(value, max), fields[1:], nil
+}
+
+// PingCommand sends ICMP echo requests from the minion
+type PingCommand struct {
+	*BaseCommand
+}
+
+// pingRequest holds the parsed arguments of net:ping
+type pingRequest struct {
+	host  string
+	count int
+}
+
+// NewPingCommand creates a new ping command
+func NewPingCommand() *PingCommand {
+	base := NewBaseCommand(
+		"net:ping",
+		"network",
+		"Ping a host from the minion",
+		"net:ping [-c <count>] <host>",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Check that the database answers from the web servers",
+			Command:     "command-send tag role=web net:ping db.internal",
+			Expected:    "Returns packet loss and round-trip times seen by each minion",
+		},
+	).WithParameters(
+		Param{Name: "host", Type: "string", Required: true, Description: "Host name or IP address"},
+		Param{Name: "-c", Type: "int", Required: false, Description: "Echo requests sent (max 20)", Default: "4"},
+	).WithNotes(
+		"Uses the ping tool of the minion host",
+		"The command fails when no reply is received",
+		"The result carries a structured JSON payload in addition to the ping output",
+	)
+
+	return &PingCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *PingCommand) ValidateArgs(payload string) error {
+	_, err := parsePingRequest(commandArgument(payload, "net:ping"))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PingCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "PingCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parsePingRequest(commandArgument(payload, "net:ping"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx.Context, time.Duration(request.count)*2*time.Second+5*time.Second)
+	defer cancel()
+	args := []string{"-c", strconv.Itoa(request.count), request.host}
+	if runtime.GOOS == "windows" {
+		args = []string{"-n", strconv.Itoa(request.count), request.host}
+	}
+	// ping exits with a non-zero code when replies are lost, its summary tells what happened
+	out, runErr := exec.CommandContext(pingCtx, "ping", args...).CombinedOutput()
+
+	result, err := parsePingOutput(string(out))
+	if err != nil {
+		if runErr != nil {
+			err = fmt.Errorf("ping failed: %w: %s", runErr, strings.TrimSpace(string(out)))
+		}
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	result.Host = request.host
+
+	commandResult := c.BaseCommand.CreateStructuredResult(ctx, string(out), ContentTypeNetPing, result)
+	if result.Received == 0 {
+		commandResult.ExitCode = 1
+		commandResult.Stderr = fmt.Sprintf("no reply from %s", request.host)
+	}
+	return commandResult, nil
+}
+
+// parsePingRequest parses the arguments of net:ping
+func parsePingRequest(args string) (*pingRequest, error) {
+	request := &pingRequest{count: DefaultPingCount}
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		flag := fields[0]
+		if flag != "-c" {
+			return nil, fmt.Errorf("unknown option %s", flag)
+		}
+		var err error
+		if request.count, fields, err = parseNetOption(flag, fields[1:], MaxPingCount); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("usage: net:ping [-c <count>] <host>")
+	}
+	if err := validateHost(fields[0]); err != nil {
+		return nil, err
+	}
+	request.host = fields[0]
+	return request, nil
+}
+
+var (
+	// Summaries of the Linux, BSD and busybox ping tools
+	pingUnixStats = regexp.MustCompile(`(\d+) packets transmitted, (\d+) (?:packets )?received`)
+	pingUnixRTT   = regexp.MustCompile(`min/avg/max\S* = ([\d.]+)/([\d.]+)/([\d.]+)`)
+	// Summaries of the Windows ping tool
+	pingWindowsStats = regexp.MustCompile(`Sent = (\d+), Received = (\d+)`)
+	pingWindowsRTT   = regexp.MustCompile(`Minimum = (\d+)ms, Maximum = (\d+)ms, Average = (\d+)ms`)
+)
+
+// parsePingOutput extracts the summary of a ping output
+func parsePingOutput(output string) (*PingResult, error) {
+	result := &PingResult{}
+	if match := pingUnixStats.FindStringSubmatch(output); match != nil {
+		result.Transmitted, _ = strconv.Atoi(match[1])
+		result.Received, _ = strconv.Atoi(match[2])
+		if rtt := pingUnixRTT.FindStringSubmatch(output); rtt != nil {
+			result.MinMs, _ = strconv.ParseFloat(rtt[1], 64)
+			result.AvgMs, _ = strconv.ParseFloat(rtt[2], 64)
+			result.MaxMs, _ = strconv.ParseFloat(rtt[3], 64)
+		}
+	} else if match := pingWindowsStats.FindStringSubmatch(output); match != nil {
+		result.Transmitted, _ = strconv.Atoi(match[1])
+		result.Received, _ = strconv.Atoi(match[2])
+		if rtt := pingWindowsRTT.FindStringSubmatch(output); rtt != nil {
+			result.MinMs, _ = strconv.ParseFloat(rtt[1], 64)
+			result.MaxMs, _ = strconv.ParseFloat(rtt[2], 64)
+			result.AvgMs, _ = strconv.ParseFloat(rtt[3], 64)
+		}
+	} else {
+		return nil, fmt.Errorf("unexpected ping output: %s", strings.TrimSpace(output))
+	}
+
+	if result.Transmitted > 0 {
+		result.LossPercent = float64(result.Transmitted-result.Received) * 100 / float64(result.Transmitted)
+	}
+	return result, nil
+}
+
+// TracerouteCommand traces the route from the minion to a host
+type TracerouteCommand struct {
+	*BaseCommand
+}
+
+// tracerouteRequest holds the parsed arguments of net:traceroute
+type tracerouteRequest struct {
+	host    string
+	maxHops int
+}
+
+// NewTracerouteCommand creates a new traceroute command
+func NewTracerouteCommand() *TracerouteCommand {
+	base := NewBaseCommand(
+		"net:traceroute",
+		"network",
+		"Trace the route from the minion to a host",
+		"net:traceroute [-m <max-hops>] <host>",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Find where packets to the API are lost",
+			Command:     "command-send minion abc123 net:traceroute api.example.com",
+			Expected:    "Returns each hop with its address and round-trip time",
+		},
+	).WithParameters(
+		Param{Name: "host", Type: "string", Required: true, Description: "Host name or IP address"},
+		Param{Name: "-m", Type: "int", Required: false, Description: "Maximum number of hops (max 64)", Default: "30"},
+	).WithNotes(
+		"Uses traceroute on Unix systems and tracert on Windows, one probe per hop",
+		"Hops that don't answer are reported without address",
+		"The result carries a structured JSON payload in addition to the tool output",
+	)
+
+	return &TracerouteCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *TracerouteCommand) ValidateArgs(payload string) error {
+	_, err := parseTracerouteRequest(commandArgument(payload, "net:traceroute"))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *TracerouteCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "TracerouteCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseTracerouteRequest(commandArgument(payload, "net:traceroute"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	tool := "traceroute"
+	args := []string{"-n", "-q", "1", "-w", "2", "-m", strconv.Itoa(request.maxHops), request.host}
+	if runtime.GOOS == "windows" {
+		tool = "tracert"
+		args = []string{"-d", "-w", "2000", "-h", strconv.Itoa(request.maxHops), request.host}
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s is not installed on this minion", tool)), nil
+	}
+
+	traceCtx, cancel := context.WithTimeout(ctx.Context, time.Duration(request.maxHops)*3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(traceCtx, tool, args...).CombinedOutput()
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s failed: %w: %s", tool, err, strings.TrimSpace(string(out)))), nil
+	}
+
+	return c.BaseCommand.CreateStructuredResult(ctx, string(out), ContentTypeNetTraceroute, parseTracerouteOutput(string(out))), nil
+}
+
+// parseTracerouteRequest parses the arguments of net:traceroute
+func parseTracerouteRequest(args string) (*tracerouteRequest, error) {
+	request := &tracerouteRequest{maxHops: DefaultTracerouteHops}
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		flag := fields[0]
+		if flag != "-m" {
+			return nil, fmt.Errorf("unknown option %s", flag)
+		}
+		var err error
+		if request.maxHops, fields, err = parseNetOption(flag, fields[1:], MaxTracerouteHops); err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("usage: net:traceroute [-m <max-hops>] <host>")
+	}
+	if err := validateHost(fields[0]); err != nil {
+		return nil, err
+	}
+	request.host = fields[0]
+	return request, nil
+}
+
+// parseTracerouteOutput extracts the hops of a numeric traceroute or tracert output: lines starting
+// with the hop number, followed by round-trip times ("0.512 ms", "<1 ms") and the hop address
+func parseTracerouteOutput(output string) []TracerouteHop {
+	hops := []TracerouteHop{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		number, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		hop := TracerouteHop{Hop: number}
+		for i, field := range fields[1:] {
+			if hop.Address == "" && net.ParseIP(field) != nil {
+				hop.Address = field
+			}
+			if hop.RTTMs == 0 && i+2 < len(fields) && fields[i+2] == "ms" {
+				hop.RTTMs, _ = strconv.ParseFloat(strings.TrimPrefix(field, "<"), 64)
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// PortCheckCommand checks whether TCP or UDP ports of a host are reachable from the minion
+type PortCheckCommand struct {
+	*BaseCommand
+}
+
+// portCheckRequest holds the parsed arguments of net:port-check
+type portCheckRequest struct {
+	host     string
+	ports    []int
+	protocol string
+	timeout  time.Duration
+}
+
+// NewPortCheckCommand creates a new port check command
+func NewPortCheckCommand() *PortCheckCommand {
+	base := NewBaseCommand(
+		"net:port-check",
+		"network",
+		"Check whether ports of a host are reachable from the minion",
+		"net:port-check [-u] [-t <seconds>] <host> <port>[,<port>...]",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Check that the web servers reach the database and the cache",
+			Command:     "command-send tag role=web net:port-check db.internal 5432,6379",
+			Expected:    "Returns the state and connection latency of each port",
+		},
+		Example{
+			Description: "Check a DNS server over UDP",
+			Command:     "command-send all net:port-check -u 10.0.0.2 53",
+			Expected:    "Returns closed when the host rejects the datagram, open|filtered otherwise",
+		},
+	).WithParameters(
+		Param{Name: "host", Type: "string", Required: true, Description: "Host name or IP address"},
+		Param{Name: "ports", Type: "string", Required: true, Description: "Comma-separated ports, or ranges such as 8000-8010 (max 100 ports)"},
+		Param{Name: "-u", Type: "bool", Required: false, Description: "Check UDP ports instead of TCP", Default: "false"},
+		Param{Name: "-t", Type: "int", Required: false, Description: "Timeout per port in seconds (max 30)", Default: "3"},
+	).WithNotes(
+		"TCP ports are open when a connection is established, closed when refused and filtered on timeout",
+		"UDP ports are closed when the host answers with a port unreachable, open when it replies and open|filtered otherwise",
+		"The command fails when a port is not open",
+		"The result carries a structured JSON payload in addition to the text output",
+	)
+
+	return &PortCheckCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *PortCheckCommand) ValidateArgs(payload string) error {
+	_, err := parsePortCheckRequest(commandArgument(payload, "net:port-check"))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PortCheckCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "PortCheckCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parsePortCheckRequest(commandArgument(payload, "net:port-check"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	checks := checkPorts(ctx.Context, request)
+
+	var output strings.Builder
+	unreachable := 0
+	for _, check := range checks {
+		fmt.Fprintf(&output, "%s:%d/%s %s", check.Host, check.Port, check.Protocol, check.State)
+		if check.State == "open" {
+			fmt.Fprintf(&output, " (%.1f ms)", check.LatencyMs)
+		} else {
+			unreachable++
+		}
+		if check.Error != "" {
+			fmt.Fprintf(&output, ": %s", check.Error)
+		}
+		output.WriteString("\n")
+	}
+
+	commandResult := c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeNetPortCheck, checks)
+	if unreachable > 0 {
+		commandResult.ExitCode = 1
+		commandResult.Stderr = fmt.Sprintf("%d of %d ports not open", unreachable, len(checks))
+	}
+	return commandResult, nil
+}
+
+// parsePortCheckRequest parses the arguments of net:port-check
+func parsePortCheckRequest(args string) (*portCheckRequest, error) {
+	request := &portCheckRequest{protocol: "tcp", timeout: DefaultPortTimeout * time.Second}
+	fields := strings.Fields(args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		flag := fields[0]
+		switch flag {
+		case "-u":
+			request.protocol = "udp"
+			fields = fields[1:]
+		case "-t":
+			var seconds int
+			var err error
+			if seconds, fields, err = parseNetOption(flag, fields[1:], MaxPortTimeout); err != nil {
+				return nil, err
+			}
+			request.timeout = time.Duration(seconds) * time.Second
+		default:
+			return nil, fmt.Errorf("unknown option %s", flag)
+		}
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("usage: net:port-check [-u] [-t <seconds>] <host> <port>[,<port>...]")
+	}
+	if err := validateHost(fields[0]); err != nil {
+		return nil, err
+	}
+	request.host = fields[0]
+
+	for _, item := range strings.Split(fields[1], ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := parsePort(from)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(to); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range '%s'", item)
+			}
+		}
+		for port := first; port <= last; port++ {
+			if len(request.ports) == MaxPortChecks {
+				return nil, fmt.Errorf("too many ports, at most %d are checked", MaxPortChecks)
+			}
+			request.ports = append(request.ports, port)
+		}
+	}
+	return request, nil
+}
+
+// parsePort parses a port number
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port '%s'", value)
+	}
+	return port, nil
+}
+
+// checkPorts checks the ports of a request concurrently, returning the checks in the order of the ports
+func checkPorts(ctx context.Context, request *portCheckRequest) []PortCheck {
+	checks := make([]PortCheck, len(request.ports))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(portCheckWorkers, len(request.ports)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				checks[i] = checkPort(ctx, request.host, request.ports[i], request.protocol, request.timeout)
+			}
+		}()
+	}
+	for i := range request.ports {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return checks
+}
+
+// checkPort checks a single port
+func checkPort(ctx context.Context, host string, port int, protocol string, timeout time.Duration) PortCheck {
+	check := PortCheck{Host: host, Port: port, Protocol: protocol}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, protocol, net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		check.State, check.Error = portState(err), err.Error()
+		return check
+	}
+	defer conn.Close()
+
+	if protocol == "tcp" {
+		check.State = "open"
+		check.LatencyMs = durationMs(time.Since(start))
+		return check
+	}
+
+	// A UDP port answers with data, or the host rejects the datagram with a port unreachable
+	// reported on the next read. Silence means the port is open or filtered.
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	if _, err := conn.Write([]byte{0}); err != nil {
+		check.State, check.Error = portState(err), err.Error()
+		return check
+	}
+	buffer := make([]byte, 1)
+	if _, err := conn.Read(buffer); err != nil {
+		check.State = portState(err)
+		if check.State == "filtered" {
+			check.State = "open|filtered"
+		} else {
+			check.Error = err.Error()
+		}
+		return check
+	}
+	check.State = "open"
+	check.LatencyMs = durationMs(time.Since(start))
+	return check
+}
+
+// portState returns the state of a port from the error reaching it
+func portState(err error) string {
+	if errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(err.Error(), "refused") {
+		return "closed"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "filtered"
+	}
+	return "unreachable"
+}
+
+// durationMs converts a duration to milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// DNSCommand resolves names from the minion
+type DNSCommand struct {
+	*BaseCommand
+}
+
+// dnsRequest holds the parsed arguments of net:dns
+type dnsRequest struct {
+	name       string
+	recordType string
+	server     string // host:port of the queried server, empty for the system resolver
+}
+
+// dnsRecordTypes lists the record types net:dns resolves
+var dnsRecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT", "PTR"}
+
+// NewDNSCommand creates a new DNS resolution command
+func NewDNSCommand() *DNSCommand {
+	base := NewBaseCommand(
+		"net:dns",
+		"network",
+		"Resolve a name from the minion",
+		"net:dns [@<server>] <name> [A|AAAA|CNAME|MX|NS|TXT|PTR]",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Check what the fleet resolves the API to",
+			Command:     "command-send all net:dns api.example.com",
+			Expected:    "Returns the A and AAAA records seen by each minion",
+		},
+		Example{
+			Description: "Query a specific DNS server",
+			Command:     "command-send minion abc123 net:dns @10.0.0.2 example.com MX",
+			Expected:    "Returns the MX records served by 10.0.0.2",
+		},
+	).WithParameters(
+		Param{Name: "name", Type: "string", Required: true, Description: "Name to resolve, or IP address for PTR"},
+		Param{Name: "type", Type: "string", Required: false, Description: "Record type; A returns both A and AAAA records when omitted"},
+		Param{Name: "@server", Type: "string", Required: false, Description: "DNS server queried instead of the system resolver, port 53 by default"},
+	).WithNotes(
+		"Names are resolved by the minion's resolver configuration, including /etc/hosts, unless a server is given",
+		"The command fails when the name does not resolve",
+		"The result carries a structured JSON payload in addition to the text output",
+	)
+
+	return &DNSCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *DNSCommand) ValidateArgs(payload string) error {
+	_, err := parseDNSRequest(commandArgument(payload, "net:dns"))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *DNSCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "DNSCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseDNSRequest(commandArgument(payload, "net:dns"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	resolveCtx, cancel := context.WithTimeout(ctx.Context, dnsTimeout)
+	defer cancel()
+	began := time.Now()
+	records, err := resolveDNS(resolveCtx, request)
+	elapsed := time.Since(began)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to resolve %s: %w", request.name, err)), nil
+	}
+
+	var output strings.Builder
+	for _, record := range records {
+		fmt.Fprintf(&output, "%s\t%s\t%s\n", record.Name, record.Type, record.Value)
+	}
+	server := request.server
+	if server == "" {
+		server = "system resolver"
+	}
+	fmt.Fprintf(&output, "%d records from %s in %.1f ms\n", len(records), server, durationMs(elapsed))
+
+	commandResult := c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeNetDNS, records)
+	if len(records) == 0 {
+		commandResult.ExitCode = 1
+		commandResult.Stderr = fmt.Sprintf("no %s record for %s", request.recordType, request.name)
+	}
+	return commandResult, nil
+}
+
+// parseDNSRequest parses the arguments of net:dns
+func parseDNSRequest(args string) (*dnsRequest, error) {
+	fields := strings.Fields(args)
+	request := &dnsRequest{}
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		server := strings.TrimPrefix(fields[0], "@")
+		host, port, err := net.SplitHostPort(server)
+		if err != nil {
+			host, port = server, "53"
+		}
+		if err := validateHost(host); err != nil {
+			return nil, fmt.Errorf("invalid server: %w", err)
+		}
+		if _, err := parsePort(port); err != nil {
+			return nil, fmt.Errorf("invalid server: %w", err)
+		}
+		request.server = net.JoinHostPort(host, port)
+		fields = fields[1:]
+	}
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, fmt.Errorf("usage: net:dns [@<server>] <name> [A|AAAA|CNAME|MX|NS|TXT|PTR]")
+	}
+	if err := validateHost(fields[0]); err != nil {
+		return nil, err
+	}
+	request.name = fields[0]
+
+	if len(fields) == 2 {
+		request.recordType = strings.ToUpper(fields[1])
+		valid := false
+		for _, recordType := range dnsRecordTypes {
+			valid = valid || recordType == request.recordType
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid record type '%s', expected one of %s", fields[1], strings.Join(dnsRecordTypes, ", "))
+		}
+	}
+	if request.recordType == "PTR" && net.ParseIP(request.name) == nil {
+		return nil, fmt.Errorf("PTR lookups require an IP address")
+	}
+	return request, nil
+}
+
+// resolveDNS resolves the records of a request. Without type, addresses are resolved.
+func resolveDNS(ctx context.Context, request *dnsRequest) ([]DNSRecord, error) {
+	resolver := net.DefaultResolver
+	if request.server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, request.server)
+			},
+		}
+	}
+
+	records := []DNSRecord{}
+	add := func(recordType, value string) {
+		records = append(records, DNSRecord{Name: request.name, Type: recordType, Value: value})
+	}
+
+	switch request.recordType {
+	case "", "A", "AAAA":
+		addrs, err := resolver.LookupIPAddr(ctx, request.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			recordType := "AAAA"
+			if addr.IP.To4() != nil {
+				recordType = "A"
+			}
+			if request.recordType == "" || request.recordType == recordType {
+				add(recordType, addr.String())
+			}
+		}
+	case "CNAME":
+		cname, err := resolver.LookupCNAME(ctx, request.name)
+		if err != nil {
+			return nil, err
+		}
+		add("CNAME", cname)
+	case "MX":
+		mxs, err := resolver.LookupMX(ctx, request.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			add("MX", fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+		}
+	case "NS":
+		nss, err := resolver.LookupNS(ctx, request.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			add("NS", ns.Host)
+		}
+	case "TXT":
+		txts, err := resolver.LookupTXT(ctx, request.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, txt := range txts {
+			add("TXT", txt)
+		}
+	case "PTR":
+		names, err := resolver.LookupAddr(ctx, request.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			add("PTR", name)
+		}
+	}
+	return records, nil
+}
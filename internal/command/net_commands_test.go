@@ -0,0 +1,163 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParsePingOutput(t *testing.T) {
+	linux := `PING db.internal (10.0.0.5) 56(84) bytes of data.
+64 bytes from 10.0.0.5: icmp_seq=1 ttl=64 time=0.412 ms
+
+--- db.internal ping statistics ---
+4 packets transmitted, 3 received, 25% packet loss, time 3004ms
+rtt min/avg/max/mdev = 0.301/0.412/0.520/0.080 ms
+`
+	result, err := parsePingOutput(linux)
+	if err != nil {
+		t.Fatalf("parsePingOutput failed: %v", err)
+	}
+	if result.Transmitted != 4 || result.Received != 3 || result.LossPercent != 25 ||
+		result.MinMs != 0.301 || result.AvgMs != 0.412 || result.MaxMs != 0.520 {
+		t.Errorf("Unexpected Linux summary: %+v", result)
+	}
+
+	bsd := "2 packets transmitted, 0 packets received, 100.0% packet loss\n"
+	if result, err := parsePingOutput(bsd); err != nil || result.Received != 0 || result.LossPercent != 100 {
+		t.Errorf("Unexpected BSD summary: %+v, %v", result, err)
+	}
+
+	windows := `Ping statistics for 10.0.0.5:
+    Packets: Sent = 4, Received = 4, Lost = 0 (0% loss),
+Approximate round trip times in milli-seconds:
+    Minimum = 1ms, Maximum = 3ms, Average = 2ms
+`
+	result, err = parsePingOutput(windows)
+	if err != nil || result.Received != 4 || result.MinMs != 1 || result.MaxMs != 3 || result.AvgMs != 2 {
+		t.Errorf("Unexpected Windows summary: %+v, %v", result, err)
+	}
+
+	if _, err := parsePingOutput("ping: unknown host nowhere"); err == nil {
+		t.Error("Expected an error without summary")
+	}
+}
+
+func TestParseTracerouteOutput(t *testing.T) {
+	unix := `traceroute to api.example.com (93.184.216.34), 30 hops max, 60 byte packets
+ 1  192.168.1.1  0.512 ms
+ 2  *
+ 3  93.184.216.34  12.204 ms
+`
+	hops := parseTracerouteOutput(unix)
+	if len(hops) != 3 {
+		t.Fatalf("Expected 3 hops, got %+v", hops)
+	}
+	if hops[0] != (TracerouteHop{Hop: 1, Address: "192.168.1.1", RTTMs: 0.512}) || hops[1] != (TracerouteHop{Hop: 2}) {
+		t.Errorf("Unexpected hops: %+v", hops)
+	}
+
+	windows := `Tracing route to 93.184.216.34 over a maximum of 30 hops
+
+  1    <1 ms    <1 ms    <1 ms  192.168.1.1
+  2     *        *        *     Request timed out.
+  3    14 ms    13 ms    12 ms  93.184.216.34
+
+Trace complete.
+`
+	hops = parseTracerouteOutput(windows)
+	if len(hops) != 3 || hops[0].RTTMs != 1 || hops[1].Address != "" || hops[2] != (TracerouteHop{Hop: 3, Address: "93.184.216.34", RTTMs: 14}) {
+		t.Errorf("Unexpected tracert hops: %+v", hops)
+	}
+}
+
+func TestParsePortCheckRequest(t *testing.T) {
+	request, err := parsePortCheckRequest("-u -t 60 dns.internal 53,8000-8002")
+	if err != nil {
+		t.Fatalf("parsePortCheckRequest failed: %v", err)
+	}
+	if request.host != "dns.internal" || request.protocol != "udp" || request.timeout.Seconds() != MaxPortTimeout ||
+		len(request.ports) != 4 || request.ports[3] != 8002 {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+
+	for _, args := range []string{"", "db", "-x db 80", "-t db 80", "-oProxyCommand 80", "db 0", "db 90-80", "db 1-1000"} {
+		if _, err := parsePortCheckRequest(args); err == nil {
+			t.Errorf("Expected error for %q", args)
+		}
+	}
+}
+
+func TestPortCheckCommand(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	open := listener.Addr().(*net.TCPAddr).Port
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+	defer listener.Close()
+
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	ports := strconv.Itoa(open) + "," + strconv.Itoa(closedPort)
+	result, err := NewPortCheckCommand().Execute(ctx, "net:port-check -t 2 127.0.0.1 "+ports)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ContentType != ContentTypeNetPortCheck || result.ExitCode != 1 || result.Stderr != "1 of 2 ports not open" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	var checks []PortCheck
+	if err := json.Unmarshal([]byte(result.Structured), &checks); err != nil {
+		t.Fatalf("Invalid structured payload: %v", err)
+	}
+	if len(checks) != 2 || checks[0].Port != open || checks[0].State != "open" || checks[1].State != "closed" {
+		t.Errorf("Unexpected checks: %+v", checks)
+	}
+	if !strings.Contains(result.Stdout, "127.0.0.1:"+strconv.Itoa(open)+"/tcp open") {
+		t.Errorf("Unexpected output: %s", result.Stdout)
+	}
+}
+
+func TestParseDNSRequest(t *testing.T) {
+	request, err := parseDNSRequest("@10.0.0.2 example.com mx")
+	if err != nil {
+		t.Fatalf("parseDNSRequest failed: %v", err)
+	}
+	if request.server != "10.0.0.2:53" || request.name != "example.com" || request.recordType != "MX" {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+	if request, err := parseDNSRequest("@[::1]:5353 example.com"); err != nil || request.server != "[::1]:5353" {
+		t.Errorf("Unexpected request with IPv6 server: %+v, %v", request, err)
+	}
+
+	for _, args := range []string{"", "example.com SOA", "example.com A extra", "-x", "@ example.com", "example.com PTR"} {
+		if _, err := parseDNSRequest(args); err == nil {
+			t.Errorf("Expected error for %q", args)
+		}
+	}
+}
+
+func TestDNSCommand(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	result, err := NewDNSCommand().Execute(ctx, "net:dns 127.0.0.1 A")
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute failed: %v %s", err, result.Stderr)
+	}
+	var records []DNSRecord
+	if err := json.Unmarshal([]byte(result.Structured), &records); err != nil {
+		t.Fatalf("Invalid structured payload: %v", err)
+	}
+	if len(records) != 1 || records[0] != (DNSRecord{Name: "127.0.0.1", Type: "A", Value: "127.0.0.1"}) {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeNetSpeedTest is the content type of net:speedtest structured results
+const ContentTypeNetSpeedTest = "application/vnd.minexus.net-speedtest+json"
+
+// Limits of net:speedtest, bounding the traffic it generates
+const (
+	DefaultSpeedTestSize  = 8 // megabytes uploaded, then downloaded
+	MaxSpeedTestSize      = 64
+	DefaultSpeedTestPings = 5
+	MaxSpeedTestPings     = 20
+	speedTestTimeout      = 2 * time.Minute
+)
+
+// ErrSpeedTestUnavailable is returned by net:speedtest on minions that can't measure their channel to Nexus
+var ErrSpeedTestUnavailable = errors.New("speed tests are not available on this minion")
+
+// SpeedTestResult is the latency and throughput of the channel between a minion and Nexus measured
+// by net:speedtest
+type SpeedTestResult struct {
+	Pings         int     `json:"pings"`
+	LatencyMinMs  float64 `json:"latency_min_ms"`
+	LatencyAvgMs  float64 `json:"latency_avg_ms"`
+	LatencyMaxMs  float64 `json:"latency_max_ms"`
+	UploadBytes   int64   `json:"upload_bytes"`
+	UploadMbps    float64 `json:"upload_mbps"` // minion -> Nexus, in megabits per second
+	DownloadBytes int64   `json:"download_bytes"`
+	DownloadMbps  float64 `json:"download_mbps"` // Nexus -> minion
+}
+
+// SpeedTester measures the channel between a minion and Nexus
+type SpeedTester interface {
+	// SpeedTest sends pings latency probes to Nexus, then uploads and downloads size bytes of
+	// synthetic payload
+	SpeedTest(ctx context.Context, pings int, size int64) (*SpeedTestResult, error)
+}
+
+// Mbps converts bytes transferred in elapsed to megabits per second
+func Mbps(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / 1e6 / elapsed.Seconds()
+}
+
+// SpeedTestCommand measures the latency and throughput of the channel between the minion and Nexus
+type SpeedTestCommand struct {
+	*BaseCommand
+}
+
+// speedTestRequest holds the parsed arguments of net:speedtest
+type speedTestRequest struct {
+	size  int // megabytes
+	pings int
+}
+
+// NewSpeedTestCommand creates a new speed test command
+func NewSpeedTestCommand() *SpeedTestCommand {
+	base := NewBaseCommand(
+		"net:speedtest",
+		"network",
+		"Measure the latency and throughput between the minion and Nexus",
+		"net:speedtest [-s <megabytes>] [-c <pings>]",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Find out why file pulls from a datacenter are slow",
+			Command:     "command-send dc=fra1 net:speedtest",
+			Expected:    "Returns the round-trip time to Nexus and the upload and download rates of each minion",
+		},
+	).WithParameters(
+		Param{Name: "-s", Type: "int", Required: false, Description: "Megabytes uploaded then downloaded (max 64)", Default: "8"},
+		Param{Name: "-c", Type: "int", Required: false, Description: "Latency probes sent (max 20)", Default: "5"},
+	).WithNotes(
+		"Measures the gRPC channel the minion uses to reach Nexus, through its TLS connection",
+		"Not limited by the transfer_bandwidth and max_file_transfers settings of the minion",
+		"Not available to minions connected through a relay or over HTTP long polling",
+		"The result carries a structured JSON payload, and the last one is shown by minion-inspect",
+	)
+
+	return &SpeedTestCommand{
+		BaseCommand: base,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *SpeedTestCommand) ValidateArgs(payload string) error {
+	_, err := parseSpeedTestRequest(commandArgument(payload, "net:speedtest"))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *SpeedTestCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "SpeedTestCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parseSpeedTestRequest(commandArgument(payload, "net:speedtest"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	if ctx.SpeedTest == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, ErrSpeedTestUnavailable), nil
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx.Context, speedTestTimeout)
+	defer cancel()
+	result, err := ctx.SpeedTest.SpeedTest(testCtx, request.pings, int64(request.size)*1024*1024)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("speed test failed: %w", err)), nil
+	}
+	return c.BaseCommand.CreateStructuredResult(ctx, formatSpeedTest(result), ContentTypeNetSpeedTest, result), nil
+}
+
+// parseSpeedTestRequest parses the arguments of net:speedtest
+func parseSpeedTestRequest(args string) (*speedTestRequest, error) {
+	request := &speedTestRequest{size: DefaultSpeedTestSize, pings: DefaultSpeedTestPings}
+	fields := strings.Fields(args)
+	for len(fields) > 0 {
+		flag := fields[0]
+		var err error
+		switch flag {
+		case "-s":
+			request.size, fields, err = parseNetOption(flag, fields[1:], MaxSpeedTestSize)
+		case "-c":
+			request.pings, fields, err = parseNetOption(flag, fields[1:], MaxSpeedTestPings)
+		default:
+			return nil, fmt.Errorf("usage: net:speedtest [-s <megabytes>] [-c <pings>]")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// formatSpeedTest describes a speed test result
+func formatSpeedTest(result *SpeedTestResult) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "Latency:  min %.2f ms, avg %.2f ms, max %.2f ms (%d probes)\n",
+		result.LatencyMinMs, result.LatencyAvgMs, result.LatencyMaxMs, result.Pings)
+	fmt.Fprintf(&output, "Upload:   %.2f Mbit/s (%d bytes)\n", result.UploadMbps, result.UploadBytes)
+	fmt.Fprintf(&output, "Download: %.2f Mbit/s (%d bytes)\n", result.DownloadMbps, result.DownloadBytes)
+	return output.String()
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeSpeedTester returns a fixed measure, remembering what it was asked
+type fakeSpeedTester struct {
+	pings int
+	size  int64
+}
+
+func (f *fakeSpeedTester) SpeedTest(_ context.Context, pings int, size int64) (*SpeedTestResult, error) {
+	f.pings, f.size = pings, size
+	return &SpeedTestResult{Pings: pings, LatencyAvgMs: 12.5, UploadBytes: size, UploadMbps: 80, DownloadBytes: size, DownloadMbps: 320}, nil
+}
+
+func TestParseSpeedTestRequest(t *testing.T) {
+	request, err := parseSpeedTestRequest("")
+	if err != nil || request.size != DefaultSpeedTestSize || request.pings != DefaultSpeedTestPings {
+		t.Errorf("Unexpected default request: %+v, %v", request, err)
+	}
+	request, err = parseSpeedTestRequest("-c 3 -s 500")
+	if err != nil || request.size != MaxSpeedTestSize || request.pings != 3 {
+		t.Errorf("Unexpected request: %+v, %v", request, err)
+	}
+
+	for _, args := range []string{"-s", "-s 0", "-c many", "nexus.example.com", "-x 1"} {
+		if _, err := parseSpeedTestRequest(args); err == nil {
+			t.Errorf("Expected error for %q", args)
+		}
+	}
+}
+
+func TestSpeedTestCommand(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	result, err := NewSpeedTestCommand().Execute(ctx, "net:speedtest")
+	if err != nil || result.ExitCode != 1 || result.Stderr != ErrSpeedTestUnavailable.Error() {
+		t.Errorf("Expected the speed test unavailable without tester, got %+v, %v", result, err)
+	}
+
+	tester := &fakeSpeedTester{}
+	ctx.SpeedTest = tester
+	result, err = NewSpeedTestCommand().Execute(ctx, "net:speedtest -s 2 -c 4")
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Execute failed: %v %s", err, result.Stderr)
+	}
+	if tester.pings != 4 || tester.size != 2*1024*1024 {
+		t.Errorf("Unexpected test run: %d probes, %d bytes", tester.pings, tester.size)
+	}
+	var measure SpeedTestResult
+	if err := json.Unmarshal([]byte(result.Structured), &measure); err != nil || result.ContentType != ContentTypeNetSpeedTest {
+		t.Fatalf("Invalid structured payload %s: %v", result.ContentType, err)
+	}
+	if measure.DownloadMbps != 320 || !strings.Contains(result.Stdout, "Download: 320.00 Mbit/s") {
+		t.Errorf("Unexpected result: %+v, %s", measure, result.Stdout)
+	}
+
+	if err := NewSpeedTestCommand().ValidateArgs("net:speedtest -s"); err == nil {
+		t.Error("Expected a missing size rejected")
+	}
+}
@@ -0,0 +1,635 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// Content types of the patch commands structured results
+const (
+	ContentTypePatchStatus = "application/vnd.minexus.patch-status+json"
+	ContentTypePatchApply  = "application/vnd.minexus.patch-apply+json"
+)
+
+// Reboot policies of patch:apply
+const (
+	PatchRebootNever      = "never"       // the reboot is left to the operator, the default
+	PatchRebootIfRequired = "if-required" // rebooted when the updates require it
+	PatchRebootAlways     = "always"      // rebooted once the updates are applied
+)
+
+// MaxPatchUpdates bounds the updates listed in a patch:status result, the counts covering them all
+const MaxPatchUpdates = 500
+
+// patchRebootMessage is shown to the logged-in users when patch:apply reboots the host
+const patchRebootMessage = "Rebooting to complete the installation of updates"
+
+// PatchUpdate is an update available for a package, or a Windows update
+type PatchUpdate struct {
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"` // version available, KB article of Windows updates
+	Security bool   `json:"security,omitempty"`
+}
+
+// PatchStatus is the structured payload of patch:status
+type PatchStatus struct {
+	Manager        string        `json:"manager"` // "apt", "dnf", "yum" or "windows-update"
+	Updates        int           `json:"updates"`
+	Security       int           `json:"security"`
+	RebootRequired bool          `json:"reboot_required"`
+	Packages       []PatchUpdate `json:"packages"` // security updates first, at most MaxPatchUpdates
+}
+
+// PatchApplyResult is the structured payload of patch:apply
+type PatchApplyResult struct {
+	Manager        string `json:"manager"`
+	SecurityOnly   bool   `json:"security_only"`
+	Applied        int    `json:"applied"`   // updates pending before applying
+	Remaining      int    `json:"remaining"` // updates still pending afterwards, security ones with security_only
+	RebootRequired bool   `json:"reboot_required"`
+	RebootAt       int64  `json:"reboot_at,omitempty"` // unix time of the reboot scheduled, 0 without
+}
+
+// patchHost gives the patch managers access to the host
+type patchHost struct {
+	run      stateRunner
+	lookPath func(file string) (string, error)
+	stat     func(name string) (os.FileInfo, error)
+}
+
+// newPatchHost returns the access to the minion host
+func newPatchHost() patchHost {
+	return patchHost{run: runStateTool, lookPath: exec.LookPath, stat: os.Stat}
+}
+
+// patchManager lists and applies the updates of the host with a tool of the host
+type patchManager struct {
+	name           string
+	cmd            string   // tool whose presence selects this manager
+	goos           string   // the only platform the manager is used on, any when empty
+	refresh        []string // updates the package index, nil when listing does it
+	list           func(ctx context.Context, h patchHost) ([]PatchUpdate, error)
+	apply          func(securityOnly bool, updates []PatchUpdate) []string
+	rebootRequired func(ctx context.Context, h patchHost) bool
+}
+
+// aptNonInteractive runs apt-get without prompt, keeping the configuration files modified locally
+var aptNonInteractive = []string{"env", "DEBIAN_FRONTEND=noninteractive", "apt-get", "-y", "-q",
+	"-o", "Dpkg::Options::=--force-confdef", "-o", "Dpkg::Options::=--force-confold"}
+
+// patchManagers are tried in order until one is available
+var patchManagers = []patchManager{
+	{
+		name:    "apt",
+		cmd:     "apt-get",
+		refresh: []string{"apt-get", "update", "-q"},
+		list: func(ctx context.Context, h patchHost) ([]PatchUpdate, error) {
+			out, err := h.run(ctx, []string{"apt-get", "-s", "-q", "-o", "Debug::NoLocking=1", "upgrade"})
+			if err != nil {
+				return nil, fmt.Errorf("apt-get failed: %w: %s", err, lastLine(out))
+			}
+			return parseAptUpgrade(string(out)), nil
+		},
+		apply: func(securityOnly bool, updates []PatchUpdate) []string {
+			if !securityOnly {
+				return append(append([]string{}, aptNonInteractive...), "upgrade")
+			}
+			argv := append(append([]string{}, aptNonInteractive...), "install", "--only-upgrade")
+			for _, update := range updates {
+				argv = append(argv, update.Name)
+			}
+			return argv
+		},
+		rebootRequired: func(ctx context.Context, h patchHost) bool {
+			_, err := h.stat("/var/run/reboot-required")
+			return err == nil
+		},
+	},
+	rpmPatchManager("dnf", []string{"dnf", "-q", "updateinfo", "list", "--security"}, []string{"dnf", "-y", "upgrade"}),
+	rpmPatchManager("yum", []string{"yum", "-q", "updateinfo", "list", "security"}, []string{"yum", "-y", "update"}),
+	{
+		name: "windows-update",
+		cmd:  "powershell",
+		goos: "windows",
+		list: func(ctx context.Context, h patchHost) ([]PatchUpdate, error) {
+			out, err := h.run(ctx, powershell(windowsUpdateSearch+windowsUpdateList))
+			if err != nil {
+				return nil, fmt.Errorf("windows update search failed: %w: %s", err, lastLine(out))
+			}
+			return parseWindowsUpdates(string(out)), nil
+		},
+		apply: func(securityOnly bool, _ []PatchUpdate) []string {
+			return powershell(fmt.Sprintf("$securityOnly = $%t\n", securityOnly) + windowsUpdateSearch + windowsUpdateInstall)
+		},
+		rebootRequired: func(ctx context.Context, h patchHost) bool {
+			out, err := h.run(ctx, powershell("(New-Object -ComObject Microsoft.Update.SystemInfo).RebootRequired"))
+			return err == nil && strings.TrimSpace(string(out)) == "True"
+		},
+	},
+}
+
+// rpmPatchManager returns the manager of the RPM based hosts using tool, dnf or yum
+func rpmPatchManager(tool string, securityQuery, upgrade []string) patchManager {
+	return patchManager{
+		name:    tool,
+		cmd:     tool,
+		refresh: []string{tool, "-q", "makecache"},
+		list: func(ctx context.Context, h patchHost) ([]PatchUpdate, error) {
+			// check-update exits with 100 when updates are available
+			out, err := h.run(ctx, []string{tool, "-q", "check-update"})
+			if err != nil && exitCode(err) != 100 {
+				return nil, fmt.Errorf("%s check-update failed: %w: %s", tool, err, lastLine(out))
+			}
+			updates := parseCheckUpdate(string(out))
+
+			advisories, err := h.run(ctx, securityQuery)
+			if err != nil {
+				return nil, fmt.Errorf("%s updateinfo failed: %w: %s", tool, err, lastLine(advisories))
+			}
+			markSecurityUpdates(updates, string(advisories))
+			return updates, nil
+		},
+		apply: func(securityOnly bool, _ []PatchUpdate) []string {
+			argv := append([]string{}, upgrade...)
+			if securityOnly {
+				argv = append(argv, "--security")
+			}
+			return argv
+		},
+		rebootRequired: func(ctx context.Context, h patchHost) bool {
+			// needs-restarting -r exits with 1 when a reboot is required
+			if _, err := h.lookPath("needs-restarting"); err != nil {
+				return false
+			}
+			_, err := h.run(ctx, []string{"needs-restarting", "-r"})
+			return exitCode(err) == 1
+		},
+	}
+}
+
+// Windows Update scripts, the updates of the Security Updates category being security updates
+const (
+	windowsUpdateSearch = `$session = New-Object -ComObject Microsoft.Update.Session
+$found = $session.CreateUpdateSearcher().Search("IsInstalled=0 and IsHidden=0 and Type='Software'")
+function Test-Security($update) { @($update.Categories | Where-Object { $_.Name -eq 'Security Updates' }).Count -gt 0 }
+`
+	windowsUpdateList = `foreach ($update in $found.Updates) {
+  "{0}` + "`t" + `{1}` + "`t" + `{2}" -f (($update.KBArticleIDs | ForEach-Object { "KB$_" }) -join ','), (Test-Security $update), $update.Title
+}
+`
+	windowsUpdateInstall = `$updates = New-Object -ComObject Microsoft.Update.UpdateColl
+foreach ($update in $found.Updates) {
+  if (-not $securityOnly -or (Test-Security $update)) { $update.AcceptEula(); [void]$updates.Add($update) }
+}
+if ($updates.Count -eq 0) { exit 0 }
+$downloader = $session.CreateUpdateDownloader(); $downloader.Updates = $updates; [void]$downloader.Download()
+$installer = $session.CreateUpdateInstaller(); $installer.Updates = $updates
+$result = $installer.Install()
+if ($result.ResultCode -ne 2) { Write-Error "installation failed with result code $($result.ResultCode)"; exit 1 }
+`
+)
+
+// powershell returns the command running script with PowerShell
+func powershell(script string) []string {
+	return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", script}
+}
+
+// exitCode returns the exit code of a tool from the error running it, -1 when it didn't exit
+func exitCode(err error) int {
+	var exited interface{ ExitCode() int }
+	if errors.As(err, &exited) {
+		return exited.ExitCode()
+	}
+	if err == nil {
+		return 0
+	}
+	return -1
+}
+
+// parseAptUpgrade extracts the updates from a simulated apt-get upgrade, whose lines read
+// "Inst openssl [3.0.2-0ubuntu1.10] (3.0.2-0ubuntu1.12 Ubuntu:22.04/jammy-security [amd64])"
+func parseAptUpgrade(out string) []PatchUpdate {
+	var updates []PatchUpdate
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		open, end := strings.Index(line, "("), strings.LastIndex(line, ")")
+		if len(fields) < 2 || fields[0] != "Inst" || open < 0 || end < open {
+			continue
+		}
+		origin := strings.Fields(line[open+1 : end])
+		if len(origin) == 0 {
+			continue
+		}
+		updates = append(updates, PatchUpdate{
+			Name:     fields[1],
+			Version:  origin[0],
+			Security: strings.Contains(strings.ToLower(line[open+1:end]), "security"),
+		})
+	}
+	return updates
+}
+
+// parseCheckUpdate extracts the updates from dnf or yum check-update, whose lines read
+// "openssl.x86_64  1:3.0.7-25.el9  baseos", the packages obsoleted being left out
+func parseCheckUpdate(out string) []PatchUpdate {
+	var updates []PatchUpdate
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.LastIndex(fields[0], ".") <= 0 {
+			continue
+		}
+		updates = append(updates, PatchUpdate{Name: fields[0], Version: fields[1]})
+	}
+	return updates
+}
+
+// markSecurityUpdates flags the updates fixing the security advisories listed by updateinfo, whose
+// lines read "RHSA-2024:1234 Important/Sec. openssl-1:3.0.7-25.el9.x86_64", and drops the
+// architecture check-update appends to the package names
+func markSecurityUpdates(updates []PatchUpdate, advisories string) {
+	packages := make(map[string]bool)
+	for _, line := range strings.Split(advisories, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 {
+			packages[fields[2]] = true
+		}
+	}
+
+	for i, update := range updates {
+		dot := strings.LastIndex(update.Name, ".")
+		name, arch := update.Name[:dot], update.Name[dot+1:]
+		// yum lists the advisories without the epoch of the versions
+		_, withoutEpoch, hasEpoch := strings.Cut(update.Version, ":")
+		updates[i].Name = name
+		updates[i].Security = packages[name+"-"+update.Version+"."+arch] ||
+			hasEpoch && packages[name+"-"+withoutEpoch+"."+arch]
+	}
+}
+
+// parseWindowsUpdates extracts the updates from the lines "KB5034441<TAB>True<TAB>title" of the
+// Windows Update search script
+func parseWindowsUpdates(out string) []PatchUpdate {
+	var updates []PatchUpdate
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		updates = append(updates, PatchUpdate{Name: fields[2], Version: fields[0], Security: fields[1] == "True"})
+	}
+	return updates
+}
+
+// selectManager returns the first patch manager available on the host, nil when none is
+func (h patchHost) selectManager() *patchManager {
+	for i, manager := range patchManagers {
+		if manager.goos != "" && manager.goos != runtime.GOOS {
+			continue
+		}
+		if _, err := h.lookPath(manager.cmd); err == nil {
+			return &patchManagers[i]
+		}
+	}
+	return nil
+}
+
+// refresh updates the package index of manager
+func (h patchHost) refresh(ctx context.Context, manager *patchManager) error {
+	if manager.refresh == nil {
+		return nil
+	}
+	if out, err := h.run(ctx, manager.refresh); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", manager.refresh[0], err, lastLine(out))
+	}
+	return nil
+}
+
+// securityUpdates returns the security updates of updates
+func securityUpdates(updates []PatchUpdate) []PatchUpdate {
+	var security []PatchUpdate
+	for _, update := range updates {
+		if update.Security {
+			security = append(security, update)
+		}
+	}
+	return security
+}
+
+// PatchStatusCommand reports the updates available on the minion host
+type PatchStatusCommand struct {
+	*BaseCommand
+	host patchHost
+}
+
+// NewPatchStatusCommand creates a new patch:status command
+func NewPatchStatusCommand() *PatchStatusCommand {
+	base := NewBaseCommand(
+		"patch:status",
+		"patch",
+		"Report the updates available on the host, security ones first, and whether it needs a reboot",
+		"patch:status [--refresh]",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "Check the pending security updates of the web servers",
+			Command:     "command-send tag role=web patch:status",
+			Expected:    "Number of updates and security updates available on each host, and the packages concerned",
+		},
+		Example{
+			Description: "Refresh the package index before checking",
+			Command:     "command-send all patch:status --refresh",
+		},
+	).WithParameters(
+		Param{Name: "--refresh", Type: "flag", Required: false, Description: "Update the package index first (apt-get update, dnf makecache)"},
+	).WithNotes(
+		"Updates are listed with apt-get, dnf or yum, and Windows Update on Windows",
+		"Security updates come from a security repository with apt, from a security advisory with dnf and yum, from the Security Updates category on Windows",
+		"A reboot is required when /var/run/reboot-required exists, needs-restarting -r says so, or Windows Update reports it",
+		"fleet-patches in the console summarizes the last patch:status of the fleet by tag",
+		"The result carries the status as a structured JSON payload",
+	)
+
+	return &PatchStatusCommand{BaseCommand: base, host: newPatchHost()}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *PatchStatusCommand) ValidateArgs(payload string) error {
+	_, err := parsePatchStatusArgs(commandArgument(payload, c.Metadata().Name))
+	return err
+}
+
+// parsePatchStatusArgs parses the arguments of patch:status, reporting whether the index is refreshed
+func parsePatchStatusArgs(args string) (bool, error) {
+	switch args {
+	case "":
+		return false, nil
+	case "--refresh":
+		return true, nil
+	}
+	return false, fmt.Errorf("unexpected argument %q, usage: patch:status [--refresh]", args)
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PatchStatusCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "PatchStatusCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	refresh, err := parsePatchStatusArgs(commandArgument(payload, c.Metadata().Name))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	manager := c.host.selectManager()
+	if manager == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("no supported patch manager found")), nil
+	}
+	if refresh {
+		if err := c.host.refresh(ctx.Context, manager); err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, err), nil
+		}
+	}
+
+	updates, err := manager.list(ctx.Context, c.host)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	status := newPatchStatus(manager.name, updates, manager.rebootRequired(ctx.Context, c.host))
+	logger.Debug("Patch status collected",
+		zap.String("manager", status.Manager),
+		zap.Int("updates", status.Updates),
+		zap.Int("security", status.Security))
+	return c.BaseCommand.CreateStructuredResult(ctx, formatPatchStatus(status), ContentTypePatchStatus, status), nil
+}
+
+// newPatchStatus summarizes the updates available, security ones first
+func newPatchStatus(manager string, updates []PatchUpdate, rebootRequired bool) *PatchStatus {
+	sort.SliceStable(updates, func(i, j int) bool {
+		if updates[i].Security != updates[j].Security {
+			return updates[i].Security
+		}
+		return updates[i].Name < updates[j].Name
+	})
+	status := &PatchStatus{
+		Manager:        manager,
+		Updates:        len(updates),
+		Security:       len(securityUpdates(updates)),
+		RebootRequired: rebootRequired,
+		Packages:       updates[:min(len(updates), MaxPatchUpdates)],
+	}
+	if status.Packages == nil {
+		status.Packages = []PatchUpdate{}
+	}
+	return status
+}
+
+// formatPatchStatus describes a patch status
+func formatPatchStatus(status *PatchStatus) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "%d updates available (%d security) with %s", status.Updates, status.Security, status.Manager)
+	if status.RebootRequired {
+		output.WriteString(", reboot required")
+	}
+	output.WriteString("\n")
+	for _, update := range status.Packages {
+		kind := "update"
+		if update.Security {
+			kind = "SECURITY"
+		}
+		fmt.Fprintf(&output, "%-8s %s %s\n", kind, update.Name, update.Version)
+	}
+	if status.Updates > len(status.Packages) {
+		fmt.Fprintf(&output, "... %d more updates\n", status.Updates-len(status.Packages))
+	}
+	return output.String()
+}
+
+// patchApplyRequest holds the parsed arguments of patch:apply
+type patchApplyRequest struct {
+	securityOnly bool
+	reboot       string
+	rebootDelay  time.Duration
+}
+
+// PatchApplyCommand applies the updates available on the minion host, rebooting it per policy
+type PatchApplyCommand struct {
+	*BaseCommand
+	host      patchHost
+	announcer PowerAnnouncer
+}
+
+// NewPatchApplyCommand creates a new patch:apply command.
+// A nil announcer reboots without telling Nexus beforehand.
+func NewPatchApplyCommand(announcer PowerAnnouncer) *PatchApplyCommand {
+	base := NewBaseCommand(
+		"patch:apply",
+		"patch",
+		"Apply the updates available on the host, rebooting it afterwards per policy",
+		"patch:apply [--security-only] [--reboot never|if-required|always] [--reboot-delay <duration>]",
+	).WithImpact(ImpactDisruptive).WithDiskHeavy().WithExamples(
+		Example{
+			Description: "Apply the security updates of the staging hosts, rebooting those that need it",
+			Command:     "command-send tag env=staging patch:apply --security-only --reboot if-required",
+			Expected:    "Installs the security updates and schedules a reboot in a minute on the hosts requiring one",
+		},
+		Example{
+			Description: "Patch the databases, warning their users 10 minutes before the reboot",
+			Command:     "command-send tag role=db patch:apply --reboot always --reboot-delay 10m",
+		},
+	).WithParameters(
+		Param{Name: "--security-only", Type: "flag", Required: false, Description: "Only apply the security updates"},
+		Param{Name: "--reboot", Type: "string", Required: false, Description: "never, if-required or always", Default: PatchRebootNever},
+		Param{Name: "--reboot-delay", Type: "duration", Required: false, Description: "Time before the reboot, from 1m to 24h", Default: "1m"},
+	).WithNotes(
+		"Refreshes the package index, then upgrades with apt-get, dnf or yum, or installs the updates with Windows Update",
+		"apt-get keeps the configuration files modified locally",
+		"Reboots are scheduled as power:reboot does: logged-in users are warned, Nexus is told and power:cancel calls them off",
+		"The result carries the applied and remaining updates as a structured JSON payload",
+	)
+
+	return &PatchApplyCommand{BaseCommand: base, host: newPatchHost(), announcer: announcer}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *PatchApplyCommand) ValidateArgs(payload string) error {
+	_, err := parsePatchApplyArgs(commandArgument(payload, c.Metadata().Name))
+	return err
+}
+
+// parsePatchApplyArgs parses the arguments of patch:apply
+func parsePatchApplyArgs(args string) (*patchApplyRequest, error) {
+	request := &patchApplyRequest{reboot: PatchRebootNever, rebootDelay: DefaultPowerDelay}
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		option := fields[i]
+		if option == "--security-only" {
+			request.securityOnly = true
+			continue
+		}
+		if option != "--reboot" && option != "--reboot-delay" {
+			return nil, fmt.Errorf("unexpected argument %q", option)
+		}
+		if i+1 >= len(fields) {
+			return nil, fmt.Errorf("%s requires a value", option)
+		}
+		i++
+		switch value := fields[i]; option {
+		case "--reboot":
+			if value != PatchRebootNever && value != PatchRebootIfRequired && value != PatchRebootAlways {
+				return nil, fmt.Errorf("invalid reboot policy %q, expected never, if-required or always", value)
+			}
+			request.reboot = value
+		default:
+			delay, err := time.ParseDuration(value)
+			if err != nil || delay < MinPowerDelay || delay > MaxPowerDelay {
+				return nil, fmt.Errorf("reboot delay must be a duration between %s and %s", MinPowerDelay, MaxPowerDelay)
+			}
+			request.rebootDelay = delay
+		}
+	}
+	return request, nil
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PatchApplyCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "PatchApplyCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parsePatchApplyArgs(commandArgument(payload, c.Metadata().Name))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	manager := c.host.selectManager()
+	if manager == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("no supported patch manager found")), nil
+	}
+	if err := c.host.refresh(ctx.Context, manager); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	pending := func() ([]PatchUpdate, error) {
+		updates, err := manager.list(ctx.Context, c.host)
+		if request.securityOnly {
+			updates = securityUpdates(updates)
+		}
+		return updates, err
+	}
+	updates, err := pending()
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	result := &PatchApplyResult{Manager: manager.name, SecurityOnly: request.securityOnly, Applied: len(updates)}
+	if len(updates) > 0 {
+		argv := manager.apply(request.securityOnly, updates)
+		logger.Info("Applying updates",
+			zap.String("manager", manager.name),
+			zap.Int("updates", len(updates)),
+			zap.Bool("security_only", request.securityOnly))
+		if out, err := c.host.run(ctx.Context, argv); err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s failed: %w: %s", manager.name, err, lastLine(out))), nil
+		}
+		if remaining, err := pending(); err != nil {
+			logger.Warn("Failed to list the updates remaining", zap.Error(err))
+		} else {
+			result.Remaining = len(remaining)
+		}
+	}
+	result.RebootRequired = manager.rebootRequired(ctx.Context, c.host)
+
+	if request.reboot == PatchRebootAlways || request.reboot == PatchRebootIfRequired && result.RebootRequired {
+		at, err := c.scheduleReboot(ctx, request.rebootDelay, logger)
+		if err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("updates applied but the reboot failed: %w", err)), nil
+		}
+		result.RebootAt = at.Unix()
+	}
+	return c.BaseCommand.CreateStructuredResult(ctx, formatPatchApply(result), ContentTypePatchApply, result), nil
+}
+
+// scheduleReboot reboots the host in delay as power:reboot does, returning when it goes down
+func (c *PatchApplyCommand) scheduleReboot(ctx *ExecutionContext, delay time.Duration, logger *zap.Logger) (time.Time, error) {
+	argv, delay := powerArgs(PowerActionReboot, delay, patchRebootMessage)
+	at := time.Now().Add(delay)
+	if out, err := c.host.run(ctx.Context, argv); err != nil {
+		return at, fmt.Errorf("%s failed: %w: %s", argv[0], err, strings.TrimSpace(string(out)))
+	}
+	logger.Warn("Reboot scheduled to complete patching", zap.Time("at", at))
+
+	if c.announcer != nil {
+		if err := c.announcer.AnnouncePower(ctx.Context, PowerActionReboot, at); err != nil {
+			logger.Warn("Failed to announce the reboot to Nexus", zap.Error(err))
+		}
+	}
+	return at, nil
+}
+
+// formatPatchApply describes the updates applied
+func formatPatchApply(result *PatchApplyResult) string {
+	kind := "updates"
+	if result.SecurityOnly {
+		kind = "security updates"
+	}
+	output := fmt.Sprintf("Applied %d %s with %s, %d remaining", result.Applied, kind, result.Manager, result.Remaining)
+	if result.Applied == 0 {
+		output = fmt.Sprintf("No %s to apply with %s", kind, result.Manager)
+	}
+	switch {
+	case result.RebootAt > 0:
+		output += ", reboot scheduled at " + time.Unix(result.RebootAt, 0).Format(time.RFC3339)
+	case result.RebootRequired:
+		output += ", reboot required"
+	}
+	return output + "\n"
+}
@@ -0,0 +1,166 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// exitError is the error of a tool exiting with a status
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+const aptSimulation = `NOTE: This is only a simulation!
+Reading package lists...
+Inst libssl3 [3.0.2-0ubuntu1.10] (3.0.2-0ubuntu1.12 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+Inst curl [7.81.0-1ubuntu1.14] (7.81.0-1ubuntu1.15 Ubuntu:22.04/jammy-updates [amd64])
+Conf libssl3 (3.0.2-0ubuntu1.12 Ubuntu:22.04/jammy-updates, Ubuntu:22.04/jammy-security [amd64])
+`
+
+func TestParsePatchUpdates(t *testing.T) {
+	updates := parseAptUpgrade(aptSimulation)
+	want := []PatchUpdate{
+		{Name: "libssl3", Version: "3.0.2-0ubuntu1.12", Security: true},
+		{Name: "curl", Version: "7.81.0-1ubuntu1.15"},
+	}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("parseAptUpgrade = %+v, want %+v", updates, want)
+	}
+
+	updates = parseCheckUpdate(`
+openssl.x86_64                 1:3.0.7-25.el9_3          baseos
+kernel.x86_64                  5.14.0-362.18.1.el9_3     baseos
+vim-minimal.x86_64             2:8.2.2637-20.el9_1       baseos
+Obsoleting Packages
+grub2-tools.x86_64             1:2.06-70.el9_3           baseos
+`)
+	markSecurityUpdates(updates, `RHSA-2024:0310 Important/Sec. openssl-1:3.0.7-25.el9_3.x86_64
+RHSA-2024:0461 Important/Sec. kernel-5.14.0-362.18.1.el9_3.x86_64
+`)
+	want = []PatchUpdate{
+		{Name: "openssl", Version: "1:3.0.7-25.el9_3", Security: true},
+		{Name: "kernel", Version: "5.14.0-362.18.1.el9_3", Security: true},
+		{Name: "vim-minimal", Version: "2:8.2.2637-20.el9_1"},
+	}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("check-update = %+v, want %+v", updates, want)
+	}
+
+	updates = parseWindowsUpdates("KB5034441\tTrue\t2024-01 Security Update\r\nKB890830\tFalse\tMalicious Software Removal Tool\r\n")
+	if len(updates) != 2 || !updates[0].Security || updates[1].Version != "KB890830" || updates[1].Name != "Malicious Software Removal Tool" {
+		t.Errorf("Unexpected Windows updates %+v", updates)
+	}
+}
+
+func TestParsePatchApplyArgs(t *testing.T) {
+	request, err := parsePatchApplyArgs("")
+	if err != nil || request.securityOnly || request.reboot != PatchRebootNever || request.rebootDelay != DefaultPowerDelay {
+		t.Errorf("Unexpected default request: %+v %v", request, err)
+	}
+	request, err = parsePatchApplyArgs("--reboot if-required --security-only --reboot-delay 10m")
+	if err != nil || !request.securityOnly || request.reboot != PatchRebootIfRequired || request.rebootDelay.Minutes() != 10 {
+		t.Errorf("Unexpected request: %+v %v", request, err)
+	}
+	for _, args := range []string{"--reboot", "--reboot sometimes", "--reboot-delay 10s", "--force"} {
+		if _, err := parsePatchApplyArgs(args); err == nil {
+			t.Errorf("Expected %q rejected", args)
+		}
+	}
+}
+
+func TestPatchCommands(t *testing.T) {
+	upgraded, rebootPending := false, false
+	var ran []string
+	host := patchHost{
+		lookPath: func(file string) (string, error) {
+			if file == "apt-get" {
+				return "/usr/bin/apt-get", nil
+			}
+			return "", errors.New("not found")
+		},
+		stat: func(name string) (os.FileInfo, error) {
+			if rebootPending && name == "/var/run/reboot-required" {
+				return nil, nil
+			}
+			return nil, os.ErrNotExist
+		},
+		run: func(ctx context.Context, argv []string) ([]byte, error) {
+			command := strings.Join(argv, " ")
+			switch {
+			case strings.Contains(command, "apt-get -s"):
+				if upgraded {
+					return []byte("Reading package lists...\n"), nil
+				}
+				return []byte(aptSimulation), nil
+			case strings.Contains(command, "apt-get update"):
+			case strings.HasPrefix(command, "env DEBIAN_FRONTEND=noninteractive"):
+				upgraded, rebootPending = true, true
+			case argv[0] != "shutdown":
+				return nil, exitError(1)
+			}
+			ran = append(ran, command)
+			return nil, nil
+		},
+	}
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+
+	status := NewPatchStatusCommand()
+	status.host = host
+	result, err := status.Execute(ctx, "patch:status")
+	if err != nil || result.ExitCode != 0 || result.ContentType != ContentTypePatchStatus {
+		t.Fatalf("Unexpected result: %v %v", result, err)
+	}
+	var report PatchStatus
+	if err := json.Unmarshal([]byte(result.Structured), &report); err != nil {
+		t.Fatalf("Invalid structured payload: %v", err)
+	}
+	if report.Manager != "apt" || report.Updates != 2 || report.Security != 1 || report.RebootRequired || report.Packages[0].Name != "libssl3" {
+		t.Errorf("Unexpected status: %+v", report)
+	}
+	if len(ran) != 0 {
+		t.Errorf("Expected the index left alone without --refresh, ran %v", ran)
+	}
+
+	announcer := &fakeAnnouncer{}
+	apply := NewPatchApplyCommand(announcer)
+	apply.host = host
+	result, err = apply.Execute(ctx, "patch:apply --security-only --reboot if-required")
+	if err != nil || result.ExitCode != 0 || result.ContentType != ContentTypePatchApply {
+		t.Fatalf("Unexpected result: %v %v", result, err)
+	}
+	var applied PatchApplyResult
+	if err := json.Unmarshal([]byte(result.Structured), &applied); err != nil {
+		t.Fatalf("Invalid structured payload: %v", err)
+	}
+	if applied.Applied != 1 || applied.Remaining != 0 || !applied.RebootRequired || applied.RebootAt == 0 || !applied.SecurityOnly {
+		t.Errorf("Unexpected apply result: %+v", applied)
+	}
+	if len(ran) != 3 || ran[0] != "apt-get update -q" || !strings.HasSuffix(ran[1], "install --only-upgrade libssl3") || !strings.HasPrefix(ran[2], "shutdown") {
+		t.Errorf("Unexpected commands run: %q", ran)
+	}
+	if announcer.action != PowerActionReboot || announcer.at.Unix() != applied.RebootAt {
+		t.Errorf("Expected the reboot announced to Nexus, got %+v", announcer)
+	}
+
+	// Nothing left to apply: no upgrade and no reboot without policy
+	ran, announcer.action = nil, ""
+	result, _ = apply.Execute(ctx, "patch:apply")
+	if result.ExitCode != 0 || len(ran) != 1 || announcer.action != "" || !strings.Contains(result.Stdout, "No updates to apply with apt, reboot required") {
+		t.Errorf("Unexpected result without updates: %q %q", result.Stdout, ran)
+	}
+
+	host.lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	status.host = host
+	if result, _ := status.Execute(ctx, "patch:status"); result.ExitCode == 0 || !strings.Contains(result.Stderr, "no supported patch manager") {
+		t.Errorf("Expected patch:status to fail without manager, got %+v", result)
+	}
+}
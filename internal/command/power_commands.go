@@ -0,0 +1,240 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// ContentTypePower is the content type of power:reboot and power:shutdown structured results
+const ContentTypePower = "application/vnd.minexus.power+json"
+
+// Power actions of the power commands
+const (
+	PowerActionReboot   = "reboot"
+	PowerActionShutdown = "shutdown"
+)
+
+// Limits of the power commands. The minimum delay leaves the minion the time to report the result
+// and announce the power action to Nexus before the host goes down.
+const (
+	DefaultPowerDelay     = time.Minute
+	MinPowerDelay         = time.Minute
+	MaxPowerDelay         = 24 * time.Hour
+	MaxPowerMessageLength = 512 // longest message shutdown.exe accepts
+	// PowerAnnouncementGrace is how long after its time an announced power action is honoured: a
+	// minion still up past it is taken for one whose host didn't go down, its announcement dropped
+	PowerAnnouncementGrace = 15 * time.Minute
+)
+
+// PowerSchedule is the structured payload of power:reboot and power:shutdown
+type PowerSchedule struct {
+	Action  string `json:"action"`
+	At      int64  `json:"at"` // unix time the host goes down
+	Message string `json:"message,omitempty"`
+}
+
+// PowerAnnouncer tells Nexus about the power action scheduled on the host, so that the minion going
+// down is not taken for a failure. An empty action withdraws the announcement.
+type PowerAnnouncer interface {
+	AnnouncePower(ctx context.Context, action string, at time.Time) error
+}
+
+// powerRunner runs the platform command scheduling or cancelling a power action
+type powerRunner func(ctx context.Context, argv []string) ([]byte, error)
+
+// runPowerCommand runs argv on the host
+func runPowerCommand(ctx context.Context, argv []string) ([]byte, error) {
+	return exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+}
+
+// powerRequest holds the parsed arguments of power:reboot and power:shutdown
+type powerRequest struct {
+	delay   time.Duration
+	message string
+}
+
+// PowerCommand schedules a reboot or a shutdown of the minion host
+type PowerCommand struct {
+	*BaseCommand
+	action    string
+	announcer PowerAnnouncer
+	run       powerRunner
+}
+
+// NewPowerRebootCommand creates a new power:reboot command.
+// A nil announcer schedules the reboot without telling Nexus beforehand.
+func NewPowerRebootCommand(announcer PowerAnnouncer) *PowerCommand {
+	return newPowerCommand(PowerActionReboot, "Reboot the minion host after a delay, warning the logged-in users", Example{
+		Description: "Reboot the web servers in 5 minutes",
+		Command:     "command-send tag role=web power:reboot 5m kernel upgrade",
+		Expected:    "Logged-in users are warned and the hosts reboot in 5 minutes",
+	}, announcer)
+}
+
+// NewPowerShutdownCommand creates a new power:shutdown command.
+// A nil announcer schedules the shutdown without telling Nexus beforehand.
+func NewPowerShutdownCommand(announcer PowerAnnouncer) *PowerCommand {
+	return newPowerCommand(PowerActionShutdown, "Power off the minion host after a delay, warning the logged-in users", Example{
+		Description: "Power off a host being decommissioned in an hour",
+		Command:     "command-send minion abc123 power:shutdown 1h decommissioned, save your work",
+		Expected:    "Logged-in users are warned and the host powers off in an hour",
+	}, announcer)
+}
+
+// newPowerCommand creates the command scheduling action
+func newPowerCommand(action, description string, example Example, announcer PowerAnnouncer) *PowerCommand {
+	name := "power:" + action
+	base := NewBaseCommand(
+		name,
+		"power",
+		description,
+		name+" [delay] [message]",
+	).WithImpact(ImpactDisruptive).WithExamples(
+		example,
+		Example{
+			Description: "Call it off",
+			Command:     "command-send minion abc123 power:cancel",
+		},
+	).WithParameters(
+		Param{Name: "delay", Type: "duration", Required: false, Description: "Time before the host goes down, from 1m to 24h", Default: "1m"},
+		Param{Name: "message", Type: "string", Required: false, Description: "Message shown to the logged-in users (max 512 characters)"},
+	).WithNotes(
+		"Uses the shutdown tool of the host, which requires the minion to run with administrative rights",
+		"The delay is rounded up to the minute on Linux and macOS",
+		"The minion announces the power action to Nexus, which doesn't take the host going down for a failure",
+		"power:cancel calls off the scheduled action",
+	)
+
+	return &PowerCommand{
+		BaseCommand: base,
+		action:      action,
+		announcer:   announcer,
+		run:         runPowerCommand,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *PowerCommand) ValidateArgs(payload string) error {
+	_, err := parsePowerRequest(commandArgument(payload, c.Metadata().Name))
+	return err
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PowerCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "PowerCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	request, err := parsePowerRequest(commandArgument(payload, c.Metadata().Name))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	argv, delay := powerArgs(c.action, request.delay, request.message)
+	at := time.Now().Add(delay)
+	if out, err := c.run(ctx.Context, argv); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s failed: %w: %s", argv[0], err, strings.TrimSpace(string(out)))), nil
+	}
+	logger.Warn("Power action scheduled",
+		zap.String("action", c.action),
+		zap.Time("at", at),
+		zap.String("message", request.message))
+
+	output := fmt.Sprintf("%s scheduled at %s", c.action, at.Format(time.RFC3339))
+	if c.announcer != nil {
+		if err := c.announcer.AnnouncePower(ctx.Context, c.action, at); err != nil {
+			// The next heartbeat carries the announcement
+			logger.Warn("Failed to announce the power action to Nexus", zap.Error(err))
+			output += ", announced to Nexus at the next heartbeat"
+		}
+	}
+
+	schedule := PowerSchedule{Action: c.action, At: at.Unix(), Message: request.message}
+	return c.BaseCommand.CreateStructuredResult(ctx, output, ContentTypePower, schedule), nil
+}
+
+// parsePowerRequest parses the arguments of power:reboot and power:shutdown: an optional delay
+// followed by an optional message. A number without unit is refused rather than taken for the message.
+func parsePowerRequest(args string) (*powerRequest, error) {
+	request := &powerRequest{delay: DefaultPowerDelay}
+	fields := strings.Fields(args)
+	if len(fields) > 0 {
+		if _, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			return nil, fmt.Errorf("delay %s has no unit, e.g. %sm", fields[0], fields[0])
+		}
+		if delay, err := time.ParseDuration(fields[0]); err == nil {
+			if delay < MinPowerDelay || delay > MaxPowerDelay {
+				return nil, fmt.Errorf("delay must be between %s and %s", MinPowerDelay, MaxPowerDelay)
+			}
+			request.delay = delay
+			fields = fields[1:]
+		}
+	}
+
+	request.message = strings.Join(fields, " ")
+	if len(request.message) > MaxPowerMessageLength {
+		return nil, fmt.Errorf("message exceeds %d characters", MaxPowerMessageLength)
+	}
+	return request, nil
+}
+
+// PowerCancelCommand calls off the reboot or shutdown scheduled on the minion host
+type PowerCancelCommand struct {
+	*BaseCommand
+	announcer PowerAnnouncer
+	run       powerRunner
+}
+
+// NewPowerCancelCommand creates a new power:cancel command.
+// A nil announcer cancels the power action without telling Nexus.
+func NewPowerCancelCommand(announcer PowerAnnouncer) *PowerCancelCommand {
+	base := NewBaseCommand(
+		"power:cancel",
+		"power",
+		"Call off the reboot or shutdown scheduled on the minion host",
+		"power:cancel",
+	).WithExamples(
+		Example{
+			Description: "Keep the web servers running",
+			Command:     "command-send tag role=web power:cancel",
+			Expected:    "The scheduled reboot is called off and Nexus expects the hosts to stay up",
+		},
+	).WithNotes(
+		"Only power actions scheduled with the shutdown tool of the host can be called off",
+	)
+
+	return &PowerCancelCommand{
+		BaseCommand: base,
+		announcer:   announcer,
+		run:         runPowerCommand,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PowerCancelCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "PowerCancelCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	argv := powerCancelArgs()
+	if out, err := c.run(ctx.Context, argv); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s failed: %w: %s", argv[0], err, strings.TrimSpace(string(out)))), nil
+	}
+	logger.Info("Power action cancelled")
+
+	output := "scheduled power action cancelled"
+	if c.announcer != nil {
+		if err := c.announcer.AnnouncePower(ctx.Context, "", time.Time{}); err != nil {
+			logger.Warn("Failed to withdraw the power action announced to Nexus", zap.Error(err))
+			output += ", withdrawn from Nexus at the next heartbeat"
+		}
+	}
+	return c.BaseCommand.CreateSuccessResult(ctx, output), nil
+}
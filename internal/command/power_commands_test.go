@@ -0,0 +1,100 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeAnnouncer records the power actions announced to Nexus
+type fakeAnnouncer struct {
+	action string
+	at     time.Time
+	err    error
+}
+
+func (a *fakeAnnouncer) AnnouncePower(ctx context.Context, action string, at time.Time) error {
+	a.action, a.at = action, at
+	return a.err
+}
+
+func TestParsePowerRequest(t *testing.T) {
+	request, err := parsePowerRequest("")
+	if err != nil || request.delay != DefaultPowerDelay || request.message != "" {
+		t.Errorf("Unexpected default request: %v %v", request, err)
+	}
+	request, err = parsePowerRequest("15m  kernel upgrade, back soon")
+	if err != nil || request.delay != 15*time.Minute || request.message != "kernel upgrade, back soon" {
+		t.Errorf("Unexpected request: %v %v", request, err)
+	}
+	request, err = parsePowerRequest("maintenance tonight")
+	if err != nil || request.delay != DefaultPowerDelay || request.message != "maintenance tonight" {
+		t.Errorf("Expected a message without delay, got %v %v", request, err)
+	}
+
+	for _, args := range []string{"10s", "25h", "5", "15 kernel upgrade", "5m " + strings.Repeat("x", MaxPowerMessageLength+1)} {
+		if _, err := parsePowerRequest(args); err == nil {
+			t.Errorf("Expected %.20q rejected", args)
+		}
+	}
+}
+
+func TestPowerCommands(t *testing.T) {
+	announcer := &fakeAnnouncer{}
+	var ran [][]string
+	run := func(ctx context.Context, argv []string) ([]byte, error) {
+		ran = append(ran, argv)
+		return nil, nil
+	}
+
+	reboot := NewPowerRebootCommand(announcer)
+	reboot.run = run
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+	result, err := reboot.Execute(ctx, "power:reboot 90s kernel upgrade")
+	if err != nil || result.ExitCode != 0 || result.ContentType != ContentTypePower {
+		t.Fatalf("Unexpected result: %v %v", result, err)
+	}
+	var schedule PowerSchedule
+	if err := json.Unmarshal([]byte(result.Structured), &schedule); err != nil || schedule.Action != PowerActionReboot || schedule.Message != "kernel upgrade" {
+		t.Errorf("Unexpected structured payload: %s", result.Structured)
+	}
+	if announcer.action != PowerActionReboot || announcer.at.Unix() != schedule.At || time.Until(announcer.at) < 89*time.Second {
+		t.Errorf("Expected the reboot announced, got %s at %s", announcer.action, announcer.at)
+	}
+	if len(ran) != 1 || ran[0][0] != "shutdown" || ran[0][len(ran[0])-1] != "kernel upgrade" {
+		t.Errorf("Unexpected command: %v", ran)
+	}
+	if runtime.GOOS != "windows" && !slices.Contains(ran[0], "--") {
+		t.Errorf("Expected the options ended before the message, got %v", ran[0])
+	}
+
+	// A failed announcement is retried by the heartbeats, a failed schedule isn't announced
+	announcer.err = errors.New("nexus unreachable")
+	shutdown := NewPowerShutdownCommand(announcer)
+	shutdown.run = run
+	if result, _ := shutdown.Execute(ctx, "power:shutdown"); result.ExitCode != 0 || !strings.Contains(result.Stdout, "next heartbeat") {
+		t.Errorf("Expected the shutdown scheduled despite the announcement failure, got %v", result)
+	}
+	announcer.err = nil
+	announcer.action = ""
+	shutdown.run = func(ctx context.Context, argv []string) ([]byte, error) {
+		return []byte("Permission denied"), errors.New("exit status 1")
+	}
+	if result, _ := shutdown.Execute(ctx, "power:shutdown 5m"); result.ExitCode != 1 || !strings.Contains(result.Stderr, "Permission denied") || announcer.action != "" {
+		t.Errorf("Expected the failed shutdown not announced, got %v (%q)", result, announcer.action)
+	}
+
+	cancel := NewPowerCancelCommand(announcer)
+	cancel.run = run
+	announcer.action = PowerActionReboot
+	if result, _ := cancel.Execute(ctx, "power:cancel"); result.ExitCode != 0 || announcer.action != "" || !announcer.at.IsZero() {
+		t.Errorf("Expected the announcement withdrawn, got %v (%q)", result, announcer.action)
+	}
+}
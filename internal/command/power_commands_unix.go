@@ -0,0 +1,36 @@
+//go:build !windows
+// +build !windows
+
+package command
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// powerArgs returns the shutdown command scheduling action in delay, rounded up to the minute, with
+// message broadcast to the logged-in users, and the delay it applies. Options end before the time so
+// that a message starting with a dash isn't taken for one.
+func powerArgs(action string, delay time.Duration, message string) ([]string, time.Duration) {
+	minutes := int((delay + time.Minute - 1) / time.Minute)
+	flag := "-h"
+	if action == PowerActionReboot {
+		flag = "-r"
+	}
+
+	argv := []string{"shutdown", flag, "--", "+" + strconv.Itoa(minutes)}
+	if message != "" {
+		argv = append(argv, message)
+	}
+	return argv, time.Duration(minutes) * time.Minute
+}
+
+// powerCancelArgs returns the command calling off the scheduled shutdown: macOS has no cancel
+// option, the pending shutdown process is killed instead
+func powerCancelArgs() []string {
+	if runtime.GOOS == "darwin" {
+		return []string{"killall", "shutdown"}
+	}
+	return []string{"shutdown", "-c"}
+}
@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package command
+
+import (
+	"strconv"
+	"time"
+)
+
+// powerArgs returns the shutdown.exe command scheduling action in delay with message shown to the
+// logged-in users, and the delay it applies
+func powerArgs(action string, delay time.Duration, message string) ([]string, time.Duration) {
+	seconds := int(delay / time.Second)
+	flag := "/s"
+	if action == PowerActionReboot {
+		flag = "/r"
+	}
+
+	argv := []string{"shutdown", flag, "/t", strconv.Itoa(seconds)}
+	if message != "" {
+		argv = append(argv, "/c", message)
+	}
+	return argv, time.Duration(seconds) * time.Second
+}
+
+// powerCancelArgs returns the command aborting the scheduled shutdown
+func powerCancelArgs() []string {
+	return []string{"shutdown", "/a"}
+}
@@ -0,0 +1,396 @@
+package command
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// defaultTopCount is the number of processes process:top returns without count
+const defaultTopCount = 10
+
+// ProcessInfo describes a running process in the process:list and process:top payloads
+type ProcessInfo struct {
+	PID     int     `json:"pid"`
+	PPID    int     `json:"ppid"`
+	User    string  `json:"user"`
+	CPU     float64 `json:"cpu"`
+	Memory  float64 `json:"memory"` // percent of physical memory, KB on Windows
+	Command string  `json:"command"`
+}
+
+// PackageInfo describes an installed package in the pkg:list payload
+type PackageInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Manager string `json:"manager"`
+}
+
+// ProcessListCommand lists running processes
+type ProcessListCommand struct {
+	*BaseCommand
+}
+
+// NewProcessListCommand creates a new process list command
+func NewProcessListCommand() *ProcessListCommand {
+	base := NewBaseCommand(
+		"process:list",
+		"system",
+		"List running processes",
+		"process:list [filter]",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "List all processes",
+			Command:     "command-send minion abc123 process:list",
+			Expected:    "Returns PID, parent PID, user, CPU, memory and command of each process",
+		},
+		Example{
+			Description: "List nginx processes",
+			Command:     "command-send tag role=web process:list nginx",
+			Expected:    "Returns the processes whose command contains 'nginx'",
+		},
+	).WithParameters(
+		Param{Name: "filter", Type: "string", Required: false, Description: "Only list processes whose command contains this text"},
+	).WithNotes(
+		"Uses ps on Unix systems and tasklist on Windows",
+		"The result carries a structured JSON payload in addition to the text table",
+	)
+
+	return &ProcessListCommand{
+		BaseCommand: base,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ProcessListCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	filter := commandArgument(payload, "process:list")
+
+	processes, err := listProcesses(ctx.Context)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	if filter != "" {
+		filtered := make([]ProcessInfo, 0, len(processes))
+		for _, process := range processes {
+			if strings.Contains(process.Command, filter) {
+				filtered = append(filtered, process)
+			}
+		}
+		processes = filtered
+	}
+
+	return c.BaseCommand.CreateStructuredResult(ctx, formatProcessTable(processes), ContentTypeProcessList, processes), nil
+}
+
+// ProcessTopCommand lists the processes using the most CPU or memory
+type ProcessTopCommand struct {
+	*BaseCommand
+}
+
+// NewProcessTopCommand creates a new process top command
+func NewProcessTopCommand() *ProcessTopCommand {
+	base := NewBaseCommand(
+		"process:top",
+		"system",
+		"List the processes using the most CPU or memory",
+		"process:top [count] [cpu|memory]",
+	).WithImpact(ImpactReadOnly).WithVersion(2).WithExamples(
+		Example{
+			Description: "Show the 10 processes using the most CPU",
+			Command:     "command-send minion abc123 process:top",
+			Expected:    "Returns the busiest processes, highest CPU first",
+		},
+		Example{
+			Description: "Show the 5 processes using the most memory",
+			Command:     "command-send tag role=db process:top 5 memory",
+			Expected:    "Returns the 5 processes using the most memory, highest first",
+		},
+	).WithParameters(
+		Param{Name: "count", Type: "int", Required: false, Default: "10", Description: "Number of processes returned"},
+		Param{Name: "sort", Type: "string", Required: false, Default: "cpu", Description: "Rank processes by cpu or memory"},
+	).WithNotes(
+		"Uses ps on Unix systems, where CPU is the share of CPU time since the process started",
+		"tasklist doesn't report CPU usage, processes are always ranked by memory on Windows",
+		"The result carries a structured JSON payload in addition to the text table",
+	)
+
+	return &ProcessTopCommand{
+		BaseCommand: base,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ProcessTopCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	count, sortBy, err := parseProcessTopArguments(commandArgument(payload, "process:top"))
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	if runtime.GOOS == "windows" {
+		sortBy = "memory"
+	}
+
+	processes, err := listProcesses(ctx.Context)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	processes = topProcesses(processes, sortBy, count)
+
+	return c.BaseCommand.CreateStructuredResult(ctx, formatProcessTable(processes), ContentTypeProcessTop, processes), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *ProcessTopCommand) ValidateArgs(payload string) error {
+	_, _, err := parseProcessTopArguments(commandArgument(payload, "process:top"))
+	return err
+}
+
+// parseProcessTopArguments parses the optional count and sort key of process:top, in any order
+func parseProcessTopArguments(arguments string) (int, string, error) {
+	count, sortBy := defaultTopCount, "cpu"
+	for _, argument := range strings.Fields(arguments) {
+		switch argument {
+		case "cpu":
+			sortBy = "cpu"
+		case "memory", "mem":
+			sortBy = "memory"
+		default:
+			n, err := strconv.Atoi(argument)
+			if err != nil || n <= 0 {
+				return 0, "", fmt.Errorf("invalid process:top argument '%s': use a positive count, cpu or memory", argument)
+			}
+			count = n
+		}
+	}
+	return count, sortBy, nil
+}
+
+// topProcesses returns the count processes using the most CPU or memory, the other resource breaking ties
+func topProcesses(processes []ProcessInfo, sortBy string, count int) []ProcessInfo {
+	primary := func(p ProcessInfo) float64 { return p.CPU }
+	secondary := func(p ProcessInfo) float64 { return p.Memory }
+	if sortBy == "memory" {
+		primary, secondary = secondary, primary
+	}
+
+	sort.SliceStable(processes, func(i, j int) bool {
+		if primary(processes[i]) != primary(processes[j]) {
+			return primary(processes[i]) > primary(processes[j])
+		}
+		return secondary(processes[i]) > secondary(processes[j])
+	})
+	if len(processes) > count {
+		processes = processes[:count]
+	}
+	return processes
+}
+
+// formatProcessTable formats processes as the text output of the process commands
+func formatProcessTable(processes []ProcessInfo) string {
+	var output strings.Builder
+	fmt.Fprintf(&output, "%-8s %-8s %-12s %6s %6s %s\n", "PID", "PPID", "USER", "CPU", "MEM", "COMMAND")
+	for _, process := range processes {
+		fmt.Fprintf(&output, "%-8d %-8d %-12s %6.1f %6.1f %s\n",
+			process.PID, process.PPID, process.User, process.CPU, process.Memory, process.Command)
+	}
+	return output.String()
+}
+
+// PackageListCommand lists installed packages
+type PackageListCommand struct {
+	*BaseCommand
+}
+
+// NewPackageListCommand creates a new package list command
+func NewPackageListCommand() *PackageListCommand {
+	base := NewBaseCommand(
+		"pkg:list",
+		"system",
+		"List installed packages",
+		"pkg:list [filter]",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "List installed packages",
+			Command:     "command-send all pkg:list",
+			Expected:    "Returns the name and version of each installed package",
+		},
+		Example{
+			Description: "Check the installed openssl version",
+			Command:     "command-send tag env=prod pkg:list openssl",
+			Expected:    "Returns the packages whose name contains 'openssl'",
+		},
+	).WithParameters(
+		Param{Name: "filter", Type: "string", Required: false, Description: "Only list packages whose name contains this text"},
+	).WithNotes(
+		"Supports dpkg, rpm and Homebrew, the first available package manager is used",
+		"The result carries a structured JSON payload in addition to the text table",
+	)
+
+	return &PackageListCommand{
+		BaseCommand: base,
+	}
+}
+
+// packageManager describes how to list packages with a given tool
+type packageManager struct {
+	name  string
+	cmd   string
+	args  []string
+	parse func(output string) []PackageInfo
+}
+
+// packageManagers are tried in order until one is available
+var packageManagers = []packageManager{
+	{name: "dpkg", cmd: "dpkg-query", args: []string{"-W", "-f=${Package}\t${Version}\n"}, parse: parseTabSeparatedPackages("dpkg")},
+	{name: "rpm", cmd: "rpm", args: []string{"-qa", "--qf", "%{NAME}\t%{VERSION}-%{RELEASE}\n"}, parse: parseTabSeparatedPackages("rpm")},
+	{name: "brew", cmd: "brew", args: []string{"list", "--versions"}, parse: parseBrewPackages},
+}
+
+// Execute implements ExecutableCommand interface
+func (c *PackageListCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	filter := commandArgument(payload, "pkg:list")
+
+	var packages []PackageInfo
+	found := false
+	for _, manager := range packageManagers {
+		if _, err := exec.LookPath(manager.cmd); err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx.Context, manager.cmd, manager.args...).Output()
+		if err != nil {
+			return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("%s failed: %w", manager.cmd, err)), nil
+		}
+		packages = manager.parse(string(out))
+		found = true
+		break
+	}
+	if !found {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("no supported package manager found")), nil
+	}
+
+	filtered := make([]PackageInfo, 0, len(packages))
+	for _, pkg := range packages {
+		if filter == "" || strings.Contains(pkg.Name, filter) {
+			filtered = append(filtered, pkg)
+		}
+	}
+
+	var output strings.Builder
+	for _, pkg := range filtered {
+		fmt.Fprintf(&output, "%-40s %s\n", pkg.Name, pkg.Version)
+	}
+
+	return c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypePackageList, filtered), nil
+}
+
+// commandArgument returns the payload text following the command name
+func commandArgument(payload, name string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(payload), name))
+}
+
+// listProcesses returns the running processes using the platform tool
+func listProcesses(ctx context.Context) ([]ProcessInfo, error) {
+	if runtime.GOOS == "windows" {
+		out, err := exec.CommandContext(ctx, "tasklist", "/fo", "csv", "/nh").Output()
+		if err != nil {
+			return nil, fmt.Errorf("tasklist failed: %w", err)
+		}
+		return parseTasklistOutput(string(out))
+	}
+
+	out, err := exec.CommandContext(ctx, "ps", "-axo", "pid=,ppid=,user=,pcpu=,pmem=,comm=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ps failed: %w", err)
+	}
+	return parsePSOutput(string(out)), nil
+}
+
+// parsePSOutput parses "ps -axo pid=,ppid=,user=,pcpu=,pmem=,comm=" output
+func parsePSOutput(output string) []ProcessInfo {
+	processes := make([]ProcessInfo, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		cpu, _ := strconv.ParseFloat(fields[3], 64)
+		mem, _ := strconv.ParseFloat(fields[4], 64)
+		processes = append(processes, ProcessInfo{
+			PID:     pid,
+			PPID:    ppid,
+			User:    fields[2],
+			CPU:     cpu,
+			Memory:  mem,
+			Command: strings.Join(fields[5:], " "), // command names may contain spaces
+		})
+	}
+	return processes
+}
+
+// parseTasklistOutput parses "tasklist /fo csv /nh" output
+func parseTasklistOutput(output string) ([]ProcessInfo, error) {
+	records, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tasklist output: %w", err)
+	}
+
+	processes := make([]ProcessInfo, 0, len(records))
+	for _, record := range records {
+		if len(record) < 5 {
+			continue
+		}
+		pid, err := strconv.Atoi(record[1])
+		if err != nil {
+			continue
+		}
+		// Memory is reported as "12,345 K"
+		memText := strings.NewReplacer(",", "", ".", "", " K", "", " ", "").Replace(record[4])
+		mem, _ := strconv.ParseFloat(strings.TrimSpace(memText), 64)
+		processes = append(processes, ProcessInfo{
+			PID:     pid,
+			Memory:  mem,
+			Command: record[0],
+		})
+	}
+	return processes, nil
+}
+
+// parseTabSeparatedPackages returns a parser for "name<TAB>version" lines
+func parseTabSeparatedPackages(manager string) func(string) []PackageInfo {
+	return func(output string) []PackageInfo {
+		packages := make([]PackageInfo, 0)
+		for _, line := range strings.Split(output, "\n") {
+			parts := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				continue
+			}
+			packages = append(packages, PackageInfo{Name: parts[0], Version: parts[1], Manager: manager})
+		}
+		return packages
+	}
+}
+
+// parseBrewPackages parses "brew list --versions" output ("name version [version...]")
+func parseBrewPackages(output string) []PackageInfo {
+	packages := make([]PackageInfo, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		packages = append(packages, PackageInfo{Name: fields[0], Version: fields[len(fields)-1], Manager: "brew"})
+	}
+	return packages
+}
@@ -0,0 +1,140 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParsePSOutput(t *testing.T) {
+	output := `    1     0 root       0.0  0.1 systemd
+  812     1 www-data   1.5  2.3 nginx: worker process
+  bad line
+`
+	processes := parsePSOutput(output)
+	if len(processes) != 2 {
+		t.Fatalf("Expected 2 processes, got %d: %+v", len(processes), processes)
+	}
+	if processes[1].PID != 812 || processes[1].PPID != 1 || processes[1].User != "www-data" ||
+		processes[1].CPU != 1.5 || processes[1].Command != "nginx: worker process" {
+		t.Errorf("Unexpected process: %+v", processes[1])
+	}
+}
+
+func TestParseTasklistOutput(t *testing.T) {
+	output := `"System Idle Process","0","Services","0","8 K"
+"svchost.exe","1234","Services","0","12,345 K"
+`
+	processes, err := parseTasklistOutput(output)
+	if err != nil {
+		t.Fatalf("parseTasklistOutput failed: %v", err)
+	}
+	if len(processes) != 2 || processes[1].PID != 1234 || processes[1].Memory != 12345 || processes[1].Command != "svchost.exe" {
+		t.Errorf("Unexpected processes: %+v", processes)
+	}
+}
+
+func TestParseProcessTopArguments(t *testing.T) {
+	tests := []struct {
+		arguments string
+		count     int
+		sortBy    string
+		wantErr   bool
+	}{
+		{"", defaultTopCount, "cpu", false},
+		{"5", 5, "cpu", false},
+		{"memory 3", 3, "memory", false},
+		{"20 mem", 20, "memory", false},
+		{"0", 0, "", true},
+		{"disk", 0, "", true},
+	}
+	for _, tt := range tests {
+		count, sortBy, err := parseProcessTopArguments(tt.arguments)
+		if (err != nil) != tt.wantErr || count != tt.count || sortBy != tt.sortBy {
+			t.Errorf("parseProcessTopArguments(%q) = %d, %q, %v", tt.arguments, count, sortBy, err)
+		}
+	}
+}
+
+func TestTopProcesses(t *testing.T) {
+	processes := []ProcessInfo{
+		{PID: 1, CPU: 0.5, Memory: 10},
+		{PID: 2, CPU: 20, Memory: 1},
+		{PID: 3, CPU: 0.5, Memory: 30},
+		{PID: 4, CPU: 5, Memory: 2},
+	}
+
+	top := topProcesses(append([]ProcessInfo(nil), processes...), "cpu", 3)
+	if len(top) != 3 || top[0].PID != 2 || top[1].PID != 4 || top[2].PID != 3 {
+		t.Errorf("Unexpected CPU ranking: %+v", top)
+	}
+	top = topProcesses(append([]ProcessInfo(nil), processes...), "memory", 10)
+	if len(top) != 4 || top[0].PID != 3 || top[1].PID != 1 || top[3].PID != 2 {
+		t.Errorf("Unexpected memory ranking: %+v", top)
+	}
+}
+
+func TestProcessTopStructuredResult(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+
+	result, err := NewProcessTopCommand().Execute(ctx, "process:top 2 memory")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Skipf("Process listing unavailable: %s", result.Stderr)
+	}
+	if result.ContentType != ContentTypeProcessTop {
+		t.Errorf("Expected content type %s, got %s", ContentTypeProcessTop, result.ContentType)
+	}
+
+	var processes []ProcessInfo
+	if err := json.Unmarshal([]byte(result.Structured), &processes); err != nil {
+		t.Fatalf("Expected valid structured payload: %v", err)
+	}
+	if len(processes) == 0 || len(processes) > 2 {
+		t.Errorf("Expected at most 2 processes, got %+v", processes)
+	}
+
+	result, _ = NewProcessTopCommand().Execute(ctx, "process:top disk")
+	if result.ExitCode == 0 {
+		t.Error("Expected an invalid sort key to fail")
+	}
+}
+
+func TestParsePackages(t *testing.T) {
+	dpkg := parseTabSeparatedPackages("dpkg")("openssl\t3.0.2-0ubuntu1\nlibc6\t2.35-0ubuntu3\n\n")
+	if len(dpkg) != 2 || dpkg[0].Name != "openssl" || dpkg[0].Version != "3.0.2-0ubuntu1" || dpkg[0].Manager != "dpkg" {
+		t.Errorf("Unexpected dpkg packages: %+v", dpkg)
+	}
+
+	brew := parseBrewPackages("git 2.42.0\npython@3.11 3.11.5 3.11.6\n")
+	if len(brew) != 2 || brew[1].Name != "python@3.11" || brew[1].Version != "3.11.6" {
+		t.Errorf("Unexpected brew packages: %+v", brew)
+	}
+}
+
+func TestSystemInfoStructuredResult(t *testing.T) {
+	ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+
+	result, err := NewSystemInfoCommand().Execute(ctx, "system:info")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.ContentType != ContentTypeSystemInfo {
+		t.Errorf("Expected content type %s, got %s", ContentTypeSystemInfo, result.ContentType)
+	}
+
+	var info SystemInfo
+	if err := json.Unmarshal([]byte(result.Structured), &info); err != nil {
+		t.Fatalf("Expected valid structured payload: %v", err)
+	}
+	if info.OS == "" || info.Arch == "" || info.Goroutines == 0 {
+		t.Errorf("Unexpected structured payload: %+v", info)
+	}
+	if result.Stdout == "" {
+		t.Error("Expected text output to be kept alongside the structured payload")
+	}
+}
@@ -14,6 +14,12 @@ type ExecutableCommand interface {
 	Metadata() Definition
 }
 
+// ArgumentValidator is implemented by the commands able to check their arguments without
+// running, so that consoles can report argument errors before sending them
+type ArgumentValidator interface {
+	ValidateArgs(payload string) error
+}
+
 // Registry provides a cleaner, self-registering command system
 type Registry struct {
 	commands map[string]ExecutableCommand
@@ -46,10 +52,12 @@ func (r *Registry) Execute(ctx *ExecutionContext, command *pb.Command) (*pb.Comm
 		return cmd.Execute(ctx, command.Payload)
 	}
 
-	// Pattern-based lookup for commands like "system:info"
+	// Pattern-based lookup for commands with arguments like "file:get /etc/hosts"
 	if strings.Contains(command.Payload, ":") {
-		if cmd, exists := r.commands[command.Payload]; exists {
-			return cmd.Execute(ctx, command.Payload)
+		if fields := strings.Fields(command.Payload); len(fields) > 0 {
+			if cmd, exists := r.commands[fields[0]]; exists {
+				return cmd.Execute(ctx, command.Payload)
+			}
 		}
 	}
 
@@ -77,6 +85,35 @@ func (r *Registry) Execute(ctx *ExecutionContext, command *pb.Command) (*pb.Comm
 	}, fmt.Errorf("command not found: %s", command.Payload)
 }
 
+// Validate checks the arguments of a payload without running it, with the command's own parsing
+// when it implements ArgumentValidator, otherwise against its usage: a command whose usage is its
+// bare name takes no arguments. Payloads not naming a registered command, such as shell
+// commands, are not checked.
+func (r *Registry) Validate(payload string) error {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return fmt.Errorf("command cannot be empty")
+	}
+	if !strings.Contains(fields[0], ":") {
+		return nil
+	}
+
+	r.mutex.RLock()
+	cmd, exists := r.commands[fields[0]]
+	r.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	if validator, ok := cmd.(ArgumentValidator); ok {
+		return validator.ValidateArgs(payload)
+	}
+	if metadata := cmd.Metadata(); metadata.Usage == metadata.Name && len(fields) > 1 {
+		return fmt.Errorf("%s takes no arguments", metadata.Name)
+	}
+	return nil
+}
+
 // GetCommand returns a command by name
 func (r *Registry) GetCommand(name string) (ExecutableCommand, bool) {
 	r.mutex.RLock()
@@ -148,6 +185,7 @@ func (r *Registry) FormatCommandHelp(commandName string) string {
 
 	help.WriteString(fmt.Sprintf("Command: %s\n", metadata.Name))
 	help.WriteString(fmt.Sprintf("Category: %s\n", metadata.Category))
+	help.WriteString(fmt.Sprintf("Impact: %s\n", metadata.Impact))
 	help.WriteString(fmt.Sprintf("Description: %s\n\n", metadata.Description))
 
 	help.WriteString("Usage:\n")
@@ -0,0 +1,89 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryValidate(t *testing.T) {
+	registry := SetupCommands(15 * time.Second)
+
+	valid := []string{
+		"file:grep -i -C 2 root login /etc/ssh",
+		"file:compress /tmp/logs.tar.gz /var/log/*.log",
+		"file:hash -a sha512 /etc",
+		"file:get /etc/hosts",
+		"file:copy /etc/hosts /tmp/hosts",
+		"process:top memory 5",
+		"config:set log_level debug",
+		"schedule:add @daily uptime",
+		"lock:acquire deploy",
+		"watch:add /etc/ssh/sshd_config systemctl reload sshd",
+		"docker-compose:up /opt/app --build web",
+		"system:info",
+		"net:ping -c 2 10.0.0.5",
+		"net:port-check -u dns.internal 53",
+		"net:dns @10.0.0.2 example.com MX",
+		"uname -a",
+		"ls /tmp:/var",
+	}
+	for _, payload := range valid {
+		if err := registry.Validate(payload); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", payload, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"file:grep -x root /etc":         "unknown option -x",
+		"file:grep -C many root /etc":    "invalid -C value",
+		"file:grep root":                 "usage: file:grep",
+		"file:compress /tmp/logs.rar /a": "archive",
+		"file:extract /tmp/a.tar.gz":     "usage: file:extract",
+		"file:hash -a crc32 /etc":        "crc32",
+		"file:copy /etc/hosts":           "failed to parse request",
+		"file:get ../etc/passwd":         "path traversal",
+		"process:top fastest":            "invalid process:top argument",
+		"config:set log_level":           "usage: config:set",
+		"config:get a b":                 "usage: config:get",
+		"schedule:add 0 3 * *":           "usage: schedule:add",
+		"schedule:remove":                "usage: schedule:remove",
+		"watch:add /etc/hosts":           "usage: watch:add",
+		"watch:add ../etc/hosts uptime":  "path traversal",
+		"watch:remove":                   "usage: watch:remove",
+		"lock:acquire bad/name":          "invalid lock name",
+		"docker-compose:ps":              "path is required",
+		"certs:rotate {\"cert\": \"x\"}": "cert, key and ca are required",
+		"system:info verbose":            "system:info takes no arguments",
+		"net:ping -c 2":                  "usage: net:ping",
+		"net:traceroute -m 5 -x":         "unknown option -x",
+		"net:port-check db 70000":        "invalid port",
+	}
+	for payload, expected := range invalid {
+		err := registry.Validate(payload)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q to fail with %q, got %v", payload, expected, err)
+		}
+	}
+}
+
+func TestRegistryDiskTargets(t *testing.T) {
+	registry := SetupCommands(time.Second)
+	for payload, expected := range map[string]string{
+		"file:copy /etc/hosts /backup/hosts":                "/backup/hosts",
+		"file:extract -o /tmp/release.tar.gz /srv/app":      "/srv/app",
+		"file:compress /tmp/logs.tar.gz /var/log/app/*.log": "/tmp/logs.tar.gz",
+		"patch:apply --security-only":                       "",
+		`state:apply {"packages": ["nginx"]}`:               "",
+	} {
+		paths, heavy := registry.DiskTargets(payload)
+		if !heavy || strings.Join(paths, " ") != expected {
+			t.Errorf("%q: expected disk-heavy writing to %q, got %v %v", payload, expected, paths, heavy)
+		}
+	}
+	for _, payload := range []string{"system:info", "file:get /etc/hosts", "df -h", ""} {
+		if _, heavy := registry.DiskTargets(payload); heavy {
+			t.Errorf("%q: expected not disk-heavy", payload)
+		}
+	}
+}
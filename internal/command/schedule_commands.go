@@ -0,0 +1,233 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeScheduleList is the content type of schedule:list structured results
+const ContentTypeScheduleList = "application/vnd.minexus.schedule-list+json"
+
+// ScheduledTask is a command run periodically by the minion itself, even when disconnected from Nexus
+type ScheduledTask struct {
+	ID            string `json:"id"`
+	Schedule      string `json:"schedule"` // cron expression
+	Payload       string `json:"payload"`
+	CreatedAt     int64  `json:"created_at"`
+	LastRun       int64  `json:"last_run,omitempty"`
+	LastCommandID string `json:"last_command_id,omitempty"`
+	LastExitCode  int32  `json:"last_exit_code,omitempty"`
+}
+
+// TaskScheduler stores and runs the scheduled tasks of a minion
+type TaskScheduler interface {
+	Add(schedule, payload string) (*ScheduledTask, error)
+	List() []ScheduledTask
+	Remove(id string) error
+}
+
+// ScheduleAddCommand schedules a command on the minion
+type ScheduleAddCommand struct {
+	*BaseCommand
+	scheduler TaskScheduler
+}
+
+// NewScheduleAddCommand creates a new schedule:add command.
+// A nil scheduler registers the command for validation and help purposes only.
+func NewScheduleAddCommand(scheduler TaskScheduler) *ScheduleAddCommand {
+	base := NewBaseCommand(
+		"schedule:add",
+		"schedule",
+		"Schedule a command run by the minion itself, even when disconnected from Nexus",
+		"schedule:add <minute> <hour> <day-of-month> <month> <day-of-week> <command>",
+	).WithExamples(
+		Example{
+			Description: "Collect system information every 15 minutes",
+			Command:     "command-send all schedule:add */15 * * * * system:info",
+			Expected:    "Returns the ID of the scheduled task",
+		},
+		Example{
+			Description: "Run a shell command every night",
+			Command:     "command-send minion abc123 schedule:add @daily df -h",
+		},
+	).WithParameters(
+		Param{Name: "schedule", Type: "string", Required: true, Description: "Five field cron expression, or @hourly, @daily, @weekly, @monthly, @yearly"},
+		Param{Name: "command", Type: "string", Required: true, Description: "Command to run, as sent with command-send"},
+	).WithNotes(
+		"Times are evaluated in the minion's local time zone",
+		"Results are reported to Nexus, buffered while the minion is disconnected",
+		"Tasks are persisted when the minion has a schedule file",
+	)
+
+	return &ScheduleAddCommand{
+		BaseCommand: base,
+		scheduler:   scheduler,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ScheduleAddCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.scheduler == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("scheduled tasks are not enabled on this minion")), nil
+	}
+
+	schedule, command, err := ParseScheduleAdd(payload)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	task, err := c.scheduler.Add(schedule, command)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	return c.BaseCommand.CreateSuccessResult(ctx, fmt.Sprintf("Scheduled task %s: '%s' at '%s'", task.ID, task.Payload, task.Schedule)), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface. The cron expression is checked by the
+// minion scheduler.
+func (c *ScheduleAddCommand) ValidateArgs(payload string) error {
+	_, _, err := ParseScheduleAdd(payload)
+	return err
+}
+
+// ParseScheduleAdd splits a schedule:add payload into its cron expression and command.
+// The command is kept verbatim.
+func ParseScheduleAdd(payload string) (string, string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(payload), "schedule:add"))
+
+	fieldCount := 5
+	if strings.HasPrefix(rest, "@") {
+		fieldCount = 1
+	}
+
+	fields := make([]string, 0, fieldCount)
+	for len(fields) < fieldCount {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			end = len(rest)
+		}
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+	}
+
+	command := strings.TrimSpace(rest)
+	if len(fields) < fieldCount || command == "" {
+		return "", "", fmt.Errorf("usage: schedule:add <minute> <hour> <day-of-month> <month> <day-of-week> <command>")
+	}
+	return strings.Join(fields, " "), command, nil
+}
+
+// ScheduleListCommand lists the tasks scheduled on the minion
+type ScheduleListCommand struct {
+	*BaseCommand
+	scheduler TaskScheduler
+}
+
+// NewScheduleListCommand creates a new schedule:list command
+func NewScheduleListCommand(scheduler TaskScheduler) *ScheduleListCommand {
+	base := NewBaseCommand(
+		"schedule:list",
+		"schedule",
+		"List the tasks scheduled on the minion",
+		"schedule:list",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "List scheduled tasks",
+			Command:     "command-send all schedule:list",
+			Expected:    "Returns the scheduled tasks with their last run",
+		},
+	)
+
+	return &ScheduleListCommand{
+		BaseCommand: base,
+		scheduler:   scheduler,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ScheduleListCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.scheduler == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("scheduled tasks are not enabled on this minion")), nil
+	}
+
+	tasks := c.scheduler.List()
+	if len(tasks) == 0 {
+		return c.BaseCommand.CreateStructuredResult(ctx, "No scheduled tasks", ContentTypeScheduleList, tasks), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%-10s %-20s %-20s %s\n", "ID", "SCHEDULE", "LAST RUN", "COMMAND"))
+	for _, task := range tasks {
+		lastRun := "never"
+		if task.LastRun != 0 {
+			lastRun = time.Unix(task.LastRun, 0).Format("2006-01-02 15:04")
+			if task.LastExitCode != 0 {
+				lastRun += fmt.Sprintf(" (%d)", task.LastExitCode)
+			}
+		}
+		output.WriteString(fmt.Sprintf("%-10s %-20s %-20s %s\n", task.ID, task.Schedule, lastRun, task.Payload))
+	}
+
+	return c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeScheduleList, tasks), nil
+}
+
+// ScheduleRemoveCommand removes a task scheduled on the minion
+type ScheduleRemoveCommand struct {
+	*BaseCommand
+	scheduler TaskScheduler
+}
+
+// NewScheduleRemoveCommand creates a new schedule:remove command
+func NewScheduleRemoveCommand(scheduler TaskScheduler) *ScheduleRemoveCommand {
+	base := NewBaseCommand(
+		"schedule:remove",
+		"schedule",
+		"Remove a task scheduled on the minion",
+		"schedule:remove <task-id>",
+	).WithExamples(
+		Example{
+			Description: "Remove a scheduled task",
+			Command:     "command-send minion abc123 schedule:remove 1a2b3c4d",
+		},
+	).WithParameters(
+		Param{Name: "task-id", Type: "string", Required: true, Description: "ID returned by schedule:add or shown by schedule:list"},
+	)
+
+	return &ScheduleRemoveCommand{
+		BaseCommand: base,
+		scheduler:   scheduler,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *ScheduleRemoveCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.scheduler == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("scheduled tasks are not enabled on this minion")), nil
+	}
+
+	if err := c.ValidateArgs(payload); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	fields := strings.Fields(payload)
+	if err := c.scheduler.Remove(fields[1]); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	return c.BaseCommand.CreateSuccessResult(ctx, fmt.Sprintf("Scheduled task %s removed", fields[1])), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *ScheduleRemoveCommand) ValidateArgs(payload string) error {
+	if len(strings.Fields(payload)) != 2 {
+		return fmt.Errorf("usage: schedule:remove <task-id>")
+	}
+	return nil
+}
@@ -9,6 +9,9 @@ func SetupCommands(shellTimeout time.Duration) *Registry {
 	// Register system commands
 	registry.Register(NewSystemInfoCommand())
 	registry.Register(NewSystemOSCommand())
+	registry.Register(NewProcessListCommand())
+	registry.Register(NewProcessTopCommand())
+	registry.Register(NewPackageListCommand())
 
 	// Register logging commands
 	registry.Register(NewLoggingLevelCommand())
@@ -20,6 +23,11 @@ func SetupCommands(shellTimeout time.Duration) *Registry {
 	registry.Register(NewFileCopyCommand())
 	registry.Register(NewFileMoveCommand())
 	registry.Register(NewFileInfoCommand())
+	registry.Register(NewFileGrepCommand())
+	registry.Register(NewFileCompressCommand())
+	registry.Register(NewFileExtractCommand())
+	registry.Register(NewFileHashCommand())
+	registry.Register(NewFileVerifyCommand())
 	registry.Register(NewFileCommand()) // Unified file command for routing
 
 	// Register shell commands (migrated to simplified system)
@@ -34,5 +42,46 @@ func SetupCommands(shellTimeout time.Duration) *Registry {
 	registry.Register(NewDockerComposeViewCommand())
 	registry.Register(NewDockerComposeCommand()) // Unified docker-compose command for routing
 
+	// Register network diagnostic commands
+	registry.Register(NewPingCommand())
+	registry.Register(NewTracerouteCommand())
+	registry.Register(NewPortCheckCommand())
+	registry.Register(NewDNSCommand())
+	registry.Register(NewSpeedTestCommand())
+
+	// Register desired state commands
+	registry.Register(NewStateApplyCommand())
+
+	// Register patch commands (reboots announced to Nexus once enabled by the minion)
+	registry.Register(NewPatchStatusCommand())
+	registry.Register(NewPatchApplyCommand(nil))
+
+	// Register certificate commands (rotation is enabled by the minion at startup)
+	registry.Register(NewCertsRotateCommand(nil))
+
+	// Register scheduled task commands (the scheduler is enabled by the minion at startup)
+	registry.Register(NewScheduleAddCommand(nil))
+	registry.Register(NewScheduleListCommand(nil))
+	registry.Register(NewScheduleRemoveCommand(nil))
+
+	// Register file watcher commands (the watcher is enabled by the minion at startup)
+	registry.Register(NewWatchAddCommand(nil))
+	registry.Register(NewWatchListCommand(nil))
+	registry.Register(NewWatchRemoveCommand(nil))
+
+	// Register runtime configuration commands (enabled by the minion)
+	registry.Register(NewConfigSetCommand(nil))
+	registry.Register(NewConfigGetCommand(nil))
+	registry.Register(NewConfigShowCommand(nil))
+
+	// Register power commands (announced to Nexus once enabled by the minion)
+	registry.Register(NewPowerRebootCommand(nil))
+	registry.Register(NewPowerShutdownCommand(nil))
+	registry.Register(NewPowerCancelCommand(nil))
+
+	// Register host lock commands (answered by Nexus)
+	registry.Register(NewLockAcquireCommand())
+	registry.Register(NewLockReleaseCommand())
+
 	return registry
 }
@@ -2,9 +2,12 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,18 +21,77 @@ type ShellRequest struct {
 	Command string `json:"command"`
 	Shell   string `json:"shell,omitempty"`   // Optional: specify shell (sh, bash, cmd, powershell)
 	Timeout int    `json:"timeout,omitempty"` // Optional: timeout in seconds
+	RunAs   string `json:"run_as,omitempty"`  // Optional: user to run the command as (minion must run as root, not on Windows)
+	// Optional: resource limits and isolation of the command (Linux minions running as root)
+	Sandbox *SandboxOptions `json:"sandbox,omitempty"`
+	// Optional: environment variables added to the one of the minion, overriding those set by Nexus
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// SandboxOptions limits the resources a shell command can use
+type SandboxOptions struct {
+	MemoryMB   int  `json:"memory_mb,omitempty"`   // memory limit in MB, the command is killed above it
+	CPUPercent int  `json:"cpu_percent,omitempty"` // share of one CPU, e.g. 50 or 200 for two CPUs
+	CPUSeconds int  `json:"cpu_seconds,omitempty"` // CPU time limit in seconds
+	ReadOnly   bool `json:"read_only,omitempty"`   // mount the root filesystem read-only for the command
+}
+
+// Validate checks that the sandbox options are consistent
+func (o *SandboxOptions) Validate(runAs string) error {
+	if o.MemoryMB < 0 || o.CPUPercent < 0 || o.CPUSeconds < 0 {
+		return fmt.Errorf("sandbox: limits cannot be negative")
+	}
+	if o.MemoryMB == 0 && o.CPUPercent == 0 && o.CPUSeconds == 0 && !o.ReadOnly {
+		return fmt.Errorf("sandbox: no limit specified, use memory_mb, cpu_percent, cpu_seconds or read_only")
+	}
+	// Remounting the filesystem requires privileges the impersonated user doesn't have
+	if o.ReadOnly && runAs != "" {
+		return fmt.Errorf("sandbox: read_only cannot be combined with run_as")
+	}
+	return nil
 }
 
 // ShellResponse represents the response from a shell command
 type ShellResponse struct {
 	Command   string `json:"command"`
 	Shell     string `json:"shell"`
+	RunAs     string `json:"run_as,omitempty"`
 	ExitCode  int32  `json:"exit_code"`
 	Stdout    string `json:"stdout,omitempty"`
 	Stderr    string `json:"stderr,omitempty"`
 	Duration  string `json:"duration"`
 	TimedOut  bool   `json:"timed_out,omitempty"`
+	Sandboxed bool   `json:"sandboxed,omitempty"`
 	Timestamp int64  `json:"timestamp"`
+	// Execution environment of the command, reported in the execution info of its result
+	WorkingDir string `json:"working_dir,omitempty"`
+	Path       string `json:"path,omitempty"`
+	MaxRSSKB   int64  `json:"max_rss_kb,omitempty"`
+}
+
+// executionInfo returns the execution info of the result of a shell command, the minion filling
+// the user when the command didn't run as another one
+func (r *ShellResponse) executionInfo() *pb.ExecutionInfo {
+	return &pb.ExecutionInfo{
+		WorkingDir: r.WorkingDir,
+		User:       r.RunAs,
+		Path:       r.Path,
+		MaxRssKb:   r.MaxRSSKB,
+	}
+}
+
+// commandPath returns the PATH of a command run with env, that of the minion when env is nil
+func commandPath(env []string) string {
+	if env == nil {
+		return os.Getenv("PATH")
+	}
+	path := ""
+	for _, variable := range env {
+		if name, value, found := strings.Cut(variable, "="); found && strings.EqualFold(name, "PATH") {
+			path = value // the last definition wins, as for exec.Cmd
+		}
+	}
+	return path
 }
 
 // ShellExecutor handles shell command execution
@@ -56,11 +118,14 @@ func ParseShellRequest(payload string) (*ShellRequest, error) {
 		}, nil
 	}
 
-	// JSON format parsing would go here if needed
-	// For now, treat everything as simple command
-	return &ShellRequest{
-		Command: payload,
-	}, nil
+	// JSON format, falling back to a simple command when it is not a shell request
+	var request ShellRequest
+	if err := json.Unmarshal([]byte(payload), &request); err != nil || request.Command == "" {
+		return &ShellRequest{
+			Command: payload,
+		}, nil
+	}
+	return &request, nil
 }
 
 // Execute processes a shell command and returns the response
@@ -69,6 +134,7 @@ func (se *ShellExecutor) Execute(ctx context.Context, request *ShellRequest) *Sh
 
 	response := &ShellResponse{
 		Command:   request.Command,
+		RunAs:     request.RunAs,
 		Timestamp: startTime.Unix(),
 	}
 
@@ -107,9 +173,58 @@ func (se *ShellExecutor) Execute(ctx context.Context, request *ShellRequest) *Sh
 		}
 	}
 
+	// Switch to the requested user before starting the command
+	if request.RunAs != "" {
+		if err := configureRunAs(execCmd, request.RunAs); err != nil {
+			response.ExitCode = 1
+			response.Stderr = err.Error()
+			response.Duration = time.Since(startTime).String()
+			return response
+		}
+	}
+
+	// Add the requested variables to the environment, after run_as which replaces it
+	if len(request.Env) > 0 {
+		if execCmd.Env == nil {
+			execCmd.Env = os.Environ()
+		}
+		names := make([]string, 0, len(request.Env))
+		for name := range request.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			execCmd.Env = append(execCmd.Env, name+"="+request.Env[name])
+		}
+	}
+
+	// Apply the sandbox limits, the command isn't run when they can't be enforced
+	var sb *sandbox
+	if request.Sandbox != nil {
+		err := request.Sandbox.Validate(request.RunAs)
+		if err == nil {
+			sb, err = prepareSandbox(execCmd, request.Sandbox)
+		}
+		if err != nil {
+			response.ExitCode = 1
+			response.Stderr = err.Error()
+			response.Duration = time.Since(startTime).String()
+			return response
+		}
+		response.Sandboxed = true
+	}
+
 	// Execute and capture output
 	output, err := execCmd.CombinedOutput()
 	response.Duration = time.Since(startTime).String()
+	response.WorkingDir = execCmd.Dir
+	if response.WorkingDir == "" {
+		response.WorkingDir, _ = os.Getwd()
+	}
+	response.Path = commandPath(execCmd.Env)
+	if execCmd.ProcessState != nil {
+		response.MaxRSSKB = maxRSSKB(execCmd.ProcessState)
+	}
 
 	if err != nil {
 		response.ExitCode = 1
@@ -132,6 +247,12 @@ func (se *ShellExecutor) Execute(ctx context.Context, request *ShellRequest) *Sh
 		response.Stdout = string(output)
 	}
 
+	if sb != nil {
+		if note := sb.finish(); note != "" {
+			response.Stderr = strings.TrimSpace(response.Stderr + "\n" + note)
+		}
+	}
+
 	return response
 }
 
@@ -179,8 +300,8 @@ func NewShellCommand(defaultTimeout time.Duration) *ShellCommand {
 		"shell",
 		"shell",
 		"Execute shell commands with enhanced logging and validation",
-		`{"command": "ls -la", "shell": "bash", "timeout": 30}`,
-	).WithExamples(
+		`{"command": "ls -la", "shell": "bash", "timeout": 30, "run_as": "nobody", "sandbox": {"memory_mb": 256}}`,
+	).WithVersion(shellEnvVersion).WithExamples(
 		Example{
 			Description: "Simple shell command",
 			Command:     "command-send minion abc123 'shell ls -la'",
@@ -196,16 +317,37 @@ func NewShellCommand(defaultTimeout time.Duration) *ShellCommand {
 			Command:     `command-send minion abc123 '{"command": "sleep 5", "timeout": 10}'`,
 			Expected:    "Executes command with 10 second timeout",
 		},
+		Example{
+			Description: "Shell command as an unprivileged user",
+			Command:     `command-send minion abc123 '{"command": "id", "run_as": "nobody"}'`,
+			Expected:    "Executes command with the uid, gid and groups of user nobody",
+		},
+		Example{
+			Description: "Sandboxed shell command",
+			Command:     `command-send minion abc123 '{"command": "make test", "sandbox": {"memory_mb": 512, "cpu_percent": 50, "read_only": true}}'`,
+			Expected:    "Executes command limited to 512MB of memory and half a CPU on a read-only root filesystem",
+		},
+		Example{
+			Description: "Shell command with environment variables",
+			Command:     `command-send minion abc123 '{"command": "curl -sI https://example.com", "env": {"HTTPS_PROXY": "http://proxy:3128"}}'`,
+			Expected:    "Executes command with HTTPS_PROXY set",
+		},
 	).WithParameters(
 		Param{Name: "command", Type: "string", Required: true, Description: "Shell command to execute"},
 		Param{Name: "shell", Type: "string", Required: false, Description: "Specific shell to use (bash, sh, zsh, cmd, powershell)", Default: "OS default"},
 		Param{Name: "timeout", Type: "int", Required: false, Description: "Timeout in seconds", Default: "15"},
+		Param{Name: "run_as", Type: "string", Required: false, Description: "User to run the command as", Default: "minion user"},
+		Param{Name: "sandbox", Type: "object", Required: false, Description: "Resource limits: memory_mb, cpu_percent, cpu_seconds, read_only", Default: "none"},
+		Param{Name: "env", Type: "object", Required: false, Description: "Environment variables added for the command", Default: "none"},
 	).WithNotes(
 		"Commands are executed in the shell specified or OS default",
 		"All output (stdout/stderr) is captured and returned",
 		"Exit codes and execution duration are tracked",
 		"Commands have a default 15-second timeout for safety",
 		"Timed out commands are properly terminated",
+		"run_as requires the minion to run as root and is not supported on Windows",
+		"sandbox requires a Linux minion running as root with cgroup v2, the command is not run otherwise",
+		"Variables set with env-set on Nexus are added to the environment, env overriding them",
 	)
 
 	return &ShellCommand{
@@ -214,6 +356,33 @@ func NewShellCommand(defaultTimeout time.Duration) *ShellCommand {
 	}
 }
 
+// protectedEnvVars are the variables of the shell startup and of the command search the environment set
+// by Nexus can't set, as they would run code of its choosing in every command
+var protectedEnvVars = map[string]bool{
+	"PATH":           true,
+	"BASH_ENV":       true,
+	"ENV":            true,
+	"IFS":            true,
+	"CDPATH":         true,
+	"SHELLOPTS":      true,
+	"BASHOPTS":       true,
+	"PS4":            true,
+	"PROMPT_COMMAND": true,
+	"PATHEXT":        true,
+	"COMSPEC":        true,
+}
+
+// IsProtectedEnvVar reports whether a variable may not be set by the environment Nexus manages: the
+// dynamic loader variables (LD_*, DYLD_*), exported bash functions and protectedEnvVars. Names are
+// compared case-insensitively, as Windows does.
+func IsProtectedEnvVar(name string) bool {
+	upper := strings.ToUpper(name)
+	return protectedEnvVars[upper] ||
+		strings.HasPrefix(upper, "LD_") ||
+		strings.HasPrefix(upper, "DYLD_") ||
+		strings.HasPrefix(upper, "BASH_FUNC_")
+}
+
 // Execute implements Command interface for shell commands
 func (c *ShellCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
 	// Parse the request
@@ -227,6 +396,21 @@ func (c *ShellCommand) Execute(ctx *ExecutionContext, payload string) (*pb.Comma
 		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("empty command")), nil
 	}
 
+	// The environment set by Nexus comes first, the variables of the request override it
+	if len(ctx.Env) > 0 {
+		env := make(map[string]string, len(ctx.Env)+len(request.Env))
+		for name, value := range ctx.Env {
+			if IsProtectedEnvVar(name) {
+				continue
+			}
+			env[name] = value
+		}
+		for name, value := range request.Env {
+			env[name] = value
+		}
+		request.Env = env
+	}
+
 	// Execute the shell command
 	response := c.executor.Execute(ctx.Context, request)
 
@@ -238,18 +422,26 @@ func (c *ShellCommand) Execute(ctx *ExecutionContext, payload string) (*pb.Comma
 		ExitCode:  response.ExitCode,
 		Stdout:    response.Stdout,
 		Stderr:    response.Stderr,
+		Execution: response.executionInfo(),
 	}
 
 	// Add execution metadata to stdout if successful
 	if response.ExitCode == 0 && response.Stdout != "" {
 		metadata := fmt.Sprintf("\n--- Execution Info ---\nShell: %s\nDuration: %s\nExit Code: %d\n",
 			response.Shell, response.Duration, response.ExitCode)
+		if response.RunAs != "" {
+			metadata += fmt.Sprintf("Run As: %s\n", response.RunAs)
+		}
+		if response.Sandboxed {
+			metadata += "Sandboxed: yes\n"
+		}
 		result.Stdout = response.Stdout + metadata
 	}
 
 	ctx.Logger.Info("Shell command executed",
 		zap.String("command", request.Command),
 		zap.String("shell", response.Shell),
+		zap.String("run_as", response.RunAs),
 		zap.Int32("exit_code", response.ExitCode),
 		zap.String("duration", response.Duration),
 		zap.Bool("timed_out", response.TimedOut),
@@ -271,7 +463,7 @@ func NewSystemCommand(defaultTimeout time.Duration) *SystemCommand {
 		"shell",
 		"Execute system commands (alias for shell command)",
 		"system <command>",
-	).WithExamples(
+	).WithVersion(shellEnvVersion).WithExamples(
 		Example{
 			Description: "System command execution",
 			Command:     "command-send minion abc123 'system uname -a'",
@@ -282,6 +474,7 @@ func NewSystemCommand(defaultTimeout time.Duration) *SystemCommand {
 	).WithNotes(
 		"This is an alias for the shell command for backwards compatibility",
 		"Uses the OS default shell for execution",
+		"JSON shell requests (command, shell, timeout, run_as, env) are accepted as well",
 	)
 
 	return &SystemCommand{
@@ -292,9 +485,10 @@ func NewSystemCommand(defaultTimeout time.Duration) *SystemCommand {
 
 // Execute implements Command interface for system commands
 func (c *SystemCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
-	// For system commands, treat payload as direct command
-	request := &ShellRequest{
-		Command: payload,
+	// For system commands, treat payload as direct command unless it is a JSON shell request
+	request, err := ParseShellRequest(payload)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to parse shell request: %w", err)), nil
 	}
 
 	response := c.executor.Execute(ctx.Context, request)
@@ -306,11 +500,13 @@ func (c *SystemCommand) Execute(ctx *ExecutionContext, payload string) (*pb.Comm
 		ExitCode:  response.ExitCode,
 		Stdout:    response.Stdout,
 		Stderr:    response.Stderr,
+		Execution: response.executionInfo(),
 	}
 
 	ctx.Logger.Info("System command executed",
 		zap.String("command", request.Command),
 		zap.String("shell", response.Shell),
+		zap.String("run_as", response.RunAs),
 		zap.Int32("exit_code", response.ExitCode),
 		zap.String("duration", response.Duration),
 	)
@@ -0,0 +1,181 @@
+package command
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestParseShellRequest(t *testing.T) {
+	request, err := ParseShellRequest(`{"command": "id -u", "shell": "sh", "timeout": 5, "run_as": "nobody"}`)
+	if err != nil {
+		t.Fatalf("ParseShellRequest failed: %v", err)
+	}
+	if request.Command != "id -u" || request.Shell != "sh" || request.Timeout != 5 || request.RunAs != "nobody" {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+
+	// Payloads that are not JSON shell requests are run verbatim
+	for _, payload := range []string{"ls -la", `{"path": "/tmp"}`, "{ not json"} {
+		request, err := ParseShellRequest(payload)
+		if err != nil || request.Command != payload || request.RunAs != "" {
+			t.Errorf("Expected %q to be kept as command, got %+v (%v)", payload, request, err)
+		}
+	}
+}
+
+func TestShellRunAs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("run_as is not supported on Windows")
+	}
+	executor := NewShellExecutor(5 * time.Second)
+
+	response := executor.Execute(context.Background(), &ShellRequest{Command: "id -u", RunAs: "no-such-user-minexus"})
+	if response.ExitCode == 0 || !strings.Contains(response.Stderr, "does not exist") {
+		t.Errorf("Expected unknown user error, got exit %d: %s", response.ExitCode, response.Stderr)
+	}
+
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody not available")
+	}
+
+	response = executor.Execute(context.Background(), &ShellRequest{Command: "id -u", RunAs: "nobody"})
+	if os.Geteuid() != 0 {
+		if response.ExitCode == 0 || !strings.Contains(response.Stderr, "requires root") {
+			t.Errorf("Expected privilege error, got exit %d: %s", response.ExitCode, response.Stderr)
+		}
+		return
+	}
+	if response.ExitCode != 0 || strings.TrimSpace(response.Stdout) != nobody.Uid {
+		t.Errorf("Expected command to run as uid %s, got exit %d: %q %s", nobody.Uid, response.ExitCode, response.Stdout, response.Stderr)
+	}
+}
+
+func TestShellSandbox(t *testing.T) {
+	request, err := ParseShellRequest(`{"command": "echo ok", "sandbox": {"memory_mb": 64, "cpu_percent": 50, "read_only": true}}`)
+	if err != nil {
+		t.Fatalf("ParseShellRequest failed: %v", err)
+	}
+	if request.Sandbox == nil || request.Sandbox.MemoryMB != 64 || request.Sandbox.CPUPercent != 50 || !request.Sandbox.ReadOnly {
+		t.Fatalf("Unexpected sandbox options: %+v", request.Sandbox)
+	}
+
+	invalid := map[string]*SandboxOptions{
+		"no limit":          {},
+		"negative limit":    {MemoryMB: -1},
+		"read-only as user": {ReadOnly: true},
+	}
+	for name, options := range invalid {
+		runAs := ""
+		if options.ReadOnly {
+			runAs = "nobody"
+		}
+		if err := options.Validate(runAs); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	// The command is not run when the sandbox can't be set up
+	executor := NewShellExecutor(5 * time.Second)
+	marker := t.TempDir() + "/ran"
+	response := executor.Execute(context.Background(), &ShellRequest{Command: "touch " + marker, Sandbox: &SandboxOptions{}})
+	if response.ExitCode == 0 || !strings.Contains(response.Stderr, "sandbox:") {
+		t.Errorf("Expected sandbox error, got exit code %d: %s", response.ExitCode, response.Stderr)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected the command not to run")
+	}
+
+	if !sandboxSupported() {
+		response := executor.Execute(context.Background(), &ShellRequest{Command: "touch " + marker, Sandbox: &SandboxOptions{MemoryMB: 64}})
+		if response.ExitCode == 0 {
+			t.Error("Expected sandboxed command to fail where sandboxing is unsupported")
+		}
+		return
+	}
+
+	// Sandboxed commands run with the CPU time limit applied by the shell prelude
+	response = executor.Execute(context.Background(), &ShellRequest{Command: "ulimit -t", Sandbox: &SandboxOptions{CPUSeconds: 7}})
+	if response.ExitCode != 0 || strings.TrimSpace(response.Stdout) != "7" || !response.Sandboxed {
+		t.Errorf("Expected CPU time limit of 7s, got exit code %d: %q %s", response.ExitCode, response.Stdout, response.Stderr)
+	}
+}
+
+func TestShellEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on a POSIX shell")
+	}
+	shell := NewShellCommand(5 * time.Second)
+	ctx := &ExecutionContext{
+		Context:  context.Background(),
+		Logger:   zap.NewNop(),
+		MinionID: "minion-1",
+		Env:      map[string]string{"MINEXUS_PROXY": "http://nexus-proxy:3128", "MINEXUS_MIRROR": "mirror.local", "PATH": "/tmp/evil", "LD_PRELOAD": "/tmp/evil.so"},
+	}
+
+	// Variables of the request override those set by Nexus, the environment of the minion being kept
+	// and the protected variables set by Nexus ignored
+	result, err := shell.Execute(ctx, `{"command": "echo $MINEXUS_PROXY $MINEXUS_MIRROR $PATH [$LD_PRELOAD]", "env": {"MINEXUS_PROXY": "http://proxy:3128"}}`)
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("Unexpected failure: %v %v", result, err)
+	}
+	fields := strings.Fields(strings.SplitN(result.Stdout, "\n", 2)[0])
+	if len(fields) != 4 || fields[0] != "http://proxy:3128" || fields[1] != "mirror.local" || fields[2] != os.Getenv("PATH") || fields[3] != "[]" {
+		t.Errorf("Unexpected environment: %q", result.Stdout)
+	}
+
+	ctx.Env = nil
+	result, _ = shell.Execute(ctx, "echo \"[$MINEXUS_MIRROR]\"")
+	if !strings.HasPrefix(result.Stdout, "[]") {
+		t.Errorf("Expected no variable without environment, got %q", result.Stdout)
+	}
+}
+
+func TestIsProtectedEnvVar(t *testing.T) {
+	for _, name := range []string{"PATH", "Path", "LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "BASH_ENV", "ENV", "IFS", "BASH_FUNC_ls%%", "PROMPT_COMMAND"} {
+		if !IsProtectedEnvVar(name) {
+			t.Errorf("Expected %s protected", name)
+		}
+	}
+	for _, name := range []string{"HTTP_PROXY", "MIRROR", "ENVIRONMENT", "OLD_PATH"} {
+		if IsProtectedEnvVar(name) {
+			t.Errorf("Expected %s allowed", name)
+		}
+	}
+}
+
+func TestShellExecutionInfo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test relies on a POSIX shell")
+	}
+	shell := NewShellCommand(5 * time.Second)
+	ctx := &ExecutionContext{Context: context.Background(), Logger: zap.NewNop(), MinionID: "minion-1"}
+	wd, _ := os.Getwd()
+
+	// Failures report the directory, PATH and memory of the command too
+	result, err := shell.Execute(ctx, `{"command": "exit 3", "env": {"PATH": "/opt/tools/bin:/usr/bin:/bin"}}`)
+	if err != nil || result.ExitCode != 3 || result.Execution == nil {
+		t.Fatalf("Unexpected result: %v %v", result, err)
+	}
+	info := result.Execution
+	if info.WorkingDir != wd || info.Path != "/opt/tools/bin:/usr/bin:/bin" || info.User != "" {
+		t.Errorf("Unexpected execution info: %v", info)
+	}
+	if runtime.GOOS == "linux" && info.MaxRssKb <= 0 {
+		t.Errorf("Expected the peak memory of the shell, got %d", info.MaxRssKb)
+	}
+
+	if path := commandPath(nil); path != os.Getenv("PATH") {
+		t.Errorf("Expected the PATH of the minion, got %q", path)
+	}
+	if path := commandPath([]string{"PATH=/a", "HOME=/root", "PATH=/b"}); path != "/b" {
+		t.Errorf("Expected the last PATH, got %q", path)
+	}
+}
@@ -0,0 +1,79 @@
+//go:build !windows
+// +build !windows
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// configureRunAs makes cmd run with the uid, gid and supplementary groups of username
+func configureRunAs(cmd *exec.Cmd, username string) error {
+	u, err := user.Lookup(username)
+	if err != nil {
+		var unknown user.UnknownUserError
+		if errors.As(err, &unknown) {
+			return fmt.Errorf("run_as: user '%s' does not exist on this minion", username)
+		}
+		return fmt.Errorf("run_as: failed to look up user '%s': %w", username, err)
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("run_as: invalid uid '%s' for user '%s'", u.Uid, username)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("run_as: invalid gid '%s' for user '%s'", u.Gid, username)
+	}
+
+	// Running as ourselves needs no privilege
+	euid := os.Geteuid()
+	if uint64(euid) == uid {
+		return nil
+	}
+	if euid != 0 {
+		return fmt.Errorf("run_as: minion runs as uid %d and lacks the privileges to run commands as user '%s' (requires root)", euid, username)
+	}
+
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		return fmt.Errorf("run_as: failed to look up groups of user '%s': %w", username, err)
+	}
+	groups := make([]uint32, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+			groups = append(groups, uint32(g))
+		}
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{
+			Uid:    uint32(uid),
+			Gid:    uint32(gid),
+			Groups: groups,
+		},
+	}
+	// Give the command the environment of the user rather than the minion's
+	cmd.Env = append(os.Environ(), "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	return nil
+}
+
+// maxRSSKB returns the peak resident memory of an exited process in kilobytes, which macOS reports in bytes
+func maxRSSKB(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	if runtime.GOOS == "darwin" {
+		return int64(usage.Maxrss) / 1024
+	}
+	return int64(usage.Maxrss)
+}
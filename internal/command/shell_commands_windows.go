@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// configureRunAs is not supported on Windows: starting a process as another user
+// (CreateProcessAsUser) requires a logon token, hence the user's password
+func configureRunAs(cmd *exec.Cmd, username string) error {
+	return fmt.Errorf("run_as: running commands as user '%s' is not supported on Windows minions", username)
+}
+
+// maxRSSKB is unknown on Windows, whose process accounting doesn't report the peak resident memory
+func maxRSSKB(state *os.ProcessState) int64 {
+	return 0
+}
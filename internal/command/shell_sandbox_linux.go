@@ -0,0 +1,179 @@
+//go:build linux
+// +build linux
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+const (
+	// cgroupRoot is the mount point of the cgroup v2 unified hierarchy
+	cgroupRoot = "/sys/fs/cgroup"
+	// sandboxCgroupParent groups the cgroups of sandboxed commands
+	sandboxCgroupParent = "minexus"
+	// cpuPeriod is the cpu.max period in microseconds
+	cpuPeriod = 100000
+)
+
+// sandboxSequence numbers the cgroups of sandboxed commands
+var sandboxSequence atomic.Uint64
+
+// sandbox is the execution environment prepared for a sandboxed command
+type sandbox struct {
+	cgroupDir string   // empty without memory or CPU limit
+	cgroupFD  *os.File // keeps the cgroup open until the command started
+}
+
+// sandboxSupported reports whether the minion can run sandboxed commands:
+// it needs root and a writable cgroup v2 hierarchy
+func sandboxSupported() bool {
+	return os.Geteuid() == 0 && hasCgroupV2()
+}
+
+// hasCgroupV2 reports whether the cgroup v2 unified hierarchy is mounted
+func hasCgroupV2() bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// prepareSandbox applies the sandbox options to cmd before it starts.
+// Memory and CPU limits are enforced by a dedicated cgroup the command starts in,
+// the CPU time limit by ulimit and the read-only filesystem by a private mount namespace.
+func prepareSandbox(cmd *exec.Cmd, options *SandboxOptions) (*sandbox, error) {
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("sandbox: minion runs as uid %d and lacks the privileges to sandbox commands (requires root)", os.Geteuid())
+	}
+
+	sb := &sandbox{}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	if options.MemoryMB > 0 || options.CPUPercent > 0 {
+		if err := sb.createCgroup(options); err != nil {
+			sb.release()
+			return nil, err
+		}
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(sb.cgroupFD.Fd())
+	}
+
+	// The command runs through a shell prelude applying the limits that can't be set from Go
+	var prelude []string
+	if options.CPUSeconds > 0 {
+		prelude = append(prelude, fmt.Sprintf("ulimit -t %d", options.CPUSeconds))
+	}
+	if options.ReadOnly {
+		// Mounts are private to the namespace, remounting / read-only doesn't affect the host
+		cmd.SysProcAttr.Unshareflags |= syscall.CLONE_NEWNS
+		prelude = append(prelude, "mount -o remount,bind,ro /")
+	}
+	if len(prelude) > 0 {
+		sh, err := exec.LookPath("sh")
+		if err != nil {
+			sb.release()
+			return nil, fmt.Errorf("sandbox: %w", err)
+		}
+		script := strings.Join(prelude, " && ") + ` && exec "$@"`
+		cmd.Args = append([]string{"sh", "-c", script, "minexus-sandbox", cmd.Path}, cmd.Args[1:]...)
+		cmd.Path = sh
+	}
+
+	return sb, nil
+}
+
+// createCgroup creates the cgroup limiting the memory and CPU of the command
+func (sb *sandbox) createCgroup(options *SandboxOptions) error {
+	if !hasCgroupV2() {
+		return fmt.Errorf("sandbox: memory and CPU limits require the cgroup v2 hierarchy mounted on %s", cgroupRoot)
+	}
+	parent := filepath.Join(cgroupRoot, sandboxCgroupParent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("sandbox: failed to create cgroup: %w", err)
+	}
+	// Controllers must be enabled in the parent for its children to use them
+	if err := enableControllers(cgroupRoot); err != nil {
+		return err
+	}
+	if err := enableControllers(parent); err != nil {
+		return err
+	}
+
+	sb.cgroupDir = filepath.Join(parent, fmt.Sprintf("cmd-%d-%d", os.Getpid(), sandboxSequence.Add(1)))
+	if err := os.Mkdir(sb.cgroupDir, 0755); err != nil {
+		sb.cgroupDir = ""
+		return fmt.Errorf("sandbox: failed to create cgroup: %w", err)
+	}
+
+	if options.MemoryMB > 0 {
+		limit := strconv.FormatInt(int64(options.MemoryMB)*1024*1024, 10)
+		if err := writeCgroupFile(sb.cgroupDir, "memory.max", limit); err != nil {
+			return err
+		}
+		// Without swap the limit is a hard limit, the kernel OOM kills the command
+		writeCgroupFile(sb.cgroupDir, "memory.swap.max", "0")
+	}
+	if options.CPUPercent > 0 {
+		quota := options.CPUPercent * cpuPeriod / 100
+		if err := writeCgroupFile(sb.cgroupDir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			return err
+		}
+	}
+
+	fd, err := os.Open(sb.cgroupDir)
+	if err != nil {
+		return fmt.Errorf("sandbox: failed to open cgroup: %w", err)
+	}
+	sb.cgroupFD = fd
+	return nil
+}
+
+// enableControllers enables the memory and cpu controllers for the children of dir
+func enableControllers(dir string) error {
+	return writeCgroupFile(dir, "cgroup.subtree_control", "+memory +cpu")
+}
+
+// writeCgroupFile writes value to a cgroup interface file
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+		return fmt.Errorf("sandbox: failed to set %s: %w", name, err)
+	}
+	return nil
+}
+
+// finish releases the sandbox once the command exited and describes the limits the command hit
+func (sb *sandbox) finish() string {
+	var note string
+	if sb.cgroupDir != "" {
+		if data, err := os.ReadFile(filepath.Join(sb.cgroupDir, "memory.events")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+					note = "sandbox: command killed after exceeding its memory limit"
+				}
+			}
+		}
+	}
+	sb.release()
+	return note
+}
+
+// release closes and removes the cgroup of the command
+func (sb *sandbox) release() {
+	if sb.cgroupFD != nil {
+		sb.cgroupFD.Close()
+		sb.cgroupFD = nil
+	}
+	if sb.cgroupDir != "" {
+		os.Remove(sb.cgroupDir)
+		sb.cgroupDir = ""
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// sandbox is the execution environment prepared for a sandboxed command
+type sandbox struct{}
+
+// sandboxSupported reports whether the minion can run sandboxed commands
+func sandboxSupported() bool {
+	return false
+}
+
+// prepareSandbox is not supported outside Linux: limits rely on cgroups and mount namespaces
+func prepareSandbox(cmd *exec.Cmd, options *SandboxOptions) (*sandbox, error) {
+	return nil, fmt.Errorf("sandbox: sandboxed execution is not supported on %s minions", runtime.GOOS)
+}
+
+// finish releases the sandbox once the command exited
+func (sb *sandbox) finish() string {
+	return ""
+}
@@ -0,0 +1,543 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// ContentTypeStateApply is the content type of state:apply structured results
+const ContentTypeStateApply = "application/vnd.minexus.state-apply+json"
+
+// Limits of state:apply documents
+const (
+	MaxStateResources = 200         // files, packages and services of a document
+	MaxStateFileSize  = 1024 * 1024 // content of a file
+	maxStateDiffLines = 200         // changed lines reported for a file
+	maxStateDiffInput = 5000        // lines of a file beyond which its changes are summarized
+)
+
+// Kinds of the resources of a state:apply document
+const (
+	StateKindFile    = "file"
+	StateKindPackage = "package"
+	StateKindService = "service"
+)
+
+// Actions taken by state:apply on the resources that weren't in the desired state, or that it
+// would take in check mode
+const (
+	StateCreated   = "created"   // the file was written
+	StateUpdated   = "updated"   // the content or the mode of the file was changed
+	StateRemoved   = "removed"   // the absent file was deleted
+	StateInstalled = "installed" // the package was installed
+	StateStarted   = "started"   // the service was started
+	StateFailed    = "failed"    // the resource couldn't be checked or brought in state
+)
+
+// DefaultStateFileMode is the mode of the files created by state:apply without mode
+const DefaultStateFileMode = 0o644
+
+// StateDocument is the desired state state:apply converges the minion host to: packages installed,
+// then files written or deleted, then services running
+type StateDocument struct {
+	Check    bool        `json:"check,omitempty"` // report the changes without making them
+	Files    []FileState `json:"files,omitempty"`
+	Packages []string    `json:"packages,omitempty"` // installed
+	Services []string    `json:"services,omitempty"` // running
+}
+
+// FileState is the desired state of a file
+type FileState struct {
+	Path    string `json:"path"`              // absolute
+	Content string `json:"content,omitempty"` // exact content of the file
+	Mode    string `json:"mode,omitempty"`    // octal permission bits, e.g. 0640; empty keeps those of existing files
+	Absent  bool   `json:"absent,omitempty"`  // the file must not exist
+}
+
+// StateChange is a resource state:apply brought in state, or failed to
+type StateChange struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"` // path of files
+	Action string   `json:"action"`
+	Mode   string   `json:"mode,omitempty"`  // mode change of files, e.g. "0600 -> 0644"
+	Diff   []string `json:"diff,omitempty"`  // changed lines of files, prefixed with - or +
+	Error  string   `json:"error,omitempty"` // why the resource failed
+}
+
+// StateApplyResult is the change report of state:apply, changes in the order they were made
+type StateApplyResult struct {
+	Check     bool          `json:"check"`
+	Resources int           `json:"resources"`
+	Unchanged int           `json:"unchanged"`
+	Failed    int           `json:"failed"`
+	Changes   []StateChange `json:"changes"` // changed and failed resources
+}
+
+// stateTool checks and establishes the state of packages or services with a tool of the host
+type stateTool struct {
+	cmd    string   // tool whose presence selects this one
+	goos   string   // the only platform the tool is used on, any when empty
+	query  []string // the resource name appended, succeeds once the resource is in state
+	mark   string   // text the query output contains once the resource is in state, the exit code deciding when empty
+	ensure []string // the resource name appended, brings the resource in state
+}
+
+// packageTools install packages, tried in order until one is available
+var packageTools = []stateTool{
+	{cmd: "apt-get", query: []string{"dpkg-query", "-W", "-f=${Status}"}, mark: "install ok installed",
+		ensure: []string{"env", "DEBIAN_FRONTEND=noninteractive", "apt-get", "install", "-y", "-q"}},
+	{cmd: "dnf", query: []string{"rpm", "-q"}, ensure: []string{"dnf", "install", "-y"}},
+	{cmd: "yum", query: []string{"rpm", "-q"}, ensure: []string{"yum", "install", "-y"}},
+	{cmd: "zypper", query: []string{"rpm", "-q"}, ensure: []string{"zypper", "--non-interactive", "install"}},
+	{cmd: "apk", query: []string{"apk", "info", "-e"}, ensure: []string{"apk", "add"}},
+	{cmd: "brew", query: []string{"brew", "list", "--versions"}, ensure: []string{"brew", "install"}},
+}
+
+// serviceTools start services, tried in order until one is available
+var serviceTools = []stateTool{
+	{cmd: "systemctl", query: []string{"systemctl", "is-active", "--quiet"}, ensure: []string{"systemctl", "start"}},
+	{cmd: "sc", goos: "windows", query: []string{"sc", "query"}, mark: "RUNNING", ensure: []string{"sc", "start"}},
+}
+
+// stateNamePattern matches the names of packages and services
+var stateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+:@/-]*$`)
+
+// stateRunner runs a tool of the host, returning its combined output
+type stateRunner func(ctx context.Context, argv []string) ([]byte, error)
+
+// runStateTool runs argv on the host
+func runStateTool(ctx context.Context, argv []string) ([]byte, error) {
+	return exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+}
+
+// StateApplyCommand converges the minion host to a declarative document
+type StateApplyCommand struct {
+	*BaseCommand
+	run      stateRunner
+	lookPath func(file string) (string, error)
+}
+
+// NewStateApplyCommand creates a new state apply command
+func NewStateApplyCommand() *StateApplyCommand {
+	base := NewBaseCommand(
+		"state:apply",
+		"state",
+		"Converge the host to a declarative document of files, packages and services",
+		`state:apply {"check": false, "packages": ["<name>"], "files": [{"path": "<path>", "content": "<text>", "mode": "0644"}], "services": ["<name>"]}`,
+	).WithImpact(ImpactMutating).WithDiskHeavy().WithExamples(
+		Example{
+			Description: "Converge the web servers to a document, the console reading it locally",
+			Command:     "command-send tag role=web state:apply web.yaml",
+			Expected:    "Installs the missing packages, rewrites the drifted files, starts the stopped services and reports each change",
+		},
+		Example{
+			Description: "Preview the changes without making them",
+			Command:     "command-send tag role=web state:apply --check web.yaml",
+			Expected:    "Reports the changes that would be made, file diffs included",
+		},
+	).WithParameters(
+		Param{Name: "packages", Type: "array", Required: false, Description: "Packages installed"},
+		Param{Name: "files", Type: "array", Required: false, Description: "Files with their exact content and mode, or absent"},
+		Param{Name: "services", Type: "array", Required: false, Description: "Services running"},
+		Param{Name: "check", Type: "bool", Required: false, Description: "Report the changes without making them", Default: "false"},
+	).WithNotes(
+		"The console builds the JSON payload from a local YAML or JSON document",
+		"Idempotent: resources already in state are left untouched, so applying a document twice changes nothing the second time",
+		"Packages are installed with apt-get, dnf, yum, zypper, apk or Homebrew, services started with systemctl, or sc on Windows",
+		"Files are replaced atomically; services are started, not restarted when their files change",
+		"The command fails with exit code 1 when a resource can't be brought in state, the other ones being applied",
+		"The result carries the change report as a structured JSON payload",
+	)
+
+	return &StateApplyCommand{
+		BaseCommand: base,
+		run:         runStateTool,
+		lookPath:    exec.LookPath,
+	}
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *StateApplyCommand) ValidateArgs(payload string) error {
+	if _, err := parseStateDocument(payload); err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+	return nil
+}
+
+// Execute implements ExecutableCommand interface
+func (c *StateApplyCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	logger, start := logging.FuncLogger(ctx.Logger, "StateApplyCommand.Execute")
+	defer logging.FuncExit(logger, start)
+
+	document, err := parseStateDocument(payload)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("failed to parse document: %w", err)), nil
+	}
+
+	result := &StateApplyResult{
+		Check:     document.Check,
+		Resources: len(document.Packages) + len(document.Files) + len(document.Services),
+		Changes:   []StateChange{},
+	}
+	record := func(change *StateChange) {
+		switch {
+		case change == nil:
+			result.Unchanged++
+			return
+		case change.Action == StateFailed:
+			result.Failed++
+		case !document.Check:
+			logger.Info("Resource brought in state",
+				zap.String("kind", change.Kind),
+				zap.String("name", change.Name),
+				zap.String("action", change.Action))
+		}
+		result.Changes = append(result.Changes, *change)
+	}
+
+	packages := c.selectTool(packageTools)
+	for _, name := range document.Packages {
+		record(c.ensureTool(ctx.Context, packages, StateKindPackage, name, StateInstalled, document.Check))
+	}
+	for _, file := range document.Files {
+		record(ensureFile(file, document.Check))
+	}
+	services := c.selectTool(serviceTools)
+	for _, name := range document.Services {
+		record(c.ensureTool(ctx.Context, services, StateKindService, name, StateStarted, document.Check))
+	}
+
+	commandResult := c.BaseCommand.CreateStructuredResult(ctx, formatStateApply(result), ContentTypeStateApply, result)
+	if result.Failed > 0 {
+		commandResult.ExitCode = 1
+		commandResult.Stderr = fmt.Sprintf("%d resources couldn't be brought in state", result.Failed)
+	}
+	return commandResult, nil
+}
+
+// parseStateDocument extracts the document from a state:apply payload
+func parseStateDocument(payload string) (*StateDocument, error) {
+	body := commandArgument(payload, "state:apply")
+	if body == "" {
+		return nil, fmt.Errorf("missing document")
+	}
+
+	var document StateDocument
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	resources := len(document.Files) + len(document.Packages) + len(document.Services)
+	if resources == 0 {
+		return nil, fmt.Errorf("the document lists no file, package or service")
+	}
+	if resources > MaxStateResources {
+		return nil, fmt.Errorf("the document lists more than %d resources", MaxStateResources)
+	}
+
+	paths := make(map[string]bool, len(document.Files))
+	for _, file := range document.Files {
+		if !filepath.IsAbs(file.Path) {
+			return nil, fmt.Errorf("file path %q must be absolute", file.Path)
+		}
+		if err := validatePath(file.Path); err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", file.Path, err)
+		}
+		clean := filepath.Clean(file.Path)
+		if paths[clean] {
+			return nil, fmt.Errorf("file %s is listed twice", file.Path)
+		}
+		paths[clean] = true
+		if file.Absent && (file.Content != "" || file.Mode != "") {
+			return nil, fmt.Errorf("absent file %s can't have a content or a mode", file.Path)
+		}
+		if len(file.Content) > MaxStateFileSize {
+			return nil, fmt.Errorf("content of %s exceeds %d bytes", file.Path, MaxStateFileSize)
+		}
+		if _, err := parseStateFileMode(file.Mode); err != nil {
+			return nil, fmt.Errorf("file %s: %w", file.Path, err)
+		}
+	}
+	if err := checkStateNames(StateKindPackage, document.Packages); err != nil {
+		return nil, err
+	}
+	if err := checkStateNames(StateKindService, document.Services); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// checkStateNames checks the names of packages or services, which are passed to host tools
+func checkStateNames(kind string, names []string) error {
+	for _, name := range names {
+		if !stateNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid %s name %q", kind, name)
+		}
+	}
+	return nil
+}
+
+// parseStateFileMode parses the octal permission bits of a file, 0 when unset
+func parseStateFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+	bits, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || bits > 0o777 {
+		return 0, fmt.Errorf("invalid mode %q, expected octal permission bits such as 0644", mode)
+	}
+	return os.FileMode(bits), nil
+}
+
+// selectTool returns the first available tool of tools, nil when none is
+func (c *StateApplyCommand) selectTool(tools []stateTool) *stateTool {
+	for i, tool := range tools {
+		if tool.goos != "" && tool.goos != runtime.GOOS {
+			continue
+		}
+		if _, err := c.lookPath(tool.cmd); err == nil {
+			return &tools[i]
+		}
+	}
+	return nil
+}
+
+// ensureTool brings a package or a service in state with tool, returning nil when it already was
+func (c *StateApplyCommand) ensureTool(ctx context.Context, tool *stateTool, kind, name, action string, check bool) *StateChange {
+	change := &StateChange{Kind: kind, Name: name, Action: action}
+	if tool == nil {
+		change.Action, change.Error = StateFailed, fmt.Sprintf("no supported %s manager found", kind)
+		return change
+	}
+
+	inState := func() bool {
+		out, err := c.run(ctx, append(append([]string{}, tool.query...), name))
+		return err == nil && (tool.mark == "" || strings.Contains(string(out), tool.mark))
+	}
+	if inState() {
+		return nil
+	}
+	if check {
+		return change
+	}
+
+	argv := append(append([]string{}, tool.ensure...), name)
+	if out, err := c.run(ctx, argv); err != nil {
+		change.Action, change.Error = StateFailed, fmt.Sprintf("%s failed: %v: %s", tool.cmd, err, lastLine(out))
+	} else if !inState() {
+		change.Action, change.Error = StateFailed, fmt.Sprintf("%s succeeded but the %s is still not %s", tool.cmd, kind, action)
+	}
+	return change
+}
+
+// lastLine returns the last non-empty line of a tool output, usually its error message
+func lastLine(out []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// ensureFile brings a file in state, returning nil when it already was
+func ensureFile(file FileState, check bool) *StateChange {
+	change := &StateChange{Kind: StateKindFile, Name: file.Path}
+	fail := func(err error) *StateChange {
+		change.Action, change.Error = StateFailed, err.Error()
+		return change
+	}
+
+	info, err := os.Lstat(file.Path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return fail(err)
+	}
+	if exists && !info.Mode().IsRegular() {
+		return fail(fmt.Errorf("%s is not a regular file", file.Path))
+	}
+
+	if file.Absent {
+		if !exists {
+			return nil
+		}
+		change.Action = StateRemoved
+		if !check {
+			if err := os.Remove(file.Path); err != nil {
+				return fail(err)
+			}
+		}
+		return change
+	}
+
+	mode, _ := parseStateFileMode(file.Mode)
+	if !exists {
+		change.Action = StateCreated
+		if mode == 0 {
+			mode = DefaultStateFileMode
+		}
+		change.Diff = lineDiff("", file.Content)
+	} else {
+		current, err := os.ReadFile(file.Path)
+		if err != nil {
+			return fail(err)
+		}
+		if !bytes.Equal(current, []byte(file.Content)) {
+			change.Diff = lineDiff(string(current), file.Content)
+		}
+		if mode != 0 && mode != info.Mode().Perm() {
+			change.Mode = fmt.Sprintf("%04o -> %04o", info.Mode().Perm(), mode)
+		}
+		if change.Diff == nil && change.Mode == "" {
+			return nil
+		}
+		change.Action = StateUpdated
+		if mode == 0 {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	if !check {
+		if err := writeFileAtomically(file.Path, []byte(file.Content), mode); err != nil {
+			return fail(err)
+		}
+	}
+	return change
+}
+
+// writeFileAtomically replaces a file with data through a temporary file renamed over it, creating
+// the missing parent directories. The file keeps the owner and group of the file it replaces, and is
+// synced to disk before being renamed.
+func writeFileAtomically(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	existing, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".minexus-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if existing != nil {
+		if err := copyOwner(tmp, existing); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to keep the owner of %s: %w", path, err)
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// lineDiff returns the lines removed from before, prefixed with -, and added by after, prefixed with +,
+// in order; nil when they have the same lines. Large files are summarized.
+func lineDiff(before, after string) []string {
+	old, updated := splitLines(before), splitLines(after)
+	if len(old) > maxStateDiffInput || len(updated) > maxStateDiffInput {
+		return []string{fmt.Sprintf("~%d lines replaced by %d lines", len(old), len(updated))}
+	}
+
+	// Longest common subsequence, lcs[i][j] being that of old[i:] and updated[j:]
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(updated)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(updated) - 1; j >= 0; j-- {
+			if old[i] == updated[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(old) || j < len(updated) {
+		switch {
+		case i < len(old) && j < len(updated) && old[i] == updated[j]:
+			i, j = i+1, j+1
+			continue
+		case i < len(old) && (j == len(updated) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+old[i])
+			i++
+		default:
+			diff = append(diff, "+"+updated[j])
+			j++
+		}
+	}
+	if len(diff) == 0 && before != after {
+		diff = []string{"~line endings changed"}
+	}
+	if len(diff) > maxStateDiffLines {
+		diff = append(diff[:maxStateDiffLines], fmt.Sprintf("~%d more changed lines", len(diff)-maxStateDiffLines))
+	}
+	return diff
+}
+
+// splitLines splits text in lines, without the final newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// formatStateApply describes a change report
+func formatStateApply(result *StateApplyResult) string {
+	var output strings.Builder
+	verb := "Applied"
+	if result.Check {
+		verb = "Checked"
+	}
+	fmt.Fprintf(&output, "%s %d resources: %d changed, %d unchanged, %d failed\n", verb, result.Resources,
+		len(result.Changes)-result.Failed, result.Unchanged, result.Failed)
+	if result.Check && len(result.Changes) > result.Failed {
+		output.WriteString("Check mode, the changes below were not made\n")
+	}
+
+	for _, change := range result.Changes {
+		line := fmt.Sprintf("%-10s %-8s %s", strings.ToUpper(change.Action), change.Kind, change.Name)
+		if change.Mode != "" {
+			line += " (mode " + change.Mode + ")"
+		}
+		if change.Error != "" {
+			line += ": " + change.Error
+		}
+		output.WriteString(line + "\n")
+		for _, diff := range change.Diff {
+			output.WriteString("    " + diff + "\n")
+		}
+	}
+	return output.String()
+}
@@ -0,0 +1,212 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParseStateDocument(t *testing.T) {
+	document, err := parseStateDocument(`state:apply {"check": true, "packages": ["nginx"], "files": [{"path": "/etc/motd", "content": "hi\n", "mode": "0644"}], "services": ["nginx"]}`)
+	if err != nil || !document.Check || len(document.Files) != 1 || document.Packages[0] != "nginx" || document.Services[0] != "nginx" {
+		t.Fatalf("Unexpected document: %+v %v", document, err)
+	}
+
+	for _, payload := range []string{
+		"state:apply",
+		"state:apply {}",
+		`state:apply {"packages": ["nginx"], "users": ["bob"]}`,
+		`state:apply {"files": [{"path": "etc/motd"}]}`,
+		`state:apply {"files": [{"path": "/etc/motd"}, {"path": "/etc//motd"}]}`,
+		`state:apply {"files": [{"path": "/etc/motd", "absent": true, "content": "x"}]}`,
+		`state:apply {"files": [{"path": "/etc/motd", "mode": "0999"}]}`,
+		`state:apply {"files": [{"path": "/etc/motd", "mode": "01777"}]}`,
+		`state:apply {"packages": ["-y"]}`,
+		`state:apply {"services": ["nginx; reboot"]}`,
+	} {
+		if _, err := parseStateDocument(payload); err == nil {
+			t.Errorf("Expected %s rejected", payload)
+		}
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		before, after string
+		want          []string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", nil},
+		{"", "a\nb\n", []string{"+a", "+b"}},
+		{"a\nb\nc\n", "a\nB\nc\nd\n", []string{"-b", "+B", "+d"}},
+		{"a\n", "a", []string{"~line endings changed"}},
+	}
+	for _, test := range tests {
+		if got := lineDiff(test.before, test.after); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("lineDiff(%q, %q) = %q, want %q", test.before, test.after, got, test.want)
+		}
+	}
+
+	long := strings.Repeat("x\n", maxStateDiffLines+10)
+	if diff := lineDiff("", long); len(diff) != maxStateDiffLines+1 || diff[maxStateDiffLines] != "~10 more changed lines" {
+		t.Errorf("Expected the diff truncated, got %d lines", len(diff))
+	}
+}
+
+func TestStateApplyCommand(t *testing.T) {
+	dir := t.TempDir()
+	motd := filepath.Join(dir, "motd")
+	conf := filepath.Join(dir, "app", "app.conf")
+	stale := filepath.Join(dir, "stale")
+	if err := os.WriteFile(motd, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Packages and services in state once ensured, curl failing to install
+	inState := map[string]bool{"nginx-installed": true}
+	var ran []string
+	cmd := NewStateApplyCommand()
+	cmd.lookPath = func(file string) (string, error) {
+		if file == "apt-get" || file == "systemctl" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	cmd.run = func(ctx context.Context, argv []string) ([]byte, error) {
+		name := argv[len(argv)-1]
+		switch argv[0] {
+		case "dpkg-query":
+			if inState[name+"-installed"] {
+				return []byte("install ok installed"), nil
+			}
+			return []byte("unknown ok not-installed"), nil
+		case "env":
+			ran = append(ran, "install "+name)
+			if name == "curl" {
+				return []byte("Reading package lists...\nE: Unable to locate package curl"), errors.New("exit status 100")
+			}
+			inState[name+"-installed"] = true
+		case "systemctl":
+			if argv[1] == "start" {
+				ran = append(ran, "start "+name)
+				inState[name+"-running"] = true
+			} else if !inState[name+"-running"] {
+				return nil, errors.New("exit status 3")
+			}
+		}
+		return nil, nil
+	}
+
+	document := StateDocument{
+		Packages: []string{"nginx", "jq", "curl"},
+		Files: []FileState{
+			{Path: motd, Content: "new\n", Mode: "0644"},
+			{Path: conf, Content: "port=80\n"},
+			{Path: stale, Absent: true},
+		},
+		Services: []string{"nginx"},
+	}
+	apply := func(check bool) *StateApplyResult {
+		t.Helper()
+		document.Check = check
+		body, _ := json.Marshal(document)
+		ctx := NewExecutionContext(context.Background(), zap.NewNop(), nil, "minion-1", "cmd-1")
+		result, err := cmd.Execute(ctx, "state:apply "+string(body))
+		if err != nil || result.ContentType != ContentTypeStateApply {
+			t.Fatalf("Unexpected result: %v %v", result, err)
+		}
+		var report StateApplyResult
+		if err := json.Unmarshal([]byte(result.Structured), &report); err != nil {
+			t.Fatalf("Invalid structured payload: %v", err)
+		}
+		if (report.Failed > 0) != (result.ExitCode == 1) {
+			t.Errorf("Exit code %d with %d failures", result.ExitCode, report.Failed)
+		}
+		return &report
+	}
+
+	// Check mode reports the changes without making them
+	report := apply(true)
+	if !report.Check || report.Resources != 7 || report.Unchanged != 1 || report.Failed != 0 || len(report.Changes) != 6 {
+		t.Fatalf("Unexpected check report: %+v", report)
+	}
+	if data, _ := os.ReadFile(motd); string(data) != "old\n" || len(ran) != 0 {
+		t.Fatalf("Check mode changed the host: %q %v", data, ran)
+	}
+
+	report = apply(false)
+	want := []StateChange{
+		{Kind: StateKindPackage, Name: "jq", Action: StateInstalled},
+		{Kind: StateKindPackage, Name: "curl", Action: StateFailed, Error: "apt-get failed: exit status 100: E: Unable to locate package curl"},
+		{Kind: StateKindFile, Name: motd, Action: StateUpdated, Mode: "0600 -> 0644", Diff: []string{"-old", "+new"}},
+		{Kind: StateKindFile, Name: conf, Action: StateCreated, Diff: []string{"+port=80"}},
+		{Kind: StateKindFile, Name: stale, Action: StateRemoved},
+		{Kind: StateKindService, Name: "nginx", Action: StateStarted},
+	}
+	if !reflect.DeepEqual(report.Changes, want) || report.Unchanged != 1 || report.Failed != 1 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if data, _ := os.ReadFile(motd); string(data) != "new\n" {
+		t.Errorf("Unexpected content %q", data)
+	}
+	if info, err := os.Stat(conf); err != nil || info.Mode().Perm() != DefaultStateFileMode {
+		t.Errorf("Expected %s created with the default mode: %v %v", conf, info, err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("Expected %s removed: %v", stale, err)
+	}
+
+	// Applying again only retries the failed package
+	ran = nil
+	report = apply(false)
+	if report.Unchanged != 6 || report.Failed != 1 || len(report.Changes) != 1 || !reflect.DeepEqual(ran, []string{"install curl"}) {
+		t.Errorf("Expected the second run idempotent, got %+v %v", report, ran)
+	}
+
+	// Without package manager, packages fail and files are still applied
+	cmd.lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	document = StateDocument{Packages: []string{"jq"}, Files: []FileState{{Path: motd, Content: "newer\n"}}}
+	report = apply(false)
+	if report.Failed != 1 || report.Changes[0].Error != "no supported package manager found" || report.Changes[1].Action != StateUpdated {
+		t.Errorf("Unexpected report without package manager: %+v", report)
+	}
+}
+
+func TestWriteFileAtomicallyKeepsOwner(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() != 0 {
+		t.Skip("changing file owners requires root on a Unix system")
+	}
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte("old"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path, 1234, 5678); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomically(path, []byte("new"), 0o640); err != nil {
+		t.Fatalf("writeFileAtomically failed: %v", err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info FileInfo
+	addUnixOwnerInfo(stat, &info)
+	if info.Owner != "1234" || info.Group != "5678" {
+		t.Errorf("Expected the file to keep its owner 1234:5678, got %s:%s", info.Owner, info.Group)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("Unexpected content %q", data)
+	}
+}
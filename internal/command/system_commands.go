@@ -7,6 +7,21 @@ import (
 	pb "github.com/arhuman/minexus/protogen"
 )
 
+// SystemInfo is the structured payload of system:info
+type SystemInfo struct {
+	OS                string `json:"os"`
+	Arch              string `json:"arch"`
+	TotalMemoryMB     uint64 `json:"total_memory_mb"`
+	AllocatedMemoryMB uint64 `json:"allocated_memory_mb"`
+	Goroutines        int    `json:"goroutines"`
+}
+
+// SystemOS is the structured payload of system:os
+type SystemOS struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
 // SystemInfoCommand provides system information
 type SystemInfoCommand struct {
 	*BaseCommand
@@ -19,7 +34,7 @@ func NewSystemInfoCommand() *SystemInfoCommand {
 		"system",
 		"Get system information including memory, uptime, and load",
 		"system:info",
-	).WithExamples(
+	).WithImpact(ImpactReadOnly).WithExamples(
 		Example{
 			Description: "Get system information",
 			Command:     "command-send all system:info",
@@ -37,10 +52,18 @@ func (c *SystemInfoCommand) Execute(ctx *ExecutionContext, payload string) (*pb.
 	memStats := new(runtime.MemStats)
 	runtime.ReadMemStats(memStats)
 
+	info := SystemInfo{
+		OS:                runtime.GOOS,
+		Arch:              runtime.GOARCH,
+		TotalMemoryMB:     memStats.TotalAlloc / 1024 / 1024,
+		AllocatedMemoryMB: memStats.Alloc / 1024 / 1024,
+		Goroutines:        runtime.NumGoroutine(),
+	}
+
 	output := fmt.Sprintf("OS: %s\nArch: %s\nTotal Memory: %d MB\nAllocated Memory: %d MB\nGoroutines: %d",
-		runtime.GOOS, runtime.GOARCH, memStats.TotalAlloc/1024/1024, memStats.Alloc/1024/1024, runtime.NumGoroutine())
+		info.OS, info.Arch, info.TotalMemoryMB, info.AllocatedMemoryMB, info.Goroutines)
 
-	return c.BaseCommand.CreateSuccessResult(ctx, output), nil
+	return c.BaseCommand.CreateStructuredResult(ctx, output, ContentTypeSystemInfo, info), nil
 }
 
 // SystemOSCommand provides OS information
@@ -55,7 +78,7 @@ func NewSystemOSCommand() *SystemOSCommand {
 		"system",
 		"Get operating system and architecture information",
 		"system:os",
-	).WithExamples(
+	).WithImpact(ImpactReadOnly).WithExamples(
 		Example{
 			Description: "Get OS information",
 			Command:     "command-send all system:os",
@@ -70,6 +93,7 @@ func NewSystemOSCommand() *SystemOSCommand {
 
 // Execute implements ExecutableCommand interface
 func (c *SystemOSCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
-	output := fmt.Sprintf("OS: %s\nArch: %s", runtime.GOOS, runtime.GOARCH)
-	return c.BaseCommand.CreateSuccessResult(ctx, output), nil
+	info := SystemOS{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	output := fmt.Sprintf("OS: %s\nArch: %s", info.OS, info.Arch)
+	return c.BaseCommand.CreateStructuredResult(ctx, output, ContentTypeSystemOS, info), nil
 }
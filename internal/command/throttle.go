@@ -0,0 +1,48 @@
+package command
+
+import (
+	"context"
+	"io"
+)
+
+// throttleReadSize bounds the bytes read at once by a throttled reader, so that a large read
+// doesn't wait for seconds before returning anything
+const throttleReadSize = 64 * 1024
+
+// TransferThrottle caps the file transfers of a minion: how many run at the same time and the
+// bytes per second they read, all transfers together
+type TransferThrottle interface {
+	// Begin reserves a transfer, failing when the minion runs too many of them. end releases it.
+	Begin() (end func(), err error)
+	// WaitN blocks until n more bytes may be transferred, or ctx is done
+	WaitN(ctx context.Context, n int) error
+}
+
+// throttledReader reads at the pace of a throttle
+type throttledReader struct {
+	ctx      context.Context
+	r        io.Reader
+	throttle TransferThrottle
+}
+
+// ThrottledReader returns a reader of r paced by throttle, r itself when throttle is nil
+func ThrottledReader(ctx context.Context, r io.Reader, throttle TransferThrottle) io.Reader {
+	if throttle == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, throttle: throttle}
+}
+
+// Read implements io.Reader
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleReadSize {
+		p = p[:throttleReadSize]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.throttle.WaitN(t.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
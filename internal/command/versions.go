@@ -0,0 +1,79 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellOptionsVersion is the shell handler version supporting the run_as and sandbox options.
+// Older minions ignore these options, running the command as their own user without limits.
+const shellOptionsVersion = 2
+
+// shellEnvVersion is the shell handler version supporting the env option and the environment set by
+// Nexus. Older minions run the command with their own environment only.
+const shellEnvVersion = 3
+
+// CommandFamily returns the family of a command, the part of its name before ':'
+// (file:get belongs to file). The system command, an alias of shell, belongs to shell.
+func CommandFamily(name string) string {
+	if name == "system" {
+		return "shell"
+	}
+	family, _, _ := strings.Cut(name, ":")
+	return family
+}
+
+// SupportsCommandEnv reports whether a minion reporting the handler versions runs its shell commands
+// with the environment set by Nexus
+func SupportsCommandEnv(versions map[string]int32) bool {
+	return versions[CommandFamily("shell")] >= shellEnvVersion
+}
+
+// FamilyVersions returns the handler version of each command family, the highest version of its commands
+func (r *Registry) FamilyVersions() map[string]int32 {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	versions := make(map[string]int32)
+	for name, cmd := range r.commands {
+		family := CommandFamily(name)
+		if version := int32(cmd.Metadata().Version); version > versions[family] {
+			versions[family] = version
+		}
+	}
+	return versions
+}
+
+// RequiredVersion returns the command family of a payload and the handler version it requires:
+// the version of the command it names, or the shell version supporting the options of a JSON shell
+// request. It returns 0 when any handler supports the payload.
+func (r *Registry) RequiredVersion(payload string) (string, int) {
+	payload = strings.TrimSpace(payload)
+	if strings.HasPrefix(payload, "{") {
+		request, err := ParseShellRequest(payload)
+		switch {
+		case err != nil:
+			return "", 0
+		case len(request.Env) > 0:
+			return CommandFamily("shell"), shellEnvVersion
+		case request.RunAs != "" || request.Sandbox != nil:
+			return CommandFamily("shell"), shellOptionsVersion
+		}
+		return "", 0
+	}
+
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return "", 0
+	}
+	cmd, exists := r.GetCommand(fields[0])
+	if !exists || cmd.Metadata().Version <= 1 {
+		return "", 0
+	}
+	return CommandFamily(fields[0]), cmd.Metadata().Version
+}
+
+// FormatVersion formats a command family version, e.g. "shell v2"
+func FormatVersion(family string, version int) string {
+	return fmt.Sprintf("%s v%d", family, version)
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommandFamily(t *testing.T) {
+	tests := map[string]string{
+		"file:get":    "file",
+		"process:top": "process",
+		"system":      "shell",
+		"shell":       "shell",
+		"system:info": "system",
+		"health":      "health",
+	}
+	for name, expected := range tests {
+		if got := CommandFamily(name); got != expected {
+			t.Errorf("CommandFamily(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestFamilyVersions(t *testing.T) {
+	versions := SetupCommands(15 * time.Second).FamilyVersions()
+	expected := map[string]int32{"shell": shellEnvVersion, "process": 2, "file": 3, "logging": 1}
+	for family, version := range expected {
+		if versions[family] != version {
+			t.Errorf("Expected %s version %d, got %d", family, version, versions[family])
+		}
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+	registry := SetupCommands(15 * time.Second)
+	tests := []struct {
+		payload string
+		family  string
+		version int
+	}{
+		{`{"command": "id", "run_as": "nobody"}`, "shell", shellOptionsVersion},
+		{`{"command": "make", "sandbox": {"memory_mb": 64}}`, "shell", shellOptionsVersion},
+		{`{"command": "env", "env": {"HTTP_PROXY": "http://proxy:3128"}}`, "shell", shellEnvVersion},
+		{`{"command": "id"}`, "", 0},
+		{"process:top 5 mem", "process", 2},
+		{"process:list", "", 0},
+		{"ls -la", "", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		family, version := registry.RequiredVersion(tt.payload)
+		if family != tt.family || version != tt.version {
+			t.Errorf("RequiredVersion(%q) = (%q, %d), expected (%q, %d)", tt.payload, family, version, tt.family, tt.version)
+		}
+	}
+	if got := FormatVersion("shell", 2); got != "shell v2" {
+		t.Errorf("Expected shell v2, got %q", got)
+	}
+}
@@ -0,0 +1,217 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// ContentTypeWatchList is the content type of watch:list structured results
+const ContentTypeWatchList = "application/vnd.minexus.watch-list+json"
+
+// WatchRule is a command run by the minion itself whenever a file or directory changes
+type WatchRule struct {
+	ID            string `json:"id"`
+	Path          string `json:"path"`
+	Payload       string `json:"payload"`
+	CreatedAt     int64  `json:"created_at"`
+	LastEvent     string `json:"last_event,omitempty"` // change that triggered the last run, e.g. "WRITE /etc/hosts"
+	LastRun       int64  `json:"last_run,omitempty"`
+	LastCommandID string `json:"last_command_id,omitempty"`
+	LastExitCode  int32  `json:"last_exit_code,omitempty"`
+}
+
+// FileWatcher stores the watch rules of a minion and runs their commands on changes
+type FileWatcher interface {
+	Add(path, payload string) (*WatchRule, error)
+	List() []WatchRule
+	Remove(id string) error
+}
+
+// WatchAddCommand runs a command on the minion whenever a file or directory changes
+type WatchAddCommand struct {
+	*BaseCommand
+	watcher FileWatcher
+}
+
+// NewWatchAddCommand creates a new watch:add command.
+// A nil watcher registers the command for validation and help purposes only.
+func NewWatchAddCommand(watcher FileWatcher) *WatchAddCommand {
+	base := NewBaseCommand(
+		"watch:add",
+		"watch",
+		"Run a command on the minion whenever a file or directory changes",
+		"watch:add <path> <command>",
+	).WithExamples(
+		Example{
+			Description: "Restore the SSH configuration whenever it drifts",
+			Command:     `command-send tag role=web watch:add /etc/ssh/sshd_config file:copy {"source": "/opt/golden/sshd_config", "destination": "/etc/ssh/sshd_config", "options": {"overwrite": true}}`,
+			Expected:    "Returns the ID of the watch rule",
+		},
+		Example{
+			Description: "Reload nginx when a site is added",
+			Command:     "command-send minion abc123 watch:add /etc/nginx/sites-enabled systemctl reload nginx",
+		},
+	).WithParameters(
+		Param{Name: "path", Type: "string", Required: true, Description: "File or directory watched; directories are watched without their subdirectories"},
+		Param{Name: "command", Type: "string", Required: true, Description: "Command to run, as sent with command-send"},
+	).WithNotes(
+		"Changes are debounced: a burst of changes runs the command once",
+		"Changes made while the command runs, or right after it, don't run it again",
+		"Results are reported to Nexus with the triggering change, buffered while the minion is disconnected",
+		"Rules are persisted when the minion has a watch file",
+	)
+
+	return &WatchAddCommand{
+		BaseCommand: base,
+		watcher:     watcher,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *WatchAddCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.watcher == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("file watchers are not enabled on this minion")), nil
+	}
+
+	path, command, err := ParseWatchAdd(payload)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	rule, err := c.watcher.Add(path, command)
+	if err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	return c.BaseCommand.CreateSuccessResult(ctx, fmt.Sprintf("Watch rule %s: '%s' when %s changes", rule.ID, rule.Payload, rule.Path)), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface. The path is checked by the minion watcher.
+func (c *WatchAddCommand) ValidateArgs(payload string) error {
+	_, _, err := ParseWatchAdd(payload)
+	return err
+}
+
+// ParseWatchAdd splits a watch:add payload into its path and command. The command is kept verbatim.
+func ParseWatchAdd(payload string) (string, string, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(payload), "watch:add"))
+	path, command, _ := strings.Cut(rest, " ")
+	command = strings.TrimSpace(command)
+	if path == "" || command == "" {
+		return "", "", fmt.Errorf("usage: watch:add <path> <command>")
+	}
+	if err := validatePath(path); err != nil {
+		return "", "", fmt.Errorf("invalid path: %w", err)
+	}
+	return path, command, nil
+}
+
+// WatchListCommand lists the watch rules of the minion
+type WatchListCommand struct {
+	*BaseCommand
+	watcher FileWatcher
+}
+
+// NewWatchListCommand creates a new watch:list command
+func NewWatchListCommand(watcher FileWatcher) *WatchListCommand {
+	base := NewBaseCommand(
+		"watch:list",
+		"watch",
+		"List the watch rules of the minion",
+		"watch:list",
+	).WithImpact(ImpactReadOnly).WithExamples(
+		Example{
+			Description: "List watch rules",
+			Command:     "command-send all watch:list",
+			Expected:    "Returns the watch rules with the change that last triggered them",
+		},
+	)
+
+	return &WatchListCommand{
+		BaseCommand: base,
+		watcher:     watcher,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *WatchListCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.watcher == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("file watchers are not enabled on this minion")), nil
+	}
+
+	rules := c.watcher.List()
+	if len(rules) == 0 {
+		return c.BaseCommand.CreateStructuredResult(ctx, "No watch rules", ContentTypeWatchList, rules), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("%-10s %-30s %-20s %s\n", "ID", "PATH", "LAST RUN", "COMMAND"))
+	for _, rule := range rules {
+		lastRun := "never"
+		if rule.LastRun != 0 {
+			lastRun = time.Unix(rule.LastRun, 0).Format("2006-01-02 15:04")
+			if rule.LastExitCode != 0 {
+				lastRun += fmt.Sprintf(" (%d)", rule.LastExitCode)
+			}
+		}
+		output.WriteString(fmt.Sprintf("%-10s %-30s %-20s %s\n", rule.ID, rule.Path, lastRun, rule.Payload))
+	}
+
+	return c.BaseCommand.CreateStructuredResult(ctx, output.String(), ContentTypeWatchList, rules), nil
+}
+
+// WatchRemoveCommand removes a watch rule of the minion
+type WatchRemoveCommand struct {
+	*BaseCommand
+	watcher FileWatcher
+}
+
+// NewWatchRemoveCommand creates a new watch:remove command
+func NewWatchRemoveCommand(watcher FileWatcher) *WatchRemoveCommand {
+	base := NewBaseCommand(
+		"watch:remove",
+		"watch",
+		"Remove a watch rule of the minion",
+		"watch:remove <watch-id>",
+	).WithExamples(
+		Example{
+			Description: "Remove a watch rule",
+			Command:     "command-send minion abc123 watch:remove 1a2b3c4d",
+		},
+	).WithParameters(
+		Param{Name: "watch-id", Type: "string", Required: true, Description: "ID returned by watch:add or shown by watch:list"},
+	)
+
+	return &WatchRemoveCommand{
+		BaseCommand: base,
+		watcher:     watcher,
+	}
+}
+
+// Execute implements ExecutableCommand interface
+func (c *WatchRemoveCommand) Execute(ctx *ExecutionContext, payload string) (*pb.CommandResult, error) {
+	if c.watcher == nil {
+		return c.BaseCommand.CreateErrorResult(ctx, fmt.Errorf("file watchers are not enabled on this minion")), nil
+	}
+
+	if err := c.ValidateArgs(payload); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+
+	fields := strings.Fields(payload)
+	if err := c.watcher.Remove(fields[1]); err != nil {
+		return c.BaseCommand.CreateErrorResult(ctx, err), nil
+	}
+	return c.BaseCommand.CreateSuccessResult(ctx, fmt.Sprintf("Watch rule %s removed", fields[1])), nil
+}
+
+// ValidateArgs implements ArgumentValidator interface
+func (c *WatchRemoveCommand) ValidateArgs(payload string) error {
+	if len(strings.Fields(payload)) != 2 {
+		return fmt.Errorf("usage: watch:remove <watch-id>")
+	}
+	return nil
+}
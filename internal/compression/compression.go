@@ -0,0 +1,142 @@
+// Package compression registers the gRPC compressors used between minions, relays, Nexus and consoles.
+//
+// Importing the package registers the gzip and zstd compressors, so a peer decompresses the
+// messages of the others whatever they are configured to send.
+package compression
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Compression algorithms
+const (
+	None = "none"
+	Gzip = gzip.Name
+	Zstd = "zstd"
+)
+
+func init() {
+	encoding.RegisterCompressor(newZstdCompressor())
+}
+
+// DialOption returns the client option compressing the messages sent with name
+func DialOption(name string) grpc.DialOption {
+	if name == "" || name == None {
+		return grpc.EmptyDialOption{}
+	}
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(name))
+}
+
+// ServerOptions returns the server options compressing the messages sent with name to the
+// clients supporting it. Otherwise responses use the compression of the client requests.
+func ServerOptions(name string) []grpc.ServerOption {
+	if name == "" || name == None {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			setSendCompressor(ctx, name)
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			setSendCompressor(ss.Context(), name)
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// setSendCompressor compresses the responses of a call with name when the client advertised it
+func setSendCompressor(ctx context.Context, name string) {
+	supported, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return
+	}
+	for _, compressor := range supported {
+		if compressor == name {
+			_ = grpc.SetSendCompressor(ctx, name)
+			return
+		}
+	}
+}
+
+// zstdCompressor is a gRPC compressor reusing zstd encoders and decoders across messages
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+// newZstdCompressor creates a zstd compressor
+func newZstdCompressor() *zstdCompressor {
+	c := &zstdCompressor{}
+	c.encoders.New = func() any {
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return &zstdWriter{Encoder: encoder, pool: &c.encoders}
+	}
+	c.decoders.New = func() any {
+		decoder, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		return &zstdReader{Decoder: decoder, pool: &c.decoders}
+	}
+	return c
+}
+
+// Name implements encoding.Compressor
+func (c *zstdCompressor) Name() string {
+	return Zstd
+}
+
+// Compress implements encoding.Compressor
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	writer := c.encoders.Get().(*zstdWriter)
+	writer.Reset(w)
+	return writer, nil
+}
+
+// Decompress implements encoding.Compressor
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	reader := c.decoders.Get().(*zstdReader)
+	if err := reader.Reset(r); err != nil {
+		c.decoders.Put(reader)
+		return nil, err
+	}
+	reader.done = false
+	return reader, nil
+}
+
+// zstdWriter returns its encoder to the pool once the message is compressed
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+// Close flushes the compressed message and releases the encoder
+func (w *zstdWriter) Close() error {
+	defer w.pool.Put(w)
+	return w.Encoder.Close()
+}
+
+// zstdReader returns its decoder to the pool once the message is read
+type zstdReader struct {
+	*zstd.Decoder
+	pool *sync.Pool
+	done bool
+}
+
+// Read reads the decompressed message, releasing the decoder at its end
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, io.EOF
+	}
+	n, err := r.Decoder.Read(p)
+	if err == io.EOF {
+		r.done = true
+		r.pool.Put(r)
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCompressorsRoundTrip(t *testing.T) {
+	payload := []byte(strings.Repeat("Filesystem Size Used Avail Use% Mounted on\n", 2000))
+
+	for _, name := range []string{Gzip, Zstd} {
+		compressor := encoding.GetCompressor(name)
+		if compressor == nil {
+			t.Fatalf("Compressor %s is not registered", name)
+		}
+
+		// Several messages exercise the reuse of pooled encoders and decoders
+		for i := 0; i < 3; i++ {
+			var compressed bytes.Buffer
+			writer, err := compressor.Compress(&compressed)
+			if err != nil {
+				t.Fatalf("%s: Compress failed: %v", name, err)
+			}
+			if _, err := writer.Write(payload); err != nil {
+				t.Fatalf("%s: Write failed: %v", name, err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("%s: Close failed: %v", name, err)
+			}
+			if compressed.Len() >= len(payload)/10 {
+				t.Errorf("%s: expected repetitive output to compress well, got %d bytes from %d", name, compressed.Len(), len(payload))
+			}
+
+			reader, err := compressor.Decompress(&compressed)
+			if err != nil {
+				t.Fatalf("%s: Decompress failed: %v", name, err)
+			}
+			decompressed, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("%s: Read failed: %v", name, err)
+			}
+			if !bytes.Equal(decompressed, payload) {
+				t.Fatalf("%s: round trip altered the payload", name)
+			}
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+	if options := ServerOptions(None); len(options) != 0 {
+		t.Errorf("Expected no server option without compression, got %d", len(options))
+	}
+	if options := ServerOptions(Zstd); len(options) != 2 {
+		t.Errorf("Expected unary and stream interceptors, got %d options", len(options))
+	}
+}
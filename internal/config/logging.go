@@ -0,0 +1,128 @@
+package config
+
+import (
+	"flag"
+	"strconv"
+
+	"github.com/arhuman/minexus/internal/logging"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultLogMaxSizeMB is the size at which log files are rotated by default
+const defaultLogMaxSizeMB = 100
+
+// logFlagValues holds the parsed logging command line flag values
+type logFlagValues struct {
+	level      *string
+	format     *string
+	sampling   *string
+	file       *string
+	maxSizeMB  *int
+	maxBackups *int
+	maxAgeDays *int
+	compress   *bool
+}
+
+// loadLogOptions loads the logging options of a component from the environment variables
+// prefixed with prefix, e.g. NEXUS_LOG_LEVEL for NEXUS
+func loadLogOptions(loader *ConfigLoader, prefix string, opts *logging.Options, validationErrors *[]error) {
+	opts.Level = loader.GetString(prefix+"_LOG_LEVEL", opts.Level)
+	opts.Format = loader.GetString(prefix+"_LOG_FORMAT", opts.Format)
+	opts.Sampling = loader.GetString(prefix+"_LOG_SAMPLING", opts.Sampling)
+	opts.File = loader.GetString(prefix+"_LOG_FILE", opts.File)
+	for _, setting := range []struct {
+		envVar   string
+		target   *int
+		min, max int
+	}{
+		{prefix + "_LOG_MAX_SIZE_MB", &opts.MaxSizeMB, 1, 1024 * 1024},
+		{prefix + "_LOG_MAX_BACKUPS", &opts.MaxBackups, 0, 10000},
+		{prefix + "_LOG_MAX_AGE_DAYS", &opts.MaxAgeDays, 0, 36500},
+	} {
+		if value, err := loader.GetIntInRange(setting.envVar, *setting.target, setting.min, setting.max); err != nil {
+			*validationErrors = append(*validationErrors, err)
+		} else {
+			*setting.target = value
+		}
+	}
+	if compress, err := loader.GetBool(prefix+"_LOG_COMPRESS", opts.Compress); err != nil {
+		*validationErrors = append(*validationErrors, err)
+	} else {
+		opts.Compress = compress
+	}
+}
+
+// parseLogFlags registers the logging command line flags
+func parseLogFlags(opts *logging.Options) *logFlagValues {
+	return &logFlagValues{
+		level:      flag.String("log-level", opts.Level, "Log level: debug, info, warn or error (default: debug with -debug, info otherwise)"),
+		format:     flag.String("log-format", opts.Format, "Log encoding: json or console (default: console with -debug, json otherwise)"),
+		sampling:   flag.String("log-sampling", opts.Sampling, "Entries logged per second with the same message before sampling, then one out of n: <initial>,<n> or off"),
+		file:       flag.String("log-file", opts.File, "File the logs are written to instead of stderr, rotated by size"),
+		maxSizeMB:  flag.Int("log-max-size-mb", opts.MaxSizeMB, "Size in MB at which the log file is rotated"),
+		maxBackups: flag.Int("log-max-backups", opts.MaxBackups, "Rotated log files kept (0 keeps all)"),
+		maxAgeDays: flag.Int("log-max-age-days", opts.MaxAgeDays, "Days rotated log files are kept (0 keeps them forever)"),
+		compress:   flag.Bool("log-compress", opts.Compress, "Compress rotated log files with gzip"),
+	}
+}
+
+// applyLogFlags applies and validates the logging command line flag values
+func applyLogFlags(flags *logFlagValues, opts *logging.Options, validationErrors *[]error) {
+	opts.Level = *flags.level
+	opts.Format = *flags.format
+	opts.Sampling = *flags.sampling
+	opts.File = *flags.file
+	opts.MaxSizeMB = *flags.maxSizeMB
+	opts.MaxBackups = *flags.maxBackups
+	opts.MaxAgeDays = *flags.maxAgeDays
+	opts.Compress = *flags.compress
+	if err := validateLogOptions(opts); err != nil {
+		*validationErrors = append(*validationErrors, err)
+	}
+}
+
+// validateLogOptions validates logging options, empty values keeping the defaults
+func validateLogOptions(opts *logging.Options) error {
+	switch opts.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return ValidationError{Field: "log-level", Value: opts.Level, Message: "must be 'debug', 'info', 'warn' or 'error'"}
+	}
+	switch opts.Format {
+	case "", "json", "console":
+	default:
+		return ValidationError{Field: "log-format", Value: opts.Format, Message: "must be 'json' or 'console'"}
+	}
+	if opts.Sampling != "" {
+		if _, _, err := logging.ParseSampling(opts.Sampling); err != nil {
+			return ValidationError{Field: "log-sampling", Value: opts.Sampling, Message: "must be '<initial>,<thereafter>' or 'off'"}
+		}
+	}
+	if opts.MaxSizeMB < 1 {
+		return ValidationError{Field: "log-max-size-mb", Value: strconv.Itoa(opts.MaxSizeMB), Message: "must be at least 1"}
+	}
+	if opts.MaxBackups < 0 {
+		return ValidationError{Field: "log-max-backups", Value: strconv.Itoa(opts.MaxBackups), Message: "must not be negative"}
+	}
+	if opts.MaxAgeDays < 0 {
+		return ValidationError{Field: "log-max-age-days", Value: strconv.Itoa(opts.MaxAgeDays), Message: "must not be negative"}
+	}
+	return nil
+}
+
+// logOptions logs logging options as an object
+type logOptions logging.Options
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (o logOptions) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("level", o.Level)
+	enc.AddString("format", o.Format)
+	enc.AddString("sampling", o.Sampling)
+	enc.AddString("file", o.File)
+	enc.AddInt("max_size_mb", o.MaxSizeMB)
+	enc.AddInt("max_backups", o.MaxBackups)
+	enc.AddInt("max_age_days", o.MaxAgeDays)
+	enc.AddBool("compress", o.Compress)
+	return nil
+}
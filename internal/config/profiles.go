@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// defaultProfileFileName is the console profile file created in the user's home directory
+const defaultProfileFileName = ".minexus_profiles.json"
+
+// ConsoleProfile is a named set of console connection settings, for operators managing several
+// Nexus environments. Empty certificate paths use the embedded console credentials, an empty
+// output format or compression keeps the one configured, an empty token file presents no token.
+type ConsoleProfile struct {
+	Server      string `json:"server"`                // Nexus console address, host:port
+	CACert      string `json:"ca_cert,omitempty"`     // PEM CA certificate verifying Nexus
+	ClientCert  string `json:"client_cert,omitempty"` // PEM console client certificate
+	ClientKey   string `json:"client_key,omitempty"`  // PEM console client key
+	TokenFile   string `json:"token_file,omitempty"`  // file holding the OpenID Connect token presented to Nexus
+	Output      string `json:"output,omitempty"`      // default output format: "text" or "json"
+	Compression string `json:"compression,omitempty"` // gRPC compression: "none", "gzip" or "zstd"
+}
+
+// DefaultProfileFile returns the console profile file path in the user's home directory
+func DefaultProfileFile() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, defaultProfileFileName)
+}
+
+// LoadConsoleProfiles reads the console profiles of path, the default profile file if empty.
+// A missing file yields no profile.
+func LoadConsoleProfiles(path string) (map[string]ConsoleProfile, error) {
+	if path == "" {
+		path = DefaultProfileFile()
+	}
+
+	profiles := make(map[string]ConsoleProfile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile file: %w", err)
+	}
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profile file %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// ProfileNames returns the names of profiles in alphabetical order
+func ProfileNames(profiles map[string]ConsoleProfile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate checks the settings of the profile name
+func (p ConsoleProfile) validate(name string) error {
+	field := "profile " + name
+	if err := NewConfigLoader().ValidateNetworkAddress(field+" server", p.Server); err != nil {
+		return err
+	}
+	if (p.ClientCert == "") != (p.ClientKey == "") {
+		return ValidationError{Field: field, Value: p.ClientCert, Message: "client certificate and key must be set together"}
+	}
+	if p.Output != "" {
+		if err := validateOutputFormat(p.Output); err != nil {
+			return err
+		}
+	}
+	if p.Compression != "" {
+		if err := validateCompression(p.Compression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseProfile applies the settings of the profile name, read from the configured profile file
+func (c *ConsoleConfig) UseProfile(name string) error {
+	profiles, err := LoadConsoleProfiles(c.ProfileFile)
+	if err != nil {
+		return err
+	}
+	profile, exists := profiles[name]
+	if !exists {
+		return ValidationError{Field: "profile", Value: name, Message: "profile not found"}
+	}
+	if err := profile.validate(name); err != nil {
+		return err
+	}
+
+	c.Profile = name
+	c.ServerAddr = profile.Server
+	c.CACert = profile.CACert
+	c.ClientCert = profile.ClientCert
+	c.ClientKey = profile.ClientKey
+	c.TokenFile = profile.TokenFile
+	if profile.Output != "" {
+		c.OutputFormat = profile.Output
+	}
+	if profile.Compression != "" {
+		c.Compression = profile.Compression
+	}
+	return nil
+}
@@ -2,9 +2,15 @@
 package logging
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // FuncLogger returns a logger with the function name as a field and the current time
@@ -20,21 +26,117 @@ func FuncExit(logger *zap.Logger, start time.Time) {
 	logger.With(zap.Duration("elapsed", time.Since(start))).Info("function exited")
 }
 
-// SetupLogger creates a configured logger instance with consistent settings
-// across all Minexus components. Returns logger, atomic level, and error.
-func SetupLogger(debug bool) (*zap.Logger, zap.AtomicLevel, error) {
-	var atom zap.AtomicLevel
-	var config zap.Config
+// Options configures the logger of a component. Zero values keep the defaults of the debug mode:
+// debug level, console encoding and no sampling with debug, info level, JSON encoding and sampling otherwise.
+type Options struct {
+	Level      string // "debug", "info", "warn" or "error"
+	Format     string // "json" or "console"
+	Sampling   string // "<initial>,<thereafter>" entries logged per second with the same level and message, or "off"
+	File       string // file the logs are written to instead of stderr, rotated by size (empty: stderr)
+	MaxSizeMB  int    // size in megabytes at which the log file is rotated (0: 100)
+	MaxBackups int    // rotated log files kept (0: all)
+	MaxAgeDays int    // days rotated log files are kept (0: forever)
+	Compress   bool   // gzip rotated log files
+}
 
+// defaultSampling is the sampling of the loggers without debug, as in zap production loggers
+const defaultSampling = "100,100"
+
+// ParseSampling parses sampling rates, "<initial>,<thereafter>" or "off" which disables sampling (0, 0)
+func ParseSampling(sampling string) (int, int, error) {
+	if sampling == "off" {
+		return 0, 0, nil
+	}
+	initialValue, thereafterValue, found := strings.Cut(sampling, ",")
+	initial, err := strconv.Atoi(strings.TrimSpace(initialValue))
+	if !found || err != nil || initial < 1 {
+		return 0, 0, fmt.Errorf("invalid sampling '%s', use <initial>,<thereafter> or off", sampling)
+	}
+	thereafter, err := strconv.Atoi(strings.TrimSpace(thereafterValue))
+	if err != nil || thereafter < 0 {
+		return 0, 0, fmt.Errorf("invalid sampling '%s', use <initial>,<thereafter> or off", sampling)
+	}
+	return initial, thereafter, nil
+}
+
+// FormatSampling formats sampling rates as parsed by ParseSampling
+func FormatSampling(initial, thereafter int) string {
+	if initial <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%d,%d", initial, thereafter)
+}
+
+// NewLogger creates the logger of a component from its options. The returned atomic level and
+// sampler let the level and the sampling rates be changed at runtime.
+func NewLogger(debug bool, opts Options) (*zap.Logger, zap.AtomicLevel, *Sampler, error) {
+	var config zap.Config
 	if debug {
-		atom = zap.NewAtomicLevelAt(zap.DebugLevel)
 		config = zap.NewDevelopmentConfig()
 	} else {
-		atom = zap.NewAtomicLevelAt(zap.InfoLevel)
 		config = zap.NewProductionConfig()
 	}
 
-	config.Level = atom
-	logger, err := config.Build()
+	atom := config.Level
+	if opts.Level != "" {
+		level, err := zapcore.ParseLevel(opts.Level)
+		if err != nil {
+			return nil, atom, nil, fmt.Errorf("invalid log level '%s'", opts.Level)
+		}
+		atom = zap.NewAtomicLevelAt(level)
+	}
+
+	var encoder zapcore.Encoder
+	format := opts.Format
+	if format == "" {
+		format = config.Encoding
+	}
+	switch format {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(config.EncoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(config.EncoderConfig)
+	default:
+		return nil, atom, nil, fmt.Errorf("invalid log format '%s', use json or console", format)
+	}
+
+	sampling := opts.Sampling
+	if sampling == "" {
+		sampling = "off"
+		if !debug {
+			sampling = defaultSampling
+		}
+	}
+	initial, thereafter, err := ParseSampling(sampling)
+	if err != nil {
+		return nil, atom, nil, err
+	}
+	sampler := NewSampler(initial, thereafter)
+
+	var output zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+	if opts.File != "" {
+		output = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			Compress:   opts.Compress,
+		})
+	}
+
+	core := sampler.Wrap(zapcore.NewCore(encoder, output, atom))
+	zapOptions := []zap.Option{zap.ErrorOutput(zapcore.Lock(os.Stderr)), zap.AddCaller()}
+	if debug {
+		zapOptions = append(zapOptions, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	} else {
+		zapOptions = append(zapOptions, zap.AddStacktrace(zapcore.ErrorLevel))
+	}
+	return zap.New(core, zapOptions...), atom, sampler, nil
+}
+
+// SetupLogger creates a configured logger instance with consistent settings
+// across all Minexus components. Returns logger, atomic level, and error.
+func SetupLogger(debug bool) (*zap.Logger, zap.AtomicLevel, error) {
+	logger, atom, _, err := NewLogger(debug, Options{})
 	return logger, atom, err
 }
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseSampling(t *testing.T) {
+	if initial, thereafter, err := ParseSampling("100, 10"); err != nil || initial != 100 || thereafter != 10 {
+		t.Errorf("Unexpected sampling: %d %d %v", initial, thereafter, err)
+	}
+	if initial, thereafter, err := ParseSampling("off"); err != nil || initial != 0 || thereafter != 0 {
+		t.Errorf("Unexpected disabled sampling: %d %d %v", initial, thereafter, err)
+	}
+	for _, sampling := range []string{"", "100", "0,10", "10,-1", "a,b"} {
+		if _, _, err := ParseSampling(sampling); err == nil {
+			t.Errorf("Expected error for %q", sampling)
+		}
+	}
+	if FormatSampling(0, 10) != "off" || FormatSampling(5, 2) != "5,2" {
+		t.Error("Unexpected formatted sampling")
+	}
+}
+
+func TestSampler(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	sampler := NewSampler(2, 3)
+	logger := zap.New(sampler.Wrap(core)).With(zap.String("component", "test"))
+
+	for i := 0; i < 8; i++ {
+		logger.Info("repeated")
+	}
+	logger.Warn("repeated")
+	logger.Info("other")
+	// 2 first, then the 5th and 8th entries
+	if n := logs.FilterMessage("repeated").FilterLevelExact(zapcore.InfoLevel).Len(); n != 4 {
+		t.Errorf("Expected 4 sampled entries, got %d", n)
+	}
+	if logs.FilterLevelExact(zapcore.WarnLevel).Len() != 1 || logs.FilterMessage("other").Len() != 1 {
+		t.Error("Expected entries of other levels and messages to be counted apart")
+	}
+	if entry := logs.All()[0]; entry.ContextMap()["component"] != "test" {
+		t.Errorf("Expected the fields of the logger to be kept, got %v", entry.ContextMap())
+	}
+
+	// Rates changed at runtime apply to the loggers already created
+	sampler.SetRates(0, 0)
+	logs.TakeAll()
+	for i := 0; i < 10; i++ {
+		logger.Info("repeated")
+	}
+	if logs.Len() != 10 {
+		t.Errorf("Expected every entry without sampling, got %d", logs.Len())
+	}
+	if initial, thereafter := sampler.Rates(); initial != 0 || thereafter != 0 {
+		t.Errorf("Unexpected rates: %d %d", initial, thereafter)
+	}
+}
+
+func TestSamplerTick(t *testing.T) {
+	sampler := NewSampler(1, 0)
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Message: "tick", Time: time.Now()}
+	if !sampler.allow(entry) || sampler.allow(entry) {
+		t.Error("Expected only the first entry of the tick to be logged")
+	}
+	entry.Time = entry.Time.Add(samplerTick)
+	if !sampler.allow(entry) {
+		t.Error("Expected the count to restart on the next tick")
+	}
+}
+
+func TestNewLoggerFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "nexus.log")
+	logger, atom, sampler, err := NewLogger(false, Options{Level: "warn", Format: "console", Sampling: "off", File: file, MaxSizeMB: 1})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	if atom.Level() != zapcore.WarnLevel {
+		t.Errorf("Expected warn level, got %v", atom.Level())
+	}
+	if initial, _ := sampler.Rates(); initial != 0 {
+		t.Errorf("Expected sampling disabled, got %d", initial)
+	}
+
+	logger.Info("hidden")
+	logger.Warn("disk almost full")
+	_ = logger.Sync()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "disk almost full") || strings.Contains(string(data), "hidden") || strings.HasPrefix(string(data), "{") {
+		t.Errorf("Unexpected log file content: %s", data)
+	}
+
+	if _, _, sampler, err := NewLogger(false, Options{}); err != nil || sampler == nil {
+		t.Errorf("Expected the production defaults, got %v", err)
+	} else if initial, thereafter := sampler.Rates(); initial != 100 || thereafter != 100 {
+		t.Errorf("Expected the production sampling, got %d,%d", initial, thereafter)
+	}
+	for _, opts := range []Options{{Level: "loud"}, {Format: "xml"}, {Sampling: "often"}} {
+		if _, _, _, err := NewLogger(false, opts); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
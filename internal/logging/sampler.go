@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Sampler parameters: entries are counted per level and message hash over one second
+const (
+	samplerTick     = time.Second
+	samplerCounters = 4096
+	samplerLevels   = int(zapcore.FatalLevel-zapcore.DebugLevel) + 1
+)
+
+// Sampler drops repeated log entries: each second, the first initial entries with the same level
+// and message are logged, then one out of thereafter. Unlike the zap sampler, its rates can be
+// changed at runtime.
+type Sampler struct {
+	initial    atomic.Int64
+	thereafter atomic.Int64
+	counters   [samplerLevels][samplerCounters]samplerCounter
+}
+
+// samplerCounter counts the entries of a level and message hash since resetAt
+type samplerCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// NewSampler creates a sampler, an initial rate of 0 disabling sampling
+func NewSampler(initial, thereafter int) *Sampler {
+	s := &Sampler{}
+	s.SetRates(initial, thereafter)
+	return s
+}
+
+// SetRates changes the sampling rates, an initial rate of 0 disabling sampling
+func (s *Sampler) SetRates(initial, thereafter int) {
+	s.initial.Store(int64(initial))
+	s.thereafter.Store(int64(thereafter))
+}
+
+// Rates returns the sampling rates
+func (s *Sampler) Rates() (int, int) {
+	return int(s.initial.Load()), int(s.thereafter.Load())
+}
+
+// Wrap returns a core dropping the entries of core discarded by the sampler
+func (s *Sampler) Wrap(core zapcore.Core) zapcore.Core {
+	return &samplerCore{Core: core, sampler: s}
+}
+
+// allow reports whether an entry is logged
+func (s *Sampler) allow(ent zapcore.Entry) bool {
+	initial := uint64(s.initial.Load())
+	if initial == 0 {
+		return true
+	}
+	level := int(ent.Level - zapcore.DebugLevel)
+	if level < 0 || level >= samplerLevels {
+		return true
+	}
+
+	counter := &s.counters[level][messageHash(ent.Message)%samplerCounters]
+	n := counter.inc(ent.Time.UnixNano())
+	if n <= initial {
+		return true
+	}
+	thereafter := uint64(s.thereafter.Load())
+	return thereafter > 0 && (n-initial)%thereafter == 0
+}
+
+// inc counts an entry logged at now, restarting the count once a tick elapsed
+func (c *samplerCounter) inc(now int64) uint64 {
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+	if c.resetAt.CompareAndSwap(resetAt, now+int64(samplerTick)) {
+		c.count.Store(1)
+		return 1
+	}
+	return c.count.Add(1)
+}
+
+// messageHash is the FNV-1a hash of a message
+func messageHash(message string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(message); i++ {
+		hash ^= uint32(message[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// samplerCore is a core whose entries are sampled
+type samplerCore struct {
+	zapcore.Core
+	sampler *Sampler
+}
+
+// With implements zapcore.Core, the fields being added to the sampled core
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerCore{Core: c.Core.With(fields), sampler: c.sampler}
+}
+
+// Check implements zapcore.Core
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) || !c.sampler.allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,309 @@
+package longpoll
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// pollGrace is added to the poll wait to detect a Nexus that stopped answering
+const pollGrace = 30 * time.Second
+
+// Client implements pb.MinionServiceClient over HTTP long polling. Errors are returned as gRPC
+// status errors so minions handle both transports alike.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	wait       time.Duration
+}
+
+// NewClient creates a client reaching the long-polling endpoints of Nexus at baseURL
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+		wait:       DefaultPollWait,
+	}
+}
+
+// Register registers the minion with Nexus
+func (c *Client) Register(ctx context.Context, in *pb.HostInfo, _ ...grpc.CallOption) (*pb.RegisterResponse, error) {
+	body, err := protojson.Marshal(in)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode host info: %v", err)
+	}
+
+	data, err := c.do(ctx, http.MethodPost, RegisterPath, in.Id, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.RegisterResponse{}
+	if err := protojson.Unmarshal(data, resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode registration response: %v", err)
+	}
+	return resp, nil
+}
+
+// StreamCommands returns a stream polling for the commands of the minion identified by the
+// "minion-id" metadata of ctx, and posting the messages sent
+func (c *Client) StreamCommands(ctx context.Context, _ ...grpc.CallOption) (grpc.BidiStreamingClient[pb.CommandStreamMessage, pb.CommandStreamMessage], error) {
+	var minionID string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get("minion-id"); len(values) > 0 {
+			minionID = values[0]
+		}
+	}
+	if minionID == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID not provided")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &stream{
+		ctx:      ctx,
+		cancel:   cancel,
+		client:   c,
+		minionID: minionID,
+		messages: make(chan *pb.CommandStreamMessage),
+		done:     make(chan struct{}),
+		acked:    make(chan struct{}, 1),
+	}
+	go s.poll()
+	return s, nil
+}
+
+// RelayStream is not supported, relays connect to Nexus over gRPC
+func (c *Client) RelayStream(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[pb.RelayMessage, pb.RelayMessage], error) {
+	return nil, status.Error(codes.Unimplemented, "relays are not supported over HTTP long polling")
+}
+
+// SpeedTest is not supported, speed tests measure the gRPC channel to Nexus
+func (c *Client) SpeedTest(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[pb.SpeedTestProbe, pb.SpeedTestProbe], error) {
+	return nil, status.Error(codes.Unimplemented, "speed tests are not supported over HTTP long polling")
+}
+
+// poll fetches the messages queued for a minion, waiting up to the poll wait for some
+func (c *Client) poll(ctx context.Context, minionID string) ([]*pb.CommandStreamMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.wait+pollGrace)
+	defer cancel()
+
+	path := fmt.Sprintf("%s?wait=%d", PollPath, int(c.wait/time.Second))
+	data, err := c.do(ctx, http.MethodGet, path, minionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	msgs, err := UnmarshalMessages(data)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return msgs, nil
+}
+
+// do sends a request to Nexus and returns the response body
+func (c *Client) do(ctx context.Context, method, path, minionID string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", ContentType)
+	}
+	if minionID != "" {
+		req.Header.Set(MinionIDHeader, minionID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, status.FromContextError(ctxErr).Err()
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, status.Errorf(codes.Unavailable, "%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, status.Error(statusCode(resp.StatusCode), strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// statusCode returns the gRPC code of an HTTP error status
+func statusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusInternalServerError:
+		return codes.Internal
+	default:
+		return codes.Unavailable
+	}
+}
+
+// stream implements the command stream of a minion over long polling. Polls run in the
+// background; Recv returns their messages one at a time, after the result acknowledgements
+// Nexus answered the posted messages with.
+type stream struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	client   *Client
+	minionID string
+	messages chan *pb.CommandStreamMessage
+	done     chan struct{}
+	err      error // set before done is closed
+
+	acksMu sync.Mutex
+	acks   []*pb.CommandStreamMessage // acknowledgements not returned by Recv yet
+	acked  chan struct{}              // signaled when acknowledgements are queued
+}
+
+// poll polls until the stream is closed or a poll fails
+func (s *stream) poll() {
+	defer close(s.done)
+	for {
+		msgs, err := s.client.poll(s.ctx, s.minionID)
+		if err != nil {
+			s.err = err
+			return
+		}
+		for _, msg := range msgs {
+			select {
+			case s.messages <- msg:
+			case <-s.ctx.Done():
+				s.err = status.FromContextError(s.ctx.Err()).Err()
+				return
+			}
+		}
+	}
+}
+
+// Recv returns the next result acknowledgement, or else the next message polled from Nexus
+func (s *stream) Recv() (*pb.CommandStreamMessage, error) {
+	for {
+		if ack := s.nextAck(); ack != nil {
+			return ack, nil
+		}
+		select {
+		case msg := <-s.messages:
+			return msg, nil
+		case <-s.acked:
+		case <-s.done:
+			return nil, s.err
+		}
+	}
+}
+
+// nextAck removes the first queued acknowledgement, nil when there is none
+func (s *stream) nextAck() *pb.CommandStreamMessage {
+	s.acksMu.Lock()
+	defer s.acksMu.Unlock()
+
+	if len(s.acks) == 0 {
+		return nil
+	}
+	ack := s.acks[0]
+	s.acks = s.acks[1:]
+	return ack
+}
+
+// Send posts a message to Nexus, queuing the result acknowledgements it answers with
+func (s *stream) Send(msg *pb.CommandStreamMessage) error {
+	body, err := MarshalMessages([]*pb.CommandStreamMessage{msg})
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	data, err := s.client.do(s.ctx, http.MethodPost, MessagesPath, s.minionID, body)
+	// Nexus answers without body when no result was acknowledged
+	if err != nil || len(data) == 0 {
+		return err
+	}
+
+	acks, err := UnmarshalMessages(data)
+	if err != nil {
+		// The message was delivered, its results are sent again without acknowledgement
+		return nil
+	}
+	s.acksMu.Lock()
+	s.acks = append(s.acks, acks...)
+	s.acksMu.Unlock()
+	select {
+	case s.acked <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Header returns no metadata, the transport has none
+func (s *stream) Header() (metadata.MD, error) {
+	return metadata.MD{}, nil
+}
+
+// Trailer returns no metadata, the transport has none
+func (s *stream) Trailer() metadata.MD {
+	return nil
+}
+
+// CloseSend closes the stream, stopping its polls
+func (s *stream) CloseSend() error {
+	s.cancel()
+	return nil
+}
+
+// Context returns the context of the stream
+func (s *stream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg sends m, which must be a *pb.CommandStreamMessage
+func (s *stream) SendMsg(m any) error {
+	msg, ok := m.(*pb.CommandStreamMessage)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected message type %T", m)
+	}
+	return s.Send(msg)
+}
+
+// RecvMsg receives the next message into m, which must be a *pb.CommandStreamMessage
+func (s *stream) RecvMsg(m any) error {
+	target, ok := m.(*pb.CommandStreamMessage)
+	if !ok {
+		return status.Errorf(codes.Internal, "unexpected message type %T", m)
+	}
+	msg, err := s.Recv()
+	if err != nil {
+		return err
+	}
+	proto.Merge(target, msg)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package longpoll implements the HTTP long-polling transport minions fall back to when
+// networks block their long-lived gRPC command stream.
+//
+// A minion registers with a POST of its host information, polls for commands with GETs held by
+// Nexus until commands are queued or the wait expires, and posts its results and status updates.
+// Messages are the protobuf messages of the gRPC transport, encoded as JSON.
+package longpoll
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Endpoints of the transport
+const (
+	RegisterPath = "/minion/register"
+	PollPath     = "/minion/poll"
+	MessagesPath = "/minion/messages"
+)
+
+// MinionIDHeader identifies the minion polling or posting messages
+const MinionIDHeader = "X-Minion-Id"
+
+// ContentType of the requests and responses
+const ContentType = "application/json"
+
+// Poll waits: how long Nexus holds a poll without commands before answering empty
+const (
+	DefaultPollWait = 25 * time.Second
+	MaxPollWait     = 60 * time.Second
+)
+
+// messageBatch is the JSON encoding of a list of stream messages
+type messageBatch struct {
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// MarshalMessages encodes stream messages as JSON
+func MarshalMessages(msgs []*pb.CommandStreamMessage) ([]byte, error) {
+	batch := messageBatch{Messages: make([]json.RawMessage, 0, len(msgs))}
+	for _, msg := range msgs {
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message: %w", err)
+		}
+		batch.Messages = append(batch.Messages, data)
+	}
+	return json.Marshal(batch)
+}
+
+// UnmarshalMessages decodes stream messages encoded by MarshalMessages
+func UnmarshalMessages(data []byte) ([]*pb.CommandStreamMessage, error) {
+	var batch messageBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	msgs := make([]*pb.CommandStreamMessage, 0, len(batch.Messages))
+	for _, raw := range batch.Messages {
+		msg := &pb.CommandStreamMessage{}
+		if err := protojson.Unmarshal(raw, msg); err != nil {
+			return nil, fmt.Errorf("failed to decode message: %w", err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
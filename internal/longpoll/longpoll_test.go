@@ -0,0 +1,69 @@
+package longpoll
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMessagesRoundTrip(t *testing.T) {
+	msgs := []*pb.CommandStreamMessage{
+		{Message: &pb.CommandStreamMessage_Command{Command: &pb.Command{Id: "cmd-1", Payload: "uptime"}}},
+		{Message: &pb.CommandStreamMessage_Result{Result: &pb.CommandResult{CommandId: "cmd-1", ExitCode: 2, Stdout: "out"}}},
+	}
+
+	data, err := MarshalMessages(msgs)
+	if err != nil {
+		t.Fatalf("MarshalMessages failed: %v", err)
+	}
+	decoded, err := UnmarshalMessages(data)
+	if err != nil {
+		t.Fatalf("UnmarshalMessages failed: %v", err)
+	}
+
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(decoded))
+	}
+	if decoded[0].GetCommand().GetPayload() != "uptime" {
+		t.Errorf("Unexpected command %v", decoded[0])
+	}
+	if result := decoded[1].GetResult(); result.GetExitCode() != 2 || result.GetStdout() != "out" {
+		t.Errorf("Unexpected result %v", decoded[1])
+	}
+
+	if _, err := UnmarshalMessages([]byte(`{"messages":[{"unknown":1}]}`)); err == nil {
+		t.Error("Expected an error for an invalid message")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(MinionIDHeader) != "minion-1" {
+			http.Error(w, "minion ID not provided", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "namespace not allowed", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", server.Client())
+	_, err := client.Register(context.Background(), &pb.HostInfo{Id: "minion-1"})
+	if status.Code(err) != codes.PermissionDenied || status.Convert(err).Message() != "namespace not allowed" {
+		t.Errorf("Expected PermissionDenied, got %v", err)
+	}
+
+	if _, err := client.StreamCommands(context.Background()); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+
+	server.Close()
+	if _, err := client.Register(context.Background(), &pb.HostInfo{Id: "minion-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable once Nexus is down, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// certVerifyTimeout bounds the verification handshake made with new credentials
+	certVerifyTimeout = 10 * time.Second
+	// certReconnectDelay leaves time for the rotation result to reach Nexus before reconnecting
+	certReconnectDelay = 2 * time.Second
+)
+
+// certRotator implements command.CertRotator on top of a certificate store
+type certRotator struct {
+	store          *certs.Store
+	serverAddr     string
+	verifyTimeout  time.Duration
+	reconnectDelay time.Duration
+	verify         func(ctx context.Context, addr string, bundle *certs.Bundle) error
+	logger         *zap.Logger
+}
+
+// NewCertRotator creates a rotator that installs bundles in store and verifies them against serverAddr
+func NewCertRotator(store *certs.Store, serverAddr string, logger *zap.Logger) command.CertRotator {
+	return &certRotator{
+		store:          store,
+		serverAddr:     serverAddr,
+		verifyTimeout:  certVerifyTimeout,
+		reconnectDelay: certReconnectDelay,
+		verify:         certs.VerifyHandshake,
+		logger:         logger,
+	}
+}
+
+// Rotate installs bundle, checks that Nexus accepts it and schedules a reconnection.
+// The previous bundle is restored when the verification handshake fails.
+func (r *certRotator) Rotate(ctx context.Context, bundle *certs.Bundle) error {
+	logger, start := logging.FuncLogger(r.logger, "certRotator.Rotate")
+	defer logging.FuncExit(logger, start)
+
+	previous := r.store.Current()
+
+	if err := r.store.Install(bundle); err != nil {
+		logger.Error("Failed to install certificate bundle", zap.Error(err))
+		return fmt.Errorf("failed to install certificate bundle: %w", err)
+	}
+
+	logger.Info("Certificate bundle installed, verifying handshake",
+		zap.String("previous", previous.Fingerprint()),
+		zap.String("new", bundle.Fingerprint()),
+		zap.String("server", r.serverAddr))
+
+	verifyCtx, cancel := context.WithTimeout(ctx, r.verifyTimeout)
+	defer cancel()
+
+	if err := r.verify(verifyCtx, r.serverAddr, bundle); err != nil {
+		logger.Error("Handshake with new credentials failed, rolling back", zap.Error(err))
+		if rollbackErr := r.store.Rollback(); rollbackErr != nil {
+			logger.Error("Failed to restore previous certificate bundle", zap.Error(rollbackErr))
+			return fmt.Errorf("handshake with new credentials failed: %v (rollback failed: %v)", err, rollbackErr)
+		}
+		return fmt.Errorf("handshake with new credentials failed, previous credentials restored: %w", err)
+	}
+
+	// Existing connections still use the old credentials: drop them once the
+	// result of the rotation has been sent so gRPC reconnects with the new bundle
+	time.AfterFunc(r.reconnectDelay, func() {
+		logger.Info("Resetting connections to use rotated credentials")
+		r.store.ResetConnections()
+	})
+
+	return nil
+}
+
+// EnableCertRotation enables the certs:rotate command using the given credential store
+func (m *Minion) EnableCertRotation(store *certs.Store, serverAddr string) {
+	m.registry.Register(command.NewCertsRotateCommand(NewCertRotator(store, serverAddr, m.logger)))
+}
+
+// EnableCommandVerification makes the minion execute only the commands signed by a console
+// whose certificate chains to one of the PEM certificates of bundle
+func (m *Minion) EnableCommandVerification(bundle []byte) error {
+	verifier, err := certs.NewCommandVerifier(bundle)
+	if err != nil {
+		return err
+	}
+	m.commandProcessor.(*commandProcessor).verifier = verifier
+	return nil
+}
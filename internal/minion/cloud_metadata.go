@@ -0,0 +1,250 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// metadataTimeout bounds the detection of the cloud provider at startup
+	metadataTimeout = 2 * time.Second
+	// metadataAddress is the link-local address of the instance metadata services
+	metadataAddress = "http://169.254.169.254"
+)
+
+// CloudMetadata describes the cloud instance a minion runs on
+type CloudMetadata struct {
+	Provider     string // aws, gcp or azure
+	InstanceID   string
+	Region       string
+	Zone         string
+	InstanceType string
+}
+
+// Tags returns the minion tags derived from the metadata, omitting unknown values
+func (md *CloudMetadata) Tags() map[string]string {
+	tags := make(map[string]string)
+	for key, value := range map[string]string{
+		"cloud":         md.Provider,
+		"instance-id":   md.InstanceID,
+		"region":        md.Region,
+		"zone":          md.Zone,
+		"instance-type": md.InstanceType,
+	} {
+		if value != "" {
+			tags[key] = value
+		}
+	}
+	return tags
+}
+
+// MetadataCollector queries the instance metadata services of AWS, GCP and Azure
+type MetadataCollector struct {
+	client   *http.Client
+	awsURL   string
+	gcpURL   string
+	azureURL string
+	logger   *zap.Logger
+}
+
+// NewMetadataCollector creates a collector querying the standard metadata endpoints
+func NewMetadataCollector(logger *zap.Logger) *MetadataCollector {
+	return &MetadataCollector{
+		client:   &http.Client{Timeout: metadataTimeout},
+		awsURL:   metadataAddress,
+		gcpURL:   "http://metadata.google.internal",
+		azureURL: metadataAddress,
+		logger:   logger,
+	}
+}
+
+// Collect queries the metadata services concurrently and returns the metadata of the first provider
+// answering, in AWS, GCP, Azure order. It returns an error when the minion doesn't run on any of them.
+func (mc *MetadataCollector) Collect(ctx context.Context) (*CloudMetadata, error) {
+	logger, start := logging.FuncLogger(mc.logger, "MetadataCollector.Collect")
+	defer logging.FuncExit(logger, start)
+
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	probes := []func(context.Context) (*CloudMetadata, error){mc.collectAWS, mc.collectGCP, mc.collectAzure}
+	results := make([]chan *CloudMetadata, len(probes))
+	for i, probe := range probes {
+		results[i] = make(chan *CloudMetadata, 1)
+		go func(probe func(context.Context) (*CloudMetadata, error), result chan<- *CloudMetadata) {
+			md, err := probe(ctx)
+			if err != nil {
+				logger.Debug("Metadata probe failed", zap.Error(err))
+			}
+			result <- md
+		}(probe, results[i])
+	}
+
+	for _, result := range results {
+		if md := <-result; md != nil {
+			return md, nil
+		}
+	}
+	return nil, fmt.Errorf("no cloud instance metadata service found")
+}
+
+// collectAWS reads the EC2 instance identity document using an IMDSv2 session token
+func (mc *MetadataCollector) collectAWS(ctx context.Context) (*CloudMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, mc.awsURL+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := mc.fetch(req)
+	if err != nil {
+		return nil, fmt.Errorf("aws: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, mc.awsURL+"/latest/dynamic/instance-identity/document", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", string(token))
+	data, err := mc.fetch(req)
+	if err != nil {
+		return nil, fmt.Errorf("aws: %w", err)
+	}
+
+	var document struct {
+		InstanceID       string `json:"instanceId"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		InstanceType     string `json:"instanceType"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("aws: invalid instance identity document: %w", err)
+	}
+	return &CloudMetadata{
+		Provider:     "aws",
+		InstanceID:   document.InstanceID,
+		Region:       document.Region,
+		Zone:         document.AvailabilityZone,
+		InstanceType: document.InstanceType,
+	}, nil
+}
+
+// collectGCP reads the Compute Engine instance metadata
+func (mc *MetadataCollector) collectGCP(ctx context.Context) (*CloudMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mc.gcpURL+"/computeMetadata/v1/instance/?recursive=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	data, err := mc.fetch(req)
+	if err != nil {
+		return nil, fmt.Errorf("gcp: %w", err)
+	}
+
+	var instance struct {
+		ID          json.Number `json:"id"`
+		Zone        string      `json:"zone"`        // projects/<number>/zones/<zone>
+		MachineType string      `json:"machineType"` // projects/<number>/machineTypes/<type>
+	}
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return nil, fmt.Errorf("gcp: invalid instance metadata: %w", err)
+	}
+
+	zone := lastPathSegment(instance.Zone)
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return &CloudMetadata{
+		Provider:     "gcp",
+		InstanceID:   instance.ID.String(),
+		Region:       region,
+		Zone:         zone,
+		InstanceType: lastPathSegment(instance.MachineType),
+	}, nil
+}
+
+// collectAzure reads the Azure Instance Metadata Service compute attributes
+func (mc *MetadataCollector) collectAzure(ctx context.Context) (*CloudMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mc.azureURL+"/metadata/instance/compute?api-version=2021-02-01", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	data, err := mc.fetch(req)
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+
+	var compute struct {
+		VMID     string `json:"vmId"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VMSize   string `json:"vmSize"`
+	}
+	if err := json.Unmarshal(data, &compute); err != nil {
+		return nil, fmt.Errorf("azure: invalid instance metadata: %w", err)
+	}
+	return &CloudMetadata{
+		Provider:     "azure",
+		InstanceID:   compute.VMID,
+		Region:       compute.Location,
+		Zone:         compute.Zone,
+		InstanceType: compute.VMSize,
+	}, nil
+}
+
+// fetch sends req and returns the response body, failing on non 2xx status codes
+func (mc *MetadataCollector) fetch(req *http.Request) ([]byte, error) {
+	resp, err := mc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	return data, nil
+}
+
+// lastPathSegment returns the part of path following its last slash
+func lastPathSegment(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// EnableCloudMetadata detects the cloud instance the minion runs on and adds its
+// provider, instance ID, region, zone and instance type to the tags advertised at registration.
+// Minions outside AWS, GCP and Azure keep running without these tags.
+func (m *Minion) EnableCloudMetadata(ctx context.Context) {
+	m.enableCloudMetadata(ctx, NewMetadataCollector(m.logger))
+}
+
+// enableCloudMetadata adds the tags of the metadata found by collector
+func (m *Minion) enableCloudMetadata(ctx context.Context, collector *MetadataCollector) {
+	md, err := collector.Collect(ctx)
+	if err != nil {
+		m.logger.Info("Cloud metadata unavailable, no cloud tags added", zap.Error(err))
+		return
+	}
+
+	m.logger.Info("Detected cloud instance",
+		zap.String("provider", md.Provider),
+		zap.String("instance_id", md.InstanceID),
+		zap.String("region", md.Region),
+		zap.String("zone", md.Zone),
+		zap.String("instance_type", md.InstanceType))
+	m.registrationMgr.(*registrationManager).addTags(md.Tags())
+}
@@ -0,0 +1,120 @@
+package minion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestCollector returns a collector whose AWS, GCP and Azure endpoints all point to server
+func newTestCollector(server *httptest.Server) *MetadataCollector {
+	collector := NewMetadataCollector(zap.NewNop())
+	collector.awsURL = server.URL
+	collector.gcpURL = server.URL
+	collector.azureURL = server.URL
+	return collector
+}
+
+func TestMetadataCollector(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    CloudMetadata
+	}{
+		{
+			name: "aws",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+					w.Write([]byte("token-1"))
+				case r.URL.Path == "/latest/dynamic/instance-identity/document" && r.Header.Get("X-aws-ec2-metadata-token") == "token-1":
+					w.Write([]byte(`{"instanceId":"i-0abc","region":"eu-west-1","availabilityZone":"eu-west-1b","instanceType":"t3.micro"}`))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+			want: CloudMetadata{Provider: "aws", InstanceID: "i-0abc", Region: "eu-west-1", Zone: "eu-west-1b", InstanceType: "t3.micro"},
+		},
+		{
+			name: "gcp",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/computeMetadata/v1/instance/" || r.Header.Get("Metadata-Flavor") != "Google" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(`{"id":4520031799277581759,"zone":"projects/123/zones/us-central1-a","machineType":"projects/123/machineTypes/e2-medium"}`))
+			},
+			want: CloudMetadata{Provider: "gcp", InstanceID: "4520031799277581759", Region: "us-central1", Zone: "us-central1-a", InstanceType: "e2-medium"},
+		},
+		{
+			name: "azure",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/metadata/instance/compute" || r.Header.Get("Metadata") != "true" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(`{"vmId":"02aab8a4-74ef","location":"westeurope","zone":"1","vmSize":"Standard_B2s"}`))
+			},
+			want: CloudMetadata{Provider: "azure", InstanceID: "02aab8a4-74ef", Region: "westeurope", Zone: "1", InstanceType: "Standard_B2s"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			md, err := newTestCollector(server).Collect(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *md != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *md)
+			}
+		})
+	}
+
+	t.Run("not_in_cloud", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		if _, err := newTestCollector(server).Collect(context.Background()); err == nil {
+			t.Error("Expected an error outside of a cloud instance")
+		}
+	})
+}
+
+func TestEnableCloudMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/instance/compute" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"vmId":"vm-1","location":"westeurope","vmSize":"Standard_B2s"}`))
+	}))
+	defer server.Close()
+
+	atom := zap.NewAtomicLevel()
+	minion := NewMinion("test-minion", &mockMinionServiceClient{}, time.Hour, time.Hour, time.Hour, 15*time.Second, 30*time.Second, zap.NewNop(), atom)
+	minion.enableCloudMetadata(context.Background(), newTestCollector(server))
+	// Static tags are advertised along, replacing the cloud tags with the same key
+	minion.AddTags(map[string]string{"role": "web", "region": "eu-west"})
+
+	hostInfo, err := minion.registrationMgr.(*registrationManager).createHostInfo()
+	if err != nil {
+		t.Fatalf("Failed to create host info: %v", err)
+	}
+	want := map[string]string{"cloud": "azure", "instance-id": "vm-1", "region": "eu-west", "instance-type": "Standard_B2s", "role": "web"}
+	if len(hostInfo.Tags) != len(want) {
+		t.Fatalf("Expected tags %v, got %v", want, hostInfo.Tags)
+	}
+	for key, value := range want {
+		if hostInfo.Tags[key] != value {
+			t.Errorf("Expected tag %s=%s, got %v", key, value, hostInfo.Tags)
+		}
+	}
+}
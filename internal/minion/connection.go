@@ -98,7 +98,7 @@ func (cm *connectionManager) Connect(ctx context.Context) error {
 	logger.Info("Successfully obtained command stream",
 		zap.String("minion_id", cm.id),
 		zap.String("stream_ptr", fmt.Sprintf("%p", stream)))
-	cm.reconnectMgr.ResetDelay() // Reset delay on successful connection
+	cm.reconnectMgr.MarkConnected() // Reset delay once the connection proves stable
 	return nil
 }
 
@@ -219,7 +219,7 @@ func (cm *connectionManager) HandleReconnection(ctx context.Context) error {
 	logger.Info("Successfully reconnected to command stream",
 		zap.String("minion_id", cm.id),
 		zap.String("new_stream_ptr", fmt.Sprintf("%p", stream)))
-	cm.reconnectMgr.ResetDelay() // Reset delay on successful reconnection
+	cm.reconnectMgr.MarkConnected() // Reset delay once the reconnection proves stable
 	return nil
 }
 
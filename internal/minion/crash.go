@@ -0,0 +1,238 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/version"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// crashRestartDelay is the time a main loop of the minion waits before running again after a panic
+	crashRestartDelay = time.Second
+
+	// maxPendingCrashes is the number of crash reports kept until sent to Nexus, the oldest being dropped
+	maxPendingCrashes = 20
+
+	// maxCrashStackBytes is the size over which the stack trace of a crash report is truncated
+	maxCrashStackBytes = 64 * 1024
+
+	// crashFilePattern matches the crash report files written in the crash directory
+	crashFilePattern = "crash-*.json"
+)
+
+// pendingCrash is a crash report not sent to Nexus yet
+type pendingCrash struct {
+	report *pb.CrashReport
+	file   string // file the report is written to, empty when kept in memory only
+}
+
+// crashReporter records the panics recovered by the minion until they are sent to Nexus at its next
+// registration. Reports are written to a directory when one is set, surviving a restart of the minion.
+type crashReporter struct {
+	mu      sync.Mutex
+	dir     string // empty: in memory only
+	pending []pendingCrash
+	logger  *zap.Logger
+}
+
+// newCrashReporter creates a crash reporter keeping its reports in memory
+func newCrashReporter(logger *zap.Logger) *crashReporter {
+	return &crashReporter{logger: logger}
+}
+
+// setDir writes the crash reports to dir from now on, loading the reports left there by a previous run
+func (c *crashReporter) setDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create crash directory: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, crashFilePattern))
+	if err != nil {
+		return fmt.Errorf("failed to list crash reports: %w", err)
+	}
+	sort.Strings(files)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read crash report: %w", err)
+		}
+		report := &pb.CrashReport{}
+		if err := protojson.Unmarshal(data, report); err != nil {
+			// A report truncated by the minion being killed while writing it is useless
+			c.logger.Warn("Removing unreadable crash report", zap.String("file", file), zap.Error(err))
+			os.Remove(file)
+			continue
+		}
+		c.addLocked(pendingCrash{report: report, file: file})
+	}
+	if len(c.pending) > 0 {
+		c.logger.Info("Crash reports of a previous run loaded, sent at the next registration",
+			zap.String("dir", dir), zap.Int("count", len(c.pending)))
+	}
+	return nil
+}
+
+// protect runs fn, recording a crash report for component when it panics. It returns whether fn panicked.
+func (c *crashReporter) protect(component string, fn func()) (panicked bool) {
+	defer func() {
+		if value := recover(); value != nil {
+			c.record(component, value, debug.Stack())
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// record records the crash report of a panic of component with value and stack
+func (c *crashReporter) record(component string, value any, stack []byte) *pb.CrashReport {
+	if len(stack) > maxCrashStackBytes {
+		stack = append(stack[:maxCrashStackBytes:maxCrashStackBytes], "\n... truncated"...)
+	}
+	now := time.Now()
+	report := &pb.CrashReport{
+		Timestamp: now.Unix(),
+		Component: component,
+		Message:   fmt.Sprint(value),
+		Stack:     string(stack),
+		Version:   version.Short(),
+	}
+	c.logger.Error("Recovered from panic, crash report sent to Nexus at the next registration",
+		zap.String("component", component),
+		zap.String("panic", report.Message),
+		zap.String("stack", report.Stack))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	crash := pendingCrash{report: report}
+	if c.dir != "" {
+		crash.file = filepath.Join(c.dir, fmt.Sprintf("crash-%d.json", now.UnixNano()))
+		data, err := protojson.Marshal(report)
+		if err == nil {
+			err = os.WriteFile(crash.file, data, 0600)
+		}
+		if err != nil {
+			c.logger.Warn("Failed to write crash report, keeping it in memory only", zap.Error(err))
+			crash.file = ""
+		}
+	}
+	c.addLocked(crash)
+	return report
+}
+
+// addLocked adds a pending crash, dropping the oldest over maxPendingCrashes, c.mu must be held
+func (c *crashReporter) addLocked(crash pendingCrash) {
+	c.pending = append(c.pending, crash)
+	for len(c.pending) > maxPendingCrashes {
+		if c.pending[0].file != "" {
+			os.Remove(c.pending[0].file)
+		}
+		c.pending = c.pending[1:]
+	}
+}
+
+// reports returns the crash reports not sent to Nexus yet, oldest first
+func (c *crashReporter) reports() []*pb.CrashReport {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reports []*pb.CrashReport
+	for _, crash := range c.pending {
+		reports = append(reports, crash.report)
+	}
+	return reports
+}
+
+// delivered forgets the crash reports Nexus received, deleting their files
+func (c *crashReporter) delivered(reports []*pb.CrashReport) {
+	if c == nil || len(reports) == 0 {
+		return
+	}
+	sent := make(map[*pb.CrashReport]bool, len(reports))
+	for _, report := range reports {
+		sent[report] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := c.pending[:0]
+	for _, crash := range c.pending {
+		if !sent[crash.report] {
+			kept = append(kept, crash)
+			continue
+		}
+		if crash.file != "" {
+			if err := os.Remove(crash.file); err != nil && !os.IsNotExist(err) {
+				c.logger.Warn("Failed to remove delivered crash report", zap.String("file", crash.file), zap.Error(err))
+			}
+		}
+	}
+	c.pending = kept
+}
+
+// recoverCommand turns a panic of the execution of cmd into a failed result, recording its crash report.
+// It must be deferred by the function executing the command, whose results it sets.
+func (c *crashReporter) recoverCommand(cmd *pb.Command, minionID string, result **pb.CommandResult, err *error) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	report := c.record("command "+cmd.Id+": "+commandName(cmd.Payload), value, debug.Stack())
+	*err = fmt.Errorf("command panicked: %s", report.Message)
+	*result = &pb.CommandResult{
+		CommandId: cmd.Id,
+		MinionId:  minionID,
+		Timestamp: time.Now().Unix(),
+		ExitCode:  1,
+		Stderr:    (*err).Error(),
+	}
+}
+
+// commandName returns the name of the command of a payload, without its arguments
+func commandName(payload string) string {
+	if fields := strings.Fields(payload); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// supervise runs loop until it returns, running it again after crashRestartDelay when it panics unless
+// the minion stopped meanwhile. It is started for each main loop of the minion, counted in m.wg.
+func (m *Minion) supervise(ctx context.Context, component string, loop func(ctx context.Context)) {
+	defer m.wg.Done()
+
+	for m.crashes.protect(component, func() { loop(ctx) }) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case <-time.After(crashRestartDelay):
+			m.logger.Warn("Restarting after panic", zap.String("component", component))
+		}
+	}
+}
+
+// PersistCrashReports writes the crash reports to dir until they are sent to Nexus, so that they survive a
+// restart of the minion. The reports left there by a previous run are sent at the next registration.
+func (m *Minion) PersistCrashReports(dir string) error {
+	return m.crashes.setDir(dir)
+}
@@ -0,0 +1,145 @@
+package minion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// panicCommand is a command panicking when executed
+type panicCommand struct {
+	*command.BaseCommand
+}
+
+func (c *panicCommand) Execute(ctx *command.ExecutionContext, payload string) (*pb.CommandResult, error) {
+	panic("kaboom")
+}
+
+func TestCrashReporterProtect(t *testing.T) {
+	crashes := newCrashReporter(zap.NewNop())
+
+	if crashes.protect("scheduler", func() {}) {
+		t.Error("Expected no panic reported")
+	}
+	if !crashes.protect("scheduler", func() { panic("boom") }) {
+		t.Fatal("Expected the panic to be recovered")
+	}
+
+	reports := crashes.reports()
+	if len(reports) != 1 {
+		t.Fatalf("Expected one crash report, got %d", len(reports))
+	}
+	if reports[0].Component != "scheduler" || reports[0].Message != "boom" || reports[0].Timestamp == 0 || reports[0].Version == "" {
+		t.Errorf("Unexpected crash report: %v", reports[0])
+	}
+	if !strings.Contains(reports[0].Stack, "TestCrashReporterProtect") {
+		t.Errorf("Expected the stack trace of the panic, got %s", reports[0].Stack)
+	}
+
+	crashes.delivered(reports)
+	if len(crashes.reports()) != 0 {
+		t.Error("Expected delivered reports to be forgotten")
+	}
+
+	for i := 0; i < maxPendingCrashes+5; i++ {
+		crashes.protect("watcher", func() { panic(i) })
+	}
+	if reports := crashes.reports(); len(reports) != maxPendingCrashes || reports[0].Message != "5" {
+		t.Errorf("Expected the oldest reports to be dropped, got %d starting with %v", len(reports), reports[0])
+	}
+}
+
+func TestCrashReporterDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crashes")
+	crashes := newCrashReporter(zap.NewNop())
+	if err := crashes.setDir(dir); err != nil {
+		t.Fatalf("setDir failed: %v", err)
+	}
+	crashes.protect("command loop", func() { panic("first") })
+	crashes.protect("command loop", func() { panic("second") })
+	if err := os.WriteFile(filepath.Join(dir, "crash-0.json"), []byte("{trunc"), 0600); err != nil {
+		t.Fatalf("Failed to write truncated report: %v", err)
+	}
+
+	// Reports survive a restart, truncated ones being removed
+	restarted := newCrashReporter(zap.NewNop())
+	if err := restarted.setDir(dir); err != nil {
+		t.Fatalf("setDir failed: %v", err)
+	}
+	reports := restarted.reports()
+	if len(reports) != 2 || reports[0].Message != "first" || reports[1].Message != "second" {
+		t.Fatalf("Unexpected reloaded reports: %v", reports)
+	}
+
+	restarted.delivered(reports[:1])
+	files, _ := filepath.Glob(filepath.Join(dir, crashFilePattern))
+	if len(files) != 1 {
+		t.Errorf("Expected only the report not delivered to be kept, got %v", files)
+	}
+}
+
+func TestExecuteRecoversPanic(t *testing.T) {
+	m := NewMinion("minion-1", nil, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	m.registry.Register(&panicCommand{command.NewBaseCommand("panic:now", "test", "Panic", "panic:now")})
+
+	result, err := m.commandProcessor.Execute(context.Background(), &pb.Command{Id: "cmd-1", Payload: "panic:now secret"})
+	if err == nil || result == nil || result.ExitCode != 1 || !strings.Contains(result.Stderr, "command panicked: kaboom") {
+		t.Fatalf("Expected a failed result, got %v %v", result, err)
+	}
+
+	reports := m.crashes.reports()
+	if len(reports) != 1 || reports[0].Component != "command cmd-1: panic:now" {
+		t.Errorf("Expected a crash report without the arguments of the command, got %v", reports)
+	}
+}
+
+func TestSuperviseRestartsLoop(t *testing.T) {
+	m := NewMinion("minion-1", nil, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+
+	runs := 0
+	m.wg.Add(1)
+	m.supervise(context.Background(), "test loop", func(ctx context.Context) {
+		runs++
+		if runs == 1 {
+			panic("first run")
+		}
+	})
+
+	if runs != 2 {
+		t.Errorf("Expected the loop to run again after its panic, got %d runs", runs)
+	}
+	if reports := m.crashes.reports(); len(reports) != 1 || reports[0].Component != "test loop" {
+		t.Errorf("Unexpected crash reports: %v", reports)
+	}
+}
+
+func TestRegistrationSendsCrashes(t *testing.T) {
+	var sent *pb.HostInfo
+	service := &mockMinionServiceClient{
+		registerFunc: func(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+			sent = in
+			return &pb.RegisterResponse{Success: true, AssignedId: in.Id}, nil
+		},
+	}
+	m := NewMinion("minion-1", service, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	m.crashes.protect("watchdog", func() { panic("boom") })
+
+	if _, err := m.registrationMgr.Register(context.Background(), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if sent == nil || len(sent.Crashes) != 1 || sent.Crashes[0].Message != "boom" {
+		t.Fatalf("Expected the crash report sent at registration, got %v", sent)
+	}
+	if len(m.crashes.reports()) != 0 {
+		t.Error("Expected the crash report forgotten once registered")
+	}
+}
@@ -0,0 +1,107 @@
+package minion
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/arhuman/minexus/internal/command"
+)
+
+// DiskThresholds are the free disk space and inodes under which the minion refuses disk-heavy
+// commands, zero disabling a threshold
+type DiskThresholds struct {
+	MinFreeMB            int // free space of the filesystem, in MB available to the minion
+	MinFreeInodesPercent int // free inodes of the filesystem, in percent of its inodes
+}
+
+// enabled reports whether at least one threshold is set
+func (t DiskThresholds) enabled() bool {
+	return t.MinFreeMB > 0 || t.MinFreeInodesPercent > 0
+}
+
+// diskUsage is the free space and inodes of a filesystem
+type diskUsage struct {
+	free       uint64 // bytes available to the minion
+	inodes     uint64 // inodes of the filesystem, 0 for filesystems without inodes
+	freeInodes uint64
+}
+
+// diskGuard refuses the disk-heavy commands writing to a filesystem short of space or inodes,
+// before they fill it and fail halfway
+type diskGuard struct {
+	thresholds DiskThresholds
+	stat       func(path string) (diskUsage, error)
+	logger     *zap.Logger
+}
+
+// newDiskGuard creates a guard enforcing thresholds
+func newDiskGuard(thresholds DiskThresholds, logger *zap.Logger) *diskGuard {
+	return &diskGuard{
+		thresholds: thresholds,
+		stat:       statDisk,
+		logger:     logger,
+	}
+}
+
+// check returns the error of the first path whose filesystem is below the thresholds, the system
+// disk being checked when paths is empty. Paths not created yet are checked on their closest
+// existing parent; a filesystem that can't be read is let through, the command failing on its own.
+func (g *diskGuard) check(paths []string) *command.DiskFullError {
+	if len(paths) == 0 {
+		paths = systemDiskPaths()
+	}
+	for _, path := range paths {
+		usage, err := g.statClosest(path)
+		if err != nil {
+			g.logger.Debug("Failed to read the free disk space", zap.String("path", path), zap.Error(err))
+			continue
+		}
+
+		freeMB := usage.free / (1024 * 1024)
+		var freeInodesPercent float64
+		if usage.inodes > 0 {
+			freeInodesPercent = float64(usage.freeInodes) * 100 / float64(usage.inodes)
+		}
+		lowSpace := g.thresholds.MinFreeMB > 0 && freeMB < uint64(g.thresholds.MinFreeMB)
+		lowInodes := g.thresholds.MinFreeInodesPercent > 0 && usage.inodes > 0 && freeInodesPercent < float64(g.thresholds.MinFreeInodesPercent)
+		if lowSpace || lowInodes {
+			return &command.DiskFullError{
+				Code:                 command.ErrorCodeDiskFull,
+				Path:                 path,
+				FreeMB:               freeMB,
+				MinFreeMB:            g.thresholds.MinFreeMB,
+				FreeInodesPercent:    freeInodesPercent,
+				MinFreeInodesPercent: g.thresholds.MinFreeInodesPercent,
+			}
+		}
+	}
+	return nil
+}
+
+// statClosest returns the usage of the filesystem of path, or of its closest existing parent
+func (g *diskGuard) statClosest(path string) (diskUsage, error) {
+	path = filepath.Clean(path)
+	for {
+		usage, err := g.stat(path)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return usage, err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return usage, err
+		}
+		path = parent
+	}
+}
+
+// EnableDiskGuard makes the minion refuse disk-heavy commands when the filesystem they write to is
+// below thresholds
+func (m *Minion) EnableDiskGuard(thresholds DiskThresholds) {
+	if !thresholds.enabled() {
+		return
+	}
+	m.commandProcessor.(*commandProcessor).disk = newDiskGuard(thresholds, m.logger)
+}
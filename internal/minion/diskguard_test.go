@@ -0,0 +1,83 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestDiskGuard(t *testing.T) {
+	full := filepath.FromSlash("/full")
+	guard := newDiskGuard(DiskThresholds{MinFreeMB: 100, MinFreeInodesPercent: 5}, zap.NewNop())
+	var statted []string
+	guard.stat = func(path string) (diskUsage, error) {
+		statted = append(statted, path)
+		switch path {
+		case full:
+			return diskUsage{free: 50 * 1024 * 1024, inodes: 1000, freeInodes: 900}, nil
+		case filepath.FromSlash("/inodes"):
+			return diskUsage{free: 1 << 40, inodes: 1000, freeInodes: 10}, nil
+		case filepath.FromSlash("/ok"), filepath.FromSlash("/fat"):
+			return diskUsage{free: 1 << 40}, nil
+		}
+		return diskUsage{}, fs.ErrNotExist
+	}
+
+	diskFull := guard.check([]string{"/ok", "/full/releases/v2"})
+	if diskFull == nil || diskFull.Code != command.ErrorCodeDiskFull || diskFull.FreeMB != 50 || diskFull.Path != "/full/releases/v2" {
+		t.Fatalf("Expected the missing path checked on its closest parent, got %+v", diskFull)
+	}
+	if statted[len(statted)-1] != full {
+		t.Errorf("Expected the parents statted up to %s, got %v", full, statted)
+	}
+	if diskFull := guard.check([]string{"/inodes"}); diskFull == nil || diskFull.FreeInodesPercent != 1 {
+		t.Errorf("Expected the command refused for lack of inodes, got %+v", diskFull)
+	}
+	// Filesystems without inodes are only checked for space
+	if diskFull := guard.check([]string{"/fat"}); diskFull != nil {
+		t.Errorf("Expected enough space on /fat, got %v", diskFull)
+	}
+	guard.thresholds.MinFreeMB = 0
+	if diskFull := guard.check([]string{"/full"}); diskFull != nil {
+		t.Errorf("Expected the space unchecked without threshold, got %v", diskFull)
+	}
+
+	guard.stat = func(string) (diskUsage, error) { return diskUsage{}, errors.New("permission denied") }
+	if diskFull := guard.check(nil); diskFull != nil {
+		t.Errorf("Expected unreadable filesystems let through, got %v", diskFull)
+	}
+}
+
+func TestDiskGuardRejectsCommands(t *testing.T) {
+	minion := NewMinion("test-minion", &mockMinionServiceClient{}, time.Hour, time.Hour, time.Hour, 15*time.Second, 30*time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	minion.EnableDiskGuard(DiskThresholds{MinFreeMB: 100})
+	processor := minion.commandProcessor.(*commandProcessor)
+	processor.disk.stat = func(string) (diskUsage, error) { return diskUsage{free: 10 * 1024 * 1024}, nil }
+
+	result, err := processor.Execute(context.Background(), &pb.Command{Id: "cmd-1", Payload: "file:extract /tmp/release.tar.gz /srv/app"})
+	if err == nil || result.ExitCode != 1 || result.ContentType != command.ContentTypeDiskFull {
+		t.Fatalf("Expected the extraction refused, got %v %v", result, err)
+	}
+	var diskFull command.DiskFullError
+	if err := json.Unmarshal([]byte(result.Structured), &diskFull); err != nil {
+		t.Fatalf("Invalid structured payload: %v", err)
+	}
+	if diskFull.Code != command.ErrorCodeDiskFull || diskFull.Path != "/srv/app" || diskFull.FreeMB != 10 || diskFull.MinFreeMB != 100 {
+		t.Errorf("Unexpected disk full error: %+v", diskFull)
+	}
+
+	// Commands that are not disk-heavy run whatever the free space
+	result, err = processor.Execute(context.Background(), &pb.Command{Id: "cmd-2", Payload: "system:os"})
+	if err != nil || result.ExitCode != 0 {
+		t.Errorf("Expected system:os to run, got %v %v", result, err)
+	}
+}
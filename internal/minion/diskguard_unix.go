@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package minion
+
+import (
+	"os"
+	"syscall"
+)
+
+// statDisk returns the free space and inodes of the filesystem of path
+func statDisk(path string) (diskUsage, error) {
+	var stats syscall.Statfs_t
+	if err := syscall.Statfs(path, &stats); err != nil {
+		return diskUsage{}, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return diskUsage{
+		free:       uint64(stats.Bavail) * uint64(stats.Bsize),
+		inodes:     uint64(stats.Files),
+		freeInodes: uint64(stats.Ffree),
+	}, nil
+}
+
+// systemDiskPaths returns the paths checked for the disk-heavy commands that don't say where they
+// write: the root filesystem, where packages are installed, and the temporary directory
+func systemDiskPaths() []string {
+	return []string{"/", os.TempDir()}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+// +build windows
+
+package minion
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// statDisk returns the free space of the volume of path, NTFS having no inode limit
+func statDisk(path string) (diskUsage, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return diskUsage{}, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &free, nil, nil); err != nil {
+		return diskUsage{}, &os.PathError{Op: "GetDiskFreeSpaceEx", Path: path, Err: err}
+	}
+	return diskUsage{free: free}, nil
+}
+
+// systemDiskPaths returns the paths checked for the disk-heavy commands that don't say where they
+// write: the system drive, where updates are installed, and the temporary directory
+func systemDiskPaths() []string {
+	drive := os.Getenv("SystemDrive")
+	if drive == "" {
+		drive = "C:"
+	}
+	return []string{drive + `\`, os.TempDir()}
+}
@@ -0,0 +1,38 @@
+package minion
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// minionUser returns the name of the user the minion runs as, its uid when it has no name
+var minionUser = sync.OnceValue(func() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(os.Geteuid())
+})
+
+// captureExecution completes the execution info of a result: commands that didn't report theirs,
+// such as the ones run within the minion, ran in its working directory, as its user and with its PATH
+func captureExecution(result *pb.CommandResult, elapsed time.Duration) {
+	if result.Execution == nil {
+		result.Execution = &pb.ExecutionInfo{}
+	}
+	info := result.Execution
+	if info.WorkingDir == "" {
+		info.WorkingDir, _ = os.Getwd()
+	}
+	if info.User == "" {
+		info.User = minionUser()
+	}
+	if info.Path == "" {
+		info.Path = os.Getenv("PATH")
+	}
+	info.DurationMs = elapsed.Milliseconds()
+}
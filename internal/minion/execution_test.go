@@ -0,0 +1,42 @@
+package minion
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestCaptureExecution(t *testing.T) {
+	wd, _ := os.Getwd()
+
+	// Commands run within the minion get its environment
+	result := &pb.CommandResult{CommandId: "cmd-1"}
+	captureExecution(result, 1500*time.Millisecond)
+	info := result.Execution
+	if info == nil || info.WorkingDir != wd || info.User != minionUser() || info.Path != os.Getenv("PATH") || info.DurationMs != 1500 {
+		t.Fatalf("Unexpected execution info: %v", info)
+	}
+
+	// What commands reported is kept
+	result = &pb.CommandResult{Execution: &pb.ExecutionInfo{WorkingDir: "/srv", User: "deploy", Path: "/opt/bin", MaxRssKb: 512}}
+	captureExecution(result, time.Second)
+	info = result.Execution
+	if info.WorkingDir != "/srv" || info.User != "deploy" || info.Path != "/opt/bin" || info.MaxRssKb != 512 || info.DurationMs != 1000 {
+		t.Errorf("Expected the reported execution info kept, got %v", info)
+	}
+
+	// The processor captures it for every result, including unknown commands
+	processor := NewCommandProcessor("minion-1", command.SetupCommands(5*time.Second), nil, nil, time.Second, zap.NewNop())
+	for _, payload := range []string{"system:os", "nosuch:command"} {
+		result, _ := processor.Execute(context.Background(), &pb.Command{Id: "cmd-2", Payload: payload})
+		if result == nil || result.Execution == nil || result.Execution.User == "" {
+			t.Errorf("Expected the execution info of %s captured, got %v", payload, result)
+		}
+	}
+}
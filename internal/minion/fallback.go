@@ -0,0 +1,241 @@
+package minion
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/arhuman/minexus/internal/logging"
+)
+
+const (
+	// stableStreamDuration is how long a stream must last before its end no longer counts as
+	// a failure of its transport
+	stableStreamDuration = time.Minute
+
+	// primaryRetryInterval is how long the minion stays on the fallback transport before
+	// trying the gRPC stream again
+	primaryRetryInterval = 5 * time.Minute
+)
+
+// fallbackConnectionManager implements the ConnectionManager interface over the gRPC command
+// stream, switching to a fallback transport, HTTP long polling, once the stream failed
+// repeatedly. It switches back to gRPC periodically, staying there if the stream then works.
+type fallbackConnectionManager struct {
+	primary         *connectionManager
+	fallback        *connectionManager
+	primaryService  pb.MinionServiceClient
+	fallbackService pb.MinionServiceClient
+	threshold       int // consecutive failures switching transport
+	retryInterval   time.Duration
+	logger          *zap.Logger
+
+	mu            sync.Mutex
+	failures      int
+	usingFallback bool
+	connectedAt   time.Time
+	cancelStream  context.CancelFunc // ends the fallback stream to retry gRPC
+}
+
+// newFallbackConnectionManager creates a connection manager switching from primary to fallback
+// after threshold consecutive failures
+func newFallbackConnectionManager(primary *connectionManager, primaryService pb.MinionServiceClient, fallback *connectionManager, fallbackService pb.MinionServiceClient, threshold int, logger *zap.Logger) *fallbackConnectionManager {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &fallbackConnectionManager{
+		primary:         primary,
+		fallback:        fallback,
+		primaryService:  primaryService,
+		fallbackService: fallbackService,
+		threshold:       threshold,
+		retryInterval:   primaryRetryInterval,
+		logger:          logger,
+	}
+}
+
+// active returns the connection manager of the transport in use
+func (fm *fallbackConnectionManager) active() *connectionManager {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.usingFallback {
+		return fm.fallback
+	}
+	return fm.primary
+}
+
+// streamContext returns the context of a new stream. Fallback streams end after the retry
+// interval so that the next connection tries gRPC again.
+func (fm *fallbackConnectionManager) streamContext(ctx context.Context) context.Context {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if !fm.usingFallback {
+		return ctx
+	}
+	ctx, fm.cancelStream = context.WithTimeout(ctx, fm.retryInterval)
+	return ctx
+}
+
+// Connect establishes a connection with the transport in use
+func (fm *fallbackConnectionManager) Connect(ctx context.Context) error {
+	cm := fm.active()
+	err := cm.Connect(fm.streamContext(ctx))
+	fm.recordConnection(err)
+	return err
+}
+
+// HandleReconnection reconnects with the transport in use
+func (fm *fallbackConnectionManager) HandleReconnection(ctx context.Context) error {
+	cm := fm.active()
+	err := cm.HandleReconnection(fm.streamContext(ctx))
+	fm.recordConnection(err)
+	return err
+}
+
+// Disconnect closes the connection of the transport in use, counting short-lived streams as
+// failures of their transport
+func (fm *fallbackConnectionManager) Disconnect() error {
+	err := fm.active().Disconnect()
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.cancelStream != nil {
+		fm.cancelStream()
+		fm.cancelStream = nil
+	}
+	connectedAt := fm.connectedAt
+	fm.connectedAt = time.Time{}
+	switch {
+	case connectedAt.IsZero():
+		fm.recordFailureLocked()
+	case fm.usingFallback && time.Since(connectedAt) >= fm.retryInterval:
+		// A single gRPC failure brings the minion back to the fallback transport
+		fm.logger.Info("Retrying gRPC command stream")
+		fm.usingFallback = false
+		fm.failures = fm.threshold - 1
+	case time.Since(connectedAt) >= stableStreamDuration:
+		fm.failures = 0
+	default:
+		fm.recordFailureLocked()
+	}
+	return err
+}
+
+// IsConnected reports whether the transport in use is connected
+func (fm *fallbackConnectionManager) IsConnected() bool {
+	return fm.active().IsConnected()
+}
+
+// Stream returns the command stream of the transport in use
+func (fm *fallbackConnectionManager) Stream() (pb.MinionService_StreamCommandsClient, error) {
+	return fm.active().Stream()
+}
+
+// UpdateMinionID updates the minion ID used by both transports
+func (fm *fallbackConnectionManager) UpdateMinionID(newID string) {
+	fm.primary.UpdateMinionID(newID)
+	fm.fallback.UpdateMinionID(newID)
+}
+
+// UsingFallback reports whether the fallback transport is in use
+func (fm *fallbackConnectionManager) UsingFallback() bool {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	return fm.usingFallback
+}
+
+// recordConnection records the outcome of a connection attempt
+func (fm *fallbackConnectionManager) recordConnection(err error) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if err != nil {
+		if fm.cancelStream != nil {
+			fm.cancelStream()
+			fm.cancelStream = nil
+		}
+		fm.recordFailureLocked()
+		return
+	}
+	fm.connectedAt = time.Now()
+}
+
+// recordFailure records a failure of the transport in use
+func (fm *fallbackConnectionManager) recordFailure() {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.recordFailureLocked()
+}
+
+// recordFailureLocked records a failure of the transport in use, switching to the other one
+// after threshold consecutive failures. fm.mu must be held.
+func (fm *fallbackConnectionManager) recordFailureLocked() {
+	fm.failures++
+	if fm.failures < fm.threshold {
+		return
+	}
+
+	fm.failures = 0
+	fm.usingFallback = !fm.usingFallback
+	if fm.usingFallback {
+		fm.logger.Warn("gRPC command stream failed repeatedly, falling back to HTTP long polling",
+			zap.Int("failures", fm.threshold))
+	} else {
+		fm.logger.Warn("HTTP long polling failed repeatedly, switching back to gRPC",
+			zap.Int("failures", fm.threshold))
+	}
+}
+
+// Service returns a client registering through the transport in use
+func (fm *fallbackConnectionManager) Service() pb.MinionServiceClient {
+	return &fallbackService{manager: fm}
+}
+
+// fallbackService implements pb.MinionServiceClient over the transport in use of a
+// fallbackConnectionManager, so that registrations follow the command stream
+type fallbackService struct {
+	manager *fallbackConnectionManager
+}
+
+// service returns the client of the transport in use
+func (fs *fallbackService) service() pb.MinionServiceClient {
+	if fs.manager.UsingFallback() {
+		return fs.manager.fallbackService
+	}
+	return fs.manager.primaryService
+}
+
+// Register registers through the transport in use, counting failures against it
+func (fs *fallbackService) Register(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+	logger, start := logging.FuncLogger(fs.manager.logger, "fallbackService.Register")
+	defer logging.FuncExit(logger, start)
+
+	resp, err := fs.service().Register(ctx, in, opts...)
+	if err != nil {
+		fs.manager.recordFailure()
+	}
+	return resp, err
+}
+
+// StreamCommands opens a command stream with the transport in use
+func (fs *fallbackService) StreamCommands(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[pb.CommandStreamMessage, pb.CommandStreamMessage], error) {
+	return fs.service().StreamCommands(ctx, opts...)
+}
+
+// RelayStream opens a relay stream, relays only connect over gRPC
+func (fs *fallbackService) RelayStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[pb.RelayMessage, pb.RelayMessage], error) {
+	return fs.manager.primaryService.RelayStream(ctx, opts...)
+}
+
+// SpeedTest opens a speed test stream with the transport in use
+func (fs *fallbackService) SpeedTest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[pb.SpeedTestProbe, pb.SpeedTestProbe], error) {
+	return fs.service().SpeedTest(ctx, opts...)
+}
@@ -0,0 +1,99 @@
+package minion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPFallbackRegistration(t *testing.T) {
+	var primaryCalls, fallbackCalls int
+	primary := &mockMinionServiceClient{
+		registerFunc: func(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+			primaryCalls++
+			return nil, status.Error(codes.Unavailable, "stream blocked")
+		},
+	}
+	fallback := &mockMinionServiceClient{
+		registerFunc: func(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+			fallbackCalls++
+			return &pb.RegisterResponse{Success: true, AssignedId: in.Id}, nil
+		},
+	}
+
+	m := NewMinion("fallback-minion", primary, time.Minute, time.Millisecond, time.Millisecond, time.Second, 10*time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	m.EnableHTTPFallback(fallback, 2)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := m.registrationMgr.Register(ctx, &pb.HostInfo{Id: "fallback-minion"}); err == nil {
+			t.Fatalf("Registration %d should fail over gRPC", i+1)
+		}
+	}
+	resp, err := m.registrationMgr.Register(ctx, &pb.HostInfo{Id: "fallback-minion"})
+	if err != nil || !resp.Success {
+		t.Fatalf("Registration should succeed over the fallback transport, got %v, %v", resp, err)
+	}
+
+	if primaryCalls != 2 || fallbackCalls != 1 {
+		t.Errorf("Expected 2 gRPC and 1 fallback registrations, got %d and %d", primaryCalls, fallbackCalls)
+	}
+	if !m.connectionMgr.(*fallbackConnectionManager).UsingFallback() {
+		t.Error("Expected the fallback transport to be in use")
+	}
+}
+
+func TestHTTPFallbackStreamFailures(t *testing.T) {
+	logger := zap.NewNop()
+	reconnectMgr := NewReconnectionManager(time.Millisecond, time.Millisecond, logger)
+	primaryService := &mockMinionServiceClient{}
+	fallbackService := &mockMinionServiceClient{}
+	manager := newFallbackConnectionManager(
+		NewConnectionManager("minion", primaryService, reconnectMgr, logger), primaryService,
+		NewConnectionManager("minion", fallbackService, reconnectMgr, logger), fallbackService,
+		2, logger)
+	manager.retryInterval = 20 * time.Millisecond
+
+	// Streams ending right after they opened count as failures
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if manager.UsingFallback() {
+			t.Fatalf("Switched to the fallback transport after %d failures", i)
+		}
+		if err := manager.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		manager.Disconnect()
+	}
+	if !manager.UsingFallback() {
+		t.Fatal("Expected the fallback transport after 2 short-lived streams")
+	}
+
+	// Fallback streams end after the retry interval to try gRPC again
+	if err := manager.Connect(ctx); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	stream, _ := manager.Stream()
+	if _, ok := stream.(*mockStreamCommandsClient); !ok {
+		t.Fatalf("Unexpected stream %T", stream)
+	}
+	time.Sleep(manager.retryInterval)
+	manager.Disconnect()
+	if manager.UsingFallback() {
+		t.Fatal("Expected gRPC to be retried after the retry interval")
+	}
+
+	// A single failure of the retried gRPC stream brings the minion back to the fallback
+	manager.Connect(ctx)
+	manager.Disconnect()
+	if !manager.UsingFallback() {
+		t.Error("Expected the fallback transport after the retried gRPC stream failed")
+	}
+}
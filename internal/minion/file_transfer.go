@@ -0,0 +1,195 @@
+package minion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// fileChunkSize is the size of the chunks of the files pulled by Nexus, well below the gRPC
+// message size limits
+const fileChunkSize = 512 * 1024
+
+// fileSender sends the files pulled by Nexus in chunks, from the offset Nexus asks for, at the
+// pace of the transfer throttle of the minion
+type fileSender struct {
+	mu        sync.Mutex
+	transfers map[string]context.CancelFunc
+	throttle  *transferThrottle
+	logger    *zap.Logger
+}
+
+// newFileSender creates a sender without transfers
+func newFileSender(throttle *transferThrottle, logger *zap.Logger) *fileSender {
+	return &fileSender{
+		transfers: make(map[string]context.CancelFunc),
+		throttle:  throttle,
+		logger:    logger,
+	}
+}
+
+// handle processes a file transfer request received from Nexus. Chunks and failures are
+// reported through send.
+func (f *fileSender) handle(msg *pb.FileChunk, send func(*pb.FileChunk) error) {
+	if msg.Cancel {
+		f.mu.Lock()
+		cancel, exists := f.transfers[msg.TransferId]
+		f.mu.Unlock()
+		if exists {
+			cancel()
+		}
+		return
+	}
+	if !msg.Start {
+		return
+	}
+
+	f.mu.Lock()
+	if _, exists := f.transfers[msg.TransferId]; exists {
+		f.mu.Unlock()
+		return
+	}
+	end, err := f.throttle.Begin()
+	if err != nil {
+		f.mu.Unlock()
+		send(&pb.FileChunk{TransferId: msg.TransferId, Error: err.Error()})
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f.transfers[msg.TransferId] = cancel
+	f.mu.Unlock()
+
+	go func() {
+		defer func() {
+			f.mu.Lock()
+			delete(f.transfers, msg.TransferId)
+			f.mu.Unlock()
+			cancel()
+			end()
+		}()
+
+		if err := sendFile(ctx, msg, f.throttle, send); err != nil {
+			f.logger.Warn("File transfer stopped",
+				zap.String("transfer_id", msg.TransferId),
+				zap.String("path", msg.Path),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(err))
+			return
+		}
+		f.logger.Info("File sent to Nexus",
+			zap.String("transfer_id", msg.TransferId),
+			zap.String("path", msg.Path),
+			zap.Int64("offset", msg.Offset))
+	}()
+}
+
+// closeAll stops the transfers, which don't survive their stream
+func (f *fileSender) closeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, cancel := range f.transfers {
+		cancel()
+	}
+}
+
+// sendFile sends the file of a request from its offset up to the size the file has when the
+// transfer starts, paced by throttle, the last chunk carrying the checksum of the whole file. A
+// file that can't be read is reported to Nexus.
+func sendFile(ctx context.Context, req *pb.FileChunk, throttle *transferThrottle, send func(*pb.FileChunk) error) error {
+	file, size, hash, err := openFileFrom(req.Path, req.Offset)
+	if err != nil {
+		if sendErr := send(&pb.FileChunk{TransferId: req.TransferId, Error: err.Error()}); sendErr != nil {
+			return sendErr
+		}
+		return err
+	}
+	defer file.Close()
+
+	offset := req.Offset
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n := int64(fileChunkSize)
+		if size-offset < n {
+			n = size - offset
+		}
+		data := make([]byte, n)
+		read, err := io.ReadFull(file, data)
+		if err != nil {
+			err = fmt.Errorf("failed to read %s at offset %d: %w", req.Path, offset, err)
+			send(&pb.FileChunk{TransferId: req.TransferId, Error: err.Error()})
+			return err
+		}
+		hash.Write(data)
+		if err := throttle.WaitN(ctx, read); err != nil {
+			return err
+		}
+
+		chunk := &pb.FileChunk{
+			TransferId: req.TransferId,
+			Offset:     offset,
+			Data:       data,
+			Size:       size,
+		}
+		offset += int64(read)
+		if offset == size {
+			chunk.Eof = true
+			chunk.Sha256 = hex.EncodeToString(hash.Sum(nil))
+		}
+		if err := send(chunk); err != nil {
+			return err
+		}
+		if chunk.Eof {
+			return nil
+		}
+	}
+}
+
+// openFileFrom opens a regular file positioned at offset and returns its size and the checksum
+// of the bytes before offset
+func openFileFrom(path string, offset int64) (*os.File, int64, hash.Hash, error) {
+	if !filepath.IsAbs(path) {
+		return nil, 0, nil, fmt.Errorf("path %s must be absolute", path)
+	}
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, 0, nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if offset < 0 || offset > info.Size() {
+		file.Close()
+		return nil, 0, nil, fmt.Errorf("offset %d is outside %s of %d bytes", offset, path, info.Size())
+	}
+
+	hash := sha256.New()
+	if _, err := io.CopyN(hash, file, offset); err != nil {
+		file.Close()
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("%s shrank below offset %d", path, offset)
+		}
+		return nil, 0, nil, err
+	}
+	return file, info.Size(), hash, nil
+}
@@ -0,0 +1,57 @@
+package minion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestSendFile(t *testing.T) {
+	content := []byte(strings.Repeat("minexus", fileChunkSize/4))
+	path := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+
+	for _, offset := range []int64{0, 1000, int64(len(content))} {
+		var received []byte
+		var last *pb.FileChunk
+		err := sendFile(context.Background(), &pb.FileChunk{TransferId: "t1", Path: path, Offset: offset}, newTransferThrottle(0, 0), func(chunk *pb.FileChunk) error {
+			if chunk.Offset != offset+int64(len(received)) || len(chunk.Data) > fileChunkSize {
+				t.Errorf("Unexpected chunk at offset %d of %d bytes", chunk.Offset, len(chunk.Data))
+			}
+			received = append(received, chunk.Data...)
+			last = chunk
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error from offset %d: %v", offset, err)
+		}
+		if string(received) != string(content[offset:]) {
+			t.Errorf("Unexpected data from offset %d", offset)
+		}
+		if !last.Eof || last.Size != int64(len(content)) || last.Sha256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("Expected the last chunk to carry the checksum of the whole file, got eof %v size %d sha256 %s", last.Eof, last.Size, last.Sha256)
+		}
+	}
+}
+
+func TestSendFileErrors(t *testing.T) {
+	for _, path := range []string{"relative/file", t.TempDir(), filepath.Join(t.TempDir(), "missing")} {
+		var reported *pb.FileChunk
+		err := sendFile(context.Background(), &pb.FileChunk{TransferId: "t1", Path: path}, newTransferThrottle(0, 0), func(chunk *pb.FileChunk) error {
+			reported = chunk
+			return nil
+		})
+		if err == nil || reported == nil || reported.Error == "" {
+			t.Errorf("Expected %s to be reported as an error, got %v", path, reported)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package minion
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// computeFingerprint hashes the attributes identifying a host so Nexus can detect
+// a minion whose host was re-imaged or moved
+func computeFingerprint(hostname, ip, osVersion string, macs []string) string {
+	sorted := append([]string(nil), macs...)
+	sort.Strings(sorted)
+
+	hash := sha256.New()
+	for _, part := range []string{hostname, ip, osVersion, strings.Join(sorted, ",")} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// getMACAddresses returns the sorted hardware addresses of non-loopback interfaces
+func getMACAddresses() []string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	macs := make([]string, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		mac := iface.HardwareAddr.String()
+		if !seen[mac] {
+			seen[mac] = true
+			macs = append(macs, mac)
+		}
+	}
+	sort.Strings(macs)
+	return macs
+}
+
+// getOSVersion returns a human readable operating system version
+func getOSVersion() string {
+	switch runtime.GOOS {
+	case "linux":
+		if version := parseOSRelease("/etc/os-release"); version != "" {
+			return version
+		}
+	case "darwin":
+		if out, err := exec.Command("sw_vers", "-productVersion").Output(); err == nil {
+			return "macOS " + strings.TrimSpace(string(out))
+		}
+	case "windows":
+		if out, err := exec.Command("cmd", "/c", "ver").Output(); err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	return runtime.GOOS
+}
+
+// parseOSRelease returns PRETTY_NAME from an os-release file
+func parseOSRelease(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); found {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// logShipInterval is the interval between two batches of logs sent to Nexus
+	logShipInterval = 10 * time.Second
+
+	// logShipBufferSize is the number of log entries kept while Nexus is unreachable, the
+	// oldest being dropped beyond
+	logShipBufferSize = 1000
+)
+
+// LogShipper buffers the logs of the minion at or above a level so they are shipped to Nexus
+// on the command stream, letting a remote minion be debugged without access to its host
+type LogShipper struct {
+	level    zapcore.Level
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries []*pb.MinionLogEntry
+	dropped int64 // entries dropped since the last batch, the buffer being full
+}
+
+// NewLogShipper creates a shipper of the logs at or above level
+func NewLogShipper(level zapcore.Level) *LogShipper {
+	return &LogShipper{
+		level:    level,
+		size:     logShipBufferSize,
+		interval: logShipInterval,
+	}
+}
+
+// Wrap returns logger also writing its entries to the shipper
+func (s *LogShipper) Wrap(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, &logShipperCore{shipper: s})
+	}))
+}
+
+// add buffers an entry, dropping the oldest one when the buffer is full
+func (s *LogShipper) add(entry *pb.MinionLogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) >= s.size {
+		s.entries = s.entries[1:]
+		s.dropped++
+	}
+	s.entries = append(s.entries, entry)
+}
+
+// take returns the buffered entries as a batch and empties the buffer, nil when there is nothing to ship
+func (s *LogShipper) take() *pb.MinionLogBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) == 0 && s.dropped == 0 {
+		return nil
+	}
+	batch := &pb.MinionLogBatch{Entries: s.entries, Dropped: s.dropped}
+	s.entries = nil
+	s.dropped = 0
+	return batch
+}
+
+// requeue puts back a batch that couldn't be sent before the entries logged since,
+// keeping the most recent ones when they don't fit
+func (s *LogShipper) requeue(batch *pb.MinionLogBatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := append(batch.Entries, s.entries...)
+	if excess := len(entries) - s.size; excess > 0 {
+		entries = entries[excess:]
+		s.dropped += int64(excess)
+	}
+	s.entries = entries
+	s.dropped += batch.Dropped
+}
+
+// run ships the buffered logs through send until ctx is done, requeuing the batches
+// send fails to deliver
+func (s *LogShipper) run(ctx context.Context, send func(*pb.CommandStreamMessage) error, logger *zap.Logger) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		batch := s.take()
+		if batch == nil {
+			continue
+		}
+		if err := send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Logs{Logs: batch}}); err != nil {
+			// Logged at debug level only, not to ship logs about shipping logs
+			logger.Debug("Failed to ship logs, keeping them for the next stream", zap.Error(err))
+			s.requeue(batch)
+			return
+		}
+	}
+}
+
+// logShipperCore is the zap core writing the entries of a logger to its shipper
+type logShipperCore struct {
+	shipper *LogShipper
+	fields  []zapcore.Field
+}
+
+// Enabled reports whether entries at level are shipped
+func (c *logShipperCore) Enabled(level zapcore.Level) bool {
+	return level >= c.shipper.level
+}
+
+// With returns a core adding fields to the entries it ships
+func (c *logShipperCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &logShipperCore{shipper: c.shipper, fields: make([]zapcore.Field, 0, len(c.fields)+len(fields))}
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+// Check adds the core to the entries it ships
+func (c *logShipperCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write buffers an entry in the shipper, its fields encoded as a JSON object
+func (c *logShipperCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	if entry.Stack != "" {
+		encoder.AddString("stacktrace", entry.Stack)
+	}
+
+	shipped := &pb.MinionLogEntry{
+		TimestampMs: entry.Time.UnixMilli(),
+		Level:       entry.Level.String(),
+		Logger:      entry.LoggerName,
+		Message:     entry.Message,
+	}
+	if entry.Caller.Defined {
+		shipped.Caller = entry.Caller.TrimmedPath()
+	}
+	if len(encoder.Fields) > 0 {
+		if encoded, err := json.Marshal(encoder.Fields); err == nil {
+			shipped.Fields = string(encoded)
+		}
+	}
+
+	c.shipper.add(shipped)
+	return nil
+}
+
+// Sync has nothing to flush, the entries being shipped by the command stream
+func (c *logShipperCore) Sync() error {
+	return nil
+}
+
+// EnableLogShipping makes the minion ship the logs buffered by shipper to Nexus on its command
+// stream. The logger of the minion must have been wrapped by the shipper.
+func (m *Minion) EnableLogShipping(shipper *LogShipper) {
+	m.commandProcessor.(*commandProcessor).logShipper = shipper
+}
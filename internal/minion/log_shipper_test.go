@@ -0,0 +1,100 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogShipperCapture(t *testing.T) {
+	shipper := NewLogShipper(zapcore.WarnLevel)
+	logger := shipper.Wrap(zap.NewNop()).With(zap.String("minion_id", "minion-1"))
+
+	logger.Info("Not shipped")
+	logger.Warn("Stream timeout", zap.Int("attempt", 3))
+
+	batch := shipper.take()
+	if batch == nil || len(batch.Entries) != 1 {
+		t.Fatalf("Expected one shipped entry, got %v", batch)
+	}
+	entry := batch.Entries[0]
+	if entry.Level != "warn" || entry.Message != "Stream timeout" || entry.TimestampMs == 0 {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+	if !strings.Contains(entry.Fields, `"minion_id":"minion-1"`) || !strings.Contains(entry.Fields, `"attempt":3`) {
+		t.Errorf("Expected the fields encoded as JSON, got %s", entry.Fields)
+	}
+	if shipper.take() != nil {
+		t.Error("Expected the buffer emptied")
+	}
+}
+
+func TestLogShipperBuffer(t *testing.T) {
+	shipper := NewLogShipper(zapcore.WarnLevel)
+	shipper.size = 3
+	for _, message := range []string{"1", "2", "3", "4"} {
+		shipper.add(&pb.MinionLogEntry{Message: message})
+	}
+
+	batch := shipper.take()
+	if len(batch.Entries) != 3 || batch.Entries[0].Message != "2" || batch.Dropped != 1 {
+		t.Fatalf("Expected the oldest entry dropped, got %v", batch)
+	}
+
+	// A batch requeued before newer entries keeps the most recent ones
+	shipper.add(&pb.MinionLogEntry{Message: "5"})
+	shipper.requeue(batch)
+	requeued := shipper.take()
+	if len(requeued.Entries) != 3 || requeued.Entries[0].Message != "3" || requeued.Entries[2].Message != "5" || requeued.Dropped != 2 {
+		t.Errorf("Unexpected requeued batch: %v", requeued)
+	}
+}
+
+func TestLogShipperRun(t *testing.T) {
+	shipper := NewLogShipper(zapcore.WarnLevel)
+	shipper.interval = 10 * time.Millisecond
+	shipper.add(&pb.MinionLogEntry{Message: "shipped"})
+
+	sent := make(chan *pb.MinionLogBatch, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go shipper.run(ctx, func(msg *pb.CommandStreamMessage) error {
+		sent <- msg.GetLogs()
+		return nil
+	}, zap.NewNop())
+
+	select {
+	case batch := <-sent:
+		if len(batch.Entries) != 1 || batch.Entries[0].Message != "shipped" {
+			t.Errorf("Unexpected batch: %v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the logs to be shipped")
+	}
+	cancel()
+
+	// A batch failing to be sent is kept for the next stream
+	shipper.add(&pb.MinionLogEntry{Message: "kept"})
+	done := make(chan struct{})
+	go func() {
+		shipper.run(context.Background(), func(*pb.CommandStreamMessage) error {
+			return errors.New("stream closed")
+		}, zap.NewNop())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the shipping to stop on a send failure")
+	}
+	if batch := shipper.take(); batch == nil || len(batch.Entries) != 1 || batch.Entries[0].Message != "kept" {
+		t.Errorf("Expected the batch requeued, got %v", batch)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/arhuman/minexus/internal/availability"
 	"github.com/arhuman/minexus/internal/command"
 	"github.com/arhuman/minexus/internal/logging"
 )
@@ -26,6 +27,12 @@ type Minion struct {
 	logger            *zap.Logger
 	Atom              zap.AtomicLevel
 	registry          *command.Registry
+	scheduler         *Scheduler             // nil unless scheduled tasks are enabled
+	watcher           *Watcher               // nil unless file watchers are enabled
+	watchdog          *watchdog              // nil unless resource limits are enforced
+	availability      *availability.Schedule // windows outside which the minion sleeps, nil: always available
+	crashes           *crashReporter         // panics recovered, reported to Nexus at the next registration
+	retirement        *retirement            // retirement of the host by Nexus, stopping the minion for good
 
 	// New component interfaces
 	connectionMgr    ConnectionManager
@@ -40,11 +47,24 @@ func NewMinion(id string, service pb.MinionServiceClient, heartbeatInterval time
 
 	reconnectMgr := NewReconnectionManager(initialReconnectDelay, maxReconnectDelay, logger)
 	registry := command.SetupCommands(shellTimeout)
+	crashes := newCrashReporter(logger)
+	retired := newRetirement(logger)
 
 	// Create component instances
 	connectionMgr := NewConnectionManager(id, service, reconnectMgr, logger)
 	commandProcessor := NewCommandProcessor(id, registry, &atom, service, streamTimeout, logger)
+	commandProcessor.reconnectHint = reconnectMgr.Postpone
+	commandProcessor.crashes = crashes
+	commandProcessor.retired = retired.retire
 	registrationMgr := NewRegistrationManager(id, service, connectionMgr, logger)
+	registrationMgr.onRegistered = commandProcessor.registered
+	registrationMgr.registry = registry
+	registrationMgr.crashes = crashes
+	registrationMgr.onRetired = retired.retire
+	registry.Register(command.NewPowerRebootCommand(registrationMgr))
+	registry.Register(command.NewPowerShutdownCommand(registrationMgr))
+	registry.Register(command.NewPowerCancelCommand(registrationMgr))
+	registry.Register(command.NewPatchApplyCommand(registrationMgr))
 
 	return &Minion{
 		id:                id,
@@ -55,17 +75,32 @@ func NewMinion(id string, service pb.MinionServiceClient, heartbeatInterval time
 		logger:            logger,
 		Atom:              atom,
 		registry:          registry,
+		crashes:           crashes,
+		retirement:        retired,
 		connectionMgr:     connectionMgr,
 		commandProcessor:  commandProcessor,
 		registrationMgr:   registrationMgr,
 	}
 }
 
-// Start begins the minion's operation
+// Start begins the minion's operation. Its loops recover from panics, reported to Nexus, and run again.
 func (m *Minion) Start(ctx context.Context) error {
 	m.wg.Add(2) // One for command processing, one for periodic registration
-	go m.run(ctx)
-	go m.periodicRegistration(ctx)
+	go m.supervise(ctx, "command loop", m.run)
+	go m.supervise(ctx, "periodic registration", m.periodicRegistration)
+
+	if m.scheduler != nil {
+		m.wg.Add(1)
+		go m.supervise(ctx, "scheduler", m.runScheduler)
+	}
+	if m.watcher != nil {
+		m.wg.Add(1)
+		go m.supervise(ctx, "watcher", m.runWatcher)
+	}
+	if m.watchdog != nil {
+		m.wg.Add(1)
+		go m.supervise(ctx, "watchdog", m.runWatchdog)
+	}
 	return nil
 }
 
@@ -79,7 +114,6 @@ func (m *Minion) Stop() {
 func (m *Minion) run(ctx context.Context) {
 	logger, start := logging.FuncLogger(m.logger, "Minion.run")
 	defer logging.FuncExit(logger, start)
-	defer m.wg.Done()
 
 	// Step 1: Perform initial registration
 	resp, err := m.performInitialRegistration(ctx)
@@ -103,6 +137,9 @@ func (m *Minion) performInitialRegistration(ctx context.Context) (*pb.RegisterRe
 		if err == nil && resp.Success {
 			return resp, nil
 		}
+		if err == nil && resp.Retire != nil {
+			return nil, fmt.Errorf("minion retired")
+		}
 
 		if attempt < 5 {
 			if !m.waitBetweenAttempts(ctx, attempt, err, logger) {
@@ -150,6 +187,10 @@ func (m *Minion) commandProcessingLoop(ctx context.Context) {
 			return
 		}
 
+		if !m.waitForAvailability(ctx, logger) {
+			return
+		}
+
 		if !m.ensureConnection(ctx) {
 			continue
 		}
@@ -174,6 +215,34 @@ func (m *Minion) shouldStop(ctx context.Context, logger *zap.Logger) bool {
 	}
 }
 
+// waitForAvailability disconnects a minion outside its availability windows and sleeps until the
+// next one opens. It returns false if the minion stopped while sleeping.
+func (m *Minion) waitForAvailability(ctx context.Context, logger *zap.Logger) bool {
+	now := time.Now()
+	if m.availability.Available(now) {
+		return true
+	}
+
+	if m.connectionMgr.IsConnected() {
+		m.connectionMgr.Disconnect()
+	}
+	wake := m.availability.NextWake(now)
+	logger.Info("Outside availability windows, sleeping until the next one",
+		zap.String("minion_id", m.id),
+		zap.String("availability", m.availability.String()),
+		zap.Time("next_wake", wake))
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-m.done:
+		return false
+	case <-time.After(time.Until(wake)):
+		logger.Info("Availability window opened, reconnecting", zap.String("minion_id", m.id))
+		return true
+	}
+}
+
 // ensureConnection ensures the connection is established
 func (m *Minion) ensureConnection(ctx context.Context) bool {
 	if m.connectionMgr.IsConnected() {
@@ -215,20 +284,15 @@ func (m *Minion) reregisterForConnection(ctx context.Context, logger *zap.Logger
 	return true
 }
 
-// establishConnection attempts to establish the connection
+// establishConnection attempts to establish the connection. A failed attempt backs off once before
+// the next registration and connection attempt.
 func (m *Minion) establishConnection(ctx context.Context, logger *zap.Logger) bool {
 	if err := m.connectionMgr.Connect(ctx); err != nil {
-		logger.Warn("RACE CONDITION FIX: Connect() failed after re-registration, calling HandleReconnection()",
+		logger.Warn("RACE CONDITION FIX: Connect() failed after re-registration, retrying after backoff",
 			zap.String("minion_id", m.id),
 			zap.Error(err),
 			zap.String("error_type", fmt.Sprintf("%T", err)))
-
-		if err := m.connectionMgr.HandleReconnection(ctx); err != nil {
-			logger.Error("RACE CONDITION FIX: HandleReconnection() also failed",
-				zap.String("minion_id", m.id),
-				zap.Error(err))
-			return ctx.Err() == nil
-		}
+		return m.waitBeforeRetry(ctx)
 	}
 	return true
 }
@@ -242,9 +306,22 @@ func (m *Minion) processCommandsFromStream(ctx context.Context, logger *zap.Logg
 		return false
 	}
 
+	// The stream ends with the availability window
+	streamCtx := ctx
+	if sleepAt := m.availability.SleepAt(time.Now()); !sleepAt.IsZero() {
+		var cancel context.CancelFunc
+		streamCtx, cancel = context.WithDeadline(ctx, sleepAt)
+		defer cancel()
+	}
+
 	logger.Debug("Starting command processing loop", zap.String("minion_id", m.id))
-	err = m.commandProcessor.(*commandProcessor).ProcessCommands(ctx, stream)
+	err = m.commandProcessor.(*commandProcessor).ProcessCommands(streamCtx, stream)
 
+	if ctx.Err() == nil && streamCtx.Err() != nil {
+		logger.Info("Availability window over, disconnecting until the next one", zap.String("minion_id", m.id))
+		m.connectionMgr.Disconnect()
+		return true
+	}
 	return m.handleProcessingError(ctx, err, logger)
 }
 
@@ -265,19 +342,74 @@ func (m *Minion) handleProcessingError(ctx context.Context, err error, logger *z
 	return true
 }
 
-// waitBeforeRetry waits before retrying to avoid tight loops
+// waitBeforeRetry waits before retrying to avoid tight loops, backing off with jitter so that
+// minions disconnected together, e.g. by a Nexus restart, don't all come back at once
 func (m *Minion) waitBeforeRetry(ctx context.Context) bool {
+	delay := m.reconnectMgr.GetNextDelay()
+	if postponed := m.reconnectMgr.TakePostponement(); postponed > delay {
+		delay = postponed
+	}
+
 	select {
 	case <-ctx.Done():
 		return false
-	case <-time.After(time.Second):
+	case <-m.done:
+		return false
+	case <-time.After(delay):
 		return false
 	}
 }
 
+// SetReconnectPolicy sets the jitter of the reconnection delays, in percent of the delay, and how
+// long a connection must last before the delays start over from the initial one
+func (m *Minion) SetReconnectPolicy(jitterPercent int, resetAfter time.Duration) {
+	m.reconnectMgr.SetJitterPercent(jitterPercent)
+	m.reconnectMgr.SetResetAfter(resetAfter)
+}
+
+// SetNamespace sets the namespace the minion requests at registration. Nexus rejects the
+// namespaces its certificate does not allow.
+func (m *Minion) SetNamespace(namespace string) {
+	m.registrationMgr.(*registrationManager).setNamespace(namespace)
+}
+
+// SetTopology sets the failure domains the minion advertises at registration. Nexus falls
+// back to the region, datacenter (dc, zone) and rack tags for those left empty.
+func (m *Minion) SetTopology(region, datacenter, rack string) {
+	m.registrationMgr.(*registrationManager).setTopology(region, datacenter, rack)
+}
+
+// SetAvailability makes the minion power-aware: outside the windows of schedule it disconnects
+// and sleeps until the next one, Nexus queuing its commands meanwhile. The windows, when it sleeps
+// and when it wakes are advertised at registration. A nil schedule keeps it always available.
+func (m *Minion) SetAvailability(schedule *availability.Schedule) {
+	m.availability = schedule
+	m.registrationMgr.(*registrationManager).setAvailability(schedule)
+}
+
+// AddTags adds static tags, such as those of the minion configuration, to the tags advertised at
+// registration, replacing the cloud metadata tags with the same key. Nexus merges them with the
+// tags set from consoles according to its tag conflict policy.
+func (m *Minion) AddTags(tags map[string]string) {
+	m.registrationMgr.(*registrationManager).addTags(tags)
+}
+
+// EnableHTTPFallback makes the minion fall back to service, the HTTP long-polling transport,
+// once its gRPC stream or registration failed after times in a row. Registrations and
+// heartbeats follow the transport of the command stream.
+func (m *Minion) EnableHTTPFallback(service pb.MinionServiceClient, after int) {
+	fallback := NewConnectionManager(m.id, service, m.reconnectMgr, m.logger)
+	manager := newFallbackConnectionManager(m.connectionMgr.(*connectionManager), m.service, fallback, service, after, m.logger)
+	m.connectionMgr = manager
+
+	registrationMgr := m.registrationMgr.(*registrationManager)
+	registrationMgr.service = manager.Service()
+	registrationMgr.connectionMgr = manager
+}
+
 // updateComponentsWithNewID updates all components with the new minion ID
 func (m *Minion) updateComponentsWithNewID(newID string) {
-	m.connectionMgr.(*connectionManager).UpdateMinionID(newID)
+	m.connectionMgr.(interface{ UpdateMinionID(string) }).UpdateMinionID(newID)
 	m.commandProcessor.(*commandProcessor).UpdateMinionID(newID)
 	m.registrationMgr.(*registrationManager).UpdateMinionID(newID)
 }
@@ -286,7 +418,6 @@ func (m *Minion) updateComponentsWithNewID(newID string) {
 func (m *Minion) periodicRegistration(ctx context.Context) {
 	logger, start := logging.FuncLogger(m.logger, "Minion.periodicRegistration")
 	defer logging.FuncExit(logger, start)
-	defer m.wg.Done()
 
 	// Create a context that can be cancelled by the done channel
 	cancelCtx, cancel := context.WithCancel(ctx)
@@ -308,6 +439,36 @@ func (m *Minion) periodicRegistration(ctx context.Context) {
 	}
 }
 
+// runScheduler runs scheduled tasks until the minion stops
+func (m *Minion) runScheduler(ctx context.Context) {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.done:
+			cancel()
+		case <-cancelCtx.Done():
+		}
+	}()
+
+	m.scheduler.Run(cancelCtx)
+}
+
+// runWatcher runs the commands of watch rules until the minion stops
+func (m *Minion) runWatcher(ctx context.Context) {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.done:
+			cancel()
+		case <-cancelCtx.Done():
+		}
+	}()
+
+	m.watcher.Run(cancelCtx)
+}
+
 // executeCommand handles the execution of a single command
 func (m *Minion) executeCommand(ctx context.Context, cmd *pb.Command) (*pb.CommandResult, error) {
 	logger, start := logging.FuncLogger(m.logger, "Minion.executeCommand")
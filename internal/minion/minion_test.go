@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/arhuman/minexus/internal/availability"
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/version"
 	pb "github.com/arhuman/minexus/protogen"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -28,6 +35,14 @@ func (m *mockMinionServiceClient) Register(ctx context.Context, in *pb.HostInfo,
 	return &pb.RegisterResponse{Success: true, AssignedId: in.Id}, nil
 }
 
+func (m *mockMinionServiceClient) RelayStream(_ context.Context, _ ...grpc.CallOption) (pb.MinionService_RelayStreamClient, error) {
+	return nil, errors.New("relay stream not supported by mock")
+}
+
+func (m *mockMinionServiceClient) SpeedTest(_ context.Context, _ ...grpc.CallOption) (pb.MinionService_SpeedTestClient, error) {
+	return nil, errors.New("speed test not supported by mock")
+}
+
 func (m *mockMinionServiceClient) StreamCommands(ctx context.Context, opts ...grpc.CallOption) (pb.MinionService_StreamCommandsClient, error) {
 	if m.streamCommandsFunc != nil {
 		return m.streamCommandsFunc(ctx, opts...)
@@ -549,6 +564,125 @@ func TestGetIPAddress(t *testing.T) {
 	}
 }
 
+func TestAvailabilityAdvertised(t *testing.T) {
+	rm := NewRegistrationManager("laptop", &mockMinionServiceClient{}, &mockConnectionManager{}, zap.NewNop())
+	info, err := rm.createHostInfo()
+	if err != nil || info.Availability != "" || info.SleepAt != 0 || info.NextWake != 0 {
+		t.Fatalf("Expected an always available minion, got %v, %v", info, err)
+	}
+
+	// Available from an hour ago to an hour from now, every day
+	now := time.Now()
+	spec := now.Add(-time.Hour).Format("15:04") + "-" + now.Add(time.Hour).Format("15:04")
+	schedule, err := availability.Parse(spec)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rm.setAvailability(schedule)
+	info, err = rm.createHostInfo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Availability != spec {
+		t.Errorf("Expected availability %s, got %s", spec, info.Availability)
+	}
+	if sleepIn := time.Until(time.Unix(info.SleepAt, 0)); sleepIn <= 58*time.Minute || sleepIn > time.Hour {
+		t.Errorf("Expected the minion to sleep in an hour, got %v", sleepIn)
+	}
+	if wakeIn := time.Until(time.Unix(info.NextWake, 0)); wakeIn <= 22*time.Hour || wakeIn > 23*time.Hour {
+		t.Errorf("Expected the minion to wake in 22 hours, got %v", wakeIn)
+	}
+}
+
+func TestVersionAdvertised(t *testing.T) {
+	var responses []*pb.RegisterResponse
+	service := &mockMinionServiceClient{
+		registerFunc: func(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+			if in.Version != version.Short() || in.BuildCommit != version.GitCommit || in.ProtocolVersion != version.ProtocolVersion {
+				t.Errorf("Expected the build of the minion advertised, got %v", in)
+			}
+			resp := responses[0]
+			responses = responses[1:]
+			return resp, nil
+		},
+	}
+	core, logs := observer.New(zap.WarnLevel)
+	rm := NewRegistrationManager("web-01", service, &mockConnectionManager{}, zap.New(core))
+
+	// Nexus speaking the same protocol, then an older one twice: a single warning
+	responses = []*pb.RegisterResponse{
+		{Success: true, ProtocolVersion: version.ProtocolVersion},
+		{Success: true},
+		{Success: true},
+	}
+	for range responses {
+		if _, err := rm.Register(context.Background(), nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if logs.Len() != 1 || !strings.Contains(logs.All()[0].Message, "predates protocol versioning") {
+		t.Errorf("Expected a single protocol warning, got %v", logs.All())
+	}
+}
+
+func TestPowerAnnounced(t *testing.T) {
+	var sent []*pb.HostInfo
+	service := &mockMinionServiceClient{
+		registerFunc: func(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+			sent = append(sent, in)
+			return &pb.RegisterResponse{Success: true, AssignedId: in.Id}, nil
+		},
+	}
+	rm := NewRegistrationManager("web-01", service, &mockConnectionManager{}, zap.NewNop())
+
+	// The reboot is announced right away, then at each heartbeat until called off
+	at := time.Now().Add(5 * time.Minute)
+	if err := rm.AnnouncePower(context.Background(), command.PowerActionReboot, at); err != nil {
+		t.Fatalf("AnnouncePower failed: %v", err)
+	}
+	if _, err := rm.Register(context.Background(), nil); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if len(sent) != 2 || sent[0].PowerAction != "reboot" || sent[1].PowerAt != at.Unix() {
+		t.Fatalf("Expected the reboot advertised, got %v", sent)
+	}
+
+	if err := rm.AnnouncePower(context.Background(), "", time.Time{}); err != nil {
+		t.Fatalf("AnnouncePower failed: %v", err)
+	}
+	if len(sent) != 3 || sent[2].PowerAction != "" || sent[2].PowerAt != 0 {
+		t.Errorf("Expected the reboot withdrawn, got %v", sent[2])
+	}
+
+	// A reboot that didn't happen is dropped after its grace period
+	if err := rm.AnnouncePower(context.Background(), command.PowerActionReboot, time.Now().Add(-command.PowerAnnouncementGrace)); err != nil {
+		t.Fatalf("AnnouncePower failed: %v", err)
+	}
+	if len(sent) != 4 || sent[3].PowerAction != "" || sent[3].PowerAt != 0 {
+		t.Errorf("Expected the expired reboot dropped, got %v", sent[3])
+	}
+}
+
+func TestWaitForAvailability(t *testing.T) {
+	m := NewMinion("laptop", &mockMinionServiceClient{}, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	if !m.waitForAvailability(context.Background(), zap.NewNop()) {
+		t.Fatal("Expected a minion without availability windows to never sleep")
+	}
+
+	// Outside its windows, the minion sleeps until stopped
+	now := time.Now()
+	schedule, err := availability.Parse(now.Add(time.Hour).Format("15:04") + "-" + now.Add(2*time.Hour).Format("15:04"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	m.SetAvailability(schedule)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if m.waitForAvailability(ctx, zap.NewNop()) {
+		t.Error("Expected the minion to sleep until stopped")
+	}
+}
+
 func TestCommandStatusUpdates(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -1065,3 +1199,168 @@ func TestMinionRaceConditionIntegration(t *testing.T) {
 
 	// If we get here without panicking or race detector errors, the test passes
 }
+
+func TestCertRotation(t *testing.T) {
+	rotated := &certs.Bundle{CertPEM: certs.ConsoleClientCertPEM, KeyPEM: certs.ConsoleClientKeyPEM, CAPEM: certs.CAPem}
+
+	newRotator := func(t *testing.T, verifyErr error) (*certRotator, *certs.Store) {
+		store, err := certs.NewStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewStore failed: %v", err)
+		}
+		rotator := NewCertRotator(store, "localhost:11972", zap.NewNop()).(*certRotator)
+		rotator.reconnectDelay = time.Millisecond
+		rotator.verify = func(ctx context.Context, addr string, bundle *certs.Bundle) error {
+			return verifyErr
+		}
+		return rotator, store
+	}
+
+	t.Run("successful_rotation", func(t *testing.T) {
+		rotator, store := newRotator(t, nil)
+		if err := rotator.Rotate(context.Background(), rotated); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+		if string(store.Current().CertPEM) != string(certs.ConsoleClientCertPEM) {
+			t.Error("Expected rotated certificate to be active")
+		}
+	})
+
+	t.Run("rollback_on_handshake_failure", func(t *testing.T) {
+		rotator, store := newRotator(t, errors.New("handshake failure"))
+		err := rotator.Rotate(context.Background(), rotated)
+		if err == nil || !strings.Contains(err.Error(), "previous credentials restored") {
+			t.Fatalf("Expected rollback error, got: %v", err)
+		}
+		if string(store.Current().CertPEM) != string(certs.CertPEM) {
+			t.Error("Expected previous certificate to be restored")
+		}
+	})
+
+	t.Run("command_registered", func(t *testing.T) {
+		m := NewMinion("test-minion", &mockMinionServiceClient{}, time.Second, time.Second, time.Second, 15*time.Second, 30*time.Second, zap.NewNop(), zap.NewAtomicLevel())
+		store, _ := certs.NewStore("")
+		m.EnableCertRotation(store, "localhost:11972")
+
+		cmd := &pb.Command{Id: "cmd-1", Type: pb.CommandType_SYSTEM, Payload: "certs:rotate {}"}
+		result, err := m.commandProcessor.Execute(context.Background(), cmd)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if result.ExitCode == 0 || !strings.Contains(result.Stderr, "cert, key and ca are required") {
+			t.Errorf("Expected parse error from certs:rotate, got exit=%d stderr=%q", result.ExitCode, result.Stderr)
+		}
+	})
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	base := computeFingerprint("web-01", "10.0.0.1", "Debian 12", []string{"aa:bb", "cc:dd"})
+	if len(base) != 64 {
+		t.Fatalf("Expected a hex SHA-256 fingerprint, got %q", base)
+	}
+	if computeFingerprint("web-01", "10.0.0.1", "Debian 12", []string{"cc:dd", "aa:bb"}) != base {
+		t.Error("Expected fingerprint to ignore MAC address order")
+	}
+	for _, changed := range []string{
+		computeFingerprint("web-02", "10.0.0.1", "Debian 12", []string{"aa:bb", "cc:dd"}),
+		computeFingerprint("web-01", "10.0.0.2", "Debian 12", []string{"aa:bb", "cc:dd"}),
+		computeFingerprint("web-01", "10.0.0.1", "Debian 13", []string{"aa:bb", "cc:dd"}),
+		computeFingerprint("web-01", "10.0.0.1", "Debian 12", []string{"aa:bb"}),
+	} {
+		if changed == base {
+			t.Error("Expected fingerprint to change with host attributes")
+		}
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := "NAME=\"Debian GNU/Linux\"\nPRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if version := parseOSRelease(path); version != "Debian GNU/Linux 12 (bookworm)" {
+		t.Errorf("Unexpected OS version: %q", version)
+	}
+	if version := parseOSRelease(filepath.Join(t.TempDir(), "missing")); version != "" {
+		t.Errorf("Expected empty version for missing file, got %q", version)
+	}
+}
+
+func TestCommandSignatureVerification(t *testing.T) {
+	atom := zap.NewAtomicLevel()
+	minion := NewMinion("test-minion", &mockMinionServiceClient{}, time.Hour, time.Hour, time.Hour, 15*time.Second, 30*time.Second, zap.NewNop(), atom)
+	if err := minion.EnableCommandVerification(certs.CAPem); err != nil {
+		t.Fatalf("Failed to enable verification: %v", err)
+	}
+	processor := minion.commandProcessor.(*commandProcessor)
+
+	signer, err := certs.NewCommandSigner(certs.ConsoleClientCertPEM, certs.ConsoleClientKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	signed := &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "system:os"}
+	if err := signer.Sign(signed, []string{"test-minion"}); err != nil {
+		t.Fatalf("Failed to sign command: %v", err)
+	}
+	signed.Id = "cmd-signed"
+	signed.Env = map[string]string{"LD_PRELOAD": "/tmp/evil.so"}
+	unsigned := &pb.Command{Id: "cmd-unsigned", Type: pb.CommandType_SYSTEM, Payload: "system:os"}
+
+	stream := &mockStreamCommandsClient{}
+	for _, cmd := range []*pb.Command{signed, unsigned} {
+		msg := &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Command{Command: cmd}}
+		if err := processor.processReceivedMessage(context.Background(), msg, stream, zap.NewNop(), time.Now()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	shell := &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Shell{Shell: &pb.ShellMessage{SessionId: "s1", Open: true}}}
+	processor.processReceivedMessage(context.Background(), shell, stream, zap.NewNop(), time.Now())
+
+	results := make(map[string]*pb.CommandResult)
+	var shellReply *pb.ShellMessage
+	for _, msg := range stream.sendMsgs {
+		if result := msg.GetResult(); result != nil {
+			results[result.CommandId] = result
+		}
+		if reply := msg.GetShell(); reply != nil {
+			shellReply = reply
+		}
+	}
+
+	if result := results["cmd-signed"]; result == nil || result.ExitCode != 0 {
+		t.Errorf("Expected the signed command to run, got %v", result)
+	}
+	if len(signed.Env) != 0 {
+		t.Errorf("Expected the unsigned environment of the signed command dropped, got %v", signed.Env)
+	}
+	if result := results["cmd-unsigned"]; result == nil || result.ExitCode == 0 || !strings.Contains(result.Stderr, "command rejected: command is not signed") {
+		t.Errorf("Expected the unsigned command to be rejected, got %v", result)
+	}
+	if shellReply == nil || !shellReply.Close || shellReply.Error == "" {
+		t.Errorf("Expected shell sessions to be refused, got %v", shellReply)
+	}
+}
+
+func TestCommandTracePropagation(t *testing.T) {
+	processor := NewCommandProcessor("test-minion", command.SetupCommands(15*time.Second), nil, &mockMinionServiceClient{}, time.Hour, zap.NewNop())
+	stream := &mockStreamCommandsClient{}
+
+	cmd := &pb.Command{Id: "cmd-1", Type: pb.CommandType_SYSTEM, Payload: "echo traced", TraceId: "trace-1"}
+	if err := processor.executeCommandWorkflow(context.Background(), cmd, stream, zap.NewNop(), time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Every status and the result reported to Nexus carry the trace of the command
+	if len(stream.sendMsgs) != 4 {
+		t.Fatalf("Expected 3 statuses and the result, got %d messages", len(stream.sendMsgs))
+	}
+	for _, msg := range stream.sendMsgs {
+		if status := msg.GetStatus(); status != nil && status.TraceId != "trace-1" {
+			t.Errorf("Expected status %s to carry trace-1, got %q", status.Status, status.TraceId)
+		}
+		if result := msg.GetResult(); result != nil && result.TraceId != "trace-1" {
+			t.Errorf("Expected the result to carry trace-1, got %q", result.TraceId)
+		}
+	}
+}
@@ -2,11 +2,14 @@ package minion
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/arhuman/minexus/internal/certs"
 	"github.com/arhuman/minexus/internal/command"
 	"github.com/arhuman/minexus/internal/logging"
 	pb "github.com/arhuman/minexus/protogen"
@@ -15,6 +18,13 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+const (
+	// maxPendingResults caps the results buffered while Nexus is unreachable, the oldest being dropped
+	maxPendingResults = 1000
+	// pendingResultTTL is how long a buffered result waits for Nexus before being dropped
+	pendingResultTTL = 24 * time.Hour
+)
+
 // commandProcessor implements the CommandExecutor interface
 type commandProcessor struct {
 	id              string
@@ -28,6 +38,20 @@ type commandProcessor struct {
 	pendingResults  []*pb.CommandResult       // Buffer for results that couldn't be sent
 	pendingStatuses []*pb.CommandStatusUpdate // Buffer for status updates that couldn't be sent
 	pendingMutex    sync.RWMutex              // Protects pending buffers
+	acks            *resultTracker            // Results sent and not acknowledged by Nexus yet
+	maxMsgSize      atomic.Int32              // Message size announced by Nexus, larger results being split, 0: never split
+	sendMutex       sync.Mutex                // Serializes sends, shell output being sent concurrently
+	shells          *shellManager
+	files           *fileSender
+	transfers       *transferThrottle      // caps file-pull transfers and file:get reads
+	verifier        *certs.CommandVerifier // nil unless command signatures are verified
+	watchdog        *watchdog              // nil unless resource limits are enforced
+	disk            *diskGuard             // nil unless disk thresholds are enforced
+	reconnectHint   func(time.Duration)    // Called when Nexus asks to reconnect later
+	retired         func(*pb.RetireNotice) // Called when Nexus retires the host, nil: ignored
+	logShipper      *LogShipper            // nil unless logs are shipped to Nexus
+	crashes         *crashReporter         // records the panics of commands, nil: panics are not recovered
+	simulator       *commandSimulator      // fakes the execution of commands on virtual minions, nil: commands are run
 }
 
 // NewCommandProcessor creates a new command processor
@@ -47,7 +71,11 @@ func NewCommandProcessor(id string, registry *command.Registry, atom *zap.Atomic
 		pendingResults:  make([]*pb.CommandResult, 0),
 		pendingStatuses: make([]*pb.CommandStatusUpdate, 0),
 		pendingMutex:    sync.RWMutex{},
+		acks:            newResultTracker(),
+		shells:          newShellManager(logger),
 	}
+	processor.transfers = newTransferThrottle(0, defaultMaxFileTransfers)
+	processor.files = newFileSender(processor.transfers, logger)
 
 	logger.Debug("Command processor created",
 		zap.String("minion_id", id),
@@ -55,10 +83,19 @@ func NewCommandProcessor(id string, registry *command.Registry, atom *zap.Atomic
 	return processor
 }
 
-// Execute runs the specified command and returns the result
-func (cp *commandProcessor) Execute(ctx context.Context, cmd *pb.Command) (*pb.CommandResult, error) {
+// Execute runs the specified command and returns the result, a panic of the command failing it
+func (cp *commandProcessor) Execute(ctx context.Context, cmd *pb.Command) (result *pb.CommandResult, err error) {
 	logger, start := logging.FuncLogger(cp.logger, "commandProcessor.Execute")
 	defer logging.FuncExit(logger, start)
+	// Deferred first to run last, once a panic of the command turned into its result
+	defer func() {
+		if result != nil {
+			captureExecution(result, time.Since(start))
+		}
+	}()
+	if cp.crashes != nil {
+		defer cp.crashes.recoverCommand(cmd, cp.id, &result, &err)
+	}
 
 	// Extract sequence number for logging
 	seqNum := "unknown"
@@ -68,21 +105,71 @@ func (cp *commandProcessor) Execute(ctx context.Context, cmd *pb.Command) (*pb.C
 		}
 	}
 
-	// Try registry-based execution first
+	// Reject the command while the minion is over its resource limits
+	if cp.watchdog != nil {
+		release, err := cp.watchdog.admit()
+		if err != nil {
+			logger.Warn("Rejected command, minion over its resource limits",
+				zap.String("command_id", cmd.Id),
+				zap.Error(err))
+			return &pb.CommandResult{
+				CommandId: cmd.Id,
+				MinionId:  cp.id,
+				Timestamp: time.Now().Unix(),
+				ExitCode:  1,
+				Stderr:    err.Error(),
+			}, err
+		}
+		defer release()
+	}
+
+	if cp.simulator != nil {
+		return cp.simulator.execute(ctx, cmd, cp.id)
+	}
+
+	// Refuse the disk-heavy commands while the disk they write to is short of space
+	if cp.disk != nil {
+		if paths, heavy := cp.registry.DiskTargets(cmd.Payload); heavy {
+			if diskFull := cp.disk.check(paths); diskFull != nil {
+				logger.Warn("Rejected disk-heavy command, disk short of space",
+					zap.String("command_id", cmd.Id),
+					zap.Error(diskFull))
+				result := &pb.CommandResult{
+					CommandId: cmd.Id,
+					MinionId:  cp.id,
+					Timestamp: time.Now().Unix(),
+					ExitCode:  1,
+					Stderr:    diskFull.Error(),
+				}
+				if encoded, err := json.Marshal(diskFull); err == nil {
+					result.ContentType = command.ContentTypeDiskFull
+					result.Structured = string(encoded)
+				}
+				return result, diskFull
+			}
+		}
+	}
+
+	// Try registry-based execution first, logging under the trace of the command
 	execCtx := command.NewExecutionContext(
 		ctx,
-		cp.logger,
+		traceLogger(cp.logger, cmd.TraceId),
 		cp.atom,
 		cp.id,
 		cmd.Id,
 	)
+	execCtx.Env = cmd.Env
+	execCtx.Transfers = cp.transfers
+	if cp.service != nil {
+		execCtx.SpeedTest = &speedTester{minionID: cp.id, service: cp.service}
+	}
 
 	logger.Debug("Attempting registry-based command execution",
 		zap.String("command_id", cmd.Id),
 		zap.String("payload", cmd.Payload),
 		zap.String("seq_num", seqNum))
 
-	result, err := cp.registry.Execute(execCtx, cmd)
+	result, err = cp.registry.Execute(execCtx, cmd)
 	if err == nil {
 		logger.Debug("Registry execution successful",
 			zap.String("command_id", cmd.Id))
@@ -136,6 +223,9 @@ func (cp *commandProcessor) ProcessCommands(ctx context.Context, stream pb.Minio
 
 	logger.Debug("Starting command listening loop")
 
+	// Results sent on the previous stream may have been lost with it
+	cp.resendUnacked(stream, true)
+
 	// Flush any pending results from previous stream disconnection
 	if err := cp.flushPendingResults(stream); err != nil {
 		logger.Warn("HARDENING: Failed to flush some pending results on stream reconnect",
@@ -143,8 +233,26 @@ func (cp *commandProcessor) ProcessCommands(ctx context.Context, stream pb.Minio
 		// Continue processing - don't fail on pending result flush errors
 	}
 
+	// Ship the logs to Nexus as long as the stream is open
+	if cp.logShipper != nil {
+		shipCtx, stopShipping := context.WithCancel(ctx)
+		defer stopShipping()
+		go cp.logShipper.run(shipCtx, func(msg *pb.CommandStreamMessage) error {
+			return cp.send(stream, msg)
+		}, logger)
+	}
+
 	for {
 		loopStart := time.Now()
+
+		// Deliver results queued while processing, like those of scheduled tasks
+		if cp.hasPendingResults() {
+			if err := cp.flushPendingResults(stream); err != nil {
+				logger.Warn("HARDENING: Failed to flush some pending results", zap.Error(err))
+			}
+		}
+		cp.resendUnacked(stream, false)
+
 		logger.Debug("Waiting for next command on stream")
 
 		// Receive message from stream
@@ -247,6 +355,10 @@ func (cp *commandProcessor) handleStreamError(ctx context.Context, err error, lo
 	// Buffer any pending results before stream disconnection
 	cp.logPendingBufferState()
 
+	// Shell sessions and file transfers don't survive their stream
+	cp.shells.closeAll()
+	cp.files.closeAll()
+
 	// Enhanced error logging
 	cp.logStreamError(err, logger)
 
@@ -299,6 +411,72 @@ func (cp *commandProcessor) processReceivedMessage(ctx context.Context, msg *pb.
 		zap.Bool("has_result", msg.GetResult() != nil),
 		zap.Bool("has_status", msg.GetStatus() != nil))
 
+	if hint := msg.GetReconnect(); hint != nil {
+		delay := time.Duration(hint.DelaySeconds) * time.Second
+		logger.Info("Nexus asked to reconnect later",
+			zap.String("reason", hint.Reason),
+			zap.Duration("delay", delay))
+		if cp.reconnectHint != nil {
+			cp.reconnectHint(delay)
+		}
+		return errSkipMessage
+	}
+
+	if notice := msg.GetRetire(); notice != nil {
+		if cp.retired != nil {
+			cp.retired(notice)
+		}
+		return errSkipMessage
+	}
+
+	if ack := msg.GetAck(); ack != nil {
+		if cp.acks.ack(ack.CommandId) {
+			logger.Debug("Result acknowledged by Nexus", zap.String("command_id", ack.CommandId))
+		}
+		return errSkipMessage
+	}
+
+	if shell := msg.GetShell(); shell != nil {
+		reply := func(reply *pb.ShellMessage) error {
+			reply.MinionId = cp.id
+			return cp.send(stream, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Shell{Shell: reply}})
+		}
+		// Interactive sessions can't be signed, they would bypass signature verification
+		if shell.Open && cp.verifier != nil {
+			reply(&pb.ShellMessage{SessionId: shell.SessionId, Close: true, ExitCode: -1, Error: "shell sessions are disabled on minions verifying command signatures"})
+			return errSkipMessage
+		}
+		if shell.Open && cp.simulator != nil {
+			reply(&pb.ShellMessage{SessionId: shell.SessionId, Close: true, ExitCode: -1, Error: "shell sessions are disabled on simulated minions"})
+			return errSkipMessage
+		}
+		if shell.Open && cp.watchdog != nil {
+			if err := cp.watchdog.overloaded(); err != nil {
+				reply(&pb.ShellMessage{SessionId: shell.SessionId, Close: true, ExitCode: -1, Error: err.Error()})
+				return errSkipMessage
+			}
+		}
+		cp.shells.handle(shell, reply)
+		return errSkipMessage
+	}
+
+	if file := msg.GetFile(); file != nil {
+		send := func(chunk *pb.FileChunk) error {
+			return cp.send(stream, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_File{File: chunk}})
+		}
+		// Transfers can't be signed, they would bypass signature verification
+		if file.Start && cp.verifier != nil {
+			send(&pb.FileChunk{TransferId: file.TransferId, Error: "file transfers are disabled on minions verifying command signatures"})
+			return errSkipMessage
+		}
+		if file.Start && cp.simulator != nil {
+			send(&pb.FileChunk{TransferId: file.TransferId, Error: "file transfers are disabled on simulated minions"})
+			return errSkipMessage
+		}
+		cp.files.handle(file, send)
+		return errSkipMessage
+	}
+
 	command := msg.GetCommand()
 	if command == nil {
 		logger.Warn("Received non-command message, skipping",
@@ -309,6 +487,7 @@ func (cp *commandProcessor) processReceivedMessage(ctx context.Context, msg *pb.
 
 	// Extract and store sequence number
 	seqNum := cp.extractAndStoreSequenceNumber(command)
+	logger = traceLogger(logger, command.TraceId)
 
 	logger.Debug("Processing command",
 		zap.String("command_id", command.Id),
@@ -337,17 +516,21 @@ func (cp *commandProcessor) extractAndStoreSequenceNumber(command *pb.Command) s
 // executeCommandWorkflow executes the complete command workflow
 func (cp *commandProcessor) executeCommandWorkflow(ctx context.Context, command *pb.Command, stream pb.MinionService_StreamCommandsClient, logger *zap.Logger, loopStart time.Time) error {
 	// Send status updates
-	cp.sendStatusUpdates(stream, command.Id, logger)
+	cp.sendStatusUpdates(stream, command, logger)
 
-	// Execute command
-	result, err := cp.Execute(ctx, command)
+	// Execute command, unless its signature is required and invalid
+	result, err := cp.verifySignature(command, logger)
+	if err == nil {
+		result, err = cp.Execute(ctx, command)
+	}
 	if err != nil {
 		cp.handleCommandExecutionError(command.Id, err, result, logger)
 	}
+	result.TraceId = command.TraceId
 
 	// Send result and final status
 	cp.sendCommandResultHelper(stream, result, logger)
-	cp.sendFinalStatus(stream, command.Id, result, logger)
+	cp.sendFinalStatus(stream, command, result, logger)
 
 	logger.Debug("Command processing completed",
 		zap.Duration("iteration_time", time.Since(loopStart)),
@@ -356,13 +539,39 @@ func (cp *commandProcessor) executeCommandWorkflow(ctx context.Context, command
 	return nil
 }
 
+// verifySignature rejects the commands without a valid signature when signatures are verified, dropping
+// the environment set by Nexus from the others
+func (cp *commandProcessor) verifySignature(command *pb.Command, logger *zap.Logger) (*pb.CommandResult, error) {
+	if cp.verifier == nil {
+		return nil, nil
+	}
+	if err := cp.verifier.Verify(command, cp.id); err != nil {
+		logger.Warn("Rejected command with invalid signature",
+			zap.String("command_id", command.Id),
+			zap.Error(err))
+		return &pb.CommandResult{
+			CommandId: command.Id,
+			MinionId:  cp.id,
+			Timestamp: time.Now().Unix(),
+		}, fmt.Errorf("command rejected: %w", err)
+	}
+	// The environment set by Nexus isn't signed, it could inject code in signed commands
+	if len(command.Env) > 0 {
+		logger.Warn("Dropped the unsigned environment of a signed command",
+			zap.String("command_id", command.Id),
+			zap.Int("variables", len(command.Env)))
+		command.Env = nil
+	}
+	return nil, nil
+}
+
 // sendStatusUpdates sends the initial status updates for a command
-func (cp *commandProcessor) sendStatusUpdates(stream pb.MinionService_StreamCommandsClient, commandID string, logger *zap.Logger) {
-	if err := cp.sendStatusUpdateWithBuffer(stream, commandID, "RECEIVED"); err != nil {
+func (cp *commandProcessor) sendStatusUpdates(stream pb.MinionService_StreamCommandsClient, command *pb.Command, logger *zap.Logger) {
+	if err := cp.sendStatusUpdateWithBuffer(stream, command, "RECEIVED"); err != nil {
 		logger.Warn("HARDENING: Failed to send RECEIVED status - buffered for retry, continuing processing", zap.Error(err))
 	}
 
-	if err := cp.sendStatusUpdateWithBuffer(stream, commandID, "EXECUTING"); err != nil {
+	if err := cp.sendStatusUpdateWithBuffer(stream, command, "EXECUTING"); err != nil {
 		logger.Warn("HARDENING: Failed to send EXECUTING status - buffered for retry, continuing processing", zap.Error(err))
 	}
 }
@@ -384,42 +593,59 @@ func (cp *commandProcessor) sendCommandResultHelper(stream pb.MinionService_Stre
 }
 
 // sendFinalStatus sends the final status update for a command
-func (cp *commandProcessor) sendFinalStatus(stream pb.MinionService_StreamCommandsClient, commandID string, result *pb.CommandResult, logger *zap.Logger) {
+func (cp *commandProcessor) sendFinalStatus(stream pb.MinionService_StreamCommandsClient, command *pb.Command, result *pb.CommandResult, logger *zap.Logger) {
 	status := "COMPLETED"
 	if result.ExitCode != 0 {
 		status = "FAILED"
 	}
-	if err := cp.sendStatusUpdateWithBuffer(stream, commandID, status); err != nil {
+	if err := cp.sendStatusUpdateWithBuffer(stream, command, status); err != nil {
 		logger.Warn("HARDENING: Failed to send final status - buffered for retry, continuing processing", zap.Error(err))
 	}
 }
 
 // sendStatusUpdate sends a status update through the stream
-func (cp *commandProcessor) sendStatusUpdate(stream pb.MinionService_StreamCommandsClient, commandID, status string) error {
-	update := &pb.CommandStatusUpdate{
-		CommandId: commandID,
-		MinionId:  cp.id,
-		Status:    status,
-		Timestamp: time.Now().Unix(),
-	}
-
+func (cp *commandProcessor) sendStatusUpdate(stream pb.MinionService_StreamCommandsClient, update *pb.CommandStatusUpdate) error {
 	msg := &pb.CommandStreamMessage{
 		Message: &pb.CommandStreamMessage_Status{
 			Status: update,
 		},
 	}
 
-	return stream.Send(msg)
+	return cp.send(stream, msg)
 }
 
-// sendCommandResult sends a command result through the stream
+// sendCommandResult sends a command result through the stream, in chunks when larger than the
+// messages Nexus accepts
 func (cp *commandProcessor) sendCommandResult(stream pb.MinionService_StreamCommandsClient, result *pb.CommandResult) error {
+	chunks, err := splitResult(result, resultChunkSize(cp.maxMsgSize.Load()))
+	if err != nil {
+		return err
+	}
+	if chunks != nil {
+		cp.logger.Debug("Sending result in chunks",
+			zap.String("command_id", result.CommandId),
+			zap.Int("chunks", len(chunks)))
+		for _, chunk := range chunks {
+			if err := cp.send(stream, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_ResultChunk{ResultChunk: chunk}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	msg := &pb.CommandStreamMessage{
 		Message: &pb.CommandStreamMessage_Result{
 			Result: result,
 		},
 	}
 
+	return cp.send(stream, msg)
+}
+
+// send sends a message through the stream
+func (cp *commandProcessor) send(stream pb.MinionService_StreamCommandsClient, msg *pb.CommandStreamMessage) error {
+	cp.sendMutex.Lock()
+	defer cp.sendMutex.Unlock()
 	return stream.Send(msg)
 }
 
@@ -428,6 +654,7 @@ func (cp *commandProcessor) flushPendingResults(stream pb.MinionService_StreamCo
 	cp.pendingMutex.Lock()
 	defer cp.pendingMutex.Unlock()
 
+	cp.pruneResultsLocked(time.Now())
 	var flushErrors []string
 
 	// Flush pending results
@@ -436,6 +663,7 @@ func (cp *commandProcessor) flushPendingResults(stream pb.MinionService_StreamCo
 			flushErrors = append(flushErrors, fmt.Sprintf("result %d: %v", i, err))
 			continue
 		}
+		cp.acks.sent(result, time.Now())
 		cp.logger.Info("HARDENING: Flushed pending result",
 			zap.String("command_id", result.CommandId),
 			zap.String("minion_id", result.MinionId))
@@ -443,7 +671,7 @@ func (cp *commandProcessor) flushPendingResults(stream pb.MinionService_StreamCo
 
 	// Flush pending status updates
 	for i, status := range cp.pendingStatuses {
-		if err := cp.sendStatusUpdate(stream, status.CommandId, status.Status); err != nil {
+		if err := cp.sendStatusUpdate(stream, status); err != nil {
 			flushErrors = append(flushErrors, fmt.Sprintf("status %d: %v", i, err))
 			continue
 		}
@@ -473,6 +701,7 @@ func (cp *commandProcessor) logPendingBufferState() {
 
 	cp.logger.Info("HARDENING: Current pending buffer state",
 		zap.Int("pending_results", len(cp.pendingResults)),
+		zap.Int("unacknowledged_results", cp.acks.len()),
 		zap.Int("pending_statuses", len(cp.pendingStatuses)),
 		zap.String("minion_id", cp.id))
 
@@ -495,23 +724,24 @@ func (cp *commandProcessor) logPendingBufferState() {
 }
 
 // sendStatusUpdateWithBuffer sends a status update with buffering on failure
-func (cp *commandProcessor) sendStatusUpdateWithBuffer(stream pb.MinionService_StreamCommandsClient, commandID, status string) error {
+func (cp *commandProcessor) sendStatusUpdateWithBuffer(stream pb.MinionService_StreamCommandsClient, command *pb.Command, status string) error {
 	update := &pb.CommandStatusUpdate{
-		CommandId: commandID,
+		CommandId: command.Id,
 		MinionId:  cp.id,
 		Status:    status,
 		Timestamp: time.Now().Unix(),
+		TraceId:   command.TraceId,
 	}
 
 	// Try to send directly first
-	if err := cp.sendStatusUpdate(stream, commandID, status); err != nil {
+	if err := cp.sendStatusUpdate(stream, update); err != nil {
 		// Buffer the status update for later retry
 		cp.pendingMutex.Lock()
 		cp.pendingStatuses = append(cp.pendingStatuses, update)
 		cp.pendingMutex.Unlock()
 
 		cp.logger.Warn("HARDENING: Status update failed, buffered for retry",
-			zap.String("command_id", commandID),
+			zap.String("command_id", command.Id),
 			zap.String("status", status),
 			zap.Error(err))
 		return err
@@ -531,7 +761,7 @@ func (cp *commandProcessor) sendCommandResultWithBuffer(stream pb.MinionService_
 	if err := cp.sendCommandResult(stream, result); err != nil {
 		// Buffer the result for later retry
 		cp.pendingMutex.Lock()
-		cp.pendingResults = append(cp.pendingResults, result)
+		cp.bufferResultLocked(result, time.Now())
 		cp.pendingMutex.Unlock()
 
 		cp.logger.Error("HARDENING: Command result failed to send, buffered for retry",
@@ -542,12 +772,59 @@ func (cp *commandProcessor) sendCommandResultWithBuffer(stream pb.MinionService_
 		return err
 	}
 
+	cp.acks.sent(result, time.Now())
 	cp.logger.Info("DIAGNOSTIC: Command result sent successfully",
 		zap.String("command_id", result.CommandId),
 		zap.String("minion_id", result.MinionId))
 	return nil
 }
 
+// queueResult buffers a result produced outside of the command stream, it is sent
+// with the pending results once the minion is connected
+func (cp *commandProcessor) queueResult(result *pb.CommandResult) {
+	cp.pendingMutex.Lock()
+	defer cp.pendingMutex.Unlock()
+	cp.bufferResultLocked(result, time.Now())
+}
+
+// bufferResultLocked adds a result to the pending ones. The caller must hold cp.pendingMutex
+func (cp *commandProcessor) bufferResultLocked(result *pb.CommandResult, now time.Time) {
+	cp.pendingResults = append(cp.pendingResults, result)
+	cp.pruneResultsLocked(now)
+}
+
+// pruneResultsLocked drops the pending results older than pendingResultTTL, and the oldest ones
+// beyond maxPendingResults. The caller must hold cp.pendingMutex
+func (cp *commandProcessor) pruneResultsLocked(now time.Time) {
+	cutoff := now.Add(-pendingResultTTL).Unix()
+	kept := cp.pendingResults[:0]
+	for _, result := range cp.pendingResults {
+		if result.Timestamp == 0 || result.Timestamp >= cutoff {
+			kept = append(kept, result)
+		}
+	}
+	expired := len(cp.pendingResults) - len(kept)
+	clear(cp.pendingResults[len(kept):])
+
+	overflow := max(len(kept)-maxPendingResults, 0)
+	clear(kept[:overflow])
+	cp.pendingResults = kept[overflow:]
+
+	if expired > 0 || overflow > 0 {
+		cp.logger.Warn("HARDENING: Dropped pending results",
+			zap.Int("expired", expired),
+			zap.Int("overflow", overflow),
+			zap.Int("pending_results", len(cp.pendingResults)))
+	}
+}
+
+// hasPendingResults reports whether results or status updates are waiting to be sent
+func (cp *commandProcessor) hasPendingResults() bool {
+	cp.pendingMutex.RLock()
+	defer cp.pendingMutex.RUnlock()
+	return len(cp.pendingResults) > 0 || len(cp.pendingStatuses) > 0
+}
+
 // UpdateMinionID updates the minion ID used for command results
 func (cp *commandProcessor) UpdateMinionID(newID string) {
 	logger, start := logging.FuncLogger(cp.logger, "commandProcessor.UpdateMinionID")
@@ -559,3 +836,11 @@ func (cp *commandProcessor) UpdateMinionID(newID string) {
 
 	cp.id = newID
 }
+
+// traceLogger returns a logger tagging every line with the trace ID, when the command has one
+func traceLogger(logger *zap.Logger, traceID string) *zap.Logger {
+	if traceID == "" {
+		return logger
+	}
+	return logger.With(zap.String("trace_id", traceID))
+}
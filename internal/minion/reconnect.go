@@ -19,7 +19,11 @@ type ReconnectionManager struct {
 	attemptCount      int
 	logger            *zap.Logger
 	jitterEnabled     bool
+	jitterPercent     int // Share of the delay randomized away, 100 for full jitter
 	backoffMultiplier float64
+	resetAfter        time.Duration // Time a connection must last before the delay resets
+	connectedAt       time.Time     // Start of the current connection, zero while disconnected
+	postponed         time.Duration // Minimum delay before the next reconnection, hinted by Nexus
 }
 
 // NewReconnectionManager creates a new reconnection manager with exponential backoff
@@ -34,6 +38,7 @@ func NewReconnectionManager(initialDelay, maxDelay time.Duration, logger *zap.Lo
 		attemptCount:      0,
 		logger:            logger,
 		jitterEnabled:     true,
+		jitterPercent:     100,
 		backoffMultiplier: 2.0, // Double the delay each time
 	}
 }
@@ -47,6 +52,15 @@ func (rm *ReconnectionManager) GetNextDelay() time.Duration {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
+	// The connection that just ended lasted long enough to start over from the initial delay
+	if !rm.connectedAt.IsZero() {
+		if time.Since(rm.connectedAt) >= rm.resetAfter {
+			rm.currentDelay = rm.initialDelay
+			rm.attemptCount = 0
+		}
+		rm.connectedAt = time.Time{}
+	}
+
 	// First attempt should use initial delay
 	if rm.attemptCount == 0 {
 		rm.attemptCount++
@@ -107,6 +121,41 @@ func (rm *ReconnectionManager) ResetDelay() {
 	rm.attemptCount = 0
 }
 
+// MarkConnected records a successful connection. The delay resets once the connection has lasted
+// the reset threshold, so that connections dropped right away keep backing off.
+func (rm *ReconnectionManager) MarkConnected() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.resetAfter <= 0 {
+		rm.currentDelay = rm.initialDelay
+		rm.attemptCount = 0
+		return
+	}
+	rm.connectedAt = time.Now()
+}
+
+// Postpone makes the next reconnection wait at least delay, as hinted by a stopping Nexus
+func (rm *ReconnectionManager) Postpone(delay time.Duration) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.postponed = delay
+}
+
+// TakePostponement returns the delay hinted by Nexus, spread by up to half of it so minions
+// don't all reconnect at once, and clears it
+func (rm *ReconnectionManager) TakePostponement() time.Duration {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	delay := rm.postponed
+	rm.postponed = 0
+	if delay > 0 && rm.jitterEnabled {
+		delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	}
+	return delay
+}
+
 // GetCurrentDelay returns the current delay without incrementing
 func (rm *ReconnectionManager) GetCurrentDelay() time.Duration {
 	rm.mu.Lock()
@@ -128,6 +177,21 @@ func (rm *ReconnectionManager) SetJitterEnabled(enabled bool) {
 	rm.jitterEnabled = enabled
 }
 
+// SetJitterPercent sets the share of the delay randomized away, from 0 (no jitter) to 100 (full jitter)
+func (rm *ReconnectionManager) SetJitterPercent(percent int) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.jitterPercent = min(max(percent, 0), 100)
+}
+
+// SetResetAfter sets how long a connection must last before the delay resets to its initial value,
+// 0 resetting it on every successful connection
+func (rm *ReconnectionManager) SetResetAfter(resetAfter time.Duration) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.resetAfter = resetAfter
+}
+
 // SetBackoffMultiplier sets the multiplier for exponential backoff
 func (rm *ReconnectionManager) SetBackoffMultiplier(multiplier float64) {
 	rm.mu.Lock()
@@ -138,7 +202,8 @@ func (rm *ReconnectionManager) SetBackoffMultiplier(multiplier float64) {
 }
 
 // addJitter adds random jitter to the delay to prevent thundering herd problems
-// Uses full jitter approach: delay = random(0, delay)
+// Removes a random part of the delay up to the jitter percentage: full jitter picks a
+// random value between 0 and delay
 func (rm *ReconnectionManager) addJitter(delay time.Duration) time.Duration {
 	if delay <= 0 {
 		// For zero or negative delays, apply minimum jitter
@@ -146,8 +211,10 @@ func (rm *ReconnectionManager) addJitter(delay time.Duration) time.Duration {
 		return minDelay
 	}
 
-	// Use full jitter: pick a random value between 0 and delay
-	jitterDelay := time.Duration(rand.Int63n(int64(delay)))
+	jitterDelay := delay
+	if spread := int64(delay) * int64(rm.jitterPercent) / 100; spread > 0 {
+		jitterDelay -= time.Duration(rand.Int63n(spread))
+	}
 
 	// Ensure minimum delay of at least 100ms to avoid too frequent retries
 	// Apply minimum regardless of original delay size when jitter is enabled
@@ -178,6 +245,8 @@ func (rm *ReconnectionManager) GetStats() ReconnectionStats {
 		IsAtMaxDelay:      rm.currentDelay >= rm.maxDelay,
 		BackoffMultiplier: rm.backoffMultiplier,
 		JitterEnabled:     rm.jitterEnabled,
+		JitterPercent:     rm.jitterPercent,
+		ResetAfter:        rm.resetAfter,
 	}
 }
 
@@ -190,4 +259,6 @@ type ReconnectionStats struct {
 	IsAtMaxDelay      bool          `json:"is_at_max_delay"`
 	BackoffMultiplier float64       `json:"backoff_multiplier"`
 	JitterEnabled     bool          `json:"jitter_enabled"`
+	JitterPercent     int           `json:"jitter_percent"`
+	ResetAfter        time.Duration `json:"reset_after"`
 }
@@ -1,6 +1,8 @@
 package minion
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -192,3 +194,104 @@ func TestReconnectionManagerEdgeCases(t *testing.T) {
 		t.Errorf("Expected delay to be preserved without jitter: expected %v, got %v", 1*time.Nanosecond, delay)
 	}
 }
+
+func TestReconnectionManagerPostpone(t *testing.T) {
+	rm := NewReconnectionManager(time.Second, time.Minute, zap.NewNop())
+
+	if delay := rm.TakePostponement(); delay != 0 {
+		t.Errorf("Expected no postponement, got %s", delay)
+	}
+
+	rm.Postpone(10 * time.Second)
+	delay := rm.TakePostponement()
+	if delay < 10*time.Second || delay > 15*time.Second {
+		t.Errorf("Expected a postponement between 10s and 15s, got %s", delay)
+	}
+	if delay := rm.TakePostponement(); delay != 0 {
+		t.Errorf("Expected the postponement to be taken once, got %s", delay)
+	}
+}
+
+func TestReconnectionManagerJitterPercent(t *testing.T) {
+	rm := NewReconnectionManager(10*time.Second, time.Minute, zap.NewNop())
+
+	// Jitter removes at most its share of the delay
+	rm.SetJitterPercent(20)
+	for i := 0; i < 100; i++ {
+		delay := rm.GetNextDelay()
+		rm.ResetDelay()
+		if delay < 8*time.Second || delay > 10*time.Second {
+			t.Fatalf("Expected a delay between 8s and 10s, got %s", delay)
+		}
+	}
+
+	rm.SetJitterPercent(0)
+	if delay := rm.GetNextDelay(); delay != 10*time.Second {
+		t.Errorf("Expected no jitter, got %s", delay)
+	}
+	if stats := rm.GetStats(); stats.JitterPercent != 0 {
+		t.Errorf("Expected jitter percent 0 in stats, got %d", stats.JitterPercent)
+	}
+}
+
+func TestReconnectionManagerResetAfter(t *testing.T) {
+	rm := NewReconnectionManager(time.Second, time.Minute, zap.NewNop())
+	rm.SetJitterEnabled(false)
+	rm.SetResetAfter(time.Hour)
+
+	rm.GetNextDelay()
+	rm.GetNextDelay()
+
+	// Connections dropped before the threshold keep backing off
+	rm.MarkConnected()
+	if delay := rm.GetNextDelay(); delay != 4*time.Second {
+		t.Errorf("Expected the backoff to continue with 4s, got %s", delay)
+	}
+
+	// Stable connections start over from the initial delay
+	rm.SetResetAfter(0)
+	rm.MarkConnected()
+	if delay := rm.GetNextDelay(); delay != time.Second {
+		t.Errorf("Expected the initial delay after a stable connection, got %s", delay)
+	}
+	rm.SetResetAfter(time.Millisecond)
+	rm.GetNextDelay()
+	rm.MarkConnected()
+	time.Sleep(5 * time.Millisecond)
+	if delay := rm.GetNextDelay(); delay != time.Second {
+		t.Errorf("Expected the initial delay after the threshold, got %s", delay)
+	}
+}
+
+// failingConnectionManager fails to connect, counting the reconnections it is asked for
+type failingConnectionManager struct {
+	mockConnectionManager
+	reconnections int
+}
+
+func (f *failingConnectionManager) Connect(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func (f *failingConnectionManager) HandleReconnection(ctx context.Context) error {
+	f.reconnections++
+	return errors.New("connection refused")
+}
+
+func TestFailedConnectionBacksOffOnce(t *testing.T) {
+	rm := NewReconnectionManager(time.Millisecond, time.Second, zap.NewNop())
+	rm.SetJitterEnabled(false)
+	conn := &failingConnectionManager{}
+	m := &Minion{id: "minion-1", done: make(chan struct{}), reconnectMgr: rm, connectionMgr: conn, logger: zap.NewNop()}
+
+	for i := 0; i < 3; i++ {
+		if m.establishConnection(context.Background(), zap.NewNop()) {
+			t.Fatal("Expected the failed connection to be retried")
+		}
+	}
+	stats := rm.GetStats()
+	if stats.AttemptCount != 3 || stats.CurrentDelay != 4*time.Millisecond || conn.reconnections != 0 {
+		t.Errorf("Expected one backoff step per failed attempt, got %d attempts, delay %s, %d reconnections",
+			stats.AttemptCount, stats.CurrentDelay, conn.reconnections)
+	}
+}
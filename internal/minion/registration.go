@@ -2,6 +2,7 @@ package minion
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"os/exec"
 	"runtime"
@@ -13,7 +14,10 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/arhuman/minexus/internal/availability"
+	"github.com/arhuman/minexus/internal/command"
 	"github.com/arhuman/minexus/internal/logging"
+	"github.com/arhuman/minexus/internal/version"
 )
 
 // registrationManager implements the RegistrationManager interface
@@ -23,6 +27,34 @@ type registrationManager struct {
 	service       pb.MinionServiceClient
 	connectionMgr ConnectionManager
 	logger        *zap.Logger
+
+	capabilitiesOnce sync.Once
+	capabilities     []string // detected once, advertised at each registration
+
+	registry *command.Registry // commands whose family versions are advertised at each registration
+
+	tags map[string]string // advertised at each registration, such as the configured and cloud metadata tags
+
+	namespace string // requested at each registration, empty letting Nexus decide
+
+	region, datacenter, rack string // failure domains advertised at each registration
+
+	availability *availability.Schedule // windows outside which the minion sleeps, nil: always available
+
+	intervals chan time.Duration // heartbeat interval changes, applied by PeriodicRegister
+
+	onRegistered func(*pb.RegisterResponse) // called with each successful registration response, nil: none
+
+	onRetired func(*pb.RetireNotice) // called when Nexus refuses the registration of a retired host, nil: none
+
+	crashes *crashReporter // crash reports sent at each registration until Nexus received them, nil: none
+
+	simulatedHost string // hostname of a virtual minion, advertised without inspecting the host, empty: real host
+
+	powerAction string    // reboot or shutdown scheduled on the host, advertised until called off, empty: none
+	powerAt     time.Time // when the host goes down
+
+	nexusProtocol int32 // protocol version of Nexus at the last registration, 0 until known
 }
 
 // NewRegistrationManager creates a new registration manager
@@ -35,6 +67,7 @@ func NewRegistrationManager(id string, service pb.MinionServiceClient, connMgr C
 		service:       service,
 		connectionMgr: connMgr,
 		logger:        logger,
+		intervals:     make(chan time.Duration, 1),
 	}
 }
 
@@ -63,11 +96,17 @@ func (rm *registrationManager) Register(ctx context.Context, hostInfo *pb.HostIn
 	if !resp.Success {
 		logger.Error("Registration unsuccessful",
 			zap.String("error", resp.ErrorMessage))
+		rm.retired(resp)
 
 		return resp, nil
 	}
 
 	logger.Debug("Registration successful")
+	rm.checkNexusProtocol(resp.ProtocolVersion)
+	rm.crashesSent(hostInfo.Crashes, resp)
+	if rm.onRegistered != nil {
+		rm.onRegistered(resp)
+	}
 
 	// If server assigned a new ID, update it
 	if resp.AssignedId != "" && resp.AssignedId != rm.getID() {
@@ -95,7 +134,15 @@ func (rm *registrationManager) PeriodicRegister(ctx context.Context, interval ti
 		case <-ctx.Done():
 			logger.Debug("Context cancelled, stopping periodic registration")
 			return ctx.Err()
+		case interval = <-rm.intervals:
+			ticker.Reset(interval)
+			logger.Info("Heartbeat interval changed", zap.Duration("interval", interval))
 		case <-ticker.C:
+			// A sleeping minion is not heard from until its next window
+			if !rm.getAvailability().Available(time.Now()) {
+				continue
+			}
+
 			// Create updated host info for heartbeat
 			hostInfo, err := rm.createHostInfo()
 			if err != nil {
@@ -116,25 +163,127 @@ func (rm *registrationManager) PeriodicRegister(ctx context.Context, interval ti
 			if !resp.Success {
 				logger.Error("Periodic registration unsuccessful",
 					zap.String("error", resp.ErrorMessage))
+				rm.retired(resp)
 				continue
 			}
 
+			rm.crashesSent(hostInfo.Crashes, resp)
 			logger.Debug("Periodic registration successful",
 				zap.String("minion_id", rm.getID()))
 		}
 	}
 }
 
+// crashesSent forgets the crash reports of a successful registration once Nexus stored them. Nexus
+// versions not acknowledging crash reports only log them on failure.
+func (rm *registrationManager) crashesSent(crashes []*pb.CrashReport, resp *pb.RegisterResponse) {
+	if resp.CrashAcks && !resp.CrashesStored {
+		return
+	}
+	rm.crashes.delivered(crashes)
+}
+
+// retired hands the retirement notice of a refused registration to onRetired
+func (rm *registrationManager) retired(resp *pb.RegisterResponse) {
+	if resp.Retire != nil && rm.onRetired != nil {
+		rm.onRetired(resp.Retire)
+	}
+}
+
+// setHeartbeatInterval changes the interval of the periodic registration heartbeats,
+// replacing a change not applied yet
+func (rm *registrationManager) setHeartbeatInterval(interval time.Duration) {
+	if rm.intervals == nil {
+		return
+	}
+	select {
+	case <-rm.intervals:
+	default:
+	}
+	rm.intervals <- interval
+}
+
 // createHostInfo creates host information for registration
 func (rm *registrationManager) createHostInfo() (*pb.HostInfo, error) {
 
-	return &pb.HostInfo{
-		Id:       rm.getID(),
-		Hostname: getHostname(),
-		Ip:       rm.getIPAddress(),
-		Os:       runtime.GOOS,
-		Tags:     make(map[string]string),
-	}, nil
+	var hostname, ip, osVersion string
+	var macs []string
+	if simulatedHost := rm.getSimulatedHost(); simulatedHost != "" {
+		// Virtual minions run by the thousand, the host is neither inspected nor advertised
+		hostname, ip, osVersion = simulatedHost, simulatedIP(simulatedHost), simulatedOSVersion
+	} else {
+		hostname = getHostname()
+		ip = rm.getIPAddress()
+		osVersion = getOSVersion()
+		macs = getMACAddresses()
+		rm.capabilitiesOnce.Do(func() {
+			rm.capabilities = command.DetectCapabilities()
+		})
+	}
+	region, datacenter, rack := rm.getTopology()
+
+	info := &pb.HostInfo{
+		Id:              rm.getID(),
+		Hostname:        hostname,
+		Ip:              ip,
+		Os:              runtime.GOOS,
+		Tags:            rm.getTags(),
+		OsVersion:       osVersion,
+		MacAddresses:    macs,
+		Fingerprint:     computeFingerprint(hostname, ip, osVersion, macs),
+		Capabilities:    rm.capabilities,
+		Namespace:       rm.getNamespace(),
+		Region:          region,
+		Datacenter:      datacenter,
+		Rack:            rack,
+		CommandVersions: rm.commandVersions(),
+		Crashes:         rm.crashes.reports(),
+		Version:         version.Short(),
+		BuildCommit:     version.GitCommit,
+		ProtocolVersion: version.ProtocolVersion,
+	}
+	if action, at := rm.getPower(); action != "" {
+		info.PowerAction = action
+		info.PowerAt = at.Unix()
+	}
+	if schedule := rm.getAvailability(); schedule != nil {
+		now := time.Now()
+		info.Availability = schedule.String()
+		if sleepAt := schedule.SleepAt(now); !sleepAt.IsZero() {
+			info.SleepAt = sleepAt.Unix()
+			info.NextWake = schedule.NextWake(now).Unix()
+		}
+	}
+	return info, nil
+}
+
+// checkNexusProtocol warns when Nexus starts speaking another protocol version than the minion,
+// once per change rather than at each heartbeat
+func (rm *registrationManager) checkNexusProtocol(protocol int32) {
+	rm.mu.Lock()
+	changed := protocol != rm.nexusProtocol
+	rm.nexusProtocol = protocol
+	rm.mu.Unlock()
+
+	if !changed || protocol == version.ProtocolVersion {
+		return
+	}
+	if protocol == 0 {
+		rm.logger.Warn("Nexus predates protocol versioning, upgrade it to use all the minion features",
+			zap.Int32("minion_protocol", version.ProtocolVersion))
+		return
+	}
+	rm.logger.Warn("Nexus speaks another protocol version than the minion",
+		zap.Int32("nexus_protocol", protocol),
+		zap.Int32("minion_protocol", version.ProtocolVersion))
+}
+
+// commandVersions returns the handler version of each command family the minion executes
+func (rm *registrationManager) commandVersions() map[string]int32 {
+	if rm.registry == nil {
+		return nil
+	}
+	return rm.registry.FamilyVersions()
 }
 
 // GetMinionID returns the current minion ID
@@ -211,6 +360,116 @@ func (rm *registrationManager) getID() string {
 	return rm.id
 }
 
+// getTags returns a copy of the tags advertised at registration
+func (rm *registrationManager) getTags() map[string]string {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	tags := make(map[string]string, len(rm.tags))
+	for key, value := range rm.tags {
+		tags[key] = value
+	}
+	return tags
+}
+
+// addTags adds tags to the tags advertised at registration, replacing those with the same key
+func (rm *registrationManager) addTags(tags map[string]string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.tags == nil {
+		rm.tags = make(map[string]string, len(tags))
+	}
+	for key, value := range tags {
+		rm.tags[key] = value
+	}
+}
+
+// getNamespace returns the requested namespace with proper locking
+func (rm *registrationManager) getNamespace() string {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.namespace
+}
+
+// setNamespace sets the requested namespace with proper locking
+func (rm *registrationManager) setNamespace(namespace string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.namespace = namespace
+}
+
+// getTopology returns the failure domains with proper locking
+func (rm *registrationManager) getTopology() (region, datacenter, rack string) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.region, rm.datacenter, rm.rack
+}
+
+// setTopology sets the failure domains with proper locking
+func (rm *registrationManager) setTopology(region, datacenter, rack string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.region, rm.datacenter, rm.rack = region, datacenter, rack
+}
+
+// getSimulatedHost returns the hostname of a virtual minion with proper locking
+func (rm *registrationManager) getSimulatedHost() string {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.simulatedHost
+}
+
+// setSimulatedHost makes the minion advertise a virtual host with proper locking
+func (rm *registrationManager) setSimulatedHost(hostname string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.simulatedHost = hostname
+}
+
+// getPower returns the power action scheduled on the host with proper locking. An action still
+// announced command.PowerAnnouncementGrace after its time didn't happen and is dropped.
+func (rm *registrationManager) getPower() (string, time.Time) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.powerAction != "" && !time.Now().Before(rm.powerAt.Add(command.PowerAnnouncementGrace)) {
+		rm.logger.Warn("Host still up after its announced power action, dropping the announcement",
+			zap.String("power_action", rm.powerAction),
+			zap.Time("power_at", rm.powerAt))
+		rm.powerAction, rm.powerAt = "", time.Time{}
+	}
+	return rm.powerAction, rm.powerAt
+}
+
+// AnnouncePower implements command.PowerAnnouncer: the power action is advertised right away, then
+// at each heartbeat until the host goes down or the action is called off
+func (rm *registrationManager) AnnouncePower(ctx context.Context, action string, at time.Time) error {
+	rm.mu.Lock()
+	rm.powerAction, rm.powerAt = action, at
+	rm.mu.Unlock()
+
+	resp, err := rm.Register(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("registration refused: %s", resp.ErrorMessage)
+	}
+	return nil
+}
+
+// getAvailability returns the availability windows with proper locking
+func (rm *registrationManager) getAvailability() *availability.Schedule {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return rm.availability
+}
+
+// setAvailability sets the availability windows with proper locking
+func (rm *registrationManager) setAvailability(schedule *availability.Schedule) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.availability = schedule
+}
+
 // setID safely sets the minion ID
 func (rm *registrationManager) setID(newID string) {
 	rm.mu.Lock()
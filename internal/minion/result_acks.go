@@ -0,0 +1,136 @@
+package minion
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// resultAckTimeout is the time a result sent to Nexus waits for its acknowledgement before
+	// being sent again on the same stream
+	resultAckTimeout = time.Minute
+
+	// resultAckExpiry is the time after which a result never acknowledged is dropped, such as
+	// the result of a command Nexus no longer knows
+	resultAckExpiry = 24 * time.Hour
+)
+
+// unackedResult is a result sent to Nexus and not acknowledged yet
+type unackedResult struct {
+	result    *pb.CommandResult
+	firstSent time.Time
+	lastSent  time.Time
+}
+
+// resultTracker keeps the results sent to a Nexus acknowledging them until their acknowledgement,
+// so that the results lost with their stream, or that Nexus failed to store, are sent again
+type resultTracker struct {
+	enabled atomic.Bool // Nexus acknowledges results, per its last registration response
+
+	mu      sync.Mutex
+	results map[string]*unackedResult // by command ID
+}
+
+// newResultTracker creates a tracker, disabled until Nexus announces acknowledgements
+func newResultTracker() *resultTracker {
+	return &resultTracker{results: make(map[string]*unackedResult)}
+}
+
+// setEnabled enables the tracking of the results when Nexus acknowledges them. Results already
+// tracked are kept, a Nexus downgrade leaving them to expire.
+func (t *resultTracker) setEnabled(enabled bool) {
+	t.enabled.Store(enabled)
+}
+
+// sent tracks a result sent to Nexus until its acknowledgement. A new result of a command, such
+// as the result of its next attempt, replaces the one still tracked.
+func (t *resultTracker) sent(result *pb.CommandResult, now time.Time) {
+	if !t.enabled.Load() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if unacked, exists := t.results[result.CommandId]; exists && unacked.result == result {
+		unacked.lastSent = now
+		return
+	}
+	t.results[result.CommandId] = &unackedResult{result: result, firstSent: now, lastSent: now}
+}
+
+// ack stops tracking the result of a command, reporting whether it was tracked
+func (t *resultTracker) ack(commandID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, exists := t.results[commandID]
+	delete(t.results, commandID)
+	return exists
+}
+
+// due returns the results to send again, all of them on a new stream or else those waiting for
+// their acknowledgement for longer than resultAckTimeout, and drops the expired ones
+func (t *resultTracker) due(now time.Time, all bool) (due []*pb.CommandResult, expired []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for commandID, unacked := range t.results {
+		if now.Sub(unacked.firstSent) > resultAckExpiry {
+			delete(t.results, commandID)
+			expired = append(expired, commandID)
+			continue
+		}
+		if all || now.Sub(unacked.lastSent) >= resultAckTimeout {
+			due = append(due, unacked.result)
+		}
+	}
+	return due, expired
+}
+
+// len returns the number of results waiting for their acknowledgement
+func (t *resultTracker) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.results)
+}
+
+// resendUnacked sends again the results waiting for their acknowledgement, all of them on a
+// new stream or else those whose acknowledgement is overdue
+func (cp *commandProcessor) resendUnacked(stream pb.MinionService_StreamCommandsClient, all bool) {
+	now := time.Now()
+	due, expired := cp.acks.due(now, all)
+	if len(expired) > 0 {
+		cp.logger.Warn("Dropped results never acknowledged by Nexus",
+			zap.Strings("command_ids", expired),
+			zap.Duration("expiry", resultAckExpiry))
+	}
+
+	for _, result := range due {
+		if err := cp.sendCommandResult(stream, result); err != nil {
+			cp.logger.Warn("Failed to send unacknowledged result again",
+				zap.String("command_id", result.CommandId),
+				zap.Error(err))
+			return
+		}
+		cp.acks.sent(result, now)
+		cp.logger.Info("Sent unacknowledged result again",
+			zap.String("command_id", result.CommandId))
+	}
+}
+
+// registered applies the result handling Nexus announces in a registration response
+func (cp *commandProcessor) registered(resp *pb.RegisterResponse) {
+	cp.setResultAcks(resp)
+	cp.setResultChunking(resp)
+}
+
+// setResultAcks enables the tracking of the results sent when Nexus acknowledges them
+func (cp *commandProcessor) setResultAcks(resp *pb.RegisterResponse) {
+	cp.acks.setEnabled(resp.ResultAcks)
+}
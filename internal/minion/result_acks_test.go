@@ -0,0 +1,113 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestResultTracker(t *testing.T) {
+	tracker := newResultTracker()
+	now := time.Now()
+
+	// Results aren't tracked until Nexus announces acknowledgements
+	tracker.sent(&pb.CommandResult{CommandId: "cmd-0"}, now)
+	if tracker.len() != 0 {
+		t.Fatal("Expected no result tracked without acknowledgements")
+	}
+
+	tracker.setEnabled(true)
+	tracker.sent(&pb.CommandResult{CommandId: "cmd-1"}, now)
+	tracker.sent(&pb.CommandResult{CommandId: "cmd-2"}, now)
+
+	if due, _ := tracker.due(now.Add(time.Second), false); len(due) != 0 {
+		t.Errorf("Expected no result due before the timeout, got %v", due)
+	}
+	if due, _ := tracker.due(now.Add(time.Second), true); len(due) != 2 {
+		t.Errorf("Expected every result due on a new stream, got %v", due)
+	}
+
+	if !tracker.ack("cmd-1") || tracker.ack("cmd-1") {
+		t.Error("Expected cmd-1 acknowledged once")
+	}
+	due, _ := tracker.due(now.Add(resultAckTimeout), false)
+	if len(due) != 1 || due[0].CommandId != "cmd-2" {
+		t.Errorf("Expected cmd-2 due after the timeout, got %v", due)
+	}
+
+	// Sending a result again keeps its first send for the expiry
+	tracker.sent(due[0], now.Add(resultAckExpiry))
+	due, expired := tracker.due(now.Add(resultAckExpiry+time.Second), true)
+	if len(due) != 0 || len(expired) != 1 || expired[0] != "cmd-2" || tracker.len() != 0 {
+		t.Errorf("Expected cmd-2 expired, got due %v, expired %v", due, expired)
+	}
+
+	// The result of a next attempt replaces the one waiting for its acknowledgement
+	tracker.sent(&pb.CommandResult{CommandId: "cmd-3", ExitCode: 1, Attempt: 1}, now)
+	tracker.sent(&pb.CommandResult{CommandId: "cmd-3", Attempt: 2}, now)
+	due, _ = tracker.due(now, true)
+	if len(due) != 1 || due[0].Attempt != 2 {
+		t.Errorf("Expected the second attempt due, got %v", due)
+	}
+}
+
+func TestResultAcknowledgement(t *testing.T) {
+	processor := NewCommandProcessor("minion-1", nil, nil, nil, time.Second, zap.NewNop())
+	processor.setResultAcks(&pb.RegisterResponse{Success: true, ResultAcks: true})
+
+	stream := &mockStreamCommandsClient{}
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1"}
+	if err := processor.sendCommandResultWithBuffer(stream, result); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if processor.acks.len() != 1 {
+		t.Fatal("Expected the result kept until acknowledged")
+	}
+
+	// A new stream gets the unacknowledged results again
+	stream = &mockStreamCommandsClient{}
+	processor.resendUnacked(stream, true)
+	if len(stream.sendMsgs) != 1 || stream.sendMsgs[0].GetResult().GetCommandId() != "cmd-1" {
+		t.Fatalf("Expected cmd-1 sent again, got %v", stream.sendMsgs)
+	}
+
+	// A failed send keeps the result
+	failing := &mockStreamCommandsClient{sendCallback: func(*pb.CommandStreamMessage) error { return errors.New("stream closed") }}
+	processor.resendUnacked(failing, true)
+	if processor.acks.len() != 1 {
+		t.Fatal("Expected the result kept after a failed send")
+	}
+
+	ack := &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Ack{Ack: &pb.ResultAck{CommandId: "cmd-1"}}}
+	if err := processor.processReceivedMessage(context.Background(), ack, stream, zap.NewNop(), time.Now()); err != errSkipMessage {
+		t.Errorf("Expected the acknowledgement skipped, got %v", err)
+	}
+	if processor.acks.len() != 0 {
+		t.Error("Expected the acknowledged result dropped")
+	}
+}
+
+func TestPendingResultsBounded(t *testing.T) {
+	processor := NewCommandProcessor("minion-1", nil, nil, nil, time.Second, zap.NewNop())
+	now := time.Now()
+
+	// Results waiting for Nexus longer than their TTL are dropped
+	processor.bufferResultLocked(&pb.CommandResult{CommandId: "old", Timestamp: now.Add(-pendingResultTTL - time.Minute).Unix()}, now)
+	processor.bufferResultLocked(&pb.CommandResult{CommandId: "recent", Timestamp: now.Unix()}, now)
+	if len(processor.pendingResults) != 1 || processor.pendingResults[0].CommandId != "recent" {
+		t.Fatalf("Expected the expired result dropped, got %v", processor.pendingResults)
+	}
+
+	// Beyond the cap, the oldest results are dropped
+	for i := 0; i < maxPendingResults; i++ {
+		processor.queueResult(&pb.CommandResult{CommandId: "scheduled", Timestamp: now.Unix()})
+	}
+	if len(processor.pendingResults) != maxPendingResults || processor.pendingResults[0].CommandId != "scheduled" {
+		t.Errorf("Expected %d results with the oldest dropped, got %d", maxPendingResults, len(processor.pendingResults))
+	}
+}
@@ -0,0 +1,57 @@
+package minion
+
+import (
+	"fmt"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// resultChunkOverhead is the part of the message size kept for the fields of a chunk and the
+// envelope of a relay forwarding it
+const resultChunkOverhead = 512
+
+// resultChunkSize returns the size of the chunks of the results sent to a Nexus accepting
+// messages of maxMsgSize bytes, 0 when Nexus doesn't reassemble chunks. Chunks fill 3/4 of the
+// message, their base64 encoding by the long-polling transport growing them by a third.
+func resultChunkSize(maxMsgSize int32) int {
+	if maxMsgSize <= 0 {
+		return 0
+	}
+	size := (int(maxMsgSize) - resultChunkOverhead) * 3 / 4
+	if size < 1 {
+		size = int(maxMsgSize) / 2
+	}
+	return size
+}
+
+// splitResult splits a result larger than chunkSize in ordered chunks of its serialization, nil
+// when the result fits in a single message
+func splitResult(result *pb.CommandResult, chunkSize int) ([]*pb.ResultChunk, error) {
+	if chunkSize <= 0 || proto.Size(result) <= chunkSize {
+		return nil, nil
+	}
+	data, err := proto.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize result: %w", err)
+	}
+
+	total := (len(data) + chunkSize - 1) / chunkSize
+	chunks := make([]*pb.ResultChunk, 0, total)
+	for index := 0; index < total; index++ {
+		end := min((index+1)*chunkSize, len(data))
+		chunks = append(chunks, &pb.ResultChunk{
+			CommandId: result.CommandId,
+			Index:     int32(index),
+			Total:     int32(total),
+			Data:      data[index*chunkSize : end],
+		})
+	}
+	return chunks, nil
+}
+
+// setResultChunking splits the results larger than the message size announced by Nexus
+func (cp *commandProcessor) setResultChunking(resp *pb.RegisterResponse) {
+	cp.maxMsgSize.Store(resp.MaxMsgSize)
+}
@@ -0,0 +1,96 @@
+package minion
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestResultChunkSize(t *testing.T) {
+	tests := []struct {
+		maxMsgSize int32
+		want       int
+	}{
+		{0, 0},
+		{-1, 0},
+		{1024, 384},
+		{10 * 1024 * 1024, (10*1024*1024 - resultChunkOverhead) * 3 / 4},
+		{256, 128},
+	}
+	for _, tt := range tests {
+		if got := resultChunkSize(tt.maxMsgSize); got != tt.want {
+			t.Errorf("resultChunkSize(%d) = %d, want %d", tt.maxMsgSize, got, tt.want)
+		}
+	}
+}
+
+func TestSplitResult(t *testing.T) {
+	small := &pb.CommandResult{CommandId: "cmd-1", Stdout: "up"}
+	if chunks, err := splitResult(small, 1024); chunks != nil || err != nil {
+		t.Errorf("Expected a small result kept whole, got %v, %v", chunks, err)
+	}
+
+	large := &pb.CommandResult{CommandId: "cmd-2", Stdout: strings.Repeat("output ", 1000)}
+	if chunks, _ := splitResult(large, 0); chunks != nil {
+		t.Error("Expected results kept whole without chunk size")
+	}
+	chunks, err := splitResult(large, 1024)
+	if err != nil {
+		t.Fatalf("splitResult failed: %v", err)
+	}
+	if len(chunks) != (proto.Size(large)+1023)/1024 {
+		t.Fatalf("Unexpected number of chunks %d for %d bytes", len(chunks), proto.Size(large))
+	}
+
+	var data []byte
+	for i, chunk := range chunks {
+		if chunk.CommandId != "cmd-2" || chunk.Index != int32(i) || chunk.Total != int32(len(chunks)) || len(chunk.Data) > 1024 {
+			t.Errorf("Unexpected chunk %d: %s %d/%d of %d bytes", i, chunk.CommandId, chunk.Index, chunk.Total, len(chunk.Data))
+		}
+		data = append(data, chunk.Data...)
+	}
+	reassembled := &pb.CommandResult{}
+	if err := proto.Unmarshal(data, reassembled); err != nil || !proto.Equal(reassembled, large) {
+		t.Errorf("Expected the chunks to reassemble the result, got %v", err)
+	}
+}
+
+func TestSendChunkedResult(t *testing.T) {
+	processor := NewCommandProcessor("minion-1", nil, nil, nil, time.Second, zap.NewNop())
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: strings.Repeat("x", 10000)}
+
+	// Results stay whole until Nexus announces its message size
+	stream := &mockStreamCommandsClient{}
+	if err := processor.sendCommandResult(stream, result); err != nil {
+		t.Fatalf("sendCommandResult failed: %v", err)
+	}
+	if len(stream.sendMsgs) != 1 || stream.sendMsgs[0].GetResult() == nil {
+		t.Fatalf("Expected the result sent whole, got %d messages", len(stream.sendMsgs))
+	}
+
+	processor.registered(&pb.RegisterResponse{Success: true, MaxMsgSize: 4096})
+	stream = &mockStreamCommandsClient{}
+	if err := processor.sendCommandResult(stream, result); err != nil {
+		t.Fatalf("sendCommandResult failed: %v", err)
+	}
+	if len(stream.sendMsgs) < 2 {
+		t.Fatalf("Expected the result sent in chunks, got %d messages", len(stream.sendMsgs))
+	}
+	var data bytes.Buffer
+	for _, msg := range stream.sendMsgs {
+		if size := proto.Size(msg); size > 4096 {
+			t.Errorf("Expected chunks below the message size, got %d bytes", size)
+		}
+		data.Write(msg.GetResultChunk().GetData())
+	}
+	reassembled := &pb.CommandResult{}
+	if err := proto.Unmarshal(data.Bytes(), reassembled); err != nil || !proto.Equal(reassembled, result) {
+		t.Errorf("Expected the chunks to reassemble the result, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package minion
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// retirement records the retirement of the host by Nexus, received over the command stream or at registration
+type retirement struct {
+	once    sync.Once
+	retired chan struct{} // closed once retired
+	notice  *pb.RetireNotice
+	logger  *zap.Logger
+}
+
+// newRetirement creates a retirement not received yet
+func newRetirement(logger *zap.Logger) *retirement {
+	return &retirement{retired: make(chan struct{}), logger: logger}
+}
+
+// retire records the retirement notice sent by Nexus, the first one only
+func (r *retirement) retire(notice *pb.RetireNotice) {
+	r.once.Do(func() {
+		r.notice = notice
+		r.logger.Warn("Host retired by Nexus, stopping for good",
+			zap.String("retired_by", notice.RetiredBy),
+			zap.String("reason", notice.Reason),
+			zap.Bool("uninstall", notice.Uninstall))
+		close(r.retired)
+	})
+}
+
+// Retired returns a channel closed when Nexus retired the host, the minion having to stop for good
+func (m *Minion) Retired() <-chan struct{} {
+	if m.retirement == nil {
+		return nil
+	}
+	return m.retirement.retired
+}
+
+// RetireNotice returns the retirement notice sent by Nexus, nil until the host is retired
+func (m *Minion) RetireNotice() *pb.RetireNotice {
+	if m.retirement == nil {
+		return nil
+	}
+	select {
+	case <-m.retirement.retired:
+		return m.retirement.notice
+	default:
+		return nil
+	}
+}
+
+// Uninstall removes the minion executable, as asked by Nexus retiring the host with uninstall, and
+// returns its path. The service unit starting the minion is left to the host administrator.
+func Uninstall() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate minion executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	if err := os.Remove(executable); err != nil {
+		return executable, fmt.Errorf("failed to remove minion executable: %w", err)
+	}
+	return executable, nil
+}
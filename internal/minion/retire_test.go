@@ -0,0 +1,59 @@
+package minion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func TestRetiredOverStream(t *testing.T) {
+	m := NewMinion("minion-1", &mockMinionServiceClient{}, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	if m.RetireNotice() != nil {
+		t.Fatal("Expected no retirement notice before retirement")
+	}
+
+	processor := m.commandProcessor.(*commandProcessor)
+	notice := &pb.RetireNotice{Reason: "hardware refresh", Uninstall: true}
+	msg := &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Retire{Retire: notice}}
+	for i := 0; i < 2; i++ {
+		if err := processor.processReceivedMessage(context.Background(), msg, &mockStreamCommandsClient{}, zap.NewNop(), time.Now()); err != errSkipMessage {
+			t.Fatalf("Expected the notice skipped as a command, got %v", err)
+		}
+	}
+
+	select {
+	case <-m.Retired():
+	default:
+		t.Fatal("Expected the minion retired")
+	}
+	if got := m.RetireNotice(); got != notice {
+		t.Errorf("Expected the retirement notice kept, got %v", got)
+	}
+}
+
+func TestRetiredAtRegistration(t *testing.T) {
+	service := &mockMinionServiceClient{
+		registerFunc: func(ctx context.Context, in *pb.HostInfo, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+			return &pb.RegisterResponse{Success: false, ErrorMessage: "minion minion-1 is retired", Retire: &pb.RetireNotice{Reason: "moved"}}, nil
+		},
+	}
+	m := NewMinion("minion-1", service, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+
+	// Registration is not attempted again once retired
+	if _, err := m.performInitialRegistration(context.Background()); err == nil {
+		t.Fatal("Expected the registration of a retired minion to fail")
+	}
+	select {
+	case <-m.Retired():
+	default:
+		t.Fatal("Expected the minion retired")
+	}
+	if m.RetireNotice().GetReason() != "moved" {
+		t.Errorf("Unexpected retirement notice %v", m.RetireNotice())
+	}
+}
@@ -0,0 +1,312 @@
+package minion
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// maxScheduledTasks bounds the number of tasks a minion accepts
+const maxScheduledTasks = 100
+
+// scheduledTask is a scheduled task with its parsed cron expression
+type scheduledTask struct {
+	command.ScheduledTask
+	schedule *cronSchedule
+	running  bool
+}
+
+// TaskRunner executes the command of a scheduled task and returns its result
+type TaskRunner func(ctx context.Context, cmd *pb.Command) *pb.CommandResult
+
+// Scheduler runs commands scheduled on the minion with cron expressions, independently of Nexus.
+// It implements command.TaskScheduler.
+type Scheduler struct {
+	mu     sync.Mutex
+	tasks  map[string]*scheduledTask
+	file   string // empty: tasks are kept in memory only
+	run    TaskRunner
+	report func(result *pb.CommandResult)
+	logger *zap.Logger
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler persisting its tasks to file, loading the tasks already saved there.
+// Task results are passed to report.
+func NewScheduler(file string, run TaskRunner, report func(result *pb.CommandResult), logger *zap.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		tasks:  make(map[string]*scheduledTask),
+		file:   file,
+		run:    run,
+		report: report,
+		logger: logger,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add schedules a command
+func (s *Scheduler) Add(schedule, payload string) (*command.ScheduledTask, error) {
+	parsed, err := parseCron(schedule)
+	if err != nil {
+		return nil, err
+	}
+	if payload == "" {
+		return nil, errors.New("command cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.tasks) >= maxScheduledTasks {
+		return nil, fmt.Errorf("too many scheduled tasks (maximum %d)", maxScheduledTasks)
+	}
+
+	task := &scheduledTask{
+		ScheduledTask: command.ScheduledTask{
+			ID:        generateTaskID(),
+			Schedule:  schedule,
+			Payload:   payload,
+			CreatedAt: time.Now().Unix(),
+		},
+		schedule: parsed,
+	}
+	s.tasks[task.ID] = task
+
+	if err := s.saveLocked(); err != nil {
+		delete(s.tasks, task.ID)
+		return nil, err
+	}
+
+	s.logger.Info("Scheduled task added",
+		zap.String("task_id", task.ID),
+		zap.String("schedule", schedule),
+		zap.String("payload", payload))
+	added := task.ScheduledTask
+	return &added, nil
+}
+
+// List returns the scheduled tasks ordered by creation
+func (s *Scheduler) List() []command.ScheduledTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]command.ScheduledTask, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task.ScheduledTask)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt != tasks[j].CreatedAt {
+			return tasks[i].CreatedAt < tasks[j].CreatedAt
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+// Remove removes a scheduled task
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		return fmt.Errorf("scheduled task %s not found", id)
+	}
+	delete(s.tasks, id)
+
+	if err := s.saveLocked(); err != nil {
+		s.tasks[id] = task
+		return err
+	}
+
+	s.logger.Info("Scheduled task removed", zap.String("task_id", id))
+	return nil
+}
+
+// Run starts due tasks at the beginning of every minute until ctx is cancelled,
+// then waits for running tasks to finish
+func (s *Scheduler) Run(ctx context.Context) {
+	logger, start := logging.FuncLogger(s.logger, "Scheduler.Run")
+	defer logging.FuncExit(logger, start)
+	defer s.wg.Wait()
+
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runDue(ctx, next)
+		}
+	}
+}
+
+// runDue starts the tasks scheduled at the minute of t, skipping tasks whose previous run is still going
+func (s *Scheduler) runDue(ctx context.Context, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, task := range s.tasks {
+		if !task.schedule.matches(t) {
+			continue
+		}
+		if task.running {
+			s.logger.Warn("Skipping scheduled task still running from a previous run",
+				zap.String("task_id", task.ID))
+			continue
+		}
+
+		task.running = true
+		s.wg.Add(1)
+		go s.execute(ctx, task.ID, task.Payload, t)
+	}
+}
+
+// execute runs a scheduled task and reports its result
+func (s *Scheduler) execute(ctx context.Context, taskID, payload string, at time.Time) {
+	defer s.wg.Done()
+
+	cmd := &pb.Command{
+		Id:      fmt.Sprintf("sched-%s-%d", taskID, at.Unix()),
+		Type:    pb.CommandType_SYSTEM,
+		Payload: payload,
+	}
+	result := s.run(ctx, cmd)
+	result.CommandId = cmd.Id
+	result.ScheduleId = taskID
+	result.Payload = payload
+
+	s.logger.Info("Scheduled task executed",
+		zap.String("task_id", taskID),
+		zap.String("command_id", cmd.Id),
+		zap.Int32("exit_code", result.ExitCode))
+
+	s.mu.Lock()
+	if task, exists := s.tasks[taskID]; exists {
+		task.running = false
+		task.LastRun = at.Unix()
+		task.LastCommandID = cmd.Id
+		task.LastExitCode = result.ExitCode
+		if err := s.saveLocked(); err != nil {
+			s.logger.Warn("Failed to persist scheduled task state", zap.String("task_id", taskID), zap.Error(err))
+		}
+	}
+	s.mu.Unlock()
+
+	s.report(result)
+}
+
+// load reads the tasks saved in the schedule file, a missing file meaning no task
+func (s *Scheduler) load() error {
+	if s.file == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schedule file: %w", err)
+	}
+
+	var tasks []command.ScheduledTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("invalid schedule file %s: %w", s.file, err)
+	}
+
+	for _, task := range tasks {
+		parsed, err := parseCron(task.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid task %s in schedule file %s: %w", task.ID, s.file, err)
+		}
+		s.tasks[task.ID] = &scheduledTask{ScheduledTask: task, schedule: parsed}
+	}
+
+	s.logger.Info("Scheduled tasks loaded", zap.String("file", s.file), zap.Int("count", len(tasks)))
+	return nil
+}
+
+// saveLocked atomically writes the tasks to the schedule file, s.mu must be held
+func (s *Scheduler) saveLocked() error {
+	if s.file == "" {
+		return nil
+	}
+
+	tasks := make([]command.ScheduledTask, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task.ScheduledTask)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scheduled tasks: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.file), ".schedule-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save scheduled tasks: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save scheduled tasks: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save scheduled tasks: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		return fmt.Errorf("failed to save scheduled tasks: %w", err)
+	}
+	return nil
+}
+
+// generateTaskID returns a short random task ID
+func generateTaskID() string {
+	bytes := make([]byte, 4)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// EnableScheduler enables the schedule: commands, persisting tasks to file (empty: in memory only).
+// Scheduled tasks run when the minion starts, even while it is disconnected from Nexus;
+// their results are buffered and reported to Nexus once connected.
+func (m *Minion) EnableScheduler(file string) error {
+	processor := m.commandProcessor.(*commandProcessor)
+	run := func(ctx context.Context, cmd *pb.Command) *pb.CommandResult {
+		result, _ := processor.Execute(ctx, cmd)
+		return result
+	}
+
+	scheduler, err := NewScheduler(file, run, processor.queueResult, m.logger)
+	if err != nil {
+		return err
+	}
+
+	m.scheduler = scheduler
+	m.registry.Register(command.NewScheduleAddCommand(scheduler))
+	m.registry.Register(command.NewScheduleListCommand(scheduler))
+	m.registry.Register(command.NewScheduleRemoveCommand(scheduler))
+	return nil
+}
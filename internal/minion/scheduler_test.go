@@ -0,0 +1,142 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if err != nil {
+			t.Fatalf("Invalid test time %s: %v", value, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		expr    string
+		time    string
+		matches bool
+	}{
+		{"* * * * *", "2030-01-07 12:34", true},
+		{"*/15 * * * *", "2030-01-07 12:45", true},
+		{"*/15 * * * *", "2030-01-07 12:46", false},
+		{"0 2 * * *", "2030-01-07 02:00", true},
+		{"0 2 * * *", "2030-01-07 03:00", false},
+		{"0 9-17/4 * * 1-5", "2030-01-07 13:00", true},  // Monday
+		{"0 9-17/4 * * 1-5", "2030-01-06 13:00", false}, // Sunday
+		{"0 0 * * 7", "2030-01-06 00:00", true},         // 7 is Sunday too
+		{"0 0 1 * 1", "2030-01-07 00:00", true},         // day of week matches
+		{"0 0 1 * 1", "2030-02-01 00:00", true},         // day of month matches
+		{"0 0 1 * 1", "2030-02-02 00:00", false},
+		{"30 4 1,15 3 *", "2030-03-15 04:30", true},
+		{"@hourly", "2030-01-07 05:00", true},
+		{"@daily", "2030-01-07 05:00", false},
+	}
+
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := schedule.matches(at(tt.time)); got != tt.matches {
+			t.Errorf("%q at %s: expected %v, got %v", tt.expr, tt.time, tt.matches, got)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@sometimes", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("Expected parseCron(%q) to fail", expr)
+		}
+	}
+}
+
+func TestSchedulerPersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "schedule.json")
+	run := func(ctx context.Context, cmd *pb.Command) *pb.CommandResult {
+		return &pb.CommandResult{}
+	}
+	report := func(result *pb.CommandResult) {}
+
+	scheduler, err := NewScheduler(file, run, report, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	if _, err := scheduler.Add("not a cron", "uptime"); err == nil {
+		t.Error("Expected invalid cron expression to be rejected")
+	}
+	first, err := scheduler.Add("*/5 * * * *", "system:info")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	second, err := scheduler.Add("@daily", "df -h")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := scheduler.Remove(first.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := scheduler.Remove(first.ID); err == nil {
+		t.Error("Expected removing an unknown task to fail")
+	}
+
+	// Tasks survive a restart
+	reloaded, err := NewScheduler(file, run, report, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to reload scheduler: %v", err)
+	}
+	tasks := reloaded.List()
+	if len(tasks) != 1 || tasks[0].ID != second.ID || tasks[0].Payload != "df -h" || tasks[0].Schedule != "@daily" {
+		t.Fatalf("Unexpected reloaded tasks: %+v", tasks)
+	}
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	var mu sync.Mutex
+	var reported []*pb.CommandResult
+	run := func(ctx context.Context, cmd *pb.Command) *pb.CommandResult {
+		return &pb.CommandResult{MinionId: "minion-1", ExitCode: 3, Stdout: cmd.Payload}
+	}
+	report := func(result *pb.CommandResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, result)
+	}
+
+	scheduler, err := NewScheduler("", run, report, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	hourly, _ := scheduler.Add("0 * * * *", "uptime")
+	if _, err := scheduler.Add("30 * * * *", "df -h"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	at := time.Date(2030, 1, 7, 12, 0, 0, 0, time.Local)
+	scheduler.runDue(context.Background(), at)
+	scheduler.wg.Wait()
+
+	if len(reported) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(reported))
+	}
+	result := reported[0]
+	if result.ScheduleId != hourly.ID || result.Payload != "uptime" || result.CommandId != fmt.Sprintf("sched-%s-%d", hourly.ID, at.Unix()) {
+		t.Errorf("Unexpected scheduled result: %+v", result)
+	}
+
+	tasks := scheduler.List()
+	for _, task := range tasks {
+		if task.ID == hourly.ID && (task.LastRun != at.Unix() || task.LastCommandID != result.CommandId || task.LastExitCode != 3) {
+			t.Errorf("Expected last run to be recorded, got %+v", task)
+		}
+	}
+}
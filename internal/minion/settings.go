@@ -0,0 +1,301 @@
+package minion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/arhuman/minexus/internal/command"
+)
+
+// Runtime settings changed with config:set
+const (
+	settingHeartbeatInterval     = "heartbeat_interval"
+	settingLogLevel              = "log_level"
+	settingMaxConcurrentCommands = "max_concurrent_commands"
+	settingMaxMemoryMB           = "max_memory_mb"
+	settingMaxCPUPercent         = "max_cpu_percent"
+	settingTransferBandwidth     = "transfer_bandwidth"
+	settingMaxFileTransfers      = "max_file_transfers"
+)
+
+// maxTransferBandwidth is the highest transfer_bandwidth, in bytes per second
+const maxTransferBandwidth = 1 << 30
+
+// settingDescriptions describes the runtime settings, config:show listing them in this order
+var settingDescriptions = []struct{ key, description string }{
+	{settingHeartbeatInterval, "Interval between two registration heartbeats"},
+	{settingLogLevel, "Minimum level of the minion logs (debug, info, warn, error)"},
+	{settingMaxConcurrentCommands, "Commands executing at the same time, 0 for unlimited"},
+	{settingMaxMemoryMB, "Memory of the minion process in MB, 0 for unlimited"},
+	{settingMaxCPUPercent, "CPU usage of the minion process in percent of one core, 0 for unlimited"},
+	{settingTransferBandwidth, "Bytes per second read by the file transfers, all together, 0 for unlimited"},
+	{settingMaxFileTransfers, "File transfers running at the same time, 0 for unlimited"},
+}
+
+// runtimeSettings are the settings of a running minion changed with config:set. Changes are
+// persisted to a JSON file mapping keys to values, applied again at the next start.
+type runtimeSettings struct {
+	minion *Minion
+	file   string // empty: changes are lost when the minion stops
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	heartbeat time.Duration
+	defaults  map[string]string // values from the minion configuration
+	overrides map[string]string
+}
+
+// Get returns the effective value of a setting
+func (s *runtimeSettings) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getLocked(key)
+}
+
+// Set applies a setting and persists it, "default" restoring the value from the minion
+// configuration. It returns the effective value.
+func (s *runtimeSettings) Set(key, value string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, err := s.getLocked(key)
+	if err != nil {
+		return "", err
+	}
+	if value == "default" {
+		value = s.defaults[key]
+	}
+
+	applied, err := s.applyLocked(key, value)
+	if err != nil {
+		return "", err
+	}
+	previousOverride, overridden := s.overrides[key]
+	if applied == s.defaults[key] {
+		delete(s.overrides, key)
+	} else {
+		s.overrides[key] = applied
+	}
+
+	if err := s.saveLocked(); err != nil {
+		s.applyLocked(key, previous)
+		if overridden {
+			s.overrides[key] = previousOverride
+		} else {
+			delete(s.overrides, key)
+		}
+		return "", err
+	}
+
+	s.logger.Info("Runtime setting changed", zap.String("key", key), zap.String("value", applied))
+	return applied, nil
+}
+
+// Show returns the effective value of every setting, with the state of the file transfers it limits
+func (s *runtimeSettings) Show() []command.Setting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transfers, bandwidth := s.transfers().state()
+	states := map[string]string{settingTransferBandwidth: bandwidth, settingMaxFileTransfers: transfers}
+	settings := make([]command.Setting, 0, len(settingDescriptions))
+	for _, d := range settingDescriptions {
+		value, _ := s.getLocked(d.key)
+		_, overridden := s.overrides[d.key]
+		settings = append(settings, command.Setting{Key: d.key, Value: value, Overridden: overridden, Description: d.description, State: states[d.key]})
+	}
+	return settings
+}
+
+// transfers returns the throttle of the file transfers of the minion
+func (s *runtimeSettings) transfers() *transferThrottle {
+	return s.minion.commandProcessor.(*commandProcessor).transfers
+}
+
+// getLocked returns the effective value of a setting, s.mu must be held
+func (s *runtimeSettings) getLocked(key string) (string, error) {
+	limits := s.minion.watchdog.getLimits()
+	switch key {
+	case settingHeartbeatInterval:
+		return s.heartbeat.String(), nil
+	case settingLogLevel:
+		return s.minion.Atom.Level().String(), nil
+	case settingMaxConcurrentCommands:
+		return strconv.Itoa(limits.MaxConcurrentCommands), nil
+	case settingMaxMemoryMB:
+		return strconv.Itoa(limits.MaxMemoryMB), nil
+	case settingMaxCPUPercent:
+		return strconv.Itoa(limits.MaxCPUPercent), nil
+	case settingTransferBandwidth, settingMaxFileTransfers:
+		bandwidth, maxTransfers := s.transfers().limits()
+		if key == settingTransferBandwidth {
+			return strconv.Itoa(bandwidth), nil
+		}
+		return strconv.Itoa(maxTransfers), nil
+	}
+	return "", fmt.Errorf("unknown setting '%s', see config:show", key)
+}
+
+// applyLocked validates and applies a setting, returning its normalized value. s.mu must be held.
+func (s *runtimeSettings) applyLocked(key, value string) (string, error) {
+	switch key {
+	case settingHeartbeatInterval:
+		interval, err := time.ParseDuration(value)
+		if seconds, convErr := strconv.Atoi(value); convErr == nil {
+			interval, err = time.Duration(seconds)*time.Second, nil
+		}
+		if err != nil || interval < time.Second {
+			return "", fmt.Errorf("invalid %s '%s': use a duration of at least 1s", key, value)
+		}
+		s.heartbeat = interval
+		if rm, ok := s.minion.registrationMgr.(*registrationManager); ok {
+			rm.setHeartbeatInterval(interval)
+		}
+		return interval.String(), nil
+
+	case settingLogLevel:
+		level, err := zapcore.ParseLevel(value)
+		if err != nil || level < zapcore.DebugLevel || level > zapcore.ErrorLevel {
+			return "", fmt.Errorf("invalid %s '%s': use debug, info, warn or error", key, value)
+		}
+		s.minion.Atom.SetLevel(level)
+		return level.String(), nil
+
+	case settingMaxConcurrentCommands, settingMaxMemoryMB, settingMaxCPUPercent:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid %s '%s': use a positive number, 0 for unlimited", key, value)
+		}
+		limits := s.minion.watchdog.getLimits()
+		switch key {
+		case settingMaxConcurrentCommands:
+			limits.MaxConcurrentCommands = n
+		case settingMaxMemoryMB:
+			limits.MaxMemoryMB = n
+		case settingMaxCPUPercent:
+			limits.MaxCPUPercent = n
+		}
+		s.minion.watchdog.setLimits(limits)
+		return strconv.Itoa(n), nil
+
+	case settingTransferBandwidth:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > maxTransferBandwidth {
+			return "", fmt.Errorf("invalid %s '%s': use bytes per second up to %d, 0 for unlimited", key, value, maxTransferBandwidth)
+		}
+		s.transfers().setBandwidth(n)
+		return strconv.Itoa(n), nil
+
+	case settingMaxFileTransfers:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid %s '%s': use a positive number, 0 for unlimited", key, value)
+		}
+		s.transfers().setMaxTransfers(n)
+		return strconv.Itoa(n), nil
+	}
+	return "", fmt.Errorf("unknown setting '%s', see config:show", key)
+}
+
+// load applies the settings saved in the settings file, a missing file meaning no change
+func (s *runtimeSettings) load() error {
+	if s.file == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read runtime config file: %w", err)
+	}
+
+	var overrides map[string]string
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return fmt.Errorf("invalid runtime config file %s: %w", s.file, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range overrides {
+		applied, err := s.applyLocked(key, value)
+		if err != nil {
+			return fmt.Errorf("invalid runtime config file %s: %w", s.file, err)
+		}
+		s.overrides[key] = applied
+	}
+
+	s.logger.Info("Runtime settings loaded", zap.String("file", s.file), zap.Int("count", len(overrides)))
+	return nil
+}
+
+// saveLocked atomically writes the changed settings to the settings file, s.mu must be held
+func (s *runtimeSettings) saveLocked() error {
+	if s.file == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode runtime settings: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.file), ".runtime-config-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save runtime settings: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save runtime settings: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save runtime settings: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.file); err != nil {
+		return fmt.Errorf("failed to save runtime settings: %w", err)
+	}
+	return nil
+}
+
+// EnableRuntimeConfig enables the config: commands, persisting changed settings to file (empty:
+// in memory only) and applying the ones saved by a previous run. It must be called after
+// EnableWatchdog, before the minion starts.
+func (m *Minion) EnableRuntimeConfig(file string) error {
+	// The watchdog runs even without limits so config:set can set them
+	if m.watchdog == nil {
+		m.watchdog = newWatchdog(WatchdogLimits{}, m.logger)
+		m.commandProcessor.(*commandProcessor).watchdog = m.watchdog
+	}
+
+	settings := &runtimeSettings{
+		minion:    m,
+		file:      file,
+		logger:    m.logger,
+		heartbeat: m.heartbeatInterval,
+		defaults:  make(map[string]string),
+		overrides: make(map[string]string),
+	}
+	for _, d := range settingDescriptions {
+		settings.defaults[d.key], _ = settings.getLocked(d.key)
+	}
+	if err := settings.load(); err != nil {
+		return err
+	}
+
+	m.registry.Register(command.NewConfigSetCommand(settings))
+	m.registry.Register(command.NewConfigGetCommand(settings))
+	m.registry.Register(command.NewConfigShowCommand(settings))
+	return nil
+}
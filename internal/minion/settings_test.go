@@ -0,0 +1,110 @@
+package minion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+)
+
+func newSettingsTestMinion(t *testing.T, file string) *Minion {
+	t.Helper()
+	m := NewMinion("settings-minion", &mockMinionServiceClient{}, time.Minute, time.Second, time.Second, 15*time.Second, 30*time.Second, zap.NewNop(), zap.NewAtomicLevelAt(zap.InfoLevel))
+	m.EnableWatchdog(WatchdogLimits{MaxMemoryMB: 512})
+	if err := m.EnableRuntimeConfig(file); err != nil {
+		t.Fatalf("EnableRuntimeConfig failed: %v", err)
+	}
+	return m
+}
+
+func runSettingsCommand(t *testing.T, m *Minion, payload string) *pb.CommandResult {
+	t.Helper()
+	result, err := m.executeCommand(context.Background(), &pb.Command{Id: "cmd-1", Type: pb.CommandType_SYSTEM, Payload: payload})
+	if err != nil {
+		t.Fatalf("%s failed: %v", payload, err)
+	}
+	return result
+}
+
+func TestRuntimeConfigCommands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "runtime.json")
+	m := newSettingsTestMinion(t, file)
+
+	result := runSettingsCommand(t, m, "config:set heartbeat_interval 90")
+	if result.ExitCode != 0 || !strings.Contains(result.Stdout, "heartbeat_interval set to 1m30s") {
+		t.Fatalf("Unexpected config:set result: %+v", result)
+	}
+	select {
+	case interval := <-m.registrationMgr.(*registrationManager).intervals:
+		if interval != 90*time.Second {
+			t.Errorf("Expected a 90s heartbeat interval, got %s", interval)
+		}
+	default:
+		t.Error("Expected the heartbeat interval change to reach the registration manager")
+	}
+
+	runSettingsCommand(t, m, "config:set log_level debug")
+	if m.Atom.Level() != zap.DebugLevel {
+		t.Errorf("Expected the debug log level, got %s", m.Atom.Level())
+	}
+	runSettingsCommand(t, m, "config:set max_concurrent_commands 2")
+	if limits := m.watchdog.getLimits(); limits.MaxConcurrentCommands != 2 || limits.MaxMemoryMB != 512 {
+		t.Errorf("Expected the concurrency limit changed alone, got %+v", limits)
+	}
+
+	if result := runSettingsCommand(t, m, "config:get max_concurrent_commands"); result.Stdout != "2" {
+		t.Errorf("Expected config:get to return 2, got %q", result.Stdout)
+	}
+
+	result = runSettingsCommand(t, m, "config:show")
+	for _, line := range []string{"heartbeat_interval", "log_level", "max_memory_mb"} {
+		if !strings.Contains(result.Stdout, line) {
+			t.Errorf("Expected config:show to list %s, got:\n%s", line, result.Stdout)
+		}
+	}
+
+	for _, payload := range []string{"config:set heartbeat_interval 0", "config:set log_level loud", "config:set max_memory_mb -1", "config:set colour blue", "config:get"} {
+		if result := runSettingsCommand(t, m, payload); result.ExitCode == 0 {
+			t.Errorf("Expected %q to fail", payload)
+		}
+	}
+
+	runSettingsCommand(t, m, "config:set log_level default")
+	if m.Atom.Level() != zap.InfoLevel {
+		t.Errorf("Expected default to restore the info log level, got %s", m.Atom.Level())
+	}
+
+	// Changed settings are applied again at the next start
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read runtime config file: %v", err)
+	}
+	if strings.Contains(string(data), "log_level") {
+		t.Errorf("Expected the restored log level not to be persisted, got %s", data)
+	}
+
+	restarted := newSettingsTestMinion(t, file)
+	if limits := restarted.watchdog.getLimits(); limits.MaxConcurrentCommands != 2 {
+		t.Errorf("Expected the persisted concurrency limit applied at start, got %+v", limits)
+	}
+	if result := runSettingsCommand(t, restarted, "config:get heartbeat_interval"); result.Stdout != "1m30s" {
+		t.Errorf("Expected the persisted heartbeat interval, got %q", result.Stdout)
+	}
+}
+
+func TestRuntimeConfigInvalidFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "runtime.json")
+	if err := os.WriteFile(file, []byte(`{"heartbeat_interval": "never"}`), 0600); err != nil {
+		t.Fatalf("Failed to write runtime config file: %v", err)
+	}
+
+	m := NewMinion("settings-minion", &mockMinionServiceClient{}, time.Minute, time.Second, time.Second, 15*time.Second, 30*time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	if err := m.EnableRuntimeConfig(file); err == nil {
+		t.Error("Expected an invalid runtime config file to be rejected")
+	}
+}
@@ -0,0 +1,155 @@
+package minion
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// maxShellSessions bounds the number of interactive shells running at once on a minion
+const maxShellSessions = 4
+
+// shellProcess is a running shell and the terminal it is attached to
+type shellProcess struct {
+	cmd    *exec.Cmd
+	input  io.WriteCloser // keystrokes
+	output io.ReadCloser  // terminal output
+	resize func(rows, cols uint32) error
+}
+
+// close releases the terminal of the shell, ending it if still running
+func (p *shellProcess) close() {
+	p.input.Close()
+	p.output.Close()
+	p.cmd.Process.Kill()
+}
+
+// shellCommand returns the shell run by interactive sessions
+func shellCommand() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return defaultShell
+}
+
+// shellManager runs the interactive shell sessions opened from consoles
+type shellManager struct {
+	mu       sync.Mutex
+	sessions map[string]*shellProcess
+	logger   *zap.Logger
+}
+
+// newShellManager creates a manager without sessions
+func newShellManager(logger *zap.Logger) *shellManager {
+	return &shellManager{
+		sessions: make(map[string]*shellProcess),
+		logger:   logger,
+	}
+}
+
+// handle processes a shell message received from the nexus. Output and the end of
+// the sessions are reported through send.
+func (m *shellManager) handle(msg *pb.ShellMessage, send func(*pb.ShellMessage) error) {
+	if msg.Open {
+		m.open(msg, send)
+		return
+	}
+
+	m.mu.Lock()
+	process, exists := m.sessions[msg.SessionId]
+	m.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	switch {
+	case msg.Close:
+		process.close()
+	case len(msg.Data) > 0:
+		if _, err := process.input.Write(msg.Data); err != nil {
+			m.logger.Warn("Failed to write shell input", zap.String("session_id", msg.SessionId), zap.Error(err))
+		}
+	case msg.Rows > 0 && msg.Cols > 0:
+		if err := process.resize(msg.Rows, msg.Cols); err != nil {
+			m.logger.Debug("Failed to resize shell terminal", zap.String("session_id", msg.SessionId), zap.Error(err))
+		}
+	}
+}
+
+// open starts the shell of a new session
+func (m *shellManager) open(msg *pb.ShellMessage, send func(*pb.ShellMessage) error) {
+	m.mu.Lock()
+	if len(m.sessions) >= maxShellSessions {
+		m.mu.Unlock()
+		send(&pb.ShellMessage{SessionId: msg.SessionId, Close: true, ExitCode: -1, Error: "too many shell sessions on this minion"})
+		return
+	}
+
+	process, err := startShell(shellCommand(), msg.Rows, msg.Cols)
+	if err != nil {
+		m.mu.Unlock()
+		m.logger.Error("Failed to start shell", zap.String("session_id", msg.SessionId), zap.Error(err))
+		send(&pb.ShellMessage{SessionId: msg.SessionId, Close: true, ExitCode: -1, Error: err.Error()})
+		return
+	}
+	m.sessions[msg.SessionId] = process
+	m.mu.Unlock()
+
+	m.logger.Info("Shell session started",
+		zap.String("session_id", msg.SessionId),
+		zap.Int("pid", process.cmd.Process.Pid))
+
+	go m.pump(msg.SessionId, process, send)
+}
+
+// pump streams the output of a session until its shell exits
+func (m *shellManager) pump(sessionID string, process *shellProcess, send func(*pb.ShellMessage) error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := process.output.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			if sendErr := send(&pb.ShellMessage{SessionId: sessionID, Data: data}); sendErr != nil {
+				process.close()
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	exitCode := int32(0)
+	if err := process.cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			exitCode = -1
+		} else {
+			exitCode = int32(exitErr.ExitCode())
+		}
+	}
+	process.close()
+
+	m.mu.Lock()
+	delete(m.sessions, sessionID)
+	m.mu.Unlock()
+
+	m.logger.Info("Shell session ended", zap.String("session_id", sessionID), zap.Int32("exit_code", exitCode))
+	send(&pb.ShellMessage{SessionId: sessionID, Close: true, ExitCode: exitCode})
+}
+
+// closeAll ends all the sessions, their command stream being gone
+func (m *shellManager) closeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, process := range m.sessions {
+		process.close()
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !linux
+// +build !linux
+
+package minion
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// defaultShell is run by interactive sessions when SHELL is not set
+var defaultShell = func() string {
+	if runtime.GOOS == "windows" {
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}()
+
+// startShell runs shell with its input and output connected to pipes. Pseudo-terminals are only
+// allocated on Linux: elsewhere the shell has no terminal, so programs expecting one misbehave.
+func startShell(shell string, rows, cols uint32) (*shellProcess, error) {
+	cmd := exec.Command(shell)
+	if runtime.GOOS != "windows" {
+		cmd.Args = append(cmd.Args, "-i")
+	}
+
+	input, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	output, outputWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+
+	err = cmd.Start()
+	outputWriter.Close()
+	if err != nil {
+		output.Close()
+		return nil, fmt.Errorf("failed to start %s: %w", shell, err)
+	}
+
+	return &shellProcess{
+		cmd:    cmd,
+		input:  input,
+		output: output,
+		resize: func(rows, cols uint32) error { return nil },
+	}, nil
+}
@@ -0,0 +1,87 @@
+package minion
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestShellSession(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	manager := newShellManager(zap.NewNop())
+
+	sent := make(chan *pb.ShellMessage, 100)
+	send := func(msg *pb.ShellMessage) error {
+		sent <- msg
+		return nil
+	}
+
+	manager.handle(&pb.ShellMessage{SessionId: "s1", Open: true, Rows: 24, Cols: 80}, send)
+	manager.handle(&pb.ShellMessage{SessionId: "s1", Data: []byte("echo shell-$((40+2))\nexit 3\n")}, send)
+
+	var output strings.Builder
+	for {
+		select {
+		case msg := <-sent:
+			output.Write(msg.Data)
+			if !msg.Close {
+				continue
+			}
+			if msg.ExitCode != 3 {
+				t.Errorf("Expected exit code 3, got %d (%s)", msg.ExitCode, msg.Error)
+			}
+			if !strings.Contains(output.String(), "shell-42") {
+				t.Errorf("Expected command output, got %q", output.String())
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for the shell to exit, output: %q", output.String())
+		}
+	}
+}
+
+func TestShellSessionClose(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	manager := newShellManager(zap.NewNop())
+
+	sent := make(chan *pb.ShellMessage, 100)
+	send := func(msg *pb.ShellMessage) error {
+		sent <- msg
+		return nil
+	}
+
+	manager.handle(&pb.ShellMessage{SessionId: "s1", Open: true}, send)
+	manager.handle(&pb.ShellMessage{SessionId: "s1", Close: true}, send)
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-sent:
+			if msg.Close {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Closed session did not end")
+		}
+	}
+}
+
+func TestShellSessionLimit(t *testing.T) {
+	manager := newShellManager(zap.NewNop())
+	for i := 0; i < maxShellSessions; i++ {
+		manager.sessions[string(rune('a'+i))] = nil
+	}
+
+	var reply *pb.ShellMessage
+	manager.handle(&pb.ShellMessage{SessionId: "extra", Open: true}, func(msg *pb.ShellMessage) error {
+		reply = msg
+		return nil
+	})
+	if reply == nil || !reply.Close || reply.Error == "" {
+		t.Errorf("Expected session refused, got %v", reply)
+	}
+}
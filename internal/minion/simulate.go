@@ -0,0 +1,72 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// simulatedOSVersion is the OS version advertised by virtual minions
+const simulatedOSVersion = "simulated"
+
+// commandSimulator fakes the execution of commands on virtual minions used to load test Nexus:
+// commands succeed after a random latency without running anything on the host.
+type commandSimulator struct {
+	minLatency time.Duration
+	maxLatency time.Duration
+}
+
+// latency returns the execution time of a simulated command
+func (s *commandSimulator) latency() time.Duration {
+	if s.maxLatency <= s.minLatency {
+		return s.minLatency
+	}
+	return s.minLatency + time.Duration(rand.Int63n(int64(s.maxLatency-s.minLatency)+1))
+}
+
+// execute fakes the execution of cmd, failing it when ctx is done first
+func (s *commandSimulator) execute(ctx context.Context, cmd *pb.Command, minionID string) (*pb.CommandResult, error) {
+	latency := s.latency()
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return &pb.CommandResult{
+			CommandId: cmd.Id,
+			MinionId:  minionID,
+			Timestamp: time.Now().Unix(),
+			ExitCode:  1,
+			Stderr:    "simulated command interrupted",
+		}, ctx.Err()
+	case <-timer.C:
+	}
+
+	return &pb.CommandResult{
+		CommandId: cmd.Id,
+		MinionId:  minionID,
+		Timestamp: time.Now().Unix(),
+		ExitCode:  0,
+		Stdout:    fmt.Sprintf("simulated %s in %s\n", cmd.Payload, latency),
+	}, nil
+}
+
+// simulatedIP returns the address advertised by a virtual minion, in the 198.18.0.0/15 benchmarking range
+func simulatedIP(hostname string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(hostname))
+	sum := hash.Sum32()
+	return fmt.Sprintf("198.%d.%d.%d", 18+(sum>>16)&1, (sum>>8)&0xff, sum&0xff)
+}
+
+// EnableSimulation turns the minion into a virtual minion for load testing Nexus. It registers as
+// hostname with a fake address and no capability, and fakes its commands, which succeed after a
+// latency between minLatency and maxLatency. Interactive shells and file transfers are refused.
+func (m *Minion) EnableSimulation(hostname string, minLatency, maxLatency time.Duration) {
+	m.commandProcessor.(*commandProcessor).simulator = &commandSimulator{minLatency: minLatency, maxLatency: maxLatency}
+	m.registrationMgr.(*registrationManager).setSimulatedHost(hostname)
+}
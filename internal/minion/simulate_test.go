@@ -0,0 +1,54 @@
+package minion
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestSimulatedCommands(t *testing.T) {
+	m := NewMinion("sim-00001", nil, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	m.EnableSimulation("sim-00001", 20*time.Millisecond, 40*time.Millisecond)
+
+	begin := time.Now()
+	result, err := m.commandProcessor.Execute(context.Background(), &pb.Command{Id: "cmd-1", Payload: "rm -rf /tmp/minexus-simulation"})
+	elapsed := time.Since(begin)
+	if err != nil || result.ExitCode != 0 || result.MinionId != "sim-00001" || !strings.HasPrefix(result.Stdout, "simulated rm -rf") {
+		t.Fatalf("Unexpected simulated result: %v %v", result, err)
+	}
+	if elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the configured latency, command took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result, err := m.commandProcessor.Execute(ctx, &pb.Command{Id: "cmd-2", Payload: "uptime"}); err == nil || result.ExitCode != 1 {
+		t.Errorf("Expected an interrupted command, got %v %v", result, err)
+	}
+
+	simulator := &commandSimulator{minLatency: time.Second, maxLatency: time.Second}
+	if latency := simulator.latency(); latency != time.Second {
+		t.Errorf("Expected a fixed latency, got %s", latency)
+	}
+}
+
+func TestSimulatedHostInfo(t *testing.T) {
+	m := NewMinion("sim-00002", nil, time.Minute, time.Second, time.Second, time.Second, time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	m.EnableSimulation("sim-00002", 0, 0)
+
+	info, err := m.registrationMgr.(*registrationManager).createHostInfo()
+	if err != nil {
+		t.Fatalf("createHostInfo failed: %v", err)
+	}
+	if info.Hostname != "sim-00002" || info.OsVersion != simulatedOSVersion || len(info.MacAddresses) != 0 || len(info.Capabilities) != 0 {
+		t.Errorf("Unexpected simulated host info: %v", info)
+	}
+	if !strings.HasPrefix(info.Ip, "198.1") || info.Ip != simulatedIP("sim-00002") || info.Ip == simulatedIP("sim-00003") {
+		t.Errorf("Expected a stable address per virtual minion, got %s", info.Ip)
+	}
+}
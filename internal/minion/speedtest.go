@@ -0,0 +1,126 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// speedTestChunkSize is the size of the synthetic payloads uploaded by net:speedtest
+const speedTestChunkSize = 256 * 1024
+
+// speedTester runs net:speedtest over a SpeedTest stream opened with Nexus on the channel of the
+// command stream
+type speedTester struct {
+	minionID string
+	service  pb.MinionServiceClient
+}
+
+// SpeedTest implements command.SpeedTester
+func (t *speedTester) SpeedTest(ctx context.Context, pings int, size int64) (*command.SpeedTestResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := t.service.SpeedTest(ctx)
+	if err != nil {
+		return nil, speedTestError(err)
+	}
+	defer stream.CloseSend()
+
+	result := &command.SpeedTestResult{Pings: pings}
+	if err := t.measureLatency(stream, result); err != nil {
+		return nil, err
+	}
+
+	// Upload: timed until Nexus reports the bytes it received
+	payload := make([]byte, speedTestChunkSize)
+	start := time.Now()
+	for sent := int64(0); sent < size; sent += speedTestChunkSize {
+		if err := stream.Send(&pb.SpeedTestProbe{Data: payload[:min(int64(len(payload)), size-sent)]}); err != nil {
+			return nil, speedTestError(err)
+		}
+	}
+	if err := stream.Send(&pb.SpeedTestProbe{UploadDone: true}); err != nil {
+		return nil, speedTestError(err)
+	}
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			return nil, speedTestError(err)
+		}
+		if reply.UploadDone {
+			result.UploadBytes = reply.Received
+			break
+		}
+	}
+	result.UploadMbps = command.Mbps(result.UploadBytes, time.Since(start))
+
+	// Download: timed until the last chunk is received
+	start = time.Now()
+	if err := stream.Send(&pb.SpeedTestProbe{Download: size}); err != nil {
+		return nil, speedTestError(err)
+	}
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			return nil, speedTestError(err)
+		}
+		result.DownloadBytes += int64(len(reply.Data))
+		if reply.DownloadDone {
+			break
+		}
+	}
+	result.DownloadMbps = command.Mbps(result.DownloadBytes, time.Since(start))
+	return result, nil
+}
+
+// measureLatency sends the latency probes one at a time, timing their echo by Nexus
+func (t *speedTester) measureLatency(stream pb.MinionService_SpeedTestClient, result *command.SpeedTestResult) error {
+	var total time.Duration
+	for seq := 1; seq <= result.Pings; seq++ {
+		probe := &pb.SpeedTestProbe{Ping: int32(seq)}
+		if seq == 1 {
+			probe.MinionId = t.minionID
+		}
+		start := time.Now()
+		if err := stream.Send(probe); err != nil {
+			return speedTestError(err)
+		}
+		reply, err := stream.Recv()
+		if err != nil {
+			return speedTestError(err)
+		}
+		if reply.Ping != int32(seq) {
+			return fmt.Errorf("unexpected reply to latency probe %d", seq)
+		}
+
+		elapsed := time.Since(start)
+		rtt := float64(elapsed) / float64(time.Millisecond)
+		if seq == 1 || rtt < result.LatencyMinMs {
+			result.LatencyMinMs = rtt
+		}
+		result.LatencyMaxMs = max(result.LatencyMaxMs, rtt)
+		total += elapsed
+	}
+	if result.Pings > 0 {
+		result.LatencyAvgMs = float64(total) / float64(time.Millisecond) / float64(result.Pings)
+	}
+	return nil
+}
+
+// speedTestError explains the stream errors of a speed test
+func speedTestError(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("nexus closed the speed test")
+	}
+	if status.Code(err) == codes.Unimplemented {
+		return fmt.Errorf("%w: %s", command.ErrSpeedTestUnavailable, status.Convert(err).Message())
+	}
+	return err
+}
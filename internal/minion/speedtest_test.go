@@ -0,0 +1,91 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeSpeedTestStream answers a speed test like Nexus, queuing its replies
+type fakeSpeedTestStream struct {
+	grpc.ClientStream
+	received int64
+	replies  []*pb.SpeedTestProbe
+	minionID string
+}
+
+func (f *fakeSpeedTestStream) Send(msg *pb.SpeedTestProbe) error {
+	if msg.MinionId != "" {
+		f.minionID = msg.MinionId
+	}
+	switch {
+	case msg.Ping > 0:
+		f.replies = append(f.replies, &pb.SpeedTestProbe{Ping: msg.Ping})
+	case msg.UploadDone:
+		f.replies = append(f.replies, &pb.SpeedTestProbe{UploadDone: true, Received: f.received})
+	case msg.Download > 0:
+		for sent := int64(0); sent < msg.Download; sent += 1000 {
+			f.replies = append(f.replies, &pb.SpeedTestProbe{Data: make([]byte, min(1000, msg.Download-sent)), DownloadDone: sent+1000 >= msg.Download})
+		}
+	default:
+		f.received += int64(len(msg.Data))
+	}
+	return nil
+}
+
+func (f *fakeSpeedTestStream) Recv() (*pb.SpeedTestProbe, error) {
+	if len(f.replies) == 0 {
+		return nil, io.EOF
+	}
+	reply := f.replies[0]
+	f.replies = f.replies[1:]
+	return reply, nil
+}
+
+func (f *fakeSpeedTestStream) CloseSend() error {
+	return nil
+}
+
+// speedTestService opens the fake speed test stream, or fails with err
+type speedTestService struct {
+	mockMinionServiceClient
+	stream *fakeSpeedTestStream
+	err    error
+}
+
+func (s *speedTestService) SpeedTest(_ context.Context, _ ...grpc.CallOption) (pb.MinionService_SpeedTestClient, error) {
+	return s.stream, s.err
+}
+
+func TestSpeedTester(t *testing.T) {
+	service := &speedTestService{stream: &fakeSpeedTestStream{}}
+	tester := &speedTester{minionID: "minion-1", service: service}
+
+	result, err := tester.SpeedTest(context.Background(), 3, 600*1024)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if service.stream.minionID != "minion-1" {
+		t.Errorf("Expected the minion ID sent with the first probe, got %q", service.stream.minionID)
+	}
+	if result.Pings != 3 || result.LatencyMinMs > result.LatencyMaxMs || result.LatencyAvgMs <= 0 {
+		t.Errorf("Unexpected latency: %+v", result)
+	}
+	if result.UploadBytes != 600*1024 || result.DownloadBytes != 600*1024 || result.UploadMbps <= 0 || result.DownloadMbps <= 0 {
+		t.Errorf("Unexpected throughput: %+v", result)
+	}
+
+	// Relays and HTTP long polling don't answer speed tests
+	service.err = status.Error(codes.Unimplemented, "method SpeedTest not implemented")
+	if _, err := tester.SpeedTest(context.Background(), 1, 1024); !errors.Is(err, command.ErrSpeedTestUnavailable) {
+		t.Errorf("Expected the speed test unavailable, got %v", err)
+	}
+}
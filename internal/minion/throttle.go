@@ -0,0 +1,136 @@
+package minion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultMaxFileTransfers bounds the number of files transferred at once by a minion
+const defaultMaxFileTransfers = 4
+
+// errTooManyTransfers rejects a transfer when the minion runs max_file_transfers already
+var errTooManyTransfers = errors.New("too many file transfers on this minion")
+
+// transferThrottle caps the file transfers of a minion, file-pull transfers and file:get reads
+// alike, so that distributing files to the fleet doesn't saturate the network of the hosts. Bytes
+// are paced: each WaitN pushes back the time the next bytes may go by their duration at the
+// configured bandwidth, shared by all transfers.
+type transferThrottle struct {
+	mu             sync.Mutex
+	bytesPerSecond int // 0: unlimited
+	maxTransfers   int // 0: unlimited
+	active         int
+	next           time.Time // when the bytes paced so far are transferred
+
+	transferred int64         // bytes paced since the minion started
+	delayed     time.Duration // time transfers waited for bandwidth since the minion started
+}
+
+// newTransferThrottle creates a throttle with limits, 0 meaning unlimited
+func newTransferThrottle(bytesPerSecond, maxTransfers int) *transferThrottle {
+	return &transferThrottle{bytesPerSecond: bytesPerSecond, maxTransfers: maxTransfers}
+}
+
+// Begin implements command.TransferThrottle
+func (t *transferThrottle) Begin() (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.maxTransfers > 0 && t.active >= t.maxTransfers {
+		return nil, errTooManyTransfers
+	}
+	t.active++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.active--
+			t.mu.Unlock()
+		})
+	}, nil
+}
+
+// WaitN implements command.TransferThrottle. A cancelled wait gives its bytes back to the other
+// transfers.
+func (t *transferThrottle) WaitN(ctx context.Context, n int) error {
+	t.mu.Lock()
+	t.transferred += int64(n)
+	if t.bytesPerSecond <= 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	wait := t.next.Sub(now)
+	reserved := time.Duration(n) * time.Second / time.Duration(t.bytesPerSecond)
+	t.next = t.next.Add(reserved)
+	t.delayed += wait
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		t.transferred -= int64(n)
+		t.delayed -= time.Until(now.Add(wait))
+		t.next = t.next.Add(-reserved)
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// limits returns the bandwidth in bytes per second and the concurrent transfers allowed
+func (t *transferThrottle) limits() (int, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bytesPerSecond, t.maxTransfers
+}
+
+// setBandwidth changes the bytes per second of the transfers, 0 for unlimited
+func (t *transferThrottle) setBandwidth(bytesPerSecond int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bytesPerSecond = bytesPerSecond
+	t.next = time.Time{}
+}
+
+// setMaxTransfers changes the transfers allowed at the same time, 0 for unlimited. Running
+// transfers above the new limit complete.
+func (t *transferThrottle) setMaxTransfers(maxTransfers int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxTransfers = maxTransfers
+}
+
+// state describes the current transfers and how much they were throttled
+func (t *transferThrottle) state() (active string, bandwidth string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active = fmt.Sprintf("%d running", t.active)
+	bandwidth = fmt.Sprintf("%d bytes transferred, delayed %s", t.transferred, t.delayed.Round(time.Millisecond))
+	if t.bytesPerSecond > 0 && t.next.After(time.Now()) {
+		bandwidth = "throttling, " + bandwidth
+	}
+	return active, bandwidth
+}
+
+// EnableTransferThrottle caps the file transfers of the minion: the bytes per second they read all
+// together and how many run at the same time, 0 meaning unlimited. config:set changes both limits.
+func (m *Minion) EnableTransferThrottle(bytesPerSecond, maxTransfers int) {
+	throttle := m.commandProcessor.(*commandProcessor).transfers
+	throttle.setBandwidth(bytesPerSecond)
+	throttle.setMaxTransfers(maxTransfers)
+}
@@ -0,0 +1,114 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+)
+
+func TestTransferThrottle(t *testing.T) {
+	throttle := newTransferThrottle(0, 2)
+	end1, err1 := throttle.Begin()
+	end2, err2 := throttle.Begin()
+	if err1 != nil || err2 != nil {
+		t.Fatalf("Expected 2 transfers allowed, got %v %v", err1, err2)
+	}
+	if _, err := throttle.Begin(); !errors.Is(err, errTooManyTransfers) {
+		t.Errorf("Expected a third transfer rejected, got %v", err)
+	}
+	end1()
+	end1() // released once
+	end3, err := throttle.Begin()
+	if err != nil {
+		t.Fatalf("Expected a released slot reused, got %v", err)
+	}
+	if _, err := throttle.Begin(); err == nil {
+		t.Error("Expected releasing a transfer twice not to free two slots")
+	}
+	end2()
+	end3()
+
+	// 10 KB at 100 KB/s take about 100ms, whatever the transfers reading them
+	throttle.setBandwidth(100 * 1024)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if err := throttle.WaitN(context.Background(), 1024); err != nil {
+			t.Fatalf("WaitN failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected 10 KB paced over about 100ms, took %s", elapsed)
+	}
+	if _, bandwidth := throttle.state(); !strings.Contains(bandwidth, "10240 bytes transferred") {
+		t.Errorf("Unexpected bandwidth state: %s", bandwidth)
+	}
+
+	// A cancelled transfer stops waiting and gives its bytes back to the others
+	throttle.setBandwidth(1)
+	throttle.WaitN(context.Background(), 10)
+	next := throttle.next
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := throttle.WaitN(ctx, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the wait cancelled, got %v", err)
+	}
+	if !throttle.next.Equal(next) {
+		t.Errorf("Expected the cancelled bytes not to delay the next transfers, next moved by %s", throttle.next.Sub(next))
+	}
+	if _, bandwidth := throttle.state(); !strings.Contains(bandwidth, "10250 bytes transferred") {
+		t.Errorf("Expected the cancelled bytes not counted, got %s", bandwidth)
+	}
+}
+
+func TestTransferThrottleSettings(t *testing.T) {
+	m := newSettingsTestMinion(t, "")
+	m.EnableTransferThrottle(0, 1)
+
+	runSettingsCommand(t, m, "config:set transfer_bandwidth 1048576")
+	if bandwidth, maxTransfers := m.commandProcessor.(*commandProcessor).transfers.limits(); bandwidth != 1048576 || maxTransfers != 1 {
+		t.Errorf("Expected the bandwidth changed alone, got %d %d", bandwidth, maxTransfers)
+	}
+	for _, payload := range []string{"config:set transfer_bandwidth fast", "config:set transfer_bandwidth 2147483648", "config:set max_file_transfers -1"} {
+		if result := runSettingsCommand(t, m, payload); result.ExitCode == 0 {
+			t.Errorf("Expected %q to fail", payload)
+		}
+	}
+
+	// A running transfer shows in config:show and keeps file:get from starting another one
+	end, err := m.commandProcessor.(*commandProcessor).transfers.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "motd")
+	if err := os.WriteFile(path, []byte("welcome"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if result := runSettingsCommand(t, m, "file:get "+path); result.ExitCode == 0 || !strings.Contains(result.Stderr, "too many file transfers") {
+		t.Errorf("Expected file:get rejected, got %+v", result)
+	}
+
+	result := runSettingsCommand(t, m, "config:show")
+	var settings []command.Setting
+	if err := json.Unmarshal([]byte(result.Structured), &settings); err != nil {
+		t.Fatalf("Invalid config:show payload: %v", err)
+	}
+	states := make(map[string]string)
+	for _, setting := range settings {
+		states[setting.Key] = setting.State
+	}
+	if states["max_file_transfers"] != "1 running" || !strings.Contains(states["transfer_bandwidth"], "bytes transferred") {
+		t.Errorf("Expected the transfer state in config:show, got %v", states)
+	}
+
+	end()
+	if result := runSettingsCommand(t, m, "file:get "+path); result.ExitCode != 0 || !strings.Contains(result.Stdout, `"size":7`) {
+		t.Errorf("Expected file:get to read the file once the transfer ended, got %+v", result)
+	}
+}
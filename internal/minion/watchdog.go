@@ -0,0 +1,240 @@
+package minion
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/arhuman/minexus/internal/logging"
+)
+
+const (
+	// watchdogInterval is the interval between two checks of the resource usage of the minion
+	watchdogInterval = 10 * time.Second
+
+	// leakChecks is the number of consecutive checks the memory must stay over its limit,
+	// after returning its free memory to the system, for the minion to consider it leaked
+	leakChecks = 6
+)
+
+// WatchdogLimits are the resource limits the minion enforces on itself, zero meaning unlimited
+type WatchdogLimits struct {
+	MaxMemoryMB           int // memory of the minion process
+	MaxCPUPercent         int // CPU usage of the minion process, in percent of one core
+	MaxConcurrentCommands int // commands executing at the same time
+}
+
+// enabled reports whether at least one limit is set
+func (l WatchdogLimits) enabled() bool {
+	return l.MaxMemoryMB > 0 || l.MaxCPUPercent > 0 || l.MaxConcurrentCommands > 0
+}
+
+// resourceUsage is the resource usage of the minion process
+type resourceUsage struct {
+	memory uint64        // bytes obtained from the system and not returned to it
+	cpu    time.Duration // CPU time consumed since the process started
+}
+
+// sampleResourceUsage returns the current resource usage of the minion process
+func sampleResourceUsage() resourceUsage {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return resourceUsage{
+		memory: stats.Sys - stats.HeapReleased,
+		cpu:    processCPUTime(),
+	}
+}
+
+// watchdog monitors the resource usage of the minion. It rejects new commands while the
+// minion is over its limits, and requests a restart when its memory stays over its limit,
+// which is a leak.
+type watchdog struct {
+	limits   WatchdogLimits
+	interval time.Duration
+	sample   func() resourceUsage
+	logger   *zap.Logger
+
+	restart     chan struct{} // closed once a restart is requested
+	restartOnce sync.Once
+
+	mu         sync.Mutex
+	running    int    // commands executing
+	overload   string // reason new commands are rejected, empty if none
+	overMemory int    // consecutive checks over the memory limit
+	lastCPU    time.Duration
+	lastCheck  time.Time
+}
+
+// newWatchdog creates a watchdog enforcing limits
+func newWatchdog(limits WatchdogLimits, logger *zap.Logger) *watchdog {
+	return &watchdog{
+		limits:   limits,
+		interval: watchdogInterval,
+		sample:   sampleResourceUsage,
+		logger:   logger,
+		restart:  make(chan struct{}),
+	}
+}
+
+// admit reserves a command execution, failing when the minion is over its limits. release
+// must be called once the command ended.
+func (w *watchdog) admit() (release func(), err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.overload != "" {
+		return nil, fmt.Errorf("minion overloaded: %s", w.overload)
+	}
+	if w.limits.MaxConcurrentCommands > 0 && w.running >= w.limits.MaxConcurrentCommands {
+		return nil, fmt.Errorf("minion overloaded: %d commands already executing", w.running)
+	}
+
+	w.running++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.mu.Lock()
+			w.running--
+			w.mu.Unlock()
+		})
+	}, nil
+}
+
+// overloaded returns an error when the minion is over its resource limits
+func (w *watchdog) overloaded() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.overload != "" {
+		return fmt.Errorf("minion overloaded: %s", w.overload)
+	}
+	return nil
+}
+
+// getLimits returns the limits the watchdog enforces
+func (w *watchdog) getLimits() WatchdogLimits {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.limits
+}
+
+// setLimits changes the limits the watchdog enforces, taking effect at the next check
+func (w *watchdog) setLimits(limits WatchdogLimits) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.limits = limits
+	w.overMemory = 0
+}
+
+// Run checks the resource usage of the minion at every interval until ctx is cancelled
+func (w *watchdog) Run(ctx context.Context) {
+	logger, start := logging.FuncLogger(w.logger, "watchdog.Run")
+	defer logging.FuncExit(logger, start)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.check(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check samples the resource usage and updates the overload state of the minion
+func (w *watchdog) check(now time.Time) {
+	usage := w.sample()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var reasons []string
+
+	if w.limits.MaxMemoryMB > 0 {
+		limit := uint64(w.limits.MaxMemoryMB) * 1024 * 1024
+		if usage.memory > limit {
+			// Memory the runtime kept after commands ended is not a leak
+			debug.FreeOSMemory()
+			usage.memory = w.sample().memory
+		}
+		if usage.memory > limit {
+			w.overMemory++
+			reasons = append(reasons, fmt.Sprintf("memory %d MB over limit %d MB", usage.memory/(1024*1024), w.limits.MaxMemoryMB))
+		} else {
+			w.overMemory = 0
+		}
+	}
+
+	if w.limits.MaxCPUPercent > 0 && !w.lastCheck.IsZero() {
+		if elapsed := now.Sub(w.lastCheck); elapsed > 0 {
+			percent := int(100 * (usage.cpu - w.lastCPU) / elapsed)
+			if percent > w.limits.MaxCPUPercent {
+				reasons = append(reasons, fmt.Sprintf("CPU %d%% over limit %d%%", percent, w.limits.MaxCPUPercent))
+			}
+		}
+	}
+	w.lastCPU = usage.cpu
+	w.lastCheck = now
+
+	overload := strings.Join(reasons, ", ")
+	if overload != "" && w.overload == "" {
+		w.logger.Warn("Minion over its resource limits, rejecting new commands", zap.String("reason", overload))
+	} else if overload == "" && w.overload != "" {
+		w.logger.Info("Minion back under its resource limits, accepting commands")
+	}
+	w.overload = overload
+
+	if w.overMemory >= leakChecks {
+		w.restartOnce.Do(func() {
+			w.logger.Error("Memory stayed over its limit, restarting minion",
+				zap.Uint64("memory", usage.memory),
+				zap.Int("max_memory_mb", w.limits.MaxMemoryMB),
+				zap.Int("checks", w.overMemory))
+			close(w.restart)
+		})
+	}
+}
+
+// EnableWatchdog makes the minion enforce limits on its own resource usage: commands are
+// rejected while it is over them, and a restart is requested when its memory leaks
+func (m *Minion) EnableWatchdog(limits WatchdogLimits) {
+	if !limits.enabled() {
+		return
+	}
+	m.watchdog = newWatchdog(limits, m.logger)
+	m.commandProcessor.(*commandProcessor).watchdog = m.watchdog
+}
+
+// RestartRequested returns a channel closed when the watchdog requests a restart of the
+// minion, never closed when the watchdog is disabled
+func (m *Minion) RestartRequested() <-chan struct{} {
+	if m.watchdog == nil {
+		return nil
+	}
+	return m.watchdog.restart
+}
+
+// runWatchdog runs the watchdog until the minion stops
+func (m *Minion) runWatchdog(ctx context.Context) {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.done:
+			cancel()
+		case <-cancelCtx.Done():
+		}
+	}()
+
+	m.watchdog.Run(cancelCtx)
+}
@@ -0,0 +1,121 @@
+package minion
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestWatchdogConcurrentCommands(t *testing.T) {
+	w := newWatchdog(WatchdogLimits{MaxConcurrentCommands: 2}, zap.NewNop())
+
+	first, err := w.admit()
+	if err != nil {
+		t.Fatalf("First command rejected: %v", err)
+	}
+	if _, err := w.admit(); err != nil {
+		t.Fatalf("Second command rejected: %v", err)
+	}
+	if _, err := w.admit(); err == nil {
+		t.Fatal("Expected the third concurrent command to be rejected")
+	}
+
+	// Releasing twice must not free two slots
+	first()
+	first()
+	if _, err := w.admit(); err != nil {
+		t.Fatalf("Command rejected once a slot was released: %v", err)
+	}
+	if _, err := w.admit(); err == nil {
+		t.Error("Expected a command to be rejected with all slots in use")
+	}
+}
+
+func TestWatchdogResourceLimits(t *testing.T) {
+	usage := resourceUsage{memory: 10 * 1024 * 1024}
+	w := newWatchdog(WatchdogLimits{MaxMemoryMB: 64, MaxCPUPercent: 50}, zap.NewNop())
+	w.sample = func() resourceUsage { return usage }
+
+	now := time.Now()
+	w.check(now)
+	if err := w.overloaded(); err != nil {
+		t.Fatalf("Unexpected overload: %v", err)
+	}
+
+	// 8 seconds of CPU over 10 seconds is 80% of one core
+	usage.cpu = 8 * time.Second
+	now = now.Add(10 * time.Second)
+	w.check(now)
+	if _, err := w.admit(); err == nil || !strings.Contains(err.Error(), "CPU 80% over limit 50%") {
+		t.Fatalf("Expected commands to be rejected over the CPU limit, got %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	w.check(now)
+	if err := w.overloaded(); err != nil {
+		t.Fatalf("Expected commands to be accepted once the CPU is idle, got %v", err)
+	}
+
+	// Memory staying over its limit is a leak
+	usage.memory = 100 * 1024 * 1024
+	for i := 1; i < leakChecks; i++ {
+		now = now.Add(10 * time.Second)
+		w.check(now)
+		if err := w.overloaded(); err == nil || !strings.Contains(err.Error(), "memory 100 MB over limit 64 MB") {
+			t.Fatalf("Expected commands to be rejected over the memory limit, got %v", err)
+		}
+	}
+	select {
+	case <-w.restart:
+		t.Fatal("Restart requested before the memory was considered leaked")
+	default:
+	}
+
+	w.check(now.Add(10 * time.Second))
+	select {
+	case <-w.restart:
+	default:
+		t.Fatal("Expected a restart once the memory stayed over its limit")
+	}
+}
+
+func TestWatchdogRejectsCommands(t *testing.T) {
+	minion := NewMinion("test-minion", &mockMinionServiceClient{}, time.Hour, time.Hour, time.Hour, 15*time.Second, 30*time.Second, zap.NewNop(), zap.NewAtomicLevel())
+	if minion.RestartRequested() != nil {
+		t.Error("Expected no restart channel without watchdog")
+	}
+	minion.EnableWatchdog(WatchdogLimits{MaxMemoryMB: 1})
+	minion.watchdog.sample = func() resourceUsage { return resourceUsage{memory: 2 * 1024 * 1024} }
+	minion.watchdog.check(time.Now())
+
+	processor := minion.commandProcessor.(*commandProcessor)
+	stream := &mockStreamCommandsClient{}
+	msg := &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Command{Command: &pb.Command{Id: "cmd-1", Type: pb.CommandType_SYSTEM, Payload: "system:os"}}}
+	if err := processor.processReceivedMessage(context.Background(), msg, stream, zap.NewNop(), time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	shell := &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Shell{Shell: &pb.ShellMessage{SessionId: "s1", Open: true}}}
+	processor.processReceivedMessage(context.Background(), shell, stream, zap.NewNop(), time.Now())
+
+	var result *pb.CommandResult
+	var shellReply *pb.ShellMessage
+	for _, msg := range stream.sendMsgs {
+		if r := msg.GetResult(); r != nil {
+			result = r
+		}
+		if reply := msg.GetShell(); reply != nil {
+			shellReply = reply
+		}
+	}
+	if result == nil || result.ExitCode == 0 || !strings.Contains(result.Stderr, "minion overloaded") {
+		t.Errorf("Expected the command to be rejected, got %v", result)
+	}
+	if shellReply == nil || !shellReply.Close || !strings.Contains(shellReply.Error, "minion overloaded") {
+		t.Errorf("Expected the shell session to be refused, got %v", shellReply)
+	}
+}
@@ -0,0 +1,411 @@
+package minion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxWatchRules bounds the number of watch rules a minion accepts
+	maxWatchRules = 100
+	// defaultWatchDebounce is how long a path must stay unchanged before its command runs,
+	// and how long changes are ignored after the command ran
+	defaultWatchDebounce = 2 * time.Second
+)
+
+// watchRule is a watch rule with its pending and running state
+type watchRule struct {
+	command.WatchRule
+	dir        string      // directory watched for the rule
+	timer      *time.Timer // pending run, nil when no change is pending
+	event      string      // last change seen, passed to the pending run
+	running    bool
+	quietUntil time.Time // changes are ignored until then, after a run
+}
+
+// matches reports whether a change of name concerns the rule: the watched file itself,
+// or the watched directory and its entries
+func (r *watchRule) matches(name string) bool {
+	return name == r.Path || (r.dir == r.Path && filepath.Dir(name) == r.Path)
+}
+
+// Watcher runs commands on the minion when watched files or directories change, independently
+// of Nexus. It implements command.FileWatcher.
+type Watcher struct {
+	mu       sync.Mutex
+	rules    map[string]*watchRule
+	dirs     map[string]int // watched directories with the number of rules using them
+	file     string         // empty: rules are kept in memory only
+	run      TaskRunner
+	report   func(result *pb.CommandResult)
+	logger   *zap.Logger
+	notify   *fsnotify.Watcher
+	debounce time.Duration
+	ctx      context.Context // context of Run, nil until it starts and once it stopped
+	wg       sync.WaitGroup
+}
+
+// NewWatcher creates a watcher persisting its rules to file, loading the rules already saved there.
+// Command results are passed to report.
+func NewWatcher(file string, run TaskRunner, report func(result *pb.CommandResult), logger *zap.Logger) (*Watcher, error) {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	w := &Watcher{
+		rules:    make(map[string]*watchRule),
+		dirs:     make(map[string]int),
+		file:     file,
+		run:      run,
+		report:   report,
+		logger:   logger,
+		notify:   notify,
+		debounce: defaultWatchDebounce,
+	}
+	if err := w.load(); err != nil {
+		notify.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Add runs payload whenever path changes. A directory is watched with its entries, without its
+// subdirectories; a file is watched through its directory so that replacing it is seen too.
+func (w *Watcher) Add(path, payload string) (*command.WatchRule, error) {
+	if payload == "" {
+		return nil, errors.New("command cannot be empty")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.rules) >= maxWatchRules {
+		return nil, fmt.Errorf("too many watch rules (maximum %d)", maxWatchRules)
+	}
+
+	rule := &watchRule{
+		WatchRule: command.WatchRule{
+			ID:        generateTaskID(),
+			Path:      filepath.Clean(path),
+			Payload:   payload,
+			CreatedAt: time.Now().Unix(),
+		},
+	}
+	if err := w.watchLocked(rule); err != nil {
+		return nil, err
+	}
+	w.rules[rule.ID] = rule
+
+	if err := w.saveLocked(); err != nil {
+		delete(w.rules, rule.ID)
+		w.unwatchLocked(rule)
+		return nil, err
+	}
+
+	w.logger.Info("Watch rule added",
+		zap.String("watch_id", rule.ID),
+		zap.String("path", rule.Path),
+		zap.String("payload", payload))
+	added := rule.WatchRule
+	return &added, nil
+}
+
+// List returns the watch rules ordered by creation
+func (w *Watcher) List() []command.WatchRule {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rules := make([]command.WatchRule, 0, len(w.rules))
+	for _, rule := range w.rules {
+		rules = append(rules, rule.WatchRule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].CreatedAt != rules[j].CreatedAt {
+			return rules[i].CreatedAt < rules[j].CreatedAt
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// Remove removes a watch rule, cancelling its pending run
+func (w *Watcher) Remove(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rule, exists := w.rules[id]
+	if !exists {
+		return fmt.Errorf("watch rule %s not found", id)
+	}
+	delete(w.rules, id)
+
+	if err := w.saveLocked(); err != nil {
+		w.rules[id] = rule
+		return err
+	}
+
+	if rule.timer != nil {
+		rule.timer.Stop()
+	}
+	w.unwatchLocked(rule)
+	w.logger.Info("Watch rule removed", zap.String("watch_id", id))
+	return nil
+}
+
+// Run processes file changes until ctx is cancelled, then waits for running commands to finish
+func (w *Watcher) Run(ctx context.Context) {
+	logger, start := logging.FuncLogger(w.logger, "Watcher.Run")
+	defer logging.FuncExit(logger, start)
+
+	w.mu.Lock()
+	w.ctx = ctx
+	w.mu.Unlock()
+
+	defer w.notify.Close()
+	defer w.wg.Wait()
+	defer w.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.notify.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.notify.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("File watcher error", zap.Error(err))
+		}
+	}
+}
+
+// stop cancels the pending runs, no run starts afterwards
+func (w *Watcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.ctx = nil
+	for _, rule := range w.rules {
+		if rule.timer != nil {
+			rule.timer.Stop()
+			rule.timer = nil
+		}
+	}
+}
+
+// handle schedules the run of the rules concerned by a change, once the path stays unchanged
+// for the debounce delay. Changes made while the command runs or right after are ignored, so that
+// a command restoring the file doesn't trigger itself.
+func (w *Watcher) handle(event fsnotify.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	for _, rule := range w.rules {
+		if !rule.matches(event.Name) || rule.running || now.Before(rule.quietUntil) {
+			continue
+		}
+		rule.event = fmt.Sprintf("%s %s", event.Op, event.Name)
+		if rule.timer != nil {
+			rule.timer.Reset(w.debounce)
+			continue
+		}
+		id := rule.ID
+		rule.timer = time.AfterFunc(w.debounce, func() { w.fire(id) })
+	}
+}
+
+// fire starts the pending run of a rule
+func (w *Watcher) fire(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	rule, exists := w.rules[id]
+	if !exists || w.ctx == nil || rule.timer == nil {
+		return
+	}
+	rule.timer = nil
+	rule.running = true
+	w.wg.Add(1)
+	go w.execute(w.ctx, id, rule.Payload, rule.event)
+}
+
+// execute runs the command of a watch rule and reports its result
+func (w *Watcher) execute(ctx context.Context, id, payload, event string) {
+	defer w.wg.Done()
+
+	at := time.Now()
+	cmd := &pb.Command{
+		Id:      fmt.Sprintf("watch-%s-%d", id, at.UnixMilli()),
+		Type:    pb.CommandType_SYSTEM,
+		Payload: payload,
+	}
+	result := w.run(ctx, cmd)
+	result.CommandId = cmd.Id
+	result.WatchId = id
+	result.WatchEvent = event
+	result.Payload = payload
+
+	w.logger.Info("Watch rule triggered",
+		zap.String("watch_id", id),
+		zap.String("event", event),
+		zap.String("command_id", cmd.Id),
+		zap.Int32("exit_code", result.ExitCode))
+
+	w.mu.Lock()
+	if rule, exists := w.rules[id]; exists {
+		rule.running = false
+		rule.quietUntil = time.Now().Add(w.debounce)
+		rule.LastEvent = event
+		rule.LastRun = at.Unix()
+		rule.LastCommandID = cmd.Id
+		rule.LastExitCode = result.ExitCode
+		if err := w.saveLocked(); err != nil {
+			w.logger.Warn("Failed to persist watch rule state", zap.String("watch_id", id), zap.Error(err))
+		}
+	}
+	w.mu.Unlock()
+
+	w.report(result)
+}
+
+// watchLocked watches the directory of a rule: the path itself for directories, its parent
+// otherwise. w.mu must be held.
+func (w *Watcher) watchLocked(rule *watchRule) error {
+	if !filepath.IsAbs(rule.Path) {
+		return fmt.Errorf("path %s must be absolute", rule.Path)
+	}
+	rule.dir = filepath.Dir(rule.Path)
+	if info, err := os.Stat(rule.Path); err == nil && info.IsDir() {
+		rule.dir = rule.Path
+	}
+
+	if w.dirs[rule.dir] == 0 {
+		if err := w.notify.Add(rule.dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", rule.dir, err)
+		}
+	}
+	w.dirs[rule.dir]++
+	return nil
+}
+
+// unwatchLocked stops watching the directory of a rule once no other rule uses it. w.mu must be held.
+func (w *Watcher) unwatchLocked(rule *watchRule) {
+	if w.dirs[rule.dir] == 0 {
+		return
+	}
+	w.dirs[rule.dir]--
+	if w.dirs[rule.dir] == 0 {
+		delete(w.dirs, rule.dir)
+		_ = w.notify.Remove(rule.dir)
+	}
+}
+
+// load reads the rules saved in the watch file, a missing file meaning no rule. Rules whose
+// path can't be watched are kept, unwatched, so that they are not lost.
+func (w *Watcher) load() error {
+	if w.file == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(w.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read watch file: %w", err)
+	}
+
+	var rules []command.WatchRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("invalid watch file %s: %w", w.file, err)
+	}
+
+	for _, saved := range rules {
+		rule := &watchRule{WatchRule: saved}
+		if err := w.watchLocked(rule); err != nil {
+			w.logger.Warn("Watch rule not active", zap.String("watch_id", rule.ID), zap.Error(err))
+		}
+		w.rules[rule.ID] = rule
+	}
+
+	w.logger.Info("Watch rules loaded", zap.String("file", w.file), zap.Int("count", len(rules)))
+	return nil
+}
+
+// saveLocked atomically writes the rules to the watch file, w.mu must be held
+func (w *Watcher) saveLocked() error {
+	if w.file == "" {
+		return nil
+	}
+
+	rules := make([]command.WatchRule, 0, len(w.rules))
+	for _, rule := range w.rules {
+		rules = append(rules, rule.WatchRule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode watch rules: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(w.file), ".watch-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save watch rules: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save watch rules: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save watch rules: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), w.file); err != nil {
+		return fmt.Errorf("failed to save watch rules: %w", err)
+	}
+	return nil
+}
+
+// EnableWatcher enables the watch: commands, persisting rules to file (empty: in memory only).
+// Watch rules run their commands when the minion starts, even while it is disconnected from Nexus;
+// their results are buffered and reported to Nexus once connected.
+func (m *Minion) EnableWatcher(file string) error {
+	processor := m.commandProcessor.(*commandProcessor)
+	run := func(ctx context.Context, cmd *pb.Command) *pb.CommandResult {
+		result, _ := processor.Execute(ctx, cmd)
+		return result
+	}
+
+	watcher, err := NewWatcher(file, run, processor.queueResult, m.logger)
+	if err != nil {
+		return err
+	}
+
+	m.watcher = watcher
+	m.registry.Register(command.NewWatchAddCommand(watcher))
+	m.registry.Register(command.NewWatchListCommand(watcher))
+	m.registry.Register(command.NewWatchRemoveCommand(watcher))
+	return nil
+}
@@ -0,0 +1,138 @@
+package minion
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+)
+
+func TestWatcherPersistence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "watch.json")
+	run := func(ctx context.Context, cmd *pb.Command) *pb.CommandResult {
+		return &pb.CommandResult{}
+	}
+	report := func(result *pb.CommandResult) {}
+
+	watcher, err := NewWatcher(file, run, report, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer watcher.notify.Close()
+
+	if _, err := watcher.Add("relative/path", "uptime"); err == nil {
+		t.Error("Expected a relative path to be rejected")
+	}
+	if _, err := watcher.Add(filepath.Join(dir, "missing", "app.conf"), "uptime"); err == nil {
+		t.Error("Expected a path in a missing directory to be rejected")
+	}
+	first, err := watcher.Add(filepath.Join(dir, "app.conf"), "file:get /etc/hosts")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	second, err := watcher.Add(dir, "df -h")
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if watcher.dirs[dir] != 2 {
+		t.Errorf("Expected both rules to watch %s, got %v", dir, watcher.dirs)
+	}
+	if err := watcher.Remove(first.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := watcher.Remove(first.ID); err == nil {
+		t.Error("Expected removing an unknown rule to fail")
+	}
+
+	// Rules survive a restart
+	reloaded, err := NewWatcher(file, run, report, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Failed to reload watcher: %v", err)
+	}
+	defer reloaded.notify.Close()
+	rules := reloaded.List()
+	if len(rules) != 1 || rules[0].ID != second.ID || rules[0].Path != dir || rules[0].Payload != "df -h" {
+		t.Fatalf("Unexpected reloaded rules: %+v", rules)
+	}
+}
+
+func TestWatcherRunsCommandOnChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.conf")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := make(chan *pb.Command, 10)
+	reported := make(chan *pb.CommandResult, 10)
+	run := func(ctx context.Context, cmd *pb.Command) *pb.CommandResult {
+		runs <- cmd
+		// The command restores the file, which must not trigger it again
+		_ = os.WriteFile(path, []byte("original"), 0644)
+		return &pb.CommandResult{MinionId: "minion-1", Stdout: "restored"}
+	}
+	report := func(result *pb.CommandResult) { reported <- result }
+
+	watcher, err := NewWatcher("", run, report, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	watcher.debounce = 50 * time.Millisecond
+	rule, err := watcher.Add(path, "file:copy /opt/golden/app.conf "+path)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// Changes to other files of the directory are ignored
+	if _, err := watcher.Add(filepath.Join(dir, "other.conf"), "uptime"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// A burst of changes runs the command once
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(path, []byte("drifted"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var result *pb.CommandResult
+	select {
+	case result = <-reported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the watch rule to run")
+	}
+	if result.WatchId != rule.ID || !strings.HasPrefix(result.CommandId, "watch-"+rule.ID+"-") ||
+		result.Payload != rule.Payload || !strings.HasSuffix(result.WatchEvent, path) {
+		t.Errorf("Unexpected watch result: %+v", result)
+	}
+	<-runs
+
+	select {
+	case cmd := <-runs:
+		t.Errorf("Expected a single run, got another one: %v", cmd)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	for _, recorded := range watcher.List() {
+		if recorded.ID == rule.ID && (recorded.LastCommandID != result.CommandId || recorded.LastEvent != result.WatchEvent) {
+			t.Errorf("Expected the last run to be recorded, got %+v", recorded)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package nexus
+
+import (
+	"context"
+	"sort"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// adminLogLevels are the log levels SetLogLevel accepts
+var adminLogLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+// SetAdmins sets the console identities allowed to use the AdminService,
+// the service rejecting every call without administrator
+func (s *Server) SetAdmins(admins []string) {
+	s.admins = admins
+}
+
+// SetAtomicLevel sets the level of the Nexus logger, letting administrators change it at runtime
+func (s *Server) SetAtomicLevel(level zap.AtomicLevel) {
+	s.logLevel = &level
+}
+
+// SetLogSampler sets the sampler of the Nexus logger, letting administrators change its rates at runtime
+func (s *Server) SetLogSampler(sampler *logging.Sampler) {
+	s.logSampler = sampler
+}
+
+// requireAdmin rejects the consoles whose identity is not an administrator,
+// and logs the operation of the others
+func (s *Server) requireAdmin(ctx context.Context, operation string) error {
+	if len(s.admins) == 0 {
+		return status.Error(codes.PermissionDenied, "the admin service is disabled, no administrator is configured")
+	}
+	identity := consoleIdentity(ctx)
+	if identity == "" || !containsString(s.admins, identity) || consoleScope(ctx).restricted() {
+		s.logger.Warn("Admin operation denied", zap.String("operation", operation), zap.String("console", identity))
+		return status.Errorf(codes.PermissionDenied, "%s requires an administrator console", operation)
+	}
+	s.logger.Info("Admin operation", zap.String("operation", operation), zap.String("admin", identity))
+	return nil
+}
+
+// FlushCaches forgets the in-memory state left by disconnected minions in the AdminService: the trackers
+// of commands only awaiting their results and their connection histories. Results received later are
+// still stored, but notified without their command payload.
+func (s *Server) FlushCaches(ctx context.Context, req *pb.Empty) (*pb.FlushCachesResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.FlushCaches")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "flushing caches"); err != nil {
+		return nil, err
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	resp := &pb.FlushCachesResponse{}
+
+	s.pendingMu.Lock()
+	for commandID, tracker := range s.pendingCommands {
+		connected := false
+		for minionID := range tracker.Pending {
+			if registry.HasStream(minionID) {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			delete(s.pendingCommands, commandID)
+			resp.PendingCommands++
+		}
+	}
+	s.pendingMu.Unlock()
+
+	resp.Diagnostics = int32(s.diagnostics.ForgetDisconnected())
+
+	logger.Info("Caches flushed",
+		zap.Int32("pending_commands", resp.PendingCommands),
+		zap.Int32("diagnostics", resp.Diagnostics))
+	return resp, nil
+}
+
+// SetLogLevel changes the level and the sampling of the Nexus logs in the AdminService, empty values
+// returning them unchanged
+func (s *Server) SetLogLevel(ctx context.Context, req *pb.LogLevelRequest) (*pb.LogLevelResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.SetLogLevel")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "changing the log level"); err != nil {
+		return nil, err
+	}
+	if s.logLevel == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the log level can't be changed at runtime")
+	}
+
+	var level zapcore.Level
+	if req.Level != "" {
+		if !adminLogLevels[req.Level] {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid log level '%s', use debug, info, warn or error", req.Level)
+		}
+		var err error
+		if level, err = zapcore.ParseLevel(req.Level); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid log level '%s'", req.Level)
+		}
+	}
+	var initial, thereafter int
+	if req.Sampling != "" {
+		if s.logSampler == nil {
+			return nil, status.Error(codes.FailedPrecondition, "the log sampling can't be changed at runtime")
+		}
+		var err error
+		if initial, thereafter, err = logging.ParseSampling(req.Sampling); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	previous := s.logLevel.Level().String()
+	resp := &pb.LogLevelResponse{Level: previous, PreviousLevel: previous}
+	if s.logSampler != nil {
+		resp.PreviousSampling = logging.FormatSampling(s.logSampler.Rates())
+		resp.Sampling = resp.PreviousSampling
+	}
+
+	if req.Level != "" {
+		s.logLevel.SetLevel(level)
+		resp.Level = level.String()
+		logger.Warn("Log level changed", zap.String("previous", previous), zap.String("level", resp.Level))
+	}
+	if req.Sampling != "" {
+		s.logSampler.SetRates(initial, thereafter)
+		resp.Sampling = logging.FormatSampling(initial, thereafter)
+		logger.Warn("Log sampling changed", zap.String("previous", resp.PreviousSampling), zap.String("sampling", resp.Sampling))
+	}
+	return resp, nil
+}
+
+// DumpRegistry returns the in-memory state of every registered minion in the AdminService
+func (s *Server) DumpRegistry(ctx context.Context, req *pb.Empty) (*pb.RegistryDump, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.DumpRegistry")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "dumping the registry"); err != nil {
+		return nil, err
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	dump := &pb.RegistryDump{}
+	for _, info := range registry.ListMinions() {
+		conn, exists := registry.GetConnectionImpl(info.Id)
+		if !exists {
+			continue
+		}
+		lastSeen, _ := registry.LastSeen(info.Id)
+		entry := &pb.RegistryEntry{
+			MinionId:        info.Id,
+			Hostname:        info.Hostname,
+			LastSeen:        lastSeen.Unix(),
+			Connected:       registry.HasStream(info.Id),
+			QueuedCommands:  int32(conn.Commands.Len()),
+			InFlight:        int32(conn.Commands.InFlight()),
+			PendingCommands: s.pendingCommandCount(info.Id),
+		}
+		if collisions, last := registry.IDCollisions(info.Id); collisions > 0 {
+			entry.IdCollisions = int32(collisions)
+			entry.LastIdCollision = last.Unix()
+		}
+		if entry.Connected {
+			dump.Connected++
+		}
+		dump.Minions = append(dump.Minions, entry)
+	}
+	sort.Slice(dump.Minions, func(i, j int) bool {
+		return dump.Minions[i].MinionId < dump.Minions[j].MinionId
+	})
+
+	s.pendingMu.Lock()
+	dump.PendingCommands = int32(len(s.pendingCommands))
+	s.pendingMu.Unlock()
+	if s.maintenance != nil {
+		dump.HeldCommands = int32(s.maintenance.HeldCount())
+	}
+	return dump, nil
+}
+
+// DisconnectMinion closes the command stream of a minion in the AdminService. Its queued commands
+// are kept and sent once the minion reconnects.
+func (s *Server) DisconnectMinion(ctx context.Context, req *pb.DisconnectMinionRequest) (*pb.Ack, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.DisconnectMinion")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "disconnecting minions"); err != nil {
+		return nil, err
+	}
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	if _, exists := registry.GetConnectionImpl(req.MinionId); !exists {
+		return nil, status.Errorf(codes.NotFound, "minion %s not found", req.MinionId)
+	}
+	if !registry.DisconnectStream(req.MinionId) {
+		return nil, status.Errorf(codes.FailedPrecondition, "minion %s has no open command stream", req.MinionId)
+	}
+
+	logger.Warn("Minion disconnected by an administrator", zap.String("minion_id", req.MinionId))
+	return &pb.Ack{Success: true}, nil
+}
+
+// PruneDatabase archives right away the results and deletes the minion logs older than their retention
+// period in the AdminService, instead of waiting for the next runs
+func (s *Server) PruneDatabase(ctx context.Context, req *pb.Empty) (*pb.PruneDatabaseResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.PruneDatabase")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "pruning the database"); err != nil {
+		return nil, err
+	}
+	if s.archiver == nil && s.logPruner == nil {
+		return nil, status.Error(codes.FailedPrecondition,
+			"database pruning requires result archival or minion log retention, see NEXUS_ARCHIVE_DAYS and NEXUS_MINION_LOG_RETENTION_DAYS")
+	}
+
+	resp := &pb.PruneDatabaseResponse{}
+	if s.archiver != nil {
+		resp.ArchivedCommands = int32(s.archiver.ArchiveOnce(ctx))
+	}
+	if s.logPruner != nil {
+		resp.PrunedLogs = s.logPruner.PruneOnce(ctx)
+	}
+	logger.Info("Database pruned",
+		zap.Int32("archived_commands", resp.ArchivedCommands),
+		zap.Int64("pruned_logs", resp.PrunedLogs))
+	return resp, nil
+}
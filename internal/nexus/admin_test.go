@@ -0,0 +1,156 @@
+package nexus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminAuthorization(t *testing.T) {
+	server := createTestServer(nil)
+	if _, err := server.DumpRegistry(identityContext("alice"), &pb.Empty{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied without administrator, got %v", err)
+	}
+
+	server.SetAdmins([]string{"alice"})
+	restricted := &x509.Certificate{Subject: pkix.Name{CommonName: "alice", OrganizationalUnit: []string{"team-a"}}}
+	restrictedCtx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{restricted}}}},
+	})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected codes.Code
+	}{
+		{"administrator", identityContext("alice"), codes.OK},
+		{"other console", identityContext("bob"), codes.PermissionDenied},
+		{"no certificate", context.Background(), codes.PermissionDenied},
+		{"namespace restricted administrator", restrictedCtx, codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := server.DumpRegistry(tt.ctx, &pb.Empty{}); status.Code(err) != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestAdminOperations(t *testing.T) {
+	server := createTestServer(nil)
+	server.SetAdmins([]string{"alice"})
+	ctx := identityContext("alice")
+
+	registry := server.GetMinionRegistryImpl()
+	for _, id := range []string{"minion-1", "minion-2"} {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id, Hostname: id + ".local"},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(10),
+		}
+	}
+	dead := registry.OpenStream("minion-1")
+	registry.minions["minion-1"].Commands.Push(&pb.Command{Id: "queued"})
+	server.trackCommand("cmd-1", "system:info", []string{"minion-1", "minion-2"})
+	server.trackCommand("cmd-2", "system:info", []string{"minion-2"})
+
+	dump, err := server.DumpRegistry(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(dump.Minions) != 2 || dump.Connected != 1 || dump.PendingCommands != 2 {
+		t.Fatalf("Unexpected registry dump: %v", dump)
+	}
+	if first := dump.Minions[0]; first.MinionId != "minion-1" || !first.Connected || first.QueuedCommands != 1 || first.PendingCommands != 1 {
+		t.Errorf("Unexpected state of minion-1: %v", first)
+	}
+
+	// The trackers awaiting only disconnected minions are forgotten
+	flushed, err := server.FlushCaches(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if flushed.PendingCommands != 1 || server.pendingCommandCount("minion-1") != 1 {
+		t.Errorf("Expected cmd-2 to be forgotten, got %v", flushed)
+	}
+
+	// Disconnecting closes the stream and keeps the queued commands
+	if _, err := server.DisconnectMinion(ctx, &pb.DisconnectMinionRequest{MinionId: "minion-1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-dead:
+	default:
+		t.Error("Expected the command stream to be signaled closed")
+	}
+	if registry.HasStream("minion-1") || registry.minions["minion-1"].Commands.Len() != 1 {
+		t.Error("Expected the stream closed and the queued command kept")
+	}
+	if _, err := server.DisconnectMinion(ctx, &pb.DisconnectMinionRequest{MinionId: "minion-1"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without stream, got %v", err)
+	}
+	if _, err := server.DisconnectMinion(ctx, &pb.DisconnectMinionRequest{MinionId: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown minion, got %v", err)
+	}
+
+	if _, err := server.PruneDatabase(ctx, &pb.Empty{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without archival, got %v", err)
+	}
+}
+
+func TestAdminSetLogLevel(t *testing.T) {
+	server := createTestServer(nil)
+	server.SetAdmins([]string{"alice"})
+	ctx := identityContext("alice")
+
+	if _, err := server.SetLogLevel(ctx, &pb.LogLevelRequest{Level: "debug"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without atomic level, got %v", err)
+	}
+
+	atom := zap.NewAtomicLevelAt(zap.InfoLevel)
+	server.SetAtomicLevel(atom)
+	resp, err := server.SetLogLevel(ctx, &pb.LogLevelRequest{Level: "debug"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.PreviousLevel != "info" || resp.Level != "debug" || atom.Level() != zap.DebugLevel {
+		t.Errorf("Expected the level changed from info to debug, got %v", resp)
+	}
+
+	if resp, err := server.SetLogLevel(ctx, &pb.LogLevelRequest{}); err != nil || resp.Level != "debug" {
+		t.Errorf("Expected the current level, got %v %v", resp, err)
+	}
+	if _, err := server.SetLogLevel(ctx, &pb.LogLevelRequest{Level: "fatal"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+
+	// Sampling
+	if _, err := server.SetLogLevel(ctx, &pb.LogLevelRequest{Sampling: "off"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without sampler, got %v", err)
+	}
+	sampler := logging.NewSampler(100, 100)
+	server.SetLogSampler(sampler)
+	resp, err = server.SetLogLevel(ctx, &pb.LogLevelRequest{Sampling: "10,50"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if initial, thereafter := sampler.Rates(); resp.PreviousSampling != "100,100" || resp.Sampling != "10,50" || initial != 10 || thereafter != 50 || resp.Level != "debug" {
+		t.Errorf("Expected the sampling changed from 100,100 to 10,50, got %v", resp)
+	}
+	if _, err := server.SetLogLevel(ctx, &pb.LogLevelRequest{Level: "info", Sampling: "sometimes"}); status.Code(err) != codes.InvalidArgument || atom.Level() != zap.DebugLevel {
+		t.Errorf("Expected InvalidArgument without any change, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// admissionSampleInterval is how often the load of Nexus is measured
+const admissionSampleInterval = time.Second
+
+// ingestionBackpressureDelay is how long the receipt of each message of a minion is delayed while
+// Nexus is overloaded, slowing the minions down without making their streams look dead
+const ingestionBackpressureDelay = 200 * time.Millisecond
+
+// OverloadLimits are the thresholds above which Nexus sheds load, 0 disabling a threshold
+type OverloadLimits struct {
+	MaxGoroutines     int           // goroutines running in Nexus
+	MaxDBLatency      time.Duration // round trip of a ping to the primary database
+	MaxQueuedCommands int           // commands queued for all the minions
+}
+
+// AdmissionController periodically measures the load of Nexus, reporting it overloaded while one of
+// its limits is exceeded
+type AdmissionController struct {
+	limits     OverloadLimits
+	interval   time.Duration
+	goroutines func() int
+	queued     func() int
+	ping       func(ctx context.Context) error // nil without database
+	overload   atomic.Pointer[string]          // why Nexus is overloaded, nil when it is not
+	logger     *zap.Logger
+	done       chan struct{}
+	wg         sync.WaitGroup
+	stopOnce   sync.Once
+}
+
+// NewAdmissionController creates a controller measuring the commands queued with queued and the
+// database latency with ping, which may be nil
+func NewAdmissionController(limits OverloadLimits, queued func() int, ping func(ctx context.Context) error, logger *zap.Logger) *AdmissionController {
+	return &AdmissionController{
+		limits:     limits,
+		interval:   admissionSampleInterval,
+		goroutines: runtime.NumGoroutine,
+		queued:     queued,
+		ping:       ping,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start launches the measurement loop
+func (a *AdmissionController) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+				a.Sample()
+			}
+		}
+	}()
+}
+
+// Stop stops the measurement loop
+func (a *AdmissionController) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+	})
+	a.wg.Wait()
+}
+
+// Sample measures the load of Nexus and updates whether it is overloaded
+func (a *AdmissionController) Sample() {
+	var exceeded []string
+	if a.limits.MaxGoroutines > 0 {
+		if n := a.goroutines(); n > a.limits.MaxGoroutines {
+			exceeded = append(exceeded, fmt.Sprintf("%d goroutines (limit %d)", n, a.limits.MaxGoroutines))
+		}
+	}
+	if a.limits.MaxQueuedCommands > 0 {
+		if n := a.queued(); n > a.limits.MaxQueuedCommands {
+			exceeded = append(exceeded, fmt.Sprintf("%d queued commands (limit %d)", n, a.limits.MaxQueuedCommands))
+		}
+	}
+	if a.limits.MaxDBLatency > 0 && a.ping != nil {
+		if latency, slow := a.databaseLatency(); slow {
+			exceeded = append(exceeded, fmt.Sprintf("database latency %s (limit %s)", latency, a.limits.MaxDBLatency))
+		}
+	}
+
+	if len(exceeded) == 0 {
+		if previous := a.overload.Swap(nil); previous != nil {
+			a.logger.Info("Nexus load back under its limits, accepting dispatches again")
+		}
+		return
+	}
+	reason := strings.Join(exceeded, ", ")
+	if previous := a.overload.Swap(&reason); previous == nil {
+		a.logger.Warn("Nexus overloaded, shedding console dispatches and slowing down minion results",
+			zap.String("reason", reason))
+	}
+}
+
+// databaseLatency pings the database, reporting whether it answered slower than the limit. A ping
+// timing out is slow; a database that can't be reached is not overload, Nexus runs without it.
+func (a *AdmissionController) databaseLatency() (time.Duration, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*a.limits.MaxDBLatency)
+	defer cancel()
+
+	start := time.Now()
+	err := a.ping(ctx)
+	latency := time.Since(start).Round(time.Millisecond)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return latency, false
+	}
+	return latency, latency > a.limits.MaxDBLatency
+}
+
+// Overloaded returns why Nexus is overloaded, and whether it is
+func (a *AdmissionController) Overloaded() (string, bool) {
+	if reason := a.overload.Load(); reason != nil {
+		return *reason, true
+	}
+	return "", false
+}
+
+// EnableLoadShedding starts measuring the load of Nexus: while one of limits is exceeded, console
+// dispatches are rejected with RESOURCE_EXHAUSTED and the receipt of minion messages is slowed down
+func (s *Server) EnableLoadShedding(limits OverloadLimits) {
+	var ping func(ctx context.Context) error
+	if s.dbService != nil {
+		ping = s.dbService.Ping
+	}
+	queued := func() int { return 0 }
+	if registry, ok := s.minionRegistry.(*MinionRegistryImpl); ok {
+		queued = registry.QueuedCommands
+	}
+	s.admission = NewAdmissionController(limits, queued, ping, s.logger)
+	s.admission.Start()
+}
+
+// admitDispatch rejects a console dispatch while Nexus is overloaded
+func (s *Server) admitDispatch(logger *zap.Logger) error {
+	if s.admission == nil {
+		return nil
+	}
+	reason, overloaded := s.admission.Overloaded()
+	if !overloaded {
+		return nil
+	}
+	logger.Warn("Dispatch rejected, Nexus overloaded", zap.String("reason", reason))
+	return status.Errorf(codes.ResourceExhausted, "Nexus is overloaded (%s), retry later", reason)
+}
+
+// throttleIngestion delays the handling of a minion result while Nexus is overloaded, and with it the
+// receipt of the next messages of the minion
+func (s *Server) throttleIngestion(ctx context.Context) {
+	if s.admission == nil {
+		return
+	}
+	if _, overloaded := s.admission.Overloaded(); !overloaded {
+		return
+	}
+	select {
+	case <-time.After(ingestionBackpressureDelay):
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,102 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdmissionControllerSample(t *testing.T) {
+	goroutines, queued := 10, 0
+	var pingErr error
+	pingDelay := time.Duration(0)
+	controller := NewAdmissionController(OverloadLimits{MaxGoroutines: 100, MaxDBLatency: 20 * time.Millisecond, MaxQueuedCommands: 50},
+		func() int { return queued },
+		func(ctx context.Context) error {
+			select {
+			case <-time.After(pingDelay):
+				return pingErr
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, zap.NewNop())
+	controller.goroutines = func() int { return goroutines }
+
+	controller.Sample()
+	if _, overloaded := controller.Overloaded(); overloaded {
+		t.Fatal("Expected Nexus under its limits")
+	}
+
+	goroutines, queued = 150, 60
+	controller.Sample()
+	reason, overloaded := controller.Overloaded()
+	if !overloaded || !strings.Contains(reason, "150 goroutines") || !strings.Contains(reason, "60 queued commands") {
+		t.Errorf("Expected the exceeded limits reported, got %q", reason)
+	}
+
+	// A ping timing out is slow, a database that can't be reached is not overload
+	goroutines, queued, pingDelay = 10, 0, time.Second
+	controller.Sample()
+	if reason, _ := controller.Overloaded(); !strings.Contains(reason, "database latency") {
+		t.Errorf("Expected the database latency reported, got %q", reason)
+	}
+	pingDelay, pingErr = 0, errors.New("connection refused")
+	controller.Sample()
+	if _, overloaded := controller.Overloaded(); overloaded {
+		t.Error("Expected an unreachable database not to be overload")
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	server := createTestServer(nil)
+	server.GetMinionRegistryImpl().minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: make(map[string]string)},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
+	}
+	server.admission = NewAdmissionController(OverloadLimits{MaxQueuedCommands: 1}, server.GetMinionRegistryImpl().QueuedCommands, nil, zap.NewNop())
+
+	req := &pb.CommandRequest{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: "uptime"}}
+	for i := 0; i < 2; i++ {
+		if _, err := server.SendCommand(context.Background(), req); err != nil {
+			t.Fatalf("Unexpected error under the limit: %v", err)
+		}
+	}
+	server.admission.Sample()
+
+	if _, err := server.SendCommand(context.Background(), req); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted while overloaded, got %v", err)
+	}
+	if _, err := server.BatchSendCommand(context.Background(), &pb.BatchCommandRequest{Requests: []*pb.CommandRequest{req}}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for a batch while overloaded, got %v", err)
+	}
+	dryRun := &pb.CommandRequest{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: "uptime"}, DryRun: true}
+	if _, err := server.SendCommand(context.Background(), dryRun); err != nil {
+		t.Errorf("Expected dry runs accepted while overloaded, got %v", err)
+	}
+
+	// Only results are delayed, heartbeats and status updates are handled right away
+	server.diagnostics = NewDiagnosticsTracker()
+	stream := &MockStreamServer{ctx: context.Background()}
+	start := time.Now()
+	server.handleReceivedMessage(stream, "minion-1", &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Status{
+		Status: &pb.CommandStatusUpdate{CommandId: "cmd-1", Status: "RUNNING"},
+	}}, zap.NewNop())
+	if time.Since(start) >= ingestionBackpressureDelay {
+		t.Error("Expected status updates not to be delayed while overloaded")
+	}
+	start = time.Now()
+	server.handleReceivedMessage(stream, "minion-1", &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Result{
+		Result: &pb.CommandResult{CommandId: "cmd-1"},
+	}}, zap.NewNop())
+	if time.Since(start) < ingestionBackpressureDelay {
+		t.Error("Expected the results of minions to be delayed while overloaded")
+	}
+}
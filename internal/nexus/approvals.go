@@ -0,0 +1,406 @@
+package nexus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// approvalTTL is how long a command waits for its approval before expiring
+const approvalTTL = 24 * time.Hour
+
+// approvalRetention is how long decided and expired approvals remain listed
+const approvalRetention = 7 * 24 * time.Hour
+
+// Statuses of a command requiring approval
+const (
+	ApprovalPending  = "PENDING_APPROVAL"
+	ApprovalApproved = "APPROVED"
+	ApprovalRejected = "REJECTED"
+	ApprovalExpired  = "EXPIRED"
+)
+
+// ApprovalRule describes commands requiring the approval of a second operator: the commands whose
+// payload matches Pattern, of Impact or a more dangerous class, and reaching a minion matching Tag.
+// A rule without Pattern or Impact applies to every command reaching Tag, a rule without Tag to the
+// matching commands whatever their targets.
+type ApprovalRule struct {
+	Name    string `json:"name"`              // shown to the operators
+	Pattern string `json:"pattern,omitempty"` // regular expression matched against the command payload
+	Impact  string `json:"impact,omitempty"`  // least dangerous impact class of the commands: read-only, mutating or disruptive
+	Tag     string `json:"tag,omitempty"`     // "key" or "key=value" matched against the target minion tags
+
+	re *regexp.Regexp
+}
+
+// ApprovalPolicy lists the commands requiring approval and the operators allowed to approve them
+type ApprovalPolicy struct {
+	Rules     []*ApprovalRule `json:"rules"`
+	Approvers []string        `json:"approvers"` // console certificate common names
+}
+
+// LoadApprovalPolicy reads an approval policy from a JSON file
+func LoadApprovalPolicy(path string) (*ApprovalPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval policy: %w", err)
+	}
+
+	var policy ApprovalPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse approval policy: %w", err)
+	}
+
+	for i, rule := range policy.Rules {
+		if rule == nil || rule.Name == "" {
+			return nil, fmt.Errorf("approval rule %d: name is required", i)
+		}
+		if rule.Pattern == "" && rule.Impact == "" && rule.Tag == "" {
+			return nil, fmt.Errorf("approval rule %s: pattern, impact or tag is required", rule.Name)
+		}
+		if err := command.ValidateImpact(rule.Impact); err != nil {
+			return nil, fmt.Errorf("approval rule %s: %w", rule.Name, err)
+		}
+		if strings.HasPrefix(rule.Tag, "=") {
+			return nil, fmt.Errorf("approval rule %s: invalid tag %q", rule.Name, rule.Tag)
+		}
+		if rule.Pattern != "" {
+			if rule.re, err = regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("approval rule %s: %w", rule.Name, err)
+			}
+		}
+	}
+	if len(policy.Approvers) == 0 {
+		return nil, fmt.Errorf("approval policy: at least one approver is required")
+	}
+	return &policy, nil
+}
+
+// match returns the name of the first rule a command of impact class matches, empty when it requires
+// no approval
+func (ap *ApprovalPolicy) match(payload, impact string, targets []string, tagsOf func(string) map[string]string) string {
+	for _, rule := range ap.Rules {
+		if rule.re != nil && !rule.re.MatchString(payload) {
+			continue
+		}
+		if rule.Impact != "" && command.ImpactRank(impact) < command.ImpactRank(rule.Impact) {
+			continue
+		}
+		if rule.Tag == "" {
+			return rule.Name
+		}
+		for _, minionID := range targets {
+			if matchesTagSpec(rule.Tag, tagsOf(minionID)) {
+				return rule.Name
+			}
+		}
+	}
+	return ""
+}
+
+// isApprover reports whether an operator may approve commands
+func (ap *ApprovalPolicy) isApprover(identity string) bool {
+	return identity != "" && containsString(ap.Approvers, identity)
+}
+
+// ApprovalGate keeps the commands awaiting the approval of a second operator, the four-eyes principle
+type ApprovalGate struct {
+	mu        sync.Mutex
+	policy    *ApprovalPolicy
+	approvals map[string]*pendingApproval
+}
+
+// pendingApproval is a command requiring approval, with the request dispatched once approved
+type pendingApproval struct {
+	approval *pb.CommandApproval
+	request  *pb.CommandRequest
+}
+
+// NewApprovalGate creates a gate for the commands matching the rules of policy
+func NewApprovalGate(policy *ApprovalPolicy) *ApprovalGate {
+	return &ApprovalGate{
+		policy:    policy,
+		approvals: make(map[string]*pendingApproval),
+	}
+}
+
+// Match returns the name of the approval rule a command of impact class reaching targets matches,
+// empty when the command requires no approval
+func (g *ApprovalGate) Match(payload, impact string, targets []string, tagsOf func(string) map[string]string) string {
+	if g == nil {
+		return ""
+	}
+	return g.policy.match(payload, impact, targets, tagsOf)
+}
+
+// add records a command awaiting approval
+func (g *ApprovalGate) add(approval *pb.CommandApproval, req *pb.CommandRequest) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.approvals[approval.CommandId] = &pendingApproval{approval: approval, request: req}
+}
+
+// restore adds the approvals recorded before Nexus restarted, keeping the ones already known
+func (g *ApprovalGate) restore(approvals []*pendingApproval) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	restored := 0
+	for _, p := range approvals {
+		if _, exists := g.approvals[p.approval.CommandId]; !exists {
+			g.approvals[p.approval.CommandId] = p
+			restored++
+		}
+	}
+	return restored
+}
+
+// sweep expires the approvals pending for too long and forgets the old ones. It returns the
+// approvals it expired.
+func (g *ApprovalGate) sweep(now time.Time) []*pb.CommandApproval {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var expired []*pb.CommandApproval
+	for id, p := range g.approvals {
+		requested := time.Unix(p.approval.RequestedAt, 0)
+		switch {
+		case now.Sub(requested) > approvalRetention:
+			delete(g.approvals, id)
+		case p.approval.Status == ApprovalPending && now.Sub(requested) > approvalTTL:
+			p.approval.Status = ApprovalExpired
+			p.approval.DecidedAt = now.Unix()
+			expired = append(expired, proto.Clone(p.approval).(*pb.CommandApproval))
+		}
+	}
+	return expired
+}
+
+// list returns copies of the approvals, most recent first
+func (g *ApprovalGate) list() []*pb.CommandApproval {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	approvals := make([]*pb.CommandApproval, 0, len(g.approvals))
+	for _, p := range g.approvals {
+		approvals = append(approvals, proto.Clone(p.approval).(*pb.CommandApproval))
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		if approvals[i].RequestedAt != approvals[j].RequestedAt {
+			return approvals[i].RequestedAt > approvals[j].RequestedAt
+		}
+		return approvals[i].CommandId < approvals[j].CommandId
+	})
+	return approvals
+}
+
+// get returns a copy of an approval
+func (g *ApprovalGate) get(commandID string) (*pb.CommandApproval, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, exists := g.approvals[commandID]
+	if !exists {
+		return nil, false
+	}
+	return proto.Clone(p.approval).(*pb.CommandApproval), true
+}
+
+// decide approves or rejects a pending command on behalf of approver, who must not have sent it.
+// It returns a copy of the decided approval and the request to dispatch.
+func (g *ApprovalGate) decide(commandID, approver string, reject bool, comment string, now time.Time) (*pb.CommandApproval, *pb.CommandRequest, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, exists := g.approvals[commandID]
+	switch {
+	case !exists:
+		return nil, nil, status.Errorf(codes.NotFound, "no command %s awaiting approval", commandID)
+	case p.approval.Status != ApprovalPending:
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "command %s is already %s", commandID, strings.ToLower(p.approval.Status))
+	case p.approval.RequestedBy == approver:
+		return nil, nil, status.Error(codes.PermissionDenied, "commands must be approved by another operator than the one who sent them")
+	}
+
+	p.approval.Status = ApprovalApproved
+	if reject {
+		p.approval.Status = ApprovalRejected
+	}
+	p.approval.DecidedBy = approver
+	p.approval.DecidedAt = now.Unix()
+	p.approval.Comment = comment
+	return proto.Clone(p.approval).(*pb.CommandApproval), p.request, nil
+}
+
+// SetApprovalPolicy makes the commands matching the rules of policy wait for the approval of a second
+// operator. The approvals recorded in the database are restored once it is reached.
+func (s *Server) SetApprovalPolicy(policy *ApprovalPolicy) {
+	s.approvals = NewApprovalGate(policy)
+	if s.dbService != nil {
+		go s.loadApprovals(s.approvals)
+	}
+}
+
+// loadApprovals restores into gate the approvals of the last days recorded in the database, so that
+// the commands pending when Nexus stopped can still be approved
+func (s *Server) loadApprovals(gate *ApprovalGate) {
+	if s.hosts != nil {
+		select {
+		case <-s.hosts.ready:
+		case <-s.hosts.stop:
+			return
+		}
+	}
+
+	approvals, err := s.dbService.GetApprovals(context.Background(), time.Now().Add(-approvalRetention))
+	if err != nil {
+		s.logger.Warn("Failed to load command approvals", zap.Error(err))
+		return
+	}
+	if restored := gate.restore(approvals); restored > 0 {
+		s.logger.Info("Command approvals restored", zap.Int("count", restored))
+	}
+}
+
+// requestApproval records a command awaiting approval instead of dispatching it
+func (s *Server) requestApproval(ctx context.Context, req *pb.CommandRequest, targets, skipped []string, reason string, logger *zap.Logger) *pb.CommandDispatchResponse {
+	s.sweepApprovals(ctx, logger)
+
+	req.Command.Id = generateMinionID()
+	approval := &pb.CommandApproval{
+		CommandId:       req.Command.Id,
+		Payload:         redactPayload(req.Command.Payload),
+		TargetMinionIds: targets,
+		Reason:          reason,
+		RequestedBy:     consoleIdentity(ctx),
+		RequestedAt:     time.Now().Unix(),
+		Status:          ApprovalPending,
+		Impact:          req.Impact,
+	}
+	s.approvals.add(approval, req)
+	s.storeApproval(ctx, approval, req, logger)
+
+	logger.Info("COMMAND_FLOW_MONITORING: Command awaiting approval",
+		zap.String("stage", "APPROVAL_REQUIRED"),
+		zap.String("command_id", approval.CommandId),
+		zap.String("reason", reason),
+		zap.String("requested_by", approval.RequestedBy),
+		zap.Strings("target_minion_ids", targets),
+		zap.Time("timestamp", time.Now()))
+
+	return &pb.CommandDispatchResponse{
+		CommandId:        approval.CommandId,
+		TargetMinionIds:  targets,
+		SkippedMinionIds: skipped,
+		ApprovalRequired: true,
+		ApprovalReason:   reason,
+		TraceId:          req.Command.TraceId,
+		Impact:           req.Impact,
+	}
+}
+
+// ListCommandApprovals returns the commands requiring approval of the last days, within the console's namespaces
+func (s *Server) ListCommandApprovals(ctx context.Context, _ *pb.Empty) (*pb.CommandApprovalList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListCommandApprovals")
+	defer logging.FuncExit(logger, start)
+
+	list := &pb.CommandApprovalList{}
+	if s.approvals == nil {
+		return list, nil
+	}
+	s.sweepApprovals(ctx, logger)
+
+	scope := consoleScope(ctx)
+	for _, approval := range s.approvals.list() {
+		if s.inScope(scope, approval.TargetMinionIds) {
+			list.Approvals = append(list.Approvals, approval)
+		}
+	}
+	return list, nil
+}
+
+// DecideCommandApproval approves or rejects a command awaiting approval, dispatching it once approved.
+// Only the approvers of the policy may decide, on the commands sent by other operators.
+func (s *Server) DecideCommandApproval(ctx context.Context, req *pb.ApprovalDecision) (*pb.CommandDispatchResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.DecideCommandApproval")
+	defer logging.FuncExit(logger, start)
+
+	if s.approvals == nil {
+		return nil, status.Error(codes.FailedPrecondition, "command approval is not enabled on this Nexus")
+	}
+	approver := consoleIdentity(ctx)
+	if !s.approvals.policy.isApprover(approver) {
+		logger.Warn("Approval decision denied", zap.String("command_id", req.CommandId), zap.String("operator", approver))
+		return nil, status.Error(codes.PermissionDenied, "only approvers may decide on commands awaiting approval")
+	}
+	s.sweepApprovals(ctx, logger)
+
+	// Consoles restricted to namespaces don't see the commands reaching other namespaces
+	if approval, exists := s.approvals.get(req.CommandId); !exists || !s.inScope(consoleScope(ctx), approval.TargetMinionIds) {
+		return nil, status.Errorf(codes.NotFound, "no command %s awaiting approval", req.CommandId)
+	}
+	// An approval is left pending while its command can't be dispatched
+	if !req.Reject {
+		if err := s.admitDispatch(logger); err != nil {
+			return nil, err
+		}
+	}
+
+	approval, request, err := s.approvals.decide(req.CommandId, approver, req.Reject, req.Comment, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.storeApproval(ctx, approval, nil, logger)
+
+	logger.Info("COMMAND_FLOW_MONITORING: Command approval decided",
+		zap.String("stage", "APPROVAL_"+approval.Status),
+		zap.String("command_id", approval.CommandId),
+		zap.String("requested_by", approval.RequestedBy),
+		zap.String("decided_by", approval.DecidedBy),
+		zap.String("comment", approval.Comment),
+		zap.Time("timestamp", time.Now()))
+
+	if req.Reject {
+		return &pb.CommandDispatchResponse{CommandId: approval.CommandId, TargetMinionIds: approval.TargetMinionIds}, nil
+	}
+	return s.dispatchCommand(ctx, request, approval.TargetMinionIds, nil, ensureTraceID(request.Command, logger)), nil
+}
+
+// inScope reports whether all the minions are within scope
+func (s *Server) inScope(scope namespaceScope, minionIDs []string) bool {
+	return len(s.scopedMinionIDs(scope, minionIDs)) == len(minionIDs)
+}
+
+// sweepApprovals expires the approvals pending for too long, recording them in the audit trail
+func (s *Server) sweepApprovals(ctx context.Context, logger *zap.Logger) {
+	for _, approval := range s.approvals.sweep(time.Now()) {
+		logger.Info("Command approval expired", zap.String("command_id", approval.CommandId))
+		s.storeApproval(ctx, approval, nil, logger)
+	}
+}
+
+// storeApproval records an approval in the database audit trail, with the request of a pending command
+// so that it survives a Nexus restart
+func (s *Server) storeApproval(ctx context.Context, approval *pb.CommandApproval, request *pb.CommandRequest, logger *zap.Logger) {
+	if s.dbService == nil {
+		return
+	}
+	if err := s.dbService.StoreApproval(ctx, approval, request); err != nil {
+		logger.Error("Failed to store command approval", zap.String("command_id", approval.CommandId), zap.Error(err))
+	}
+}
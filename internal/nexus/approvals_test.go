@@ -0,0 +1,303 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// identityContext returns a context whose peer presented a verified certificate with the given common name
+func identityContext(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func createApprovalTestServer(t *testing.T) (*Server, *MinionConnectionImpl) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "approval.json")
+	policy := `{
+		"rules": [
+			{"name": "production", "tag": "env=prod"},
+			{"name": "package upgrade", "pattern": "apt-get\\s+upgrade"}
+		],
+		"approvers": ["alice", "bob"]
+	}`
+	if err := os.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatalf("Failed to write approval policy: %v", err)
+	}
+	loaded, err := LoadApprovalPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadApprovalPolicy failed: %v", err)
+	}
+
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	server.SetApprovalPolicy(loaded)
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: map[string]string{"env": "prod"}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+	registry.minions["minion-2"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-2", Tags: map[string]string{"env": "dev"}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+	conn, _ := registry.GetConnectionImpl("minion-1")
+	return server, conn
+}
+
+func TestLoadApprovalPolicyInvalid(t *testing.T) {
+	for _, content := range []string{
+		`{"rules": [{"name": "any", "pattern": "x"}]}`,
+		`{"rules": [{"pattern": "x"}], "approvers": ["alice"]}`,
+		`{"rules": [{"name": "empty"}], "approvers": ["alice"]}`,
+		`{"rules": [{"name": "bad", "pattern": "("}], "approvers": ["alice"]}`,
+		`{"rules": [{"name": "bad", "tag": "=prod"}], "approvers": ["alice"]}`,
+		`{"rules": [{"name": "bad", "impact": "harmless"}], "approvers": ["alice"]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "approval.json")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write approval policy: %v", err)
+		}
+		if _, err := LoadApprovalPolicy(path); err == nil {
+			t.Errorf("Expected %s to be rejected", content)
+		}
+	}
+}
+
+func TestApprovalRuleImpact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approval.json")
+	content := `{"rules": [{"name": "disruptive", "impact": "disruptive"}, {"name": "production changes", "impact": "mutating", "tag": "env=prod"}], "approvers": ["alice"]}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write approval policy: %v", err)
+	}
+	loaded, err := LoadApprovalPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadApprovalPolicy failed: %v", err)
+	}
+
+	tags := map[string]map[string]string{"minion-1": {"env": "prod"}, "minion-2": {"env": "dev"}}
+	tagsOf := func(id string) map[string]string { return tags[id] }
+	tests := []struct {
+		impact   string
+		target   string
+		expected string
+	}{
+		{"read-only", "minion-1", ""},
+		{"mutating", "minion-1", "production changes"},
+		{"mutating", "minion-2", ""},
+		{"disruptive", "minion-2", "disruptive"},
+	}
+	for _, tt := range tests {
+		if got := loaded.match("uptime", tt.impact, []string{tt.target}, tagsOf); got != tt.expected {
+			t.Errorf("%s command on %s: expected rule %q, got %q", tt.impact, tt.target, tt.expected, got)
+		}
+	}
+}
+
+func TestCommandApprovalWorkflow(t *testing.T) {
+	server, conn := createApprovalTestServer(t)
+
+	// Commands matching no rule are dispatched at once
+	resp, err := server.SendCommand(identityContext("alice"), &pb.CommandRequest{
+		MinionIds: []string{"minion-2"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+	})
+	if err != nil || !resp.Accepted || resp.ApprovalRequired {
+		t.Fatalf("Expected uptime on a dev minion to be dispatched, got %+v (%v)", resp, err)
+	}
+
+	resp, err = server.SendCommand(identityContext("alice"), &pb.CommandRequest{
+		TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}}},
+		Command:     &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+	})
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if resp.Accepted || !resp.ApprovalRequired || resp.ApprovalReason != "production" || resp.CommandId == "" {
+		t.Fatalf("Expected the command to await approval, got %+v", resp)
+	}
+	if conn.Commands.Len() != 0 {
+		t.Fatal("Expected no command dispatched before approval")
+	}
+
+	decide := func(ctx context.Context, reject bool) (*pb.CommandDispatchResponse, codes.Code) {
+		resp, err := server.DecideCommandApproval(ctx, &pb.ApprovalDecision{CommandId: resp.CommandId, Reject: reject, Comment: "checked"})
+		return resp, status.Code(err)
+	}
+	if _, code := decide(identityContext("carol"), false); code != codes.PermissionDenied {
+		t.Errorf("Expected non approvers to be denied, got %s", code)
+	}
+	if _, code := decide(identityContext("alice"), false); code != codes.PermissionDenied {
+		t.Errorf("Expected the sender not to approve their own command, got %s", code)
+	}
+
+	approved, code := decide(identityContext("bob"), false)
+	if code != codes.OK || !approved.Accepted || approved.CommandId != resp.CommandId {
+		t.Fatalf("Expected bob's approval to dispatch the command, got %+v (%s)", approved, code)
+	}
+	if conn.Commands.Len() != 1 {
+		t.Errorf("Expected the approved command queued, got %d", conn.Commands.Len())
+	}
+	if _, code := decide(identityContext("bob"), true); code != codes.FailedPrecondition {
+		t.Errorf("Expected a decided command not to be decided again, got %s", code)
+	}
+
+	list, err := server.ListCommandApprovals(context.Background(), &pb.Empty{})
+	if err != nil || len(list.Approvals) != 1 {
+		t.Fatalf("Expected 1 approval listed, got %v (%v)", list, err)
+	}
+	approval := list.Approvals[0]
+	if approval.Status != ApprovalApproved || approval.RequestedBy != "alice" || approval.DecidedBy != "bob" || approval.Comment != "checked" {
+		t.Errorf("Unexpected audit trail: %+v", approval)
+	}
+}
+
+func TestCommandApprovalRejectAndExpire(t *testing.T) {
+	server, conn := createApprovalTestServer(t)
+
+	send := func() string {
+		resp, err := server.SendCommand(identityContext("alice"), &pb.CommandRequest{
+			MinionIds: []string{"minion-2"},
+			Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "apt-get upgrade -y"},
+		})
+		if err != nil || !resp.ApprovalRequired || resp.ApprovalReason != "package upgrade" {
+			t.Fatalf("Expected the upgrade to await approval, got %+v (%v)", resp, err)
+		}
+		return resp.CommandId
+	}
+
+	rejected := send()
+	if _, err := server.DecideCommandApproval(identityContext("bob"), &pb.ApprovalDecision{CommandId: rejected, Reject: true}); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if conn.Commands.Len() != 0 {
+		t.Error("Expected a rejected command not to be dispatched")
+	}
+
+	expiring := send()
+	expired := server.approvals.sweep(time.Now().Add(approvalTTL + time.Minute))
+	if len(expired) != 1 || expired[0].CommandId != expiring || expired[0].Status != ApprovalExpired {
+		t.Fatalf("Expected the pending command to expire, got %v", expired)
+	}
+	_, err := server.DecideCommandApproval(identityContext("bob"), &pb.ApprovalDecision{CommandId: expiring})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected an expired command not to be approved, got %v", err)
+	}
+
+	server.approvals.sweep(time.Now().Add(approvalRetention + time.Minute))
+	if approvals := server.approvals.list(); len(approvals) != 0 {
+		t.Errorf("Expected old approvals to be forgotten, got %d", len(approvals))
+	}
+}
+
+// capturedArg matches any value, keeping the last one
+type capturedArg struct {
+	value *driver.Value
+}
+
+func (a capturedArg) Match(v driver.Value) bool {
+	*a.value = v
+	return true
+}
+
+func TestCommandApprovalRestored(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	dbService := NewDatabaseService(db, zap.NewNop())
+	if dbService.encryptor, err = NewEncryptor([][]byte{bytes.Repeat([]byte{1}, 32)}); err != nil {
+		t.Fatalf("NewEncryptor failed: %v", err)
+	}
+
+	// The request of a pending command is stored encrypted with its approval, along its impact class
+	requestedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	approval := &pb.CommandApproval{
+		CommandId:       "cmd-1",
+		Payload:         "apt-get upgrade",
+		TargetMinionIds: []string{"minion-1"},
+		Reason:          "package upgrade",
+		RequestedBy:     "alice",
+		RequestedAt:     requestedAt.Unix(),
+		Status:          ApprovalPending,
+		Impact:          command.ImpactMutating,
+	}
+	request := &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Id: "cmd-1", Type: pb.CommandType_SYSTEM, Payload: "apt-get upgrade"},
+	}
+	var stored driver.Value
+	args := make([]driver.Value, 10)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+	mock.ExpectExec("INSERT INTO command_approvals").
+		WithArgs(append(args, command.ImpactMutating, capturedArg{&stored})...).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := dbService.StoreApproval(context.Background(), approval, request); err != nil {
+		t.Fatalf("StoreApproval failed: %v", err)
+	}
+	if value, ok := stored.(string); !ok || !strings.HasPrefix(value, encryptedPrefix) {
+		t.Fatalf("Expected the request stored encrypted, got %v", stored)
+	}
+
+	// After a restart, the pending approval is restored with its request, the decided one without it
+	// and the pending one whose request was lost is skipped
+	columns := []string{"command_id", "payload", "targets", "reason", "requested_by", "requested_at", "status", "decided_by", "decided_at", "comment", "impact", "request"}
+	mock.ExpectQuery("SELECT command_id, payload, targets").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("cmd-1", "apt-get upgrade", "minion-1", "package upgrade", "alice", requestedAt, ApprovalPending, nil, nil, nil, command.ImpactMutating, stored).
+			AddRow("cmd-2", "uptime", "minion-1", "production", "alice", requestedAt, ApprovalRejected, "bob", requestedAt, "no", nil, nil).
+			AddRow("cmd-3", "uptime", "minion-1", "production", "alice", requestedAt, ApprovalPending, nil, nil, nil, command.ImpactMutating, nil))
+	approvals, err := dbService.GetApprovals(context.Background(), time.Now().Add(-approvalRetention))
+	if err != nil {
+		t.Fatalf("GetApprovals failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	server, conn := createApprovalTestServer(t)
+	if restored := server.approvals.restore(approvals); restored != 2 {
+		t.Fatalf("Expected 2 approvals restored, got %d", restored)
+	}
+	if pending, _ := server.approvals.get("cmd-1"); pending == nil || pending.Impact != command.ImpactMutating {
+		t.Errorf("Expected the impact of the pending approval restored, got %+v", pending)
+	}
+	rejected, _ := server.approvals.get("cmd-2")
+	if rejected == nil || rejected.Status != ApprovalRejected || rejected.DecidedBy != "bob" || rejected.Comment != "no" {
+		t.Errorf("Expected the decided approval restored, got %+v", rejected)
+	}
+
+	approved, err := server.DecideCommandApproval(identityContext("bob"), &pb.ApprovalDecision{CommandId: "cmd-1"})
+	if err != nil || !approved.Accepted {
+		t.Fatalf("Expected the restored command dispatched once approved, got %+v (%v)", approved, err)
+	}
+	if conn.Commands.Len() != 1 {
+		t.Errorf("Expected the approved command queued, got %d", conn.Commands.Len())
+	}
+}
@@ -0,0 +1,451 @@
+package nexus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// archiveCheckInterval is how often results are checked for archival
+	archiveCheckInterval = time.Hour
+	// archiveBatchSize bounds the number of commands archived per run
+	archiveBatchSize = 100
+	// archiveKeyPrefix is prepended to the object key of archived results
+	archiveKeyPrefix = "results/"
+	// archiveDeleteBatchSize bounds the number of minions whose archived results are deleted per statement
+	archiveDeleteBatchSize = 500
+	// archiveCacheSize bounds the number of commands whose archived results are kept in memory,
+	// so that paging through them doesn't download the object for each page
+	archiveCacheSize = 16
+	// archiveCacheTTL is how long archived results are kept in memory
+	archiveCacheTTL = 5 * time.Minute
+)
+
+// ObjectStore stores archived command results
+type ObjectStore interface {
+	// Put stores data under key, replacing any existing object.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get retrieves the object stored under key.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// S3Config describes an S3-compatible bucket
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Store is an ObjectStore backed by an S3-compatible bucket.
+// Objects are addressed path-style and requests are signed with AWS Signature Version 4.
+type S3Store struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Store creates an object store for the bucket described by cfg
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("archive endpoint and bucket are required")
+	}
+	if _, err := url.Parse(cfg.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid archive endpoint: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+
+	return &S3Store{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 60 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+// Put uploads data under key
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to upload %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+// Get downloads the object stored under key
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("failed to download %s: %s", key, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// do sends a signed request for key
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	path := "/" + url.PathEscape(s.cfg.Bucket) + "/" + strings.Join(segments, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive request: %w", err)
+	}
+	s.sign(req, path, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("archive request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to req
+func (s *S3Store) sign(req *http.Request, path string, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"",
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), day)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// ResultArchiver moves the results of commands older than a retention period to an object store,
+// leaving a pointer row in the database so they can still be retrieved.
+type ResultArchiver struct {
+	db        DatabaseService
+	store     ObjectStore
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+	logger    *zap.Logger
+	done      chan struct{}
+	wg        sync.WaitGroup
+	stopOnce  sync.Once
+
+	cacheMu sync.Mutex
+	cache   map[string]archivedResults // decrypted archived results by command ID
+}
+
+// archivedResults are the decrypted results of an archived object, kept for paging through them
+type archivedResults struct {
+	key     string
+	results []*pb.CommandResult
+	fetched time.Time
+}
+
+// NewResultArchiver creates an archiver moving results older than retention to store
+func NewResultArchiver(db DatabaseService, store ObjectStore, retention time.Duration, logger *zap.Logger) *ResultArchiver {
+	return &ResultArchiver{
+		db:        db,
+		store:     store,
+		retention: retention,
+		interval:  archiveCheckInterval,
+		now:       time.Now,
+		logger:    logger,
+		done:      make(chan struct{}),
+		cache:     make(map[string]archivedResults),
+	}
+}
+
+// EnableArchive starts moving the results of commands older than retention to store.
+// Archived results stay available through GetCommandResults.
+func (s *Server) EnableArchive(store ObjectStore, retention time.Duration) error {
+	if s.dbService == nil {
+		return fmt.Errorf("result archival requires a database")
+	}
+	s.archiver = NewResultArchiver(s.dbService, store, retention, s.logger)
+	s.archiver.Start()
+	return nil
+}
+
+// Start launches the archival loop
+func (a *ResultArchiver) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			a.ArchiveOnce(context.Background())
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the archival loop
+func (a *ResultArchiver) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.done)
+	})
+	a.wg.Wait()
+}
+
+// ArchiveOnce archives the commands whose results are all older than the retention period
+// and returns the number of archived commands
+func (a *ResultArchiver) ArchiveOnce(ctx context.Context) int {
+	logger, start := logging.FuncLogger(a.logger, "ResultArchiver.ArchiveOnce")
+	defer logging.FuncExit(logger, start)
+
+	cutoff := a.now().Add(-a.retention)
+	commandIDs, err := a.db.ListArchivableCommands(ctx, cutoff, archiveBatchSize)
+	if err != nil {
+		logger.Error("Failed to list archivable commands", zap.Error(err))
+		return 0
+	}
+
+	archived := 0
+	for _, commandID := range commandIDs {
+		if err := a.archiveCommand(ctx, commandID); err != nil {
+			logger.Error("Failed to archive command results",
+				zap.String("command_id", commandID),
+				zap.Error(err))
+			continue
+		}
+		archived++
+	}
+
+	if archived > 0 {
+		logger.Info("Archived command results", zap.Int("commands", archived))
+	}
+	return archived
+}
+
+// archiveCommand uploads the results of a command and replaces them with a pointer row.
+// With database encryption, the output is uploaded encrypted with the same key.
+func (a *ResultArchiver) archiveCommand(ctx context.Context, commandID string) error {
+	results, err := a.db.GetCommandResults(ctx, commandID)
+	if err != nil {
+		return err
+	}
+	encryptor := a.encryptor()
+	for i, result := range results {
+		if results[i], err = encryptor.EncryptResult(result); err != nil {
+			return fmt.Errorf("failed to encrypt archived results: %w", err)
+		}
+	}
+
+	minionIDs := make([]string, 0, len(results))
+	for _, result := range results {
+		minionIDs = append(minionIDs, result.MinionId)
+	}
+
+	// Results reported after a previous archival are merged into the existing object
+	previous, err := a.fetch(ctx, commandID)
+	if err != nil {
+		return err
+	}
+	results = mergeArchivedResults(previous, results)
+
+	data, err := encodeArchivedResults(results)
+	if err != nil {
+		return err
+	}
+
+	key := archiveKeyPrefix + commandID + ".json.gz"
+	if err := a.store.Put(ctx, key, data); err != nil {
+		return err
+	}
+	a.cacheMu.Lock()
+	delete(a.cache, commandID)
+	a.cacheMu.Unlock()
+	return a.db.MarkResultsArchived(ctx, commandID, key, len(results), minionIDs)
+}
+
+// Fetch retrieves the archived results of a command, returning nil when it was not archived.
+// The results of the last archiveCacheSize commands fetched are kept archiveCacheTTL in memory.
+func (a *ResultArchiver) Fetch(ctx context.Context, commandID string) ([]*pb.CommandResult, error) {
+	key, err := a.db.GetArchivedResultsKey(ctx, commandID)
+	if err != nil || key == "" {
+		return nil, err
+	}
+
+	now := a.now()
+	a.cacheMu.Lock()
+	cached, ok := a.cache[commandID]
+	a.cacheMu.Unlock()
+	if ok && cached.key == key && now.Sub(cached.fetched) < archiveCacheTTL {
+		return append([]*pb.CommandResult(nil), cached.results...), nil
+	}
+
+	results, err := a.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	encryptor := a.encryptor()
+	for _, result := range results {
+		if err := encryptor.DecryptResult(result); err != nil {
+			a.logger.Warn("Failed to decrypt archived result output",
+				zap.String("command_id", result.CommandId),
+				zap.String("minion_id", result.MinionId),
+				zap.Error(err))
+		}
+	}
+
+	a.cacheMu.Lock()
+	if _, ok := a.cache[commandID]; !ok && len(a.cache) >= archiveCacheSize {
+		oldest := ""
+		for id, entry := range a.cache {
+			if oldest == "" || entry.fetched.Before(a.cache[oldest].fetched) {
+				oldest = id
+			}
+		}
+		delete(a.cache, oldest)
+	}
+	a.cache[commandID] = archivedResults{key: key, results: results, fetched: now}
+	a.cacheMu.Unlock()
+	return append([]*pb.CommandResult(nil), results...), nil
+}
+
+// mergeArchivedResults appends to archived the live results of the minions not archived yet: the
+// results of an interrupted archival are both archived and still in command_results
+func mergeArchivedResults(archived, live []*pb.CommandResult) []*pb.CommandResult {
+	seen := make(map[string]bool, len(archived))
+	for _, result := range archived {
+		seen[result.MinionId] = true
+	}
+	merged := append([]*pb.CommandResult(nil), archived...)
+	for _, result := range live {
+		if !seen[result.MinionId] {
+			merged = append(merged, result)
+		}
+	}
+	return merged
+}
+
+// encryptor returns the encryptor of the database, nil when results are stored in clear
+func (a *ResultArchiver) encryptor() *Encryptor {
+	if impl, ok := a.db.(*DatabaseServiceImpl); ok {
+		return impl.encryptor
+	}
+	return nil
+}
+
+// fetch retrieves the archived results of a command as uploaded, their output still encrypted
+func (a *ResultArchiver) fetch(ctx context.Context, commandID string) ([]*pb.CommandResult, error) {
+	key, err := a.db.GetArchivedResultsKey(ctx, commandID)
+	if err != nil || key == "" {
+		return nil, err
+	}
+	return a.get(ctx, key)
+}
+
+// get downloads and decodes the archived object stored under key
+func (a *ResultArchiver) get(ctx context.Context, key string) ([]*pb.CommandResult, error) {
+	data, err := a.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return decodeArchivedResults(data)
+}
+
+// encodeArchivedResults serializes results as gzip-compressed JSON
+func encodeArchivedResults(results []*pb.CommandResult) ([]byte, error) {
+	data, err := protojson.Marshal(&pb.CommandResults{Results: results})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archived results: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress archived results: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archived results: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeArchivedResults reverses encodeArchivedResults
+func decodeArchivedResults(data []byte) ([]*pb.CommandResult, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archived results: %w", err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archived results: %w", err)
+	}
+
+	var results pb.CommandResults
+	if err := protojson.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode archived results: %w", err)
+	}
+	return results.Results, nil
+}
@@ -0,0 +1,330 @@
+package nexus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// memoryStore is an in-memory ObjectStore
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (m *memoryStore) Put(ctx context.Context, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.objects[key], nil
+}
+
+var resultColumns = []string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "content_type", "structured", "timestamp", "attempt", "execution"}
+
+func TestResultArchiverArchiveOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	store := &memoryStore{objects: make(map[string][]byte)}
+	archiver := NewResultArchiver(NewDatabaseService(db, zap.NewNop()), store, 24*time.Hour, zap.NewNop())
+
+	mock.ExpectQuery("SELECT command_id FROM command_results GROUP BY command_id HAVING MAX\\(timestamp\\) < \\$1").
+		WithArgs(sqlmock.AnyArg(), archiveBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"command_id"}).AddRow("cmd-old"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("FROM command_results WHERE command_id = \\$1").
+		WithArgs("cmd-old").
+		WillReturnRows(sqlmock.NewRows(resultColumns).
+			AddRow("cmd-old", "minion-1", 0, "out1", "", "", "", 1640995200, 1, "").
+			AddRow("cmd-old", "minion-2", 1, "", "err2", "", "", 1640995201, 1, ""))
+	mock.ExpectQuery("SELECT object_key FROM archived_results").
+		WithArgs("cmd-old").
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}))
+	mock.ExpectExec("INSERT INTO archived_results").
+		WithArgs("cmd-old", "results/cmd-old.json.gz", 2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM command_results WHERE command_id = \\$1 AND minion_id = ANY\\(\\$2\\)").
+		WithArgs("cmd-old", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if archived := archiver.ArchiveOnce(context.Background()); archived != 1 {
+		t.Fatalf("Expected 1 archived command, got %d", archived)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+
+	results, err := decodeArchivedResults(store.objects["results/cmd-old.json.gz"])
+	if err != nil {
+		t.Fatalf("Failed to decode archived object: %v", err)
+	}
+	if len(results) != 2 || results[1].Stderr != "err2" || results[1].ExitCode != 1 {
+		t.Errorf("Unexpected archived results: %v", results)
+	}
+}
+
+func TestResultArchiverKeepsOutputEncrypted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	if err := server.SetEncryptionKeys([][]byte{bytes.Repeat([]byte{5}, 32)}); err != nil {
+		t.Fatalf("SetEncryptionKeys failed: %v", err)
+	}
+	encryptor := server.dbService.(*DatabaseServiceImpl).encryptor
+	stdout, _ := encryptor.Encrypt("db-password=hunter2", encryptionBinding{"cmd-old", "minion-1", stdoutColumn})
+	stderr, _ := encryptor.Encrypt("warning: hunter2 in use", encryptionBinding{"cmd-old", "minion-1", stderrColumn})
+
+	store := &memoryStore{objects: make(map[string][]byte)}
+	archiver := NewResultArchiver(server.dbService, store, 24*time.Hour, zap.NewNop())
+
+	mock.ExpectQuery("SELECT command_id FROM command_results GROUP BY command_id").
+		WillReturnRows(sqlmock.NewRows([]string{"command_id"}).AddRow("cmd-old"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("FROM command_results WHERE command_id = \\$1").
+		WillReturnRows(sqlmock.NewRows(resultColumns).
+			AddRow("cmd-old", "minion-1", 0, stdout, stderr, "", "", 1640995200, 1, ""))
+	mock.ExpectQuery("SELECT object_key FROM archived_results").
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}))
+	mock.ExpectExec("INSERT INTO archived_results").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM command_results").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if archived := archiver.ArchiveOnce(context.Background()); archived != 1 {
+		t.Fatalf("Expected 1 archived command, got %d", archived)
+	}
+
+	// No plaintext output reaches the object store
+	zr, err := gzip.NewReader(bytes.NewReader(store.objects["results/cmd-old.json.gz"]))
+	if err != nil {
+		t.Fatalf("Failed to open archived object: %v", err)
+	}
+	uploaded, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to read archived object: %v", err)
+	}
+	if bytes.Contains(uploaded, []byte("hunter2")) || !bytes.Contains(uploaded, []byte(encryptedPrefix)) {
+		t.Errorf("Expected the archived output encrypted, got %s", uploaded)
+	}
+
+	// Fetching the archive decrypts it
+	mock.ExpectQuery("SELECT object_key FROM archived_results").
+		WithArgs("cmd-old").
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("results/cmd-old.json.gz"))
+	results, err := archiver.Fetch(context.Background(), "cmd-old")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Stdout != "db-password=hunter2" || results[0].Stderr != "warning: hunter2 in use" {
+		t.Errorf("Expected the archived results decrypted, got %v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+}
+
+func TestGetCommandResultsFromArchive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	data, err := encodeArchivedResults([]*pb.CommandResult{
+		{CommandId: "cmd-old", MinionId: "minion-1", Stdout: "out1"},
+		{CommandId: "cmd-old", MinionId: "minion-2", Stdout: "out2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode results: %v", err)
+	}
+	store := &memoryStore{objects: map[string][]byte{"results/cmd-old.json.gz": data}}
+	server.archiver = NewResultArchiver(server.dbService, store, 24*time.Hour, zap.NewNop())
+
+	// Archived results come first, followed by results reported after the archival
+	mock.ExpectQuery("SELECT object_key FROM archived_results").
+		WithArgs("cmd-old").
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("results/cmd-old.json.gz"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("FROM command_results WHERE command_id = \\$1").
+		WithArgs("cmd-old").
+		WillReturnRows(sqlmock.NewRows(resultColumns).AddRow("cmd-old", "minion-3", 0, "late", "", "", "", 1640995300, 1, ""))
+
+	results, err := server.GetCommandResults(context.Background(), &pb.ResultRequest{CommandId: "cmd-old", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results.Results) != 2 || results.HasMore || results.Results[0].MinionId != "minion-2" || results.Results[1].Stdout != "late" {
+		t.Errorf("Unexpected page of archived results: %v (has_more=%v)", results.Results, results.HasMore)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+}
+
+func TestMarkResultsArchivedDeletesByBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	minionIDs := make([]string, archiveDeleteBatchSize+1)
+	for i := range minionIDs {
+		minionIDs[i] = fmt.Sprintf("minion-%d", i)
+	}
+	mock.ExpectExec("INSERT INTO archived_results").
+		WithArgs("cmd-old", "results/cmd-old.json.gz", len(minionIDs), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM command_results WHERE command_id = \\$1 AND minion_id = ANY\\(\\$2\\)").
+		WithArgs("cmd-old", pq.Array(minionIDs[:archiveDeleteBatchSize])).
+		WillReturnResult(sqlmock.NewResult(0, archiveDeleteBatchSize))
+	mock.ExpectExec("DELETE FROM command_results WHERE command_id = \\$1 AND minion_id = ANY\\(\\$2\\)").
+		WithArgs("cmd-old", pq.Array(minionIDs[archiveDeleteBatchSize:])).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	service := NewDatabaseService(db, zap.NewNop())
+	if err := service.MarkResultsArchived(context.Background(), "cmd-old", "results/cmd-old.json.gz", len(minionIDs), minionIDs); err != nil {
+		t.Fatalf("MarkResultsArchived failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+}
+
+func TestFetchArchivedResultsCached(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	data, err := encodeArchivedResults([]*pb.CommandResult{{CommandId: "cmd-old", MinionId: "minion-1", Stdout: "out1"}})
+	if err != nil {
+		t.Fatalf("Failed to encode results: %v", err)
+	}
+	store := &countingStore{memoryStore: memoryStore{objects: map[string][]byte{"results/cmd-old.json.gz": data}}}
+	archiver := NewResultArchiver(NewDatabaseService(db, zap.NewNop()), store, 24*time.Hour, zap.NewNop())
+	now := time.Now()
+	archiver.now = func() time.Time { return now }
+
+	for page := 0; page < 3; page++ {
+		mock.ExpectQuery("SELECT object_key FROM archived_results").
+			WithArgs("cmd-old").
+			WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("results/cmd-old.json.gz"))
+		results, err := archiver.Fetch(context.Background(), "cmd-old")
+		if err != nil || len(results) != 1 || results[0].Stdout != "out1" {
+			t.Fatalf("Unexpected archived results %v: %v", results, err)
+		}
+	}
+	if store.gets != 1 {
+		t.Errorf("Expected the archived object downloaded once for 3 pages, got %d downloads", store.gets)
+	}
+
+	// The object is downloaded again once the cached results expired
+	now = now.Add(archiveCacheTTL)
+	mock.ExpectQuery("SELECT object_key FROM archived_results").
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("results/cmd-old.json.gz"))
+	if _, err := archiver.Fetch(context.Background(), "cmd-old"); err != nil || store.gets != 2 {
+		t.Errorf("Expected the expired results downloaded again, got %d downloads: %v", store.gets, err)
+	}
+
+	// Live results of archived minions, left by an interrupted deletion, are not returned twice
+	merged := mergeArchivedResults(
+		[]*pb.CommandResult{{MinionId: "minion-1", Stdout: "archived"}},
+		[]*pb.CommandResult{{MinionId: "minion-1", Stdout: "archived"}, {MinionId: "minion-2", Stdout: "late"}})
+	if len(merged) != 2 || merged[1].MinionId != "minion-2" {
+		t.Errorf("Unexpected merged results %v", merged)
+	}
+}
+
+// countingStore is a memoryStore counting the downloads
+type countingStore struct {
+	memoryStore
+	gets int
+}
+
+func (c *countingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	c.gets++
+	return c.memoryStore.Get(ctx, key)
+}
+
+func TestS3StoreSignedRoundTrip(t *testing.T) {
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20250102/eu-west-1/s3/aws4_request") ||
+			r.Header.Get("x-amz-date") != "20250102T030405Z" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if sha256Hex(body) != r.Header.Get("x-amz-content-sha256") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{Endpoint: server.URL + "/", Bucket: "archive", Region: "eu-west-1", AccessKey: "AKID", SecretKey: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := store.Put(context.Background(), "results/cmd-1.json.gz", []byte("payload")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, ok := objects["/archive/results/cmd-1.json.gz"]; !ok {
+		t.Errorf("Expected path-style object key, got %v", objects)
+	}
+	data, err := store.Get(context.Background(), "results/cmd-1.json.gz")
+	if err != nil || string(data) != "payload" {
+		t.Errorf("Expected payload back, got %q (%v)", data, err)
+	}
+	if _, err := store.Get(context.Background(), "results/missing.json.gz"); err == nil {
+		t.Error("Expected an error for a missing object")
+	}
+
+	if _, err := NewS3Store(S3Config{Endpoint: server.URL}); err == nil {
+		t.Error("Expected an error without bucket")
+	}
+}
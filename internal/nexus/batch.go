@@ -0,0 +1,210 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchCommands is the maximum number of commands accepted in one batch
+const maxBatchCommands = 500
+
+// BatchSendCommand dispatches several distinct commands, each with its own targets, in one call.
+// Commands of the batch are stored in a single transaction and queued minion by minion,
+// so orchestration tools firing many commands at once save round trips.
+// Each command is validated independently: a rejected command does not reject the batch.
+func (s *Server) BatchSendCommand(ctx context.Context, req *pb.BatchCommandRequest) (*pb.BatchCommandResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.BatchSendCommand")
+	defer logging.FuncExit(logger, start)
+
+	if len(req.Requests) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "batch contains no command")
+	}
+	if len(req.Requests) > maxBatchCommands {
+		return nil, status.Errorf(codes.InvalidArgument, "batch contains %d commands, maximum is %d", len(req.Requests), maxBatchCommands)
+	}
+	if err := s.admitDispatch(logger); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*pb.BatchCommandResponse_Entry, len(req.Requests))
+	var records []CommandRecord
+	perMinion := make(map[string][]int) // minion_id -> indexes of the commands to dispatch
+	var minionOrder []string
+
+	scope := consoleScope(ctx)
+	for i, cmdReq := range req.Requests {
+		cmdReq.Operator = consoleIdentity(ctx)
+		response, targets, err := s.prepareBatchCommand(scope, cmdReq)
+		entries[i] = &pb.BatchCommandResponse_Entry{Response: response}
+		if err != nil {
+			entries[i].Error = err.Error()
+			continue
+		}
+		if !response.Accepted || response.DryRun {
+			continue
+		}
+
+		for _, minionID := range targets {
+			records = append(records, commandRecord(response.CommandId, minionID, targets, cmdReq))
+			if _, seen := perMinion[minionID]; !seen {
+				minionOrder = append(minionOrder, minionID)
+			}
+			perMinion[minionID] = append(perMinion[minionID], i)
+		}
+	}
+
+	// Store all the commands of the batch at once, failures don't prevent dispatch like in SendCommand
+	if s.dbService != nil {
+		if err := s.dbService.StoreCommands(ctx, records); err != nil {
+			logger.Error("HARDENING: Failed to store command batch in database - persistence at risk",
+				zap.Int("record_count", len(records)),
+				zap.Error(err))
+		}
+	} else if len(records) > 0 {
+		logger.Warn("HARDENING: Database service unavailable - command batch not persisted",
+			zap.Int("record_count", len(records)))
+	}
+
+	// Dispatch minion by minion, looking each connection up once for all its commands
+	minionRegistryImpl := s.minionRegistry.(*MinionRegistryImpl)
+	dispatched := 0
+	for _, minionID := range minionOrder {
+		conn, exists := minionRegistryImpl.GetConnectionImpl(minionID)
+		if !exists {
+			logger.Warn("COMMAND_FLOW_MONITORING: Minion connection not found",
+				zap.String("stage", "CHANNEL_DELIVERY_NO_CONNECTION"),
+				zap.String("minion_id", minionID),
+				zap.Int("command_count", len(perMinion[minionID])),
+				zap.Time("timestamp", time.Now()))
+			continue
+		}
+
+		for _, i := range perMinion[minionID] {
+			cmdReq := req.Requests[i]
+			held, err := s.dispatchToConnection(conn, minionID, cmdReq.Command, isEmergency(cmdReq), logger)
+			if held {
+				response := entries[i].Response
+				response.HeldMinionIds = append(response.HeldMinionIds, minionID)
+			} else if err == nil {
+				dispatched++
+			}
+		}
+	}
+
+	for i, entry := range entries {
+		if response := entry.Response; response.Accepted && !response.DryRun {
+			response.EnvUnavailable, response.EnvUnsupportedMinionIds = s.commandEnvWarnings(req.Requests[i].Command, response.TargetMinionIds, logger)
+		}
+	}
+
+	logger.Info("COMMAND_FLOW_MONITORING: Command batch dispatch completed",
+		zap.String("stage", "BATCH_DISPATCH_SUCCESS"),
+		zap.Int("command_count", len(req.Requests)),
+		zap.Int("minion_count", len(minionOrder)),
+		zap.Int("successful_dispatches", dispatched),
+		zap.Duration("dispatch_duration", time.Since(start)),
+		zap.Time("timestamp", time.Now()))
+
+	return &pb.BatchCommandResponse{Entries: entries}, nil
+}
+
+// prepareBatchCommand validates a command of a batch, resolves its targets within scope and, unless it
+// is a dry run, assigns its ID and priority. It returns the dispatch response and the targets.
+func (s *Server) prepareBatchCommand(scope namespaceScope, req *pb.CommandRequest) (*pb.CommandDispatchResponse, []string, error) {
+	if err := s.validateCommand(req.Command); err != nil {
+		return &pb.CommandDispatchResponse{}, nil, fmt.Errorf("invalid command: %v", err)
+	}
+	impact, err := s.commandImpact(req)
+	if err != nil {
+		return &pb.CommandDispatchResponse{}, nil, fmt.Errorf("invalid command: %v", err)
+	}
+	req.Impact = impact
+	if _, _, isLockCommand, _ := command.ParseLockCommand(req.Command.Payload); isLockCommand || req.Lock != "" {
+		return &pb.CommandDispatchResponse{}, nil, fmt.Errorf("host locks are not supported in command batches")
+	}
+
+	targets := s.scopedMinionIDs(scope, s.minionRegistry.FindTargetMinions(req))
+	if len(targets) == 0 {
+		return &pb.CommandDispatchResponse{DryRun: req.DryRun}, nil, nil
+	}
+
+	// Skip the minions unable to execute the command family, or running a handler too old for it
+	targets, skipped := s.filterCapableTargets(req.Command, targets, s.logger)
+	targets, incompatible := s.filterCompatibleTargets(req.Command, targets, s.logger)
+	skipped = append(skipped, incompatible...)
+	if len(targets) == 0 {
+		return &pb.CommandDispatchResponse{DryRun: req.DryRun, SkippedMinionIds: skipped}, nil, nil
+	}
+
+	reason, confirmToken, err := s.pendingConfirmation(req)
+	if err != nil {
+		return &pb.CommandDispatchResponse{}, nil, err
+	}
+	approvalReason := s.approvals.Match(req.Command.Payload, req.Impact, targets, s.minionTags)
+	unversioned, requiredVersion := s.unversionedTargets(req.Command, targets)
+	if req.DryRun {
+		return &pb.CommandDispatchResponse{
+			Accepted:             true,
+			TargetMinionIds:      targets,
+			DryRun:               true,
+			UnversionedMinionIds: unversioned,
+			RequiredVersion:      requiredVersion,
+			HeldMinionIds:        s.heldTargets(req, targets),
+			SkippedMinionIds:     skipped,
+			ConfirmationRequired: reason != "",
+			ConfirmToken:         confirmToken,
+			DestructiveReason:    reason,
+			ApprovalRequired:     approvalReason != "",
+			ApprovalReason:       approvalReason,
+			Impact:               req.Impact,
+		}, targets, nil
+	}
+	if reason != "" {
+		return &pb.CommandDispatchResponse{
+			TargetMinionIds:      targets,
+			SkippedMinionIds:     skipped,
+			ConfirmationRequired: true,
+			ConfirmToken:         confirmToken,
+			DestructiveReason:    reason,
+			Impact:               req.Impact,
+		}, nil, fmt.Errorf("destructive command (%s) requires confirmation", reason)
+	}
+	if approvalReason != "" {
+		return &pb.CommandDispatchResponse{
+			TargetMinionIds:  targets,
+			SkippedMinionIds: skipped,
+			ApprovalRequired: true,
+			ApprovalReason:   approvalReason,
+			Impact:           req.Impact,
+		}, nil, fmt.Errorf("command requiring approval (%s) must be sent alone", approvalReason)
+	}
+
+	commandID := generateMinionID()
+	req.Command.Id = commandID
+	ensureTraceID(req.Command, s.logger)
+	req.Command.Priority = req.Priority
+	if isEmergency(req) {
+		req.Command.Priority = pb.CommandPriority_EMERGENCY
+	}
+	s.trackCommand(commandID, req.Command.Payload, targets)
+
+	return &pb.CommandDispatchResponse{
+		Accepted:             true,
+		CommandId:            commandID,
+		TargetMinionIds:      targets,
+		SkippedMinionIds:     skipped,
+		UnversionedMinionIds: unversioned,
+		RequiredVersion:      requiredVersion,
+		TraceId:              req.Command.TraceId,
+		Impact:               req.Impact,
+	}, targets, nil
+}
@@ -0,0 +1,95 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestBatchSendCommand(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	registry := server.GetMinionRegistryImpl()
+	for _, id := range []string{"minion-1", "minion-2"} {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(10),
+		}
+	}
+
+	// The three stored commands are inserted in a single transaction
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare("INSERT INTO commands")
+	for i := 0; i < 3; i++ {
+		prepared.ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	response, err := server.BatchSendCommand(context.Background(), &pb.BatchCommandRequest{
+		Requests: []*pb.CommandRequest{
+			{Command: &pb.Command{Payload: "uptime"}},
+			{MinionIds: []string{"minion-2"}, Command: &pb.Command{Payload: "df -h"}},
+			{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: ""}},
+			{MinionIds: []string{"unknown"}, Command: &pb.Command{Payload: "uptime"}},
+			{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: "reboot"}, DryRun: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BatchSendCommand failed: %v", err)
+	}
+	if len(response.Entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d", len(response.Entries))
+	}
+
+	if entry := response.Entries[0]; !entry.Response.Accepted || len(entry.Response.TargetMinionIds) != 2 || entry.Error != "" {
+		t.Errorf("Expected first command accepted for both minions, got %+v", entry)
+	}
+	if entry := response.Entries[1]; !entry.Response.Accepted || entry.Response.CommandId == response.Entries[0].Response.CommandId {
+		t.Errorf("Expected second command accepted with its own ID, got %+v", entry)
+	}
+	if entry := response.Entries[2]; entry.Response.Accepted || entry.Error == "" {
+		t.Errorf("Expected invalid command rejected, got %+v", entry)
+	}
+	if entry := response.Entries[3]; entry.Response.Accepted || entry.Error != "" {
+		t.Errorf("Expected command without target not accepted, got %+v", entry)
+	}
+	if entry := response.Entries[4]; !entry.Response.DryRun || entry.Response.CommandId != "" {
+		t.Errorf("Expected dry run without command ID, got %+v", entry)
+	}
+
+	if got := registry.minions["minion-1"].Commands.Len(); got != 1 {
+		t.Errorf("Expected 1 command queued for minion-1, got %d", got)
+	}
+	if got := registry.minions["minion-2"].Commands.Len(); got != 2 {
+		t.Errorf("Expected 2 commands queued for minion-2, got %d", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestBatchSendCommandLimits(t *testing.T) {
+	server := createTestServer(nil)
+
+	if _, err := server.BatchSendCommand(context.Background(), &pb.BatchCommandRequest{}); err == nil {
+		t.Error("Expected empty batch to be rejected")
+	}
+
+	requests := make([]*pb.CommandRequest, maxBatchCommands+1)
+	for i := range requests {
+		requests[i] = &pb.CommandRequest{Command: &pb.Command{Payload: "uptime"}}
+	}
+	if _, err := server.BatchSendCommand(context.Background(), &pb.BatchCommandRequest{Requests: requests}); err == nil {
+		t.Error("Expected oversized batch to be rejected")
+	}
+}
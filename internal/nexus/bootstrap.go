@@ -0,0 +1,258 @@
+package nexus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultBootstrapTTL is how long a bootstrap token remains valid when no TTL is requested
+	defaultBootstrapTTL = time.Hour
+	// maxBootstrapTTL bounds the validity of a bootstrap token
+	maxBootstrapTTL = 24 * time.Hour
+)
+
+// bootstrapPlatforms lists the operating systems and architectures minion binaries are built for
+var bootstrapPlatforms = map[string][]string{
+	"linux":   {"amd64", "arm64"},
+	"darwin":  {"amd64", "arm64"},
+	"windows": {"amd64", "arm64"},
+}
+
+// ErrBootstrapToken is returned for an unknown, expired or already used bootstrap token
+var ErrBootstrapToken = errors.New("invalid or expired bootstrap token")
+
+// BootstrapGrant is what a bootstrap token allows to download
+type BootstrapGrant struct {
+	OS      string
+	Arch    string
+	Expires time.Time
+}
+
+// Platform returns the name of the minion binary of the grant, as served under binaries/minion
+func (g BootstrapGrant) Platform() string {
+	platform := g.OS + "-" + g.Arch
+	if g.OS == "windows" {
+		platform += ".exe"
+	}
+	return platform
+}
+
+// BootstrapProvisioner issues one-time tokens letting a new host download a minion install
+// script and binary from the web server. A token can fetch the script until it expires, and
+// is consumed by the binary download.
+type BootstrapProvisioner struct {
+	baseURL    string // public URL of the web server, without trailing slash
+	nexusHost  string // host minions connect to
+	minionPort int
+
+	mu     sync.Mutex
+	tokens map[string]BootstrapGrant
+}
+
+// NewBootstrapProvisioner creates a provisioner serving installs from the web server at baseURL,
+// the installed minions connecting to the same host on minionPort
+func NewBootstrapProvisioner(baseURL string, minionPort int) (*BootstrapProvisioner, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		// Install scripts fetched over plain HTTP could be swapped on the way to run as root
+		return nil, fmt.Errorf("invalid bootstrap URL %q: must be an https:// URL", baseURL)
+	}
+	return &BootstrapProvisioner{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		nexusHost:  u.Hostname(),
+		minionPort: minionPort,
+		tokens:     make(map[string]BootstrapGrant),
+	}, nil
+}
+
+// validateBootstrapPlatform checks that a minion binary is built for os and arch
+func validateBootstrapPlatform(os, arch string) error {
+	archs, exists := bootstrapPlatforms[os]
+	if !exists {
+		return fmt.Errorf("unsupported OS %q: must be linux, darwin or windows", os)
+	}
+	for _, a := range archs {
+		if a == arch {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported architecture %q: must be %s", arch, strings.Join(archs, " or "))
+}
+
+// Issue returns a new token granting the install of a minion for os and arch during ttl
+func (p *BootstrapProvisioner) Issue(os, arch string, ttl time.Duration) (string, BootstrapGrant, error) {
+	if err := validateBootstrapPlatform(os, arch); err != nil {
+		return "", BootstrapGrant{}, err
+	}
+	switch {
+	case ttl < 0 || ttl > maxBootstrapTTL:
+		return "", BootstrapGrant{}, fmt.Errorf("TTL must be between 0 and %s", maxBootstrapTTL)
+	case ttl == 0:
+		ttl = defaultBootstrapTTL
+	}
+
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", BootstrapGrant{}, fmt.Errorf("failed to generate bootstrap token: %w", err)
+	}
+	token := hex.EncodeToString(bytes)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for t, grant := range p.tokens {
+		if now.After(grant.Expires) {
+			delete(p.tokens, t)
+		}
+	}
+	grant := BootstrapGrant{OS: os, Arch: arch, Expires: now.Add(ttl)}
+	p.tokens[token] = grant
+	return token, grant, nil
+}
+
+// lookup returns the grant of a valid token, consuming it if consume is set
+func (p *BootstrapProvisioner) lookup(token string, consume bool) (BootstrapGrant, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	grant, exists := p.tokens[token]
+	if !exists || time.Now().After(grant.Expires) {
+		return BootstrapGrant{}, ErrBootstrapToken
+	}
+	if consume {
+		delete(p.tokens, token)
+	}
+	return grant, nil
+}
+
+// ScriptName returns the name of the install script for os
+func (p *BootstrapProvisioner) ScriptName(os string) string {
+	if os == "windows" {
+		return "install.ps1"
+	}
+	return "install.sh"
+}
+
+// URL returns the install script URL of token
+func (p *BootstrapProvisioner) URL(token string, grant BootstrapGrant) string {
+	return fmt.Sprintf("%s/bootstrap/%s/%s", p.baseURL, token, p.ScriptName(grant.OS))
+}
+
+// Script returns the install script name of a valid token, which must be the script of its OS.
+// The script downloads the minion binary with the same token and starts it against Nexus.
+// The token is not consumed.
+func (p *BootstrapProvisioner) Script(token, name string) (string, error) {
+	grant, err := p.lookup(token, false)
+	if err != nil {
+		return "", err
+	}
+	if name != p.ScriptName(grant.OS) {
+		return "", ErrBootstrapToken
+	}
+
+	binaryURL := fmt.Sprintf("%s/bootstrap/%s/minion", p.baseURL, token)
+	if grant.OS == "windows" {
+		return fmt.Sprintf(powershellInstallScript, grant.Platform(), binaryURL, p.nexusHost, p.minionPort), nil
+	}
+	return fmt.Sprintf(shellInstallScript, grant.Platform(), binaryURL, binaryURL, p.nexusHost, p.minionPort), nil
+}
+
+// Redeem consumes token, returning the minion binary it grants
+func (p *BootstrapProvisioner) Redeem(token string) (BootstrapGrant, error) {
+	return p.lookup(token, true)
+}
+
+// shellInstallScript installs and starts a minion on Linux and macOS
+const shellInstallScript = `#!/bin/sh
+# Minexus minion installer (%s), generated by Nexus
+set -e
+
+INSTALL_DIR="${MINEXUS_INSTALL_DIR:-/opt/minexus}"
+mkdir -p "$INSTALL_DIR"
+cd "$INSTALL_DIR"
+
+if command -v curl >/dev/null 2>&1; then
+	curl -fsSL -o minion "%s"
+else
+	wget -q -O minion "%s"
+fi
+chmod +x minion
+
+cat > .env.prod <<'EOF'
+NEXUS_SERVER=%s
+NEXUS_MINION_PORT=%d
+EOF
+
+MINEXUS_ENV=prod nohup ./minion > minion.log 2>&1 &
+echo "Minion installed in $INSTALL_DIR and started"
+`
+
+// powershellInstallScript installs and starts a minion on Windows
+const powershellInstallScript = `# Minexus minion installer (%s), generated by Nexus
+$ErrorActionPreference = "Stop"
+
+$dir = if ($env:MINEXUS_INSTALL_DIR) { $env:MINEXUS_INSTALL_DIR } else { Join-Path $env:ProgramFiles "Minexus" }
+New-Item -ItemType Directory -Force -Path $dir | Out-Null
+
+Invoke-WebRequest -UseBasicParsing -Uri "%s" -OutFile (Join-Path $dir "minion.exe")
+Set-Content -Path (Join-Path $dir ".env.prod") -Value "NEXUS_SERVER=%s", "NEXUS_MINION_PORT=%d"
+
+$env:MINEXUS_ENV = "prod"
+Start-Process -FilePath (Join-Path $dir "minion.exe") -WorkingDirectory $dir -WindowStyle Hidden
+Write-Output "Minion installed in $dir and started"
+`
+
+// SetBootstrapProvisioner enables the provisioning of new hosts with one-time install URLs
+func (s *Server) SetBootstrapProvisioner(provisioner *BootstrapProvisioner) {
+	s.bootstrap = provisioner
+}
+
+// BootstrapProvisioner returns the provisioner of install URLs, nil when bootstrap is disabled
+func (s *Server) BootstrapProvisioner() *BootstrapProvisioner {
+	return s.bootstrap
+}
+
+// CreateBootstrapToken returns a one-time URL installing a minion for the requested platform. The
+// minions installed joining no namespace, only administrators create them.
+func (s *Server) CreateBootstrapToken(ctx context.Context, req *pb.BootstrapRequest) (*pb.BootstrapToken, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.CreateBootstrapToken")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "creating bootstrap tokens"); err != nil {
+		return nil, err
+	}
+
+	if s.bootstrap == nil {
+		return nil, status.Error(codes.FailedPrecondition, "minion bootstrap is not enabled on Nexus (set NEXUS_BOOTSTRAP_URL)")
+	}
+
+	token, grant, err := s.bootstrap.Issue(req.Os, req.Arch, time.Duration(req.TtlSeconds)*time.Second)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	logger.Info("Bootstrap token issued", zap.String("platform", grant.Platform()), zap.Time("expires", grant.Expires))
+	return &pb.BootstrapToken{
+		Token:     token,
+		Url:       s.bootstrap.URL(token, grant),
+		Os:        grant.OS,
+		Arch:      grant.Arch,
+		ExpiresAt: grant.Expires.Unix(),
+	}, nil
+}
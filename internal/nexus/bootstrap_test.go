@@ -0,0 +1,113 @@
+package nexus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBootstrapProvisioner(t *testing.T) {
+	if _, err := NewBootstrapProvisioner("nexus:8086", 11972); err == nil {
+		t.Error("Expected an error for a URL without scheme")
+	}
+	if _, err := NewBootstrapProvisioner("http://nexus.example.com:8086", 11972); err == nil {
+		t.Error("Expected an error for a plain HTTP URL")
+	}
+	provisioner, err := NewBootstrapProvisioner("https://nexus.example.com:8086/", 11972)
+	if err != nil {
+		t.Fatalf("NewBootstrapProvisioner failed: %v", err)
+	}
+
+	for _, platform := range [][2]string{{"plan9", "amd64"}, {"linux", "386"}} {
+		if _, _, err := provisioner.Issue(platform[0], platform[1], 0); err == nil {
+			t.Errorf("Expected %s/%s to be rejected", platform[0], platform[1])
+		}
+	}
+	if _, _, err := provisioner.Issue("linux", "amd64", 48*time.Hour); err == nil {
+		t.Error("Expected a TTL above the maximum to be rejected")
+	}
+
+	token, grant, err := provisioner.Issue("linux", "arm64", 0)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if until := time.Until(grant.Expires); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("Expected the default TTL, token expires in %s", until)
+	}
+	if url := provisioner.URL(token, grant); url != "https://nexus.example.com:8086/bootstrap/"+token+"/install.sh" {
+		t.Errorf("Unexpected URL %s", url)
+	}
+
+	if _, err := provisioner.Script(token, "install.ps1"); err != ErrBootstrapToken {
+		t.Errorf("Expected the script of another OS to be rejected, got %v", err)
+	}
+	script, err := provisioner.Script(token, "install.sh")
+	if err != nil {
+		t.Fatalf("Script failed: %v", err)
+	}
+	for _, expected := range []string{"linux-arm64", "https://nexus.example.com:8086/bootstrap/" + token + "/minion", "NEXUS_SERVER=nexus.example.com", "NEXUS_MINION_PORT=11972"} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected %q in script:\n%s", expected, script)
+		}
+	}
+
+	// The binary download consumes the token
+	if grant, err := provisioner.Redeem(token); err != nil || grant.Platform() != "linux-arm64" {
+		t.Errorf("Expected linux-arm64, got %v, %v", grant, err)
+	}
+	if _, err := provisioner.Redeem(token); err != ErrBootstrapToken {
+		t.Errorf("Expected a used token to be rejected, got %v", err)
+	}
+	if _, err := provisioner.Script(token, "install.sh"); err != ErrBootstrapToken {
+		t.Errorf("Expected no script for a used token, got %v", err)
+	}
+
+	token, grant, _ = provisioner.Issue("windows", "amd64", time.Minute)
+	if grant.Platform() != "windows-amd64.exe" || !strings.HasSuffix(provisioner.URL(token, grant), "/install.ps1") {
+		t.Errorf("Unexpected Windows grant %v", grant)
+	}
+	provisioner.tokens[token] = BootstrapGrant{OS: "windows", Arch: "amd64", Expires: time.Now().Add(-time.Second)}
+	if _, err := provisioner.Redeem(token); err != ErrBootstrapToken {
+		t.Errorf("Expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestCreateBootstrapToken(t *testing.T) {
+	server := createTestServer(nil)
+	server.SetAdmins([]string{"alice"})
+	ctx := identityContext("alice")
+
+	_, err := server.CreateBootstrapToken(ctx, &pb.BootstrapRequest{Os: "linux", Arch: "amd64"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without bootstrap, got %v", err)
+	}
+
+	provisioner, _ := NewBootstrapProvisioner("https://nexus:8086", 11972)
+	server.SetBootstrapProvisioner(provisioner)
+
+	// Only administrators install minions
+	if _, err := server.CreateBootstrapToken(identityContext("bob"), &pb.BootstrapRequest{Os: "linux", Arch: "amd64"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a console other than an administrator, got %v", err)
+	}
+
+	_, err = server.CreateBootstrapToken(ctx, &pb.BootstrapRequest{Os: "linux", Arch: "mips"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unsupported platform, got %v", err)
+	}
+
+	resp, err := server.CreateBootstrapToken(ctx, &pb.BootstrapRequest{Os: "darwin", Arch: "arm64", TtlSeconds: 600})
+	if err != nil {
+		t.Fatalf("CreateBootstrapToken failed: %v", err)
+	}
+	if resp.Url != "https://nexus:8086/bootstrap/"+resp.Token+"/install.sh" || resp.Os != "darwin" || resp.Arch != "arm64" {
+		t.Errorf("Unexpected token %v", resp)
+	}
+	if until := time.Until(time.Unix(resp.ExpiresAt, 0)); until > 10*time.Minute || until < 9*time.Minute {
+		t.Errorf("Expected the token to expire in 10 minutes, got %s", until)
+	}
+}
@@ -0,0 +1,98 @@
+package nexus
+
+import (
+	"slices"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// filterCapableTargets splits the targets of a command between the minions able to execute it
+// and the ones lacking the command family it requires or running on a platform that can't execute it.
+// Minions advertising no capability at all, such as minions predating capability advertisement, are
+// assumed to have the command family.
+func (s *Server) filterCapableTargets(cmd *pb.Command, targets []string, logger *zap.Logger) ([]string, []string) {
+	required := command.RequiredCapability(cmd.Payload)
+	unsupported := command.UnsupportedPlatforms(cmd.Payload)
+	if required == "" && len(unsupported) == 0 {
+		return targets, nil
+	}
+
+	capable := make([]string, 0, len(targets))
+	var skipped []string
+	for _, minionID := range targets {
+		if conn, exists := s.minionRegistry.GetConnection(minionID); exists {
+			capabilities := conn.GetInfo().GetCapabilities()
+			if slices.Contains(unsupported, conn.GetInfo().GetOs()) || len(capabilities) > 0 && !command.HasCapability(capabilities, required) {
+				skipped = append(skipped, minionID)
+				continue
+			}
+		}
+		capable = append(capable, minionID)
+	}
+
+	if len(skipped) > 0 {
+		logger.Warn("COMMAND_FLOW_MONITORING: Skipping minions lacking the required capability",
+			zap.String("stage", "CAPABILITY_SKIP"),
+			zap.String("capability", required),
+			zap.Strings("unsupported_platforms", unsupported),
+			zap.Strings("skipped_minion_ids", skipped),
+			zap.Time("timestamp", time.Now()))
+	}
+	return capable, skipped
+}
+
+// filterCompatibleTargets splits the targets of a command between the minions whose handler supports
+// it and the ones reporting an older version of the command family, which would reject the command or
+// ignore its options. Minions reporting no version at all, predating command versioning, are kept.
+func (s *Server) filterCompatibleTargets(cmd *pb.Command, targets []string, logger *zap.Logger) ([]string, []string) {
+	family, required := s.commandRegistry.RequiredVersion(cmd.Payload)
+	if required == 0 {
+		return targets, nil
+	}
+
+	compatible := make([]string, 0, len(targets))
+	var incompatible []string
+	for _, minionID := range targets {
+		if conn, exists := s.minionRegistry.GetConnection(minionID); exists {
+			versions := conn.GetInfo().GetCommandVersions()
+			if len(versions) > 0 && int(versions[family]) < required {
+				incompatible = append(incompatible, minionID)
+				continue
+			}
+		}
+		compatible = append(compatible, minionID)
+	}
+
+	if len(incompatible) > 0 {
+		logger.Warn("COMMAND_FLOW_MONITORING: Skipping minions running an older command handler",
+			zap.String("stage", "VERSION_SKIP"),
+			zap.String("required_version", command.FormatVersion(family, required)),
+			zap.Strings("skipped_minion_ids", incompatible),
+			zap.Time("timestamp", time.Now()))
+	}
+	return compatible, incompatible
+}
+
+// unversionedTargets returns the targets predating command versioning when the command requires a
+// handler version, with that version, as these minions may not support the command options
+func (s *Server) unversionedTargets(cmd *pb.Command, targets []string) ([]string, string) {
+	family, required := s.commandRegistry.RequiredVersion(cmd.Payload)
+	if required == 0 {
+		return nil, ""
+	}
+
+	var unversioned []string
+	for _, minionID := range targets {
+		if conn, exists := s.minionRegistry.GetConnection(minionID); exists && len(conn.GetInfo().GetCommandVersions()) == 0 {
+			unversioned = append(unversioned, minionID)
+		}
+	}
+	if len(unversioned) == 0 {
+		return nil, ""
+	}
+	return unversioned, command.FormatVersion(family, required)
+}
@@ -0,0 +1,160 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestSendCommandSkipsIncapableMinions(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	capabilities := map[string][]string{
+		"with-docker":    {"docker-compose", "pkg:dpkg"},
+		"without-docker": {"systemd", "pkg:rpm"},
+		"legacy":         nil, // advertises nothing, assumed capable
+	}
+	for id, caps := range capabilities {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id, Tags: map[string]string{}, Capabilities: caps},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(100),
+		}
+	}
+
+	response, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "docker-compose:ps /opt/app"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !response.Accepted || len(response.TargetMinionIds) != 2 {
+		t.Errorf("Expected command accepted for 2 minions, got %+v", response)
+	}
+	if len(response.SkippedMinionIds) != 1 || response.SkippedMinionIds[0] != "without-docker" {
+		t.Errorf("Expected without-docker to be skipped, got %v", response.SkippedMinionIds)
+	}
+	if registry.minions["without-docker"].Commands.Len() != 0 {
+		t.Error("Skipped minion must not receive the command")
+	}
+
+	// Any package manager provides the pkg family
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "pkg:list openssl"},
+	})
+	if err != nil || len(response.SkippedMinionIds) != 0 || len(response.TargetMinionIds) != 3 {
+		t.Errorf("Expected pkg:list sent to all minions, got %+v (%v)", response, err)
+	}
+
+	// No capable minion: the command is not accepted
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"with-docker"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "systemctl restart nginx"},
+	})
+	if err != nil || response.Accepted || len(response.SkippedMinionIds) != 1 {
+		t.Errorf("Expected command rejected with the minion skipped, got %+v (%v)", response, err)
+	}
+}
+
+func TestSendCommandSkipsUnsupportedPlatforms(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	for id, os := range map[string]string{"linux-01": "linux", "windows-01": "windows"} {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id, Os: os, Tags: map[string]string{}},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(100),
+		}
+	}
+
+	// Windows minions can't run commands as another user, even advertising no capability
+	response, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: `{"command": "id", "run_as": "nobody"}`},
+	})
+	if err != nil || !response.Accepted || len(response.TargetMinionIds) != 1 || response.TargetMinionIds[0] != "linux-01" {
+		t.Errorf("Expected run_as sent to the Linux minion only, got %+v (%v)", response, err)
+	}
+	if len(response.SkippedMinionIds) != 1 || response.SkippedMinionIds[0] != "windows-01" {
+		t.Errorf("Expected the Windows minion skipped, got %v", response.SkippedMinionIds)
+	}
+
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"windows-01"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: `{"command": "whoami", "run_as": "nobody"}`},
+	})
+	if err != nil || response.Accepted {
+		t.Errorf("Expected run_as rejected for a Windows minion, got %+v (%v)", response, err)
+	}
+	if response, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"windows-01"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: `{"command": "whoami"}`},
+	}); err != nil || !response.Accepted {
+		t.Errorf("Expected other shell requests sent to Windows minions, got %+v (%v)", response, err)
+	}
+}
+
+func TestSendCommandSkipsOutdatedMinions(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	versions := map[string]map[string]int32{
+		"current":  {"shell": 2, "process": 2},
+		"outdated": {"shell": 1, "process": 1},
+		"legacy":   nil, // predates command versioning, dispatched with a warning
+	}
+	for id, v := range versions {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id, Tags: map[string]string{}, CommandVersions: v},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(100),
+		}
+	}
+
+	response, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		DryRun:  true,
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: `{"command": "id", "run_as": "nobody"}`},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !response.Accepted || len(response.TargetMinionIds) != 2 {
+		t.Errorf("Expected command accepted for 2 minions, got %+v", response)
+	}
+	if len(response.SkippedMinionIds) != 1 || response.SkippedMinionIds[0] != "outdated" {
+		t.Errorf("Expected outdated to be skipped, got %v", response.SkippedMinionIds)
+	}
+	if len(response.UnversionedMinionIds) != 1 || response.UnversionedMinionIds[0] != "legacy" || response.RequiredVersion != "shell v2" {
+		t.Errorf("Expected a shell v2 warning for legacy, got %v %q", response.UnversionedMinionIds, response.RequiredVersion)
+	}
+
+	// So are they in command batches
+	batch, err := server.BatchSendCommand(context.Background(), &pb.BatchCommandRequest{
+		Requests: []*pb.CommandRequest{{DryRun: true, Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: `{"command": "id", "run_as": "nobody"}`}}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entry := batch.Entries[0].Response; len(entry.TargetMinionIds) != 2 || len(entry.SkippedMinionIds) != 1 || entry.SkippedMinionIds[0] != "outdated" ||
+		len(entry.UnversionedMinionIds) != 1 || entry.RequiredVersion != "shell v2" {
+		t.Errorf("Expected outdated skipped from the batch with a shell v2 warning for legacy, got %+v", entry)
+	}
+
+	// Commands any handler supports reach every minion
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		DryRun:  true,
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "process:list"},
+	})
+	if err != nil || len(response.TargetMinionIds) != 3 || len(response.SkippedMinionIds) != 0 || len(response.UnversionedMinionIds) != 0 {
+		t.Errorf("Expected process:list sent to all minions, got %+v (%v)", response, err)
+	}
+
+	// No minion supporting the command: it is not accepted
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"outdated"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "process:top 5"},
+	})
+	if err != nil || response.Accepted || len(response.SkippedMinionIds) != 1 {
+		t.Errorf("Expected command rejected with the minion skipped, got %+v (%v)", response, err)
+	}
+}
@@ -0,0 +1,139 @@
+package nexus
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// Policies resolving the collisions of minion IDs: a host registering with the ID of a minion
+// connected from another host, typically a cloned VM image or a copied MINION_ID
+const (
+	IDConflictExisting = "existing" // the connected minion keeps the ID and the newcomer is refused, the default
+	IDConflictNewest   = "newest"   // the newcomer takes the ID over and the stream of the connected minion is closed
+)
+
+// ValidateIDConflictPolicy checks that policy is a minion ID conflict policy
+func ValidateIDConflictPolicy(policy string) error {
+	if policy != IDConflictExisting && policy != IDConflictNewest {
+		return fmt.Errorf("invalid minion ID conflict policy '%s', must be '%s' or '%s'", policy, IDConflictExisting, IDConflictNewest)
+	}
+	return nil
+}
+
+// describeHost names the host behind a registration in collision errors
+func describeHost(info *pb.HostInfo) string {
+	return fmt.Sprintf("%s (%s)", info.Hostname, info.Ip)
+}
+
+// resolveCollision checks whether a registration collides with the connected minion holding the same
+// ID: their environment fingerprints differ, a missing one differing from any other, while the minion
+// is connected, through its command stream or by polling recently. Registrations of the same host, or
+// of a host replacing a disconnected minion, don't collide. It returns the error refusing the
+// registration, nil when it may proceed; under the newest policy the stream of the connected minion is
+// closed. The caller holds the registry lock.
+func (r *MinionRegistryImpl) resolveCollision(existing *MinionConnectionImpl, hostInfo *pb.HostInfo, logger *zap.Logger) error {
+	previous := existing.Info
+	connected := existing.connected(time.Now())
+	if !connected || previous.Fingerprint == hostInfo.Fingerprint {
+		if !connected {
+			delete(r.evicted, hostInfo.Id)
+		}
+		return nil
+	}
+
+	existing.collisions++
+	existing.lastCollision = time.Now()
+	fields := []zap.Field{
+		zap.String("minion_id", hostInfo.Id),
+		zap.String("connected_host", describeHost(previous)),
+		zap.String("connected_fingerprint", previous.Fingerprint),
+		zap.String("registering_host", describeHost(hostInfo)),
+		zap.String("registering_fingerprint", hostInfo.Fingerprint),
+	}
+
+	// A host the ID was taken from can't take it back while the newcomer is connected, or both would
+	// keep evicting each other
+	policy := r.idConflict
+	if r.evicted[hostInfo.Id] == hostInfo.Fingerprint {
+		policy = IDConflictExisting
+	}
+	if policy != IDConflictNewest {
+		logger.Warn("Minion ID collision, registration refused, the connected minion keeps the ID", fields...)
+		return fmt.Errorf("minion ID %s is in use by the connected minion on %s, give this minion a unique MINION_ID",
+			hostInfo.Id, describeHost(previous))
+	}
+
+	logger.Warn("Minion ID collision, the registering minion takes the ID over, closing the stream of the connected one", fields...)
+	r.evicted[hostInfo.Id] = previous.Fingerprint
+	existing.lastPoll = time.Time{}
+	if existing.streamDead != nil {
+		r.closeReasons[existing.streamDead] = fmt.Sprintf("minion ID %s was taken over by the minion on %s, give this minion a unique MINION_ID",
+			hostInfo.Id, describeHost(hostInfo))
+		close(existing.streamDead)
+		existing.streamDead = nil
+	}
+	return nil
+}
+
+// connected reports whether a minion is connected at now: its command stream is open, or it polled
+// for commands over HTTP within longPollIdleTimeout
+func (m *MinionConnectionImpl) connected(now time.Time) bool {
+	return m.streamDead != nil || (!m.lastPoll.IsZero() && now.Sub(m.lastPoll) < longPollIdleTimeout)
+}
+
+// UpdateLastPoll records that a minion polled for commands over HTTP
+func (r *MinionRegistryImpl) UpdateLastPoll(minionID string) {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	if conn, exists := r.minions[minionID]; exists {
+		conn.LastSeen = time.Now()
+		conn.lastPoll = conn.LastSeen
+	}
+}
+
+// streamCloseReason returns why Nexus closed a command stream to hand its minion ID to another host,
+// "" when it was closed for another reason
+func (r *MinionRegistryImpl) streamCloseReason(dead <-chan struct{}) string {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	reason := r.closeReasons[dead]
+	delete(r.closeReasons, dead)
+	return reason
+}
+
+// SetIDConflictPolicy sets which minion keeps an ID claimed by two connected hosts, IDConflictExisting
+// or IDConflictNewest
+func (s *Server) SetIDConflictPolicy(policy string) error {
+	if err := ValidateIDConflictPolicy(policy); err != nil {
+		return err
+	}
+	s.GetMinionRegistryImpl().SetIDConflictPolicy(policy)
+	return nil
+}
+
+// SetIDConflictPolicy sets the policy resolving the minion ID collisions
+func (r *MinionRegistryImpl) SetIDConflictPolicy(policy string) {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	r.idConflict = policy
+}
+
+// IDCollisions returns the number of registrations of other hosts that claimed the ID of a connected
+// minion, and the time of the last one
+func (r *MinionRegistryImpl) IDCollisions(minionID string) (int, time.Time) {
+	r.minionsMu.RLock()
+	defer r.minionsMu.RUnlock()
+
+	conn, exists := r.minions[minionID]
+	if !exists {
+		return 0, time.Time{}
+	}
+	return conn.collisions, conn.lastCollision
+}
@@ -0,0 +1,97 @@
+package nexus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestMinionIDCollision(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	original := &pb.HostInfo{Id: "web-01", Hostname: "web-01", Ip: "10.0.0.1", Fingerprint: "aaa"}
+	clone := &pb.HostInfo{Id: "web-01", Hostname: "web-07", Ip: "10.0.0.7", Fingerprint: "bbb"}
+
+	if resp, err := registry.Register(original); err != nil || !resp.Success {
+		t.Fatalf("Expected the first registration accepted, got %v, %v", resp, err)
+	}
+	dead := registry.OpenStream("web-01")
+
+	// By default the connected minion keeps its ID
+	resp, err := registry.Register(clone)
+	if err != nil || resp.Success || !strings.Contains(resp.ErrorMessage, "web-01 (10.0.0.1)") {
+		t.Fatalf("Expected the clone refused, got %v, %v", resp, err)
+	}
+	if resp, _ := registry.Register(original); !resp.Success {
+		t.Errorf("Expected the heartbeat of the connected minion accepted, got %v", resp)
+	}
+	if collisions, last := registry.IDCollisions("web-01"); collisions != 1 || last.IsZero() {
+		t.Errorf("Expected the collision recorded, got %d at %v", collisions, last)
+	}
+	if conn, _ := registry.GetConnectionImpl("web-01"); conn.Info.Fingerprint != "aaa" {
+		t.Errorf("Expected the registration of the connected minion kept, got %v", conn.Info)
+	}
+
+	// Under the newest policy the clone takes the ID over and the stream of the other one is closed
+	if err := server.SetIDConflictPolicy("oldest"); err == nil {
+		t.Error("Expected an invalid policy rejected")
+	}
+	if err := server.SetIDConflictPolicy(IDConflictNewest); err != nil {
+		t.Fatalf("SetIDConflictPolicy failed: %v", err)
+	}
+	if resp, _ := registry.Register(clone); !resp.Success {
+		t.Fatalf("Expected the clone to take the ID over, got %v", resp)
+	}
+	select {
+	case <-dead:
+	default:
+		t.Fatal("Expected the stream of the evicted minion closed")
+	}
+	if reason := registry.streamCloseReason(dead); !strings.Contains(reason, "taken over by the minion on web-07 (10.0.0.7)") {
+		t.Errorf("Unexpected close reason %q", reason)
+	}
+
+	// The evicted minion can't take the ID back while the clone is connected
+	registry.OpenStream("web-01")
+	if resp, _ := registry.Register(original); resp.Success {
+		t.Error("Expected the evicted minion refused while the clone is connected")
+	}
+	if collisions, _ := registry.IDCollisions("web-01"); collisions != 3 {
+		t.Errorf("Expected 3 collisions, got %d", collisions)
+	}
+
+	// Without open stream, another host replaces the minion as before
+	registry.DisconnectStream("web-01")
+	if resp, _ := registry.Register(original); !resp.Success {
+		t.Errorf("Expected the registration accepted once the clone disconnected, got %v", resp)
+	}
+}
+
+func TestMinionIDCollisionWithoutStream(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	original := &pb.HostInfo{Id: "web-01", Hostname: "web-01", Ip: "10.0.0.1", Fingerprint: "aaa"}
+	anonymous := &pb.HostInfo{Id: "web-01", Hostname: "web-07", Ip: "10.0.0.7"}
+
+	if resp, _ := registry.Register(original); !resp.Success {
+		t.Fatalf("Expected the first registration accepted, got %v", resp)
+	}
+
+	// A minion polling over HTTP is connected between its polls
+	registry.UpdateLastPoll("web-01")
+	if resp, _ := registry.Register(anonymous); resp.Success {
+		t.Error("Expected a registration without fingerprint refused while the minion polls")
+	}
+	if resp, _ := registry.Register(original); !resp.Success {
+		t.Errorf("Expected the heartbeat of the polling minion accepted, got %v", resp)
+	}
+
+	// Once it stopped polling, another host replaces it
+	conn, _ := registry.GetConnectionImpl("web-01")
+	conn.lastPoll = time.Now().Add(-longPollIdleTimeout)
+	if resp, _ := registry.Register(anonymous); !resp.Success {
+		t.Errorf("Expected the registration accepted once the minion stopped polling, got %v", resp)
+	}
+}
@@ -0,0 +1,404 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// commandEnvLoadTimeout bounds the loading of the command environment when a command is dispatched
+const commandEnvLoadTimeout = 5 * time.Second
+
+// commandEnvRetryDelay is how long a failure to load the command environment is returned before
+// loading it again, so that dispatches don't each wait for an unavailable database
+const commandEnvRetryDelay = 30 * time.Second
+
+// commandEnvNamePattern restricts variable names to those every shell accepts
+var commandEnvNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// commandEnv caches the environment variables set for the commands of minions and tags. They are loaded
+// from the database when a command is first dispatched, and reloaded after each change.
+type commandEnv struct {
+	mu         sync.Mutex
+	loaded     bool
+	vars       []*pb.CommandEnvVar // sorted by minion ID, tag and name
+	generation uint64              // incremented by each change, discarding the loads started before
+	loading    chan struct{}       // closed when the running load completes
+	err        error               // failure of the last load, returned until retryAt
+	retryAt    time.Time
+}
+
+// validateCommandEnvVar checks that a variable targets either a minion or a tag and has a valid name
+func validateCommandEnvVar(envVar *pb.CommandEnvVar) error {
+	if (envVar.MinionId != "") == (envVar.TagKey != "") {
+		return fmt.Errorf("an environment variable must target either a minion or a tag")
+	}
+	if !commandEnvNamePattern.MatchString(envVar.Name) {
+		return fmt.Errorf("invalid environment variable name '%s'", envVar.Name)
+	}
+	if strings.ContainsRune(envVar.Value, 0) {
+		return fmt.Errorf("environment variable values cannot contain NUL characters")
+	}
+	return nil
+}
+
+// commandEnvVars returns the cached environment variables, loading them from the database if needed.
+// A single load runs at a time, outside the lock, the other callers waiting for it. Its failure is
+// returned for commandEnvRetryDelay.
+func (s *Server) commandEnvVars(ctx context.Context) ([]*pb.CommandEnvVar, error) {
+	for {
+		s.commandEnv.mu.Lock()
+		if s.commandEnv.loaded || s.dbService == nil {
+			vars := s.commandEnv.vars
+			s.commandEnv.mu.Unlock()
+			return vars, nil
+		}
+		if s.commandEnv.err != nil && time.Now().Before(s.commandEnv.retryAt) {
+			err := s.commandEnv.err
+			s.commandEnv.mu.Unlock()
+			return nil, err
+		}
+		if loading := s.commandEnv.loading; loading != nil {
+			s.commandEnv.mu.Unlock()
+			select {
+			case <-loading:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		loading := make(chan struct{})
+		s.commandEnv.loading = loading
+		generation := s.commandEnv.generation
+		s.commandEnv.mu.Unlock()
+
+		vars, err := s.dbService.GetCommandEnvVars(ctx)
+
+		s.commandEnv.mu.Lock()
+		s.commandEnv.loading = nil
+		close(loading)
+		// A change during the load makes the next call load the variables again
+		if generation == s.commandEnv.generation {
+			if err != nil {
+				s.commandEnv.err = err
+				s.commandEnv.retryAt = time.Now().Add(commandEnvRetryDelay)
+			} else {
+				s.commandEnv.vars = vars
+				s.commandEnv.loaded = true
+				s.commandEnv.err = nil
+			}
+		}
+		s.commandEnv.mu.Unlock()
+		return vars, err
+	}
+}
+
+// invalidateCommandEnv makes the next dispatch reload the environment variables
+func (s *Server) invalidateCommandEnv() {
+	s.commandEnv.mu.Lock()
+	defer s.commandEnv.mu.Unlock()
+	s.commandEnv.generation++
+	s.commandEnv.loaded = false
+	s.commandEnv.vars = nil
+	s.commandEnv.err = nil
+}
+
+// resolveCommandEnv returns the environment of the commands of a minion: the variables of its tags,
+// overridden by its own. When two tags set a variable, the tag sorting last wins.
+func resolveCommandEnv(vars []*pb.CommandEnvVar, info *pb.HostInfo) map[string]string {
+	env := make(map[string]string)
+	for _, envVar := range vars {
+		if envVar.TagKey != "" && info.Tags != nil {
+			if value, exists := info.Tags[envVar.TagKey]; exists && value == envVar.TagValue {
+				env[envVar.Name] = envVar.Value
+			}
+		}
+	}
+	for _, envVar := range vars {
+		if envVar.MinionId != "" && envVar.MinionId == info.Id {
+			env[envVar.Name] = envVar.Value
+		}
+	}
+	// Variables stored before they were protected are never sent
+	for name := range env {
+		if command.IsProtectedEnvVar(name) {
+			delete(env, name)
+		}
+	}
+	return env
+}
+
+// withCommandEnv returns the command sent to a minion, carrying the environment Nexus manages for it.
+// The environment sent by consoles is dropped, since it isn't covered by command signatures, and signed
+// commands carry none, minions verifying signatures refusing it.
+func (s *Server) withCommandEnv(info *pb.HostInfo, cmd *pb.Command, logger *zap.Logger) *pb.Command {
+	if len(cmd.Signature) > 0 {
+		if len(cmd.Env) == 0 {
+			return cmd
+		}
+		sent := proto.Clone(cmd).(*pb.Command)
+		sent.Env = nil
+		return sent
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandEnvLoadTimeout)
+	defer cancel()
+
+	vars, err := s.commandEnvVars(ctx)
+	if err != nil {
+		logger.Warn("Failed to load the command environment, command sent without it",
+			zap.String("command_id", cmd.Id),
+			zap.String("minion_id", info.Id),
+			zap.Error(err))
+	}
+	env := resolveCommandEnv(vars, info)
+	// Minions predating the command environment would ignore it
+	if len(env) > 0 && !command.SupportsCommandEnv(info.GetCommandVersions()) {
+		env = nil
+	}
+	if len(env) == 0 && len(cmd.Env) == 0 {
+		return cmd
+	}
+
+	// Commands dispatched to several minions share their request, each minion gets its own copy
+	sent := proto.Clone(cmd).(*pb.Command)
+	sent.Env = env
+	return sent
+}
+
+// commandEnvWarnings reports, for the dispatch response, whether the environment of a command couldn't
+// be loaded and the targets running it without their environment, their shell handler predating it.
+// Signed commands carry no environment.
+func (s *Server) commandEnvWarnings(cmd *pb.Command, targets []string, logger *zap.Logger) (bool, []string) {
+	if len(cmd.Signature) > 0 || s.dbService == nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandEnvLoadTimeout)
+	defer cancel()
+
+	vars, err := s.commandEnvVars(ctx)
+	if err != nil {
+		return true, nil
+	}
+	if len(vars) == 0 {
+		return false, nil
+	}
+
+	var unsupported []string
+	for _, minionID := range targets {
+		conn, exists := s.minionRegistry.GetConnection(minionID)
+		if !exists {
+			continue
+		}
+		info := conn.GetInfo()
+		if !command.SupportsCommandEnv(info.GetCommandVersions()) && len(resolveCommandEnv(vars, info)) > 0 {
+			unsupported = append(unsupported, minionID)
+		}
+	}
+	if len(unsupported) > 0 {
+		logger.Warn("COMMAND_FLOW_MONITORING: Minions running the command without their environment",
+			zap.String("stage", "ENV_UNSUPPORTED"),
+			zap.String("command_id", cmd.Id),
+			zap.Strings("minion_ids", unsupported),
+			zap.Time("timestamp", time.Now()))
+	}
+	return false, unsupported
+}
+
+// checkCommandEnvScope verifies the console may change the variables of the target of envVar
+func (s *Server) checkCommandEnvScope(ctx context.Context, envVar *pb.CommandEnvVar) error {
+	if envVar.MinionId != "" {
+		return s.checkMinionScope(ctx, envVar.MinionId)
+	}
+	return requireAllNamespaces(ctx, "a tag environment variable")
+}
+
+// SetCommandEnv creates or replaces an environment variable of the shell commands of a minion or tag
+func (s *Server) SetCommandEnv(ctx context.Context, req *pb.CommandEnvVar) (*pb.Ack, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.SetCommandEnv")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return &pb.Ack{Success: false}, status.Error(codes.Unavailable, "command environment requires a database")
+	}
+	if err := validateCommandEnvVar(req); err != nil {
+		return &pb.Ack{Success: false}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// Variables set before they were protected can still be unset
+	if command.IsProtectedEnvVar(req.Name) {
+		return &pb.Ack{Success: false}, status.Errorf(codes.InvalidArgument, "environment variable '%s' can't be set, it changes how commands are loaded or run", req.Name)
+	}
+	if err := s.checkCommandEnvScope(ctx, req); err != nil {
+		return &pb.Ack{Success: false}, err
+	}
+
+	if err := s.dbService.SetCommandEnvVar(ctx, req); err != nil {
+		logger.Error("Failed to set command environment variable", zap.String("name", req.Name), zap.Error(err))
+		return &pb.Ack{Success: false}, status.Error(codes.Internal, "failed to set the environment variable")
+	}
+	s.invalidateCommandEnv()
+
+	// Values are not logged, they may hold credentials such as proxy URLs
+	logger.Info("Command environment variable set",
+		zap.String("name", req.Name),
+		zap.String("minion_id", req.MinionId),
+		zap.String("tag", commandEnvTag(req)))
+	return &pb.Ack{Success: true}, nil
+}
+
+// UnsetCommandEnv deletes an environment variable of the shell commands of a minion or tag
+func (s *Server) UnsetCommandEnv(ctx context.Context, req *pb.CommandEnvVar) (*pb.Ack, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.UnsetCommandEnv")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return &pb.Ack{Success: false}, status.Error(codes.Unavailable, "command environment requires a database")
+	}
+	if err := validateCommandEnvVar(req); err != nil {
+		return &pb.Ack{Success: false}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.checkCommandEnvScope(ctx, req); err != nil {
+		return &pb.Ack{Success: false}, err
+	}
+
+	deleted, err := s.dbService.DeleteCommandEnvVar(ctx, req)
+	if err != nil {
+		logger.Error("Failed to unset command environment variable", zap.String("name", req.Name), zap.Error(err))
+		return &pb.Ack{Success: false}, status.Error(codes.Internal, "failed to unset the environment variable")
+	}
+	if !deleted {
+		return &pb.Ack{Success: false}, status.Errorf(codes.NotFound, "environment variable %s not found", req.Name)
+	}
+	s.invalidateCommandEnv()
+
+	logger.Info("Command environment variable unset",
+		zap.String("name", req.Name),
+		zap.String("minion_id", req.MinionId),
+		zap.String("tag", commandEnvTag(req)))
+	return &pb.Ack{Success: true}, nil
+}
+
+// ListCommandEnv returns the environment variables of the shell commands. Consoles restricted to
+// namespaces only see the variables of their minions.
+func (s *Server) ListCommandEnv(ctx context.Context, empty *pb.Empty) (*pb.CommandEnvList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListCommandEnv")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "command environment requires a database")
+	}
+	vars, err := s.commandEnvVars(ctx)
+	if err != nil {
+		logger.Error("Failed to get command environment", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get the command environment")
+	}
+
+	scope := consoleScope(ctx)
+	list := &pb.CommandEnvList{Vars: make([]*pb.CommandEnvVar, 0, len(vars))}
+	for _, envVar := range vars {
+		if scope.restricted() && (envVar.MinionId == "" || len(s.scopedMinionIDs(scope, []string{envVar.MinionId})) == 0) {
+			continue
+		}
+		list.Vars = append(list.Vars, proto.Clone(envVar).(*pb.CommandEnvVar))
+	}
+	return list, nil
+}
+
+// commandEnvTag formats the tag targeted by a variable, empty for a minion variable
+func commandEnvTag(envVar *pb.CommandEnvVar) string {
+	if envVar.TagKey == "" {
+		return ""
+	}
+	return envVar.TagKey + "=" + envVar.TagValue
+}
+
+// SetCommandEnvVar creates or replaces an environment variable of the commands of a minion or tag.
+func (d *DatabaseServiceImpl) SetCommandEnvVar(ctx context.Context, envVar *pb.CommandEnvVar) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot set environment variable %s", envVar.Name)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.SetCommandEnvVar")
+	defer logging.FuncExit(logger, start)
+
+	_, err := d.db.ExecContext(ctx,
+		`INSERT INTO command_env (minion_id, tag_key, tag_value, name, value)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (minion_id, tag_key, tag_value, name)
+		DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP`,
+		envVar.MinionId, envVar.TagKey, envVar.TagValue, envVar.Name, envVar.Value)
+	if err != nil {
+		return fmt.Errorf("failed to set environment variable: %v", err)
+	}
+	return nil
+}
+
+// DeleteCommandEnvVar deletes an environment variable of the commands of a minion or tag, reporting whether it existed.
+func (d *DatabaseServiceImpl) DeleteCommandEnvVar(ctx context.Context, envVar *pb.CommandEnvVar) (bool, error) {
+	if d == nil || d.db == nil {
+		return false, fmt.Errorf("database service unavailable - cannot delete environment variable %s", envVar.Name)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.DeleteCommandEnvVar")
+	defer logging.FuncExit(logger, start)
+
+	result, err := d.db.ExecContext(ctx,
+		`DELETE FROM command_env WHERE minion_id = $1 AND tag_key = $2 AND tag_value = $3 AND name = $4`,
+		envVar.MinionId, envVar.TagKey, envVar.TagValue, envVar.Name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete environment variable: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete environment variable: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetCommandEnvVars retrieves every environment variable of the commands, sorted by minion ID, tag and
+// name. They are read from the primary, being reloaded right after each change.
+func (d *DatabaseServiceImpl) GetCommandEnvVars(ctx context.Context) ([]*pb.CommandEnvVar, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get environment variables")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetCommandEnvVars")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT minion_id, tag_key, tag_value, name, value, EXTRACT(EPOCH FROM updated_at)::bigint
+		FROM command_env
+		ORDER BY minion_id, tag_key, tag_value, name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query environment variables: %v", err)
+	}
+	defer rows.Close()
+
+	var vars []*pb.CommandEnvVar
+	for rows.Next() {
+		var envVar pb.CommandEnvVar
+		if err := rows.Scan(&envVar.MinionId, &envVar.TagKey, &envVar.TagValue, &envVar.Name, &envVar.Value, &envVar.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan environment variable: %v", err)
+		}
+		vars = append(vars, &envVar)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading environment variables: %v", err)
+	}
+
+	logger.Debug("Retrieved environment variables", zap.Int("count", len(vars)))
+	return vars, nil
+}
@@ -0,0 +1,176 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var commandEnvColumns = []string{"minion_id", "tag_key", "tag_value", "name", "value", "updated_at"}
+
+func TestSetCommandEnv(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	invalid := []*pb.CommandEnvVar{
+		{Name: "HTTP_PROXY", Value: "http://proxy:3128"},
+		{MinionId: "minion-1", TagKey: "env", TagValue: "prod", Name: "HTTP_PROXY"},
+		{MinionId: "minion-1", Name: "1PROXY"},
+		{MinionId: "minion-1", Name: "HTTP PROXY"},
+		{MinionId: "minion-1", Name: "PROXY", Value: "a\x00b"},
+		{MinionId: "minion-1", Name: "LD_PRELOAD", Value: "/tmp/evil.so"},
+		{MinionId: "minion-1", Name: "BASH_ENV", Value: "/tmp/evil.sh"},
+		{TagKey: "env", TagValue: "prod", Name: "PATH", Value: "/tmp"},
+	}
+	for _, envVar := range invalid {
+		if _, err := server.SetCommandEnv(context.Background(), envVar); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for %v, got %v", envVar, err)
+		}
+	}
+
+	mock.ExpectExec("INSERT INTO command_env").
+		WithArgs("", "env", "prod", "HTTP_PROXY", "http://proxy:3128").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	ack, err := server.SetCommandEnv(context.Background(), &pb.CommandEnvVar{TagKey: "env", TagValue: "prod", Name: "HTTP_PROXY", Value: "http://proxy:3128"})
+	if err != nil || !ack.Success {
+		t.Fatalf("Unexpected failure: %v %v", ack, err)
+	}
+
+	mock.ExpectExec("DELETE FROM command_env").
+		WithArgs("minion-1", "", "", "HTTP_PROXY").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := server.UnsetCommandEnv(context.Background(), &pb.CommandEnvVar{MinionId: "minion-1", Name: "HTTP_PROXY"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := createTestServer(nil).SetCommandEnv(context.Background(), &pb.CommandEnvVar{MinionId: "minion-1", Name: "A"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
+
+func TestCommandEnvDispatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	prod := &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: map[string]string{"env": "prod", "dc": "par1"}, CommandVersions: map[string]int32{"shell": 3}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+	dev := &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-2", Tags: map[string]string{"env": "dev"}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+
+	// Loaded once, sorted by minion ID, tag and name
+	mock.ExpectQuery("SELECT minion_id, tag_key, tag_value, name, value").
+		WillReturnRows(sqlmock.NewRows(commandEnvColumns).
+			AddRow("", "dc", "par1", "MIRROR", "mirror.par1", int64(1700000000)).
+			AddRow("", "env", "prod", "HTTP_PROXY", "http://proxy:3128", int64(1700000000)).
+			AddRow("", "env", "prod", "MIRROR", "mirror.prod", int64(1700000000)).
+			AddRow("minion-1", "", "", "HTTP_PROXY", "http://local:3128", int64(1700000000)).
+			AddRow("minion-1", "", "", "LD_PRELOAD", "/tmp/evil.so", int64(1700000000)))
+
+	cmd := &pb.Command{Id: "cmd-1", Payload: "env", Env: map[string]string{"LD_PRELOAD": "/tmp/evil.so"}}
+	for _, conn := range []*MinionConnectionImpl{prod, dev} {
+		if _, err := server.dispatchToConnection(conn, conn.Info.Id, cmd, false, zap.NewNop()); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+	}
+
+	sent, _ := prod.Commands.Pop()
+	if len(sent.Env) != 2 || sent.Env["HTTP_PROXY"] != "http://local:3128" || sent.Env["MIRROR"] != "mirror.prod" {
+		t.Errorf("Unexpected environment of minion-1: %v", sent.Env)
+	}
+	sent, _ = dev.Commands.Pop()
+	if len(sent.Env) != 0 {
+		t.Errorf("Expected the environment sent by the console dropped, got %v", sent.Env)
+	}
+	if len(cmd.Env) != 1 {
+		t.Errorf("Expected the request left unchanged, got %v", cmd.Env)
+	}
+
+	// Signed commands carry no environment, it isn't covered by their signature
+	signed := &pb.Command{Id: "cmd-2", Payload: "env", Signature: []byte("signature")}
+	if _, err := server.dispatchToConnection(prod, "minion-1", signed, false, zap.NewNop()); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if sent, _ := prod.Commands.Pop(); len(sent.Env) != 0 {
+		t.Errorf("Expected no environment for a signed command, got %v", sent.Env)
+	}
+
+	// Changes reload the variables at the next dispatch
+	mock.ExpectExec("DELETE FROM command_env").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := server.UnsetCommandEnv(context.Background(), &pb.CommandEnvVar{MinionId: "minion-1", Name: "HTTP_PROXY"}); err != nil {
+		t.Fatalf("UnsetCommandEnv failed: %v", err)
+	}
+	mock.ExpectQuery("SELECT minion_id").WillReturnRows(sqlmock.NewRows(commandEnvColumns))
+	if _, err := server.dispatchToConnection(prod, "minion-1", cmd, false, zap.NewNop()); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if sent, _ := prod.Commands.Pop(); len(sent.Env) != 0 {
+		t.Errorf("Expected no environment left, got %v", sent.Env)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCommandEnvLoadFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	// A failure is returned until the retry delay elapses, without querying the database again
+	mock.ExpectQuery("SELECT minion_id").WillReturnError(fmt.Errorf("connection refused"))
+	for i := 0; i < 2; i++ {
+		if _, err := server.commandEnvVars(context.Background()); err == nil {
+			t.Fatal("Expected the load failure to be returned")
+		}
+	}
+	if unavailable, _ := server.commandEnvWarnings(&pb.Command{Id: "cmd-1", Payload: "env"}, []string{"minion-1"}, zap.NewNop()); !unavailable {
+		t.Error("Expected the dispatch response to report the missing environment")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	// Minions predating the command environment are reported
+	server.commandEnv.retryAt = time.Now()
+	server.minionRegistry = NewMinionRegistry(nil, zap.NewNop())
+	mock.ExpectQuery("SELECT minion_id").WillReturnRows(sqlmock.NewRows(commandEnvColumns).
+		AddRow("", "env", "prod", "HTTP_PROXY", "http://proxy:3128", int64(1700000000)))
+	for _, info := range []*pb.HostInfo{
+		{Id: "minion-1", Tags: map[string]string{"env": "prod"}, CommandVersions: map[string]int32{"shell": 2}},
+		{Id: "minion-2", Tags: map[string]string{"env": "prod"}, CommandVersions: map[string]int32{"shell": 3}},
+		{Id: "minion-3", Tags: map[string]string{"env": "dev"}},
+	} {
+		server.minionRegistry.Register(info)
+	}
+	unavailable, unsupported := server.commandEnvWarnings(&pb.Command{Id: "cmd-2", Payload: "env"}, []string{"minion-1", "minion-2", "minion-3"}, zap.NewNop())
+	if unavailable || len(unsupported) != 1 || unsupported[0] != "minion-1" {
+		t.Errorf("Expected minion-1 reported without its environment, got %v %v", unavailable, unsupported)
+	}
+}
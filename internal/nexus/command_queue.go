@@ -0,0 +1,220 @@
+package nexus
+
+import (
+	"sync"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// defaultCommandQueueSize is the number of commands that can wait for a minion
+const defaultCommandQueueSize = 100
+
+// emergencyQueueSize is the number of EMERGENCY commands that can wait for a minion on top of the
+// queue capacity, so that emergencies are admitted into a full queue without growing it unbounded
+const emergencyQueueSize = 10
+
+// priorityLevels lists priorities from the lowest to the highest dispatch rank
+var priorityLevels = []pb.CommandPriority{
+	pb.CommandPriority_LOW,
+	pb.CommandPriority_NORMAL,
+	pb.CommandPriority_HIGH,
+	pb.CommandPriority_EMERGENCY,
+}
+
+// priorityRank returns the dispatch rank of a priority, unknown values rank as NORMAL
+func priorityRank(priority pb.CommandPriority) int {
+	for rank, level := range priorityLevels {
+		if level == priority {
+			return rank
+		}
+	}
+	return priorityRank(pb.CommandPriority_NORMAL)
+}
+
+// isEmergency reports whether a request is an emergency, emergencies bypass maintenance windows
+func isEmergency(req *pb.CommandRequest) bool {
+	return req.Emergency || req.Priority == pb.CommandPriority_EMERGENCY
+}
+
+// CommandQueue is a bounded per-minion command queue delivering higher priority
+// commands first, in FIFO order within the same priority.
+// EMERGENCY commands have their own budget of emergencyQueueSize commands, accepted even when
+// the queue is full.
+// With an in-flight limit, commands stay queued while the minion has that many
+// dispatched commands without result.
+type CommandQueue struct {
+	mu       sync.Mutex
+	levels   [][]*pb.Command // indexed by priority rank
+	size     int
+	capacity int
+	closed   bool
+	ready    chan struct{}
+	limit    int             // maximum dispatched commands without result, 0: unlimited
+	inFlight map[string]bool // IDs of dispatched commands without result, tracked when limited
+}
+
+// NewCommandQueue creates a queue holding up to capacity commands
+func NewCommandQueue(capacity int) *CommandQueue {
+	return &CommandQueue{
+		levels:   make([][]*pb.Command, len(priorityLevels)),
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// Push queues a command according to its priority without blocking.
+// It returns false when the queue, or the emergency budget for EMERGENCY commands, is full or closed.
+func (q *CommandQueue) Push(cmd *pb.Command) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false
+	}
+	rank := priorityRank(cmd.Priority)
+	if cmd.Priority == pb.CommandPriority_EMERGENCY {
+		if len(q.levels[rank]) >= emergencyQueueSize {
+			return false
+		}
+	} else if q.size >= q.capacity {
+		return false
+	}
+
+	q.levels[rank] = append(q.levels[rank], cmd)
+	q.size++
+	q.signal()
+	return true
+}
+
+// Pop removes and returns the next command to dispatch.
+// It returns false when the queue is empty or the in-flight limit is reached.
+func (q *CommandQueue) Pop() (*pb.Command, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.limit > 0 && len(q.inFlight) >= q.limit {
+		return nil, false
+	}
+
+	for rank := len(q.levels) - 1; rank >= 0; rank-- {
+		if len(q.levels[rank]) == 0 {
+			continue
+		}
+		cmd := q.levels[rank][0]
+		q.levels[rank][0] = nil
+		q.levels[rank] = q.levels[rank][1:]
+		q.size--
+		if q.limit > 0 {
+			q.inFlight[cmd.Id] = true
+		}
+		return cmd, true
+	}
+	return nil, false
+}
+
+// Drain removes and returns all queued commands in dispatch order
+func (q *CommandQueue) Drain() []*pb.Command {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	drained := make([]*pb.Command, 0, q.size)
+	for rank := len(q.levels) - 1; rank >= 0; rank-- {
+		drained = append(drained, q.levels[rank]...)
+		q.levels[rank] = nil
+	}
+	q.size = 0
+	return drained
+}
+
+// SetLimit sets the maximum number of dispatched commands without result, 0 for unlimited
+func (q *CommandQueue) SetLimit(limit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.limit = limit
+	if limit == 0 {
+		q.inFlight = make(map[string]bool)
+	}
+	q.signal()
+}
+
+// Limit returns the in-flight limit, 0 meaning unlimited
+func (q *CommandQueue) Limit() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.limit
+}
+
+// Complete records the result of a dispatched command, freeing its in-flight slot
+func (q *CommandQueue) Complete(commandID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inFlight[commandID] {
+		delete(q.inFlight, commandID)
+		q.signal()
+	}
+}
+
+// ResetInFlight forgets the dispatched commands, whose results may never come
+// once the stream they were sent on is gone
+func (q *CommandQueue) ResetInFlight() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight = make(map[string]bool)
+	q.signal()
+}
+
+// InFlight returns the number of tracked commands dispatched without result
+func (q *CommandQueue) InFlight() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.inFlight)
+}
+
+// Ready returns a channel signaled when commands are pushed or the queue is closed
+func (q *CommandQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Close stops accepting commands, queued commands can still be popped
+func (q *CommandQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.signal()
+}
+
+// Closed reports whether the queue was closed
+func (q *CommandQueue) Closed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.closed
+}
+
+// Len returns the number of queued commands
+func (q *CommandQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.size
+}
+
+// Cap returns the queue capacity
+func (q *CommandQueue) Cap() int {
+	return q.capacity
+}
+
+// signal wakes up the dispatcher without blocking. The caller must hold q.mu
+func (q *CommandQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
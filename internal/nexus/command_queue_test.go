@@ -0,0 +1,197 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestCommandQueueOrdering(t *testing.T) {
+	queue := NewCommandQueue(10)
+	for _, cmd := range []*pb.Command{
+		{Id: "bulk-1", Priority: pb.CommandPriority_LOW},
+		{Id: "normal-1"},
+		{Id: "bulk-2", Priority: pb.CommandPriority_LOW},
+		{Id: "high-1", Priority: pb.CommandPriority_HIGH},
+		{Id: "normal-2", Priority: pb.CommandPriority_NORMAL},
+		{Id: "kill", Priority: pb.CommandPriority_EMERGENCY},
+	} {
+		if !queue.Push(cmd) {
+			t.Fatalf("Push(%s) failed", cmd.Id)
+		}
+	}
+
+	expected := []string{"kill", "high-1", "normal-1", "normal-2", "bulk-1", "bulk-2"}
+	for _, id := range expected {
+		cmd, ok := queue.Pop()
+		if !ok || cmd.Id != id {
+			t.Fatalf("Expected %s, got %v", id, cmd)
+		}
+	}
+	if _, ok := queue.Pop(); ok || queue.Len() != 0 {
+		t.Error("Expected queue to be empty")
+	}
+}
+
+func TestCommandQueueCapacity(t *testing.T) {
+	queue := NewCommandQueue(2)
+	queue.Push(&pb.Command{Id: "1", Priority: pb.CommandPriority_LOW})
+	queue.Push(&pb.Command{Id: "2", Priority: pb.CommandPriority_LOW})
+
+	if queue.Push(&pb.Command{Id: "3", Priority: pb.CommandPriority_HIGH}) {
+		t.Error("Expected non-emergency push to fail on a full queue")
+	}
+	if !queue.Push(&pb.Command{Id: "4", Priority: pb.CommandPriority_EMERGENCY}) {
+		t.Error("Expected emergency push to succeed on a full queue")
+	}
+	if queue.Len() != 3 || queue.Cap() != 2 {
+		t.Errorf("Unexpected queue size %d/%d", queue.Len(), queue.Cap())
+	}
+
+	// Emergencies have their own bounded budget
+	for i := 1; i < emergencyQueueSize; i++ {
+		if !queue.Push(&pb.Command{Id: "emergency", Priority: pb.CommandPriority_EMERGENCY}) {
+			t.Fatalf("Expected emergency push %d to succeed within the emergency budget", i+1)
+		}
+	}
+	if queue.Push(&pb.Command{Id: "emergency", Priority: pb.CommandPriority_EMERGENCY}) {
+		t.Error("Expected emergency push to fail beyond the emergency budget")
+	}
+	if queue.Len() != 2+emergencyQueueSize {
+		t.Errorf("Expected %d queued commands, got %d", 2+emergencyQueueSize, queue.Len())
+	}
+
+	queue.Close()
+	if queue.Push(&pb.Command{Id: "5", Priority: pb.CommandPriority_EMERGENCY}) {
+		t.Error("Expected push to fail on a closed queue")
+	}
+	if cmd, ok := queue.Pop(); !ok || cmd.Id != "4" {
+		t.Error("Expected queued commands to remain available after close")
+	}
+}
+
+func TestCommandQueueReady(t *testing.T) {
+	queue := NewCommandQueue(10)
+	select {
+	case <-queue.Ready():
+		t.Fatal("Expected no signal on an empty queue")
+	default:
+	}
+
+	queue.Push(&pb.Command{Id: "1"})
+	queue.Push(&pb.Command{Id: "2"})
+	select {
+	case <-queue.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("Expected a signal after push")
+	}
+}
+
+func TestSendCommandPriority(t *testing.T) {
+	server := createTestServer(nil)
+	server.GetMinionRegistryImpl().minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1"},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+
+	requests := []*pb.CommandRequest{
+		{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: "backup"}, Priority: pb.CommandPriority_LOW},
+		{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: "uptime"}},
+		{MinionIds: []string{"minion-1"}, Command: &pb.Command{Payload: "isolate"}, Emergency: true},
+	}
+	for _, req := range requests {
+		if _, err := server.SendCommand(context.Background(), req); err != nil {
+			t.Fatalf("SendCommand failed: %v", err)
+		}
+	}
+
+	queue := server.GetMinionRegistryImpl().minions["minion-1"].Commands
+	for _, expected := range []struct {
+		payload  string
+		priority pb.CommandPriority
+	}{
+		{"isolate", pb.CommandPriority_EMERGENCY},
+		{"uptime", pb.CommandPriority_NORMAL},
+		{"backup", pb.CommandPriority_LOW},
+	} {
+		cmd, ok := queue.Pop()
+		if !ok || cmd.Payload != expected.payload || cmd.Priority != expected.priority {
+			t.Fatalf("Expected %s with priority %s, got %v", expected.payload, expected.priority, cmd)
+		}
+	}
+}
+
+func TestCommandQueueInFlightLimit(t *testing.T) {
+	queue := NewCommandQueue(10)
+	queue.SetLimit(2)
+	for _, id := range []string{"1", "2", "3"} {
+		queue.Push(&pb.Command{Id: id})
+	}
+
+	queue.Pop()
+	queue.Pop()
+	if _, ok := queue.Pop(); ok {
+		t.Fatal("Expected the third command to wait for a free slot")
+	}
+	if queue.InFlight() != 2 || queue.Len() != 1 {
+		t.Errorf("Expected 2 in flight and 1 queued, got %d and %d", queue.InFlight(), queue.Len())
+	}
+
+	queue.Complete("unknown")
+	if _, ok := queue.Pop(); ok {
+		t.Fatal("Expected an unknown result not to free a slot")
+	}
+
+	// Draining the ready signal left by Push
+	<-queue.Ready()
+	queue.Complete("1")
+	select {
+	case <-queue.Ready():
+	default:
+		t.Fatal("Expected a completion to wake up the dispatcher")
+	}
+	if cmd, ok := queue.Pop(); !ok || cmd.Id != "3" {
+		t.Fatalf("Expected command 3 once a slot is free, got %v", cmd)
+	}
+
+	queue.ResetInFlight()
+	if queue.InFlight() != 0 {
+		t.Errorf("Expected no command in flight after reset, got %d", queue.InFlight())
+	}
+}
+
+func TestHandleCommandResultFreesInFlightSlot(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1"},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+	server.SetInFlightLimit(1)
+
+	conn, _ := registry.GetConnectionImpl("minion-1")
+	conn.Commands.Push(&pb.Command{Id: "cmd-1"})
+	conn.Commands.Push(&pb.Command{Id: "cmd-2"})
+	conn.Commands.Pop()
+	if _, ok := conn.Commands.Pop(); ok {
+		t.Fatal("Expected cmd-2 to wait while cmd-1 runs")
+	}
+
+	server.handleCommandResult(context.Background(), &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1"}, server.logger)
+	if cmd, ok := conn.Commands.Pop(); !ok || cmd.Id != "cmd-2" {
+		t.Fatalf("Expected cmd-2 dispatched after cmd-1 result, got %v", cmd)
+	}
+
+	diagnostics, err := server.GetMinionDiagnostics(context.Background(), &pb.MinionDiagnosticsRequest{MinionId: "minion-1"})
+	if err != nil {
+		t.Fatalf("GetMinionDiagnostics failed: %v", err)
+	}
+	if diagnostics.InFlight != 1 || diagnostics.InFlightLimit != 1 {
+		t.Errorf("Expected 1/1 in flight, got %d/%d", diagnostics.InFlight, diagnostics.InFlightLimit)
+	}
+}
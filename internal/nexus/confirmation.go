@@ -0,0 +1,183 @@
+package nexus
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// confirmTokenTTL is how long a confirm token remains valid
+const confirmTokenTTL = 5 * time.Minute
+
+// DestructivePattern describes commands requiring a confirmation before being dispatched
+type DestructivePattern struct {
+	Name    string `json:"name"`    // shown to the operator asked to confirm
+	Pattern string `json:"pattern"` // regular expression matched against the command payload
+
+	re *regexp.Regexp
+}
+
+// DefaultDestructivePatterns returns the patterns used when no patterns file is configured
+func DefaultDestructivePatterns() []*DestructivePattern {
+	patterns := []*DestructivePattern{
+		{Name: "recursive delete", Pattern: `\brm\s+(?:\S+\s+)*-(?:-recursive\b|[a-zA-Z]*[rR])`},
+		{Name: "move of a system path", Pattern: `^\s*file:move\b.*\s/(?:bin|boot|dev|etc|lib|lib64|proc|root|sbin|sys|usr|var)(?:/\S*)?(?:\s|$)`},
+		{Name: "extraction to a system path", Pattern: `^\s*file:extract\b.*\s/(?:bin|boot|dev|etc|lib|lib64|proc|root|sbin|sys|usr|var)(?:/\S*)?\s*$`},
+		{Name: "kill of the init process", Pattern: `(?:^\s*process:kill|\bkill)\s+(?:-\S+\s+)*1(?:\s|$)`},
+		{Name: "filesystem creation", Pattern: `\bmkfs(?:\.\w+)?\b`},
+		{Name: "raw write to a device", Pattern: `\bdd\b.*\bof=/dev/`},
+		{Name: "reboot or shutdown", Pattern: `^\s*power:(?:reboot|shutdown)\b`},
+	}
+	for _, p := range patterns {
+		p.re = regexp.MustCompile(p.Pattern)
+	}
+	return patterns
+}
+
+// LoadDestructivePatterns reads the patterns of commands requiring confirmation from a JSON file
+func LoadDestructivePatterns(path string) ([]*DestructivePattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destructive patterns: %w", err)
+	}
+
+	var patterns []*DestructivePattern
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return nil, fmt.Errorf("failed to parse destructive patterns: %w", err)
+	}
+
+	for i, p := range patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("destructive pattern %d: name is required", i)
+		}
+		if p.re, err = regexp.Compile(p.Pattern); err != nil {
+			return nil, fmt.Errorf("destructive pattern %s: %w", p.Name, err)
+		}
+	}
+	return patterns, nil
+}
+
+// ConfirmationGuard requires destructive commands to be confirmed in a second step:
+// a first request returns a single-use token bound to the command and its targets,
+// and only a request carrying that token is dispatched.
+type ConfirmationGuard struct {
+	mu       sync.Mutex
+	patterns []*DestructivePattern
+	tokens   map[string]pendingConfirmation
+}
+
+// pendingConfirmation is a confirm token waiting to be used
+type pendingConfirmation struct {
+	digest  [sha256.Size]byte
+	expires time.Time
+}
+
+// NewConfirmationGuard creates a guard for the commands matching patterns
+func NewConfirmationGuard(patterns []*DestructivePattern) *ConfirmationGuard {
+	return &ConfirmationGuard{
+		patterns: patterns,
+		tokens:   make(map[string]pendingConfirmation),
+	}
+}
+
+// Match returns the name of the first pattern matching payload, empty if the command is not destructive
+func (g *ConfirmationGuard) Match(payload string) string {
+	if g == nil {
+		return ""
+	}
+	for _, p := range g.patterns {
+		if p.re.MatchString(payload) {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// Issue returns a new token confirming the command of req for its targets
+func (g *ConfirmationGuard) Issue(req *pb.CommandRequest) (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate confirm token: %w", err)
+	}
+	token := hex.EncodeToString(bytes)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for t, pending := range g.tokens {
+		if now.After(pending.expires) {
+			delete(g.tokens, t)
+		}
+	}
+	g.tokens[token] = pendingConfirmation{digest: confirmationDigest(req), expires: now.Add(confirmTokenTTL)}
+	return token, nil
+}
+
+// Consume reports whether token confirms the command of req for its targets, invalidating it
+func (g *ConfirmationGuard) Consume(req *pb.CommandRequest, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pending, exists := g.tokens[token]
+	if !exists || time.Now().After(pending.expires) || pending.digest != confirmationDigest(req) {
+		return false
+	}
+	delete(g.tokens, token)
+	return true
+}
+
+// confirmationDigest identifies a command and its targets, so a token can't confirm another command
+func confirmationDigest(req *pb.CommandRequest) [sha256.Size]byte {
+	key := &pb.CommandRequest{
+		MinionIds:   req.MinionIds,
+		TagSelector: req.TagSelector,
+		Topology:    req.Topology,
+		Command:     &pb.Command{Type: req.GetCommand().GetType(), Payload: req.GetCommand().GetPayload()},
+	}
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(key)
+	return sha256.Sum256(data)
+}
+
+// SetDestructivePatterns replaces the patterns of the commands requiring confirmation. Without
+// patterns no command requires confirmation, disruptive ones included.
+func (s *Server) SetDestructivePatterns(patterns []*DestructivePattern) {
+	if len(patterns) == 0 {
+		s.confirmations = nil
+		return
+	}
+	s.confirmations = NewConfirmationGuard(patterns)
+}
+
+// pendingConfirmation returns why a command must be confirmed and a token confirming it. The reason is
+// empty when the command can be dispatched: it is neither destructive nor disruptive, or the request is
+// not a dry run and carries a valid confirm token, which is consumed.
+func (s *Server) pendingConfirmation(req *pb.CommandRequest) (string, string, error) {
+	reason := s.confirmations.Match(req.Command.Payload)
+	if reason == "" && s.confirmations != nil && req.Impact == command.ImpactDisruptive {
+		reason = disruptiveReason
+	}
+	if reason == "" || (!req.DryRun && s.confirmations.Consume(req, req.ConfirmToken)) {
+		return "", "", nil
+	}
+	token, err := s.confirmations.Issue(req)
+	if err != nil {
+		return "", "", err
+	}
+	return reason, token, nil
+}
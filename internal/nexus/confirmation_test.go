@@ -0,0 +1,196 @@
+package nexus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestDefaultDestructivePatterns(t *testing.T) {
+	guard := NewConfirmationGuard(DefaultDestructivePatterns())
+	tests := map[string]string{
+		"rm -rf /var/lib/app":                   "recursive delete",
+		"sudo rm -f -R /opt/old":                "recursive delete",
+		"file:move /etc/hosts /tmp/hosts":       "move of a system path",
+		"process:kill 1":                        "kill of the init process",
+		"kill -9 1":                             "kill of the init process",
+		"mkfs.ext4 /dev/sdb1":                   "filesystem creation",
+		"dd if=/dev/zero of=/dev/sda":           "raw write to a device",
+		"power:reboot 5m kernel upgrade":        "reboot or shutdown",
+		"power:shutdown":                        "reboot or shutdown",
+		"power:cancel":                          "",
+		"rm -f /tmp/lock":                       "",
+		"file:move /tmp/a /tmp/b":               "",
+		"file:extract -o /tmp/r.tgz /usr/local": "extraction to a system path",
+		"file:extract /tmp/r.tgz /opt/app":      "",
+		"kill 1234":                             "",
+		"system:info":                           "",
+	}
+	for payload, expected := range tests {
+		if got := guard.Match(payload); got != expected {
+			t.Errorf("Match(%q) = %q, expected %q", payload, got, expected)
+		}
+	}
+
+	var nilGuard *ConfirmationGuard
+	if nilGuard.Match("rm -rf /") != "" {
+		t.Error("A nil guard must not require any confirmation")
+	}
+}
+
+func TestSendCommandConfirmation(t *testing.T) {
+	server := createTestServer(nil)
+	server.confirmations = NewConfirmationGuard(DefaultDestructivePatterns())
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: map[string]string{}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
+	}
+	newRequest := func(payload, token string) *pb.CommandRequest {
+		return &pb.CommandRequest{
+			MinionIds:    []string{"minion-1"},
+			Command:      &pb.Command{Type: pb.CommandType_SYSTEM, Payload: payload},
+			ConfirmToken: token,
+		}
+	}
+	queued := func() int { return registry.minions["minion-1"].Commands.Len() }
+
+	// First phase: the command is rejected with a token
+	response, err := server.SendCommand(context.Background(), newRequest("rm -rf /var/cache/app", ""))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.Accepted || !response.ConfirmationRequired || response.ConfirmToken == "" || response.DestructiveReason != "recursive delete" {
+		t.Fatalf("Expected confirmation to be required, got %+v", response)
+	}
+	token := response.ConfirmToken
+
+	// The token only confirms the same command
+	response, _ = server.SendCommand(context.Background(), newRequest("rm -rf /", token))
+	if response.Accepted || queued() != 0 {
+		t.Fatalf("Token must not confirm another command, got %+v", response)
+	}
+
+	// Second phase: the confirmed command is dispatched
+	response, _ = server.SendCommand(context.Background(), newRequest("rm -rf /var/cache/app", token))
+	if !response.Accepted || queued() != 1 {
+		t.Fatalf("Expected confirmed command to be dispatched, got %+v", response)
+	}
+
+	// Tokens are single-use
+	response, _ = server.SendCommand(context.Background(), newRequest("rm -rf /var/cache/app", token))
+	if response.Accepted || queued() != 1 {
+		t.Fatalf("Token must not be reusable, got %+v", response)
+	}
+
+	// A dry run returns a token too
+	dryRun := newRequest("rm -rf /var/cache/app", "")
+	dryRun.DryRun = true
+	response, _ = server.SendCommand(context.Background(), dryRun)
+	if !response.Accepted || !response.ConfirmationRequired || response.ConfirmToken == "" {
+		t.Fatalf("Expected dry run to return a confirm token, got %+v", response)
+	}
+	response, _ = server.SendCommand(context.Background(), newRequest("rm -rf /var/cache/app", response.ConfirmToken))
+	if !response.Accepted || queued() != 2 {
+		t.Fatalf("Expected dry run token to confirm the command, got %+v", response)
+	}
+
+	// Other commands don't need confirmation
+	response, _ = server.SendCommand(context.Background(), newRequest("uptime", ""))
+	if !response.Accepted || response.ConfirmationRequired {
+		t.Errorf("Expected non destructive command to be dispatched, got %+v", response)
+	}
+}
+
+func TestCommandImpact(t *testing.T) {
+	server := createTestServer(nil)
+	server.confirmations = NewConfirmationGuard(DefaultDestructivePatterns())
+	tests := []struct {
+		payload  string
+		declared string
+		expected string
+	}{
+		{"uptime", "", command.ImpactMutating},
+		{"uptime", command.ImpactReadOnly, command.ImpactReadOnly},
+		{"system:info", "", command.ImpactReadOnly},
+		{"system:info", command.ImpactDisruptive, command.ImpactDisruptive},
+		{"file:copy /etc/hosts /tmp/hosts", command.ImpactReadOnly, command.ImpactMutating},
+		{"docker-compose:down /opt/app", "", command.ImpactDisruptive},
+		{"rm -rf /var/cache/app", command.ImpactReadOnly, command.ImpactDisruptive},
+	}
+	for _, tt := range tests {
+		req := &pb.CommandRequest{Command: &pb.Command{Payload: tt.payload}, Impact: tt.declared}
+		if impact, err := server.commandImpact(req); err != nil || impact != tt.expected {
+			t.Errorf("%q declared %q: expected %s, got %q (%v)", tt.payload, tt.declared, tt.expected, impact, err)
+		}
+	}
+	if _, err := server.commandImpact(&pb.CommandRequest{Command: &pb.Command{Payload: "uptime"}, Impact: "harmless"}); err == nil {
+		t.Error("Expected an unknown impact class to be rejected")
+	}
+}
+
+func TestSendCommandDisruptiveConfirmation(t *testing.T) {
+	server := createTestServer(nil)
+	server.confirmations = NewConfirmationGuard(DefaultDestructivePatterns())
+	server.GetMinionRegistryImpl().minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: map[string]string{}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
+	}
+	newRequest := func(impact string) *pb.CommandRequest {
+		return &pb.CommandRequest{
+			MinionIds: []string{"minion-1"},
+			Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "systemctl restart nginx"},
+			Impact:    impact,
+		}
+	}
+
+	response, err := server.SendCommand(context.Background(), newRequest(command.ImpactDisruptive))
+	if err != nil || response.Accepted || !response.ConfirmationRequired || response.DestructiveReason != disruptiveReason || response.Impact != command.ImpactDisruptive {
+		t.Fatalf("Expected the disruptive command to require confirmation, got %+v (%v)", response, err)
+	}
+	response, _ = server.SendCommand(context.Background(), newRequest(""))
+	if !response.Accepted || response.ConfirmationRequired || response.Impact != command.ImpactMutating {
+		t.Errorf("Expected the mutating command dispatched, got %+v", response)
+	}
+	if _, err := server.SendCommand(context.Background(), newRequest("harmless")); err == nil {
+		t.Error("Expected an unknown impact class to be rejected")
+	}
+
+	// Without destructive patterns, disruptive commands are not confirmed either
+	server.SetDestructivePatterns(nil)
+	response, _ = server.SendCommand(context.Background(), newRequest(command.ImpactDisruptive))
+	if !response.Accepted || response.ConfirmationRequired {
+		t.Errorf("Expected confirmations disabled, got %+v", response)
+	}
+}
+
+func TestLoadDestructivePatterns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "patterns.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "service stop", "pattern": "systemctl\\s+stop"}]`), 0600); err != nil {
+		t.Fatalf("Failed to write patterns: %v", err)
+	}
+
+	patterns, err := LoadDestructivePatterns(path)
+	if err != nil {
+		t.Fatalf("LoadDestructivePatterns failed: %v", err)
+	}
+	guard := NewConfirmationGuard(patterns)
+	if guard.Match("systemctl stop nginx") != "service stop" || guard.Match("rm -rf /") != "" {
+		t.Error("Expected loaded patterns to replace the defaults")
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"name": "broken", "pattern": "("}]`), 0600); err != nil {
+		t.Fatalf("Failed to write patterns: %v", err)
+	}
+	if _, err := LoadDestructivePatterns(path); err == nil {
+		t.Error("Expected invalid pattern to be rejected")
+	}
+}
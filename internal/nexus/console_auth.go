@@ -0,0 +1,207 @@
+package nexus
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/oidc"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authentication methods of consoles
+const (
+	AuthMethodCertificate = "certificate"
+	AuthMethodOIDC        = "oidc"
+)
+
+// AllNamespacesClaim is the value of the namespaces claim of a token granting every namespace
+const AllNamespacesClaim = "*"
+
+// ConsoleIdentity is the authenticated identity of a console, recorded as the operator of its
+// commands, approvals and receipts
+type ConsoleIdentity struct {
+	Name          string   // common name of the certificate, or "oidc:" and the identity claim of the token
+	Namespaces    []string // namespaces the console is restricted to, when not AllNamespaces
+	AllNamespaces bool     // whether the console administers every namespace
+	Method        string   // AuthMethodCertificate or AuthMethodOIDC
+}
+
+// ConsoleAuthenticator authenticates consoles by one method
+type ConsoleAuthenticator interface {
+	// Authenticate returns the identity of the console calling in ctx, nil when the call carries no
+	// credentials of the method, and an error when its credentials are invalid
+	Authenticate(ctx context.Context) (*ConsoleIdentity, error)
+}
+
+// consoleIdentityKey is the context key of the identity of the console calling
+type consoleIdentityKey struct{}
+
+// authenticatedConsole returns the identity the console authentication stored in ctx
+func authenticatedConsole(ctx context.Context) (*ConsoleIdentity, bool) {
+	identity, ok := ctx.Value(consoleIdentityKey{}).(*ConsoleIdentity)
+	return identity, ok
+}
+
+// certificateAuthenticator authenticates consoles by their verified client certificate: its
+// common name is the console identity and its organizational units its namespaces
+type certificateAuthenticator struct{}
+
+// CertificateAuthenticator returns the authenticator of the consoles presenting a client certificate
+func CertificateAuthenticator() ConsoleAuthenticator {
+	return certificateAuthenticator{}
+}
+
+// Authenticate implements ConsoleAuthenticator
+func (certificateAuthenticator) Authenticate(ctx context.Context) (*ConsoleIdentity, error) {
+	cert := peerCertificate(ctx)
+	if cert == nil {
+		return nil, nil
+	}
+	return &ConsoleIdentity{
+		Name:          cert.Subject.CommonName,
+		Namespaces:    cert.Subject.OrganizationalUnit,
+		AllNamespaces: len(cert.Subject.OrganizationalUnit) == 0,
+		Method:        AuthMethodCertificate,
+	}, nil
+}
+
+// oidcAuthenticator authenticates consoles by the OpenID Connect token they present as a bearer
+// token in the authorization metadata
+type oidcAuthenticator struct {
+	verifier        *oidc.Verifier
+	identityClaim   string
+	namespacesClaim string
+}
+
+// NewOIDCAuthenticator returns the authenticator of the consoles presenting a token verified by
+// verifier, identityClaim naming the console and namespacesClaim listing its namespaces. The
+// consoles are named "oidc:" and their identity claim, so that they never pass for the certificate
+// of the same name in the admins and approvers, and administer no namespace without namespacesClaim:
+// only the AllNamespacesClaim value grants every namespace.
+func NewOIDCAuthenticator(verifier *oidc.Verifier, identityClaim, namespacesClaim string) ConsoleAuthenticator {
+	return &oidcAuthenticator{verifier: verifier, identityClaim: identityClaim, namespacesClaim: namespacesClaim}
+}
+
+// Authenticate implements ConsoleAuthenticator
+func (a *oidcAuthenticator) Authenticate(ctx context.Context) (*ConsoleIdentity, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, nil
+	}
+	claims, err := a.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	name := claims.String(a.identityClaim)
+	if name == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "token without %s claim", a.identityClaim)
+	}
+	identity := &ConsoleIdentity{Name: AuthMethodOIDC + ":" + name, Method: AuthMethodOIDC}
+	if a.namespacesClaim != "" {
+		identity.Namespaces = claims.Strings(a.namespacesClaim)
+		if len(identity.Namespaces) == 0 {
+			// A console without namespace claim must not administer every namespace
+			return nil, status.Errorf(codes.PermissionDenied, "token without %s claim", a.namespacesClaim)
+		}
+		identity.AllNamespaces = slices.Contains(identity.Namespaces, AllNamespacesClaim)
+	}
+	return identity, nil
+}
+
+// bearerToken returns the bearer token of the authorization metadata of ctx, empty without one
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if scheme, token, found := strings.Cut(value, " "); found && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// SetConsoleAuthenticators sets the methods consoles authenticate with, tried in turn: the first
+// finding credentials in a call authenticates it. Client certificates only by default.
+func (s *Server) SetConsoleAuthenticators(authenticators ...ConsoleAuthenticator) {
+	s.consoleAuth = authenticators
+}
+
+// AuthenticateConsole authenticates the console calling in ctx, returning ctx carrying its identity
+func (s *Server) AuthenticateConsole(ctx context.Context) (context.Context, error) {
+	authenticators := s.consoleAuth
+	if len(authenticators) == 0 {
+		authenticators = []ConsoleAuthenticator{certificateAuthenticator{}}
+	}
+	for _, authenticator := range authenticators {
+		identity, err := authenticator.Authenticate(ctx)
+		if err != nil {
+			s.logger.Warn("Console authentication failed", zap.Error(err))
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Errorf(codes.Unauthenticated, "invalid credentials: %v", err)
+		}
+		if identity != nil {
+			s.logger.Debug("Console authenticated",
+				zap.String("console", identity.Name),
+				zap.String("method", identity.Method),
+				zap.Strings("namespaces", identity.Namespaces),
+				zap.Bool("all_namespaces", identity.AllNamespaces))
+			return context.WithValue(ctx, consoleIdentityKey{}, identity), nil
+		}
+	}
+	if len(s.consoleAuth) > 1 {
+		return nil, status.Error(codes.Unauthenticated, "a console client certificate or token is required")
+	}
+	return nil, status.Error(codes.Unauthenticated, "console credentials are required")
+}
+
+// authenticationExempt reports whether method is served without authentication: health checks,
+// probed by load balancers and orchestrators
+func authenticationExempt(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// ConsoleAuthUnary is the interceptor authenticating the unary calls of consoles
+func (s *Server) ConsoleAuthUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if authenticationExempt(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	ctx, err := s.AuthenticateConsole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// ConsoleAuthStream is the interceptor authenticating the streams of consoles
+func (s *Server) ConsoleAuthStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if authenticationExempt(info.FullMethod) {
+		return handler(srv, stream)
+	}
+	ctx, err := s.AuthenticateConsole(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream is a console stream whose context carries the console identity
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream carrying the console identity
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,106 @@
+package nexus
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tokenAuthenticator authenticates the bearer tokens it knows, standing for an SSO
+type tokenAuthenticator map[string]*ConsoleIdentity
+
+func (a tokenAuthenticator) Authenticate(ctx context.Context) (*ConsoleIdentity, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, nil
+	}
+	if identity, exists := a[token]; exists {
+		return identity, nil
+	}
+	return nil, errors.New("token expired")
+}
+
+// mockConsoleStream is a console stream of a context
+type mockConsoleStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (m *mockConsoleStream) Context() context.Context { return m.ctx }
+
+func TestAuthenticateConsole(t *testing.T) {
+	server := createTestServer(nil)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "ops-console", OrganizationalUnit: []string{"team-a"}}}
+	withToken := func(ctx context.Context, token string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	// Client certificates only by default
+	ctx, err := server.AuthenticateConsole(certificateContext(cert))
+	if err != nil || consoleIdentity(ctx) != "ops-console" || !consoleScope(ctx)["team-a"] {
+		t.Fatalf("Expected the certificate identity, got %q %v %v", consoleIdentity(ctx), consoleScope(ctx), err)
+	}
+	if _, err := server.AuthenticateConsole(withToken(context.Background(), "alice-token")); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected tokens refused without token authenticator, got %v", err)
+	}
+
+	server.SetConsoleAuthenticators(
+		tokenAuthenticator{
+			"alice-token": {Name: "alice@example.com", AllNamespaces: true, Method: AuthMethodOIDC},
+			"bob-token":   {Name: "bob@example.com", Method: AuthMethodOIDC},
+		},
+		CertificateAuthenticator(),
+	)
+	// The token identity takes precedence over the certificate, scoping the console by its own namespaces
+	ctx, err = server.AuthenticateConsole(withToken(certificateContext(cert), "alice-token"))
+	if err != nil || consoleIdentity(ctx) != "alice@example.com" || consoleScope(ctx).restricted() {
+		t.Errorf("Expected the token identity, got %q %v %v", consoleIdentity(ctx), consoleScope(ctx), err)
+	}
+	// A token granted no namespace administers none, rather than every namespace
+	ctx, err = server.AuthenticateConsole(withToken(context.Background(), "bob-token"))
+	if err != nil || !consoleScope(ctx).restricted() || consoleScope(ctx).allows(&pb.HostInfo{}) {
+		t.Errorf("Expected the token restricted to no namespace, got %v %v", consoleScope(ctx), err)
+	}
+	if _, err := server.AuthenticateConsole(withToken(certificateContext(cert), "stolen-token")); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected an invalid token refused despite the certificate, got %v", err)
+	}
+	if ctx, err := server.AuthenticateConsole(certificateContext(cert)); err != nil || consoleIdentity(ctx) != "ops-console" {
+		t.Errorf("Expected certificates still accepted, got %v", err)
+	}
+	if _, err := server.AuthenticateConsole(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected consoles without credentials refused, got %v", err)
+	}
+
+	// The interceptors hand the identity to the services, health checks being served to anyone
+	var operator string
+	handler := func(ctx context.Context, req any) (any, error) {
+		operator = consoleIdentity(ctx)
+		return &pb.Empty{}, nil
+	}
+	if _, err := server.ConsoleAuthUnary(withToken(context.Background(), "alice-token"), &pb.Empty{}, &grpc.UnaryServerInfo{FullMethod: "/minexus.ConsoleService/SendCommand"}, handler); err != nil || operator != "alice@example.com" {
+		t.Errorf("Expected the call authenticated as alice, got %q %v", operator, err)
+	}
+	if _, err := server.ConsoleAuthUnary(context.Background(), &pb.Empty{}, &grpc.UnaryServerInfo{FullMethod: "/minexus.ConsoleService/SendCommand"}, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected an anonymous call refused, got %v", err)
+	}
+	if _, err := server.ConsoleAuthUnary(context.Background(), &pb.Empty{}, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler); err != nil {
+		t.Errorf("Expected health checks served without credentials, got %v", err)
+	}
+	stream := &mockConsoleStream{ctx: withToken(context.Background(), "alice-token")}
+	err = server.ConsoleAuthStream(nil, stream, &grpc.StreamServerInfo{FullMethod: "/minexus.ConsoleService/PullFile"}, func(srv any, stream grpc.ServerStream) error {
+		operator = consoleIdentity(stream.Context())
+		return nil
+	})
+	if err != nil || operator != "alice@example.com" {
+		t.Errorf("Expected the stream authenticated as alice, got %q %v", operator, err)
+	}
+}
@@ -0,0 +1,221 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sizes of ListCrashes
+const (
+	defaultCrashListLimit = 50
+	maxCrashListLimit     = 1000
+)
+
+// Bounds of the crash reports minions send, matching what minions keep, longer fields being truncated
+const (
+	maxCrashReports         = 20 // reports of a registration, the oldest being dropped
+	maxCrashComponentLength = 255
+	maxCrashMessageBytes    = 4 * 1024
+	maxCrashStackBytes      = 64 * 1024
+	maxCrashVersionLength   = 64
+	// maxStoredCrashes is the number of crash reports kept for each minion, the oldest being deleted
+	maxStoredCrashes = 100
+)
+
+// truncateUTF8 cuts value to at most limit bytes on a rune boundary
+func truncateUTF8(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut]
+}
+
+// boundCrashes keeps the last maxCrashReports crash reports, truncating their fields to the sizes
+// of the minion_crashes columns
+func boundCrashes(crashes []*pb.CrashReport) []*pb.CrashReport {
+	if len(crashes) > maxCrashReports {
+		crashes = crashes[len(crashes)-maxCrashReports:]
+	}
+	bounded := make([]*pb.CrashReport, 0, len(crashes))
+	for _, crash := range crashes {
+		if crash == nil {
+			continue
+		}
+		bounded = append(bounded, &pb.CrashReport{
+			Timestamp: crash.Timestamp,
+			Component: truncateUTF8(crash.Component, maxCrashComponentLength),
+			Message:   truncateUTF8(crash.Message, maxCrashMessageBytes),
+			Stack:     truncateUTF8(crash.Stack, maxCrashStackBytes),
+			Version:   truncateUTF8(crash.Version, maxCrashVersionLength),
+		})
+	}
+	return bounded
+}
+
+// recordCrashes stores the crash reports a minion sent at registration and reports whether they
+// were, the minion keeping them to send them again otherwise. They are logged in any case, which
+// is all Nexus does without database.
+func (s *Server) recordCrashes(ctx context.Context, minionID string, crashes []*pb.CrashReport, logger *zap.Logger) bool {
+	if len(crashes) > maxCrashReports {
+		logger.Warn("Too many crash reports, keeping the last ones",
+			zap.String("minion_id", minionID),
+			zap.Int("count", len(crashes)),
+			zap.Int("kept", maxCrashReports))
+	}
+	crashes = boundCrashes(crashes)
+	for _, crash := range crashes {
+		logger.Warn("Minion recovered from a panic",
+			zap.String("minion_id", minionID),
+			zap.String("component", crash.Component),
+			zap.String("panic", crash.Message),
+			zap.Time("crashed_at", time.Unix(crash.Timestamp, 0)),
+			zap.String("version", crash.Version))
+	}
+	if s.dbService == nil {
+		return true
+	}
+	if err := s.dbService.StoreCrashReports(ctx, minionID, crashes); err != nil {
+		logger.Error("Failed to store crash reports, the minion sends them again", zap.String("minion_id", minionID), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// ListCrashes returns the panics minions recovered from, most recent first
+func (s *Server) ListCrashes(ctx context.Context, req *pb.CrashListRequest) (*pb.CrashList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListCrashes")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "crash reports require a database")
+	}
+	scope := consoleScope(ctx)
+	if req.MinionId != "" && scope.restricted() {
+		if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+			return nil, err
+		}
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultCrashListLimit
+	}
+	if limit > maxCrashListLimit {
+		limit = maxCrashListLimit
+	}
+
+	// Restricted consoles list the crashes of the minions of their namespaces
+	var minionIDs []string
+	switch {
+	case req.MinionId != "":
+		minionIDs = []string{req.MinionId}
+	case scope.restricted():
+		for _, info := range s.minionRegistry.ListMinions() {
+			minionIDs = append(minionIDs, info.Id)
+		}
+		if minionIDs = s.scopedMinionIDs(scope, minionIDs); len(minionIDs) == 0 {
+			return &pb.CrashList{}, nil
+		}
+	}
+
+	crashes, err := s.dbService.GetCrashReports(ctx, minionIDs, limit)
+	if err != nil {
+		logger.Error("Failed to get crash reports", zap.String("minion_id", req.MinionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get crash reports")
+	}
+	return &pb.CrashList{Crashes: crashes}, nil
+}
+
+// StoreCrashReports stores the crash reports sent by a minion.
+func (d *DatabaseServiceImpl) StoreCrashReports(ctx context.Context, minionID string, crashes []*pb.CrashReport) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot store crash reports of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreCrashReports")
+	defer logging.FuncExit(logger, start)
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin crash report transaction: %v", err)
+	}
+	defer tx.Rollback() // Will be a no-op if transaction is committed
+
+	for _, crash := range crashes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO minion_crashes (host_id, timestamp, component, message, stack, version)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			minionID, time.Unix(crash.Timestamp, 0), crash.Component, crash.Message, crash.Stack, crash.Version)
+		if err != nil {
+			return fmt.Errorf("failed to insert crash report: %v", err)
+		}
+	}
+
+	// Only the last crash reports of the minion are kept
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM minion_crashes WHERE host_id = $1 AND id NOT IN
+			(SELECT id FROM minion_crashes WHERE host_id = $1 ORDER BY timestamp DESC, id DESC LIMIT $2)`,
+		minionID, maxStoredCrashes); err != nil {
+		return fmt.Errorf("failed to prune crash reports: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit crash reports: %v", err)
+	}
+
+	logger.Debug("Stored crash reports", zap.String("host_id", minionID), zap.Int("count", len(crashes)))
+	return nil
+}
+
+// GetCrashReports retrieves the last limit crash reports of the minionIDs, of every minion when
+// minionIDs is nil, most recent first.
+func (d *DatabaseServiceImpl) GetCrashReports(ctx context.Context, minionIDs []string, limit int) ([]*pb.CrashReport, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get crash reports")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetCrashReports")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT host_id, EXTRACT(EPOCH FROM timestamp)::bigint, component, message, stack, COALESCE(version, '')
+		FROM minion_crashes
+		WHERE $1 OR host_id = ANY($2)
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $3`,
+		minionIDs == nil, pq.Array(minionIDs), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crash reports: %v", err)
+	}
+	defer rows.Close()
+
+	var crashes []*pb.CrashReport
+	for rows.Next() {
+		var crash pb.CrashReport
+		if err := rows.Scan(&crash.MinionId, &crash.Timestamp, &crash.Component, &crash.Message, &crash.Stack, &crash.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan crash report: %v", err)
+		}
+		crashes = append(crashes, &crash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading crash reports: %v", err)
+	}
+
+	logger.Debug("Retrieved crash reports",
+		zap.Strings("minion_ids", minionIDs),
+		zap.Int("count", len(crashes)))
+	return crashes, nil
+}
@@ -0,0 +1,136 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegisterStoresCrashes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	mock.ExpectQuery("INSERT INTO hosts").WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO minion_crashes").
+		WithArgs("minion-1", sqlmock.AnyArg(), "scheduler", "boom", "goroutine 1 [running]:", "v1.2.3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM minion_crashes").
+		WithArgs("minion-1", maxStoredCrashes).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	response, err := server.Register(context.Background(), &pb.HostInfo{
+		Id: "minion-1",
+		Crashes: []*pb.CrashReport{{
+			Timestamp: 1700000000,
+			Component: "scheduler",
+			Message:   "boom",
+			Stack:     "goroutine 1 [running]:",
+			Version:   "v1.2.3",
+		}},
+	})
+	if err != nil || !response.Success {
+		t.Fatalf("Unexpected registration failure: %v %v", response, err)
+	}
+	if !response.CrashAcks || !response.CrashesStored {
+		t.Errorf("Expected the crash reports to be acknowledged, got %v", response)
+	}
+	if conn, exists := server.minionRegistry.GetConnection("minion-1"); !exists || len(conn.GetInfo().Crashes) != 0 {
+		t.Error("Expected the crash reports not to be kept with the registration")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListCrashes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	columns := []string{"host_id", "timestamp", "component", "message", "stack", "version"}
+	mock.ExpectQuery("SELECT host_id, EXTRACT\\(EPOCH FROM timestamp\\)::bigint, component, message, stack").
+		WithArgs(true, sqlmock.AnyArg(), defaultCrashListLimit).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("minion-2", int64(1700000100), "watcher", "nil map", "stack 2", "v1.2.3").
+			AddRow("minion-1", int64(1700000000), "command cmd-1: docker:ps", "boom", "stack 1", ""))
+
+	crashes, err := server.ListCrashes(context.Background(), &pb.CrashListRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(crashes.Crashes) != 2 || crashes.Crashes[0].MinionId != "minion-2" || crashes.Crashes[1].Component != "command cmd-1: docker:ps" {
+		t.Errorf("Unexpected crashes: %v", crashes.Crashes)
+	}
+
+	mock.ExpectQuery("SELECT host_id").WithArgs(false, "{\"minion-1\"}", maxCrashListLimit).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := server.ListCrashes(context.Background(), &pb.CrashListRequest{MinionId: "minion-1", Limit: 5000}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := createTestServer(nil).ListCrashes(context.Background(), &pb.CrashListRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
+
+func TestRegisterKeepsUnstoredCrashes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	mock.ExpectQuery("INSERT INTO hosts").WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO minion_crashes").WillReturnError(fmt.Errorf("disk full"))
+	mock.ExpectRollback()
+
+	response, err := server.Register(context.Background(), &pb.HostInfo{
+		Id:      "minion-1",
+		Crashes: []*pb.CrashReport{{Timestamp: 1700000000, Component: "scheduler", Message: "boom"}},
+	})
+	if err != nil || !response.Success {
+		t.Fatalf("Unexpected registration failure: %v %v", response, err)
+	}
+	if !response.CrashAcks || response.CrashesStored {
+		t.Errorf("Expected the crash reports not to be acknowledged, got %v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestBoundCrashes(t *testing.T) {
+	crashes := make([]*pb.CrashReport, maxCrashReports+5)
+	for i := range crashes {
+		crashes[i] = &pb.CrashReport{Timestamp: int64(i), Component: strings.Repeat("é", maxCrashComponentLength)}
+	}
+	bounded := boundCrashes(crashes)
+	if len(bounded) != maxCrashReports || bounded[0].Timestamp != 5 {
+		t.Fatalf("Expected the last %d crash reports, got %d starting at %d", maxCrashReports, len(bounded), bounded[0].Timestamp)
+	}
+	component := bounded[0].Component
+	if len(component) > maxCrashComponentLength || !utf8.ValidString(component) {
+		t.Errorf("Expected a valid component of at most %d bytes, got %d bytes", maxCrashComponentLength, len(component))
+	}
+}
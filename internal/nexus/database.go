@@ -4,20 +4,37 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/arhuman/minexus/internal/logging"
 	pb "github.com/arhuman/minexus/protogen"
 
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// uniqueViolation is the PostgreSQL error code of unique constraint violations
+	uniqueViolation = "23505"
+	// resultUniqueConstraint allows a single result per command and minion in command_results
+	resultUniqueConstraint = "uq_command_results_command_minion"
 )
 
 // DatabaseServiceImpl implements the DatabaseService interface for nexus operations.
 // It handles all database persistence operations including hosts, commands, and results.
 type DatabaseServiceImpl struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db        *sql.DB
+	replica   *sql.DB // read-only replica serving the heavy read queries, nil to read from db
+	logger    *zap.Logger
+	redactor  *Redactor  // redacts secrets from commands and results before they are stored
+	encryptor *Encryptor // encrypts command payloads and result output at rest, nil to store them in clear
+
+	duplicateResults atomic.Int64 // results already stored, resent by minions
 }
 
 // NewDatabaseService creates a new database service instance.
@@ -27,14 +44,37 @@ func NewDatabaseService(db *sql.DB, logger *zap.Logger) *DatabaseServiceImpl {
 	defer logging.FuncExit(logger, start)
 
 	service := &DatabaseServiceImpl{
-		db:     db,
-		logger: logger,
+		db:       db,
+		logger:   logger,
+		redactor: NewRedactor(DefaultRedactionPatterns()),
 	}
 
 	logger.Debug("Database service created")
 	return service
 }
 
+// SetReadReplica makes the result, history and reporting queries read from replica, keeping them
+// off the primary database storing commands and results. A nil replica reads from the primary.
+func (d *DatabaseServiceImpl) SetReadReplica(replica *sql.DB) {
+	d.replica = replica
+}
+
+// Ping checks that the primary database is reachable.
+func (d *DatabaseServiceImpl) Ping(ctx context.Context) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot ping database")
+	}
+	return d.db.PingContext(ctx)
+}
+
+// reader returns the database serving the heavy read queries: the replica when set, the primary otherwise
+func (d *DatabaseServiceImpl) reader() *sql.DB {
+	if d.replica != nil {
+		return d.replica
+	}
+	return d.db
+}
+
 // StoreHost persists host information to the database.
 func (d *DatabaseServiceImpl) StoreHost(ctx context.Context, hostInfo *pb.HostInfo) error {
 	if d == nil || d.db == nil {
@@ -84,71 +124,435 @@ func (d *DatabaseServiceImpl) StoreHost(ctx context.Context, hostInfo *pb.HostIn
 	return nil
 }
 
-// UpdateHost updates existing host information in the database.
-func (d *DatabaseServiceImpl) UpdateHost(ctx context.Context, hostInfo *pb.HostInfo) error {
+// RegisterHost stores the registration of a minion with the tags it advertised, merging them with
+// the stored tags set from consoles, which Nexus no longer has in memory after a restart, under the
+// tag conflict policy. It returns the tags stored for the minion.
+func (d *DatabaseServiceImpl) RegisterHost(ctx context.Context, hostInfo *pb.HostInfo, advertised map[string]string, policy string) (map[string]string, error) {
 	if d == nil || d.db == nil {
-		return fmt.Errorf("database service unavailable - cannot update host %s", hostInfo.Id)
+		return nil, fmt.Errorf("database service unavailable - cannot register host %s", hostInfo.Id)
 	}
 
-	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.UpdateHost")
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.RegisterHost")
 	defer logging.FuncExit(logger, start)
 
 	tagsJSON, err := json.Marshal(hostInfo.Tags)
 	if err != nil {
 		logger.Error("Failed to marshal host tags", zap.String("host_id", hostInfo.Id))
-		return fmt.Errorf("failed to marshal host tags: %v", err)
+		return nil, fmt.Errorf("failed to marshal host tags: %v", err)
+	}
+	advertisedJSON, err := json.Marshal(advertised)
+	if err != nil {
+		logger.Error("Failed to marshal advertised tags", zap.String("host_id", hostInfo.Id))
+		return nil, fmt.Errorf("failed to marshal advertised tags: %v", err)
 	}
 
+	// The console tags are the stored tags the minion did not advertise with that value at its
+	// previous registration; hosts registered before advertised tags were recorded have none
 	now := time.Now()
-	result, err := d.db.ExecContext(ctx,
-		"UPDATE hosts SET hostname=$2, ip=$3, os=$4, last_seen=$5, tags=$6 WHERE id=$1",
-		hostInfo.Id, hostInfo.Hostname, hostInfo.Ip, hostInfo.Os, now, string(tagsJSON))
+	var stored string
+	err = d.db.QueryRowContext(ctx,
+		`INSERT INTO hosts (id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			hostname = EXCLUDED.hostname,
+			ip = EXCLUDED.ip,
+			os = EXCLUDED.os,
+			last_seen = EXCLUDED.last_seen,
+			tags = (SELECT CASE WHEN $9::text = 'minion' THEN console.tags || EXCLUDED.tags ELSE EXCLUDED.tags || console.tags END
+				FROM (SELECT COALESCE(jsonb_object_agg(t.key, t.value), '{}'::jsonb) AS tags
+					FROM jsonb_each(COALESCE(hosts.tags, '{}'::jsonb)) t
+					WHERE COALESCE(hosts.advertised_tags, hosts.tags) -> t.key IS DISTINCT FROM t.value) console),
+			advertised_tags = EXCLUDED.advertised_tags
+		RETURNING tags`,
+		hostInfo.Id, hostInfo.Hostname, hostInfo.Ip, hostInfo.Os, now, now, string(tagsJSON), string(advertisedJSON), policy).Scan(&stored)
+	if err != nil {
+		logger.Error("Failed to register host in database", zap.String("host_id", hostInfo.Id), zap.Error(err))
+		return nil, fmt.Errorf("failed to register host: %v", err)
+	}
+
+	tags := make(map[string]string)
+	if err := json.Unmarshal([]byte(stored), &tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal host tags: %v", err)
+	}
+
+	logger.Debug("Host registered successfully", zap.String("host_id", hostInfo.Id))
+	return tags, nil
+}
 
+// StoreHostChange records a change of the environment fingerprint of a minion.
+func (d *DatabaseServiceImpl) StoreHostChange(ctx context.Context, change *HostChange) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot store host change for %s", change.MinionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreHostChange")
+	defer logging.FuncExit(logger, start)
+
+	_, err := d.db.ExecContext(ctx,
+		"INSERT INTO host_changes (host_id, old_fingerprint, new_fingerprint, changes, detected_at) VALUES ($1, $2, $3, $4, $5)",
+		change.MinionID, change.OldFingerprint, change.NewFingerprint, strings.Join(change.Changes, "; "), change.DetectedAt)
 	if err != nil {
-		logger.Error("Failed to update host in database", zap.String("host_id", hostInfo.Id))
-		return fmt.Errorf("failed to update host: %v", err)
+		logger.Error("Failed to insert host change", zap.String("host_id", change.MinionID))
+		return fmt.Errorf("failed to insert host change: %v", err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		logger.Debug("Host not found, inserting instead", zap.String("host_id", hostInfo.Id))
-		// Record doesn't exist, insert it
-		return d.StoreHost(ctx, hostInfo)
+	logger.Debug("Host change stored", zap.String("host_id", change.MinionID))
+	return nil
+}
+
+// StoreApproval records a command requiring approval and its decision in the audit trail, with the
+// request dispatched once approved while it is pending (nil once decided). The request is encrypted
+// like command payloads.
+func (d *DatabaseServiceImpl) StoreApproval(ctx context.Context, approval *pb.CommandApproval, request *pb.CommandRequest) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot store approval of command %s", approval.CommandId)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreApproval")
+	defer logging.FuncExit(logger, start)
+
+	var decidedAt interface{}
+	if approval.DecidedAt != 0 {
+		decidedAt = time.Unix(approval.DecidedAt, 0)
+	}
+	var storedRequest interface{}
+	if request != nil {
+		data, err := protojson.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request of command %s: %w", approval.CommandId, err)
+		}
+		if storedRequest, err = d.encryptor.Encrypt(string(data), encryptionBinding{approval.CommandId, "", requestColumn}); err != nil {
+			return fmt.Errorf("failed to encrypt request of command %s: %w", approval.CommandId, err)
+		}
+	}
+	_, err := d.db.ExecContext(ctx,
+		`INSERT INTO command_approvals (command_id, payload, targets, reason, requested_by, requested_at, status, decided_by, decided_at, comment, impact, request)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (command_id) DO UPDATE SET status = EXCLUDED.status, decided_by = EXCLUDED.decided_by,
+		decided_at = EXCLUDED.decided_at, comment = EXCLUDED.comment, request = EXCLUDED.request`,
+		approval.CommandId, approval.Payload, strings.Join(approval.TargetMinionIds, ","), approval.Reason,
+		approval.RequestedBy, time.Unix(approval.RequestedAt, 0), approval.Status, approval.DecidedBy, decidedAt, approval.Comment,
+		nullIfEmpty(approval.Impact), storedRequest)
+	if err != nil {
+		logger.Error("Failed to store command approval", zap.String("command_id", approval.CommandId))
+		return fmt.Errorf("failed to store command approval: %v", err)
+	}
+
+	logger.Debug("Command approval stored", zap.String("command_id", approval.CommandId), zap.String("status", approval.Status))
+	return nil
+}
+
+// GetApprovals retrieves the approvals requested since since, with the requests of the pending ones.
+// Pending approvals whose request can't be read back are skipped.
+func (d *DatabaseServiceImpl) GetApprovals(ctx context.Context, since time.Time) ([]*pendingApproval, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get approvals")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetApprovals")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT command_id, payload, targets, reason, requested_by, requested_at, status, decided_by, decided_at, comment, impact, request
+		FROM command_approvals WHERE requested_at >= $1 ORDER BY requested_at`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approvals: %v", err)
+	}
+	defer rows.Close()
+
+	var approvals []*pendingApproval
+	for rows.Next() {
+		approval := &pb.CommandApproval{}
+		var targets string
+		var requestedAt time.Time
+		var decidedBy, comment, impact, storedRequest sql.NullString
+		var decidedAt sql.NullTime
+		if err := rows.Scan(&approval.CommandId, &approval.Payload, &targets, &approval.Reason, &approval.RequestedBy,
+			&requestedAt, &approval.Status, &decidedBy, &decidedAt, &comment, &impact, &storedRequest); err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %v", err)
+		}
+		if targets != "" {
+			approval.TargetMinionIds = strings.Split(targets, ",")
+		}
+		approval.RequestedAt = requestedAt.Unix()
+		approval.DecidedBy = decidedBy.String
+		approval.Comment = comment.String
+		approval.Impact = impact.String
+		if decidedAt.Valid {
+			approval.DecidedAt = decidedAt.Time.Unix()
+		}
+
+		p := &pendingApproval{approval: approval}
+		if approval.Status == ApprovalPending {
+			if p.request, err = d.approvalRequest(approval.CommandId, storedRequest); err != nil {
+				logger.Warn("Skipping pending approval", zap.String("command_id", approval.CommandId), zap.Error(err))
+				continue
+			}
+		}
+		approvals = append(approvals, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate approvals: %v", err)
 	}
+	return approvals, nil
+}
+
+// approvalRequest decrypts and decodes the stored request of a pending approval
+func (d *DatabaseServiceImpl) approvalRequest(commandID string, stored sql.NullString) (*pb.CommandRequest, error) {
+	if !stored.Valid {
+		return nil, fmt.Errorf("request not stored")
+	}
+	data, err := d.encryptor.Decrypt(stored.String, encryptionBinding{commandID, "", requestColumn})
+	if err != nil {
+		return nil, err
+	}
+	request := &pb.CommandRequest{}
+	if err := protojson.Unmarshal([]byte(data), request); err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+	if request.GetCommand().GetId() != commandID {
+		return nil, fmt.Errorf("request of another command")
+	}
+	return request, nil
+}
 
-	logger.Debug("Host updated successfully", zap.String("host_id", hostInfo.Id))
+// SaveReport stores a report, replacing any report with the same name.
+func (d *DatabaseServiceImpl) SaveReport(ctx context.Context, report *pb.Report) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot save report %s", report.Name)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.SaveReport")
+	defer logging.FuncExit(logger, start)
+
+	_, err := d.db.ExecContext(ctx,
+		`INSERT INTO reports (name, query, description, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET query = EXCLUDED.query, description = EXCLUDED.description, created_at = EXCLUDED.created_at`,
+		report.Name, report.Query, report.Description, time.Unix(report.CreatedAt, 0))
+	if err != nil {
+		logger.Error("Failed to save report", zap.String("report", report.Name))
+		return fmt.Errorf("failed to save report: %v", err)
+	}
+
+	logger.Debug("Report saved", zap.String("report", report.Name))
 	return nil
 }
 
+// GetReport retrieves a report by name, returning nil when it does not exist.
+func (d *DatabaseServiceImpl) GetReport(ctx context.Context, name string) (*pb.Report, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot load report %s", name)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetReport")
+	defer logging.FuncExit(logger, start)
+
+	var report pb.Report
+	err := d.reader().QueryRowContext(ctx,
+		"SELECT name, query, COALESCE(description, ''), EXTRACT(EPOCH FROM created_at)::bigint FROM reports WHERE name = $1",
+		name).Scan(&report.Name, &report.Query, &report.Description, &report.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("Failed to load report", zap.String("report", name))
+		return nil, fmt.Errorf("failed to load report: %v", err)
+	}
+	return &report, nil
+}
+
+// ListReports retrieves all reports ordered by name.
+func (d *DatabaseServiceImpl) ListReports(ctx context.Context) ([]*pb.Report, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot list reports")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.ListReports")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		"SELECT name, query, COALESCE(description, ''), EXTRACT(EPOCH FROM created_at)::bigint FROM reports ORDER BY name")
+	if err != nil {
+		logger.Error("Failed to query reports")
+		return nil, fmt.Errorf("failed to query reports: %v", err)
+	}
+	defer rows.Close()
+
+	var reports []*pb.Report
+	for rows.Next() {
+		var report pb.Report
+		if err := rows.Scan(&report.Name, &report.Query, &report.Description, &report.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read report: %v", err)
+		}
+		reports = append(reports, &report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading reports: %v", err)
+	}
+	return reports, nil
+}
+
+// QueryReport runs a report aggregation query built by reportQuery.sql, decrypting the payload of
+// the rows of a query by command on encrypted payloads.
+func (d *DatabaseServiceImpl) QueryReport(ctx context.Context, query string, args []interface{}) ([]ReportRow, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot run report")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.QueryReport")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Failed to run report query", zap.String("query", query), zap.Error(err))
+		return nil, fmt.Errorf("failed to run report query: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report columns: %v", err)
+	}
+	byCommand := len(columns) == 6
+
+	var result []ReportRow
+	for rows.Next() {
+		var row ReportRow
+		var commandID, minionID string
+		dest := []interface{}{&row.Dimension, &row.Count, &row.Failures}
+		if byCommand {
+			dest = []interface{}{&row.Dimension, &commandID, &minionID, &row.Command, &row.Count, &row.Failures}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to read report row: %v", err)
+		}
+		if byCommand {
+			row.Command = d.decryptPayload(row.Command, commandID, minionID)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading report rows: %v", err)
+	}
+	return result, nil
+}
+
 // StoreCommand persists command information to the database.
-func (d *DatabaseServiceImpl) StoreCommand(ctx context.Context, commandID, minionID, payload string) error {
+func (d *DatabaseServiceImpl) StoreCommand(ctx context.Context, record CommandRecord) error {
 	if d == nil || d.db == nil {
-		return fmt.Errorf("database service unavailable - cannot store command %s for minion %s", commandID, minionID)
+		return fmt.Errorf("database service unavailable - cannot store command %s for minion %s", record.CommandID, record.MinionID)
 	}
 
 	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreCommand")
 	defer logging.FuncExit(logger, start)
 
-	_, err := d.db.ExecContext(ctx,
-		"INSERT INTO commands (id, host_id, command, timestamp, direction, status) VALUES ($1, $2, $3, $4, $5, $6)",
-		commandID, minionID, payload, time.Now(), "SENT", "PENDING")
+	payload, redacted := d.redactor.Redact(record.Payload)
+	stored, err := d.encryptor.Encrypt(payload, encryptionBinding{record.CommandID, record.MinionID, payloadColumn})
+	if err != nil {
+		return fmt.Errorf("failed to encrypt command %s: %v", record.CommandID, err)
+	}
+	targets, err := record.targetsJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal targets of command %s: %v", record.CommandID, err)
+	}
+	_, err = d.db.ExecContext(ctx,
+		"INSERT INTO commands (id, host_id, command, timestamp, direction, status, redacted, trace_id, impact, operator, targets) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		record.CommandID, record.MinionID, stored, time.Now(), "SENT", "PENDING", redacted, nullIfEmpty(record.TraceID), nullIfEmpty(record.Impact), nullIfEmpty(record.Operator), targets)
 
 	if err != nil {
 		logger.Error("Failed to store command in database",
-			zap.String("command_id", commandID),
-			zap.String("minion_id", minionID))
+			zap.String("command_id", record.CommandID),
+			zap.String("minion_id", record.MinionID))
 		return fmt.Errorf("failed to store command: %v", err)
 	}
 
 	logger.Debug("Stored command in database",
-		zap.String("command_id", commandID),
-		zap.String("minion_id", minionID),
+		zap.String("command_id", record.CommandID),
+		zap.String("minion_id", record.MinionID),
+		zap.String("trace_id", record.TraceID),
+		zap.String("impact", record.Impact),
+		zap.String("operator", record.Operator),
 		zap.String("payload", payload),
 		zap.String("status", "PENDING"))
 
 	return nil
 }
 
+// CommandRecord is a command stored for one target minion.
+type CommandRecord struct {
+	CommandID string
+	MinionID  string
+	Payload   string
+	TraceID   string
+	Impact    string
+	Operator  string   // console that sent the command, empty when unknown
+	Targets   []string // minions the command was resolved to, MinionID alone when empty
+}
+
+// targetsJSON returns the JSON of the targets of the command
+func (r CommandRecord) targetsJSON() (string, error) {
+	targets := r.Targets
+	if len(targets) == 0 {
+		targets = []string{r.MinionID}
+	}
+	data, err := json.Marshal(targets)
+	return string(data), err
+}
+
+// StoreCommands persists several commands in a single transaction.
+// Either all the commands are stored or none of them.
+func (d *DatabaseServiceImpl) StoreCommands(ctx context.Context, records []CommandRecord) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot store %d commands", len(records))
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreCommands")
+	defer logging.FuncExit(logger, start)
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin command batch transaction: %v", err)
+	}
+	defer tx.Rollback() // Will be a no-op if transaction is committed
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO commands (id, host_id, command, timestamp, direction, status, redacted, trace_id, impact, operator, targets) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare command batch insert: %v", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, record := range records {
+		payload, redacted := d.redactor.Redact(record.Payload)
+		payload, err := d.encryptor.Encrypt(payload, encryptionBinding{record.CommandID, record.MinionID, payloadColumn})
+		if err != nil {
+			return fmt.Errorf("failed to encrypt command %s: %v", record.CommandID, err)
+		}
+		targets, err := record.targetsJSON()
+		if err != nil {
+			return fmt.Errorf("failed to marshal targets of command %s: %v", record.CommandID, err)
+		}
+		if _, err := stmt.ExecContext(ctx, record.CommandID, record.MinionID, payload, now, "SENT", "PENDING", redacted, nullIfEmpty(record.TraceID), nullIfEmpty(record.Impact), nullIfEmpty(record.Operator), targets); err != nil {
+			logger.Error("Failed to store command batch in database",
+				zap.String("command_id", record.CommandID),
+				zap.String("minion_id", record.MinionID),
+				zap.Error(err))
+			return fmt.Errorf("failed to store command %s for minion %s: %v", record.CommandID, record.MinionID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit command batch: %v", err)
+	}
+
+	logger.Debug("Stored command batch in database", zap.Int("count", len(records)))
+	return nil
+}
+
 // UpdateCommandStatus updates the status of a command in the database.
 func (d *DatabaseServiceImpl) UpdateCommandStatus(ctx context.Context, commandID string, status string) error {
 	if d == nil || d.db == nil {
@@ -192,11 +596,17 @@ func (d *DatabaseServiceImpl) UpdateCommandStatus(ctx context.Context, commandID
 
 // GetCommandResults retrieves all results for a specific command.
 func (d *DatabaseServiceImpl) GetCommandResults(ctx context.Context, commandID string) ([]*pb.CommandResult, error) {
+	return d.GetCommandResultsPage(ctx, commandID, 0, 0)
+}
+
+// GetCommandResultsPage retrieves up to limit results for a specific command, skipping the first offset ones.
+// A limit of 0 retrieves all results.
+func (d *DatabaseServiceImpl) GetCommandResultsPage(ctx context.Context, commandID string, limit, offset int) ([]*pb.CommandResult, error) {
 	if d == nil {
 		return []*pb.CommandResult{}, fmt.Errorf("DatabaseServiceImpl is nil")
 	}
 
-	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetCommandResults")
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetCommandResultsPage")
 	defer logging.FuncExit(logger, start)
 
 	if d.db == nil {
@@ -209,7 +619,7 @@ func (d *DatabaseServiceImpl) GetCommandResults(ctx context.Context, commandID s
 
 	// First, check if the command exists in the commands table
 	var cmdCount int
-	err := d.db.QueryRowContext(ctx,
+	err := d.reader().QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM commands WHERE id = $1",
 		commandID).Scan(&cmdCount)
 	if err != nil {
@@ -221,14 +631,19 @@ func (d *DatabaseServiceImpl) GetCommandResults(ctx context.Context, commandID s
 			zap.Int("command_count", cmdCount))
 	}
 
-	// Query database for command results
+	// Query database for command results, ordered deterministically so pages don't overlap
+	query := "SELECT command_id, minion_id, exit_code, stdout, stderr, COALESCE(content_type, ''), COALESCE(structured, ''), EXTRACT(EPOCH FROM timestamp)::bigint, attempt, COALESCE(execution, '') FROM command_results WHERE command_id = $1 ORDER BY timestamp ASC, minion_id ASC"
+	args := []interface{}{commandID}
+	if limit > 0 {
+		query += " LIMIT $2 OFFSET $3"
+		args = append(args, limit, offset)
+	}
+
 	logger.Info("DIAGNOSIS: Executing query for command results",
 		zap.String("command_id", commandID),
-		zap.String("query", "SELECT command_id, minion_id, exit_code, stdout, stderr, EXTRACT(EPOCH FROM timestamp)::bigint FROM command_results WHERE command_id = $1 ORDER BY timestamp ASC"))
+		zap.String("query", query))
 
-	rows, err := d.db.QueryContext(ctx,
-		"SELECT command_id, minion_id, exit_code, stdout, stderr, EXTRACT(EPOCH FROM timestamp)::bigint FROM command_results WHERE command_id = $1 ORDER BY timestamp ASC",
-		commandID)
+	rows, err := d.reader().QueryContext(ctx, query, args...)
 	if err != nil {
 		logger.Error("DIAGNOSIS: Failed to query command results - database connection failed",
 			zap.String("command_id", commandID),
@@ -243,7 +658,8 @@ func (d *DatabaseServiceImpl) GetCommandResults(ctx context.Context, commandID s
 	for rows.Next() {
 		var result pb.CommandResult
 		var timestamp int64
-		err := rows.Scan(&result.CommandId, &result.MinionId, &result.ExitCode, &result.Stdout, &result.Stderr, &timestamp)
+		var execution string
+		err := rows.Scan(&result.CommandId, &result.MinionId, &result.ExitCode, &result.Stdout, &result.Stderr, &result.ContentType, &result.Structured, &timestamp, &result.Attempt, &execution)
 		if err != nil {
 			logger.Warn("Failed to scan command result row",
 				zap.String("command_id", result.CommandId),
@@ -251,6 +667,18 @@ func (d *DatabaseServiceImpl) GetCommandResults(ctx context.Context, commandID s
 			continue
 		}
 		result.Timestamp = timestamp
+		if result.Execution, err = decodeExecution(execution); err != nil {
+			logger.Warn("Failed to decode command result execution info",
+				zap.String("command_id", result.CommandId),
+				zap.String("minion_id", result.MinionId),
+				zap.Error(err))
+		}
+		if err := d.encryptor.DecryptResult(&result); err != nil {
+			logger.Warn("Failed to decrypt command result output",
+				zap.String("command_id", result.CommandId),
+				zap.String("minion_id", result.MinionId),
+				zap.Error(err))
+		}
 		results = append(results, &result)
 	}
 
@@ -267,6 +695,139 @@ func (d *DatabaseServiceImpl) GetCommandResults(ctx context.Context, commandID s
 	return results, nil
 }
 
+// GetCommandStatuses retrieves the status of a command on each of its target minions.
+// Minions which reported a non-zero exit code are reported as FAILED.
+func (d *DatabaseServiceImpl) GetCommandStatuses(ctx context.Context, commandID string) ([]*pb.CommandStatusResponse_MinionStatus, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get statuses of command %s", commandID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetCommandStatuses")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT DISTINCT ON (c.host_id) c.host_id,
+			CASE WHEN r.exit_code IS NULL THEN c.status WHEN r.exit_code = 0 THEN 'COMPLETED' ELSE 'FAILED' END,
+			EXTRACT(EPOCH FROM COALESCE(r.timestamp, c.timestamp))::bigint
+		FROM commands c
+		LEFT JOIN command_results r ON r.command_id = c.id AND r.minion_id = c.host_id
+		WHERE c.id = $1
+		ORDER BY c.host_id, r.timestamp DESC`,
+		commandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query command statuses: %v", err)
+	}
+	defer rows.Close()
+
+	var statuses []*pb.CommandStatusResponse_MinionStatus
+	for rows.Next() {
+		var minionStatus pb.CommandStatusResponse_MinionStatus
+		if err := rows.Scan(&minionStatus.MinionId, &minionStatus.Status, &minionStatus.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan command status: %v", err)
+		}
+		statuses = append(statuses, &minionStatus)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading command statuses: %v", err)
+	}
+
+	logger.Debug("Retrieved command statuses",
+		zap.String("command_id", commandID),
+		zap.Int("count", len(statuses)))
+	return statuses, nil
+}
+
+// ListArchivableCommands returns up to limit commands whose results were all stored before cutoff.
+func (d *DatabaseServiceImpl) ListArchivableCommands(ctx context.Context, cutoff time.Time, limit int) ([]string, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot list archivable commands")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.ListArchivableCommands")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT command_id FROM command_results GROUP BY command_id HAVING MAX(timestamp) < $1 ORDER BY MAX(timestamp) LIMIT $2",
+		cutoff, limit)
+	if err != nil {
+		logger.Error("Failed to query archivable commands", zap.Error(err))
+		return nil, fmt.Errorf("failed to query archivable commands: %v", err)
+	}
+	defer rows.Close()
+
+	var commandIDs []string
+	for rows.Next() {
+		var commandID string
+		if err := rows.Scan(&commandID); err != nil {
+			return nil, fmt.Errorf("failed to read archivable command: %v", err)
+		}
+		commandIDs = append(commandIDs, commandID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading archivable commands: %v", err)
+	}
+	return commandIDs, nil
+}
+
+// MarkResultsArchived records where the results of a command were archived, then deletes the results
+// of minionIDs from command_results by batches of archiveDeleteBatchSize minions, so that archiving a
+// command with many results doesn't lock the table in one large DELETE. Results left by an interrupted
+// deletion are already archived: reads skip them and the next archival run deletes them.
+func (d *DatabaseServiceImpl) MarkResultsArchived(ctx context.Context, commandID, objectKey string, count int, minionIDs []string) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot archive results of command %s", commandID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.MarkResultsArchived")
+	defer logging.FuncExit(logger, start)
+
+	_, err := d.db.ExecContext(ctx,
+		`INSERT INTO archived_results (command_id, object_key, result_count, archived_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (command_id) DO UPDATE SET object_key = EXCLUDED.object_key, result_count = EXCLUDED.result_count, archived_at = EXCLUDED.archived_at`,
+		commandID, objectKey, count, time.Now())
+	if err != nil {
+		logger.Error("Failed to record archived results", zap.String("command_id", commandID), zap.Error(err))
+		return fmt.Errorf("failed to record archived results: %v", err)
+	}
+
+	for len(minionIDs) > 0 {
+		batch := minionIDs[:min(len(minionIDs), archiveDeleteBatchSize)]
+		minionIDs = minionIDs[len(batch):]
+		if _, err := d.db.ExecContext(ctx,
+			"DELETE FROM command_results WHERE command_id = $1 AND minion_id = ANY($2)",
+			commandID, pq.Array(batch)); err != nil {
+			logger.Error("Failed to delete archived results", zap.String("command_id", commandID), zap.Error(err))
+			return fmt.Errorf("failed to delete archived results: %v", err)
+		}
+	}
+
+	logger.Debug("Command results archived",
+		zap.String("command_id", commandID),
+		zap.String("object_key", objectKey),
+		zap.Int("count", count))
+	return nil
+}
+
+// GetArchivedResultsKey returns the object key of the archived results of a command,
+// or an empty string when they were not archived.
+func (d *DatabaseServiceImpl) GetArchivedResultsKey(ctx context.Context, commandID string) (string, error) {
+	if d == nil || d.db == nil {
+		return "", fmt.Errorf("database service unavailable - cannot look up archived results of command %s", commandID)
+	}
+
+	var objectKey string
+	err := d.db.QueryRowContext(ctx,
+		"SELECT object_key FROM archived_results WHERE command_id = $1",
+		commandID).Scan(&objectKey)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up archived results: %v", err)
+	}
+	return objectKey, nil
+}
+
 // updateHostTags updates the tags for a host in the database.
 // This is a helper method used by the registry for tag operations.
 func (d *DatabaseServiceImpl) updateHostTags(ctx context.Context, minionID string, hostInfo *pb.HostInfo) error {
@@ -328,7 +889,12 @@ func (d *DatabaseServiceImpl) updateHostTags(ctx context.Context, minionID strin
 	return nil
 }
 
+// ErrDuplicateResult is returned when storing a result already stored for its command and minion,
+// which the minion sent again without an acknowledgement
+var ErrDuplicateResult = errors.New("command result already stored")
+
 // StoreCommandResult persists command execution results to the database with transaction safety.
+// A result already stored is left as is and ErrDuplicateResult is returned.
 func (d *DatabaseServiceImpl) StoreCommandResult(ctx context.Context, result *pb.CommandResult) error {
 	if d == nil || d.db == nil {
 		return fmt.Errorf("database service unavailable - cannot store command result for command %s", result.CommandId)
@@ -343,6 +909,9 @@ func (d *DatabaseServiceImpl) StoreCommandResult(ctx context.Context, result *pb
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if err := d.storeResultWithRetry(ctx, result, attempt, baseDelay, logger); err != nil {
+			if errors.Is(err, ErrDuplicateResult) {
+				return err
+			}
 			if attempt == maxRetries-1 {
 				return fmt.Errorf("failed to store command result after %d attempts: %v", maxRetries, err)
 			}
@@ -384,7 +953,26 @@ func (d *DatabaseServiceImpl) executeStoreTransaction(ctx context.Context, tx *s
 		return err
 	}
 
+	if result.Attempt > 1 {
+		if err := d.supersedeAttempts(ctx, tx, result); err != nil {
+			logger.Error("Failed to supersede the previous attempts of a retried command",
+				zap.String("command_id", result.CommandId),
+				zap.String("minion_id", result.MinionId),
+				zap.Int32("command_attempt", result.Attempt),
+				zap.Error(err))
+			return err
+		}
+	}
+
 	if err := d.insertCommandResult(ctx, tx, result, attempt, logger); err != nil {
+		if isDuplicateResult(err) {
+			// The minion resent a result it had no acknowledgement for, the transaction is rolled back
+			d.duplicateResults.Add(1)
+			logger.Info("Ignored duplicate command result",
+				zap.String("command_id", result.CommandId),
+				zap.String("minion_id", result.MinionId))
+			return ErrDuplicateResult
+		}
 		return err
 	}
 
@@ -392,6 +980,15 @@ func (d *DatabaseServiceImpl) executeStoreTransaction(ctx context.Context, tx *s
 		return err
 	}
 
+	if err := d.insertResultEvent(ctx, tx, result); err != nil {
+		logger.Error("Failed to record the result in the command trace",
+			zap.String("command_id", result.CommandId),
+			zap.String("minion_id", result.MinionId),
+			zap.String("trace_id", result.TraceId),
+			zap.Error(err))
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		logger.Error("HARDENING: Failed to commit result storage transaction",
 			zap.String("command_id", result.CommandId),
@@ -463,11 +1060,22 @@ func (d *DatabaseServiceImpl) logCommandDiagnostics(ctx context.Context, tx *sql
 
 // insertCommandResult inserts the command result into the database
 func (d *DatabaseServiceImpl) insertCommandResult(ctx context.Context, tx *sql.Tx, result *pb.CommandResult, attempt int, logger *zap.Logger) error {
-	query := "INSERT INTO command_results (command_id, minion_id, exit_code, stdout, stderr, timestamp) VALUES ($1, $2, $3, $4, $5, $6)"
-	_, err := tx.ExecContext(ctx, query,
-		result.CommandId, result.MinionId, result.ExitCode, result.Stdout, result.Stderr, time.Unix(result.Timestamp, 0))
-
+	result, redacted := d.redactor.RedactResult(result)
+	result, err := d.encryptor.EncryptResult(result)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt result: %v", err)
+	}
+	execution, err := encodeExecution(result.Execution)
 	if err != nil {
+		return err
+	}
+	query := "INSERT INTO command_results (command_id, minion_id, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, attempt, execution) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+	_, err = tx.ExecContext(ctx, query,
+		result.CommandId, result.MinionId, result.ExitCode, result.Stdout, result.Stderr,
+		nullIfEmpty(result.ContentType), nullIfEmpty(result.Structured), time.Unix(result.Timestamp, 0), redacted, nullIfEmpty(result.TraceId), max(result.Attempt, 1),
+		nullIfEmpty(execution))
+
+	if err != nil && !isDuplicateResult(err) {
 		logger.Error("HARDENING: Failed to insert command result in transaction",
 			zap.String("command_id", result.CommandId),
 			zap.String("minion_id", result.MinionId),
@@ -475,9 +1083,27 @@ func (d *DatabaseServiceImpl) insertCommandResult(ctx context.Context, tx *sql.T
 			zap.String("error_type", fmt.Sprintf("%T", err)),
 			zap.Int("attempt", attempt+1),
 			zap.Error(err))
-		return err
 	}
 
+	return err
+}
+
+// supersedeAttempts moves the results of the previous attempts of a retried command on a minion from
+// command_results to command_result_attempts, the history of the attempts, before the result of the
+// next attempt is stored
+func (d *DatabaseServiceImpl) supersedeAttempts(ctx context.Context, tx *sql.Tx, result *pb.CommandResult) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO command_result_attempts (command_id, minion_id, attempt, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, execution)
+		SELECT command_id, minion_id, attempt, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, execution
+		FROM command_results WHERE command_id = $1 AND minion_id = $2 AND attempt < $3`,
+		result.CommandId, result.MinionId, result.Attempt); err != nil {
+		return fmt.Errorf("failed to record previous attempts: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM command_results WHERE command_id = $1 AND minion_id = $2 AND attempt < $3",
+		result.CommandId, result.MinionId, result.Attempt); err != nil {
+		return fmt.Errorf("failed to delete previous attempts: %v", err)
+	}
 	return nil
 }
 
@@ -498,3 +1124,49 @@ func (d *DatabaseServiceImpl) updateCommandStatusInTx(ctx context.Context, tx *s
 
 	return nil
 }
+
+// isDuplicateResult tells whether err is the violation of the unique command and minion constraint of command_results
+func isDuplicateResult(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == resultUniqueConstraint
+}
+
+// DuplicateResults returns the number of duplicate results ignored since Nexus started
+func (d *DatabaseServiceImpl) DuplicateResults() int64 {
+	if d == nil {
+		return 0
+	}
+	return d.duplicateResults.Load()
+}
+
+// encodeExecution serializes the execution info of a result as JSON, empty without one
+func encodeExecution(info *pb.ExecutionInfo) (string, error) {
+	if info == nil {
+		return "", nil
+	}
+	data, err := protojson.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode execution info: %v", err)
+	}
+	return string(data), nil
+}
+
+// decodeExecution parses the execution info stored with a result, nil for results stored without
+func decodeExecution(data string) (*pb.ExecutionInfo, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var info pb.ExecutionInfo
+	if err := protojson.Unmarshal([]byte(data), &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// nullIfEmpty stores empty optional columns as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
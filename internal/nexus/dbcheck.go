@@ -0,0 +1,174 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requiredTables lists the tables Nexus relies on
+var requiredTables = []string{"hosts", "host_changes", "commands", "command_results", "reports", "archived_results", "command_approvals", "minion_logs", "connection_events", "minion_identities", "minion_crashes", "command_env", "retired_hosts", "retired_command_results", "command_result_attempts"}
+
+// integrityCheck is a database consistency check, with the statements fixing what it finds
+type integrityCheck struct {
+	name        string
+	description string
+	count       string   // query counting the inconsistent rows
+	repair      []string // statements fixing them, run in a transaction (none: not repairable)
+}
+
+// integrityChecks are run in order, repairing a check may fix the following ones
+var integrityChecks = []integrityCheck{
+	{
+		name:        "orphaned_results",
+		description: "Command results whose command does not exist",
+		count:       "SELECT COUNT(*) FROM command_results cr WHERE NOT EXISTS (SELECT 1 FROM commands c WHERE c.id = cr.command_id)",
+		repair: []string{
+			"DELETE FROM command_results cr WHERE NOT EXISTS (SELECT 1 FROM commands c WHERE c.id = cr.command_id)",
+		},
+	},
+	{
+		name:        "orphaned_commands",
+		description: "Commands whose minion does not exist",
+		count:       "SELECT COUNT(*) FROM commands c WHERE c.host_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM hosts h WHERE h.id = c.host_id)",
+		repair: []string{
+			"DELETE FROM command_results cr WHERE cr.command_id IN (SELECT c.id FROM commands c WHERE c.host_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM hosts h WHERE h.id = c.host_id))",
+			"DELETE FROM archived_results a WHERE a.command_id IN (SELECT c.id FROM commands c WHERE c.host_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM hosts h WHERE h.id = c.host_id))",
+			"DELETE FROM commands c WHERE c.host_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM hosts h WHERE h.id = c.host_id)",
+		},
+	},
+	{
+		name:        "results_without_minion",
+		description: "Command results reported by a minion which does not exist",
+		count:       "SELECT COUNT(*) FROM command_results cr WHERE NOT EXISTS (SELECT 1 FROM hosts h WHERE h.id = cr.minion_id)",
+		repair: []string{
+			"DELETE FROM command_results cr WHERE NOT EXISTS (SELECT 1 FROM hosts h WHERE h.id = cr.minion_id)",
+		},
+	},
+	{
+		name:        "unfinished_with_results",
+		description: "Commands still pending or executing although their minion reported a result",
+		count:       "SELECT COUNT(*) FROM commands c WHERE c.status NOT IN ('COMPLETED', 'FAILED') AND EXISTS (SELECT 1 FROM command_results cr WHERE cr.command_id = c.id AND cr.minion_id = c.host_id)",
+		repair: []string{
+			"UPDATE commands c SET status = 'COMPLETED' WHERE c.status NOT IN ('COMPLETED', 'FAILED') AND EXISTS (SELECT 1 FROM command_results cr WHERE cr.command_id = c.id AND cr.minion_id = c.host_id)",
+		},
+	},
+}
+
+// CheckIntegrity runs the database consistency checks, repairing the inconsistencies found when repair is set.
+func (d *DatabaseServiceImpl) CheckIntegrity(ctx context.Context, repair bool) ([]*pb.DatabaseCheck, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot check integrity")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.CheckIntegrity")
+	defer logging.FuncExit(logger, start)
+
+	tables := &pb.DatabaseCheck{Name: "missing_tables", Description: "Tables Nexus requires which do not exist"}
+	for _, table := range requiredTables {
+		var exists bool
+		if err := d.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT FROM pg_tables WHERE tablename = $1)", table).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check table %s: %v", table, err)
+		}
+		if !exists {
+			tables.Found++
+			tables.Error += table + " "
+		}
+	}
+	if tables.Found > 0 {
+		tables.Error = "missing: " + tables.Error[:len(tables.Error)-1]
+		// Consistency checks would fail on the missing tables
+		return []*pb.DatabaseCheck{tables}, nil
+	}
+
+	checks := []*pb.DatabaseCheck{tables}
+	for _, check := range integrityChecks {
+		result := &pb.DatabaseCheck{
+			Name:        check.name,
+			Description: check.description,
+			Repairable:  len(check.repair) > 0,
+		}
+		checks = append(checks, result)
+
+		if err := d.db.QueryRowContext(ctx, check.count).Scan(&result.Found); err != nil {
+			logger.Error("Integrity check failed", zap.String("check", check.name), zap.Error(err))
+			result.Error = fmt.Sprintf("check failed: %v", err)
+			continue
+		}
+		if !repair || result.Found == 0 || !result.Repairable {
+			continue
+		}
+
+		repaired, err := d.repairIntegrity(ctx, check)
+		if err != nil {
+			logger.Error("Integrity repair failed", zap.String("check", check.name), zap.Error(err))
+			result.Error = fmt.Sprintf("repair failed: %v", err)
+			continue
+		}
+		result.Repaired = repaired
+		logger.Info("Repaired database inconsistency",
+			zap.String("check", check.name),
+			zap.Int64("found", result.Found),
+			zap.Int64("repaired", repaired))
+	}
+	return checks, nil
+}
+
+// repairIntegrity runs the repair statements of a check in a transaction and returns the number
+// of rows the last one changed
+func (d *DatabaseServiceImpl) repairIntegrity(ctx context.Context, check integrityCheck) (int64, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // Will be a no-op if transaction is committed
+
+	var repaired int64
+	for _, statement := range check.repair {
+		result, err := tx.ExecContext(ctx, statement)
+		if err != nil {
+			return 0, err
+		}
+		if repaired, err = result.RowsAffected(); err != nil {
+			return 0, err
+		}
+	}
+	return repaired, tx.Commit()
+}
+
+// CheckDatabase runs the database integrity checks in the ConsoleService, optionally repairing
+// the inconsistencies found
+func (s *Server) CheckDatabase(ctx context.Context, req *pb.DatabaseCheckRequest) (*pb.DatabaseCheckReport, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.CheckDatabase")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "database check requires a database")
+	}
+	if err := requireAllNamespaces(ctx, "database check"); err != nil {
+		return nil, err
+	}
+
+	checks, err := s.dbService.CheckIntegrity(ctx, req.Repair)
+	if err != nil {
+		logger.Error("Failed to check database integrity", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to check database integrity")
+	}
+
+	report := &pb.DatabaseCheckReport{Checks: checks, Healthy: true}
+	for _, check := range checks {
+		if check.Error != "" || check.Found > check.Repaired {
+			report.Healthy = false
+		}
+	}
+	logger.Info("Database integrity checked",
+		zap.Bool("repair", req.Repair),
+		zap.Bool("healthy", report.Healthy))
+	return report, nil
+}
@@ -0,0 +1,98 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// expectTables expects the existence check of the required tables, missing the given ones
+func expectTables(mock sqlmock.Sqlmock, missing ...string) {
+	for _, table := range requiredTables {
+		exists := true
+		for _, m := range missing {
+			if m == table {
+				exists = false
+			}
+		}
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(table).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+	}
+}
+
+func TestCheckDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+
+	// Check only: orphans are counted, nothing is changed
+	expectTables(mock)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM command_results cr WHERE NOT EXISTS \\(SELECT 1 FROM commands").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands c WHERE c.host_id IS NOT NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM command_results cr WHERE NOT EXISTS \\(SELECT 1 FROM hosts").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands c WHERE c.status NOT IN").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	report, err := server.CheckDatabase(context.Background(), &pb.DatabaseCheckRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Healthy || len(report.Checks) != len(integrityChecks)+1 {
+		t.Fatalf("Unexpected report: %v", report)
+	}
+	if check := report.Checks[1]; check.Name != "orphaned_results" || check.Found != 2 || check.Repaired != 0 {
+		t.Errorf("Unexpected orphaned results check: %v", check)
+	}
+	if check := report.Checks[3]; check.Error == "" {
+		t.Errorf("Expected failed check to report its error: %v", check)
+	}
+
+	// Repair: orphans are deleted in a transaction
+	expectTables(mock)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM command_results cr WHERE NOT EXISTS \\(SELECT 1 FROM commands").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM command_results").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	for i := 1; i < len(integrityChecks); i++ {
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	}
+
+	report, err = server.CheckDatabase(context.Background(), &pb.DatabaseCheckRequest{Repair: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.Healthy || report.Checks[1].Repaired != 2 {
+		t.Errorf("Expected repaired database, got %v", report)
+	}
+
+	// Missing tables stop the checks
+	expectTables(mock, "reports")
+	report, err = server.CheckDatabase(context.Background(), &pb.DatabaseCheckRequest{Repair: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Healthy || len(report.Checks) != 1 || report.Checks[0].Error != "missing: reports" {
+		t.Errorf("Expected missing table to be reported, got %v", report)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+
+	if _, err := createTestServer(nil).CheckDatabase(context.Background(), &pb.DatabaseCheckRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package nexus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultQueryRows and maxQueryRows bound the number of rows a database query returns
+	defaultQueryRows = 100
+	maxQueryRows     = 1000
+	// queryStatementTimeout cancels database queries running longer
+	queryStatementTimeout = "30s"
+)
+
+var (
+	// readOnlyQueryPattern matches the statements a database query can start with
+	readOnlyQueryPattern = regexp.MustCompile(`(?i)^\s*(SELECT|WITH)\s`)
+	// queryPlaceholderPattern matches the positional parameters of a query
+	queryPlaceholderPattern = regexp.MustCompile(`\$(\d+)`)
+)
+
+// DatabaseQuery is a read-only query consoles can run by name, for routine investigations
+// without direct database access. Its parameters are bound to $1, $2... in order.
+type DatabaseQuery struct {
+	Description string   `json:"description,omitempty"`
+	SQL         string   `json:"sql"`
+	Params      []string `json:"params,omitempty"`
+	MaxRows     int      `json:"max_rows,omitempty"` // 0: 100 rows
+}
+
+// LoadDatabaseQueries reads the approved database queries from a JSON file mapping names to queries
+func LoadDatabaseQueries(path string) (map[string]*DatabaseQuery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database queries: %w", err)
+	}
+
+	var queries map[string]*DatabaseQuery
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("failed to parse database queries: %w", err)
+	}
+
+	for name, q := range queries {
+		if q == nil || !reportNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("database query %q: invalid name", name)
+		}
+		if err := q.validate(); err != nil {
+			return nil, fmt.Errorf("database query %s: %w", name, err)
+		}
+	}
+	return queries, nil
+}
+
+// validate checks that the query is a single read statement whose placeholders match its parameters
+func (q *DatabaseQuery) validate() error {
+	q.SQL = strings.TrimSuffix(strings.TrimSpace(q.SQL), ";")
+	if !readOnlyQueryPattern.MatchString(q.SQL) {
+		return fmt.Errorf("must be a SELECT or WITH statement")
+	}
+	if strings.Contains(q.SQL, ";") {
+		return fmt.Errorf("must be a single statement")
+	}
+	if q.MaxRows < 0 || q.MaxRows > maxQueryRows {
+		return fmt.Errorf("max_rows must be between 0 and %d", maxQueryRows)
+	}
+	if q.MaxRows == 0 {
+		q.MaxRows = defaultQueryRows
+	}
+
+	highest := 0
+	for _, match := range queryPlaceholderPattern.FindAllStringSubmatch(q.SQL, -1) {
+		if n, _ := strconv.Atoi(match[1]); n > highest {
+			highest = n
+		}
+	}
+	if highest != len(q.Params) {
+		return fmt.Errorf("uses %d placeholders but declares %d parameters", highest, len(q.Params))
+	}
+	return nil
+}
+
+// bind returns the arguments of the query in placeholder order
+func (q *DatabaseQuery) bind(params map[string]string) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(q.Params))
+	for _, name := range q.Params {
+		value, ok := params[name]
+		if !ok {
+			return nil, fmt.Errorf("missing value for parameter %s", name)
+		}
+		args = append(args, value)
+	}
+	for name := range params {
+		if !containsString(q.Params, name) {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+	return args, nil
+}
+
+// QueryReadOnly runs query in a read-only transaction and returns its columns and up to maxRows rows,
+// values formatted as strings (NULL as an empty string). truncated reports that more rows were returned.
+func (d *DatabaseServiceImpl) QueryReadOnly(ctx context.Context, query string, args []interface{}, maxRows int) ([]string, [][]string, bool, error) {
+	if d == nil || d.db == nil {
+		return nil, nil, false, fmt.Errorf("database service unavailable - cannot run query")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.QueryReadOnly")
+	defer logging.FuncExit(logger, start)
+
+	tx, err := d.reader().BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to begin read-only transaction: %v", err)
+	}
+	// Nothing to commit in a read-only transaction
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SET LOCAL statement_timeout = '"+queryStatementTimeout+"'"); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to set statement timeout: %v", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Failed to run database query", zap.String("query", query), zap.Error(err))
+		return nil, nil, false, fmt.Errorf("failed to run query: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to read query columns: %v", err)
+	}
+
+	var result [][]string
+	truncated := false
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(result) == maxRows {
+			truncated = true
+			break
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to read query row: %v", err)
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = v.String
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, fmt.Errorf("error reading query rows: %v", err)
+	}
+	return columns, result, truncated, nil
+}
+
+// SetDatabaseQueries sets the read-only queries consoles can run against the Nexus database
+func (s *Server) SetDatabaseQueries(queries map[string]*DatabaseQuery) {
+	s.dbQueries = queries
+}
+
+// ListDatabaseQueries returns the approved database queries, ordered by name
+func (s *Server) ListDatabaseQueries(ctx context.Context, empty *pb.Empty) (*pb.DatabaseQueryList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListDatabaseQueries")
+	defer logging.FuncExit(logger, start)
+
+	if err := requireAllNamespaces(ctx, "database queries"); err != nil {
+		return nil, err
+	}
+
+	list := &pb.DatabaseQueryList{}
+	for name, q := range s.dbQueries {
+		list.Queries = append(list.Queries, &pb.DatabaseQuery{Name: name, Description: q.Description, Params: q.Params})
+	}
+	sort.Slice(list.Queries, func(i, j int) bool { return list.Queries[i].Name < list.Queries[j].Name })
+	return list, nil
+}
+
+// RunDatabaseQuery runs an approved database query with the given parameters
+func (s *Server) RunDatabaseQuery(ctx context.Context, req *pb.DatabaseQueryRequest) (*pb.DatabaseQueryResult, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.RunDatabaseQuery")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "database queries require a database")
+	}
+	if err := requireAllNamespaces(ctx, "database queries"); err != nil {
+		return nil, err
+	}
+
+	query, exists := s.dbQueries[req.Name]
+	if !exists {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("database query %s not found", req.Name))
+	}
+	args, err := query.bind(req.Params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	columns, rows, truncated, err := s.dbService.QueryReadOnly(ctx, query.SQL, args, query.MaxRows)
+	if err != nil {
+		logger.Error("Failed to run database query", zap.String("query", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to run database query %s", req.Name))
+	}
+
+	result := &pb.DatabaseQueryResult{Name: req.Name, Columns: columns, Truncated: truncated}
+	for _, row := range rows {
+		result.Rows = append(result.Rows, &pb.ReportRow{Values: row})
+	}
+	logger.Info("Database query run", zap.String("query", req.Name), zap.Int("rows", len(result.Rows)))
+	return result, nil
+}
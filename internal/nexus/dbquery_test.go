@@ -0,0 +1,125 @@
+package nexus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// writeDatabaseQueries writes a database queries file and loads it
+func writeDatabaseQueries(t *testing.T, content string) (map[string]*DatabaseQuery, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "queries.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write database queries: %v", err)
+	}
+	return LoadDatabaseQueries(path)
+}
+
+func TestLoadDatabaseQueries(t *testing.T) {
+	queries, err := writeDatabaseQueries(t, `{
+		"stale-minions": {
+			"description": "Minions not seen for a while",
+			"sql": "SELECT id, hostname, last_seen FROM hosts WHERE last_seen < now() - $1::interval ORDER BY last_seen;",
+			"params": ["age"]
+		},
+		"busiest": {"sql": "with c as (select host_id from commands) select host_id, count(*) from c group by 1", "max_rows": 10}
+	}`)
+	if err != nil {
+		t.Fatalf("LoadDatabaseQueries failed: %v", err)
+	}
+	if q := queries["stale-minions"]; q.MaxRows != defaultQueryRows || strings.HasSuffix(q.SQL, ";") {
+		t.Errorf("Unexpected query %+v", q)
+	}
+	if queries["busiest"].MaxRows != 10 {
+		t.Errorf("Expected max_rows 10, got %d", queries["busiest"].MaxRows)
+	}
+
+	invalid := map[string]string{
+		"write statement":    `{"purge": {"sql": "DELETE FROM hosts"}}`,
+		"several statements": `{"q": {"sql": "SELECT 1; DROP TABLE hosts"}}`,
+		"missing parameter":  `{"q": {"sql": "SELECT * FROM hosts WHERE id = $1"}}`,
+		"extra parameter":    `{"q": {"sql": "SELECT * FROM hosts", "params": ["id"]}}`,
+		"too many rows":      `{"q": {"sql": "SELECT * FROM hosts", "max_rows": 5000}}`,
+		"invalid name":       `{"1q": {"sql": "SELECT * FROM hosts"}}`,
+		"invalid JSON":       `{"q": [`,
+		"unknown statement":  `{"q": {"sql": "SELECTED * FROM hosts"}}`,
+	}
+	for name, content := range invalid {
+		if _, err := writeDatabaseQueries(t, content); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestDatabaseQueryRPCs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+	ctx := context.Background()
+
+	queries, err := writeDatabaseQueries(t, `{
+		"stale-minions": {"description": "Minions not seen for a while", "sql": "SELECT id, last_seen FROM hosts WHERE last_seen < now() - $1::interval", "params": ["age"], "max_rows": 2}
+	}`)
+	if err != nil {
+		t.Fatalf("LoadDatabaseQueries failed: %v", err)
+	}
+	server.SetDatabaseQueries(queries)
+
+	list, _ := server.ListDatabaseQueries(ctx, &pb.Empty{})
+	if len(list.Queries) != 1 || list.Queries[0].Name != "stale-minions" || list.Queries[0].Params[0] != "age" {
+		t.Errorf("Unexpected query list: %v", list)
+	}
+	if _, err := server.ListDatabaseQueries(namespaceContext("team-a"), &pb.Empty{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected namespace scoped consoles not to list database queries, got %v", err)
+	}
+
+	// Requests not matching the approved queries never reach the database
+	for _, req := range []*pb.DatabaseQueryRequest{
+		{Name: "drop"},
+		{Name: "stale-minions"},
+		{Name: "stale-minions", Params: map[string]string{"age": "1h", "id": "x"}},
+	} {
+		if _, err := server.RunDatabaseQuery(ctx, req); status.Code(err) != codes.NotFound && status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected %v to be rejected, got %v", req, err)
+		}
+	}
+
+	lastSeen := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("SET LOCAL statement_timeout").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, last_seen FROM hosts").
+		WithArgs("7 days").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "last_seen"}).
+			AddRow("minion-1", lastSeen).
+			AddRow("minion-2", nil).
+			AddRow("minion-3", lastSeen))
+	mock.ExpectRollback()
+
+	result, err := server.RunDatabaseQuery(ctx, &pb.DatabaseQueryRequest{Name: "stale-minions", Params: map[string]string{"age": "7 days"}})
+	if err != nil {
+		t.Fatalf("RunDatabaseQuery failed: %v", err)
+	}
+	if strings.Join(result.Columns, ",") != "id,last_seen" || len(result.Rows) != 2 || !result.Truncated {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if result.Rows[0].Values[0] != "minion-1" || !strings.HasPrefix(result.Rows[0].Values[1], "2025-01-02") || result.Rows[1].Values[1] != "" {
+		t.Errorf("Unexpected rows: %v", result.Rows)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
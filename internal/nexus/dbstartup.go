@@ -0,0 +1,197 @@
+package nexus
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// Database states reported by the health endpoints
+const (
+	DatabaseDisabled    = "disabled"    // no database configured
+	DatabaseUnavailable = "unavailable" // not reached since startup, host registrations are buffered
+	DatabaseReady       = "ready"
+)
+
+// dbPingTimeout bounds each attempt to reach the database at startup
+const dbPingTimeout = 5 * time.Second
+
+// dbRetryMin and dbRetryMax bound the delay between two attempts to reach the database at startup
+var (
+	dbRetryMin = time.Second
+	dbRetryMax = 30 * time.Second
+)
+
+// hostBuffer holds the host registrations received before the database could be reached, the
+// latest one of each minion, so that Nexus accepts minions while Postgres is slow or down
+type hostBuffer struct {
+	mu    sync.Mutex
+	hosts map[string]*pb.HostInfo
+	ready chan struct{} // closed once the database is reached
+	stop  chan struct{} // closed when Nexus shuts down
+}
+
+// newHostBuffer creates an empty buffer waiting for the database
+func newHostBuffer() *hostBuffer {
+	return &hostBuffer{
+		hosts: make(map[string]*pb.HostInfo),
+		ready: make(chan struct{}),
+		stop:  make(chan struct{}),
+	}
+}
+
+// isReady reports whether the database was reached
+func (b *hostBuffer) isReady() bool {
+	select {
+	case <-b.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// hold buffers a host registration while the database is not reached, reporting whether it did
+func (b *hostBuffer) hold(hostInfo *pb.HostInfo) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.isReady() {
+		return false
+	}
+	b.hosts[hostInfo.Id] = hostInfo
+	return true
+}
+
+// pending returns the number of buffered host registrations
+func (b *hostBuffer) pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.hosts)
+}
+
+// markReady stops buffering registrations and returns the buffered ones
+func (b *hostBuffer) markReady() map[string]*pb.HostInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hosts := b.hosts
+	b.hosts = make(map[string]*pb.HostInfo)
+	if !b.isReady() {
+		close(b.ready)
+	}
+	return hosts
+}
+
+// forget drops the buffered registration of a minion
+func (b *hostBuffer) forget(minionID string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.hosts, minionID)
+}
+
+// close stops waiting for the database
+func (b *hostBuffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+}
+
+// connectDatabase reaches the database in the background, retrying with an exponential backoff,
+// then stores the host registrations buffered meanwhile. It gives up when Nexus shuts down.
+func (s *Server) connectDatabase(db *sql.DB, buffer *hostBuffer) {
+	delay := dbRetryMin
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+
+		s.logger.Warn("Database unavailable, buffering host registrations",
+			zap.Int("attempt", attempt),
+			zap.Int("buffered", buffer.pending()),
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+		select {
+		case <-buffer.stop:
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, dbRetryMax)
+	}
+
+	hosts := buffer.markReady()
+	replayed := 0
+	for minionID, hostInfo := range hosts {
+		if err := s.GetMinionRegistryImpl().storeBufferedHost(hostInfo); err != nil {
+			s.logger.Warn("Failed to store buffered host registration", zap.String("minion_id", minionID), zap.Error(err))
+			continue
+		}
+		replayed++
+	}
+	s.logger.Info("Database ready", zap.Int("replayed_registrations", replayed))
+	s.loadRetirements(context.Background())
+}
+
+// DatabaseStatus returns the state of the database: DatabaseDisabled, DatabaseUnavailable or DatabaseReady
+func (s *Server) DatabaseStatus() string {
+	switch {
+	case s.dbService == nil:
+		return DatabaseDisabled
+	case s.hosts != nil && !s.hosts.isReady():
+		return DatabaseUnavailable
+	default:
+		return DatabaseReady
+	}
+}
+
+// DuplicateResults returns the number of results resent by minions and ignored, 0 without database
+func (s *Server) DuplicateResults() int64 {
+	if s.dbService == nil {
+		return 0
+	}
+	return s.dbService.DuplicateResults()
+}
+
+// DatabaseReached returns a channel closed once the database is reached, or right away without database
+func (s *Server) DatabaseReached() <-chan struct{} {
+	if s.hosts == nil {
+		ready := make(chan struct{})
+		close(ready)
+		return ready
+	}
+	return s.hosts.ready
+}
+
+// EnableReadReplica opens the read-only replica at connString, serving the result, history and
+// reporting queries so that they don't load the primary database storing commands and results
+func (s *Server) EnableReadReplica(connString string) error {
+	impl, ok := s.dbService.(*DatabaseServiceImpl)
+	if !ok {
+		return fmt.Errorf("read replica requires a database")
+	}
+	replica, err := sql.Open("postgres", connString)
+	if err != nil {
+		return fmt.Errorf("failed to open read replica: %w", err)
+	}
+	impl.SetReadReplica(replica)
+	return nil
+}
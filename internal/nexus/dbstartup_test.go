@@ -0,0 +1,145 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestRegisterBuffersUntilDatabaseReady(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	registry := server.GetMinionRegistryImpl()
+	registry.hosts = newHostBuffer()
+	server.hosts = registry.hosts
+	if status := server.DatabaseStatus(); status != DatabaseUnavailable {
+		t.Errorf("Expected database %s, got %s", DatabaseUnavailable, status)
+	}
+
+	// Registrations succeed without reaching the database
+	for _, id := range []string{"minion-1", "minion-2", "minion-1"} {
+		resp, err := server.Register(context.Background(), &pb.HostInfo{Id: id, Hostname: id})
+		if err != nil || !resp.Success {
+			t.Fatalf("Expected %s to register while the database is unavailable, got %v %v", id, resp, err)
+		}
+	}
+	if pending := registry.hosts.pending(); pending != 2 {
+		t.Fatalf("Expected 2 buffered registrations, got %d", pending)
+	}
+
+	// The buffered registrations are stored once the database is reached
+	originalMin := dbRetryMin
+	dbRetryMin = time.Millisecond
+	defer func() { dbRetryMin = originalMin }()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing()
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery("INSERT INTO hosts").WithArgs("minion-1", "minion-1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	mock.ExpectQuery("INSERT INTO hosts").WithArgs("minion-2", "minion-2", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+
+	server.connectDatabase(db, registry.hosts)
+	select {
+	case <-server.DatabaseReached():
+	default:
+		t.Fatal("Expected the database to be ready")
+	}
+	if status := server.DatabaseStatus(); status != DatabaseReady {
+		t.Errorf("Expected database %s, got %s", DatabaseReady, status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Buffered registrations not replayed: %v", err)
+	}
+
+	// Later registrations are stored right away
+	mock.ExpectQuery("INSERT INTO hosts").WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	if _, err := server.Register(context.Background(), &pb.HostInfo{Id: "minion-2", Hostname: "minion-2"}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Registration not stored: %v", err)
+	}
+}
+
+func TestConnectDatabaseStopsOnShutdown(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	server := createTestServer(db)
+	server.hosts = newHostBuffer()
+	server.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		server.connectDatabase(db, server.hosts)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected connectDatabase to give up once Nexus shuts down")
+	}
+	if status := server.DatabaseStatus(); status != DatabaseUnavailable {
+		t.Errorf("Expected database %s, got %s", DatabaseUnavailable, status)
+	}
+}
+
+func TestReadReplica(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer primary.Close()
+	replica, replicaMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer replica.Close()
+
+	service := NewDatabaseService(primary, zap.NewNop())
+	service.SetReadReplica(replica)
+
+	// Writes go to the primary, result queries to the replica
+	primaryMock.ExpectExec("INSERT INTO commands").WillReturnResult(sqlmock.NewResult(1, 1))
+	replicaMock.ExpectQuery("FROM commands c").WithArgs("cmd-1").
+		WillReturnRows(sqlmock.NewRows([]string{"host_id", "status", "timestamp"}).AddRow("minion-1", "PENDING", 1640995200))
+	if err := service.StoreCommand(context.Background(), CommandRecord{CommandID: "cmd-1", MinionID: "minion-1", Payload: "uptime"}); err != nil {
+		t.Fatalf("StoreCommand failed: %v", err)
+	}
+	statuses, err := service.GetCommandStatuses(context.Background(), "cmd-1")
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("Expected the status read from the replica, got %v, %v", statuses, err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled primary expectations: %v", err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled replica expectations: %v", err)
+	}
+
+	// Without replica the primary serves the reads
+	service.SetReadReplica(nil)
+	if service.reader() != primary {
+		t.Error("Expected reads from the primary without replica")
+	}
+
+	if err := createTestServer(nil).EnableReadReplica("postgres://replica/minexus"); err == nil {
+		t.Error("Expected an error enabling a read replica without database")
+	}
+}
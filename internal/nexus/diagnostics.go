@@ -0,0 +1,280 @@
+package nexus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxConnectionEvents is the number of connection events kept per minion
+const maxConnectionEvents = 20
+
+// Connection event types
+const (
+	EventRegistered   = "REGISTERED"
+	EventStreamOpened = "STREAM_OPENED"
+	EventStreamClosed = "STREAM_CLOSED"
+	EventError        = "ERROR"
+)
+
+// Stream states
+const (
+	StreamStateConnected      = "CONNECTED"
+	StreamStateDisconnected   = "DISCONNECTED"
+	StreamStateNeverConnected = "NEVER_CONNECTED"
+)
+
+// minionDiagnostics holds the connection history of a single minion
+type minionDiagnostics struct {
+	activeStreams     int32
+	streamConnectedAt time.Time
+	everConnected     bool
+	commandsSent      int64
+	resultsReceived   int64
+	events            []*pb.ConnectionEvent
+	lastError         string
+	lastErrorAt       time.Time
+}
+
+// DiagnosticsTracker records per-minion connection state so it can be queried
+// through GetMinionDiagnostics. A nil tracker ignores all records.
+type DiagnosticsTracker struct {
+	mu      sync.Mutex
+	minions map[string]*minionDiagnostics
+	now     func() time.Time
+}
+
+// NewDiagnosticsTracker creates an empty diagnostics tracker
+func NewDiagnosticsTracker() *DiagnosticsTracker {
+	return &DiagnosticsTracker{
+		minions: make(map[string]*minionDiagnostics),
+		now:     time.Now,
+	}
+}
+
+// get returns the diagnostics of a minion, creating them if needed. The caller must hold d.mu
+func (d *DiagnosticsTracker) get(minionID string) *minionDiagnostics {
+	diag, exists := d.minions[minionID]
+	if !exists {
+		diag = &minionDiagnostics{}
+		d.minions[minionID] = diag
+	}
+	return diag
+}
+
+// addEvent appends a connection event, keeping the most recent ones. The caller must hold d.mu
+func (d *DiagnosticsTracker) addEvent(diag *minionDiagnostics, event, detail string) {
+	diag.events = append(diag.events, &pb.ConnectionEvent{
+		Timestamp: d.now().Unix(),
+		Event:     event,
+		Detail:    detail,
+	})
+	if len(diag.events) > maxConnectionEvents {
+		diag.events = diag.events[len(diag.events)-maxConnectionEvents:]
+	}
+}
+
+// RecordRegistration records a minion registration
+func (d *DiagnosticsTracker) RecordRegistration(minionID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.addEvent(d.get(minionID), EventRegistered, "")
+}
+
+// RecordStreamOpened records the opening of a command stream
+func (d *DiagnosticsTracker) RecordStreamOpened(minionID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	diag := d.get(minionID)
+	diag.activeStreams++
+	diag.everConnected = true
+	diag.streamConnectedAt = d.now()
+	detail := ""
+	if diag.activeStreams > 1 {
+		detail = "concurrent stream"
+	}
+	d.addEvent(diag, EventStreamOpened, detail)
+}
+
+// RecordStreamClosed records the end of a command stream and the error that closed it, if any
+func (d *DiagnosticsTracker) RecordStreamClosed(minionID string, err error) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	diag := d.get(minionID)
+	if diag.activeStreams > 0 {
+		diag.activeStreams--
+	}
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	d.addEvent(diag, EventStreamClosed, detail)
+}
+
+// RecordError records the last error seen for a minion
+func (d *DiagnosticsTracker) RecordError(minionID string, err error) {
+	if d == nil || err == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	diag := d.get(minionID)
+	diag.lastError = err.Error()
+	diag.lastErrorAt = d.now()
+	d.addEvent(diag, EventError, err.Error())
+}
+
+// RecordCommandSent counts a command sent on the stream
+func (d *DiagnosticsTracker) RecordCommandSent(minionID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.get(minionID).commandsSent++
+}
+
+// RecordResult counts a result received from the minion
+func (d *DiagnosticsTracker) RecordResult(minionID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.get(minionID).resultsReceived++
+}
+
+// ForgetDisconnected drops the connection history of the minions without an open command stream
+// and returns their number
+func (d *DiagnosticsTracker) ForgetDisconnected() int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	forgotten := 0
+	for minionID, diag := range d.minions {
+		if diag.activeStreams == 0 {
+			delete(d.minions, minionID)
+			forgotten++
+		}
+	}
+	return forgotten
+}
+
+// Fill copies the recorded diagnostics of a minion into diagnostics
+func (d *DiagnosticsTracker) Fill(minionID string, diagnostics *pb.MinionDiagnostics) {
+	diagnostics.StreamState = StreamStateNeverConnected
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	diag, exists := d.minions[minionID]
+	if !exists {
+		return
+	}
+
+	switch {
+	case diag.activeStreams > 0:
+		diagnostics.StreamState = StreamStateConnected
+	case diag.everConnected:
+		diagnostics.StreamState = StreamStateDisconnected
+	}
+	diagnostics.ActiveStreams = diag.activeStreams
+	if !diag.streamConnectedAt.IsZero() {
+		diagnostics.StreamConnectedAt = diag.streamConnectedAt.Unix()
+	}
+	diagnostics.CommandsSent = diag.commandsSent
+	diagnostics.ResultsReceived = diag.resultsReceived
+	diagnostics.LastError = diag.lastError
+	if !diag.lastErrorAt.IsZero() {
+		diagnostics.LastErrorAt = diag.lastErrorAt.Unix()
+	}
+	diagnostics.Events = make([]*pb.ConnectionEvent, len(diag.events))
+	copy(diagnostics.Events, diag.events)
+}
+
+// pendingCommandCount returns the number of tracked commands still waiting for a result from the minion
+func (s *Server) pendingCommandCount(minionID string) int32 {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	var count int32
+	for _, tracker := range s.pendingCommands {
+		if tracker.Pending[minionID] {
+			count++
+		}
+	}
+	return count
+}
+
+// GetMinionDiagnostics returns the connection state of a minion in the ConsoleService
+func (s *Server) GetMinionDiagnostics(ctx context.Context, req *pb.MinionDiagnosticsRequest) (*pb.MinionDiagnostics, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetMinionDiagnostics")
+	defer logging.FuncExit(logger, start)
+
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if consoleScope(ctx).restricted() {
+		if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+			return nil, err
+		}
+	}
+
+	diagnostics := &pb.MinionDiagnostics{MinionId: req.MinionId}
+	s.diagnostics.Fill(req.MinionId, diagnostics)
+
+	registry := s.GetMinionRegistryImpl()
+	conn, registered := registry.GetConnectionImpl(req.MinionId)
+	if !registered && len(diagnostics.Events) == 0 {
+		return nil, status.Error(codes.NotFound, "minion not found")
+	}
+
+	if registered {
+		lastSeen, _ := registry.LastSeen(req.MinionId)
+		diagnostics.Registered = true
+		diagnostics.LastSeen = lastSeen.Unix()
+		diagnostics.ChannelDepth = int32(conn.Commands.Len())
+		diagnostics.ChannelCapacity = int32(conn.Commands.Cap())
+		diagnostics.InFlight = int32(conn.Commands.InFlight())
+		diagnostics.InFlightLimit = int32(conn.Commands.Limit())
+	}
+	diagnostics.PendingCommands = s.pendingCommandCount(req.MinionId)
+	if s.maintenance != nil {
+		diagnostics.HeldCommands = int32(s.maintenance.HeldCountFor(req.MinionId))
+	}
+	diagnostics.LastSpeedTest = s.lastSpeedTest(ctx, req.MinionId, logger)
+
+	logger.Debug("Minion diagnostics collected",
+		zap.String("minion_id", req.MinionId),
+		zap.String("stream_state", diagnostics.StreamState),
+		zap.Int32("channel_depth", diagnostics.ChannelDepth))
+
+	return diagnostics, nil
+}
@@ -0,0 +1,90 @@
+package nexus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestDiagnosticsTracker(t *testing.T) {
+	tracker := NewDiagnosticsTracker()
+
+	tracker.RecordRegistration("minion-1")
+	tracker.RecordStreamOpened("minion-1")
+	tracker.RecordStreamOpened("minion-1")
+	tracker.RecordCommandSent("minion-1")
+	tracker.RecordResult("minion-1")
+
+	diag := &pb.MinionDiagnostics{}
+	tracker.Fill("minion-1", diag)
+	if diag.StreamState != StreamStateConnected || diag.ActiveStreams != 2 {
+		t.Errorf("Expected 2 active streams, got %+v", diag)
+	}
+	if diag.Events[2].Detail != "concurrent stream" {
+		t.Errorf("Expected concurrent stream to be flagged, got %+v", diag.Events[2])
+	}
+
+	tracker.RecordStreamClosed("minion-1", nil)
+	tracker.RecordStreamClosed("minion-1", errors.New("connection reset"))
+	tracker.RecordError("minion-1", errors.New("send failed"))
+
+	diag = &pb.MinionDiagnostics{}
+	tracker.Fill("minion-1", diag)
+	if diag.StreamState != StreamStateDisconnected || diag.ActiveStreams != 0 {
+		t.Errorf("Expected disconnected state, got %+v", diag)
+	}
+	if diag.LastError != "send failed" || diag.CommandsSent != 1 || diag.ResultsReceived != 1 {
+		t.Errorf("Unexpected diagnostics: %+v", diag)
+	}
+
+	for i := 0; i < maxConnectionEvents*2; i++ {
+		tracker.RecordRegistration("minion-1")
+	}
+	diag = &pb.MinionDiagnostics{}
+	tracker.Fill("minion-1", diag)
+	if len(diag.Events) != maxConnectionEvents {
+		t.Errorf("Expected history to be capped at %d events, got %d", maxConnectionEvents, len(diag.Events))
+	}
+
+	unknown := &pb.MinionDiagnostics{}
+	tracker.Fill("unknown", unknown)
+	if unknown.StreamState != StreamStateNeverConnected {
+		t.Errorf("Expected NEVER_CONNECTED for unknown minion, got %s", unknown.StreamState)
+	}
+}
+
+func TestGetMinionDiagnostics(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1"},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
+	}
+	registry.minions["minion-1"].Commands.Push(&pb.Command{Id: "queued"})
+	server.trackCommand("cmd-1", "uptime", []string{"minion-1"})
+	server.diagnostics.RecordStreamOpened("minion-1")
+
+	diag, err := server.GetMinionDiagnostics(context.Background(), &pb.MinionDiagnosticsRequest{MinionId: "minion-1"})
+	if err != nil {
+		t.Fatalf("GetMinionDiagnostics failed: %v", err)
+	}
+	if !diag.Registered || diag.StreamState != StreamStateConnected {
+		t.Errorf("Unexpected connection state: %+v", diag)
+	}
+	if diag.ChannelDepth != 1 || diag.ChannelCapacity != 100 || diag.PendingCommands != 1 {
+		t.Errorf("Unexpected queue state: %+v", diag)
+	}
+
+	if _, err := server.GetMinionDiagnostics(context.Background(), &pb.MinionDiagnosticsRequest{MinionId: "unknown"}); err == nil {
+		t.Error("Expected error for unknown minion")
+	}
+	if _, err := server.GetMinionDiagnostics(context.Background(), &pb.MinionDiagnosticsRequest{}); err == nil {
+		t.Error("Expected error for missing minion ID")
+	}
+}
@@ -0,0 +1,158 @@
+package nexus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// allTargetsRateTag is the rate limit tag matching the commands sent to every minion
+const allTargetsRateTag = "all"
+
+// DispatchRate limits the number of minions per second a command reaches among the minions matching Tag
+type DispatchRate struct {
+	Tag       string `json:"tag"`        // "key" or "key=value" matched against the minion tags, "all" for commands sent to every minion
+	PerSecond int    `json:"per_second"` // minions reached per second
+}
+
+// DispatchRates staggers the dispatch of commands reaching many minions, so that fleet-wide
+// commands don't hit the shared infrastructure (package repositories, registries) all at once
+type DispatchRates struct {
+	rates []DispatchRate
+}
+
+// LoadDispatchRates reads the dispatch rate limits from a JSON file holding a list of DispatchRate
+func LoadDispatchRates(path string) (*DispatchRates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dispatch rates: %w", err)
+	}
+
+	var rates []DispatchRate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse dispatch rates: %w", err)
+	}
+	for _, rate := range rates {
+		if rate.Tag == "" || strings.HasPrefix(rate.Tag, "=") {
+			return nil, fmt.Errorf("dispatch rate: invalid tag %q", rate.Tag)
+		}
+		if rate.PerSecond <= 0 {
+			return nil, fmt.Errorf("dispatch rate %s: per_second must be positive", rate.Tag)
+		}
+	}
+	return &DispatchRates{rates: rates}, nil
+}
+
+// Len returns the number of rate limits
+func (dr *DispatchRates) Len() int {
+	if dr == nil {
+		return 0
+	}
+	return len(dr.rates)
+}
+
+// applies reports whether a rate limits the dispatch of a command to a minion with tags
+func (rate DispatchRate) applies(broadcast bool, tags map[string]string) bool {
+	if rate.Tag == allTargetsRateTag {
+		return broadcast
+	}
+	return matchesTagSpec(rate.Tag, tags)
+}
+
+// matchesTagSpec reports whether tags match spec, "key" requiring the tag and "key=value" its value
+func matchesTagSpec(spec string, tags map[string]string) bool {
+	key, value, hasValue := strings.Cut(spec, "=")
+	current, exists := tags[key]
+	return exists && (!hasValue || current == value)
+}
+
+// schedule returns the delay after which the command of req is dispatched to each target, every rate
+// spacing the minions it applies to by 1/per_second. It returns nil when no rate applies, and for
+// emergency commands which reach all their targets at once.
+func (dr *DispatchRates) schedule(req *pb.CommandRequest, targets []string, tagsOf func(string) map[string]string) []time.Duration {
+	if dr.Len() == 0 || isEmergency(req) {
+		return nil
+	}
+
+	broadcast := len(req.MinionIds) == 0 && len(req.TagSelector.GetRules()) == 0 && req.Topology == nil
+	nextSlot := make([]time.Duration, len(dr.rates))
+	delays := make([]time.Duration, len(targets))
+	limited := false
+	for i, minionID := range targets {
+		tags := tagsOf(minionID)
+		var matching []int
+		for r, rate := range dr.rates {
+			if rate.applies(broadcast, tags) {
+				matching = append(matching, r)
+				if nextSlot[r] > delays[i] {
+					delays[i] = nextSlot[r]
+				}
+			}
+		}
+		for _, r := range matching {
+			nextSlot[r] = delays[i] + time.Second/time.Duration(dr.rates[r].PerSecond)
+		}
+		limited = limited || delays[i] > 0
+	}
+
+	if !limited {
+		return nil
+	}
+	return delays
+}
+
+// SetDispatchRates makes Nexus stagger the dispatch of the commands reaching many minions
+func (s *Server) SetDispatchRates(rates *DispatchRates) {
+	s.dispatchRates = rates
+}
+
+// minionTags returns the tags of a connected minion
+func (s *Server) minionTags(minionID string) map[string]string {
+	if conn, exists := s.minionRegistry.GetConnection(minionID); exists {
+		return conn.GetInfo().GetTags()
+	}
+	return nil
+}
+
+// stagedDispatch is the dispatch of a command to a minion delayed by the rate limits
+type stagedDispatch struct {
+	minionID string
+	delay    time.Duration
+}
+
+// staggerDispatch dispatches a command to the minions delayed by the rate limits, each at its time.
+// The rollout stops when Nexus shuts down.
+func (s *Server) staggerDispatch(req *pb.CommandRequest, emergency bool, staged []stagedDispatch, logger *zap.Logger) {
+	sort.SliceStable(staged, func(i, j int) bool { return staged[i].delay < staged[j].delay })
+	_, stopping := s.drain.channels()
+
+	start := time.Now()
+	for i, target := range staged {
+		select {
+		case <-stopping:
+			logger.Warn("Nexus shutting down, staggered rollout interrupted",
+				zap.String("command_id", req.Command.Id),
+				zap.Int("undispatched", len(staged)-i))
+			return
+		case <-time.After(time.Until(start.Add(target.delay))):
+		}
+
+		if _, err := s.dispatchTarget(req, target.minionID, emergency, logger); err != nil {
+			logger.Warn("Staggered dispatch failed",
+				zap.String("command_id", req.Command.Id),
+				zap.String("minion_id", target.minionID),
+				zap.Error(err))
+		}
+	}
+	logger.Info("Staggered rollout completed",
+		zap.String("command_id", req.Command.Id),
+		zap.Int("minions", len(staged)),
+		zap.Duration("duration", time.Since(start)))
+}
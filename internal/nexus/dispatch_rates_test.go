@@ -0,0 +1,134 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func writeDispatchRates(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write dispatch rates: %v", err)
+	}
+	return path
+}
+
+func TestLoadDispatchRates(t *testing.T) {
+	rates, err := LoadDispatchRates(writeDispatchRates(t, `[{"tag": "all", "per_second": 10}, {"tag": "site=paris", "per_second": 2}]`))
+	if err != nil {
+		t.Fatalf("LoadDispatchRates failed: %v", err)
+	}
+	if rates.Len() != 2 {
+		t.Errorf("Expected 2 rates, got %d", rates.Len())
+	}
+
+	for _, content := range []string{`{"all": 10}`, `[{"tag": "", "per_second": 1}]`, `[{"tag": "=web", "per_second": 1}]`, `[{"tag": "all", "per_second": 0}]`} {
+		if _, err := LoadDispatchRates(writeDispatchRates(t, content)); err == nil {
+			t.Errorf("Expected %s to be rejected", content)
+		}
+	}
+}
+
+func TestDispatchRatesSchedule(t *testing.T) {
+	rates := &DispatchRates{rates: []DispatchRate{{Tag: "all", PerSecond: 10}, {Tag: "site=paris", PerSecond: 2}}}
+	tags := map[string]map[string]string{
+		"m1": {"site": "paris"},
+		"m2": {"site": "paris"},
+		"m3": {"site": "lyon"},
+		"m4": {},
+	}
+	tagsOf := func(id string) map[string]string { return tags[id] }
+	targets := []string{"m1", "m2", "m3", "m4"}
+
+	delays := rates.schedule(&pb.CommandRequest{}, targets, tagsOf)
+	expected := []time.Duration{0, 500 * time.Millisecond, 600 * time.Millisecond, 700 * time.Millisecond}
+	for i, delay := range delays {
+		if delay != expected[i] {
+			t.Errorf("Expected %s dispatched after %s, got %s", targets[i], expected[i], delay)
+		}
+	}
+
+	// The all rate only applies to the commands sent to every minion
+	byTag := &pb.CommandRequest{TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "site", Condition: &pb.TagMatch_Exists{Exists: true}}}}}
+	delays = rates.schedule(byTag, targets, tagsOf)
+	if delays[1] != 500*time.Millisecond || delays[2] != 0 || delays[3] != 0 {
+		t.Errorf("Unexpected delays for a tag selector: %v", delays)
+	}
+
+	if delays := rates.schedule(&pb.CommandRequest{}, []string{"m3"}, tagsOf); delays != nil {
+		t.Errorf("Expected no delay for a single minion, got %v", delays)
+	}
+	// Emergency commands reach all their targets at once
+	for _, req := range []*pb.CommandRequest{{Emergency: true}, {Priority: pb.CommandPriority_EMERGENCY}} {
+		if delays := rates.schedule(req, targets, tagsOf); delays != nil {
+			t.Errorf("Expected no delay for an emergency command, got %v", delays)
+		}
+	}
+	var none *DispatchRates
+	if delays := none.schedule(&pb.CommandRequest{}, targets, tagsOf); delays != nil {
+		t.Errorf("Expected no delay without rates, got %v", delays)
+	}
+}
+
+func TestSendCommandStaggered(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	server.SetDispatchRates(&DispatchRates{rates: []DispatchRate{{Tag: "all", PerSecond: 20}}})
+	registry := server.GetMinionRegistryImpl()
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("minion-%d", i)
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(10),
+		}
+	}
+
+	resp, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "system:info"},
+	})
+	if err != nil || !resp.Accepted {
+		t.Fatalf("SendCommand failed: %v (accepted %v)", err, resp.GetAccepted())
+	}
+	if len(resp.StaggeredMinionIds) != 2 || resp.RolloutSeconds != 1 {
+		t.Fatalf("Expected 2 staggered minions within 1s, got %v within %ds", resp.StaggeredMinionIds, resp.RolloutSeconds)
+	}
+
+	queued := func() int {
+		total := 0
+		for _, conn := range registry.minions {
+			total += conn.Commands.Len()
+		}
+		return total
+	}
+	if n := queued(); n != 1 {
+		t.Errorf("Expected the command queued for 1 minion at once, got %d", n)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for queued() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := queued(); n != 3 {
+		t.Errorf("Expected the staggered rollout to reach the 3 minions, got %d", n)
+	}
+
+	// Emergency commands are not staggered
+	resp, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		Command:  &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "system:info"},
+		Priority: pb.CommandPriority_EMERGENCY,
+	})
+	if err != nil || !resp.Accepted {
+		t.Fatalf("SendCommand failed: %v (accepted %v)", err, resp.GetAccepted())
+	}
+	if len(resp.StaggeredMinionIds) != 0 || queued() != 6 {
+		t.Errorf("Expected the emergency command queued for the 3 minions at once, got %d queued and %v staggered", queued(), resp.StaggeredMinionIds)
+	}
+}
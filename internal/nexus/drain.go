@@ -0,0 +1,142 @@
+package nexus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// reconnectHintDelay is how long minions are asked to wait before reconnecting to a stopping Nexus
+	reconnectHintDelay = 15 * time.Second
+	// drainPollInterval is the interval between two checks of the results still awaited while draining
+	drainPollInterval = 100 * time.Millisecond
+)
+
+// drainState tracks the shutdown of Nexus: draining once it stops accepting registrations and
+// hints minions to reconnect later, stopping once the command streams must close
+type drainState struct {
+	mu       sync.Mutex
+	draining chan struct{}
+	stopping chan struct{}
+	writes   sync.RWMutex // read locked while a result is stored
+	polls    sync.RWMutex // read locked while a long poll is held
+}
+
+// channels returns the draining and stopping channels, closed when the shutdown reaches each stage
+func (d *drainState) channels() (draining, stopping chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining == nil {
+		d.draining = make(chan struct{})
+		d.stopping = make(chan struct{})
+	}
+	return d.draining, d.stopping
+}
+
+// isDraining reports whether Nexus is shutting down
+func (d *drainState) isDraining() bool {
+	draining, _ := d.channels()
+	select {
+	case <-draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// isStopping reports whether the command streams of Nexus must close
+func (d *drainState) isStopping() bool {
+	_, stopping := d.channels()
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// advance closes the channel of a shutdown stage, once
+func (d *drainState) advance(stage chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	select {
+	case <-stage:
+	default:
+		close(stage)
+	}
+}
+
+// Drain prepares Nexus to stop. It rejects new minion registrations, hints connected minions to
+// reconnect later, waits up to grace for the results of the commands they are executing, then closes
+// the command streams and waits for the long polls held and the results being stored. The gRPC and
+// HTTP fallback servers can then stop gracefully.
+func (s *Server) Drain(grace time.Duration) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.Drain")
+	defer logging.FuncExit(logger, start)
+
+	draining, stopping := s.drain.channels()
+	s.drain.advance(draining)
+	logger.Info("Draining Nexus: registrations rejected, minions asked to reconnect later",
+		zap.Duration("grace_period", grace))
+
+	deadline := time.Now().Add(grace)
+	for {
+		awaited := s.awaitedResults()
+		if awaited == 0 {
+			logger.Info("All awaited results received")
+			break
+		}
+		if !time.Now().Before(deadline) {
+			logger.Warn("Grace period over, stopping without the awaited results", zap.Int("awaited_results", awaited))
+			break
+		}
+		time.Sleep(drainPollInterval)
+	}
+
+	s.drain.advance(stopping)
+	s.drain.polls.Lock()
+	s.drain.polls.Unlock()
+	s.drain.writes.Lock()
+	s.drain.writes.Unlock()
+	logger.Info("Nexus drained", zap.Duration("duration", time.Since(start)))
+}
+
+// awaitedResults returns the number of results still awaited from minions with an open command stream
+func (s *Server) awaitedResults() int {
+	registry, ok := s.minionRegistry.(*MinionRegistryImpl)
+	if !ok {
+		return 0
+	}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	awaited := 0
+	for _, tracker := range s.pendingCommands {
+		for minionID := range tracker.Pending {
+			if registry.HasStream(minionID) {
+				awaited++
+			}
+		}
+	}
+	return awaited
+}
+
+// reconnectHint is the message sent to minions when Nexus starts draining
+func reconnectHint() *pb.CommandStreamMessage {
+	return &pb.CommandStreamMessage{
+		Message: &pb.CommandStreamMessage_Reconnect{
+			Reconnect: &pb.ReconnectHint{
+				DelaySeconds: int32(reconnectHintDelay / time.Second),
+				Reason:       "Nexus is shutting down",
+			},
+		},
+	}
+}
@@ -0,0 +1,136 @@
+package nexus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/longpoll"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func createDrainTestServer(t *testing.T) *Server {
+	t.Helper()
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1"},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+	registry.OpenStream("minion-1")
+	return server
+}
+
+func TestDrainRejectsRegistrations(t *testing.T) {
+	server := createDrainTestServer(t)
+	server.Drain(time.Second)
+
+	resp, err := server.Register(context.Background(), &pb.HostInfo{Id: "minion-2", Hostname: "host-2"})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if resp.Success {
+		t.Error("Expected registrations to be rejected while draining")
+	}
+
+	_, stopping := server.drain.channels()
+	select {
+	case <-stopping:
+	default:
+		t.Error("Expected the command streams to be stopping once drained")
+	}
+}
+
+func TestDrainWaitsForAwaitedResults(t *testing.T) {
+	server := createDrainTestServer(t)
+	server.trackCommand("cmd-1", "system:info", []string{"minion-1"})
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		server.completeCommand("cmd-1", "minion-1")
+	}()
+
+	start := time.Now()
+	server.Drain(5 * time.Second)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected Drain to return once the result arrived, took %s", elapsed)
+	}
+}
+
+func TestDrainGracePeriod(t *testing.T) {
+	server := createDrainTestServer(t)
+	server.trackCommand("cmd-1", "system:info", []string{"minion-1"})
+	// Results from minions without a command stream can't arrive and aren't awaited
+	server.trackCommand("cmd-2", "system:info", []string{"minion-gone"})
+
+	if awaited := server.awaitedResults(); awaited != 1 {
+		t.Fatalf("Expected 1 awaited result, got %d", awaited)
+	}
+
+	start := time.Now()
+	server.Drain(200 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected Drain to give up after the grace period, took %s", elapsed)
+	}
+}
+
+func TestReconnectHint(t *testing.T) {
+	hint := reconnectHint().GetReconnect()
+	if hint == nil || hint.DelaySeconds != int32(reconnectHintDelay/time.Second) {
+		t.Errorf("Unexpected reconnect hint: %+v", hint)
+	}
+}
+
+func TestDrainLongPolls(t *testing.T) {
+	server := createDrainTestServer(t)
+	server.diagnostics = NewDiagnosticsTracker()
+	httpServer := httptest.NewServer(server.LongPollHandler(1024 * 1024))
+	defer httpServer.Close()
+
+	poll := func() (int, []*pb.CommandStreamMessage) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, httpServer.URL+longpoll.PollPath+"?wait=10", nil)
+		req.Header.Set(longpoll.MinionIDHeader, "minion-1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Poll failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		body, _ := io.ReadAll(resp.Body)
+		msgs, err := longpoll.UnmarshalMessages(body)
+		if err != nil {
+			t.Fatalf("Invalid poll response %q: %v", body, err)
+		}
+		return resp.StatusCode, msgs
+	}
+
+	// A poll held when Nexus starts draining is answered with the reconnect hint
+	server.trackCommand("cmd-1", "system:info", []string{"minion-1"})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		server.Drain(300 * time.Millisecond)
+	}()
+	if _, msgs := poll(); len(msgs) != 1 || msgs[0].GetReconnect() == nil {
+		t.Fatalf("Expected the reconnect hint, got %v", msgs)
+	}
+
+	// The next poll is held until Nexus stops, which waits for it
+	start := time.Now()
+	if _, msgs := poll(); len(msgs) != 0 {
+		t.Errorf("Expected no message once stopping, got %v", msgs)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the poll to end once Nexus stopped, took %s", elapsed)
+	}
+
+	if code, _ := poll(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 once Nexus stopped, got %d", code)
+	}
+}
@@ -0,0 +1,264 @@
+package nexus
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// encryptedPrefix marks the values encrypted at rest, followed by the key ID and the base64 nonce and
+	// ciphertext. Their additional data binds them to the row and column storing them.
+	encryptedPrefix = "enc:v2:"
+	// legacyEncryptedPrefix marks the values encrypted before they were bound to their row, their
+	// additional data being the key ID alone. They are only decrypted.
+	legacyEncryptedPrefix = "enc:v1:"
+	// encryptionKeySize is the size of the AES-256 keys encrypting stored values
+	encryptionKeySize = 32
+	// undecryptableValue replaces the stored values none of the configured keys decrypt
+	undecryptableValue = "[ENCRYPTED]"
+)
+
+// LoadEncryptionKeys reads the keys encrypting commands and results at rest from a file holding one
+// base64 AES-256 key per line, the first one encrypting and all of them decrypting. Empty lines and
+// lines starting with # are ignored. The file is typically written by the secrets agent of a KMS.
+func LoadEncryptionKeys(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption keys: %w", err)
+	}
+
+	var keys [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key on line %d: not base64: %w", line, err)
+		}
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("encryption key on line %d: must be %d bytes, got %d", line, encryptionKeySize, len(key))
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read encryption keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption key in %s", path)
+	}
+	return keys, nil
+}
+
+// encryptionKey is an AES-256-GCM key and the ID stored with the values it encrypts
+type encryptionKey struct {
+	id   string // first bytes of the SHA-256 of the key, in hex
+	aead cipher.AEAD
+}
+
+// Columns holding encrypted values, bound into their additional data
+const (
+	payloadColumn = "commands.command"
+	stdoutColumn  = "command_results.stdout"
+	stderrColumn  = "command_results.stderr"
+	requestColumn = "command_approvals.request"
+)
+
+// encryptionBinding identifies where an encrypted value is stored: the command and minion of its row
+// and its column. Bound into the additional data, it prevents moving values between rows or columns.
+type encryptionBinding struct {
+	commandID string
+	minionID  string
+	column    string
+}
+
+// additionalData returns the additional data of a value encrypted with the key keyID, each part
+// prefixed with its length so that no two bindings give the same bytes
+func (b encryptionBinding) additionalData(keyID string) []byte {
+	var data []byte
+	for _, part := range []string{keyID, b.commandID, b.minionID, b.column} {
+		data = fmt.Appendf(data, "%d:%s", len(part), part)
+	}
+	return data
+}
+
+// Encryptor encrypts command payloads and results before they are stored, and decrypts them when
+// read back. A nil encryptor stores values in clear.
+type Encryptor struct {
+	keys []encryptionKey // the first key encrypts, older keys only decrypt
+}
+
+// NewEncryptor creates an encryptor from AES-256 keys, the first one encrypting
+func NewEncryptor(keys [][]byte) (*Encryptor, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+
+	e := &Encryptor{}
+	for i, key := range keys {
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("encryption key %d: must be %d bytes, got %d", i+1, encryptionKeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d: %w", i+1, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d: %w", i+1, err)
+		}
+		sum := sha256.Sum256(key)
+		e.keys = append(e.keys, encryptionKey{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+	return e, nil
+}
+
+// Encrypt returns text encrypted with the first key for the row and column of binding, empty text
+// being kept empty
+func (e *Encryptor) Encrypt(text string, binding encryptionBinding) (string, error) {
+	if e == nil || text == "" {
+		return text, nil
+	}
+
+	key := e.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate encryption nonce: %w", err)
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(text), binding.additionalData(key.id))
+	return encryptedPrefix + key.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the clear text of a value stored in the row and column of binding. Values stored in
+// clear, before encryption was enabled, are returned unchanged.
+func (e *Encryptor) Decrypt(value string, binding encryptionBinding) (string, error) {
+	encrypted, bound := strings.CutPrefix(value, encryptedPrefix)
+	if !bound {
+		var legacy bool
+		if encrypted, legacy = strings.CutPrefix(value, legacyEncryptedPrefix); !legacy {
+			return value, nil
+		}
+	}
+	if e == nil {
+		return "", fmt.Errorf("value is encrypted but no encryption key is configured")
+	}
+
+	id, encoded, found := strings.Cut(encrypted, ":")
+	if !found {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	for _, key := range e.keys {
+		if key.id != id {
+			continue
+		}
+		if len(sealed) < key.aead.NonceSize() {
+			return "", fmt.Errorf("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+		additionalData := []byte(id)
+		if bound {
+			additionalData = binding.additionalData(id)
+		}
+		text, err := key.aead.Open(nil, nonce, ciphertext, additionalData)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt value with key %s: %w", id, err)
+		}
+		return string(text), nil
+	}
+	return "", fmt.Errorf("value encrypted with unknown key %s", id)
+}
+
+// EncryptResult returns a copy of result whose output is encrypted
+func (e *Encryptor) EncryptResult(result *pb.CommandResult) (*pb.CommandResult, error) {
+	if e == nil {
+		return result, nil
+	}
+
+	stdout, err := e.Encrypt(result.Stdout, encryptionBinding{result.CommandId, result.MinionId, stdoutColumn})
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := e.Encrypt(result.Stderr, encryptionBinding{result.CommandId, result.MinionId, stderrColumn})
+	if err != nil {
+		return nil, err
+	}
+	encrypted := proto.Clone(result).(*pb.CommandResult)
+	encrypted.Stdout = stdout
+	encrypted.Stderr = stderr
+	return encrypted, nil
+}
+
+// DecryptResult decrypts the output of a stored result in place. Output none of the keys decrypt is
+// replaced with [ENCRYPTED], and the first error is returned.
+func (e *Encryptor) DecryptResult(result *pb.CommandResult) error {
+	var firstErr error
+	for _, output := range []struct {
+		field  *string
+		column string
+	}{{&result.Stdout, stdoutColumn}, {&result.Stderr, stderrColumn}} {
+		field := output.field
+		text, err := e.Decrypt(*field, encryptionBinding{result.CommandId, result.MinionId, output.column})
+		if err != nil {
+			text = undecryptableValue
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		*field = text
+	}
+	return firstErr
+}
+
+// decryptPayload decrypts the payload stored for a command and minion, replacing a payload none of the
+// keys decrypt with [ENCRYPTED]
+func (d *DatabaseServiceImpl) decryptPayload(value, commandID, minionID string) string {
+	text, err := d.encryptor.Decrypt(value, encryptionBinding{commandID, minionID, payloadColumn})
+	if err != nil {
+		d.logger.Warn("Failed to decrypt command payload",
+			zap.String("command_id", commandID),
+			zap.String("minion_id", minionID),
+			zap.Error(err))
+		return undecryptableValue
+	}
+	return text
+}
+
+// encryptsPayloads reports whether command payloads are stored encrypted, and must be decrypted to be
+// matched or grouped
+func (s *Server) encryptsPayloads() bool {
+	impl, ok := s.dbService.(*DatabaseServiceImpl)
+	return ok && impl.encryptor != nil
+}
+
+// SetEncryptionKeys encrypts the command payloads and result output stored from now on with the
+// first of keys, the others decrypting values stored with former keys
+func (s *Server) SetEncryptionKeys(keys [][]byte) error {
+	encryptor, err := NewEncryptor(keys)
+	if err != nil {
+		return err
+	}
+	if impl, ok := s.dbService.(*DatabaseServiceImpl); ok {
+		impl.encryptor = encryptor
+	}
+	return nil
+}
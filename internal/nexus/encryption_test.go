@@ -0,0 +1,241 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// encryptedArg matches the stored values encrypting text
+type encryptedArg struct {
+	encryptor *Encryptor
+	binding   encryptionBinding
+	text      string
+}
+
+func (a encryptedArg) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	if !ok || !strings.HasPrefix(value, encryptedPrefix) {
+		return false
+	}
+	text, err := a.encryptor.Decrypt(value, a.binding)
+	return err == nil && text == a.text
+}
+
+func TestEncryptor(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	old, err := NewEncryptor([][]byte{oldKey})
+	if err != nil {
+		t.Fatalf("NewEncryptor failed: %v", err)
+	}
+	binding := encryptionBinding{"cmd-1", "minion-1", payloadColumn}
+	stored, err := old.Encrypt("cat /etc/shadow", binding)
+	if err != nil || !strings.HasPrefix(stored, encryptedPrefix) || strings.Contains(stored, "shadow") {
+		t.Fatalf("Expected the text encrypted, got %q, %v", stored, err)
+	}
+	if again, _ := old.Encrypt("cat /etc/shadow", binding); again == stored {
+		t.Error("Expected a fresh nonce for each value")
+	}
+	if empty, _ := old.Encrypt("", binding); empty != "" {
+		t.Errorf("Expected empty text kept empty, got %q", empty)
+	}
+
+	// Values can't be moved to another row or column
+	for _, moved := range []encryptionBinding{
+		{"cmd-2", "minion-1", payloadColumn},
+		{"cmd-1", "minion-2", payloadColumn},
+		{"cmd-1", "minion-1", stdoutColumn},
+	} {
+		if _, err := old.Decrypt(stored, moved); err == nil {
+			t.Errorf("Expected a value moved to %v rejected", moved)
+		}
+	}
+
+	// Values stored before they were bound to their row are still decrypted
+	key := old.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	legacy := legacyEncryptedPrefix + key.id + ":" + base64.StdEncoding.EncodeToString(key.aead.Seal(nonce, nonce, []byte("uptime"), []byte(key.id)))
+	if text, err := old.Decrypt(legacy, binding); err != nil || text != "uptime" {
+		t.Errorf("Expected a legacy value decrypted, got %q, %v", text, err)
+	}
+
+	// A rotated key still decrypts the values stored with the former one
+	rotated, err := NewEncryptor([][]byte{newKey, oldKey})
+	if err != nil {
+		t.Fatalf("NewEncryptor failed: %v", err)
+	}
+	if text, err := rotated.Decrypt(stored, binding); err != nil || text != "cat /etc/shadow" {
+		t.Errorf("Expected the value decrypted with the former key, got %q, %v", text, err)
+	}
+	if text, err := rotated.Decrypt("uptime", binding); err != nil || text != "uptime" {
+		t.Errorf("Expected a value stored in clear returned unchanged, got %q, %v", text, err)
+	}
+
+	other, _ := NewEncryptor([][]byte{newKey})
+	if _, err := other.Decrypt(stored, binding); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+	tampered := stored[:len(stored)-4] + "AAA="
+	if _, err := old.Decrypt(tampered, binding); err == nil {
+		t.Error("Expected a tampered value rejected")
+	}
+	var none *Encryptor
+	if _, err := none.Decrypt(stored, binding); err == nil {
+		t.Error("Expected an encrypted value rejected without keys")
+	}
+
+	output, _ := old.Encrypt("root:x:0:0", encryptionBinding{"cmd-1", "minion-1", stdoutColumn})
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: output, Stderr: "plain"}
+	if err := other.DecryptResult(result); err == nil || result.Stdout != undecryptableValue || result.Stderr != "plain" {
+		t.Errorf("Expected undecryptable output masked, got %v, %v", result, err)
+	}
+	if _, err := NewEncryptor([][]byte{[]byte("short")}); err == nil {
+		t.Error("Expected a short key rejected")
+	}
+}
+
+func TestLoadEncryptionKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	if err := os.WriteFile(path, []byte("# current key first\n"+key+"\n\n"+key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadEncryptionKeys(path)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d, %v", len(keys), err)
+	}
+
+	for _, content := range []string{"", "# no key\n", "not base64!\n", base64.StdEncoding.EncodeToString([]byte("short")) + "\n"} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadEncryptionKeys(path); err == nil {
+			t.Errorf("Expected keys file %q rejected", content)
+		}
+	}
+}
+
+func TestStoreEncryptedCommandResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	service := server.dbService.(*DatabaseServiceImpl)
+	if err := server.SetEncryptionKeys([][]byte{bytes.Repeat([]byte{3}, 32)}); err != nil {
+		t.Fatalf("SetEncryptionKeys failed: %v", err)
+	}
+	encryptor := service.encryptor
+
+	// Payloads are redacted, then encrypted
+	mock.ExpectExec("INSERT INTO commands").
+		WithArgs("cmd-1", "minion-1", encryptedArg{encryptor, encryptionBinding{"cmd-1", "minion-1", payloadColumn}, "psql postgres://app:[REDACTED]@db/app"}, sqlmock.AnyArg(), "SENT", "PENDING", true, nil, nil, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := service.StoreCommand(context.Background(), CommandRecord{CommandID: "cmd-1", MinionID: "minion-1", Payload: "psql postgres://app:pw@db/app"}); err != nil {
+		t.Fatalf("StoreCommand failed: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WithArgs("cmd-1", "minion-1", int32(0), encryptedArg{encryptor, encryptionBinding{"cmd-1", "minion-1", stdoutColumn}, "root:x:0:0"}, "", nil, nil, sqlmock.AnyArg(), false, nil, int32(1), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE commands SET status").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: "root:x:0:0", Timestamp: time.Now().Unix()}
+	if err := service.StoreCommandResult(context.Background(), result); err != nil {
+		t.Fatalf("StoreCommandResult failed: %v", err)
+	}
+	if result.Stdout != "root:x:0:0" {
+		t.Errorf("Expected the result received left in clear, got %q", result.Stdout)
+	}
+
+	// Reads decrypt the output, results stored before encryption being returned unchanged
+	stored, _ := encryptor.Encrypt("root:x:0:0", encryptionBinding{"cmd-1", "minion-1", stdoutColumn})
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands WHERE id = \\$1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr").
+		WillReturnRows(sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "content_type", "structured", "timestamp", "attempt", "execution"}).
+			AddRow("cmd-1", "minion-1", 0, stored, "", "", "", time.Now().Unix(), 1, "").
+			AddRow("cmd-1", "minion-2", 0, "legacy output", "", "", "", time.Now().Unix(), 1, ""))
+	results, err := service.GetCommandResults(context.Background(), "cmd-1")
+	if err != nil {
+		t.Fatalf("GetCommandResults failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Stdout != "root:x:0:0" || results[1].Stdout != "legacy output" {
+		t.Errorf("Expected the results decrypted, got %v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestEncryptedCommandReads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	service := server.dbService.(*DatabaseServiceImpl)
+	if err := server.SetEncryptionKeys([][]byte{bytes.Repeat([]byte{6}, 32)}); err != nil {
+		t.Fatalf("SetEncryptionKeys failed: %v", err)
+	}
+	uptime, _ := service.encryptor.Encrypt("uptime", encryptionBinding{"cmd-1", "minion-1", payloadColumn})
+	other, _ := NewEncryptor([][]byte{bytes.Repeat([]byte{7}, 32)})
+	unreadable, _ := other.Encrypt("whoami", encryptionBinding{"cmd-2", "minion-1", payloadColumn})
+	ctx := context.Background()
+
+	// History entries show the decrypted payloads, [ENCRYPTED] when no key decrypts them
+	mock.ExpectQuery("SELECT c.id, c.command").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "command", "status", "timestamp", "exit_code", "duration"}).
+			AddRow("cmd-2", unreadable, "PENDING", int64(1700000100), nil, nil).
+			AddRow("cmd-1", uptime, "COMPLETED", int64(1700000000), int32(0), int64(20)))
+	history, err := service.GetMinionHistory(ctx, "minion-1", 10)
+	if err != nil {
+		t.Fatalf("GetMinionHistory failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Command != undecryptableValue || history[1].Command != "uptime" {
+		t.Errorf("Expected the history payloads decrypted, got %v", history)
+	}
+
+	// So do the dispatch events of traces
+	mock.ExpectQuery("SELECT event, command_id, minion_id, detail").
+		WillReturnRows(sqlmock.NewRows([]string{"event", "command_id", "minion_id", "detail", "ts"}).
+			AddRow("DISPATCHED", "cmd-1", "minion-1", uptime, int64(1700000000000)).
+			AddRow("RESULT", "cmd-1", "minion-1", "exit code 0", int64(1700000000480)))
+	events, err := service.GetTrace(ctx, "trace-1")
+	if err != nil {
+		t.Fatalf("GetTrace failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Detail != "uptime" || events[1].Detail != "exit code 0" {
+		t.Errorf("Expected the trace payloads decrypted, got %v", events)
+	}
+
+	// And the rows of reports by command
+	mock.ExpectQuery("SELECT '' AS dimension, c.id, c.host_id, c.command").
+		WillReturnRows(sqlmock.NewRows([]string{"dimension", "id", "host_id", "command", "count", "failures"}).
+			AddRow("", "cmd-1", "minion-1", uptime, int64(3), int64(1)))
+	rows, err := service.QueryReport(ctx, "SELECT '' AS dimension, c.id, c.host_id, c.command, COUNT(*), 0 FROM commands c GROUP BY 1, 2, 3, 4", nil)
+	if err != nil {
+		t.Fatalf("QueryReport failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Command != "uptime" || rows[0].Count != 3 {
+		t.Errorf("Expected the report payloads decrypted, got %v", rows)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
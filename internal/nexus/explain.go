@@ -0,0 +1,148 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// ExplainTargets resolves a target in the ConsoleService as SendCommand would, explaining for each
+// minion the console can target why it satisfies each rule of the target or not. Nothing is sent.
+func (s *Server) ExplainTargets(ctx context.Context, req *pb.ExplainTargetsRequest) (*pb.TargetExplanations, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ExplainTargets")
+	defer logging.FuncExit(logger, start)
+
+	scope := consoleScope(ctx)
+	var minions []*pb.HostInfo
+	for _, info := range s.GetMinionRegistryImpl().ListMinions() {
+		if scope.allows(info) {
+			minions = append(minions, info)
+		}
+	}
+
+	result := &pb.TargetExplanations{}
+	known := make(map[string]bool, len(minions))
+	for _, info := range minions {
+		known[info.Id] = true
+		explanation := explainTarget(info, req)
+		if explanation.Matched {
+			result.Matched++
+		}
+		result.Minions = append(result.Minions, explanation)
+	}
+	for _, id := range req.MinionIds {
+		if !known[id] {
+			result.UnknownMinionIds = append(result.UnknownMinionIds, id)
+		}
+	}
+
+	sort.Slice(result.Minions, func(i, j int) bool {
+		a, b := result.Minions[i], result.Minions[j]
+		if a.Matched != b.Matched {
+			return a.Matched
+		}
+		return a.MinionId < b.MinionId
+	})
+
+	logger.Debug("Targets explained",
+		zap.Strings("minion_ids", req.MinionIds),
+		zap.Int32("matched", result.Matched),
+		zap.Int("minions", len(result.Minions)))
+	return result, nil
+}
+
+// explainTarget checks a minion against each rule of a target. Minion IDs take precedence over the
+// tag selector, and the topology restricts the minions matching either.
+func explainTarget(info *pb.HostInfo, req *pb.ExplainTargetsRequest) *pb.TargetExplanation {
+	explanation := &pb.TargetExplanation{MinionId: info.Id, Hostname: info.Hostname}
+
+	if len(req.MinionIds) > 0 {
+		rule := &pb.RuleExplanation{Rule: "id in " + strings.Join(req.MinionIds, ","), Reason: "not listed"}
+		for _, id := range req.MinionIds {
+			if id == info.Id {
+				rule.Matched, rule.Reason = true, "listed"
+			}
+		}
+		explanation.Rules = append(explanation.Rules, rule)
+	} else {
+		for _, match := range req.GetTagSelector().GetRules() {
+			explanation.Rules = append(explanation.Rules, explainTagRule(info, match))
+		}
+	}
+	explanation.Rules = append(explanation.Rules, explainTopology(info, req.Topology)...)
+
+	explanation.Matched = true
+	for _, rule := range explanation.Rules {
+		explanation.Matched = explanation.Matched && rule.Matched
+	}
+	return explanation
+}
+
+// explainTagRule checks a minion against a rule of a tag selector
+func explainTagRule(info *pb.HostInfo, match *pb.TagMatch) *pb.RuleExplanation {
+	value, exists := info.Tags[match.Key]
+	current := fmt.Sprintf("tag %s is not set", match.Key)
+	if exists {
+		current = fmt.Sprintf("%s is %s", match.Key, value)
+	}
+
+	switch condition := match.Condition.(type) {
+	case *pb.TagMatch_Equals:
+		return &pb.RuleExplanation{
+			Rule:    match.Key + "=" + condition.Equals,
+			Matched: exists && value == condition.Equals,
+			Reason:  current,
+		}
+	case *pb.TagMatch_Exists:
+		return &pb.RuleExplanation{
+			Rule:    match.Key,
+			Matched: exists || !condition.Exists,
+			Reason:  current,
+		}
+	case *pb.TagMatch_NotExists:
+		return &pb.RuleExplanation{
+			Rule:    "!" + match.Key,
+			Matched: !exists || !condition.NotExists,
+			Reason:  current,
+		}
+	}
+	return &pb.RuleExplanation{Rule: match.Key, Matched: true, Reason: "rule without condition"}
+}
+
+// explainTopology checks a minion against each failure domain set in selector
+func explainTopology(info *pb.HostInfo, selector *pb.TopologySelector) []*pb.RuleExplanation {
+	if selector == nil {
+		return nil
+	}
+	topo := minionTopology(info)
+	domains := []struct {
+		name, wanted, actual string
+	}{
+		{"region", selector.Region, topo.region},
+		{"dc", selector.Datacenter, topo.datacenter},
+		{"rack", selector.Rack, topo.rack},
+	}
+
+	var rules []*pb.RuleExplanation
+	for _, domain := range domains {
+		if domain.wanted == "" {
+			continue
+		}
+		reason := fmt.Sprintf("%s is %s", domain.name, domain.actual)
+		if domain.actual == "" {
+			reason = fmt.Sprintf("%s is unknown", domain.name)
+		}
+		rules = append(rules, &pb.RuleExplanation{
+			Rule:    domain.name + "=" + domain.wanted,
+			Matched: domain.wanted == domain.actual,
+			Reason:  reason,
+		})
+	}
+	return rules
+}
@@ -0,0 +1,94 @@
+package nexus
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestExplainTargets(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	minions := []*pb.HostInfo{
+		{Id: "web-1", Hostname: "web-1", Tags: map[string]string{"env": "prod", "role": "web", "region": "eu-west-1"}},
+		{Id: "web-2", Hostname: "web-2", Tags: map[string]string{"env": "prod", "role": "web", "maintenance": "yes", "region": "us-east-1"}},
+		{Id: "db-1", Hostname: "db-1", Tags: map[string]string{"env": "staging", "role": "db"}},
+		{Id: "other", Hostname: "other", Namespace: "team-b", Tags: map[string]string{"env": "prod"}},
+	}
+	for _, info := range minions {
+		if _, err := registry.Register(info); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	req := &pb.ExplainTargetsRequest{
+		TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{
+			{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}},
+			{Key: "role", Condition: &pb.TagMatch_Exists{Exists: true}},
+			{Key: "maintenance", Condition: &pb.TagMatch_NotExists{NotExists: true}},
+		}},
+		Topology: &pb.TopologySelector{Region: "eu-west-1"},
+	}
+	result, err := server.ExplainTargets(namespaceContext(DefaultNamespace), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Matched != 1 || len(result.Minions) != 3 {
+		t.Fatalf("Expected 1 of the 3 minions in scope to match, got %v", result)
+	}
+	if first := result.Minions[0]; first.MinionId != "web-1" || !first.Matched || len(first.Rules) != 4 {
+		t.Fatalf("Expected the matched minion first with every rule explained, got %v", first)
+	}
+
+	explanations := map[string]*pb.TargetExplanation{}
+	for _, explanation := range result.Minions {
+		explanations[explanation.MinionId] = explanation
+	}
+	web2 := explanations["web-2"].Rules
+	if web2[2].Rule != "!maintenance" || web2[2].Matched || web2[2].Reason != "maintenance is yes" {
+		t.Errorf("Unexpected maintenance rule explanation: %v", web2[2])
+	}
+	if web2[3].Rule != "region=eu-west-1" || web2[3].Matched || web2[3].Reason != "region is us-east-1" {
+		t.Errorf("Unexpected region rule explanation: %v", web2[3])
+	}
+	db1 := explanations["db-1"].Rules
+	if db1[0].Matched || db1[0].Reason != "env is staging" || !db1[1].Matched || db1[3].Reason != "region is unknown" {
+		t.Errorf("Unexpected db-1 explanations: %v", db1)
+	}
+
+	// The explanation agrees with the targets of SendCommand
+	targets := server.FindTargetMinions(&pb.CommandRequest{TagSelector: req.TagSelector, Topology: req.Topology})
+	if len(targets) != 1 || targets[0] != "web-1" {
+		t.Errorf("Expected the same targets as SendCommand, got %v", targets)
+	}
+
+	// Minion IDs take precedence over the tag selector, unknown ones being reported
+	result, err = server.ExplainTargets(context.Background(), &pb.ExplainTargetsRequest{
+		MinionIds:   []string{"db-1", "missing"},
+		TagSelector: req.TagSelector,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Matched != 1 || len(result.Minions) != 4 || result.Minions[0].MinionId != "db-1" || len(result.Minions[0].Rules) != 1 {
+		t.Fatalf("Expected db-1 matched by ID, got %v", result)
+	}
+	if len(result.UnknownMinionIds) != 1 || result.UnknownMinionIds[0] != "missing" {
+		t.Errorf("Expected the unknown minion reported, got %v", result.UnknownMinionIds)
+	}
+
+	// Without rules every minion matches
+	result, err = server.ExplainTargets(context.Background(), &pb.ExplainTargetsRequest{})
+	if err != nil || result.Matched != 4 {
+		t.Fatalf("Expected every minion matched, got %v, %v", result, err)
+	}
+	ids := make([]string, 0, len(result.Minions))
+	for _, explanation := range result.Minions {
+		ids = append(ids, explanation.MinionId)
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("Expected the minions sorted by ID, got %v", ids)
+	}
+}
@@ -0,0 +1,510 @@
+package nexus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// File transfer states
+const (
+	TransferRunning     = "RUNNING"
+	TransferInterrupted = "INTERRUPTED"
+	TransferCompleted   = "COMPLETED"
+	TransferFailed      = "FAILED"
+)
+
+const (
+	// fileTransferTTL is how long a transfer and its file are kept on Nexus after their last update
+	fileTransferTTL = 24 * time.Hour
+
+	// fileDownloadChunkSize is the size of the chunks streamed to downloading consoles
+	fileDownloadChunkSize = 512 * 1024
+
+	// fileTransferProgressInterval is the minimum interval between the progress updates of PullFile
+	fileTransferProgressInterval = time.Second
+)
+
+// errTransfersDisabled is returned by the transfer RPCs when Nexus has no transfer directory
+var errTransfersDisabled = status.Error(codes.Unavailable, "file transfers are disabled on this Nexus")
+
+// fileTransfer is a file being pulled from a minion and reassembled on Nexus
+type fileTransfer struct {
+	id        string
+	minionID  string
+	path      string // path of the file on the minion
+	file      string // path of the reassembled file on Nexus
+	state     string
+	received  int64
+	hash      hash.Hash // checksum of the bytes received
+	size      int64
+	sha256    string
+	err       string
+	startedAt time.Time
+	updatedAt time.Time
+	changed   chan struct{} // signaled on every update
+}
+
+// status returns the status of the transfer. The caller must hold the fileTransfers lock.
+func (t *fileTransfer) status() *pb.FileTransferStatus {
+	return &pb.FileTransferStatus{
+		TransferId: t.id,
+		MinionId:   t.minionID,
+		Path:       t.path,
+		State:      t.state,
+		Received:   t.received,
+		Size:       t.size,
+		Sha256:     t.sha256,
+		Error:      t.err,
+		StartedAt:  t.startedAt.Unix(),
+		UpdatedAt:  t.updatedAt.Unix(),
+	}
+}
+
+// update sets the state of the transfer and signals its followers. The caller must hold the
+// fileTransfers lock.
+func (t *fileTransfer) update(state, err string) {
+	t.state = state
+	t.err = err
+	t.updatedAt = time.Now()
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+// fileTransfers pulls files from minions in chunks and reassembles them in a directory.
+// Its zero value is ready to use, transfers being disabled until a directory is set.
+type fileTransfers struct {
+	mu        sync.Mutex
+	dir       string
+	outbound  map[string]chan *pb.FileChunk // requests waiting to be sent on each minion command stream
+	transfers map[string]*fileTransfer
+	logger    *zap.Logger
+}
+
+// outboundFor returns the channel of the requests to send on the command stream of a minion
+func (f *fileTransfers) outboundFor(minionID string) chan *pb.FileChunk {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.outbound == nil {
+		f.outbound = make(map[string]chan *pb.FileChunk)
+	}
+	ch, exists := f.outbound[minionID]
+	if !exists {
+		ch = make(chan *pb.FileChunk, shellBufferSize)
+		f.outbound[minionID] = ch
+	}
+	return ch
+}
+
+// sendToMinion queues a request for the command stream of a minion
+func (f *fileTransfers) sendToMinion(minionID string, msg *pb.FileChunk) error {
+	select {
+	case f.outboundFor(minionID) <- msg:
+		return nil
+	case <-time.After(shellRelayTimeout):
+		return errShellStalled
+	}
+}
+
+// EnableFileTransfers lets consoles pull files from minions, reassembled in dir. Files left in
+// dir by a previous run, whose transfers are lost, are removed.
+func (s *Server) EnableFileTransfers(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create file transfer directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read file transfer directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	s.transfers.mu.Lock()
+	defer s.transfers.mu.Unlock()
+	s.transfers.dir = dir
+	s.transfers.logger = s.logger
+	return nil
+}
+
+// start registers a new transfer of a file of a minion
+func (f *fileTransfers) start(minionID, path string) (*fileTransfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.dir == "" {
+		return nil, errTransfersDisabled
+	}
+	f.pruneLocked()
+
+	id := generateMinionID()
+	file := filepath.Join(f.dir, id)
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create transfer file: %v", err)
+	}
+	out.Close()
+
+	now := time.Now()
+	transfer := &fileTransfer{
+		id:        id,
+		minionID:  minionID,
+		path:      path,
+		file:      file,
+		state:     TransferRunning,
+		hash:      sha256.New(),
+		startedAt: now,
+		updatedAt: now,
+		changed:   make(chan struct{}, 1),
+	}
+	if f.transfers == nil {
+		f.transfers = make(map[string]*fileTransfer)
+	}
+	f.transfers[id] = transfer
+	return transfer, nil
+}
+
+// resume restarts an interrupted or failed transfer from the bytes received
+func (f *fileTransfers) resume(transfer *fileTransfer) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch transfer.state {
+	case TransferRunning:
+		return 0, status.Errorf(codes.FailedPrecondition, "transfer %s is running", transfer.id)
+	case TransferCompleted:
+		return 0, status.Errorf(codes.FailedPrecondition, "transfer %s is completed", transfer.id)
+	}
+	transfer.update(TransferRunning, "")
+	return transfer.received, nil
+}
+
+// get returns a transfer
+func (f *fileTransfers) get(transferID string) (*fileTransfer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transfer, exists := f.transfers[transferID]
+	return transfer, exists
+}
+
+// snapshot returns the status of a transfer
+func (f *fileTransfers) snapshot(transfer *fileTransfer) *pb.FileTransferStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return transfer.status()
+}
+
+// list returns the status of the transfers, most recent first
+func (f *fileTransfers) list() []*pb.FileTransferStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pruneLocked()
+	statuses := make([]*pb.FileTransferStatus, 0, len(f.transfers))
+	for _, transfer := range f.transfers {
+		statuses = append(statuses, transfer.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].StartedAt != statuses[j].StartedAt {
+			return statuses[i].StartedAt > statuses[j].StartedAt
+		}
+		return statuses[i].TransferId < statuses[j].TransferId
+	})
+	return statuses
+}
+
+// pruneLocked forgets the transfers not updated for fileTransferTTL, removing their file.
+// The caller must hold f.mu.
+func (f *fileTransfers) pruneLocked() {
+	for id, transfer := range f.transfers {
+		if transfer.state != TransferRunning && time.Since(transfer.updatedAt) > fileTransferTTL {
+			os.Remove(transfer.file)
+			delete(f.transfers, id)
+		}
+	}
+}
+
+// deliver reassembles a chunk received from a minion. Chunks of unknown or stopped transfers
+// are dropped, the minion being asked to stop sending them.
+func (f *fileTransfers) deliver(minionID string, chunk *pb.FileChunk) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	transfer, exists := f.transfers[chunk.TransferId]
+	if !exists || transfer.minionID != minionID || transfer.state != TransferRunning {
+		go f.sendToMinion(minionID, &pb.FileChunk{TransferId: chunk.TransferId, Cancel: true})
+		return
+	}
+
+	if chunk.Error != "" {
+		transfer.update(TransferFailed, chunk.Error)
+		f.logTransfer("File transfer failed on the minion", transfer)
+		return
+	}
+	if chunk.Size > 0 {
+		transfer.size = chunk.Size
+	}
+
+	// Chunks come in order on a stream, a chunk already received being a duplicate
+	if chunk.Offset > transfer.received {
+		transfer.update(TransferInterrupted, fmt.Sprintf("missing bytes from offset %d", transfer.received))
+		go f.sendToMinion(minionID, &pb.FileChunk{TransferId: transfer.id, Cancel: true})
+		f.logTransfer("File transfer interrupted", transfer)
+		return
+	}
+	if data := chunk.Data; chunk.Offset+int64(len(data)) > transfer.received {
+		data = data[transfer.received-chunk.Offset:]
+		if err := writeAt(transfer.file, transfer.received, data); err != nil {
+			transfer.update(TransferFailed, err.Error())
+			go f.sendToMinion(minionID, &pb.FileChunk{TransferId: transfer.id, Cancel: true})
+			f.logTransfer("Failed to write transferred file", transfer)
+			return
+		}
+		transfer.hash.Write(data)
+		transfer.received += int64(len(data))
+	}
+
+	if !chunk.Eof {
+		transfer.update(TransferRunning, "")
+		return
+	}
+	f.complete(transfer, chunk.Sha256)
+}
+
+// complete checks the reassembled file of a transfer against the checksum computed by the
+// minion. A mismatching file is discarded, resuming the transfer starting over.
+// The caller must hold f.mu.
+func (f *fileTransfers) complete(transfer *fileTransfer, expected string) {
+	checksum := hex.EncodeToString(transfer.hash.Sum(nil))
+	switch {
+	case transfer.received != transfer.size:
+		transfer.update(TransferFailed, fmt.Sprintf("received %d bytes of %d", transfer.received, transfer.size))
+	case checksum != expected:
+		os.Truncate(transfer.file, 0)
+		transfer.received = 0
+		transfer.hash.Reset()
+		transfer.update(TransferFailed, "checksum mismatch, the file changed during the transfer or was corrupted")
+	default:
+		transfer.sha256 = checksum
+		transfer.update(TransferCompleted, "")
+	}
+	f.logTransfer("File transfer ended", transfer)
+}
+
+// closeMinion interrupts the running transfers of a minion whose command stream ended
+func (f *fileTransfers) closeMinion(minionID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, transfer := range f.transfers {
+		if transfer.minionID == minionID && transfer.state == TransferRunning {
+			transfer.update(TransferInterrupted, "minion disconnected")
+			f.logTransfer("File transfer interrupted", transfer)
+		}
+	}
+}
+
+// logTransfer logs the state of a transfer. The caller must hold f.mu.
+func (f *fileTransfers) logTransfer(msg string, transfer *fileTransfer) {
+	if f.logger == nil {
+		return
+	}
+	f.logger.Info(msg,
+		zap.String("transfer_id", transfer.id),
+		zap.String("minion_id", transfer.minionID),
+		zap.String("path", transfer.path),
+		zap.String("state", transfer.state),
+		zap.Int64("received", transfer.received),
+		zap.Int64("size", transfer.size),
+		zap.String("error", transfer.err))
+}
+
+// writeAt writes data at offset of a file
+func writeAt(path string, offset int64, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open transfer file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteAt(data, offset); err != nil {
+		return fmt.Errorf("failed to write transfer file: %w", err)
+	}
+	return nil
+}
+
+// PullFile starts pulling a file from a minion to Nexus, or resumes an interrupted transfer from
+// the bytes received, in the ConsoleService. The progress of the transfer is streamed until it
+// ends; a console leaving doesn't stop the transfer.
+func (s *Server) PullFile(req *pb.FilePullRequest, stream pb.ConsoleService_PullFileServer) error {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.PullFile")
+	defer logging.FuncExit(logger, start)
+
+	var transfer *fileTransfer
+	var offset int64
+	if req.TransferId != "" {
+		var exists bool
+		if transfer, exists = s.transfers.get(req.TransferId); !exists {
+			return status.Errorf(codes.NotFound, "transfer %s not found", req.TransferId)
+		}
+		if err := s.checkMinionScope(stream.Context(), transfer.minionID); err != nil {
+			return err
+		}
+		if !s.GetMinionRegistryImpl().HasStream(transfer.minionID) {
+			return status.Errorf(codes.Unavailable, "minion %s is not connected", transfer.minionID)
+		}
+		var err error
+		if offset, err = s.transfers.resume(transfer); err != nil {
+			return err
+		}
+	} else {
+		if req.MinionId == "" || req.Path == "" {
+			return status.Error(codes.InvalidArgument, "minion ID and path are required")
+		}
+		if err := s.checkMinionScope(stream.Context(), req.MinionId); err != nil {
+			return err
+		}
+		if !s.GetMinionRegistryImpl().HasStream(req.MinionId) {
+			return status.Errorf(codes.Unavailable, "minion %s is not connected", req.MinionId)
+		}
+		var err error
+		if transfer, err = s.transfers.start(req.MinionId, req.Path); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("File transfer started",
+		zap.String("transfer_id", transfer.id),
+		zap.String("minion_id", transfer.minionID),
+		zap.String("path", transfer.path),
+		zap.Int64("offset", offset))
+
+	if err := s.transfers.sendToMinion(transfer.minionID, &pb.FileChunk{
+		TransferId: transfer.id,
+		Path:       transfer.path,
+		Offset:     offset,
+		Start:      true,
+	}); err != nil {
+		s.transfers.mu.Lock()
+		transfer.update(TransferInterrupted, err.Error())
+		s.transfers.mu.Unlock()
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	var sent time.Time
+	for {
+		current := s.transfers.snapshot(transfer)
+		if current.State != TransferRunning || time.Since(sent) >= fileTransferProgressInterval {
+			if err := stream.Send(current); err != nil {
+				return err
+			}
+			sent = time.Now()
+		}
+		if current.State != TransferRunning {
+			return nil
+		}
+
+		select {
+		case <-transfer.changed:
+		case <-time.After(fileTransferProgressInterval):
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// ListFileTransfers returns the transfers of the minions the console can reach in the ConsoleService
+func (s *Server) ListFileTransfers(ctx context.Context, req *pb.Empty) (*pb.FileTransferList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListFileTransfers")
+	defer logging.FuncExit(logger, start)
+
+	restricted := consoleScope(ctx).restricted()
+	list := &pb.FileTransferList{}
+	for _, transfer := range s.transfers.list() {
+		if restricted && s.checkMinionScope(ctx, transfer.MinionId) != nil {
+			continue
+		}
+		list.Transfers = append(list.Transfers, transfer)
+	}
+	return list, nil
+}
+
+// DownloadFile streams a file pulled to Nexus from an offset in the ConsoleService, its last chunk
+// carrying the checksum of the whole file
+func (s *Server) DownloadFile(req *pb.FileDownloadRequest, stream pb.ConsoleService_DownloadFileServer) error {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.DownloadFile")
+	defer logging.FuncExit(logger, start)
+
+	transfer, exists := s.transfers.get(req.TransferId)
+	if !exists {
+		return status.Errorf(codes.NotFound, "transfer %s not found", req.TransferId)
+	}
+	if err := s.checkMinionScope(stream.Context(), transfer.minionID); err != nil {
+		return err
+	}
+	current := s.transfers.snapshot(transfer)
+	if current.State != TransferCompleted {
+		return status.Errorf(codes.FailedPrecondition, "transfer %s is %s", transfer.id, current.State)
+	}
+	if req.Offset < 0 || req.Offset > current.Size {
+		return status.Errorf(codes.InvalidArgument, "offset %d is outside the file of %d bytes", req.Offset, current.Size)
+	}
+
+	file, err := os.Open(transfer.file)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to open transferred file: %v", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, fileDownloadChunkSize)
+	offset := req.Offset
+	for {
+		n, err := file.ReadAt(buf, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return status.Errorf(codes.Internal, "failed to read transferred file: %v", err)
+		}
+		chunk := &pb.FileChunk{
+			TransferId: transfer.id,
+			Offset:     offset,
+			Data:       buf[:n],
+			Size:       current.Size,
+		}
+		offset += int64(n)
+		if offset >= current.Size {
+			chunk.Eof = true
+			chunk.Sha256 = current.Sha256
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		if chunk.Eof {
+			logger.Debug("File downloaded",
+				zap.String("transfer_id", transfer.id),
+				zap.Int64("offset", req.Offset),
+				zap.Int64("size", current.Size))
+			return nil
+		}
+	}
+}
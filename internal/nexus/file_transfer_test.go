@@ -0,0 +1,165 @@
+package nexus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mockPullFileStream is a PullFile server stream collecting the statuses sent
+type mockPullFileStream struct {
+	grpc.ServerStream
+	sent chan *pb.FileTransferStatus
+}
+
+func (m *mockPullFileStream) Context() context.Context { return context.Background() }
+
+func (m *mockPullFileStream) Send(msg *pb.FileTransferStatus) error {
+	m.sent <- msg
+	return nil
+}
+
+// mockDownloadFileStream is a DownloadFile server stream collecting the chunks sent
+type mockDownloadFileStream struct {
+	grpc.ServerStream
+	chunks []*pb.FileChunk
+}
+
+func (m *mockDownloadFileStream) Context() context.Context { return context.Background() }
+
+func (m *mockDownloadFileStream) Send(msg *pb.FileChunk) error {
+	m.chunks = append(m.chunks, &pb.FileChunk{Offset: msg.Offset, Data: append([]byte(nil), msg.Data...), Eof: msg.Eof, Sha256: msg.Sha256})
+	return nil
+}
+
+// nextFileChunk waits for a request on the outbound channel of a minion
+func nextFileChunk(t *testing.T, ch chan *pb.FileChunk) *pb.FileChunk {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for file chunk")
+		return nil
+	}
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFileTransfersDisabled(t *testing.T) {
+	server := createTestServer(nil)
+	server.minionRegistry.Register(&pb.HostInfo{Id: "minion-1"})
+	server.minionRegistry.(*MinionRegistryImpl).OpenStream("minion-1")
+
+	err := server.PullFile(&pb.FilePullRequest{MinionId: "minion-1", Path: "/var/log/app.log"}, &mockPullFileStream{sent: make(chan *pb.FileTransferStatus, 10)})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without transfer directory, got %v", err)
+	}
+}
+
+func TestPullFile(t *testing.T) {
+	server := createTestServer(nil)
+	if err := server.EnableFileTransfers(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	server.minionRegistry.Register(&pb.HostInfo{Id: "minion-1"})
+	server.minionRegistry.(*MinionRegistryImpl).OpenStream("minion-1")
+	toMinion := server.transfers.outboundFor("minion-1")
+
+	content := []byte("0123456789abcdef")
+	stream := &mockPullFileStream{sent: make(chan *pb.FileTransferStatus, 100)}
+	done := make(chan error, 1)
+	go func() {
+		done <- server.PullFile(&pb.FilePullRequest{MinionId: "minion-1", Path: "/var/log/app.log"}, stream)
+	}()
+
+	req := nextFileChunk(t, toMinion)
+	if !req.Start || req.Path != "/var/log/app.log" || req.Offset != 0 {
+		t.Fatalf("Unexpected start request: %v", req)
+	}
+
+	// The minion disconnects in the middle of the file
+	server.transfers.deliver("minion-1", &pb.FileChunk{TransferId: req.TransferId, Offset: 0, Data: content[:6], Size: 16})
+	server.transfers.closeMinion("minion-1")
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var last *pb.FileTransferStatus
+	for len(stream.sent) > 0 {
+		last = <-stream.sent
+	}
+	if last.State != TransferInterrupted || last.Received != 6 {
+		t.Fatalf("Expected an interrupted transfer at 6 bytes, got %v", last)
+	}
+
+	// Resuming asks the minion for the bytes after those received, a duplicate chunk being ignored
+	go func() {
+		done <- server.PullFile(&pb.FilePullRequest{TransferId: req.TransferId}, stream)
+	}()
+	resume := nextFileChunk(t, toMinion)
+	if !resume.Start || resume.Offset != 6 || resume.TransferId != req.TransferId {
+		t.Fatalf("Unexpected resume request: %v", resume)
+	}
+	server.transfers.deliver("minion-1", &pb.FileChunk{TransferId: req.TransferId, Offset: 0, Data: content[:6], Size: 16})
+	server.transfers.deliver("minion-1", &pb.FileChunk{TransferId: req.TransferId, Offset: 6, Data: content[6:], Size: 16, Eof: true, Sha256: checksum(content)})
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transfer, _ := server.transfers.get(req.TransferId)
+	if data, err := os.ReadFile(transfer.file); err != nil || string(data) != string(content) {
+		t.Fatalf("Unexpected reassembled file %q: %v", data, err)
+	}
+	list, err := server.ListFileTransfers(context.Background(), &pb.Empty{})
+	if err != nil || len(list.Transfers) != 1 || list.Transfers[0].State != TransferCompleted || list.Transfers[0].Sha256 != checksum(content) {
+		t.Fatalf("Expected a completed transfer, got %v, %v", list, err)
+	}
+
+	// The file is downloaded from an offset, the last chunk carrying the checksum
+	download := &mockDownloadFileStream{}
+	if err := server.DownloadFile(&pb.FileDownloadRequest{TransferId: req.TransferId, Offset: 4}, download); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(download.chunks) != 1 || string(download.chunks[0].Data) != string(content[4:]) || !download.chunks[0].Eof || download.chunks[0].Sha256 != checksum(content) {
+		t.Errorf("Unexpected download: %v", download.chunks)
+	}
+	err = server.DownloadFile(&pb.FileDownloadRequest{TransferId: "unknown"}, download)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestFileTransferChecksumMismatch(t *testing.T) {
+	var transfers fileTransfers
+	transfers.dir = t.TempDir()
+	transfer, err := transfers.start("minion-1", "/etc/hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transfers.deliver("minion-1", &pb.FileChunk{TransferId: transfer.id, Data: []byte("changed"), Size: 7, Eof: true, Sha256: checksum([]byte("original"))})
+	current := transfers.snapshot(transfer)
+	if current.State != TransferFailed || current.Received != 0 {
+		t.Fatalf("Expected a failed transfer starting over, got %v", current)
+	}
+	if info, err := os.Stat(transfer.file); err != nil || info.Size() != 0 {
+		t.Errorf("Expected the file discarded, got %v, %v", info, err)
+	}
+
+	// Chunks of stopped transfers make the minion stop sending them
+	transfers.deliver("minion-1", &pb.FileChunk{TransferId: transfer.id, Data: []byte("late")})
+	if cancel := nextFileChunk(t, transfers.outboundFor("minion-1")); !cancel.Cancel || cancel.TransferId != transfer.id {
+		t.Errorf("Expected the transfer canceled on the minion, got %v", cancel)
+	}
+}
@@ -0,0 +1,391 @@
+package nexus
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// healthWindow is the period over which the health of a minion is computed
+	healthWindow = time.Hour
+
+	// maxHealthSamples bounds the reconnections, heartbeats and results kept per minion
+	maxHealthSamples = 500
+
+	// minHealthResults is the number of results below which the failure rate isn't scored
+	minHealthResults = 3
+
+	// Health score thresholds, a minion scoring below healthyScore being degraded and below
+	// degradedScore unhealthy
+	healthyScore  = 80
+	degradedScore = 50
+)
+
+// Anomalies flagged in the health of a minion
+const (
+	AnomalyFrequentReconnects  = "frequent-reconnects"  // 3 command streams reopened or more
+	AnomalyHighFailureRate     = "high-failure-rate"    // half of the results failed or more
+	AnomalySlowResults         = "slow-results"         // results received a minute after dispatch on average
+	AnomalyIrregularHeartbeats = "irregular-heartbeats" // heartbeat intervals varying by half their mean
+	AnomalyMissedHeartbeats    = "missed-heartbeats"    // no heartbeat for 3 usual intervals
+)
+
+// healthResult is a command result received from a minion
+type healthResult struct {
+	at      time.Time
+	failed  bool
+	latency time.Duration // 0 when the dispatch time is unknown
+}
+
+// minionHealth holds the recent behavior of a single minion
+type minionHealth struct {
+	connected  bool        // a command stream was opened, the next ones being reconnections
+	reconnects []time.Time // command streams reopened
+	heartbeats []time.Time // registrations
+	results    []healthResult
+}
+
+// HealthTracker records the behavior of the minions over healthWindow and scores their health
+// from their reconnection frequency, command failure rate, result latency and heartbeat
+// regularity. A nil tracker ignores all records.
+type HealthTracker struct {
+	mu      sync.Mutex
+	minions map[string]*minionHealth
+	pruned  time.Time // last time the minions without recent records were dropped
+	now     func() time.Time
+}
+
+// NewHealthTracker creates an empty health tracker
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{
+		minions: make(map[string]*minionHealth),
+		now:     time.Now,
+	}
+}
+
+// get returns the health records of a minion, creating them if needed. The caller must hold h.mu
+func (h *HealthTracker) get(minionID string) *minionHealth {
+	h.pruneIdle(h.now())
+	health, exists := h.minions[minionID]
+	if !exists {
+		health = &minionHealth{}
+		h.minions[minionID] = health
+	}
+	return health
+}
+
+// pruneIdle drops, once per healthWindow, the minions without records over the last healthWindow,
+// typically minions gone for good. The caller must hold h.mu
+func (h *HealthTracker) pruneIdle(now time.Time) {
+	if now.Sub(h.pruned) < healthWindow {
+		return
+	}
+	h.pruned = now
+
+	since := now.Add(-healthWindow)
+	for minionID, health := range h.minions {
+		if health.lastRecord().Before(since) {
+			delete(h.minions, minionID)
+		}
+	}
+}
+
+// lastRecord returns the time of the last record of a minion, zero without records
+func (m *minionHealth) lastRecord() time.Time {
+	var last time.Time
+	for _, times := range [][]time.Time{m.reconnects, m.heartbeats} {
+		if len(times) > 0 && times[len(times)-1].After(last) {
+			last = times[len(times)-1]
+		}
+	}
+	if len(m.results) > 0 && m.results[len(m.results)-1].at.After(last) {
+		last = m.results[len(m.results)-1].at
+	}
+	return last
+}
+
+// Forget drops the health records of a minion removed from the registry
+func (h *HealthTracker) Forget(minionID string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.minions, minionID)
+}
+
+// pruneTimes drops the times older than since and the oldest ones beyond maxHealthSamples
+func pruneTimes(times []time.Time, since time.Time) []time.Time {
+	first := 0
+	for first < len(times) && times[first].Before(since) {
+		first++
+	}
+	if len(times)-first > maxHealthSamples {
+		first = len(times) - maxHealthSamples
+	}
+	return times[first:]
+}
+
+// RecordHeartbeat records a registration of a minion
+func (h *HealthTracker) RecordHeartbeat(minionID string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	health := h.get(minionID)
+	health.heartbeats = pruneTimes(append(health.heartbeats, now), now.Add(-healthWindow))
+}
+
+// RecordWake records a power-aware minion waking from its sleep: the heartbeats missed while
+// sleeping and the stream it opens next are not held against it
+func (h *HealthTracker) RecordWake(minionID string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := h.get(minionID)
+	health.heartbeats = []time.Time{h.now()}
+	health.connected = false
+}
+
+// RecordStreamOpened records a command stream opened by a minion, a reconnection unless it's the first
+func (h *HealthTracker) RecordStreamOpened(minionID string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := h.get(minionID)
+	if !health.connected {
+		health.connected = true
+		return
+	}
+	now := h.now()
+	health.reconnects = pruneTimes(append(health.reconnects, now), now.Add(-healthWindow))
+}
+
+// RecordResult records a command result of a minion, latency being 0 when unknown
+func (h *HealthTracker) RecordResult(minionID string, failed bool, latency time.Duration) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	health := h.get(minionID)
+	health.results = append(health.results, healthResult{at: now, failed: failed, latency: latency})
+
+	first := 0
+	for first < len(health.results) && health.results[first].at.Before(now.Add(-healthWindow)) {
+		first++
+	}
+	if len(health.results)-first > maxHealthSamples {
+		first = len(health.results) - maxHealthSamples
+	}
+	health.results = health.results[first:]
+}
+
+// Health scores the health of a minion over healthWindow. A minion without records scores 100.
+func (h *HealthTracker) Health(minionID string) *pb.MinionHealth {
+	result := &pb.MinionHealth{Score: 100}
+	if h == nil {
+		return result
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health, exists := h.minions[minionID]
+	if !exists {
+		return result
+	}
+	now := h.now()
+	since := now.Add(-healthWindow)
+	health.reconnects = pruneTimes(health.reconnects, since)
+	health.heartbeats = pruneTimes(health.heartbeats, since)
+
+	score := 100
+
+	// Reconnections: each one costs 10 points, up to 30
+	result.Reconnects = int32(len(health.reconnects))
+	score -= min(30, 10*len(health.reconnects))
+	if len(health.reconnects) >= 3 {
+		result.Anomalies = append(result.Anomalies, AnomalyFrequentReconnects)
+	}
+
+	// Failure rate: up to 30 points once enough results were received
+	var latencies time.Duration
+	var timed int
+	for _, r := range health.results {
+		if r.at.Before(since) {
+			continue
+		}
+		result.Results++
+		if r.failed {
+			result.FailedResults++
+		}
+		if r.latency > 0 {
+			latencies += r.latency
+			timed++
+		}
+	}
+	if result.Results >= minHealthResults {
+		rate := float64(result.FailedResults) / float64(result.Results)
+		score -= int(30 * rate)
+		if rate >= 0.5 {
+			result.Anomalies = append(result.Anomalies, AnomalyHighFailureRate)
+		}
+	}
+
+	// Result latency: 10 points above 10 seconds on average, 20 above a minute
+	if timed > 0 {
+		mean := latencies / time.Duration(timed)
+		result.MeanLatencyMs = mean.Milliseconds()
+		switch {
+		case mean > time.Minute:
+			score -= 20
+			result.Anomalies = append(result.Anomalies, AnomalySlowResults)
+		case mean > 10*time.Second:
+			score -= 10
+		}
+	}
+
+	// Heartbeat regularity: 10 points when the intervals vary by half their mean,
+	// 20 when the last heartbeat is 3 median intervals old
+	if len(health.heartbeats) >= 3 {
+		intervals := make([]float64, len(health.heartbeats)-1)
+		for i := 1; i < len(health.heartbeats); i++ {
+			intervals[i-1] = float64(health.heartbeats[i].Sub(health.heartbeats[i-1]))
+		}
+		median := medianOf(intervals)
+		result.HeartbeatIntervalMs = time.Duration(median).Milliseconds()
+
+		if median > 0 && float64(now.Sub(health.heartbeats[len(health.heartbeats)-1])) > 3*median {
+			score -= 20
+			result.Anomalies = append(result.Anomalies, AnomalyMissedHeartbeats)
+		}
+		if variation(intervals) > 0.5 {
+			score -= 10
+			result.Anomalies = append(result.Anomalies, AnomalyIrregularHeartbeats)
+		}
+	}
+
+	result.Score = int32(max(0, score))
+	return result
+}
+
+// excuseSleep drops the missed heartbeats of a sleeping minion from its health
+func excuseSleep(health *pb.MinionHealth) {
+	for i, anomaly := range health.Anomalies {
+		if anomaly == AnomalyMissedHeartbeats {
+			health.Anomalies = append(health.Anomalies[:i], health.Anomalies[i+1:]...)
+			health.Score = min(100, health.Score+20)
+			return
+		}
+	}
+}
+
+// medianOf returns the median of values, which it sorts
+func medianOf(values []float64) float64 {
+	sort.Float64s(values)
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[middle-1] + values[middle]) / 2
+	}
+	return values[middle]
+}
+
+// variation returns the coefficient of variation of values, their standard deviation relative to their mean
+func variation(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares/float64(len(values))) / mean
+}
+
+// commandLatency returns the time elapsed since the dispatch of a command to a minion still
+// waiting for its result, 0 when unknown
+func (s *Server) commandLatency(commandID, minionID string) time.Duration {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	tracker, exists := s.pendingCommands[commandID]
+	if !exists || !tracker.Pending[minionID] {
+		return 0
+	}
+	return time.Since(tracker.CreatedAt)
+}
+
+// GetFleetHealth summarizes the health of the minions in the ConsoleService, listing those
+// scoring below the requested threshold
+func (s *Server) GetFleetHealth(ctx context.Context, req *pb.FleetHealthRequest) (*pb.FleetHealth, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetFleetHealth")
+	defer logging.FuncExit(logger, start)
+
+	below := req.Below
+	if below <= 0 {
+		below = healthyScore
+	}
+
+	list, err := s.ListMinions(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := &pb.FleetHealth{Total: int32(len(list.Minions))}
+	var total int
+	for _, minion := range list.Minions {
+		score := minion.Health.GetScore()
+		total += int(score)
+		switch {
+		case score >= healthyScore:
+			fleet.Healthy++
+		case score >= degradedScore:
+			fleet.Degraded++
+		default:
+			fleet.Unhealthy++
+		}
+		if score < below {
+			fleet.Minions = append(fleet.Minions, minion)
+		}
+	}
+	if fleet.Total > 0 {
+		fleet.AverageScore = int32(total / int(fleet.Total))
+	}
+	sort.Slice(fleet.Minions, func(i, j int) bool {
+		a, b := fleet.Minions[i], fleet.Minions[j]
+		if a.Health.GetScore() != b.Health.GetScore() {
+			return a.Health.GetScore() < b.Health.GetScore()
+		}
+		return a.Id < b.Id
+	})
+
+	logger.Debug("Computed fleet health",
+		zap.Int32("total", fleet.Total),
+		zap.Int32("degraded", fleet.Degraded),
+		zap.Int32("unhealthy", fleet.Unhealthy))
+	return fleet, nil
+}
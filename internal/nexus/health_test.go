@@ -0,0 +1,156 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestHealthTracker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := NewHealthTracker()
+	tracker.now = func() time.Time { return now }
+
+	if health := tracker.Health("minion-1"); health.Score != 100 || len(health.Anomalies) != 0 {
+		t.Fatalf("Expected a perfect score without records, got %v", health)
+	}
+
+	// A regular minion connected once with successful fast results stays healthy
+	for i := 0; i < 5; i++ {
+		tracker.RecordHeartbeat("minion-1")
+		now = now.Add(30 * time.Second)
+	}
+	tracker.RecordStreamOpened("minion-1")
+	for i := 0; i < 4; i++ {
+		tracker.RecordResult("minion-1", false, time.Second)
+	}
+	health := tracker.Health("minion-1")
+	if health.Score != 100 || health.Reconnects != 0 || health.Results != 4 || health.HeartbeatIntervalMs != 30000 {
+		t.Fatalf("Expected a healthy minion, got %v", health)
+	}
+
+	// Reconnections, failures and slow results are flagged
+	for i := 0; i < 3; i++ {
+		tracker.RecordStreamOpened("minion-1")
+	}
+	for i := 0; i < 4; i++ {
+		tracker.RecordResult("minion-1", true, 5*time.Minute)
+	}
+	health = tracker.Health("minion-1")
+	expected := []string{AnomalyFrequentReconnects, AnomalyHighFailureRate, AnomalySlowResults}
+	if len(health.Anomalies) != len(expected) {
+		t.Fatalf("Expected anomalies %v, got %v", expected, health.Anomalies)
+	}
+	for i, anomaly := range expected {
+		if health.Anomalies[i] != anomaly {
+			t.Errorf("Expected anomalies %v, got %v", expected, health.Anomalies)
+		}
+	}
+	if health.Score != 100-30-15-20 || health.FailedResults != 4 {
+		t.Errorf("Unexpected health: %v", health)
+	}
+
+	// Heartbeats stopping are flagged, and the records age out of the window
+	now = now.Add(5 * time.Minute)
+	if health := tracker.Health("minion-1"); !containsAnomaly(health, AnomalyMissedHeartbeats) {
+		t.Errorf("Expected missed heartbeats, got %v", health.Anomalies)
+	}
+	now = now.Add(healthWindow)
+	if health := tracker.Health("minion-1"); health.Score != 100 || health.Results != 0 || health.Reconnects != 0 {
+		t.Errorf("Expected the records expired, got %v", health)
+	}
+}
+
+func TestHealthIrregularHeartbeats(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := NewHealthTracker()
+	tracker.now = func() time.Time { return now }
+
+	for _, interval := range []time.Duration{5 * time.Second, 60 * time.Second, 5 * time.Second, 60 * time.Second, 0} {
+		tracker.RecordHeartbeat("minion-1")
+		now = now.Add(interval)
+	}
+	if health := tracker.Health("minion-1"); !containsAnomaly(health, AnomalyIrregularHeartbeats) || health.Score != 90 {
+		t.Errorf("Expected irregular heartbeats, got %v", health)
+	}
+
+	var nilTracker *HealthTracker
+	nilTracker.RecordHeartbeat("minion-1")
+	if health := nilTracker.Health("minion-1"); health.Score != 100 {
+		t.Errorf("Expected a nil tracker to score 100, got %v", health)
+	}
+}
+
+func TestHealthPruned(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := NewHealthTracker()
+	tracker.now = func() time.Time { return now }
+
+	tracker.RecordHeartbeat("gone")
+	tracker.RecordHeartbeat("removed")
+	tracker.Forget("removed")
+	if _, exists := tracker.minions["removed"]; exists {
+		t.Error("Expected the records of a removed minion dropped")
+	}
+
+	// The minions without records over the health window are dropped
+	now = now.Add(healthWindow + time.Minute)
+	tracker.RecordHeartbeat("active")
+	if _, exists := tracker.minions["gone"]; exists || len(tracker.minions) != 1 {
+		t.Errorf("Expected only the active minion kept, got %d minions", len(tracker.minions))
+	}
+
+	registry := NewMinionRegistry(nil, zap.NewNop())
+	registry.minions["minion-1"] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: "minion-1"}, Commands: NewCommandQueue(10)}
+	registry.health.RecordHeartbeat("minion-1")
+	registry.Remove("minion-1")
+	if _, exists := registry.health.minions["minion-1"]; exists {
+		t.Error("Expected the health of a retired minion dropped")
+	}
+}
+
+func TestGetFleetHealth(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	for _, id := range []string{"minion-1", "minion-2", "minion-3"} {
+		registry.minions[id] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: id}, LastSeen: time.Now()}
+	}
+	for i := 0; i < 4; i++ {
+		registry.health.RecordStreamOpened("minion-2")
+		registry.health.RecordResult("minion-2", true, 0)
+		registry.health.RecordStreamOpened("minion-3")
+	}
+
+	fleet, err := server.GetFleetHealth(context.Background(), &pb.FleetHealthRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fleet.Total != 3 || fleet.Healthy != 1 || fleet.Degraded != 1 || fleet.Unhealthy != 1 || fleet.AverageScore != (100+70+40)/3 {
+		t.Errorf("Unexpected summary: %v", fleet)
+	}
+	if len(fleet.Minions) != 2 || fleet.Minions[0].Id != "minion-2" || fleet.Minions[1].Id != "minion-3" {
+		t.Fatalf("Expected the anomalous minions, lowest score first, got %v", fleet.Minions)
+	}
+	if fleet.Minions[0].Health.Score != 40 || !containsAnomaly(fleet.Minions[0].Health, AnomalyHighFailureRate) {
+		t.Errorf("Unexpected health: %v", fleet.Minions[0].Health)
+	}
+
+	// Every minion is listed below a threshold above the scores
+	fleet, err = server.GetFleetHealth(context.Background(), &pb.FleetHealthRequest{Below: 101})
+	if err != nil || len(fleet.Minions) != 3 {
+		t.Errorf("Expected every minion listed, got %v, %v", fleet, err)
+	}
+}
+
+func containsAnomaly(health *pb.MinionHealth, anomaly string) bool {
+	for _, a := range health.Anomalies {
+		if a == anomaly {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package nexus
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// History sizes of GetMinionHistory
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 500
+)
+
+// GetMinionHistory returns the last limit commands sent to a minion, most recent first, with their
+// status and, once a result was received, its exit code and the time from dispatch to result.
+func (d *DatabaseServiceImpl) GetMinionHistory(ctx context.Context, minionID string, limit int) ([]*pb.MinionHistoryEntry, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get history of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetMinionHistory")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT c.id, c.command,
+			CASE WHEN r.exit_code IS NULL THEN c.status WHEN r.exit_code = 0 THEN 'COMPLETED' ELSE 'FAILED' END,
+			EXTRACT(EPOCH FROM c.timestamp)::bigint,
+			r.exit_code,
+			(EXTRACT(EPOCH FROM (r.timestamp - c.timestamp)) * 1000)::bigint
+		FROM commands c
+		LEFT JOIN LATERAL (
+			SELECT exit_code, timestamp FROM command_results
+			WHERE command_id = c.id AND minion_id = c.host_id
+			ORDER BY timestamp DESC LIMIT 1
+		) r ON true
+		WHERE c.host_id = $1
+		ORDER BY c.timestamp DESC
+		LIMIT $2`,
+		minionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query minion history: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*pb.MinionHistoryEntry
+	for rows.Next() {
+		var entry pb.MinionHistoryEntry
+		var exitCode sql.NullInt32
+		var duration sql.NullInt64
+		if err := rows.Scan(&entry.CommandId, &entry.Command, &entry.Status, &entry.Timestamp, &exitCode, &duration); err != nil {
+			return nil, fmt.Errorf("failed to scan minion history: %v", err)
+		}
+		entry.Command = d.decryptPayload(entry.Command, entry.CommandId, minionID)
+		if exitCode.Valid {
+			entry.HasResult = true
+			entry.ExitCode = exitCode.Int32
+			entry.DurationMs = max(duration.Int64, 0)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading minion history: %v", err)
+	}
+
+	logger.Debug("Retrieved minion history",
+		zap.String("minion_id", minionID),
+		zap.Int("count", len(entries)))
+	return entries, nil
+}
+
+// GetMinionHistory returns the last commands executed on a minion
+func (s *Server) GetMinionHistory(ctx context.Context, req *pb.MinionHistoryRequest) (*pb.MinionHistory, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetMinionHistory")
+	defer logging.FuncExit(logger, start)
+
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "minion history requires a database")
+	}
+	if consoleScope(ctx).restricted() {
+		if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	entries, err := s.dbService.GetMinionHistory(ctx, req.MinionId, limit)
+	if err != nil {
+		logger.Error("Failed to get minion history", zap.String("minion_id", req.MinionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get minion history")
+	}
+	return &pb.MinionHistory{MinionId: req.MinionId, Entries: entries}, nil
+}
@@ -0,0 +1,57 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetMinionHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+
+	mock.ExpectQuery("SELECT c.id, c.command").WithArgs("minion-1", defaultHistoryLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "command", "status", "timestamp", "exit_code", "duration"}).
+			AddRow("cmd-2", "system:info", "EXECUTING", int64(1700000100), nil, nil).
+			AddRow("cmd-1", "apt-get update", "FAILED", int64(1700000000), int32(100), int64(4250)))
+
+	history, err := server.GetMinionHistory(context.Background(), &pb.MinionHistoryRequest{MinionId: "minion-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(history.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %v", history.Entries)
+	}
+	if entry := history.Entries[0]; entry.HasResult || entry.Status != "EXECUTING" {
+		t.Errorf("Expected running command without result, got %v", entry)
+	}
+	if entry := history.Entries[1]; !entry.HasResult || entry.ExitCode != 100 || entry.DurationMs != 4250 || entry.Status != "FAILED" {
+		t.Errorf("Unexpected failed command entry: %v", entry)
+	}
+
+	// The limit is capped
+	mock.ExpectQuery("SELECT c.id, c.command").WithArgs("minion-1", maxHistoryLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "command", "status", "timestamp", "exit_code", "duration"}))
+	if _, err := server.GetMinionHistory(context.Background(), &pb.MinionHistoryRequest{MinionId: "minion-1", Limit: 10000}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := server.GetMinionHistory(context.Background(), &pb.MinionHistoryRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+	if _, err := createTestServer(nil).GetMinionHistory(context.Background(), &pb.MinionHistoryRequest{MinionId: "minion-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// HostChange describes a change of the environment fingerprint reported by a minion,
+// usually meaning its host was re-imaged or moved
+type HostChange struct {
+	MinionID       string
+	OldFingerprint string
+	NewFingerprint string
+	Changes        []string // human readable attribute changes, e.g. "ip: 10.0.0.1 -> 10.0.0.2"
+	DetectedAt     time.Time
+}
+
+// detectHostChange compares two registrations of the same minion and returns the change,
+// or nil when the fingerprint is unchanged or one of the registrations carries none
+func detectHostChange(previous, current *pb.HostInfo) *HostChange {
+	if previous == nil || current == nil {
+		return nil
+	}
+	if previous.Fingerprint == "" || current.Fingerprint == "" || previous.Fingerprint == current.Fingerprint {
+		return nil
+	}
+
+	var changes []string
+	addChange := func(name, before, after string) {
+		if before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", name, before, after))
+		}
+	}
+	addChange("hostname", previous.Hostname, current.Hostname)
+	addChange("ip", previous.Ip, current.Ip)
+	addChange("os_version", previous.OsVersion, current.OsVersion)
+	addChange("mac_addresses", joinSorted(previous.MacAddresses), joinSorted(current.MacAddresses))
+	if len(changes) == 0 {
+		changes = append(changes, "fingerprint")
+	}
+
+	return &HostChange{
+		MinionID:       current.Id,
+		OldFingerprint: previous.Fingerprint,
+		NewFingerprint: current.Fingerprint,
+		Changes:        changes,
+		DetectedAt:     time.Now(),
+	}
+}
+
+// joinSorted joins a copy of values in sorted order
+func joinSorted(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// recordHostChange logs, persists and notifies a host change
+func (s *Server) recordHostChange(ctx context.Context, change *HostChange, tags map[string]string) {
+	s.logger.Warn("Minion environment fingerprint changed",
+		zap.String("minion_id", change.MinionID),
+		zap.String("old_fingerprint", change.OldFingerprint),
+		zap.String("new_fingerprint", change.NewFingerprint),
+		zap.Strings("changes", change.Changes))
+
+	if s.dbService != nil {
+		if err := s.dbService.StoreHostChange(ctx, change); err != nil {
+			s.logger.Error("Failed to store host change",
+				zap.String("minion_id", change.MinionID),
+				zap.Error(err))
+		}
+	}
+
+	if s.notifier != nil {
+		s.notifier.NotifyHostChange(&HostChangeEvent{
+			Event:          "host_change",
+			MinionID:       change.MinionID,
+			OldFingerprint: change.OldFingerprint,
+			NewFingerprint: change.NewFingerprint,
+			Changes:        change.Changes,
+			Timestamp:      change.DetectedAt.Unix(),
+			Tags:           tags,
+		})
+	}
+}
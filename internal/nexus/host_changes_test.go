@@ -0,0 +1,132 @@
+package nexus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.uber.org/zap"
+)
+
+func TestDetectHostChange(t *testing.T) {
+	previous := &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.1", OsVersion: "Debian 12", MacAddresses: []string{"aa:bb"}, Fingerprint: "f1"}
+
+	tests := []struct {
+		name     string
+		previous *pb.HostInfo
+		current  *pb.HostInfo
+		want     []string
+	}{
+		{"unchanged", previous, &pb.HostInfo{Id: "minion-1", Fingerprint: "f1"}, nil},
+		{"no_previous", nil, &pb.HostInfo{Id: "minion-1", Fingerprint: "f2"}, nil},
+		{"legacy_minion", previous, &pb.HostInfo{Id: "minion-1", Hostname: "web-02"}, nil},
+		{"moved", previous, &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.2", OsVersion: "Debian 12", MacAddresses: []string{"aa:bb"}, Fingerprint: "f2"},
+			[]string{"ip: 10.0.0.1 -> 10.0.0.2"}},
+		{"reimaged", previous, &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.1", OsVersion: "Debian 13", MacAddresses: []string{"cc:dd"}, Fingerprint: "f3"},
+			[]string{"os_version: Debian 12 -> Debian 13", "mac_addresses: aa:bb -> cc:dd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := detectHostChange(tt.previous, tt.current)
+			if tt.want == nil {
+				if change != nil {
+					t.Fatalf("Expected no change, got %+v", change)
+				}
+				return
+			}
+			if change == nil {
+				t.Fatal("Expected a change to be detected")
+			}
+			if strings.Join(change.Changes, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Expected changes %v, got %v", tt.want, change.Changes)
+			}
+			if change.OldFingerprint != "f1" || change.NewFingerprint != tt.current.Fingerprint {
+				t.Errorf("Unexpected fingerprints: %+v", change)
+			}
+		})
+	}
+}
+
+func TestRegisterRecordsHostChange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	var mu sync.Mutex
+	var received []HostChangeEvent
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event HostChangeEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	server := createTestServer(db)
+	notifier := NewWebhookNotifier([]WebhookTarget{
+		{URL: webhook.URL, Format: WebhookFormatJSON, Retries: 1, HostChanges: true},
+	}, zap.NewNop())
+	notifier.Start()
+	server.SetNotifier(notifier)
+
+	first := &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.1", Os: "linux", Fingerprint: "f1"}
+	mock.ExpectQuery("INSERT INTO hosts").WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	if _, err := server.Register(context.Background(), first); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Periodic registration with the same fingerprint records nothing
+	mock.ExpectQuery("INSERT INTO hosts").WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	same := &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.1", Os: "linux", Fingerprint: "f1"}
+	if _, err := server.Register(context.Background(), same); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	mock.ExpectQuery("INSERT INTO hosts").WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
+	mock.ExpectExec("INSERT INTO host_changes \\(host_id, old_fingerprint, new_fingerprint, changes, detected_at\\)").
+		WithArgs("minion-1", "f1", "f2", "ip: 10.0.0.1 -> 10.0.0.9", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	moved := &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.9", Os: "linux", Fingerprint: "f2"}
+	if _, err := server.Register(context.Background(), moved); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	notifier.Stop()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled database expectations: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 host change notification, got %d", len(received))
+	}
+	if received[0].Event != "host_change" || received[0].MinionID != "minion-1" || received[0].NewFingerprint != "f2" {
+		t.Errorf("Unexpected host change event: %+v", received[0])
+	}
+}
+
+func TestWebhookTargetMatchesHostChange(t *testing.T) {
+	event := &HostChangeEvent{MinionID: "minion-1", Tags: map[string]string{"env": "prod"}}
+
+	if (&WebhookTarget{}).MatchesHostChange(event) {
+		t.Error("Expected targets to ignore host changes unless enabled")
+	}
+	if !(&WebhookTarget{HostChanges: true, Tags: map[string]string{"env": "prod"}}).MatchesHostChange(event) {
+		t.Error("Expected matching target to receive host changes")
+	}
+	if (&WebhookTarget{HostChanges: true, Tags: map[string]string{"env": "dev"}}).MatchesHostChange(event) {
+		t.Error("Expected tag filter to apply to host changes")
+	}
+}
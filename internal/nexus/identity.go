@@ -0,0 +1,277 @@
+package nexus
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// identityBindings binds minion IDs to the certificate they first registered with, so that the ID sent
+// in the gRPC metadata can't be claimed by another minion. Bindings are persisted when Nexus has a database.
+type identityBindings struct {
+	mu       sync.Mutex
+	bound    map[string]string // minion ID -> fingerprint, "" when known to be unbound
+	rotating map[string]string // minion ID -> fingerprint of the certificate sent by certs:rotate
+}
+
+// newIdentityBindings creates empty identity bindings
+func newIdentityBindings() *identityBindings {
+	return &identityBindings{
+		bound:    make(map[string]string),
+		rotating: make(map[string]string),
+	}
+}
+
+// certificateFingerprint returns the hex encoded SHA-256 of a DER certificate
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// boundFingerprint returns the fingerprint a minion ID is bound to, "" when it is unbound. Bindings
+// not cached are read from the database, and an error is returned when it can't be reached.
+func (s *Server) boundFingerprint(ctx context.Context, minionID string) (string, error) {
+	s.identities.mu.Lock()
+	fingerprint, known := s.identities.bound[minionID]
+	s.identities.mu.Unlock()
+	if known || s.dbService == nil {
+		return fingerprint, nil
+	}
+
+	fingerprint, err := s.dbService.GetMinionIdentity(ctx, minionID)
+	if err != nil {
+		return "", err
+	}
+	s.identities.mu.Lock()
+	defer s.identities.mu.Unlock()
+	// A binding made meanwhile wins over the one read
+	if cached, known := s.identities.bound[minionID]; known {
+		return cached, nil
+	}
+	s.identities.bound[minionID] = fingerprint
+	return fingerprint, nil
+}
+
+// bindIdentity binds a minion ID to a certificate fingerprint, unless it was bound meanwhile to
+// another certificate than previous, by a concurrent registration or another Nexus sharing the
+// database. It returns the fingerprint the ID is bound to, the given one when the binding was made.
+func (s *Server) bindIdentity(ctx context.Context, minionID, previous, fingerprint string) (string, error) {
+	bound := fingerprint
+	if s.dbService != nil {
+		var err error
+		if bound, err = s.dbService.BindMinionIdentity(ctx, minionID, previous, fingerprint); err != nil {
+			return "", err
+		}
+	}
+
+	s.identities.mu.Lock()
+	defer s.identities.mu.Unlock()
+	if cached := s.identities.bound[minionID]; s.dbService == nil && cached != previous {
+		bound = cached
+	}
+	s.identities.bound[minionID] = bound
+	if bound == fingerprint {
+		delete(s.identities.rotating, minionID)
+	}
+	return bound, nil
+}
+
+// checkMinionIdentity verifies that the peer in ctx may use minionID. The first certificate presented with
+// an ID binds it; afterwards the ID is only accepted with that certificate, or with the one sent to the
+// minion by certs:rotate which replaces it. IDs never bound, e.g. used without TLS client certificates,
+// are accepted as before. The returned error is a gRPC status: PermissionDenied for another certificate,
+// Unavailable when the stored binding can't be read, the ID being then neither accepted nor bound.
+func (s *Server) checkMinionIdentity(ctx context.Context, minionID string) error {
+	if s.identities == nil {
+		return nil
+	}
+	logger, start := logging.FuncLogger(s.logger, "nexus.Server.checkMinionIdentity")
+	defer logging.FuncExit(logger, start)
+
+	var presented string
+	if cert := peerCertificate(ctx); cert != nil {
+		presented = certificateFingerprint(cert)
+	}
+	bound, err := s.boundFingerprint(ctx, minionID)
+	if err != nil {
+		logger.Error("Failed to read minion identity, refusing the minion until it can be verified",
+			zap.String("minion_id", minionID), zap.Error(err))
+		return status.Errorf(codes.Unavailable, "cannot verify the identity of minion %s, retry later", minionID)
+	}
+
+	if bound == presented {
+		return nil
+	}
+
+	s.identities.mu.Lock()
+	rotated := presented != "" && s.identities.rotating[minionID] == presented
+	s.identities.mu.Unlock()
+	if bound == "" || rotated {
+		previous := bound
+		if bound, err = s.bindIdentity(ctx, minionID, previous, presented); err != nil {
+			logger.Error("Failed to store minion identity, refusing the minion until it can be bound",
+				zap.String("minion_id", minionID), zap.Error(err))
+			return status.Errorf(codes.Unavailable, "cannot bind the identity of minion %s, retry later", minionID)
+		}
+		if bound == presented {
+			if previous == "" {
+				logger.Info("Minion ID bound to its certificate",
+					zap.String("minion_id", minionID), zap.String("fingerprint", presented))
+			} else {
+				logger.Info("Minion ID bound to its rotated certificate",
+					zap.String("minion_id", minionID),
+					zap.String("previous_fingerprint", previous),
+					zap.String("fingerprint", presented))
+			}
+			return nil
+		}
+	}
+
+	logger.Warn("Minion ID presented with another certificate than the one it is bound to",
+		zap.String("minion_id", minionID),
+		zap.String("bound_fingerprint", bound),
+		zap.String("presented_fingerprint", presented))
+	return status.Errorf(codes.PermissionDenied, "minion ID %s is bound to another certificate", minionID)
+}
+
+// expectCertificateRotation records the certificate sent to a minion by certs:rotate, accepted as its new
+// identity when it reconnects. Other payloads are ignored.
+func (s *Server) expectCertificateRotation(minionID, payload string) {
+	if s.identities == nil || !strings.HasPrefix(strings.TrimSpace(payload), "certs:rotate") {
+		return
+	}
+	request, err := command.ParseCertsRotateRequest(payload)
+	if err != nil {
+		return
+	}
+	block, _ := pem.Decode([]byte(request.Cert))
+	if block == nil {
+		return
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return
+	}
+
+	s.identities.mu.Lock()
+	s.identities.rotating[minionID] = certificateFingerprint(cert)
+	s.identities.mu.Unlock()
+}
+
+// UnbindMinion forgets the certificate a minion ID is bound to in the AdminService, so that a minion
+// reinstalled with a new certificate can register again
+func (s *Server) UnbindMinion(ctx context.Context, req *pb.UnbindMinionRequest) (*pb.Ack, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.UnbindMinion")
+	defer logging.FuncExit(logger, start)
+
+	if err := s.requireAdmin(ctx, "unbinding minions"); err != nil {
+		return nil, err
+	}
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if s.identities == nil {
+		return nil, status.Error(codes.FailedPrecondition, "minion IDs are not bound to certificates")
+	}
+
+	if s.dbService != nil {
+		if err := s.dbService.UnbindMinionIdentity(ctx, req.MinionId); err != nil {
+			logger.Error("Failed to delete minion identity", zap.String("minion_id", req.MinionId), zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to unbind minion: %v", err)
+		}
+	}
+	s.identities.mu.Lock()
+	delete(s.identities.bound, req.MinionId)
+	delete(s.identities.rotating, req.MinionId)
+	s.identities.mu.Unlock()
+
+	logger.Warn("Minion ID unbound from its certificate by an administrator", zap.String("minion_id", req.MinionId))
+	return &pb.Ack{Success: true}, nil
+}
+
+// GetMinionIdentity retrieves the fingerprint of the certificate a minion ID is bound to, "" when it is unbound.
+func (d *DatabaseServiceImpl) GetMinionIdentity(ctx context.Context, minionID string) (string, error) {
+	if d == nil || d.db == nil {
+		return "", fmt.Errorf("database service unavailable - cannot get identity of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetMinionIdentity")
+	defer logging.FuncExit(logger, start)
+
+	// Bindings guard registrations, so they are read from the primary rather than a lagging replica
+	var fingerprint string
+	err := d.db.QueryRowContext(ctx,
+		"SELECT cert_fingerprint FROM minion_identities WHERE host_id = $1", minionID).Scan(&fingerprint)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query minion identity: %v", err)
+	}
+	return fingerprint, nil
+}
+
+// BindMinionIdentity binds a minion ID to the fingerprint of a certificate when it is unbound, or
+// bound to previous for a rotation. A binding made meanwhile by another registration is kept, and the
+// fingerprint the ID is bound to is returned.
+func (d *DatabaseServiceImpl) BindMinionIdentity(ctx context.Context, minionID, previous, fingerprint string) (string, error) {
+	if d == nil || d.db == nil {
+		return "", fmt.Errorf("database service unavailable - cannot bind identity of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.BindMinionIdentity")
+	defer logging.FuncExit(logger, start)
+
+	if previous != "" {
+		if _, err := d.db.ExecContext(ctx,
+			"UPDATE minion_identities SET cert_fingerprint = $3, bound_at = NOW() WHERE host_id = $1 AND cert_fingerprint = $2",
+			minionID, previous, fingerprint); err != nil {
+			return "", fmt.Errorf("failed to rotate minion identity: %v", err)
+		}
+	}
+	if _, err := d.db.ExecContext(ctx,
+		`INSERT INTO minion_identities (host_id, cert_fingerprint, bound_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (host_id) DO NOTHING`,
+		minionID, fingerprint); err != nil {
+		return "", fmt.Errorf("failed to store minion identity: %v", err)
+	}
+
+	bound, err := d.GetMinionIdentity(ctx, minionID)
+	if err != nil {
+		return "", err
+	}
+	logger.Debug("Minion identity stored",
+		zap.String("host_id", minionID),
+		zap.Bool("bound_to_another_certificate", bound != fingerprint))
+	return bound, nil
+}
+
+// UnbindMinionIdentity deletes the binding of a minion ID to a certificate.
+func (d *DatabaseServiceImpl) UnbindMinionIdentity(ctx context.Context, minionID string) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot unbind identity of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.UnbindMinionIdentity")
+	defer logging.FuncExit(logger, start)
+
+	if _, err := d.db.ExecContext(ctx, "DELETE FROM minion_identities WHERE host_id = $1", minionID); err != nil {
+		return fmt.Errorf("failed to delete minion identity: %v", err)
+	}
+	return nil
+}
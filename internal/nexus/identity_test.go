@@ -0,0 +1,209 @@
+package nexus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// certificateContext returns a context whose peer presented the given verified certificate
+func certificateContext(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+// selfSignedCertificate creates a certificate with the given common name, returned parsed and PEM encoded
+func selfSignedCertificate(t *testing.T, commonName string) (*x509.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestMinionIdentityBinding(t *testing.T) {
+	server := createTestServer(nil)
+	server.identities = newIdentityBindings()
+	owner := &x509.Certificate{Raw: []byte("owner")}
+	other := &x509.Certificate{Raw: []byte("other")}
+
+	// IDs used without certificate stay unbound
+	if err := server.checkMinionIdentity(context.Background(), "minion-1"); err != nil {
+		t.Fatalf("Unexpected error without certificate: %v", err)
+	}
+	if response, err := server.Register(certificateContext(owner), &pb.HostInfo{Id: "minion-1"}); err != nil || !response.Success {
+		t.Fatalf("Expected the first certificate to be bound, got %v %v", response, err)
+	}
+
+	response, err := server.Register(certificateContext(other), &pb.HostInfo{Id: "minion-1"})
+	if err != nil || response.Success || response.ErrorMessage != "minion ID minion-1 is bound to another certificate" {
+		t.Errorf("Expected the registration with another certificate rejected, got %v %v", response, err)
+	}
+	if err := server.checkMinionIdentity(context.Background(), "minion-1"); err == nil {
+		t.Error("Expected a bound ID to be rejected without certificate")
+	}
+	if err := server.checkMinionIdentity(certificateContext(owner), "minion-1"); err != nil {
+		t.Errorf("Expected the bound certificate to be accepted, got %v", err)
+	}
+
+	// Polls use the same binding
+	if err := server.checkMinionIdentity(certificateContext(other), "minion-1"); err == nil {
+		t.Error("Expected another certificate to be rejected")
+	}
+
+	// A registration that read the ID unbound before it was bound keeps the first binding
+	if bound, err := server.bindIdentity(context.Background(), "minion-1", "", certificateFingerprint(other)); err != nil || bound != certificateFingerprint(owner) {
+		t.Errorf("Expected the first binding kept, got %q (%v)", bound, err)
+	}
+}
+
+func TestMinionIdentityRotation(t *testing.T) {
+	server := createTestServer(nil)
+	server.identities = newIdentityBindings()
+	current, _ := selfSignedCertificate(t, "minion-1")
+	rotated, rotatedPEM := selfSignedCertificate(t, "minion-1")
+
+	if err := server.checkMinionIdentity(certificateContext(current), "minion-1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := server.checkMinionIdentity(certificateContext(rotated), "minion-1"); err == nil {
+		t.Fatal("Expected the new certificate to be rejected before its rotation")
+	}
+
+	bundle, _ := json.Marshal(map[string]string{"cert": rotatedPEM, "key": "key", "ca": "ca"})
+	server.expectCertificateRotation("minion-1", "certs:rotate "+string(bundle))
+	server.expectCertificateRotation("minion-1", "uptime")
+	if err := server.checkMinionIdentity(certificateContext(rotated), "minion-1"); err != nil {
+		t.Fatalf("Expected the rotated certificate to be accepted, got %v", err)
+	}
+	if err := server.checkMinionIdentity(certificateContext(current), "minion-1"); err == nil {
+		t.Error("Expected the previous certificate to be rejected after the rotation")
+	}
+}
+
+func TestMinionIdentityDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	server.identities = newIdentityBindings()
+	owner := &x509.Certificate{Raw: []byte("owner")}
+	fingerprint := certificateFingerprint(owner)
+
+	// Bindings stored by a previous run are enforced
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-1").
+		WillReturnRows(sqlmock.NewRows([]string{"cert_fingerprint"}).AddRow(fingerprint))
+	if err := server.checkMinionIdentity(certificateContext(&x509.Certificate{Raw: []byte("other")}), "minion-1"); err == nil {
+		t.Error("Expected the stored binding to be enforced")
+	}
+
+	// New bindings are stored
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-2").
+		WillReturnRows(sqlmock.NewRows([]string{"cert_fingerprint"}))
+	mock.ExpectExec("INSERT INTO minion_identities .* ON CONFLICT \\(host_id\\) DO NOTHING").WithArgs("minion-2", fingerprint).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-2").
+		WillReturnRows(sqlmock.NewRows([]string{"cert_fingerprint"}).AddRow(fingerprint))
+	if err := server.checkMinionIdentity(certificateContext(owner), "minion-2"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// A binding made meanwhile by another Nexus is kept and enforced
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-4").
+		WillReturnRows(sqlmock.NewRows([]string{"cert_fingerprint"}))
+	mock.ExpectExec("INSERT INTO minion_identities").WithArgs("minion-4", fingerprint).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-4").
+		WillReturnRows(sqlmock.NewRows([]string{"cert_fingerprint"}).AddRow("other"))
+	if err := server.checkMinionIdentity(certificateContext(owner), "minion-4"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected the concurrent binding enforced, got %v", err)
+	}
+	if server.identities.bound["minion-4"] != "other" {
+		t.Errorf("Expected the concurrent binding cached, got %q", server.identities.bound["minion-4"])
+	}
+
+	// When the stored binding can't be read, the ID is refused and neither bound nor cached
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-3").
+		WillReturnError(errors.New("connection refused"))
+	if err := server.checkMinionIdentity(certificateContext(owner), "minion-3"); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable when the binding can't be read, got %v", err)
+	}
+	if _, cached := server.identities.bound["minion-3"]; cached {
+		t.Error("Expected no binding cached after a failed read")
+	}
+	mock.ExpectQuery("SELECT cert_fingerprint FROM minion_identities").WithArgs("minion-3").
+		WillReturnRows(sqlmock.NewRows([]string{"cert_fingerprint"}).AddRow(fingerprint))
+	if err := server.checkMinionIdentity(context.Background(), "minion-3"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected the stored binding enforced once readable, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUnbindMinion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	server.identities = newIdentityBindings()
+	server.SetAdmins([]string{"alice"})
+	server.identities.bound["minion-1"] = "fingerprint"
+
+	mock.ExpectExec("DELETE FROM minion_identities").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := server.UnbindMinion(identityContext("alice"), &pb.UnbindMinionRequest{MinionId: "minion-1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, bound := server.identities.bound["minion-1"]; bound {
+		t.Error("Expected the binding to be forgotten")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := server.UnbindMinion(identityContext("bob"), &pb.UnbindMinionRequest{MinionId: "minion-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a non administrator, got %v", err)
+	}
+	if _, err := server.UnbindMinion(identityContext("alice"), &pb.UnbindMinionRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package nexus
+
+import (
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// disruptiveReason is the confirmation reason of the disruptive commands matching no destructive pattern
+const disruptiveReason = "disruptive impact"
+
+// commandImpact returns the impact class of a command request, resolved by payloadImpact from the
+// class declared by the operator
+func (s *Server) commandImpact(req *pb.CommandRequest) (string, error) {
+	if err := command.ValidateImpact(req.Impact); err != nil {
+		return "", err
+	}
+	return s.payloadImpact(req.Impact, req.Command.Payload), nil
+}
+
+// payloadImpact returns the impact class of a payload: the declared class, raised to the class of the
+// command the payload names, and to disruptive when the payload matches a destructive pattern. Shell
+// commands declaring no class are mutating.
+func (s *Server) payloadImpact(declared, payload string) string {
+	impact := command.MaxImpact(declared, s.commandRegistry.Impact(payload))
+	if s.confirmations.Match(payload) != "" {
+		impact = command.ImpactDisruptive
+	}
+	if impact == "" {
+		impact = command.ImpactMutating
+	}
+	return impact
+}
@@ -2,6 +2,7 @@ package nexus
 
 import (
 	"context"
+	"time"
 
 	pb "github.com/arhuman/minexus/protogen"
 )
@@ -40,21 +41,158 @@ type MinionRegistry interface {
 // DatabaseService handles all database operations cleanly.
 // It provides methods for persisting hosts, commands, and results.
 type DatabaseService interface {
+	// Ping checks that the primary database is reachable.
+	Ping(ctx context.Context) error
+
 	// StoreHost persists host information to the database.
 	StoreHost(ctx context.Context, hostInfo *pb.HostInfo) error
 
-	// UpdateHost updates existing host information in the database.
-	UpdateHost(ctx context.Context, hostInfo *pb.HostInfo) error
+	// RegisterHost stores the registration of a minion, merging the tags it advertised with the
+	// stored tags set from consoles, and returns the tags stored.
+	RegisterHost(ctx context.Context, hostInfo *pb.HostInfo, advertised map[string]string, policy string) (map[string]string, error)
 
 	// StoreCommand persists command information to the database.
-	StoreCommand(ctx context.Context, commandID, minionID, payload string) error
+	StoreCommand(ctx context.Context, record CommandRecord) error
+
+	// StoreCommands persists several commands in a single transaction.
+	StoreCommands(ctx context.Context, records []CommandRecord) error
 
 	// UpdateCommandStatus updates the status of a command in the database.
 	UpdateCommandStatus(ctx context.Context, commandID string, status string) error
 
 	// StoreCommandResult persists command execution results to the database.
+	// A result already stored for the command and minion, resent by a minion that missed
+	// the acknowledgement, is ignored without error.
 	StoreCommandResult(ctx context.Context, result *pb.CommandResult) error
 
+	// CountOmittedResult counts a successful result of a sampled command without storing it.
+	CountOmittedResult(ctx context.Context, result *pb.CommandResult, sample int32) error
+
+	// GetOmittedResults returns the number of successful results of a command counted but not stored.
+	GetOmittedResults(ctx context.Context, commandID string) (int32, error)
+
+	// LastSpeedTest returns the last successful net:speedtest result of a minion, nil without one.
+	LastSpeedTest(ctx context.Context, minionID string) (*pb.SpeedTestSummary, error)
+
+	// LatestPatchStatuses returns the last successful patch:status result of every minion that ran one.
+	LatestPatchStatuses(ctx context.Context) (map[string]patchReport, error)
+
+	// DuplicateResults returns the number of duplicate results ignored since Nexus started.
+	DuplicateResults() int64
+
 	// GetCommandResults retrieves all results for a specific command.
 	GetCommandResults(ctx context.Context, commandID string) ([]*pb.CommandResult, error)
+
+	// GetCommandResultsPage retrieves up to limit results for a specific command, skipping the first offset ones.
+	// A limit of 0 retrieves all results.
+	GetCommandResultsPage(ctx context.Context, commandID string, limit, offset int) ([]*pb.CommandResult, error)
+
+	// GetCommandStatuses retrieves the status of a command on each of its target minions.
+	GetCommandStatuses(ctx context.Context, commandID string) ([]*pb.CommandStatusResponse_MinionStatus, error)
+
+	// ListArchivableCommands returns up to limit commands whose results were all stored before cutoff.
+	ListArchivableCommands(ctx context.Context, cutoff time.Time, limit int) ([]string, error)
+
+	// MarkResultsArchived records where the results of a command were archived and deletes the
+	// results of the archived minions.
+	MarkResultsArchived(ctx context.Context, commandID, objectKey string, count int, minionIDs []string) error
+
+	// GetArchivedResultsKey returns the object key of the archived results of a command,
+	// or an empty string when they were not archived.
+	GetArchivedResultsKey(ctx context.Context, commandID string) (string, error)
+
+	// StoreCommandEvent records an event of the timeline of a traced command.
+	StoreCommandEvent(ctx context.Context, event CommandEvent) error
+
+	// GetTrace retrieves the timeline of the commands sharing a trace ID, oldest event first.
+	GetTrace(ctx context.Context, traceID string) ([]*pb.TraceEvent, error)
+
+	// GetMinionHistory retrieves the last limit commands sent to a minion, most recent first.
+	GetMinionHistory(ctx context.Context, minionID string, limit int) ([]*pb.MinionHistoryEntry, error)
+
+	// StoreMinionLogs persists log entries shipped by a minion.
+	StoreMinionLogs(ctx context.Context, minionID string, entries []*pb.MinionLogEntry) error
+
+	// GetMinionLogs retrieves the last limit log entries of a minion at one of levels since since, most recent first.
+	GetMinionLogs(ctx context.Context, minionID string, levels []string, since time.Time, limit int) ([]*pb.MinionLogEntry, error)
+
+	// PruneMinionLogs deletes the minion logs older than cutoff and returns their number.
+	PruneMinionLogs(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// StoreCrashReports persists the crash reports sent by a minion.
+	StoreCrashReports(ctx context.Context, minionID string, crashes []*pb.CrashReport) error
+
+	// GetCrashReports retrieves the last limit crash reports of the minionIDs, of every minion when minionIDs is nil, most recent first.
+	GetCrashReports(ctx context.Context, minionIDs []string, limit int) ([]*pb.CrashReport, error)
+
+	// SetCommandEnvVar creates or replaces an environment variable of the commands of a minion or tag.
+	SetCommandEnvVar(ctx context.Context, envVar *pb.CommandEnvVar) error
+
+	// DeleteCommandEnvVar deletes an environment variable of the commands of a minion or tag, reporting whether it existed.
+	DeleteCommandEnvVar(ctx context.Context, envVar *pb.CommandEnvVar) (bool, error)
+
+	// GetCommandEnvVars retrieves every environment variable of the commands.
+	GetCommandEnvVars(ctx context.Context) ([]*pb.CommandEnvVar, error)
+
+	// CountExecutions returns the number of commands sent between since and until.
+	CountExecutions(ctx context.Context, since, until time.Time) (int, error)
+
+	// StreamExecutions calls fn with each of the first limit commands sent between since and until, oldest first, with their results.
+	StreamExecutions(ctx context.Context, since, until time.Time, limit int, fn func(*ExecutionRecord) error) error
+
+	// GetCommandStats aggregates the results received between since and until for the commands matching pattern.
+	GetCommandStats(ctx context.Context, since, until time.Time, pattern string, slowest int) (*pb.CommandStats, error)
+
+	// CheckIntegrity runs the database consistency checks, repairing the inconsistencies found when repair is set.
+	CheckIntegrity(ctx context.Context, repair bool) ([]*pb.DatabaseCheck, error)
+
+	// StoreHostChange records a change of the environment fingerprint of a minion.
+	StoreHostChange(ctx context.Context, change *HostChange) error
+
+	// StoreConnectionEvent records the opening or the end of the command stream of a minion.
+	StoreConnectionEvent(ctx context.Context, event *ConnectionEvent) error
+
+	// GetConnectionEvents retrieves the connection events of a minion since since, oldest first, preceded by the last event before since.
+	GetConnectionEvents(ctx context.Context, minionID string, since time.Time) ([]*ConnectionEvent, error)
+
+	// GetMinionIdentity retrieves the fingerprint of the certificate a minion ID is bound to, "" when it is unbound.
+	GetMinionIdentity(ctx context.Context, minionID string) (string, error)
+
+	// BindMinionIdentity binds a minion ID to the fingerprint of a certificate when it is unbound, or
+	// bound to previous for a rotation, and returns the fingerprint the ID is bound to.
+	BindMinionIdentity(ctx context.Context, minionID, previous, fingerprint string) (string, error)
+
+	// UnbindMinionIdentity deletes the binding of a minion ID to a certificate.
+	UnbindMinionIdentity(ctx context.Context, minionID string) error
+
+	// RetireHost moves a host and its results into the retired_hosts and retired_command_results tables, deleting its other rows.
+	RetireHost(ctx context.Context, minionID string, info *pb.HostInfo, notice *pb.RetireNotice) (int, error)
+
+	// MarkRetirementNotified records that a retired minion received its retirement notice.
+	MarkRetirementNotified(ctx context.Context, minionID string) error
+
+	// PendingRetirements retrieves the notices of the retired minions that didn't receive them yet, by minion ID.
+	PendingRetirements(ctx context.Context) (map[string]*pb.RetireNotice, error)
+
+	// SaveReport stores a report, replacing any report with the same name.
+	SaveReport(ctx context.Context, report *pb.Report) error
+
+	// GetReport retrieves a report by name, returning nil when it does not exist.
+	GetReport(ctx context.Context, name string) (*pb.Report, error)
+
+	// ListReports retrieves all reports ordered by name.
+	ListReports(ctx context.Context) ([]*pb.Report, error)
+
+	// QueryReport runs a report aggregation query and returns its rows.
+	QueryReport(ctx context.Context, query string, args []interface{}) ([]ReportRow, error)
+
+	// StoreApproval records a command requiring approval and its decision in the audit trail, with the
+	// request dispatched once approved while it is pending (nil once decided).
+	StoreApproval(ctx context.Context, approval *pb.CommandApproval, request *pb.CommandRequest) error
+
+	// GetApprovals retrieves the approvals requested since since, with the requests of the pending ones.
+	GetApprovals(ctx context.Context, since time.Time) ([]*pendingApproval, error)
+
+	// QueryReadOnly runs a query in a read-only transaction and returns its columns and up to maxRows rows.
+	QueryReadOnly(ctx context.Context, query string, args []interface{}, maxRows int) ([]string, [][]string, bool, error)
 }
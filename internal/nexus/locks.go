@@ -0,0 +1,302 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// lockKey identifies a named lock on a minion
+type lockKey struct {
+	minionID string
+	name     string
+}
+
+const (
+	// lockHoldTTL is how long a command sent with a lock may hold it without reporting its result,
+	// as long as Nexus tracks the command
+	lockHoldTTL = pendingCommandTTL
+	// lockWaitTimeout is how long a command waits for its lock before failing
+	lockWaitTimeout = time.Hour
+)
+
+// lockHolder is the command holding a lock
+type lockHolder struct {
+	commandID string
+	since     time.Time
+	explicit  bool // taken by lock:acquire, held until lock:release
+}
+
+// lockWaiter is a command waiting for a lock on one of its target minions
+type lockWaiter struct {
+	minionID  string
+	lock      string
+	command   *pb.Command
+	emergency bool
+	since     time.Time
+}
+
+// hostLocks coordinates named locks on minions, so commands that must not overlap on a host are
+// serialized. Each lock is held by a command ID: lock:acquire holds it until lock:release, commands
+// sent with a lock hold it until their result, the expiry of their minion stream or lockHoldTTL.
+// Locks are kept in memory and lost on restart.
+type hostLocks struct {
+	mu      sync.Mutex
+	holders map[lockKey]lockHolder
+	waiting []*lockWaiter // FIFO
+	now     func() time.Time
+}
+
+// clock returns the current time. Callers hold l.mu.
+func (l *hostLocks) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// acquire takes the lock name on a minion for holder until it is released, returning false and
+// the current holder when another command holds it
+func (l *hostLocks) acquire(minionID, name, holder string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.take(lockKey{minionID: minionID, name: name}, holder, true)
+}
+
+// take gives a lock to holder unless another command holds it. Callers hold l.mu.
+func (l *hostLocks) take(key lockKey, holder string, explicit bool) (string, bool) {
+	if l.holders == nil {
+		l.holders = make(map[lockKey]lockHolder)
+	}
+	if current, held := l.holders[key]; held && current.commandID != holder {
+		return current.commandID, false
+	}
+	l.holders[key] = lockHolder{commandID: holder, since: l.clock(), explicit: explicit}
+	return holder, true
+}
+
+// acquireOrWait takes the lock of a command on a minion, or queues the command until the lock is
+// released. It reports whether the lock was taken.
+func (l *hostLocks) acquireOrWait(minionID, name string, cmd *pb.Command, emergency bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.take(lockKey{minionID: minionID, name: name}, cmd.Id, false); ok {
+		return true
+	}
+	l.waiting = append(l.waiting, &lockWaiter{minionID: minionID, lock: name, command: cmd, emergency: emergency, since: l.clock()})
+	return false
+}
+
+// release frees the lock name on a minion whoever holds it. It reports whether the lock was held
+// and returns the waiting command it was granted to, if any.
+func (l *hostLocks) release(minionID, name string) (bool, []*lockWaiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := lockKey{minionID: minionID, name: name}
+	if _, held := l.holders[key]; !held {
+		return false, nil
+	}
+	delete(l.holders, key)
+	return true, l.grant(key)
+}
+
+// releaseHeld frees the locks a command holds on a minion and returns the waiting commands they
+// were granted to
+func (l *hostLocks) releaseHeld(minionID, holder string) []*lockWaiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var granted []*lockWaiter
+	for key, current := range l.holders {
+		if key.minionID == minionID && current.commandID == holder {
+			delete(l.holders, key)
+			granted = append(granted, l.grant(key)...)
+		}
+	}
+	return granted
+}
+
+// releaseMinion frees the locks held on a minion by the commands sent with a lock, which won't
+// report their result, and returns the waiting commands they were granted to. Locks taken by
+// lock:acquire stay held until lock:release.
+func (l *hostLocks) releaseMinion(minionID string) []*lockWaiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var granted []*lockWaiter
+	for key, current := range l.holders {
+		if key.minionID == minionID && !current.explicit {
+			delete(l.holders, key)
+			granted = append(granted, l.grant(key)...)
+		}
+	}
+	return granted
+}
+
+// expire frees the locks held for longer than lockHoldTTL by commands sent with a lock and drops
+// the commands waiting for longer than lockWaitTimeout. It returns the dropped commands and the
+// waiting commands the freed locks were granted to.
+func (l *hostLocks) expire() (expired, granted []*lockWaiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	waiting := l.waiting[:0]
+	for _, w := range l.waiting {
+		if now.Sub(w.since) > lockWaitTimeout {
+			expired = append(expired, w)
+		} else {
+			waiting = append(waiting, w)
+		}
+	}
+	l.waiting = waiting
+
+	for key, current := range l.holders {
+		if !current.explicit && now.Sub(current.since) > lockHoldTTL {
+			delete(l.holders, key)
+			granted = append(granted, l.grant(key)...)
+		}
+	}
+	return expired, granted
+}
+
+// grant gives a free lock to the first command waiting for it. Callers hold l.mu.
+func (l *hostLocks) grant(key lockKey) []*lockWaiter {
+	for i, w := range l.waiting {
+		if w.minionID == key.minionID && w.lock == key.name {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			l.holders[key] = lockHolder{commandID: w.command.Id, since: l.clock()}
+			return []*lockWaiter{w}
+		}
+	}
+	return nil
+}
+
+// handleLockCommand answers a lock:acquire or lock:release command for its targets itself,
+// storing a result per minion as if the minions had run it
+func (s *Server) handleLockCommand(ctx context.Context, req *pb.CommandRequest, action, name string, targets, skipped []string, logger *zap.Logger) *pb.CommandDispatchResponse {
+	commandID := generateMinionID()
+	req.Command.Id = commandID
+	s.trackCommand(commandID, req.Command.Payload, targets)
+	s.expireLocks(logger)
+
+	for _, minionID := range targets {
+		result := &pb.CommandResult{CommandId: commandID, MinionId: minionID, Timestamp: time.Now().Unix(), TraceId: req.Command.TraceId}
+		switch action {
+		case "acquire":
+			if holder, ok := s.locks.acquire(minionID, name, commandID); ok {
+				result.Stdout = fmt.Sprintf("Lock %s acquired", name)
+			} else {
+				result.ExitCode = 1
+				result.Stderr = fmt.Sprintf("lock %s is held by command %s", name, holder)
+			}
+		case "release":
+			held, granted := s.locks.release(minionID, name)
+			if held {
+				result.Stdout = fmt.Sprintf("Lock %s released", name)
+			} else {
+				result.Stdout = fmt.Sprintf("Lock %s was not held", name)
+			}
+			s.dispatchLockWaiters(granted, logger)
+		}
+
+		if s.dbService != nil {
+			if err := s.dbService.StoreCommand(ctx, commandRecord(commandID, minionID, targets, req)); err != nil {
+				logger.Error("Failed to store lock command", zap.String("command_id", commandID), zap.String("minion_id", minionID), zap.Error(err))
+			}
+			s.storeCommandResult(ctx, result, logger)
+		}
+		s.notifyCompletion(result)
+	}
+
+	logger.Info("Lock command handled",
+		zap.String("command_id", commandID),
+		zap.String("action", action),
+		zap.String("lock", name),
+		zap.Strings("target_minion_ids", targets))
+	return &pb.CommandDispatchResponse{
+		Accepted:         true,
+		CommandId:        commandID,
+		TargetMinionIds:  targets,
+		SkippedMinionIds: skipped,
+		TraceId:          req.Command.TraceId,
+		Impact:           req.Impact,
+	}
+}
+
+// releaseCommandLocks frees the locks held by a command on a minion once it reported its result
+func (s *Server) releaseCommandLocks(minionID, commandID string, logger *zap.Logger) {
+	s.dispatchLockWaiters(s.locks.releaseHeld(minionID, commandID), logger)
+}
+
+// releaseMinionLocks frees the locks held by the commands of a minion whose stream expired
+func (s *Server) releaseMinionLocks(minionID string, logger *zap.Logger) {
+	s.dispatchLockWaiters(s.locks.releaseMinion(minionID), logger)
+}
+
+// expireLocks frees the locks held for too long and fails the commands waiting for too long for
+// their lock
+func (s *Server) expireLocks(logger *zap.Logger) {
+	expired, granted := s.locks.expire()
+	for _, w := range expired {
+		logger.Warn("Command waiting for its lock timed out",
+			zap.String("command_id", w.command.Id),
+			zap.String("minion_id", w.minionID),
+			zap.String("lock", w.lock),
+			zap.Duration("timeout", lockWaitTimeout))
+		s.failLockWaiter(w, fmt.Errorf("lock %s not free after %s", w.lock, lockWaitTimeout), logger)
+	}
+	s.dispatchLockWaiters(granted, logger)
+}
+
+// failLockWaiter records a failed result for a command that waited for its lock in vain, so it
+// completes
+func (s *Server) failLockWaiter(w *lockWaiter, err error, logger *zap.Logger) {
+	s.recordResult(context.Background(), &pb.CommandResult{
+		CommandId: w.command.Id,
+		MinionId:  w.minionID,
+		ExitCode:  -1,
+		Stderr:    fmt.Sprintf("command not delivered: %v", err),
+		Timestamp: time.Now().Unix(),
+	}, logger)
+}
+
+// dispatchLockWaiters dispatches the commands granted a lock. A command that can't be dispatched
+// releases its lock to the next waiting command.
+func (s *Server) dispatchLockWaiters(waiters []*lockWaiter, logger *zap.Logger) {
+	for len(waiters) > 0 {
+		w := waiters[0]
+		waiters = waiters[1:]
+
+		var err error
+		registry, ok := s.minionRegistry.(*MinionRegistryImpl)
+		if !ok {
+			err = fmt.Errorf("minion registry does not dispatch commands")
+		} else if conn, exists := registry.GetConnectionImpl(w.minionID); exists {
+			if _, err = s.dispatchToConnection(conn, w.minionID, w.command, w.emergency, logger); err == nil {
+				logger.Info("Command granted its lock dispatched",
+					zap.String("command_id", w.command.Id),
+					zap.String("minion_id", w.minionID),
+					zap.String("lock", w.lock))
+				continue
+			}
+		} else {
+			err = fmt.Errorf("minion %s not connected", w.minionID)
+		}
+
+		logger.Warn("Command granted its lock could not be dispatched",
+			zap.String("command_id", w.command.Id),
+			zap.String("minion_id", w.minionID),
+			zap.String("lock", w.lock),
+			zap.Error(err))
+		waiters = append(waiters, s.locks.releaseHeld(w.minionID, w.command.Id)...)
+		s.failLockWaiter(w, err, logger)
+	}
+}
@@ -0,0 +1,196 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func createLockTestServer(t *testing.T) (*Server, *MinionConnectionImpl) {
+	t.Helper()
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1"},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+	conn, _ := registry.GetConnectionImpl("minion-1")
+	return server, conn
+}
+
+func sendLockTestCommand(t *testing.T, server *Server, payload, lock string) *pb.CommandDispatchResponse {
+	t.Helper()
+	resp, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: payload},
+		Lock:      lock,
+	})
+	if err != nil {
+		t.Fatalf("SendCommand(%s) failed: %v", payload, err)
+	}
+	if !resp.Accepted {
+		t.Fatalf("Expected %s to be accepted", payload)
+	}
+	return resp
+}
+
+func TestHostLocksAcquireRelease(t *testing.T) {
+	var locks hostLocks
+
+	if _, ok := locks.acquire("minion-1", "deploy", "cmd-1"); !ok {
+		t.Fatal("Expected a free lock to be acquired")
+	}
+	if _, ok := locks.acquire("minion-1", "deploy", "cmd-1"); !ok {
+		t.Error("Expected the holder to acquire its lock again")
+	}
+	if holder, ok := locks.acquire("minion-1", "deploy", "cmd-2"); ok || holder != "cmd-1" {
+		t.Errorf("Expected the lock held by cmd-1, got %q (acquired %v)", holder, ok)
+	}
+	if _, ok := locks.acquire("minion-2", "deploy", "cmd-2"); !ok {
+		t.Error("Expected locks to be per minion")
+	}
+
+	waiter := &pb.Command{Id: "cmd-3"}
+	if locks.acquireOrWait("minion-1", "deploy", waiter, false) {
+		t.Fatal("Expected cmd-3 to wait for the lock")
+	}
+	held, granted := locks.release("minion-1", "deploy")
+	if !held || len(granted) != 1 || granted[0].command != waiter {
+		t.Fatalf("Expected the lock granted to cmd-3, got held=%v granted=%v", held, granted)
+	}
+	if holder, _ := locks.acquire("minion-1", "deploy", "cmd-4"); holder != "cmd-3" {
+		t.Errorf("Expected cmd-3 to hold the lock, got %q", holder)
+	}
+	if granted := locks.releaseHeld("minion-1", "cmd-3"); len(granted) != 0 {
+		t.Errorf("Expected no waiter left, got %d", len(granted))
+	}
+	if held, _ := locks.release("minion-1", "deploy"); held {
+		t.Error("Expected the lock to be free")
+	}
+}
+
+func TestHostLocksExpiry(t *testing.T) {
+	now := time.Now()
+	locks := hostLocks{now: func() time.Time { return now }}
+
+	if _, ok := locks.acquire("minion-1", "maintenance", "lock-cmd"); !ok {
+		t.Fatal("Expected a free lock to be acquired")
+	}
+	if !locks.acquireOrWait("minion-1", "deploy", &pb.Command{Id: "cmd-1"}, false) {
+		t.Fatal("Expected cmd-1 to take the deploy lock")
+	}
+	waiter := &pb.Command{Id: "cmd-2"}
+	if locks.acquireOrWait("minion-1", "deploy", waiter, false) {
+		t.Fatal("Expected cmd-2 to wait for the lock")
+	}
+
+	// Commands waiting for too long are dropped
+	now = now.Add(lockWaitTimeout + time.Second)
+	expired, granted := locks.expire()
+	if len(expired) != 1 || expired[0].command != waiter || len(granted) != 0 {
+		t.Fatalf("Expected cmd-2 to time out, got expired=%v granted=%v", expired, granted)
+	}
+
+	// Locks of commands that never report are freed, unlike the locks taken by lock:acquire
+	now = now.Add(lockHoldTTL)
+	if _, granted := locks.expire(); len(granted) != 0 {
+		t.Errorf("Expected no waiter left, got %v", granted)
+	}
+	if _, ok := locks.acquire("minion-1", "deploy", "cmd-3"); !ok {
+		t.Error("Expected the deploy lock freed after its hold TTL")
+	}
+	if holder, ok := locks.acquire("minion-1", "maintenance", "cmd-3"); ok || holder != "lock-cmd" {
+		t.Errorf("Expected lock:acquire to keep its lock, got %q", holder)
+	}
+}
+
+func TestMinionLocksReleasedOnStreamExpiry(t *testing.T) {
+	server, conn := createLockTestServer(t)
+	server.streamMonitor = NewStreamMonitor(server.GetMinionRegistryImpl(), time.Minute, server.failDrainedCommands, server.logger)
+
+	first := sendLockTestCommand(t, server, "/opt/deploy.sh", "deploy")
+	if _, ok := conn.Commands.Pop(); !ok {
+		t.Fatal("Expected the first command delivered")
+	}
+	second := sendLockTestCommand(t, server, "/opt/deploy.sh", "deploy")
+	if conn.Commands.Len() != 0 {
+		t.Fatal("Expected the second command waiting for the lock")
+	}
+
+	// The delivered command won't report once its stream is dead: its lock goes to the next command
+	server.failDrainedCommands("minion-1", nil)
+	cmd, ok := conn.Commands.Pop()
+	if !ok || cmd.Id != second.CommandId {
+		t.Fatalf("Expected the waiting command dispatched after %s, got %v", first.CommandId, cmd)
+	}
+}
+
+func TestLockCommandsHandledByNexus(t *testing.T) {
+	server, conn := createLockTestServer(t)
+
+	first := sendLockTestCommand(t, server, "lock:acquire deploy", "")
+	if conn.Commands.Len() != 0 {
+		t.Error("Expected lock:acquire not to be dispatched to the minion")
+	}
+	if _, pending := server.pendingCommands[first.CommandId]; pending {
+		t.Error("Expected lock:acquire to be completed by Nexus")
+	}
+
+	sendLockTestCommand(t, server, "lock:acquire deploy", "")
+	if holder, _ := server.locks.acquire("minion-1", "deploy", "probe"); holder != first.CommandId {
+		t.Errorf("Expected the lock to stay held by the first lock:acquire, got %q", holder)
+	}
+
+	if _, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "lock:acquire"},
+	}); err == nil {
+		t.Error("Expected lock:acquire without a name to be rejected")
+	}
+}
+
+func TestCommandWaitsForItsLock(t *testing.T) {
+	server, conn := createLockTestServer(t)
+
+	sendLockTestCommand(t, server, "lock:acquire deploy", "")
+	resp := sendLockTestCommand(t, server, "system:info", "deploy")
+	if len(resp.LockWaitingMinionIds) != 1 || resp.LockWaitingMinionIds[0] != "minion-1" {
+		t.Fatalf("Expected the command to wait for its lock on minion-1, got %v", resp.LockWaitingMinionIds)
+	}
+	if _, ok := conn.Commands.Pop(); ok {
+		t.Fatal("Expected the command not to be dispatched while the lock is held")
+	}
+
+	sendLockTestCommand(t, server, "lock:release deploy", "")
+	cmd, ok := conn.Commands.Pop()
+	if !ok || cmd.Id != resp.CommandId {
+		t.Fatalf("Expected the waiting command dispatched once the lock is released, got %v", cmd)
+	}
+
+	// The command holds the lock until its result
+	other := sendLockTestCommand(t, server, "system:info", "deploy")
+	if len(other.LockWaitingMinionIds) != 1 {
+		t.Fatal("Expected a second command to wait while the first one runs")
+	}
+	server.handleCommandResult(context.Background(), &pb.CommandResult{CommandId: resp.CommandId, MinionId: "minion-1"}, server.logger)
+	if cmd, ok := conn.Commands.Pop(); !ok || cmd.Id != other.CommandId {
+		t.Fatalf("Expected the second command dispatched after the first result, got %v", cmd)
+	}
+}
+
+func TestSendCommandRejectsInvalidLockName(t *testing.T) {
+	server, _ := createLockTestServer(t)
+
+	_, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "system:info"},
+		Lock:      "bad lock",
+	})
+	if err == nil {
+		t.Error("Expected an invalid lock name to be rejected")
+	}
+}
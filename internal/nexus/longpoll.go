@@ -0,0 +1,400 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/arhuman/minexus/internal/longpoll"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// longPollIdleTimeout closes the command stream of a minion that stopped polling
+const longPollIdleTimeout = longpoll.MaxPollWait + 30*time.Second
+
+// longPollSession is the command stream of a minion polling over HTTP
+type longPollSession struct {
+	polled chan struct{} // signaled by each poll, keeping the session open
+	done   chan struct{} // closed once the session ended
+	hinted atomic.Bool   // set once the minion was asked to reconnect later
+}
+
+// longPollSessions tracks the minions polling for commands over HTTP
+type longPollSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*longPollSession
+}
+
+// LongPollHandler returns the HTTP handler of the long-polling transport minions fall back to
+// when their network blocks gRPC streams. Request bodies are limited to maxMsgSize bytes.
+func (s *Server) LongPollHandler(maxMsgSize int) http.Handler {
+	limit := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, int64(maxMsgSize))
+			next(w, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+longpoll.RegisterPath, limit(s.handleLongPollRegister))
+	mux.HandleFunc("GET "+longpoll.PollPath, s.handleLongPoll)
+	mux.HandleFunc("POST "+longpoll.MessagesPath, limit(s.handleLongPollMessages))
+	return mux
+}
+
+// longPollContext returns the context of a request, carrying the TLS state of the minion
+// so that its certificate sets its namespaces as over gRPC
+func longPollContext(r *http.Request) context.Context {
+	if r.TLS == nil {
+		return r.Context()
+	}
+	return peer.NewContext(r.Context(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: *r.TLS}})
+}
+
+// writeIdentityError answers a poll whose minion identity was refused by checkMinionIdentity,
+// with 503 when it couldn't be verified rather than 403
+func writeIdentityError(w http.ResponseWriter, err error) {
+	code := http.StatusForbidden
+	if status.Code(err) == codes.Unavailable {
+		code = http.StatusServiceUnavailable
+	}
+	http.Error(w, status.Convert(err).Message(), code)
+}
+
+// handleLongPollRegister registers a minion
+func (s *Server) handleLongPollRegister(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	hostInfo := &pb.HostInfo{}
+	if err := protojson.Unmarshal(body, hostInfo); err != nil {
+		http.Error(w, "invalid host info", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.Register(longPollContext(r), hostInfo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", longpoll.ContentType)
+	w.Write(data)
+}
+
+// handleLongPoll answers with the commands queued for a minion, holding the request up to the
+// requested wait while there are none
+func (s *Server) handleLongPoll(w http.ResponseWriter, r *http.Request) {
+	minionID := r.Header.Get(longpoll.MinionIDHeader)
+	if minionID == "" {
+		http.Error(w, "minion ID not provided", http.StatusBadRequest)
+		return
+	}
+	// Retired minions polling without registering again learn it right away, as they would registering,
+	// before their ID is bound anew
+	if notice := s.retired.pending(minionID, time.Now()); notice != nil {
+		s.writeRetireNotice(w, notice, minionID)
+		return
+	}
+	if err := s.checkMinionIdentity(longPollContext(r), minionID); err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	conn, exists := registry.GetConnectionImpl(minionID)
+	if !exists {
+		http.Error(w, "minion not found", http.StatusNotFound)
+		return
+	}
+
+	// Nexus waits for the polls held before stopping
+	s.drain.polls.RLock()
+	defer s.drain.polls.RUnlock()
+	if s.drain.isStopping() {
+		http.Error(w, "nexus is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	wait := longpoll.DefaultPollWait
+	if seconds, err := strconv.Atoi(r.URL.Query().Get("wait")); err == nil && seconds >= 0 {
+		wait = min(time.Duration(seconds)*time.Second, longpoll.MaxPollWait)
+	}
+
+	session := s.openLongPollSession(minionID)
+	select {
+	case session.polled <- struct{}{}:
+	default:
+	}
+	registry.UpdateLastPoll(minionID)
+
+	msgs := s.waitLongPoll(r.Context(), session, conn, minionID, wait)
+	data, err := longpoll.MarshalMessages(msgs)
+	if err != nil {
+		s.logger.Error("Failed to encode polled messages", zap.String("minion_id", minionID), zap.Error(err))
+		http.Error(w, "failed to encode messages", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", longpoll.ContentType)
+	w.Write(data)
+}
+
+// writeRetireNotice answers the poll of a retired minion with its retirement notice
+func (s *Server) writeRetireNotice(w http.ResponseWriter, notice *pb.RetireNotice, minionID string) {
+	data, err := longpoll.MarshalMessages([]*pb.CommandStreamMessage{{Message: &pb.CommandStreamMessage_Retire{Retire: notice}}})
+	if err != nil {
+		s.logger.Error("Failed to encode retirement notice", zap.String("minion_id", minionID), zap.Error(err))
+		http.Error(w, "failed to encode messages", http.StatusInternalServerError)
+		return
+	}
+	s.notifyRetired(minionID, s.logger)
+	w.Header().Set("Content-Type", longpoll.ContentType)
+	w.Write(data)
+}
+
+// waitLongPoll returns the messages to send to a minion once some are queued, or none after wait.
+// Once Nexus drains, the minion is asked to reconnect later and its polls are answered until Nexus stops.
+func (s *Server) waitLongPoll(ctx context.Context, session *longPollSession, conn *MinionConnectionImpl, minionID string, wait time.Duration) []*pb.CommandStreamMessage {
+	shellOutbound := s.shells.outboundFor(minionID)
+	fileOutbound := s.transfers.outboundFor(minionID)
+	draining, stopping := s.drain.channels()
+	if session.hinted.Load() {
+		draining = nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		// Commands left queued by a previous poll don't signal the queue again
+		if msgs := s.popLongPollMessages(conn, shellOutbound, fileOutbound, minionID); len(msgs) > 0 {
+			return msgs
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			return nil
+		case <-session.done:
+			return nil
+		case <-draining:
+			// Keep answering polls for the results of the commands being executed
+			draining = nil
+			if session.hinted.CompareAndSwap(false, true) {
+				s.logger.Info("Minion asked to reconnect later", zap.String("minion_id", minionID))
+				return []*pb.CommandStreamMessage{reconnectHint()}
+			}
+		case <-stopping:
+			return nil
+		case <-conn.Commands.Ready():
+		case msg := <-shellOutbound:
+			return append([]*pb.CommandStreamMessage{{Message: &pb.CommandStreamMessage_Shell{Shell: msg}}},
+				s.popLongPollMessages(conn, shellOutbound, fileOutbound, minionID)...)
+		case msg := <-fileOutbound:
+			return append([]*pb.CommandStreamMessage{{Message: &pb.CommandStreamMessage_File{File: msg}}},
+				s.popLongPollMessages(conn, shellOutbound, fileOutbound, minionID)...)
+		}
+	}
+}
+
+// popLongPollMessages removes the queued commands, shell messages and file transfer requests of a
+// minion without waiting
+func (s *Server) popLongPollMessages(conn *MinionConnectionImpl, shellOutbound chan *pb.ShellMessage, fileOutbound chan *pb.FileChunk, minionID string) []*pb.CommandStreamMessage {
+	var msgs []*pb.CommandStreamMessage
+	for {
+		select {
+		case msg := <-shellOutbound:
+			msgs = append(msgs, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Shell{Shell: msg}})
+			continue
+		case msg := <-fileOutbound:
+			msgs = append(msgs, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_File{File: msg}})
+			continue
+		default:
+		}
+
+		cmd, ok := conn.Commands.Pop()
+		if !ok {
+			return msgs
+		}
+		msgs = append(msgs, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Command{Command: cmd}})
+		s.diagnostics.RecordCommandSent(minionID)
+	}
+}
+
+// handleLongPollMessages handles the results, status updates, shell output and file chunks posted by a minion,
+// answering with the acknowledgements of the results stored
+func (s *Server) handleLongPollMessages(w http.ResponseWriter, r *http.Request) {
+	minionID := r.Header.Get(longpoll.MinionIDHeader)
+	if minionID == "" {
+		http.Error(w, "minion ID not provided", http.StatusBadRequest)
+		return
+	}
+	if err := s.checkMinionIdentity(longPollContext(r), minionID); err != nil {
+		writeIdentityError(w, err)
+		return
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	if _, exists := registry.GetConnectionImpl(minionID); !exists {
+		http.Error(w, "minion not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	msgs, err := longpoll.UnmarshalMessages(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Minions posting results while Nexus is overloaded are slowed down once per request
+	if carriesResults(msgs) {
+		s.throttleIngestion(r.Context())
+	}
+
+	// Any message proves the minion is alive
+	registry.UpdateLastSeen(minionID)
+	var acks []*pb.CommandStreamMessage
+	for _, msg := range msgs {
+		// The minion ID header is authoritative on which minion sent the message
+		switch m := msg.Message.(type) {
+		case *pb.CommandStreamMessage_Result:
+			m.Result.MinionId = minionID
+			if err := s.handleCommandResult(r.Context(), m.Result, s.logger); err == nil {
+				acks = append(acks, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Ack{Ack: &pb.ResultAck{CommandId: m.Result.CommandId}}})
+			}
+		case *pb.CommandStreamMessage_ResultChunk:
+			result := s.assembleResult(r.Context(), minionID, m.ResultChunk, s.logger)
+			if result == nil {
+				continue
+			}
+			result.MinionId = minionID
+			if err := s.handleCommandResult(r.Context(), result, s.logger); err == nil {
+				acks = append(acks, &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Ack{Ack: &pb.ResultAck{CommandId: result.CommandId}}})
+			}
+		case *pb.CommandStreamMessage_Status:
+			m.Status.MinionId = minionID
+			s.handleStatusUpdate(r.Context(), m.Status, s.logger)
+		case *pb.CommandStreamMessage_Shell:
+			m.Shell.MinionId = minionID
+			s.shells.deliver(m.Shell)
+		case *pb.CommandStreamMessage_File:
+			s.transfers.deliver(minionID, m.File)
+		case *pb.CommandStreamMessage_Logs:
+			s.storeMinionLogs(r.Context(), minionID, m.Logs, s.logger)
+		}
+	}
+
+	if len(acks) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	data, err := longpoll.MarshalMessages(acks)
+	if err != nil {
+		// The minion sends the results again
+		s.logger.Error("Failed to encode result acknowledgements", zap.String("minion_id", minionID), zap.Error(err))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", longpoll.ContentType)
+	w.Write(data)
+}
+
+// carriesResults reports whether messages posted by a minion include results
+func carriesResults(msgs []*pb.CommandStreamMessage) bool {
+	for _, msg := range msgs {
+		if msg.GetResult() != nil || msg.GetResultChunk() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// openLongPollSession returns the command stream of a polling minion, opening it on its first poll
+func (s *Server) openLongPollSession(minionID string) *longPollSession {
+	s.longPolls.mu.Lock()
+	defer s.longPolls.mu.Unlock()
+
+	if session, exists := s.longPolls.sessions[minionID]; exists {
+		return session
+	}
+	if s.longPolls.sessions == nil {
+		s.longPolls.sessions = make(map[string]*longPollSession)
+	}
+
+	session := &longPollSession{
+		polled: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	s.longPolls.sessions[minionID] = session
+
+	s.logger.Info("Minion polling for commands over HTTP", zap.String("minion_id", minionID))
+	s.setupConnection(minionID, s.logger)
+	dead := s.GetMinionRegistryImpl().OpenStream(minionID)
+	s.diagnostics.RecordStreamOpened(minionID)
+	s.recordConnectionEvent(minionID, ConnectionEventConnect, nil)
+	go s.watchLongPollSession(minionID, session, dead)
+	return session
+}
+
+// watchLongPollSession closes the command stream of a minion once it stopped polling, was
+// detected dead or was disconnected by an administrator
+func (s *Server) watchLongPollSession(minionID string, session *longPollSession, dead <-chan struct{}) {
+	idle := time.NewTimer(longPollIdleTimeout)
+	defer idle.Stop()
+
+	var err error
+	for err == nil {
+		select {
+		case <-session.polled:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(longPollIdleTimeout)
+		case <-dead:
+			err = status.Error(codes.Unavailable, "command stream closed by nexus")
+		case <-idle.C:
+			err = fmt.Errorf("minion stopped polling for %s", longPollIdleTimeout)
+		}
+	}
+
+	s.longPolls.mu.Lock()
+	if s.longPolls.sessions[minionID] == session {
+		delete(s.longPolls.sessions, minionID)
+	}
+	s.longPolls.mu.Unlock()
+	close(session.done)
+
+	s.logger.Info("Minion HTTP command stream closed", zap.String("minion_id", minionID), zap.Error(err))
+	s.GetMinionRegistryImpl().CloseStream(minionID, dead)
+	s.diagnostics.RecordStreamClosed(minionID, err)
+	if conn, exists := s.minionRegistry.GetConnection(minionID); exists {
+		err = disconnectReason(conn.GetInfo(), err)
+	}
+	s.recordConnectionEvent(minionID, ConnectionEventDisconnect, err)
+	s.shells.closeMinion(minionID)
+	s.transfers.closeMinion(minionID)
+}
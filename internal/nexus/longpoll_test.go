@@ -0,0 +1,148 @@
+package nexus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/longpoll"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestLongPollTransport(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	httpServer := httptest.NewServer(server.LongPollHandler(1024 * 1024))
+	defer httpServer.Close()
+
+	client := longpoll.NewClient(httpServer.URL, httpServer.Client())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Register(ctx, &pb.HostInfo{Id: "polling-minion", Hostname: "host"})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if !resp.Success || resp.AssignedId != "polling-minion" || !resp.ResultAcks {
+		t.Fatalf("Unexpected registration response: %v", resp)
+	}
+
+	streamCtx := metadata.AppendToOutgoingContext(ctx, "minion-id", "polling-minion")
+	stream, err := client.StreamCommands(streamCtx)
+	if err != nil {
+		t.Fatalf("StreamCommands failed: %v", err)
+	}
+	defer stream.CloseSend()
+
+	conn, _ := server.GetMinionRegistryImpl().GetConnectionImpl("polling-minion")
+	conn.Commands.Push(&pb.Command{Id: "cmd-1", Payload: "uptime"})
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if msg.GetCommand().GetId() != "cmd-1" {
+		t.Fatalf("Expected command cmd-1, got %v", msg)
+	}
+	if !server.GetMinionRegistryImpl().HasStream("polling-minion") {
+		t.Error("Expected the polling minion to have an open command stream")
+	}
+
+	// The minion ID header is authoritative on the sender of results
+	err = stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Result{
+		Result: &pb.CommandResult{CommandId: "cmd-1", MinionId: "spoofed", Stdout: "up"},
+	}})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	diag := &pb.MinionDiagnostics{}
+	server.diagnostics.Fill("polling-minion", diag)
+	if diag.ResultsReceived != 1 {
+		t.Errorf("Expected 1 result recorded for the polling minion, got %d", diag.ResultsReceived)
+	}
+
+	// The result is acknowledged on the stream
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if msg.GetAck().GetCommandId() != "cmd-1" {
+		t.Errorf("Expected the result of cmd-1 acknowledged, got %v", msg)
+	}
+
+	// Nor can the minion write into the shell sessions of another minion
+	session := server.shells.open("other-minion")
+	defer server.shells.remove(session)
+	err = stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Shell{
+		Shell: &pb.ShellMessage{SessionId: session.id, MinionId: "other-minion", Data: []byte("spoofed")},
+	}})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	select {
+	case msg := <-session.output:
+		t.Errorf("Expected the spoofed shell output dropped, got %v", msg)
+	default:
+	}
+}
+
+func TestLongPollUnknownMinion(t *testing.T) {
+	server := createTestServer(nil)
+	httpServer := httptest.NewServer(server.LongPollHandler(1024 * 1024))
+	defer httpServer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL+longpoll.PollPath+"?wait=0", nil)
+	req.Header.Set(longpoll.MinionIDHeader, "unknown")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown minion, got %d", resp.StatusCode)
+	}
+
+	client := longpoll.NewClient(httpServer.URL, httpServer.Client())
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "minion-id", "unknown")
+	stream, err := client.StreamCommands(ctx)
+	if err != nil {
+		t.Fatalf("StreamCommands failed: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound from Recv, got %v", err)
+	}
+}
+
+func TestLongPollEmptyWait(t *testing.T) {
+	server := createTestServer(nil)
+	server.minionRegistry.Register(&pb.HostInfo{Id: "idle-minion"})
+	httpServer := httptest.NewServer(server.LongPollHandler(1024 * 1024))
+	defer httpServer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL+longpoll.PollPath+"?wait=0", nil)
+	req.Header.Set(longpoll.MinionIDHeader, "idle-minion")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	var body [256]byte
+	n, _ := resp.Body.Read(body[:])
+	msgs, err := longpoll.UnmarshalMessages(body[:n])
+	if err != nil {
+		t.Fatalf("Invalid poll response %q: %v", body[:n], err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("Expected no message, got %d", len(msgs))
+	}
+}
@@ -0,0 +1,388 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// maintenanceCheckInterval is how often held commands are checked for release
+const maintenanceCheckInterval = 5 * time.Second
+
+// heldCommand is a command waiting for a maintenance window to open
+type heldCommand struct {
+	minionID string
+	command  *pb.Command
+	heldAt   time.Time
+}
+
+// MaintenanceScheduler holds commands for minions covered by maintenance windows
+// and releases them once one of their windows opens.
+// Windows and held commands are kept in memory and do not survive a restart.
+type MaintenanceScheduler struct {
+	mu       sync.Mutex
+	windows  map[string]*pb.MaintenanceWindow
+	held     []*heldCommand
+	registry MinionRegistry
+	dispatch func(minionID string, cmd *pb.Command) bool
+	interval time.Duration
+	now      func() time.Time
+	logger   *zap.Logger
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewMaintenanceScheduler creates a scheduler releasing commands through dispatch
+func NewMaintenanceScheduler(registry MinionRegistry, dispatch func(minionID string, cmd *pb.Command) bool, logger *zap.Logger) *MaintenanceScheduler {
+	return &MaintenanceScheduler{
+		windows:  make(map[string]*pb.MaintenanceWindow),
+		registry: registry,
+		dispatch: dispatch,
+		interval: maintenanceCheckInterval,
+		now:      time.Now,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the release loop
+func (m *MaintenanceScheduler) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				m.ReleaseDue()
+			}
+		}
+	}()
+}
+
+// Stop stops the release loop
+func (m *MaintenanceScheduler) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+	})
+	m.wg.Wait()
+}
+
+// AddWindow validates and registers a maintenance window, returning it with its assigned ID
+func (m *MaintenanceScheduler) AddWindow(window *pb.MaintenanceWindow) (*pb.MaintenanceWindow, error) {
+	hasMinion := window.MinionId != ""
+	hasSelector := window.TagSelector != nil && len(window.TagSelector.Rules) > 0
+	if hasMinion == hasSelector {
+		return nil, fmt.Errorf("a maintenance window must target either a minion or a tag selector")
+	}
+	if window.End <= window.Start {
+		return nil, fmt.Errorf("maintenance window end must be after its start")
+	}
+	if window.End <= m.now().Unix() {
+		return nil, fmt.Errorf("maintenance window is already over")
+	}
+
+	stored := proto.Clone(window).(*pb.MaintenanceWindow)
+	stored.Id = generateMinionID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.windows[stored.Id] = stored
+
+	return proto.Clone(stored).(*pb.MaintenanceWindow), nil
+}
+
+// RemoveWindow deletes a maintenance window
+func (m *MaintenanceScheduler) RemoveWindow(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.windows[id]; !exists {
+		return fmt.Errorf("maintenance window %s not found", id)
+	}
+	delete(m.windows, id)
+	return nil
+}
+
+// Window returns a maintenance window by ID
+func (m *MaintenanceScheduler) Window(id string) (*pb.MaintenanceWindow, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	window, exists := m.windows[id]
+	if !exists {
+		return nil, false
+	}
+	return proto.Clone(window).(*pb.MaintenanceWindow), true
+}
+
+// ListWindows returns the windows that are not over yet, ordered by start time
+func (m *MaintenanceScheduler) ListWindows() []*pb.MaintenanceWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneExpiredLocked()
+
+	windows := make([]*pb.MaintenanceWindow, 0, len(m.windows))
+	for _, window := range m.windows {
+		windows = append(windows, proto.Clone(window).(*pb.MaintenanceWindow))
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i].Start < windows[j].Start
+	})
+	return windows
+}
+
+// HeldCount returns the number of commands waiting for a window
+func (m *MaintenanceScheduler) HeldCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.held)
+}
+
+// HeldCountFor returns the number of commands waiting for a window for a minion
+func (m *MaintenanceScheduler) HeldCountFor(minionID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, held := range m.held {
+		if held.minionID == minionID {
+			count++
+		}
+	}
+	return count
+}
+
+// ShouldHold reports whether commands for the minion must wait for a maintenance window.
+// Minions without upcoming windows, or inside an open window, receive commands immediately.
+func (m *MaintenanceScheduler) ShouldHold(info *pb.HostInfo) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.shouldHoldLocked(info, m.now().Unix())
+}
+
+// shouldHoldLocked implements ShouldHold, the caller must hold m.mu
+func (m *MaintenanceScheduler) shouldHoldLocked(info *pb.HostInfo, now int64) bool {
+	covered := false
+	for _, window := range m.windows {
+		if window.End <= now || !windowCovers(window, info) {
+			continue
+		}
+		if window.Start <= now {
+			return false // window currently open
+		}
+		covered = true
+	}
+	return covered
+}
+
+// Hold queues a command until a maintenance window opens for the minion
+func (m *MaintenanceScheduler) Hold(minionID string, cmd *pb.Command) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.held = append(m.held, &heldCommand{
+		minionID: minionID,
+		command:  cmd,
+		heldAt:   m.now(),
+	})
+}
+
+// ReleaseDue dispatches the held commands whose minion is no longer covered by a future window.
+// They are dispatched without holding the lock, the ones not delivered being held again.
+func (m *MaintenanceScheduler) ReleaseDue() {
+	logger, start := logging.FuncLogger(m.logger, "MaintenanceScheduler.ReleaseDue")
+	defer logging.FuncExit(logger, start)
+
+	due := m.takeDue()
+	var undelivered []*heldCommand
+	for _, held := range due {
+		if !m.dispatch(held.minionID, held.command) {
+			undelivered = append(undelivered, held)
+			continue
+		}
+
+		logger.Info("Maintenance window open, held command released",
+			zap.String("command_id", held.command.Id),
+			zap.String("minion_id", held.minionID),
+			zap.Duration("held_for", m.now().Sub(held.heldAt)))
+	}
+	if len(undelivered) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.held = append(undelivered, m.held...)
+}
+
+// takeDue removes and returns the held commands whose connected minion is no longer covered by a future window
+func (m *MaintenanceScheduler) takeDue() []*heldCommand {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneExpiredLocked()
+	now := m.now().Unix()
+
+	var due []*heldCommand
+	remaining := m.held[:0]
+	for _, held := range m.held {
+		conn, exists := m.registry.GetConnection(held.minionID)
+		if !exists || m.shouldHoldLocked(conn.GetInfo(), now) {
+			remaining = append(remaining, held)
+			continue
+		}
+		due = append(due, held)
+	}
+	clear(m.held[len(remaining):])
+	m.held = remaining
+	return due
+}
+
+// pruneExpiredLocked removes windows that are over, the caller must hold m.mu
+func (m *MaintenanceScheduler) pruneExpiredLocked() {
+	now := m.now().Unix()
+	for id, window := range m.windows {
+		if window.End <= now {
+			delete(m.windows, id)
+		}
+	}
+}
+
+// windowCovers reports whether a maintenance window applies to the minion
+func windowCovers(window *pb.MaintenanceWindow, info *pb.HostInfo) bool {
+	if window.MinionId != "" {
+		return window.MinionId == info.Id
+	}
+	return MatchesTags(info, window.TagSelector)
+}
+
+// dispatchHeldCommand delivers a released command to a connected minion
+func (s *Server) dispatchHeldCommand(minionID string, cmd *pb.Command) bool {
+	conn, exists := s.GetMinionRegistryImpl().GetConnectionImpl(minionID)
+	if !exists {
+		return false
+	}
+
+	return conn.Commands.Push(cmd)
+}
+
+// heldTargets returns the targets for which the command would be held by a maintenance window
+func (s *Server) heldTargets(req *pb.CommandRequest, targets []string) []string {
+	if isEmergency(req) || s.maintenance == nil {
+		return nil
+	}
+
+	var held []string
+	for _, minionID := range targets {
+		if conn, exists := s.minionRegistry.GetConnection(minionID); exists && s.maintenance.ShouldHold(conn.GetInfo()) {
+			held = append(held, minionID)
+		}
+	}
+	return held
+}
+
+// AddMaintenanceWindow declares a maintenance window in the ConsoleService
+func (s *Server) AddMaintenanceWindow(ctx context.Context, req *pb.MaintenanceWindow) (*pb.MaintenanceWindow, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.AddMaintenanceWindow")
+	defer logging.FuncExit(logger, start)
+
+	if s.maintenance == nil {
+		return nil, status.Error(codes.Unavailable, "maintenance windows are not available")
+	}
+	if req.MinionId != "" {
+		if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+			return nil, err
+		}
+	} else if err := requireAllNamespaces(ctx, "a tag maintenance window"); err != nil {
+		return nil, err
+	}
+
+	window, err := s.maintenance.AddWindow(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	logger.Info("Maintenance window added",
+		zap.String("window_id", window.Id),
+		zap.String("minion_id", window.MinionId),
+		zap.Time("start", time.Unix(window.Start, 0)),
+		zap.Time("end", time.Unix(window.End, 0)))
+
+	return window, nil
+}
+
+// ListMaintenanceWindows returns the current and upcoming maintenance windows in the ConsoleService.
+// Namespace scoped consoles only see the windows and held commands of their minions.
+func (s *Server) ListMaintenanceWindows(ctx context.Context, empty *pb.Empty) (*pb.MaintenanceWindowList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListMaintenanceWindows")
+	defer logging.FuncExit(logger, start)
+
+	if s.maintenance == nil {
+		return &pb.MaintenanceWindowList{}, nil
+	}
+
+	scope := consoleScope(ctx)
+	if !scope.restricted() {
+		return &pb.MaintenanceWindowList{
+			Windows:      s.maintenance.ListWindows(),
+			HeldCommands: int32(s.maintenance.HeldCount()),
+		}, nil
+	}
+
+	list := &pb.MaintenanceWindowList{}
+	for _, window := range s.maintenance.ListWindows() {
+		if window.MinionId != "" && s.inScope(scope, []string{window.MinionId}) {
+			list.Windows = append(list.Windows, window)
+		}
+	}
+	for _, info := range s.minionRegistry.ListMinions() {
+		if scope.allows(info) {
+			list.HeldCommands += int32(s.maintenance.HeldCountFor(info.Id))
+		}
+	}
+	return list, nil
+}
+
+// RemoveMaintenanceWindow deletes a maintenance window in the ConsoleService.
+// Commands held only by this window are released on the next check.
+func (s *Server) RemoveMaintenanceWindow(ctx context.Context, req *pb.MaintenanceWindowRequest) (*pb.Ack, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.RemoveMaintenanceWindow")
+	defer logging.FuncExit(logger, start)
+
+	if s.maintenance == nil {
+		return &pb.Ack{Success: false}, status.Error(codes.Unavailable, "maintenance windows are not available")
+	}
+	if window, exists := s.maintenance.Window(req.Id); exists {
+		if window.MinionId != "" {
+			if err := s.checkMinionScope(ctx, window.MinionId); err != nil {
+				return &pb.Ack{Success: false}, status.Errorf(codes.NotFound, "maintenance window %s not found", req.Id)
+			}
+		} else if err := requireAllNamespaces(ctx, "removing a tag maintenance window"); err != nil {
+			return &pb.Ack{Success: false}, err
+		}
+	}
+
+	if err := s.maintenance.RemoveWindow(req.Id); err != nil {
+		return &pb.Ack{Success: false}, status.Error(codes.NotFound, err.Error())
+	}
+
+	logger.Info("Maintenance window removed", zap.String("window_id", req.Id))
+	return &pb.Ack{Success: true}, nil
+}
@@ -0,0 +1,228 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMaintenanceAddWindowValidation(t *testing.T) {
+	scheduler := NewMaintenanceScheduler(nil, nil, zap.NewNop())
+	now := time.Now().Unix()
+	selector := &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}}}
+
+	tests := []struct {
+		name    string
+		window  *pb.MaintenanceWindow
+		wantErr bool
+	}{
+		{"minion", &pb.MaintenanceWindow{MinionId: "minion-1", Start: now + 60, End: now + 120}, false},
+		{"tag", &pb.MaintenanceWindow{TagSelector: selector, Start: now + 60, End: now + 120}, false},
+		{"no_target", &pb.MaintenanceWindow{Start: now + 60, End: now + 120}, true},
+		{"both_targets", &pb.MaintenanceWindow{MinionId: "minion-1", TagSelector: selector, Start: now + 60, End: now + 120}, true},
+		{"end_before_start", &pb.MaintenanceWindow{MinionId: "minion-1", Start: now + 120, End: now + 60}, true},
+		{"already_over", &pb.MaintenanceWindow{MinionId: "minion-1", Start: now - 120, End: now - 60}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := scheduler.AddWindow(tt.window)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && window.Id == "" {
+				t.Error("Expected an ID to be assigned")
+			}
+		})
+	}
+
+	if windows := scheduler.ListWindows(); len(windows) != 2 {
+		t.Errorf("Expected 2 windows, got %d", len(windows))
+	}
+}
+
+func TestMaintenanceHoldAndRelease(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	for id, env := range map[string]string{"minion-1": "prod", "minion-2": "dev"} {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id, Tags: map[string]string{"env": env}},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(100),
+		}
+	}
+
+	now := time.Now()
+	server.maintenance = NewMaintenanceScheduler(registry, server.dispatchHeldCommand, zap.NewNop())
+	server.maintenance.now = func() time.Time { return now }
+
+	_, err := server.AddMaintenanceWindow(context.Background(), &pb.MaintenanceWindow{
+		TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}}},
+		Start:       now.Add(time.Hour).Unix(),
+		End:         now.Add(2 * time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("AddMaintenanceWindow failed: %v", err)
+	}
+
+	response, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+	})
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if !response.Accepted || len(response.HeldMinionIds) != 1 || response.HeldMinionIds[0] != "minion-1" {
+		t.Fatalf("Expected command held for minion-1, got %+v", response)
+	}
+	if registry.minions["minion-1"].Commands.Len() != 0 || registry.minions["minion-2"].Commands.Len() != 1 {
+		t.Fatal("Expected only minion-2 to receive the command immediately")
+	}
+
+	// Emergency commands bypass the window
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+		Emergency: true,
+	})
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if len(response.HeldMinionIds) != 0 || registry.minions["minion-1"].Commands.Len() != 1 {
+		t.Fatalf("Expected emergency command to be dispatched, got %+v", response)
+	}
+	registry.minions["minion-1"].Commands.Pop()
+
+	// Nothing is released before the window opens
+	server.maintenance.ReleaseDue()
+	if registry.minions["minion-1"].Commands.Len() != 0 {
+		t.Fatal("Held command released before the window opened")
+	}
+
+	list, _ := server.ListMaintenanceWindows(context.Background(), &pb.Empty{})
+	if len(list.Windows) != 1 || list.HeldCommands != 1 {
+		t.Fatalf("Unexpected window list: %+v", list)
+	}
+
+	now = now.Add(90 * time.Minute)
+	server.maintenance.ReleaseDue()
+	if registry.minions["minion-1"].Commands.Len() != 1 {
+		t.Fatal("Expected held command to be released once the window opened")
+	}
+	if server.maintenance.HeldCount() != 0 {
+		t.Errorf("Expected no held commands, got %d", server.maintenance.HeldCount())
+	}
+
+	// Commands sent while the window is open are dispatched immediately
+	response, _ = server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+	})
+	if len(response.HeldMinionIds) != 0 {
+		t.Errorf("Expected no hold inside an open window, got %v", response.HeldMinionIds)
+	}
+}
+
+func TestMaintenanceReleaseUnlocked(t *testing.T) {
+	registry := NewMinionRegistry(nil, zap.NewNop())
+	for _, id := range []string{"minion-1", "minion-2"} {
+		registry.minions[id] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: id}, LastSeen: time.Now(), Commands: NewCommandQueue(10)}
+	}
+
+	// The dispatch path may call back into the scheduler, and commands it does not deliver stay held
+	var scheduler *MaintenanceScheduler
+	dispatch := func(minionID string, cmd *pb.Command) bool {
+		scheduler.Hold("minion-3", &pb.Command{Id: "cmd-3"})
+		return minionID == "minion-1"
+	}
+	scheduler = NewMaintenanceScheduler(registry, dispatch, zap.NewNop())
+	scheduler.Hold("minion-1", &pb.Command{Id: "cmd-1"})
+	scheduler.Hold("minion-2", &pb.Command{Id: "cmd-2"})
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.ReleaseDue()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReleaseDue deadlocked dispatching with the lock held")
+	}
+	if scheduler.HeldCountFor("minion-1") != 0 || scheduler.HeldCountFor("minion-2") != 1 || scheduler.HeldCountFor("minion-3") != 2 {
+		t.Errorf("Expected the undelivered command held again, got %d held", scheduler.HeldCount())
+	}
+}
+
+func TestRemoveMaintenanceWindow(t *testing.T) {
+	server := createTestServer(nil)
+	server.maintenance = NewMaintenanceScheduler(server.minionRegistry, server.dispatchHeldCommand, zap.NewNop())
+
+	window, err := server.AddMaintenanceWindow(context.Background(), &pb.MaintenanceWindow{
+		MinionId: "minion-1",
+		Start:    time.Now().Add(time.Hour).Unix(),
+		End:      time.Now().Add(2 * time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("AddMaintenanceWindow failed: %v", err)
+	}
+
+	if _, err := server.RemoveMaintenanceWindow(context.Background(), &pb.MaintenanceWindowRequest{Id: window.Id}); err != nil {
+		t.Errorf("RemoveMaintenanceWindow failed: %v", err)
+	}
+	if _, err := server.RemoveMaintenanceWindow(context.Background(), &pb.MaintenanceWindowRequest{Id: window.Id}); err == nil {
+		t.Error("Expected error when removing an unknown window")
+	}
+}
+
+func TestMaintenanceWindowScoping(t *testing.T) {
+	server := createTestServer(nil)
+	server.maintenance = NewMaintenanceScheduler(server.minionRegistry, server.dispatchHeldCommand, zap.NewNop())
+	for id, ctx := range map[string]context.Context{
+		"minion-a": namespaceContext("team-a"),
+		"minion-b": namespaceContext("team-b"),
+	} {
+		if response, err := server.Register(ctx, &pb.HostInfo{Id: id}); err != nil || !response.Success {
+			t.Fatalf("Failed to register %s: %+v (%v)", id, response, err)
+		}
+	}
+
+	windows := make(map[string]string)
+	for _, window := range []*pb.MaintenanceWindow{
+		{MinionId: "minion-a"},
+		{MinionId: "minion-b"},
+		{TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}}}},
+	} {
+		window.Start = time.Now().Add(time.Hour).Unix()
+		window.End = time.Now().Add(2 * time.Hour).Unix()
+		added, err := server.AddMaintenanceWindow(context.Background(), window)
+		if err != nil {
+			t.Fatalf("AddMaintenanceWindow failed: %v", err)
+		}
+		windows[window.MinionId] = added.Id
+	}
+
+	// A namespace scoped console only sees and removes the windows of its minions
+	console := namespaceContext("team-a")
+	list, err := server.ListMaintenanceWindows(console, &pb.Empty{})
+	if err != nil || len(list.Windows) != 1 || list.Windows[0].MinionId != "minion-a" {
+		t.Errorf("Expected only the window of minion-a, got %v (%v)", list, err)
+	}
+	if _, err := server.RemoveMaintenanceWindow(console, &pb.MaintenanceWindowRequest{Id: windows["minion-b"]}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected the window of another namespace hidden, got %v", err)
+	}
+	if _, err := server.RemoveMaintenanceWindow(console, &pb.MaintenanceWindowRequest{Id: windows[""]}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected tag windows reserved to unrestricted consoles, got %v", err)
+	}
+	if _, err := server.RemoveMaintenanceWindow(console, &pb.MaintenanceWindowRequest{Id: windows["minion-a"]}); err != nil {
+		t.Errorf("RemoveMaintenanceWindow failed: %v", err)
+	}
+	if list, _ := server.ListMaintenanceWindows(context.Background(), &pb.Empty{}); len(list.Windows) != 2 {
+		t.Errorf("Expected the 2 other windows kept, got %v", list.Windows)
+	}
+}
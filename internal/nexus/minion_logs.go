@@ -0,0 +1,299 @@
+package nexus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Sizes of GetMinionLogs
+const (
+	defaultMinionLogsLimit = 100
+	maxMinionLogsLimit     = 1000
+)
+
+// Sizes of the stored log entries, bounded to the minion_logs columns so that a single entry
+// can't fail the insert of its whole batch
+const (
+	maxLogLevelSize   = 10
+	maxLogNameSize    = 255 // logger and caller
+	maxLogMessageSize = 64 << 10
+	maxLogFieldsSize  = 64 << 10
+)
+
+// minionLogPruneInterval is how often the logs older than the retention period are deleted
+const minionLogPruneInterval = time.Hour
+
+// minionLogLevels are the levels of the logs minions ship, lowest first
+var minionLogLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// levelsFrom returns the log levels at or above minLevel, every level when it is empty
+func levelsFrom(minLevel string) ([]string, error) {
+	if minLevel == "" {
+		return minionLogLevels, nil
+	}
+	level, err := zapcore.ParseLevel(minLevel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level '%s'", minLevel)
+	}
+	for i, name := range minionLogLevels {
+		if name == level.String() {
+			return minionLogLevels[i:], nil
+		}
+	}
+	return nil, fmt.Errorf("invalid log level '%s'", minLevel)
+}
+
+// StoreMinionLogs stores log entries shipped by a minion, redacting their secrets
+func (d *DatabaseServiceImpl) StoreMinionLogs(ctx context.Context, minionID string, entries []*pb.MinionLogEntry) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot store %d logs of minion %s", len(entries), minionID)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreMinionLogs")
+	defer logging.FuncExit(logger, start)
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin minion logs transaction: %v", err)
+	}
+	defer tx.Rollback() // Will be a no-op if transaction is committed
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO minion_logs (minion_id, timestamp, level, logger, message, caller, fields) VALUES ($1, $2, $3, $4, $5, $6, $7)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare minion logs insert: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		message, _ := d.redactor.Redact(entry.Message)
+		var fields sql.NullString
+		if entry.Fields != "" {
+			fields.String, _ = d.redactor.Redact(entry.Fields)
+			if fields.Valid = storableFields(fields.String); !fields.Valid {
+				logger.Warn("Dropping the fields of a minion log", zap.String("minion_id", minionID), zap.Int("size", len(fields.String)))
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, minionID, time.UnixMilli(entry.TimestampMs), logText(entry.Level, maxLogLevelSize),
+			nullIfEmpty(logText(entry.Logger, maxLogNameSize)), logText(message, maxLogMessageSize),
+			nullIfEmpty(logText(entry.Caller, maxLogNameSize)), fields); err != nil {
+			return fmt.Errorf("failed to store log of minion %s: %v", minionID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit minion logs: %v", err)
+	}
+
+	logger.Debug("Stored minion logs", zap.String("minion_id", minionID), zap.Int("count", len(entries)))
+	return nil
+}
+
+// logText returns value as valid UTF-8 without the NUL characters PostgreSQL text can't hold,
+// truncated to limit bytes
+func logText(value string, limit int) string {
+	value = strings.ReplaceAll(strings.ToValidUTF8(value, "\uFFFD"), "\x00", "")
+	return truncateUTF8(value, limit)
+}
+
+// storableFields reports whether the fields of a log entry fit the fields column: JSON of at most
+// maxLogFieldsSize bytes, without the NUL characters jsonb can't hold
+func storableFields(fields string) bool {
+	return len(fields) <= maxLogFieldsSize && json.Valid([]byte(fields)) && !strings.Contains(fields, `\u0000`)
+}
+
+// GetMinionLogs returns the last limit log entries of a minion at one of levels, logged since since,
+// most recent first
+func (d *DatabaseServiceImpl) GetMinionLogs(ctx context.Context, minionID string, levels []string, since time.Time, limit int) ([]*pb.MinionLogEntry, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get logs of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetMinionLogs")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT (EXTRACT(EPOCH FROM timestamp) * 1000)::bigint, level, COALESCE(logger, ''), message,
+			COALESCE(caller, ''), COALESCE(fields::text, '')
+		FROM minion_logs
+		WHERE minion_id = $1 AND level = ANY($2) AND timestamp >= $3
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $4`,
+		minionID, pq.Array(levels), since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query minion logs: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*pb.MinionLogEntry
+	for rows.Next() {
+		var entry pb.MinionLogEntry
+		if err := rows.Scan(&entry.TimestampMs, &entry.Level, &entry.Logger, &entry.Message, &entry.Caller, &entry.Fields); err != nil {
+			return nil, fmt.Errorf("failed to scan minion logs: %v", err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading minion logs: %v", err)
+	}
+
+	logger.Debug("Retrieved minion logs",
+		zap.String("minion_id", minionID),
+		zap.Int("count", len(entries)))
+	return entries, nil
+}
+
+// PruneMinionLogs deletes the minion logs older than cutoff and returns their number
+func (d *DatabaseServiceImpl) PruneMinionLogs(ctx context.Context, cutoff time.Time) (int64, error) {
+	if d == nil || d.db == nil {
+		return 0, fmt.Errorf("database service unavailable - cannot prune minion logs")
+	}
+
+	res, err := d.db.ExecContext(ctx, "DELETE FROM minion_logs WHERE timestamp < $1", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune minion logs: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// storeMinionLogs stores the logs shipped by a minion on its command stream
+func (s *Server) storeMinionLogs(ctx context.Context, minionID string, batch *pb.MinionLogBatch, logger *zap.Logger) {
+	if batch.Dropped > 0 {
+		logger.Warn("Minion dropped logs, its buffer being full",
+			zap.String("minion_id", minionID),
+			zap.Int64("dropped", batch.Dropped))
+	}
+	if s.dbService == nil {
+		logger.Debug("Minion logs not stored without database",
+			zap.String("minion_id", minionID),
+			zap.Int("count", len(batch.Entries)))
+		return
+	}
+	if err := s.dbService.StoreMinionLogs(ctx, minionID, batch.Entries); err != nil {
+		logger.Error("Failed to store minion logs", zap.String("minion_id", minionID), zap.Error(err))
+	}
+}
+
+// GetMinionLogs returns the logs shipped by a minion
+func (s *Server) GetMinionLogs(ctx context.Context, req *pb.MinionLogsRequest) (*pb.MinionLogs, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetMinionLogs")
+	defer logging.FuncExit(logger, start)
+
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "minion logs require a database")
+	}
+	if consoleScope(ctx).restricted() {
+		if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+			return nil, err
+		}
+	}
+
+	levels, err := levelsFrom(req.MinLevel)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultMinionLogsLimit
+	}
+	if limit > maxMinionLogsLimit {
+		limit = maxMinionLogsLimit
+	}
+
+	entries, err := s.dbService.GetMinionLogs(ctx, req.MinionId, levels, time.Unix(req.Since, 0), limit)
+	if err != nil {
+		logger.Error("Failed to get minion logs", zap.String("minion_id", req.MinionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get minion logs")
+	}
+	return &pb.MinionLogs{MinionId: req.MinionId, Entries: entries}, nil
+}
+
+// MinionLogPruner periodically deletes the minion logs older than a retention period
+type MinionLogPruner struct {
+	db        DatabaseService
+	retention time.Duration
+	logger    *zap.Logger
+	done      chan struct{}
+	wg        sync.WaitGroup
+	stopOnce  sync.Once
+}
+
+// NewMinionLogPruner creates a pruner deleting the minion logs older than retention
+func NewMinionLogPruner(db DatabaseService, retention time.Duration, logger *zap.Logger) *MinionLogPruner {
+	return &MinionLogPruner{
+		db:        db,
+		retention: retention,
+		logger:    logger,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start launches the pruning loop, pruning right away
+func (p *MinionLogPruner) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(minionLogPruneInterval)
+		defer ticker.Stop()
+
+		for {
+			p.PruneOnce(context.Background())
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the pruning loop
+func (p *MinionLogPruner) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+	p.wg.Wait()
+}
+
+// PruneOnce deletes the minion logs older than the retention period and returns their number
+func (p *MinionLogPruner) PruneOnce(ctx context.Context) int64 {
+	pruned, err := p.db.PruneMinionLogs(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		p.logger.Error("Failed to prune minion logs", zap.Error(err))
+		return 0
+	}
+	if pruned > 0 {
+		p.logger.Info("Pruned minion logs", zap.Int64("entries", pruned), zap.Duration("retention", p.retention))
+	}
+	return pruned
+}
+
+// EnableMinionLogRetention starts deleting the logs shipped by minions once older than retention
+func (s *Server) EnableMinionLogRetention(retention time.Duration) error {
+	if s.dbService == nil {
+		return fmt.Errorf("minion log retention requires a database")
+	}
+	s.logPruner = NewMinionLogPruner(s.dbService, retention, s.logger)
+	s.logPruner.Start()
+	return nil
+}
@@ -0,0 +1,150 @@
+package nexus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLevelsFrom(t *testing.T) {
+	tests := []struct {
+		minLevel string
+		expected int
+		wantErr  bool
+	}{
+		{"", len(minionLogLevels), false},
+		{"debug", len(minionLogLevels), false},
+		{"warn", 5, false},
+		{"ERROR", 4, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		levels, err := levelsFrom(tt.minLevel)
+		if (err != nil) != tt.wantErr || len(levels) != tt.expected {
+			t.Errorf("levelsFrom(%q) = %v, %v", tt.minLevel, levels, err)
+		}
+	}
+}
+
+func TestStoreMinionLogs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	entries := []*pb.MinionLogEntry{
+		{TimestampMs: 1700000000000, Level: "warn", Message: "Stream timeout"},
+		{TimestampMs: 1700000001000, Level: "error", Logger: "minion", Message: "Command failed", Caller: "minion/processor.go:120", Fields: `{"command_id":"cmd-1"}`},
+		// Oversized or unstorable values are bounded instead of failing the batch
+		{TimestampMs: 1700000002000, Level: "info", Logger: strings.Repeat("l", 300), Message: "nul\x00" + strings.Repeat("m", maxLogMessageSize), Fields: `{"output":`},
+	}
+
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare("INSERT INTO minion_logs")
+	prepared.ExpectExec().
+		WithArgs("minion-1", time.UnixMilli(1700000000000), "warn", nil, "Stream timeout", nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	prepared.ExpectExec().
+		WithArgs("minion-1", time.UnixMilli(1700000001000), "error", "minion", "Command failed", "minion/processor.go:120", `{"command_id":"cmd-1"}`).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	prepared.ExpectExec().
+		WithArgs("minion-1", time.UnixMilli(1700000002000), "info", strings.Repeat("l", maxLogNameSize), "nul"+strings.Repeat("m", maxLogMessageSize-3), nil, nil).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	server.storeMinionLogs(context.Background(), "minion-1", &pb.MinionLogBatch{Entries: entries, Dropped: 3}, zap.NewNop())
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	// Without database the logs are dropped
+	createTestServer(nil).storeMinionLogs(context.Background(), "minion-1", &pb.MinionLogBatch{Entries: entries}, zap.NewNop())
+}
+
+func TestGetMinionLogs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	columns := []string{"timestamp", "level", "logger", "message", "caller", "fields"}
+
+	mock.ExpectQuery("FROM minion_logs").
+		WithArgs("minion-1", sqlmock.AnyArg(), time.Unix(1700000000, 0), defaultMinionLogsLimit).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(int64(1700000001000), "error", "minion", "Command failed", "minion/processor.go:120", `{"command_id":"cmd-1"}`).
+			AddRow(int64(1700000000000), "warn", "", "Stream timeout", "", ""))
+
+	logs, err := server.GetMinionLogs(context.Background(), &pb.MinionLogsRequest{MinionId: "minion-1", MinLevel: "warn", Since: 1700000000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(logs.Entries) != 2 || logs.Entries[0].Level != "error" || logs.Entries[0].Fields != `{"command_id":"cmd-1"}` {
+		t.Errorf("Unexpected logs: %v", logs.Entries)
+	}
+
+	// The limit is capped
+	mock.ExpectQuery("FROM minion_logs").
+		WithArgs("minion-1", sqlmock.AnyArg(), sqlmock.AnyArg(), maxMinionLogsLimit).
+		WillReturnRows(sqlmock.NewRows(columns))
+	if _, err := server.GetMinionLogs(context.Background(), &pb.MinionLogsRequest{MinionId: "minion-1", Limit: 100000}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := server.GetMinionLogs(context.Background(), &pb.MinionLogsRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+	if _, err := server.GetMinionLogs(context.Background(), &pb.MinionLogsRequest{MinionId: "minion-1", MinLevel: "verbose"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an invalid level, got %v", err)
+	}
+	if _, err := createTestServer(nil).GetMinionLogs(context.Background(), &pb.MinionLogsRequest{MinionId: "minion-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
+
+func TestMinionLogPruner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	server.SetAdmins([]string{"alice"})
+	server.logPruner = NewMinionLogPruner(server.dbService, 7*24*time.Hour, zap.NewNop())
+
+	mock.ExpectExec("DELETE FROM minion_logs WHERE timestamp < \\$1").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 42))
+
+	// Pruning the database prunes the minion logs without result archival
+	resp, err := server.PruneDatabase(identityContext("alice"), &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.PrunedLogs != 42 || resp.ArchivedCommands != 0 {
+		t.Errorf("Expected 42 pruned logs, got %v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if err := createTestServer(nil).EnableMinionLogRetention(time.Hour); err == nil {
+		t.Error("Expected an error enabling retention without database")
+	}
+}
@@ -0,0 +1,168 @@
+package nexus
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"sort"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultNamespace is the namespace of the minions registering without one
+const DefaultNamespace = "default"
+
+// namespaceScope is the set of namespaces a console may act on, nil allowing all of them
+type namespaceScope map[string]bool
+
+// peerCertificate returns the verified client certificate of the peer in ctx, nil when the peer
+// presented no certificate
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return tlsInfo.State.VerifiedChains[0][0]
+}
+
+// certificateNamespaces returns the organizational units of the verified client certificate
+// of the peer in ctx, nil when the peer presented no certificate
+func certificateNamespaces(ctx context.Context) []string {
+	if cert := peerCertificate(ctx); cert != nil {
+		return cert.Subject.OrganizationalUnit
+	}
+	return nil
+}
+
+// consoleIdentity returns the identity of the console calling in ctx: the name it authenticated
+// with, or the common name of its certificate, empty when it presented neither
+func consoleIdentity(ctx context.Context) string {
+	if identity, ok := authenticatedConsole(ctx); ok {
+		return identity.Name
+	}
+	if cert := peerCertificate(ctx); cert != nil {
+		return cert.Subject.CommonName
+	}
+	return ""
+}
+
+// consoleScope returns the namespaces the console calling in ctx is restricted to: the namespaces it
+// authenticated with, or the organizational units of its certificate. Certificates without
+// organizational unit administer every namespace, authenticated consoles only when granted all.
+func consoleScope(ctx context.Context) namespaceScope {
+	units := certificateNamespaces(ctx)
+	if identity, ok := authenticatedConsole(ctx); ok {
+		if identity.AllNamespaces {
+			return nil
+		}
+		// An authenticated console without namespaces administers none
+		units = identity.Namespaces
+	} else if len(units) == 0 {
+		return nil
+	}
+	scope := make(namespaceScope, len(units))
+	for _, unit := range units {
+		scope[unit] = true
+	}
+	return scope
+}
+
+// restricted reports whether the scope excludes some namespaces
+func (ns namespaceScope) restricted() bool {
+	return ns != nil
+}
+
+// allows reports whether a minion belongs to a namespace of the scope
+func (ns namespaceScope) allows(info *pb.HostInfo) bool {
+	return ns == nil || ns[minionNamespace(info)]
+}
+
+// minionNamespace returns the namespace of a minion
+func minionNamespace(info *pb.HostInfo) string {
+	if info.Namespace == "" {
+		return DefaultNamespace
+	}
+	return info.Namespace
+}
+
+// registrationNamespace returns the namespace a minion registers into. The organizational units of
+// its certificate, when it has some, are the namespaces it may join, the first being the default.
+func registrationNamespace(ctx context.Context, requested string) (string, error) {
+	units := certificateNamespaces(ctx)
+	if len(units) == 0 {
+		if requested == "" {
+			return DefaultNamespace, nil
+		}
+		return requested, nil
+	}
+	if requested == "" {
+		return units[0], nil
+	}
+	for _, unit := range units {
+		if unit == requested {
+			return requested, nil
+		}
+	}
+	return "", fmt.Errorf("namespace %s is not allowed by the minion certificate", requested)
+}
+
+// scopedMinionIDs keeps the minions of ids belonging to the scope
+func (s *Server) scopedMinionIDs(scope namespaceScope, ids []string) []string {
+	if !scope.restricted() {
+		return ids
+	}
+	scoped := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if conn, exists := s.minionRegistry.GetConnection(id); exists && scope.allows(conn.GetInfo()) {
+			scoped = append(scoped, id)
+		}
+	}
+	return scoped
+}
+
+// checkMinionScope returns a NotFound error when the console calling in ctx may not act on a minion,
+// hiding the minions of the other namespaces
+func (s *Server) checkMinionScope(ctx context.Context, minionID string) error {
+	scope := consoleScope(ctx)
+	if len(s.scopedMinionIDs(scope, []string{minionID})) == 0 {
+		return status.Errorf(codes.NotFound, "minion %s not found", minionID)
+	}
+	return nil
+}
+
+// requireAllNamespaces rejects the operations spanning every namespace for namespace scoped consoles
+func requireAllNamespaces(ctx context.Context, operation string) error {
+	if consoleScope(ctx).restricted() {
+		return status.Errorf(codes.PermissionDenied, "%s requires a console certificate without namespace restriction", operation)
+	}
+	return nil
+}
+
+// scopedTags returns the tags of the minions of the scope, formatted as ListTags does
+func (s *Server) scopedTags(scope namespaceScope) []string {
+	tagSet := make(map[string]bool)
+	for _, info := range s.minionRegistry.ListMinions() {
+		if !scope.allows(info) {
+			continue
+		}
+		for key, value := range info.Tags {
+			tagSet[fmt.Sprintf("%s:%s", key, value)] = true
+		}
+	}
+
+	tags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,124 @@
+package nexus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// namespaceContext returns a context whose peer presented a verified certificate with the given organizational units
+func namespaceContext(units ...string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: units}}
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+	})
+}
+
+func TestRegistrationNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested string
+		expected  string
+		wantErr   bool
+	}{
+		{"no certificate, no request", context.Background(), "", DefaultNamespace, false},
+		{"no certificate, requested", context.Background(), "team-a", "team-a", false},
+		{"certificate default", namespaceContext("team-a", "team-b"), "", "team-a", false},
+		{"certificate allows", namespaceContext("team-a", "team-b"), "team-b", "team-b", false},
+		{"certificate denies", namespaceContext("team-a"), "team-b", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, err := registrationNamespace(tt.ctx, tt.requested)
+			if (err != nil) != tt.wantErr || namespace != tt.expected {
+				t.Errorf("Expected %q (error %v), got %q (%v)", tt.expected, tt.wantErr, namespace, err)
+			}
+		})
+	}
+}
+
+func TestNamespaceScoping(t *testing.T) {
+	server := createTestServer(nil)
+
+	for id, ctx := range map[string]context.Context{
+		"minion-a": namespaceContext("team-a"),
+		"minion-b": namespaceContext("team-b"),
+		"minion-d": context.Background(),
+	} {
+		response, err := server.Register(ctx, &pb.HostInfo{Id: id, Tags: map[string]string{"owner": id}})
+		if err != nil || !response.Success {
+			t.Fatalf("Failed to register %s: %+v (%v)", id, response, err)
+		}
+	}
+
+	// A minion ID can't move to another namespace
+	response, err := server.Register(namespaceContext("team-b"), &pb.HostInfo{Id: "minion-a"})
+	if err != nil || response.Success {
+		t.Errorf("Expected registration into another namespace to be rejected, got %+v (%v)", response, err)
+	}
+	response, _ = server.Register(context.Background(), &pb.HostInfo{Id: "minion-x", Namespace: "team-a"})
+	if !response.Success {
+		t.Errorf("Expected registration without certificate unit to honour the requested namespace")
+	}
+
+	console := namespaceContext("team-a")
+	minions, err := server.ListMinions(console, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("ListMinions failed: %v", err)
+	}
+	if len(minions.Minions) != 2 {
+		t.Errorf("Expected the 2 minions of team-a, got %+v", minions.Minions)
+	}
+	for _, info := range minions.Minions {
+		if info.Namespace != "team-a" {
+			t.Errorf("Unexpected minion %s in namespace %s", info.Id, info.Namespace)
+		}
+	}
+	minions, _ = server.ListMinions(context.Background(), &pb.Empty{})
+	if len(minions.Minions) != 4 {
+		t.Errorf("Expected unrestricted console to list all minions, got %d", len(minions.Minions))
+	}
+
+	tags, _ := server.ListTags(console, &pb.Empty{})
+	if len(tags.Tags) != 1 || tags.Tags[0] != "owner:minion-a" {
+		t.Errorf("Expected only the tags of team-a minions, got %v", tags.Tags)
+	}
+
+	dispatch, err := server.SendCommand(console, &pb.CommandRequest{
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "system:info"},
+	})
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if len(dispatch.TargetMinionIds) != 2 {
+		t.Errorf("Expected command sent to the 2 minions of team-a, got %v", dispatch.TargetMinionIds)
+	}
+	dispatch, _ = server.SendCommand(console, &pb.CommandRequest{
+		MinionIds: []string{"minion-b"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "system:info"},
+	})
+	if dispatch.Accepted {
+		t.Errorf("Expected command to another namespace to be rejected, got %+v", dispatch)
+	}
+
+	_, err = server.SetTags(console, &pb.SetTagsRequest{MinionId: "minion-b", Tags: map[string]string{"x": "y"}})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound tagging a minion of another namespace, got %v", err)
+	}
+	if err := requireAllNamespaces(console, "reports"); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a scoped console, got %v", err)
+	}
+	if err := requireAllNamespaces(context.Background(), "reports"); err != nil {
+		t.Errorf("Expected unrestricted console to be allowed, got %v", err)
+	}
+}
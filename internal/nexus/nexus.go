@@ -8,12 +8,16 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/arhuman/minexus/internal/certs"
 	"github.com/arhuman/minexus/internal/command"
 	"github.com/arhuman/minexus/internal/logging"
+	"github.com/arhuman/minexus/internal/version"
 	pb "github.com/arhuman/minexus/protogen"
 
 	_ "github.com/lib/pq"
@@ -23,24 +27,75 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// Server represents the core Nexus server that implements both MinionService and ConsoleService
-// gRPC interfaces. It orchestrates operations between the database service and minion registry
-// to provide distributed command execution capabilities for the Minexus system.
+// Server represents the core Nexus server that implements the MinionService, ConsoleService and
+// AdminService gRPC interfaces. It orchestrates operations between the database service and minion
+// registry to provide distributed command execution capabilities for the Minexus system.
 type Server struct {
 	pb.UnimplementedMinionServiceServer
 	pb.UnimplementedConsoleServiceServer
+	pb.UnimplementedAdminServiceServer
 
 	logger          *zap.Logger
 	dbService       DatabaseService
 	minionRegistry  MinionRegistry
 	pendingCommands map[string]*CommandTracker
+	pendingMu       sync.Mutex
 	commandRegistry *command.Registry
+	notifier        *WebhookNotifier
+	redactor        *Redactor // redacts secrets from notifications, like from stored commands and results
+	maintenance     *MaintenanceScheduler
+	diagnostics     *DiagnosticsTracker
+	streamMonitor   *StreamMonitor     // nil unless dead stream detection is enabled
+	confirmations   *ConfirmationGuard // nil: no command requires confirmation
+	approvals       *ApprovalGate      // nil: no command requires approval
+	shells          shellSessions
+	transfers       fileTransfers
+	longPolls       longPollSessions
+	chunks          resultChunks // results split in chunks by minions, being reassembled
+	retired         retirements  // retired minions refused at registration
+	locks           hostLocks
+	drain           drainState
+	archiver        *ResultArchiver       // nil unless result archival is enabled
+	logPruner       *MinionLogPruner      // nil unless minion log retention is enabled
+	tagSchema       *TagSchema            // nil: every tag is accepted
+	bootstrap       *BootstrapProvisioner // nil unless minion bootstrap is enabled
+	dbQueries       map[string]*DatabaseQuery
+	dispatchRates   *DispatchRates         // nil: commands reach all their targets at once
+	hosts           *hostBuffer            // nil without database
+	admins          []string               // console identities allowed to use the AdminService
+	consoleAuth     []ConsoleAuthenticator // authenticate consoles in turn, nil: client certificates only
+	logLevel        *zap.AtomicLevel       // nil: the log level can't be changed at runtime
+	logSampler      *logging.Sampler       // nil: the log sampling can't be changed at runtime
+	identities      *identityBindings      // nil: minion IDs aren't bound to their certificate
+	admission       *AdmissionController   // nil: Nexus never sheds load
+	commandEnv      commandEnv
+	versions        versionPolicy        // minimum protocol version of the minions, 0 accepting all
+	receiptSigner   *certs.CommandSigner // nil: execution receipts are signed with the server certificate
+	maxMsgSize      int                  // announced to minions splitting larger results, 0: results are never split
 }
 
+// pendingCommandTTL is how long a dispatched command is tracked while waiting for results
+const pendingCommandTTL = 24 * time.Hour
+
+// resultAckBufferSize is the number of result acknowledgements waiting for the dispatch loop
+// of a stream before the receipt of the next results waits
+const resultAckBufferSize = 64
+
+// maxResultPageSize bounds the number of results returned by a GetCommandResults page
+const maxResultPageSize = 1000
+
 // CommandTracker tracks the execution status and results of commands sent to minions.
 // It maintains state information for distributed command execution across the system.
 type CommandTracker struct {
-	// Add fields as needed
+	Payload   string          // redacted command payload
+	Pending   map[string]bool // minion IDs that have not reported a result yet
+	CreatedAt time.Time
+
+	Retry    *pb.CommandRequest       // request dispatched again to the targets whose result failed, nil: no retry
+	Attempts map[string]*retryAttempt // minion ID -> attempt, for retried commands
+
+	Sample int32 // successful results stored in full, the others only counted, 0: all stored
+	Stored int32 // successful results stored in full so far, for sampled commands
 }
 
 // NewServer creates and initializes a new Nexus server instance with the specified
@@ -53,6 +108,7 @@ func NewServer(dbConnectionString string, logger *zap.Logger) (*Server, error) {
 	defer logging.FuncExit(logger, start)
 
 	var dbService DatabaseService
+	var db *sql.DB
 
 	// DIAGNOSIS: Log database connection attempt details
 	logger.Info("DIAGNOSIS: Database service initialization",
@@ -64,7 +120,8 @@ func NewServer(dbConnectionString string, logger *zap.Logger) (*Server, error) {
 		logger.Info("DIAGNOSIS: Attempting to create database connection",
 			zap.String("connection_string", dbConnectionString))
 
-		db, err := sql.Open("postgres", dbConnectionString)
+		var err error
+		db, err = sql.Open("postgres", dbConnectionString)
 		if err != nil {
 			logger.Error("DIAGNOSIS: Failed to create database connection - database service will be nil",
 				zap.String("connection_string", dbConnectionString),
@@ -72,17 +129,8 @@ func NewServer(dbConnectionString string, logger *zap.Logger) (*Server, error) {
 			return nil, err
 		}
 
-		// Test the database connection but don't fail server creation
-		// This allows graceful degradation when database is unavailable
-		if err := db.Ping(); err != nil {
-			logger.Warn("DIAGNOSIS: Database ping failed - operations will degrade gracefully",
-				zap.String("connection_string", dbConnectionString),
-				zap.Error(err))
-			// Still set the database connection; individual operations will handle errors
-		} else {
-			logger.Info("DIAGNOSIS: Database connection successful")
-		}
-
+		// The database is reached in the background so that a slow or down Postgres
+		// doesn't delay minion registrations, see connectDatabase
 		dbService = NewDatabaseService(db, logger)
 		logger.Info("DIAGNOSIS: Database service created successfully")
 	} else {
@@ -95,6 +143,13 @@ func NewServer(dbConnectionString string, logger *zap.Logger) (*Server, error) {
 		dbServiceImpl = dbService.(*DatabaseServiceImpl)
 	}
 	minionRegistry := NewMinionRegistry(dbServiceImpl, logger)
+	if db != nil {
+		minionRegistry.hosts = newHostBuffer()
+	}
+	redactor := NewRedactor(DefaultRedactionPatterns())
+	if dbServiceImpl != nil {
+		redactor = dbServiceImpl.redactor
+	}
 
 	// Create the server instance with extracted services
 	s := &Server{
@@ -103,7 +158,17 @@ func NewServer(dbConnectionString string, logger *zap.Logger) (*Server, error) {
 		minionRegistry:  minionRegistry,
 		pendingCommands: make(map[string]*CommandTracker),
 		commandRegistry: command.SetupCommands(15 * time.Second), // Default timeout for nexus command registry
+		redactor:        redactor,
+		diagnostics:     NewDiagnosticsTracker(),
+		confirmations:   NewConfirmationGuard(DefaultDestructivePatterns()),
+		hosts:           minionRegistry.hosts,
+		identities:      newIdentityBindings(),
+	}
+	if db != nil {
+		go s.connectDatabase(db, s.hosts)
 	}
+	s.maintenance = NewMaintenanceScheduler(minionRegistry, s.dispatchHeldCommand, logger)
+	s.maintenance.Start()
 
 	// DIAGNOSIS: Log final server state
 	logger.Info("DIAGNOSIS: Server created with database service state",
@@ -111,6 +176,11 @@ func NewServer(dbConnectionString string, logger *zap.Logger) (*Server, error) {
 	return s, nil
 }
 
+// SetNotifier configures the notifier receiving command completion events
+func (s *Server) SetNotifier(notifier *WebhookNotifier) {
+	s.notifier = notifier
+}
+
 // Shutdown gracefully shuts down the Nexus server, closing database connections
 // and cleaning up resources. This method should be called when the server is
 // being terminated to ensure proper cleanup.
@@ -118,6 +188,25 @@ func (s *Server) Shutdown() {
 	logger, start := logging.FuncLogger(s.logger, "Server.Shutdown")
 	defer logging.FuncExit(logger, start)
 
+	if s.maintenance != nil {
+		s.maintenance.Stop()
+	}
+	if s.streamMonitor != nil {
+		s.streamMonitor.Stop()
+	}
+	if s.archiver != nil {
+		s.archiver.Stop()
+	}
+	if s.logPruner != nil {
+		s.logPruner.Stop()
+	}
+	if s.admission != nil {
+		s.admission.Stop()
+	}
+	if s.hosts != nil {
+		s.hosts.close()
+	}
+
 	// Database cleanup is handled by the database service internally
 	// No direct cleanup needed for the registry
 	logger.Debug("Server shutdown completed")
@@ -137,6 +226,11 @@ func (s *Server) Register(ctx context.Context, hostInfo *pb.HostInfo) (*pb.Regis
 	logger, start := logging.FuncLogger(s.logger, "nexus.Server.Register")
 	defer logging.FuncExit(logger, start)
 
+	if s.drain.isDraining() {
+		logger.Info("Registration rejected while draining", zap.String("host_id", hostInfo.Id))
+		return &pb.RegisterResponse{Success: false, ErrorMessage: "Nexus is shutting down, retry later"}, nil
+	}
+
 	// Use provided ID if available, otherwise generate a new one
 	var minionID string
 	if hostInfo.Id != "" {
@@ -148,7 +242,61 @@ func (s *Server) Register(ctx context.Context, hostInfo *pb.HostInfo) (*pb.Regis
 	// Update the hostInfo with the final ID
 	hostInfo.Id = minionID
 
-	logger.Debug("Registering minion", zap.String("host_id", hostInfo.Id))
+	if notice := s.retired.pending(minionID, time.Now()); notice != nil {
+		logger.Warn("Registration rejected, minion retired", zap.String("host_id", minionID))
+		s.notifyRetired(minionID, logger)
+		return &pb.RegisterResponse{
+			Success:      false,
+			ErrorMessage: fmt.Sprintf("minion %s is retired", minionID),
+			Retire:       notice,
+		}, nil
+	}
+
+	namespace, err := registrationNamespace(ctx, hostInfo.Namespace)
+	if err != nil {
+		logger.Warn("Registration rejected", zap.String("host_id", hostInfo.Id), zap.Error(err))
+		return &pb.RegisterResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+	hostInfo.Namespace = namespace
+
+	if err := s.checkMinionIdentity(ctx, hostInfo.Id); err != nil {
+		// The minion retries registrations failing while its identity can't be verified
+		if status.Code(err) == codes.Unavailable {
+			return nil, err
+		}
+		logger.Warn("Registration rejected", zap.String("host_id", hostInfo.Id), zap.Error(err))
+		return &pb.RegisterResponse{Success: false, ErrorMessage: status.Convert(err).Message()}, nil
+	}
+
+	if err := s.versions.check(hostInfo); err != nil {
+		logger.Warn("Registration rejected", zap.String("host_id", hostInfo.Id), zap.String("version", hostInfo.Version), zap.Error(err))
+		return &pb.RegisterResponse{Success: false, ErrorMessage: err.Error(), ProtocolVersion: version.ProtocolVersion}, nil
+	}
+
+	// Crash reports are stored apart rather than kept with the registration
+	crashes := hostInfo.Crashes
+	hostInfo.Crashes = nil
+
+	logger.Debug("Registering minion",
+		zap.String("host_id", hostInfo.Id),
+		zap.String("namespace", hostInfo.Namespace))
+
+	// Keep the previous registration to detect environment changes
+	var previous *pb.HostInfo
+	if conn, exists := s.minionRegistry.GetConnection(hostInfo.Id); exists {
+		previous = conn.GetInfo()
+		// A minion ID can't be taken over from another namespace
+		if minionNamespace(previous) != hostInfo.Namespace {
+			logger.Warn("Registration rejected, minion ID registered in another namespace",
+				zap.String("host_id", hostInfo.Id),
+				zap.String("namespace", hostInfo.Namespace),
+				zap.String("registered_namespace", minionNamespace(previous)))
+			return &pb.RegisterResponse{
+				Success:      false,
+				ErrorMessage: fmt.Sprintf("minion ID %s is already registered in another namespace", hostInfo.Id),
+			}, nil
+		}
+	}
 
 	// Register minion using the extracted registry
 	resp, err := s.minionRegistry.Register(hostInfo)
@@ -165,11 +313,29 @@ func (s *Server) Register(ctx context.Context, hostInfo *pb.HostInfo) (*pb.Regis
 	} else {
 		logger.Info("Minion registered successfully",
 			zap.String("host_id", hostInfo.Id))
+		resp.ResultAcks = true
+		resp.ProtocolVersion = version.ProtocolVersion
+		resp.MaxMsgSize = int32(s.maxMsgSize)
+		resp.CrashAcks = true
+		resp.CrashesStored = true
+		s.diagnostics.RecordRegistration(hostInfo.Id)
+		if change := detectHostChange(previous, hostInfo); change != nil {
+			s.recordHostChange(ctx, change, hostInfo.Tags)
+		}
+		if len(crashes) > 0 {
+			resp.CrashesStored = s.recordCrashes(ctx, hostInfo.Id, crashes, logger)
+		}
 	}
 
 	return resp, nil
 }
 
+// SetInFlightLimit limits the number of commands dispatched to each minion without result.
+// Excess commands wait in the minion command queue, 0 means unlimited.
+func (s *Server) SetInFlightLimit(limit int) {
+	s.GetMinionRegistryImpl().SetInFlightLimit(limit)
+}
+
 // GetMinionIDFromContext extracts the minion ID from gRPC metadata.
 func GetMinionIDFromContext(ctx context.Context) string {
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -197,19 +363,42 @@ func (s *Server) StreamCommands(stream pb.MinionService_StreamCommandsServer) er
 	if err != nil {
 		return err
 	}
+	if err := s.checkMinionIdentity(stream.Context(), minionID); err != nil {
+		return err
+	}
 
 	// Find minion connection with retry logic
 	conn, err := s.findMinionConnectionWithRetry(minionID, logger, start)
 	if err != nil {
+		s.diagnostics.RecordError(minionID, err)
 		return err
 	}
 
 	// Setup connection and start message handling
 	s.setupConnection(minionID, logger)
-	errCh := s.startMessageReceiver(stream, logger)
+	minionRegistryImpl := s.minionRegistry.(*MinionRegistryImpl)
+	dead := minionRegistryImpl.OpenStream(minionID)
+	defer minionRegistryImpl.CloseStream(minionID, dead)
+	s.diagnostics.RecordStreamOpened(minionID)
+	s.recordConnectionEvent(minionID, ConnectionEventConnect, nil)
+	errCh, acks := s.startMessageReceiver(stream, minionID, logger)
 
 	// Run main command dispatch loop
-	return s.runCommandDispatchLoop(stream, conn, errCh, minionID, logger)
+	err = s.runCommandDispatchLoop(stream, conn, errCh, acks, dead, minionID, logger)
+	s.diagnostics.RecordStreamClosed(minionID, err)
+	s.recordConnectionEvent(minionID, ConnectionEventDisconnect, disconnectReason(conn.GetInfo(), err))
+	s.shells.closeMinion(minionID)
+	s.transfers.closeMinion(minionID)
+	return err
+}
+
+// disconnectReason returns why the command stream of a minion ended: the power action it announced
+// rather than the stream error when the host is going down on purpose, the action being due
+func disconnectReason(info *pb.HostInfo, err error) error {
+	if powerDue(info, time.Now()) {
+		return fmt.Errorf("announced %s", info.PowerAction)
+	}
+	return err
 }
 
 // validateAndExtractMinionID validates and extracts the minion ID from the stream context
@@ -221,7 +410,7 @@ func (s *Server) validateAndExtractMinionID(stream pb.MinionService_StreamComman
 	}
 
 	// RACE CONDITION DIAGNOSIS: Log concurrent StreamCommands attempts
-	logger.Info("RACE CONDITION DIAGNOSIS: StreamCommands called",
+	logger.Debug("RACE CONDITION DIAGNOSIS: StreamCommands called",
 		zap.String("minion_id", minionID),
 		zap.String("stream_ptr", fmt.Sprintf("%p", stream)),
 		zap.Time("timestamp", time.Now()))
@@ -251,7 +440,7 @@ func (s *Server) findMinionConnectionWithRetry(minionID string, logger *zap.Logg
 // logRegistryState logs the current state of the minion registry for diagnosis
 func (s *Server) logRegistryState(registry *MinionRegistryImpl, minionID string, logger *zap.Logger) {
 	allMinions := registry.ListMinions()
-	logger.Info("RACE CONDITION DIAGNOSIS: Registry state",
+	logger.Debug("RACE CONDITION DIAGNOSIS: Registry state",
 		zap.String("minion_id", minionID),
 		zap.Int("total_minions", len(allMinions)),
 		zap.Strings("minion_ids", s.extractMinionIDs(allMinions)))
@@ -274,7 +463,7 @@ func (s *Server) retryFindConnection(registry *MinionRegistryImpl, minionID stri
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		conn, exists := registry.GetConnectionImpl(minionID)
 		if exists {
-			logger.Info("RACE CONDITION FIX: Connection found",
+			logger.Debug("RACE CONDITION FIX: Connection found",
 				zap.String("minion_id", minionID),
 				zap.Int("attempt", attempt+1),
 				zap.Duration("total_retry_time", time.Since(start)))
@@ -305,11 +494,19 @@ func (s *Server) setupConnection(minionID string, logger *zap.Logger) {
 	logger.Debug("Minion connected to command stream", zap.String("minion_id", minionID))
 	minionRegistryImpl := s.minionRegistry.(*MinionRegistryImpl)
 	minionRegistryImpl.UpdateLastSeen(minionID)
+
+	// Results of commands sent on a previous stream may never come, don't let them hold the limit
+	if conn, exists := minionRegistryImpl.GetConnectionImpl(minionID); exists {
+		conn.Commands.ResetInFlight()
+	}
 }
 
-// startMessageReceiver starts a goroutine to receive messages from the minion
-func (s *Server) startMessageReceiver(stream pb.MinionService_StreamCommandsServer, logger *zap.Logger) chan error {
+// startMessageReceiver starts a goroutine to receive messages from the minion. The acknowledgements
+// of the results received are returned for the dispatch loop to send them, stream.Send not being
+// safe for concurrent use.
+func (s *Server) startMessageReceiver(stream pb.MinionService_StreamCommandsServer, minionID string, logger *zap.Logger) (chan error, chan *pb.ResultAck) {
 	errCh := make(chan error, 1)
+	acks := make(chan *pb.ResultAck, resultAckBufferSize)
 
 	go func() {
 		for {
@@ -319,42 +516,216 @@ func (s *Server) startMessageReceiver(stream pb.MinionService_StreamCommandsServ
 				return
 			}
 
-			s.handleReceivedMessage(stream, msg, logger)
+			// Any message proves the stream is alive
+			s.minionRegistry.(*MinionRegistryImpl).UpdateLastSeen(minionID)
+			ack := s.handleReceivedMessage(stream, minionID, msg, logger)
+			if ack == nil {
+				continue
+			}
+			select {
+			case acks <- ack:
+			case <-stream.Context().Done():
+				errCh <- stream.Context().Err()
+				return
+			}
 		}
 	}()
 
-	return errCh
+	return errCh, acks
 }
 
-// handleReceivedMessage handles different types of messages received from minions
-func (s *Server) handleReceivedMessage(stream pb.MinionService_StreamCommandsServer, msg *pb.CommandStreamMessage, logger *zap.Logger) {
+// handleReceivedMessage handles different types of messages received from minions, returning
+// the acknowledgement of a result once stored
+func (s *Server) handleReceivedMessage(stream pb.MinionService_StreamCommandsServer, minionID string, msg *pb.CommandStreamMessage, logger *zap.Logger) *pb.ResultAck {
 	switch m := msg.Message.(type) {
 	case *pb.CommandStreamMessage_Result:
-		s.handleCommandResult(stream, m.Result, logger)
+		// Minions sending results while Nexus is overloaded are slowed down by gRPC flow control
+		s.throttleIngestion(stream.Context())
+		// The stream is authoritative on which minion sent the message
+		m.Result.MinionId = minionID
+		if err := s.handleCommandResult(stream.Context(), m.Result, logger); err != nil {
+			return nil
+		}
+		return &pb.ResultAck{CommandId: m.Result.CommandId}
+	case *pb.CommandStreamMessage_ResultChunk:
+		s.throttleIngestion(stream.Context())
+		result := s.assembleResult(stream.Context(), minionID, m.ResultChunk, logger)
+		if result == nil {
+			return nil
+		}
+		result.MinionId = minionID
+		if err := s.handleCommandResult(stream.Context(), result, logger); err != nil {
+			return nil
+		}
+		return &pb.ResultAck{CommandId: result.CommandId}
 	case *pb.CommandStreamMessage_Status:
-		s.handleStatusUpdate(stream, m.Status, logger)
+		m.Status.MinionId = minionID
+		s.handleStatusUpdate(stream.Context(), m.Status, logger)
+	case *pb.CommandStreamMessage_Shell:
+		m.Shell.MinionId = minionID
+		s.shells.deliver(m.Shell)
+	case *pb.CommandStreamMessage_File:
+		s.transfers.deliver(minionID, m.File)
+	case *pb.CommandStreamMessage_Logs:
+		s.storeMinionLogs(stream.Context(), minionID, m.Logs, logger)
 	}
+	return nil
 }
 
-// handleCommandResult handles command result messages
-func (s *Server) handleCommandResult(stream pb.MinionService_StreamCommandsServer, result *pb.CommandResult, logger *zap.Logger) {
+// handleCommandResult handles command result messages. An error means the result couldn't be
+// stored and is left unacknowledged, for the minion to send it again.
+func (s *Server) handleCommandResult(ctx context.Context, result *pb.CommandResult, logger *zap.Logger) error {
+	result.TraceId = untrustedTraceID(result.TraceId, result.CommandId, result.MinionId, logger)
+	logger = traceLogger(logger, result.TraceId)
 	logger.Info("COMMAND_FLOW_MONITORING: Command result received from minion",
 		zap.String("stage", "RESULT_RECEIVED"),
 		zap.String("command_id", result.CommandId),
 		zap.String("minion_id", result.MinionId),
 		zap.Int32("exit_code", result.ExitCode),
 		zap.Time("timestamp", time.Now()))
+	if result.WatchId != "" {
+		logger.Info("File change handled by a watch rule",
+			zap.String("minion_id", result.MinionId),
+			zap.String("watch_id", result.WatchId),
+			zap.String("event", result.WatchEvent))
+	}
+	// Nexus waits for the results being stored before stopping
+	s.drain.writes.RLock()
+	defer s.drain.writes.RUnlock()
+
+	s.diagnostics.RecordResult(result.MinionId)
+	if registry, ok := s.minionRegistry.(*MinionRegistryImpl); ok {
+		if conn, exists := registry.GetConnectionImpl(result.MinionId); exists {
+			conn.Commands.Complete(result.CommandId)
+		}
+		registry.health.RecordResult(result.MinionId, result.ExitCode != 0, s.commandLatency(result.CommandId, result.MinionId))
+	}
+	s.releaseCommandLocks(result.MinionId, result.CommandId, logger)
+
+	// Failed results of commands with a retry policy are stored, then superseded by the result of
+	// the next attempt
+	if s.retryFailedResult(result, logger) {
+		if err := s.storeResult(ctx, result, logger); !errors.Is(err, ErrDuplicateResult) {
+			return err
+		}
+		return nil
+	}
+	return s.recordResult(ctx, result, logger)
+}
 
+// recordResult stores a result and notifies its completion, the last result of its minion. A result
+// already stored, sent again by its minion, was notified when first stored.
+func (s *Server) recordResult(ctx context.Context, result *pb.CommandResult, logger *zap.Logger) error {
+	err := s.storeResult(ctx, result, logger)
+	if errors.Is(err, ErrDuplicateResult) {
+		return nil
+	}
+	s.notifyCompletion(result)
+	return err
+}
+
+// storeResult stores a result, counting it only for the successful results of sampled commands
+func (s *Server) storeResult(ctx context.Context, result *pb.CommandResult, logger *zap.Logger) error {
+	var err error
 	if s.dbService != nil {
-		s.storeCommandResult(stream, result, logger)
+		if result.ScheduleId != "" || result.WatchId != "" {
+			s.storeScheduledCommand(ctx, result, logger)
+		}
+		if sample, omitted := s.sampledOut(result); omitted {
+			err = s.countOmittedResult(ctx, result, sample, logger)
+		} else {
+			err = s.storeCommandResult(ctx, result, logger)
+		}
 	} else {
 		s.logSkippedResultStorage(result, logger)
 	}
+	return err
+}
+
+// trackCommand remembers a dispatched command until every target reported a result
+func (s *Server) trackCommand(commandID, payload string, targets []string) {
+	pending := make(map[string]bool, len(targets))
+	for _, minionID := range targets {
+		pending[minionID] = true
+	}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.pendingCommands == nil {
+		s.pendingCommands = make(map[string]*CommandTracker)
+	}
+
+	// Forget commands whose minions never reported back
+	for id, tracker := range s.pendingCommands {
+		if time.Since(tracker.CreatedAt) > pendingCommandTTL {
+			delete(s.pendingCommands, id)
+		}
+	}
+	s.pendingCommands[commandID] = &CommandTracker{
+		Payload:   redactPayload(payload),
+		Pending:   pending,
+		CreatedAt: time.Now(),
+	}
+}
+
+// completeCommand marks a minion result as received and returns the command payload
+func (s *Server) completeCommand(commandID, minionID string) string {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	tracker, exists := s.pendingCommands[commandID]
+	if !exists {
+		return ""
+	}
+
+	delete(tracker.Pending, minionID)
+	if len(tracker.Pending) == 0 {
+		delete(s.pendingCommands, commandID)
+	}
+	return tracker.Payload
+}
+
+// notifyCompletion sends a completion event for result to the configured notifier
+func (s *Server) notifyCompletion(result *pb.CommandResult) {
+	payload := s.completeCommand(result.CommandId, result.MinionId)
+	if s.notifier == nil {
+		return
+	}
+
+	tags := make(map[string]string)
+	if s.minionRegistry != nil {
+		if conn, exists := s.minionRegistry.GetConnection(result.MinionId); exists {
+			for key, value := range conn.GetInfo().Tags {
+				tags[key] = value
+			}
+		}
+	}
+
+	// Webhook targets are outside the stores secrets are redacted from
+	payload, _ = s.redactor.Redact(payload)
+	result, _ = s.redactor.RedactResult(result)
+	s.notifier.Notify(&CompletionEvent{
+		CommandID: result.CommandId,
+		MinionID:  result.MinionId,
+		Command:   payload,
+		ExitCode:  result.ExitCode,
+		Stdout:    truncateOutput(result.Stdout),
+		Stderr:    truncateOutput(result.Stderr),
+		Timestamp: result.Timestamp,
+		Tags:      tags,
+	})
 }
 
 // storeCommandResult stores the command result in the database
-func (s *Server) storeCommandResult(stream pb.MinionService_StreamCommandsServer, result *pb.CommandResult, logger *zap.Logger) {
-	if err := s.dbService.StoreCommandResult(stream.Context(), result); err != nil {
+func (s *Server) storeCommandResult(ctx context.Context, result *pb.CommandResult, logger *zap.Logger) error {
+	err := s.dbService.StoreCommandResult(ctx, result)
+	if errors.Is(err, ErrDuplicateResult) {
+		logger.Info("COMMAND_FLOW_MONITORING: Result already stored",
+			zap.String("stage", "RESULT_DUPLICATE"),
+			zap.String("command_id", result.CommandId),
+			zap.String("minion_id", result.MinionId),
+			zap.Time("timestamp", time.Now()))
+	} else if err != nil {
 		logger.Error("COMMAND_FLOW_MONITORING: Result storage failed",
 			zap.String("stage", "RESULT_STORAGE_FAILED"),
 			zap.String("command_id", result.CommandId),
@@ -368,6 +739,26 @@ func (s *Server) storeCommandResult(stream pb.MinionService_StreamCommandsServer
 			zap.String("minion_id", result.MinionId),
 			zap.Time("timestamp", time.Now()))
 	}
+	return err
+}
+
+// storeScheduledCommand stores the command of a task scheduled or a watch rule triggered on the
+// minion, which Nexus never dispatched, so that its result can be stored and retrieved like any other
+func (s *Server) storeScheduledCommand(ctx context.Context, result *pb.CommandResult, logger *zap.Logger) {
+	if err := s.dbService.StoreCommand(ctx, CommandRecord{
+		CommandID: result.CommandId,
+		MinionID:  result.MinionId,
+		Payload:   redactPayload(result.Payload),
+		Impact:    s.payloadImpact("", result.Payload),
+	}); err != nil {
+		// Results flushed again after a reconnection find their command already stored
+		logger.Debug("Scheduled command not stored",
+			zap.String("command_id", result.CommandId),
+			zap.String("schedule_id", result.ScheduleId),
+			zap.String("watch_id", result.WatchId),
+			zap.String("minion_id", result.MinionId),
+			zap.Error(err))
+	}
 }
 
 // logSkippedResultStorage logs when result storage is skipped due to unavailable database
@@ -380,7 +771,9 @@ func (s *Server) logSkippedResultStorage(result *pb.CommandResult, logger *zap.L
 }
 
 // handleStatusUpdate handles status update messages
-func (s *Server) handleStatusUpdate(stream pb.MinionService_StreamCommandsServer, statusUpdate *pb.CommandStatusUpdate, logger *zap.Logger) {
+func (s *Server) handleStatusUpdate(ctx context.Context, statusUpdate *pb.CommandStatusUpdate, logger *zap.Logger) {
+	statusUpdate.TraceId = untrustedTraceID(statusUpdate.TraceId, statusUpdate.CommandId, statusUpdate.MinionId, logger)
+	logger = traceLogger(logger, statusUpdate.TraceId)
 	logger.Debug("COMMAND_FLOW_MONITORING: Status update received",
 		zap.String("stage", "STATUS_UPDATE_RECEIVED"),
 		zap.String("command_id", statusUpdate.CommandId),
@@ -389,15 +782,16 @@ func (s *Server) handleStatusUpdate(stream pb.MinionService_StreamCommandsServer
 		zap.Time("timestamp", time.Now()))
 
 	if s.dbService != nil {
-		s.updateCommandStatus(stream, statusUpdate, logger)
+		s.updateCommandStatus(ctx, statusUpdate, logger)
+		s.recordStatusEvent(ctx, statusUpdate, logger)
 	} else {
 		s.logSkippedStatusUpdate(statusUpdate, logger)
 	}
 }
 
 // updateCommandStatus updates the command status in the database
-func (s *Server) updateCommandStatus(stream pb.MinionService_StreamCommandsServer, statusUpdate *pb.CommandStatusUpdate, logger *zap.Logger) {
-	if err := s.dbService.UpdateCommandStatus(stream.Context(), statusUpdate.CommandId, statusUpdate.Status); err != nil {
+func (s *Server) updateCommandStatus(ctx context.Context, statusUpdate *pb.CommandStatusUpdate, logger *zap.Logger) {
+	if err := s.dbService.UpdateCommandStatus(ctx, statusUpdate.CommandId, statusUpdate.Status); err != nil {
 		logger.Error("COMMAND_FLOW_MONITORING: Status update failed",
 			zap.String("stage", "STATUS_UPDATE_FAILED"),
 			zap.String("command_id", statusUpdate.CommandId),
@@ -424,28 +818,109 @@ func (s *Server) logSkippedStatusUpdate(statusUpdate *pb.CommandStatusUpdate, lo
 }
 
 // runCommandDispatchLoop runs the main loop for dispatching commands to minions
-func (s *Server) runCommandDispatchLoop(stream pb.MinionService_StreamCommandsServer, conn *MinionConnectionImpl, errCh chan error, minionID string, logger *zap.Logger) error {
+func (s *Server) runCommandDispatchLoop(stream pb.MinionService_StreamCommandsServer, conn *MinionConnectionImpl, errCh chan error, acks chan *pb.ResultAck, dead <-chan struct{}, minionID string, logger *zap.Logger) error {
+	shellOutbound := s.shells.outboundFor(minionID)
+	fileOutbound := s.transfers.outboundFor(minionID)
+	retireOutbound := s.retired.outboundFor(minionID)
+	draining, stopping := s.drain.channels()
 	for {
 		select {
 		case <-stream.Context().Done():
 			return stream.Context().Err()
 
+		case <-draining:
+			// Keep the stream open for the results of the commands being executed
+			draining = nil
+			if err := stream.Send(reconnectHint()); err != nil {
+				logger.Error("Failed to send reconnect hint", zap.String("minion_id", minionID), zap.Error(err))
+				return err
+			}
+			logger.Info("Minion asked to reconnect later", zap.String("minion_id", minionID))
+
+		case <-stopping:
+			return status.Error(codes.Unavailable, "nexus is shutting down")
+
 		case err := <-errCh:
+			// A minion that closed its side of the stream still receives the acknowledgements
+			for len(acks) > 0 {
+				if s.sendResultAck(stream, <-acks, minionID, logger) != nil {
+					break
+				}
+			}
 			return err
 
-		case cmd, ok := <-conn.CommandCh:
-			if !ok {
-				logger.Warn("Command channel closed", zap.String("minion_id", minionID))
+		case <-dead:
+			if reason := s.GetMinionRegistryImpl().streamCloseReason(dead); reason != "" {
+				logger.Warn("Closing command stream of a minion whose ID was taken over", zap.String("minion_id", minionID))
+				return status.Error(codes.AlreadyExists, reason)
+			}
+			logger.Warn("Closing command stream detected dead or disconnected by an administrator", zap.String("minion_id", minionID))
+			return status.Error(codes.Unavailable, "command stream closed by nexus")
+
+		case <-conn.Commands.Ready():
+			// Pop one command at a time so commands pushed meanwhile are ordered by priority
+			for {
+				cmd, ok := conn.Commands.Pop()
+				if !ok {
+					break
+				}
+				if err := s.sendCommandToMinion(stream, cmd, minionID, logger); err != nil {
+					return err
+				}
+			}
+
+			if conn.Commands.Closed() {
+				logger.Warn("Command queue closed", zap.String("minion_id", minionID))
 				return nil
 			}
 
-			if err := s.sendCommandToMinion(stream, cmd, minionID, logger); err != nil {
+		case msg := <-shellOutbound:
+			if err := stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Shell{Shell: msg}}); err != nil {
+				logger.Error("Failed to send shell message",
+					zap.String("minion_id", minionID),
+					zap.String("session_id", msg.SessionId))
+				s.diagnostics.RecordError(minionID, err)
+				return err
+			}
+
+		case msg := <-fileOutbound:
+			if err := stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_File{File: msg}}); err != nil {
+				logger.Error("Failed to send file transfer request",
+					zap.String("minion_id", minionID),
+					zap.String("transfer_id", msg.TransferId))
+				s.diagnostics.RecordError(minionID, err)
 				return err
 			}
+
+		case ack := <-acks:
+			if err := s.sendResultAck(stream, ack, minionID, logger); err != nil {
+				return err
+			}
+
+		case notice := <-retireOutbound:
+			if err := stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Retire{Retire: notice}}); err != nil {
+				logger.Error("Failed to send retirement notice", zap.String("minion_id", minionID), zap.Error(err))
+				return err
+			}
+			s.notifyRetired(minionID, logger)
+			return status.Error(codes.FailedPrecondition, "minion retired")
 		}
 	}
 }
 
+// sendResultAck acknowledges a result to the minion. A lost acknowledgement only makes the
+// minion send the result again.
+func (s *Server) sendResultAck(stream pb.MinionService_StreamCommandsServer, ack *pb.ResultAck, minionID string, logger *zap.Logger) error {
+	if err := stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Ack{Ack: ack}}); err != nil {
+		logger.Error("Failed to acknowledge result",
+			zap.String("minion_id", minionID),
+			zap.String("command_id", ack.CommandId))
+		s.diagnostics.RecordError(minionID, err)
+		return err
+	}
+	return nil
+}
+
 // sendCommandToMinion sends a command to the specified minion
 func (s *Server) sendCommandToMinion(stream pb.MinionService_StreamCommandsServer, cmd *pb.Command, minionID string, logger *zap.Logger) error {
 	msg := &pb.CommandStreamMessage{
@@ -458,8 +933,10 @@ func (s *Server) sendCommandToMinion(stream pb.MinionService_StreamCommandsServe
 		logger.Error("Failed to send command",
 			zap.String("minion_id", minionID),
 			zap.String("command_id", cmd.Id))
+		s.diagnostics.RecordError(minionID, err)
 		return err
 	}
+	s.diagnostics.RecordCommandSent(minionID)
 
 	logger.Debug("Command sent successfully",
 		zap.String("minion_id", minionID),
@@ -474,7 +951,14 @@ func (s *Server) ListMinions(ctx context.Context, empty *pb.Empty) (*pb.MinionLi
 	logger, start := logging.FuncLogger(s.logger, "Nexus.ListMinions")
 	defer logging.FuncExit(logger, start)
 
-	minions := s.minionRegistry.ListMinions()
+	scope := consoleScope(ctx)
+	minions := make([]*pb.HostInfo, 0)
+	for _, info := range s.minionRegistry.ListMinions() {
+		if scope.allows(info) {
+			info.Outdated = s.versions.outdated(info)
+			minions = append(minions, info)
+		}
+	}
 	logger.Debug("Listed minions", zap.Int("count", len(minions)))
 	return &pb.MinionList{Minions: minions}, nil
 }
@@ -485,8 +969,13 @@ func (s *Server) ListTags(ctx context.Context, empty *pb.Empty) (*pb.TagList, er
 	logger, start := logging.FuncLogger(s.logger, "Nexus.ListTags")
 	defer logging.FuncExit(logger, start)
 
-	minionRegistryImpl := s.minionRegistry.(*MinionRegistryImpl)
-	tags := minionRegistryImpl.ListTags()
+	var tags []string
+	if scope := consoleScope(ctx); scope.restricted() {
+		tags = s.scopedTags(scope)
+	} else {
+		minionRegistryImpl := s.minionRegistry.(*MinionRegistryImpl)
+		tags = minionRegistryImpl.ListTags()
+	}
 	logger.Debug("Listed tags", zap.Int("count", len(tags)))
 	return &pb.TagList{Tags: tags}, nil
 }
@@ -501,6 +990,12 @@ func (s *Server) SetTags(ctx context.Context, req *pb.SetTagsRequest) (*pb.Ack,
 		zap.String("minion_id", req.MinionId),
 		zap.Int("tag_count", len(req.Tags)))
 
+	if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+		return &pb.Ack{Success: false}, err
+	}
+	if err := s.validateTags(req.Tags); err != nil {
+		return &pb.Ack{Success: false}, err
+	}
 	if err := s.minionRegistry.SetTags(req.MinionId, req.Tags); err != nil {
 		logger.Error("Failed to set tags",
 			zap.String("minion_id", req.MinionId))
@@ -524,6 +1019,12 @@ func (s *Server) UpdateTags(ctx context.Context, req *pb.UpdateTagsRequest) (*pb
 		zap.Int("add_count", len(req.Add)),
 		zap.Int("remove_count", len(req.RemoveKeys)))
 
+	if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+		return &pb.Ack{Success: false}, err
+	}
+	if err := s.validateTags(req.Add); err != nil {
+		return &pb.Ack{Success: false}, err
+	}
 	if err := s.minionRegistry.UpdateTags(req.MinionId, req.Add, req.RemoveKeys); err != nil {
 		logger.Error("Failed to update tags",
 			zap.String("minion_id", req.MinionId))
@@ -549,7 +1050,7 @@ func (s *Server) validateCommand(cmd *pb.Command) error {
 
 	logger.Info("DIAGNOSIS: Validating command",
 		zap.String("command_id", cmd.Id),
-		zap.String("payload", cmd.Payload),
+		zap.String("payload", redactPayload(cmd.Payload)),
 		zap.String("type", cmd.Type.String()))
 
 	if cmd.Payload == "" {
@@ -557,6 +1058,9 @@ func (s *Server) validateCommand(cmd *pb.Command) error {
 			zap.String("command_id", cmd.Id))
 		return fmt.Errorf("command payload is empty")
 	}
+	if !validTraceID(cmd.TraceId) {
+		return fmt.Errorf("trace ID must have at most %d letters, digits, '.', '-' or '_'", maxTraceIDLength)
+	}
 
 	// For system commands, check if they are registered
 	if cmd.Type == pb.CommandType_SYSTEM {
@@ -568,12 +1072,12 @@ func (s *Server) validateCommand(cmd *pb.Command) error {
 			cmdName := strings.Fields(payload)[0]
 			logger.Info("DIAGNOSIS: Checking system command in registry",
 				zap.String("command_name", cmdName),
-				zap.String("full_payload", payload))
+				zap.String("full_payload", redactPayload(payload)))
 
 			if _, exists := s.commandRegistry.GetCommand(cmdName); !exists {
 				logger.Error("DIAGNOSIS: Unknown command - not found in registry",
 					zap.String("command", cmdName),
-					zap.String("full_payload", payload))
+					zap.String("full_payload", redactPayload(payload)))
 				return fmt.Errorf("unknown command: %s", cmdName)
 			} else {
 				logger.Info("DIAGNOSIS: System command found in registry",
@@ -581,18 +1085,40 @@ func (s *Server) validateCommand(cmd *pb.Command) error {
 			}
 		} else {
 			logger.Info("DIAGNOSIS: Non-prefixed system command - allowing through",
-				zap.String("payload", payload))
+				zap.String("payload", redactPayload(payload)))
 		}
 		// For other system commands (shell commands), we allow them through
 	}
 
 	logger.Debug("DIAGNOSIS: Command validated successfully",
 		zap.String("command_id", cmd.Id),
-		zap.String("payload", cmd.Payload))
+		zap.String("payload", redactPayload(cmd.Payload)))
 
 	return nil
 }
 
+// redactPayload hides secrets carried by some commands (e.g. private keys sent by
+// certs:rotate) so they are never logged nor persisted in the database
+func redactPayload(payload string) string {
+	if strings.HasPrefix(strings.TrimSpace(payload), "certs:rotate") {
+		return "certs:rotate <redacted>"
+	}
+	return payload
+}
+
+// commandRecord returns the command of req stored for a target minion among the targets it was resolved to
+func commandRecord(commandID, minionID string, targets []string, req *pb.CommandRequest) CommandRecord {
+	return CommandRecord{
+		CommandID: commandID,
+		MinionID:  minionID,
+		Payload:   redactPayload(req.Command.Payload),
+		TraceID:   req.Command.TraceId,
+		Impact:    req.Impact,
+		Operator:  req.Operator,
+		Targets:   targets,
+	}
+}
+
 // MatchesTags checks if a HostInfo matches the given TagSelector.
 // This is a utility function used by tests and other components.
 func MatchesTags(info *pb.HostInfo, selector *pb.TagSelector) bool {
@@ -643,39 +1169,159 @@ func (s *Server) SendCommand(ctx context.Context, req *pb.CommandRequest) (*pb.C
 	logger, start := logging.FuncLogger(s.logger, "Nexus.SendCommand")
 	defer logging.FuncExit(logger, start)
 
+	logger = ensureTraceID(req.Command, logger)
+
 	logger.Info("COMMAND_FLOW_MONITORING: Command dispatch initiated",
 		zap.String("stage", "DISPATCH_START"),
 		zap.Strings("requested_minion_ids", req.MinionIds),
-		zap.String("command_payload", req.Command.Payload),
+		zap.String("command_payload", redactPayload(req.Command.Payload)),
 		zap.String("command_type", req.Command.Type.String()),
 		zap.Time("timestamp", time.Now()))
 
 	// Validate the command first
 	if err := s.validateCommand(req.Command); err != nil {
 		logger.Warn("Invalid command rejected",
-			zap.String("payload", req.Command.Payload))
+			zap.String("payload", redactPayload(req.Command.Payload)))
 		return &pb.CommandDispatchResponse{
 			Accepted:  false,
 			CommandId: "",
 		}, fmt.Errorf("invalid command: %v", err)
 	}
+	impact, err := s.commandImpact(req)
+	if err == nil {
+		err = validateRetryPolicy(req.Retry)
+	}
+	if err == nil {
+		err = validateSample(req.Sample)
+	}
+	if err != nil {
+		return &pb.CommandDispatchResponse{}, fmt.Errorf("invalid command: %v", err)
+	}
+	req.Impact = impact
+	req.Operator = consoleIdentity(ctx)
+	lockAction, lockName, isLockCommand, err := command.ParseLockCommand(req.Command.Payload)
+	if err == nil && req.Lock != "" {
+		err = command.ValidateLockName(req.Lock)
+	}
+	if err != nil {
+		return &pb.CommandDispatchResponse{}, fmt.Errorf("invalid command: %v", err)
+	}
+	if !req.DryRun {
+		if err := s.admitDispatch(logger); err != nil {
+			return nil, err
+		}
+	}
 
-	targets := s.minionRegistry.FindTargetMinions(req)
+	// Namespace scoped consoles only reach the minions of their namespaces
+	targets := s.scopedMinionIDs(consoleScope(ctx), s.minionRegistry.FindTargetMinions(req))
 	if len(targets) == 0 {
 		logger.Warn("COMMAND_FLOW_MONITORING: No target minions found",
 			zap.String("stage", "TARGET_RESOLUTION_FAILED"),
 			zap.Strings("requested_minion_ids", req.MinionIds),
-			zap.String("payload", req.Command.Payload),
+			zap.String("payload", redactPayload(req.Command.Payload)),
 			zap.Time("timestamp", time.Now()))
 		return &pb.CommandDispatchResponse{
 			Accepted:  false,
 			CommandId: "",
+			DryRun:    req.DryRun,
+		}, nil
+	}
+
+	// Skip the minions unable to execute the command family, or running a handler too old for it
+	targets, skipped := s.filterCapableTargets(req.Command, targets, logger)
+	targets, incompatible := s.filterCompatibleTargets(req.Command, targets, logger)
+	skipped = append(skipped, incompatible...)
+	if len(targets) == 0 {
+		return &pb.CommandDispatchResponse{
+			Accepted:         false,
+			DryRun:           req.DryRun,
+			SkippedMinionIds: skipped,
 		}, nil
 	}
 
+	// Consecutive dispatches reach different failure domains, which staggered rollouts benefit from
+	targets = s.spreadTargets(targets)
+
+	// Destructive commands are only dispatched once confirmed with a token
+	reason, confirmToken, err := s.pendingConfirmation(req)
+	if err != nil {
+		return &pb.CommandDispatchResponse{}, err
+	}
+	if reason != "" && !req.DryRun {
+		logger.Warn("COMMAND_FLOW_MONITORING: Destructive command awaiting confirmation",
+			zap.String("stage", "CONFIRMATION_REQUIRED"),
+			zap.String("reason", reason),
+			zap.Bool("invalid_token", req.ConfirmToken != ""),
+			zap.Strings("target_minion_ids", targets),
+			zap.Time("timestamp", time.Now()))
+		return &pb.CommandDispatchResponse{
+			Accepted:             false,
+			TargetMinionIds:      targets,
+			SkippedMinionIds:     skipped,
+			ConfirmationRequired: true,
+			ConfirmToken:         confirmToken,
+			DestructiveReason:    reason,
+			Impact:               req.Impact,
+		}, nil
+	}
+
+	// Commands matching an approval rule need a second operator
+	approvalReason := s.approvals.Match(req.Command.Payload, req.Impact, targets, s.minionTags)
+
+	// Dry run: report the resolved targets without storing or dispatching anything
+	if req.DryRun {
+		logger.Info("COMMAND_FLOW_MONITORING: Dry run completed",
+			zap.String("stage", "DRY_RUN"),
+			zap.Int("target_count", len(targets)),
+			zap.Strings("target_minion_ids", targets),
+			zap.Time("timestamp", time.Now()))
+		unversioned, requiredVersion := s.unversionedTargets(req.Command, targets)
+		return &pb.CommandDispatchResponse{
+			Accepted:             true,
+			TargetMinionIds:      targets,
+			DryRun:               true,
+			UnversionedMinionIds: unversioned,
+			RequiredVersion:      requiredVersion,
+			HeldMinionIds:        s.heldTargets(req, targets),
+			SkippedMinionIds:     skipped,
+			ConfirmationRequired: reason != "",
+			ConfirmToken:         confirmToken,
+			DestructiveReason:    reason,
+			ApprovalRequired:     approvalReason != "",
+			ApprovalReason:       approvalReason,
+			Impact:               req.Impact,
+		}, nil
+	}
+
+	// Lock commands are answered by Nexus, which coordinates the locks
+	if isLockCommand {
+		return s.handleLockCommand(ctx, req, lockAction, lockName, targets, skipped, logger), nil
+	}
+
+	// Commands matching an approval rule wait for a second operator
+	if approvalReason != "" {
+		return s.requestApproval(ctx, req, targets, skipped, approvalReason, logger), nil
+	}
+
 	// Generate command ID
-	commandID := generateMinionID()
-	req.Command.Id = commandID
+	req.Command.Id = generateMinionID()
+	return s.dispatchCommand(ctx, req, targets, skipped, logger), nil
+}
+
+// dispatchCommand stores the command of req, identified by its ID, and dispatches it to its targets
+func (s *Server) dispatchCommand(ctx context.Context, req *pb.CommandRequest, targets, skipped []string, logger *zap.Logger) *pb.CommandDispatchResponse {
+	start := time.Now()
+	commandID := req.Command.Id
+
+	// The emergency flag implies the EMERGENCY priority
+	emergency := isEmergency(req)
+	req.Command.Priority = req.Priority
+	if emergency {
+		req.Command.Priority = pb.CommandPriority_EMERGENCY
+	}
+	s.trackCommand(commandID, req.Command.Payload, targets)
+	s.trackRetries(req)
+	s.trackSampling(req)
 
 	logger.Info("COMMAND_FLOW_MONITORING: Target minions resolved",
 		zap.String("stage", "TARGET_RESOLUTION_SUCCESS"),
@@ -688,7 +1334,7 @@ func (s *Server) SendCommand(ctx context.Context, req *pb.CommandRequest) (*pb.C
 	var dbErrors []string
 	if s.dbService != nil {
 		for _, minionID := range targets {
-			if err := s.dbService.StoreCommand(ctx, commandID, minionID, req.Command.Payload); err != nil {
+			if err := s.dbService.StoreCommand(ctx, commandRecord(commandID, minionID, targets, req)); err != nil {
 				errMsg := fmt.Sprintf("minion %s: %v", minionID, err)
 				dbErrors = append(dbErrors, errMsg)
 				logger.Error("HARDENING: Failed to store command in database - persistence at risk",
@@ -716,58 +1362,55 @@ func (s *Server) SendCommand(ctx context.Context, req *pb.CommandRequest) (*pb.C
 	}
 
 	// Send command to target minions using registry
-	minionRegistryImpl := s.minionRegistry.(*MinionRegistryImpl)
 	var dispatchErrors []string
+	var heldMinions, lockWaiting []string
 	successfulDispatches := 0
 
-	for _, minionID := range targets {
-		if conn, exists := minionRegistryImpl.GetConnectionImpl(minionID); exists {
-			// Replace non-blocking select with timeout-based blocking
-			// This prevents silent command dropping and ensures proper error handling
-			timeout := 100 * time.Millisecond // Optimized: reduced from 1s to 100ms for faster dispatch
-			ctx, cancel := context.WithTimeout(ctx, timeout)
-			defer cancel()
+	// Commands reaching many minions are staggered by the dispatch rate limits
+	delays := s.dispatchRates.schedule(req, targets, s.minionTags)
+	var staged []stagedDispatch
+	var staggered []string
 
-			select {
-			case conn.CommandCh <- req.Command:
-				logger.Info("COMMAND_FLOW_MONITORING: Command delivered to channel",
-					zap.String("stage", "CHANNEL_DELIVERY_SUCCESS"),
-					zap.String("command_id", commandID),
-					zap.String("minion_id", minionID),
-					zap.String("payload", req.Command.Payload),
-					zap.Int("channel_len", len(conn.CommandCh)),
-					zap.Int("channel_cap", cap(conn.CommandCh)),
-					zap.Time("timestamp", time.Now()))
-				successfulDispatches++
-			case <-ctx.Done():
-				errMsg := fmt.Sprintf("Command dispatch timeout for minion %s: channel full or unresponsive", minionID)
-				dispatchErrors = append(dispatchErrors, errMsg)
-				logger.Error("COMMAND_FLOW_MONITORING: Channel delivery failed",
-					zap.String("stage", "CHANNEL_DELIVERY_TIMEOUT"),
-					zap.String("command_id", commandID),
-					zap.String("minion_id", minionID),
-					zap.String("payload", req.Command.Payload),
-					zap.Int("channel_len", len(conn.CommandCh)),
-					zap.Int("channel_cap", cap(conn.CommandCh)),
-					zap.String("error", errMsg),
-					zap.Time("timestamp", time.Now()))
+	for i, minionID := range targets {
+		if delays != nil && delays[i] > 0 {
+			staged = append(staged, stagedDispatch{minionID: minionID, delay: delays[i]})
+			staggered = append(staggered, minionID)
+			continue
+		}
+		outcome, err := s.dispatchTarget(req, minionID, emergency, logger)
+		switch outcome {
+		case dispatchLockWaiting:
+			lockWaiting = append(lockWaiting, minionID)
+		case dispatchHeld:
+			heldMinions = append(heldMinions, minionID)
+		case dispatchFailed:
+			dispatchErrors = append(dispatchErrors, err.Error())
+		default:
+			successfulDispatches++
+		}
+	}
+
+	var rolloutSeconds int32
+	if len(staged) > 0 {
+		var rollout time.Duration
+		for _, target := range staged {
+			if target.delay > rollout {
+				rollout = target.delay
 			}
-		} else {
-			errMsg := fmt.Sprintf("Minion %s not found when dispatching command", minionID)
-			dispatchErrors = append(dispatchErrors, errMsg)
-			logger.Warn("COMMAND_FLOW_MONITORING: Minion connection not found",
-				zap.String("stage", "CHANNEL_DELIVERY_NO_CONNECTION"),
-				zap.String("command_id", commandID),
-				zap.String("minion_id", minionID),
-				zap.String("payload", req.Command.Payload),
-				zap.String("error", errMsg),
-				zap.Time("timestamp", time.Now()))
 		}
+		rolloutSeconds = int32((rollout + time.Second - 1) / time.Second)
+		logger.Info("COMMAND_FLOW_MONITORING: Command rollout staggered by dispatch rates",
+			zap.String("stage", "DISPATCH_STAGGERED"),
+			zap.String("command_id", commandID),
+			zap.Int("staggered_count", len(staged)),
+			zap.Duration("rollout_duration", rollout),
+			zap.Time("timestamp", time.Now()))
+		go s.staggerDispatch(req, emergency, staged, logger)
 	}
 
 	// Commands are accepted if stored in database, regardless of channel delivery
 	// Channel delivery failures (like full channels) should not cause command rejection
-	if successfulDispatches == 0 {
+	if successfulDispatches == 0 && len(heldMinions) == 0 && len(lockWaiting) == 0 && len(staged) == 0 {
 		logger.Warn("COMMAND_FLOW_MONITORING: All channel deliveries failed",
 			zap.String("stage", "DISPATCH_CHANNEL_FAILURES"),
 			zap.String("command_id", commandID),
@@ -792,14 +1435,135 @@ func (s *Server) SendCommand(ctx context.Context, req *pb.CommandRequest) (*pb.C
 		zap.String("command_id", commandID),
 		zap.Int("target_count", len(targets)),
 		zap.Int("successful_dispatches", successfulDispatches),
+		zap.Int("held_dispatches", len(heldMinions)),
+		zap.Int("lock_waiting", len(lockWaiting)),
+		zap.Int("staggered", len(staged)),
 		zap.Duration("dispatch_duration", time.Since(start)),
 		zap.Time("timestamp", time.Now()))
 
 	// Commands are accepted if they passed validation and had targets, regardless of channel delivery status
+	unversioned, requiredVersion := s.unversionedTargets(req.Command, targets)
+	envUnavailable, envUnsupported := s.commandEnvWarnings(req.Command, targets, logger)
 	return &pb.CommandDispatchResponse{
-		Accepted:  true,
-		CommandId: commandID,
-	}, nil
+		Accepted:                true,
+		CommandId:               commandID,
+		TargetMinionIds:         targets,
+		HeldMinionIds:           heldMinions,
+		SkippedMinionIds:        skipped,
+		LockWaitingMinionIds:    lockWaiting,
+		StaggeredMinionIds:      staggered,
+		RolloutSeconds:          rolloutSeconds,
+		UnversionedMinionIds:    unversioned,
+		RequiredVersion:         requiredVersion,
+		TraceId:                 req.Command.TraceId,
+		Impact:                  req.Impact,
+		EnvUnavailable:          envUnavailable,
+		EnvUnsupportedMinionIds: envUnsupported,
+	}
+}
+
+// dispatchOutcome is what became of a command dispatched to a minion
+type dispatchOutcome int
+
+const (
+	dispatchQueued      dispatchOutcome = iota // queued for the minion
+	dispatchHeld                               // held until the minion's maintenance window opens
+	dispatchLockWaiting                        // waiting until its lock is free on the minion
+	dispatchFailed                             // not delivered, its locks released
+)
+
+// dispatchTarget dispatches the command of req to one of its target minions
+func (s *Server) dispatchTarget(req *pb.CommandRequest, minionID string, emergency bool, logger *zap.Logger) (dispatchOutcome, error) {
+	commandID := req.Command.Id
+
+	// Commands declaring a lock wait until it is free on the minion
+	if req.Lock != "" {
+		s.expireLocks(logger)
+	}
+	if req.Lock != "" && !s.locks.acquireOrWait(minionID, req.Lock, req.Command, emergency) {
+		logger.Info("COMMAND_FLOW_MONITORING: Command waiting for its lock",
+			zap.String("stage", "LOCK_WAIT"),
+			zap.String("command_id", commandID),
+			zap.String("minion_id", minionID),
+			zap.String("lock", req.Lock),
+			zap.Time("timestamp", time.Now()))
+		return dispatchLockWaiting, nil
+	}
+
+	minionRegistryImpl, ok := s.minionRegistry.(*MinionRegistryImpl)
+	if !ok {
+		s.releaseCommandLocks(minionID, commandID, logger)
+		return dispatchFailed, errors.New("minion registry does not dispatch commands")
+	}
+	conn, exists := minionRegistryImpl.GetConnectionImpl(minionID)
+	if !exists {
+		errMsg := fmt.Sprintf("Minion %s not found when dispatching command", minionID)
+		s.releaseCommandLocks(minionID, commandID, logger)
+		logger.Warn("COMMAND_FLOW_MONITORING: Minion connection not found",
+			zap.String("stage", "CHANNEL_DELIVERY_NO_CONNECTION"),
+			zap.String("command_id", commandID),
+			zap.String("minion_id", minionID),
+			zap.String("payload", redactPayload(req.Command.Payload)),
+			zap.String("error", errMsg),
+			zap.Time("timestamp", time.Now()))
+		return dispatchFailed, errors.New(errMsg)
+	}
+
+	held, err := s.dispatchToConnection(conn, minionID, req.Command, emergency, logger)
+	switch {
+	case held:
+		return dispatchHeld, nil
+	case err != nil:
+		s.releaseCommandLocks(minionID, commandID, logger)
+		return dispatchFailed, err
+	default:
+		return dispatchQueued, nil
+	}
+}
+
+// dispatchToConnection queues a command for a connected minion, or holds it until the
+// minion's maintenance window opens. It reports whether the command was held.
+func (s *Server) dispatchToConnection(conn *MinionConnectionImpl, minionID string, cmd *pb.Command, emergency bool, logger *zap.Logger) (bool, error) {
+	s.expectCertificateRotation(minionID, cmd.Payload)
+	cmd = s.withCommandEnv(conn.Info, cmd, logger)
+
+	// Non-emergency commands wait for the minion's maintenance window
+	if !emergency && s.maintenance != nil && s.maintenance.ShouldHold(conn.Info) {
+		s.maintenance.Hold(minionID, cmd)
+		logger.Info("COMMAND_FLOW_MONITORING: Command held until maintenance window opens",
+			zap.String("stage", "MAINTENANCE_HOLD"),
+			zap.String("command_id", cmd.Id),
+			zap.String("minion_id", minionID),
+			zap.Time("timestamp", time.Now()))
+		return true, nil
+	}
+
+	if !conn.Commands.Push(cmd) {
+		errMsg := fmt.Sprintf("Command dispatch failed for minion %s: command queue full", minionID)
+		s.diagnostics.RecordError(minionID, errors.New(errMsg))
+		logger.Error("COMMAND_FLOW_MONITORING: Queue delivery failed",
+			zap.String("stage", "QUEUE_DELIVERY_FULL"),
+			zap.String("command_id", cmd.Id),
+			zap.String("minion_id", minionID),
+			zap.String("payload", redactPayload(cmd.Payload)),
+			zap.String("priority", cmd.Priority.String()),
+			zap.Int("queue_len", conn.Commands.Len()),
+			zap.Int("queue_cap", conn.Commands.Cap()),
+			zap.String("error", errMsg),
+			zap.Time("timestamp", time.Now()))
+		return false, errors.New(errMsg)
+	}
+
+	logger.Info("COMMAND_FLOW_MONITORING: Command delivered to queue",
+		zap.String("stage", "QUEUE_DELIVERY_SUCCESS"),
+		zap.String("command_id", cmd.Id),
+		zap.String("minion_id", minionID),
+		zap.String("payload", redactPayload(cmd.Payload)),
+		zap.String("priority", cmd.Priority.String()),
+		zap.Int("queue_len", conn.Commands.Len()),
+		zap.Int("queue_cap", conn.Commands.Cap()),
+		zap.Time("timestamp", time.Now()))
+	return false, nil
 }
 
 // GetCommandResults retrieves the execution results for a specific command in the ConsoleService.
@@ -818,7 +1582,52 @@ func (s *Server) GetCommandResults(ctx context.Context, req *pb.ResultRequest) (
 		return &pb.CommandResults{}, nil
 	}
 
-	results, err := s.dbService.GetCommandResults(ctx, req.CommandId)
+	if req.Limit < 0 || req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset cannot be negative")
+	}
+	limit := int(req.Limit)
+	if limit > maxResultPageSize {
+		limit = maxResultPageSize
+	}
+
+	// Namespace scoped consoles only see the results of their minions, filtered before pagination
+	if scope := consoleScope(ctx); scope.restricted() {
+		results, err := s.scopedCommandResults(ctx, scope, req.CommandId)
+		if err != nil {
+			logger.Error("Error getting command results",
+				zap.String("command_id", req.CommandId),
+				zap.Error(err))
+			return nil, err
+		}
+		return paginateResults(results, limit, int(req.Offset)), nil
+	}
+
+	// Archived results are fetched from object storage and paginated in memory
+	if s.archiver != nil {
+		archived, err := s.archiver.Fetch(ctx, req.CommandId)
+		if err != nil {
+			logger.Error("Error getting archived command results",
+				zap.String("command_id", req.CommandId),
+				zap.Error(err))
+			return nil, err
+		}
+		if archived != nil {
+			live, err := s.dbService.GetCommandResults(ctx, req.CommandId)
+			if err != nil {
+				return nil, err
+			}
+			page := paginateResults(mergeArchivedResults(archived, live), limit, int(req.Offset))
+			page.OmittedResults = s.omittedResults(ctx, req.CommandId, logger)
+			return page, nil
+		}
+	}
+
+	// Fetch one extra result to know whether another page follows
+	fetch := 0
+	if limit > 0 {
+		fetch = limit + 1
+	}
+	results, err := s.dbService.GetCommandResultsPage(ctx, req.CommandId, fetch, int(req.Offset))
 	if err != nil {
 		logger.Error("Error getting command results from database",
 			zap.String("command_id", req.CommandId),
@@ -826,9 +1635,99 @@ func (s *Server) GetCommandResults(ctx context.Context, req *pb.ResultRequest) (
 		return nil, err
 	}
 
+	hasMore := limit > 0 && len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+
 	logger.Debug("Retrieved command results",
 		zap.String("command_id", req.CommandId),
-		zap.Int("result_count", len(results)))
+		zap.Int("result_count", len(results)),
+		zap.Bool("has_more", hasMore))
+
+	return &pb.CommandResults{Results: results, HasMore: hasMore, OmittedResults: s.omittedResults(ctx, req.CommandId, logger)}, nil
+}
+
+// scopedCommandResults returns the live and archived results of a command reported by the minions of scope
+func (s *Server) scopedCommandResults(ctx context.Context, scope namespaceScope, commandID string) ([]*pb.CommandResult, error) {
+	results, err := s.dbService.GetCommandResults(ctx, commandID)
+	if err != nil {
+		return nil, err
+	}
+	if s.archiver != nil {
+		archived, err := s.archiver.Fetch(ctx, commandID)
+		if err != nil {
+			return nil, err
+		}
+		results = mergeArchivedResults(archived, results)
+	}
+
+	allowed := make(map[string]bool)
+	scoped := make([]*pb.CommandResult, 0, len(results))
+	for _, result := range results {
+		visible, known := allowed[result.MinionId]
+		if !known {
+			visible = len(s.scopedMinionIDs(scope, []string{result.MinionId})) > 0
+			allowed[result.MinionId] = visible
+		}
+		if visible {
+			scoped = append(scoped, result)
+		}
+	}
+	return scoped, nil
+}
+
+// paginateResults returns the page of results starting at offset, limit 0 returning them all
+func paginateResults(results []*pb.CommandResult, limit, offset int) *pb.CommandResults {
+	if offset >= len(results) {
+		return &pb.CommandResults{}
+	}
+	results = results[offset:]
+	hasMore := limit > 0 && len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+	return &pb.CommandResults{Results: results, HasMore: hasMore}
+}
+
+// GetCommandStatus returns the status of a command on each of its target minions
+// along with the number of minions in each status
+func (s *Server) GetCommandStatus(ctx context.Context, req *pb.ResultRequest) (*pb.CommandStatusResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetCommandStatus")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "command status requires a database")
+	}
 
-	return &pb.CommandResults{Results: results}, nil
+	statuses, err := s.dbService.GetCommandStatuses(ctx, req.CommandId)
+	if err != nil {
+		logger.Error("Error getting command statuses from database",
+			zap.String("command_id", req.CommandId),
+			zap.Error(err))
+		return nil, err
+	}
+	if scope := consoleScope(ctx); scope.restricted() {
+		scoped := make([]*pb.CommandStatusResponse_MinionStatus, 0, len(statuses))
+		for _, minionStatus := range statuses {
+			if len(s.scopedMinionIDs(scope, []string{minionStatus.MinionId})) > 0 {
+				scoped = append(scoped, minionStatus)
+			}
+		}
+		statuses = scoped
+	}
+	if len(statuses) == 0 {
+		return nil, status.Errorf(codes.NotFound, "command %s not found", req.CommandId)
+	}
+
+	counts := make(map[string]int32)
+	for _, minionStatus := range statuses {
+		counts[minionStatus.Status]++
+	}
+
+	return &pb.CommandStatusResponse{
+		CommandId:    req.CommandId,
+		Statuses:     statuses,
+		StatusCounts: counts,
+	}, nil
 }
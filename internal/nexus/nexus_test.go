@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -15,10 +16,12 @@ import (
 	pb "github.com/arhuman/minexus/protogen"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // createTestServer creates a new Server instance for testing
@@ -43,6 +46,7 @@ func createTestServer(db *sql.DB) *Server {
 		minionRegistry:  minionRegistry,
 		pendingCommands: make(map[string]*CommandTracker),
 		commandRegistry: command.SetupCommands(15 * time.Second),
+		redactor:        NewRedactor(DefaultRedactionPatterns()),
 	}
 }
 
@@ -65,8 +69,8 @@ func TestListMinionsInMemory(t *testing.T) {
 			Os:       "linux",
 			Tags:     map[string]string{"env": "test"},
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	registry.minions["minion-2"] = &MinionConnectionImpl{
@@ -77,8 +81,8 @@ func TestListMinionsInMemory(t *testing.T) {
 			Os:       "windows",
 			Tags:     map[string]string{"env": "prod"},
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	list, err := server.ListMinions(context.Background(), &pb.Empty{})
@@ -175,8 +179,8 @@ func TestSetTagsWithMissingDatabaseRecord(t *testing.T) {
 			Os:       "linux",
 			Tags:     make(map[string]string),
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	// Mock the UPDATE operation to return 0 rows affected (record doesn't exist)
@@ -242,8 +246,8 @@ func TestUpdateTagsWithMissingDatabaseRecord(t *testing.T) {
 				"existing": "tag",
 			},
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	// Mock the UPDATE operation to return 0 rows affected (record doesn't exist)
@@ -310,8 +314,8 @@ func TestSendCommandWithNonExistentCommand(t *testing.T) {
 			Os:       "linux",
 			Tags:     make(map[string]string),
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	tests := []struct {
@@ -360,8 +364,8 @@ func TestSendCommandWithNonExistentCommand(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// For valid commands, expect a database insert
 			if !tt.shouldError {
-				mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\)").
-					WithArgs(sqlmock.AnyArg(), minionID, tt.command.Payload, sqlmock.AnyArg(), "SENT", "PENDING").
+				mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status, redacted, trace_id, impact, operator, targets\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11\\)").
+					WithArgs(sqlmock.AnyArg(), minionID, tt.command.Payload, sqlmock.AnyArg(), "SENT", "PENDING", false, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 			}
 
@@ -423,8 +427,8 @@ func TestSendCommandWithEmptyPayload(t *testing.T) {
 			Os:       "linux",
 			Tags:     make(map[string]string),
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	req := &pb.CommandRequest{
@@ -480,10 +484,10 @@ func TestMinionRegistrationDataIntegrity(t *testing.T) {
 	}
 
 	// Mock the database operations for registration
-	// New architecture calls StoreHost directly for new minions
-	mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
-		WithArgs(testMinionID, testHostname, testIP, testOS, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// New architecture calls RegisterHost directly for new minions
+	mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
+		WithArgs(testMinionID, testHostname, testIP, testOS, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "{}", TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
 
 	// Call Register
 	response, err := server.Register(context.Background(), hostInfo)
@@ -549,10 +553,10 @@ func TestDatabaseSchemaConsistency(t *testing.T) {
 		Tags:     make(map[string]string),
 	}
 
-	// Mock registration database operations - new architecture calls StoreHost directly
-	mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
-		WithArgs(testMinionID, testHostname, testIP, testOS, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Mock registration database operations - new architecture calls RegisterHost directly
+	mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
+		WithArgs(testMinionID, testHostname, testIP, testOS, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "{}", TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
 
 	_, err = server.Register(context.Background(), hostInfo)
 	if err != nil {
@@ -623,10 +627,10 @@ func TestMinionRegistrationWithPredefinedID(t *testing.T) {
 		Tags:     make(map[string]string),
 	}
 
-	// Mock database operations - new architecture calls StoreHost directly
-	mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
-		WithArgs(predefinedMinionID, actualHostname, actualIP, actualOS, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Mock database operations - new architecture calls RegisterHost directly
+	mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
+		WithArgs(predefinedMinionID, actualHostname, actualIP, actualOS, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "{}", TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
 
 	// Register the minion
 	response, err := server.Register(context.Background(), hostInfo)
@@ -858,9 +862,9 @@ func TestStreamCommandsWithResults(t *testing.T) {
 	// Add test minion
 	minionID := "test-minion"
 	server.GetMinionRegistryImpl().minions[minionID] = &MinionConnectionImpl{
-		Info:      &pb.HostInfo{Id: minionID},
-		CommandCh: make(chan *pb.Command, 10),
-		LastSeen:  time.Now(),
+		Info:     &pb.HostInfo{Id: minionID},
+		Commands: NewCommandQueue(10),
+		LastSeen: time.Now(),
 	}
 
 	// Mock complete StoreCommandResult flow expectations:
@@ -873,8 +877,8 @@ func TestStreamCommandsWithResults(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 
 	// 3. Insert result
-	mock.ExpectExec("INSERT INTO command_results \\(command_id, minion_id, exit_code, stdout, stderr, timestamp\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\)").
-		WithArgs("cmd-123", minionID, int32(0), "success output", "", sqlmock.AnyArg()).
+	mock.ExpectExec("INSERT INTO command_results \\(command_id, minion_id, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, attempt, execution\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11, \\$12\\)").
+		WithArgs("cmd-123", minionID, int32(0), "success output", "", nil, nil, sqlmock.AnyArg(), false, nil, int32(1), nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// 4. Update command status to COMPLETED
@@ -1295,11 +1299,11 @@ func TestGetCommandResults(t *testing.T) {
 					WithArgs("cmd-123").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
-				rows := sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "timestamp"}).
-					AddRow("cmd-123", "minion-1", 0, "output1", "", 1640995200).
-					AddRow("cmd-123", "minion-2", 1, "output2", "error2", 1640995201)
+				rows := sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "content_type", "structured", "timestamp", "attempt", "execution"}).
+					AddRow("cmd-123", "minion-1", 0, "output1", "", "", "", 1640995200, 1, "").
+					AddRow("cmd-123", "minion-2", 1, "output2", "error2", "", "", 1640995201, 1, "")
 
-				mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr, EXTRACT\\(EPOCH FROM timestamp\\)::bigint FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC").
+				mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr, COALESCE\\(content_type, ''\\), COALESCE\\(structured, ''\\), EXTRACT\\(EPOCH FROM timestamp\\)::bigint, attempt, COALESCE\\(execution, ''\\) FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC").
 					WithArgs("cmd-123").
 					WillReturnRows(rows)
 			},
@@ -1315,9 +1319,9 @@ func TestGetCommandResults(t *testing.T) {
 					WithArgs("cmd-456").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
-				rows := sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "timestamp"})
+				rows := sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "content_type", "structured", "timestamp", "attempt", "execution"})
 
-				mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr, EXTRACT\\(EPOCH FROM timestamp\\)::bigint FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC").
+				mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr, COALESCE\\(content_type, ''\\), COALESCE\\(structured, ''\\), EXTRACT\\(EPOCH FROM timestamp\\)::bigint, attempt, COALESCE\\(execution, ''\\) FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC").
 					WithArgs("cmd-456").
 					WillReturnRows(rows)
 			},
@@ -1333,7 +1337,7 @@ func TestGetCommandResults(t *testing.T) {
 					WithArgs("cmd-789").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
-				mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr, EXTRACT\\(EPOCH FROM timestamp\\)::bigint FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC").
+				mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr, COALESCE\\(content_type, ''\\), COALESCE\\(structured, ''\\), EXTRACT\\(EPOCH FROM timestamp\\)::bigint, attempt, COALESCE\\(execution, ''\\) FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC").
 					WithArgs("cmd-789").
 					WillReturnError(fmt.Errorf("database connection failed"))
 			},
@@ -1377,6 +1381,90 @@ func TestGetCommandResults(t *testing.T) {
 	}
 }
 
+// TestGetCommandResultsPagination tests paged command result retrieval
+func TestGetCommandResultsPagination(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+
+	// One extra result is fetched to know whether another page follows
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands WHERE id = \\$1").
+		WithArgs("cmd-123").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("FROM command_results WHERE command_id = \\$1 ORDER BY timestamp ASC, minion_id ASC LIMIT \\$2 OFFSET \\$3").
+		WithArgs("cmd-123", 3, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "content_type", "structured", "timestamp", "attempt", "execution"}).
+			AddRow("cmd-123", "minion-5", 0, "output5", "", "", "", 1640995200, 1, "").
+			AddRow("cmd-123", "minion-6", 0, "output6", "", "", "", 1640995201, 1, "").
+			AddRow("cmd-123", "minion-7", 0, "output7", "", "", "", 1640995202, 1, ""))
+
+	results, err := server.GetCommandResults(context.Background(), &pb.ResultRequest{CommandId: "cmd-123", Limit: 2, Offset: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results.Results) != 2 || !results.HasMore || results.Results[1].MinionId != "minion-6" {
+		t.Errorf("Expected first 2 results with more to follow, got %d results (has_more=%v)", len(results.Results), results.HasMore)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+
+	if _, err := server.GetCommandResults(context.Background(), &pb.ResultRequest{CommandId: "cmd-123", Limit: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for a negative limit, got %v", err)
+	}
+}
+
+// TestCommandResultExecution tests that the execution info of results is stored and read back
+func TestCommandResultExecution(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := createTestServer(db).dbService.(*DatabaseServiceImpl)
+	info := &pb.ExecutionInfo{WorkingDir: "/opt/app", User: "deploy", Path: "/usr/bin:/bin", DurationMs: 1500, MaxRssKb: 20480}
+	encoded, err := encodeExecution(info)
+	if err != nil {
+		t.Fatalf("encodeExecution failed: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WithArgs("cmd-1", "minion-1", int32(127), "", "not found", nil, nil, sqlmock.AnyArg(), false, nil, int32(1), encoded).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE commands SET status").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", ExitCode: 127, Stderr: "not found", Timestamp: time.Now().Unix(), Execution: info}
+	if err := service.StoreCommandResult(context.Background(), result); err != nil {
+		t.Fatalf("StoreCommandResult failed: %v", err)
+	}
+
+	// Results stored without execution info, or with an unreadable one, are returned without
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands WHERE id = \\$1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT command_id, minion_id, exit_code, stdout, stderr").
+		WillReturnRows(sqlmock.NewRows([]string{"command_id", "minion_id", "exit_code", "stdout", "stderr", "content_type", "structured", "timestamp", "attempt", "execution"}).
+			AddRow("cmd-1", "minion-1", 127, "", "not found", "", "", time.Now().Unix(), 1, encoded).
+			AddRow("cmd-1", "minion-2", 0, "ok", "", "", "", time.Now().Unix(), 1, "").
+			AddRow("cmd-1", "minion-3", 0, "ok", "", "", "", time.Now().Unix(), 1, "{corrupt"))
+	results, err := service.GetCommandResults(context.Background(), "cmd-1")
+	if err != nil || len(results) != 3 {
+		t.Fatalf("GetCommandResults failed: %v %v", results, err)
+	}
+	if !proto.Equal(results[0].Execution, info) || results[1].Execution != nil || results[2].Execution != nil {
+		t.Errorf("Unexpected execution info: %v %v %v", results[0].Execution, results[1].Execution, results[2].Execution)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
 // TestGetCommandResultsWithoutDatabase tests result retrieval without database
 func TestGetCommandResultsWithoutDatabase(t *testing.T) {
 	server := createTestServer(nil) // No database
@@ -1392,6 +1480,64 @@ func TestGetCommandResultsWithoutDatabase(t *testing.T) {
 	}
 }
 
+// TestStoreDuplicateCommandResult tests that resent command results are ignored and counted
+func TestStoreDuplicateCommandResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: "ok", Timestamp: time.Now().Unix()}
+
+	// A result resent after a lost acknowledgement is ignored without touching the command
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: resultUniqueConstraint})
+	mock.ExpectRollback()
+	if err := server.dbService.StoreCommandResult(context.Background(), result); !errors.Is(err, ErrDuplicateResult) {
+		t.Fatalf("Expected duplicate results to be reported, got %v", err)
+	}
+	if got := server.DuplicateResults(); got != 1 {
+		t.Errorf("Expected 1 duplicate result, got %d", got)
+	}
+
+	// A duplicate is acknowledged without notifying the completion of its command again
+	server.trackCommand("cmd-1", "uptime", []string{"minion-1"})
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: resultUniqueConstraint})
+	mock.ExpectRollback()
+	if err := server.handleCommandResult(context.Background(), result, zap.NewNop()); err != nil {
+		t.Fatalf("Expected duplicate results to be acknowledged, got %v", err)
+	}
+	if !server.awaitedCommand("cmd-1") {
+		t.Error("Expected the duplicate not to complete the command")
+	}
+
+	// Other constraint violations still fail
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: "command_results_pkey"})
+	mock.ExpectRollback()
+	if err := server.dbService.StoreCommandResult(context.Background(), result); err == nil {
+		t.Error("Expected other unique violations to fail")
+	}
+	if got := server.DuplicateResults(); got != 2 {
+		t.Errorf("Expected 2 duplicate results, got %d", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if got := createTestServer(nil).DuplicateResults(); got != 0 {
+		t.Errorf("Expected no duplicate result without database, got %d", got)
+	}
+}
+
 // TestSetTagsWithExistingRecord tests SetTags when database record exists
 func TestSetTagsWithExistingRecord(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -1412,8 +1558,8 @@ func TestSetTagsWithExistingRecord(t *testing.T) {
 			Os:       "linux",
 			Tags:     make(map[string]string),
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	// Mock the UPDATE operation to succeed (1 row affected)
@@ -1479,8 +1625,8 @@ func TestUpdateTagsWithExistingRecord(t *testing.T) {
 				"remove":  "me",
 			},
 		},
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
 	}
 
 	// Mock the UPDATE operation to succeed (1 row affected)
@@ -1597,10 +1743,10 @@ func TestRegisterWithExistingRecord(t *testing.T) {
 		Tags:     map[string]string{"env": "test"},
 	}
 
-	// Mock the INSERT operation for new registration (new architecture calls StoreHost)
-	mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
-		WithArgs(testMinionID, testHostname, testIP, testOS, sqlmock.AnyArg(), sqlmock.AnyArg(), `{"env":"test"}`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Mock the INSERT operation for new registration (new architecture calls RegisterHost)
+	mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
+		WithArgs(testMinionID, testHostname, testIP, testOS, sqlmock.AnyArg(), sqlmock.AnyArg(), `{"env":"test"}`, `{"env":"test"}`, TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow(`{"env":"test"}`))
 
 	response, err := server.Register(context.Background(), hostInfo)
 	if err != nil {
@@ -1653,10 +1799,10 @@ func TestRegisterWithoutID(t *testing.T) {
 		Tags:     nil, // Will be initialized
 	}
 
-	// Expect INSERT for new registration (new architecture calls StoreHost)
-	mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
-		WithArgs(sqlmock.AnyArg(), "new-host", "192.168.1.150", "linux", sqlmock.AnyArg(), sqlmock.AnyArg(), "{}").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Expect INSERT for new registration (new architecture calls RegisterHost)
+	mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
+		WithArgs(sqlmock.AnyArg(), "new-host", "192.168.1.150", "linux", sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "{}", TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow("{}"))
 
 	response, err := server.Register(context.Background(), hostInfo)
 	if err != nil {
@@ -1705,7 +1851,7 @@ func TestSendCommandSuccessful(t *testing.T) {
 			Id:   minionID1,
 			Tags: map[string]string{"env": "production"},
 		},
-		CommandCh: make(chan *pb.Command, 100),
+		Commands: NewCommandQueue(100),
 	}
 
 	server.GetMinionRegistryImpl().minions[minionID2] = &MinionConnectionImpl{
@@ -1713,16 +1859,16 @@ func TestSendCommandSuccessful(t *testing.T) {
 			Id:   minionID2,
 			Tags: map[string]string{"env": "production"},
 		},
-		CommandCh: make(chan *pb.Command, 100),
+		Commands: NewCommandQueue(100),
 	}
 
 	// Mock database inserts for both minions
-	mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\)").
-		WithArgs(sqlmock.AnyArg(), minionID1, "ls -la", sqlmock.AnyArg(), "SENT", "PENDING").
+	mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status, redacted, trace_id, impact, operator, targets\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11\\)").
+		WithArgs(sqlmock.AnyArg(), minionID1, "ls -la", sqlmock.AnyArg(), "SENT", "PENDING", false, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\)").
-		WithArgs(sqlmock.AnyArg(), minionID2, "ls -la", sqlmock.AnyArg(), "SENT", "PENDING").
+	mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status, redacted, trace_id, impact, operator, targets\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11\\)").
+		WithArgs(sqlmock.AnyArg(), minionID2, "ls -la", sqlmock.AnyArg(), "SENT", "PENDING", false, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	req := &pb.CommandRequest{
@@ -1747,24 +1893,22 @@ func TestSendCommandSuccessful(t *testing.T) {
 	}
 
 	// Verify commands were sent to minions
-	select {
-	case cmd := <-server.GetMinionRegistryImpl().minions[minionID1].CommandCh:
+	if cmd, ok := server.GetMinionRegistryImpl().minions[minionID1].Commands.Pop(); ok {
 		if cmd.Payload != "ls -la" {
 			t.Errorf("Expected payload 'ls -la', got '%s'", cmd.Payload)
 		}
 		if cmd.Id != response.CommandId {
 			t.Errorf("Expected command ID %s, got %s", response.CommandId, cmd.Id)
 		}
-	default:
+	} else {
 		t.Error("Expected command to be sent to minion-1")
 	}
 
-	select {
-	case cmd := <-server.GetMinionRegistryImpl().minions[minionID2].CommandCh:
+	if cmd, ok := server.GetMinionRegistryImpl().minions[minionID2].Commands.Pop(); ok {
 		if cmd.Payload != "ls -la" {
 			t.Errorf("Expected payload 'ls -la', got '%s'", cmd.Payload)
 		}
-	default:
+	} else {
 		t.Error("Expected command to be sent to minion-2")
 	}
 
@@ -1806,6 +1950,60 @@ func TestSendCommandNoTargets(t *testing.T) {
 	}
 }
 
+// TestSendCommandDryRun tests that dry runs resolve targets without dispatching
+func TestSendCommandDryRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	registry := server.GetMinionRegistryImpl()
+	for _, id := range []string{"minion-1", "minion-2"} {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id, Tags: map[string]string{"env": "prod"}},
+			LastSeen: time.Now(),
+			Commands: NewCommandQueue(100),
+		}
+	}
+
+	req := &pb.CommandRequest{
+		TagSelector: &pb.TagSelector{
+			Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}},
+		},
+		Command: &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+		DryRun:  true,
+	}
+
+	response, err := server.SendCommand(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !response.Accepted || !response.DryRun {
+		t.Errorf("Expected accepted dry run response, got %+v", response)
+	}
+	if response.CommandId != "" {
+		t.Errorf("Expected no command ID for dry run, got %s", response.CommandId)
+	}
+	if len(response.TargetMinionIds) != 2 {
+		t.Errorf("Expected 2 target minions, got %v", response.TargetMinionIds)
+	}
+
+	for id, conn := range registry.minions {
+		if conn.Commands.Len() != 0 {
+			t.Errorf("Dry run must not dispatch commands, minion %s received one", id)
+		}
+	}
+	if len(server.pendingCommands) != 0 {
+		t.Error("Dry run must not track commands")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Dry run must not touch the database: %v", err)
+	}
+}
+
 // TestValidateCommandInternal tests command validation logic
 func TestValidateCommandInternal(t *testing.T) {
 	db, _, err := sqlmock.New()
@@ -1983,16 +2181,16 @@ func TestStreamCommands(t *testing.T) {
 				minionID := "test-minion"
 				registry := s.GetMinionRegistryImpl()
 				registry.minions[minionID] = &MinionConnectionImpl{
-					Info:      &pb.HostInfo{Id: minionID},
-					CommandCh: make(chan *pb.Command, 10),
-					LastSeen:  time.Now(),
+					Info:     &pb.HostInfo{Id: minionID},
+					Commands: NewCommandQueue(10),
+					LastSeen: time.Now(),
 				}
 
-				// Pre-populate the command channel
-				registry.minions[minionID].CommandCh <- &pb.Command{
+				// Pre-populate the command queue
+				registry.minions[minionID].Commands.Push(&pb.Command{
 					Id:      "cmd-1",
 					Payload: "test command",
-				}
+				})
 
 				return minionID
 			},
@@ -2032,17 +2230,28 @@ func TestStreamCommands(t *testing.T) {
 					t.Error("Expected LastSeen to be updated")
 				}
 
-				// Verify command was sent
-				if len(stream.sentMsgs) != 1 {
-					t.Errorf("Expected 1 command to be sent, got %d", len(stream.sentMsgs))
+				// Verify command was sent, and the result acknowledged
+				if len(stream.sentMsgs) != 2 {
+					t.Errorf("Expected a command and an acknowledgement to be sent, got %d messages", len(stream.sentMsgs))
 				} else {
-					msg := stream.sentMsgs[0]
-					cmd := msg.GetCommand()
+					var cmd *pb.Command
+					var ack *pb.ResultAck
+					for _, msg := range stream.sentMsgs {
+						if msg.GetCommand() != nil {
+							cmd = msg.GetCommand()
+						}
+						if msg.GetAck() != nil {
+							ack = msg.GetAck()
+						}
+					}
 					if cmd == nil {
 						t.Error("Expected CommandStreamMessage to contain a Command")
 					} else if cmd.Payload != "test command" {
 						t.Errorf("Expected command payload 'test command', got '%s'", cmd.Payload)
 					}
+					if ack == nil || ack.CommandId != "cmd-1" {
+						t.Errorf("Expected the result of cmd-1 acknowledged, got %v", ack)
+					}
 				}
 
 				// Verify status and result messages were processed
@@ -2081,8 +2290,8 @@ func TestStreamCommands(t *testing.T) {
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 
 				// 3. Insert result
-				mock.ExpectExec("INSERT INTO command_results \\(command_id, minion_id, exit_code, stdout, stderr, timestamp\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\)").
-					WithArgs("cmd-1", "test-minion", int32(0), "test output", "", sqlmock.AnyArg()).
+				mock.ExpectExec("INSERT INTO command_results \\(command_id, minion_id, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, attempt, execution\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11, \\$12\\)").
+					WithArgs("cmd-1", "test-minion", int32(0), "test output", "", nil, nil, sqlmock.AnyArg(), false, nil, int32(1), nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				// 4. Update command status to COMPLETED
@@ -2134,18 +2343,18 @@ func TestStreamCommandsError(t *testing.T) {
 
 	minionID := "test-minion"
 	server.GetMinionRegistryImpl().minions[minionID] = &MinionConnectionImpl{
-		Info:      &pb.HostInfo{Id: minionID},
-		CommandCh: make(chan *pb.Command, 10),
-		LastSeen:  time.Now(),
+		Info:     &pb.HostInfo{Id: minionID},
+		Commands: NewCommandQueue(10),
+		LastSeen: time.Now(),
 	}
 
-	// Send a command and close the channel
+	// Send a command and close the queue
 	go func() {
-		server.GetMinionRegistryImpl().minions[minionID].CommandCh <- &pb.Command{
+		server.GetMinionRegistryImpl().minions[minionID].Commands.Push(&pb.Command{
 			Id:      "cmd-1",
 			Payload: "test command",
-		}
-		close(server.GetMinionRegistryImpl().minions[minionID].CommandCh)
+		})
+		server.GetMinionRegistryImpl().minions[minionID].Commands.Close()
 	}()
 
 	md := metadata.New(map[string]string{"minion-id": minionID})
@@ -2162,8 +2371,8 @@ func TestStreamCommandsError(t *testing.T) {
 	}
 }
 
-// TestSendCommandChannelFull tests command dispatch when minion channel is full
-func TestSendCommandChannelFull(t *testing.T) {
+// TestSendCommandQueueFull tests command dispatch when minion queue is full
+func TestSendCommandQueueFull(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Failed to create mock database: %v", err)
@@ -2177,16 +2386,16 @@ func TestSendCommandChannelFull(t *testing.T) {
 		Info: &pb.HostInfo{
 			Id: minionID,
 		},
-		CommandCh: make(chan *pb.Command, 1), // Small buffer
-		LastSeen:  time.Now(),
+		Commands: NewCommandQueue(1), // Small buffer
+		LastSeen: time.Now(),
 	}
 
-	// Fill the channel
-	server.GetMinionRegistryImpl().minions[minionID].CommandCh <- &pb.Command{Id: "existing"}
+	// Fill the queue
+	server.GetMinionRegistryImpl().minions[minionID].Commands.Push(&pb.Command{Id: "existing"})
 
 	// Mock database insert
-	mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6\\)").
-		WithArgs(sqlmock.AnyArg(), minionID, "ls -la", sqlmock.AnyArg(), "SENT", "PENDING").
+	mock.ExpectExec("INSERT INTO commands \\(id, host_id, command, timestamp, direction, status, redacted, trace_id, impact, operator, targets\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8, \\$9, \\$10, \\$11\\)").
+		WithArgs(sqlmock.AnyArg(), minionID, "ls -la", sqlmock.AnyArg(), "SENT", "PENDING", false, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	req := &pb.CommandRequest{
@@ -2203,10 +2412,10 @@ func TestSendCommandChannelFull(t *testing.T) {
 	}
 
 	if !response.Accepted {
-		t.Error("Expected command to be accepted even if channel is full")
+		t.Error("Expected command to be accepted even if queue is full")
 	}
 
-	// The command should still be logged to database but not sent to the full channel
+	// The command should still be logged to database but not sent to the full queue
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled mock expectations: %v", err)
 	}
@@ -2223,7 +2432,7 @@ func TestDatabaseErrors(t *testing.T) {
 
 		server := createTestServer(db)
 
-		mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
+		mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
 			WillReturnError(fmt.Errorf("database connection failed"))
 
 		hostInfo := &pb.HostInfo{
@@ -2249,7 +2458,7 @@ func TestDatabaseErrors(t *testing.T) {
 
 		server := createTestServer(db)
 
-		mock.ExpectExec("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) ON CONFLICT \\(id\\) DO UPDATE SET hostname = EXCLUDED.hostname, ip = EXCLUDED.ip, os = EXCLUDED.os, last_seen = EXCLUDED.last_seen, tags = EXCLUDED.tags").
+		mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) ON CONFLICT \\(id\\) DO UPDATE SET").
 			WillReturnError(fmt.Errorf("insert failed"))
 
 		hostInfo := &pb.HostInfo{
@@ -2379,8 +2588,8 @@ func TestSendCommandWithoutDatabase(t *testing.T) {
 
 	minionID := "test-minion"
 	server.GetMinionRegistryImpl().minions[minionID] = &MinionConnectionImpl{
-		Info:      &pb.HostInfo{Id: minionID},
-		CommandCh: make(chan *pb.Command, 100),
+		Info:     &pb.HostInfo{Id: minionID},
+		Commands: NewCommandQueue(100),
 	}
 
 	req := &pb.CommandRequest{
@@ -2401,12 +2610,11 @@ func TestSendCommandWithoutDatabase(t *testing.T) {
 	}
 
 	// Verify command was sent
-	select {
-	case cmd := <-server.GetMinionRegistryImpl().minions[minionID].CommandCh:
+	if cmd, ok := server.GetMinionRegistryImpl().minions[minionID].Commands.Pop(); ok {
 		if cmd.Payload != "echo hello" {
 			t.Errorf("Expected payload 'echo hello', got '%s'", cmd.Payload)
 		}
-	default:
+	} else {
 		t.Error("Expected command to be sent to minion")
 	}
 }
@@ -2429,8 +2637,8 @@ func TestConcurrentAccess(t *testing.T) {
 				Id:   minionID,
 				Tags: map[string]string{"index": fmt.Sprintf("%d", i)},
 			},
-			CommandCh: make(chan *pb.Command, 100),
-			LastSeen:  time.Now(),
+			Commands: NewCommandQueue(100),
+			LastSeen: time.Now(),
 		}
 	}
 
@@ -2501,9 +2709,9 @@ func TestStreamCommandsWithStatusUpdates(t *testing.T) {
 	// Add test minion
 	minionID := "test-minion"
 	server.GetMinionRegistryImpl().minions[minionID] = &MinionConnectionImpl{
-		Info:      &pb.HostInfo{Id: minionID},
-		CommandCh: make(chan *pb.Command, 10),
-		LastSeen:  time.Now(),
+		Info:     &pb.HostInfo{Id: minionID},
+		Commands: NewCommandQueue(10),
+		LastSeen: time.Now(),
 	}
 
 	// Mock database operations for status update
@@ -2557,3 +2765,81 @@ func TestStreamCommandsWithStatusUpdates(t *testing.T) {
 		t.Errorf("Unfulfilled mock expectations: %v", err)
 	}
 }
+
+func TestHandleScheduledCommandResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+
+	// The command of a scheduled task is stored before its result, with the impact class of a shell
+	// command dispatched by Nexus
+	mock.ExpectExec("INSERT INTO commands").
+		WithArgs("sched-1a2b3c4d-1700000000", "minion-1", "df -h", sqlmock.AnyArg(), "SENT", "PENDING", false, sqlmock.AnyArg(), "mutating", nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs("sched-1a2b3c4d-1700000000", "minion-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE commands SET status").
+		WithArgs("COMPLETED", "sched-1a2b3c4d-1700000000", "minion-1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	server.handleCommandResult(context.Background(), &pb.CommandResult{
+		CommandId:  "sched-1a2b3c4d-1700000000",
+		MinionId:   "minion-1",
+		Stdout:     "Filesystem ...",
+		Timestamp:  1700000000,
+		ScheduleId: "1a2b3c4d",
+		Payload:    "df -h",
+	}, zap.NewNop())
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetCommandStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+
+	mock.ExpectQuery("SELECT DISTINCT ON \\(c.host_id\\)").
+		WithArgs("cmd-1").
+		WillReturnRows(sqlmock.NewRows([]string{"host_id", "status", "timestamp"}).
+			AddRow("minion-1", "COMPLETED", 1640995200).
+			AddRow("minion-2", "FAILED", 1640995201).
+			AddRow("minion-3", "EXECUTING", 1640995100))
+
+	response, err := server.GetCommandStatus(context.Background(), &pb.ResultRequest{CommandId: "cmd-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(response.Statuses) != 3 || response.StatusCounts["COMPLETED"] != 1 || response.StatusCounts["FAILED"] != 1 || response.StatusCounts["EXECUTING"] != 1 {
+		t.Errorf("Unexpected command status: %v", response)
+	}
+
+	mock.ExpectQuery("SELECT DISTINCT ON \\(c.host_id\\)").
+		WithArgs("cmd-unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"host_id", "status", "timestamp"}))
+	if _, err := server.GetCommandStatus(context.Background(), &pb.ResultRequest{CommandId: "cmd-unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown command, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+
+	if _, err := createTestServer(nil).GetCommandStatus(context.Background(), &pb.ResultRequest{CommandId: "cmd-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
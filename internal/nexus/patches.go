@@ -0,0 +1,135 @@
+package nexus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// patchReport is the last successful patch:status of a minion
+type patchReport struct {
+	status    command.PatchStatus
+	timestamp int64 // unix time of the result
+}
+
+// GetPatchCompliance summarizes the last successful patch:status of the minions in the ConsoleService,
+// grouped by the value of a tag
+func (s *Server) GetPatchCompliance(ctx context.Context, req *pb.PatchComplianceRequest) (*pb.PatchCompliance, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetPatchCompliance")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "patch compliance requires a database")
+	}
+	list, err := s.ListMinions(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.dbService.LatestPatchStatuses(ctx)
+	if err != nil {
+		logger.Error("Failed to get patch statuses", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get patch statuses")
+	}
+
+	compliance := &pb.PatchCompliance{GroupBy: req.GroupBy, Total: &pb.PatchGroup{}}
+	groups := make(map[string]*pb.PatchGroup)
+	for _, minion := range list.Minions {
+		report, reported := reports[minion.Id]
+		countPatchStatus(compliance.Total, report, reported)
+		if req.GroupBy == "" {
+			continue
+		}
+		value := minion.Tags[req.GroupBy]
+		group, exists := groups[value]
+		if !exists {
+			group = &pb.PatchGroup{Value: value}
+			groups[value] = group
+			compliance.Groups = append(compliance.Groups, group)
+		}
+		countPatchStatus(group, report, reported)
+	}
+	sort.Slice(compliance.Groups, func(i, j int) bool {
+		a, b := compliance.Groups[i], compliance.Groups[j]
+		if a.SecurityUpdates != b.SecurityUpdates {
+			return a.SecurityUpdates > b.SecurityUpdates
+		}
+		return a.Value < b.Value
+	})
+
+	logger.Debug("Computed patch compliance",
+		zap.String("group_by", req.GroupBy),
+		zap.Int32("minions", compliance.Total.Minions),
+		zap.Int32("vulnerable", compliance.Total.Vulnerable),
+		zap.Int32("unknown", compliance.Total.Unknown))
+	return compliance, nil
+}
+
+// countPatchStatus counts the last patch:status of a minion in group, the minion being unknown
+// without report
+func countPatchStatus(group *pb.PatchGroup, report patchReport, reported bool) {
+	group.Minions++
+	if !reported {
+		group.Unknown++
+		return
+	}
+	if report.status.Security > 0 {
+		group.Vulnerable++
+	} else {
+		group.Compliant++
+	}
+	if report.status.RebootRequired {
+		group.RebootRequired++
+	}
+	group.SecurityUpdates += int32(report.status.Security)
+	group.Updates += int32(report.status.Updates)
+	if group.OldestStatus == 0 || report.timestamp < group.OldestStatus {
+		group.OldestStatus = report.timestamp
+	}
+}
+
+// LatestPatchStatuses returns the last successful patch:status result of every minion that ran one
+func (d *DatabaseServiceImpl) LatestPatchStatuses(ctx context.Context) (map[string]patchReport, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.LatestPatchStatuses")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT DISTINCT ON (minion_id) minion_id, EXTRACT(EPOCH FROM timestamp)::bigint, structured FROM command_results
+		WHERE content_type = $1 AND exit_code = 0 ORDER BY minion_id, timestamp DESC`,
+		command.ContentTypePatchStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query patch statuses: %v", err)
+	}
+	defer rows.Close()
+
+	reports := make(map[string]patchReport)
+	for rows.Next() {
+		var minionID, structured string
+		var report patchReport
+		if err := rows.Scan(&minionID, &report.timestamp, &structured); err != nil {
+			return nil, fmt.Errorf("failed to scan patch status: %v", err)
+		}
+		if err := json.Unmarshal([]byte(structured), &report.status); err != nil {
+			// Counted as unknown, as if the minion never reported
+			logger.Warn("Ignoring an invalid patch status", zap.String("minion_id", minionID), zap.Error(err))
+			continue
+		}
+		reports[minionID] = report
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read patch statuses: %v", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,73 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetPatchCompliance(t *testing.T) {
+	if _, err := createTestServer(nil).GetPatchCompliance(context.Background(), &pb.PatchComplianceRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+	registry := server.GetMinionRegistryImpl()
+	for id, env := range map[string]string{"web-1": "prod", "web-2": "prod", "db-1": "prod", "ci-1": "staging", "lab-1": ""} {
+		tags := map[string]string{}
+		if env != "" {
+			tags["env"] = env
+		}
+		registry.minions[id] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: id, Tags: tags}}
+	}
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"minion_id", "timestamp", "structured"}).
+			AddRow("ci-1", int64(1700000500), `{"manager":"apt","updates":4,"security":0,"reboot_required":false,"packages":[]}`).
+			AddRow("db-1", int64(1700000100), `{"manager":"dnf","updates":10,"security":3,"reboot_required":true,"packages":[]}`).
+			AddRow("gone-1", int64(1700000000), `{"manager":"apt","updates":1,"security":1,"packages":[]}`).
+			AddRow("web-1", int64(1700000200), `{"manager":"apt","updates":2,"security":1,"reboot_required":false,"packages":[]}`).
+			AddRow("web-2", int64(1700000300), `not json`)
+	}
+	mock.ExpectQuery("SELECT DISTINCT ON \\(minion_id\\) minion_id").WithArgs(command.ContentTypePatchStatus).WillReturnRows(rows())
+	compliance, err := server.GetPatchCompliance(context.Background(), &pb.PatchComplianceRequest{GroupBy: "env"})
+	if err != nil {
+		t.Fatalf("GetPatchCompliance failed: %v", err)
+	}
+
+	total := compliance.Total
+	if total.Minions != 5 || total.Compliant != 1 || total.Vulnerable != 2 || total.Unknown != 2 || total.RebootRequired != 1 ||
+		total.SecurityUpdates != 4 || total.Updates != 16 || total.OldestStatus != 1700000100 {
+		t.Errorf("Unexpected total: %v", total)
+	}
+	if len(compliance.Groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %v", compliance.Groups)
+	}
+	// Most security updates pending first, then by value
+	prod, untagged, staging := compliance.Groups[0], compliance.Groups[1], compliance.Groups[2]
+	if prod.Value != "prod" || prod.Minions != 3 || prod.Vulnerable != 2 || prod.Unknown != 1 || prod.SecurityUpdates != 4 {
+		t.Errorf("Unexpected prod group: %v", prod)
+	}
+	if staging.Value != "staging" || staging.Compliant != 1 || untagged.Value != "" || untagged.Unknown != 1 {
+		t.Errorf("Unexpected groups: %v %v", staging, untagged)
+	}
+
+	mock.ExpectQuery("FROM command_results").WillReturnRows(rows())
+	compliance, err = server.GetPatchCompliance(context.Background(), &pb.PatchComplianceRequest{})
+	if err != nil || len(compliance.Groups) != 0 || compliance.Total.Minions != 5 {
+		t.Errorf("Expected the fleet total only without group, got %v %v", compliance, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
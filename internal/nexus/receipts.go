@@ -0,0 +1,367 @@
+package nexus
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultReceiptRange is the range of commands exported when the request sets no start
+	defaultReceiptRange = 24 * time.Hour
+	// maxReceiptCommands bounds the commands of an export, longer ranges being exported in parts
+	maxReceiptCommands = 100000
+)
+
+// ExecutionRecord is a stored command with its results, certified by an execution receipt
+type ExecutionRecord struct {
+	CommandID  string
+	TraceID    string
+	Payload    string // redacted payload, as stored
+	Redacted   bool
+	Impact     string
+	Operator   string // console that sent the command, empty when unknown
+	ApprovedBy string // operator who approved the command, empty when no approval was required
+	SentAt     time.Time
+	Targets    []string // minions the command was resolved to, answered or not
+	Results    []ExecutionResult
+	Archived   bool // results moved to the archive, not covered by the receipt
+}
+
+// ExecutionResult is the result of a minion certified by an execution receipt, its output reduced
+// to a digest
+type ExecutionResult struct {
+	MinionID     string
+	ExitCode     int32
+	Attempt      int32
+	ReceivedAt   time.Time
+	OutputDigest string // SHA-256 of stdout, a NUL byte and stderr
+}
+
+// executionReceiptsQuery selects the commands sent in a range, oldest first, with their results
+const executionReceiptsQuery = `SELECT c.id, COALESCE(c.host_id, ''), COALESCE(c.targets::text, ''), c.command, c.redacted,
+		COALESCE(c.trace_id, ''), COALESCE(c.impact, ''), COALESCE(c.operator, ''), COALESCE(a.decided_by, ''),
+		EXTRACT(EPOCH FROM c.timestamp)::bigint, ar.command_id IS NOT NULL,
+		r.minion_id, r.exit_code, r.stdout, r.stderr, EXTRACT(EPOCH FROM r.timestamp)::bigint, r.attempt
+	FROM (SELECT * FROM commands WHERE timestamp >= $1 AND timestamp < $2 ORDER BY timestamp, id LIMIT $3) c
+	LEFT JOIN command_approvals a ON a.command_id = c.id AND a.status = 'APPROVED'
+	LEFT JOIN archived_results ar ON ar.command_id = c.id
+	LEFT JOIN command_results r ON r.command_id = c.id
+	ORDER BY c.timestamp, c.id, r.minion_id`
+
+// CountExecutions returns the number of commands sent between since and until
+func (d *DatabaseServiceImpl) CountExecutions(ctx context.Context, since, until time.Time) (int, error) {
+	if d == nil || d.db == nil {
+		return 0, fmt.Errorf("database service unavailable - cannot count executions")
+	}
+
+	var count int
+	if err := d.reader().QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM commands WHERE timestamp >= $1 AND timestamp < $2", since, until).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count executions: %v", err)
+	}
+	return count, nil
+}
+
+// StreamExecutions calls fn with each of the first limit commands sent between since and until,
+// oldest first, with their results. The outputs are reduced to their digest as the rows are read,
+// so that only one command is held in memory. An error of fn stops the stream and is returned as is.
+func (d *DatabaseServiceImpl) StreamExecutions(ctx context.Context, since, until time.Time, limit int, fn func(*ExecutionRecord) error) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot list executions")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StreamExecutions")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx, executionReceiptsQuery, since, until, limit)
+	if err != nil {
+		return fmt.Errorf("failed to query executions: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	var record *ExecutionRecord
+	var answered []string // targets of commands stored before the targets were recorded
+	flush := func() error {
+		if record == nil {
+			return nil
+		}
+		record.Targets = append(record.Targets, answered...)
+		sort.Strings(record.Targets)
+		record.Targets = slices.Compact(record.Targets)
+		count++
+		return fn(record)
+	}
+	for rows.Next() {
+		var current ExecutionRecord
+		var hostID, targets string
+		var sentAt int64
+		var minionID, stdout, stderr sql.NullString
+		var exitCode, attempt sql.NullInt32
+		var receivedAt sql.NullInt64
+		if err := rows.Scan(&current.CommandID, &hostID, &targets, &current.Payload, &current.Redacted, &current.TraceID,
+			&current.Impact, &current.Operator, &current.ApprovedBy, &sentAt, &current.Archived,
+			&minionID, &exitCode, &stdout, &stderr, &receivedAt, &attempt); err != nil {
+			return fmt.Errorf("failed to scan execution: %v", err)
+		}
+
+		if record == nil || record.CommandID != current.CommandID {
+			if err := flush(); err != nil {
+				return err
+			}
+			payload, err := d.encryptor.Decrypt(current.Payload, encryptionBinding{current.CommandID, hostID, payloadColumn})
+			if err != nil {
+				logger.Warn("Failed to decrypt command payload",
+					zap.String("command_id", current.CommandID),
+					zap.Error(err))
+				payload = undecryptableValue
+			}
+			current.Payload = payload
+			current.SentAt = time.Unix(sentAt, 0)
+			answered = nil
+			if targets != "" {
+				if err := json.Unmarshal([]byte(targets), &current.Targets); err != nil {
+					return fmt.Errorf("invalid targets of command %s: %v", current.CommandID, err)
+				}
+			} else if hostID != "" {
+				answered = []string{hostID}
+			}
+			record = &current
+		}
+		if !minionID.Valid {
+			continue
+		}
+
+		result := &pb.CommandResult{
+			CommandId: record.CommandID,
+			MinionId:  minionID.String,
+			Stdout:    stdout.String,
+			Stderr:    stderr.String,
+		}
+		if err := d.encryptor.DecryptResult(result); err != nil {
+			logger.Warn("Failed to decrypt command result output",
+				zap.String("command_id", result.CommandId),
+				zap.String("minion_id", result.MinionId),
+				zap.Error(err))
+		}
+		output := sha256.Sum256([]byte(result.Stdout + "\x00" + result.Stderr))
+		record.Results = append(record.Results, ExecutionResult{
+			MinionID:     minionID.String,
+			ExitCode:     exitCode.Int32,
+			Attempt:      attempt.Int32,
+			ReceivedAt:   time.Unix(receivedAt.Int64, 0),
+			OutputDigest: hex.EncodeToString(output[:]),
+		})
+		if targets == "" {
+			answered = append(answered, minionID.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading executions: %v", err)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	logger.Debug("Streamed executions",
+		zap.Time("since", since),
+		zap.Time("until", until),
+		zap.Int("count", count))
+	return nil
+}
+
+// executionReceipt is the JSON receipt of a command signed by Nexus
+type executionReceipt struct {
+	Sequence        int             `json:"sequence"`        // position in the export, from 1
+	Total           int             `json:"total"`           // receipts in the export, revealing truncated exports
+	PreviousDigest  string          `json:"previous_digest"` // SHA-256 of the previous receipt, empty for the first
+	Since           time.Time       `json:"since"`
+	Until           time.Time       `json:"until"`
+	CommandID       string          `json:"command_id"`
+	TraceID         string          `json:"trace_id,omitempty"`
+	Command         string          `json:"command"`
+	Redacted        bool            `json:"redacted,omitempty"`
+	Impact          string          `json:"impact,omitempty"`
+	Operator        string          `json:"operator"`
+	ApprovedBy      string          `json:"approved_by,omitempty"`
+	Targets         []string        `json:"targets"`
+	SentAt          time.Time       `json:"sent_at"`
+	CompletedAt     *time.Time      `json:"completed_at,omitempty"` // last result received
+	Results         []receiptResult `json:"results"`
+	ResultsDigest   string          `json:"results_digest"` // SHA-256 of the JSON of results
+	ResultsArchived bool            `json:"results_archived,omitempty"`
+	IssuedAt        time.Time       `json:"issued_at"`
+}
+
+// receiptResult is the result of a minion certified by an execution receipt
+type receiptResult struct {
+	MinionID     string    `json:"minion_id"`
+	ExitCode     int32     `json:"exit_code"`
+	Attempt      int32     `json:"attempt"`
+	ReceivedAt   time.Time `json:"received_at"`
+	OutputDigest string    `json:"output_digest"` // SHA-256 of stdout, a NUL byte and stderr
+}
+
+// newExecutionReceipt returns the receipt of a stored command
+func newExecutionReceipt(record *ExecutionRecord, since, until, issuedAt time.Time) (*executionReceipt, error) {
+	receipt := &executionReceipt{
+		Since:           since.UTC(),
+		Until:           until.UTC(),
+		CommandID:       record.CommandID,
+		TraceID:         record.TraceID,
+		Command:         record.Payload,
+		Redacted:        record.Redacted,
+		Impact:          record.Impact,
+		Operator:        record.Operator,
+		ApprovedBy:      record.ApprovedBy,
+		Targets:         record.Targets,
+		SentAt:          record.SentAt.UTC(),
+		Results:         make([]receiptResult, 0, len(record.Results)),
+		ResultsArchived: record.Archived,
+		IssuedAt:        issuedAt.UTC(),
+	}
+	if receipt.Targets == nil {
+		receipt.Targets = []string{}
+	}
+	for _, result := range record.Results {
+		receivedAt := result.ReceivedAt.UTC()
+		receipt.Results = append(receipt.Results, receiptResult{
+			MinionID:     result.MinionID,
+			ExitCode:     result.ExitCode,
+			Attempt:      max(result.Attempt, 1),
+			ReceivedAt:   receivedAt,
+			OutputDigest: result.OutputDigest,
+		})
+		if receipt.CompletedAt == nil || receivedAt.After(*receipt.CompletedAt) {
+			receipt.CompletedAt = &receivedAt
+		}
+	}
+
+	results, err := json.Marshal(receipt.Results)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(results)
+	receipt.ResultsDigest = hex.EncodeToString(digest[:])
+	return receipt, nil
+}
+
+// SetReceiptSigner replaces the signer of execution receipts, Nexus signing them with its server
+// certificate by default
+func (s *Server) SetReceiptSigner(signer *certs.CommandSigner) {
+	s.receiptSigner = signer
+}
+
+// ExportReceipts streams the signed and chained execution receipts of the commands sent between the
+// since and until of req in the ConsoleService, oldest first
+func (s *Server) ExportReceipts(req *pb.ReceiptExportRequest, stream pb.ConsoleService_ExportReceiptsServer) error {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ExportReceipts")
+	defer logging.FuncExit(logger, start)
+
+	ctx := stream.Context()
+	if s.dbService == nil {
+		return status.Error(codes.Unavailable, "compliance receipts require a database")
+	}
+	if err := requireAllNamespaces(ctx, "compliance receipts"); err != nil {
+		return err
+	}
+
+	until := time.Now()
+	if req.Until > 0 {
+		until = time.Unix(req.Until, 0)
+	}
+	since := until.Add(-defaultReceiptRange)
+	if req.Since > 0 {
+		since = time.Unix(req.Since, 0)
+	}
+	if !since.Before(until) {
+		return status.Error(codes.InvalidArgument, "since must be before until")
+	}
+
+	signer := s.receiptSigner
+	if signer == nil {
+		var err error
+		if signer, err = certs.NewCommandSigner(certs.CertPEM, certs.KeyPEM); err != nil {
+			logger.Error("Failed to load the receipt signing certificate", zap.Error(err))
+			return status.Error(codes.Internal, "failed to load the receipt signing certificate")
+		}
+	}
+
+	total, err := s.dbService.CountExecutions(ctx, since, until)
+	if err != nil {
+		logger.Error("Failed to count executions", zap.Error(err))
+		return status.Error(codes.Internal, "failed to list executions")
+	}
+	if total > maxReceiptCommands {
+		return status.Errorf(codes.ResourceExhausted, "more than %d commands were sent in the range, export a shorter range", maxReceiptCommands)
+	}
+
+	// Receipts are sent as the commands are read, the stream errors ending the export as they are
+	issuedAt := time.Now()
+	previous := ""
+	sequence := 0
+	var sendErr error
+	err = s.dbService.StreamExecutions(ctx, since, until, total, func(record *ExecutionRecord) error {
+		sequence++
+		receipt, err := newExecutionReceipt(record, since, until, issuedAt)
+		if err != nil {
+			sendErr = status.Errorf(codes.Internal, "failed to build the receipt of command %s: %v", record.CommandID, err)
+			return sendErr
+		}
+		receipt.Sequence = sequence
+		receipt.Total = total
+		receipt.PreviousDigest = previous
+
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			sendErr = status.Errorf(codes.Internal, "failed to build the receipt of command %s: %v", record.CommandID, err)
+			return sendErr
+		}
+		signature, err := signer.SignData(data)
+		if err != nil {
+			logger.Error("Failed to sign receipt", zap.String("command_id", record.CommandID), zap.Error(err))
+			sendErr = status.Error(codes.Internal, "failed to sign receipts")
+			return sendErr
+		}
+		if sendErr = stream.Send(&pb.ExecutionReceipt{
+			Receipt:           data,
+			Signature:         signature,
+			SignerCertificate: signer.Certificate(),
+		}); sendErr != nil {
+			return sendErr
+		}
+
+		digest := sha256.Sum256(data)
+		previous = hex.EncodeToString(digest[:])
+		return nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		logger.Error("Failed to list executions", zap.Error(err))
+		return status.Error(codes.Internal, "failed to list executions")
+	}
+
+	logger.Info("Execution receipts exported",
+		zap.String("operator", consoleIdentity(ctx)),
+		zap.Time("since", since),
+		zap.Time("until", until),
+		zap.Int("count", sequence))
+	return nil
+}
@@ -0,0 +1,118 @@
+package nexus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/arhuman/minexus/internal/certs"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mockExportReceiptsStream is an ExportReceipts server stream collecting the receipts sent
+type mockExportReceiptsStream struct {
+	grpc.ServerStream
+	receipts []*pb.ExecutionReceipt
+}
+
+func (m *mockExportReceiptsStream) Context() context.Context { return context.Background() }
+
+func (m *mockExportReceiptsStream) Send(msg *pb.ExecutionReceipt) error {
+	m.receipts = append(m.receipts, msg)
+	return nil
+}
+
+var executionColumns = []string{"id", "host_id", "targets", "command", "redacted", "trace_id", "impact", "operator", "decided_by",
+	"timestamp", "archived", "minion_id", "exit_code", "stdout", "stderr", "result_timestamp", "attempt"}
+
+func TestExportReceipts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	since, until := time.Unix(1700000000, 0), time.Unix(1700086400, 0)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM commands WHERE timestamp >= \\$1 AND timestamp < \\$2").
+		WithArgs(since, until).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	// cmd-1 was resolved to a minion that never answered, cmd-2 was stored before the targets were recorded
+	mock.ExpectQuery("FROM \\(SELECT \\* FROM commands WHERE timestamp >= \\$1").
+		WithArgs(since, until, 2).
+		WillReturnRows(sqlmock.NewRows(executionColumns).
+			AddRow("cmd-1", "minion-1", `["minion-1","minion-2","minion-4"]`, "systemctl restart nginx", false, "trace-1", "disruptive", "alice", "bob",
+				1700000100, false, "minion-1", 0, "ok", "", 1700000105, 1).
+			AddRow("cmd-1", "minion-1", `["minion-1","minion-2","minion-4"]`, "systemctl restart nginx", false, "trace-1", "disruptive", "alice", "bob",
+				1700000100, false, "minion-2", 1, "", "failed", 1700000110, 2).
+			AddRow("cmd-2", "minion-3", "", "uptime", false, "", "read-only", "alice", "",
+				1700000200, false, nil, nil, nil, nil, nil, nil))
+
+	stream := &mockExportReceiptsStream{}
+	if err := server.ExportReceipts(&pb.ReceiptExportRequest{Since: since.Unix(), Until: until.Unix()}, stream); err != nil {
+		t.Fatalf("ExportReceipts failed: %v", err)
+	}
+	if len(stream.receipts) != 2 {
+		t.Fatalf("Expected 2 receipts, got %d", len(stream.receipts))
+	}
+
+	var receipts []executionReceipt
+	previous := ""
+	for _, signed := range stream.receipts {
+		cert, err := certs.CheckDataSignature(signed.SignerCertificate, signed.Receipt, signed.Signature)
+		if err != nil {
+			t.Fatalf("Expected a valid signature, got %v", err)
+		}
+		if string(signed.SignerCertificate) != string(certs.CertPEM) {
+			t.Errorf("Expected the receipts signed by the server certificate, got %s", cert.Subject.CommonName)
+		}
+		var receipt executionReceipt
+		if err := json.Unmarshal(signed.Receipt, &receipt); err != nil {
+			t.Fatalf("Invalid receipt: %v", err)
+		}
+		if receipt.PreviousDigest != previous || receipt.Total != 2 {
+			t.Errorf("Expected receipt %d chained to %q, got %q of %d", receipt.Sequence, previous, receipt.PreviousDigest, receipt.Total)
+		}
+		digest := sha256.Sum256(signed.Receipt)
+		previous = hex.EncodeToString(digest[:])
+		receipts = append(receipts, receipt)
+	}
+
+	first := receipts[0]
+	if first.Sequence != 1 || first.CommandID != "cmd-1" || first.Operator != "alice" || first.ApprovedBy != "bob" || first.Impact != "disruptive" {
+		t.Errorf("Unexpected first receipt: %+v", first)
+	}
+	if !reflect.DeepEqual(first.Targets, []string{"minion-1", "minion-2", "minion-4"}) {
+		t.Errorf("Expected the resolved targets, got %v", first.Targets)
+	}
+	output := sha256.Sum256([]byte("\x00failed"))
+	if len(first.Results) != 2 || first.Results[1].Attempt != 2 || first.Results[1].OutputDigest != hex.EncodeToString(output[:]) {
+		t.Errorf("Unexpected results: %+v", first.Results)
+	}
+	if first.CompletedAt == nil || !first.CompletedAt.Equal(time.Unix(1700000110, 0)) || first.ResultsDigest == "" {
+		t.Errorf("Expected the completion of the last result, got %v", first.CompletedAt)
+	}
+	if second := receipts[1]; second.Sequence != 2 || len(second.Results) != 0 || second.CompletedAt != nil || len(second.Targets) != 1 {
+		t.Errorf("Expected the command without results certified as pending, got %+v", second)
+	}
+
+	if err := server.ExportReceipts(&pb.ReceiptExportRequest{Since: until.Unix(), Until: since.Unix()}, stream); err == nil {
+		t.Error("Expected an empty range rejected")
+	}
+
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(maxReceiptCommands + 1))
+	if err := server.ExportReceipts(&pb.ReceiptExportRequest{Since: since.Unix(), Until: until.Unix()}, stream); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected a range of too many commands rejected, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package nexus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// redactedValue replaces the secrets found in stored commands and results
+const redactedValue = "[REDACTED]"
+
+// RedactionPattern describes secrets redacted from command payloads and results before they are stored
+type RedactionPattern struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"` // regular expression; with a capture group, only the first group is redacted
+
+	re *regexp.Regexp
+}
+
+// DefaultRedactionPatterns returns the patterns used when no patterns file is configured
+func DefaultRedactionPatterns() []*RedactionPattern {
+	patterns := []*RedactionPattern{
+		{Name: "password assignment", Pattern: `(?i)\b(?:[\w-]*[_.-])?(?:password|passwd|pass|pwd)["']?\s*[:=]\s*["']?([^\s"',;]+)`},
+		{Name: "token or key assignment", Pattern: `(?i)\b(?:[\w-]*[_.-])?(?:token|secret|api[_-]?key|access[_-]?key|private[_-]?key)["']?\s*[:=]\s*["']?([^\s"',;]+)`},
+		{Name: "password option", Pattern: `--password(?:=|\s+)([^\s"',;]+)`},
+		{Name: "authorization header", Pattern: `(?i)\bauthorization:\s*(?:bearer|basic|token)\s+([^\s"',;]+)`},
+		{Name: "URL credentials", Pattern: `\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@"']+:([^\s/@"']+)@`},
+		{Name: "AWS access key ID", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+		{Name: "private key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	}
+	for _, p := range patterns {
+		p.re = regexp.MustCompile(p.Pattern)
+	}
+	return patterns
+}
+
+// LoadRedactionPatterns reads the patterns of secrets to redact from a JSON file
+func LoadRedactionPatterns(path string) ([]*RedactionPattern, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction patterns: %w", err)
+	}
+
+	var patterns []*RedactionPattern
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction patterns: %w", err)
+	}
+
+	for i, p := range patterns {
+		if p.Name == "" {
+			return nil, fmt.Errorf("redaction pattern %d: name is required", i)
+		}
+		if p.re, err = regexp.Compile(p.Pattern); err != nil {
+			return nil, fmt.Errorf("redaction pattern %s: %w", p.Name, err)
+		}
+	}
+	return patterns, nil
+}
+
+// Redactor replaces the secrets matched by its patterns
+type Redactor struct {
+	patterns []*RedactionPattern
+}
+
+// NewRedactor creates a redactor for the secrets matching patterns
+func NewRedactor(patterns []*RedactionPattern) *Redactor {
+	return &Redactor{patterns: patterns}
+}
+
+// Redact returns text with its secrets replaced, and whether any was found
+func (r *Redactor) Redact(text string) (string, bool) {
+	if r == nil || text == "" {
+		return text, false
+	}
+
+	redacted := false
+	for _, p := range r.patterns {
+		matches := p.re.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			// Redact the first capture group when the pattern has one, the whole match otherwise
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if text[start:end] == redactedValue {
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(redactedValue)
+			last = end
+			redacted = true
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text, redacted
+}
+
+// RedactResult returns a copy of result whose output is redacted, and whether a secret was found
+func (r *Redactor) RedactResult(result *pb.CommandResult) (*pb.CommandResult, bool) {
+	stdout, inStdout := r.Redact(result.Stdout)
+	stderr, inStderr := r.Redact(result.Stderr)
+	structured, inStructured := r.Redact(result.Structured)
+	payload, inPayload := r.Redact(result.Payload)
+	if !inStdout && !inStderr && !inStructured && !inPayload {
+		return result, false
+	}
+
+	redacted := proto.Clone(result).(*pb.CommandResult)
+	redacted.Stdout = stdout
+	redacted.Stderr = stderr
+	redacted.Structured = structured
+	redacted.Payload = payload
+	// A structured payload no longer valid JSON after redaction is dropped rather than stored corrupted
+	if inStructured && !json.Valid([]byte(structured)) {
+		redacted.ContentType = ""
+		redacted.Structured = ""
+	}
+	return redacted, true
+}
+
+// SetRedactionPatterns replaces the patterns of secrets redacted before commands and results are
+// stored or notified
+func (s *Server) SetRedactionPatterns(patterns []*RedactionPattern) {
+	s.redactor = NewRedactor(patterns)
+	if impl, ok := s.dbService.(*DatabaseServiceImpl); ok {
+		impl.redactor = s.redactor
+	}
+}
@@ -3,9 +3,11 @@ package nexus
 import (
 	"context"
 	"fmt"
+	"maps"
 	"sync"
 	"time"
 
+	"github.com/arhuman/minexus/internal/command"
 	"github.com/arhuman/minexus/internal/logging"
 	pb "github.com/arhuman/minexus/protogen"
 
@@ -17,9 +19,45 @@ import (
 // MinionConnectionImpl implements the MinionConnection interface.
 // It represents an active connection to a minion node in the system.
 type MinionConnectionImpl struct {
-	Info      *pb.HostInfo     // Host information including ID, hostname, IP, OS, and tags
-	LastSeen  time.Time        // Timestamp of the last communication from this minion
-	CommandCh chan *pb.Command // Channel for sending commands to this minion
+	Info     *pb.HostInfo  // Host information including ID, hostname, IP, OS, and tags
+	LastSeen time.Time     // Timestamp of the last communication from this minion
+	Commands *CommandQueue // Priority queue of commands waiting to be sent to this minion
+
+	advertised map[string]string // tags the minion advertised at its last registration
+
+	streamDead chan struct{} // closed when the open command stream is detected dead, nil without stream
+	lastPoll   time.Time     // last poll for commands over HTTP, zero for minions using gRPC streams
+
+	collisions    int       // registrations of other hosts claiming the ID while the minion was connected
+	lastCollision time.Time // time of the last collision
+}
+
+// asleep reports whether a power-aware minion is sleeping: past the sleep time it advertised and
+// without command stream
+func (m *MinionConnectionImpl) asleep(now time.Time) bool {
+	return m.streamDead == nil && m.Info.SleepAt > 0 && now.Unix() >= m.Info.SleepAt
+}
+
+// poweredDown reports whether a minion went down for the reboot or shutdown it announced: the
+// announced power action is due at now and the minion has no command stream
+func (m *MinionConnectionImpl) poweredDown(now time.Time) bool {
+	return m.streamDead == nil && powerDue(m.Info, now)
+}
+
+// powerDue reports whether the power action announced in info is due at now: from its time until
+// command.PowerAnnouncementGrace after it. A minion going down earlier isn't taken for the action,
+// nor one going down long after.
+func powerDue(info *pb.HostInfo, now time.Time) bool {
+	if info == nil || info.PowerAction == "" {
+		return false
+	}
+	at := time.Unix(info.PowerAt, 0)
+	return !now.Before(at) && now.Before(at.Add(command.PowerAnnouncementGrace))
+}
+
+// powerExpired reports whether the power action announced in info is past its grace period at now
+func powerExpired(info *pb.HostInfo, now time.Time) bool {
+	return info.PowerAction != "" && !now.Before(time.Unix(info.PowerAt, 0).Add(command.PowerAnnouncementGrace))
 }
 
 // GetInfo returns the host information for this minion connection.
@@ -30,18 +68,32 @@ func (m *MinionConnectionImpl) GetInfo() *pb.HostInfo {
 // MinionRegistryImpl manages minion connections and tag operations.
 // It provides methods to register minions, manage connections, and perform tag-based operations.
 type MinionRegistryImpl struct {
-	minions   map[string]*MinionConnectionImpl
-	minionsMu sync.RWMutex
-	dbService *DatabaseServiceImpl
-	logger    *zap.Logger
+	minions       map[string]*MinionConnectionImpl
+	minionsMu     sync.RWMutex
+	dbService     *DatabaseServiceImpl
+	logger        *zap.Logger
+	inFlightLimit int                        // per-minion limit of dispatched commands without result, 0: unlimited
+	hosts         *hostBuffer                // buffers the registrations until the database is reached, nil: stored right away
+	tagConflict   string                     // policy resolving the tag conflicts at re-registration
+	idConflict    string                     // policy resolving the minion ID collisions, IDConflictExisting when empty
+	evicted       map[string]string          // minion ID -> fingerprint of the host the ID was taken from
+	closeReasons  map[<-chan struct{}]string // streams closed to hand their minion ID over -> reason
+	health        *HealthTracker
+	generation    uint64         // bumped when minions are added or removed or their tags or topology change, invalidates the cached selector results
+	selectors     *selectorCache // minions matching the recent tag and topology selectors
 }
 
 // NewMinionRegistry creates a new minion registry instance.
 func NewMinionRegistry(dbService *DatabaseServiceImpl, logger *zap.Logger) *MinionRegistryImpl {
 	return &MinionRegistryImpl{
-		minions:   make(map[string]*MinionConnectionImpl),
-		dbService: dbService,
-		logger:    logger,
+		minions:      make(map[string]*MinionConnectionImpl),
+		dbService:    dbService,
+		logger:       logger,
+		evicted:      make(map[string]string),
+		closeReasons: make(map[<-chan struct{}]string),
+		health:       NewHealthTracker(),
+		selectors:    newSelectorCache(),
+		tagConflict:  TagConflictServer,
 	}
 }
 
@@ -61,17 +113,41 @@ func (r *MinionRegistryImpl) Register(hostInfo *pb.HostInfo) (*pb.RegisterRespon
 
 	// Check if minion already exists to preserve existing channel
 	if existing, exists := r.minions[hostInfo.Id]; exists {
+		if err := r.resolveCollision(existing, hostInfo, logger); err != nil {
+			return &pb.RegisterResponse{Success: false, ErrorMessage: err.Error()}, nil
+		}
+		r.health.RecordHeartbeat(hostInfo.Id)
 		logger.Info("Updating existing minion registration",
 			zap.String("minion_id", hostInfo.Id),
-			zap.Int("command_channel_buffer", len(existing.CommandCh)))
+			zap.Int("command_queue_len", existing.Commands.Len()))
 
-		// Update existing connection but preserve the command channel
+		if existing.asleep(time.Now()) {
+			r.health.RecordWake(hostInfo.Id)
+			logger.Info("Power-aware minion woke up",
+				zap.String("minion_id", hostInfo.Id),
+				zap.Int("queued_commands", existing.Commands.Len()))
+		}
+		if existing.poweredDown(time.Now()) && hostInfo.PowerAction == "" {
+			r.health.RecordWake(hostInfo.Id)
+			logger.Info("Minion back after its announced power action",
+				zap.String("minion_id", hostInfo.Id),
+				zap.String("power_action", existing.Info.PowerAction))
+		}
+
+		// Update existing connection but preserve the command queue and the tags set from consoles
+		advertised := copyTags(hostInfo.Tags)
+		hostInfo.Tags = mergeTags(existing.Info.Tags, existing.advertised, advertised, r.tagConflict)
+		// Heartbeats re-register minions unchanged, keeping the cached selector results
+		if !maps.Equal(existing.Info.Tags, hostInfo.Tags) || minionTopology(existing.Info) != minionTopology(hostInfo) {
+			r.generation++
+		}
 		existing.Info = hostInfo
+		existing.advertised = advertised
 		existing.LastSeen = time.Now()
 
 		// Update database if available
-		if r.dbService != nil {
-			if err := r.dbService.UpdateHost(context.Background(), hostInfo); err != nil {
+		if r.dbService != nil && !r.hosts.hold(hostInfo) {
+			if err := r.storeHost(existing); err != nil {
 				logger.Error("Failed to update host in database", zap.Error(err))
 				return nil, err
 			}
@@ -83,19 +159,26 @@ func (r *MinionRegistryImpl) Register(hostInfo *pb.HostInfo) (*pb.RegisterRespon
 		}, nil
 	}
 
+	r.health.RecordHeartbeat(hostInfo.Id)
+	r.generation++
+
 	// Create new connection with simplified structure
 	logger.Info("Creating new minion connection",
 		zap.String("minion_id", hostInfo.Id))
 
-	r.minions[hostInfo.Id] = &MinionConnectionImpl{
-		Info:      hostInfo,
-		LastSeen:  time.Now(),
-		CommandCh: make(chan *pb.Command, 100),
+	commands := NewCommandQueue(defaultCommandQueueSize)
+	commands.SetLimit(r.inFlightLimit)
+	conn := &MinionConnectionImpl{
+		Info:       hostInfo,
+		LastSeen:   time.Now(),
+		Commands:   commands,
+		advertised: copyTags(hostInfo.Tags),
 	}
+	r.minions[hostInfo.Id] = conn
 
-	// Store in database if available
-	if r.dbService != nil {
-		if err := r.dbService.StoreHost(context.Background(), hostInfo); err != nil {
+	// Store in database if available, which brings back the console tags after a restart
+	if r.dbService != nil && !r.hosts.hold(hostInfo) {
+		if err := r.storeHost(conn); err != nil {
 			return nil, err
 		}
 	}
@@ -106,6 +189,33 @@ func (r *MinionRegistryImpl) Register(hostInfo *pb.HostInfo) (*pb.RegisterRespon
 	}, nil
 }
 
+// storeHost persists the registration of a minion, taking the tags merged with the stored console
+// tags. The caller must hold minionsMu.
+func (r *MinionRegistryImpl) storeHost(conn *MinionConnectionImpl) error {
+	tags, err := r.dbService.RegisterHost(context.Background(), conn.Info, conn.advertised, r.tagConflict)
+	if err != nil {
+		return err
+	}
+	if !maps.Equal(conn.Info.Tags, tags) {
+		conn.Info.Tags = tags
+		r.generation++
+	}
+	return nil
+}
+
+// storeBufferedHost persists a registration buffered while the database was unavailable, with the
+// current tags of the minion if it is still registered
+func (r *MinionRegistryImpl) storeBufferedHost(hostInfo *pb.HostInfo) error {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	if conn, exists := r.minions[hostInfo.Id]; exists {
+		return r.storeHost(conn)
+	}
+	_, err := r.dbService.RegisterHost(context.Background(), hostInfo, hostInfo.Tags, r.tagConflict)
+	return err
+}
+
 // GetConnection retrieves the connection information for a specific minion.
 func (r *MinionRegistryImpl) GetConnection(minionID string) (MinionConnection, bool) {
 	r.minionsMu.RLock()
@@ -129,6 +239,18 @@ func (r *MinionRegistryImpl) GetConnectionImpl(minionID string) (*MinionConnecti
 	return conn, exists
 }
 
+// SetInFlightLimit sets the number of dispatched commands without result above which
+// commands wait in the queue of a minion, 0 for unlimited
+func (r *MinionRegistryImpl) SetInFlightLimit(limit int) {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	r.inFlightLimit = limit
+	for _, conn := range r.minions {
+		conn.Commands.SetLimit(limit)
+	}
+}
+
 // UpdateLastSeen updates the last seen timestamp for a minion.
 func (r *MinionRegistryImpl) UpdateLastSeen(minionID string) {
 	r.minionsMu.Lock()
@@ -139,6 +261,123 @@ func (r *MinionRegistryImpl) UpdateLastSeen(minionID string) {
 	}
 }
 
+// LastSeen returns the last seen timestamp of a minion.
+func (r *MinionRegistryImpl) LastSeen(minionID string) (time.Time, bool) {
+	r.minionsMu.RLock()
+	defer r.minionsMu.RUnlock()
+
+	conn, exists := r.minions[minionID]
+	if !exists {
+		return time.Time{}, false
+	}
+	return conn.LastSeen, true
+}
+
+// OpenStream records that a minion opened its command stream. The returned channel is
+// closed if the stream is later detected dead by ExpireDeadStreams or closed by DisconnectStream.
+func (r *MinionRegistryImpl) OpenStream(minionID string) <-chan struct{} {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	dead := make(chan struct{})
+	if conn, exists := r.minions[minionID]; exists {
+		conn.streamDead = dead
+		conn.LastSeen = time.Now()
+		r.health.RecordStreamOpened(minionID)
+	}
+	return dead
+}
+
+// HasStream reports whether a minion has an open command stream
+func (r *MinionRegistryImpl) HasStream(minionID string) bool {
+	r.minionsMu.RLock()
+	defer r.minionsMu.RUnlock()
+
+	conn, exists := r.minions[minionID]
+	return exists && conn.streamDead != nil
+}
+
+// CloseStream records that the command stream opened with OpenStream ended.
+// A stream opened since by a reconnecting minion is left untouched.
+func (r *MinionRegistryImpl) CloseStream(minionID string, dead <-chan struct{}) {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	if conn, exists := r.minions[minionID]; exists && conn.streamDead != nil && (<-chan struct{})(conn.streamDead) == dead {
+		conn.streamDead = nil
+	}
+}
+
+// DisconnectStream signals the open command stream of a minion to close, its queued commands being
+// kept for the next stream. It reports whether the minion had an open stream.
+func (r *MinionRegistryImpl) DisconnectStream(minionID string) bool {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	conn, exists := r.minions[minionID]
+	if !exists || conn.streamDead == nil {
+		return false
+	}
+	close(conn.streamDead)
+	conn.streamDead = nil
+	return true
+}
+
+// ExpireDeadStreams detects half-open command streams: minions with an open stream that were not
+// heard from for longer than deadline, typically because their TCP session died without FIN.
+// Their streams are signaled dead and their queued commands drained, except for power-aware minions
+// which keep them until they wake. It returns the drained commands of each expired minion.
+func (r *MinionRegistryImpl) ExpireDeadStreams(deadline time.Duration) map[string][]*pb.Command {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	expired := make(map[string][]*pb.Command)
+	for minionID, conn := range r.minions {
+		if conn.streamDead == nil || time.Since(conn.LastSeen) <= deadline {
+			continue
+		}
+
+		close(conn.streamDead)
+		conn.streamDead = nil
+		if conn.Info.Availability != "" {
+			r.logger.Info("Power-aware minion command stream detected dead, keeping its queued commands",
+				zap.String("minion_id", minionID),
+				zap.Time("last_seen", conn.LastSeen),
+				zap.Int("queued_commands", conn.Commands.Len()))
+			continue
+		}
+		if powerDue(conn.Info, time.Now()) {
+			r.logger.Info("Command stream of a minion going down on purpose detected dead, keeping its queued commands",
+				zap.String("minion_id", minionID),
+				zap.String("power_action", conn.Info.PowerAction),
+				zap.Int("queued_commands", conn.Commands.Len()))
+			continue
+		}
+		expired[minionID] = conn.Commands.Drain()
+
+		r.logger.Warn("Minion command stream detected dead",
+			zap.String("minion_id", minionID),
+			zap.Time("last_seen", conn.LastSeen),
+			zap.Duration("deadline", deadline),
+			zap.Int("drained_commands", len(expired[minionID])))
+	}
+	return expired
+}
+
+// QueuedCommands returns the number of commands queued for all the minions
+func (r *MinionRegistryImpl) QueuedCommands() int {
+	r.minionsMu.RLock()
+	defer r.minionsMu.RUnlock()
+
+	queued := 0
+	for _, conn := range r.minions {
+		if conn.Commands != nil {
+			queued += conn.Commands.Len()
+		}
+	}
+	return queued
+}
+
 // ListMinions returns a list of all registered minions.
 func (r *MinionRegistryImpl) ListMinions() []*pb.HostInfo {
 	r.minionsMu.RLock()
@@ -148,16 +387,47 @@ func (r *MinionRegistryImpl) ListMinions() []*pb.HostInfo {
 
 	// Use in-memory data to ensure consistency with command targeting
 	// This shows only currently connected minions that can receive commands
+	now := time.Now()
 	for _, conn := range r.minions {
 		// Create a copy of the HostInfo to avoid modifying the original
 		hostInfo := &pb.HostInfo{
-			Id:       conn.Info.Id,
-			Hostname: conn.Info.Hostname,
-			Ip:       conn.Info.Ip,
-			Os:       conn.Info.Os,
-			LastSeen: conn.LastSeen.Unix(),
-			Tags:     make(map[string]string),
+			Id:           conn.Info.Id,
+			Hostname:     conn.Info.Hostname,
+			Ip:           conn.Info.Ip,
+			Os:           conn.Info.Os,
+			Namespace:    conn.Info.Namespace,
+			LastSeen:     conn.LastSeen.Unix(),
+			Tags:         make(map[string]string),
+			Capabilities: append([]string(nil), conn.Info.Capabilities...),
+			Health:       r.health.Health(conn.Info.Id),
+
+			Availability: conn.Info.Availability,
+			SleepAt:      conn.Info.SleepAt,
+			NextWake:     conn.Info.NextWake,
+			Sleeping:     conn.asleep(now),
+			PoweredDown:  conn.poweredDown(now),
+
+			Version:         conn.Info.Version,
+			BuildCommit:     conn.Info.BuildCommit,
+			ProtocolVersion: conn.Info.ProtocolVersion,
+		}
+		if !powerExpired(conn.Info, now) {
+			hostInfo.PowerAction, hostInfo.PowerAt = conn.Info.PowerAction, conn.Info.PowerAt
+		}
+		if conn.Commands != nil {
+			hostInfo.QueuedCommands = int32(conn.Commands.Len())
+		}
+		if hostInfo.Sleeping || hostInfo.PoweredDown {
+			excuseSleep(hostInfo.Health)
 		}
+		if len(conn.Info.CommandVersions) > 0 {
+			hostInfo.CommandVersions = make(map[string]int32, len(conn.Info.CommandVersions))
+			for family, version := range conn.Info.CommandVersions {
+				hostInfo.CommandVersions[family] = version
+			}
+		}
+		topo := minionTopology(conn.Info)
+		hostInfo.Region, hostInfo.Datacenter, hostInfo.Rack = topo.region, topo.datacenter, topo.rack
 
 		// Copy tags to avoid modification of original
 		for k, v := range conn.Info.Tags {
@@ -186,14 +456,25 @@ func (r *MinionRegistryImpl) FindTargetMinions(req *pb.CommandRequest) []string
 		return targets
 	}
 
-	// Otherwise, use tag selector to find matching minions
+	// Bursts of commands with the same selectors reuse the minions matched by the first one
+	key, cacheable := selectorKey(req.TagSelector, req.Topology)
+	if cacheable {
+		if targets, ok := r.selectors.get(key, r.generation); ok {
+			return targets
+		}
+	}
+
+	// Otherwise, use tag and topology selectors to find matching minions
 	var targets []string
 	for id, conn := range r.minions {
-		if r.matchesTags(conn.Info, req.TagSelector) {
+		if r.matchesTags(conn.Info, req.TagSelector) && matchesTopology(conn.Info, req.Topology) {
 			targets = append(targets, id)
 		}
 	}
 
+	if cacheable {
+		r.selectors.put(key, r.generation, targets)
+	}
 	return targets
 }
 
@@ -236,6 +517,7 @@ func (r *MinionRegistryImpl) UpdateTags(minionID string, add map[string]string,
 	if !exists {
 		return status.Error(codes.NotFound, "minion not found")
 	}
+	r.generation++
 
 	// Create a deep copy of the host info to avoid modifying the original
 	updatedInfo := &pb.HostInfo{
@@ -280,6 +562,7 @@ func (r *MinionRegistryImpl) SetTags(minionID string, tags map[string]string) er
 	if !exists {
 		return status.Error(codes.NotFound, "minion not found")
 	}
+	r.generation++
 
 	// Create a deep copy of the host info to avoid modifying the original
 	updatedInfo := &pb.HostInfo{
@@ -0,0 +1,289 @@
+package nexus
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// relaySession serves the minions registered through one relay stream
+type relaySession struct {
+	server  *Server
+	stream  pb.MinionService_RelayStreamServer
+	relayID string
+	logger  *zap.Logger
+
+	sendMu sync.Mutex // serializes stream.Send across dispatchers
+
+	mu      sync.Mutex
+	minions map[string]bool               // minions registered through this relay
+	active  map[string]context.CancelFunc // dispatchers of connected minions
+	wg      sync.WaitGroup
+}
+
+// GetRelayIDFromContext extracts the relay ID from gRPC metadata.
+func GetRelayIDFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("relay-id")
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// RelayStream serves a relay multiplexing the registrations, commands and results
+// of the minions of an isolated network segment over a single stream.
+func (s *Server) RelayStream(stream pb.MinionService_RelayStreamServer) error {
+	logger, start := logging.FuncLogger(s.logger, "nexus.Server.RelayStream")
+	defer logging.FuncExit(logger, start)
+
+	relayID := GetRelayIDFromContext(stream.Context())
+	if relayID == "" {
+		logger.Error("Relay ID not provided in metadata")
+		return status.Error(codes.Unauthenticated, "relay ID not provided")
+	}
+
+	session := &relaySession{
+		server:  s,
+		stream:  stream,
+		relayID: relayID,
+		logger:  logger.With(zap.String("relay_id", relayID)),
+		minions: make(map[string]bool),
+		active:  make(map[string]context.CancelFunc),
+	}
+
+	session.logger.Info("Relay connected")
+	err := session.run()
+	session.close(err)
+	session.logger.Info("Relay disconnected", zap.Error(err))
+	return err
+}
+
+// run handles relay messages until the stream ends
+func (r *relaySession) run() error {
+	for {
+		msg, err := r.stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.handle(msg)
+	}
+}
+
+// handle routes a message received from the relay
+func (r *relaySession) handle(msg *pb.RelayMessage) {
+	switch m := msg.Message.(type) {
+	case *pb.RelayMessage_Register:
+		r.register(msg.RequestId, m.Register)
+	case *pb.RelayMessage_Connected:
+		r.connect(msg.MinionId)
+	case *pb.RelayMessage_Disconnected:
+		r.disconnect(msg.MinionId, nil)
+	case *pb.RelayMessage_Stream:
+		if !r.owns(msg.MinionId) {
+			r.logger.Warn("Ignoring message for minion not registered through relay",
+				zap.String("minion_id", msg.MinionId))
+			return
+		}
+		switch sm := m.Stream.Message.(type) {
+		case *pb.CommandStreamMessage_Result:
+			// The relay is authoritative on which minion sent the message
+			sm.Result.MinionId = msg.MinionId
+			if err := r.server.handleCommandResult(r.stream.Context(), sm.Result, r.logger); err == nil {
+				r.acknowledge(msg.MinionId, sm.Result.CommandId)
+			}
+		case *pb.CommandStreamMessage_ResultChunk:
+			result := r.server.assembleResult(r.stream.Context(), msg.MinionId, sm.ResultChunk, r.logger)
+			if result == nil {
+				return
+			}
+			result.MinionId = msg.MinionId
+			if err := r.server.handleCommandResult(r.stream.Context(), result, r.logger); err == nil {
+				r.acknowledge(msg.MinionId, result.CommandId)
+			}
+		case *pb.CommandStreamMessage_Status:
+			sm.Status.MinionId = msg.MinionId
+			r.server.handleStatusUpdate(r.stream.Context(), sm.Status, r.logger)
+		case *pb.CommandStreamMessage_Logs:
+			r.server.storeMinionLogs(r.stream.Context(), msg.MinionId, sm.Logs, r.logger)
+		}
+	}
+}
+
+// acknowledge sends the acknowledgement of a stored result to the relay, for the minion
+func (r *relaySession) acknowledge(minionID, commandID string) {
+	err := r.send(&pb.RelayMessage{
+		MinionId: minionID,
+		Message: &pb.RelayMessage_Stream{Stream: &pb.CommandStreamMessage{
+			Message: &pb.CommandStreamMessage_Ack{Ack: &pb.ResultAck{CommandId: commandID}},
+		}},
+	})
+	if err != nil {
+		// The minion sends the result again
+		r.logger.Warn("Failed to acknowledge relayed result",
+			zap.String("minion_id", minionID),
+			zap.String("command_id", commandID),
+			zap.Error(err))
+	}
+}
+
+// register registers a downstream minion and answers the relay with the outcome
+func (r *relaySession) register(requestID string, hostInfo *pb.HostInfo) {
+	if hostInfo == nil {
+		return
+	}
+
+	resp, err := r.server.Register(r.stream.Context(), hostInfo)
+	if err != nil {
+		resp = &pb.RegisterResponse{Success: false, ErrorMessage: err.Error()}
+	}
+
+	minionID := hostInfo.Id
+	if resp.Success {
+		minionID = resp.AssignedId
+		r.mu.Lock()
+		r.minions[minionID] = true
+		r.mu.Unlock()
+		r.logger.Debug("Minion registered through relay", zap.String("minion_id", minionID))
+	}
+
+	if err := r.send(&pb.RelayMessage{
+		MinionId:  minionID,
+		RequestId: requestID,
+		Message:   &pb.RelayMessage_Registered{Registered: resp},
+	}); err != nil {
+		r.logger.Error("Failed to send registration response to relay",
+			zap.String("minion_id", minionID),
+			zap.Error(err))
+	}
+}
+
+// connect starts dispatching queued commands of a minion connected to the relay
+func (r *relaySession) connect(minionID string) {
+	if !r.owns(minionID) {
+		r.logger.Warn("Ignoring connection of minion not registered through relay",
+			zap.String("minion_id", minionID))
+		return
+	}
+
+	conn, ok := r.server.GetMinionRegistryImpl().GetConnectionImpl(minionID)
+	if !ok {
+		r.logger.Warn("No connection found for relayed minion", zap.String("minion_id", minionID))
+		return
+	}
+
+	r.mu.Lock()
+	if cancel, ok := r.active[minionID]; ok {
+		// A reconnection replaces the previous dispatcher
+		cancel()
+		r.server.diagnostics.RecordStreamClosed(minionID, nil)
+	}
+	ctx, cancel := context.WithCancel(r.stream.Context())
+	r.active[minionID] = cancel
+	r.mu.Unlock()
+
+	r.server.setupConnection(minionID, r.logger)
+	r.server.diagnostics.RecordStreamOpened(minionID)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.dispatch(ctx, conn, minionID)
+	}()
+}
+
+// disconnect stops dispatching commands to a minion that left the relay
+func (r *relaySession) disconnect(minionID string, err error) {
+	r.mu.Lock()
+	cancel, ok := r.active[minionID]
+	delete(r.active, minionID)
+	r.mu.Unlock()
+
+	if ok {
+		cancel()
+		r.server.diagnostics.RecordStreamClosed(minionID, err)
+	}
+}
+
+// dispatch forwards queued commands of a minion to the relay until ctx is canceled
+func (r *relaySession) dispatch(ctx context.Context, conn *MinionConnectionImpl, minionID string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-conn.Commands.Ready():
+			for {
+				cmd, ok := conn.Commands.Pop()
+				if !ok {
+					break
+				}
+				err := r.send(&pb.RelayMessage{
+					MinionId: minionID,
+					Message: &pb.RelayMessage_Stream{Stream: &pb.CommandStreamMessage{
+						Message: &pb.CommandStreamMessage_Command{Command: cmd},
+					}},
+				})
+				if err != nil {
+					r.logger.Error("Failed to send command to relay",
+						zap.String("minion_id", minionID),
+						zap.String("command_id", cmd.Id))
+					r.server.diagnostics.RecordError(minionID, err)
+					return
+				}
+				r.server.diagnostics.RecordCommandSent(minionID)
+			}
+
+			if conn.Commands.Closed() {
+				r.logger.Warn("Command queue closed", zap.String("minion_id", minionID))
+				return
+			}
+		}
+	}
+}
+
+// close stops every dispatcher once the relay stream ended
+func (r *relaySession) close(err error) {
+	r.mu.Lock()
+	minionIDs := make([]string, 0, len(r.active))
+	for minionID := range r.active {
+		minionIDs = append(minionIDs, minionID)
+	}
+	r.mu.Unlock()
+
+	for _, minionID := range minionIDs {
+		r.disconnect(minionID, err)
+	}
+	r.wg.Wait()
+}
+
+// owns reports whether a minion registered through this relay
+func (r *relaySession) owns(minionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.minions[minionID]
+}
+
+// send sends a message to the relay, stream.Send is not safe for concurrent use
+func (r *relaySession) send(msg *pb.RelayMessage) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	return r.stream.Send(msg)
+}
@@ -0,0 +1,164 @@
+package nexus
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// mockRelayStream is a RelayStream server stream fed through channels
+type mockRelayStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv chan *pb.RelayMessage
+	sent chan *pb.RelayMessage
+}
+
+func newMockRelayStream(relayID string) *mockRelayStream {
+	ctx := context.Background()
+	if relayID != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("relay-id", relayID))
+	}
+	return &mockRelayStream{
+		ctx:  ctx,
+		recv: make(chan *pb.RelayMessage, 10),
+		sent: make(chan *pb.RelayMessage, 10),
+	}
+}
+
+func (m *mockRelayStream) Context() context.Context { return m.ctx }
+
+func (m *mockRelayStream) Send(msg *pb.RelayMessage) error {
+	m.sent <- msg
+	return nil
+}
+
+func (m *mockRelayStream) Recv() (*pb.RelayMessage, error) {
+	msg, ok := <-m.recv
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func (m *mockRelayStream) next(t *testing.T) *pb.RelayMessage {
+	t.Helper()
+	select {
+	case msg := <-m.sent:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for relay message")
+		return nil
+	}
+}
+
+func TestRelayStreamRequiresRelayID(t *testing.T) {
+	server := createTestServer(nil)
+
+	if err := server.RelayStream(newMockRelayStream("")); err == nil {
+		t.Error("Expected error without relay ID")
+	}
+}
+
+func TestRelayStream(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	stream := newMockRelayStream("edge-1")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.RelayStream(stream)
+	}()
+
+	// Registration is answered with the same request ID
+	stream.recv <- &pb.RelayMessage{
+		MinionId:  "edge-minion",
+		RequestId: "req-1",
+		Message: &pb.RelayMessage_Register{Register: &pb.HostInfo{
+			Id:       "edge-minion",
+			Hostname: "edge-host",
+		}},
+	}
+	msg := stream.next(t)
+	registered := msg.GetRegistered()
+	if registered == nil || !registered.Success {
+		t.Fatalf("Expected successful registration, got %v", msg)
+	}
+	if msg.RequestId != "req-1" || msg.MinionId != "edge-minion" {
+		t.Errorf("Unexpected registration routing: request_id=%q minion_id=%q", msg.RequestId, msg.MinionId)
+	}
+
+	// Commands of a connected minion are forwarded to the relay
+	stream.recv <- &pb.RelayMessage{
+		MinionId: "edge-minion",
+		Message:  &pb.RelayMessage_Connected{Connected: true},
+	}
+	// Wait for the dispatcher to be registered before sending the command
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		diag, err := server.GetMinionDiagnostics(context.Background(), &pb.MinionDiagnosticsRequest{MinionId: "edge-minion"})
+		if err == nil && diag.ActiveStreams == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Relayed minion never connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"edge-minion"},
+		Command:   &pb.Command{Payload: "system:info"},
+	})
+	if err != nil || !resp.Accepted {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	msg = stream.next(t)
+	if msg.MinionId != "edge-minion" || msg.GetStream().GetCommand().GetId() != resp.CommandId {
+		t.Fatalf("Expected command %s for edge-minion, got %v", resp.CommandId, msg)
+	}
+
+	// Results are attributed to the minion named by the relay
+	stream.recv <- &pb.RelayMessage{
+		MinionId: "edge-minion",
+		Message: &pb.RelayMessage_Stream{Stream: &pb.CommandStreamMessage{
+			Message: &pb.CommandStreamMessage_Result{Result: &pb.CommandResult{
+				CommandId: resp.CommandId,
+				MinionId:  "spoofed",
+				Stdout:    "ok",
+			}},
+		}},
+	}
+
+	// Messages for minions registered elsewhere are ignored
+	stream.recv <- &pb.RelayMessage{
+		MinionId: "other-minion",
+		Message:  &pb.RelayMessage_Connected{Connected: true},
+	}
+
+	close(stream.recv)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean relay shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RelayStream did not return")
+	}
+
+	diag, err := server.GetMinionDiagnostics(context.Background(), &pb.MinionDiagnosticsRequest{MinionId: "edge-minion"})
+	if err != nil {
+		t.Fatalf("GetMinionDiagnostics failed: %v", err)
+	}
+	if diag.ActiveStreams != 0 {
+		t.Errorf("Expected no active stream after relay disconnection, got %d", diag.ActiveStreams)
+	}
+	if diag.ResultsReceived != 1 {
+		t.Errorf("Expected 1 result received, got %d", diag.ResultsReceived)
+	}
+}
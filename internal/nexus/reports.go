@@ -0,0 +1,523 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultReportLimit and maxReportLimit bound the number of rows of a report
+	defaultReportLimit = 100
+	maxReportLimit     = 1000
+)
+
+// reportNamePattern restricts report names to identifiers usable in the console
+var reportNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// reportMetrics lists the metrics a report can select
+var reportMetrics = map[string]bool{
+	"count":        true,
+	"successes":    true,
+	"failures":     true,
+	"success_rate": true,
+	"failure_rate": true,
+}
+
+// ReportRow is an aggregated row of command results for one dimension value
+type ReportRow struct {
+	Dimension string
+	Count     int64
+	Failures  int64  // results with a non-zero exit code
+	Command   string // decrypted payload of the aggregated results, for reports by or on command
+}
+
+// reportCondition restricts the results a report aggregates
+type reportCondition struct {
+	field  string // "command", "minion", "impact" or "tag"
+	tagKey string
+	like   bool
+	value  string
+}
+
+// reportQuery is a parsed report query:
+//
+//	SELECT <metric>[, <metric>...] BY <minion|command|day|impact|tag:<key>>
+//	  [WHERE <condition> [AND <condition>...]] [SINCE <duration>] [LIMIT <n>]
+//
+// Conditions are "command LIKE <pattern>" (* is a wildcard), "command = <payload>", "minion = <id>",
+// "impact = <class>" and "tag:<key> = <value>". Values may be $placeholders bound when the report runs.
+type reportQuery struct {
+	metrics    []string
+	dimension  string
+	tagKey     string
+	conditions []reportCondition
+	since      time.Duration
+	limit      int
+	encrypted  bool // command payloads are stored encrypted, matched and grouped once decrypted
+}
+
+// parseReportQuery parses a report query binding its $placeholders with params.
+// With nil params placeholders are only checked syntactically, to validate a report before saving it.
+func parseReportQuery(text string, params map[string]string) (*reportQuery, error) {
+	tokens := strings.Fields(strings.ReplaceAll(text, ",", " , "))
+	q := &reportQuery{limit: defaultReportLimit}
+	pos := 0
+
+	next := func() (string, bool) {
+		if pos >= len(tokens) {
+			return "", false
+		}
+		pos++
+		return tokens[pos-1], true
+	}
+	keyword := func(expected string) error {
+		token, ok := next()
+		if !ok || !strings.EqualFold(token, expected) {
+			return fmt.Errorf("expected %s", expected)
+		}
+		return nil
+	}
+	// value returns a literal or bound placeholder, bound is false for unbound placeholders
+	value := func(what string) (string, bool, error) {
+		token, ok := next()
+		if !ok {
+			return "", false, fmt.Errorf("missing %s", what)
+		}
+		if name, found := strings.CutPrefix(token, "$"); found {
+			if params == nil {
+				return "", false, nil
+			}
+			bound, ok := params[name]
+			if !ok {
+				return "", false, fmt.Errorf("missing value for parameter $%s", name)
+			}
+			return bound, true, nil
+		}
+		return strings.Trim(token, `'"`), true, nil
+	}
+
+	if err := keyword("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		metric, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("missing metric")
+		}
+		metric = strings.ToLower(metric)
+		if !reportMetrics[metric] {
+			return nil, fmt.Errorf("unknown metric '%s': use count, successes, failures, success_rate or failure_rate", metric)
+		}
+		q.metrics = append(q.metrics, metric)
+		if pos < len(tokens) && tokens[pos] == "," {
+			pos++
+			continue
+		}
+		break
+	}
+
+	if err := keyword("BY"); err != nil {
+		return nil, err
+	}
+	dimension, ok := next()
+	if !ok {
+		return nil, fmt.Errorf("missing dimension")
+	}
+	if key, found := strings.CutPrefix(dimension, "tag:"); found && key != "" {
+		q.dimension, q.tagKey = "tag", key
+	} else {
+		switch strings.ToLower(dimension) {
+		case "minion", "command", "day", "impact":
+			q.dimension = strings.ToLower(dimension)
+		default:
+			return nil, fmt.Errorf("unknown dimension '%s': use minion, command, day, impact or tag:<key>", dimension)
+		}
+	}
+
+	for pos < len(tokens) {
+		clause, _ := next()
+		switch strings.ToUpper(clause) {
+		case "WHERE":
+			for {
+				cond, err := parseReportCondition(next, value)
+				if err != nil {
+					return nil, err
+				}
+				q.conditions = append(q.conditions, cond)
+				if pos < len(tokens) && strings.EqualFold(tokens[pos], "AND") {
+					pos++
+					continue
+				}
+				break
+			}
+		case "SINCE":
+			raw, bound, err := value("duration")
+			if err != nil {
+				return nil, err
+			}
+			if bound {
+				if q.since, err = parseReportDuration(raw); err != nil {
+					return nil, err
+				}
+			}
+		case "LIMIT":
+			raw, bound, err := value("limit")
+			if err != nil {
+				return nil, err
+			}
+			if bound {
+				limit, err := strconv.Atoi(raw)
+				if err != nil || limit < 1 || limit > maxReportLimit {
+					return nil, fmt.Errorf("limit must be between 1 and %d", maxReportLimit)
+				}
+				q.limit = limit
+			}
+		default:
+			return nil, fmt.Errorf("unexpected '%s': expected WHERE, SINCE or LIMIT", clause)
+		}
+	}
+
+	return q, nil
+}
+
+// parseReportCondition parses "<field> <operator> <value>"
+func parseReportCondition(next func() (string, bool), value func(string) (string, bool, error)) (reportCondition, error) {
+	var cond reportCondition
+
+	field, ok := next()
+	if !ok {
+		return cond, fmt.Errorf("missing condition")
+	}
+	if key, found := strings.CutPrefix(field, "tag:"); found && key != "" {
+		cond.field, cond.tagKey = "tag", key
+	} else {
+		switch strings.ToLower(field) {
+		case "command", "minion", "impact":
+			cond.field = strings.ToLower(field)
+		default:
+			return cond, fmt.Errorf("unknown condition field '%s': use command, minion, impact or tag:<key>", field)
+		}
+	}
+
+	operator, ok := next()
+	switch {
+	case !ok:
+		return cond, fmt.Errorf("missing operator after %s", field)
+	case operator == "=":
+	case strings.EqualFold(operator, "LIKE") && cond.field == "command":
+		cond.like = true
+	default:
+		return cond, fmt.Errorf("unsupported operator '%s' for %s", operator, field)
+	}
+
+	v, _, err := value("value")
+	if err != nil {
+		return cond, err
+	}
+	cond.value = v
+	return cond, nil
+}
+
+// parseReportDuration parses a Go duration or a number of days such as "7d"
+func parseReportDuration(raw string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(raw, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration '%s': use e.g. 7d or 12h", raw)
+	}
+	return d, nil
+}
+
+// byCommand reports whether the query groups or filters by command on encrypted payloads. Such
+// queries return each stored payload with the command and minion it is bound to, and are aggregated
+// by command once decrypted.
+func (q *reportQuery) byCommand() bool {
+	if !q.encrypted {
+		return false
+	}
+	if q.dimension == "command" {
+		return true
+	}
+	for _, cond := range q.conditions {
+		if cond.field == "command" {
+			return true
+		}
+	}
+	return false
+}
+
+// sql builds the aggregation query. Identifiers come from a fixed set,
+// every user supplied value is passed as an argument.
+func (q *reportQuery) sql(now time.Time) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var dimension string
+	switch q.dimension {
+	case "minion":
+		dimension = "r.minion_id"
+	case "command":
+		dimension = "split_part(c.command, ' ', 1)"
+		if q.byCommand() {
+			dimension = "''"
+		}
+	case "day":
+		dimension = "to_char(r.timestamp, 'YYYY-MM-DD')"
+	case "impact":
+		dimension = "COALESCE(c.impact, '')"
+	case "tag":
+		dimension = fmt.Sprintf("COALESCE(h.tags->>%s, '')", arg(q.tagKey))
+	}
+
+	var where []string
+	if q.since > 0 {
+		where = append(where, "r.timestamp >= "+arg(now.Add(-q.since)))
+	}
+	for _, cond := range q.conditions {
+		switch {
+		case cond.field == "command" && q.byCommand():
+			// matched by aggregateByCommand once decrypted
+		case cond.field == "command" && cond.like:
+			where = append(where, "c.command LIKE "+arg(strings.ReplaceAll(cond.value, "*", "%")))
+		case cond.field == "command":
+			where = append(where, "c.command = "+arg(cond.value))
+		case cond.field == "minion":
+			where = append(where, "r.minion_id = "+arg(cond.value))
+		case cond.field == "impact":
+			where = append(where, "c.impact = "+arg(cond.value))
+		case cond.field == "tag":
+			where = append(where, fmt.Sprintf("h.tags->>%s = %s", arg(cond.tagKey), arg(cond.value)))
+		}
+	}
+
+	var query strings.Builder
+	query.WriteString("SELECT " + dimension + " AS dimension, ")
+	if q.byCommand() {
+		query.WriteString("c.id, c.host_id, c.command, ")
+	}
+	query.WriteString("COUNT(*), ")
+	query.WriteString("COALESCE(SUM(CASE WHEN r.exit_code <> 0 THEN 1 ELSE 0 END), 0) ")
+	query.WriteString("FROM command_results r ")
+	query.WriteString("JOIN commands c ON c.id = r.command_id ")
+	query.WriteString("JOIN hosts h ON h.id = r.minion_id")
+	if len(where) > 0 {
+		query.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	if q.byCommand() {
+		query.WriteString(" GROUP BY 1, 2, 3, 4")
+	} else {
+		query.WriteString(" GROUP BY 1 ORDER BY 1 LIMIT " + strconv.Itoa(q.limit))
+	}
+
+	return query.String(), args
+}
+
+// aggregateByCommand filters the rows of a query by command on their decrypted payload and
+// aggregates them by dimension, the first word of the payload for a report by command
+func (q *reportQuery) aggregateByCommand(rows []ReportRow) []ReportRow {
+	byDimension := map[string]*ReportRow{}
+	var dimensions []string
+	for _, row := range rows {
+		if !q.matchCommand(row.Command) {
+			continue
+		}
+		dimension := row.Dimension
+		if q.dimension == "command" {
+			dimension, _, _ = strings.Cut(row.Command, " ")
+		}
+		aggregated, exists := byDimension[dimension]
+		if !exists {
+			aggregated = &ReportRow{Dimension: dimension}
+			byDimension[dimension] = aggregated
+			dimensions = append(dimensions, dimension)
+		}
+		aggregated.Count += row.Count
+		aggregated.Failures += row.Failures
+	}
+
+	sort.Strings(dimensions)
+	if len(dimensions) > q.limit {
+		dimensions = dimensions[:q.limit]
+	}
+	aggregated := make([]ReportRow, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		aggregated = append(aggregated, *byDimension[dimension])
+	}
+	return aggregated
+}
+
+// matchCommand reports whether a decrypted payload satisfies the command conditions of the query
+func (q *reportQuery) matchCommand(payload string) bool {
+	for _, cond := range q.conditions {
+		switch {
+		case cond.field == "command" && cond.like:
+			if !matchCommandPattern(cond.value, payload) {
+				return false
+			}
+		case cond.field == "command":
+			if payload != cond.value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// columns returns the header of the report table
+func (q *reportQuery) columns() []string {
+	dimension := q.dimension
+	if dimension == "tag" {
+		dimension = "tag:" + q.tagKey
+	}
+	return append([]string{dimension}, q.metrics...)
+}
+
+// format renders an aggregated row with the selected metrics
+func (q *reportQuery) format(row ReportRow) *pb.ReportRow {
+	values := []string{row.Dimension}
+	for _, metric := range q.metrics {
+		switch metric {
+		case "count":
+			values = append(values, strconv.FormatInt(row.Count, 10))
+		case "successes":
+			values = append(values, strconv.FormatInt(row.Count-row.Failures, 10))
+		case "failures":
+			values = append(values, strconv.FormatInt(row.Failures, 10))
+		case "success_rate":
+			values = append(values, formatRate(row.Count-row.Failures, row.Count))
+		case "failure_rate":
+			values = append(values, formatRate(row.Failures, row.Count))
+		}
+	}
+	return &pb.ReportRow{Values: values}
+}
+
+// formatRate formats part/total as a percentage
+func formatRate(part, total int64) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+}
+
+// CreateReport saves a report in the ConsoleService, replacing any report with the same name
+func (s *Server) CreateReport(ctx context.Context, req *pb.Report) (*pb.Report, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.CreateReport")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "reports require a database")
+	}
+	if err := requireAllNamespaces(ctx, "reports"); err != nil {
+		return nil, err
+	}
+	if !reportNamePattern.MatchString(req.Name) {
+		return nil, status.Error(codes.InvalidArgument, "report name must start with a letter and contain only letters, digits, '-' and '_'")
+	}
+	if _, err := parseReportQuery(req.Query, nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid report query: %v", err))
+	}
+
+	report := &pb.Report{
+		Name:        req.Name,
+		Query:       req.Query,
+		Description: req.Description,
+		CreatedAt:   time.Now().Unix(),
+	}
+	if err := s.dbService.SaveReport(ctx, report); err != nil {
+		logger.Error("Failed to save report", zap.String("report", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to save report")
+	}
+
+	logger.Info("Report saved", zap.String("report", report.Name), zap.String("query", report.Query))
+	return report, nil
+}
+
+// ListReports returns the saved reports in the ConsoleService
+func (s *Server) ListReports(ctx context.Context, empty *pb.Empty) (*pb.ReportList, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.ListReports")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return &pb.ReportList{}, nil
+	}
+	if err := requireAllNamespaces(ctx, "reports"); err != nil {
+		return nil, err
+	}
+
+	reports, err := s.dbService.ListReports(ctx)
+	if err != nil {
+		logger.Error("Failed to list reports", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to list reports")
+	}
+	return &pb.ReportList{Reports: reports}, nil
+}
+
+// RunReport runs a saved report with the given parameters in the ConsoleService
+func (s *Server) RunReport(ctx context.Context, req *pb.ReportRequest) (*pb.ReportResult, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.RunReport")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "reports require a database")
+	}
+	if err := requireAllNamespaces(ctx, "reports"); err != nil {
+		return nil, err
+	}
+
+	report, err := s.dbService.GetReport(ctx, req.Name)
+	if err != nil {
+		logger.Error("Failed to load report", zap.String("report", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load report")
+	}
+	if report == nil {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("report %s not found", req.Name))
+	}
+
+	params := req.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+	query, err := parseReportQuery(report.Query, params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	query.encrypted = s.encryptsPayloads()
+	sqlQuery, args := query.sql(time.Now())
+	rows, err := s.dbService.QueryReport(ctx, sqlQuery, args)
+	if err != nil {
+		logger.Error("Failed to run report", zap.String("report", req.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to run report")
+	}
+
+	if query.byCommand() {
+		rows = query.aggregateByCommand(rows)
+	}
+
+	result := &pb.ReportResult{Name: report.Name, Columns: query.columns()}
+	for _, row := range rows {
+		result.Rows = append(result.Rows, query.format(row))
+	}
+	logger.Debug("Report run", zap.String("report", req.Name), zap.Int("rows", len(result.Rows)))
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package nexus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseReportQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		params  map[string]string
+		wantErr string
+	}{
+		{"simple", "SELECT count BY minion", map[string]string{}, ""},
+		{"full", "select count, failure_rate by tag:env where command like system:* and tag:role = web since 7d limit 10", map[string]string{}, ""},
+		{"bound parameters", "SELECT failures BY day SINCE $period LIMIT $limit", map[string]string{"period": "12h", "limit": "5"}, ""},
+		{"validation only", "SELECT failures BY day WHERE minion = $minion SINCE $period", nil, ""},
+		{"missing parameter", "SELECT failures BY day SINCE $period", map[string]string{}, "missing value for parameter $period"},
+		{"unknown metric", "SELECT avg BY minion", nil, "unknown metric"},
+		{"unknown dimension", "SELECT count BY host", nil, "unknown dimension"},
+		{"missing BY", "SELECT count", nil, "expected BY"},
+		{"like on minion", "SELECT count BY minion WHERE minion LIKE m*", nil, "unsupported operator"},
+		{"invalid duration", "SELECT count BY minion SINCE forever", nil, "invalid duration"},
+		{"invalid limit", "SELECT count BY minion LIMIT 0", nil, "limit must be between"},
+		{"by impact", "SELECT count, failures BY impact WHERE impact = disruptive SINCE 30d", nil, ""},
+		{"like on impact", "SELECT count BY impact WHERE impact LIKE dis*", nil, "unsupported operator"},
+		{"trailing clause", "SELECT count BY minion ORDER dimension", nil, "unexpected 'ORDER'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseReportQuery(tt.query, tt.params)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReportQuerySQL(t *testing.T) {
+	q, err := parseReportQuery("SELECT count, failure_rate BY tag:env WHERE command LIKE 'system:*' AND tag:role = web SINCE 7d", map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+
+	// Payloads stored in clear are matched in SQL
+	query, args := q.sql(now)
+	for _, fragment := range []string{"c.command LIKE $3", "h.tags->>$4 = $5", "GROUP BY 1 ORDER BY 1 LIMIT 100"} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("Expected query to contain %q, got: %s", fragment, query)
+		}
+	}
+	if len(args) != 5 || args[2] != "system:%" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	// Encrypted payloads are returned bound to their command and minion, the command condition being
+	// left to aggregateByCommand
+	q.encrypted = true
+	query, args = q.sql(now)
+	for _, fragment := range []string{
+		"COALESCE(h.tags->>$1, '') AS dimension, c.id, c.host_id, c.command, COUNT(*)",
+		"r.timestamp >= $2",
+		"h.tags->>$3 = $4",
+		"GROUP BY 1, 2, 3, 4",
+	} {
+		if !strings.Contains(query, fragment) {
+			t.Errorf("Expected query to contain %q, got: %s", fragment, query)
+		}
+	}
+	if strings.Contains(query, "c.command LIKE") || strings.Contains(query, "LIMIT") {
+		t.Errorf("Expected the command condition and limit applied after decryption, got: %s", query)
+	}
+
+	want := []interface{}{"env", now.Add(-7 * 24 * time.Hour), "role", "web"}
+	if len(args) != len(want) {
+		t.Fatalf("Expected %d args, got %d: %v", len(want), len(args), args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("Arg %d: expected %v, got %v", i, want[i], args[i])
+		}
+	}
+
+	row := q.format(ReportRow{Dimension: "prod", Count: 8, Failures: 2})
+	if got := strings.Join(row.Values, "|"); got != "prod|8|25.0%" {
+		t.Errorf("Unexpected formatted row: %s", got)
+	}
+	if got := strings.Join(q.columns(), "|"); got != "tag:env|count|failure_rate" {
+		t.Errorf("Unexpected columns: %s", got)
+	}
+
+	rows := q.aggregateByCommand([]ReportRow{
+		{Dimension: "prod", Command: "system:info", Count: 3, Failures: 1},
+		{Dimension: "prod", Command: "docker:ps", Count: 5, Failures: 5},
+		{Dimension: "prod", Command: "system:disk", Count: 2},
+		{Dimension: "dev", Command: "system:info", Count: 1},
+	})
+	if len(rows) != 2 || rows[0] != (ReportRow{Dimension: "dev", Count: 1}) || rows[1] != (ReportRow{Dimension: "prod", Count: 5, Failures: 1}) {
+		t.Errorf("Unexpected aggregated rows: %v", rows)
+	}
+
+	q, err = parseReportQuery("SELECT count BY impact WHERE impact = disruptive", map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	query, args = q.sql(now)
+	if !strings.Contains(query, "COALESCE(c.impact, '') AS dimension") || !strings.Contains(query, "c.impact = $") || args[len(args)-1] != "disruptive" {
+		t.Errorf("Unexpected impact query: %s %v", query, args)
+	}
+	if !strings.Contains(query, "GROUP BY 1 ORDER BY 1 LIMIT 100") || strings.Contains(query, "c.command") {
+		t.Errorf("Expected an impact report aggregated in SQL, got: %s", query)
+	}
+
+	q, err = parseReportQuery("SELECT count BY command LIMIT 1", map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if query, _ = q.sql(now); !strings.Contains(query, "split_part(c.command, ' ', 1) AS dimension") {
+		t.Errorf("Expected a report by command on payloads in clear grouped in SQL, got: %s", query)
+	}
+	q.encrypted = true
+	rows = q.aggregateByCommand([]ReportRow{
+		{Command: "system:info", Count: 3},
+		{Command: "docker:ps -a", Count: 2},
+		{Command: "docker:ps", Count: 1, Failures: 1},
+	})
+	if len(rows) != 1 || rows[0] != (ReportRow{Dimension: "docker:ps", Count: 3, Failures: 1}) {
+		t.Errorf("Expected the rows aggregated by first word and limited, got %v", rows)
+	}
+}
+
+func TestReportRPCs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+	ctx := context.Background()
+
+	// Invalid queries are rejected before reaching the database
+	if _, err := server.CreateReport(ctx, &pb.Report{Name: "bad", Query: "SELECT nothing"}); err == nil {
+		t.Error("Expected invalid query to be rejected")
+	}
+	if _, err := server.CreateReport(ctx, &pb.Report{Name: "1bad", Query: "SELECT count BY minion"}); err == nil {
+		t.Error("Expected invalid name to be rejected")
+	}
+
+	mock.ExpectExec("INSERT INTO reports").
+		WithArgs("failures", "SELECT failures BY minion SINCE $period", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if _, err := server.CreateReport(ctx, &pb.Report{Name: "failures", Query: "SELECT failures BY minion SINCE $period"}); err != nil {
+		t.Fatalf("CreateReport failed: %v", err)
+	}
+
+	reportRows := sqlmock.NewRows([]string{"name", "query", "description", "created_at"}).
+		AddRow("failures", "SELECT failures BY minion SINCE $period", "", int64(1700000000))
+	mock.ExpectQuery("SELECT name, query").WithArgs("failures").WillReturnRows(reportRows)
+	mock.ExpectQuery("SELECT r.minion_id AS dimension").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"dimension", "count", "failures"}).
+			AddRow("minion-1", int64(4), int64(1)).
+			AddRow("minion-2", int64(2), int64(0)))
+
+	result, err := server.RunReport(ctx, &pb.ReportRequest{Name: "failures", Params: map[string]string{"period": "7d"}})
+	if err != nil {
+		t.Fatalf("RunReport failed: %v", err)
+	}
+	if len(result.Rows) != 2 || strings.Join(result.Rows[0].Values, "|") != "minion-1|1" {
+		t.Errorf("Unexpected report result: %v", result)
+	}
+
+	// Namespace scoped consoles neither run nor list reports
+	if _, err := server.ListReports(namespaceContext("team-a"), &pb.Empty{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected namespace scoped consoles not to list reports, got %v", err)
+	}
+
+	// Unknown reports
+	mock.ExpectQuery("SELECT name, query").WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "query", "description", "created_at"}))
+	if _, err := server.RunReport(ctx, &pb.ReportRequest{Name: "missing"}); err == nil {
+		t.Error("Expected error for unknown report")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// resultChunkExpiry is the time a result split in chunks waits for its missing chunks before
+	// being dropped, the minion sending the whole result again without acknowledgement
+	resultChunkExpiry = 10 * time.Minute
+
+	// maxResultChunks bounds the number of chunks of a result
+	maxResultChunks = 1024
+
+	// maxPendingChunkedResults and maxMinionChunkBytes bound the results a minion has being
+	// reassembled and the size of their chunks, and so the memory it can make Nexus hold
+	maxPendingChunkedResults = 16
+	maxMinionChunkBytes      = 256 << 20
+
+	// maxPendingChunkBytes bounds the size of the chunks of all the minions, a fleet sending large
+	// results at once being refused rather than exhausting the memory of Nexus
+	maxPendingChunkBytes = 1 << 30
+)
+
+// chunkedResult is a result whose chunks are being received
+type chunkedResult struct {
+	chunks   [][]byte // by index, nil until received
+	received int
+	size     int // bytes of the chunks received
+	updated  time.Time
+}
+
+// minionChunks are the results of a minion being reassembled
+type minionChunks struct {
+	results map[string]*chunkedResult // by command ID
+	size    int                       // bytes of the chunks received for all the results
+}
+
+// resultChunks reassembles the results minions split in chunks, those larger than the message
+// size announced at registration
+type resultChunks struct {
+	mu      sync.Mutex
+	minions map[string]*minionChunks // by minion ID
+	size    int                      // bytes of the chunks received from all the minions
+}
+
+// started reports whether chunks of the result of a command were received from a minion
+func (c *resultChunks) started(minionID, commandID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	minion, exists := c.minions[minionID]
+	if !exists {
+		return false
+	}
+	_, exists = minion.results[commandID]
+	return exists
+}
+
+// dropLocked forgets the result of a command a minion has being reassembled
+func (c *resultChunks) dropLocked(minion *minionChunks, commandID string) {
+	if pending, exists := minion.results[commandID]; exists {
+		minion.size -= pending.size
+		c.size -= pending.size
+		delete(minion.results, commandID)
+	}
+}
+
+// add stores a chunk sent by a minion, returning the result once all its chunks are received.
+// A chunk announcing another number of chunks restarts the result, sent again by the minion.
+// Chunks making the minion exceed maxPendingChunkedResults or maxMinionChunkBytes, or all the minions
+// exceed maxPendingChunkBytes, are rejected.
+func (c *resultChunks) add(minionID string, chunk *pb.ResultChunk, now time.Time) (*pb.CommandResult, error) {
+	if chunk.Total < 1 || chunk.Total > maxResultChunks {
+		return nil, fmt.Errorf("result split in %d chunks, at most %d allowed", chunk.Total, maxResultChunks)
+	}
+	if chunk.Index < 0 || chunk.Index >= chunk.Total {
+		return nil, fmt.Errorf("chunk %d of a result split in %d chunks", chunk.Index, chunk.Total)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.minions == nil {
+		c.minions = make(map[string]*minionChunks)
+	}
+	c.pruneLocked(now)
+
+	minion, exists := c.minions[minionID]
+	if !exists {
+		minion = &minionChunks{results: make(map[string]*chunkedResult)}
+		c.minions[minionID] = minion
+	}
+	pending, exists := minion.results[chunk.CommandId]
+	if exists && len(pending.chunks) != int(chunk.Total) {
+		c.dropLocked(minion, chunk.CommandId)
+		exists = false
+	}
+	if !exists && len(minion.results) >= maxPendingChunkedResults {
+		c.forgetIdleLocked(minionID)
+		return nil, fmt.Errorf("%d results already being reassembled, at most %d allowed", len(minion.results), maxPendingChunkedResults)
+	}
+	var replaced int
+	if exists {
+		replaced = len(pending.chunks[chunk.Index])
+	}
+	if minion.size-replaced+len(chunk.Data) > maxMinionChunkBytes {
+		c.forgetIdleLocked(minionID)
+		return nil, fmt.Errorf("results being reassembled exceed %d bytes", maxMinionChunkBytes)
+	}
+	if c.size-replaced+len(chunk.Data) > maxPendingChunkBytes {
+		c.forgetIdleLocked(minionID)
+		return nil, fmt.Errorf("results of all minions being reassembled exceed %d bytes", maxPendingChunkBytes)
+	}
+	if !exists {
+		pending = &chunkedResult{chunks: make([][]byte, chunk.Total)}
+		minion.results[chunk.CommandId] = pending
+	}
+
+	if pending.chunks[chunk.Index] == nil {
+		pending.received++
+	}
+	// Empty chunks are stored non-nil to count as received
+	data := chunk.Data
+	if data == nil {
+		data = []byte{}
+	}
+	pending.chunks[chunk.Index] = data
+	pending.size += len(chunk.Data) - replaced
+	minion.size += len(chunk.Data) - replaced
+	c.size += len(chunk.Data) - replaced
+	pending.updated = now
+	if pending.received < len(pending.chunks) {
+		return nil, nil
+	}
+
+	c.dropLocked(minion, chunk.CommandId)
+	c.forgetIdleLocked(minionID)
+	data = make([]byte, 0, pending.size)
+	for _, part := range pending.chunks {
+		data = append(data, part...)
+	}
+	result := &pb.CommandResult{}
+	if err := proto.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("invalid chunked result: %w", err)
+	}
+	if result.CommandId != chunk.CommandId {
+		return nil, fmt.Errorf("chunks of command %s carry the result of command %s", chunk.CommandId, result.CommandId)
+	}
+	return result, nil
+}
+
+// forgetIdleLocked forgets a minion without results being reassembled
+func (c *resultChunks) forgetIdleLocked(minionID string) {
+	if minion, exists := c.minions[minionID]; exists && len(minion.results) == 0 {
+		delete(c.minions, minionID)
+	}
+}
+
+// pruneLocked drops the results waiting for their missing chunks for longer than resultChunkExpiry
+func (c *resultChunks) pruneLocked(now time.Time) {
+	for minionID, minion := range c.minions {
+		for commandID, pending := range minion.results {
+			if now.Sub(pending.updated) > resultChunkExpiry {
+				c.dropLocked(minion, commandID)
+			}
+		}
+		c.forgetIdleLocked(minionID)
+	}
+}
+
+// len returns the number of results waiting for their missing chunks
+func (c *resultChunks) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for _, minion := range c.minions {
+		count += len(minion.results)
+	}
+	return count
+}
+
+// SetMaxMsgSize sets the message size announced to minions at registration, minions splitting
+// the results larger than it in chunks. 0 keeps results whole.
+func (s *Server) SetMaxMsgSize(size int) {
+	s.maxMsgSize = size
+}
+
+// assembleResult stores a chunk of a result, returning the result once complete. The first chunk
+// of a result is only accepted while the command waits for the result of the minion.
+func (s *Server) assembleResult(ctx context.Context, minionID string, chunk *pb.ResultChunk, logger *zap.Logger) *pb.CommandResult {
+	if !s.chunks.started(minionID, chunk.CommandId) && !s.resultOutstanding(ctx, chunk.CommandId, minionID) {
+		logger.Warn("Dropped result chunk of a command not waiting for the minion",
+			zap.String("minion_id", minionID),
+			zap.String("command_id", chunk.CommandId))
+		return nil
+	}
+
+	result, err := s.chunks.add(minionID, chunk, time.Now())
+	if err != nil {
+		logger.Warn("Dropped invalid result chunk",
+			zap.String("minion_id", minionID),
+			zap.String("command_id", chunk.CommandId),
+			zap.Error(err))
+		return nil
+	}
+	if result != nil {
+		logger.Debug("Reassembled chunked result",
+			zap.String("minion_id", minionID),
+			zap.String("command_id", result.CommandId),
+			zap.Int32("chunks", chunk.Total))
+	}
+	return result
+}
+
+// resultOutstanding reports whether a command waits for the result of a minion, according to the
+// commands tracked since Nexus started, then to the command statuses stored in the database
+func (s *Server) resultOutstanding(ctx context.Context, commandID, minionID string) bool {
+	s.pendingMu.Lock()
+	tracker, exists := s.pendingCommands[commandID]
+	pending := exists && tracker.Pending[minionID]
+	s.pendingMu.Unlock()
+	if pending || s.dbService == nil {
+		return pending
+	}
+
+	statuses, err := s.dbService.GetCommandStatuses(ctx, commandID)
+	if err != nil {
+		s.logger.Warn("Failed to read command statuses",
+			zap.String("command_id", commandID), zap.Error(err))
+		return false
+	}
+	for _, minionStatus := range statuses {
+		if minionStatus.MinionId == minionID {
+			return minionStatus.Status != "COMPLETED" && minionStatus.Status != "FAILED"
+		}
+	}
+	return false
+}
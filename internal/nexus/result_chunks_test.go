@@ -0,0 +1,283 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/arhuman/minexus/internal/longpoll"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// chunkResult splits the serialization of a result in n chunks
+func chunkResult(t *testing.T, result *pb.CommandResult, n int) []*pb.ResultChunk {
+	t.Helper()
+	data, err := proto.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	size := (len(data) + n - 1) / n
+	chunks := make([]*pb.ResultChunk, 0, n)
+	for i := 0; i < n; i++ {
+		end := min((i+1)*size, len(data))
+		chunks = append(chunks, &pb.ResultChunk{CommandId: result.CommandId, Index: int32(i), Total: int32(n), Data: data[i*size : end]})
+	}
+	return chunks
+}
+
+func TestResultChunks(t *testing.T) {
+	var chunks resultChunks
+	now := time.Now()
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: strings.Repeat("output ", 100)}
+	parts := chunkResult(t, result, 3)
+
+	// Chunks are reassembled in any order, duplicates included
+	for _, chunk := range []*pb.ResultChunk{parts[2], parts[0], parts[2]} {
+		if got, err := chunks.add("minion-1", chunk, now); got != nil || err != nil {
+			t.Fatalf("Expected the result incomplete, got %v, %v", got, err)
+		}
+	}
+	// Chunks of other minions are kept apart
+	if got, _ := chunks.add("minion-2", parts[1], now); got != nil {
+		t.Fatal("Expected the chunk of another minion not to complete the result")
+	}
+	got, err := chunks.add("minion-1", parts[1], now)
+	if err != nil || !proto.Equal(got, result) {
+		t.Fatalf("Expected the result reassembled, got %v, %v", got, err)
+	}
+	if chunks.len() != 1 {
+		t.Errorf("Expected only the result of minion-2 pending, got %d", chunks.len())
+	}
+
+	// A result split anew restarts its reassembly
+	chunks.add("minion-1", parts[0], now)
+	again := chunkResult(t, result, 2)
+	chunks.add("minion-1", again[0], now)
+	if got, err := chunks.add("minion-1", again[1], now); err != nil || !proto.Equal(got, result) {
+		t.Errorf("Expected the result split anew reassembled, got %v, %v", got, err)
+	}
+
+	// Incomplete results expire
+	chunks.add("minion-1", parts[0], now)
+	chunks.add("minion-1", parts[1], now.Add(resultChunkExpiry+time.Minute))
+	if chunks.len() != 1 {
+		t.Errorf("Expected the expired results dropped, got %d pending", chunks.len())
+	}
+
+	invalid := []*pb.ResultChunk{
+		{CommandId: "cmd-2", Index: 0, Total: 0},
+		{CommandId: "cmd-2", Index: 2, Total: 2},
+		{CommandId: "cmd-2", Index: -1, Total: 2},
+		{CommandId: "cmd-2", Index: 0, Total: maxResultChunks + 1},
+	}
+	for _, chunk := range invalid {
+		if _, err := chunks.add("minion-1", chunk, now); err == nil {
+			t.Errorf("Expected chunk %d/%d rejected", chunk.Index, chunk.Total)
+		}
+	}
+
+	// Chunks must carry the result of their command
+	if _, err := chunks.add("minion-1", &pb.ResultChunk{CommandId: "cmd-3", Index: 0, Total: 1, Data: parts[0].Data}, now); err == nil {
+		t.Error("Expected chunks of another command rejected")
+	}
+	data, _ := proto.Marshal(result)
+	if _, err := chunks.add("minion-1", &pb.ResultChunk{CommandId: "cmd-1", Index: 0, Total: 1, Data: data}, now); err != nil {
+		t.Errorf("Expected a single chunk accepted, got %v", err)
+	}
+}
+
+func TestResultChunksLimits(t *testing.T) {
+	var chunks resultChunks
+	now := time.Now()
+
+	// A minion can only have so many results being reassembled, others minions being unaffected
+	for i := 0; i < maxPendingChunkedResults; i++ {
+		if _, err := chunks.add("minion-1", &pb.ResultChunk{CommandId: fmt.Sprintf("cmd-%d", i), Index: 0, Total: 2}, now); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := chunks.add("minion-1", &pb.ResultChunk{CommandId: "cmd-extra", Index: 0, Total: 2}, now); err == nil {
+		t.Error("Expected a result beyond the limit rejected")
+	}
+	if _, err := chunks.add("minion-1", &pb.ResultChunk{CommandId: "cmd-0", Index: 0, Total: 2}, now); err != nil {
+		t.Errorf("Expected the chunks of the results started accepted, got %v", err)
+	}
+	if _, err := chunks.add("minion-2", &pb.ResultChunk{CommandId: "cmd-extra", Index: 0, Total: 2}, now); err != nil {
+		t.Errorf("Expected the results of another minion accepted, got %v", err)
+	}
+
+	// So do the bytes of their chunks, a chunk sent again replacing its previous data
+	large := make([]byte, maxMinionChunkBytes/2)
+	if _, err := chunks.add("minion-3", &pb.ResultChunk{CommandId: "cmd-1", Index: 0, Total: 3, Data: large}, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := chunks.add("minion-3", &pb.ResultChunk{CommandId: "cmd-1", Index: 0, Total: 3, Data: large}, now); err != nil {
+		t.Errorf("Expected a chunk sent again accepted, got %v", err)
+	}
+	if _, err := chunks.add("minion-3", &pb.ResultChunk{CommandId: "cmd-1", Index: 1, Total: 3, Data: large}, now); err != nil {
+		t.Errorf("Expected the chunks up to the limit accepted, got %v", err)
+	}
+	if _, err := chunks.add("minion-3", &pb.ResultChunk{CommandId: "cmd-2", Index: 0, Total: 2, Data: []byte("x")}, now); err == nil {
+		t.Error("Expected the chunks beyond the byte limit rejected")
+	}
+
+	// Expired results free their room
+	if _, err := chunks.add("minion-3", &pb.ResultChunk{CommandId: "cmd-2", Index: 0, Total: 2, Data: []byte("x")}, now.Add(resultChunkExpiry+time.Minute)); err != nil {
+		t.Errorf("Expected room once the results expired, got %v", err)
+	}
+
+	// All the minions together are bounded as well
+	var fleet resultChunks
+	for i := 0; i < maxPendingChunkBytes/maxMinionChunkBytes; i++ {
+		for index := int32(0); index < 2; index++ {
+			if _, err := fleet.add(fmt.Sprintf("minion-%d", i), &pb.ResultChunk{CommandId: "cmd-1", Index: index, Total: 3, Data: large}, now); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	if _, err := fleet.add("minion-extra", &pb.ResultChunk{CommandId: "cmd-1", Index: 0, Total: 2, Data: []byte("x")}, now); err == nil {
+		t.Error("Expected the chunks beyond the limit of all minions rejected")
+	}
+	if _, err := fleet.add("minion-extra", &pb.ResultChunk{CommandId: "cmd-1", Index: 0, Total: 2, Data: []byte("x")}, now.Add(resultChunkExpiry+time.Minute)); err != nil || fleet.size != 1 {
+		t.Errorf("Expected room once the results expired, got %v (%d bytes)", err, fleet.size)
+	}
+}
+
+func TestAssembleResultOutstanding(t *testing.T) {
+	server := createTestServer(nil)
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: strings.Repeat("output ", 100)}
+	parts := chunkResult(t, result, 2)
+
+	// Chunks of commands not waiting for the minion are dropped
+	server.trackCommand("cmd-1", "system:info", []string{"minion-2"})
+	server.assembleResult(context.Background(), "minion-1", parts[0], zap.NewNop())
+	if server.chunks.len() != 0 {
+		t.Fatal("Expected the chunk of a command not sent to the minion dropped")
+	}
+
+	server.trackCommand("cmd-1", "system:info", []string{"minion-1", "minion-2"})
+	server.assembleResult(context.Background(), "minion-1", parts[0], zap.NewNop())
+	if got := server.assembleResult(context.Background(), "minion-1", parts[1], zap.NewNop()); !proto.Equal(got, result) {
+		t.Errorf("Expected the result reassembled, got %v", got)
+	}
+}
+
+func TestResultOutstandingFromDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	// Commands dispatched before Nexus restarted are checked in the database
+	statuses := sqlmock.NewRows([]string{"host_id", "status", "timestamp"}).
+		AddRow("minion-1", "EXECUTING", int64(1700000000)).
+		AddRow("minion-2", "COMPLETED", int64(1700000000))
+	mock.ExpectQuery("FROM commands c").WithArgs("cmd-1").WillReturnRows(statuses)
+	if !server.resultOutstanding(context.Background(), "cmd-1", "minion-1") {
+		t.Error("Expected the stored command waiting for minion-1")
+	}
+	statuses = sqlmock.NewRows([]string{"host_id", "status", "timestamp"}).
+		AddRow("minion-1", "EXECUTING", int64(1700000000)).
+		AddRow("minion-2", "COMPLETED", int64(1700000000))
+	mock.ExpectQuery("FROM commands c").WithArgs("cmd-1").WillReturnRows(statuses)
+	if server.resultOutstanding(context.Background(), "cmd-1", "minion-2") {
+		t.Error("Expected the command not waiting for a completed minion")
+	}
+	mock.ExpectQuery("FROM commands c").WithArgs("cmd-1").
+		WillReturnRows(sqlmock.NewRows([]string{"host_id", "status", "timestamp"}).AddRow("minion-1", "EXECUTING", int64(1700000000)))
+	if server.resultOutstanding(context.Background(), "cmd-1", "minion-3") {
+		t.Error("Expected the command not waiting for a minion it wasn't sent to")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestChunkedResultOverLongPolling(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	server.SetMaxMsgSize(4096)
+	httpServer := httptest.NewServer(server.LongPollHandler(4096))
+	defer httpServer.Close()
+
+	client := longpoll.NewClient(httpServer.URL, httpServer.Client())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := client.Register(ctx, &pb.HostInfo{Id: "chunking-minion", Hostname: "host"})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if resp.MaxMsgSize != 4096 {
+		t.Fatalf("Expected the message size announced, got %d", resp.MaxMsgSize)
+	}
+
+	streamCtx := metadata.AppendToOutgoingContext(ctx, "minion-id", "chunking-minion")
+	stream, err := client.StreamCommands(streamCtx)
+	if err != nil {
+		t.Fatalf("StreamCommands failed: %v", err)
+	}
+	defer stream.CloseSend()
+
+	server.trackCommand("cmd-1", "system:info", []string{"chunking-minion"})
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "spoofed", Stdout: strings.Repeat("x", 6000)}
+	for _, chunk := range chunkResult(t, result, 3) {
+		if err := stream.Send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_ResultChunk{ResultChunk: chunk}}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	diag := &pb.MinionDiagnostics{}
+	server.diagnostics.Fill("chunking-minion", diag)
+	if diag.ResultsReceived != 1 {
+		t.Errorf("Expected the reassembled result recorded for the minion, got %d", diag.ResultsReceived)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if msg.GetAck().GetCommandId() != "cmd-1" {
+		t.Errorf("Expected the reassembled result acknowledged, got %v", msg)
+	}
+}
+
+func TestStreamResultsAttributedToSender(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	stream := &MockStreamServer{ctx: context.Background()}
+	server.trackCommand("cmd-1", "uptime", []string{"minion-1"})
+	server.trackCommand("cmd-2", "uptime", []string{"minion-1"})
+
+	// Results claiming another minion, whole or in chunks, are recorded for the minion of the stream
+	server.handleReceivedMessage(stream, "minion-1", &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Result{
+		Result: &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-2"},
+	}}, zap.NewNop())
+	var ack *pb.ResultAck
+	for _, chunk := range chunkResult(t, &pb.CommandResult{CommandId: "cmd-2", MinionId: "minion-2", Stdout: "up"}, 2) {
+		ack = server.handleReceivedMessage(stream, "minion-1", &pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_ResultChunk{ResultChunk: chunk}}, zap.NewNop())
+	}
+	if ack.GetCommandId() != "cmd-2" {
+		t.Fatalf("Expected the reassembled result acknowledged, got %v", ack)
+	}
+
+	diag := &pb.MinionDiagnostics{}
+	server.diagnostics.Fill("minion-1", diag)
+	if diag.ResultsReceived != 2 {
+		t.Errorf("Expected 2 results recorded for the minion of the stream, got %d", diag.ResultsReceived)
+	}
+	diag = &pb.MinionDiagnostics{}
+	server.diagnostics.Fill("minion-2", diag)
+	if diag.ResultsReceived != 0 {
+		t.Errorf("Expected no result recorded for the spoofed minion, got %d", diag.ResultsReceived)
+	}
+}
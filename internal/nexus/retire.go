@@ -0,0 +1,366 @@
+package nexus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retireGracePeriod is the time a retired minion keeps being refused once notified, so that a minion
+// restarted by its service manager before it stopped for good doesn't register the host again
+const retireGracePeriod = 10 * time.Minute
+
+// errHostNotFound is returned by RetireHost for a host neither stored nor registered
+var errHostNotFound = errors.New("host not found")
+
+// retiredMinion is a minion retired from a console, refused at registration until notified
+type retiredMinion struct {
+	notice   *pb.RetireNotice
+	notified time.Time // zero until the minion received the notice
+}
+
+// retirements tracks the retired minions that still have to learn it, or learned it recently
+type retirements struct {
+	mu       sync.Mutex
+	minions  map[string]*retiredMinion
+	outbound map[string]chan *pb.RetireNotice // notices waiting for the command stream of each minion
+}
+
+// outboundFor returns the channel of the retirement notice waiting for the command stream of a minion
+func (r *retirements) outboundFor(minionID string) chan *pb.RetireNotice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.outbound == nil {
+		r.outbound = make(map[string]chan *pb.RetireNotice)
+	}
+	ch, exists := r.outbound[minionID]
+	if !exists {
+		ch = make(chan *pb.RetireNotice, 1)
+		r.outbound[minionID] = ch
+	}
+	return ch
+}
+
+// add records a retired minion, notified reporting whether it already received the notice
+func (r *retirements) add(minionID string, notice *pb.RetireNotice, notified time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.minions == nil {
+		r.minions = make(map[string]*retiredMinion)
+	}
+	r.minions[minionID] = &retiredMinion{notice: notice, notified: notified}
+}
+
+// pending returns the retirement notice of a minion still refused at registration, nil for the
+// minions that aren't retired or were notified longer than retireGracePeriod ago
+func (r *retirements) pending(minionID string, now time.Time) *pb.RetireNotice {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, retired := range r.minions {
+		if !retired.notified.IsZero() && now.Sub(retired.notified) > retireGracePeriod {
+			delete(r.minions, id)
+			delete(r.outbound, id)
+		}
+	}
+	if retired, exists := r.minions[minionID]; exists {
+		return retired.notice
+	}
+	return nil
+}
+
+// markNotified records that a minion received its retirement notice, reporting whether it is the
+// first time
+func (r *retirements) markNotified(minionID string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	retired, exists := r.minions[minionID]
+	if !exists || !retired.notified.IsZero() {
+		return false
+	}
+	retired.notified = now
+	return true
+}
+
+// notifyRetired records that a minion received its retirement notice, persisting it so that a
+// restarted Nexus doesn't send it again
+func (s *Server) notifyRetired(minionID string, logger *zap.Logger) {
+	if !s.retired.markNotified(minionID, time.Now()) {
+		return
+	}
+	logger.Info("Retired minion notified", zap.String("minion_id", minionID))
+	if s.dbService == nil {
+		return
+	}
+	if err := s.dbService.MarkRetirementNotified(context.Background(), minionID); err != nil {
+		logger.Error("Failed to record retirement notification", zap.String("minion_id", minionID), zap.Error(err))
+	}
+}
+
+// loadRetirements restores the retired minions not notified yet from the database
+func (s *Server) loadRetirements(ctx context.Context) {
+	notices, err := s.dbService.PendingRetirements(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load pending retirements", zap.Error(err))
+		return
+	}
+	for minionID, notice := range notices {
+		s.retired.add(minionID, notice, time.Time{})
+	}
+	if len(notices) > 0 {
+		s.logger.Info("Retired minions waiting for their notice", zap.Int("count", len(notices)))
+	}
+}
+
+// RetireMinion decommissions a host: Nexus stops dispatching to it, tells the minion to stop for good
+// and to uninstall itself if asked, archives its host and result rows into the retired_hosts and
+// retired_command_results tables and removes it from the active listings. Minions without command
+// stream learn it at their next registration, refused from then on. Retiring deletes the identity
+// binding of the minion and its results, so only administrators retire minions.
+func (s *Server) RetireMinion(ctx context.Context, req *pb.RetireMinionRequest) (*pb.RetireMinionResponse, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.RetireMinion")
+	defer logging.FuncExit(logger, start)
+
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if err := s.requireAdmin(ctx, "retiring minions"); err != nil {
+		return nil, err
+	}
+	if s.DatabaseStatus() == DatabaseUnavailable {
+		return nil, status.Error(codes.Unavailable, "database unavailable, retry later")
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	var info *pb.HostInfo
+	if conn, exists := registry.GetConnectionImpl(req.MinionId); exists {
+		info = conn.GetInfo()
+	}
+
+	notice := &pb.RetireNotice{
+		Uninstall: req.Uninstall,
+		Reason:    req.Reason,
+		RetiredBy: consoleIdentity(ctx),
+	}
+	resp := &pb.RetireMinionResponse{MinionId: req.MinionId}
+	if s.dbService != nil {
+		archived, err := s.dbService.RetireHost(ctx, req.MinionId, info, notice)
+		if errors.Is(err, errHostNotFound) {
+			return nil, status.Errorf(codes.NotFound, "minion %s not found", req.MinionId)
+		}
+		if err != nil {
+			logger.Error("Failed to archive retired host", zap.String("minion_id", req.MinionId), zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to retire minion: %v", err)
+		}
+		resp.ArchivedResults = int32(archived)
+	} else if info == nil {
+		return nil, status.Errorf(codes.NotFound, "minion %s not found", req.MinionId)
+	}
+
+	// Refuse the registrations of the minion before it leaves the registry
+	s.retired.add(req.MinionId, notice, time.Time{})
+	if registry.HasStream(req.MinionId) {
+		select {
+		case s.retired.outboundFor(req.MinionId) <- notice:
+			resp.Notified = true
+		default:
+		}
+	}
+
+	dropped := registry.Remove(req.MinionId)
+	resp.DroppedCommands = int32(len(dropped))
+	s.forgetPendingResults(req.MinionId)
+	s.hosts.forget(req.MinionId)
+	s.invalidateCommandEnv()
+	if s.identities != nil {
+		s.identities.mu.Lock()
+		delete(s.identities.bound, req.MinionId)
+		delete(s.identities.rotating, req.MinionId)
+		s.identities.mu.Unlock()
+	}
+
+	logger.Warn("Minion retired",
+		zap.String("minion_id", req.MinionId),
+		zap.String("retired_by", notice.RetiredBy),
+		zap.String("reason", notice.Reason),
+		zap.Bool("uninstall", notice.Uninstall),
+		zap.Bool("notified", resp.Notified),
+		zap.Int32("archived_results", resp.ArchivedResults),
+		zap.Int32("dropped_commands", resp.DroppedCommands))
+	return resp, nil
+}
+
+// forgetPendingResults stops waiting for the results of a retired minion
+func (s *Server) forgetPendingResults(minionID string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	for commandID, tracker := range s.pendingCommands {
+		if !tracker.Pending[minionID] {
+			continue
+		}
+		delete(tracker.Pending, minionID)
+		if len(tracker.Pending) == 0 {
+			delete(s.pendingCommands, commandID)
+		}
+	}
+}
+
+// Remove deletes a minion from the registry, signaling its command stream to close, and returns its
+// queued commands
+func (r *MinionRegistryImpl) Remove(minionID string) []*pb.Command {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	conn, exists := r.minions[minionID]
+	if !exists {
+		return nil
+	}
+	delete(r.minions, minionID)
+	delete(r.evicted, minionID)
+	r.health.Forget(minionID)
+	r.generation++
+	return conn.Commands.Drain()
+}
+
+// RetireHost moves a host and its results into the retired_hosts and retired_command_results tables,
+// deleting its other rows: host changes, connection events, identity binding, shipped logs, crash
+// reports, command environment and command trace events. It returns the number of archived results.
+// info is stored for a registered host not stored yet, errHostNotFound being returned when it is nil.
+func (d *DatabaseServiceImpl) RetireHost(ctx context.Context, minionID string, info *pb.HostInfo, notice *pb.RetireNotice) (int, error) {
+	if d == nil || d.db == nil {
+		return 0, fmt.Errorf("database service unavailable - cannot retire host %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.RetireHost")
+	defer logging.FuncExit(logger, start)
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin retirement transaction: %v", err)
+	}
+	defer tx.Rollback() // Will be a no-op if transaction is committed
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO retired_hosts (host_id, hostname, ip, os, first_seen, last_seen, tags, retired_by, reason, uninstall)
+		SELECT id, hostname, ip, os, first_seen, last_seen, tags, $2, $3, $4 FROM hosts WHERE id = $1`,
+		minionID, notice.RetiredBy, notice.Reason, notice.Uninstall)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive host: %v", err)
+	}
+	if stored, _ := res.RowsAffected(); stored == 0 {
+		if info == nil {
+			return 0, errHostNotFound
+		}
+		tagsJSON, err := json.Marshal(info.Tags)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal host tags: %v", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO retired_hosts (host_id, hostname, ip, os, tags, retired_by, reason, uninstall)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			minionID, info.Hostname, nullIfEmpty(info.Ip), info.Os, string(tagsJSON), notice.RetiredBy, notice.Reason, notice.Uninstall); err != nil {
+			return 0, fmt.Errorf("failed to archive host: %v", err)
+		}
+	}
+
+	res, err = tx.ExecContext(ctx,
+		`INSERT INTO retired_command_results (command_id, minion_id, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, attempt, execution)
+		SELECT command_id, minion_id, exit_code, stdout, stderr, content_type, structured, timestamp, redacted, trace_id, attempt, execution
+		FROM command_results WHERE minion_id = $1`,
+		minionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive results: %v", err)
+	}
+	archived, _ := res.RowsAffected()
+
+	for _, query := range []string{
+		"DELETE FROM command_results WHERE minion_id = $1",
+		"DELETE FROM command_result_attempts WHERE minion_id = $1",
+		"DELETE FROM host_changes WHERE host_id = $1",
+		"DELETE FROM connection_events WHERE host_id = $1",
+		"UPDATE commands SET host_id = NULL WHERE host_id = $1",
+		"DELETE FROM minion_identities WHERE host_id = $1",
+		"DELETE FROM minion_logs WHERE minion_id = $1",
+		"DELETE FROM minion_crashes WHERE host_id = $1",
+		"DELETE FROM command_env WHERE minion_id = $1",
+		"DELETE FROM command_events WHERE minion_id = $1",
+		"DELETE FROM hosts WHERE id = $1",
+	} {
+		if _, err := tx.ExecContext(ctx, query, minionID); err != nil {
+			return 0, fmt.Errorf("failed to delete rows of retired host: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit retirement: %v", err)
+	}
+
+	logger.Debug("Host retired", zap.String("host_id", minionID), zap.Int64("archived_results", archived))
+	return int(archived), nil
+}
+
+// MarkRetirementNotified records that a retired minion received its retirement notice.
+func (d *DatabaseServiceImpl) MarkRetirementNotified(ctx context.Context, minionID string) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot mark retirement of %s notified", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.MarkRetirementNotified")
+	defer logging.FuncExit(logger, start)
+
+	if _, err := d.db.ExecContext(ctx,
+		"UPDATE retired_hosts SET notified_at = $2 WHERE host_id = $1 AND notified_at IS NULL",
+		minionID, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark retirement notified: %v", err)
+	}
+	return nil
+}
+
+// PendingRetirements retrieves the notices of the retired minions that didn't receive them yet, by minion ID.
+func (d *DatabaseServiceImpl) PendingRetirements(ctx context.Context) (map[string]*pb.RetireNotice, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get pending retirements")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.PendingRetirements")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT host_id, uninstall, reason, retired_by FROM retired_hosts WHERE notified_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending retirements: %v", err)
+	}
+	defer rows.Close()
+
+	notices := make(map[string]*pb.RetireNotice)
+	for rows.Next() {
+		var minionID string
+		var reason, retiredBy sql.NullString
+		notice := &pb.RetireNotice{}
+		if err := rows.Scan(&minionID, &notice.Uninstall, &reason, &retiredBy); err != nil {
+			return nil, fmt.Errorf("failed to scan pending retirement: %v", err)
+		}
+		notice.Reason = reason.String
+		notice.RetiredBy = retiredBy.String
+		notices[minionID] = notice
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pending retirements: %v", err)
+	}
+	return notices, nil
+}
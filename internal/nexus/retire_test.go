@@ -0,0 +1,177 @@
+package nexus
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/arhuman/minexus/internal/longpoll"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetirements(t *testing.T) {
+	var retired retirements
+	now := time.Now()
+	notice := &pb.RetireNotice{Reason: "decommissioned"}
+
+	if retired.pending("minion-1", now) != nil {
+		t.Fatal("Expected no retirement pending")
+	}
+	retired.add("minion-1", notice, time.Time{})
+	if retired.pending("minion-1", now.Add(24*time.Hour)) != notice {
+		t.Fatal("Expected the minion refused until notified")
+	}
+	if !retired.markNotified("minion-1", now) || retired.markNotified("minion-1", now) {
+		t.Error("Expected the minion notified once")
+	}
+	if retired.pending("minion-1", now.Add(retireGracePeriod-time.Minute)) != notice {
+		t.Error("Expected the minion refused during the grace period")
+	}
+	if retired.pending("minion-1", now.Add(retireGracePeriod+time.Minute)) != nil {
+		t.Error("Expected the minion forgotten after the grace period")
+	}
+}
+
+func TestRetireMinion(t *testing.T) {
+	server := createTestServer(nil)
+	server.SetAdmins([]string{"alice"})
+	ctx := identityContext("alice")
+
+	if _, err := server.Register(ctx, &pb.HostInfo{Id: "retired-minion", Hostname: "old-host"}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	conn, _ := server.GetMinionRegistryImpl().GetConnectionImpl("retired-minion")
+	conn.Commands.Push(&pb.Command{Id: "cmd-1", Payload: "uptime"})
+
+	// Only administrators retire minions
+	if _, err := server.RetireMinion(identityContext("bob"), &pb.RetireMinionRequest{MinionId: "retired-minion"}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a console other than an administrator, got %v", err)
+	}
+
+	if _, err := server.RetireMinion(ctx, &pb.RetireMinionRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+	if _, err := server.RetireMinion(ctx, &pb.RetireMinionRequest{MinionId: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown minion, got %v", err)
+	}
+
+	resp, err := server.RetireMinion(ctx, &pb.RetireMinionRequest{MinionId: "retired-minion", Uninstall: true, Reason: "hardware refresh"})
+	if err != nil {
+		t.Fatalf("RetireMinion failed: %v", err)
+	}
+	if resp.Notified || resp.DroppedCommands != 1 {
+		t.Errorf("Expected the queued command dropped for a minion without stream, got %+v", resp)
+	}
+	for _, info := range server.minionRegistry.ListMinions() {
+		if info.Id == "retired-minion" {
+			t.Error("Expected the retired minion removed from the listings")
+		}
+	}
+
+	// The minion learns it registering again, refused
+	registered, err := server.Register(ctx, &pb.HostInfo{Id: "retired-minion", Hostname: "old-host"})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if registered.Success || !registered.Retire.GetUninstall() || registered.Retire.GetReason() != "hardware refresh" {
+		t.Errorf("Expected the registration refused with the retirement notice, got %+v", registered)
+	}
+	if _, exists := server.minionRegistry.GetConnection("retired-minion"); exists {
+		t.Error("Expected the retired minion kept out of the registry")
+	}
+	if _, err := server.RetireMinion(ctx, &pb.RetireMinionRequest{MinionId: "retired-minion"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound retiring the minion again, got %v", err)
+	}
+}
+
+func TestRetireMinionOverLongPolling(t *testing.T) {
+	server := createTestServer(nil)
+	server.diagnostics = NewDiagnosticsTracker()
+	httpServer := httptest.NewServer(server.LongPollHandler(4096))
+	defer httpServer.Close()
+
+	client := longpoll.NewClient(httpServer.URL, httpServer.Client())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.Register(ctx, &pb.HostInfo{Id: "polling-minion", Hostname: "host"}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	server.SetAdmins([]string{"alice"})
+	if _, err := server.RetireMinion(identityContext("alice"), &pb.RetireMinionRequest{MinionId: "polling-minion", Reason: "moved"}); err != nil {
+		t.Fatalf("RetireMinion failed: %v", err)
+	}
+
+	streamCtx := metadata.AppendToOutgoingContext(ctx, "minion-id", "polling-minion")
+	stream, err := client.StreamCommands(streamCtx)
+	if err != nil {
+		t.Fatalf("StreamCommands failed: %v", err)
+	}
+	defer stream.CloseSend()
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	if msg.GetRetire().GetReason() != "moved" {
+		t.Fatalf("Expected the retirement notice polled, got %v", msg)
+	}
+	if server.retired.markNotified("polling-minion", time.Now()) {
+		t.Error("Expected the minion recorded notified")
+	}
+}
+
+func TestRetireHost(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	service := NewDatabaseService(db, zap.NewNop())
+	notice := &pb.RetireNotice{RetiredBy: "ops", Reason: "hardware refresh", Uninstall: true}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO retired_hosts .* FROM hosts WHERE id = \\$1").
+		WithArgs("minion-1", "ops", "hardware refresh", true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO retired_command_results .* FROM command_results WHERE minion_id = \\$1").
+		WithArgs("minion-1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM command_results WHERE minion_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM command_result_attempts WHERE minion_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM host_changes WHERE host_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM connection_events WHERE host_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 8))
+	mock.ExpectExec("UPDATE commands SET host_id = NULL WHERE host_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM minion_identities WHERE host_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM minion_logs WHERE minion_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 20))
+	mock.ExpectExec("DELETE FROM minion_crashes WHERE host_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM command_env WHERE minion_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM command_events WHERE minion_id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 6))
+	mock.ExpectExec("DELETE FROM hosts WHERE id = \\$1").WithArgs("minion-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	archived, err := service.RetireHost(context.Background(), "minion-1", nil, notice)
+	if err != nil || archived != 3 {
+		t.Fatalf("Expected 3 archived results, got %d, %v", archived, err)
+	}
+
+	// A host neither stored nor registered is not found
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO retired_hosts").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	if _, err := service.RetireHost(context.Background(), "unknown", nil, notice); err != errHostNotFound {
+		t.Errorf("Expected errHostNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled mock expectations: %v", err)
+	}
+}
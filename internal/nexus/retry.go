@@ -0,0 +1,171 @@
+package nexus
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxRetryAttempts bounds the dispatches of a command to each of its targets
+	maxRetryAttempts = 10
+	// maxRetryBackoff bounds the delay before each retry, so that the retries of a command end well
+	// before its tracker expires after pendingCommandTTL
+	maxRetryBackoff = time.Hour
+	// defaultRetryBackoff is the delay before the first retry when the policy doesn't set one
+	defaultRetryBackoff = 5 * time.Second
+)
+
+// retryAttempt is the attempt of a retried command on one of its targets
+type retryAttempt struct {
+	number  int32 // 1 for the first dispatch
+	waiting bool  // a failed result was received, the command is dispatched again after the backoff
+}
+
+// validateRetryPolicy checks the retry policy of a command request, nil being valid
+func validateRetryPolicy(policy *pb.RetryPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAttempts < 1 || policy.MaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("retry attempts must be between 1 and %d, got %d", maxRetryAttempts, policy.MaxAttempts)
+	}
+	if policy.BackoffSeconds < 0 || time.Duration(policy.BackoffSeconds)*time.Second > maxRetryBackoff {
+		return fmt.Errorf("retry backoff must be between 0 and %s, got %ds", maxRetryBackoff, policy.BackoffSeconds)
+	}
+	for _, code := range policy.ExitCodes {
+		if code == 0 {
+			return fmt.Errorf("exit code 0 is a success, it can't be retried")
+		}
+	}
+	return nil
+}
+
+// retryable reports whether a result with exitCode is retried under policy
+func retryable(policy *pb.RetryPolicy, exitCode int32) bool {
+	if exitCode == 0 {
+		return false
+	}
+	if len(policy.ExitCodes) == 0 {
+		return true
+	}
+	for _, code := range policy.ExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff returns the delay before dispatching attempt, the second one waiting the backoff of policy,
+// doubled for each next one up to maxRetryBackoff
+func retryBackoff(policy *pb.RetryPolicy, attempt int32) time.Duration {
+	backoff := time.Duration(policy.BackoffSeconds) * time.Second
+	if backoff == 0 {
+		backoff = defaultRetryBackoff
+	}
+	delay := backoff << (attempt - 2)
+	if delay <= 0 || delay > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return delay
+}
+
+// trackRetries remembers the request of a command with a retry policy, to dispatch it again to the
+// targets whose result failed
+func (s *Server) trackRetries(req *pb.CommandRequest) {
+	if req.Retry.GetMaxAttempts() <= 1 {
+		return
+	}
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if tracker, exists := s.pendingCommands[req.Command.Id]; exists {
+		tracker.Retry = proto.Clone(req).(*pb.CommandRequest)
+		tracker.Attempts = make(map[string]*retryAttempt, len(tracker.Pending))
+	}
+}
+
+// retryFailedResult numbers the result of a retried command with its attempt and, when its policy
+// retries it, dispatches the command again to the minion after the backoff. It reports whether the
+// result was retried, or was a duplicate of one being retried: the result is then stored without
+// completing the command, the result of the next attempt superseding it.
+func (s *Server) retryFailedResult(result *pb.CommandResult, logger *zap.Logger) bool {
+	s.pendingMu.Lock()
+	tracker, exists := s.pendingCommands[result.CommandId]
+	if !exists || tracker.Retry == nil || !tracker.Pending[result.MinionId] {
+		s.pendingMu.Unlock()
+		return false
+	}
+	attempt, exists := tracker.Attempts[result.MinionId]
+	if !exists {
+		attempt = &retryAttempt{number: 1}
+		tracker.Attempts[result.MinionId] = attempt
+	}
+	if attempt.waiting {
+		// Resent by the minion after a lost acknowledgement
+		s.pendingMu.Unlock()
+		return true
+	}
+	result.Attempt = attempt.number
+	req := tracker.Retry
+	if attempt.number >= req.Retry.MaxAttempts || !retryable(req.Retry, result.ExitCode) {
+		s.pendingMu.Unlock()
+		return false
+	}
+	attempt.number++
+	attempt.waiting = true
+	next := attempt.number
+	s.pendingMu.Unlock()
+
+	delay := retryBackoff(req.Retry, next)
+	logger.Info("COMMAND_FLOW_MONITORING: Failed command retried",
+		zap.String("stage", "RETRY_SCHEDULED"),
+		zap.String("command_id", result.CommandId),
+		zap.String("minion_id", result.MinionId),
+		zap.Int32("exit_code", result.ExitCode),
+		zap.Int32("attempt", next),
+		zap.Int32("max_attempts", req.Retry.MaxAttempts),
+		zap.Duration("backoff", delay))
+	go s.retryTarget(req, result, delay, logger)
+	return true
+}
+
+// retryTarget dispatches a command again to the minion of a failed result after delay. The failed
+// result, already stored, completes the command when Nexus stops meanwhile or the command can't be
+// dispatched.
+func (s *Server) retryTarget(req *pb.CommandRequest, failed *pb.CommandResult, delay time.Duration, logger *zap.Logger) {
+	_, stopping := s.drain.channels()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-stopping:
+		err = fmt.Errorf("nexus is shutting down")
+	case <-timer.C:
+		s.pendingMu.Lock()
+		if tracker, exists := s.pendingCommands[failed.CommandId]; exists {
+			if attempt, exists := tracker.Attempts[failed.MinionId]; exists {
+				attempt.waiting = false
+			}
+		}
+		s.pendingMu.Unlock()
+
+		var outcome dispatchOutcome
+		if outcome, err = s.dispatchTarget(req, failed.MinionId, isEmergency(req), logger); outcome != dispatchFailed {
+			return
+		}
+	}
+
+	logger.Warn("Failed command not retried, keeping its last result",
+		zap.String("command_id", failed.CommandId),
+		zap.String("minion_id", failed.MinionId),
+		zap.Int32("attempt", failed.Attempt),
+		zap.Error(err))
+	s.notifyCompletion(failed)
+}
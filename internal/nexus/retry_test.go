@@ -0,0 +1,157 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"go.uber.org/zap"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	for _, policy := range []*pb.RetryPolicy{
+		{MaxAttempts: 0},
+		{MaxAttempts: 11},
+		{MaxAttempts: 3, BackoffSeconds: -1},
+		{MaxAttempts: 3, BackoffSeconds: 7200},
+		{MaxAttempts: 3, ExitCodes: []int32{75, 0}},
+	} {
+		if err := validateRetryPolicy(policy); err == nil {
+			t.Errorf("Expected %v to be invalid", policy)
+		}
+	}
+	if err := validateRetryPolicy(&pb.RetryPolicy{MaxAttempts: 3, BackoffSeconds: 30, ExitCodes: []int32{75}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	policy := &pb.RetryPolicy{MaxAttempts: 4, ExitCodes: []int32{75}}
+	if retryable(policy, 0) || retryable(policy, 1) || !retryable(policy, 75) || !retryable(&pb.RetryPolicy{MaxAttempts: 2}, 1) {
+		t.Error("Unexpected retryable exit codes")
+	}
+	if retryBackoff(policy, 2) != defaultRetryBackoff || retryBackoff(&pb.RetryPolicy{BackoffSeconds: 10}, 4) != 40*time.Second {
+		t.Error("Expected the backoff doubled before each retry")
+	}
+	if retryBackoff(&pb.RetryPolicy{BackoffSeconds: 3600}, 10) != maxRetryBackoff {
+		t.Error("Expected the backoff capped")
+	}
+}
+
+func TestStoreRetriedResultSupersedesAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", ExitCode: 0, Attempt: 2, Timestamp: time.Now().Unix()}
+
+	// The results of the previous attempts move to their history before the next one is stored
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_result_attempts .* FROM command_results WHERE command_id = \\$1 AND minion_id = \\$2 AND attempt < \\$3").
+		WithArgs("cmd-1", "minion-1", int32(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM command_results WHERE command_id = \\$1 AND minion_id = \\$2 AND attempt < \\$3").
+		WithArgs("cmd-1", "minion-1", int32(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO command_results").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE commands SET status").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := server.dbService.StoreCommandResult(context.Background(), result); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRetryFailedResults(t *testing.T) {
+	server, conn := createLockTestServer(t)
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	resp, err := server.SendCommand(ctx, &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "apt-get update"},
+		Retry:     &pb.RetryPolicy{MaxAttempts: 3, BackoffSeconds: 1, ExitCodes: []int32{100}},
+	})
+	if err != nil || !resp.Accepted {
+		t.Fatalf("Expected the command accepted, got %v, %v", resp, err)
+	}
+	if _, ok := conn.Commands.Pop(); !ok {
+		t.Fatal("Expected the command queued")
+	}
+
+	// A retried exit code dispatches the command again after the backoff, even when resent by the minion
+	failed := &pb.CommandResult{CommandId: resp.CommandId, MinionId: "minion-1", ExitCode: 100}
+	for i := 0; i < 2; i++ {
+		if err := server.handleCommandResult(ctx, failed, logger); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if failed.Attempt != 1 || conn.Commands.Len() != 0 {
+		t.Fatalf("Expected the first attempt retried after the backoff, got attempt %d, %d queued", failed.Attempt, conn.Commands.Len())
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for conn.Commands.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	retried, ok := conn.Commands.Pop()
+	if !ok || retried.Id != resp.CommandId {
+		t.Fatalf("Expected the command dispatched again, got %v", retried)
+	}
+	if conn.Commands.Len() != 0 {
+		t.Error("Expected the resent result not to be retried twice")
+	}
+
+	// Other exit codes are kept, numbered with their attempt
+	result := &pb.CommandResult{CommandId: resp.CommandId, MinionId: "minion-1", ExitCode: 1}
+	if err := server.handleCommandResult(ctx, result, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Attempt != 2 || conn.Commands.Len() != 0 {
+		t.Errorf("Expected the second attempt kept, got attempt %d, %d queued", result.Attempt, conn.Commands.Len())
+	}
+	if server.awaitedCommand(resp.CommandId) {
+		t.Error("Expected the command complete")
+	}
+
+	// Stopping Nexus keeps the failed result rather than waiting for the retry
+	resp, err = server.SendCommand(ctx, &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "apt-get update"},
+		Retry:     &pb.RetryPolicy{MaxAttempts: 2, BackoffSeconds: 60},
+	})
+	if err != nil || !resp.Accepted {
+		t.Fatalf("Expected the command accepted, got %v, %v", resp, err)
+	}
+	if err := server.handleCommandResult(ctx, &pb.CommandResult{CommandId: resp.CommandId, MinionId: "minion-1", ExitCode: 1}, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, stopping := server.drain.channels()
+	server.drain.advance(stopping)
+	deadline = time.Now().Add(time.Second)
+	for server.awaitedCommand(resp.CommandId) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if server.awaitedCommand(resp.CommandId) {
+		t.Error("Expected the failed result kept when Nexus stops")
+	}
+
+	if _, err := server.SendCommand(ctx, &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+		Retry:     &pb.RetryPolicy{MaxAttempts: 20},
+	}); err == nil {
+		t.Error("Expected an invalid retry policy rejected")
+	}
+}
+
+// awaitedCommand reports whether results of a command are still awaited
+func (s *Server) awaitedCommand(commandID string) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	_, exists := s.pendingCommands[commandID]
+	return exists
+}
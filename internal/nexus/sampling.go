@@ -0,0 +1,133 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// validateSample checks the result sample of a command request, 0 storing every result
+func validateSample(sample int32) error {
+	if sample < 0 {
+		return fmt.Errorf("result sample cannot be negative, got %d", sample)
+	}
+	return nil
+}
+
+// trackSampling remembers the result sample of a command, whose successful results beyond the sample
+// are only counted
+func (s *Server) trackSampling(req *pb.CommandRequest) {
+	if req.Sample <= 0 {
+		return
+	}
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if tracker, exists := s.pendingCommands[req.Command.Id]; exists {
+		tracker.Sample = req.Sample
+	}
+}
+
+// sampledOut reports whether a result is left out of the sample of its command: a success received
+// once the sample is full. Failures are always kept, and so are the results of commands no longer
+// tracked, Nexus having restarted meanwhile.
+func (s *Server) sampledOut(result *pb.CommandResult) (int32, bool) {
+	if result.ExitCode != 0 {
+		return 0, false
+	}
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	tracker, exists := s.pendingCommands[result.CommandId]
+	if !exists || tracker.Sample == 0 || !tracker.Pending[result.MinionId] {
+		return 0, false
+	}
+	if tracker.Stored < tracker.Sample {
+		tracker.Stored++
+		return 0, false
+	}
+	return tracker.Sample, true
+}
+
+// countOmittedResult counts a successful result left out of the sample of its command instead of
+// storing it, storing the result when it can't be counted
+func (s *Server) countOmittedResult(ctx context.Context, result *pb.CommandResult, sample int32, logger *zap.Logger) error {
+	err := s.dbService.CountOmittedResult(ctx, result, sample)
+	if err == nil {
+		logger.Debug("Successful result left out of the command sample",
+			zap.String("command_id", result.CommandId),
+			zap.String("minion_id", result.MinionId),
+			zap.Int32("sample", sample))
+		return nil
+	}
+	logger.Warn("Failed to count the result left out of the command sample, storing it",
+		zap.String("command_id", result.CommandId),
+		zap.String("minion_id", result.MinionId),
+		zap.Error(err))
+	return s.storeCommandResult(ctx, result, logger)
+}
+
+// omittedResults returns the number of successful results of a command that were counted but not
+// stored, 0 when it can't be read
+func (s *Server) omittedResults(ctx context.Context, commandID string, logger *zap.Logger) int32 {
+	omitted, err := s.dbService.GetOmittedResults(ctx, commandID)
+	if err != nil {
+		logger.Warn("Failed to read the results omitted by the command sample",
+			zap.String("command_id", commandID),
+			zap.Error(err))
+		return 0
+	}
+	return omitted
+}
+
+// CountOmittedResult counts a successful result of a sampled command without storing it, and marks
+// the command completed on its minion
+func (d *DatabaseServiceImpl) CountOmittedResult(ctx context.Context, result *pb.CommandResult, sample int32) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot count result for command %s", result.CommandId)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.CountOmittedResult")
+	defer logging.FuncExit(logger, start)
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Will be a no-op if transaction is committed
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO command_result_samples (command_id, sample_size, omitted_results, last_result_at) VALUES ($1, $2, 1, $3)
+		ON CONFLICT (command_id) DO UPDATE SET omitted_results = command_result_samples.omitted_results + 1,
+		last_result_at = GREATEST(command_result_samples.last_result_at, EXCLUDED.last_result_at)`,
+		result.CommandId, sample, time.Unix(result.Timestamp, 0))
+	if err != nil {
+		return fmt.Errorf("failed to count omitted result: %v", err)
+	}
+	if err := d.updateCommandStatusInTx(ctx, tx, result, 0, logger); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetOmittedResults returns the number of successful results of a command counted but not stored,
+// 0 when the command doesn't sample its results
+func (d *DatabaseServiceImpl) GetOmittedResults(ctx context.Context, commandID string) (int32, error) {
+	if d == nil || d.db == nil {
+		return 0, fmt.Errorf("database service unavailable")
+	}
+
+	var omitted int32
+	err := d.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(omitted_results), 0) FROM command_result_samples WHERE command_id = $1",
+		commandID).Scan(&omitted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get omitted results: %v", err)
+	}
+	return omitted, nil
+}
@@ -0,0 +1,72 @@
+package nexus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestResultSampling(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	if err := validateSample(-1); err == nil {
+		t.Error("Expected a negative sample rejected")
+	}
+
+	req := &pb.CommandRequest{Command: &pb.Command{Id: "cmd-1", Payload: "uptime"}, Sample: 1}
+	server.trackCommand("cmd-1", "uptime", []string{"minion-1", "minion-2", "minion-3", "minion-4"})
+	server.trackSampling(req)
+
+	stored := func(minionID string) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").WithArgs("cmd-1", minionID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO command_results").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE commands SET status").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	counted := func(minionID string) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO command_result_samples").WithArgs("cmd-1", int32(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE commands SET status").WithArgs("COMPLETED", "cmd-1", minionID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	// The first success fills the sample, the next ones are counted and failures are always stored
+	stored("minion-1")
+	counted("minion-2")
+	stored("minion-3")
+	counted("minion-4")
+	for _, result := range []*pb.CommandResult{
+		{CommandId: "cmd-1", MinionId: "minion-1"},
+		{CommandId: "cmd-1", MinionId: "minion-2"},
+		{CommandId: "cmd-1", MinionId: "minion-3", ExitCode: 1},
+		{CommandId: "cmd-1", MinionId: "minion-4"},
+	} {
+		if err := server.recordResult(ctx, result, logger); err != nil {
+			t.Fatalf("Unexpected error recording the result of %s: %v", result.MinionId, err)
+		}
+	}
+	if server.awaitedCommand("cmd-1") {
+		t.Error("Expected the command complete")
+	}
+
+	mock.ExpectQuery("FROM command_result_samples").WithArgs("cmd-1").WillReturnRows(sqlmock.NewRows([]string{"omitted"}).AddRow(2))
+	if omitted := server.omittedResults(ctx, "cmd-1", logger); omitted != 2 {
+		t.Errorf("Expected 2 omitted results, got %d", omitted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
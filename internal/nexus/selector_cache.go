@@ -0,0 +1,90 @@
+package nexus
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// selectorCacheTTL is how long the minions matching a selector are reused without re-scanning the registry
+	selectorCacheTTL = 2 * time.Second
+	// maxSelectorCacheEntries bounds the number of cached selectors
+	maxSelectorCacheEntries = 256
+)
+
+// cachedTargets holds the minions that matched a selector
+type cachedTargets struct {
+	targets    []string
+	generation uint64 // registry generation the targets were computed at
+	expires    time.Time
+}
+
+// selectorCache caches the minions matching tag and topology selectors, so that bursts of commands
+// sent with the same selector don't each scan the whole registry. Entries expire after selectorCacheTTL
+// and are invalidated as soon as the registry generation changes (minion registered, tags changed).
+type selectorCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedTargets
+	now     func() time.Time
+}
+
+// newSelectorCache creates an empty selector cache
+func newSelectorCache() *selectorCache {
+	return &selectorCache{entries: make(map[string]cachedTargets), now: time.Now}
+}
+
+// selectorKey returns a key identifying a tag and topology selector pair, false when it can't be encoded
+func selectorKey(tags *pb.TagSelector, topology *pb.TopologySelector) (string, bool) {
+	options := proto.MarshalOptions{Deterministic: true}
+	tagBytes, err := options.Marshal(tags)
+	if err != nil {
+		return "", false
+	}
+	topologyBytes, err := options.Marshal(topology)
+	if err != nil {
+		return "", false
+	}
+	key := binary.AppendUvarint(nil, uint64(len(tagBytes)))
+	key = append(key, tagBytes...)
+	return string(append(key, topologyBytes...)), true
+}
+
+// get returns a copy of the targets cached for key, if computed at generation and not expired
+func (c *selectorCache) get(key string, generation uint64) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.generation != generation || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return append([]string(nil), entry.targets...), true
+}
+
+// put caches a copy of the targets matching key at generation
+func (c *selectorCache) put(key string, generation uint64, targets []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= maxSelectorCacheEntries {
+		for k, entry := range c.entries {
+			if entry.generation != generation || !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxSelectorCacheEntries {
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = cachedTargets{
+		targets:    append([]string(nil), targets...),
+		generation: generation,
+		expires:    now.Add(selectorCacheTTL),
+	}
+}
@@ -0,0 +1,100 @@
+package nexus
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestFindTargetMinionsCache(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	now := time.Now()
+	registry.selectors.now = func() time.Time { return now }
+	for _, id := range []string{"minion-1", "minion-2"} {
+		if _, err := registry.Register(&pb.HostInfo{Id: id, Tags: map[string]string{"env": "prod"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prod := func() []string {
+		targets := registry.FindTargetMinions(&pb.CommandRequest{
+			TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}}},
+		})
+		sort.Strings(targets)
+		return targets
+	}
+	if targets := prod(); !reflect.DeepEqual(targets, []string{"minion-1", "minion-2"}) {
+		t.Fatalf("Expected both minions, got %v", targets)
+	}
+
+	// Changes made behind the registry's back are only seen once the cached result expires
+	registry.minions["minion-3"] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: "minion-3", Tags: map[string]string{"env": "prod"}}}
+	targets := prod()
+	if !reflect.DeepEqual(targets, []string{"minion-1", "minion-2"}) {
+		t.Fatalf("Expected the cached targets, got %v", targets)
+	}
+	targets[0] = "changed"
+	if targets := prod(); targets[0] != "minion-1" {
+		t.Fatalf("Expected callers unable to alter the cached targets, got %v", targets)
+	}
+	now = now.Add(selectorCacheTTL)
+	if targets := prod(); len(targets) != 3 {
+		t.Fatalf("Expected the expired result computed again, got %v", targets)
+	}
+
+	// Tag changes and registrations invalidate the cached results
+	if err := registry.UpdateTags("minion-1", nil, []string{"env"}); err != nil {
+		t.Fatal(err)
+	}
+	if targets := prod(); !reflect.DeepEqual(targets, []string{"minion-2", "minion-3"}) {
+		t.Fatalf("Expected minion-1 no longer targeted, got %v", targets)
+	}
+	if _, err := registry.Register(&pb.HostInfo{Id: "minion-4", Tags: map[string]string{"env": "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if targets := prod(); !reflect.DeepEqual(targets, []string{"minion-2", "minion-3", "minion-4"}) {
+		t.Fatalf("Expected the new minion targeted, got %v", targets)
+	}
+	if err := registry.SetTags("minion-1", map[string]string{"env": "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if targets := prod(); len(targets) != 4 {
+		t.Fatalf("Expected minion-1 targeted again, got %v", targets)
+	}
+
+	// Heartbeats re-registering a minion unchanged keep the cached results
+	registry.minions["minion-5"] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: "minion-5", Tags: map[string]string{"env": "prod"}}}
+	if _, err := registry.Register(&pb.HostInfo{Id: "minion-2", Tags: map[string]string{"env": "prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if targets := prod(); len(targets) != 4 {
+		t.Fatalf("Expected the cached targets kept after a heartbeat, got %v", targets)
+	}
+
+	// Re-registrations changing the topology or the tags invalidate them
+	if _, err := registry.Register(&pb.HostInfo{Id: "minion-2", Tags: map[string]string{"env": "prod"}, Region: "eu-west"}); err != nil {
+		t.Fatal(err)
+	}
+	if targets := prod(); len(targets) != 5 {
+		t.Fatalf("Expected the targets computed again after a topology change, got %v", targets)
+	}
+	delete(registry.minions, "minion-5")
+	if _, err := registry.Register(&pb.HostInfo{Id: "minion-2", Tags: map[string]string{"env": "staging"}, Region: "eu-west"}); err != nil {
+		t.Fatal(err)
+	}
+	if targets := prod(); !reflect.DeepEqual(targets, []string{"minion-1", "minion-3", "minion-4"}) {
+		t.Fatalf("Expected minion-2 no longer targeted after a tag change, got %v", targets)
+	}
+
+	// Other selectors are cached separately
+	targets = registry.FindTargetMinions(&pb.CommandRequest{
+		TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "staging"}}}},
+	})
+	if !reflect.DeepEqual(targets, []string{"minion-2"}) {
+		t.Errorf("Expected minion-2 as the only staging minion, got %v", targets)
+	}
+}
@@ -0,0 +1,248 @@
+package nexus
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	shellBufferSize   = 256             // messages buffered per session and per minion
+	shellRelayTimeout = 5 * time.Second // a session whose peer doesn't keep up is ended
+)
+
+// shellSessionPayload is the payload interactive shells are matched as against the approval rules.
+// A shell runs whatever its operator types, hence the disruptive impact.
+const shellSessionPayload = "shell"
+
+// errShellStalled is returned when a session peer stopped consuming its messages
+var errShellStalled = errors.New("shell session stalled")
+
+// shellSession is an interactive shell session between a console and a minion
+type shellSession struct {
+	id       string
+	minionID string
+	output   chan *pb.ShellMessage // minion messages waiting to be sent to the console
+	done     chan struct{}         // closed when the console side of the session ended
+}
+
+// shellSessions routes the traffic of interactive shell sessions between consoles and minions.
+// Its zero value is ready to use.
+type shellSessions struct {
+	mu       sync.Mutex
+	outbound map[string]chan *pb.ShellMessage // messages waiting to be sent on each minion command stream
+	sessions map[string]*shellSession
+}
+
+// outboundFor returns the channel of the messages to send on the command stream of a minion
+func (s *shellSessions) outboundFor(minionID string) chan *pb.ShellMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.outbound == nil {
+		s.outbound = make(map[string]chan *pb.ShellMessage)
+	}
+	ch, exists := s.outbound[minionID]
+	if !exists {
+		ch = make(chan *pb.ShellMessage, shellBufferSize)
+		s.outbound[minionID] = ch
+	}
+	return ch
+}
+
+// open registers a new session on a minion
+func (s *shellSessions) open(minionID string) *shellSession {
+	session := &shellSession{
+		id:       generateMinionID(),
+		minionID: minionID,
+		output:   make(chan *pb.ShellMessage, shellBufferSize),
+		done:     make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*shellSession)
+	}
+	s.sessions[session.id] = session
+	return session
+}
+
+// remove unregisters a session once its console is gone
+func (s *shellSessions) remove(session *shellSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, session.id)
+	close(session.done)
+}
+
+// sendToMinion queues a message for the command stream of its minion
+func (s *shellSessions) sendToMinion(msg *pb.ShellMessage) error {
+	select {
+	case s.outboundFor(msg.MinionId) <- msg:
+		return nil
+	case <-time.After(shellRelayTimeout):
+		return errShellStalled
+	}
+}
+
+// deliver hands a message received from a minion to the console of its session. Messages of
+// unknown sessions, typically sessions whose console left, and of sessions opened on another minion
+// are dropped.
+func (s *shellSessions) deliver(msg *pb.ShellMessage) {
+	s.mu.Lock()
+	session, exists := s.sessions[msg.SessionId]
+	s.mu.Unlock()
+	if !exists || session.minionID != msg.MinionId {
+		return
+	}
+
+	select {
+	case session.output <- msg:
+	case <-session.done:
+	case <-time.After(shellRelayTimeout):
+	}
+}
+
+// closeMinion ends the sessions of a minion whose command stream ended
+func (s *shellSessions) closeMinion(minionID string) {
+	s.mu.Lock()
+	var closing []*shellSession
+	for _, session := range s.sessions {
+		if session.minionID == minionID {
+			closing = append(closing, session)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, session := range closing {
+		s.deliver(&pb.ShellMessage{
+			SessionId: session.id,
+			MinionId:  minionID,
+			Close:     true,
+			ExitCode:  -1,
+			Error:     "minion disconnected",
+		})
+	}
+}
+
+// OpenShell attaches a console to an interactive shell on a minion. The first console message
+// names the minion; the following ones carry keystrokes and terminal resizes. The minion output
+// is streamed back until the shell exits or either side closes the session.
+func (s *Server) OpenShell(stream pb.ConsoleService_OpenShellServer) error {
+	logger, start := logging.FuncLogger(s.logger, "nexus.Server.OpenShell")
+	defer logging.FuncExit(logger, start)
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if first.MinionId == "" {
+		return status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if err := s.checkMinionScope(stream.Context(), first.MinionId); err != nil {
+		return err
+	}
+	if registry, ok := s.minionRegistry.(*MinionRegistryImpl); !ok || !registry.HasStream(first.MinionId) {
+		return status.Errorf(codes.Unavailable, "minion %s is not connected", first.MinionId)
+	}
+	// A session can't wait for a second operator: shells matching an approval rule are refused
+	targets := []string{first.MinionId}
+	if reason := s.approvals.Match(shellSessionPayload, command.ImpactDisruptive, targets, s.minionTags); reason != "" {
+		logger.Warn("Shell session requiring approval refused",
+			zap.String("minion_id", first.MinionId),
+			zap.String("rule", reason),
+			zap.String("console", consoleIdentity(stream.Context())))
+		return status.Errorf(codes.FailedPrecondition, "shell sessions on minion %s require approval (%s), send the commands for approval instead", first.MinionId, reason)
+	}
+
+	session := s.shells.open(first.MinionId)
+	defer s.shells.remove(session)
+
+	logger.Info("Shell session opened",
+		zap.String("session_id", session.id),
+		zap.String("minion_id", session.minionID))
+
+	if err := s.shells.sendToMinion(&pb.ShellMessage{
+		SessionId: session.id,
+		MinionId:  session.minionID,
+		Open:      true,
+		Rows:      first.Rows,
+		Cols:      first.Cols,
+	}); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err := stream.Send(&pb.ShellMessage{SessionId: session.id, MinionId: session.minionID, Open: true}); err != nil {
+		s.closeShell(session)
+		return err
+	}
+
+	go s.relayConsoleInput(stream, session, logger)
+
+	for {
+		select {
+		case msg := <-session.output:
+			if err := stream.Send(msg); err != nil {
+				s.closeShell(session)
+				return err
+			}
+			if msg.Close {
+				logger.Info("Shell session closed",
+					zap.String("session_id", session.id),
+					zap.String("minion_id", session.minionID),
+					zap.Int32("exit_code", msg.ExitCode),
+					zap.String("error", msg.Error),
+					zap.Duration("duration", time.Since(start)))
+				return nil
+			}
+
+		case <-stream.Context().Done():
+			s.closeShell(session)
+			return stream.Context().Err()
+		}
+	}
+}
+
+// relayConsoleInput forwards the keystrokes and resizes of a console to the minion of its session,
+// asking the minion to end the session when the console closes it or goes away
+func (s *Server) relayConsoleInput(stream pb.ConsoleService_OpenShellServer, session *shellSession, logger *zap.Logger) {
+	for {
+		msg, err := stream.Recv()
+		select {
+		case <-session.done:
+			return
+		default:
+		}
+		if err != nil || msg.Close {
+			s.closeShell(session)
+			return
+		}
+
+		if err := s.shells.sendToMinion(&pb.ShellMessage{
+			SessionId: session.id,
+			MinionId:  session.minionID,
+			Data:      msg.Data,
+			Rows:      msg.Rows,
+			Cols:      msg.Cols,
+		}); err != nil {
+			logger.Warn("Failed to relay shell input",
+				zap.String("session_id", session.id),
+				zap.String("minion_id", session.minionID),
+				zap.Error(err))
+			return
+		}
+	}
+}
+
+// closeShell asks the minion of a session to end its shell
+func (s *Server) closeShell(session *shellSession) {
+	s.shells.sendToMinion(&pb.ShellMessage{SessionId: session.id, MinionId: session.minionID, Close: true})
+}
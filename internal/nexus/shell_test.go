@@ -0,0 +1,152 @@
+package nexus
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mockShellStream is an OpenShell server stream fed through channels
+type mockShellStream struct {
+	grpc.ServerStream
+	recv chan *pb.ShellMessage
+	sent chan *pb.ShellMessage
+}
+
+func newMockShellStream() *mockShellStream {
+	return &mockShellStream{
+		recv: make(chan *pb.ShellMessage, 10),
+		sent: make(chan *pb.ShellMessage, 10),
+	}
+}
+
+func (m *mockShellStream) Context() context.Context { return context.Background() }
+
+func (m *mockShellStream) Send(msg *pb.ShellMessage) error {
+	m.sent <- msg
+	return nil
+}
+
+func (m *mockShellStream) Recv() (*pb.ShellMessage, error) {
+	msg, ok := <-m.recv
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+// nextShellMessage waits for a message on ch
+func nextShellMessage(t *testing.T, ch chan *pb.ShellMessage) *pb.ShellMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for shell message")
+		return nil
+	}
+}
+
+func TestOpenShellRequiresConnectedMinion(t *testing.T) {
+	server := createTestServer(nil)
+	server.minionRegistry.Register(&pb.HostInfo{Id: "minion-1"})
+
+	stream := newMockShellStream()
+	stream.recv <- &pb.ShellMessage{MinionId: "minion-1"}
+	err := server.OpenShell(stream)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without command stream, got %v", err)
+	}
+
+	stream = newMockShellStream()
+	stream.recv <- &pb.ShellMessage{}
+	err = server.OpenShell(stream)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+}
+
+func TestOpenShell(t *testing.T) {
+	server := createTestServer(nil)
+	server.minionRegistry.Register(&pb.HostInfo{Id: "minion-1"})
+	server.minionRegistry.(*MinionRegistryImpl).OpenStream("minion-1")
+	toMinion := server.shells.outboundFor("minion-1")
+
+	stream := newMockShellStream()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.OpenShell(stream)
+	}()
+
+	// The minion is asked to open a session with the console terminal size
+	stream.recv <- &pb.ShellMessage{MinionId: "minion-1", Rows: 24, Cols: 80}
+	open := nextShellMessage(t, toMinion)
+	if !open.Open || open.SessionId == "" || open.Rows != 24 || open.Cols != 80 {
+		t.Fatalf("Unexpected open message: %v", open)
+	}
+	ack := nextShellMessage(t, stream.sent)
+	if !ack.Open || ack.SessionId != open.SessionId {
+		t.Fatalf("Unexpected open acknowledgment: %v", ack)
+	}
+
+	// Keystrokes are relayed to the minion
+	stream.recv <- &pb.ShellMessage{Data: []byte("ls\n")}
+	input := nextShellMessage(t, toMinion)
+	if string(input.Data) != "ls\n" || input.SessionId != open.SessionId || input.MinionId != "minion-1" {
+		t.Errorf("Unexpected input message: %v", input)
+	}
+
+	// Output is relayed to the console, messages of other sessions are dropped
+	server.shells.deliver(&pb.ShellMessage{SessionId: "other", MinionId: "minion-1", Data: []byte("nope")})
+	// Other minions can't write into the session
+	server.shells.deliver(&pb.ShellMessage{SessionId: open.SessionId, MinionId: "minion-2", Data: []byte("spoofed")})
+	server.shells.deliver(&pb.ShellMessage{SessionId: open.SessionId, MinionId: "minion-2", Close: true})
+	server.shells.deliver(&pb.ShellMessage{SessionId: open.SessionId, MinionId: "minion-1", Data: []byte("file.txt\n")})
+	output := nextShellMessage(t, stream.sent)
+	if string(output.Data) != "file.txt\n" {
+		t.Errorf("Unexpected output message: %v", output)
+	}
+
+	// The session ends with the minion stream
+	server.shells.closeMinion("minion-1")
+	closed := nextShellMessage(t, stream.sent)
+	if !closed.Close || closed.Error == "" {
+		t.Errorf("Expected close with error, got %v", closed)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("OpenShell failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OpenShell did not return")
+	}
+}
+
+func TestOpenShellRequiringApproval(t *testing.T) {
+	server := createTestServer(nil)
+	server.minionRegistry.Register(&pb.HostInfo{Id: "minion-1", Tags: map[string]string{"env": "prod"}})
+	server.minionRegistry.(*MinionRegistryImpl).OpenStream("minion-1")
+	server.SetApprovalPolicy(&ApprovalPolicy{
+		Rules:     []*ApprovalRule{{Name: "production changes", Impact: "mutating", Tag: "env=prod"}},
+		Approvers: []string{"bob"},
+	})
+
+	stream := newMockShellStream()
+	stream.recv <- &pb.ShellMessage{MinionId: "minion-1"}
+	if err := server.OpenShell(stream); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a shell requiring approval, got %v", err)
+	}
+	select {
+	case msg := <-server.shells.outboundFor("minion-1"):
+		t.Errorf("Expected no session opened on the minion, got %v", msg)
+	default:
+	}
+}
@@ -0,0 +1,179 @@
+package nexus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxSpeedTestBytes bounds the bytes uploaded and downloaded by a net:speedtest
+	maxSpeedTestBytes = command.MaxSpeedTestSize * 1024 * 1024
+	// maxSpeedTestStreamBytes bounds the bytes uploaded and downloaded over a speed test stream, one
+	// upload and one download
+	maxSpeedTestStreamBytes = 2 * maxSpeedTestBytes
+	// speedTestChunkSize is the size of the synthetic payloads downloaded by net:speedtest
+	speedTestChunkSize = 256 * 1024
+)
+
+// speedTestDeadline bounds a speed test stream, past the timeout of net:speedtest on minions
+var speedTestDeadline = 3 * time.Minute
+
+// speedTestPayload is the synthetic payload sent to the minions, never modified
+var speedTestPayload = make([]byte, speedTestChunkSize)
+
+// SpeedTest answers a net:speedtest run by a minion: it echoes its latency probes, counts the
+// bytes it uploads and sends it the bytes it downloads. The minion must have a net:speedtest
+// pending, and a stream is bounded in bytes and time so that it can't use Nexus as a bandwidth
+// source or sink.
+func (s *Server) SpeedTest(stream pb.MinionService_SpeedTestServer) error {
+	logger, start := logging.FuncLogger(s.logger, "nexus.Server.SpeedTest")
+	defer logging.FuncExit(logger, start)
+
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	minionID := msg.MinionId
+	if minionID == "" {
+		return status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if err := s.checkMinionIdentity(stream.Context(), minionID); err != nil {
+		return err
+	}
+	if _, exists := s.GetMinionRegistryImpl().GetConnectionImpl(minionID); !exists {
+		return status.Error(codes.NotFound, "minion not registered")
+	}
+	if !s.pendingSpeedTest(minionID) {
+		return status.Error(codes.FailedPrecondition, "no net:speedtest is pending for the minion")
+	}
+	logger.Info("Speed test started", zap.String("minion_id", minionID))
+
+	deadline := time.Now().Add(speedTestDeadline)
+	var received, transferred int64
+	for {
+		if time.Now().After(deadline) {
+			return status.Errorf(codes.DeadlineExceeded, "speed tests last at most %s", speedTestDeadline)
+		}
+		switch {
+		case msg.Ping > 0:
+			err = stream.Send(&pb.SpeedTestProbe{Ping: msg.Ping})
+		case msg.UploadDone:
+			err = stream.Send(&pb.SpeedTestProbe{UploadDone: true, Received: received})
+			received = 0
+		case msg.Download > 0:
+			size := min(msg.Download, maxSpeedTestBytes, maxSpeedTestStreamBytes-transferred)
+			if size <= 0 {
+				return status.Errorf(codes.ResourceExhausted, "speed tests transfer at most %d bytes", maxSpeedTestStreamBytes)
+			}
+			transferred += size
+			err = sendSpeedTestDownload(stream, size)
+		default:
+			received += int64(len(msg.Data))
+			transferred += int64(len(msg.Data))
+			if received > maxSpeedTestBytes {
+				return status.Errorf(codes.ResourceExhausted, "speed tests upload at most %d bytes", maxSpeedTestBytes)
+			}
+			if transferred > maxSpeedTestStreamBytes {
+				return status.Errorf(codes.ResourceExhausted, "speed tests transfer at most %d bytes", maxSpeedTestStreamBytes)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg, err = stream.Recv(); err == io.EOF {
+			logger.Info("Speed test completed", zap.String("minion_id", minionID))
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// pendingSpeedTest reports whether a net:speedtest dispatched to a minion awaits its result
+func (s *Server) pendingSpeedTest(minionID string) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	for _, tracker := range s.pendingCommands {
+		if !tracker.Pending[minionID] || time.Since(tracker.CreatedAt) > pendingCommandTTL {
+			continue
+		}
+		if fields := strings.Fields(tracker.Payload); len(fields) > 0 && fields[0] == "net:speedtest" {
+			return true
+		}
+	}
+	return false
+}
+
+// sendSpeedTestDownload sends size bytes of synthetic payload, the last chunk flagged
+func sendSpeedTestDownload(stream pb.MinionService_SpeedTestServer, size int64) error {
+	for sent := int64(0); ; {
+		chunk := speedTestPayload[:min(int64(speedTestChunkSize), size-sent)]
+		sent += int64(len(chunk))
+		if err := stream.Send(&pb.SpeedTestProbe{Data: chunk, DownloadDone: sent >= size}); err != nil {
+			return err
+		}
+		if sent >= size {
+			return nil
+		}
+	}
+}
+
+// lastSpeedTest returns the last successful net:speedtest of a minion, nil without one or when it
+// can't be read
+func (s *Server) lastSpeedTest(ctx context.Context, minionID string, logger *zap.Logger) *pb.SpeedTestSummary {
+	if s.dbService == nil {
+		return nil
+	}
+	summary, err := s.dbService.LastSpeedTest(ctx, minionID)
+	if err != nil {
+		logger.Warn("Failed to read the last speed test of the minion",
+			zap.String("minion_id", minionID),
+			zap.Error(err))
+		return nil
+	}
+	return summary
+}
+
+// LastSpeedTest returns the last successful net:speedtest result of a minion, nil without one
+func (d *DatabaseServiceImpl) LastSpeedTest(ctx context.Context, minionID string) (*pb.SpeedTestSummary, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable")
+	}
+
+	summary := &pb.SpeedTestSummary{}
+	var structured string
+	err := d.db.QueryRowContext(ctx,
+		`SELECT command_id, EXTRACT(EPOCH FROM timestamp)::bigint, structured FROM command_results
+		WHERE minion_id = $1 AND content_type = $2 AND exit_code = 0 ORDER BY timestamp DESC LIMIT 1`,
+		minionID, command.ContentTypeNetSpeedTest).Scan(&summary.CommandId, &summary.Timestamp, &structured)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last speed test: %v", err)
+	}
+
+	var result command.SpeedTestResult
+	if err := json.Unmarshal([]byte(structured), &result); err != nil {
+		return nil, fmt.Errorf("invalid speed test result %s: %v", summary.CommandId, err)
+	}
+	summary.LatencyMs = result.LatencyAvgMs
+	summary.UploadMbps = result.UploadMbps
+	summary.DownloadMbps = result.DownloadMbps
+	return summary, nil
+}
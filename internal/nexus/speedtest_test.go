@@ -0,0 +1,144 @@
+package nexus
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// speedTestClient serves the minion service of server over an in-memory listener
+func speedTestClient(t *testing.T, server *Server) pb.MinionServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	pb.RegisterMinionServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial in-memory server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMinionServiceClient(conn)
+}
+
+func TestSpeedTest(t *testing.T) {
+	server, _ := createLockTestServer(t)
+	client := speedTestClient(t, server)
+	ctx := context.Background()
+	server.trackCommand("cmd-1", "net:speedtest -s 1", []string{"minion-1"})
+
+	stream, err := client.SpeedTest(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open speed test: %v", err)
+	}
+	if err := stream.Send(&pb.SpeedTestProbe{MinionId: "minion-1", Ping: 1}); err != nil {
+		t.Fatalf("Failed to send probe: %v", err)
+	}
+	if reply, err := stream.Recv(); err != nil || reply.Ping != 1 {
+		t.Fatalf("Expected the probe echoed, got %v, %v", reply, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := stream.Send(&pb.SpeedTestProbe{Data: make([]byte, 1000)}); err != nil {
+			t.Fatalf("Failed to upload: %v", err)
+		}
+	}
+	stream.Send(&pb.SpeedTestProbe{UploadDone: true})
+	if reply, err := stream.Recv(); err != nil || !reply.UploadDone || reply.Received != 3000 {
+		t.Fatalf("Expected 3000 bytes received, got %v, %v", reply, err)
+	}
+
+	stream.Send(&pb.SpeedTestProbe{Download: speedTestChunkSize + 10})
+	var downloaded int64
+	for {
+		reply, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		downloaded += int64(len(reply.Data))
+		if reply.DownloadDone {
+			break
+		}
+	}
+	if downloaded != speedTestChunkSize+10 {
+		t.Errorf("Expected %d bytes downloaded, got %d", speedTestChunkSize+10, downloaded)
+	}
+	stream.CloseSend()
+
+	// Only registered minions run speed tests
+	stream, err = client.SpeedTest(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open speed test: %v", err)
+	}
+	stream.Send(&pb.SpeedTestProbe{MinionId: "unknown", Ping: 1})
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unregistered minion, got %v", err)
+	}
+
+	// Streams are bounded in time
+	originalDeadline := speedTestDeadline
+	speedTestDeadline = -time.Second
+	defer func() { speedTestDeadline = originalDeadline }()
+	stream, err = client.SpeedTest(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open speed test: %v", err)
+	}
+	stream.Send(&pb.SpeedTestProbe{MinionId: "minion-1", Ping: 1})
+	if _, err := stream.Recv(); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded past the deadline, got %v", err)
+	}
+
+	// Once its result is received, the minion has no speed test pending
+	server.completeCommand("cmd-1", "minion-1")
+	stream, err = client.SpeedTest(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open speed test: %v", err)
+	}
+	stream.Send(&pb.SpeedTestProbe{MinionId: "minion-1", Ping: 1})
+	if _, err := stream.Recv(); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition without pending speed test, got %v", err)
+	}
+}
+
+func TestLastSpeedTest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	mock.ExpectQuery("SELECT command_id, EXTRACT\\(EPOCH FROM timestamp\\)::bigint, structured FROM command_results").
+		WithArgs("minion-1", command.ContentTypeNetSpeedTest).
+		WillReturnRows(sqlmock.NewRows([]string{"command_id", "timestamp", "structured"}).
+			AddRow("cmd-1", int64(1700000000), `{"pings":5,"latency_avg_ms":12.5,"upload_mbps":80.2,"download_mbps":310.7}`))
+	summary := server.lastSpeedTest(context.Background(), "minion-1", server.logger)
+	if summary == nil || summary.CommandId != "cmd-1" || summary.LatencyMs != 12.5 || summary.UploadMbps != 80.2 || summary.DownloadMbps != 310.7 {
+		t.Errorf("Unexpected speed test summary: %v", summary)
+	}
+
+	mock.ExpectQuery("FROM command_results").WillReturnRows(sqlmock.NewRows([]string{"command_id", "timestamp", "structured"}))
+	if summary := server.lastSpeedTest(context.Background(), "minion-2", server.logger); summary != nil {
+		t.Errorf("Expected no speed test, got %v", summary)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
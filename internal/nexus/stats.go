@@ -0,0 +1,229 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultStatsRange is the time range of GetCommandStats without since
+	defaultStatsRange = 24 * time.Hour
+	// defaultStatsSlowest and maxStatsSlowest bound the number of slowest minions of GetCommandStats
+	defaultStatsSlowest = 5
+	maxStatsSlowest     = 100
+)
+
+// statsDurationsQuery selects the results counted by GetCommandStats with their duration, from dispatch to result
+const statsDurationsQuery = `WITH d AS (
+	SELECT r.minion_id, r.exit_code, COALESCE(c.impact, '') AS impact,
+		GREATEST(EXTRACT(EPOCH FROM (r.timestamp - c.timestamp)) * 1000, 0) AS ms
+	FROM command_results r
+	JOIN commands c ON c.id = r.command_id
+	WHERE r.timestamp >= $1 AND r.timestamp < $2 AND ($3::text[] IS NULL OR c.id = ANY($3)) AND ($4 = '' OR c.command LIKE $4)
+) `
+
+// GetCommandStats aggregates the results received between since and until for the commands matching
+// pattern (* is a wildcard), with the slowest minions by median duration and a breakdown by impact class.
+func (d *DatabaseServiceImpl) GetCommandStats(ctx context.Context, since, until time.Time, pattern string, slowest int) (*pb.CommandStats, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get command statistics")
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetCommandStats")
+	defer logging.FuncExit(logger, start)
+
+	stats := &pb.CommandStats{Since: since.Unix(), Until: until.Unix()}
+
+	// Encrypted payloads are matched once decrypted, the statistics being restricted to the matching
+	// commands, and payloads stored in clear in SQL
+	var commandIDs interface{}
+	like := strings.ReplaceAll(pattern, "*", "%")
+	if pattern != "" && d.encryptor != nil {
+		like = ""
+		ids, err := d.matchingCommandIDs(ctx, since, until, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return stats, nil
+		}
+		commandIDs = pq.Array(ids)
+	}
+
+	err := d.reader().QueryRowContext(ctx, statsDurationsQuery+`SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN exit_code <> 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY ms), 0)::bigint,
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY ms), 0)::bigint,
+			COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY ms), 0)::bigint,
+			COALESCE(MAX(ms), 0)::bigint
+		FROM d`,
+		since, until, commandIDs, like).Scan(&stats.Count, &stats.Failures, &stats.MedianMs, &stats.P90Ms, &stats.P99Ms, &stats.MaxMs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query command statistics: %v", err)
+	}
+	if stats.Count == 0 {
+		return stats, nil
+	}
+
+	rows, err := d.reader().QueryContext(ctx, statsDurationsQuery+`SELECT minion_id, COUNT(*),
+			SUM(CASE WHEN exit_code <> 0 THEN 1 ELSE 0 END),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY ms)::bigint,
+			MAX(ms)::bigint
+		FROM d
+		GROUP BY minion_id
+		ORDER BY 4 DESC, 1
+		LIMIT $5`,
+		since, until, commandIDs, like, slowest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slowest minions: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var minion pb.MinionCommandStats
+		if err := rows.Scan(&minion.MinionId, &minion.Count, &minion.Failures, &minion.MedianMs, &minion.MaxMs); err != nil {
+			return nil, fmt.Errorf("failed to scan slowest minions: %v", err)
+		}
+		stats.SlowestMinions = append(stats.SlowestMinions, &minion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading slowest minions: %v", err)
+	}
+
+	if stats.ByImpact, err = d.getImpactStats(ctx, since, until, commandIDs, like); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Retrieved command statistics",
+		zap.Int64("count", stats.Count),
+		zap.String("pattern", pattern))
+	return stats, nil
+}
+
+// matchingCommandIDs returns the commands with results between since and until whose decrypted payload
+// matches pattern
+func (d *DatabaseServiceImpl) matchingCommandIDs(ctx context.Context, since, until time.Time, pattern string) ([]string, error) {
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT DISTINCT c.id, c.host_id, c.command
+		FROM command_results r
+		JOIN commands c ON c.id = r.command_id
+		WHERE r.timestamp >= $1 AND r.timestamp < $2`,
+		since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commands: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	matched := make(map[string]bool)
+	for rows.Next() {
+		var id, minionID, payload string
+		if err := rows.Scan(&id, &minionID, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan commands: %v", err)
+		}
+		if payload, err = d.encryptor.Decrypt(payload, encryptionBinding{id, minionID, payloadColumn}); err != nil {
+			continue
+		}
+		if !matched[id] && matchCommandPattern(pattern, payload) {
+			matched[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading commands: %v", err)
+	}
+	return ids, nil
+}
+
+// matchCommandPattern reports whether payload matches pattern, * matching any sequence of characters
+func matchCommandPattern(pattern, payload string) bool {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matched, _ := regexp.MatchString("(?s)^"+strings.Join(parts, ".*")+"$", payload)
+	return matched
+}
+
+// getImpactStats counts the results of GetCommandStats by impact class, least dangerous first
+func (d *DatabaseServiceImpl) getImpactStats(ctx context.Context, since, until time.Time, commandIDs interface{}, like string) ([]*pb.ImpactCommandStats, error) {
+	rows, err := d.reader().QueryContext(ctx, statsDurationsQuery+`SELECT impact, COUNT(*),
+			SUM(CASE WHEN exit_code <> 0 THEN 1 ELSE 0 END)
+		FROM d
+		GROUP BY impact`,
+		since, until, commandIDs, like)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impact statistics: %v", err)
+	}
+	defer rows.Close()
+
+	var byImpact []*pb.ImpactCommandStats
+	for rows.Next() {
+		var impact pb.ImpactCommandStats
+		if err := rows.Scan(&impact.Impact, &impact.Count, &impact.Failures); err != nil {
+			return nil, fmt.Errorf("failed to scan impact statistics: %v", err)
+		}
+		byImpact = append(byImpact, &impact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading impact statistics: %v", err)
+	}
+
+	sort.Slice(byImpact, func(i, j int) bool {
+		return command.ImpactRank(byImpact[i].Impact) < command.ImpactRank(byImpact[j].Impact)
+	})
+	return byImpact, nil
+}
+
+// GetCommandStats returns success and failure counts and execution durations of the fleet in the ConsoleService
+func (s *Server) GetCommandStats(ctx context.Context, req *pb.CommandStatsRequest) (*pb.CommandStats, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetCommandStats")
+	defer logging.FuncExit(logger, start)
+
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "command statistics require a database")
+	}
+	if err := requireAllNamespaces(ctx, "command statistics"); err != nil {
+		return nil, err
+	}
+
+	until := time.Now()
+	if req.Until > 0 {
+		until = time.Unix(req.Until, 0)
+	}
+	since := until.Add(-defaultStatsRange)
+	if req.Since > 0 {
+		since = time.Unix(req.Since, 0)
+	}
+	if !since.Before(until) {
+		return nil, status.Error(codes.InvalidArgument, "since must be before until")
+	}
+
+	slowest := int(req.Slowest)
+	if slowest <= 0 {
+		slowest = defaultStatsSlowest
+	}
+	if slowest > maxStatsSlowest {
+		slowest = maxStatsSlowest
+	}
+
+	stats, err := s.dbService.GetCommandStats(ctx, since, until, req.CommandPattern, slowest)
+	if err != nil {
+		logger.Error("Failed to get command statistics", zap.String("pattern", req.CommandPattern), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get command statistics")
+	}
+	return stats, nil
+}
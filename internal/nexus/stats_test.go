@@ -0,0 +1,120 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"github.com/lib/pq"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetCommandStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	if err := server.SetEncryptionKeys([][]byte{bytes.Repeat([]byte{4}, 32)}); err != nil {
+		t.Fatalf("SetEncryptionKeys failed: %v", err)
+	}
+	encryptor := server.dbService.(*DatabaseServiceImpl).encryptor
+	since, until := time.Unix(1700000000, 0), time.Unix(1700086400, 0)
+
+	// The pattern is matched on the decrypted payloads, encrypted or stored in clear
+	dockerPS, _ := encryptor.Encrypt("docker:ps", encryptionBinding{"cmd-1", "minion-1", payloadColumn})
+	systemInfo, _ := encryptor.Encrypt("system:info", encryptionBinding{"cmd-2", "minion-1", payloadColumn})
+	matching := pq.Array([]string{"cmd-1", "cmd-3"})
+	mock.ExpectQuery("SELECT DISTINCT c.id, c.host_id, c.command").WithArgs(since, until).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host_id", "command"}).
+			AddRow("cmd-1", "minion-1", dockerPS).
+			AddRow("cmd-2", "minion-1", systemInfo).
+			AddRow("cmd-3", "minion-1", "docker:logs web").
+			AddRow("cmd-3", "minion-2", "docker:logs web"))
+	mock.ExpectQuery("SELECT COUNT").WithArgs(since, until, matching, "").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "failures", "p50", "p90", "p99", "max"}).
+			AddRow(int64(40), int64(3), int64(850), int64(4200), int64(9800), int64(12000)))
+	mock.ExpectQuery("SELECT minion_id").WithArgs(since, until, matching, "", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"minion_id", "count", "failures", "median", "max"}).
+			AddRow("minion-2", int64(10), int64(3), int64(5100), int64(12000)).
+			AddRow("minion-1", int64(30), int64(0), int64(600), int64(1900)))
+	mock.ExpectQuery("SELECT impact").WithArgs(since, until, matching, "").
+		WillReturnRows(sqlmock.NewRows([]string{"impact", "count", "failures"}).
+			AddRow("disruptive", int64(4), int64(1)).
+			AddRow("read-only", int64(30), int64(0)).
+			AddRow("", int64(6), int64(2)))
+
+	stats, err := server.GetCommandStats(context.Background(), &pb.CommandStatsRequest{
+		Since: since.Unix(), Until: until.Unix(), CommandPattern: "docker:*", Slowest: 2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Count != 40 || stats.Failures != 3 || stats.MedianMs != 850 || stats.P99Ms != 9800 {
+		t.Errorf("Unexpected statistics: %v", stats)
+	}
+	if len(stats.SlowestMinions) != 2 || stats.SlowestMinions[0].MinionId != "minion-2" {
+		t.Errorf("Expected minion-2 as the slowest minion, got %v", stats.SlowestMinions)
+	}
+	if len(stats.ByImpact) != 3 || stats.ByImpact[0].Impact != "" || stats.ByImpact[1].Impact != "read-only" || stats.ByImpact[2].Failures != 1 {
+		t.Errorf("Expected the impact classes least dangerous first, got %v", stats.ByImpact)
+	}
+
+	// Without matching commands, the statistics are empty
+	mock.ExpectQuery("SELECT DISTINCT c.id, c.host_id, c.command").WithArgs(since, until).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host_id", "command"}).AddRow("cmd-2", "minion-1", systemInfo))
+	stats, err = server.GetCommandStats(context.Background(), &pb.CommandStatsRequest{
+		Since: since.Unix(), Until: until.Unix(), CommandPattern: "docker:*",
+	})
+	if err != nil || stats.Count != 0 {
+		t.Errorf("Expected empty statistics, got %v, %v", stats, err)
+	}
+
+	// Without results, the slowest minions are not queried
+	mock.ExpectQuery("SELECT COUNT").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "failures", "p50", "p90", "p99", "max"}).
+			AddRow(int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)))
+	stats, err = server.GetCommandStats(context.Background(), &pb.CommandStatsRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Until-stats.Since != int64(defaultStatsRange.Seconds()) {
+		t.Errorf("Expected the default range, got %d to %d", stats.Since, stats.Until)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := server.GetCommandStats(context.Background(), &pb.CommandStatsRequest{Since: until.Unix(), Until: since.Unix()}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument with an empty range, got %v", err)
+	}
+	if _, err := createTestServer(nil).GetCommandStats(context.Background(), &pb.CommandStatsRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
+
+func TestGetCommandStatsInClear(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	// Without encryption, the pattern is matched in SQL
+	server := createTestServer(db)
+	mock.ExpectQuery("SELECT COUNT").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "docker:%").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "failures", "p50", "p90", "p99", "max"}).
+			AddRow(int64(0), int64(0), int64(0), int64(0), int64(0), int64(0)))
+	if _, err := server.GetCommandStats(context.Background(), &pb.CommandStatsRequest{CommandPattern: "docker:*"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// StreamMonitor periodically detects half-open minion command streams
+type StreamMonitor struct {
+	registry *MinionRegistryImpl
+	deadline time.Duration
+	interval time.Duration
+	expire   func(minionID string, drained []*pb.Command)
+	logger   *zap.Logger
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewStreamMonitor creates a monitor marking streams dead once their minion has not been heard
+// from for longer than deadline. Commands drained from dead streams are passed to expire.
+func NewStreamMonitor(registry *MinionRegistryImpl, deadline time.Duration, expire func(minionID string, drained []*pb.Command), logger *zap.Logger) *StreamMonitor {
+	// Check often enough to detect dead streams shortly after the deadline
+	interval := deadline / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	return &StreamMonitor{
+		registry: registry,
+		deadline: deadline,
+		interval: interval,
+		expire:   expire,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the detection loop
+func (m *StreamMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				m.CheckStreams()
+			}
+		}
+	}()
+}
+
+// Stop stops the detection loop
+func (m *StreamMonitor) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+	})
+	m.wg.Wait()
+}
+
+// CheckStreams expires the dead streams and hands over their drained commands
+func (m *StreamMonitor) CheckStreams() {
+	for minionID, drained := range m.registry.ExpireDeadStreams(m.deadline) {
+		m.expire(minionID, drained)
+	}
+}
+
+// EnableStreamMonitor starts detecting half-open command streams: a minion with an open stream
+// that is not heard from (results, status updates, heartbeats) for longer than deadline is
+// disconnected and the commands queued for it fail. Minions reconnecting get a fresh stream.
+func (s *Server) EnableStreamMonitor(deadline time.Duration) {
+	s.streamMonitor = NewStreamMonitor(s.GetMinionRegistryImpl(), deadline, s.failDrainedCommands, s.logger)
+	s.streamMonitor.Start()
+}
+
+// failDrainedCommands records the commands drained from a dead stream as failed
+func (s *Server) failDrainedCommands(minionID string, drained []*pb.Command) {
+	err := fmt.Errorf("command stream detected dead after %s without activity", s.streamMonitor.deadline)
+	s.diagnostics.RecordError(minionID, err)
+	// Commands delivered on the dead stream won't report their result
+	s.releaseMinionLocks(minionID, s.logger)
+
+	for _, cmd := range drained {
+		s.handleCommandResult(context.Background(), &pb.CommandResult{
+			CommandId: cmd.Id,
+			MinionId:  minionID,
+			ExitCode:  -1,
+			Stderr:    fmt.Sprintf("command not delivered: %v", err),
+			Timestamp: time.Now().Unix(),
+		}, s.logger)
+	}
+}
@@ -0,0 +1,210 @@
+package nexus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/command"
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+)
+
+func TestExpireDeadStreams(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	for _, id := range []string{"alive", "half-open", "no-stream"} {
+		registry.minions[id] = &MinionConnectionImpl{
+			Info:     &pb.HostInfo{Id: id},
+			Commands: NewCommandQueue(10),
+		}
+	}
+
+	aliveDead := registry.OpenStream("alive")
+	halfOpenDead := registry.OpenStream("half-open")
+
+	registry.minions["half-open"].LastSeen = time.Now().Add(-time.Hour)
+	registry.minions["no-stream"].LastSeen = time.Now().Add(-time.Hour)
+	registry.minions["half-open"].Commands.Push(&pb.Command{Id: "cmd-1", Priority: pb.CommandPriority_LOW})
+	registry.minions["half-open"].Commands.Push(&pb.Command{Id: "cmd-2", Priority: pb.CommandPriority_HIGH})
+
+	expired := map[string][]*pb.Command{}
+	monitor := NewStreamMonitor(registry, time.Minute, func(minionID string, drained []*pb.Command) {
+		expired[minionID] = drained
+	}, zap.NewNop())
+	monitor.CheckStreams()
+
+	if len(expired) != 1 {
+		t.Fatalf("Expected only the half-open stream to expire, got %v", expired)
+	}
+	if drained := expired["half-open"]; len(drained) != 2 || drained[0].Id != "cmd-2" || drained[1].Id != "cmd-1" {
+		t.Errorf("Expected queued commands drained in dispatch order, got %v", drained)
+	}
+	if registry.minions["half-open"].Commands.Len() != 0 {
+		t.Error("Expected the command queue to be drained")
+	}
+
+	select {
+	case <-halfOpenDead:
+	default:
+		t.Error("Expected the half-open stream to be signaled dead")
+	}
+	select {
+	case <-aliveDead:
+		t.Error("Expected the alive stream to stay open")
+	default:
+	}
+
+	// An expired stream is not expired again
+	expired = map[string][]*pb.Command{}
+	monitor.CheckStreams()
+	if len(expired) != 0 {
+		t.Errorf("Expected no further expiration, got %v", expired)
+	}
+}
+
+func TestCloseStreamKeepsNewerStream(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1"},
+		Commands: NewCommandQueue(10),
+	}
+
+	// The minion reconnects before its previous stream handler returned
+	old := registry.OpenStream("minion-1")
+	current := registry.OpenStream("minion-1")
+	registry.CloseStream("minion-1", old)
+
+	registry.minions["minion-1"].LastSeen = time.Now().Add(-time.Hour)
+	if expired := registry.ExpireDeadStreams(time.Minute); len(expired) != 1 {
+		t.Fatalf("Expected the current stream to still be tracked, got %v", expired)
+	}
+	select {
+	case <-current:
+	default:
+		t.Error("Expected the current stream to be signaled dead")
+	}
+}
+
+func TestSleepingMinionKeepsQueuedCommands(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	sleepAt := time.Now().Add(-time.Minute).Unix()
+	nextWake := time.Now().Add(time.Hour).Unix()
+	if _, err := registry.Register(&pb.HostInfo{Id: "laptop", Availability: "Mon-Fri 08:00-18:00", SleepAt: sleepAt, NextWake: nextWake}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The laptop suspended without closing its stream: the stream expires but the commands wait
+	registry.OpenStream("laptop")
+	registry.minions["laptop"].LastSeen = time.Now().Add(-time.Hour)
+	registry.minions["laptop"].Commands.Push(&pb.Command{Id: "cmd-1"})
+	if expired := registry.ExpireDeadStreams(time.Minute); len(expired) != 0 {
+		t.Fatalf("Expected the commands of a power-aware minion kept, got %v", expired)
+	}
+	registry.minions["laptop"].Commands.Push(&pb.Command{Id: "cmd-2"})
+
+	// Minion lists show it sleeping with its queue depth and next wake, missed heartbeats excused
+	clock := time.Now().Add(-10 * time.Minute)
+	registry.health.now = func() time.Time { return clock }
+	for i := 0; i < 3; i++ {
+		registry.health.RecordHeartbeat("laptop")
+		clock = clock.Add(30 * time.Second)
+	}
+	clock = time.Now()
+	if !containsAnomaly(registry.health.Health("laptop"), AnomalyMissedHeartbeats) {
+		t.Fatal("Expected the missed heartbeats recorded")
+	}
+	minions := registry.ListMinions()
+	if len(minions) != 1 || !minions[0].Sleeping || minions[0].QueuedCommands != 2 || minions[0].NextWake != nextWake || minions[0].Availability != "Mon-Fri 08:00-18:00" {
+		t.Fatalf("Unexpected minion list: %v", minions)
+	}
+	if containsAnomaly(minions[0].Health, AnomalyMissedHeartbeats) {
+		t.Errorf("Expected the missed heartbeats of a sleeping minion excused, got %v", minions[0].Health)
+	}
+
+	// Waking up, it is no longer sleeping and its commands are still queued for its stream
+	registry.health.now = time.Now
+	if _, err := registry.Register(&pb.HostInfo{Id: "laptop", Availability: "Mon-Fri 08:00-18:00", SleepAt: nextWake, NextWake: nextWake + 3600}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	registry.OpenStream("laptop")
+	minions = registry.ListMinions()
+	if minions[0].Sleeping || minions[0].QueuedCommands != 2 || minions[0].Health.Reconnects != 0 {
+		t.Errorf("Expected the minion awake with its queued commands, got %v", minions[0])
+	}
+}
+
+func TestPoweredDownMinion(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	if _, err := registry.Register(&pb.HostInfo{Id: "web-01"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	dead := registry.OpenStream("web-01")
+
+	// The minion announces its reboot: going down before its time is a failure
+	powerAt := time.Now().Add(time.Minute).Unix()
+	if _, err := registry.Register(&pb.HostInfo{Id: "web-01", PowerAction: "reboot", PowerAt: powerAt}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reason := disconnectReason(registry.minions["web-01"].Info, errors.New("connection reset")); reason.Error() != "connection reset" {
+		t.Errorf("Expected the reboot not due yet ignored, got %v", reason)
+	}
+	minions := registry.ListMinions()
+	if minions[0].PowerAction != "reboot" || minions[0].PowerAt != powerAt || minions[0].PoweredDown {
+		t.Fatalf("Expected the reboot announced while the stream is open, got %v", minions[0])
+	}
+
+	// Once due, its host goes down without closing the stream
+	powerAt = time.Now().Add(-time.Minute).Unix()
+	if _, err := registry.Register(&pb.HostInfo{Id: "web-01", PowerAction: "reboot", PowerAt: powerAt}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reason := disconnectReason(registry.minions["web-01"].Info, errors.New("connection reset")); reason.Error() != "announced reboot" {
+		t.Errorf("Expected the announced reboot as disconnect reason, got %v", reason)
+	}
+
+	registry.minions["web-01"].LastSeen = time.Now().Add(-time.Hour)
+	registry.minions["web-01"].Commands.Push(&pb.Command{Id: "cmd-1"})
+	if expired := registry.ExpireDeadStreams(time.Minute); len(expired) != 0 {
+		t.Fatalf("Expected the commands of a rebooting minion kept, got %v", expired)
+	}
+	registry.CloseStream("web-01", dead)
+
+	clock := time.Now().Add(-10 * time.Minute)
+	registry.health.now = func() time.Time { return clock }
+	for i := 0; i < 3; i++ {
+		registry.health.RecordHeartbeat("web-01")
+		clock = clock.Add(30 * time.Second)
+	}
+	clock = time.Now()
+	minions = registry.ListMinions()
+	if !minions[0].PoweredDown || minions[0].QueuedCommands != 1 || containsAnomaly(minions[0].Health, AnomalyMissedHeartbeats) {
+		t.Fatalf("Expected the minion down on purpose, its missed heartbeats excused, got %v", minions[0])
+	}
+
+	// Past its grace period, the announcement isn't honoured anymore
+	info := registry.minions["web-01"].Info
+	info.PowerAt = time.Now().Add(-command.PowerAnnouncementGrace - time.Minute).Unix()
+	minions = registry.ListMinions()
+	if minions[0].PoweredDown || minions[0].PowerAction != "" {
+		t.Errorf("Expected the expired announcement dropped, got %v", minions[0])
+	}
+	if reason := disconnectReason(info, errors.New("connection reset")); reason.Error() != "connection reset" {
+		t.Errorf("Expected the expired announcement ignored as disconnect reason, got %v", reason)
+	}
+	info.PowerAt = powerAt
+
+	// Back from the reboot, its reconnection isn't held against it
+	registry.health.now = time.Now
+	if _, err := registry.Register(&pb.HostInfo{Id: "web-01"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	registry.OpenStream("web-01")
+	minions = registry.ListMinions()
+	if minions[0].PowerAction != "" || minions[0].PoweredDown || minions[0].QueuedCommands != 1 || minions[0].Health.Reconnects != 0 {
+		t.Errorf("Expected the minion back with its queued commands, got %v", minions[0])
+	}
+}
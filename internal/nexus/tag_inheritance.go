@@ -0,0 +1,67 @@
+package nexus
+
+import "fmt"
+
+// Policies resolving the conflicts between the tags a minion advertises at registration,
+// such as the static tags of its configuration, and the tags set on it from consoles
+const (
+	TagConflictServer = "server" // the tags set from consoles win, the default
+	TagConflictMinion = "minion" // the tags advertised by the minion win
+)
+
+// ValidateTagConflictPolicy checks that policy is a tag conflict policy
+func ValidateTagConflictPolicy(policy string) error {
+	if policy != TagConflictServer && policy != TagConflictMinion {
+		return fmt.Errorf("invalid tag conflict policy '%s', must be '%s' or '%s'", policy, TagConflictServer, TagConflictMinion)
+	}
+	return nil
+}
+
+// mergeTags merges the tags a minion advertises at registration with the tags it has.
+// previous are the tags it advertised at its previous registration: the current tags it didn't
+// advertise, or whose value changed since, were set from consoles and are kept, winning the
+// conflicts with advertised under the server policy. The tags the minion no longer advertises
+// are dropped.
+func mergeTags(current, previous, advertised map[string]string, policy string) map[string]string {
+	merged := make(map[string]string, len(current)+len(advertised))
+	for key, value := range advertised {
+		merged[key] = value
+	}
+	for key, value := range current {
+		if advertisedValue, ok := previous[key]; ok && advertisedValue == value {
+			continue
+		}
+		if _, conflict := advertised[key]; conflict && policy == TagConflictMinion {
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// copyTags returns a copy of tags
+func copyTags(tags map[string]string) map[string]string {
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
+
+// SetTagConflictPolicy sets how the tags advertised by a re-registering minion are merged with
+// the tags set on it from consoles, TagConflictServer or TagConflictMinion
+func (s *Server) SetTagConflictPolicy(policy string) error {
+	if err := ValidateTagConflictPolicy(policy); err != nil {
+		return err
+	}
+	s.GetMinionRegistryImpl().SetTagConflictPolicy(policy)
+	return nil
+}
+
+// SetTagConflictPolicy sets the policy resolving the tag conflicts at re-registration
+func (r *MinionRegistryImpl) SetTagConflictPolicy(policy string) {
+	r.minionsMu.Lock()
+	defer r.minionsMu.Unlock()
+
+	r.tagConflict = policy
+}
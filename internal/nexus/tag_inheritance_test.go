@@ -0,0 +1,152 @@
+package nexus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestMergeTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    map[string]string
+		previous   map[string]string
+		advertised map[string]string
+		policy     string
+		expected   map[string]string
+	}{
+		{
+			name:       "first registration",
+			advertised: map[string]string{"role": "web"},
+			policy:     TagConflictServer,
+			expected:   map[string]string{"role": "web"},
+		},
+		{
+			name:       "console tags kept",
+			current:    map[string]string{"role": "web", "owner": "ops"},
+			previous:   map[string]string{"role": "web"},
+			advertised: map[string]string{"role": "web"},
+			policy:     TagConflictServer,
+			expected:   map[string]string{"role": "web", "owner": "ops"},
+		},
+		{
+			name:       "minion tag changed",
+			current:    map[string]string{"role": "web"},
+			previous:   map[string]string{"role": "web"},
+			advertised: map[string]string{"role": "db"},
+			policy:     TagConflictServer,
+			expected:   map[string]string{"role": "db"},
+		},
+		{
+			name:       "minion tag removed",
+			current:    map[string]string{"role": "web", "owner": "ops"},
+			previous:   map[string]string{"role": "web"},
+			advertised: map[string]string{},
+			policy:     TagConflictServer,
+			expected:   map[string]string{"owner": "ops"},
+		},
+		{
+			name:       "server wins",
+			current:    map[string]string{"env": "staging"},
+			previous:   map[string]string{"env": "prod"},
+			advertised: map[string]string{"env": "prod"},
+			policy:     TagConflictServer,
+			expected:   map[string]string{"env": "staging"},
+		},
+		{
+			name:       "minion wins",
+			current:    map[string]string{"env": "staging", "owner": "ops"},
+			previous:   map[string]string{"env": "prod"},
+			advertised: map[string]string{"env": "prod"},
+			policy:     TagConflictMinion,
+			expected:   map[string]string{"env": "prod", "owner": "ops"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeTags(tt.current, tt.previous, tt.advertised, tt.policy)
+			if !reflect.DeepEqual(merged, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, merged)
+			}
+		})
+	}
+}
+
+func TestRegisterKeepsConsoleTags(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+
+	register := func(tags map[string]string) map[string]string {
+		t.Helper()
+		if _, err := registry.Register(&pb.HostInfo{Id: "minion-1", Tags: tags}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		conn, _ := registry.GetConnection("minion-1")
+		return conn.GetInfo().Tags
+	}
+
+	register(map[string]string{"env": "prod", "role": "web"})
+	if err := registry.UpdateTags("minion-1", map[string]string{"env": "staging", "owner": "ops"}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tags := register(map[string]string{"env": "prod", "role": "web"})
+	expected := map[string]string{"env": "staging", "role": "web", "owner": "ops"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected the console tags kept, got %v", tags)
+	}
+
+	if err := server.SetTagConflictPolicy(TagConflictMinion); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tags = register(map[string]string{"env": "prod", "role": "web"})
+	expected = map[string]string{"env": "prod", "role": "web", "owner": "ops"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected the minion tags to win, got %v", tags)
+	}
+
+	if err := server.SetTagConflictPolicy("both"); err == nil {
+		t.Error("Expected an error for an invalid policy")
+	}
+}
+
+func TestRegisterRestoresStoredConsoleTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+	registry := server.GetMinionRegistryImpl()
+
+	// After a restart, the database merges the advertised tags with the stored console tags
+	mock.ExpectQuery("INSERT INTO hosts \\(id, hostname, ip, os, first_seen, last_seen, tags, advertised_tags\\)").
+		WithArgs("minion-1", "web-01", "10.0.0.1", "linux", sqlmock.AnyArg(), sqlmock.AnyArg(), `{"env":"prod"}`, `{"env":"prod"}`, TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow(`{"env":"staging","owner":"ops"}`))
+	hostInfo := &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.1", Os: "linux", Tags: map[string]string{"env": "prod"}}
+	if _, err := registry.Register(hostInfo); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	conn, _ := registry.GetConnection("minion-1")
+	expected := map[string]string{"env": "staging", "owner": "ops"}
+	if tags := conn.GetInfo().Tags; !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected the stored console tags restored, got %v", tags)
+	}
+
+	// The next registration records the advertised tags again, not the merged ones
+	mock.ExpectQuery("INSERT INTO hosts").
+		WithArgs("minion-1", "web-01", "10.0.0.1", "linux", sqlmock.AnyArg(), sqlmock.AnyArg(), `{"env":"staging","owner":"ops"}`, `{"env":"prod"}`, TagConflictServer).
+		WillReturnRows(sqlmock.NewRows([]string{"tags"}).AddRow(`{"env":"staging","owner":"ops"}`))
+	hostInfo = &pb.HostInfo{Id: "minion-1", Hostname: "web-01", Ip: "10.0.0.1", Os: "linux", Tags: map[string]string{"env": "prod"}}
+	if _, err := registry.Register(hostInfo); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package nexus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TagSchemaKey describes the values allowed for a tag key
+type TagSchemaKey struct {
+	Description string   `json:"description,omitempty"`
+	Values      []string `json:"values,omitempty"`  // allowed values, empty: any value matching Pattern
+	Pattern     string   `json:"pattern,omitempty"` // regular expression the whole value must match, empty: any value
+
+	re *regexp.Regexp
+}
+
+// TagSchema restricts the tags consoles set on minions, keeping tag keys and values consistent
+type TagSchema struct {
+	Keys           map[string]*TagSchemaKey `json:"keys"`
+	AllowOtherKeys bool                     `json:"allow_other_keys"` // keys not in Keys are accepted with any value
+}
+
+// LoadTagSchema reads a tag schema from a JSON file
+func LoadTagSchema(path string) (*TagSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag schema: %w", err)
+	}
+
+	var schema TagSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse tag schema: %w", err)
+	}
+
+	for key, k := range schema.Keys {
+		if key == "" || k == nil {
+			return nil, fmt.Errorf("tag schema: invalid key %q", key)
+		}
+		if k.Pattern == "" {
+			continue
+		}
+		if k.re, err = regexp.Compile("^(?:" + k.Pattern + ")$"); err != nil {
+			return nil, fmt.Errorf("tag schema key %s: %w", key, err)
+		}
+	}
+	return &schema, nil
+}
+
+// Validate checks tags against the schema, a nil schema accepting every tag
+func (ts *TagSchema) Validate(tags map[string]string) error {
+	if ts == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		value := tags[key]
+		k, exists := ts.Keys[key]
+		switch {
+		case !exists && !ts.AllowOtherKeys:
+			problems = append(problems, fmt.Sprintf("tag key %q is not allowed", key))
+		case !exists:
+		case len(k.Values) > 0 && !containsString(k.Values, value):
+			problems = append(problems, fmt.Sprintf("tag %s=%q: value must be one of %s", key, value, strings.Join(k.Values, ", ")))
+		case k.re != nil && !k.re.MatchString(value):
+			problems = append(problems, fmt.Sprintf("tag %s=%q: value must match %s", key, value, k.Pattern))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid tags: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// proto returns the protobuf representation of the schema, keys sorted
+func (ts *TagSchema) proto() *pb.TagSchema {
+	if ts == nil {
+		return &pb.TagSchema{}
+	}
+
+	schema := &pb.TagSchema{Enabled: true, AllowOtherKeys: ts.AllowOtherKeys}
+	for key, k := range ts.Keys {
+		schema.Keys = append(schema.Keys, &pb.TagSchema_Key{
+			Key:           key,
+			Description:   k.Description,
+			AllowedValues: k.Values,
+			Pattern:       k.Pattern,
+		})
+	}
+	sort.Slice(schema.Keys, func(i, j int) bool { return schema.Keys[i].Key < schema.Keys[j].Key })
+	return schema
+}
+
+// SetTagSchema makes Nexus validate the tags set from consoles against schema
+func (s *Server) SetTagSchema(schema *TagSchema) {
+	s.tagSchema = schema
+}
+
+// validateTags checks the tags set from a console against the tag schema
+func (s *Server) validateTags(tags map[string]string) error {
+	if err := s.tagSchema.Validate(tags); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// GetTagSchema returns the tag schema, disabled when Nexus has none
+func (s *Server) GetTagSchema(ctx context.Context, req *pb.Empty) (*pb.TagSchema, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetTagSchema")
+	defer logging.FuncExit(logger, start)
+
+	return s.tagSchema.proto(), nil
+}
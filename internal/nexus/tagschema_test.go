@@ -0,0 +1,115 @@
+package nexus
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// writeTagSchema writes a tag schema file and loads it
+func writeTagSchema(t *testing.T, content string) (*TagSchema, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tags.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write tag schema: %v", err)
+	}
+	return LoadTagSchema(path)
+}
+
+func TestTagSchemaValidate(t *testing.T) {
+	schema, err := writeTagSchema(t, `{
+		"keys": {
+			"env": {"description": "Deployment environment", "values": ["prod", "staging", "dev"]},
+			"role": {"pattern": "[a-z][a-z0-9-]*"},
+			"owner": {}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("LoadTagSchema failed: %v", err)
+	}
+
+	valid := []map[string]string{
+		{"env": "prod", "role": "web-1", "owner": "Team Infra"},
+		{},
+	}
+	for _, tags := range valid {
+		if err := schema.Validate(tags); err != nil {
+			t.Errorf("Expected %v to be valid, got %v", tags, err)
+		}
+	}
+
+	invalid := map[string]map[string]string{
+		`tag env="Prod": value must be one of prod, staging, dev`: {"env": "Prod"},
+		`tag role="web server": value must match [a-z][a-z0-9-]*`: {"role": "web server"},
+		`tag key "environment" is not allowed`:                    {"environment": "prod"},
+	}
+	for expected, tags := range invalid {
+		if err := schema.Validate(tags); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q for %v, got %v", expected, tags, err)
+		}
+	}
+
+	// Patterns match whole values
+	if err := schema.Validate(map[string]string{"role": "web;rm"}); err == nil {
+		t.Error("Expected a partially matching value to be rejected")
+	}
+
+	schema.AllowOtherKeys = true
+	if err := schema.Validate(map[string]string{"environment": "anything"}); err != nil {
+		t.Errorf("Expected other keys to be accepted, got %v", err)
+	}
+
+	var none *TagSchema
+	if err := none.Validate(map[string]string{"any": "thing"}); err != nil {
+		t.Errorf("Expected every tag to be accepted without schema, got %v", err)
+	}
+}
+
+func TestLoadTagSchemaInvalid(t *testing.T) {
+	if _, err := writeTagSchema(t, `{"keys": {"role": {"pattern": "[a-z"}}}`); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+	if _, err := writeTagSchema(t, `{"keys": [`); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+	if _, err := LoadTagSchema(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestTagSchemaRPCs(t *testing.T) {
+	server := createTestServer(nil)
+	ctx := context.Background()
+
+	schema, err := server.GetTagSchema(ctx, &pb.Empty{})
+	if err != nil || schema.Enabled {
+		t.Fatalf("Expected a disabled schema, got %v, %v", schema, err)
+	}
+
+	tagSchema, err := writeTagSchema(t, `{"keys": {"env": {"values": ["prod", "dev"]}, "app": {}}}`)
+	if err != nil {
+		t.Fatalf("LoadTagSchema failed: %v", err)
+	}
+	server.SetTagSchema(tagSchema)
+
+	schema, _ = server.GetTagSchema(ctx, &pb.Empty{})
+	if !schema.Enabled || len(schema.Keys) != 2 || schema.Keys[0].Key != "app" || schema.Keys[1].AllowedValues[1] != "dev" {
+		t.Errorf("Unexpected schema: %v", schema)
+	}
+
+	resp, err := server.SetTags(ctx, &pb.SetTagsRequest{MinionId: "minion-1", Tags: map[string]string{"env": "qa"}})
+	if status.Code(err) != codes.InvalidArgument || resp.Success {
+		t.Errorf("Expected SetTags to be rejected, got %v, %v", resp, err)
+	}
+	resp, err = server.UpdateTags(ctx, &pb.UpdateTagsRequest{MinionId: "minion-1", Add: map[string]string{"team": "infra"}})
+	if status.Code(err) != codes.InvalidArgument || resp.Success {
+		t.Errorf("Expected UpdateTags to be rejected, got %v, %v", resp, err)
+	}
+}
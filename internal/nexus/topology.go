@@ -0,0 +1,120 @@
+package nexus
+
+import (
+	"sort"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+// topology is the set of failure domains of a minion, from the widest to the narrowest
+type topology struct {
+	region     string
+	datacenter string
+	rack       string
+}
+
+// topologyTags are the tags each failure domain falls back to, in order of preference. The zone
+// tag set from the cloud instance metadata is the datacenter of cloud minions.
+var topologyTags = struct{ region, datacenter, rack []string }{
+	region:     []string{"region"},
+	datacenter: []string{"datacenter", "dc", "zone"},
+	rack:       []string{"rack"},
+}
+
+// minionTopology returns the failure domains of a minion: those it reported at registration,
+// or else the ones of its tags
+func minionTopology(info *pb.HostInfo) topology {
+	tags := info.GetTags()
+	return topology{
+		region:     topologyValue(info.GetRegion(), tags, topologyTags.region),
+		datacenter: topologyValue(info.GetDatacenter(), tags, topologyTags.datacenter),
+		rack:       topologyValue(info.GetRack(), tags, topologyTags.rack),
+	}
+}
+
+// topologyValue returns the reported failure domain, or else the value of the first tag set
+func topologyValue(reported string, tags map[string]string, keys []string) string {
+	for _, key := range keys {
+		if reported != "" {
+			break
+		}
+		reported = tags[key]
+	}
+	return reported
+}
+
+// matchesTopology reports whether a minion is in the failure domains set in selector
+func matchesTopology(info *pb.HostInfo, selector *pb.TopologySelector) bool {
+	if selector == nil {
+		return true
+	}
+	topo := minionTopology(info)
+	return (selector.Region == "" || selector.Region == topo.region) &&
+		(selector.Datacenter == "" || selector.Datacenter == topo.datacenter) &&
+		(selector.Rack == "" || selector.Rack == topo.rack)
+}
+
+// topologyLevels extract the failure domain of each level, from the widest to the narrowest
+var topologyLevels = []func(topology) string{
+	func(t topology) string { return t.region },
+	func(t topology) string { return t.datacenter },
+	func(t topology) string { return t.rack },
+}
+
+// spreadAcrossFailureDomains orders targets so that consecutive minions are in different failure
+// domains whenever possible: regions alternate, then the datacenters of each region, then their racks.
+// Minions of the same rack keep their order.
+func spreadAcrossFailureDomains(targets []string, topologyOf func(string) topology) []string {
+	topologies := make(map[string]topology, len(targets))
+	for _, minionID := range targets {
+		topologies[minionID] = topologyOf(minionID)
+	}
+	return spreadLevels(targets, topologies, topologyLevels)
+}
+
+// spreadLevels interleaves the minions of the failure domains of the first level, each domain
+// being itself spread across the next levels
+func spreadLevels(targets []string, topologies map[string]topology, levels []func(topology) string) []string {
+	if len(levels) == 0 || len(targets) < 2 {
+		return targets
+	}
+
+	groups := make(map[string][]string)
+	var domains []string
+	for _, minionID := range targets {
+		domain := levels[0](topologies[minionID])
+		if _, exists := groups[domain]; !exists {
+			domains = append(domains, domain)
+		}
+		groups[domain] = append(groups[domain], minionID)
+	}
+	if len(domains) == 1 {
+		return spreadLevels(targets, topologies, levels[1:])
+	}
+
+	sort.Strings(domains)
+	for _, domain := range domains {
+		groups[domain] = spreadLevels(groups[domain], topologies, levels[1:])
+	}
+
+	spread := make([]string, 0, len(targets))
+	for len(spread) < len(targets) {
+		for _, domain := range domains {
+			if group := groups[domain]; len(group) > 0 {
+				spread = append(spread, group[0])
+				groups[domain] = group[1:]
+			}
+		}
+	}
+	return spread
+}
+
+// spreadTargets orders the targets of a command across their failure domains
+func (s *Server) spreadTargets(targets []string) []string {
+	return spreadAcrossFailureDomains(targets, func(minionID string) topology {
+		if conn, exists := s.minionRegistry.GetConnection(minionID); exists {
+			return minionTopology(conn.GetInfo())
+		}
+		return topology{}
+	})
+}
@@ -0,0 +1,91 @@
+package nexus
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestMinionTopology(t *testing.T) {
+	// Reported failure domains win over the tags
+	info := &pb.HostInfo{Region: "eu-west-1", Tags: map[string]string{"region": "us-east-1", "dc": "eu-west-1a", "zone": "eu-west-1b", "rack": "r12"}}
+	expected := topology{region: "eu-west-1", datacenter: "eu-west-1a", rack: "r12"}
+	if topo := minionTopology(info); topo != expected {
+		t.Errorf("Expected %+v, got %+v", expected, topo)
+	}
+
+	// Cloud minions fall back to their zone
+	info = &pb.HostInfo{Tags: map[string]string{"region": "eu-west-1", "zone": "eu-west-1b"}}
+	expected = topology{region: "eu-west-1", datacenter: "eu-west-1b"}
+	if topo := minionTopology(info); topo != expected {
+		t.Errorf("Expected %+v, got %+v", expected, topo)
+	}
+}
+
+func TestMatchesTopology(t *testing.T) {
+	info := &pb.HostInfo{Datacenter: "paris-1", Tags: map[string]string{"region": "eu", "rack": "r1"}}
+	tests := []struct {
+		selector *pb.TopologySelector
+		expected bool
+	}{
+		{nil, true},
+		{&pb.TopologySelector{Datacenter: "paris-1"}, true},
+		{&pb.TopologySelector{Region: "eu", Rack: "r1"}, true},
+		{&pb.TopologySelector{Datacenter: "paris-2"}, false},
+		{&pb.TopologySelector{Datacenter: "paris-1", Rack: "r2"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchesTopology(info, tt.selector); got != tt.expected {
+			t.Errorf("matchesTopology(%v) = %v, expected %v", tt.selector, got, tt.expected)
+		}
+	}
+}
+
+func TestSpreadAcrossFailureDomains(t *testing.T) {
+	topologies := map[string]topology{
+		"a1": {region: "eu", datacenter: "paris", rack: "r1"},
+		"a2": {region: "eu", datacenter: "paris", rack: "r1"},
+		"a3": {region: "eu", datacenter: "paris", rack: "r2"},
+		"b1": {region: "eu", datacenter: "berlin", rack: "r1"},
+		"c1": {region: "us", datacenter: "ohio", rack: "r1"},
+		"c2": {region: "us", datacenter: "ohio", rack: "r1"},
+	}
+	topologyOf := func(id string) topology { return topologies[id] }
+
+	spread := spreadAcrossFailureDomains([]string{"a1", "a2", "a3", "b1", "c1", "c2"}, topologyOf)
+	expected := []string{"b1", "c1", "a1", "c2", "a3", "a2"}
+	if !reflect.DeepEqual(spread, expected) {
+		t.Errorf("Expected %v, got %v", expected, spread)
+	}
+
+	// Minions without topology keep their order
+	targets := []string{"m3", "m1", "m2"}
+	if spread := spreadAcrossFailureDomains(targets, topologyOf); !reflect.DeepEqual(spread, targets) {
+		t.Errorf("Expected %v, got %v", targets, spread)
+	}
+}
+
+func TestFindTargetMinionsTopology(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: "minion-1", Datacenter: "paris", Tags: map[string]string{"env": "prod"}}}
+	registry.minions["minion-2"] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: "minion-2", Tags: map[string]string{"dc": "paris", "env": "staging"}}}
+	registry.minions["minion-3"] = &MinionConnectionImpl{Info: &pb.HostInfo{Id: "minion-3", Datacenter: "berlin", Tags: map[string]string{"env": "prod"}}}
+
+	targets := registry.FindTargetMinions(&pb.CommandRequest{Topology: &pb.TopologySelector{Datacenter: "paris"}})
+	sort.Strings(targets)
+	if !reflect.DeepEqual(targets, []string{"minion-1", "minion-2"}) {
+		t.Errorf("Expected the paris minions, got %v", targets)
+	}
+
+	// The topology narrows the tag selector
+	targets = registry.FindTargetMinions(&pb.CommandRequest{
+		TagSelector: &pb.TagSelector{Rules: []*pb.TagMatch{{Key: "env", Condition: &pb.TagMatch_Equals{Equals: "prod"}}}},
+		Topology:    &pb.TopologySelector{Datacenter: "paris"},
+	})
+	if !reflect.DeepEqual(targets, []string{"minion-1"}) {
+		t.Errorf("Expected minion-1, got %v", targets)
+	}
+}
@@ -0,0 +1,224 @@
+package nexus
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Events of a command timeline recorded by Nexus itself, the other ones being the minion statuses
+const (
+	TraceEventDispatched = "DISPATCHED"
+	TraceEventResult     = "RESULT"
+)
+
+// Components reported in the events of a command timeline
+const (
+	TraceComponentNexus  = "nexus"
+	TraceComponentMinion = "minion"
+)
+
+// maxTraceIDLength is the length of the trace_id columns
+const maxTraceIDLength = 64
+
+// CommandEvent is an event of the timeline of a traced command
+type CommandEvent struct {
+	TraceID   string
+	CommandID string
+	MinionID  string
+	Event     string // status reported by the minion, or TraceEventResult
+	Detail    string
+}
+
+// newTraceID generates a trace ID for the commands sent without one
+func newTraceID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// validTraceID reports whether a trace ID fits the trace_id columns and only uses letters, digits,
+// '.', '-' and '_'
+func validTraceID(traceID string) bool {
+	if len(traceID) > maxTraceIDLength {
+		return false
+	}
+	for _, c := range traceID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// untrustedTraceID returns the trace ID reported by a minion, or no trace ID when it is not valid,
+// so it can't fail the storage of the result or status it comes with
+func untrustedTraceID(traceID, commandID, minionID string, logger *zap.Logger) string {
+	if validTraceID(traceID) {
+		return traceID
+	}
+	logger.Warn("Ignoring invalid trace ID reported by minion",
+		zap.String("command_id", commandID),
+		zap.String("minion_id", minionID),
+		zap.Int("length", len(traceID)))
+	return ""
+}
+
+// ensureTraceID gives its trace ID to a command sent without one and returns a logger tagging
+// every line with it
+func ensureTraceID(cmd *pb.Command, logger *zap.Logger) *zap.Logger {
+	if cmd.TraceId == "" {
+		cmd.TraceId = newTraceID()
+	}
+	return traceLogger(logger, cmd.TraceId)
+}
+
+// traceLogger returns a logger tagging every line with the trace ID, when the command has one
+func traceLogger(logger *zap.Logger, traceID string) *zap.Logger {
+	if traceID == "" {
+		return logger
+	}
+	return logger.With(zap.String("trace_id", traceID))
+}
+
+// traceComponent returns the component an event of a command timeline comes from
+func traceComponent(event string) string {
+	if event == TraceEventDispatched || event == TraceEventResult {
+		return TraceComponentNexus
+	}
+	return TraceComponentMinion
+}
+
+// StoreCommandEvent records an event of the timeline of a traced command.
+func (d *DatabaseServiceImpl) StoreCommandEvent(ctx context.Context, event CommandEvent) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot record event %s of command %s", event.Event, event.CommandID)
+	}
+
+	_, err := d.db.ExecContext(ctx,
+		"INSERT INTO command_events (trace_id, command_id, minion_id, event, detail) VALUES ($1, $2, $3, $4, $5)",
+		event.TraceID, event.CommandID, event.MinionID, event.Event, nullIfEmpty(event.Detail))
+	if err != nil {
+		return fmt.Errorf("failed to record command event: %v", err)
+	}
+	return nil
+}
+
+// insertResultEvent records the result of a traced command in its timeline, within the transaction storing it
+func (d *DatabaseServiceImpl) insertResultEvent(ctx context.Context, tx *sql.Tx, result *pb.CommandResult) error {
+	if result.TraceId == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO command_events (trace_id, command_id, minion_id, event, detail) VALUES ($1, $2, $3, $4, $5)",
+		result.TraceId, result.CommandId, result.MinionId, TraceEventResult, fmt.Sprintf("exit code %d", result.ExitCode))
+	return err
+}
+
+// GetTrace retrieves the timeline of the commands sharing a trace ID, oldest event first: their
+// dispatch to each minion, the statuses the minions reported and the results Nexus received.
+// All the events are timed by Nexus, whose clock may differ from the minions' one.
+func (d *DatabaseServiceImpl) GetTrace(ctx context.Context, traceID string) ([]*pb.TraceEvent, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get trace %s", traceID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetTrace")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT event, command_id, minion_id, detail, (EXTRACT(EPOCH FROM ts) * 1000)::bigint FROM (
+			SELECT 'DISPATCHED' AS event, id AS command_id, host_id AS minion_id, command AS detail, timestamp AS ts, 0 AS seq
+			FROM commands WHERE trace_id = $1
+			UNION ALL
+			SELECT event, command_id, minion_id, COALESCE(detail, ''), timestamp, id
+			FROM command_events WHERE trace_id = $1
+		) events
+		ORDER BY ts, seq`,
+		traceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace: %v", err)
+	}
+	defer rows.Close()
+
+	var events []*pb.TraceEvent
+	for rows.Next() {
+		var event pb.TraceEvent
+		if err := rows.Scan(&event.Event, &event.CommandId, &event.MinionId, &event.Detail, &event.TimestampMs); err != nil {
+			return nil, fmt.Errorf("failed to scan trace event: %v", err)
+		}
+		event.Detail = d.decryptPayload(event.Detail, event.CommandId, event.MinionId)
+		event.Component = traceComponent(event.Event)
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading trace: %v", err)
+	}
+
+	logger.Debug("Retrieved trace",
+		zap.String("trace_id", traceID),
+		zap.Int("count", len(events)))
+	return events, nil
+}
+
+// recordStatusEvent records the status reported by a minion in the timeline of its traced command
+func (s *Server) recordStatusEvent(ctx context.Context, statusUpdate *pb.CommandStatusUpdate, logger *zap.Logger) {
+	if statusUpdate.TraceId == "" {
+		return
+	}
+	event := CommandEvent{
+		TraceID:   statusUpdate.TraceId,
+		CommandID: statusUpdate.CommandId,
+		MinionID:  statusUpdate.MinionId,
+		Event:     statusUpdate.Status,
+	}
+	if err := s.dbService.StoreCommandEvent(ctx, event); err != nil {
+		logger.Warn("Failed to record the status in the command trace",
+			zap.String("command_id", statusUpdate.CommandId),
+			zap.String("minion_id", statusUpdate.MinionId),
+			zap.Error(err))
+	}
+}
+
+// GetTrace returns the timeline of the commands sharing a trace ID, within the console's namespaces
+func (s *Server) GetTrace(ctx context.Context, req *pb.TraceRequest) (*pb.Trace, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetTrace")
+	defer logging.FuncExit(logger, start)
+
+	if req.TraceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "trace ID is required")
+	}
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "command traces require a database")
+	}
+
+	events, err := s.dbService.GetTrace(ctx, req.TraceId)
+	if err != nil {
+		logger.Error("Failed to get trace", zap.String("trace_id", req.TraceId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get trace")
+	}
+
+	// Namespace scoped consoles only see the events of their minions
+	if scope := consoleScope(ctx); scope.restricted() {
+		visible := events[:0]
+		for _, event := range events {
+			if s.checkMinionScope(ctx, event.MinionId) == nil {
+				visible = append(visible, event)
+			}
+		}
+		events = visible
+	}
+	if len(events) == 0 {
+		return nil, status.Errorf(codes.NotFound, "trace %s not found", req.TraceId)
+	}
+	return &pb.Trace{TraceId: req.TraceId, Events: events}, nil
+}
@@ -0,0 +1,166 @@
+package nexus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSendCommandTraceID(t *testing.T) {
+	server := createTestServer(nil)
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: map[string]string{}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(10),
+	}
+
+	// Nexus traces the commands sent without a trace ID
+	response, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime"},
+	})
+	if err != nil || !response.Accepted || len(response.TraceId) != 32 {
+		t.Fatalf("Expected a generated trace ID, got %+v (%v)", response, err)
+	}
+
+	// The trace ID of the console reaches the minion
+	response, err = server.SendCommand(context.Background(), &pb.CommandRequest{
+		MinionIds: []string{"minion-1"},
+		Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime", TraceId: "trace-1"},
+	})
+	if err != nil || response.TraceId != "trace-1" {
+		t.Fatalf("Expected trace-1, got %+v (%v)", response, err)
+	}
+	queue := registry.minions["minion-1"].Commands
+	queue.Pop()
+	if cmd, ok := queue.Pop(); !ok || cmd.TraceId != "trace-1" {
+		t.Errorf("Expected the dispatched command to carry trace-1, got %v", cmd)
+	}
+
+	// Trace IDs not fitting the trace_id columns are rejected
+	for _, traceID := range []string{strings.Repeat("a", maxTraceIDLength+1), "trace 1", "trace\n1"} {
+		if _, err := server.SendCommand(context.Background(), &pb.CommandRequest{
+			MinionIds: []string{"minion-1"},
+			Command:   &pb.Command{Type: pb.CommandType_SYSTEM, Payload: "uptime", TraceId: traceID},
+		}); err == nil {
+			t.Errorf("Expected trace ID %q to be rejected", traceID)
+		}
+	}
+}
+
+func TestInvalidMinionTraceIDIgnored(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	// An oversized trace ID reported by a minion neither fails its status nor its result
+	mock.ExpectExec("UPDATE commands SET status").WithArgs("RECEIVED", "cmd-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	server.handleStatusUpdate(context.Background(), &pb.CommandStatusUpdate{CommandId: "cmd-1", MinionId: "minion-1", Status: "RECEIVED", TraceId: strings.Repeat("t", 100)}, zap.NewNop())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WithArgs("cmd-1", "minion-1", int32(0), "ok", "", nil, nil, sqlmock.AnyArg(), false, nil, int32(1), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE commands SET status").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: "ok", Timestamp: time.Now().Unix(), TraceId: strings.Repeat("t", 100)}
+	if err := server.handleCommandResult(context.Background(), result, zap.NewNop()); err != nil {
+		t.Fatalf("handleCommandResult failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTraceEventsRecorded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	// Statuses of traced commands are recorded in their timeline
+	mock.ExpectExec("UPDATE commands SET status").WithArgs("RECEIVED", "cmd-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO command_events").WithArgs("trace-1", "cmd-1", "minion-1", "RECEIVED", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	server.handleStatusUpdate(context.Background(), &pb.CommandStatusUpdate{CommandId: "cmd-1", MinionId: "minion-1", Status: "RECEIVED", TraceId: "trace-1"}, zap.NewNop())
+
+	// So are their results, with the result itself
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("INSERT INTO command_results").
+		WithArgs("cmd-1", "minion-1", int32(2), "", "boom", nil, nil, sqlmock.AnyArg(), false, "trace-1", int32(1), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE commands SET status").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO command_events").WithArgs("trace-1", "cmd-1", "minion-1", TraceEventResult, "exit code 2").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	result := &pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", ExitCode: 2, Stderr: "boom", Timestamp: time.Now().Unix(), TraceId: "trace-1"}
+	if err := server.dbService.StoreCommandResult(context.Background(), result); err != nil {
+		t.Fatalf("StoreCommandResult failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetTrace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+	server := createTestServer(db)
+
+	mock.ExpectQuery("SELECT event, command_id, minion_id, detail").WithArgs("trace-1").
+		WillReturnRows(sqlmock.NewRows([]string{"event", "command_id", "minion_id", "detail", "ts"}).
+			AddRow("DISPATCHED", "cmd-1", "minion-1", "uptime", int64(1700000000000)).
+			AddRow("RECEIVED", "cmd-1", "minion-1", "", int64(1700000000120)).
+			AddRow("RESULT", "cmd-1", "minion-1", "exit code 0", int64(1700000000480)))
+
+	trace, err := server.GetTrace(context.Background(), &pb.TraceRequest{TraceId: "trace-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(trace.Events) != 3 {
+		t.Fatalf("Expected 3 events, got %v", trace.Events)
+	}
+	expected := []string{TraceComponentNexus, TraceComponentMinion, TraceComponentNexus}
+	for i, event := range trace.Events {
+		if event.Component != expected[i] {
+			t.Errorf("Expected event %s from %s, got %s", event.Event, expected[i], event.Component)
+		}
+	}
+
+	// Unknown traces are not found
+	mock.ExpectQuery("SELECT event, command_id, minion_id, detail").WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"event", "command_id", "minion_id", "detail", "ts"}))
+	if _, err := server.GetTrace(context.Background(), &pb.TraceRequest{TraceId: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := server.GetTrace(context.Background(), &pb.TraceRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without trace ID, got %v", err)
+	}
+	if _, err := createTestServer(nil).GetTrace(context.Background(), &pb.TraceRequest{TraceId: "trace-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
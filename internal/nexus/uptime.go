@@ -0,0 +1,240 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Connection events recorded when a minion command stream opens and ends
+const (
+	ConnectionEventConnect    = "CONNECT"
+	ConnectionEventDisconnect = "DISCONNECT"
+)
+
+// Windows of GetMinionUptime
+const (
+	defaultUptimeWindow = 24 * time.Hour
+	maxUptimeWindow     = 90 * 24 * time.Hour
+)
+
+// unrecordedDisconnect is the reason of the periods whose end was not recorded, e.g. when Nexus stopped
+const unrecordedDisconnect = "no disconnect recorded"
+
+// ConnectionEvent is the opening or the end of the command stream of a minion
+type ConnectionEvent struct {
+	MinionID  string
+	Event     string // ConnectionEventConnect or ConnectionEventDisconnect
+	Reason    string // why the stream ended
+	Timestamp time.Time
+}
+
+// recordConnectionEvent persists the opening or the end of the command stream of a minion.
+// The event outlives the stream, so it is not stored with the stream context.
+func (s *Server) recordConnectionEvent(minionID, event string, err error) {
+	// The rows of retired hosts were archived
+	if s.dbService == nil || s.retired.pending(minionID, time.Now()) != nil {
+		return
+	}
+
+	connectionEvent := &ConnectionEvent{MinionID: minionID, Event: event, Timestamp: time.Now()}
+	if err != nil {
+		connectionEvent.Reason = err.Error()
+	}
+	if err := s.dbService.StoreConnectionEvent(context.Background(), connectionEvent); err != nil {
+		s.logger.Error("Failed to store connection event",
+			zap.String("minion_id", minionID),
+			zap.String("event", event),
+			zap.Error(err))
+	}
+}
+
+// StoreConnectionEvent records the opening or the end of the command stream of a minion.
+func (d *DatabaseServiceImpl) StoreConnectionEvent(ctx context.Context, event *ConnectionEvent) error {
+	if d == nil || d.db == nil {
+		return fmt.Errorf("database service unavailable - cannot store connection event for %s", event.MinionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.StoreConnectionEvent")
+	defer logging.FuncExit(logger, start)
+
+	_, err := d.db.ExecContext(ctx,
+		"INSERT INTO connection_events (host_id, event, reason, timestamp) VALUES ($1, $2, $3, $4)",
+		event.MinionID, event.Event, event.Reason, event.Timestamp)
+	if err != nil {
+		logger.Error("Failed to insert connection event", zap.String("host_id", event.MinionID))
+		return fmt.Errorf("failed to insert connection event: %v", err)
+	}
+
+	logger.Debug("Connection event stored", zap.String("host_id", event.MinionID), zap.String("event", event.Event))
+	return nil
+}
+
+// GetConnectionEvents retrieves the connection events of a minion since since, oldest first, preceded
+// by the last event before since which tells whether the minion was connected at the start of the window.
+func (d *DatabaseServiceImpl) GetConnectionEvents(ctx context.Context, minionID string, since time.Time) ([]*ConnectionEvent, error) {
+	if d == nil || d.db == nil {
+		return nil, fmt.Errorf("database service unavailable - cannot get connection events of minion %s", minionID)
+	}
+
+	logger, start := logging.FuncLogger(d.logger, "DatabaseServiceImpl.GetConnectionEvents")
+	defer logging.FuncExit(logger, start)
+
+	rows, err := d.reader().QueryContext(ctx,
+		`SELECT event, COALESCE(reason, ''), timestamp
+		FROM connection_events
+		WHERE host_id = $1 AND timestamp >= COALESCE(
+			(SELECT MAX(timestamp) FROM connection_events WHERE host_id = $1 AND timestamp < $2), $2)
+		ORDER BY timestamp, id`,
+		minionID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query connection events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []*ConnectionEvent
+	for rows.Next() {
+		event := &ConnectionEvent{MinionID: minionID}
+		if err := rows.Scan(&event.Event, &event.Reason, &event.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan connection event: %v", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading connection events: %v", err)
+	}
+
+	logger.Debug("Retrieved connection events",
+		zap.String("minion_id", minionID),
+		zap.Int("count", len(events)))
+	return events, nil
+}
+
+// connectionPeriods turns the connection events of a minion into the periods it was connected between
+// since and until, oldest first, with the share of the window they cover. A minion still connected has
+// a last period without end; the end of a period that was not recorded is the next connection, or the
+// last time the minion was seen when it has no open stream anymore.
+func connectionPeriods(events []*ConnectionEvent, since, until time.Time, connected bool, lastSeen time.Time) ([]*pb.ConnectionPeriod, float64) {
+	type period struct {
+		start, end time.Time
+		reason     string
+	}
+
+	var periods []period
+	var connectedAt *time.Time
+	for i, event := range events {
+		switch event.Event {
+		case ConnectionEventConnect:
+			if connectedAt != nil {
+				periods = append(periods, period{*connectedAt, event.Timestamp, unrecordedDisconnect})
+			}
+			connectedAt = &event.Timestamp
+		case ConnectionEventDisconnect:
+			if connectedAt == nil {
+				// Events recorded since the start of the window begin with a disconnection
+				// when the minion connected before they were recorded
+				if i > 0 || event.Timestamp.Before(since) {
+					continue
+				}
+				connectedAt = &since
+			}
+			periods = append(periods, period{*connectedAt, event.Timestamp, event.Reason})
+			connectedAt = nil
+		}
+	}
+	if connectedAt != nil {
+		if connected {
+			periods = append(periods, period{start: *connectedAt})
+		} else {
+			periods = append(periods, period{*connectedAt, maxTime(lastSeen, *connectedAt), unrecordedDisconnect})
+		}
+	}
+
+	var result []*pb.ConnectionPeriod
+	var up time.Duration
+	for _, p := range periods {
+		start, end := maxTime(p.start, since), p.end
+		if end.IsZero() || end.After(until) {
+			end = until
+		}
+		if !end.After(start) {
+			continue
+		}
+		up += end.Sub(start)
+
+		connectionPeriod := &pb.ConnectionPeriod{ConnectedAt: start.Unix(), Reason: p.reason}
+		if !p.end.IsZero() {
+			connectionPeriod.DisconnectedAt = end.Unix()
+		}
+		result = append(result, connectionPeriod)
+	}
+
+	window := until.Sub(since)
+	if window <= 0 {
+		return result, 0
+	}
+	return result, float64(up) * 100 / float64(window)
+}
+
+// maxTime returns the latest of two times
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// GetMinionUptime returns the periods a minion was connected over a window ending now, with its uptime percentage
+func (s *Server) GetMinionUptime(ctx context.Context, req *pb.MinionUptimeRequest) (*pb.MinionUptime, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetMinionUptime")
+	defer logging.FuncExit(logger, start)
+
+	if req.MinionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "minion ID is required")
+	}
+	if req.WindowSeconds < 0 {
+		return nil, status.Error(codes.InvalidArgument, "window must be positive")
+	}
+	if s.dbService == nil {
+		return nil, status.Error(codes.Unavailable, "minion uptime requires a database")
+	}
+	if consoleScope(ctx).restricted() {
+		if err := s.checkMinionScope(ctx, req.MinionId); err != nil {
+			return nil, err
+		}
+	}
+
+	window := time.Duration(req.WindowSeconds) * time.Second
+	if window == 0 {
+		window = defaultUptimeWindow
+	}
+	if window > maxUptimeWindow {
+		window = maxUptimeWindow
+	}
+	until := time.Now()
+	since := until.Add(-window)
+
+	events, err := s.dbService.GetConnectionEvents(ctx, req.MinionId, since)
+	if err != nil {
+		logger.Error("Failed to get connection events", zap.String("minion_id", req.MinionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get minion uptime")
+	}
+
+	registry := s.GetMinionRegistryImpl()
+	lastSeen, _ := registry.LastSeen(req.MinionId)
+	periods, uptime := connectionPeriods(events, since, until, registry.HasStream(req.MinionId), lastSeen)
+	return &pb.MinionUptime{
+		MinionId:      req.MinionId,
+		Since:         since.Unix(),
+		Until:         until.Unix(),
+		UptimePercent: uptime,
+		Periods:       periods,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package nexus
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectionPeriods(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+	until := since.Add(10 * time.Hour)
+	at := func(hours float64) time.Time { return since.Add(time.Duration(hours * float64(time.Hour))) }
+	event := func(name string, hours float64, reason string) *ConnectionEvent {
+		return &ConnectionEvent{MinionID: "minion-1", Event: name, Reason: reason, Timestamp: at(hours)}
+	}
+
+	// Connected before the window, out for 2 hours, then connected until now
+	periods, uptime := connectionPeriods([]*ConnectionEvent{
+		event(ConnectionEventConnect, -5, ""),
+		event(ConnectionEventDisconnect, 3, "EOF"),
+		event(ConnectionEventConnect, 5, ""),
+	}, since, until, true, until)
+	if uptime != 80 {
+		t.Errorf("Expected 80%% uptime, got %v", uptime)
+	}
+	if len(periods) != 2 ||
+		periods[0].ConnectedAt != since.Unix() || periods[0].DisconnectedAt != at(3).Unix() || periods[0].Reason != "EOF" ||
+		periods[1].ConnectedAt != at(5).Unix() || periods[1].DisconnectedAt != 0 {
+		t.Errorf("Unexpected periods: %v", periods)
+	}
+
+	// Disconnected before the window
+	periods, uptime = connectionPeriods([]*ConnectionEvent{
+		event(ConnectionEventDisconnect, -1, "EOF"),
+		event(ConnectionEventConnect, 9, ""),
+		event(ConnectionEventDisconnect, 9.5, "EOF"),
+	}, since, until, false, until)
+	if uptime != 5 || len(periods) != 1 {
+		t.Errorf("Expected a single period and 5%% uptime, got %v %v", periods, uptime)
+	}
+
+	// Disconnections not recorded end at the next connection or when the minion was last seen
+	periods, uptime = connectionPeriods([]*ConnectionEvent{
+		event(ConnectionEventConnect, 1, ""),
+		event(ConnectionEventConnect, 2, ""),
+	}, since, until, false, at(4))
+	if math.Abs(uptime-30) > 1e-9 || len(periods) != 2 ||
+		periods[0].Reason != unrecordedDisconnect || periods[1].DisconnectedAt != at(4).Unix() {
+		t.Errorf("Unexpected periods without disconnection: %v %v", periods, uptime)
+	}
+
+	// Events recorded since the start of the window may begin with a disconnection
+	periods, uptime = connectionPeriods([]*ConnectionEvent{event(ConnectionEventDisconnect, 1, "EOF")}, since, until, false, at(1))
+	if uptime != 10 || len(periods) != 1 || periods[0].ConnectedAt != since.Unix() {
+		t.Errorf("Unexpected periods starting with a disconnection: %v %v", periods, uptime)
+	}
+
+	if periods, uptime := connectionPeriods(nil, since, until, false, time.Time{}); len(periods) != 0 || uptime != 0 {
+		t.Errorf("Expected no uptime without events, got %v %v", periods, uptime)
+	}
+}
+
+func TestGetMinionUptime(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	defer db.Close()
+
+	server := createTestServer(db)
+
+	// Connected before the window until 12 hours ago
+	disconnectedAt := time.Now().Add(-12 * time.Hour)
+	mock.ExpectQuery("SELECT event, COALESCE\\(reason, ''\\), timestamp").WithArgs("minion-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"event", "reason", "timestamp"}).
+			AddRow(ConnectionEventConnect, "", disconnectedAt.Add(-24*time.Hour)).
+			AddRow(ConnectionEventDisconnect, "EOF", disconnectedAt))
+
+	uptime, err := server.GetMinionUptime(context.Background(), &pb.MinionUptimeRequest{MinionId: "minion-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if uptime.Until-uptime.Since != int64(defaultUptimeWindow.Seconds()) {
+		t.Errorf("Expected the default window, got %d-%d", uptime.Since, uptime.Until)
+	}
+	if len(uptime.Periods) != 1 || uptime.Periods[0].Reason != "EOF" || math.Abs(uptime.UptimePercent-50) > 0.1 {
+		t.Errorf("Unexpected uptime: %v", uptime)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+
+	if _, err := server.GetMinionUptime(context.Background(), &pb.MinionUptimeRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without minion ID, got %v", err)
+	}
+	if _, err := server.GetMinionUptime(context.Background(), &pb.MinionUptimeRequest{MinionId: "minion-1", WindowSeconds: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument with a negative window, got %v", err)
+	}
+	if _, err := createTestServer(nil).GetMinionUptime(context.Background(), &pb.MinionUptimeRequest{MinionId: "minion-1"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable without database, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package nexus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/arhuman/minexus/internal/logging"
+	"github.com/arhuman/minexus/internal/version"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+// Policies applied to the minions registering with a protocol version below the minimum
+const (
+	OutdatedReject = "reject" // the registration is refused, the default
+	OutdatedFlag   = "flag"   // the minion registers, flagged outdated in minion lists
+)
+
+// ValidateOutdatedPolicy checks that policy is an outdated minion policy
+func ValidateOutdatedPolicy(policy string) error {
+	if policy != OutdatedReject && policy != OutdatedFlag {
+		return fmt.Errorf("invalid outdated minion policy '%s', must be '%s' or '%s'", policy, OutdatedReject, OutdatedFlag)
+	}
+	return nil
+}
+
+// versionPolicy is the minimum protocol version of the minions and what happens to those below it
+type versionPolicy struct {
+	minProtocol int32 // 0: every minion is accepted
+	reject      bool  // refuse outdated minions rather than flag them
+}
+
+// outdated reports whether a minion speaks a protocol version below the minimum
+func (p versionPolicy) outdated(info *pb.HostInfo) bool {
+	return info.ProtocolVersion < p.minProtocol
+}
+
+// check returns the error refusing the registration of an outdated minion under the reject policy
+func (p versionPolicy) check(info *pb.HostInfo) error {
+	if !p.reject || !p.outdated(info) {
+		return nil
+	}
+	return fmt.Errorf("minion protocol version %d is below the minimum %d required by Nexus, upgrade the minion",
+		info.ProtocolVersion, p.minProtocol)
+}
+
+// SetMinimumProtocol sets the protocol version below which minions are refused or flagged, per
+// policy OutdatedReject or OutdatedFlag. Minions predating version reporting speak protocol 0.
+func (s *Server) SetMinimumProtocol(minimum int32, policy string) error {
+	if err := ValidateOutdatedPolicy(policy); err != nil {
+		return err
+	}
+	if minimum < 0 || minimum > version.ProtocolVersion {
+		return fmt.Errorf("invalid minimum protocol version %d, must be between 0 and %d", minimum, version.ProtocolVersion)
+	}
+	s.versions = versionPolicy{minProtocol: minimum, reject: policy == OutdatedReject}
+	return nil
+}
+
+// GetFleetVersions counts the minions running each build in the ConsoleService and lists those
+// flagged below the minimum protocol version
+func (s *Server) GetFleetVersions(ctx context.Context, _ *pb.Empty) (*pb.FleetVersions, error) {
+	logger, start := logging.FuncLogger(s.logger, "Nexus.GetFleetVersions")
+	defer logging.FuncExit(logger, start)
+
+	list, err := s.ListMinions(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := &pb.FleetVersions{
+		Total:                int32(len(list.Minions)),
+		NexusVersion:         version.Short(),
+		NexusProtocolVersion: version.ProtocolVersion,
+		MinProtocolVersion:   s.versions.minProtocol,
+	}
+	counts := make(map[string]*pb.VersionCount)
+	for _, minion := range list.Minions {
+		key := fmt.Sprintf("%s\x00%s\x00%d", minion.Version, minion.BuildCommit, minion.ProtocolVersion)
+		count, exists := counts[key]
+		if !exists {
+			count = &pb.VersionCount{Version: minion.Version, BuildCommit: minion.BuildCommit, ProtocolVersion: minion.ProtocolVersion}
+			counts[key] = count
+			fleet.Versions = append(fleet.Versions, count)
+		}
+		count.Minions++
+		if minion.Outdated {
+			fleet.Outdated = append(fleet.Outdated, minion)
+		}
+	}
+	sort.Slice(fleet.Versions, func(i, j int) bool {
+		a, b := fleet.Versions[i], fleet.Versions[j]
+		if a.Minions != b.Minions {
+			return a.Minions > b.Minions
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.BuildCommit < b.BuildCommit
+	})
+	sort.Slice(fleet.Outdated, func(i, j int) bool {
+		return fleet.Outdated[i].Id < fleet.Outdated[j].Id
+	})
+
+	logger.Debug("Computed fleet versions",
+		zap.Int32("total", fleet.Total),
+		zap.Int("versions", len(fleet.Versions)),
+		zap.Int("outdated", len(fleet.Outdated)))
+	return fleet, nil
+}
@@ -0,0 +1,96 @@
+package nexus
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arhuman/minexus/internal/version"
+	pb "github.com/arhuman/minexus/protogen"
+)
+
+func TestMinimumProtocol(t *testing.T) {
+	server := createTestServer(nil)
+	ctx := context.Background()
+
+	// Every minion is accepted by default, Nexus announcing its protocol version
+	resp, err := server.Register(ctx, &pb.HostInfo{Id: "legacy-1"})
+	if err != nil || !resp.Success || resp.ProtocolVersion != version.ProtocolVersion {
+		t.Fatalf("Expected a legacy minion accepted, got %v, %v", resp, err)
+	}
+
+	if err := server.SetMinimumProtocol(1, OutdatedReject); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp, err = server.Register(ctx, &pb.HostInfo{Id: "legacy-2"})
+	if err != nil || resp.Success || !strings.Contains(resp.ErrorMessage, "below the minimum 1") {
+		t.Errorf("Expected an outdated minion rejected, got %v, %v", resp, err)
+	}
+	if _, exists := server.minionRegistry.GetConnection("legacy-2"); exists {
+		t.Error("Expected the rejected minion not registered")
+	}
+	resp, err = server.Register(ctx, &pb.HostInfo{Id: "current-1", Version: "v1.2.0", BuildCommit: "abc123", ProtocolVersion: 1})
+	if err != nil || !resp.Success {
+		t.Errorf("Expected a current minion accepted, got %v, %v", resp, err)
+	}
+
+	if err := server.SetMinimumProtocol(1, OutdatedFlag); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp, err = server.Register(ctx, &pb.HostInfo{Id: "legacy-2"})
+	if err != nil || !resp.Success {
+		t.Fatalf("Expected an outdated minion accepted under the flag policy, got %v, %v", resp, err)
+	}
+	list, err := server.ListMinions(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, minion := range list.Minions {
+		if minion.Outdated != strings.HasPrefix(minion.Id, "legacy") {
+			t.Errorf("Unexpected outdated flag for %s: %v", minion.Id, minion.Outdated)
+		}
+		if minion.Id == "current-1" && (minion.Version != "v1.2.0" || minion.BuildCommit != "abc123" || minion.ProtocolVersion != 1) {
+			t.Errorf("Expected the build of the minion listed, got %v", minion)
+		}
+	}
+
+	for _, tt := range []struct {
+		minimum int32
+		policy  string
+	}{{1, "ignore"}, {-1, OutdatedFlag}, {version.ProtocolVersion + 1, OutdatedReject}} {
+		if err := server.SetMinimumProtocol(tt.minimum, tt.policy); err == nil {
+			t.Errorf("Expected minimum %d with policy %q rejected", tt.minimum, tt.policy)
+		}
+	}
+}
+
+func TestGetFleetVersions(t *testing.T) {
+	server := createTestServer(nil)
+	if err := server.SetMinimumProtocol(1, OutdatedFlag); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, info := range []*pb.HostInfo{
+		{Id: "minion-1", Version: "v1.2.0", BuildCommit: "abc123", ProtocolVersion: 1},
+		{Id: "minion-2", Version: "v1.2.0", BuildCommit: "abc123", ProtocolVersion: 1},
+		{Id: "minion-3", Version: "v1.3.0", BuildCommit: "def456", ProtocolVersion: 1},
+		{Id: "minion-4"},
+	} {
+		if resp, err := server.Register(context.Background(), info); err != nil || !resp.Success {
+			t.Fatalf("Failed to register %s: %v, %v", info.Id, resp, err)
+		}
+	}
+
+	fleet, err := server.GetFleetVersions(context.Background(), &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fleet.Total != 4 || fleet.MinProtocolVersion != 1 || fleet.NexusProtocolVersion != version.ProtocolVersion {
+		t.Errorf("Unexpected summary: %v", fleet)
+	}
+	if len(fleet.Versions) != 3 || fleet.Versions[0].Version != "v1.2.0" || fleet.Versions[0].Minions != 2 || fleet.Versions[1].Version != "" {
+		t.Errorf("Expected the builds counted, most common first, got %v", fleet.Versions)
+	}
+	if len(fleet.Outdated) != 1 || fleet.Outdated[0].Id != "minion-4" {
+		t.Errorf("Expected the legacy minion outdated, got %v", fleet.Outdated)
+	}
+}
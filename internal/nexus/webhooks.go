@@ -0,0 +1,329 @@
+package nexus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// WebhookFormatJSON posts the completion event as a JSON document
+	WebhookFormatJSON = "json"
+	// WebhookFormatSlack posts a Slack-compatible {"text": "..."} message
+	WebhookFormatSlack = "slack"
+
+	defaultWebhookRetries    = 3
+	defaultWebhookRetryDelay = time.Second
+	defaultWebhookTimeout    = 10 * time.Second
+	webhookQueueSize         = 1000
+	webhookOutputLimit       = 1024
+)
+
+// WebhookTarget describes an endpoint notified when commands complete
+type WebhookTarget struct {
+	URL      string            `json:"url"`
+	Format   string            `json:"format,omitempty"`   // "json" (default) or "slack"
+	Tags     map[string]string `json:"tags,omitempty"`     // only notify for minions carrying all these tags
+	Commands []string          `json:"commands,omitempty"` // only notify for these command families or names
+	Retries  int               `json:"retries,omitempty"`  // delivery attempts after the first one
+	// HostChanges also notifies the target when the environment fingerprint of a minion changes
+	HostChanges bool `json:"host_changes,omitempty"`
+}
+
+// CompletionEvent is the notification sent when a minion reports a command result
+type CompletionEvent struct {
+	CommandID string            `json:"command_id"`
+	MinionID  string            `json:"minion_id"`
+	Command   string            `json:"command"`
+	ExitCode  int32             `json:"exit_code"`
+	Stdout    string            `json:"stdout"`
+	Stderr    string            `json:"stderr"`
+	Timestamp int64             `json:"timestamp"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// HostChangeEvent is the notification sent when the environment fingerprint of a minion changes
+type HostChangeEvent struct {
+	Event          string            `json:"event"` // always "host_change"
+	MinionID       string            `json:"minion_id"`
+	OldFingerprint string            `json:"old_fingerprint"`
+	NewFingerprint string            `json:"new_fingerprint"`
+	Changes        []string          `json:"changes"`
+	Timestamp      int64             `json:"timestamp"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// webhookEvent is a queued notification, exactly one field is set
+type webhookEvent struct {
+	completion *CompletionEvent
+	hostChange *HostChangeEvent
+}
+
+// LoadWebhookTargets reads webhook targets from a JSON file
+func LoadWebhookTargets(path string) ([]WebhookTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook configuration: %w", err)
+	}
+
+	var targets []WebhookTarget
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook configuration: %w", err)
+	}
+
+	for i := range targets {
+		if targets[i].URL == "" {
+			return nil, fmt.Errorf("webhook target %d: url is required", i)
+		}
+		if targets[i].Format == "" {
+			targets[i].Format = WebhookFormatJSON
+		}
+		if targets[i].Format != WebhookFormatJSON && targets[i].Format != WebhookFormatSlack {
+			return nil, fmt.Errorf("webhook target %d: invalid format '%s', use 'json' or 'slack'", i, targets[i].Format)
+		}
+		if targets[i].Retries <= 0 {
+			targets[i].Retries = defaultWebhookRetries
+		}
+	}
+
+	return targets, nil
+}
+
+// Matches reports whether the target wants to be notified of event
+func (t *WebhookTarget) Matches(event *CompletionEvent) bool {
+	if !t.matchesTags(event.Tags) {
+		return false
+	}
+
+	if len(t.Commands) == 0 {
+		return true
+	}
+
+	name, family := commandFamily(event.Command)
+	for _, filter := range t.Commands {
+		if filter == name || filter == family {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesHostChange reports whether the target wants to be notified of a host change
+func (t *WebhookTarget) MatchesHostChange(event *HostChangeEvent) bool {
+	return t.HostChanges && t.matchesTags(event.Tags)
+}
+
+// matchesTags reports whether tags carry all the tags required by the target
+func (t *WebhookTarget) matchesTags(tags map[string]string) bool {
+	for key, value := range t.Tags {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// commandFamily returns the command name and its family ("file:get /x" -> "file:get", "file")
+func commandFamily(payload string) (string, string) {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	name := fields[0]
+	if idx := strings.Index(name, ":"); idx > 0 {
+		return name, name[:idx]
+	}
+	// Plain shell commands belong to the shell family
+	return name, "shell"
+}
+
+// WebhookNotifier delivers completion events to webhook targets in the background
+type WebhookNotifier struct {
+	targets    []WebhookTarget
+	client     *http.Client
+	queue      chan webhookEvent
+	retryDelay time.Duration
+	logger     *zap.Logger
+	wg         sync.WaitGroup
+	stopOnce   sync.Once
+}
+
+// NewWebhookNotifier creates a notifier for the given targets
+func NewWebhookNotifier(targets []WebhookTarget, logger *zap.Logger) *WebhookNotifier {
+	return &WebhookNotifier{
+		targets:    targets,
+		client:     &http.Client{Timeout: defaultWebhookTimeout},
+		queue:      make(chan webhookEvent, webhookQueueSize),
+		retryDelay: defaultWebhookRetryDelay,
+		logger:     logger,
+	}
+}
+
+// Start launches the delivery worker
+func (n *WebhookNotifier) Start() {
+	n.wg.Add(1)
+	go n.run()
+}
+
+// Stop drains pending events and stops the delivery worker
+func (n *WebhookNotifier) Stop() {
+	n.stopOnce.Do(func() {
+		close(n.queue)
+	})
+	n.wg.Wait()
+}
+
+// Notify queues an event for delivery without blocking the caller.
+// Events are dropped when the queue is full.
+func (n *WebhookNotifier) Notify(event *CompletionEvent) {
+	select {
+	case n.queue <- webhookEvent{completion: event}:
+	default:
+		n.logger.Warn("Webhook queue full, dropping completion event",
+			zap.String("command_id", event.CommandID),
+			zap.String("minion_id", event.MinionID))
+	}
+}
+
+// NotifyHostChange queues a host change event for delivery without blocking the caller.
+// Events are dropped when the queue is full.
+func (n *WebhookNotifier) NotifyHostChange(event *HostChangeEvent) {
+	select {
+	case n.queue <- webhookEvent{hostChange: event}:
+	default:
+		n.logger.Warn("Webhook queue full, dropping host change event",
+			zap.String("minion_id", event.MinionID))
+	}
+}
+
+// run delivers queued events until the queue is closed
+func (n *WebhookNotifier) run() {
+	defer n.wg.Done()
+
+	for event := range n.queue {
+		for i := range n.targets {
+			target := &n.targets[i]
+			var body []byte
+			var err error
+			var commandID, minionID string
+			switch {
+			case event.completion != nil && target.Matches(event.completion):
+				body, err = n.buildPayload(target, event.completion)
+				commandID, minionID = event.completion.CommandID, event.completion.MinionID
+			case event.hostChange != nil && target.MatchesHostChange(event.hostChange):
+				body, err = n.buildHostChangePayload(target, event.hostChange)
+				minionID = event.hostChange.MinionID
+			default:
+				continue
+			}
+
+			if err != nil {
+				n.logger.Error("Failed to build webhook payload", zap.String("url", target.URL), zap.Error(err))
+				continue
+			}
+			n.deliver(target, body, commandID, minionID)
+		}
+	}
+}
+
+// deliver posts body to target, retrying with exponential backoff
+func (n *WebhookNotifier) deliver(target *WebhookTarget, body []byte, commandID, minionID string) {
+	logger, start := logging.FuncLogger(n.logger, "WebhookNotifier.deliver")
+	defer logging.FuncExit(logger, start)
+
+	var err error
+	delay := n.retryDelay
+	for attempt := 0; attempt <= target.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		if err = n.post(target.URL, body); err == nil {
+			logger.Debug("Webhook delivered",
+				zap.String("url", target.URL),
+				zap.String("command_id", commandID),
+				zap.String("minion_id", minionID),
+				zap.Int("attempt", attempt+1))
+			return
+		}
+
+		logger.Warn("Webhook delivery failed",
+			zap.String("url", target.URL),
+			zap.String("command_id", commandID),
+			zap.Int("attempt", attempt+1),
+			zap.Error(err))
+	}
+
+	logger.Error("Webhook delivery abandoned after retries",
+		zap.String("url", target.URL),
+		zap.String("command_id", commandID),
+		zap.String("minion_id", minionID),
+		zap.Error(err))
+}
+
+// post sends body to url and checks for a 2xx response
+func (n *WebhookNotifier) post(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// buildPayload encodes event in the target format
+func (n *WebhookNotifier) buildPayload(target *WebhookTarget, event *CompletionEvent) ([]byte, error) {
+	if target.Format == WebhookFormatSlack {
+		status := "succeeded"
+		if event.ExitCode != 0 {
+			status = fmt.Sprintf("failed (exit code %d)", event.ExitCode)
+		}
+		text := fmt.Sprintf("Command `%s` on minion `%s` %s [%s]", event.Command, event.MinionID, status, event.CommandID)
+		if output := strings.TrimSpace(event.Stdout + event.Stderr); output != "" {
+			text += fmt.Sprintf("\n```%s```", output)
+		}
+		return json.Marshal(map[string]string{"text": text})
+	}
+	return json.Marshal(event)
+}
+
+// buildHostChangePayload encodes a host change event in the target format
+func (n *WebhookNotifier) buildHostChangePayload(target *WebhookTarget, event *HostChangeEvent) ([]byte, error) {
+	if target.Format == WebhookFormatSlack {
+		text := fmt.Sprintf("Host fingerprint of minion `%s` changed: %s", event.MinionID, strings.Join(event.Changes, ", "))
+		return json.Marshal(map[string]string{"text": text})
+	}
+	return json.Marshal(event)
+}
+
+// truncateOutput limits command output sent in notifications, cutting it on a rune boundary
+func truncateOutput(output string) string {
+	if len(output) <= webhookOutputLimit {
+		return output
+	}
+	return truncateUTF8(output, webhookOutputLimit) + "...(truncated)"
+}
@@ -0,0 +1,224 @@
+package nexus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+)
+
+func TestLoadWebhookTargets(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("valid", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.json")
+		content := `[{"url": "http://example.com/hook"}, {"url": "http://example.com/slack", "format": "slack", "retries": 5}]`
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		targets, err := LoadWebhookTargets(path)
+		if err != nil {
+			t.Fatalf("LoadWebhookTargets failed: %v", err)
+		}
+		if len(targets) != 2 {
+			t.Fatalf("Expected 2 targets, got %d", len(targets))
+		}
+		if targets[0].Format != WebhookFormatJSON || targets[0].Retries != defaultWebhookRetries {
+			t.Errorf("Expected defaults to be applied, got %+v", targets[0])
+		}
+		if targets[1].Format != WebhookFormatSlack || targets[1].Retries != 5 {
+			t.Errorf("Unexpected second target: %+v", targets[1])
+		}
+	})
+
+	invalid := map[string]string{
+		"missing_url":    `[{"format": "json"}]`,
+		"invalid_format": `[{"url": "http://example.com", "format": "xml"}]`,
+		"invalid_json":   `{not json`,
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".json")
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadWebhookTargets(path); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestWebhookTargetMatches(t *testing.T) {
+	event := &CompletionEvent{
+		Command: "file:get /etc/hosts",
+		Tags:    map[string]string{"env": "prod", "role": "web"},
+	}
+
+	tests := []struct {
+		name     string
+		target   WebhookTarget
+		expected bool
+	}{
+		{"no_filters", WebhookTarget{}, true},
+		{"matching_tag", WebhookTarget{Tags: map[string]string{"env": "prod"}}, true},
+		{"non_matching_tag", WebhookTarget{Tags: map[string]string{"env": "dev"}}, false},
+		{"matching_family", WebhookTarget{Commands: []string{"file"}}, true},
+		{"matching_name", WebhookTarget{Commands: []string{"file:get"}}, true},
+		{"non_matching_command", WebhookTarget{Commands: []string{"system", "shell"}}, false},
+		{"tag_and_command", WebhookTarget{Tags: map[string]string{"role": "web"}, Commands: []string{"file"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.Matches(event); got != tt.expected {
+				t.Errorf("Matches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	shellEvent := &CompletionEvent{Command: "uptime"}
+	shellTarget := WebhookTarget{Commands: []string{"shell"}}
+	if !shellTarget.Matches(shellEvent) {
+		t.Error("Expected plain commands to belong to the shell family")
+	}
+}
+
+func TestWebhookNotifierDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var received []CompletionEvent
+	var slackText string
+	var failures int32
+
+	jsonServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt to exercise retries
+		if atomic.AddInt32(&failures, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var event CompletionEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer jsonServer.Close()
+
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		slackText = body["text"]
+		mu.Unlock()
+	}))
+	defer slackServer.Close()
+
+	notifier := NewWebhookNotifier([]WebhookTarget{
+		{URL: jsonServer.URL, Format: WebhookFormatJSON, Retries: 2},
+		{URL: slackServer.URL, Format: WebhookFormatSlack, Retries: 1, Tags: map[string]string{"env": "prod"}},
+	}, zap.NewNop())
+	notifier.retryDelay = time.Millisecond
+	notifier.Start()
+
+	notifier.Notify(&CompletionEvent{CommandID: "cmd-1", MinionID: "minion-1", Command: "uptime", ExitCode: 1, Tags: map[string]string{"env": "prod"}})
+	notifier.Notify(&CompletionEvent{CommandID: "cmd-2", MinionID: "minion-2", Command: "uptime", Tags: map[string]string{"env": "dev"}})
+	notifier.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 JSON deliveries, got %d", len(received))
+	}
+	if received[0].CommandID != "cmd-1" || received[0].ExitCode != 1 {
+		t.Errorf("Unexpected first event: %+v", received[0])
+	}
+	if slackText == "" || !containsAll(slackText, "cmd-1", "minion-1", "failed") {
+		t.Errorf("Unexpected slack message: %q", slackText)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	if got := truncateOutput("ok"); got != "ok" {
+		t.Errorf("Expected short output unchanged, got %q", got)
+	}
+
+	// A multi-byte rune straddling the limit is dropped, not split
+	output := strings.Repeat("a", webhookOutputLimit-1) + "é" + "tail"
+	got := truncateOutput(output)
+	if !utf8.ValidString(got) || got != strings.Repeat("a", webhookOutputLimit-1)+"...(truncated)" {
+		t.Errorf("Expected the output cut before the rune, got %q", got[len(got)-20:])
+	}
+}
+
+func TestNotifyCompletion(t *testing.T) {
+	var mu sync.Mutex
+	var received []CompletionEvent
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event CompletionEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		received = append(received, event)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	server := createTestServer(nil)
+	notifier := NewWebhookNotifier([]WebhookTarget{{URL: webhook.URL, Format: WebhookFormatJSON, Retries: 1}}, zap.NewNop())
+	notifier.Start()
+	server.SetNotifier(notifier)
+
+	registry := server.GetMinionRegistryImpl()
+	registry.minions["minion-1"] = &MinionConnectionImpl{
+		Info:     &pb.HostInfo{Id: "minion-1", Tags: map[string]string{"env": "prod"}},
+		LastSeen: time.Now(),
+		Commands: NewCommandQueue(100),
+	}
+
+	server.trackCommand("cmd-1", "system:info", []string{"minion-1"})
+	server.notifyCompletion(&pb.CommandResult{CommandId: "cmd-1", MinionId: "minion-1", Stdout: "ok"})
+	// Secrets are redacted from the notifications like from the stored commands and results
+	server.trackCommand("cmd-2", "mysql --password=hunter2 -e 'select 1'", []string{"minion-1"})
+	server.notifyCompletion(&pb.CommandResult{CommandId: "cmd-2", MinionId: "minion-1", Stdout: "DB_PASSWORD=hunter2", Stderr: "token: abc123"})
+	notifier.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(received))
+	}
+	if received[0].Command != "system:info" || received[0].Tags["env"] != "prod" || received[0].Stdout != "ok" {
+		t.Errorf("Unexpected event: %+v", received[0])
+	}
+	for _, field := range []string{received[1].Command, received[1].Stdout, received[1].Stderr} {
+		if strings.Contains(field, "hunter2") || strings.Contains(field, "abc123") || !strings.Contains(field, redactedValue) {
+			t.Errorf("Expected the secrets redacted from the notification, got %q", field)
+		}
+	}
+	if _, tracked := server.pendingCommands["cmd-1"]; tracked {
+		t.Error("Expected command to be untracked once all results are received")
+	}
+}
+
+func containsAll(s string, parts ...string) bool {
+	for _, part := range parts {
+		if !strings.Contains(s, part) {
+			return false
+		}
+	}
+	return true
+}
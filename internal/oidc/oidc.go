@@ -0,0 +1,385 @@
+// Package oidc verifies the OpenID Connect ID tokens consoles present to Nexus, tying console access
+// to the single sign-on of an organization.
+//
+// The keys of the issuer are discovered from its /.well-known/openid-configuration document and
+// cached, then fetched again when a token is signed with an unknown key. Tokens must be signed with
+// RS256, RS384, RS512, ES256, ES384 or ES512, be issued by the issuer for the audience, and be valid.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keysTTL is the time the keys of the issuer are used before being fetched again
+	keysTTL = time.Hour
+	// keysRefreshInterval is the shortest interval between two fetches of the keys, bounding the
+	// fetches tokens signed with unknown keys trigger
+	keysRefreshInterval = time.Minute
+	// clockSkew is the difference tolerated between the clocks of the issuer and Nexus
+	clockSkew = time.Minute
+	// maxDocumentSize bounds the discovery and key set documents read from the issuer
+	maxDocumentSize = 1 << 20
+)
+
+// Claims are the claims of a verified token
+type Claims map[string]any
+
+// String returns the string claim name, empty when missing or not a string
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Strings returns the claim name as a list of strings, a single string claim being a list of one
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Verifier verifies the tokens of an issuer for an audience
+type Verifier struct {
+	issuer   string
+	audience string
+	client   *http.Client
+	now      func() time.Time
+
+	refresh sync.Mutex // held while fetching the keys
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey // keys of the issuer by key ID
+	fetched time.Time                   // when the keys were fetched, zero before the first fetch
+}
+
+// NewVerifier creates a verifier of the tokens of issuer for audience, client fetching the keys of
+// the issuer (http.DefaultClient when nil)
+func NewVerifier(issuer, audience string, client *http.Client) *Verifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Verifier{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		client:   client,
+		now:      time.Now,
+	}
+}
+
+// header is the JOSE header of a token
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verify checks the signature, issuer, audience and validity period of a token, returning its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var hdr header
+	if err := decodeSegment(parts[0], &hdr); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+	hash, err := algorithmHash(hdr.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	key, err := v.key(ctx, hdr.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	digest := hash.New()
+	digest.Write([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(key, hdr.Algorithm, hash, digest.Sum(nil), signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate checks the issuer, audience and validity period of the claims of a token
+func (v *Verifier) validate(claims Claims) error {
+	if strings.TrimSuffix(claims.String("iss"), "/") != v.issuer {
+		return fmt.Errorf("token issued by %q, expected %q", claims.String("iss"), v.issuer)
+	}
+	audienceFound := false
+	for _, audience := range claims.Strings("aud") {
+		audienceFound = audienceFound || audience == v.audience
+	}
+	if !audienceFound {
+		return fmt.Errorf("token not issued for audience %q", v.audience)
+	}
+
+	now := v.now()
+	expiry, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token without expiry")
+	}
+	if now.After(time.Unix(int64(expiry), 0).Add(clockSkew)) {
+		return errors.New("token expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return errors.New("token not valid yet")
+	}
+	return nil
+}
+
+// key returns the key of the issuer with ID kid, fetching the keys when they expired or kid is
+// unknown, at most once every keysRefreshInterval. The keys are fetched outside v.mu so that a slow
+// issuer only holds up the verifications needing the new keys.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, known, fetched := v.cached(kid)
+	if known && v.now().Sub(fetched) < keysTTL {
+		return key, nil
+	}
+	if !v.refresh.TryLock() {
+		if known {
+			// Keep using the expired key while another verification fetches the keys
+			return key, nil
+		}
+		v.refresh.Lock()
+	}
+	defer v.refresh.Unlock()
+
+	// The keys may have been fetched while waiting for the refresh
+	now := v.now()
+	key, known, fetched = v.cached(kid)
+	if known && now.Sub(fetched) < keysTTL {
+		return key, nil
+	}
+	if fetched.IsZero() || now.Sub(fetched) >= keysRefreshInterval {
+		keys, err := v.fetchKeys(ctx)
+		if err != nil {
+			if known {
+				// Keep using the expired keys while the issuer is unreachable
+				return key, nil
+			}
+			return nil, fmt.Errorf("failed to fetch the keys of %s: %w", v.issuer, err)
+		}
+		v.mu.Lock()
+		v.keys, v.fetched = keys, now
+		v.mu.Unlock()
+		key, known, _ = v.cached(kid)
+	}
+	if !known {
+		return nil, fmt.Errorf("token signed with unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// cached returns the key with ID kid among the keys fetched, and when they were fetched
+func (v *Verifier) cached(kid string) (crypto.PublicKey, bool, time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key, known := v.lookup(kid)
+	return key, known, v.fetched
+}
+
+// lookup returns the key with ID kid, the only key of the issuer matching tokens without key ID
+func (v *Verifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, exists := v.keys[kid]
+	return key, exists
+}
+
+// fetchKeys discovers the key set of the issuer and returns its RSA and EC signing keys by key ID
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.fetch(ctx, v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != v.issuer {
+		return nil, fmt.Errorf("discovery document of issuer %q", discovery.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document without jwks_uri")
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.fetch(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of other types or curves are skipped, tokens signed with them being rejected
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+// fetch decodes the JSON document at url
+func (v *Verifier) fetch(ctx context.Context, url string, document any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(document); err != nil {
+		return fmt.Errorf("invalid document %s: %w", url, err)
+	}
+	return nil
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`   // RSA modulus
+	E       string `json:"e"`   // RSA exponent
+	Curve   string `json:"crv"` // EC curve
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// publicKey returns the RSA or EC public key of the JSON Web Key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC key not on its curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// algorithmHash returns the hash of a signature algorithm, rejecting unsigned and HMAC tokens
+func algorithmHash(algorithm string) (crypto.Hash, error) {
+	switch algorithm {
+	case "RS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "ES512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported token algorithm %q", algorithm)
+}
+
+// verifySignature checks the signature of digest with the key the algorithm requires
+func verifySignature(key crypto.PublicKey, algorithm string, hash crypto.Hash, digest, signature []byte) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if algorithm[:2] == "RS" && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		// ES signatures are the big-endian r and s, each the size of the curve
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if algorithm[:2] == "ES" && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("invalid token signature")
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeInt decodes a base64url big-endian integer of a JSON Web Key
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issuer is a fake OpenID Connect issuer signing tokens with an RSA and an EC key
+type issuer struct {
+	server     *httptest.Server
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	keyFetches int
+	block      chan struct{} // holds the key fetches until closed, when set
+}
+
+func newIssuer(t *testing.T) *issuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	iss := &issuer{rsaKey: rsaKey, ecKey: ecKey}
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": iss.server.URL, "jwks_uri": iss.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		if iss.block != nil {
+			<-iss.block
+		}
+		iss.keyFetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "oct", "kid": "hmac-1", "k": "c2VjcmV0"},
+		}})
+	})
+	iss.server = httptest.NewTLSServer(mux)
+	t.Cleanup(iss.server.Close)
+	return iss
+}
+
+// sign returns a token of claims signed with the key kid
+func (iss *issuer) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	encodeJSON := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encodeJSON(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeJSON(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	iss := newIssuer(t)
+	verifier := NewVerifier(iss.server.URL+"/", "minexus", iss.server.Client())
+	now := time.Unix(1700000000, 0)
+	verifier.now = func() time.Time { return now }
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{"iss": iss.server.URL, "aud": []string{"minexus", "other"}, "exp": now.Add(time.Hour).Unix(), "sub": "1234", "email": "alice@example.com", "groups": []string{"web", "db"}}
+		for name, value := range changes {
+			if value == nil {
+				delete(c, name)
+			} else {
+				c[name] = value
+			}
+		}
+		return c
+	}
+	ctx := context.Background()
+
+	verified, err := verifier.Verify(ctx, iss.sign(t, "RS256", "rsa-1", claims(nil)))
+	if err != nil {
+		t.Fatalf("Expected a valid RS256 token, got %v", err)
+	}
+	if verified.String("email") != "alice@example.com" || strings.Join(verified.Strings("groups"), ",") != "web,db" || verified.Strings("missing") != nil {
+		t.Errorf("Unexpected claims: %v", verified)
+	}
+	if _, err := verifier.Verify(ctx, iss.sign(t, "ES256", "ec-1", claims(map[string]any{"aud": "minexus"}))); err != nil {
+		t.Errorf("Expected a valid ES256 token, got %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		token    string
+		expected string
+	}{
+		"expired":        {iss.sign(t, "RS256", "rsa-1", claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()})), "expired"},
+		"no expiry":      {iss.sign(t, "RS256", "rsa-1", claims(map[string]any{"exp": nil})), "without expiry"},
+		"not yet valid":  {iss.sign(t, "RS256", "rsa-1", claims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), "not valid yet"},
+		"other audience": {iss.sign(t, "RS256", "rsa-1", claims(map[string]any{"aud": "other"})), "audience"},
+		"other issuer":   {iss.sign(t, "RS256", "rsa-1", claims(map[string]any{"iss": "https://evil.example.com"})), "issued by"},
+		"unknown key":    {iss.sign(t, "RS256", "rsa-2", claims(nil)), "unknown key"},
+		"wrong key type": {iss.sign(t, "RS256", "ec-1", claims(nil)), "invalid token signature"},
+		"no signature":   {strings.Join(strings.Split(iss.sign(t, "RS256", "rsa-1", claims(nil)), ".")[:2], ".") + ".", "invalid token signature"},
+		"alg none":       {base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.", "unsupported token algorithm"},
+		"malformed":      {"not-a-token", "malformed"},
+	} {
+		if _, err := verifier.Verify(ctx, tc.token); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tc.expected, err)
+		}
+	}
+
+	// The keys are cached, unknown keys refetching them at most once a minute
+	if iss.keyFetches != 1 {
+		t.Errorf("Expected the keys fetched once, got %d fetches", iss.keyFetches)
+	}
+	now = now.Add(2 * time.Minute)
+	verifier.Verify(ctx, iss.sign(t, "RS256", "rsa-2", claims(nil)))
+	if iss.keyFetches != 2 {
+		t.Errorf("Expected the keys fetched again for an unknown key, got %d fetches", iss.keyFetches)
+	}
+}
+
+func TestVerifyDuringKeyFetch(t *testing.T) {
+	iss := newIssuer(t)
+	verifier := NewVerifier(iss.server.URL, "minexus", iss.server.Client())
+	now := time.Unix(1700000000, 0)
+	verifier.now = func() time.Time { return now }
+	token := func(kid string) string {
+		return iss.sign(t, "RS256", kid, map[string]any{"iss": iss.server.URL, "aud": "minexus", "exp": now.Add(time.Hour).Unix()})
+	}
+	ctx := context.Background()
+	if _, err := verifier.Verify(ctx, token("rsa-1")); err != nil {
+		t.Fatalf("Expected a valid token, got %v", err)
+	}
+
+	// A token signed with an unknown key holds a fetch of the keys at the issuer
+	now = now.Add(2 * time.Minute)
+	iss.block = make(chan struct{})
+	fetched := make(chan error)
+	go func() {
+		_, err := verifier.Verify(ctx, token("rsa-2"))
+		fetched <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Tokens signed with the keys known are verified meanwhile
+	verified := make(chan error)
+	go func() {
+		_, err := verifier.Verify(ctx, token("rsa-1"))
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("Expected a valid token during the fetch, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the verification not to wait for the fetch of the keys")
+	}
+	close(iss.block)
+	if err := <-fetched; err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("Expected the unknown key refused, got %v", err)
+	}
+	if iss.keyFetches != 2 {
+		t.Errorf("Expected the keys fetched twice, got %d fetches", iss.keyFetches)
+	}
+}
@@ -0,0 +1,425 @@
+// Package relay implements a sub-Nexus serving the minions of a NAT'd or air-gapped
+// network segment. Downstream minions connect to the relay as they would to Nexus,
+// and the relay multiplexes their registrations, commands and results over a single
+// upstream RelayStream.
+package relay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/arhuman/minexus/internal/logging"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// registerTimeout bounds the wait for Nexus to answer a forwarded registration
+	registerTimeout = 10 * time.Second
+	// initialUpstreamDelay and maxUpstreamDelay bound the upstream reconnection backoff
+	initialUpstreamDelay = time.Second
+	maxUpstreamDelay     = 30 * time.Second
+)
+
+// downstreamMinion is the command stream of a minion connected to the relay
+type downstreamMinion struct {
+	stream pb.MinionService_StreamCommandsServer
+	sendMu sync.Mutex // stream.Send is not safe for concurrent use
+}
+
+func (d *downstreamMinion) send(msg *pb.CommandStreamMessage) error {
+	d.sendMu.Lock()
+	defer d.sendMu.Unlock()
+
+	return d.stream.Send(msg)
+}
+
+// Relay serves downstream minions and multiplexes them over an upstream RelayStream
+type Relay struct {
+	pb.UnimplementedMinionServiceServer
+
+	id       string
+	upstream pb.MinionServiceClient
+	logger   *zap.Logger
+
+	requestSeq uint64
+
+	sendMu sync.Mutex // serializes sends on the upstream stream
+
+	maxMsgSize int32 // largest message the relay accepts from minions, 0: the size announced by Nexus
+
+	mu         sync.Mutex
+	stream     pb.MinionService_RelayStreamClient // nil while disconnected from Nexus
+	pending    map[string]chan *pb.RegisterResponse
+	hosts      map[string]*pb.HostInfo // last registration of each minion, replayed on reconnection
+	downstream map[string]*downstreamMinion
+}
+
+// NewRelay creates a relay identified by id forwarding to the upstream Nexus client
+func NewRelay(id string, upstream pb.MinionServiceClient, logger *zap.Logger) *Relay {
+	return &Relay{
+		id:         id,
+		upstream:   upstream,
+		logger:     logger.With(zap.String("relay_id", id)),
+		pending:    make(map[string]chan *pb.RegisterResponse),
+		hosts:      make(map[string]*pb.HostInfo),
+		downstream: make(map[string]*downstreamMinion),
+	}
+}
+
+// SetMaxMsgSize sets the largest message the relay accepts from minions, announced to them in
+// place of the size of Nexus when smaller so that the chunks of their results pass the relay
+func (r *Relay) SetMaxMsgSize(size int) {
+	r.maxMsgSize = int32(size)
+}
+
+// Run maintains the upstream stream until ctx is canceled, reconnecting with exponential backoff
+func (r *Relay) Run(ctx context.Context) error {
+	logger, start := logging.FuncLogger(r.logger, "relay.Relay.Run")
+	defer logging.FuncExit(logger, start)
+
+	delay := initialUpstreamDelay
+	for {
+		connected, err := r.serveUpstream(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			delay = initialUpstreamDelay
+		}
+
+		logger.Warn("Upstream stream to Nexus lost",
+			zap.Error(err),
+			zap.Duration("retry_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxUpstreamDelay {
+			delay = maxUpstreamDelay
+		}
+	}
+}
+
+// serveUpstream opens the upstream stream and routes Nexus messages until it fails.
+// connected reports whether the stream was established.
+func (r *Relay) serveUpstream(ctx context.Context) (connected bool, err error) {
+	streamCtx := metadata.AppendToOutgoingContext(ctx, "relay-id", r.id)
+	stream, err := r.upstream.RelayStream(streamCtx)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	r.stream = stream
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.stream = nil
+		r.mu.Unlock()
+	}()
+
+	r.logger.Info("Upstream stream to Nexus established")
+	if err := r.resync(); err != nil {
+		return true, err
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return true, fmt.Errorf("stream closed by Nexus")
+		}
+		if err != nil {
+			return true, err
+		}
+		r.handleUpstream(msg)
+	}
+}
+
+// resync replays registrations and connections so a restarted Nexus, or a new
+// upstream stream, knows every minion behind the relay
+func (r *Relay) resync() error {
+	r.mu.Lock()
+	hosts := make([]*pb.HostInfo, 0, len(r.hosts))
+	for _, hostInfo := range r.hosts {
+		hosts = append(hosts, hostInfo)
+	}
+	connected := make([]string, 0, len(r.downstream))
+	for minionID := range r.downstream {
+		connected = append(connected, minionID)
+	}
+	r.mu.Unlock()
+
+	// Nexus handles messages in order, so registrations precede connections
+	for _, hostInfo := range hosts {
+		if err := r.sendUpstream(&pb.RelayMessage{
+			MinionId: hostInfo.Id,
+			Message:  &pb.RelayMessage_Register{Register: hostInfo},
+		}); err != nil {
+			return err
+		}
+	}
+	for _, minionID := range connected {
+		if err := r.sendUpstream(&pb.RelayMessage{
+			MinionId: minionID,
+			Message:  &pb.RelayMessage_Connected{Connected: true},
+		}); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Info("Relayed minions resynchronized with Nexus",
+		zap.Int("registered", len(hosts)),
+		zap.Int("connected", len(connected)))
+	return nil
+}
+
+// handleUpstream routes a message received from Nexus
+func (r *Relay) handleUpstream(msg *pb.RelayMessage) {
+	switch m := msg.Message.(type) {
+	case *pb.RelayMessage_Registered:
+		r.mu.Lock()
+		waiter, ok := r.pending[msg.RequestId]
+		r.mu.Unlock()
+		if ok {
+			waiter <- m.Registered
+		} else if !m.Registered.Success {
+			r.logger.Warn("Replayed registration rejected by Nexus",
+				zap.String("minion_id", msg.MinionId),
+				zap.String("error", m.Registered.ErrorMessage))
+		}
+	case *pb.RelayMessage_Stream:
+		if cmd := m.Stream.GetCommand(); cmd != nil {
+			r.deliver(msg.MinionId, cmd)
+		} else if ack := m.Stream.GetAck(); ack != nil {
+			r.acknowledge(msg.MinionId, ack)
+		}
+	}
+}
+
+// deliver forwards a command to a downstream minion, reporting a failed result when it is gone
+func (r *Relay) deliver(minionID string, cmd *pb.Command) {
+	r.mu.Lock()
+	d, ok := r.downstream[minionID]
+	r.mu.Unlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("minion disconnected from relay %s", r.id)
+	} else {
+		err = d.send(&pb.CommandStreamMessage{
+			Message: &pb.CommandStreamMessage_Command{Command: cmd},
+		})
+	}
+	if err == nil {
+		return
+	}
+
+	r.logger.Warn("Failed to deliver command to relayed minion",
+		zap.String("minion_id", minionID),
+		zap.String("command_id", cmd.Id),
+		zap.Error(err))
+	result := &pb.CommandResult{
+		CommandId: cmd.Id,
+		MinionId:  minionID,
+		ExitCode:  -1,
+		Stderr:    err.Error(),
+		Timestamp: time.Now().Unix(),
+	}
+	if err := r.sendUpstream(&pb.RelayMessage{
+		MinionId: minionID,
+		Message: &pb.RelayMessage_Stream{Stream: &pb.CommandStreamMessage{
+			Message: &pb.CommandStreamMessage_Result{Result: result},
+		}},
+	}); err != nil {
+		r.logger.Error("Failed to report undeliverable command", zap.Error(err))
+	}
+}
+
+// acknowledge forwards the acknowledgement of a result to a downstream minion. A minion gone
+// meanwhile sends the result again once reconnected.
+func (r *Relay) acknowledge(minionID string, ack *pb.ResultAck) {
+	r.mu.Lock()
+	d, ok := r.downstream[minionID]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if err := d.send(&pb.CommandStreamMessage{Message: &pb.CommandStreamMessage_Ack{Ack: ack}}); err != nil {
+		r.logger.Debug("Failed to forward result acknowledgement to relayed minion",
+			zap.String("minion_id", minionID),
+			zap.String("command_id", ack.CommandId),
+			zap.Error(err))
+	}
+}
+
+// Register forwards a downstream minion registration to Nexus and waits for the outcome
+func (r *Relay) Register(ctx context.Context, hostInfo *pb.HostInfo) (*pb.RegisterResponse, error) {
+	logger, start := logging.FuncLogger(r.logger, "relay.Relay.Register")
+	defer logging.FuncExit(logger, start)
+
+	requestID := fmt.Sprintf("%s-%d", r.id, atomic.AddUint64(&r.requestSeq, 1))
+	waiter := make(chan *pb.RegisterResponse, 1)
+	r.mu.Lock()
+	r.pending[requestID] = waiter
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, requestID)
+		r.mu.Unlock()
+	}()
+
+	if err := r.sendUpstream(&pb.RelayMessage{
+		MinionId:  hostInfo.Id,
+		RequestId: requestID,
+		Message:   &pb.RelayMessage_Register{Register: hostInfo},
+	}); err != nil {
+		logger.Warn("Cannot forward registration", zap.String("minion_id", hostInfo.Id), zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "relay is not connected to nexus")
+	}
+
+	timer := time.NewTimer(registerTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-waiter:
+		if resp.Success {
+			registered := proto.Clone(hostInfo).(*pb.HostInfo)
+			registered.Id = resp.AssignedId
+			// Nexus announcing no size doesn't reassemble chunks
+			if r.maxMsgSize > 0 && resp.MaxMsgSize > r.maxMsgSize {
+				resp.MaxMsgSize = r.maxMsgSize
+			}
+			r.mu.Lock()
+			r.hosts[resp.AssignedId] = registered
+			r.mu.Unlock()
+			logger.Info("Minion registered through relay", zap.String("minion_id", resp.AssignedId))
+		} else if resp.Retire != nil {
+			// A retired minion is no longer replayed to Nexus
+			r.mu.Lock()
+			delete(r.hosts, hostInfo.Id)
+			r.mu.Unlock()
+			logger.Info("Relayed minion retired", zap.String("minion_id", hostInfo.Id))
+		}
+		return resp, nil
+	case <-timer.C:
+		return nil, status.Error(codes.DeadlineExceeded, "nexus did not answer the registration")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// minionIDFromContext extracts the minion ID from gRPC metadata
+func minionIDFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get("minion-id")
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// StreamCommands serves the command stream of a downstream minion
+func (r *Relay) StreamCommands(stream pb.MinionService_StreamCommandsServer) error {
+	logger, start := logging.FuncLogger(r.logger, "relay.Relay.StreamCommands")
+	defer logging.FuncExit(logger, start)
+
+	minionID := minionIDFromContext(stream.Context())
+	if minionID == "" {
+		return status.Error(codes.Unauthenticated, "minion ID not provided")
+	}
+
+	d := &downstreamMinion{stream: stream}
+	r.mu.Lock()
+	// A reconnecting minion replaces its previous stream
+	r.downstream[minionID] = d
+	r.mu.Unlock()
+	logger.Info("Minion connected to relay", zap.String("minion_id", minionID))
+
+	// While Nexus is unreachable the connection is announced by the next resync
+	if err := r.sendUpstream(&pb.RelayMessage{
+		MinionId: minionID,
+		Message:  &pb.RelayMessage_Connected{Connected: true},
+	}); err != nil {
+		logger.Debug("Connection not forwarded to Nexus", zap.String("minion_id", minionID), zap.Error(err))
+	}
+
+	err := r.forwardDownstream(stream, minionID)
+
+	r.mu.Lock()
+	current := r.downstream[minionID] == d
+	if current {
+		delete(r.downstream, minionID)
+	}
+	r.mu.Unlock()
+
+	if current {
+		if sendErr := r.sendUpstream(&pb.RelayMessage{
+			MinionId: minionID,
+			Message:  &pb.RelayMessage_Disconnected{Disconnected: true},
+		}); sendErr != nil {
+			logger.Debug("Disconnection not forwarded to Nexus", zap.String("minion_id", minionID), zap.Error(sendErr))
+		}
+	}
+	logger.Info("Minion disconnected from relay", zap.String("minion_id", minionID), zap.Error(err))
+
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// forwardDownstream forwards results and status updates of a minion until its stream ends
+func (r *Relay) forwardDownstream(stream pb.MinionService_StreamCommandsServer, minionID string) error {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if msg.GetCommand() != nil {
+			continue
+		}
+
+		if err := r.sendUpstream(&pb.RelayMessage{
+			MinionId: minionID,
+			Message:  &pb.RelayMessage_Stream{Stream: msg},
+		}); err != nil {
+			r.logger.Warn("Dropping minion message, Nexus unreachable",
+				zap.String("minion_id", minionID),
+				zap.Error(err))
+		}
+	}
+}
+
+// sendUpstream sends a message to Nexus, failing while the upstream stream is down
+func (r *Relay) sendUpstream(msg *pb.RelayMessage) error {
+	r.mu.Lock()
+	stream := r.stream
+	r.mu.Unlock()
+	if stream == nil {
+		return fmt.Errorf("not connected to nexus")
+	}
+
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	return stream.Send(msg)
+}
@@ -0,0 +1,245 @@
+package relay
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeNexus accepts a single RelayStream and exposes its traffic through channels
+type fakeNexus struct {
+	pb.UnimplementedMinionServiceServer
+	relayIDs chan string
+	received chan *pb.RelayMessage
+	toRelay  chan *pb.RelayMessage
+
+	maxMsgSize int32 // announced in registration responses
+}
+
+func newFakeNexus() *fakeNexus {
+	return &fakeNexus{
+		relayIDs: make(chan string, 1),
+		received: make(chan *pb.RelayMessage, 10),
+		toRelay:  make(chan *pb.RelayMessage, 10),
+	}
+}
+
+func (f *fakeNexus) RelayStream(stream pb.MinionService_RelayStreamServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if values := md.Get("relay-id"); len(values) > 0 {
+		f.relayIDs <- values[0]
+	}
+
+	go func() {
+		for {
+			select {
+			case msg := <-f.toRelay:
+				if err := stream.Send(msg); err != nil {
+					return
+				}
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		// Answer registrations like Nexus does
+		if hostInfo := msg.GetRegister(); hostInfo != nil {
+			f.toRelay <- &pb.RelayMessage{
+				MinionId:  hostInfo.Id,
+				RequestId: msg.RequestId,
+				Message: &pb.RelayMessage_Registered{Registered: &pb.RegisterResponse{
+					Success:    true,
+					AssignedId: hostInfo.Id,
+					MaxMsgSize: f.maxMsgSize,
+				}},
+			}
+		}
+		f.received <- msg
+	}
+}
+
+func (f *fakeNexus) next(t *testing.T) *pb.RelayMessage {
+	t.Helper()
+	select {
+	case msg := <-f.received:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for relayed message")
+		return nil
+	}
+}
+
+// serve starts a gRPC server over an in-memory listener and returns a client connection to it
+func serve(t *testing.T, service pb.MinionServiceServer) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterMinionServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial in-memory server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRegisterWithoutUpstream(t *testing.T) {
+	relay := NewRelay("edge-1", nil, zap.NewNop())
+
+	if _, err := relay.Register(context.Background(), &pb.HostInfo{Id: "m1"}); err == nil {
+		t.Error("Expected registration to fail while Nexus is unreachable")
+	}
+}
+
+func TestRegisterMessageSize(t *testing.T) {
+	nexus := newFakeNexus()
+	nexus.maxMsgSize = 4096
+	relay := NewRelay("edge-1", pb.NewMinionServiceClient(serve(t, nexus)), zap.NewNop())
+	relay.SetMaxMsgSize(2048)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+	<-nexus.relayIDs
+
+	register := func(id string) *pb.RegisterResponse {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			resp, err := relay.Register(ctx, &pb.HostInfo{Id: id})
+			if err == nil {
+				nexus.next(t)
+				return resp
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Registration failed: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Minions split their results below the smallest size of the relay and Nexus
+	if resp := register("m1"); resp.MaxMsgSize != 2048 {
+		t.Errorf("Expected the relay size announced, got %d", resp.MaxMsgSize)
+	}
+	nexus.maxMsgSize = 1024
+	if resp := register("m2"); resp.MaxMsgSize != 1024 {
+		t.Errorf("Expected the Nexus size announced, got %d", resp.MaxMsgSize)
+	}
+	// A Nexus not reassembling chunks keeps results whole
+	nexus.maxMsgSize = 0
+	if resp := register("m3"); resp.MaxMsgSize != 0 {
+		t.Errorf("Expected no size announced, got %d", resp.MaxMsgSize)
+	}
+}
+
+func TestRelay(t *testing.T) {
+	nexus := newFakeNexus()
+	relay := NewRelay("edge-1", pb.NewMinionServiceClient(serve(t, nexus)), zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+
+	select {
+	case relayID := <-nexus.relayIDs:
+		if relayID != "edge-1" {
+			t.Errorf("Expected relay ID edge-1, got %q", relayID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Relay never connected upstream")
+	}
+
+	minion := pb.NewMinionServiceClient(serve(t, relay))
+
+	// Registration is forwarded upstream and answered with Nexus' response
+	var resp *pb.RegisterResponse
+	var err error
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err = minion.Register(ctx, &pb.HostInfo{Id: "m1", Hostname: "edge-host"})
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil || !resp.Success || resp.AssignedId != "m1" {
+		t.Fatalf("Expected successful registration, got %v (%v)", resp, err)
+	}
+	if msg := nexus.next(t); msg.GetRegister().GetHostname() != "edge-host" {
+		t.Errorf("Expected forwarded registration, got %v", msg)
+	}
+
+	// Opening the command stream is announced upstream
+	streamCtx := metadata.AppendToOutgoingContext(ctx, "minion-id", "m1")
+	stream, err := minion.StreamCommands(streamCtx)
+	if err != nil {
+		t.Fatalf("Failed to open command stream: %v", err)
+	}
+	if msg := nexus.next(t); msg.MinionId != "m1" || !msg.GetConnected() {
+		t.Fatalf("Expected connected message for m1, got %v", msg)
+	}
+
+	// Commands reach the minion
+	nexus.toRelay <- &pb.RelayMessage{
+		MinionId: "m1",
+		Message: &pb.RelayMessage_Stream{Stream: &pb.CommandStreamMessage{
+			Message: &pb.CommandStreamMessage_Command{Command: &pb.Command{Id: "cmd-1", Payload: "system:info"}},
+		}},
+	}
+	msg, err := stream.Recv()
+	if err != nil || msg.GetCommand().GetId() != "cmd-1" {
+		t.Fatalf("Expected command cmd-1, got %v (%v)", msg, err)
+	}
+
+	// Results reach Nexus tagged with the minion ID
+	if err := stream.Send(&pb.CommandStreamMessage{
+		Message: &pb.CommandStreamMessage_Result{Result: &pb.CommandResult{CommandId: "cmd-1", Stdout: "ok"}},
+	}); err != nil {
+		t.Fatalf("Failed to send result: %v", err)
+	}
+	if msg := nexus.next(t); msg.MinionId != "m1" || msg.GetStream().GetResult().GetCommandId() != "cmd-1" {
+		t.Fatalf("Expected result of cmd-1 from m1, got %v", msg)
+	}
+
+	// Commands for unknown minions fail back to Nexus
+	nexus.toRelay <- &pb.RelayMessage{
+		MinionId: "gone",
+		Message: &pb.RelayMessage_Stream{Stream: &pb.CommandStreamMessage{
+			Message: &pb.CommandStreamMessage_Command{Command: &pb.Command{Id: "cmd-2"}},
+		}},
+	}
+	result := nexus.next(t).GetStream().GetResult()
+	if result.GetCommandId() != "cmd-2" || result.GetExitCode() != -1 {
+		t.Errorf("Expected failed result for cmd-2, got %v", result)
+	}
+
+	// Closing the stream is announced upstream
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("Failed to close stream: %v", err)
+	}
+	if msg := nexus.next(t); msg.MinionId != "m1" || !msg.GetDisconnected() {
+		t.Errorf("Expected disconnected message for m1, got %v", msg)
+	}
+}
@@ -17,6 +17,10 @@ var (
 	BuildEnv = "unknown"
 )
 
+// ProtocolVersion is the version of the messages exchanged by minions and Nexus, incremented
+// when a change requires both to be upgraded. Minions predating it report 0.
+const ProtocolVersion = 1
+
 // Info returns detailed version information
 func Info() string {
 	return fmt.Sprintf("Version: %s, Commit: %s, Built: %s, BuildEnv: %s, Go: %s",
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+
+	pb "github.com/arhuman/minexus/protogen"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// consoleAPIPrefix is the path under which the ConsoleService is served as REST+JSON
+const consoleAPIPrefix = "/v1/"
+
+// openAPISpec describes the REST+JSON console API, generated from proto/minexus_gateway.yaml
+//
+//go:embed minexus.swagger.json
+var openAPISpec []byte
+
+// consoleAuthenticator authenticates consoles from the credentials of their calls, as Nexus does on
+// its console port
+type consoleAuthenticator interface {
+	AuthenticateConsole(ctx context.Context) (context.Context, error)
+}
+
+// consoleAPIHandler returns the REST+JSON gateway of the ConsoleService methods bound in
+// proto/minexus_gateway.yaml. Requests must present a client certificate verified by the web
+// server, or the bearer token of an Authorization header when the server accepts tokens, which
+// then scopes them exactly as a console connecting over gRPC.
+func (ws *WebServer) consoleAPIHandler(server pb.ConsoleServiceServer) (http.Handler, error) {
+	mux := runtime.NewServeMux()
+	if err := pb.RegisterConsoleServiceHandlerServer(context.Background(), mux, server); err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Nexus reads the console identity and namespaces from the gRPC peer and metadata
+		ctx := r.Context()
+		if r.TLS != nil {
+			ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *r.TLS}})
+		}
+		if authenticator, ok := server.(consoleAuthenticator); ok {
+			if authorization := r.Header.Get("Authorization"); authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+			}
+			authenticated, err := authenticator.AuthenticateConsole(ctx)
+			if err != nil {
+				ws.setJSONHeaders(w)
+				code := runtime.HTTPStatusFromCode(status.Code(err))
+				ws.writeJSONError(w, code, http.StatusText(code), status.Convert(err).Message())
+				return
+			}
+			ctx = authenticated
+		} else if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			ws.setJSONHeaders(w)
+			ws.writeJSONError(w, http.StatusUnauthorized, "Unauthorized", "A console client certificate is required")
+			return
+		}
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	}), nil
+}
+
+// handleOpenAPISpec serves the OpenAPI description of the REST+JSON console API
+func (ws *WebServer) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	ws.setJSONHeaders(w)
+	w.Write(openAPISpec)
+}
@@ -0,0 +1,189 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "github.com/arhuman/minexus/protogen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeConsoleService records the console identity of the requests it serves
+type fakeConsoleService struct {
+	pb.UnimplementedConsoleServiceServer
+	console     string
+	lastCommand *pb.CommandRequest
+	lastUpdate  *pb.UpdateTagsRequest
+}
+
+func (f *fakeConsoleService) identify(ctx context.Context) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			f.console = tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+		}
+	}
+}
+
+func (f *fakeConsoleService) ListMinions(ctx context.Context, _ *pb.Empty) (*pb.MinionList, error) {
+	f.identify(ctx)
+	return &pb.MinionList{Minions: []*pb.HostInfo{{Id: "minion-1", Hostname: "web-1"}}}, nil
+}
+
+func (f *fakeConsoleService) SendCommand(ctx context.Context, req *pb.CommandRequest) (*pb.CommandDispatchResponse, error) {
+	f.identify(ctx)
+	f.lastCommand = req
+	return &pb.CommandDispatchResponse{Accepted: true, CommandId: "cmd-1"}, nil
+}
+
+func (f *fakeConsoleService) UpdateTags(ctx context.Context, req *pb.UpdateTagsRequest) (*pb.Ack, error) {
+	f.identify(ctx)
+	f.lastUpdate = req
+	return &pb.Ack{Success: true}, nil
+}
+
+// consoleRequest returns a request presenting a verified console certificate with the given common name
+func consoleRequest(method, path, body, commonName string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return req
+}
+
+func TestConsoleAPI(t *testing.T) {
+	webServer := createTestWebServer()
+	service := &fakeConsoleService{}
+	handler, err := webServer.consoleAPIHandler(service)
+	if err != nil {
+		t.Fatalf("Failed to create console API: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/minions", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without client certificate, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, consoleRequest(http.MethodGet, "/v1/minions", "", "alice"))
+	var minions struct {
+		Minions []struct {
+			ID       string `json:"id"`
+			Hostname string `json:"hostname"`
+		} `json:"minions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &minions); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if len(minions.Minions) != 1 || minions.Minions[0].Hostname != "web-1" || service.console != "alice" {
+		t.Errorf("Unexpected minions %+v served to %q", minions, service.console)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, consoleRequest(http.MethodPost, "/v1/commands",
+		`{"minionIds": ["minion-1"], "command": {"payload": "uptime"}}`, "bob"))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"commandId":"cmd-1"`) {
+		t.Errorf("Unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if service.lastCommand.Command.Payload != "uptime" || service.lastCommand.MinionIds[0] != "minion-1" || service.console != "bob" {
+		t.Errorf("Unexpected command request %v from %q", service.lastCommand, service.console)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, consoleRequest(http.MethodPatch, "/v1/minions/minion-1/tags",
+		`{"add": {"env": "prod"}, "removeKeys": ["old"]}`, "alice"))
+	if w.Code != http.StatusOK || service.lastUpdate.MinionId != "minion-1" ||
+		service.lastUpdate.Add["env"] != "prod" || service.lastUpdate.RemoveKeys[0] != "old" {
+		t.Errorf("Unexpected tag update %d %v", w.Code, service.lastUpdate)
+	}
+
+	// Methods not implemented answer with the gRPC status mapped to HTTP
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, consoleRequest(http.MethodGet, "/v1/tags", "", "alice"))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected 501 for an unimplemented method, got %d", w.Code)
+	}
+}
+
+// tokenConsoleService authenticates consoles with a bearer token, as Nexus does with OpenID Connect
+type tokenConsoleService struct {
+	fakeConsoleService
+}
+
+type tokenIdentityKey struct{}
+
+func (f *tokenConsoleService) AuthenticateConsole(ctx context.Context) (context.Context, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		if md.Get("authorization")[0] != "Bearer alice-token" {
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials: token expired")
+		}
+		return context.WithValue(ctx, tokenIdentityKey{}, "alice@example.com"), nil
+	}
+	if _, ok := peer.FromContext(ctx); ok {
+		return ctx, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "a console client certificate or token is required")
+}
+
+func (f *tokenConsoleService) ListMinions(ctx context.Context, req *pb.Empty) (*pb.MinionList, error) {
+	f.console, _ = ctx.Value(tokenIdentityKey{}).(string)
+	return &pb.MinionList{}, nil
+}
+
+func TestConsoleAPIToken(t *testing.T) {
+	webServer := createTestWebServer()
+	service := &tokenConsoleService{}
+	handler, err := webServer.consoleAPIHandler(service)
+	if err != nil {
+		t.Fatalf("Failed to create console API: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/minions", nil)
+	req.Header.Set("Authorization", "Bearer alice-token")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || service.console != "alice@example.com" {
+		t.Errorf("Expected the request served to alice, got %d for %q", w.Code, service.console)
+	}
+
+	req.Header.Set("Authorization", "Bearer stolen-token")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "token expired") {
+		t.Errorf("Expected 401 for an invalid token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/minions", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", w.Code)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	webServer := createTestWebServer()
+	w := httptest.NewRecorder()
+	webServer.handleOpenAPISpec(w, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+
+	var spec struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Invalid OpenAPI description: %v", err)
+	}
+	for _, path := range []string{"/v1/minions", "/v1/tags", "/v1/minions/{minionId}/tags", "/v1/commands", "/v1/commands/{commandId}/results"} {
+		if _, found := spec.Paths[path]; !found {
+			t.Errorf("Expected %s in the OpenAPI description", path)
+		}
+	}
+}
@@ -50,8 +50,9 @@ type ServerInfo struct {
 
 // DatabaseStatus represents database connection status
 type DatabaseStatus struct {
-	Status string `json:"status"`
-	Host   string `json:"host"`
+	Status           string `json:"status"`
+	Host             string `json:"host"`
+	DuplicateResults int64  `json:"duplicate_results"` // results resent by minions and ignored
 }
 
 // MinionsResponse represents the API minions response
@@ -63,6 +64,7 @@ type MinionsResponse struct {
 // HealthResponse represents the API health response
 type HealthResponse struct {
 	Status    string `json:"status"`
+	Database  string `json:"database,omitempty"` // disabled, unavailable or ready
 	Timestamp string `json:"timestamp"`
 }
 
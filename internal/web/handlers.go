@@ -81,6 +81,57 @@ func (ws *WebServer) handleDownload(w http.ResponseWriter, r *http.Request) {
 	ws.serveBinaryFile(w, r, path)
 }
 
+// handleBootstrap serves the install script and minion binary of a bootstrap token:
+// /bootstrap/<token>/install.sh (install.ps1 on Windows) and /bootstrap/<token>/minion
+func (ws *WebServer) handleBootstrap(w http.ResponseWriter, r *http.Request) {
+	ws.setSecurityHeaders(w)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var provisioner *nexus.BootstrapProvisioner
+	if ws.nexus != nil {
+		provisioner = ws.nexus.BootstrapProvisioner()
+	}
+	if provisioner == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/bootstrap/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.Error(w, "Invalid bootstrap path. Expected format: /bootstrap/token/file", http.StatusBadRequest)
+		return
+	}
+	token, file := parts[0], parts[1]
+
+	switch file {
+	case "install.sh", "install.ps1":
+		script, err := provisioner.Script(token, file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(script))
+	case "minion":
+		grant, err := provisioner.Redeem(token)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		ws.logger.Info("Bootstrap binary download",
+			zap.String("platform", grant.Platform()),
+			zap.String("remote_addr", r.RemoteAddr))
+		ws.serveBinaryFile(w, r, "minion/"+grant.Platform())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
 // serveDownloadIndex serves the download index page
 func (ws *WebServer) serveDownloadIndex(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
@@ -155,6 +206,9 @@ func (ws *WebServer) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 			Host:   fmt.Sprintf("%s:%d", ws.config.DBHost, ws.config.DBPort),
 		},
 	}
+	if ws.nexus != nil {
+		response.Database.DuplicateResults = ws.nexus.DuplicateResults()
+	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		ws.logger.Error("Failed to encode status response", zap.Error(err))
@@ -197,6 +251,14 @@ func (ws *WebServer) handleAPIHealth(w http.ResponseWriter, r *http.Request) {
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
 
+	// Nexus serves minions while the database is unreachable, their registrations being buffered
+	if ws.nexus != nil {
+		response.Database = ws.nexus.DatabaseStatus()
+		if response.Database == nexus.DatabaseUnavailable {
+			response.Status = "degraded"
+		}
+	}
+
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		ws.logger.Error("Failed to encode health response", zap.Error(err))
 		ws.writeJSONError(w, http.StatusInternalServerError, "Internal Server Error", "Failed to encode response")
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/arhuman/minexus/internal/config"
+	"github.com/arhuman/minexus/internal/nexus"
 	"go.uber.org/zap"
 )
 
@@ -543,6 +545,52 @@ func TestValidPlatformNames(t *testing.T) {
 	}
 }
 
+func TestHandleBootstrap(t *testing.T) {
+	webServer := createTestWebServer()
+
+	get := func(path string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		webServer.handleBootstrap(w, req)
+		return w.Result()
+	}
+
+	// Bootstrap disabled
+	if resp := get("/bootstrap/token/install.sh"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without bootstrap, got %d", resp.StatusCode)
+	}
+
+	provisioner, _ := nexus.NewBootstrapProvisioner("https://nexus:8086", 11972)
+	webServer.nexus = &nexus.Server{}
+	webServer.nexus.SetBootstrapProvisioner(provisioner)
+	token, _, _ := provisioner.Issue("linux", "amd64", 0)
+
+	resp := get("/bootstrap/" + token + "/install.sh")
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "/bootstrap/"+token+"/minion") {
+		t.Errorf("Expected the install script, got %d: %s", resp.StatusCode, body)
+	}
+
+	testCases := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/bootstrap/" + token + "/install.ps1", http.StatusForbidden},
+		{"/bootstrap/unknown/install.sh", http.StatusForbidden},
+		{"/bootstrap/" + token + "/other", http.StatusNotFound},
+		{"/bootstrap/" + token, http.StatusBadRequest},
+		// The binary is not built in tests, the download still consumes the token
+		{"/bootstrap/" + token + "/minion", http.StatusNotFound},
+		{"/bootstrap/" + token + "/minion", http.StatusForbidden},
+		{"/bootstrap/" + token + "/install.sh", http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		if resp := get(tc.path); resp.StatusCode != tc.expectedStatus {
+			t.Errorf("Path %s: expected status %d, got %d", tc.path, tc.expectedStatus, resp.StatusCode)
+		}
+	}
+}
+
 // Integration test that actually starts HTTP server and tests content serving
 func TestWebServerIntegration(t *testing.T) {
 	webServer := createTestWebServer()
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,8 +11,9 @@ import (
 	"go.uber.org/zap"
 )
 
-// StartWebServer starts the HTTP web server
-func StartWebServer(cfg *config.NexusConfig, nexusServer *nexus.Server, logger *zap.Logger) error {
+// StartWebServer starts the web server, over HTTPS with tlsConfig when the REST+JSON console API is
+// enabled and over HTTP otherwise
+func StartWebServer(cfg *config.NexusConfig, nexusServer *nexus.Server, tlsConfig *tls.Config, logger *zap.Logger) error {
 	if !cfg.WebEnabled {
 		logger.Info("Web server disabled")
 		return nil
@@ -37,11 +39,27 @@ func StartWebServer(cfg *config.NexusConfig, nexusServer *nexus.Server, logger *
 	// Binary downloads
 	mux.HandleFunc("/download/", webServer.loggingMiddleware(webServer.handleDownload))
 
+	// One-time minion installs
+	mux.HandleFunc("/bootstrap/", webServer.loggingMiddleware(webServer.handleBootstrap))
+
 	// API endpoints
 	mux.HandleFunc("/api/status", webServer.loggingMiddleware(webServer.handleAPIStatus))
 	mux.HandleFunc("/api/minions", webServer.loggingMiddleware(webServer.handleAPIMinions))
 	mux.HandleFunc("/api/health", webServer.loggingMiddleware(webServer.handleAPIHealth))
 
+	// REST+JSON console API, authenticated by the console client certificates
+	if cfg.RESTAPI {
+		if tlsConfig == nil {
+			return fmt.Errorf("the REST API requires TLS")
+		}
+		consoleAPI, err := webServer.consoleAPIHandler(nexusServer)
+		if err != nil {
+			return fmt.Errorf("failed to create REST API: %w", err)
+		}
+		mux.Handle(consoleAPIPrefix, webServer.loggingMiddleware(consoleAPI.ServeHTTP))
+		mux.HandleFunc(consoleAPIPrefix+"openapi.json", webServer.loggingMiddleware(webServer.handleOpenAPISpec))
+	}
+
 	// Create HTTP server with appropriate timeouts
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.WebPort),
@@ -50,11 +68,18 @@ func StartWebServer(cfg *config.NexusConfig, nexusServer *nexus.Server, logger *
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if cfg.RESTAPI {
+		server.TLSConfig = tlsConfig
+	}
 
 	logger.Info("Web server starting with file system assets",
 		zap.Int("port", cfg.WebPort),
 		zap.String("webroot", cfg.WebRoot),
-		zap.String("address", server.Addr))
+		zap.String("address", server.Addr),
+		zap.Bool("rest_api", cfg.RESTAPI))
 
+	if server.TLSConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
 	return server.ListenAndServe()
 }
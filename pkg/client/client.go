@@ -0,0 +1,306 @@
+// Package client is a Go client of the Nexus console API, for integration tests and tools driving
+// minions without the console binary.
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	"github.com/arhuman/minexus/internal/compression"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultPollInterval is the interval between two result queries while waiting for results
+const DefaultPollInterval = 500 * time.Millisecond
+
+// ErrNotAccepted is returned by Run when Nexus did not dispatch the command
+var ErrNotAccepted = errors.New("command not accepted")
+
+// Config configures the connection to the console port of Nexus
+type Config struct {
+	Address        string        // host:port of the Nexus console port
+	ServerName     string        // name verified in the Nexus certificate (empty: "nexus")
+	CACert         string        // PEM CA certificate verifying Nexus
+	ClientCert     string        // PEM client certificate
+	ClientKey      string        // PEM client key
+	ConnectTimeout time.Duration // minimum connection timeout (0: gRPC default)
+	Compression    string        // gRPC compression of the requests: "none", "gzip" or "zstd"
+	SignCommands   bool          // sign the commands with the client certificate, for minions verifying signatures
+	PollInterval   time.Duration // interval between result queries while waiting (0: DefaultPollInterval)
+}
+
+// Target selects the minions a command is sent to, all the connected minions when empty
+type Target struct {
+	MinionIDs []string
+	Tags      map[string]string // minions having all these tags
+}
+
+// Minions targets the given minions
+func Minions(ids ...string) Target {
+	return Target{MinionIDs: ids}
+}
+
+// Tag targets the minions having the tag key=value
+func Tag(key, value string) Target {
+	return Target{Tags: map[string]string{key: value}}
+}
+
+// Client sends commands to minions and manages their tags through Nexus, authenticated with mutual TLS
+type Client struct {
+	service      pb.ConsoleServiceClient
+	conn         *grpc.ClientConn
+	signer       *certs.CommandSigner
+	pollInterval time.Duration
+}
+
+// New creates a client of the Nexus console port. The connection is established on the first call.
+func New(cfg Config) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("nexus address is required")
+	}
+	if cfg.CACert == "" || cfg.ClientCert == "" || cfg.ClientKey == "" {
+		return nil, fmt.Errorf("CA certificate, client certificate and client key are required")
+	}
+
+	certPEM, err := os.ReadFile(cfg.ClientCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(cfg.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client key: %w", err)
+	}
+	caPEM, err := os.ReadFile(cfg.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("failed to load CA certificate")
+	}
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName = "nexus"
+	}
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      caCertPool,
+		ServerName:   serverName,
+	})
+
+	client := &Client{pollInterval: cfg.PollInterval}
+	if client.pollInterval <= 0 {
+		client.pollInterval = DefaultPollInterval
+	}
+	if cfg.SignCommands {
+		if client.signer, err = certs.NewCommandSigner(certPEM, keyPEM); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds), compression.DialOption(cfg.Compression)}
+	if cfg.ConnectTimeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{MinConnectTimeout: cfg.ConnectTimeout}))
+	}
+	client.conn, err = grpc.NewClient(cfg.Address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nexus: %w", err)
+	}
+	client.service = pb.NewConsoleServiceClient(client.conn)
+	return client, nil
+}
+
+// Close closes the connection to Nexus
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Service returns the underlying gRPC client, for the console API not wrapped by Client
+func (c *Client) Service() pb.ConsoleServiceClient {
+	return c.service
+}
+
+// ListMinions lists the minions known to Nexus
+func (c *Client) ListMinions(ctx context.Context) ([]*pb.HostInfo, error) {
+	list, err := c.service.ListMinions(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Minions, nil
+}
+
+// NewRequest builds the request sending payload to target, with a new command and trace ID
+func NewRequest(target Target, payload string) (*pb.CommandRequest, error) {
+	traceID, err := newTraceID()
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.CommandRequest{
+		MinionIds: target.MinionIDs,
+		Command: &pb.Command{
+			Id:      fmt.Sprintf("cmd-%d", time.Now().UnixNano()),
+			Type:    pb.CommandType_SYSTEM,
+			Payload: payload,
+			TraceId: traceID,
+		},
+	}
+	if len(target.Tags) > 0 {
+		req.TagSelector = &pb.TagSelector{}
+		for key, value := range target.Tags {
+			req.TagSelector.Rules = append(req.TagSelector.Rules, &pb.TagMatch{
+				Key:       key,
+				Condition: &pb.TagMatch_Equals{Equals: value},
+			})
+		}
+	}
+	return req, nil
+}
+
+// Send sends a command request, signing its command when the client signs commands.
+// The response tells whether Nexus dispatched the command or requires a confirmation or an approval.
+func (c *Client) Send(ctx context.Context, req *pb.CommandRequest) (*pb.CommandDispatchResponse, error) {
+	if c.signer != nil {
+		targets, err := c.signedTargets(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.signer.Sign(req.Command, targets); err != nil {
+			return nil, err
+		}
+	}
+	return c.service.SendCommand(ctx, req)
+}
+
+// signedTargets returns the minions a command is signed for: the minion IDs of the request, or the
+// minions Nexus currently resolves its tag selector and failure domains to
+func (c *Client) signedTargets(ctx context.Context, req *pb.CommandRequest) ([]string, error) {
+	if len(req.MinionIds) > 0 {
+		return req.MinionIds, nil
+	}
+	explanations, err := c.service.ExplainTargets(ctx, &pb.ExplainTargetsRequest{TagSelector: req.TagSelector, Topology: req.Topology})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the targets to sign the command for: %w", err)
+	}
+	var targets []string
+	for _, minion := range explanations.Minions {
+		if minion.Matched {
+			targets = append(targets, minion.MinionId)
+		}
+	}
+	return targets, nil
+}
+
+// SendCommand sends payload to the minions of target, e.g. "uptime" or "file:get /etc/hosts"
+func (c *Client) SendCommand(ctx context.Context, target Target, payload string) (*pb.CommandDispatchResponse, error) {
+	req, err := NewRequest(target, payload)
+	if err != nil {
+		return nil, err
+	}
+	return c.Send(ctx, req)
+}
+
+// Results returns the results received so far for a command
+func (c *Client) Results(ctx context.Context, commandID string) ([]*pb.CommandResult, error) {
+	results, err := c.service.GetCommandResults(ctx, &pb.ResultRequest{CommandId: commandID})
+	if err != nil {
+		return nil, err
+	}
+	return results.Results, nil
+}
+
+// WaitForResults polls the results of a command until count minions returned theirs.
+// When ctx ends first, the results received so far are returned with the context error.
+func (c *Client) WaitForResults(ctx context.Context, commandID string, count int) ([]*pb.CommandResult, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	var results []*pb.CommandResult
+	for {
+		latest, err := c.Results(ctx, commandID)
+		if err == nil {
+			results = latest
+			if len(results) >= count {
+				return results, nil
+			}
+		} else if code := status.Code(err); ctx.Err() == nil && code != codes.DeadlineExceeded && code != codes.Canceled {
+			return results, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return results, fmt.Errorf("waiting for %d results of command %s, got %d: %w", count, commandID, len(results), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run sends payload to the minions of target and waits for the result of each minion it was dispatched to.
+// It returns ErrNotAccepted, with the response, when Nexus did not dispatch the command.
+func (c *Client) Run(ctx context.Context, target Target, payload string) (*pb.CommandDispatchResponse, []*pb.CommandResult, error) {
+	response, err := c.SendCommand(ctx, target, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !response.Accepted {
+		return response, nil, ErrNotAccepted
+	}
+	results, err := c.WaitForResults(ctx, response.CommandId, len(response.TargetMinionIds))
+	return response, results, err
+}
+
+// ListTags lists the tags of the minions, as key:value
+func (c *Client) ListTags(ctx context.Context) ([]string, error) {
+	list, err := c.service.ListTags(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Tags, nil
+}
+
+// SetTags replaces all the tags of a minion
+func (c *Client) SetTags(ctx context.Context, minionID string, tags map[string]string) error {
+	return ack(c.service.SetTags(ctx, &pb.SetTagsRequest{MinionId: minionID, Tags: tags}))
+}
+
+// UpdateTags adds tags to a minion and removes the given keys
+func (c *Client) UpdateTags(ctx context.Context, minionID string, add map[string]string, removeKeys []string) error {
+	return ack(c.service.UpdateTags(ctx, &pb.UpdateTagsRequest{MinionId: minionID, Add: add, RemoveKeys: removeKeys}))
+}
+
+// ack converts a negative acknowledgement into an error
+func ack(response *pb.Ack, err error) error {
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return fmt.Errorf("request rejected by nexus")
+	}
+	return nil
+}
+
+// newTraceID generates the trace ID following a command to its results
+func newTraceID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate trace ID: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
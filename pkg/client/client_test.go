@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arhuman/minexus/internal/certs"
+	pb "github.com/arhuman/minexus/protogen"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// fakeNexus answers the console API, returning the result of each target on the second query
+type fakeNexus struct {
+	pb.UnimplementedConsoleServiceServer
+	mu       sync.Mutex
+	requests []*pb.CommandRequest
+	queries  int
+	tags     map[string]string
+}
+
+func (f *fakeNexus) SendCommand(_ context.Context, req *pb.CommandRequest) (*pb.CommandDispatchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if req.Command.Payload == "rm -rf /" {
+		return &pb.CommandDispatchResponse{ConfirmationRequired: true}, nil
+	}
+	return &pb.CommandDispatchResponse{Accepted: true, CommandId: req.Command.Id, TargetMinionIds: []string{"minion-1", "minion-2"}}, nil
+}
+
+func (f *fakeNexus) ExplainTargets(_ context.Context, req *pb.ExplainTargetsRequest) (*pb.TargetExplanations, error) {
+	return &pb.TargetExplanations{Matched: 2, Minions: []*pb.TargetExplanation{
+		{MinionId: "minion-1", Matched: true},
+		{MinionId: "minion-2", Matched: true},
+		{MinionId: "minion-3"},
+	}}, nil
+}
+
+func (f *fakeNexus) GetCommandResults(_ context.Context, req *pb.ResultRequest) (*pb.CommandResults, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries++
+	results := []*pb.CommandResult{{CommandId: req.CommandId, MinionId: "minion-1", Stdout: "ok"}}
+	if f.queries > 1 {
+		results = append(results, &pb.CommandResult{CommandId: req.CommandId, MinionId: "minion-2", Stdout: "ok"})
+	}
+	return &pb.CommandResults{Results: results}, nil
+}
+
+func (f *fakeNexus) SetTags(_ context.Context, req *pb.SetTagsRequest) (*pb.Ack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tags = req.Tags
+	return &pb.Ack{Success: true}, nil
+}
+
+func (f *fakeNexus) UpdateTags(_ context.Context, req *pb.UpdateTagsRequest) (*pb.Ack, error) {
+	return &pb.Ack{Success: false}, nil
+}
+
+// startFakeNexus serves the fake console API with mutual TLS and the embedded certificates
+func startFakeNexus(t *testing.T) (*fakeNexus, string) {
+	t.Helper()
+	pair, err := tls.X509KeyPair(certs.CertPEM, certs.KeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load server certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certs.CAPem)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	nexus := &fakeNexus{}
+	pb.RegisterConsoleServiceServer(server, nexus)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return nexus, listener.Addr().String()
+}
+
+// testConfig configures a client of addr with the embedded console certificates written to files
+func testConfig(t *testing.T, addr string) Config {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.crt": certs.CAPem, "console.crt": certs.ConsoleClientCertPEM, "console.key": certs.ConsoleClientKeyPEM}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return Config{
+		Address:    addr,
+		CACert:     filepath.Join(dir, "ca.crt"),
+		ClientCert: filepath.Join(dir, "console.crt"),
+		ClientKey:  filepath.Join(dir, "console.key"),
+	}
+}
+
+func TestClientRun(t *testing.T) {
+	nexus, addr := startFakeNexus(t)
+	cfg := testConfig(t, addr)
+	cfg.PollInterval = 10 * time.Millisecond
+	cfg.SignCommands = true
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, results, err := client.Run(ctx, Tag("env", "prod"), "uptime")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !response.Accepted || len(results) != 2 {
+		t.Errorf("Expected the results of both targets, got %v", results)
+	}
+	req := nexus.requests[0]
+	if rule := req.TagSelector.GetRules()[0]; rule.Key != "env" || rule.GetEquals() != "prod" {
+		t.Errorf("Unexpected tag selector %v", req.TagSelector)
+	}
+	if req.Command.TraceId == "" || len(req.Command.Signature) == 0 {
+		t.Errorf("Expected a traced and signed command, got %v", req.Command)
+	}
+	// The command is signed for the minions the tag selector matches
+	if targets := req.Command.SignedTargets; len(targets) != 2 || targets[0] != "minion-1" || targets[1] != "minion-2" {
+		t.Errorf("Expected the command signed for the matched minions, got %v", targets)
+	}
+
+	// Commands Nexus does not dispatch are reported
+	response, _, err = client.Run(ctx, Minions("minion-1"), "rm -rf /")
+	if !errors.Is(err, ErrNotAccepted) || !response.ConfirmationRequired {
+		t.Errorf("Expected ErrNotAccepted, got %v (%v)", err, response)
+	}
+
+	// Waiting stops with the context
+	short, cancelShort := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancelShort()
+	results, err = client.WaitForResults(short, "cmd-1", 3)
+	if !errors.Is(err, context.DeadlineExceeded) || len(results) != 2 {
+		t.Errorf("Expected the results so far and a deadline error, got %d results (%v)", len(results), err)
+	}
+}
+
+func TestClientTags(t *testing.T) {
+	nexus, addr := startFakeNexus(t)
+	client, err := New(testConfig(t, addr))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SetTags(context.Background(), "minion-1", map[string]string{"env": "prod"}); err != nil {
+		t.Errorf("SetTags failed: %v", err)
+	}
+	if nexus.tags["env"] != "prod" {
+		t.Errorf("Expected tags to be set, got %v", nexus.tags)
+	}
+	if err := client.UpdateTags(context.Background(), "minion-1", nil, []string{"env"}); err == nil {
+		t.Error("Expected rejected requests to fail")
+	}
+}
+
+func TestNewRequiresAddress(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without address")
+	}
+	if _, err := New(Config{Address: "localhost:11973"}); err == nil {
+		t.Error("Expected an error without certificates")
+	}
+	cfg := testConfig(t, "localhost:11973")
+	cfg.CACert = "/nonexistent/ca.crt"
+	if _, err := New(cfg); err == nil {
+		t.Error("Expected an error with a missing CA certificate")
+	}
+}
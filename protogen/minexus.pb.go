@@ -67,16 +67,96 @@ func (CommandType) EnumDescriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{0}
 }
 
+// Dispatch priority, higher priorities jump ahead of commands already queued for a minion
+type CommandPriority int32
+
+const (
+	CommandPriority_NORMAL    CommandPriority = 0
+	CommandPriority_LOW       CommandPriority = 1
+	CommandPriority_HIGH      CommandPriority = 2
+	CommandPriority_EMERGENCY CommandPriority = 3
+)
+
+// Enum value maps for CommandPriority.
+var (
+	CommandPriority_name = map[int32]string{
+		0: "NORMAL",
+		1: "LOW",
+		2: "HIGH",
+		3: "EMERGENCY",
+	}
+	CommandPriority_value = map[string]int32{
+		"NORMAL":    0,
+		"LOW":       1,
+		"HIGH":      2,
+		"EMERGENCY": 3,
+	}
+)
+
+func (x CommandPriority) Enum() *CommandPriority {
+	p := new(CommandPriority)
+	*p = x
+	return p
+}
+
+func (x CommandPriority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CommandPriority) Descriptor() protoreflect.EnumDescriptor {
+	return file_minexus_proto_enumTypes[1].Descriptor()
+}
+
+func (CommandPriority) Type() protoreflect.EnumType {
+	return &file_minexus_proto_enumTypes[1]
+}
+
+func (x CommandPriority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CommandPriority.Descriptor instead.
+func (CommandPriority) EnumDescriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{1}
+}
+
 type HostInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	Ip            string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
-	Os            string                 `protobuf:"bytes,4,opt,name=os,proto3" json:"os,omitempty"`
-	Tags          map[string]string      `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	LastSeen      int64                  `protobuf:"varint,6,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // Unix timestamp of last registration/communication
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hostname     string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Ip           string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
+	Os           string                 `protobuf:"bytes,4,opt,name=os,proto3" json:"os,omitempty"`
+	Tags         map[string]string      `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	LastSeen     int64                  `protobuf:"varint,6,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // Unix timestamp of last registration/communication
+	OsVersion    string                 `protobuf:"bytes,7,opt,name=os_version,json=osVersion,proto3" json:"os_version,omitempty"`
+	MacAddresses []string               `protobuf:"bytes,8,rep,name=mac_addresses,json=macAddresses,proto3" json:"mac_addresses,omitempty"`
+	Fingerprint  string                 `protobuf:"bytes,9,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`    // Hash of hostname, IP, OS version and MAC addresses
+	Capabilities []string               `protobuf:"bytes,10,rep,name=capabilities,proto3" json:"capabilities,omitempty"` // command families the minion can execute (docker-compose, systemd, pkg:<manager>)
+	Namespace    string                 `protobuf:"bytes,11,opt,name=namespace,proto3" json:"namespace,omitempty"`       // tenant the minion belongs to, "default" when unset
+	// Failure domains of the minion, Nexus falling back to the region, datacenter (dc, zone) and rack tags
+	Region          string           `protobuf:"bytes,12,opt,name=region,proto3" json:"region,omitempty"`
+	Datacenter      string           `protobuf:"bytes,13,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Rack            string           `protobuf:"bytes,14,opt,name=rack,proto3" json:"rack,omitempty"`
+	CommandVersions map[string]int32 `protobuf:"bytes,15,rep,name=command_versions,json=commandVersions,proto3" json:"command_versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // handler version of each command family, empty for minions predating versioning
+	Health          *MinionHealth    `protobuf:"bytes,16,opt,name=health,proto3" json:"health,omitempty"`                                                                                                                     // computed by Nexus in minion lists
+	// Availability windows of power-aware minions, which sleep outside them, empty when always available
+	Availability   string         `protobuf:"bytes,17,opt,name=availability,proto3" json:"availability,omitempty"`
+	SleepAt        int64          `protobuf:"varint,18,opt,name=sleep_at,json=sleepAt,proto3" json:"sleep_at,omitempty"`                      // unix time the minion goes to sleep, 0 when it never sleeps
+	NextWake       int64          `protobuf:"varint,19,opt,name=next_wake,json=nextWake,proto3" json:"next_wake,omitempty"`                   // unix time the minion wakes after its next sleep, 0 when it never sleeps
+	QueuedCommands int32          `protobuf:"varint,20,opt,name=queued_commands,json=queuedCommands,proto3" json:"queued_commands,omitempty"` // computed by Nexus in minion lists
+	Sleeping       bool           `protobuf:"varint,21,opt,name=sleeping,proto3" json:"sleeping,omitempty"`                                   // computed by Nexus in minion lists
+	Crashes        []*CrashReport `protobuf:"bytes,22,rep,name=crashes,proto3" json:"crashes,omitempty"`                                      // panics recovered by the minion since its last successful registration
+	// Reboot or shutdown scheduled with the power commands, announced so that the host going down isn't taken for a failure
+	PowerAction string `protobuf:"bytes,23,opt,name=power_action,json=powerAction,proto3" json:"power_action,omitempty"`  // reboot or shutdown, empty when none is scheduled
+	PowerAt     int64  `protobuf:"varint,24,opt,name=power_at,json=powerAt,proto3" json:"power_at,omitempty"`             // unix time the host goes down
+	PoweredDown bool   `protobuf:"varint,25,opt,name=powered_down,json=poweredDown,proto3" json:"powered_down,omitempty"` // computed by Nexus in minion lists
+	// Build of the minion, empty and 0 for minions predating version reporting
+	Version         string `protobuf:"bytes,26,opt,name=version,proto3" json:"version,omitempty"`
+	BuildCommit     string `protobuf:"bytes,27,opt,name=build_commit,json=buildCommit,proto3" json:"build_commit,omitempty"`
+	ProtocolVersion int32  `protobuf:"varint,28,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Outdated        bool   `protobuf:"varint,29,opt,name=outdated,proto3" json:"outdated,omitempty"` // computed by Nexus in minion lists: protocol below the minimum Nexus requires
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *HostInfo) Reset() {
@@ -151,14 +231,183 @@ func (x *HostInfo) GetLastSeen() int64 {
 	return 0
 }
 
+func (x *HostInfo) GetOsVersion() string {
+	if x != nil {
+		return x.OsVersion
+	}
+	return ""
+}
+
+func (x *HostInfo) GetMacAddresses() []string {
+	if x != nil {
+		return x.MacAddresses
+	}
+	return nil
+}
+
+func (x *HostInfo) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *HostInfo) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *HostInfo) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *HostInfo) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *HostInfo) GetDatacenter() string {
+	if x != nil {
+		return x.Datacenter
+	}
+	return ""
+}
+
+func (x *HostInfo) GetRack() string {
+	if x != nil {
+		return x.Rack
+	}
+	return ""
+}
+
+func (x *HostInfo) GetCommandVersions() map[string]int32 {
+	if x != nil {
+		return x.CommandVersions
+	}
+	return nil
+}
+
+func (x *HostInfo) GetHealth() *MinionHealth {
+	if x != nil {
+		return x.Health
+	}
+	return nil
+}
+
+func (x *HostInfo) GetAvailability() string {
+	if x != nil {
+		return x.Availability
+	}
+	return ""
+}
+
+func (x *HostInfo) GetSleepAt() int64 {
+	if x != nil {
+		return x.SleepAt
+	}
+	return 0
+}
+
+func (x *HostInfo) GetNextWake() int64 {
+	if x != nil {
+		return x.NextWake
+	}
+	return 0
+}
+
+func (x *HostInfo) GetQueuedCommands() int32 {
+	if x != nil {
+		return x.QueuedCommands
+	}
+	return 0
+}
+
+func (x *HostInfo) GetSleeping() bool {
+	if x != nil {
+		return x.Sleeping
+	}
+	return false
+}
+
+func (x *HostInfo) GetCrashes() []*CrashReport {
+	if x != nil {
+		return x.Crashes
+	}
+	return nil
+}
+
+func (x *HostInfo) GetPowerAction() string {
+	if x != nil {
+		return x.PowerAction
+	}
+	return ""
+}
+
+func (x *HostInfo) GetPowerAt() int64 {
+	if x != nil {
+		return x.PowerAt
+	}
+	return 0
+}
+
+func (x *HostInfo) GetPoweredDown() bool {
+	if x != nil {
+		return x.PoweredDown
+	}
+	return false
+}
+
+func (x *HostInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *HostInfo) GetBuildCommit() string {
+	if x != nil {
+		return x.BuildCommit
+	}
+	return ""
+}
+
+func (x *HostInfo) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *HostInfo) GetOutdated() bool {
+	if x != nil {
+		return x.Outdated
+	}
+	return false
+}
+
 type Command struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Type          CommandType            `protobuf:"varint,2,opt,name=type,proto3,enum=minexus.CommandType" json:"type,omitempty"`
-	Payload       string                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
-	Metadata      map[string]string      `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type              CommandType            `protobuf:"varint,2,opt,name=type,proto3,enum=minexus.CommandType" json:"type,omitempty"`
+	Payload           string                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Metadata          map[string]string      `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Priority          CommandPriority        `protobuf:"varint,5,opt,name=priority,proto3,enum=minexus.CommandPriority" json:"priority,omitempty"`
+	Signature         []byte                 `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`                                                                // console signature of type, signed_at, signature_nonce, signed_targets and payload
+	SignerCertificate []byte                 `protobuf:"bytes,7,opt,name=signer_certificate,json=signerCertificate,proto3" json:"signer_certificate,omitempty"`                       // PEM certificate of the signing console
+	SignedAt          int64                  `protobuf:"varint,8,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`                                                 // unix time of the signature
+	TraceId           string                 `protobuf:"bytes,9,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`                                                     // identifies the command across components, in logs and stored rows
+	Env               map[string]string      `protobuf:"bytes,10,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // environment variables set by Nexus for the target minion, unsigned
+	SignatureNonce    []byte                 `protobuf:"bytes,11,opt,name=signature_nonce,json=signatureNonce,proto3" json:"signature_nonce,omitempty"`                               // random bytes making each signature unique, for minions to refuse replays
+	SignedTargets     []string               `protobuf:"bytes,12,rep,name=signed_targets,json=signedTargets,proto3" json:"signed_targets,omitempty"`                                  // minion IDs the signature is valid for, sorted
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *Command) Reset() {
@@ -219,6 +468,62 @@ func (x *Command) GetMetadata() map[string]string {
 	return nil
 }
 
+func (x *Command) GetPriority() CommandPriority {
+	if x != nil {
+		return x.Priority
+	}
+	return CommandPriority_NORMAL
+}
+
+func (x *Command) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *Command) GetSignerCertificate() []byte {
+	if x != nil {
+		return x.SignerCertificate
+	}
+	return nil
+}
+
+func (x *Command) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
+func (x *Command) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *Command) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *Command) GetSignatureNonce() []byte {
+	if x != nil {
+		return x.SignatureNonce
+	}
+	return nil
+}
+
+func (x *Command) GetSignedTargets() []string {
+	if x != nil {
+		return x.SignedTargets
+	}
+	return nil
+}
+
 type CommandResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
@@ -227,6 +532,15 @@ type CommandResult struct {
 	Stdout        string                 `protobuf:"bytes,4,opt,name=stdout,proto3" json:"stdout,omitempty"`
 	Stderr        string                 `protobuf:"bytes,5,opt,name=stderr,proto3" json:"stderr,omitempty"`
 	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ContentType   string                 `protobuf:"bytes,7,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // type of structured, empty for plain text results
+	Structured    string                 `protobuf:"bytes,8,opt,name=structured,proto3" json:"structured,omitempty"`                      // optional machine-readable JSON payload
+	ScheduleId    string                 `protobuf:"bytes,9,opt,name=schedule_id,json=scheduleId,proto3" json:"schedule_id,omitempty"`    // set for results of tasks scheduled on the minion itself
+	Payload       string                 `protobuf:"bytes,10,opt,name=payload,proto3" json:"payload,omitempty"`                           // command payload, set with schedule_id or watch_id as Nexus never dispatched the command
+	TraceId       string                 `protobuf:"bytes,11,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`            // trace ID of the command
+	WatchId       string                 `protobuf:"bytes,12,opt,name=watch_id,json=watchId,proto3" json:"watch_id,omitempty"`            // set for results of commands triggered by a file watcher on the minion itself
+	WatchEvent    string                 `protobuf:"bytes,13,opt,name=watch_event,json=watchEvent,proto3" json:"watch_event,omitempty"`   // file change that triggered the command, set with watch_id
+	Attempt       int32                  `protobuf:"varint,14,opt,name=attempt,proto3" json:"attempt,omitempty"`                          // dispatch of the command the result comes from, above 1 once Nexus retried it
+	Execution     *ExecutionInfo         `protobuf:"bytes,15,opt,name=execution,proto3" json:"execution,omitempty"`                       // how the minion ran the command, to debug failures without running it again
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -303,71 +617,96 @@ func (x *CommandResult) GetTimestamp() int64 {
 	return 0
 }
 
-type Ack struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *CommandResult) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
 }
 
-func (x *Ack) Reset() {
-	*x = Ack{}
-	mi := &file_minexus_proto_msgTypes[3]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *CommandResult) GetStructured() string {
+	if x != nil {
+		return x.Structured
+	}
+	return ""
 }
 
-func (x *Ack) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *CommandResult) GetScheduleId() string {
+	if x != nil {
+		return x.ScheduleId
+	}
+	return ""
 }
 
-func (*Ack) ProtoMessage() {}
+func (x *CommandResult) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
 
-func (x *Ack) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[3]
+func (x *CommandResult) GetTraceId() string {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.TraceId
 	}
-	return mi.MessageOf(x)
+	return ""
 }
 
-// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
-func (*Ack) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{3}
+func (x *CommandResult) GetWatchId() string {
+	if x != nil {
+		return x.WatchId
+	}
+	return ""
 }
 
-func (x *Ack) GetSuccess() bool {
+func (x *CommandResult) GetWatchEvent() string {
 	if x != nil {
-		return x.Success
+		return x.WatchEvent
 	}
-	return false
+	return ""
 }
 
-type Empty struct {
+func (x *CommandResult) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *CommandResult) GetExecution() *ExecutionInfo {
+	if x != nil {
+		return x.Execution
+	}
+	return nil
+}
+
+// ExecutionInfo is the execution environment of a command captured by the minion
+type ExecutionInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkingDir    string                 `protobuf:"bytes,1,opt,name=working_dir,json=workingDir,proto3" json:"working_dir,omitempty"`  // directory the command ran in
+	User          string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`                                // user the command ran as
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`                                // PATH of the command
+	DurationMs    int64                  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"` // time the minion spent executing the command
+	MaxRssKb      int64                  `protobuf:"varint,5,opt,name=max_rss_kb,json=maxRssKb,proto3" json:"max_rss_kb,omitempty"`     // peak resident memory of the processes the command started, 0 when it started none or it is unknown
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Empty) Reset() {
-	*x = Empty{}
-	mi := &file_minexus_proto_msgTypes[4]
+func (x *ExecutionInfo) Reset() {
+	*x = ExecutionInfo{}
+	mi := &file_minexus_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Empty) String() string {
+func (x *ExecutionInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Empty) ProtoMessage() {}
+func (*ExecutionInfo) ProtoMessage() {}
 
-func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[4]
+func (x *ExecutionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -378,11 +717,126 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
-func (*Empty) Descriptor() ([]byte, []int) {
+// Deprecated: Use ExecutionInfo.ProtoReflect.Descriptor instead.
+func (*ExecutionInfo) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ExecutionInfo) GetWorkingDir() string {
+	if x != nil {
+		return x.WorkingDir
+	}
+	return ""
+}
+
+func (x *ExecutionInfo) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *ExecutionInfo) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ExecutionInfo) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *ExecutionInfo) GetMaxRssKb() int64 {
+	if x != nil {
+		return x.MaxRssKb
+	}
+	return 0
+}
+
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_minexus_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{4}
 }
 
+func (x *Ack) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_minexus_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{5}
+}
+
 type SetTagsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
@@ -393,7 +847,7 @@ type SetTagsRequest struct {
 
 func (x *SetTagsRequest) Reset() {
 	*x = SetTagsRequest{}
-	mi := &file_minexus_proto_msgTypes[5]
+	mi := &file_minexus_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -405,7 +859,7 @@ func (x *SetTagsRequest) String() string {
 func (*SetTagsRequest) ProtoMessage() {}
 
 func (x *SetTagsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[5]
+	mi := &file_minexus_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -418,7 +872,7 @@ func (x *SetTagsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetTagsRequest.ProtoReflect.Descriptor instead.
 func (*SetTagsRequest) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{5}
+	return file_minexus_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *SetTagsRequest) GetMinionId() string {
@@ -446,7 +900,7 @@ type UpdateTagsRequest struct {
 
 func (x *UpdateTagsRequest) Reset() {
 	*x = UpdateTagsRequest{}
-	mi := &file_minexus_proto_msgTypes[6]
+	mi := &file_minexus_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -458,7 +912,7 @@ func (x *UpdateTagsRequest) String() string {
 func (*UpdateTagsRequest) ProtoMessage() {}
 
 func (x *UpdateTagsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[6]
+	mi := &file_minexus_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -471,7 +925,7 @@ func (x *UpdateTagsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateTagsRequest.ProtoReflect.Descriptor instead.
 func (*UpdateTagsRequest) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{6}
+	return file_minexus_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *UpdateTagsRequest) GetMinionId() string {
@@ -504,7 +958,7 @@ type TagList struct {
 
 func (x *TagList) Reset() {
 	*x = TagList{}
-	mi := &file_minexus_proto_msgTypes[7]
+	mi := &file_minexus_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -516,7 +970,7 @@ func (x *TagList) String() string {
 func (*TagList) ProtoMessage() {}
 
 func (x *TagList) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[7]
+	mi := &file_minexus_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -529,7 +983,7 @@ func (x *TagList) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TagList.ProtoReflect.Descriptor instead.
 func (*TagList) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{7}
+	return file_minexus_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *TagList) GetTags() []string {
@@ -554,7 +1008,7 @@ type TagMatch struct {
 
 func (x *TagMatch) Reset() {
 	*x = TagMatch{}
-	mi := &file_minexus_proto_msgTypes[8]
+	mi := &file_minexus_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -566,7 +1020,7 @@ func (x *TagMatch) String() string {
 func (*TagMatch) ProtoMessage() {}
 
 func (x *TagMatch) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[8]
+	mi := &file_minexus_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -579,7 +1033,7 @@ func (x *TagMatch) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TagMatch.ProtoReflect.Descriptor instead.
 func (*TagMatch) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{8}
+	return file_minexus_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *TagMatch) GetKey() string {
@@ -645,73 +1099,30 @@ func (*TagMatch_Exists) isTagMatch_Condition() {}
 
 func (*TagMatch_NotExists) isTagMatch_Condition() {}
 
-type TagSelector struct {
+// TopologySelector matches the minions in the failure domains whose fields are set
+type TopologySelector struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Rules         []*TagMatch            `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"` // AND logique
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *TagSelector) Reset() {
-	*x = TagSelector{}
-	mi := &file_minexus_proto_msgTypes[9]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *TagSelector) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*TagSelector) ProtoMessage() {}
-
-func (x *TagSelector) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[9]
-	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use TagSelector.ProtoReflect.Descriptor instead.
-func (*TagSelector) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{9}
-}
-
-func (x *TagSelector) GetRules() []*TagMatch {
-	if x != nil {
-		return x.Rules
-	}
-	return nil
-}
-
-type CommandStatusResponse struct {
-	state         protoimpl.MessageState                `protogen:"open.v1"`
-	CommandId     string                                `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
-	Statuses      []*CommandStatusResponse_MinionStatus `protobuf:"bytes,2,rep,name=statuses,proto3" json:"statuses,omitempty"`
-	StatusCounts  map[string]int32                      `protobuf:"bytes,3,rep,name=status_counts,json=statusCounts,proto3" json:"status_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Count of minions in each status
+	Region        string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	Datacenter    string                 `protobuf:"bytes,2,opt,name=datacenter,proto3" json:"datacenter,omitempty"`
+	Rack          string                 `protobuf:"bytes,3,opt,name=rack,proto3" json:"rack,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandStatusResponse) Reset() {
-	*x = CommandStatusResponse{}
+func (x *TopologySelector) Reset() {
+	*x = TopologySelector{}
 	mi := &file_minexus_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandStatusResponse) String() string {
+func (x *TopologySelector) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandStatusResponse) ProtoMessage() {}
+func (*TopologySelector) ProtoMessage() {}
 
-func (x *CommandStatusResponse) ProtoReflect() protoreflect.Message {
+func (x *TopologySelector) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -723,53 +1134,53 @@ func (x *CommandStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandStatusResponse.ProtoReflect.Descriptor instead.
-func (*CommandStatusResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use TopologySelector.ProtoReflect.Descriptor instead.
+func (*TopologySelector) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *CommandStatusResponse) GetCommandId() string {
+func (x *TopologySelector) GetRegion() string {
 	if x != nil {
-		return x.CommandId
+		return x.Region
 	}
 	return ""
 }
 
-func (x *CommandStatusResponse) GetStatuses() []*CommandStatusResponse_MinionStatus {
+func (x *TopologySelector) GetDatacenter() string {
 	if x != nil {
-		return x.Statuses
+		return x.Datacenter
 	}
-	return nil
+	return ""
 }
 
-func (x *CommandStatusResponse) GetStatusCounts() map[string]int32 {
+func (x *TopologySelector) GetRack() string {
 	if x != nil {
-		return x.StatusCounts
+		return x.Rack
 	}
-	return nil
+	return ""
 }
 
-type MinionList struct {
+type TagSelector struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Minions       []*HostInfo            `protobuf:"bytes,1,rep,name=minions,proto3" json:"minions,omitempty"`
+	Rules         []*TagMatch            `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"` // AND logique
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MinionList) Reset() {
-	*x = MinionList{}
+func (x *TagSelector) Reset() {
+	*x = TagSelector{}
 	mi := &file_minexus_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MinionList) String() string {
+func (x *TagSelector) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MinionList) ProtoMessage() {}
+func (*TagSelector) ProtoMessage() {}
 
-func (x *MinionList) ProtoReflect() protoreflect.Message {
+func (x *TagSelector) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -781,41 +1192,42 @@ func (x *MinionList) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MinionList.ProtoReflect.Descriptor instead.
-func (*MinionList) Descriptor() ([]byte, []int) {
+// Deprecated: Use TagSelector.ProtoReflect.Descriptor instead.
+func (*TagSelector) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *MinionList) GetMinions() []*HostInfo {
+func (x *TagSelector) GetRules() []*TagMatch {
 	if x != nil {
-		return x.Minions
+		return x.Rules
 	}
 	return nil
 }
 
-type CommandRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	MinionIds     []string               `protobuf:"bytes,1,rep,name=minion_ids,json=minionIds,proto3" json:"minion_ids,omitempty"`
-	TagSelector   *TagSelector           `protobuf:"bytes,2,opt,name=tag_selector,json=tagSelector,proto3" json:"tag_selector,omitempty"`
-	Command       *Command               `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// TagSchema restricts the tags set from consoles, when configured on Nexus
+type TagSchema struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Enabled        bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"` // false when Nexus has no schema, every tag being accepted
+	Keys           []*TagSchema_Key       `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	AllowOtherKeys bool                   `protobuf:"varint,3,opt,name=allow_other_keys,json=allowOtherKeys,proto3" json:"allow_other_keys,omitempty"` // keys not in the schema are accepted with any value
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
-func (x *CommandRequest) Reset() {
-	*x = CommandRequest{}
+func (x *TagSchema) Reset() {
+	*x = TagSchema{}
 	mi := &file_minexus_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandRequest) String() string {
+func (x *TagSchema) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandRequest) ProtoMessage() {}
+func (*TagSchema) ProtoMessage() {}
 
-func (x *CommandRequest) ProtoReflect() protoreflect.Message {
+func (x *TagSchema) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -827,54 +1239,56 @@ func (x *CommandRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandRequest.ProtoReflect.Descriptor instead.
-func (*CommandRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use TagSchema.ProtoReflect.Descriptor instead.
+func (*TagSchema) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *CommandRequest) GetMinionIds() []string {
+func (x *TagSchema) GetEnabled() bool {
 	if x != nil {
-		return x.MinionIds
+		return x.Enabled
 	}
-	return nil
+	return false
 }
 
-func (x *CommandRequest) GetTagSelector() *TagSelector {
+func (x *TagSchema) GetKeys() []*TagSchema_Key {
 	if x != nil {
-		return x.TagSelector
+		return x.Keys
 	}
 	return nil
 }
 
-func (x *CommandRequest) GetCommand() *Command {
+func (x *TagSchema) GetAllowOtherKeys() bool {
 	if x != nil {
-		return x.Command
+		return x.AllowOtherKeys
 	}
-	return nil
+	return false
 }
 
-type CommandDispatchResponse struct {
+// BootstrapRequest asks for a one-time URL installing a minion on a new host
+type BootstrapRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
-	CommandId     string                 `protobuf:"bytes,2,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Os            string                 `protobuf:"bytes,1,opt,name=os,proto3" json:"os,omitempty"`                                    // "linux", "darwin" or "windows"
+	Arch          string                 `protobuf:"bytes,2,opt,name=arch,proto3" json:"arch,omitempty"`                                // "amd64" or "arm64"
+	TtlSeconds    int64                  `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // validity of the token, 0: default
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandDispatchResponse) Reset() {
-	*x = CommandDispatchResponse{}
+func (x *BootstrapRequest) Reset() {
+	*x = BootstrapRequest{}
 	mi := &file_minexus_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandDispatchResponse) String() string {
+func (x *BootstrapRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandDispatchResponse) ProtoMessage() {}
+func (*BootstrapRequest) ProtoMessage() {}
 
-func (x *CommandDispatchResponse) ProtoReflect() protoreflect.Message {
+func (x *BootstrapRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -886,46 +1300,57 @@ func (x *CommandDispatchResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandDispatchResponse.ProtoReflect.Descriptor instead.
-func (*CommandDispatchResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use BootstrapRequest.ProtoReflect.Descriptor instead.
+func (*BootstrapRequest) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *CommandDispatchResponse) GetAccepted() bool {
+func (x *BootstrapRequest) GetOs() string {
 	if x != nil {
-		return x.Accepted
+		return x.Os
 	}
-	return false
+	return ""
 }
 
-func (x *CommandDispatchResponse) GetCommandId() string {
+func (x *BootstrapRequest) GetArch() string {
 	if x != nil {
-		return x.CommandId
+		return x.Arch
 	}
 	return ""
 }
 
-type ResultRequest struct {
+func (x *BootstrapRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+type BootstrapToken struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"` // install script URL, usable once
+	Os            string                 `protobuf:"bytes,3,opt,name=os,proto3" json:"os,omitempty"`
+	Arch          string                 `protobuf:"bytes,4,opt,name=arch,proto3" json:"arch,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // unix timestamp
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ResultRequest) Reset() {
-	*x = ResultRequest{}
+func (x *BootstrapToken) Reset() {
+	*x = BootstrapToken{}
 	mi := &file_minexus_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResultRequest) String() string {
+func (x *BootstrapToken) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResultRequest) ProtoMessage() {}
+func (*BootstrapToken) ProtoMessage() {}
 
-func (x *ResultRequest) ProtoReflect() protoreflect.Message {
+func (x *BootstrapToken) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -937,39 +1362,69 @@ func (x *ResultRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResultRequest.ProtoReflect.Descriptor instead.
-func (*ResultRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use BootstrapToken.ProtoReflect.Descriptor instead.
+func (*BootstrapToken) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *ResultRequest) GetCommandId() string {
+func (x *BootstrapToken) GetToken() string {
 	if x != nil {
-		return x.CommandId
+		return x.Token
 	}
 	return ""
 }
 
-type CommandResults struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Results       []*CommandResult       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+func (x *BootstrapToken) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *BootstrapToken) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *BootstrapToken) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *BootstrapToken) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type CommandStatusResponse struct {
+	state         protoimpl.MessageState                `protogen:"open.v1"`
+	CommandId     string                                `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Statuses      []*CommandStatusResponse_MinionStatus `protobuf:"bytes,2,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	StatusCounts  map[string]int32                      `protobuf:"bytes,3,rep,name=status_counts,json=statusCounts,proto3" json:"status_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Count of minions in each status
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandResults) Reset() {
-	*x = CommandResults{}
+func (x *CommandStatusResponse) Reset() {
+	*x = CommandStatusResponse{}
 	mi := &file_minexus_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandResults) String() string {
+func (x *CommandStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandResults) ProtoMessage() {}
+func (*CommandStatusResponse) ProtoMessage() {}
 
-func (x *CommandResults) ProtoReflect() protoreflect.Message {
+func (x *CommandStatusResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -981,43 +1436,53 @@ func (x *CommandResults) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandResults.ProtoReflect.Descriptor instead.
-func (*CommandResults) Descriptor() ([]byte, []int) {
+// Deprecated: Use CommandStatusResponse.ProtoReflect.Descriptor instead.
+func (*CommandStatusResponse) Descriptor() ([]byte, []int) {
 	return file_minexus_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *CommandResults) GetResults() []*CommandResult {
+func (x *CommandStatusResponse) GetCommandId() string {
 	if x != nil {
-		return x.Results
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *CommandStatusResponse) GetStatuses() []*CommandStatusResponse_MinionStatus {
+	if x != nil {
+		return x.Statuses
 	}
 	return nil
 }
 
-// New message for command status updates
-type CommandStatusUpdate struct {
+func (x *CommandStatusResponse) GetStatusCounts() map[string]int32 {
+	if x != nil {
+		return x.StatusCounts
+	}
+	return nil
+}
+
+type MinionList struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
-	MinionId      string                 `protobuf:"bytes,2,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
-	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"` // "RECEIVED", "EXECUTING", "COMPLETED", "FAILED"
-	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Minions       []*HostInfo            `protobuf:"bytes,1,rep,name=minions,proto3" json:"minions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandStatusUpdate) Reset() {
-	*x = CommandStatusUpdate{}
+func (x *MinionList) Reset() {
+	*x = MinionList{}
 	mi := &file_minexus_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandStatusUpdate) String() string {
+func (x *MinionList) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandStatusUpdate) ProtoMessage() {}
+func (*MinionList) ProtoMessage() {}
 
-func (x *CommandStatusUpdate) ProtoReflect() protoreflect.Message {
+func (x *MinionList) ProtoReflect() protoreflect.Message {
 	mi := &file_minexus_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1029,63 +1494,6378 @@ func (x *CommandStatusUpdate) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandStatusUpdate.ProtoReflect.Descriptor instead.
-func (*CommandStatusUpdate) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use MinionList.ProtoReflect.Descriptor instead.
+func (*MinionList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *MinionList) GetMinions() []*HostInfo {
+	if x != nil {
+		return x.Minions
+	}
+	return nil
+}
+
+type CommandRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionIds     []string               `protobuf:"bytes,1,rep,name=minion_ids,json=minionIds,proto3" json:"minion_ids,omitempty"`
+	TagSelector   *TagSelector           `protobuf:"bytes,2,opt,name=tag_selector,json=tagSelector,proto3" json:"tag_selector,omitempty"`
+	Command       *Command               `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	DryRun        bool                   `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`                    // validate and resolve targets without dispatching
+	Emergency     bool                   `protobuf:"varint,5,opt,name=emergency,proto3" json:"emergency,omitempty"`                            // bypass maintenance windows
+	Priority      CommandPriority        `protobuf:"varint,6,opt,name=priority,proto3,enum=minexus.CommandPriority" json:"priority,omitempty"` // EMERGENCY also bypasses maintenance windows
+	ConfirmToken  string                 `protobuf:"bytes,7,opt,name=confirm_token,json=confirmToken,proto3" json:"confirm_token,omitempty"`   // confirms a destructive command, as returned when confirmation was required
+	Lock          string                 `protobuf:"bytes,8,opt,name=lock,proto3" json:"lock,omitempty"`                                       // host lock held by the command while it runs, Nexus queuing it until the lock is free
+	Topology      *TopologySelector      `protobuf:"bytes,9,opt,name=topology,proto3" json:"topology,omitempty"`                               // restricts the tag selector targets to failure domains
+	Impact        string                 `protobuf:"bytes,10,opt,name=impact,proto3" json:"impact,omitempty"`                                  // impact class declared by the operator (read-only, mutating, disruptive), raised by Nexus to the class of the command
+	Retry         *RetryPolicy           `protobuf:"bytes,11,opt,name=retry,proto3" json:"retry,omitempty"`                                    // targets whose result failed get the command again, unset: no retry
+	Operator      string                 `protobuf:"bytes,12,opt,name=operator,proto3" json:"operator,omitempty"`                              // common name of the certificate of the sending console, set by Nexus
+	Sample        int32                  `protobuf:"varint,13,opt,name=sample,proto3" json:"sample,omitempty"`                                 // successful results stored in full, the others only counted; failures are all stored. 0 stores every result
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandRequest) Reset() {
+	*x = CommandRequest{}
+	mi := &file_minexus_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandRequest) ProtoMessage() {}
+
+func (x *CommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandRequest.ProtoReflect.Descriptor instead.
+func (*CommandRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CommandRequest) GetMinionIds() []string {
+	if x != nil {
+		return x.MinionIds
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetTagSelector() *TagSelector {
+	if x != nil {
+		return x.TagSelector
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetCommand() *Command {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *CommandRequest) GetEmergency() bool {
+	if x != nil {
+		return x.Emergency
+	}
+	return false
+}
+
+func (x *CommandRequest) GetPriority() CommandPriority {
+	if x != nil {
+		return x.Priority
+	}
+	return CommandPriority_NORMAL
+}
+
+func (x *CommandRequest) GetConfirmToken() string {
+	if x != nil {
+		return x.ConfirmToken
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetLock() string {
+	if x != nil {
+		return x.Lock
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetTopology() *TopologySelector {
+	if x != nil {
+		return x.Topology
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetImpact() string {
+	if x != nil {
+		return x.Impact
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetRetry() *RetryPolicy {
+	if x != nil {
+		return x.Retry
+	}
+	return nil
+}
+
+func (x *CommandRequest) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *CommandRequest) GetSample() int32 {
+	if x != nil {
+		return x.Sample
+	}
+	return 0
+}
+
+// RetryPolicy has Nexus dispatch a command again to the targets whose result failed, only the
+// result of their last attempt being stored
+type RetryPolicy struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MaxAttempts    int32                  `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`          // dispatches to each target, the first one included, up to 10
+	BackoffSeconds int32                  `protobuf:"varint,2,opt,name=backoff_seconds,json=backoffSeconds,proto3" json:"backoff_seconds,omitempty"` // delay before the first retry, doubled before each next one, 0 for 5s
+	ExitCodes      []int32                `protobuf:"varint,3,rep,packed,name=exit_codes,json=exitCodes,proto3" json:"exit_codes,omitempty"`         // exit codes retried, empty for any non-zero exit code
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RetryPolicy) Reset() {
+	*x = RetryPolicy{}
+	mi := &file_minexus_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicy) ProtoMessage() {}
+
+func (x *RetryPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicy.ProtoReflect.Descriptor instead.
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RetryPolicy) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetBackoffSeconds() int32 {
+	if x != nil {
+		return x.BackoffSeconds
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetExitCodes() []int32 {
+	if x != nil {
+		return x.ExitCodes
+	}
+	return nil
+}
+
+// ExplainTargetsRequest is a target resolved as SendCommand would, without command
+type ExplainTargetsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionIds     []string               `protobuf:"bytes,1,rep,name=minion_ids,json=minionIds,proto3" json:"minion_ids,omitempty"`
+	TagSelector   *TagSelector           `protobuf:"bytes,2,opt,name=tag_selector,json=tagSelector,proto3" json:"tag_selector,omitempty"` // ignored when minion IDs are set, as by SendCommand
+	Topology      *TopologySelector      `protobuf:"bytes,3,opt,name=topology,proto3" json:"topology,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainTargetsRequest) Reset() {
+	*x = ExplainTargetsRequest{}
+	mi := &file_minexus_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainTargetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainTargetsRequest) ProtoMessage() {}
+
+func (x *ExplainTargetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainTargetsRequest.ProtoReflect.Descriptor instead.
+func (*ExplainTargetsRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ExplainTargetsRequest) GetMinionIds() []string {
+	if x != nil {
+		return x.MinionIds
+	}
+	return nil
+}
+
+func (x *ExplainTargetsRequest) GetTagSelector() *TagSelector {
+	if x != nil {
+		return x.TagSelector
+	}
+	return nil
+}
+
+func (x *ExplainTargetsRequest) GetTopology() *TopologySelector {
+	if x != nil {
+		return x.Topology
+	}
+	return nil
+}
+
+// RuleExplanation tells why a minion satisfies a rule of the target or not
+type RuleExplanation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rule          string                 `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"` // env=prod, env (tag set), !env (tag not set), region=eu-west-1, id in a,b
+	Matched       bool                   `protobuf:"varint,2,opt,name=matched,proto3" json:"matched,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"` // e.g. "env is staging", "tag env is not set"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuleExplanation) Reset() {
+	*x = RuleExplanation{}
+	mi := &file_minexus_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuleExplanation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleExplanation) ProtoMessage() {}
+
+func (x *RuleExplanation) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleExplanation.ProtoReflect.Descriptor instead.
+func (*RuleExplanation) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RuleExplanation) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+func (x *RuleExplanation) GetMatched() bool {
+	if x != nil {
+		return x.Matched
+	}
+	return false
+}
+
+func (x *RuleExplanation) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type TargetExplanation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Matched       bool                   `protobuf:"varint,3,opt,name=matched,proto3" json:"matched,omitempty"` // every rule matched
+	Rules         []*RuleExplanation     `protobuf:"bytes,4,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TargetExplanation) Reset() {
+	*x = TargetExplanation{}
+	mi := &file_minexus_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TargetExplanation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TargetExplanation) ProtoMessage() {}
+
+func (x *TargetExplanation) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TargetExplanation.ProtoReflect.Descriptor instead.
+func (*TargetExplanation) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *TargetExplanation) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *TargetExplanation) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *TargetExplanation) GetMatched() bool {
+	if x != nil {
+		return x.Matched
+	}
+	return false
+}
+
+func (x *TargetExplanation) GetRules() []*RuleExplanation {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+type TargetExplanations struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Matched          int32                  `protobuf:"varint,1,opt,name=matched,proto3" json:"matched,omitempty"`
+	Minions          []*TargetExplanation   `protobuf:"bytes,2,rep,name=minions,proto3" json:"minions,omitempty"`                                             // the minions the console can target, matched first
+	UnknownMinionIds []string               `protobuf:"bytes,3,rep,name=unknown_minion_ids,json=unknownMinionIds,proto3" json:"unknown_minion_ids,omitempty"` // requested minion IDs not registered
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *TargetExplanations) Reset() {
+	*x = TargetExplanations{}
+	mi := &file_minexus_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TargetExplanations) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TargetExplanations) ProtoMessage() {}
+
+func (x *TargetExplanations) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TargetExplanations.ProtoReflect.Descriptor instead.
+func (*TargetExplanations) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *TargetExplanations) GetMatched() int32 {
+	if x != nil {
+		return x.Matched
+	}
+	return 0
+}
+
+func (x *TargetExplanations) GetMinions() []*TargetExplanation {
+	if x != nil {
+		return x.Minions
+	}
+	return nil
+}
+
+func (x *TargetExplanations) GetUnknownMinionIds() []string {
+	if x != nil {
+		return x.UnknownMinionIds
+	}
+	return nil
+}
+
+type CommandDispatchResponse struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	Accepted                bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	CommandId               string                 `protobuf:"bytes,2,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	TargetMinionIds         []string               `protobuf:"bytes,3,rep,name=target_minion_ids,json=targetMinionIds,proto3" json:"target_minion_ids,omitempty"` // minions receiving (or that would receive) the command
+	DryRun                  bool                   `protobuf:"varint,4,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	HeldMinionIds           []string               `protobuf:"bytes,5,rep,name=held_minion_ids,json=heldMinionIds,proto3" json:"held_minion_ids,omitempty"`                                  // minions for which the command is held until their maintenance window opens
+	SkippedMinionIds        []string               `protobuf:"bytes,6,rep,name=skipped_minion_ids,json=skippedMinionIds,proto3" json:"skipped_minion_ids,omitempty"`                         // targeted minions skipped because they lack the capability or handler version the command requires
+	ConfirmationRequired    bool                   `protobuf:"varint,7,opt,name=confirmation_required,json=confirmationRequired,proto3" json:"confirmation_required,omitempty"`              // the command is destructive: resend it with confirm_token to dispatch it
+	ConfirmToken            string                 `protobuf:"bytes,8,opt,name=confirm_token,json=confirmToken,proto3" json:"confirm_token,omitempty"`                                       // single-use token confirming this command for these targets
+	DestructiveReason       string                 `protobuf:"bytes,9,opt,name=destructive_reason,json=destructiveReason,proto3" json:"destructive_reason,omitempty"`                        // name of the destructive pattern the command matched
+	LockWaitingMinionIds    []string               `protobuf:"bytes,10,rep,name=lock_waiting_minion_ids,json=lockWaitingMinionIds,proto3" json:"lock_waiting_minion_ids,omitempty"`          // minions for which the command waits until its lock is free
+	StaggeredMinionIds      []string               `protobuf:"bytes,11,rep,name=staggered_minion_ids,json=staggeredMinionIds,proto3" json:"staggered_minion_ids,omitempty"`                  // minions reached later, the dispatch rate limits staggering the rollout
+	RolloutSeconds          int32                  `protobuf:"varint,12,opt,name=rollout_seconds,json=rolloutSeconds,proto3" json:"rollout_seconds,omitempty"`                               // time until the staggered rollout reaches its last minion
+	ApprovalRequired        bool                   `protobuf:"varint,13,opt,name=approval_required,json=approvalRequired,proto3" json:"approval_required,omitempty"`                         // the command awaits the approval of another operator, command_id identifying it
+	ApprovalReason          string                 `protobuf:"bytes,14,opt,name=approval_reason,json=approvalReason,proto3" json:"approval_reason,omitempty"`                                // name of the approval rule the command matched
+	UnversionedMinionIds    []string               `protobuf:"bytes,15,rep,name=unversioned_minion_ids,json=unversionedMinionIds,proto3" json:"unversioned_minion_ids,omitempty"`            // targets predating command versioning, which may not support the command options
+	RequiredVersion         string                 `protobuf:"bytes,16,opt,name=required_version,json=requiredVersion,proto3" json:"required_version,omitempty"`                             // command family and handler version the command requires, e.g. "shell v2"
+	TraceId                 string                 `protobuf:"bytes,17,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`                                                     // trace ID of the command, to reconstruct its timeline with GetTrace
+	Impact                  string                 `protobuf:"bytes,18,opt,name=impact,proto3" json:"impact,omitempty"`                                                                      // impact class of the command, stored with it
+	EnvUnavailable          bool                   `protobuf:"varint,19,opt,name=env_unavailable,json=envUnavailable,proto3" json:"env_unavailable,omitempty"`                               // the command environment couldn't be loaded, the command is sent without it
+	EnvUnsupportedMinionIds []string               `protobuf:"bytes,20,rep,name=env_unsupported_minion_ids,json=envUnsupportedMinionIds,proto3" json:"env_unsupported_minion_ids,omitempty"` // targets whose shell handler predates the command environment, running the command without it
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *CommandDispatchResponse) Reset() {
+	*x = CommandDispatchResponse{}
+	mi := &file_minexus_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandDispatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandDispatchResponse) ProtoMessage() {}
+
+func (x *CommandDispatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandDispatchResponse.ProtoReflect.Descriptor instead.
+func (*CommandDispatchResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CommandDispatchResponse) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *CommandDispatchResponse) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetTargetMinionIds() []string {
+	if x != nil {
+		return x.TargetMinionIds
+	}
+	return nil
+}
+
+func (x *CommandDispatchResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *CommandDispatchResponse) GetHeldMinionIds() []string {
+	if x != nil {
+		return x.HeldMinionIds
+	}
+	return nil
+}
+
+func (x *CommandDispatchResponse) GetSkippedMinionIds() []string {
+	if x != nil {
+		return x.SkippedMinionIds
+	}
+	return nil
+}
+
+func (x *CommandDispatchResponse) GetConfirmationRequired() bool {
+	if x != nil {
+		return x.ConfirmationRequired
+	}
+	return false
+}
+
+func (x *CommandDispatchResponse) GetConfirmToken() string {
+	if x != nil {
+		return x.ConfirmToken
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetDestructiveReason() string {
+	if x != nil {
+		return x.DestructiveReason
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetLockWaitingMinionIds() []string {
+	if x != nil {
+		return x.LockWaitingMinionIds
+	}
+	return nil
+}
+
+func (x *CommandDispatchResponse) GetStaggeredMinionIds() []string {
+	if x != nil {
+		return x.StaggeredMinionIds
+	}
+	return nil
+}
+
+func (x *CommandDispatchResponse) GetRolloutSeconds() int32 {
+	if x != nil {
+		return x.RolloutSeconds
+	}
+	return 0
+}
+
+func (x *CommandDispatchResponse) GetApprovalRequired() bool {
+	if x != nil {
+		return x.ApprovalRequired
+	}
+	return false
+}
+
+func (x *CommandDispatchResponse) GetApprovalReason() string {
+	if x != nil {
+		return x.ApprovalReason
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetUnversionedMinionIds() []string {
+	if x != nil {
+		return x.UnversionedMinionIds
+	}
+	return nil
+}
+
+func (x *CommandDispatchResponse) GetRequiredVersion() string {
+	if x != nil {
+		return x.RequiredVersion
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetImpact() string {
+	if x != nil {
+		return x.Impact
+	}
+	return ""
+}
+
+func (x *CommandDispatchResponse) GetEnvUnavailable() bool {
+	if x != nil {
+		return x.EnvUnavailable
+	}
+	return false
+}
+
+func (x *CommandDispatchResponse) GetEnvUnsupportedMinionIds() []string {
+	if x != nil {
+		return x.EnvUnsupportedMinionIds
+	}
+	return nil
+}
+
+type BatchCommandRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*CommandRequest      `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"` // distinct commands, each with its own targets
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCommandRequest) Reset() {
+	*x = BatchCommandRequest{}
+	mi := &file_minexus_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCommandRequest) ProtoMessage() {}
+
+func (x *BatchCommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCommandRequest.ProtoReflect.Descriptor instead.
+func (*BatchCommandRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BatchCommandRequest) GetRequests() []*CommandRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type BatchCommandResponse struct {
+	state         protoimpl.MessageState        `protogen:"open.v1"`
+	Entries       []*BatchCommandResponse_Entry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"` // in the order of the requests
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCommandResponse) Reset() {
+	*x = BatchCommandResponse{}
+	mi := &file_minexus_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCommandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCommandResponse) ProtoMessage() {}
+
+func (x *BatchCommandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCommandResponse.ProtoReflect.Descriptor instead.
+func (*BatchCommandResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BatchCommandResponse) GetEntries() []*BatchCommandResponse_Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type ResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`   // maximum number of results returned, 0 for all (GetCommandResults only)
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"` // number of results skipped, to fetch the following pages
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultRequest) Reset() {
+	*x = ResultRequest{}
+	mi := &file_minexus_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultRequest) ProtoMessage() {}
+
+func (x *ResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultRequest.ProtoReflect.Descriptor instead.
+func (*ResultRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ResultRequest) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *ResultRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ResultRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type CommandResults struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Results        []*CommandResult       `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	HasMore        bool                   `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`                      // more results follow the returned page
+	OmittedResults int32                  `protobuf:"varint,3,opt,name=omitted_results,json=omittedResults,proto3" json:"omitted_results,omitempty"` // successful results counted but not stored, the command sampling its results
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CommandResults) Reset() {
+	*x = CommandResults{}
+	mi := &file_minexus_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandResults) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResults) ProtoMessage() {}
+
+func (x *CommandResults) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResults.ProtoReflect.Descriptor instead.
+func (*CommandResults) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *CommandResults) GetResults() []*CommandResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *CommandResults) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+func (x *CommandResults) GetOmittedResults() int32 {
+	if x != nil {
+		return x.OmittedResults
+	}
+	return 0
+}
+
+// Commands for minions covered by a maintenance window are held by Nexus
+// until the window opens, unless the command is flagged as emergency.
+type MaintenanceWindow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	MinionId      string                 `protobuf:"bytes,2,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`          // target a single minion...
+	TagSelector   *TagSelector           `protobuf:"bytes,3,opt,name=tag_selector,json=tagSelector,proto3" json:"tag_selector,omitempty"` // ...or every minion matching the selector
+	Start         int64                  `protobuf:"varint,4,opt,name=start,proto3" json:"start,omitempty"`                               // Unix timestamp
+	End           int64                  `protobuf:"varint,5,opt,name=end,proto3" json:"end,omitempty"`                                   // Unix timestamp
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MaintenanceWindow) Reset() {
+	*x = MaintenanceWindow{}
+	mi := &file_minexus_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MaintenanceWindow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MaintenanceWindow) ProtoMessage() {}
+
+func (x *MaintenanceWindow) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MaintenanceWindow.ProtoReflect.Descriptor instead.
+func (*MaintenanceWindow) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *MaintenanceWindow) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *MaintenanceWindow) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MaintenanceWindow) GetTagSelector() *TagSelector {
+	if x != nil {
+		return x.TagSelector
+	}
+	return nil
+}
+
+func (x *MaintenanceWindow) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *MaintenanceWindow) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+// CommandApproval is a command requiring the approval of a second operator before its dispatch
+type CommandApproval struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	CommandId       string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Payload         string                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	TargetMinionIds []string               `protobuf:"bytes,3,rep,name=target_minion_ids,json=targetMinionIds,proto3" json:"target_minion_ids,omitempty"`
+	Reason          string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`                              // name of the approval rule the command matched
+	RequestedBy     string                 `protobuf:"bytes,5,opt,name=requested_by,json=requestedBy,proto3" json:"requested_by,omitempty"` // console certificate common name of the operator sending the command
+	RequestedAt     int64                  `protobuf:"varint,6,opt,name=requested_at,json=requestedAt,proto3" json:"requested_at,omitempty"`
+	Status          string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"` // PENDING_APPROVAL, APPROVED, REJECTED or EXPIRED
+	DecidedBy       string                 `protobuf:"bytes,8,opt,name=decided_by,json=decidedBy,proto3" json:"decided_by,omitempty"`
+	DecidedAt       int64                  `protobuf:"varint,9,opt,name=decided_at,json=decidedAt,proto3" json:"decided_at,omitempty"`
+	Comment         string                 `protobuf:"bytes,10,opt,name=comment,proto3" json:"comment,omitempty"`
+	Impact          string                 `protobuf:"bytes,11,opt,name=impact,proto3" json:"impact,omitempty"` // impact class of the command: read-only, mutating or disruptive
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CommandApproval) Reset() {
+	*x = CommandApproval{}
+	mi := &file_minexus_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandApproval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandApproval) ProtoMessage() {}
+
+func (x *CommandApproval) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandApproval.ProtoReflect.Descriptor instead.
+func (*CommandApproval) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CommandApproval) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetTargetMinionIds() []string {
+	if x != nil {
+		return x.TargetMinionIds
+	}
+	return nil
+}
+
+func (x *CommandApproval) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetRequestedBy() string {
+	if x != nil {
+		return x.RequestedBy
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetRequestedAt() int64 {
+	if x != nil {
+		return x.RequestedAt
+	}
+	return 0
+}
+
+func (x *CommandApproval) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetDecidedBy() string {
+	if x != nil {
+		return x.DecidedBy
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetDecidedAt() int64 {
+	if x != nil {
+		return x.DecidedAt
+	}
+	return 0
+}
+
+func (x *CommandApproval) GetComment() string {
+	if x != nil {
+		return x.Comment
+	}
+	return ""
+}
+
+func (x *CommandApproval) GetImpact() string {
+	if x != nil {
+		return x.Impact
+	}
+	return ""
+}
+
+type CommandApprovalList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Approvals     []*CommandApproval     `protobuf:"bytes,1,rep,name=approvals,proto3" json:"approvals,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandApprovalList) Reset() {
+	*x = CommandApprovalList{}
+	mi := &file_minexus_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandApprovalList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandApprovalList) ProtoMessage() {}
+
+func (x *CommandApprovalList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandApprovalList.ProtoReflect.Descriptor instead.
+func (*CommandApprovalList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *CommandApprovalList) GetApprovals() []*CommandApproval {
+	if x != nil {
+		return x.Approvals
+	}
+	return nil
+}
+
+type ApprovalDecision struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Reject        bool                   `protobuf:"varint,2,opt,name=reject,proto3" json:"reject,omitempty"`
+	Comment       string                 `protobuf:"bytes,3,opt,name=comment,proto3" json:"comment,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApprovalDecision) Reset() {
+	*x = ApprovalDecision{}
+	mi := &file_minexus_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApprovalDecision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovalDecision) ProtoMessage() {}
+
+func (x *ApprovalDecision) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovalDecision.ProtoReflect.Descriptor instead.
+func (*ApprovalDecision) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ApprovalDecision) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *ApprovalDecision) GetReject() bool {
+	if x != nil {
+		return x.Reject
+	}
+	return false
+}
+
+func (x *ApprovalDecision) GetComment() string {
+	if x != nil {
+		return x.Comment
+	}
+	return ""
+}
+
+type MaintenanceWindowList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Windows       []*MaintenanceWindow   `protobuf:"bytes,1,rep,name=windows,proto3" json:"windows,omitempty"`
+	HeldCommands  int32                  `protobuf:"varint,2,opt,name=held_commands,json=heldCommands,proto3" json:"held_commands,omitempty"` // commands currently waiting for a window
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MaintenanceWindowList) Reset() {
+	*x = MaintenanceWindowList{}
+	mi := &file_minexus_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MaintenanceWindowList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MaintenanceWindowList) ProtoMessage() {}
+
+func (x *MaintenanceWindowList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MaintenanceWindowList.ProtoReflect.Descriptor instead.
+func (*MaintenanceWindowList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *MaintenanceWindowList) GetWindows() []*MaintenanceWindow {
+	if x != nil {
+		return x.Windows
+	}
+	return nil
+}
+
+func (x *MaintenanceWindowList) GetHeldCommands() int32 {
+	if x != nil {
+		return x.HeldCommands
+	}
+	return 0
+}
+
+type MaintenanceWindowRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MaintenanceWindowRequest) Reset() {
+	*x = MaintenanceWindowRequest{}
+	mi := &file_minexus_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MaintenanceWindowRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MaintenanceWindowRequest) ProtoMessage() {}
+
+func (x *MaintenanceWindowRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MaintenanceWindowRequest.ProtoReflect.Descriptor instead.
+func (*MaintenanceWindowRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *MaintenanceWindowRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Report is a saved query over command results, e.g.
+// "SELECT count, failure_rate BY tag:env SINCE $period"
+type Report struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Query         string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix timestamp, set by Nexus
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	mi := &file_minexus_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *Report) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Report) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *Report) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Report) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type ReportList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reports       []*Report              `protobuf:"bytes,1,rep,name=reports,proto3" json:"reports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportList) Reset() {
+	*x = ReportList{}
+	mi := &file_minexus_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportList) ProtoMessage() {}
+
+func (x *ReportList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportList.ProtoReflect.Descriptor instead.
+func (*ReportList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ReportList) GetReports() []*Report {
+	if x != nil {
+		return x.Reports
+	}
+	return nil
+}
+
+type ReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // values of the $placeholders of the query
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportRequest) Reset() {
+	*x = ReportRequest{}
+	mi := &file_minexus_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRequest) ProtoMessage() {}
+
+func (x *ReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRequest.ProtoReflect.Descriptor instead.
+func (*ReportRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ReportRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ReportRequest) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type ReportRow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        []string               `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportRow) Reset() {
+	*x = ReportRow{}
+	mi := &file_minexus_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRow) ProtoMessage() {}
+
+func (x *ReportRow) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRow.ProtoReflect.Descriptor instead.
+func (*ReportRow) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *ReportRow) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type ReportResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Columns       []string               `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
+	Rows          []*ReportRow           `protobuf:"bytes,3,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportResult) Reset() {
+	*x = ReportResult{}
+	mi := &file_minexus_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportResult) ProtoMessage() {}
+
+func (x *ReportResult) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportResult.ProtoReflect.Descriptor instead.
+func (*ReportResult) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *ReportResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ReportResult) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *ReportResult) GetRows() []*ReportRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type DatabaseCheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Repair        bool                   `protobuf:"varint,1,opt,name=repair,proto3" json:"repair,omitempty"` // fix the inconsistencies which can be repaired
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseCheckRequest) Reset() {
+	*x = DatabaseCheckRequest{}
+	mi := &file_minexus_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseCheckRequest) ProtoMessage() {}
+
+func (x *DatabaseCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseCheckRequest.ProtoReflect.Descriptor instead.
+func (*DatabaseCheckRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *DatabaseCheckRequest) GetRepair() bool {
+	if x != nil {
+		return x.Repair
+	}
+	return false
+}
+
+type DatabaseCheck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // e.g. "orphaned_results"
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Found         int64                  `protobuf:"varint,3,opt,name=found,proto3" json:"found,omitempty"`       // number of inconsistent rows
+	Repaired      int64                  `protobuf:"varint,4,opt,name=repaired,proto3" json:"repaired,omitempty"` // number of rows fixed, with repair
+	Repairable    bool                   `protobuf:"varint,5,opt,name=repairable,proto3" json:"repairable,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"` // set when the check could not run
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseCheck) Reset() {
+	*x = DatabaseCheck{}
+	mi := &file_minexus_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseCheck) ProtoMessage() {}
+
+func (x *DatabaseCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseCheck.ProtoReflect.Descriptor instead.
+func (*DatabaseCheck) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *DatabaseCheck) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DatabaseCheck) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *DatabaseCheck) GetFound() int64 {
+	if x != nil {
+		return x.Found
+	}
+	return 0
+}
+
+func (x *DatabaseCheck) GetRepaired() int64 {
+	if x != nil {
+		return x.Repaired
+	}
+	return 0
+}
+
+func (x *DatabaseCheck) GetRepairable() bool {
+	if x != nil {
+		return x.Repairable
+	}
+	return false
+}
+
+func (x *DatabaseCheck) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type DatabaseCheckReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Checks        []*DatabaseCheck       `protobuf:"bytes,1,rep,name=checks,proto3" json:"checks,omitempty"`
+	Healthy       bool                   `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"` // no inconsistency left and all checks ran
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseCheckReport) Reset() {
+	*x = DatabaseCheckReport{}
+	mi := &file_minexus_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseCheckReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseCheckReport) ProtoMessage() {}
+
+func (x *DatabaseCheckReport) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseCheckReport.ProtoReflect.Descriptor instead.
+func (*DatabaseCheckReport) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *DatabaseCheckReport) GetChecks() []*DatabaseCheck {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
+func (x *DatabaseCheckReport) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+// DatabaseQuery is a read-only query approved on Nexus, run by name from consoles
+type DatabaseQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Params        []string               `protobuf:"bytes,3,rep,name=params,proto3" json:"params,omitempty"` // names of the parameters bound to $1, $2...
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseQuery) Reset() {
+	*x = DatabaseQuery{}
+	mi := &file_minexus_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseQuery) ProtoMessage() {}
+
+func (x *DatabaseQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseQuery.ProtoReflect.Descriptor instead.
+func (*DatabaseQuery) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *DatabaseQuery) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DatabaseQuery) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *DatabaseQuery) GetParams() []string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type DatabaseQueryList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Queries       []*DatabaseQuery       `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseQueryList) Reset() {
+	*x = DatabaseQueryList{}
+	mi := &file_minexus_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseQueryList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseQueryList) ProtoMessage() {}
+
+func (x *DatabaseQueryList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseQueryList.ProtoReflect.Descriptor instead.
+func (*DatabaseQueryList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *DatabaseQueryList) GetQueries() []*DatabaseQuery {
+	if x != nil {
+		return x.Queries
+	}
+	return nil
+}
+
+type DatabaseQueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseQueryRequest) Reset() {
+	*x = DatabaseQueryRequest{}
+	mi := &file_minexus_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseQueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseQueryRequest) ProtoMessage() {}
+
+func (x *DatabaseQueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseQueryRequest.ProtoReflect.Descriptor instead.
+func (*DatabaseQueryRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *DatabaseQueryRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DatabaseQueryRequest) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type DatabaseQueryResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Columns       []string               `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
+	Rows          []*ReportRow           `protobuf:"bytes,3,rep,name=rows,proto3" json:"rows,omitempty"`
+	Truncated     bool                   `protobuf:"varint,4,opt,name=truncated,proto3" json:"truncated,omitempty"` // more rows than the query limit were returned
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DatabaseQueryResult) Reset() {
+	*x = DatabaseQueryResult{}
+	mi := &file_minexus_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DatabaseQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DatabaseQueryResult) ProtoMessage() {}
+
+func (x *DatabaseQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DatabaseQueryResult.ProtoReflect.Descriptor instead.
+func (*DatabaseQueryResult) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *DatabaseQueryResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DatabaseQueryResult) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *DatabaseQueryResult) GetRows() []*ReportRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *DatabaseQueryResult) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type MinionHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"` // number of commands returned, 0 for the default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionHistoryRequest) Reset() {
+	*x = MinionHistoryRequest{}
+	mi := &file_minexus_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionHistoryRequest) ProtoMessage() {}
+
+func (x *MinionHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionHistoryRequest.ProtoReflect.Descriptor instead.
+func (*MinionHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *MinionHistoryRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionHistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type MinionHistoryEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Command       string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`                         // "PENDING", "RECEIVED", "EXECUTING", "COMPLETED", "FAILED"
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                  // Unix timestamp the command was sent
+	HasResult     bool                   `protobuf:"varint,5,opt,name=has_result,json=hasResult,proto3" json:"has_result,omitempty"` // exit_code and duration_ms are only set with a result
+	ExitCode      int32                  `protobuf:"varint,6,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,7,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"` // from dispatch to result
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionHistoryEntry) Reset() {
+	*x = MinionHistoryEntry{}
+	mi := &file_minexus_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionHistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionHistoryEntry) ProtoMessage() {}
+
+func (x *MinionHistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionHistoryEntry.ProtoReflect.Descriptor instead.
+func (*MinionHistoryEntry) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *MinionHistoryEntry) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *MinionHistoryEntry) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *MinionHistoryEntry) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *MinionHistoryEntry) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *MinionHistoryEntry) GetHasResult() bool {
+	if x != nil {
+		return x.HasResult
+	}
+	return false
+}
+
+func (x *MinionHistoryEntry) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *MinionHistoryEntry) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+type MinionHistory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Entries       []*MinionHistoryEntry  `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"` // most recent first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionHistory) Reset() {
+	*x = MinionHistory{}
+	mi := &file_minexus_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionHistory) ProtoMessage() {}
+
+func (x *MinionHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionHistory.ProtoReflect.Descriptor instead.
+func (*MinionHistory) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *MinionHistory) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionHistory) GetEntries() []*MinionHistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type MinionUptimeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	WindowSeconds int64                  `protobuf:"varint,2,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"` // period ending now, 0 for the last 24 hours
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionUptimeRequest) Reset() {
+	*x = MinionUptimeRequest{}
+	mi := &file_minexus_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionUptimeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionUptimeRequest) ProtoMessage() {}
+
+func (x *MinionUptimeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionUptimeRequest.ProtoReflect.Descriptor instead.
+func (*MinionUptimeRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *MinionUptimeRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionUptimeRequest) GetWindowSeconds() int64 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+type ConnectionPeriod struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConnectedAt    int64                  `protobuf:"varint,1,opt,name=connected_at,json=connectedAt,proto3" json:"connected_at,omitempty"`          // Unix timestamp, clipped to the start of the window
+	DisconnectedAt int64                  `protobuf:"varint,2,opt,name=disconnected_at,json=disconnectedAt,proto3" json:"disconnected_at,omitempty"` // Unix timestamp, 0 while still connected
+	Reason         string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`                                        // why the command stream ended
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ConnectionPeriod) Reset() {
+	*x = ConnectionPeriod{}
+	mi := &file_minexus_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionPeriod) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionPeriod) ProtoMessage() {}
+
+func (x *ConnectionPeriod) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionPeriod.ProtoReflect.Descriptor instead.
+func (*ConnectionPeriod) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ConnectionPeriod) GetConnectedAt() int64 {
+	if x != nil {
+		return x.ConnectedAt
+	}
+	return 0
+}
+
+func (x *ConnectionPeriod) GetDisconnectedAt() int64 {
+	if x != nil {
+		return x.DisconnectedAt
+	}
+	return 0
+}
+
+func (x *ConnectionPeriod) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type MinionUptime struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Since         int64                  `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`                                       // Unix timestamp of the start of the window
+	Until         int64                  `protobuf:"varint,3,opt,name=until,proto3" json:"until,omitempty"`                                       // Unix timestamp of the end of the window
+	UptimePercent float64                `protobuf:"fixed64,4,opt,name=uptime_percent,json=uptimePercent,proto3" json:"uptime_percent,omitempty"` // share of the window with an open command stream
+	Periods       []*ConnectionPeriod    `protobuf:"bytes,5,rep,name=periods,proto3" json:"periods,omitempty"`                                    // oldest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionUptime) Reset() {
+	*x = MinionUptime{}
+	mi := &file_minexus_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionUptime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionUptime) ProtoMessage() {}
+
+func (x *MinionUptime) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionUptime.ProtoReflect.Descriptor instead.
+func (*MinionUptime) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *MinionUptime) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionUptime) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *MinionUptime) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+func (x *MinionUptime) GetUptimePercent() float64 {
+	if x != nil {
+		return x.UptimePercent
+	}
+	return 0
+}
+
+func (x *MinionUptime) GetPeriods() []*ConnectionPeriod {
+	if x != nil {
+		return x.Periods
+	}
+	return nil
+}
+
+// MinionLogEntry is a structured log entry of a minion
+type MinionLogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TimestampMs   int64                  `protobuf:"varint,1,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"` // Unix timestamp in milliseconds
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`                                 // "debug", "info", "warn", "error", ...
+	Logger        string                 `protobuf:"bytes,3,opt,name=logger,proto3" json:"logger,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Caller        string                 `protobuf:"bytes,5,opt,name=caller,proto3" json:"caller,omitempty"`
+	Fields        string                 `protobuf:"bytes,6,opt,name=fields,proto3" json:"fields,omitempty"` // JSON object of the structured fields
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionLogEntry) Reset() {
+	*x = MinionLogEntry{}
+	mi := &file_minexus_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionLogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionLogEntry) ProtoMessage() {}
+
+func (x *MinionLogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionLogEntry.ProtoReflect.Descriptor instead.
+func (*MinionLogEntry) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *MinionLogEntry) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+func (x *MinionLogEntry) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *MinionLogEntry) GetLogger() string {
+	if x != nil {
+		return x.Logger
+	}
+	return ""
+}
+
+func (x *MinionLogEntry) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *MinionLogEntry) GetCaller() string {
+	if x != nil {
+		return x.Caller
+	}
+	return ""
+}
+
+func (x *MinionLogEntry) GetFields() string {
+	if x != nil {
+		return x.Fields
+	}
+	return ""
+}
+
+// MinionLogBatch carries the logs a minion ships to Nexus
+type MinionLogBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*MinionLogEntry      `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Dropped       int64                  `protobuf:"varint,2,opt,name=dropped,proto3" json:"dropped,omitempty"` // entries dropped since the previous batch, the minion buffer being full
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionLogBatch) Reset() {
+	*x = MinionLogBatch{}
+	mi := &file_minexus_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionLogBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionLogBatch) ProtoMessage() {}
+
+func (x *MinionLogBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionLogBatch.ProtoReflect.Descriptor instead.
+func (*MinionLogBatch) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *MinionLogBatch) GetEntries() []*MinionLogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *MinionLogBatch) GetDropped() int64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+type MinionLogsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	MinLevel      string                 `protobuf:"bytes,2,opt,name=min_level,json=minLevel,proto3" json:"min_level,omitempty"` // lowest level returned, empty for every level
+	Since         int64                  `protobuf:"varint,3,opt,name=since,proto3" json:"since,omitempty"`                      // Unix timestamp, 0 for every stored entry
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                      // number of entries returned, 0 for the default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionLogsRequest) Reset() {
+	*x = MinionLogsRequest{}
+	mi := &file_minexus_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionLogsRequest) ProtoMessage() {}
+
+func (x *MinionLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionLogsRequest.ProtoReflect.Descriptor instead.
+func (*MinionLogsRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *MinionLogsRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionLogsRequest) GetMinLevel() string {
+	if x != nil {
+		return x.MinLevel
+	}
+	return ""
+}
+
+func (x *MinionLogsRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *MinionLogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type MinionLogs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Entries       []*MinionLogEntry      `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"` // most recent first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionLogs) Reset() {
+	*x = MinionLogs{}
+	mi := &file_minexus_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionLogs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionLogs) ProtoMessage() {}
+
+func (x *MinionLogs) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionLogs.ProtoReflect.Descriptor instead.
+func (*MinionLogs) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *MinionLogs) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionLogs) GetEntries() []*MinionLogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// RetireMinionRequest decommissions a host: Nexus stops dispatching to it, tells the minion to stop
+// and moves its host and result rows to the history tables
+type RetireMinionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Uninstall     bool                   `protobuf:"varint,2,opt,name=uninstall,proto3" json:"uninstall,omitempty"` // the minion removes its executable once stopped
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetireMinionRequest) Reset() {
+	*x = RetireMinionRequest{}
+	mi := &file_minexus_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetireMinionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetireMinionRequest) ProtoMessage() {}
+
+func (x *RetireMinionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetireMinionRequest.ProtoReflect.Descriptor instead.
+func (*RetireMinionRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *RetireMinionRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *RetireMinionRequest) GetUninstall() bool {
+	if x != nil {
+		return x.Uninstall
+	}
+	return false
+}
+
+func (x *RetireMinionRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type RetireMinionResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	MinionId        string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Notified        bool                   `protobuf:"varint,2,opt,name=notified,proto3" json:"notified,omitempty"`                                      // the minion was connected and told to stop, else it is told when it registers again
+	ArchivedResults int32                  `protobuf:"varint,3,opt,name=archived_results,json=archivedResults,proto3" json:"archived_results,omitempty"` // results moved to the history tables
+	DroppedCommands int32                  `protobuf:"varint,4,opt,name=dropped_commands,json=droppedCommands,proto3" json:"dropped_commands,omitempty"` // commands queued for the minion, never sent
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RetireMinionResponse) Reset() {
+	*x = RetireMinionResponse{}
+	mi := &file_minexus_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetireMinionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetireMinionResponse) ProtoMessage() {}
+
+func (x *RetireMinionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetireMinionResponse.ProtoReflect.Descriptor instead.
+func (*RetireMinionResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *RetireMinionResponse) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *RetireMinionResponse) GetNotified() bool {
+	if x != nil {
+		return x.Notified
+	}
+	return false
+}
+
+func (x *RetireMinionResponse) GetArchivedResults() int32 {
+	if x != nil {
+		return x.ArchivedResults
+	}
+	return 0
+}
+
+func (x *RetireMinionResponse) GetDroppedCommands() int32 {
+	if x != nil {
+		return x.DroppedCommands
+	}
+	return 0
+}
+
+// RetireNotice tells a retired minion to stop for good
+type RetireNotice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Uninstall     bool                   `protobuf:"varint,1,opt,name=uninstall,proto3" json:"uninstall,omitempty"` // remove the executable of the minion once stopped
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	RetiredBy     string                 `protobuf:"bytes,3,opt,name=retired_by,json=retiredBy,proto3" json:"retired_by,omitempty"` // console identity of the operator who retired the minion
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetireNotice) Reset() {
+	*x = RetireNotice{}
+	mi := &file_minexus_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetireNotice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetireNotice) ProtoMessage() {}
+
+func (x *RetireNotice) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetireNotice.ProtoReflect.Descriptor instead.
+func (*RetireNotice) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *RetireNotice) GetUninstall() bool {
+	if x != nil {
+		return x.Uninstall
+	}
+	return false
+}
+
+func (x *RetireNotice) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RetireNotice) GetRetiredBy() string {
+	if x != nil {
+		return x.RetiredBy
+	}
+	return ""
+}
+
+// CrashReport is a panic recovered by a minion, sent to Nexus at its next registration
+type CrashReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`              // Unix timestamp of the panic
+	Component     string                 `protobuf:"bytes,2,opt,name=component,proto3" json:"component,omitempty"`               // what panicked, e.g. "command <id>" or "scheduler"
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`                   // value the minion panicked with
+	Stack         string                 `protobuf:"bytes,4,opt,name=stack,proto3" json:"stack,omitempty"`                       // stack trace of the panicking goroutine
+	Version       string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`                   // version of the minion
+	MinionId      string                 `protobuf:"bytes,6,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"` // set by Nexus in crash lists
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrashReport) Reset() {
+	*x = CrashReport{}
+	mi := &file_minexus_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrashReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrashReport) ProtoMessage() {}
+
+func (x *CrashReport) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrashReport.ProtoReflect.Descriptor instead.
+func (*CrashReport) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *CrashReport) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *CrashReport) GetComponent() string {
+	if x != nil {
+		return x.Component
+	}
+	return ""
+}
+
+func (x *CrashReport) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CrashReport) GetStack() string {
+	if x != nil {
+		return x.Stack
+	}
+	return ""
+}
+
+func (x *CrashReport) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *CrashReport) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+type CrashListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"` // empty for every minion
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                      // number of crashes returned, 0 for the default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrashListRequest) Reset() {
+	*x = CrashListRequest{}
+	mi := &file_minexus_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrashListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrashListRequest) ProtoMessage() {}
+
+func (x *CrashListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrashListRequest.ProtoReflect.Descriptor instead.
+func (*CrashListRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *CrashListRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *CrashListRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type CrashList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Crashes       []*CrashReport         `protobuf:"bytes,1,rep,name=crashes,proto3" json:"crashes,omitempty"` // most recent first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrashList) Reset() {
+	*x = CrashList{}
+	mi := &file_minexus_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrashList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrashList) ProtoMessage() {}
+
+func (x *CrashList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrashList.ProtoReflect.Descriptor instead.
+func (*CrashList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *CrashList) GetCrashes() []*CrashReport {
+	if x != nil {
+		return x.Crashes
+	}
+	return nil
+}
+
+// CommandEnvVar is an environment variable Nexus sets for the shell commands of a minion, or of every
+// minion with a tag, variables of a minion overriding those of its tags
+type CommandEnvVar struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"` // target a single minion...
+	TagKey        string                 `protobuf:"bytes,2,opt,name=tag_key,json=tagKey,proto3" json:"tag_key,omitempty"`       // ...or every minion with this tag
+	TagValue      string                 `protobuf:"bytes,3,opt,name=tag_value,json=tagValue,proto3" json:"tag_value,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`                           // ignored by UnsetCommandEnv
+	UpdatedAt     int64                  `protobuf:"varint,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // unix timestamp, set by Nexus
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandEnvVar) Reset() {
+	*x = CommandEnvVar{}
+	mi := &file_minexus_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandEnvVar) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandEnvVar) ProtoMessage() {}
+
+func (x *CommandEnvVar) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandEnvVar.ProtoReflect.Descriptor instead.
+func (*CommandEnvVar) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *CommandEnvVar) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *CommandEnvVar) GetTagKey() string {
+	if x != nil {
+		return x.TagKey
+	}
+	return ""
+}
+
+func (x *CommandEnvVar) GetTagValue() string {
+	if x != nil {
+		return x.TagValue
+	}
+	return ""
+}
+
+func (x *CommandEnvVar) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CommandEnvVar) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *CommandEnvVar) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type CommandEnvList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Vars          []*CommandEnvVar       `protobuf:"bytes,1,rep,name=vars,proto3" json:"vars,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandEnvList) Reset() {
+	*x = CommandEnvList{}
+	mi := &file_minexus_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandEnvList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandEnvList) ProtoMessage() {}
+
+func (x *CommandEnvList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandEnvList.ProtoReflect.Descriptor instead.
+func (*CommandEnvList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{63}
+}
+
+func (x *CommandEnvList) GetVars() []*CommandEnvVar {
+	if x != nil {
+		return x.Vars
+	}
+	return nil
+}
+
+type TraceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraceId       string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TraceRequest) Reset() {
+	*x = TraceRequest{}
+	mi := &file_minexus_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TraceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceRequest) ProtoMessage() {}
+
+func (x *TraceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceRequest.ProtoReflect.Descriptor instead.
+func (*TraceRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *TraceRequest) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+type TraceEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TimestampMs   int64                  `protobuf:"varint,1,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"` // Unix time in milliseconds, from the Nexus clock
+	Component     string                 `protobuf:"bytes,2,opt,name=component,proto3" json:"component,omitempty"`                         // "nexus" or "minion"
+	Event         string                 `protobuf:"bytes,3,opt,name=event,proto3" json:"event,omitempty"`                                 // "DISPATCHED", "RECEIVED", "EXECUTING", "COMPLETED", "FAILED", "RESULT"
+	CommandId     string                 `protobuf:"bytes,4,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	MinionId      string                 `protobuf:"bytes,5,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Detail        string                 `protobuf:"bytes,6,opt,name=detail,proto3" json:"detail,omitempty"` // command payload for DISPATCHED, exit code for RESULT
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TraceEvent) Reset() {
+	*x = TraceEvent{}
+	mi := &file_minexus_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TraceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceEvent) ProtoMessage() {}
+
+func (x *TraceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceEvent.ProtoReflect.Descriptor instead.
+func (*TraceEvent) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *TraceEvent) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+func (x *TraceEvent) GetComponent() string {
+	if x != nil {
+		return x.Component
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *TraceEvent) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type Trace struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TraceId       string                 `protobuf:"bytes,1,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	Events        []*TraceEvent          `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"` // oldest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Trace) Reset() {
+	*x = Trace{}
+	mi := &file_minexus_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Trace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trace) ProtoMessage() {}
+
+func (x *Trace) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trace.ProtoReflect.Descriptor instead.
+func (*Trace) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{66}
+}
+
+func (x *Trace) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *Trace) GetEvents() []*TraceEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type CommandStatsRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Since          int64                  `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`                                        // Unix timestamp of the oldest result counted, 0 for 24 hours before until
+	Until          int64                  `protobuf:"varint,2,opt,name=until,proto3" json:"until,omitempty"`                                        // Unix timestamp of the end of the range, 0 for now
+	CommandPattern string                 `protobuf:"bytes,3,opt,name=command_pattern,json=commandPattern,proto3" json:"command_pattern,omitempty"` // commands counted, * is a wildcard, e.g. "docker:*"; empty for all
+	Slowest        int32                  `protobuf:"varint,4,opt,name=slowest,proto3" json:"slowest,omitempty"`                                    // number of slowest minions returned, 0 for the default
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CommandStatsRequest) Reset() {
+	*x = CommandStatsRequest{}
+	mi := &file_minexus_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandStatsRequest) ProtoMessage() {}
+
+func (x *CommandStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandStatsRequest.ProtoReflect.Descriptor instead.
+func (*CommandStatsRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *CommandStatsRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *CommandStatsRequest) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+func (x *CommandStatsRequest) GetCommandPattern() string {
+	if x != nil {
+		return x.CommandPattern
+	}
+	return ""
+}
+
+func (x *CommandStatsRequest) GetSlowest() int32 {
+	if x != nil {
+		return x.Slowest
+	}
+	return 0
+}
+
+type MinionCommandStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Failures      int64                  `protobuf:"varint,3,opt,name=failures,proto3" json:"failures,omitempty"` // results with a non-zero exit code
+	MedianMs      int64                  `protobuf:"varint,4,opt,name=median_ms,json=medianMs,proto3" json:"median_ms,omitempty"`
+	MaxMs         int64                  `protobuf:"varint,5,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionCommandStats) Reset() {
+	*x = MinionCommandStats{}
+	mi := &file_minexus_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionCommandStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionCommandStats) ProtoMessage() {}
+
+func (x *MinionCommandStats) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionCommandStats.ProtoReflect.Descriptor instead.
+func (*MinionCommandStats) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *MinionCommandStats) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionCommandStats) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *MinionCommandStats) GetFailures() int64 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *MinionCommandStats) GetMedianMs() int64 {
+	if x != nil {
+		return x.MedianMs
+	}
+	return 0
+}
+
+func (x *MinionCommandStats) GetMaxMs() int64 {
+	if x != nil {
+		return x.MaxMs
+	}
+	return 0
+}
+
+type ImpactCommandStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Impact        string                 `protobuf:"bytes,1,opt,name=impact,proto3" json:"impact,omitempty"` // impact class, empty for the commands stored before impact classes
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Failures      int64                  `protobuf:"varint,3,opt,name=failures,proto3" json:"failures,omitempty"` // results with a non-zero exit code
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImpactCommandStats) Reset() {
+	*x = ImpactCommandStats{}
+	mi := &file_minexus_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImpactCommandStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImpactCommandStats) ProtoMessage() {}
+
+func (x *ImpactCommandStats) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImpactCommandStats.ProtoReflect.Descriptor instead.
+func (*ImpactCommandStats) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{69}
+}
+
+func (x *ImpactCommandStats) GetImpact() string {
+	if x != nil {
+		return x.Impact
+	}
+	return ""
+}
+
+func (x *ImpactCommandStats) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ImpactCommandStats) GetFailures() int64 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+// Durations go from the dispatch of a command to its result
+type CommandStats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Since          int64                  `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`
+	Until          int64                  `protobuf:"varint,2,opt,name=until,proto3" json:"until,omitempty"`
+	Count          int64                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`       // results received in the range
+	Failures       int64                  `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"` // results with a non-zero exit code
+	MedianMs       int64                  `protobuf:"varint,5,opt,name=median_ms,json=medianMs,proto3" json:"median_ms,omitempty"`
+	P90Ms          int64                  `protobuf:"varint,6,opt,name=p90_ms,json=p90Ms,proto3" json:"p90_ms,omitempty"`
+	P99Ms          int64                  `protobuf:"varint,7,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
+	MaxMs          int64                  `protobuf:"varint,8,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+	SlowestMinions []*MinionCommandStats  `protobuf:"bytes,9,rep,name=slowest_minions,json=slowestMinions,proto3" json:"slowest_minions,omitempty"` // highest median first
+	ByImpact       []*ImpactCommandStats  `protobuf:"bytes,10,rep,name=by_impact,json=byImpact,proto3" json:"by_impact,omitempty"`                  // least dangerous class first
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CommandStats) Reset() {
+	*x = CommandStats{}
+	mi := &file_minexus_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandStats) ProtoMessage() {}
+
+func (x *CommandStats) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandStats.ProtoReflect.Descriptor instead.
+func (*CommandStats) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *CommandStats) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *CommandStats) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+func (x *CommandStats) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CommandStats) GetFailures() int64 {
+	if x != nil {
+		return x.Failures
+	}
+	return 0
+}
+
+func (x *CommandStats) GetMedianMs() int64 {
+	if x != nil {
+		return x.MedianMs
+	}
+	return 0
+}
+
+func (x *CommandStats) GetP90Ms() int64 {
+	if x != nil {
+		return x.P90Ms
+	}
+	return 0
+}
+
+func (x *CommandStats) GetP99Ms() int64 {
+	if x != nil {
+		return x.P99Ms
+	}
+	return 0
+}
+
+func (x *CommandStats) GetMaxMs() int64 {
+	if x != nil {
+		return x.MaxMs
+	}
+	return 0
+}
+
+func (x *CommandStats) GetSlowestMinions() []*MinionCommandStats {
+	if x != nil {
+		return x.SlowestMinions
+	}
+	return nil
+}
+
+func (x *CommandStats) GetByImpact() []*ImpactCommandStats {
+	if x != nil {
+		return x.ByImpact
+	}
+	return nil
+}
+
+type ReceiptExportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Since         int64                  `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"` // Unix timestamp of the oldest command sent exported
+	Until         int64                  `protobuf:"varint,2,opt,name=until,proto3" json:"until,omitempty"` // Unix timestamp of the end of the range, 0 for now
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReceiptExportRequest) Reset() {
+	*x = ReceiptExportRequest{}
+	mi := &file_minexus_proto_msgTypes[71]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReceiptExportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReceiptExportRequest) ProtoMessage() {}
+
+func (x *ReceiptExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[71]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReceiptExportRequest.ProtoReflect.Descriptor instead.
+func (*ReceiptExportRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{71}
+}
+
+func (x *ReceiptExportRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *ReceiptExportRequest) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+// ExecutionReceipt is the receipt of a command signed by Nexus. The receipts of an export are chained,
+// each receipt holding the digest of the previous one and the number of receipts exported.
+type ExecutionReceipt struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Receipt           []byte                 `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`                                              // JSON of the receipt, covered by the signature
+	Signature         []byte                 `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`                                          // Nexus signature of receipt
+	SignerCertificate []byte                 `protobuf:"bytes,3,opt,name=signer_certificate,json=signerCertificate,proto3" json:"signer_certificate,omitempty"` // PEM certificate of Nexus
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ExecutionReceipt) Reset() {
+	*x = ExecutionReceipt{}
+	mi := &file_minexus_proto_msgTypes[72]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutionReceipt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionReceipt) ProtoMessage() {}
+
+func (x *ExecutionReceipt) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[72]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionReceipt.ProtoReflect.Descriptor instead.
+func (*ExecutionReceipt) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{72}
+}
+
+func (x *ExecutionReceipt) GetReceipt() []byte {
+	if x != nil {
+		return x.Receipt
+	}
+	return nil
+}
+
+func (x *ExecutionReceipt) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *ExecutionReceipt) GetSignerCertificate() []byte {
+	if x != nil {
+		return x.SignerCertificate
+	}
+	return nil
+}
+
+type MinionDiagnosticsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionDiagnosticsRequest) Reset() {
+	*x = MinionDiagnosticsRequest{}
+	mi := &file_minexus_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionDiagnosticsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionDiagnosticsRequest) ProtoMessage() {}
+
+func (x *MinionDiagnosticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[73]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionDiagnosticsRequest.ProtoReflect.Descriptor instead.
+func (*MinionDiagnosticsRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *MinionDiagnosticsRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+type ConnectionEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Event         string                 `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"` // "REGISTERED", "STREAM_OPENED", "STREAM_CLOSED", "ERROR"
+	Detail        string                 `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectionEvent) Reset() {
+	*x = ConnectionEvent{}
+	mi := &file_minexus_proto_msgTypes[74]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionEvent) ProtoMessage() {}
+
+func (x *ConnectionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[74]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionEvent.ProtoReflect.Descriptor instead.
+func (*ConnectionEvent) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{74}
+}
+
+func (x *ConnectionEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ConnectionEvent) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *ConnectionEvent) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type MinionDiagnostics struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MinionId          string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Registered        bool                   `protobuf:"varint,2,opt,name=registered,proto3" json:"registered,omitempty"`
+	StreamState       string                 `protobuf:"bytes,3,opt,name=stream_state,json=streamState,proto3" json:"stream_state,omitempty"`                      // "CONNECTED", "DISCONNECTED", "NEVER_CONNECTED"
+	ActiveStreams     int32                  `protobuf:"varint,4,opt,name=active_streams,json=activeStreams,proto3" json:"active_streams,omitempty"`               // more than one indicates concurrent streams for the same minion
+	StreamConnectedAt int64                  `protobuf:"varint,5,opt,name=stream_connected_at,json=streamConnectedAt,proto3" json:"stream_connected_at,omitempty"` // Unix timestamp of the current or last stream opening
+	LastSeen          int64                  `protobuf:"varint,6,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	ChannelDepth      int32                  `protobuf:"varint,7,opt,name=channel_depth,json=channelDepth,proto3" json:"channel_depth,omitempty"` // commands waiting in the dispatch channel
+	ChannelCapacity   int32                  `protobuf:"varint,8,opt,name=channel_capacity,json=channelCapacity,proto3" json:"channel_capacity,omitempty"`
+	PendingCommands   int32                  `protobuf:"varint,9,opt,name=pending_commands,json=pendingCommands,proto3" json:"pending_commands,omitempty"` // dispatched commands without result
+	HeldCommands      int32                  `protobuf:"varint,10,opt,name=held_commands,json=heldCommands,proto3" json:"held_commands,omitempty"`         // commands held by a maintenance window
+	CommandsSent      int64                  `protobuf:"varint,11,opt,name=commands_sent,json=commandsSent,proto3" json:"commands_sent,omitempty"`
+	ResultsReceived   int64                  `protobuf:"varint,12,opt,name=results_received,json=resultsReceived,proto3" json:"results_received,omitempty"`
+	Events            []*ConnectionEvent     `protobuf:"bytes,13,rep,name=events,proto3" json:"events,omitempty"` // most recent last
+	LastError         string                 `protobuf:"bytes,14,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastErrorAt       int64                  `protobuf:"varint,15,opt,name=last_error_at,json=lastErrorAt,proto3" json:"last_error_at,omitempty"`
+	InFlight          int32                  `protobuf:"varint,16,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`                  // dispatched commands counted against the in-flight limit
+	InFlightLimit     int32                  `protobuf:"varint,17,opt,name=in_flight_limit,json=inFlightLimit,proto3" json:"in_flight_limit,omitempty"` // 0: unlimited
+	LastSpeedTest     *SpeedTestSummary      `protobuf:"bytes,18,opt,name=last_speed_test,json=lastSpeedTest,proto3" json:"last_speed_test,omitempty"`  // last successful net:speedtest, unset without one
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *MinionDiagnostics) Reset() {
+	*x = MinionDiagnostics{}
+	mi := &file_minexus_proto_msgTypes[75]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionDiagnostics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionDiagnostics) ProtoMessage() {}
+
+func (x *MinionDiagnostics) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[75]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionDiagnostics.ProtoReflect.Descriptor instead.
+func (*MinionDiagnostics) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{75}
+}
+
+func (x *MinionDiagnostics) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *MinionDiagnostics) GetRegistered() bool {
+	if x != nil {
+		return x.Registered
+	}
+	return false
+}
+
+func (x *MinionDiagnostics) GetStreamState() string {
+	if x != nil {
+		return x.StreamState
+	}
+	return ""
+}
+
+func (x *MinionDiagnostics) GetActiveStreams() int32 {
+	if x != nil {
+		return x.ActiveStreams
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetStreamConnectedAt() int64 {
+	if x != nil {
+		return x.StreamConnectedAt
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetChannelDepth() int32 {
+	if x != nil {
+		return x.ChannelDepth
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetChannelCapacity() int32 {
+	if x != nil {
+		return x.ChannelCapacity
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetPendingCommands() int32 {
+	if x != nil {
+		return x.PendingCommands
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetHeldCommands() int32 {
+	if x != nil {
+		return x.HeldCommands
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetCommandsSent() int64 {
+	if x != nil {
+		return x.CommandsSent
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetResultsReceived() int64 {
+	if x != nil {
+		return x.ResultsReceived
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetEvents() []*ConnectionEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *MinionDiagnostics) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *MinionDiagnostics) GetLastErrorAt() int64 {
+	if x != nil {
+		return x.LastErrorAt
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetInFlight() int32 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetInFlightLimit() int32 {
+	if x != nil {
+		return x.InFlightLimit
+	}
+	return 0
+}
+
+func (x *MinionDiagnostics) GetLastSpeedTest() *SpeedTestSummary {
+	if x != nil {
+		return x.LastSpeedTest
+	}
+	return nil
+}
+
+// SpeedTestSummary is a net:speedtest measuring the channel between a minion and Nexus
+type SpeedTestSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	LatencyMs     float64                `protobuf:"fixed64,3,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`          // average round trip time
+	UploadMbps    float64                `protobuf:"fixed64,4,opt,name=upload_mbps,json=uploadMbps,proto3" json:"upload_mbps,omitempty"`       // minion -> Nexus, in megabits per second
+	DownloadMbps  float64                `protobuf:"fixed64,5,opt,name=download_mbps,json=downloadMbps,proto3" json:"download_mbps,omitempty"` // Nexus -> minion
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SpeedTestSummary) Reset() {
+	*x = SpeedTestSummary{}
+	mi := &file_minexus_proto_msgTypes[76]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpeedTestSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpeedTestSummary) ProtoMessage() {}
+
+func (x *SpeedTestSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[76]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpeedTestSummary.ProtoReflect.Descriptor instead.
+func (*SpeedTestSummary) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{76}
+}
+
+func (x *SpeedTestSummary) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *SpeedTestSummary) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *SpeedTestSummary) GetLatencyMs() float64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *SpeedTestSummary) GetUploadMbps() float64 {
+	if x != nil {
+		return x.UploadMbps
+	}
+	return 0
+}
+
+func (x *SpeedTestSummary) GetDownloadMbps() float64 {
+	if x != nil {
+		return x.DownloadMbps
+	}
+	return 0
+}
+
+// FileChunk carries a file pulled from a minion in chunks, between the minion and Nexus which
+// reassembles it, and from Nexus to the console downloading it
+type FileChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`      // Nexus -> Minion: file to send, with start
+	Offset        int64                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"` // offset of data in the file, or to send the file from with start
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Start         bool                   `protobuf:"varint,5,opt,name=start,proto3" json:"start,omitempty"`   // Nexus -> Minion: send the file from offset
+	Cancel        bool                   `protobuf:"varint,6,opt,name=cancel,proto3" json:"cancel,omitempty"` // Nexus -> Minion: stop sending the file
+	Size          int64                  `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"`     // size of the file, sent up to this size
+	Eof           bool                   `protobuf:"varint,8,opt,name=eof,proto3" json:"eof,omitempty"`       // last chunk of the file
+	Sha256        string                 `protobuf:"bytes,9,opt,name=sha256,proto3" json:"sha256,omitempty"`  // checksum of the whole file, with eof
+	Error         string                 `protobuf:"bytes,10,opt,name=error,proto3" json:"error,omitempty"`   // Minion -> Nexus: the file can't be sent
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_minexus_proto_msgTypes[77]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[77]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{77}
+}
+
+func (x *FileChunk) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *FileChunk) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileChunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *FileChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *FileChunk) GetStart() bool {
+	if x != nil {
+		return x.Start
+	}
+	return false
+}
+
+func (x *FileChunk) GetCancel() bool {
+	if x != nil {
+		return x.Cancel
+	}
+	return false
+}
+
+func (x *FileChunk) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileChunk) GetEof() bool {
+	if x != nil {
+		return x.Eof
+	}
+	return false
+}
+
+func (x *FileChunk) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *FileChunk) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type FilePullRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`                               // absolute path of the file on the minion
+	TransferId    string                 `protobuf:"bytes,3,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"` // resumes an interrupted transfer instead, from the bytes Nexus received
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FilePullRequest) Reset() {
+	*x = FilePullRequest{}
+	mi := &file_minexus_proto_msgTypes[78]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FilePullRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilePullRequest) ProtoMessage() {}
+
+func (x *FilePullRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[78]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilePullRequest.ProtoReflect.Descriptor instead.
+func (*FilePullRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{78}
+}
+
+func (x *FilePullRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *FilePullRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FilePullRequest) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+type FileTransferStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+	MinionId      string                 `protobuf:"bytes,2,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`                            // "RUNNING", "INTERRUPTED", "COMPLETED", "FAILED"
+	Received      int64                  `protobuf:"varint,5,opt,name=received,proto3" json:"received,omitempty"`                     // bytes reassembled on Nexus
+	Size          int64                  `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`                             // 0 until the minion reported it
+	Sha256        string                 `protobuf:"bytes,7,opt,name=sha256,proto3" json:"sha256,omitempty"`                          // checksum of the file, once completed
+	Error         string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`                            // why the transfer was interrupted or failed
+	StartedAt     int64                  `protobuf:"varint,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`  // Unix timestamp
+	UpdatedAt     int64                  `protobuf:"varint,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // Unix timestamp
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileTransferStatus) Reset() {
+	*x = FileTransferStatus{}
+	mi := &file_minexus_proto_msgTypes[79]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileTransferStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileTransferStatus) ProtoMessage() {}
+
+func (x *FileTransferStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[79]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileTransferStatus.ProtoReflect.Descriptor instead.
+func (*FileTransferStatus) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{79}
+}
+
+func (x *FileTransferStatus) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *FileTransferStatus) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *FileTransferStatus) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FileTransferStatus) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *FileTransferStatus) GetReceived() int64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+func (x *FileTransferStatus) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileTransferStatus) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *FileTransferStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *FileTransferStatus) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *FileTransferStatus) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type FileTransferList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transfers     []*FileTransferStatus  `protobuf:"bytes,1,rep,name=transfers,proto3" json:"transfers,omitempty"` // most recent first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileTransferList) Reset() {
+	*x = FileTransferList{}
+	mi := &file_minexus_proto_msgTypes[80]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileTransferList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileTransferList) ProtoMessage() {}
+
+func (x *FileTransferList) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[80]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileTransferList.ProtoReflect.Descriptor instead.
+func (*FileTransferList) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{80}
+}
+
+func (x *FileTransferList) GetTransfers() []*FileTransferStatus {
+	if x != nil {
+		return x.Transfers
+	}
+	return nil
+}
+
+type FileDownloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransferId    string                 `protobuf:"bytes,1,opt,name=transfer_id,json=transferId,proto3" json:"transfer_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // bytes the console already has
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileDownloadRequest) Reset() {
+	*x = FileDownloadRequest{}
+	mi := &file_minexus_proto_msgTypes[81]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileDownloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileDownloadRequest) ProtoMessage() {}
+
+func (x *FileDownloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[81]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileDownloadRequest.ProtoReflect.Descriptor instead.
+func (*FileDownloadRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{81}
+}
+
+func (x *FileDownloadRequest) GetTransferId() string {
+	if x != nil {
+		return x.TransferId
+	}
+	return ""
+}
+
+func (x *FileDownloadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+// MinionHealth scores a minion from its behavior over the last hour
+type MinionHealth struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Score               int32                  `protobuf:"varint,1,opt,name=score,proto3" json:"score,omitempty"`           // 0 to 100, 100 for a minion without anomaly
+	Anomalies           []string               `protobuf:"bytes,2,rep,name=anomalies,proto3" json:"anomalies,omitempty"`    // "frequent-reconnects", "high-failure-rate", "slow-results", "irregular-heartbeats", "missed-heartbeats"
+	Reconnects          int32                  `protobuf:"varint,3,opt,name=reconnects,proto3" json:"reconnects,omitempty"` // command streams reopened
+	Results             int32                  `protobuf:"varint,4,opt,name=results,proto3" json:"results,omitempty"`
+	FailedResults       int32                  `protobuf:"varint,5,opt,name=failed_results,json=failedResults,proto3" json:"failed_results,omitempty"`                     // results with a non-zero exit code
+	MeanLatencyMs       int64                  `protobuf:"varint,6,opt,name=mean_latency_ms,json=meanLatencyMs,proto3" json:"mean_latency_ms,omitempty"`                   // from the dispatch of a command to its result, 0 when unknown
+	HeartbeatIntervalMs int64                  `protobuf:"varint,7,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs,proto3" json:"heartbeat_interval_ms,omitempty"` // median interval between registrations, 0 when unknown
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *MinionHealth) Reset() {
+	*x = MinionHealth{}
+	mi := &file_minexus_proto_msgTypes[82]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionHealth) ProtoMessage() {}
+
+func (x *MinionHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[82]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionHealth.ProtoReflect.Descriptor instead.
+func (*MinionHealth) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{82}
+}
+
+func (x *MinionHealth) GetScore() int32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *MinionHealth) GetAnomalies() []string {
+	if x != nil {
+		return x.Anomalies
+	}
+	return nil
+}
+
+func (x *MinionHealth) GetReconnects() int32 {
+	if x != nil {
+		return x.Reconnects
+	}
+	return 0
+}
+
+func (x *MinionHealth) GetResults() int32 {
+	if x != nil {
+		return x.Results
+	}
+	return 0
+}
+
+func (x *MinionHealth) GetFailedResults() int32 {
+	if x != nil {
+		return x.FailedResults
+	}
+	return 0
+}
+
+func (x *MinionHealth) GetMeanLatencyMs() int64 {
+	if x != nil {
+		return x.MeanLatencyMs
+	}
+	return 0
+}
+
+func (x *MinionHealth) GetHeartbeatIntervalMs() int64 {
+	if x != nil {
+		return x.HeartbeatIntervalMs
+	}
+	return 0
+}
+
+type FleetHealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Below         int32                  `protobuf:"varint,1,opt,name=below,proto3" json:"below,omitempty"` // minions scoring below are listed, 0 for the default of 80
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FleetHealthRequest) Reset() {
+	*x = FleetHealthRequest{}
+	mi := &file_minexus_proto_msgTypes[83]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FleetHealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FleetHealthRequest) ProtoMessage() {}
+
+func (x *FleetHealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[83]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FleetHealthRequest.ProtoReflect.Descriptor instead.
+func (*FleetHealthRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{83}
+}
+
+func (x *FleetHealthRequest) GetBelow() int32 {
+	if x != nil {
+		return x.Below
+	}
+	return 0
+}
+
+type FleetHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Healthy       int32                  `protobuf:"varint,2,opt,name=healthy,proto3" json:"healthy,omitempty"`     // score of 80 and above
+	Degraded      int32                  `protobuf:"varint,3,opt,name=degraded,proto3" json:"degraded,omitempty"`   // score from 50 to 79
+	Unhealthy     int32                  `protobuf:"varint,4,opt,name=unhealthy,proto3" json:"unhealthy,omitempty"` // score below 50
+	AverageScore  int32                  `protobuf:"varint,5,opt,name=average_score,json=averageScore,proto3" json:"average_score,omitempty"`
+	Minions       []*HostInfo            `protobuf:"bytes,6,rep,name=minions,proto3" json:"minions,omitempty"` // minions scoring below the threshold, lowest score first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FleetHealth) Reset() {
+	*x = FleetHealth{}
+	mi := &file_minexus_proto_msgTypes[84]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FleetHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FleetHealth) ProtoMessage() {}
+
+func (x *FleetHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[84]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FleetHealth.ProtoReflect.Descriptor instead.
+func (*FleetHealth) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{84}
+}
+
+func (x *FleetHealth) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *FleetHealth) GetHealthy() int32 {
+	if x != nil {
+		return x.Healthy
+	}
+	return 0
+}
+
+func (x *FleetHealth) GetDegraded() int32 {
+	if x != nil {
+		return x.Degraded
+	}
+	return 0
+}
+
+func (x *FleetHealth) GetUnhealthy() int32 {
+	if x != nil {
+		return x.Unhealthy
+	}
+	return 0
+}
+
+func (x *FleetHealth) GetAverageScore() int32 {
+	if x != nil {
+		return x.AverageScore
+	}
+	return 0
+}
+
+func (x *FleetHealth) GetMinions() []*HostInfo {
+	if x != nil {
+		return x.Minions
+	}
+	return nil
+}
+
+type FleetVersions struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Total                int32                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	NexusVersion         string                 `protobuf:"bytes,2,opt,name=nexus_version,json=nexusVersion,proto3" json:"nexus_version,omitempty"`
+	NexusProtocolVersion int32                  `protobuf:"varint,3,opt,name=nexus_protocol_version,json=nexusProtocolVersion,proto3" json:"nexus_protocol_version,omitempty"`
+	MinProtocolVersion   int32                  `protobuf:"varint,4,opt,name=min_protocol_version,json=minProtocolVersion,proto3" json:"min_protocol_version,omitempty"` // 0 when any minion is accepted
+	Versions             []*VersionCount        `protobuf:"bytes,5,rep,name=versions,proto3" json:"versions,omitempty"`                                                  // most common build first
+	Outdated             []*HostInfo            `protobuf:"bytes,6,rep,name=outdated,proto3" json:"outdated,omitempty"`                                                  // minions flagged below the minimum protocol version
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *FleetVersions) Reset() {
+	*x = FleetVersions{}
+	mi := &file_minexus_proto_msgTypes[85]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FleetVersions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FleetVersions) ProtoMessage() {}
+
+func (x *FleetVersions) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[85]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FleetVersions.ProtoReflect.Descriptor instead.
+func (*FleetVersions) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{85}
+}
+
+func (x *FleetVersions) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *FleetVersions) GetNexusVersion() string {
+	if x != nil {
+		return x.NexusVersion
+	}
+	return ""
+}
+
+func (x *FleetVersions) GetNexusProtocolVersion() int32 {
+	if x != nil {
+		return x.NexusProtocolVersion
+	}
+	return 0
+}
+
+func (x *FleetVersions) GetMinProtocolVersion() int32 {
+	if x != nil {
+		return x.MinProtocolVersion
+	}
+	return 0
+}
+
+func (x *FleetVersions) GetVersions() []*VersionCount {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+func (x *FleetVersions) GetOutdated() []*HostInfo {
+	if x != nil {
+		return x.Outdated
+	}
+	return nil
+}
+
+type VersionCount struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Version         string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"` // empty for minions predating version reporting
+	BuildCommit     string                 `protobuf:"bytes,2,opt,name=build_commit,json=buildCommit,proto3" json:"build_commit,omitempty"`
+	ProtocolVersion int32                  `protobuf:"varint,3,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	Minions         int32                  `protobuf:"varint,4,opt,name=minions,proto3" json:"minions,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *VersionCount) Reset() {
+	*x = VersionCount{}
+	mi := &file_minexus_proto_msgTypes[86]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionCount) ProtoMessage() {}
+
+func (x *VersionCount) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[86]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionCount.ProtoReflect.Descriptor instead.
+func (*VersionCount) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{86}
+}
+
+func (x *VersionCount) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *VersionCount) GetBuildCommit() string {
+	if x != nil {
+		return x.BuildCommit
+	}
+	return ""
+}
+
+func (x *VersionCount) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *VersionCount) GetMinions() int32 {
+	if x != nil {
+		return x.Minions
+	}
+	return 0
+}
+
+// PatchComplianceRequest groups the minions by the value of a tag to summarize their last patch:status
+type PatchComplianceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupBy       string                 `protobuf:"bytes,1,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"` // tag key, empty for a single group of the whole fleet
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchComplianceRequest) Reset() {
+	*x = PatchComplianceRequest{}
+	mi := &file_minexus_proto_msgTypes[87]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchComplianceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchComplianceRequest) ProtoMessage() {}
+
+func (x *PatchComplianceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[87]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchComplianceRequest.ProtoReflect.Descriptor instead.
+func (*PatchComplianceRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{87}
+}
+
+func (x *PatchComplianceRequest) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+type PatchCompliance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupBy       string                 `protobuf:"bytes,1,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	Total         *PatchGroup            `protobuf:"bytes,2,opt,name=total,proto3" json:"total,omitempty"`
+	Groups        []*PatchGroup          `protobuf:"bytes,3,rep,name=groups,proto3" json:"groups,omitempty"` // most security updates pending first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchCompliance) Reset() {
+	*x = PatchCompliance{}
+	mi := &file_minexus_proto_msgTypes[88]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchCompliance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchCompliance) ProtoMessage() {}
+
+func (x *PatchCompliance) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[88]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchCompliance.ProtoReflect.Descriptor instead.
+func (*PatchCompliance) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{88}
+}
+
+func (x *PatchCompliance) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+func (x *PatchCompliance) GetTotal() *PatchGroup {
+	if x != nil {
+		return x.Total
+	}
+	return nil
+}
+
+func (x *PatchCompliance) GetGroups() []*PatchGroup {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+// PatchGroup summarizes the last patch:status of the minions sharing a tag value
+type PatchGroup struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Value           string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"` // tag value, empty for the minions without the tag
+	Minions         int32                  `protobuf:"varint,2,opt,name=minions,proto3" json:"minions,omitempty"`
+	Compliant       int32                  `protobuf:"varint,3,opt,name=compliant,proto3" json:"compliant,omitempty"`   // no security update pending
+	Vulnerable      int32                  `protobuf:"varint,4,opt,name=vulnerable,proto3" json:"vulnerable,omitempty"` // security updates pending
+	Unknown         int32                  `protobuf:"varint,5,opt,name=unknown,proto3" json:"unknown,omitempty"`       // no successful patch:status stored
+	RebootRequired  int32                  `protobuf:"varint,6,opt,name=reboot_required,json=rebootRequired,proto3" json:"reboot_required,omitempty"`
+	SecurityUpdates int32                  `protobuf:"varint,7,opt,name=security_updates,json=securityUpdates,proto3" json:"security_updates,omitempty"` // pending on the minions of the group
+	Updates         int32                  `protobuf:"varint,8,opt,name=updates,proto3" json:"updates,omitempty"`
+	OldestStatus    int64                  `protobuf:"varint,9,opt,name=oldest_status,json=oldestStatus,proto3" json:"oldest_status,omitempty"` // unix time of the oldest patch:status of the group, 0 without any
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PatchGroup) Reset() {
+	*x = PatchGroup{}
+	mi := &file_minexus_proto_msgTypes[89]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchGroup) ProtoMessage() {}
+
+func (x *PatchGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[89]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchGroup.ProtoReflect.Descriptor instead.
+func (*PatchGroup) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{89}
+}
+
+func (x *PatchGroup) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *PatchGroup) GetMinions() int32 {
+	if x != nil {
+		return x.Minions
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetCompliant() int32 {
+	if x != nil {
+		return x.Compliant
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetVulnerable() int32 {
+	if x != nil {
+		return x.Vulnerable
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetUnknown() int32 {
+	if x != nil {
+		return x.Unknown
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetRebootRequired() int32 {
+	if x != nil {
+		return x.RebootRequired
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetSecurityUpdates() int32 {
+	if x != nil {
+		return x.SecurityUpdates
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetUpdates() int32 {
+	if x != nil {
+		return x.Updates
+	}
+	return 0
+}
+
+func (x *PatchGroup) GetOldestStatus() int64 {
+	if x != nil {
+		return x.OldestStatus
+	}
+	return 0
+}
+
+type FlushCachesResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PendingCommands int32                  `protobuf:"varint,1,opt,name=pending_commands,json=pendingCommands,proto3" json:"pending_commands,omitempty"` // forgotten trackers of commands awaiting only disconnected minions
+	Diagnostics     int32                  `protobuf:"varint,2,opt,name=diagnostics,proto3" json:"diagnostics,omitempty"`                                // forgotten connection histories of minions without stream
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *FlushCachesResponse) Reset() {
+	*x = FlushCachesResponse{}
+	mi := &file_minexus_proto_msgTypes[90]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FlushCachesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlushCachesResponse) ProtoMessage() {}
+
+func (x *FlushCachesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[90]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlushCachesResponse.ProtoReflect.Descriptor instead.
+func (*FlushCachesResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{90}
+}
+
+func (x *FlushCachesResponse) GetPendingCommands() int32 {
+	if x != nil {
+		return x.PendingCommands
+	}
+	return 0
+}
+
+func (x *FlushCachesResponse) GetDiagnostics() int32 {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return 0
+}
+
+type LogLevelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`       // "debug", "info", "warn" or "error", empty: unchanged
+	Sampling      string                 `protobuf:"bytes,2,opt,name=sampling,proto3" json:"sampling,omitempty"` // "<initial>,<thereafter>" or "off", empty: unchanged
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogLevelRequest) Reset() {
+	*x = LogLevelRequest{}
+	mi := &file_minexus_proto_msgTypes[91]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogLevelRequest) ProtoMessage() {}
+
+func (x *LogLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[91]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogLevelRequest.ProtoReflect.Descriptor instead.
+func (*LogLevelRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{91}
+}
+
+func (x *LogLevelRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogLevelRequest) GetSampling() string {
+	if x != nil {
+		return x.Sampling
+	}
+	return ""
+}
+
+type LogLevelResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Level            string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"` // level in effect
+	PreviousLevel    string                 `protobuf:"bytes,2,opt,name=previous_level,json=previousLevel,proto3" json:"previous_level,omitempty"`
+	Sampling         string                 `protobuf:"bytes,3,opt,name=sampling,proto3" json:"sampling,omitempty"` // sampling in effect, empty when it can't be changed at runtime
+	PreviousSampling string                 `protobuf:"bytes,4,opt,name=previous_sampling,json=previousSampling,proto3" json:"previous_sampling,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LogLevelResponse) Reset() {
+	*x = LogLevelResponse{}
+	mi := &file_minexus_proto_msgTypes[92]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogLevelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogLevelResponse) ProtoMessage() {}
+
+func (x *LogLevelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[92]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogLevelResponse.ProtoReflect.Descriptor instead.
+func (*LogLevelResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{92}
+}
+
+func (x *LogLevelResponse) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogLevelResponse) GetPreviousLevel() string {
+	if x != nil {
+		return x.PreviousLevel
+	}
+	return ""
+}
+
+func (x *LogLevelResponse) GetSampling() string {
+	if x != nil {
+		return x.Sampling
+	}
+	return ""
+}
+
+func (x *LogLevelResponse) GetPreviousSampling() string {
+	if x != nil {
+		return x.PreviousSampling
+	}
+	return ""
+}
+
+type RegistryEntry struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	MinionId        string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Hostname        string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	LastSeen        int64                  `protobuf:"varint,3,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // unix timestamp
+	Connected       bool                   `protobuf:"varint,4,opt,name=connected,proto3" json:"connected,omitempty"`               // has an open command stream
+	QueuedCommands  int32                  `protobuf:"varint,5,opt,name=queued_commands,json=queuedCommands,proto3" json:"queued_commands,omitempty"`
+	InFlight        int32                  `protobuf:"varint,6,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`
+	PendingCommands int32                  `protobuf:"varint,7,opt,name=pending_commands,json=pendingCommands,proto3" json:"pending_commands,omitempty"`   // commands awaiting its result
+	IdCollisions    int32                  `protobuf:"varint,8,opt,name=id_collisions,json=idCollisions,proto3" json:"id_collisions,omitempty"`            // registrations of other hosts claiming its ID while it was connected
+	LastIdCollision int64                  `protobuf:"varint,9,opt,name=last_id_collision,json=lastIdCollision,proto3" json:"last_id_collision,omitempty"` // unix timestamp, 0 without collision
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegistryEntry) Reset() {
+	*x = RegistryEntry{}
+	mi := &file_minexus_proto_msgTypes[93]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegistryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegistryEntry) ProtoMessage() {}
+
+func (x *RegistryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[93]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegistryEntry.ProtoReflect.Descriptor instead.
+func (*RegistryEntry) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{93}
+}
+
+func (x *RegistryEntry) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *RegistryEntry) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *RegistryEntry) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *RegistryEntry) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *RegistryEntry) GetQueuedCommands() int32 {
+	if x != nil {
+		return x.QueuedCommands
+	}
+	return 0
+}
+
+func (x *RegistryEntry) GetInFlight() int32 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *RegistryEntry) GetPendingCommands() int32 {
+	if x != nil {
+		return x.PendingCommands
+	}
+	return 0
+}
+
+func (x *RegistryEntry) GetIdCollisions() int32 {
+	if x != nil {
+		return x.IdCollisions
+	}
+	return 0
+}
+
+func (x *RegistryEntry) GetLastIdCollision() int64 {
+	if x != nil {
+		return x.LastIdCollision
+	}
+	return 0
+}
+
+type RegistryDump struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Minions         []*RegistryEntry       `protobuf:"bytes,1,rep,name=minions,proto3" json:"minions,omitempty"`
+	Connected       int32                  `protobuf:"varint,2,opt,name=connected,proto3" json:"connected,omitempty"`
+	PendingCommands int32                  `protobuf:"varint,3,opt,name=pending_commands,json=pendingCommands,proto3" json:"pending_commands,omitempty"` // tracked commands awaiting results
+	HeldCommands    int32                  `protobuf:"varint,4,opt,name=held_commands,json=heldCommands,proto3" json:"held_commands,omitempty"`          // commands held by maintenance windows
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegistryDump) Reset() {
+	*x = RegistryDump{}
+	mi := &file_minexus_proto_msgTypes[94]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegistryDump) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegistryDump) ProtoMessage() {}
+
+func (x *RegistryDump) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[94]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegistryDump.ProtoReflect.Descriptor instead.
+func (*RegistryDump) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{94}
+}
+
+func (x *RegistryDump) GetMinions() []*RegistryEntry {
+	if x != nil {
+		return x.Minions
+	}
+	return nil
+}
+
+func (x *RegistryDump) GetConnected() int32 {
+	if x != nil {
+		return x.Connected
+	}
+	return 0
+}
+
+func (x *RegistryDump) GetPendingCommands() int32 {
+	if x != nil {
+		return x.PendingCommands
+	}
+	return 0
+}
+
+func (x *RegistryDump) GetHeldCommands() int32 {
+	if x != nil {
+		return x.HeldCommands
+	}
+	return 0
+}
+
+type DisconnectMinionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectMinionRequest) Reset() {
+	*x = DisconnectMinionRequest{}
+	mi := &file_minexus_proto_msgTypes[95]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectMinionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectMinionRequest) ProtoMessage() {}
+
+func (x *DisconnectMinionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[95]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectMinionRequest.ProtoReflect.Descriptor instead.
+func (*DisconnectMinionRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{95}
+}
+
+func (x *DisconnectMinionRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+type PruneDatabaseResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ArchivedCommands int32                  `protobuf:"varint,1,opt,name=archived_commands,json=archivedCommands,proto3" json:"archived_commands,omitempty"`
+	PrunedLogs       int64                  `protobuf:"varint,2,opt,name=pruned_logs,json=prunedLogs,proto3" json:"pruned_logs,omitempty"` // minion log entries deleted
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PruneDatabaseResponse) Reset() {
+	*x = PruneDatabaseResponse{}
+	mi := &file_minexus_proto_msgTypes[96]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneDatabaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneDatabaseResponse) ProtoMessage() {}
+
+func (x *PruneDatabaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[96]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneDatabaseResponse.ProtoReflect.Descriptor instead.
+func (*PruneDatabaseResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{96}
+}
+
+func (x *PruneDatabaseResponse) GetArchivedCommands() int32 {
+	if x != nil {
+		return x.ArchivedCommands
+	}
+	return 0
+}
+
+func (x *PruneDatabaseResponse) GetPrunedLogs() int64 {
+	if x != nil {
+		return x.PrunedLogs
+	}
+	return 0
+}
+
+type UnbindMinionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnbindMinionRequest) Reset() {
+	*x = UnbindMinionRequest{}
+	mi := &file_minexus_proto_msgTypes[97]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnbindMinionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnbindMinionRequest) ProtoMessage() {}
+
+func (x *UnbindMinionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[97]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnbindMinionRequest.ProtoReflect.Descriptor instead.
+func (*UnbindMinionRequest) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{97}
+}
+
+func (x *UnbindMinionRequest) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+// New message for command status updates
+type CommandStatusUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	MinionId      string                 `protobuf:"bytes,2,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"` // "RECEIVED", "EXECUTING", "COMPLETED", "FAILED"
+	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	TraceId       string                 `protobuf:"bytes,5,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"` // trace ID of the command
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandStatusUpdate) Reset() {
+	*x = CommandStatusUpdate{}
+	mi := &file_minexus_proto_msgTypes[98]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandStatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandStatusUpdate) ProtoMessage() {}
+
+func (x *CommandStatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[98]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandStatusUpdate.ProtoReflect.Descriptor instead.
+func (*CommandStatusUpdate) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{98}
+}
+
+func (x *CommandStatusUpdate) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *CommandStatusUpdate) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *CommandStatusUpdate) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *CommandStatusUpdate) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *CommandStatusUpdate) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+type RegisterResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	AssignedId      string                 `protobuf:"bytes,2,opt,name=assigned_id,json=assignedId,proto3" json:"assigned_id,omitempty"`
+	ErrorMessage    string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	ResultAcks      bool                   `protobuf:"varint,4,opt,name=result_acks,json=resultAcks,proto3" json:"result_acks,omitempty"`                // Nexus acknowledges the results it received, minions keep them until then
+	ProtocolVersion int32                  `protobuf:"varint,5,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // of Nexus, minions warning when it differs from theirs
+	MaxMsgSize      int32                  `protobuf:"varint,6,opt,name=max_msg_size,json=maxMsgSize,proto3" json:"max_msg_size,omitempty"`              // largest message Nexus accepts, minions splitting larger results in chunks; 0: results are never split
+	Retire          *RetireNotice          `protobuf:"bytes,7,opt,name=retire,proto3" json:"retire,omitempty"`                                           // set when the minion was retired, its registration being refused
+	CrashAcks       bool                   `protobuf:"varint,8,opt,name=crash_acks,json=crashAcks,proto3" json:"crash_acks,omitempty"`                   // Nexus reports whether it stored the crash reports, minions keeping them until then
+	CrashesStored   bool                   `protobuf:"varint,9,opt,name=crashes_stored,json=crashesStored,proto3" json:"crashes_stored,omitempty"`       // the crash reports of the registration were stored
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_minexus_proto_msgTypes[99]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[99]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{99}
+}
+
+func (x *RegisterResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterResponse) GetAssignedId() string {
+	if x != nil {
+		return x.AssignedId
+	}
+	return ""
+}
+
+func (x *RegisterResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *RegisterResponse) GetResultAcks() bool {
+	if x != nil {
+		return x.ResultAcks
+	}
+	return false
+}
+
+func (x *RegisterResponse) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *RegisterResponse) GetMaxMsgSize() int32 {
+	if x != nil {
+		return x.MaxMsgSize
+	}
+	return 0
+}
+
+func (x *RegisterResponse) GetRetire() *RetireNotice {
+	if x != nil {
+		return x.Retire
+	}
+	return nil
+}
+
+func (x *RegisterResponse) GetCrashAcks() bool {
+	if x != nil {
+		return x.CrashAcks
+	}
+	return false
+}
+
+func (x *RegisterResponse) GetCrashesStored() bool {
+	if x != nil {
+		return x.CrashesStored
+	}
+	return false
+}
+
+type MinionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MinionInfo) Reset() {
+	*x = MinionInfo{}
+	mi := &file_minexus_proto_msgTypes[100]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MinionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MinionInfo) ProtoMessage() {}
+
+func (x *MinionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[100]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MinionInfo.ProtoReflect.Descriptor instead.
+func (*MinionInfo) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{100}
+}
+
+func (x *MinionInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CommandStreamMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Message:
+	//
+	//	*CommandStreamMessage_Command
+	//	*CommandStreamMessage_Result
+	//	*CommandStreamMessage_Status
+	//	*CommandStreamMessage_Shell
+	//	*CommandStreamMessage_Reconnect
+	//	*CommandStreamMessage_Logs
+	//	*CommandStreamMessage_Ack
+	//	*CommandStreamMessage_File
+	//	*CommandStreamMessage_ResultChunk
+	//	*CommandStreamMessage_Retire
+	Message       isCommandStreamMessage_Message `protobuf_oneof:"message"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommandStreamMessage) Reset() {
+	*x = CommandStreamMessage{}
+	mi := &file_minexus_proto_msgTypes[101]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommandStreamMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandStreamMessage) ProtoMessage() {}
+
+func (x *CommandStreamMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[101]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandStreamMessage.ProtoReflect.Descriptor instead.
+func (*CommandStreamMessage) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{101}
+}
+
+func (x *CommandStreamMessage) GetMessage() isCommandStreamMessage_Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetCommand() *Command {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Command); ok {
+			return x.Command
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetResult() *CommandResult {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetStatus() *CommandStatusUpdate {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Status); ok {
+			return x.Status
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetShell() *ShellMessage {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Shell); ok {
+			return x.Shell
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetReconnect() *ReconnectHint {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Reconnect); ok {
+			return x.Reconnect
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetLogs() *MinionLogBatch {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Logs); ok {
+			return x.Logs
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetAck() *ResultAck {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetFile() *FileChunk {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_File); ok {
+			return x.File
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetResultChunk() *ResultChunk {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_ResultChunk); ok {
+			return x.ResultChunk
+		}
+	}
+	return nil
+}
+
+func (x *CommandStreamMessage) GetRetire() *RetireNotice {
+	if x != nil {
+		if x, ok := x.Message.(*CommandStreamMessage_Retire); ok {
+			return x.Retire
+		}
+	}
+	return nil
+}
+
+type isCommandStreamMessage_Message interface {
+	isCommandStreamMessage_Message()
+}
+
+type CommandStreamMessage_Command struct {
+	Command *Command `protobuf:"bytes,1,opt,name=command,proto3,oneof"` // Nexus -> Minion: New command to execute
+}
+
+type CommandStreamMessage_Result struct {
+	Result *CommandResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"` // Minion -> Nexus: Result of executed command
+}
+
+type CommandStreamMessage_Status struct {
+	Status *CommandStatusUpdate `protobuf:"bytes,3,opt,name=status,proto3,oneof"` // Minion -> Nexus: Status update for command
+}
+
+type CommandStreamMessage_Shell struct {
+	Shell *ShellMessage `protobuf:"bytes,4,opt,name=shell,proto3,oneof"` // Both ways: Traffic of an interactive shell session
+}
+
+type CommandStreamMessage_Reconnect struct {
+	Reconnect *ReconnectHint `protobuf:"bytes,5,opt,name=reconnect,proto3,oneof"` // Nexus -> Minion: Nexus is shutting down, reconnect later
+}
+
+type CommandStreamMessage_Logs struct {
+	Logs *MinionLogBatch `protobuf:"bytes,6,opt,name=logs,proto3,oneof"` // Minion -> Nexus: Logs shipped by the minion
+}
+
+type CommandStreamMessage_Ack struct {
+	Ack *ResultAck `protobuf:"bytes,7,opt,name=ack,proto3,oneof"` // Nexus -> Minion: A result was received and stored
+}
+
+type CommandStreamMessage_File struct {
+	File *FileChunk `protobuf:"bytes,8,opt,name=file,proto3,oneof"` // Both ways: Chunks of a file pulled from the minion
+}
+
+type CommandStreamMessage_ResultChunk struct {
+	ResultChunk *ResultChunk `protobuf:"bytes,9,opt,name=result_chunk,json=resultChunk,proto3,oneof"` // Minion -> Nexus: Part of a result too large for a single message
+}
+
+type CommandStreamMessage_Retire struct {
+	Retire *RetireNotice `protobuf:"bytes,10,opt,name=retire,proto3,oneof"` // Nexus -> Minion: The host was retired, stop for good
+}
+
+func (*CommandStreamMessage_Command) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Result) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Status) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Shell) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Reconnect) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Logs) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Ack) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_File) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_ResultChunk) isCommandStreamMessage_Message() {}
+
+func (*CommandStreamMessage_Retire) isCommandStreamMessage_Message() {}
+
+// ResultChunk is a part of a serialized CommandResult larger than the message size of Nexus,
+// reassembled by Nexus once all the parts are received
+type ResultChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	Index         int32                  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"` // position of the chunk, from 0
+	Total         int32                  `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"` // number of chunks of the result
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`    // part of the serialized result
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultChunk) Reset() {
+	*x = ResultChunk{}
+	mi := &file_minexus_proto_msgTypes[102]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultChunk) ProtoMessage() {}
+
+func (x *ResultChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[102]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultChunk.ProtoReflect.Descriptor instead.
+func (*ResultChunk) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{102}
+}
+
+func (x *ResultChunk) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+func (x *ResultChunk) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ResultChunk) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ResultChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// ResultAck acknowledges a command result, which the minion no longer needs to send again
+type ResultAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CommandId     string                 `protobuf:"bytes,1,opt,name=command_id,json=commandId,proto3" json:"command_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultAck) Reset() {
+	*x = ResultAck{}
+	mi := &file_minexus_proto_msgTypes[103]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultAck) ProtoMessage() {}
+
+func (x *ResultAck) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[103]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultAck.ProtoReflect.Descriptor instead.
+func (*ResultAck) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{103}
+}
+
+func (x *ResultAck) GetCommandId() string {
+	if x != nil {
+		return x.CommandId
+	}
+	return ""
+}
+
+// ReconnectHint asks a minion to wait before reconnecting once its command stream is closed
+type ReconnectHint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DelaySeconds  int32                  `protobuf:"varint,1,opt,name=delay_seconds,json=delaySeconds,proto3" json:"delay_seconds,omitempty"` // minimum delay before reconnecting
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReconnectHint) Reset() {
+	*x = ReconnectHint{}
+	mi := &file_minexus_proto_msgTypes[104]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReconnectHint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReconnectHint) ProtoMessage() {}
+
+func (x *ReconnectHint) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[104]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReconnectHint.ProtoReflect.Descriptor instead.
+func (*ReconnectHint) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{104}
+}
+
+func (x *ReconnectHint) GetDelaySeconds() int32 {
+	if x != nil {
+		return x.DelaySeconds
+	}
+	return 0
+}
+
+func (x *ReconnectHint) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// ShellMessage carries the traffic of an interactive shell session between a console and a minion
+type ShellMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	MinionId      string                 `protobuf:"bytes,2,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"` // Console -> Nexus: minion to open the session on, in the first message
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`                         // Keystrokes towards the minion, terminal output towards the console
+	Rows          uint32                 `protobuf:"varint,4,opt,name=rows,proto3" json:"rows,omitempty"`                        // Terminal size, on open and on resize
+	Cols          uint32                 `protobuf:"varint,5,opt,name=cols,proto3" json:"cols,omitempty"`
+	Open          bool                   `protobuf:"varint,6,opt,name=open,proto3" json:"open,omitempty"`                         // Nexus -> Minion: start the session; Nexus -> Console: session started
+	Close         bool                   `protobuf:"varint,7,opt,name=close,proto3" json:"close,omitempty"`                       // Either way: the session ended or must be ended
+	ExitCode      int32                  `protobuf:"varint,8,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"` // Exit code of the shell, with close
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`                        // Why the session could not be opened or ended abnormally
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShellMessage) Reset() {
+	*x = ShellMessage{}
+	mi := &file_minexus_proto_msgTypes[105]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShellMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShellMessage) ProtoMessage() {}
+
+func (x *ShellMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[105]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShellMessage.ProtoReflect.Descriptor instead.
+func (*ShellMessage) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{105}
+}
+
+func (x *ShellMessage) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ShellMessage) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *ShellMessage) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ShellMessage) GetRows() uint32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *ShellMessage) GetCols() uint32 {
+	if x != nil {
+		return x.Cols
+	}
+	return 0
+}
+
+func (x *ShellMessage) GetOpen() bool {
+	if x != nil {
+		return x.Open
+	}
+	return false
+}
+
+func (x *ShellMessage) GetClose() bool {
+	if x != nil {
+		return x.Close
+	}
+	return false
+}
+
+func (x *ShellMessage) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *ShellMessage) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// SpeedTestProbe carries the latency probes and synthetic payloads of a net:speedtest
+type SpeedTestProbe struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`              // Minion -> Nexus: minion running the test, in the first message
+	Ping          int32                  `protobuf:"varint,2,opt,name=ping,proto3" json:"ping,omitempty"`                                     // Both ways: sequence number of a latency probe, echoed by Nexus
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`                                      // Minion -> Nexus: uploaded payload; Nexus -> Minion: downloaded payload
+	UploadDone    bool                   `protobuf:"varint,4,opt,name=upload_done,json=uploadDone,proto3" json:"upload_done,omitempty"`       // Minion -> Nexus: the upload is complete; Nexus -> Minion: with received
+	Received      int64                  `protobuf:"varint,5,opt,name=received,proto3" json:"received,omitempty"`                             // Nexus -> Minion: bytes of the upload received
+	Download      int64                  `protobuf:"varint,6,opt,name=download,proto3" json:"download,omitempty"`                             // Minion -> Nexus: bytes to download
+	DownloadDone  bool                   `protobuf:"varint,7,opt,name=download_done,json=downloadDone,proto3" json:"download_done,omitempty"` // Nexus -> Minion: last chunk of the download
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SpeedTestProbe) Reset() {
+	*x = SpeedTestProbe{}
+	mi := &file_minexus_proto_msgTypes[106]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpeedTestProbe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpeedTestProbe) ProtoMessage() {}
+
+func (x *SpeedTestProbe) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[106]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpeedTestProbe.ProtoReflect.Descriptor instead.
+func (*SpeedTestProbe) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{106}
+}
+
+func (x *SpeedTestProbe) GetMinionId() string {
+	if x != nil {
+		return x.MinionId
+	}
+	return ""
+}
+
+func (x *SpeedTestProbe) GetPing() int32 {
+	if x != nil {
+		return x.Ping
+	}
+	return 0
 }
 
-func (x *CommandStatusUpdate) GetCommandId() string {
+func (x *SpeedTestProbe) GetData() []byte {
 	if x != nil {
-		return x.CommandId
+		return x.Data
 	}
-	return ""
+	return nil
 }
 
-func (x *CommandStatusUpdate) GetMinionId() string {
+func (x *SpeedTestProbe) GetUploadDone() bool {
 	if x != nil {
-		return x.MinionId
+		return x.UploadDone
 	}
-	return ""
+	return false
 }
 
-func (x *CommandStatusUpdate) GetStatus() string {
+func (x *SpeedTestProbe) GetReceived() int64 {
 	if x != nil {
-		return x.Status
+		return x.Received
 	}
-	return ""
+	return 0
 }
 
-func (x *CommandStatusUpdate) GetTimestamp() int64 {
+func (x *SpeedTestProbe) GetDownload() int64 {
 	if x != nil {
-		return x.Timestamp
+		return x.Download
 	}
 	return 0
 }
 
-type RegisterResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	AssignedId    string                 `protobuf:"bytes,2,opt,name=assigned_id,json=assignedId,proto3" json:"assigned_id,omitempty"`
-	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+func (x *SpeedTestProbe) GetDownloadDone() bool {
+	if x != nil {
+		return x.DownloadDone
+	}
+	return false
+}
+
+// RelayMessage carries the traffic of one minion behind a relay
+type RelayMessage struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	MinionId  string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
+	RequestId string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // correlates a registration with its response
+	// Types that are valid to be assigned to Message:
+	//
+	//	*RelayMessage_Register
+	//	*RelayMessage_Registered
+	//	*RelayMessage_Connected
+	//	*RelayMessage_Disconnected
+	//	*RelayMessage_Stream
+	Message       isRelayMessage_Message `protobuf_oneof:"message"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RegisterResponse) Reset() {
-	*x = RegisterResponse{}
-	mi := &file_minexus_proto_msgTypes[17]
+func (x *RelayMessage) Reset() {
+	*x = RelayMessage{}
+	mi := &file_minexus_proto_msgTypes[107]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterResponse) String() string {
+func (x *RelayMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterResponse) ProtoMessage() {}
+func (*RelayMessage) ProtoMessage() {}
 
-func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[17]
+func (x *RelayMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[107]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1096,103 +7876,136 @@ func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
-func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use RelayMessage.ProtoReflect.Descriptor instead.
+func (*RelayMessage) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{107}
 }
 
-func (x *RegisterResponse) GetSuccess() bool {
+func (x *RelayMessage) GetMinionId() string {
 	if x != nil {
-		return x.Success
+		return x.MinionId
 	}
-	return false
+	return ""
 }
 
-func (x *RegisterResponse) GetAssignedId() string {
+func (x *RelayMessage) GetRequestId() string {
 	if x != nil {
-		return x.AssignedId
+		return x.RequestId
 	}
 	return ""
 }
 
-func (x *RegisterResponse) GetErrorMessage() string {
+func (x *RelayMessage) GetMessage() isRelayMessage_Message {
 	if x != nil {
-		return x.ErrorMessage
+		return x.Message
 	}
-	return ""
+	return nil
 }
 
-type MinionInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *RelayMessage) GetRegister() *HostInfo {
+	if x != nil {
+		if x, ok := x.Message.(*RelayMessage_Register); ok {
+			return x.Register
+		}
+	}
+	return nil
 }
 
-func (x *MinionInfo) Reset() {
-	*x = MinionInfo{}
-	mi := &file_minexus_proto_msgTypes[18]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *RelayMessage) GetRegistered() *RegisterResponse {
+	if x != nil {
+		if x, ok := x.Message.(*RelayMessage_Registered); ok {
+			return x.Registered
+		}
+	}
+	return nil
 }
 
-func (x *MinionInfo) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *RelayMessage) GetConnected() bool {
+	if x != nil {
+		if x, ok := x.Message.(*RelayMessage_Connected); ok {
+			return x.Connected
+		}
+	}
+	return false
 }
 
-func (*MinionInfo) ProtoMessage() {}
+func (x *RelayMessage) GetDisconnected() bool {
+	if x != nil {
+		if x, ok := x.Message.(*RelayMessage_Disconnected); ok {
+			return x.Disconnected
+		}
+	}
+	return false
+}
 
-func (x *MinionInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[18]
+func (x *RelayMessage) GetStream() *CommandStreamMessage {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
+		if x, ok := x.Message.(*RelayMessage_Stream); ok {
+			return x.Stream
 		}
-		return ms
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use MinionInfo.ProtoReflect.Descriptor instead.
-func (*MinionInfo) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{18}
+type isRelayMessage_Message interface {
+	isRelayMessage_Message()
 }
 
-func (x *MinionInfo) GetId() string {
-	if x != nil {
-		return x.Id
-	}
-	return ""
+type RelayMessage_Register struct {
+	Register *HostInfo `protobuf:"bytes,3,opt,name=register,proto3,oneof"` // Relay -> Nexus: minion registration
 }
 
-type CommandStreamMessage struct {
-	state protoimpl.MessageState `protogen:"open.v1"`
-	// Types that are valid to be assigned to Message:
-	//
-	//	*CommandStreamMessage_Command
-	//	*CommandStreamMessage_Result
-	//	*CommandStreamMessage_Status
-	Message       isCommandStreamMessage_Message `protobuf_oneof:"message"`
+type RelayMessage_Registered struct {
+	Registered *RegisterResponse `protobuf:"bytes,4,opt,name=registered,proto3,oneof"` // Nexus -> Relay: registration outcome
+}
+
+type RelayMessage_Connected struct {
+	Connected bool `protobuf:"varint,5,opt,name=connected,proto3,oneof"` // Relay -> Nexus: minion opened its command stream
+}
+
+type RelayMessage_Disconnected struct {
+	Disconnected bool `protobuf:"varint,6,opt,name=disconnected,proto3,oneof"` // Relay -> Nexus: minion closed its command stream
+}
+
+type RelayMessage_Stream struct {
+	Stream *CommandStreamMessage `protobuf:"bytes,7,opt,name=stream,proto3,oneof"` // Commands, results and status updates of the minion
+}
+
+func (*RelayMessage_Register) isRelayMessage_Message() {}
+
+func (*RelayMessage_Registered) isRelayMessage_Message() {}
+
+func (*RelayMessage_Connected) isRelayMessage_Message() {}
+
+func (*RelayMessage_Disconnected) isRelayMessage_Message() {}
+
+func (*RelayMessage_Stream) isRelayMessage_Message() {}
+
+type TagSchema_Key struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	AllowedValues []string               `protobuf:"bytes,3,rep,name=allowed_values,json=allowedValues,proto3" json:"allowed_values,omitempty"` // empty: any value matching pattern
+	Pattern       string                 `protobuf:"bytes,4,opt,name=pattern,proto3" json:"pattern,omitempty"`                                  // regular expression values must match, empty: any value
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CommandStreamMessage) Reset() {
-	*x = CommandStreamMessage{}
-	mi := &file_minexus_proto_msgTypes[19]
+func (x *TagSchema_Key) Reset() {
+	*x = TagSchema_Key{}
+	mi := &file_minexus_proto_msgTypes[114]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommandStreamMessage) String() string {
+func (x *TagSchema_Key) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommandStreamMessage) ProtoMessage() {}
+func (*TagSchema_Key) ProtoMessage() {}
 
-func (x *CommandStreamMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[19]
+func (x *TagSchema_Key) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[114]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1203,67 +8016,39 @@ func (x *CommandStreamMessage) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommandStreamMessage.ProtoReflect.Descriptor instead.
-func (*CommandStreamMessage) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{19}
+// Deprecated: Use TagSchema_Key.ProtoReflect.Descriptor instead.
+func (*TagSchema_Key) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{12, 0}
 }
 
-func (x *CommandStreamMessage) GetMessage() isCommandStreamMessage_Message {
+func (x *TagSchema_Key) GetKey() string {
 	if x != nil {
-		return x.Message
+		return x.Key
 	}
-	return nil
+	return ""
 }
 
-func (x *CommandStreamMessage) GetCommand() *Command {
+func (x *TagSchema_Key) GetDescription() string {
 	if x != nil {
-		if x, ok := x.Message.(*CommandStreamMessage_Command); ok {
-			return x.Command
-		}
+		return x.Description
 	}
-	return nil
+	return ""
 }
 
-func (x *CommandStreamMessage) GetResult() *CommandResult {
+func (x *TagSchema_Key) GetAllowedValues() []string {
 	if x != nil {
-		if x, ok := x.Message.(*CommandStreamMessage_Result); ok {
-			return x.Result
-		}
+		return x.AllowedValues
 	}
 	return nil
 }
 
-func (x *CommandStreamMessage) GetStatus() *CommandStatusUpdate {
+func (x *TagSchema_Key) GetPattern() string {
 	if x != nil {
-		if x, ok := x.Message.(*CommandStreamMessage_Status); ok {
-			return x.Status
-		}
+		return x.Pattern
 	}
-	return nil
-}
-
-type isCommandStreamMessage_Message interface {
-	isCommandStreamMessage_Message()
-}
-
-type CommandStreamMessage_Command struct {
-	Command *Command `protobuf:"bytes,1,opt,name=command,proto3,oneof"` // Nexus -> Minion: New command to execute
-}
-
-type CommandStreamMessage_Result struct {
-	Result *CommandResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"` // Minion -> Nexus: Result of executed command
-}
-
-type CommandStreamMessage_Status struct {
-	Status *CommandStatusUpdate `protobuf:"bytes,3,opt,name=status,proto3,oneof"` // Minion -> Nexus: Status update for command
+	return ""
 }
 
-func (*CommandStreamMessage_Command) isCommandStreamMessage_Message() {}
-
-func (*CommandStreamMessage_Result) isCommandStreamMessage_Message() {}
-
-func (*CommandStreamMessage_Status) isCommandStreamMessage_Message() {}
-
 type CommandStatusResponse_MinionStatus struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	MinionId      string                 `protobuf:"bytes,1,opt,name=minion_id,json=minionId,proto3" json:"minion_id,omitempty"`
@@ -1275,7 +8060,7 @@ type CommandStatusResponse_MinionStatus struct {
 
 func (x *CommandStatusResponse_MinionStatus) Reset() {
 	*x = CommandStatusResponse_MinionStatus{}
-	mi := &file_minexus_proto_msgTypes[24]
+	mi := &file_minexus_proto_msgTypes[115]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1287,7 +8072,7 @@ func (x *CommandStatusResponse_MinionStatus) String() string {
 func (*CommandStatusResponse_MinionStatus) ProtoMessage() {}
 
 func (x *CommandStatusResponse_MinionStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_minexus_proto_msgTypes[24]
+	mi := &file_minexus_proto_msgTypes[115]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1300,7 +8085,7 @@ func (x *CommandStatusResponse_MinionStatus) ProtoReflect() protoreflect.Message
 
 // Deprecated: Use CommandStatusResponse_MinionStatus.ProtoReflect.Descriptor instead.
 func (*CommandStatusResponse_MinionStatus) Descriptor() ([]byte, []int) {
-	return file_minexus_proto_rawDescGZIP(), []int{10, 0}
+	return file_minexus_proto_rawDescGZIP(), []int{15, 0}
 }
 
 func (x *CommandStatusResponse_MinionStatus) GetMinionId() string {
@@ -1324,29 +8109,123 @@ func (x *CommandStatusResponse_MinionStatus) GetTimestamp() int64 {
 	return 0
 }
 
+type BatchCommandResponse_Entry struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Response      *CommandDispatchResponse `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	Error         string                   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"` // set when the command was rejected
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCommandResponse_Entry) Reset() {
+	*x = BatchCommandResponse_Entry{}
+	mi := &file_minexus_proto_msgTypes[117]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCommandResponse_Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCommandResponse_Entry) ProtoMessage() {}
+
+func (x *BatchCommandResponse_Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_minexus_proto_msgTypes[117]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCommandResponse_Entry.ProtoReflect.Descriptor instead.
+func (*BatchCommandResponse_Entry) Descriptor() ([]byte, []int) {
+	return file_minexus_proto_rawDescGZIP(), []int{25, 0}
+}
+
+func (x *BatchCommandResponse_Entry) GetResponse() *CommandDispatchResponse {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *BatchCommandResponse_Entry) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 var File_minexus_proto protoreflect.FileDescriptor
 
 const file_minexus_proto_rawDesc = "" +
 	"\n" +
-	"\rminexus.proto\x12\aminexus\"\xdd\x01\n" +
+	"\rminexus.proto\x12\aminexus\"\xcd\b\n" +
 	"\bHostInfo\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x0e\n" +
 	"\x02ip\x18\x03 \x01(\tR\x02ip\x12\x0e\n" +
 	"\x02os\x18\x04 \x01(\tR\x02os\x12/\n" +
 	"\x04tags\x18\x05 \x03(\v2\x1b.minexus.HostInfo.TagsEntryR\x04tags\x12\x1b\n" +
-	"\tlast_seen\x18\x06 \x01(\x03R\blastSeen\x1a7\n" +
+	"\tlast_seen\x18\x06 \x01(\x03R\blastSeen\x12\x1d\n" +
+	"\n" +
+	"os_version\x18\a \x01(\tR\tosVersion\x12#\n" +
+	"\rmac_addresses\x18\b \x03(\tR\fmacAddresses\x12 \n" +
+	"\vfingerprint\x18\t \x01(\tR\vfingerprint\x12\"\n" +
+	"\fcapabilities\x18\n" +
+	" \x03(\tR\fcapabilities\x12\x1c\n" +
+	"\tnamespace\x18\v \x01(\tR\tnamespace\x12\x16\n" +
+	"\x06region\x18\f \x01(\tR\x06region\x12\x1e\n" +
+	"\n" +
+	"datacenter\x18\r \x01(\tR\n" +
+	"datacenter\x12\x12\n" +
+	"\x04rack\x18\x0e \x01(\tR\x04rack\x12Q\n" +
+	"\x10command_versions\x18\x0f \x03(\v2&.minexus.HostInfo.CommandVersionsEntryR\x0fcommandVersions\x12-\n" +
+	"\x06health\x18\x10 \x01(\v2\x15.minexus.MinionHealthR\x06health\x12\"\n" +
+	"\favailability\x18\x11 \x01(\tR\favailability\x12\x19\n" +
+	"\bsleep_at\x18\x12 \x01(\x03R\asleepAt\x12\x1b\n" +
+	"\tnext_wake\x18\x13 \x01(\x03R\bnextWake\x12'\n" +
+	"\x0fqueued_commands\x18\x14 \x01(\x05R\x0equeuedCommands\x12\x1a\n" +
+	"\bsleeping\x18\x15 \x01(\bR\bsleeping\x12.\n" +
+	"\acrashes\x18\x16 \x03(\v2\x14.minexus.CrashReportR\acrashes\x12!\n" +
+	"\fpower_action\x18\x17 \x01(\tR\vpowerAction\x12\x19\n" +
+	"\bpower_at\x18\x18 \x01(\x03R\apowerAt\x12!\n" +
+	"\fpowered_down\x18\x19 \x01(\bR\vpoweredDown\x12\x18\n" +
+	"\aversion\x18\x1a \x01(\tR\aversion\x12!\n" +
+	"\fbuild_commit\x18\x1b \x01(\tR\vbuildCommit\x12)\n" +
+	"\x10protocol_version\x18\x1c \x01(\x05R\x0fprotocolVersion\x12\x1a\n" +
+	"\boutdated\x18\x1d \x01(\bR\boutdated\x1a7\n" +
 	"\tTagsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd6\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aB\n" +
+	"\x14CommandVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xc6\x04\n" +
 	"\aCommand\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
 	"\x04type\x18\x02 \x01(\x0e2\x14.minexus.CommandTypeR\x04type\x12\x18\n" +
 	"\apayload\x18\x03 \x01(\tR\apayload\x12:\n" +
-	"\bmetadata\x18\x04 \x03(\v2\x1e.minexus.Command.MetadataEntryR\bmetadata\x1a;\n" +
+	"\bmetadata\x18\x04 \x03(\v2\x1e.minexus.Command.MetadataEntryR\bmetadata\x124\n" +
+	"\bpriority\x18\x05 \x01(\x0e2\x18.minexus.CommandPriorityR\bpriority\x12\x1c\n" +
+	"\tsignature\x18\x06 \x01(\fR\tsignature\x12-\n" +
+	"\x12signer_certificate\x18\a \x01(\fR\x11signerCertificate\x12\x1b\n" +
+	"\tsigned_at\x18\b \x01(\x03R\bsignedAt\x12\x19\n" +
+	"\btrace_id\x18\t \x01(\tR\atraceId\x12+\n" +
+	"\x03env\x18\n" +
+	" \x03(\v2\x19.minexus.Command.EnvEntryR\x03env\x12'\n" +
+	"\x0fsignature_nonce\x18\v \x01(\fR\x0esignatureNonce\x12%\n" +
+	"\x0esigned_targets\x18\f \x03(\tR\rsignedTargets\x1a;\n" +
 	"\rMetadataEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb6\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xdb\x03\n" +
 	"\rCommandResult\x12\x1d\n" +
 	"\n" +
 	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x1b\n" +
@@ -1354,7 +8233,30 @@ const file_minexus_proto_rawDesc = "" +
 	"\texit_code\x18\x03 \x01(\x05R\bexitCode\x12\x16\n" +
 	"\x06stdout\x18\x04 \x01(\tR\x06stdout\x12\x16\n" +
 	"\x06stderr\x18\x05 \x01(\tR\x06stderr\x12\x1c\n" +
-	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\"\x1f\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\x12!\n" +
+	"\fcontent_type\x18\a \x01(\tR\vcontentType\x12\x1e\n" +
+	"\n" +
+	"structured\x18\b \x01(\tR\n" +
+	"structured\x12\x1f\n" +
+	"\vschedule_id\x18\t \x01(\tR\n" +
+	"scheduleId\x12\x18\n" +
+	"\apayload\x18\n" +
+	" \x01(\tR\apayload\x12\x19\n" +
+	"\btrace_id\x18\v \x01(\tR\atraceId\x12\x19\n" +
+	"\bwatch_id\x18\f \x01(\tR\awatchId\x12\x1f\n" +
+	"\vwatch_event\x18\r \x01(\tR\n" +
+	"watchEvent\x12\x18\n" +
+	"\aattempt\x18\x0e \x01(\x05R\aattempt\x124\n" +
+	"\texecution\x18\x0f \x01(\v2\x16.minexus.ExecutionInfoR\texecution\"\x97\x01\n" +
+	"\rExecutionInfo\x12\x1f\n" +
+	"\vworking_dir\x18\x01 \x01(\tR\n" +
+	"workingDir\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x1f\n" +
+	"\vduration_ms\x18\x04 \x01(\x03R\n" +
+	"durationMs\x12\x1c\n" +
+	"\n" +
+	"max_rss_kb\x18\x05 \x01(\x03R\bmaxRssKb\"\x1f\n" +
 	"\x03Ack\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\"\a\n" +
 	"\x05Empty\"\x9d\x01\n" +
@@ -1380,9 +8282,36 @@ const file_minexus_proto_rawDesc = "" +
 	"\x06exists\x18\x03 \x01(\bH\x00R\x06exists\x12\x1f\n" +
 	"\n" +
 	"not_exists\x18\x04 \x01(\bH\x00R\tnotExistsB\v\n" +
-	"\tcondition\"6\n" +
+	"\tcondition\"^\n" +
+	"\x10TopologySelector\x12\x16\n" +
+	"\x06region\x18\x01 \x01(\tR\x06region\x12\x1e\n" +
+	"\n" +
+	"datacenter\x18\x02 \x01(\tR\n" +
+	"datacenter\x12\x12\n" +
+	"\x04rack\x18\x03 \x01(\tR\x04rack\"6\n" +
 	"\vTagSelector\x12'\n" +
-	"\x05rules\x18\x01 \x03(\v2\x11.minexus.TagMatchR\x05rules\"\xfa\x02\n" +
+	"\x05rules\x18\x01 \x03(\v2\x11.minexus.TagMatchR\x05rules\"\xf7\x01\n" +
+	"\tTagSchema\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12*\n" +
+	"\x04keys\x18\x02 \x03(\v2\x16.minexus.TagSchema.KeyR\x04keys\x12(\n" +
+	"\x10allow_other_keys\x18\x03 \x01(\bR\x0eallowOtherKeys\x1az\n" +
+	"\x03Key\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12%\n" +
+	"\x0eallowed_values\x18\x03 \x03(\tR\rallowedValues\x12\x18\n" +
+	"\apattern\x18\x04 \x01(\tR\apattern\"W\n" +
+	"\x10BootstrapRequest\x12\x0e\n" +
+	"\x02os\x18\x01 \x01(\tR\x02os\x12\x12\n" +
+	"\x04arch\x18\x02 \x01(\tR\x04arch\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x03R\n" +
+	"ttlSeconds\"{\n" +
+	"\x0eBootstrapToken\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x0e\n" +
+	"\x02os\x18\x03 \x01(\tR\x02os\x12\x12\n" +
+	"\x04arch\x18\x04 \x01(\tR\x04arch\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\x03R\texpiresAt\"\xfa\x02\n" +
 	"\x15CommandStatusResponse\x12\x1d\n" +
 	"\n" +
 	"command_id\x18\x01 \x01(\tR\tcommandId\x12G\n" +
@@ -1397,56 +8326,611 @@ const file_minexus_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"9\n" +
 	"\n" +
 	"MinionList\x12+\n" +
-	"\aminions\x18\x01 \x03(\v2\x11.minexus.HostInfoR\aminions\"\x94\x01\n" +
+	"\aminions\x18\x01 \x03(\v2\x11.minexus.HostInfoR\aminions\"\xe9\x03\n" +
 	"\x0eCommandRequest\x12\x1d\n" +
 	"\n" +
 	"minion_ids\x18\x01 \x03(\tR\tminionIds\x127\n" +
 	"\ftag_selector\x18\x02 \x01(\v2\x14.minexus.TagSelectorR\vtagSelector\x12*\n" +
-	"\acommand\x18\x03 \x01(\v2\x10.minexus.CommandR\acommand\"T\n" +
+	"\acommand\x18\x03 \x01(\v2\x10.minexus.CommandR\acommand\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\x12\x1c\n" +
+	"\temergency\x18\x05 \x01(\bR\temergency\x124\n" +
+	"\bpriority\x18\x06 \x01(\x0e2\x18.minexus.CommandPriorityR\bpriority\x12#\n" +
+	"\rconfirm_token\x18\a \x01(\tR\fconfirmToken\x12\x12\n" +
+	"\x04lock\x18\b \x01(\tR\x04lock\x125\n" +
+	"\btopology\x18\t \x01(\v2\x19.minexus.TopologySelectorR\btopology\x12\x16\n" +
+	"\x06impact\x18\n" +
+	" \x01(\tR\x06impact\x12*\n" +
+	"\x05retry\x18\v \x01(\v2\x14.minexus.RetryPolicyR\x05retry\x12\x1a\n" +
+	"\boperator\x18\f \x01(\tR\boperator\x12\x16\n" +
+	"\x06sample\x18\r \x01(\x05R\x06sample\"x\n" +
+	"\vRetryPolicy\x12!\n" +
+	"\fmax_attempts\x18\x01 \x01(\x05R\vmaxAttempts\x12'\n" +
+	"\x0fbackoff_seconds\x18\x02 \x01(\x05R\x0ebackoffSeconds\x12\x1d\n" +
+	"\n" +
+	"exit_codes\x18\x03 \x03(\x05R\texitCodes\"\xa6\x01\n" +
+	"\x15ExplainTargetsRequest\x12\x1d\n" +
+	"\n" +
+	"minion_ids\x18\x01 \x03(\tR\tminionIds\x127\n" +
+	"\ftag_selector\x18\x02 \x01(\v2\x14.minexus.TagSelectorR\vtagSelector\x125\n" +
+	"\btopology\x18\x03 \x01(\v2\x19.minexus.TopologySelectorR\btopology\"W\n" +
+	"\x0fRuleExplanation\x12\x12\n" +
+	"\x04rule\x18\x01 \x01(\tR\x04rule\x12\x18\n" +
+	"\amatched\x18\x02 \x01(\bR\amatched\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\x96\x01\n" +
+	"\x11TargetExplanation\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x18\n" +
+	"\amatched\x18\x03 \x01(\bR\amatched\x12.\n" +
+	"\x05rules\x18\x04 \x03(\v2\x18.minexus.RuleExplanationR\x05rules\"\x92\x01\n" +
+	"\x12TargetExplanations\x12\x18\n" +
+	"\amatched\x18\x01 \x01(\x05R\amatched\x124\n" +
+	"\aminions\x18\x02 \x03(\v2\x1a.minexus.TargetExplanationR\aminions\x12,\n" +
+	"\x12unknown_minion_ids\x18\x03 \x03(\tR\x10unknownMinionIds\"\xda\x06\n" +
 	"\x17CommandDispatchResponse\x12\x1a\n" +
 	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x1d\n" +
 	"\n" +
-	"command_id\x18\x02 \x01(\tR\tcommandId\".\n" +
+	"command_id\x18\x02 \x01(\tR\tcommandId\x12*\n" +
+	"\x11target_minion_ids\x18\x03 \x03(\tR\x0ftargetMinionIds\x12\x17\n" +
+	"\adry_run\x18\x04 \x01(\bR\x06dryRun\x12&\n" +
+	"\x0fheld_minion_ids\x18\x05 \x03(\tR\rheldMinionIds\x12,\n" +
+	"\x12skipped_minion_ids\x18\x06 \x03(\tR\x10skippedMinionIds\x123\n" +
+	"\x15confirmation_required\x18\a \x01(\bR\x14confirmationRequired\x12#\n" +
+	"\rconfirm_token\x18\b \x01(\tR\fconfirmToken\x12-\n" +
+	"\x12destructive_reason\x18\t \x01(\tR\x11destructiveReason\x125\n" +
+	"\x17lock_waiting_minion_ids\x18\n" +
+	" \x03(\tR\x14lockWaitingMinionIds\x120\n" +
+	"\x14staggered_minion_ids\x18\v \x03(\tR\x12staggeredMinionIds\x12'\n" +
+	"\x0frollout_seconds\x18\f \x01(\x05R\x0erolloutSeconds\x12+\n" +
+	"\x11approval_required\x18\r \x01(\bR\x10approvalRequired\x12'\n" +
+	"\x0fapproval_reason\x18\x0e \x01(\tR\x0eapprovalReason\x124\n" +
+	"\x16unversioned_minion_ids\x18\x0f \x03(\tR\x14unversionedMinionIds\x12)\n" +
+	"\x10required_version\x18\x10 \x01(\tR\x0frequiredVersion\x12\x19\n" +
+	"\btrace_id\x18\x11 \x01(\tR\atraceId\x12\x16\n" +
+	"\x06impact\x18\x12 \x01(\tR\x06impact\x12'\n" +
+	"\x0fenv_unavailable\x18\x13 \x01(\bR\x0eenvUnavailable\x12;\n" +
+	"\x1aenv_unsupported_minion_ids\x18\x14 \x03(\tR\x17envUnsupportedMinionIds\"J\n" +
+	"\x13BatchCommandRequest\x123\n" +
+	"\brequests\x18\x01 \x03(\v2\x17.minexus.CommandRequestR\brequests\"\xb2\x01\n" +
+	"\x14BatchCommandResponse\x12=\n" +
+	"\aentries\x18\x01 \x03(\v2#.minexus.BatchCommandResponse.EntryR\aentries\x1a[\n" +
+	"\x05Entry\x12<\n" +
+	"\bresponse\x18\x01 \x01(\v2 .minexus.CommandDispatchResponseR\bresponse\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\\\n" +
 	"\rResultRequest\x12\x1d\n" +
 	"\n" +
-	"command_id\x18\x01 \x01(\tR\tcommandId\"B\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"\x86\x01\n" +
 	"\x0eCommandResults\x120\n" +
-	"\aresults\x18\x01 \x03(\v2\x16.minexus.CommandResultR\aresults\"\x87\x01\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.minexus.CommandResultR\aresults\x12\x19\n" +
+	"\bhas_more\x18\x02 \x01(\bR\ahasMore\x12'\n" +
+	"\x0fomitted_results\x18\x03 \x01(\x05R\x0eomittedResults\"\xa1\x01\n" +
+	"\x11MaintenanceWindow\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tminion_id\x18\x02 \x01(\tR\bminionId\x127\n" +
+	"\ftag_selector\x18\x03 \x01(\v2\x14.minexus.TagSelectorR\vtagSelector\x12\x14\n" +
+	"\x05start\x18\x04 \x01(\x03R\x05start\x12\x10\n" +
+	"\x03end\x18\x05 \x01(\x03R\x03end\"\xdc\x02\n" +
+	"\x0fCommandApproval\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\tR\apayload\x12*\n" +
+	"\x11target_minion_ids\x18\x03 \x03(\tR\x0ftargetMinionIds\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12!\n" +
+	"\frequested_by\x18\x05 \x01(\tR\vrequestedBy\x12!\n" +
+	"\frequested_at\x18\x06 \x01(\x03R\vrequestedAt\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"decided_by\x18\b \x01(\tR\tdecidedBy\x12\x1d\n" +
+	"\n" +
+	"decided_at\x18\t \x01(\x03R\tdecidedAt\x12\x18\n" +
+	"\acomment\x18\n" +
+	" \x01(\tR\acomment\x12\x16\n" +
+	"\x06impact\x18\v \x01(\tR\x06impact\"M\n" +
+	"\x13CommandApprovalList\x126\n" +
+	"\tapprovals\x18\x01 \x03(\v2\x18.minexus.CommandApprovalR\tapprovals\"c\n" +
+	"\x10ApprovalDecision\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x16\n" +
+	"\x06reject\x18\x02 \x01(\bR\x06reject\x12\x18\n" +
+	"\acomment\x18\x03 \x01(\tR\acomment\"r\n" +
+	"\x15MaintenanceWindowList\x124\n" +
+	"\awindows\x18\x01 \x03(\v2\x1a.minexus.MaintenanceWindowR\awindows\x12#\n" +
+	"\rheld_commands\x18\x02 \x01(\x05R\fheldCommands\"*\n" +
+	"\x18MaintenanceWindowRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"s\n" +
+	"\x06Report\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\"7\n" +
+	"\n" +
+	"ReportList\x12)\n" +
+	"\areports\x18\x01 \x03(\v2\x0f.minexus.ReportR\areports\"\x9a\x01\n" +
+	"\rReportRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12:\n" +
+	"\x06params\x18\x02 \x03(\v2\".minexus.ReportRequest.ParamsEntryR\x06params\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"#\n" +
+	"\tReportRow\x12\x16\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"d\n" +
+	"\fReportResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\acolumns\x18\x02 \x03(\tR\acolumns\x12&\n" +
+	"\x04rows\x18\x03 \x03(\v2\x12.minexus.ReportRowR\x04rows\".\n" +
+	"\x14DatabaseCheckRequest\x12\x16\n" +
+	"\x06repair\x18\x01 \x01(\bR\x06repair\"\xad\x01\n" +
+	"\rDatabaseCheck\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05found\x18\x03 \x01(\x03R\x05found\x12\x1a\n" +
+	"\brepaired\x18\x04 \x01(\x03R\brepaired\x12\x1e\n" +
+	"\n" +
+	"repairable\x18\x05 \x01(\bR\n" +
+	"repairable\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"_\n" +
+	"\x13DatabaseCheckReport\x12.\n" +
+	"\x06checks\x18\x01 \x03(\v2\x16.minexus.DatabaseCheckR\x06checks\x12\x18\n" +
+	"\ahealthy\x18\x02 \x01(\bR\ahealthy\"]\n" +
+	"\rDatabaseQuery\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06params\x18\x03 \x03(\tR\x06params\"E\n" +
+	"\x11DatabaseQueryList\x120\n" +
+	"\aqueries\x18\x01 \x03(\v2\x16.minexus.DatabaseQueryR\aqueries\"\xa8\x01\n" +
+	"\x14DatabaseQueryRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12A\n" +
+	"\x06params\x18\x02 \x03(\v2).minexus.DatabaseQueryRequest.ParamsEntryR\x06params\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x89\x01\n" +
+	"\x13DatabaseQueryResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\acolumns\x18\x02 \x03(\tR\acolumns\x12&\n" +
+	"\x04rows\x18\x03 \x03(\v2\x12.minexus.ReportRowR\x04rows\x12\x1c\n" +
+	"\ttruncated\x18\x04 \x01(\bR\ttruncated\"I\n" +
+	"\x14MinionHistoryRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"\xe0\x01\n" +
+	"\x12MinionHistoryEntry\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x18\n" +
+	"\acommand\x18\x02 \x01(\tR\acommand\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"has_result\x18\x05 \x01(\bR\thasResult\x12\x1b\n" +
+	"\texit_code\x18\x06 \x01(\x05R\bexitCode\x12\x1f\n" +
+	"\vduration_ms\x18\a \x01(\x03R\n" +
+	"durationMs\"c\n" +
+	"\rMinionHistory\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x125\n" +
+	"\aentries\x18\x02 \x03(\v2\x1b.minexus.MinionHistoryEntryR\aentries\"Y\n" +
+	"\x13MinionUptimeRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12%\n" +
+	"\x0ewindow_seconds\x18\x02 \x01(\x03R\rwindowSeconds\"v\n" +
+	"\x10ConnectionPeriod\x12!\n" +
+	"\fconnected_at\x18\x01 \x01(\x03R\vconnectedAt\x12'\n" +
+	"\x0fdisconnected_at\x18\x02 \x01(\x03R\x0edisconnectedAt\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\xb3\x01\n" +
+	"\fMinionUptime\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x14\n" +
+	"\x05since\x18\x02 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05until\x18\x03 \x01(\x03R\x05until\x12%\n" +
+	"\x0euptime_percent\x18\x04 \x01(\x01R\ruptimePercent\x123\n" +
+	"\aperiods\x18\x05 \x03(\v2\x19.minexus.ConnectionPeriodR\aperiods\"\xab\x01\n" +
+	"\x0eMinionLogEntry\x12!\n" +
+	"\ftimestamp_ms\x18\x01 \x01(\x03R\vtimestampMs\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12\x16\n" +
+	"\x06logger\x18\x03 \x01(\tR\x06logger\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x16\n" +
+	"\x06caller\x18\x05 \x01(\tR\x06caller\x12\x16\n" +
+	"\x06fields\x18\x06 \x01(\tR\x06fields\"]\n" +
+	"\x0eMinionLogBatch\x121\n" +
+	"\aentries\x18\x01 \x03(\v2\x17.minexus.MinionLogEntryR\aentries\x12\x18\n" +
+	"\adropped\x18\x02 \x01(\x03R\adropped\"y\n" +
+	"\x11MinionLogsRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1b\n" +
+	"\tmin_level\x18\x02 \x01(\tR\bminLevel\x12\x14\n" +
+	"\x05since\x18\x03 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"\\\n" +
+	"\n" +
+	"MinionLogs\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x121\n" +
+	"\aentries\x18\x02 \x03(\v2\x17.minexus.MinionLogEntryR\aentries\"h\n" +
+	"\x13RetireMinionRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1c\n" +
+	"\tuninstall\x18\x02 \x01(\bR\tuninstall\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\xa5\x01\n" +
+	"\x14RetireMinionResponse\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1a\n" +
+	"\bnotified\x18\x02 \x01(\bR\bnotified\x12)\n" +
+	"\x10archived_results\x18\x03 \x01(\x05R\x0farchivedResults\x12)\n" +
+	"\x10dropped_commands\x18\x04 \x01(\x05R\x0fdroppedCommands\"c\n" +
+	"\fRetireNotice\x12\x1c\n" +
+	"\tuninstall\x18\x01 \x01(\bR\tuninstall\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"retired_by\x18\x03 \x01(\tR\tretiredBy\"\xb0\x01\n" +
+	"\vCrashReport\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x1c\n" +
+	"\tcomponent\x18\x02 \x01(\tR\tcomponent\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x14\n" +
+	"\x05stack\x18\x04 \x01(\tR\x05stack\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12\x1b\n" +
+	"\tminion_id\x18\x06 \x01(\tR\bminionId\"E\n" +
+	"\x10CrashListRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\";\n" +
+	"\tCrashList\x12.\n" +
+	"\acrashes\x18\x01 \x03(\v2\x14.minexus.CrashReportR\acrashes\"\xab\x01\n" +
+	"\rCommandEnvVar\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x17\n" +
+	"\atag_key\x18\x02 \x01(\tR\x06tagKey\x12\x1b\n" +
+	"\ttag_value\x18\x03 \x01(\tR\btagValue\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x05 \x01(\tR\x05value\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\x03R\tupdatedAt\"<\n" +
+	"\x0eCommandEnvList\x12*\n" +
+	"\x04vars\x18\x01 \x03(\v2\x16.minexus.CommandEnvVarR\x04vars\")\n" +
+	"\fTraceRequest\x12\x19\n" +
+	"\btrace_id\x18\x01 \x01(\tR\atraceId\"\xb7\x01\n" +
+	"\n" +
+	"TraceEvent\x12!\n" +
+	"\ftimestamp_ms\x18\x01 \x01(\x03R\vtimestampMs\x12\x1c\n" +
+	"\tcomponent\x18\x02 \x01(\tR\tcomponent\x12\x14\n" +
+	"\x05event\x18\x03 \x01(\tR\x05event\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x04 \x01(\tR\tcommandId\x12\x1b\n" +
+	"\tminion_id\x18\x05 \x01(\tR\bminionId\x12\x16\n" +
+	"\x06detail\x18\x06 \x01(\tR\x06detail\"O\n" +
+	"\x05Trace\x12\x19\n" +
+	"\btrace_id\x18\x01 \x01(\tR\atraceId\x12+\n" +
+	"\x06events\x18\x02 \x03(\v2\x13.minexus.TraceEventR\x06events\"\x84\x01\n" +
+	"\x13CommandStatsRequest\x12\x14\n" +
+	"\x05since\x18\x01 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05until\x18\x02 \x01(\x03R\x05until\x12'\n" +
+	"\x0fcommand_pattern\x18\x03 \x01(\tR\x0ecommandPattern\x12\x18\n" +
+	"\aslowest\x18\x04 \x01(\x05R\aslowest\"\x97\x01\n" +
+	"\x12MinionCommandStats\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x1a\n" +
+	"\bfailures\x18\x03 \x01(\x03R\bfailures\x12\x1b\n" +
+	"\tmedian_ms\x18\x04 \x01(\x03R\bmedianMs\x12\x15\n" +
+	"\x06max_ms\x18\x05 \x01(\x03R\x05maxMs\"^\n" +
+	"\x12ImpactCommandStats\x12\x16\n" +
+	"\x06impact\x18\x01 \x01(\tR\x06impact\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x1a\n" +
+	"\bfailures\x18\x03 \x01(\x03R\bfailures\"\xce\x02\n" +
+	"\fCommandStats\x12\x14\n" +
+	"\x05since\x18\x01 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05until\x18\x02 \x01(\x03R\x05until\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x03R\x05count\x12\x1a\n" +
+	"\bfailures\x18\x04 \x01(\x03R\bfailures\x12\x1b\n" +
+	"\tmedian_ms\x18\x05 \x01(\x03R\bmedianMs\x12\x15\n" +
+	"\x06p90_ms\x18\x06 \x01(\x03R\x05p90Ms\x12\x15\n" +
+	"\x06p99_ms\x18\a \x01(\x03R\x05p99Ms\x12\x15\n" +
+	"\x06max_ms\x18\b \x01(\x03R\x05maxMs\x12D\n" +
+	"\x0fslowest_minions\x18\t \x03(\v2\x1b.minexus.MinionCommandStatsR\x0eslowestMinions\x128\n" +
+	"\tby_impact\x18\n" +
+	" \x03(\v2\x1b.minexus.ImpactCommandStatsR\bbyImpact\"B\n" +
+	"\x14ReceiptExportRequest\x12\x14\n" +
+	"\x05since\x18\x01 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05until\x18\x02 \x01(\x03R\x05until\"y\n" +
+	"\x10ExecutionReceipt\x12\x18\n" +
+	"\areceipt\x18\x01 \x01(\fR\areceipt\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\x12-\n" +
+	"\x12signer_certificate\x18\x03 \x01(\fR\x11signerCertificate\"7\n" +
+	"\x18MinionDiagnosticsRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\"]\n" +
+	"\x0fConnectionEvent\x12\x1c\n" +
+	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x14\n" +
+	"\x05event\x18\x02 \x01(\tR\x05event\x12\x16\n" +
+	"\x06detail\x18\x03 \x01(\tR\x06detail\"\xd4\x05\n" +
+	"\x11MinionDiagnostics\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1e\n" +
+	"\n" +
+	"registered\x18\x02 \x01(\bR\n" +
+	"registered\x12!\n" +
+	"\fstream_state\x18\x03 \x01(\tR\vstreamState\x12%\n" +
+	"\x0eactive_streams\x18\x04 \x01(\x05R\ractiveStreams\x12.\n" +
+	"\x13stream_connected_at\x18\x05 \x01(\x03R\x11streamConnectedAt\x12\x1b\n" +
+	"\tlast_seen\x18\x06 \x01(\x03R\blastSeen\x12#\n" +
+	"\rchannel_depth\x18\a \x01(\x05R\fchannelDepth\x12)\n" +
+	"\x10channel_capacity\x18\b \x01(\x05R\x0fchannelCapacity\x12)\n" +
+	"\x10pending_commands\x18\t \x01(\x05R\x0fpendingCommands\x12#\n" +
+	"\rheld_commands\x18\n" +
+	" \x01(\x05R\fheldCommands\x12#\n" +
+	"\rcommands_sent\x18\v \x01(\x03R\fcommandsSent\x12)\n" +
+	"\x10results_received\x18\f \x01(\x03R\x0fresultsReceived\x120\n" +
+	"\x06events\x18\r \x03(\v2\x18.minexus.ConnectionEventR\x06events\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x0e \x01(\tR\tlastError\x12\"\n" +
+	"\rlast_error_at\x18\x0f \x01(\x03R\vlastErrorAt\x12\x1b\n" +
+	"\tin_flight\x18\x10 \x01(\x05R\binFlight\x12&\n" +
+	"\x0fin_flight_limit\x18\x11 \x01(\x05R\rinFlightLimit\x12A\n" +
+	"\x0flast_speed_test\x18\x12 \x01(\v2\x19.minexus.SpeedTestSummaryR\rlastSpeedTest\"\xb4\x01\n" +
+	"\x10SpeedTestSummary\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x03 \x01(\x01R\tlatencyMs\x12\x1f\n" +
+	"\vupload_mbps\x18\x04 \x01(\x01R\n" +
+	"uploadMbps\x12#\n" +
+	"\rdownload_mbps\x18\x05 \x01(\x01R\fdownloadMbps\"\xee\x01\n" +
+	"\tFileChunk\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x14\n" +
+	"\x05start\x18\x05 \x01(\bR\x05start\x12\x16\n" +
+	"\x06cancel\x18\x06 \x01(\bR\x06cancel\x12\x12\n" +
+	"\x04size\x18\a \x01(\x03R\x04size\x12\x10\n" +
+	"\x03eof\x18\b \x01(\bR\x03eof\x12\x16\n" +
+	"\x06sha256\x18\t \x01(\tR\x06sha256\x12\x14\n" +
+	"\x05error\x18\n" +
+	" \x01(\tR\x05error\"c\n" +
+	"\x0fFilePullRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1f\n" +
+	"\vtransfer_id\x18\x03 \x01(\tR\n" +
+	"transferId\"\x98\x02\n" +
+	"\x12FileTransferStatus\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12\x1b\n" +
+	"\tminion_id\x18\x02 \x01(\tR\bminionId\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x1a\n" +
+	"\breceived\x18\x05 \x01(\x03R\breceived\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\a \x01(\tR\x06sha256\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\t \x01(\x03R\tstartedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\n" +
+	" \x01(\x03R\tupdatedAt\"M\n" +
+	"\x10FileTransferList\x129\n" +
+	"\ttransfers\x18\x01 \x03(\v2\x1b.minexus.FileTransferStatusR\ttransfers\"N\n" +
+	"\x13FileDownloadRequest\x12\x1f\n" +
+	"\vtransfer_id\x18\x01 \x01(\tR\n" +
+	"transferId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\"\xff\x01\n" +
+	"\fMinionHealth\x12\x14\n" +
+	"\x05score\x18\x01 \x01(\x05R\x05score\x12\x1c\n" +
+	"\tanomalies\x18\x02 \x03(\tR\tanomalies\x12\x1e\n" +
+	"\n" +
+	"reconnects\x18\x03 \x01(\x05R\n" +
+	"reconnects\x12\x18\n" +
+	"\aresults\x18\x04 \x01(\x05R\aresults\x12%\n" +
+	"\x0efailed_results\x18\x05 \x01(\x05R\rfailedResults\x12&\n" +
+	"\x0fmean_latency_ms\x18\x06 \x01(\x03R\rmeanLatencyMs\x122\n" +
+	"\x15heartbeat_interval_ms\x18\a \x01(\x03R\x13heartbeatIntervalMs\"*\n" +
+	"\x12FleetHealthRequest\x12\x14\n" +
+	"\x05below\x18\x01 \x01(\x05R\x05below\"\xc9\x01\n" +
+	"\vFleetHealth\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12\x18\n" +
+	"\ahealthy\x18\x02 \x01(\x05R\ahealthy\x12\x1a\n" +
+	"\bdegraded\x18\x03 \x01(\x05R\bdegraded\x12\x1c\n" +
+	"\tunhealthy\x18\x04 \x01(\x05R\tunhealthy\x12#\n" +
+	"\raverage_score\x18\x05 \x01(\x05R\faverageScore\x12+\n" +
+	"\aminions\x18\x06 \x03(\v2\x11.minexus.HostInfoR\aminions\"\x94\x02\n" +
+	"\rFleetVersions\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x05R\x05total\x12#\n" +
+	"\rnexus_version\x18\x02 \x01(\tR\fnexusVersion\x124\n" +
+	"\x16nexus_protocol_version\x18\x03 \x01(\x05R\x14nexusProtocolVersion\x120\n" +
+	"\x14min_protocol_version\x18\x04 \x01(\x05R\x12minProtocolVersion\x121\n" +
+	"\bversions\x18\x05 \x03(\v2\x15.minexus.VersionCountR\bversions\x12-\n" +
+	"\boutdated\x18\x06 \x03(\v2\x11.minexus.HostInfoR\boutdated\"\x90\x01\n" +
+	"\fVersionCount\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12!\n" +
+	"\fbuild_commit\x18\x02 \x01(\tR\vbuildCommit\x12)\n" +
+	"\x10protocol_version\x18\x03 \x01(\x05R\x0fprotocolVersion\x12\x18\n" +
+	"\aminions\x18\x04 \x01(\x05R\aminions\"3\n" +
+	"\x16PatchComplianceRequest\x12\x19\n" +
+	"\bgroup_by\x18\x01 \x01(\tR\agroupBy\"\x84\x01\n" +
+	"\x0fPatchCompliance\x12\x19\n" +
+	"\bgroup_by\x18\x01 \x01(\tR\agroupBy\x12)\n" +
+	"\x05total\x18\x02 \x01(\v2\x13.minexus.PatchGroupR\x05total\x12+\n" +
+	"\x06groups\x18\x03 \x03(\v2\x13.minexus.PatchGroupR\x06groups\"\xa7\x02\n" +
+	"\n" +
+	"PatchGroup\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x18\n" +
+	"\aminions\x18\x02 \x01(\x05R\aminions\x12\x1c\n" +
+	"\tcompliant\x18\x03 \x01(\x05R\tcompliant\x12\x1e\n" +
+	"\n" +
+	"vulnerable\x18\x04 \x01(\x05R\n" +
+	"vulnerable\x12\x18\n" +
+	"\aunknown\x18\x05 \x01(\x05R\aunknown\x12'\n" +
+	"\x0freboot_required\x18\x06 \x01(\x05R\x0erebootRequired\x12)\n" +
+	"\x10security_updates\x18\a \x01(\x05R\x0fsecurityUpdates\x12\x18\n" +
+	"\aupdates\x18\b \x01(\x05R\aupdates\x12#\n" +
+	"\roldest_status\x18\t \x01(\x03R\foldestStatus\"b\n" +
+	"\x13FlushCachesResponse\x12)\n" +
+	"\x10pending_commands\x18\x01 \x01(\x05R\x0fpendingCommands\x12 \n" +
+	"\vdiagnostics\x18\x02 \x01(\x05R\vdiagnostics\"C\n" +
+	"\x0fLogLevelRequest\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x12\x1a\n" +
+	"\bsampling\x18\x02 \x01(\tR\bsampling\"\x98\x01\n" +
+	"\x10LogLevelResponse\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x12%\n" +
+	"\x0eprevious_level\x18\x02 \x01(\tR\rpreviousLevel\x12\x1a\n" +
+	"\bsampling\x18\x03 \x01(\tR\bsampling\x12+\n" +
+	"\x11previous_sampling\x18\x04 \x01(\tR\x10previousSampling\"\xc5\x02\n" +
+	"\rRegistryEntry\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x1b\n" +
+	"\tlast_seen\x18\x03 \x01(\x03R\blastSeen\x12\x1c\n" +
+	"\tconnected\x18\x04 \x01(\bR\tconnected\x12'\n" +
+	"\x0fqueued_commands\x18\x05 \x01(\x05R\x0equeuedCommands\x12\x1b\n" +
+	"\tin_flight\x18\x06 \x01(\x05R\binFlight\x12)\n" +
+	"\x10pending_commands\x18\a \x01(\x05R\x0fpendingCommands\x12#\n" +
+	"\rid_collisions\x18\b \x01(\x05R\fidCollisions\x12*\n" +
+	"\x11last_id_collision\x18\t \x01(\x03R\x0flastIdCollision\"\xae\x01\n" +
+	"\fRegistryDump\x120\n" +
+	"\aminions\x18\x01 \x03(\v2\x16.minexus.RegistryEntryR\aminions\x12\x1c\n" +
+	"\tconnected\x18\x02 \x01(\x05R\tconnected\x12)\n" +
+	"\x10pending_commands\x18\x03 \x01(\x05R\x0fpendingCommands\x12#\n" +
+	"\rheld_commands\x18\x04 \x01(\x05R\fheldCommands\"6\n" +
+	"\x17DisconnectMinionRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\"e\n" +
+	"\x15PruneDatabaseResponse\x12+\n" +
+	"\x11archived_commands\x18\x01 \x01(\x05R\x10archivedCommands\x12\x1f\n" +
+	"\vpruned_logs\x18\x02 \x01(\x03R\n" +
+	"prunedLogs\"2\n" +
+	"\x13UnbindMinionRequest\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\"\xa2\x01\n" +
 	"\x13CommandStatusUpdate\x12\x1d\n" +
 	"\n" +
 	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x1b\n" +
 	"\tminion_id\x18\x02 \x01(\tR\bminionId\x12\x16\n" +
 	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"r\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x19\n" +
+	"\btrace_id\x18\x05 \x01(\tR\atraceId\"\xd5\x02\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1f\n" +
 	"\vassigned_id\x18\x02 \x01(\tR\n" +
 	"assignedId\x12#\n" +
-	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\"\x1c\n" +
+	"\rerror_message\x18\x03 \x01(\tR\ferrorMessage\x12\x1f\n" +
+	"\vresult_acks\x18\x04 \x01(\bR\n" +
+	"resultAcks\x12)\n" +
+	"\x10protocol_version\x18\x05 \x01(\x05R\x0fprotocolVersion\x12 \n" +
+	"\fmax_msg_size\x18\x06 \x01(\x05R\n" +
+	"maxMsgSize\x12-\n" +
+	"\x06retire\x18\a \x01(\v2\x15.minexus.RetireNoticeR\x06retire\x12\x1d\n" +
+	"\n" +
+	"crash_acks\x18\b \x01(\bR\tcrashAcks\x12%\n" +
+	"\x0ecrashes_stored\x18\t \x01(\bR\rcrashesStored\"\x1c\n" +
 	"\n" +
 	"MinionInfo\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"\xb9\x01\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x8d\x04\n" +
 	"\x14CommandStreamMessage\x12,\n" +
 	"\acommand\x18\x01 \x01(\v2\x10.minexus.CommandH\x00R\acommand\x120\n" +
 	"\x06result\x18\x02 \x01(\v2\x16.minexus.CommandResultH\x00R\x06result\x126\n" +
-	"\x06status\x18\x03 \x01(\v2\x1c.minexus.CommandStatusUpdateH\x00R\x06statusB\t\n" +
+	"\x06status\x18\x03 \x01(\v2\x1c.minexus.CommandStatusUpdateH\x00R\x06status\x12-\n" +
+	"\x05shell\x18\x04 \x01(\v2\x15.minexus.ShellMessageH\x00R\x05shell\x126\n" +
+	"\treconnect\x18\x05 \x01(\v2\x16.minexus.ReconnectHintH\x00R\treconnect\x12-\n" +
+	"\x04logs\x18\x06 \x01(\v2\x17.minexus.MinionLogBatchH\x00R\x04logs\x12&\n" +
+	"\x03ack\x18\a \x01(\v2\x12.minexus.ResultAckH\x00R\x03ack\x12(\n" +
+	"\x04file\x18\b \x01(\v2\x12.minexus.FileChunkH\x00R\x04file\x129\n" +
+	"\fresult_chunk\x18\t \x01(\v2\x14.minexus.ResultChunkH\x00R\vresultChunk\x12/\n" +
+	"\x06retire\x18\n" +
+	" \x01(\v2\x15.minexus.RetireNoticeH\x00R\x06retireB\t\n" +
+	"\amessage\"l\n" +
+	"\vResultChunk\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x05R\x05index\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x05R\x05total\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\"*\n" +
+	"\tResultAck\x12\x1d\n" +
+	"\n" +
+	"command_id\x18\x01 \x01(\tR\tcommandId\"L\n" +
+	"\rReconnectHint\x12#\n" +
+	"\rdelay_seconds\x18\x01 \x01(\x05R\fdelaySeconds\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\xe3\x01\n" +
+	"\fShellMessage\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1b\n" +
+	"\tminion_id\x18\x02 \x01(\tR\bminionId\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04rows\x18\x04 \x01(\rR\x04rows\x12\x12\n" +
+	"\x04cols\x18\x05 \x01(\rR\x04cols\x12\x12\n" +
+	"\x04open\x18\x06 \x01(\bR\x04open\x12\x14\n" +
+	"\x05close\x18\a \x01(\bR\x05close\x12\x1b\n" +
+	"\texit_code\x18\b \x01(\x05R\bexitCode\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"\xd3\x01\n" +
+	"\x0eSpeedTestProbe\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x12\n" +
+	"\x04ping\x18\x02 \x01(\x05R\x04ping\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x1f\n" +
+	"\vupload_done\x18\x04 \x01(\bR\n" +
+	"uploadDone\x12\x1a\n" +
+	"\breceived\x18\x05 \x01(\x03R\breceived\x12\x1a\n" +
+	"\bdownload\x18\x06 \x01(\x03R\bdownload\x12#\n" +
+	"\rdownload_done\x18\a \x01(\bR\fdownloadDone\"\xc2\x02\n" +
+	"\fRelayMessage\x12\x1b\n" +
+	"\tminion_id\x18\x01 \x01(\tR\bminionId\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12/\n" +
+	"\bregister\x18\x03 \x01(\v2\x11.minexus.HostInfoH\x00R\bregister\x12;\n" +
+	"\n" +
+	"registered\x18\x04 \x01(\v2\x19.minexus.RegisterResponseH\x00R\n" +
+	"registered\x12\x1e\n" +
+	"\tconnected\x18\x05 \x01(\bH\x00R\tconnected\x12$\n" +
+	"\fdisconnected\x18\x06 \x01(\bH\x00R\fdisconnected\x127\n" +
+	"\x06stream\x18\a \x01(\v2\x1d.minexus.CommandStreamMessageH\x00R\x06streamB\t\n" +
 	"\amessage*'\n" +
 	"\vCommandType\x12\n" +
 	"\n" +
 	"\x06SYSTEM\x10\x00\x12\f\n" +
-	"\bINTERNAL\x10\x012\xb8\x03\n" +
+	"\bINTERNAL\x10\x01*?\n" +
+	"\x0fCommandPriority\x12\n" +
+	"\n" +
+	"\x06NORMAL\x10\x00\x12\a\n" +
+	"\x03LOW\x10\x01\x12\b\n" +
+	"\x04HIGH\x10\x02\x12\r\n" +
+	"\tEMERGENCY\x10\x032\xe3\x15\n" +
 	"\x0eConsoleService\x122\n" +
 	"\vListMinions\x12\x0e.minexus.Empty\x1a\x13.minexus.MinionList\x12,\n" +
 	"\bListTags\x12\x0e.minexus.Empty\x1a\x10.minexus.TagList\x120\n" +
 	"\aSetTags\x12\x17.minexus.SetTagsRequest\x1a\f.minexus.Ack\x126\n" +
 	"\n" +
-	"UpdateTags\x12\x1a.minexus.UpdateTagsRequest\x1a\f.minexus.Ack\x12H\n" +
-	"\vSendCommand\x12\x17.minexus.CommandRequest\x1a .minexus.CommandDispatchResponse\x12D\n" +
+	"UpdateTags\x12\x1a.minexus.UpdateTagsRequest\x1a\f.minexus.Ack\x122\n" +
+	"\fGetTagSchema\x12\x0e.minexus.Empty\x1a\x12.minexus.TagSchema\x12J\n" +
+	"\x14CreateBootstrapToken\x12\x19.minexus.BootstrapRequest\x1a\x17.minexus.BootstrapToken\x12H\n" +
+	"\vSendCommand\x12\x17.minexus.CommandRequest\x1a .minexus.CommandDispatchResponse\x12M\n" +
+	"\x0eExplainTargets\x12\x1e.minexus.ExplainTargetsRequest\x1a\x1b.minexus.TargetExplanations\x12O\n" +
+	"\x10BatchSendCommand\x12\x1c.minexus.BatchCommandRequest\x1a\x1d.minexus.BatchCommandResponse\x12D\n" +
 	"\x11GetCommandResults\x12\x16.minexus.ResultRequest\x1a\x17.minexus.CommandResults\x12J\n" +
-	"\x10GetCommandStatus\x12\x16.minexus.ResultRequest\x1a\x1e.minexus.CommandStatusResponse2\x9d\x01\n" +
+	"\x10GetCommandStatus\x12\x16.minexus.ResultRequest\x1a\x1e.minexus.CommandStatusResponse\x12D\n" +
+	"\x14ListCommandApprovals\x12\x0e.minexus.Empty\x1a\x1c.minexus.CommandApprovalList\x12T\n" +
+	"\x15DecideCommandApproval\x12\x19.minexus.ApprovalDecision\x1a .minexus.CommandDispatchResponse\x12N\n" +
+	"\x14AddMaintenanceWindow\x12\x1a.minexus.MaintenanceWindow\x1a\x1a.minexus.MaintenanceWindow\x12H\n" +
+	"\x16ListMaintenanceWindows\x12\x0e.minexus.Empty\x1a\x1e.minexus.MaintenanceWindowList\x12J\n" +
+	"\x17RemoveMaintenanceWindow\x12!.minexus.MaintenanceWindowRequest\x1a\f.minexus.Ack\x125\n" +
+	"\rSetCommandEnv\x12\x16.minexus.CommandEnvVar\x1a\f.minexus.Ack\x127\n" +
+	"\x0fUnsetCommandEnv\x12\x16.minexus.CommandEnvVar\x1a\f.minexus.Ack\x129\n" +
+	"\x0eListCommandEnv\x12\x0e.minexus.Empty\x1a\x17.minexus.CommandEnvList\x12U\n" +
+	"\x14GetMinionDiagnostics\x12!.minexus.MinionDiagnosticsRequest\x1a\x1a.minexus.MinionDiagnostics\x12C\n" +
+	"\x0eGetFleetHealth\x12\x1b.minexus.FleetHealthRequest\x1a\x14.minexus.FleetHealth\x12:\n" +
+	"\x10GetFleetVersions\x12\x0e.minexus.Empty\x1a\x16.minexus.FleetVersions\x12O\n" +
+	"\x12GetPatchCompliance\x12\x1f.minexus.PatchComplianceRequest\x1a\x18.minexus.PatchCompliance\x12I\n" +
+	"\x10GetMinionHistory\x12\x1d.minexus.MinionHistoryRequest\x1a\x16.minexus.MinionHistory\x12F\n" +
+	"\x0fGetMinionUptime\x12\x1c.minexus.MinionUptimeRequest\x1a\x15.minexus.MinionUptime\x12@\n" +
+	"\rGetMinionLogs\x12\x1a.minexus.MinionLogsRequest\x1a\x13.minexus.MinionLogs\x12K\n" +
+	"\fRetireMinion\x12\x1c.minexus.RetireMinionRequest\x1a\x1d.minexus.RetireMinionResponse\x12<\n" +
+	"\vListCrashes\x12\x19.minexus.CrashListRequest\x1a\x12.minexus.CrashList\x121\n" +
+	"\bGetTrace\x12\x15.minexus.TraceRequest\x1a\x0e.minexus.Trace\x12F\n" +
+	"\x0fGetCommandStats\x12\x1c.minexus.CommandStatsRequest\x1a\x15.minexus.CommandStats\x12L\n" +
+	"\x0eExportReceipts\x12\x1d.minexus.ReceiptExportRequest\x1a\x19.minexus.ExecutionReceipt0\x01\x120\n" +
+	"\fCreateReport\x12\x0f.minexus.Report\x1a\x0f.minexus.Report\x122\n" +
+	"\vListReports\x12\x0e.minexus.Empty\x1a\x13.minexus.ReportList\x12:\n" +
+	"\tRunReport\x12\x16.minexus.ReportRequest\x1a\x15.minexus.ReportResult\x12L\n" +
+	"\rCheckDatabase\x12\x1d.minexus.DatabaseCheckRequest\x1a\x1c.minexus.DatabaseCheckReport\x12A\n" +
+	"\x13ListDatabaseQueries\x12\x0e.minexus.Empty\x1a\x1a.minexus.DatabaseQueryList\x12O\n" +
+	"\x10RunDatabaseQuery\x12\x1d.minexus.DatabaseQueryRequest\x1a\x1c.minexus.DatabaseQueryResult\x12=\n" +
+	"\tOpenShell\x12\x15.minexus.ShellMessage\x1a\x15.minexus.ShellMessage(\x010\x01\x12C\n" +
+	"\bPullFile\x12\x18.minexus.FilePullRequest\x1a\x1b.minexus.FileTransferStatus0\x01\x12>\n" +
+	"\x11ListFileTransfers\x12\x0e.minexus.Empty\x1a\x19.minexus.FileTransferList\x12B\n" +
+	"\fDownloadFile\x12\x1c.minexus.FileDownloadRequest\x1a\x12.minexus.FileChunk0\x012\x87\x03\n" +
+	"\fAdminService\x12;\n" +
+	"\vFlushCaches\x12\x0e.minexus.Empty\x1a\x1c.minexus.FlushCachesResponse\x12B\n" +
+	"\vSetLogLevel\x12\x18.minexus.LogLevelRequest\x1a\x19.minexus.LogLevelResponse\x125\n" +
+	"\fDumpRegistry\x12\x0e.minexus.Empty\x1a\x15.minexus.RegistryDump\x12B\n" +
+	"\x10DisconnectMinion\x12 .minexus.DisconnectMinionRequest\x1a\f.minexus.Ack\x12?\n" +
+	"\rPruneDatabase\x12\x0e.minexus.Empty\x1a\x1e.minexus.PruneDatabaseResponse\x12:\n" +
+	"\fUnbindMinion\x12\x1c.minexus.UnbindMinionRequest\x1a\f.minexus.Ack2\xa1\x02\n" +
 	"\rMinionService\x128\n" +
 	"\bRegister\x12\x11.minexus.HostInfo\x1a\x19.minexus.RegisterResponse\x12R\n" +
-	"\x0eStreamCommands\x12\x1d.minexus.CommandStreamMessage\x1a\x1d.minexus.CommandStreamMessage(\x010\x01B\x15Z\x13minexus/proto;protob\x06proto3"
+	"\x0eStreamCommands\x12\x1d.minexus.CommandStreamMessage\x1a\x1d.minexus.CommandStreamMessage(\x010\x01\x12?\n" +
+	"\vRelayStream\x12\x15.minexus.RelayMessage\x1a\x15.minexus.RelayMessage(\x010\x01\x12A\n" +
+	"\tSpeedTest\x12\x17.minexus.SpeedTestProbe\x1a\x17.minexus.SpeedTestProbe(\x010\x01B\x15Z\x13minexus/proto;protob\x06proto3"
 
 var (
 	file_minexus_proto_rawDescOnce sync.Once
@@ -1460,76 +8944,311 @@ func file_minexus_proto_rawDescGZIP() []byte {
 	return file_minexus_proto_rawDescData
 }
 
-var file_minexus_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_minexus_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_minexus_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_minexus_proto_msgTypes = make([]protoimpl.MessageInfo, 120)
 var file_minexus_proto_goTypes = []any{
-	(CommandType)(0),                // 0: minexus.CommandType
-	(*HostInfo)(nil),                // 1: minexus.HostInfo
-	(*Command)(nil),                 // 2: minexus.Command
-	(*CommandResult)(nil),           // 3: minexus.CommandResult
-	(*Ack)(nil),                     // 4: minexus.Ack
-	(*Empty)(nil),                   // 5: minexus.Empty
-	(*SetTagsRequest)(nil),          // 6: minexus.SetTagsRequest
-	(*UpdateTagsRequest)(nil),       // 7: minexus.UpdateTagsRequest
-	(*TagList)(nil),                 // 8: minexus.TagList
-	(*TagMatch)(nil),                // 9: minexus.TagMatch
-	(*TagSelector)(nil),             // 10: minexus.TagSelector
-	(*CommandStatusResponse)(nil),   // 11: minexus.CommandStatusResponse
-	(*MinionList)(nil),              // 12: minexus.MinionList
-	(*CommandRequest)(nil),          // 13: minexus.CommandRequest
-	(*CommandDispatchResponse)(nil), // 14: minexus.CommandDispatchResponse
-	(*ResultRequest)(nil),           // 15: minexus.ResultRequest
-	(*CommandResults)(nil),          // 16: minexus.CommandResults
-	(*CommandStatusUpdate)(nil),     // 17: minexus.CommandStatusUpdate
-	(*RegisterResponse)(nil),        // 18: minexus.RegisterResponse
-	(*MinionInfo)(nil),              // 19: minexus.MinionInfo
-	(*CommandStreamMessage)(nil),    // 20: minexus.CommandStreamMessage
-	nil,                             // 21: minexus.HostInfo.TagsEntry
-	nil,                             // 22: minexus.Command.MetadataEntry
-	nil,                             // 23: minexus.SetTagsRequest.TagsEntry
-	nil,                             // 24: minexus.UpdateTagsRequest.AddEntry
-	(*CommandStatusResponse_MinionStatus)(nil), // 25: minexus.CommandStatusResponse.MinionStatus
-	nil, // 26: minexus.CommandStatusResponse.StatusCountsEntry
+	(CommandType)(0),                           // 0: minexus.CommandType
+	(CommandPriority)(0),                       // 1: minexus.CommandPriority
+	(*HostInfo)(nil),                           // 2: minexus.HostInfo
+	(*Command)(nil),                            // 3: minexus.Command
+	(*CommandResult)(nil),                      // 4: minexus.CommandResult
+	(*ExecutionInfo)(nil),                      // 5: minexus.ExecutionInfo
+	(*Ack)(nil),                                // 6: minexus.Ack
+	(*Empty)(nil),                              // 7: minexus.Empty
+	(*SetTagsRequest)(nil),                     // 8: minexus.SetTagsRequest
+	(*UpdateTagsRequest)(nil),                  // 9: minexus.UpdateTagsRequest
+	(*TagList)(nil),                            // 10: minexus.TagList
+	(*TagMatch)(nil),                           // 11: minexus.TagMatch
+	(*TopologySelector)(nil),                   // 12: minexus.TopologySelector
+	(*TagSelector)(nil),                        // 13: minexus.TagSelector
+	(*TagSchema)(nil),                          // 14: minexus.TagSchema
+	(*BootstrapRequest)(nil),                   // 15: minexus.BootstrapRequest
+	(*BootstrapToken)(nil),                     // 16: minexus.BootstrapToken
+	(*CommandStatusResponse)(nil),              // 17: minexus.CommandStatusResponse
+	(*MinionList)(nil),                         // 18: minexus.MinionList
+	(*CommandRequest)(nil),                     // 19: minexus.CommandRequest
+	(*RetryPolicy)(nil),                        // 20: minexus.RetryPolicy
+	(*ExplainTargetsRequest)(nil),              // 21: minexus.ExplainTargetsRequest
+	(*RuleExplanation)(nil),                    // 22: minexus.RuleExplanation
+	(*TargetExplanation)(nil),                  // 23: minexus.TargetExplanation
+	(*TargetExplanations)(nil),                 // 24: minexus.TargetExplanations
+	(*CommandDispatchResponse)(nil),            // 25: minexus.CommandDispatchResponse
+	(*BatchCommandRequest)(nil),                // 26: minexus.BatchCommandRequest
+	(*BatchCommandResponse)(nil),               // 27: minexus.BatchCommandResponse
+	(*ResultRequest)(nil),                      // 28: minexus.ResultRequest
+	(*CommandResults)(nil),                     // 29: minexus.CommandResults
+	(*MaintenanceWindow)(nil),                  // 30: minexus.MaintenanceWindow
+	(*CommandApproval)(nil),                    // 31: minexus.CommandApproval
+	(*CommandApprovalList)(nil),                // 32: minexus.CommandApprovalList
+	(*ApprovalDecision)(nil),                   // 33: minexus.ApprovalDecision
+	(*MaintenanceWindowList)(nil),              // 34: minexus.MaintenanceWindowList
+	(*MaintenanceWindowRequest)(nil),           // 35: minexus.MaintenanceWindowRequest
+	(*Report)(nil),                             // 36: minexus.Report
+	(*ReportList)(nil),                         // 37: minexus.ReportList
+	(*ReportRequest)(nil),                      // 38: minexus.ReportRequest
+	(*ReportRow)(nil),                          // 39: minexus.ReportRow
+	(*ReportResult)(nil),                       // 40: minexus.ReportResult
+	(*DatabaseCheckRequest)(nil),               // 41: minexus.DatabaseCheckRequest
+	(*DatabaseCheck)(nil),                      // 42: minexus.DatabaseCheck
+	(*DatabaseCheckReport)(nil),                // 43: minexus.DatabaseCheckReport
+	(*DatabaseQuery)(nil),                      // 44: minexus.DatabaseQuery
+	(*DatabaseQueryList)(nil),                  // 45: minexus.DatabaseQueryList
+	(*DatabaseQueryRequest)(nil),               // 46: minexus.DatabaseQueryRequest
+	(*DatabaseQueryResult)(nil),                // 47: minexus.DatabaseQueryResult
+	(*MinionHistoryRequest)(nil),               // 48: minexus.MinionHistoryRequest
+	(*MinionHistoryEntry)(nil),                 // 49: minexus.MinionHistoryEntry
+	(*MinionHistory)(nil),                      // 50: minexus.MinionHistory
+	(*MinionUptimeRequest)(nil),                // 51: minexus.MinionUptimeRequest
+	(*ConnectionPeriod)(nil),                   // 52: minexus.ConnectionPeriod
+	(*MinionUptime)(nil),                       // 53: minexus.MinionUptime
+	(*MinionLogEntry)(nil),                     // 54: minexus.MinionLogEntry
+	(*MinionLogBatch)(nil),                     // 55: minexus.MinionLogBatch
+	(*MinionLogsRequest)(nil),                  // 56: minexus.MinionLogsRequest
+	(*MinionLogs)(nil),                         // 57: minexus.MinionLogs
+	(*RetireMinionRequest)(nil),                // 58: minexus.RetireMinionRequest
+	(*RetireMinionResponse)(nil),               // 59: minexus.RetireMinionResponse
+	(*RetireNotice)(nil),                       // 60: minexus.RetireNotice
+	(*CrashReport)(nil),                        // 61: minexus.CrashReport
+	(*CrashListRequest)(nil),                   // 62: minexus.CrashListRequest
+	(*CrashList)(nil),                          // 63: minexus.CrashList
+	(*CommandEnvVar)(nil),                      // 64: minexus.CommandEnvVar
+	(*CommandEnvList)(nil),                     // 65: minexus.CommandEnvList
+	(*TraceRequest)(nil),                       // 66: minexus.TraceRequest
+	(*TraceEvent)(nil),                         // 67: minexus.TraceEvent
+	(*Trace)(nil),                              // 68: minexus.Trace
+	(*CommandStatsRequest)(nil),                // 69: minexus.CommandStatsRequest
+	(*MinionCommandStats)(nil),                 // 70: minexus.MinionCommandStats
+	(*ImpactCommandStats)(nil),                 // 71: minexus.ImpactCommandStats
+	(*CommandStats)(nil),                       // 72: minexus.CommandStats
+	(*ReceiptExportRequest)(nil),               // 73: minexus.ReceiptExportRequest
+	(*ExecutionReceipt)(nil),                   // 74: minexus.ExecutionReceipt
+	(*MinionDiagnosticsRequest)(nil),           // 75: minexus.MinionDiagnosticsRequest
+	(*ConnectionEvent)(nil),                    // 76: minexus.ConnectionEvent
+	(*MinionDiagnostics)(nil),                  // 77: minexus.MinionDiagnostics
+	(*SpeedTestSummary)(nil),                   // 78: minexus.SpeedTestSummary
+	(*FileChunk)(nil),                          // 79: minexus.FileChunk
+	(*FilePullRequest)(nil),                    // 80: minexus.FilePullRequest
+	(*FileTransferStatus)(nil),                 // 81: minexus.FileTransferStatus
+	(*FileTransferList)(nil),                   // 82: minexus.FileTransferList
+	(*FileDownloadRequest)(nil),                // 83: minexus.FileDownloadRequest
+	(*MinionHealth)(nil),                       // 84: minexus.MinionHealth
+	(*FleetHealthRequest)(nil),                 // 85: minexus.FleetHealthRequest
+	(*FleetHealth)(nil),                        // 86: minexus.FleetHealth
+	(*FleetVersions)(nil),                      // 87: minexus.FleetVersions
+	(*VersionCount)(nil),                       // 88: minexus.VersionCount
+	(*PatchComplianceRequest)(nil),             // 89: minexus.PatchComplianceRequest
+	(*PatchCompliance)(nil),                    // 90: minexus.PatchCompliance
+	(*PatchGroup)(nil),                         // 91: minexus.PatchGroup
+	(*FlushCachesResponse)(nil),                // 92: minexus.FlushCachesResponse
+	(*LogLevelRequest)(nil),                    // 93: minexus.LogLevelRequest
+	(*LogLevelResponse)(nil),                   // 94: minexus.LogLevelResponse
+	(*RegistryEntry)(nil),                      // 95: minexus.RegistryEntry
+	(*RegistryDump)(nil),                       // 96: minexus.RegistryDump
+	(*DisconnectMinionRequest)(nil),            // 97: minexus.DisconnectMinionRequest
+	(*PruneDatabaseResponse)(nil),              // 98: minexus.PruneDatabaseResponse
+	(*UnbindMinionRequest)(nil),                // 99: minexus.UnbindMinionRequest
+	(*CommandStatusUpdate)(nil),                // 100: minexus.CommandStatusUpdate
+	(*RegisterResponse)(nil),                   // 101: minexus.RegisterResponse
+	(*MinionInfo)(nil),                         // 102: minexus.MinionInfo
+	(*CommandStreamMessage)(nil),               // 103: minexus.CommandStreamMessage
+	(*ResultChunk)(nil),                        // 104: minexus.ResultChunk
+	(*ResultAck)(nil),                          // 105: minexus.ResultAck
+	(*ReconnectHint)(nil),                      // 106: minexus.ReconnectHint
+	(*ShellMessage)(nil),                       // 107: minexus.ShellMessage
+	(*SpeedTestProbe)(nil),                     // 108: minexus.SpeedTestProbe
+	(*RelayMessage)(nil),                       // 109: minexus.RelayMessage
+	nil,                                        // 110: minexus.HostInfo.TagsEntry
+	nil,                                        // 111: minexus.HostInfo.CommandVersionsEntry
+	nil,                                        // 112: minexus.Command.MetadataEntry
+	nil,                                        // 113: minexus.Command.EnvEntry
+	nil,                                        // 114: minexus.SetTagsRequest.TagsEntry
+	nil,                                        // 115: minexus.UpdateTagsRequest.AddEntry
+	(*TagSchema_Key)(nil),                      // 116: minexus.TagSchema.Key
+	(*CommandStatusResponse_MinionStatus)(nil), // 117: minexus.CommandStatusResponse.MinionStatus
+	nil,                                // 118: minexus.CommandStatusResponse.StatusCountsEntry
+	(*BatchCommandResponse_Entry)(nil), // 119: minexus.BatchCommandResponse.Entry
+	nil,                                // 120: minexus.ReportRequest.ParamsEntry
+	nil,                                // 121: minexus.DatabaseQueryRequest.ParamsEntry
 }
 var file_minexus_proto_depIdxs = []int32{
-	21, // 0: minexus.HostInfo.tags:type_name -> minexus.HostInfo.TagsEntry
-	0,  // 1: minexus.Command.type:type_name -> minexus.CommandType
-	22, // 2: minexus.Command.metadata:type_name -> minexus.Command.MetadataEntry
-	23, // 3: minexus.SetTagsRequest.tags:type_name -> minexus.SetTagsRequest.TagsEntry
-	24, // 4: minexus.UpdateTagsRequest.add:type_name -> minexus.UpdateTagsRequest.AddEntry
-	9,  // 5: minexus.TagSelector.rules:type_name -> minexus.TagMatch
-	25, // 6: minexus.CommandStatusResponse.statuses:type_name -> minexus.CommandStatusResponse.MinionStatus
-	26, // 7: minexus.CommandStatusResponse.status_counts:type_name -> minexus.CommandStatusResponse.StatusCountsEntry
-	1,  // 8: minexus.MinionList.minions:type_name -> minexus.HostInfo
-	10, // 9: minexus.CommandRequest.tag_selector:type_name -> minexus.TagSelector
-	2,  // 10: minexus.CommandRequest.command:type_name -> minexus.Command
-	3,  // 11: minexus.CommandResults.results:type_name -> minexus.CommandResult
-	2,  // 12: minexus.CommandStreamMessage.command:type_name -> minexus.Command
-	3,  // 13: minexus.CommandStreamMessage.result:type_name -> minexus.CommandResult
-	17, // 14: minexus.CommandStreamMessage.status:type_name -> minexus.CommandStatusUpdate
-	5,  // 15: minexus.ConsoleService.ListMinions:input_type -> minexus.Empty
-	5,  // 16: minexus.ConsoleService.ListTags:input_type -> minexus.Empty
-	6,  // 17: minexus.ConsoleService.SetTags:input_type -> minexus.SetTagsRequest
-	7,  // 18: minexus.ConsoleService.UpdateTags:input_type -> minexus.UpdateTagsRequest
-	13, // 19: minexus.ConsoleService.SendCommand:input_type -> minexus.CommandRequest
-	15, // 20: minexus.ConsoleService.GetCommandResults:input_type -> minexus.ResultRequest
-	15, // 21: minexus.ConsoleService.GetCommandStatus:input_type -> minexus.ResultRequest
-	1,  // 22: minexus.MinionService.Register:input_type -> minexus.HostInfo
-	20, // 23: minexus.MinionService.StreamCommands:input_type -> minexus.CommandStreamMessage
-	12, // 24: minexus.ConsoleService.ListMinions:output_type -> minexus.MinionList
-	8,  // 25: minexus.ConsoleService.ListTags:output_type -> minexus.TagList
-	4,  // 26: minexus.ConsoleService.SetTags:output_type -> minexus.Ack
-	4,  // 27: minexus.ConsoleService.UpdateTags:output_type -> minexus.Ack
-	14, // 28: minexus.ConsoleService.SendCommand:output_type -> minexus.CommandDispatchResponse
-	16, // 29: minexus.ConsoleService.GetCommandResults:output_type -> minexus.CommandResults
-	11, // 30: minexus.ConsoleService.GetCommandStatus:output_type -> minexus.CommandStatusResponse
-	18, // 31: minexus.MinionService.Register:output_type -> minexus.RegisterResponse
-	20, // 32: minexus.MinionService.StreamCommands:output_type -> minexus.CommandStreamMessage
-	24, // [24:33] is the sub-list for method output_type
-	15, // [15:24] is the sub-list for method input_type
-	15, // [15:15] is the sub-list for extension type_name
-	15, // [15:15] is the sub-list for extension extendee
-	0,  // [0:15] is the sub-list for field type_name
+	110, // 0: minexus.HostInfo.tags:type_name -> minexus.HostInfo.TagsEntry
+	111, // 1: minexus.HostInfo.command_versions:type_name -> minexus.HostInfo.CommandVersionsEntry
+	84,  // 2: minexus.HostInfo.health:type_name -> minexus.MinionHealth
+	61,  // 3: minexus.HostInfo.crashes:type_name -> minexus.CrashReport
+	0,   // 4: minexus.Command.type:type_name -> minexus.CommandType
+	112, // 5: minexus.Command.metadata:type_name -> minexus.Command.MetadataEntry
+	1,   // 6: minexus.Command.priority:type_name -> minexus.CommandPriority
+	113, // 7: minexus.Command.env:type_name -> minexus.Command.EnvEntry
+	5,   // 8: minexus.CommandResult.execution:type_name -> minexus.ExecutionInfo
+	114, // 9: minexus.SetTagsRequest.tags:type_name -> minexus.SetTagsRequest.TagsEntry
+	115, // 10: minexus.UpdateTagsRequest.add:type_name -> minexus.UpdateTagsRequest.AddEntry
+	11,  // 11: minexus.TagSelector.rules:type_name -> minexus.TagMatch
+	116, // 12: minexus.TagSchema.keys:type_name -> minexus.TagSchema.Key
+	117, // 13: minexus.CommandStatusResponse.statuses:type_name -> minexus.CommandStatusResponse.MinionStatus
+	118, // 14: minexus.CommandStatusResponse.status_counts:type_name -> minexus.CommandStatusResponse.StatusCountsEntry
+	2,   // 15: minexus.MinionList.minions:type_name -> minexus.HostInfo
+	13,  // 16: minexus.CommandRequest.tag_selector:type_name -> minexus.TagSelector
+	3,   // 17: minexus.CommandRequest.command:type_name -> minexus.Command
+	1,   // 18: minexus.CommandRequest.priority:type_name -> minexus.CommandPriority
+	12,  // 19: minexus.CommandRequest.topology:type_name -> minexus.TopologySelector
+	20,  // 20: minexus.CommandRequest.retry:type_name -> minexus.RetryPolicy
+	13,  // 21: minexus.ExplainTargetsRequest.tag_selector:type_name -> minexus.TagSelector
+	12,  // 22: minexus.ExplainTargetsRequest.topology:type_name -> minexus.TopologySelector
+	22,  // 23: minexus.TargetExplanation.rules:type_name -> minexus.RuleExplanation
+	23,  // 24: minexus.TargetExplanations.minions:type_name -> minexus.TargetExplanation
+	19,  // 25: minexus.BatchCommandRequest.requests:type_name -> minexus.CommandRequest
+	119, // 26: minexus.BatchCommandResponse.entries:type_name -> minexus.BatchCommandResponse.Entry
+	4,   // 27: minexus.CommandResults.results:type_name -> minexus.CommandResult
+	13,  // 28: minexus.MaintenanceWindow.tag_selector:type_name -> minexus.TagSelector
+	31,  // 29: minexus.CommandApprovalList.approvals:type_name -> minexus.CommandApproval
+	30,  // 30: minexus.MaintenanceWindowList.windows:type_name -> minexus.MaintenanceWindow
+	36,  // 31: minexus.ReportList.reports:type_name -> minexus.Report
+	120, // 32: minexus.ReportRequest.params:type_name -> minexus.ReportRequest.ParamsEntry
+	39,  // 33: minexus.ReportResult.rows:type_name -> minexus.ReportRow
+	42,  // 34: minexus.DatabaseCheckReport.checks:type_name -> minexus.DatabaseCheck
+	44,  // 35: minexus.DatabaseQueryList.queries:type_name -> minexus.DatabaseQuery
+	121, // 36: minexus.DatabaseQueryRequest.params:type_name -> minexus.DatabaseQueryRequest.ParamsEntry
+	39,  // 37: minexus.DatabaseQueryResult.rows:type_name -> minexus.ReportRow
+	49,  // 38: minexus.MinionHistory.entries:type_name -> minexus.MinionHistoryEntry
+	52,  // 39: minexus.MinionUptime.periods:type_name -> minexus.ConnectionPeriod
+	54,  // 40: minexus.MinionLogBatch.entries:type_name -> minexus.MinionLogEntry
+	54,  // 41: minexus.MinionLogs.entries:type_name -> minexus.MinionLogEntry
+	61,  // 42: minexus.CrashList.crashes:type_name -> minexus.CrashReport
+	64,  // 43: minexus.CommandEnvList.vars:type_name -> minexus.CommandEnvVar
+	67,  // 44: minexus.Trace.events:type_name -> minexus.TraceEvent
+	70,  // 45: minexus.CommandStats.slowest_minions:type_name -> minexus.MinionCommandStats
+	71,  // 46: minexus.CommandStats.by_impact:type_name -> minexus.ImpactCommandStats
+	76,  // 47: minexus.MinionDiagnostics.events:type_name -> minexus.ConnectionEvent
+	78,  // 48: minexus.MinionDiagnostics.last_speed_test:type_name -> minexus.SpeedTestSummary
+	81,  // 49: minexus.FileTransferList.transfers:type_name -> minexus.FileTransferStatus
+	2,   // 50: minexus.FleetHealth.minions:type_name -> minexus.HostInfo
+	88,  // 51: minexus.FleetVersions.versions:type_name -> minexus.VersionCount
+	2,   // 52: minexus.FleetVersions.outdated:type_name -> minexus.HostInfo
+	91,  // 53: minexus.PatchCompliance.total:type_name -> minexus.PatchGroup
+	91,  // 54: minexus.PatchCompliance.groups:type_name -> minexus.PatchGroup
+	95,  // 55: minexus.RegistryDump.minions:type_name -> minexus.RegistryEntry
+	60,  // 56: minexus.RegisterResponse.retire:type_name -> minexus.RetireNotice
+	3,   // 57: minexus.CommandStreamMessage.command:type_name -> minexus.Command
+	4,   // 58: minexus.CommandStreamMessage.result:type_name -> minexus.CommandResult
+	100, // 59: minexus.CommandStreamMessage.status:type_name -> minexus.CommandStatusUpdate
+	107, // 60: minexus.CommandStreamMessage.shell:type_name -> minexus.ShellMessage
+	106, // 61: minexus.CommandStreamMessage.reconnect:type_name -> minexus.ReconnectHint
+	55,  // 62: minexus.CommandStreamMessage.logs:type_name -> minexus.MinionLogBatch
+	105, // 63: minexus.CommandStreamMessage.ack:type_name -> minexus.ResultAck
+	79,  // 64: minexus.CommandStreamMessage.file:type_name -> minexus.FileChunk
+	104, // 65: minexus.CommandStreamMessage.result_chunk:type_name -> minexus.ResultChunk
+	60,  // 66: minexus.CommandStreamMessage.retire:type_name -> minexus.RetireNotice
+	2,   // 67: minexus.RelayMessage.register:type_name -> minexus.HostInfo
+	101, // 68: minexus.RelayMessage.registered:type_name -> minexus.RegisterResponse
+	103, // 69: minexus.RelayMessage.stream:type_name -> minexus.CommandStreamMessage
+	25,  // 70: minexus.BatchCommandResponse.Entry.response:type_name -> minexus.CommandDispatchResponse
+	7,   // 71: minexus.ConsoleService.ListMinions:input_type -> minexus.Empty
+	7,   // 72: minexus.ConsoleService.ListTags:input_type -> minexus.Empty
+	8,   // 73: minexus.ConsoleService.SetTags:input_type -> minexus.SetTagsRequest
+	9,   // 74: minexus.ConsoleService.UpdateTags:input_type -> minexus.UpdateTagsRequest
+	7,   // 75: minexus.ConsoleService.GetTagSchema:input_type -> minexus.Empty
+	15,  // 76: minexus.ConsoleService.CreateBootstrapToken:input_type -> minexus.BootstrapRequest
+	19,  // 77: minexus.ConsoleService.SendCommand:input_type -> minexus.CommandRequest
+	21,  // 78: minexus.ConsoleService.ExplainTargets:input_type -> minexus.ExplainTargetsRequest
+	26,  // 79: minexus.ConsoleService.BatchSendCommand:input_type -> minexus.BatchCommandRequest
+	28,  // 80: minexus.ConsoleService.GetCommandResults:input_type -> minexus.ResultRequest
+	28,  // 81: minexus.ConsoleService.GetCommandStatus:input_type -> minexus.ResultRequest
+	7,   // 82: minexus.ConsoleService.ListCommandApprovals:input_type -> minexus.Empty
+	33,  // 83: minexus.ConsoleService.DecideCommandApproval:input_type -> minexus.ApprovalDecision
+	30,  // 84: minexus.ConsoleService.AddMaintenanceWindow:input_type -> minexus.MaintenanceWindow
+	7,   // 85: minexus.ConsoleService.ListMaintenanceWindows:input_type -> minexus.Empty
+	35,  // 86: minexus.ConsoleService.RemoveMaintenanceWindow:input_type -> minexus.MaintenanceWindowRequest
+	64,  // 87: minexus.ConsoleService.SetCommandEnv:input_type -> minexus.CommandEnvVar
+	64,  // 88: minexus.ConsoleService.UnsetCommandEnv:input_type -> minexus.CommandEnvVar
+	7,   // 89: minexus.ConsoleService.ListCommandEnv:input_type -> minexus.Empty
+	75,  // 90: minexus.ConsoleService.GetMinionDiagnostics:input_type -> minexus.MinionDiagnosticsRequest
+	85,  // 91: minexus.ConsoleService.GetFleetHealth:input_type -> minexus.FleetHealthRequest
+	7,   // 92: minexus.ConsoleService.GetFleetVersions:input_type -> minexus.Empty
+	89,  // 93: minexus.ConsoleService.GetPatchCompliance:input_type -> minexus.PatchComplianceRequest
+	48,  // 94: minexus.ConsoleService.GetMinionHistory:input_type -> minexus.MinionHistoryRequest
+	51,  // 95: minexus.ConsoleService.GetMinionUptime:input_type -> minexus.MinionUptimeRequest
+	56,  // 96: minexus.ConsoleService.GetMinionLogs:input_type -> minexus.MinionLogsRequest
+	58,  // 97: minexus.ConsoleService.RetireMinion:input_type -> minexus.RetireMinionRequest
+	62,  // 98: minexus.ConsoleService.ListCrashes:input_type -> minexus.CrashListRequest
+	66,  // 99: minexus.ConsoleService.GetTrace:input_type -> minexus.TraceRequest
+	69,  // 100: minexus.ConsoleService.GetCommandStats:input_type -> minexus.CommandStatsRequest
+	73,  // 101: minexus.ConsoleService.ExportReceipts:input_type -> minexus.ReceiptExportRequest
+	36,  // 102: minexus.ConsoleService.CreateReport:input_type -> minexus.Report
+	7,   // 103: minexus.ConsoleService.ListReports:input_type -> minexus.Empty
+	38,  // 104: minexus.ConsoleService.RunReport:input_type -> minexus.ReportRequest
+	41,  // 105: minexus.ConsoleService.CheckDatabase:input_type -> minexus.DatabaseCheckRequest
+	7,   // 106: minexus.ConsoleService.ListDatabaseQueries:input_type -> minexus.Empty
+	46,  // 107: minexus.ConsoleService.RunDatabaseQuery:input_type -> minexus.DatabaseQueryRequest
+	107, // 108: minexus.ConsoleService.OpenShell:input_type -> minexus.ShellMessage
+	80,  // 109: minexus.ConsoleService.PullFile:input_type -> minexus.FilePullRequest
+	7,   // 110: minexus.ConsoleService.ListFileTransfers:input_type -> minexus.Empty
+	83,  // 111: minexus.ConsoleService.DownloadFile:input_type -> minexus.FileDownloadRequest
+	7,   // 112: minexus.AdminService.FlushCaches:input_type -> minexus.Empty
+	93,  // 113: minexus.AdminService.SetLogLevel:input_type -> minexus.LogLevelRequest
+	7,   // 114: minexus.AdminService.DumpRegistry:input_type -> minexus.Empty
+	97,  // 115: minexus.AdminService.DisconnectMinion:input_type -> minexus.DisconnectMinionRequest
+	7,   // 116: minexus.AdminService.PruneDatabase:input_type -> minexus.Empty
+	99,  // 117: minexus.AdminService.UnbindMinion:input_type -> minexus.UnbindMinionRequest
+	2,   // 118: minexus.MinionService.Register:input_type -> minexus.HostInfo
+	103, // 119: minexus.MinionService.StreamCommands:input_type -> minexus.CommandStreamMessage
+	109, // 120: minexus.MinionService.RelayStream:input_type -> minexus.RelayMessage
+	108, // 121: minexus.MinionService.SpeedTest:input_type -> minexus.SpeedTestProbe
+	18,  // 122: minexus.ConsoleService.ListMinions:output_type -> minexus.MinionList
+	10,  // 123: minexus.ConsoleService.ListTags:output_type -> minexus.TagList
+	6,   // 124: minexus.ConsoleService.SetTags:output_type -> minexus.Ack
+	6,   // 125: minexus.ConsoleService.UpdateTags:output_type -> minexus.Ack
+	14,  // 126: minexus.ConsoleService.GetTagSchema:output_type -> minexus.TagSchema
+	16,  // 127: minexus.ConsoleService.CreateBootstrapToken:output_type -> minexus.BootstrapToken
+	25,  // 128: minexus.ConsoleService.SendCommand:output_type -> minexus.CommandDispatchResponse
+	24,  // 129: minexus.ConsoleService.ExplainTargets:output_type -> minexus.TargetExplanations
+	27,  // 130: minexus.ConsoleService.BatchSendCommand:output_type -> minexus.BatchCommandResponse
+	29,  // 131: minexus.ConsoleService.GetCommandResults:output_type -> minexus.CommandResults
+	17,  // 132: minexus.ConsoleService.GetCommandStatus:output_type -> minexus.CommandStatusResponse
+	32,  // 133: minexus.ConsoleService.ListCommandApprovals:output_type -> minexus.CommandApprovalList
+	25,  // 134: minexus.ConsoleService.DecideCommandApproval:output_type -> minexus.CommandDispatchResponse
+	30,  // 135: minexus.ConsoleService.AddMaintenanceWindow:output_type -> minexus.MaintenanceWindow
+	34,  // 136: minexus.ConsoleService.ListMaintenanceWindows:output_type -> minexus.MaintenanceWindowList
+	6,   // 137: minexus.ConsoleService.RemoveMaintenanceWindow:output_type -> minexus.Ack
+	6,   // 138: minexus.ConsoleService.SetCommandEnv:output_type -> minexus.Ack
+	6,   // 139: minexus.ConsoleService.UnsetCommandEnv:output_type -> minexus.Ack
+	65,  // 140: minexus.ConsoleService.ListCommandEnv:output_type -> minexus.CommandEnvList
+	77,  // 141: minexus.ConsoleService.GetMinionDiagnostics:output_type -> minexus.MinionDiagnostics
+	86,  // 142: minexus.ConsoleService.GetFleetHealth:output_type -> minexus.FleetHealth
+	87,  // 143: minexus.ConsoleService.GetFleetVersions:output_type -> minexus.FleetVersions
+	90,  // 144: minexus.ConsoleService.GetPatchCompliance:output_type -> minexus.PatchCompliance
+	50,  // 145: minexus.ConsoleService.GetMinionHistory:output_type -> minexus.MinionHistory
+	53,  // 146: minexus.ConsoleService.GetMinionUptime:output_type -> minexus.MinionUptime
+	57,  // 147: minexus.ConsoleService.GetMinionLogs:output_type -> minexus.MinionLogs
+	59,  // 148: minexus.ConsoleService.RetireMinion:output_type -> minexus.RetireMinionResponse
+	63,  // 149: minexus.ConsoleService.ListCrashes:output_type -> minexus.CrashList
+	68,  // 150: minexus.ConsoleService.GetTrace:output_type -> minexus.Trace
+	72,  // 151: minexus.ConsoleService.GetCommandStats:output_type -> minexus.CommandStats
+	74,  // 152: minexus.ConsoleService.ExportReceipts:output_type -> minexus.ExecutionReceipt
+	36,  // 153: minexus.ConsoleService.CreateReport:output_type -> minexus.Report
+	37,  // 154: minexus.ConsoleService.ListReports:output_type -> minexus.ReportList
+	40,  // 155: minexus.ConsoleService.RunReport:output_type -> minexus.ReportResult
+	43,  // 156: minexus.ConsoleService.CheckDatabase:output_type -> minexus.DatabaseCheckReport
+	45,  // 157: minexus.ConsoleService.ListDatabaseQueries:output_type -> minexus.DatabaseQueryList
+	47,  // 158: minexus.ConsoleService.RunDatabaseQuery:output_type -> minexus.DatabaseQueryResult
+	107, // 159: minexus.ConsoleService.OpenShell:output_type -> minexus.ShellMessage
+	81,  // 160: minexus.ConsoleService.PullFile:output_type -> minexus.FileTransferStatus
+	82,  // 161: minexus.ConsoleService.ListFileTransfers:output_type -> minexus.FileTransferList
+	79,  // 162: minexus.ConsoleService.DownloadFile:output_type -> minexus.FileChunk
+	92,  // 163: minexus.AdminService.FlushCaches:output_type -> minexus.FlushCachesResponse
+	94,  // 164: minexus.AdminService.SetLogLevel:output_type -> minexus.LogLevelResponse
+	96,  // 165: minexus.AdminService.DumpRegistry:output_type -> minexus.RegistryDump
+	6,   // 166: minexus.AdminService.DisconnectMinion:output_type -> minexus.Ack
+	98,  // 167: minexus.AdminService.PruneDatabase:output_type -> minexus.PruneDatabaseResponse
+	6,   // 168: minexus.AdminService.UnbindMinion:output_type -> minexus.Ack
+	101, // 169: minexus.MinionService.Register:output_type -> minexus.RegisterResponse
+	103, // 170: minexus.MinionService.StreamCommands:output_type -> minexus.CommandStreamMessage
+	109, // 171: minexus.MinionService.RelayStream:output_type -> minexus.RelayMessage
+	108, // 172: minexus.MinionService.SpeedTest:output_type -> minexus.SpeedTestProbe
+	122, // [122:173] is the sub-list for method output_type
+	71,  // [71:122] is the sub-list for method input_type
+	71,  // [71:71] is the sub-list for extension type_name
+	71,  // [71:71] is the sub-list for extension extendee
+	0,   // [0:71] is the sub-list for field type_name
 }
 
 func init() { file_minexus_proto_init() }
@@ -1537,25 +9256,39 @@ func file_minexus_proto_init() {
 	if File_minexus_proto != nil {
 		return
 	}
-	file_minexus_proto_msgTypes[8].OneofWrappers = []any{
+	file_minexus_proto_msgTypes[9].OneofWrappers = []any{
 		(*TagMatch_Equals)(nil),
 		(*TagMatch_Exists)(nil),
 		(*TagMatch_NotExists)(nil),
 	}
-	file_minexus_proto_msgTypes[19].OneofWrappers = []any{
+	file_minexus_proto_msgTypes[101].OneofWrappers = []any{
 		(*CommandStreamMessage_Command)(nil),
 		(*CommandStreamMessage_Result)(nil),
 		(*CommandStreamMessage_Status)(nil),
+		(*CommandStreamMessage_Shell)(nil),
+		(*CommandStreamMessage_Reconnect)(nil),
+		(*CommandStreamMessage_Logs)(nil),
+		(*CommandStreamMessage_Ack)(nil),
+		(*CommandStreamMessage_File)(nil),
+		(*CommandStreamMessage_ResultChunk)(nil),
+		(*CommandStreamMessage_Retire)(nil),
+	}
+	file_minexus_proto_msgTypes[107].OneofWrappers = []any{
+		(*RelayMessage_Register)(nil),
+		(*RelayMessage_Registered)(nil),
+		(*RelayMessage_Connected)(nil),
+		(*RelayMessage_Disconnected)(nil),
+		(*RelayMessage_Stream)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_minexus_proto_rawDesc), len(file_minexus_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   26,
+			NumEnums:      2,
+			NumMessages:   120,
 			NumExtensions: 0,
-			NumServices:   2,
+			NumServices:   3,
 		},
 		GoTypes:           file_minexus_proto_goTypes,
 		DependencyIndexes: file_minexus_proto_depIdxs,
@@ -19,13 +19,47 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ConsoleService_ListMinions_FullMethodName       = "/minexus.ConsoleService/ListMinions"
-	ConsoleService_ListTags_FullMethodName          = "/minexus.ConsoleService/ListTags"
-	ConsoleService_SetTags_FullMethodName           = "/minexus.ConsoleService/SetTags"
-	ConsoleService_UpdateTags_FullMethodName        = "/minexus.ConsoleService/UpdateTags"
-	ConsoleService_SendCommand_FullMethodName       = "/minexus.ConsoleService/SendCommand"
-	ConsoleService_GetCommandResults_FullMethodName = "/minexus.ConsoleService/GetCommandResults"
-	ConsoleService_GetCommandStatus_FullMethodName  = "/minexus.ConsoleService/GetCommandStatus"
+	ConsoleService_ListMinions_FullMethodName             = "/minexus.ConsoleService/ListMinions"
+	ConsoleService_ListTags_FullMethodName                = "/minexus.ConsoleService/ListTags"
+	ConsoleService_SetTags_FullMethodName                 = "/minexus.ConsoleService/SetTags"
+	ConsoleService_UpdateTags_FullMethodName              = "/minexus.ConsoleService/UpdateTags"
+	ConsoleService_GetTagSchema_FullMethodName            = "/minexus.ConsoleService/GetTagSchema"
+	ConsoleService_CreateBootstrapToken_FullMethodName    = "/minexus.ConsoleService/CreateBootstrapToken"
+	ConsoleService_SendCommand_FullMethodName             = "/minexus.ConsoleService/SendCommand"
+	ConsoleService_ExplainTargets_FullMethodName          = "/minexus.ConsoleService/ExplainTargets"
+	ConsoleService_BatchSendCommand_FullMethodName        = "/minexus.ConsoleService/BatchSendCommand"
+	ConsoleService_GetCommandResults_FullMethodName       = "/minexus.ConsoleService/GetCommandResults"
+	ConsoleService_GetCommandStatus_FullMethodName        = "/minexus.ConsoleService/GetCommandStatus"
+	ConsoleService_ListCommandApprovals_FullMethodName    = "/minexus.ConsoleService/ListCommandApprovals"
+	ConsoleService_DecideCommandApproval_FullMethodName   = "/minexus.ConsoleService/DecideCommandApproval"
+	ConsoleService_AddMaintenanceWindow_FullMethodName    = "/minexus.ConsoleService/AddMaintenanceWindow"
+	ConsoleService_ListMaintenanceWindows_FullMethodName  = "/minexus.ConsoleService/ListMaintenanceWindows"
+	ConsoleService_RemoveMaintenanceWindow_FullMethodName = "/minexus.ConsoleService/RemoveMaintenanceWindow"
+	ConsoleService_SetCommandEnv_FullMethodName           = "/minexus.ConsoleService/SetCommandEnv"
+	ConsoleService_UnsetCommandEnv_FullMethodName         = "/minexus.ConsoleService/UnsetCommandEnv"
+	ConsoleService_ListCommandEnv_FullMethodName          = "/minexus.ConsoleService/ListCommandEnv"
+	ConsoleService_GetMinionDiagnostics_FullMethodName    = "/minexus.ConsoleService/GetMinionDiagnostics"
+	ConsoleService_GetFleetHealth_FullMethodName          = "/minexus.ConsoleService/GetFleetHealth"
+	ConsoleService_GetFleetVersions_FullMethodName        = "/minexus.ConsoleService/GetFleetVersions"
+	ConsoleService_GetPatchCompliance_FullMethodName      = "/minexus.ConsoleService/GetPatchCompliance"
+	ConsoleService_GetMinionHistory_FullMethodName        = "/minexus.ConsoleService/GetMinionHistory"
+	ConsoleService_GetMinionUptime_FullMethodName         = "/minexus.ConsoleService/GetMinionUptime"
+	ConsoleService_GetMinionLogs_FullMethodName           = "/minexus.ConsoleService/GetMinionLogs"
+	ConsoleService_RetireMinion_FullMethodName            = "/minexus.ConsoleService/RetireMinion"
+	ConsoleService_ListCrashes_FullMethodName             = "/minexus.ConsoleService/ListCrashes"
+	ConsoleService_GetTrace_FullMethodName                = "/minexus.ConsoleService/GetTrace"
+	ConsoleService_GetCommandStats_FullMethodName         = "/minexus.ConsoleService/GetCommandStats"
+	ConsoleService_ExportReceipts_FullMethodName          = "/minexus.ConsoleService/ExportReceipts"
+	ConsoleService_CreateReport_FullMethodName            = "/minexus.ConsoleService/CreateReport"
+	ConsoleService_ListReports_FullMethodName             = "/minexus.ConsoleService/ListReports"
+	ConsoleService_RunReport_FullMethodName               = "/minexus.ConsoleService/RunReport"
+	ConsoleService_CheckDatabase_FullMethodName           = "/minexus.ConsoleService/CheckDatabase"
+	ConsoleService_ListDatabaseQueries_FullMethodName     = "/minexus.ConsoleService/ListDatabaseQueries"
+	ConsoleService_RunDatabaseQuery_FullMethodName        = "/minexus.ConsoleService/RunDatabaseQuery"
+	ConsoleService_OpenShell_FullMethodName               = "/minexus.ConsoleService/OpenShell"
+	ConsoleService_PullFile_FullMethodName                = "/minexus.ConsoleService/PullFile"
+	ConsoleService_ListFileTransfers_FullMethodName       = "/minexus.ConsoleService/ListFileTransfers"
+	ConsoleService_DownloadFile_FullMethodName            = "/minexus.ConsoleService/DownloadFile"
 )
 
 // ConsoleServiceClient is the client API for ConsoleService service.
@@ -36,9 +70,47 @@ type ConsoleServiceClient interface {
 	ListTags(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TagList, error)
 	SetTags(ctx context.Context, in *SetTagsRequest, opts ...grpc.CallOption) (*Ack, error)
 	UpdateTags(ctx context.Context, in *UpdateTagsRequest, opts ...grpc.CallOption) (*Ack, error)
+	GetTagSchema(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TagSchema, error)
+	CreateBootstrapToken(ctx context.Context, in *BootstrapRequest, opts ...grpc.CallOption) (*BootstrapToken, error)
 	SendCommand(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandDispatchResponse, error)
+	ExplainTargets(ctx context.Context, in *ExplainTargetsRequest, opts ...grpc.CallOption) (*TargetExplanations, error)
+	BatchSendCommand(ctx context.Context, in *BatchCommandRequest, opts ...grpc.CallOption) (*BatchCommandResponse, error)
 	GetCommandResults(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*CommandResults, error)
 	GetCommandStatus(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*CommandStatusResponse, error)
+	ListCommandApprovals(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CommandApprovalList, error)
+	DecideCommandApproval(ctx context.Context, in *ApprovalDecision, opts ...grpc.CallOption) (*CommandDispatchResponse, error)
+	AddMaintenanceWindow(ctx context.Context, in *MaintenanceWindow, opts ...grpc.CallOption) (*MaintenanceWindow, error)
+	ListMaintenanceWindows(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MaintenanceWindowList, error)
+	RemoveMaintenanceWindow(ctx context.Context, in *MaintenanceWindowRequest, opts ...grpc.CallOption) (*Ack, error)
+	SetCommandEnv(ctx context.Context, in *CommandEnvVar, opts ...grpc.CallOption) (*Ack, error)
+	UnsetCommandEnv(ctx context.Context, in *CommandEnvVar, opts ...grpc.CallOption) (*Ack, error)
+	ListCommandEnv(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CommandEnvList, error)
+	GetMinionDiagnostics(ctx context.Context, in *MinionDiagnosticsRequest, opts ...grpc.CallOption) (*MinionDiagnostics, error)
+	GetFleetHealth(ctx context.Context, in *FleetHealthRequest, opts ...grpc.CallOption) (*FleetHealth, error)
+	GetFleetVersions(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FleetVersions, error)
+	GetPatchCompliance(ctx context.Context, in *PatchComplianceRequest, opts ...grpc.CallOption) (*PatchCompliance, error)
+	GetMinionHistory(ctx context.Context, in *MinionHistoryRequest, opts ...grpc.CallOption) (*MinionHistory, error)
+	GetMinionUptime(ctx context.Context, in *MinionUptimeRequest, opts ...grpc.CallOption) (*MinionUptime, error)
+	GetMinionLogs(ctx context.Context, in *MinionLogsRequest, opts ...grpc.CallOption) (*MinionLogs, error)
+	RetireMinion(ctx context.Context, in *RetireMinionRequest, opts ...grpc.CallOption) (*RetireMinionResponse, error)
+	ListCrashes(ctx context.Context, in *CrashListRequest, opts ...grpc.CallOption) (*CrashList, error)
+	GetTrace(ctx context.Context, in *TraceRequest, opts ...grpc.CallOption) (*Trace, error)
+	GetCommandStats(ctx context.Context, in *CommandStatsRequest, opts ...grpc.CallOption) (*CommandStats, error)
+	// ExportReceipts streams the signed execution receipts of the commands sent in a time range
+	ExportReceipts(ctx context.Context, in *ReceiptExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecutionReceipt], error)
+	CreateReport(ctx context.Context, in *Report, opts ...grpc.CallOption) (*Report, error)
+	ListReports(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ReportList, error)
+	RunReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*ReportResult, error)
+	CheckDatabase(ctx context.Context, in *DatabaseCheckRequest, opts ...grpc.CallOption) (*DatabaseCheckReport, error)
+	ListDatabaseQueries(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DatabaseQueryList, error)
+	RunDatabaseQuery(ctx context.Context, in *DatabaseQueryRequest, opts ...grpc.CallOption) (*DatabaseQueryResult, error)
+	// OpenShell attaches the console to an interactive shell running on a minion
+	OpenShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ShellMessage, ShellMessage], error)
+	// PullFile starts or resumes pulling a file from a minion to Nexus, streaming its progress
+	PullFile(ctx context.Context, in *FilePullRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileTransferStatus], error)
+	ListFileTransfers(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FileTransferList, error)
+	// DownloadFile streams a file pulled to Nexus, from an offset to resume a download
+	DownloadFile(ctx context.Context, in *FileDownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error)
 }
 
 type consoleServiceClient struct {
@@ -89,259 +161,1789 @@ func (c *consoleServiceClient) UpdateTags(ctx context.Context, in *UpdateTagsReq
 	return out, nil
 }
 
+func (c *consoleServiceClient) GetTagSchema(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*TagSchema, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TagSchema)
+	err := c.cc.Invoke(ctx, ConsoleService_GetTagSchema_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) CreateBootstrapToken(ctx context.Context, in *BootstrapRequest, opts ...grpc.CallOption) (*BootstrapToken, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BootstrapToken)
+	err := c.cc.Invoke(ctx, ConsoleService_CreateBootstrapToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *consoleServiceClient) SendCommand(ctx context.Context, in *CommandRequest, opts ...grpc.CallOption) (*CommandDispatchResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(CommandDispatchResponse)
-	err := c.cc.Invoke(ctx, ConsoleService_SendCommand_FullMethodName, in, out, cOpts...)
+	out := new(CommandDispatchResponse)
+	err := c.cc.Invoke(ctx, ConsoleService_SendCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ExplainTargets(ctx context.Context, in *ExplainTargetsRequest, opts ...grpc.CallOption) (*TargetExplanations, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TargetExplanations)
+	err := c.cc.Invoke(ctx, ConsoleService_ExplainTargets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) BatchSendCommand(ctx context.Context, in *BatchCommandRequest, opts ...grpc.CallOption) (*BatchCommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchCommandResponse)
+	err := c.cc.Invoke(ctx, ConsoleService_BatchSendCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetCommandResults(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*CommandResults, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandResults)
+	err := c.cc.Invoke(ctx, ConsoleService_GetCommandResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetCommandStatus(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*CommandStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandStatusResponse)
+	err := c.cc.Invoke(ctx, ConsoleService_GetCommandStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ListCommandApprovals(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CommandApprovalList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandApprovalList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListCommandApprovals_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) DecideCommandApproval(ctx context.Context, in *ApprovalDecision, opts ...grpc.CallOption) (*CommandDispatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandDispatchResponse)
+	err := c.cc.Invoke(ctx, ConsoleService_DecideCommandApproval_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) AddMaintenanceWindow(ctx context.Context, in *MaintenanceWindow, opts ...grpc.CallOption) (*MaintenanceWindow, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MaintenanceWindow)
+	err := c.cc.Invoke(ctx, ConsoleService_AddMaintenanceWindow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ListMaintenanceWindows(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*MaintenanceWindowList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MaintenanceWindowList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListMaintenanceWindows_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) RemoveMaintenanceWindow(ctx context.Context, in *MaintenanceWindowRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, ConsoleService_RemoveMaintenanceWindow_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) SetCommandEnv(ctx context.Context, in *CommandEnvVar, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, ConsoleService_SetCommandEnv_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) UnsetCommandEnv(ctx context.Context, in *CommandEnvVar, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, ConsoleService_UnsetCommandEnv_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ListCommandEnv(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CommandEnvList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandEnvList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListCommandEnv_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetMinionDiagnostics(ctx context.Context, in *MinionDiagnosticsRequest, opts ...grpc.CallOption) (*MinionDiagnostics, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MinionDiagnostics)
+	err := c.cc.Invoke(ctx, ConsoleService_GetMinionDiagnostics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetFleetHealth(ctx context.Context, in *FleetHealthRequest, opts ...grpc.CallOption) (*FleetHealth, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FleetHealth)
+	err := c.cc.Invoke(ctx, ConsoleService_GetFleetHealth_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetFleetVersions(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FleetVersions, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FleetVersions)
+	err := c.cc.Invoke(ctx, ConsoleService_GetFleetVersions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetPatchCompliance(ctx context.Context, in *PatchComplianceRequest, opts ...grpc.CallOption) (*PatchCompliance, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PatchCompliance)
+	err := c.cc.Invoke(ctx, ConsoleService_GetPatchCompliance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetMinionHistory(ctx context.Context, in *MinionHistoryRequest, opts ...grpc.CallOption) (*MinionHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MinionHistory)
+	err := c.cc.Invoke(ctx, ConsoleService_GetMinionHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetMinionUptime(ctx context.Context, in *MinionUptimeRequest, opts ...grpc.CallOption) (*MinionUptime, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MinionUptime)
+	err := c.cc.Invoke(ctx, ConsoleService_GetMinionUptime_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetMinionLogs(ctx context.Context, in *MinionLogsRequest, opts ...grpc.CallOption) (*MinionLogs, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MinionLogs)
+	err := c.cc.Invoke(ctx, ConsoleService_GetMinionLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) RetireMinion(ctx context.Context, in *RetireMinionRequest, opts ...grpc.CallOption) (*RetireMinionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RetireMinionResponse)
+	err := c.cc.Invoke(ctx, ConsoleService_RetireMinion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ListCrashes(ctx context.Context, in *CrashListRequest, opts ...grpc.CallOption) (*CrashList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CrashList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListCrashes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetTrace(ctx context.Context, in *TraceRequest, opts ...grpc.CallOption) (*Trace, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Trace)
+	err := c.cc.Invoke(ctx, ConsoleService_GetTrace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) GetCommandStats(ctx context.Context, in *CommandStatsRequest, opts ...grpc.CallOption) (*CommandStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CommandStats)
+	err := c.cc.Invoke(ctx, ConsoleService_GetCommandStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ExportReceipts(ctx context.Context, in *ReceiptExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecutionReceipt], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConsoleService_ServiceDesc.Streams[0], ConsoleService_ExportReceipts_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReceiptExportRequest, ExecutionReceipt]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_ExportReceiptsClient = grpc.ServerStreamingClient[ExecutionReceipt]
+
+func (c *consoleServiceClient) CreateReport(ctx context.Context, in *Report, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, ConsoleService_CreateReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ListReports(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ReportList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListReports_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) RunReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*ReportResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResult)
+	err := c.cc.Invoke(ctx, ConsoleService_RunReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) CheckDatabase(ctx context.Context, in *DatabaseCheckRequest, opts ...grpc.CallOption) (*DatabaseCheckReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DatabaseCheckReport)
+	err := c.cc.Invoke(ctx, ConsoleService_CheckDatabase_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) ListDatabaseQueries(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DatabaseQueryList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DatabaseQueryList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListDatabaseQueries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) RunDatabaseQuery(ctx context.Context, in *DatabaseQueryRequest, opts ...grpc.CallOption) (*DatabaseQueryResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DatabaseQueryResult)
+	err := c.cc.Invoke(ctx, ConsoleService_RunDatabaseQuery_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) OpenShell(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ShellMessage, ShellMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConsoleService_ServiceDesc.Streams[1], ConsoleService_OpenShell_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ShellMessage, ShellMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_OpenShellClient = grpc.BidiStreamingClient[ShellMessage, ShellMessage]
+
+func (c *consoleServiceClient) PullFile(ctx context.Context, in *FilePullRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileTransferStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConsoleService_ServiceDesc.Streams[2], ConsoleService_PullFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FilePullRequest, FileTransferStatus]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_PullFileClient = grpc.ServerStreamingClient[FileTransferStatus]
+
+func (c *consoleServiceClient) ListFileTransfers(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FileTransferList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FileTransferList)
+	err := c.cc.Invoke(ctx, ConsoleService_ListFileTransfers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *consoleServiceClient) DownloadFile(ctx context.Context, in *FileDownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ConsoleService_ServiceDesc.Streams[3], ConsoleService_DownloadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FileDownloadRequest, FileChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_DownloadFileClient = grpc.ServerStreamingClient[FileChunk]
+
+// ConsoleServiceServer is the server API for ConsoleService service.
+// All implementations must embed UnimplementedConsoleServiceServer
+// for forward compatibility.
+type ConsoleServiceServer interface {
+	ListMinions(context.Context, *Empty) (*MinionList, error)
+	ListTags(context.Context, *Empty) (*TagList, error)
+	SetTags(context.Context, *SetTagsRequest) (*Ack, error)
+	UpdateTags(context.Context, *UpdateTagsRequest) (*Ack, error)
+	GetTagSchema(context.Context, *Empty) (*TagSchema, error)
+	CreateBootstrapToken(context.Context, *BootstrapRequest) (*BootstrapToken, error)
+	SendCommand(context.Context, *CommandRequest) (*CommandDispatchResponse, error)
+	ExplainTargets(context.Context, *ExplainTargetsRequest) (*TargetExplanations, error)
+	BatchSendCommand(context.Context, *BatchCommandRequest) (*BatchCommandResponse, error)
+	GetCommandResults(context.Context, *ResultRequest) (*CommandResults, error)
+	GetCommandStatus(context.Context, *ResultRequest) (*CommandStatusResponse, error)
+	ListCommandApprovals(context.Context, *Empty) (*CommandApprovalList, error)
+	DecideCommandApproval(context.Context, *ApprovalDecision) (*CommandDispatchResponse, error)
+	AddMaintenanceWindow(context.Context, *MaintenanceWindow) (*MaintenanceWindow, error)
+	ListMaintenanceWindows(context.Context, *Empty) (*MaintenanceWindowList, error)
+	RemoveMaintenanceWindow(context.Context, *MaintenanceWindowRequest) (*Ack, error)
+	SetCommandEnv(context.Context, *CommandEnvVar) (*Ack, error)
+	UnsetCommandEnv(context.Context, *CommandEnvVar) (*Ack, error)
+	ListCommandEnv(context.Context, *Empty) (*CommandEnvList, error)
+	GetMinionDiagnostics(context.Context, *MinionDiagnosticsRequest) (*MinionDiagnostics, error)
+	GetFleetHealth(context.Context, *FleetHealthRequest) (*FleetHealth, error)
+	GetFleetVersions(context.Context, *Empty) (*FleetVersions, error)
+	GetPatchCompliance(context.Context, *PatchComplianceRequest) (*PatchCompliance, error)
+	GetMinionHistory(context.Context, *MinionHistoryRequest) (*MinionHistory, error)
+	GetMinionUptime(context.Context, *MinionUptimeRequest) (*MinionUptime, error)
+	GetMinionLogs(context.Context, *MinionLogsRequest) (*MinionLogs, error)
+	RetireMinion(context.Context, *RetireMinionRequest) (*RetireMinionResponse, error)
+	ListCrashes(context.Context, *CrashListRequest) (*CrashList, error)
+	GetTrace(context.Context, *TraceRequest) (*Trace, error)
+	GetCommandStats(context.Context, *CommandStatsRequest) (*CommandStats, error)
+	// ExportReceipts streams the signed execution receipts of the commands sent in a time range
+	ExportReceipts(*ReceiptExportRequest, grpc.ServerStreamingServer[ExecutionReceipt]) error
+	CreateReport(context.Context, *Report) (*Report, error)
+	ListReports(context.Context, *Empty) (*ReportList, error)
+	RunReport(context.Context, *ReportRequest) (*ReportResult, error)
+	CheckDatabase(context.Context, *DatabaseCheckRequest) (*DatabaseCheckReport, error)
+	ListDatabaseQueries(context.Context, *Empty) (*DatabaseQueryList, error)
+	RunDatabaseQuery(context.Context, *DatabaseQueryRequest) (*DatabaseQueryResult, error)
+	// OpenShell attaches the console to an interactive shell running on a minion
+	OpenShell(grpc.BidiStreamingServer[ShellMessage, ShellMessage]) error
+	// PullFile starts or resumes pulling a file from a minion to Nexus, streaming its progress
+	PullFile(*FilePullRequest, grpc.ServerStreamingServer[FileTransferStatus]) error
+	ListFileTransfers(context.Context, *Empty) (*FileTransferList, error)
+	// DownloadFile streams a file pulled to Nexus, from an offset to resume a download
+	DownloadFile(*FileDownloadRequest, grpc.ServerStreamingServer[FileChunk]) error
+	mustEmbedUnimplementedConsoleServiceServer()
+}
+
+// UnimplementedConsoleServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedConsoleServiceServer struct{}
+
+func (UnimplementedConsoleServiceServer) ListMinions(context.Context, *Empty) (*MinionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMinions not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListTags(context.Context, *Empty) (*TagList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTags not implemented")
+}
+func (UnimplementedConsoleServiceServer) SetTags(context.Context, *SetTagsRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetTags not implemented")
+}
+func (UnimplementedConsoleServiceServer) UpdateTags(context.Context, *UpdateTagsRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTags not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetTagSchema(context.Context, *Empty) (*TagSchema, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTagSchema not implemented")
+}
+func (UnimplementedConsoleServiceServer) CreateBootstrapToken(context.Context, *BootstrapRequest) (*BootstrapToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBootstrapToken not implemented")
+}
+func (UnimplementedConsoleServiceServer) SendCommand(context.Context, *CommandRequest) (*CommandDispatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendCommand not implemented")
+}
+func (UnimplementedConsoleServiceServer) ExplainTargets(context.Context, *ExplainTargetsRequest) (*TargetExplanations, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExplainTargets not implemented")
+}
+func (UnimplementedConsoleServiceServer) BatchSendCommand(context.Context, *BatchCommandRequest) (*BatchCommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchSendCommand not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetCommandResults(context.Context, *ResultRequest) (*CommandResults, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCommandResults not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetCommandStatus(context.Context, *ResultRequest) (*CommandStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCommandStatus not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListCommandApprovals(context.Context, *Empty) (*CommandApprovalList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommandApprovals not implemented")
+}
+func (UnimplementedConsoleServiceServer) DecideCommandApproval(context.Context, *ApprovalDecision) (*CommandDispatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DecideCommandApproval not implemented")
+}
+func (UnimplementedConsoleServiceServer) AddMaintenanceWindow(context.Context, *MaintenanceWindow) (*MaintenanceWindow, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddMaintenanceWindow not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListMaintenanceWindows(context.Context, *Empty) (*MaintenanceWindowList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMaintenanceWindows not implemented")
+}
+func (UnimplementedConsoleServiceServer) RemoveMaintenanceWindow(context.Context, *MaintenanceWindowRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveMaintenanceWindow not implemented")
+}
+func (UnimplementedConsoleServiceServer) SetCommandEnv(context.Context, *CommandEnvVar) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCommandEnv not implemented")
+}
+func (UnimplementedConsoleServiceServer) UnsetCommandEnv(context.Context, *CommandEnvVar) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnsetCommandEnv not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListCommandEnv(context.Context, *Empty) (*CommandEnvList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCommandEnv not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetMinionDiagnostics(context.Context, *MinionDiagnosticsRequest) (*MinionDiagnostics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMinionDiagnostics not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetFleetHealth(context.Context, *FleetHealthRequest) (*FleetHealth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFleetHealth not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetFleetVersions(context.Context, *Empty) (*FleetVersions, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFleetVersions not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetPatchCompliance(context.Context, *PatchComplianceRequest) (*PatchCompliance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPatchCompliance not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetMinionHistory(context.Context, *MinionHistoryRequest) (*MinionHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMinionHistory not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetMinionUptime(context.Context, *MinionUptimeRequest) (*MinionUptime, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMinionUptime not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetMinionLogs(context.Context, *MinionLogsRequest) (*MinionLogs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMinionLogs not implemented")
+}
+func (UnimplementedConsoleServiceServer) RetireMinion(context.Context, *RetireMinionRequest) (*RetireMinionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetireMinion not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListCrashes(context.Context, *CrashListRequest) (*CrashList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCrashes not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetTrace(context.Context, *TraceRequest) (*Trace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTrace not implemented")
+}
+func (UnimplementedConsoleServiceServer) GetCommandStats(context.Context, *CommandStatsRequest) (*CommandStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCommandStats not implemented")
+}
+func (UnimplementedConsoleServiceServer) ExportReceipts(*ReceiptExportRequest, grpc.ServerStreamingServer[ExecutionReceipt]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportReceipts not implemented")
+}
+func (UnimplementedConsoleServiceServer) CreateReport(context.Context, *Report) (*Report, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateReport not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListReports(context.Context, *Empty) (*ReportList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReports not implemented")
+}
+func (UnimplementedConsoleServiceServer) RunReport(context.Context, *ReportRequest) (*ReportResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunReport not implemented")
+}
+func (UnimplementedConsoleServiceServer) CheckDatabase(context.Context, *DatabaseCheckRequest) (*DatabaseCheckReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckDatabase not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListDatabaseQueries(context.Context, *Empty) (*DatabaseQueryList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDatabaseQueries not implemented")
+}
+func (UnimplementedConsoleServiceServer) RunDatabaseQuery(context.Context, *DatabaseQueryRequest) (*DatabaseQueryResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunDatabaseQuery not implemented")
+}
+func (UnimplementedConsoleServiceServer) OpenShell(grpc.BidiStreamingServer[ShellMessage, ShellMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method OpenShell not implemented")
+}
+func (UnimplementedConsoleServiceServer) PullFile(*FilePullRequest, grpc.ServerStreamingServer[FileTransferStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method PullFile not implemented")
+}
+func (UnimplementedConsoleServiceServer) ListFileTransfers(context.Context, *Empty) (*FileTransferList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFileTransfers not implemented")
+}
+func (UnimplementedConsoleServiceServer) DownloadFile(*FileDownloadRequest, grpc.ServerStreamingServer[FileChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadFile not implemented")
+}
+func (UnimplementedConsoleServiceServer) mustEmbedUnimplementedConsoleServiceServer() {}
+func (UnimplementedConsoleServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeConsoleServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConsoleServiceServer will
+// result in compilation errors.
+type UnsafeConsoleServiceServer interface {
+	mustEmbedUnimplementedConsoleServiceServer()
+}
+
+func RegisterConsoleServiceServer(s grpc.ServiceRegistrar, srv ConsoleServiceServer) {
+	// If the following call pancis, it indicates UnimplementedConsoleServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ConsoleService_ServiceDesc, srv)
+}
+
+func _ConsoleService_ListMinions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListMinions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListMinions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListMinions(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListTags(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_SetTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).SetTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_SetTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).SetTags(ctx, req.(*SetTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_UpdateTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).UpdateTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_UpdateTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).UpdateTags(ctx, req.(*UpdateTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetTagSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetTagSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetTagSchema_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetTagSchema(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_CreateBootstrapToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BootstrapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).CreateBootstrapToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_CreateBootstrapToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).CreateBootstrapToken(ctx, req.(*BootstrapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_SendCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).SendCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_SendCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).SendCommand(ctx, req.(*CommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ExplainTargets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainTargetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ExplainTargets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ExplainTargets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ExplainTargets(ctx, req.(*ExplainTargetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_BatchSendCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).BatchSendCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_BatchSendCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).BatchSendCommand(ctx, req.(*BatchCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetCommandResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetCommandResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetCommandResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetCommandResults(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetCommandStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetCommandStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetCommandStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetCommandStatus(ctx, req.(*ResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListCommandApprovals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListCommandApprovals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListCommandApprovals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListCommandApprovals(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_DecideCommandApproval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApprovalDecision)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).DecideCommandApproval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_DecideCommandApproval_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).DecideCommandApproval(ctx, req.(*ApprovalDecision))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_AddMaintenanceWindow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceWindow)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).AddMaintenanceWindow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_AddMaintenanceWindow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).AddMaintenanceWindow(ctx, req.(*MaintenanceWindow))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListMaintenanceWindows_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListMaintenanceWindows(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListMaintenanceWindows_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListMaintenanceWindows(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_RemoveMaintenanceWindow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceWindowRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).RemoveMaintenanceWindow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_RemoveMaintenanceWindow_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).RemoveMaintenanceWindow(ctx, req.(*MaintenanceWindowRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_SetCommandEnv_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandEnvVar)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).SetCommandEnv(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_SetCommandEnv_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).SetCommandEnv(ctx, req.(*CommandEnvVar))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_UnsetCommandEnv_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandEnvVar)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).UnsetCommandEnv(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_UnsetCommandEnv_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).UnsetCommandEnv(ctx, req.(*CommandEnvVar))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListCommandEnv_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListCommandEnv(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListCommandEnv_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListCommandEnv(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetMinionDiagnostics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinionDiagnosticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetMinionDiagnostics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetMinionDiagnostics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetMinionDiagnostics(ctx, req.(*MinionDiagnosticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetFleetHealth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FleetHealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetFleetHealth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetFleetHealth_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetFleetHealth(ctx, req.(*FleetHealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetFleetVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetFleetVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetFleetVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetFleetVersions(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetPatchCompliance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchComplianceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetPatchCompliance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetPatchCompliance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetPatchCompliance(ctx, req.(*PatchComplianceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetMinionHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinionHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetMinionHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetMinionHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetMinionHistory(ctx, req.(*MinionHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetMinionUptime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinionUptimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetMinionUptime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetMinionUptime_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetMinionUptime(ctx, req.(*MinionUptimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetMinionLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinionLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetMinionLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetMinionLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetMinionLogs(ctx, req.(*MinionLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_RetireMinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetireMinionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).RetireMinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_RetireMinion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).RetireMinion(ctx, req.(*RetireMinionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListCrashes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CrashListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListCrashes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListCrashes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListCrashes(ctx, req.(*CrashListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetTrace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TraceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetTrace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetTrace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetTrace(ctx, req.(*TraceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_GetCommandStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CommandStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).GetCommandStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_GetCommandStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).GetCommandStats(ctx, req.(*CommandStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ExportReceipts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReceiptExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConsoleServiceServer).ExportReceipts(m, &grpc.GenericServerStream[ReceiptExportRequest, ExecutionReceipt]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_ExportReceiptsServer = grpc.ServerStreamingServer[ExecutionReceipt]
+
+func _ConsoleService_CreateReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Report)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).CreateReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_CreateReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).CreateReport(ctx, req.(*Report))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListReports_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListReports(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListReports_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListReports(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_RunReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).RunReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_RunReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).RunReport(ctx, req.(*ReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_CheckDatabase_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DatabaseCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).CheckDatabase(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_CheckDatabase_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).CheckDatabase(ctx, req.(*DatabaseCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_ListDatabaseQueries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListDatabaseQueries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListDatabaseQueries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListDatabaseQueries(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_RunDatabaseQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DatabaseQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).RunDatabaseQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_RunDatabaseQuery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).RunDatabaseQuery(ctx, req.(*DatabaseQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_OpenShell_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ConsoleServiceServer).OpenShell(&grpc.GenericServerStream[ShellMessage, ShellMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_OpenShellServer = grpc.BidiStreamingServer[ShellMessage, ShellMessage]
+
+func _ConsoleService_PullFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FilePullRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConsoleServiceServer).PullFile(m, &grpc.GenericServerStream[FilePullRequest, FileTransferStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_PullFileServer = grpc.ServerStreamingServer[FileTransferStatus]
+
+func _ConsoleService_ListFileTransfers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConsoleServiceServer).ListFileTransfers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ConsoleService_ListFileTransfers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConsoleServiceServer).ListFileTransfers(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConsoleService_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FileDownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConsoleServiceServer).DownloadFile(m, &grpc.GenericServerStream[FileDownloadRequest, FileChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ConsoleService_DownloadFileServer = grpc.ServerStreamingServer[FileChunk]
+
+// ConsoleService_ServiceDesc is the grpc.ServiceDesc for ConsoleService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConsoleService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "minexus.ConsoleService",
+	HandlerType: (*ConsoleServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListMinions",
+			Handler:    _ConsoleService_ListMinions_Handler,
+		},
+		{
+			MethodName: "ListTags",
+			Handler:    _ConsoleService_ListTags_Handler,
+		},
+		{
+			MethodName: "SetTags",
+			Handler:    _ConsoleService_SetTags_Handler,
+		},
+		{
+			MethodName: "UpdateTags",
+			Handler:    _ConsoleService_UpdateTags_Handler,
+		},
+		{
+			MethodName: "GetTagSchema",
+			Handler:    _ConsoleService_GetTagSchema_Handler,
+		},
+		{
+			MethodName: "CreateBootstrapToken",
+			Handler:    _ConsoleService_CreateBootstrapToken_Handler,
+		},
+		{
+			MethodName: "SendCommand",
+			Handler:    _ConsoleService_SendCommand_Handler,
+		},
+		{
+			MethodName: "ExplainTargets",
+			Handler:    _ConsoleService_ExplainTargets_Handler,
+		},
+		{
+			MethodName: "BatchSendCommand",
+			Handler:    _ConsoleService_BatchSendCommand_Handler,
+		},
+		{
+			MethodName: "GetCommandResults",
+			Handler:    _ConsoleService_GetCommandResults_Handler,
+		},
+		{
+			MethodName: "GetCommandStatus",
+			Handler:    _ConsoleService_GetCommandStatus_Handler,
+		},
+		{
+			MethodName: "ListCommandApprovals",
+			Handler:    _ConsoleService_ListCommandApprovals_Handler,
+		},
+		{
+			MethodName: "DecideCommandApproval",
+			Handler:    _ConsoleService_DecideCommandApproval_Handler,
+		},
+		{
+			MethodName: "AddMaintenanceWindow",
+			Handler:    _ConsoleService_AddMaintenanceWindow_Handler,
+		},
+		{
+			MethodName: "ListMaintenanceWindows",
+			Handler:    _ConsoleService_ListMaintenanceWindows_Handler,
+		},
+		{
+			MethodName: "RemoveMaintenanceWindow",
+			Handler:    _ConsoleService_RemoveMaintenanceWindow_Handler,
+		},
+		{
+			MethodName: "SetCommandEnv",
+			Handler:    _ConsoleService_SetCommandEnv_Handler,
+		},
+		{
+			MethodName: "UnsetCommandEnv",
+			Handler:    _ConsoleService_UnsetCommandEnv_Handler,
+		},
+		{
+			MethodName: "ListCommandEnv",
+			Handler:    _ConsoleService_ListCommandEnv_Handler,
+		},
+		{
+			MethodName: "GetMinionDiagnostics",
+			Handler:    _ConsoleService_GetMinionDiagnostics_Handler,
+		},
+		{
+			MethodName: "GetFleetHealth",
+			Handler:    _ConsoleService_GetFleetHealth_Handler,
+		},
+		{
+			MethodName: "GetFleetVersions",
+			Handler:    _ConsoleService_GetFleetVersions_Handler,
+		},
+		{
+			MethodName: "GetPatchCompliance",
+			Handler:    _ConsoleService_GetPatchCompliance_Handler,
+		},
+		{
+			MethodName: "GetMinionHistory",
+			Handler:    _ConsoleService_GetMinionHistory_Handler,
+		},
+		{
+			MethodName: "GetMinionUptime",
+			Handler:    _ConsoleService_GetMinionUptime_Handler,
+		},
+		{
+			MethodName: "GetMinionLogs",
+			Handler:    _ConsoleService_GetMinionLogs_Handler,
+		},
+		{
+			MethodName: "RetireMinion",
+			Handler:    _ConsoleService_RetireMinion_Handler,
+		},
+		{
+			MethodName: "ListCrashes",
+			Handler:    _ConsoleService_ListCrashes_Handler,
+		},
+		{
+			MethodName: "GetTrace",
+			Handler:    _ConsoleService_GetTrace_Handler,
+		},
+		{
+			MethodName: "GetCommandStats",
+			Handler:    _ConsoleService_GetCommandStats_Handler,
+		},
+		{
+			MethodName: "CreateReport",
+			Handler:    _ConsoleService_CreateReport_Handler,
+		},
+		{
+			MethodName: "ListReports",
+			Handler:    _ConsoleService_ListReports_Handler,
+		},
+		{
+			MethodName: "RunReport",
+			Handler:    _ConsoleService_RunReport_Handler,
+		},
+		{
+			MethodName: "CheckDatabase",
+			Handler:    _ConsoleService_CheckDatabase_Handler,
+		},
+		{
+			MethodName: "ListDatabaseQueries",
+			Handler:    _ConsoleService_ListDatabaseQueries_Handler,
+		},
+		{
+			MethodName: "RunDatabaseQuery",
+			Handler:    _ConsoleService_RunDatabaseQuery_Handler,
+		},
+		{
+			MethodName: "ListFileTransfers",
+			Handler:    _ConsoleService_ListFileTransfers_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportReceipts",
+			Handler:       _ConsoleService_ExportReceipts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "OpenShell",
+			Handler:       _ConsoleService_OpenShell_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "PullFile",
+			Handler:       _ConsoleService_PullFile_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadFile",
+			Handler:       _ConsoleService_DownloadFile_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "minexus.proto",
+}
+
+const (
+	AdminService_FlushCaches_FullMethodName      = "/minexus.AdminService/FlushCaches"
+	AdminService_SetLogLevel_FullMethodName      = "/minexus.AdminService/SetLogLevel"
+	AdminService_DumpRegistry_FullMethodName     = "/minexus.AdminService/DumpRegistry"
+	AdminService_DisconnectMinion_FullMethodName = "/minexus.AdminService/DisconnectMinion"
+	AdminService_PruneDatabase_FullMethodName    = "/minexus.AdminService/PruneDatabase"
+	AdminService_UnbindMinion_FullMethodName     = "/minexus.AdminService/UnbindMinion"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService exposes the operational controls of Nexus. It is served on the console port to the
+// consoles whose certificate common name is an administrator, see NEXUS_ADMINS.
+type AdminServiceClient interface {
+	FlushCaches(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FlushCachesResponse, error)
+	SetLogLevel(ctx context.Context, in *LogLevelRequest, opts ...grpc.CallOption) (*LogLevelResponse, error)
+	DumpRegistry(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RegistryDump, error)
+	DisconnectMinion(ctx context.Context, in *DisconnectMinionRequest, opts ...grpc.CallOption) (*Ack, error)
+	PruneDatabase(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PruneDatabaseResponse, error)
+	UnbindMinion(ctx context.Context, in *UnbindMinionRequest, opts ...grpc.CallOption) (*Ack, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) FlushCaches(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*FlushCachesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FlushCachesResponse)
+	err := c.cc.Invoke(ctx, AdminService_FlushCaches_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *consoleServiceClient) GetCommandResults(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*CommandResults, error) {
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, in *LogLevelRequest, opts ...grpc.CallOption) (*LogLevelResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(CommandResults)
-	err := c.cc.Invoke(ctx, ConsoleService_GetCommandResults_FullMethodName, in, out, cOpts...)
+	out := new(LogLevelResponse)
+	err := c.cc.Invoke(ctx, AdminService_SetLogLevel_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *consoleServiceClient) GetCommandStatus(ctx context.Context, in *ResultRequest, opts ...grpc.CallOption) (*CommandStatusResponse, error) {
+func (c *adminServiceClient) DumpRegistry(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RegistryDump, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(CommandStatusResponse)
-	err := c.cc.Invoke(ctx, ConsoleService_GetCommandStatus_FullMethodName, in, out, cOpts...)
+	out := new(RegistryDump)
+	err := c.cc.Invoke(ctx, AdminService_DumpRegistry_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// ConsoleServiceServer is the server API for ConsoleService service.
-// All implementations must embed UnimplementedConsoleServiceServer
+func (c *adminServiceClient) DisconnectMinion(ctx context.Context, in *DisconnectMinionRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, AdminService_DisconnectMinion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) PruneDatabase(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*PruneDatabaseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PruneDatabaseResponse)
+	err := c.cc.Invoke(ctx, AdminService_PruneDatabase_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) UnbindMinion(ctx context.Context, in *UnbindMinionRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, AdminService_UnbindMinion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
 // for forward compatibility.
-type ConsoleServiceServer interface {
-	ListMinions(context.Context, *Empty) (*MinionList, error)
-	ListTags(context.Context, *Empty) (*TagList, error)
-	SetTags(context.Context, *SetTagsRequest) (*Ack, error)
-	UpdateTags(context.Context, *UpdateTagsRequest) (*Ack, error)
-	SendCommand(context.Context, *CommandRequest) (*CommandDispatchResponse, error)
-	GetCommandResults(context.Context, *ResultRequest) (*CommandResults, error)
-	GetCommandStatus(context.Context, *ResultRequest) (*CommandStatusResponse, error)
-	mustEmbedUnimplementedConsoleServiceServer()
+//
+// AdminService exposes the operational controls of Nexus. It is served on the console port to the
+// consoles whose certificate common name is an administrator, see NEXUS_ADMINS.
+type AdminServiceServer interface {
+	FlushCaches(context.Context, *Empty) (*FlushCachesResponse, error)
+	SetLogLevel(context.Context, *LogLevelRequest) (*LogLevelResponse, error)
+	DumpRegistry(context.Context, *Empty) (*RegistryDump, error)
+	DisconnectMinion(context.Context, *DisconnectMinionRequest) (*Ack, error)
+	PruneDatabase(context.Context, *Empty) (*PruneDatabaseResponse, error)
+	UnbindMinion(context.Context, *UnbindMinionRequest) (*Ack, error)
+	mustEmbedUnimplementedAdminServiceServer()
 }
 
-// UnimplementedConsoleServiceServer must be embedded to have
+// UnimplementedAdminServiceServer must be embedded to have
 // forward compatible implementations.
 //
 // NOTE: this should be embedded by value instead of pointer to avoid a nil
 // pointer dereference when methods are called.
-type UnimplementedConsoleServiceServer struct{}
+type UnimplementedAdminServiceServer struct{}
 
-func (UnimplementedConsoleServiceServer) ListMinions(context.Context, *Empty) (*MinionList, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListMinions not implemented")
-}
-func (UnimplementedConsoleServiceServer) ListTags(context.Context, *Empty) (*TagList, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListTags not implemented")
+func (UnimplementedAdminServiceServer) FlushCaches(context.Context, *Empty) (*FlushCachesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FlushCaches not implemented")
 }
-func (UnimplementedConsoleServiceServer) SetTags(context.Context, *SetTagsRequest) (*Ack, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SetTags not implemented")
+func (UnimplementedAdminServiceServer) SetLogLevel(context.Context, *LogLevelRequest) (*LogLevelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
 }
-func (UnimplementedConsoleServiceServer) UpdateTags(context.Context, *UpdateTagsRequest) (*Ack, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateTags not implemented")
+func (UnimplementedAdminServiceServer) DumpRegistry(context.Context, *Empty) (*RegistryDump, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpRegistry not implemented")
 }
-func (UnimplementedConsoleServiceServer) SendCommand(context.Context, *CommandRequest) (*CommandDispatchResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SendCommand not implemented")
+func (UnimplementedAdminServiceServer) DisconnectMinion(context.Context, *DisconnectMinionRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectMinion not implemented")
 }
-func (UnimplementedConsoleServiceServer) GetCommandResults(context.Context, *ResultRequest) (*CommandResults, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetCommandResults not implemented")
+func (UnimplementedAdminServiceServer) PruneDatabase(context.Context, *Empty) (*PruneDatabaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PruneDatabase not implemented")
 }
-func (UnimplementedConsoleServiceServer) GetCommandStatus(context.Context, *ResultRequest) (*CommandStatusResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetCommandStatus not implemented")
+func (UnimplementedAdminServiceServer) UnbindMinion(context.Context, *UnbindMinionRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnbindMinion not implemented")
 }
-func (UnimplementedConsoleServiceServer) mustEmbedUnimplementedConsoleServiceServer() {}
-func (UnimplementedConsoleServiceServer) testEmbeddedByValue()                        {}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
 
-// UnsafeConsoleServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to ConsoleServiceServer will
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
 // result in compilation errors.
-type UnsafeConsoleServiceServer interface {
-	mustEmbedUnimplementedConsoleServiceServer()
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
 }
 
-func RegisterConsoleServiceServer(s grpc.ServiceRegistrar, srv ConsoleServiceServer) {
-	// If the following call pancis, it indicates UnimplementedConsoleServiceServer was
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServiceServer was
 	// embedded by pointer and is nil.  This will cause panics if an
 	// unimplemented method is ever invoked, so we test this at initialization
 	// time to prevent it from happening at runtime later due to I/O.
 	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
 		t.testEmbeddedByValue()
 	}
-	s.RegisterService(&ConsoleService_ServiceDesc, srv)
-}
-
-func _ConsoleService_ListMinions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(ConsoleServiceServer).ListMinions(ctx, in)
-	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: ConsoleService_ListMinions_FullMethodName,
-	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).ListMinions(ctx, req.(*Empty))
-	}
-	return interceptor(ctx, in, info, handler)
+	s.RegisterService(&AdminService_ServiceDesc, srv)
 }
 
-func _ConsoleService_ListTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _AdminService_FlushCaches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ConsoleServiceServer).ListTags(ctx, in)
+		return srv.(AdminServiceServer).FlushCaches(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ConsoleService_ListTags_FullMethodName,
+		FullMethod: AdminService_FlushCaches_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).ListTags(ctx, req.(*Empty))
+		return srv.(AdminServiceServer).FlushCaches(ctx, req.(*Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ConsoleService_SetTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SetTagsRequest)
+func _AdminService_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogLevelRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ConsoleServiceServer).SetTags(ctx, in)
+		return srv.(AdminServiceServer).SetLogLevel(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ConsoleService_SetTags_FullMethodName,
+		FullMethod: AdminService_SetLogLevel_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).SetTags(ctx, req.(*SetTagsRequest))
+		return srv.(AdminServiceServer).SetLogLevel(ctx, req.(*LogLevelRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ConsoleService_UpdateTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateTagsRequest)
+func _AdminService_DumpRegistry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ConsoleServiceServer).UpdateTags(ctx, in)
+		return srv.(AdminServiceServer).DumpRegistry(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ConsoleService_UpdateTags_FullMethodName,
+		FullMethod: AdminService_DumpRegistry_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).UpdateTags(ctx, req.(*UpdateTagsRequest))
+		return srv.(AdminServiceServer).DumpRegistry(ctx, req.(*Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ConsoleService_SendCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CommandRequest)
+func _AdminService_DisconnectMinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectMinionRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ConsoleServiceServer).SendCommand(ctx, in)
+		return srv.(AdminServiceServer).DisconnectMinion(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ConsoleService_SendCommand_FullMethodName,
+		FullMethod: AdminService_DisconnectMinion_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).SendCommand(ctx, req.(*CommandRequest))
+		return srv.(AdminServiceServer).DisconnectMinion(ctx, req.(*DisconnectMinionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ConsoleService_GetCommandResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ResultRequest)
+func _AdminService_PruneDatabase_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ConsoleServiceServer).GetCommandResults(ctx, in)
+		return srv.(AdminServiceServer).PruneDatabase(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ConsoleService_GetCommandResults_FullMethodName,
+		FullMethod: AdminService_PruneDatabase_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).GetCommandResults(ctx, req.(*ResultRequest))
+		return srv.(AdminServiceServer).PruneDatabase(ctx, req.(*Empty))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _ConsoleService_GetCommandStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ResultRequest)
+func _AdminService_UnbindMinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnbindMinionRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(ConsoleServiceServer).GetCommandStatus(ctx, in)
+		return srv.(AdminServiceServer).UnbindMinion(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: ConsoleService_GetCommandStatus_FullMethodName,
+		FullMethod: AdminService_UnbindMinion_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ConsoleServiceServer).GetCommandStatus(ctx, req.(*ResultRequest))
+		return srv.(AdminServiceServer).UnbindMinion(ctx, req.(*UnbindMinionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-// ConsoleService_ServiceDesc is the grpc.ServiceDesc for ConsoleService service.
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
-var ConsoleService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "minexus.ConsoleService",
-	HandlerType: (*ConsoleServiceServer)(nil),
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "minexus.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
-			MethodName: "ListMinions",
-			Handler:    _ConsoleService_ListMinions_Handler,
-		},
-		{
-			MethodName: "ListTags",
-			Handler:    _ConsoleService_ListTags_Handler,
+			MethodName: "FlushCaches",
+			Handler:    _AdminService_FlushCaches_Handler,
 		},
 		{
-			MethodName: "SetTags",
-			Handler:    _ConsoleService_SetTags_Handler,
+			MethodName: "SetLogLevel",
+			Handler:    _AdminService_SetLogLevel_Handler,
 		},
 		{
-			MethodName: "UpdateTags",
-			Handler:    _ConsoleService_UpdateTags_Handler,
+			MethodName: "DumpRegistry",
+			Handler:    _AdminService_DumpRegistry_Handler,
 		},
 		{
-			MethodName: "SendCommand",
-			Handler:    _ConsoleService_SendCommand_Handler,
+			MethodName: "DisconnectMinion",
+			Handler:    _AdminService_DisconnectMinion_Handler,
 		},
 		{
-			MethodName: "GetCommandResults",
-			Handler:    _ConsoleService_GetCommandResults_Handler,
+			MethodName: "PruneDatabase",
+			Handler:    _AdminService_PruneDatabase_Handler,
 		},
 		{
-			MethodName: "GetCommandStatus",
-			Handler:    _ConsoleService_GetCommandStatus_Handler,
+			MethodName: "UnbindMinion",
+			Handler:    _AdminService_UnbindMinion_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
@@ -351,6 +1953,8 @@ var ConsoleService_ServiceDesc = grpc.ServiceDesc{
 const (
 	MinionService_Register_FullMethodName       = "/minexus.MinionService/Register"
 	MinionService_StreamCommands_FullMethodName = "/minexus.MinionService/StreamCommands"
+	MinionService_RelayStream_FullMethodName    = "/minexus.MinionService/RelayStream"
+	MinionService_SpeedTest_FullMethodName      = "/minexus.MinionService/SpeedTest"
 )
 
 // MinionServiceClient is the client API for MinionService service.
@@ -359,6 +1963,10 @@ const (
 type MinionServiceClient interface {
 	Register(ctx context.Context, in *HostInfo, opts ...grpc.CallOption) (*RegisterResponse, error)
 	StreamCommands(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[CommandStreamMessage, CommandStreamMessage], error)
+	// RelayStream multiplexes the minions connected to a relay over a single stream
+	RelayStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RelayMessage, RelayMessage], error)
+	// SpeedTest echoes latency probes and exchanges synthetic payloads with a minion running net:speedtest
+	SpeedTest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SpeedTestProbe, SpeedTestProbe], error)
 }
 
 type minionServiceClient struct {
@@ -392,12 +2000,42 @@ func (c *minionServiceClient) StreamCommands(ctx context.Context, opts ...grpc.C
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MinionService_StreamCommandsClient = grpc.BidiStreamingClient[CommandStreamMessage, CommandStreamMessage]
 
+func (c *minionServiceClient) RelayStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RelayMessage, RelayMessage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MinionService_ServiceDesc.Streams[1], MinionService_RelayStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RelayMessage, RelayMessage]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MinionService_RelayStreamClient = grpc.BidiStreamingClient[RelayMessage, RelayMessage]
+
+func (c *minionServiceClient) SpeedTest(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SpeedTestProbe, SpeedTestProbe], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MinionService_ServiceDesc.Streams[2], MinionService_SpeedTest_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SpeedTestProbe, SpeedTestProbe]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MinionService_SpeedTestClient = grpc.BidiStreamingClient[SpeedTestProbe, SpeedTestProbe]
+
 // MinionServiceServer is the server API for MinionService service.
 // All implementations must embed UnimplementedMinionServiceServer
 // for forward compatibility.
 type MinionServiceServer interface {
 	Register(context.Context, *HostInfo) (*RegisterResponse, error)
 	StreamCommands(grpc.BidiStreamingServer[CommandStreamMessage, CommandStreamMessage]) error
+	// RelayStream multiplexes the minions connected to a relay over a single stream
+	RelayStream(grpc.BidiStreamingServer[RelayMessage, RelayMessage]) error
+	// SpeedTest echoes latency probes and exchanges synthetic payloads with a minion running net:speedtest
+	SpeedTest(grpc.BidiStreamingServer[SpeedTestProbe, SpeedTestProbe]) error
 	mustEmbedUnimplementedMinionServiceServer()
 }
 
@@ -414,6 +2052,12 @@ func (UnimplementedMinionServiceServer) Register(context.Context, *HostInfo) (*R
 func (UnimplementedMinionServiceServer) StreamCommands(grpc.BidiStreamingServer[CommandStreamMessage, CommandStreamMessage]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamCommands not implemented")
 }
+func (UnimplementedMinionServiceServer) RelayStream(grpc.BidiStreamingServer[RelayMessage, RelayMessage]) error {
+	return status.Errorf(codes.Unimplemented, "method RelayStream not implemented")
+}
+func (UnimplementedMinionServiceServer) SpeedTest(grpc.BidiStreamingServer[SpeedTestProbe, SpeedTestProbe]) error {
+	return status.Errorf(codes.Unimplemented, "method SpeedTest not implemented")
+}
 func (UnimplementedMinionServiceServer) mustEmbedUnimplementedMinionServiceServer() {}
 func (UnimplementedMinionServiceServer) testEmbeddedByValue()                       {}
 
@@ -460,6 +2104,20 @@ func _MinionService_StreamCommands_Handler(srv interface{}, stream grpc.ServerSt
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MinionService_StreamCommandsServer = grpc.BidiStreamingServer[CommandStreamMessage, CommandStreamMessage]
 
+func _MinionService_RelayStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MinionServiceServer).RelayStream(&grpc.GenericServerStream[RelayMessage, RelayMessage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MinionService_RelayStreamServer = grpc.BidiStreamingServer[RelayMessage, RelayMessage]
+
+func _MinionService_SpeedTest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MinionServiceServer).SpeedTest(&grpc.GenericServerStream[SpeedTestProbe, SpeedTestProbe]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MinionService_SpeedTestServer = grpc.BidiStreamingServer[SpeedTestProbe, SpeedTestProbe]
+
 // MinionService_ServiceDesc is the grpc.ServiceDesc for MinionService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -479,6 +2137,18 @@ var MinionService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "RelayStream",
+			Handler:       _MinionService_RelayStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SpeedTest",
+			Handler:       _MinionService_SpeedTest_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "minexus.proto",
 }